@@ -58,6 +58,9 @@ type S3BearerIdentity struct {
 	// MaxScanBytes is the maximum number of bytes
 	// allowed to be scanned on any query.
 	MaxScanBytes uint64 `json:"MaxScanBytes"`
+	// FiscalYearStart is the first month of the
+	// tenant's fiscal year (1 to 12), or 0 for January.
+	FiscalYearStart int `json:"FiscalYearStart,omitempty"`
 }
 
 type S3BearerCredentials struct {
@@ -108,8 +111,12 @@ func (s *S3BearerIdentity) Tenant(ctx context.Context) (db.Tenant, error) {
 	root.Bucket = u.Host
 	root.Key = aws.DeriveKey(c.BaseURI, c.AccessKeyID, c.SecretAccessKey, s.Region, "s3")
 	root.Key.Token = c.SessionToken
+	if s.FiscalYearStart < 0 || s.FiscalYearStart > 12 {
+		return nil, fmt.Errorf("invalid FiscalYearStart %d in S3BearerIdentity", s.FiscalYearStart)
+	}
 	cfg := &db.TenantConfig{
-		MaxScanBytes: s.MaxScanBytes,
+		MaxScanBytes:    s.MaxScanBytes,
+		FiscalYearStart: s.FiscalYearStart,
 	}
 	return S3Tenant(ctx, s.ID, root, k, cfg), nil
 }
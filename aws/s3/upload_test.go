@@ -15,6 +15,8 @@
 package s3
 
 import (
+	"crypto/md5"
+	"encoding/hex"
 	"errors"
 	"io"
 	"net/http"
@@ -38,6 +40,9 @@ type testRoundTripper struct {
 		body    string
 		headers http.Header
 	}
+	// next, if non-nil, is called once
+	// after the next response is produced
+	next func()
 }
 
 var errUnexpected = errors.New("unexpected round-trip request")
@@ -80,6 +85,10 @@ func (t *testRoundTripper) RoundTrip(req *http.Request) (*http.Response, error)
 		ContentLength: int64(len(t.response.body)),
 		Header:        t.response.headers,
 	}
+	if next := t.next; next != nil {
+		t.next = nil
+		next()
+	}
 	return res, nil
 }
 
@@ -198,3 +207,91 @@ func TestUpload(t *testing.T) {
 		t.Errorf("abort: %s", err)
 	}
 }
+
+// Test that parts and the final part can be
+// uploaded again after a failure and that
+// Close verifies the ETag of the final object
+func TestUploadRetry(t *testing.T) {
+	trt := &testRoundTripper{t: t}
+	up := Uploader{
+		Key:     aws.DeriveKey("", "fake-access-key", "fake-secret-key", "us-east-1", "s3"),
+		Client:  &http.Client{Transport: trt},
+		Bucket:  "the-bucket",
+		Object:  "the-object",
+		id:      "the-upload-id",
+		started: true,
+	}
+	etag := func(buf []byte) string {
+		sum := md5.Sum(buf)
+		return `"` + hex.EncodeToString(sum[:]) + `"`
+	}
+
+	trt.expect.method = "PUT"
+	trt.expect.uri = "/the-object?partNumber=1&uploadId=the-upload-id"
+	trt.expect.skipBody = true
+	trt.response.code = 200
+	part := make([]byte, MinPartSize)
+	trt.response.headers = make(http.Header)
+	trt.response.headers.Set("ETag", "stale")
+	if err := up.Upload(1, part); err != nil {
+		t.Fatal(err)
+	}
+	// uploading part 1 again replaces it
+	trt.response.headers.Set("ETag", etag(part))
+	if err := up.Upload(1, part); err != nil {
+		t.Fatal(err)
+	}
+	if up.CompletedParts() != 1 {
+		t.Fatalf("%d completed parts", up.CompletedParts())
+	}
+
+	final := []byte("final part")
+	trt.expect.uri = "/the-object?partNumber=2&uploadId=the-upload-id"
+	trt.response.code = 403
+	if err := up.Close(final); err == nil {
+		t.Fatal("expected an error")
+	}
+
+	// the final part is uploaded as part 2 again
+	trt.response.code = 200
+	trt.response.headers.Set("ETag", etag(final))
+	trt.expect.uri = "/the-object?partNumber=2&uploadId=the-upload-id"
+	complete := func(etag string) {
+		// the final part is uploaded before the
+		// round-tripper is reconfigured for the POST
+		trt.next = func() {
+			trt.expect.method = "POST"
+			trt.expect.uri = "/the-object?uploadId=the-upload-id"
+			trt.response.headers = make(http.Header)
+			trt.response.headers.Set("Content-Type", "application/xml")
+			trt.response.body = `<CompleteMultipartUploadResult><ETag>` + etag + `</ETag></CompleteMultipartUploadResult>`
+		}
+	}
+	h := md5.New()
+	for _, p := range [][]byte{part, final} {
+		sum := md5.Sum(p)
+		h.Write(sum[:])
+	}
+	want := `"` + hex.EncodeToString(h.Sum(nil)) + `-2"`
+
+	complete(`"bad-2"`)
+	err := up.Close(final)
+	if err == nil || !strings.Contains(err.Error(), "does not match") {
+		t.Fatalf("expected ETag mismatch; got %v", err)
+	}
+	trt.expect.method = "PUT"
+	trt.expect.uri = "/the-object?partNumber=2&uploadId=the-upload-id"
+	trt.response.headers = make(http.Header)
+	trt.response.headers.Set("ETag", etag(final))
+	trt.response.body = ""
+	complete(want)
+	if err := up.Close(final); err != nil {
+		t.Fatal(err)
+	}
+	if up.ETag() != want {
+		t.Errorf("ETag %s, expected %s", up.ETag(), want)
+	}
+	if up.Size() != int64(len(part)+len(final)) {
+		t.Errorf("size %d", up.Size())
+	}
+}
@@ -15,6 +15,8 @@
 package s3
 
 import (
+	"crypto/md5"
+	"encoding/hex"
 	"encoding/xml"
 	"errors"
 	"fmt"
@@ -22,6 +24,7 @@ import (
 	"net/http"
 	"net/url"
 	"sort"
+	"strings"
 	"sync"
 	"sync/atomic"
 
@@ -88,6 +91,11 @@ type Uploader struct {
 	parts   []tagpart
 	maxpart int64
 
+	// number of the final part uploaded
+	// by Close; this is kept so that a
+	// failed Close can be retried
+	finalpart int64
+
 	// background uploads
 	bg       sync.WaitGroup
 	asyncerr error
@@ -253,7 +261,7 @@ func (u *Uploader) upload(num int64, contents []byte) error {
 	if num > u.maxpart {
 		u.maxpart = num
 	}
-	u.parts = append(u.parts, tagpart{
+	u.addPart(tagpart{
 		Num:  num,
 		ETag: etag,
 		size: int64(len(contents)),
@@ -262,6 +270,22 @@ func (u *Uploader) upload(num int64, contents []byte) error {
 	return nil
 }
 
+// addPart records a completed part;
+// a part that is uploaded more than once
+// replaces the previous upload of that part,
+// so uploads of individual parts can be retried
+//
+// the caller must hold u.lock
+func (u *Uploader) addPart(p tagpart) {
+	for i := range u.parts {
+		if u.parts[i].Num == p.Num {
+			u.parts[i] = p
+			return
+		}
+	}
+	u.parts = append(u.parts, p)
+}
+
 // CopyFrom performs a server side copy for the part number `num`.
 //
 // Set `start` and `end` to `0` to copy the entire source object.
@@ -348,7 +372,7 @@ func (u *Uploader) copy(num int64, source *Reader, start int64, end int64) {
 		return
 	}
 	u.lock.Lock()
-	u.parts = append(u.parts, tagpart{
+	u.addPart(tagpart{
 		Num:  num,
 		ETag: etag,
 		size: size,
@@ -398,6 +422,11 @@ func (u *Uploader) Size() int64 {
 //
 // Close will panic if Start has never been called
 // or if Close has already been called and returned successfully.
+// If Close returns an error, it may be called again
+// with the same final part to retry finalizing the object.
+//
+// Close verifies that the ETag of the final object
+// is the ETag computed from the ETags of its parts.
 func (u *Uploader) Close(final []byte) error {
 	if !u.started {
 		panic("s3.Uploader.Close before Start()")
@@ -410,7 +439,10 @@ func (u *Uploader) Close(final []byte) error {
 		// maxpart is updated in calls to CopyFrom and Upload,
 		// and we've specified that it is not safe for the caller
 		// to let those race with Close
-		err := u.upload(u.maxpart+1, final)
+		if u.finalpart == 0 {
+			u.finalpart = u.maxpart + 1
+		}
+		err := u.upload(u.finalpart, final)
 		if err != nil {
 			return err
 		}
@@ -479,11 +511,31 @@ func (u *Uploader) Close(final []byte) error {
 	case "CompleteMultipartUploadResult":
 		// ok; this is what we want
 	}
+	if want, ok := multipartETag(u.parts); ok && rt.ETag != want {
+		return fmt.Errorf("s3.Uploader.Close: ETag %s does not match ETag %s computed from %d parts", rt.ETag, want, len(u.parts))
+	}
 	u.finalETag = rt.ETag
 	u.finished = true
 	return nil
 }
 
+// multipartETag computes the ETag of a multi-part
+// object from the ETags of its parts, which is the
+// MD5 of the concatenated part MD5s followed by
+// the number of parts; it returns false if a part
+// ETag is not a hex-encoded MD5
+func multipartETag(parts []tagpart) (string, bool) {
+	h := md5.New()
+	for i := range parts {
+		sum, err := hex.DecodeString(strings.Trim(parts[i].ETag, `"`))
+		if err != nil || len(sum) != md5.Size {
+			return "", false
+		}
+		h.Write(sum)
+	}
+	return fmt.Sprintf(`"%x-%d"`, h.Sum(nil), len(parts)), true
+}
+
 // ETag returns the ETag of the final upload.
 // The return value of ETag is only valid after
 // Close has been called.
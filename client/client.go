@@ -0,0 +1,320 @@
+// Copyright (C) 2022 Sneller, Inc.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// Package client implements a client for
+// running queries against snellerd.
+//
+// Queries are submitted to the /streamQuery endpoint
+// and the ion results are decoded as they arrive:
+//
+//	c := client.New("http://localhost:8000", token)
+//	rows, err := c.Query(ctx, "mydb", "SELECT name, COUNT(*) AS n FROM events GROUP BY name")
+//	if err != nil {
+//		return err
+//	}
+//	defer rows.Close()
+//	for rows.Next() {
+//		var row struct {
+//			Name  string `ion:"name"`
+//			Count int64  `ion:"n"`
+//		}
+//		if err := rows.Scan(&row); err != nil {
+//			return err
+//		}
+//	}
+//	return rows.Err()
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/SnellerInc/sneller/ion"
+)
+
+const (
+	// DefaultRetries is the default value of Client.Retries
+	DefaultRetries = 3
+	// DefaultRetryDelay is the default value of Client.RetryDelay
+	DefaultRetryDelay = 250 * time.Millisecond
+	// DefaultMaxRowSize is the default value of Client.MaxRowSize
+	DefaultMaxRowSize = 1 << 20
+)
+
+// Client submits queries to snellerd.
+// The exported fields of a Client must not
+// be modified while queries are running.
+type Client struct {
+	// Endpoint is the base URL of snellerd,
+	// for example "http://localhost:8000".
+	Endpoint string
+	// Token is the bearer token used to
+	// authenticate requests.
+	Token string
+	// HTTPClient is the client used to make
+	// requests. If it is nil, http.DefaultClient
+	// is used.
+	HTTPClient *http.Client
+	// Retries is the number of times that a query
+	// is retried when it could not be submitted
+	// or snellerd responded with a transient error
+	// (429 Too Many Requests or 502, 503 or 504).
+	// Queries are never retried once results
+	// have started to arrive.
+	Retries int
+	// RetryDelay is the delay before the first retry;
+	// the delay doubles for each subsequent retry.
+	RetryDelay time.Duration
+	// MaxRowSize is the maximum size
+	// of an encoded row in the results.
+	MaxRowSize int
+}
+
+// New constructs a Client for the snellerd
+// instance at endpoint using the default
+// retry policy.
+func New(endpoint, token string) *Client {
+	return &Client{
+		Endpoint:   endpoint,
+		Token:      token,
+		Retries:    DefaultRetries,
+		RetryDelay: DefaultRetryDelay,
+		MaxRowSize: DefaultMaxRowSize,
+	}
+}
+
+// Error is returned when snellerd
+// rejects a query or the query fails.
+type Error struct {
+	// StatusCode is the HTTP status code of the
+	// response, or http.StatusOK if the query
+	// failed after results started to arrive.
+	StatusCode int
+	// Message is the error reported by snellerd.
+	Message string
+}
+
+func (e *Error) Error() string {
+	if e.StatusCode != http.StatusOK {
+		return fmt.Sprintf("snellerd: %s: %s", http.StatusText(e.StatusCode), e.Message)
+	}
+	return "snellerd: " + e.Message
+}
+
+// Status is the final status of a query.
+type Status struct {
+	// Hits and Misses are the number of
+	// cache hits and misses, respectively.
+	Hits   int64 `ion:"hits"`
+	Misses int64 `ion:"misses"`
+	// Scanned is the number of bytes scanned.
+	Scanned int64 `ion:"scanned"`
+	// Rows is the number of rows in the output,
+	// if it is known.
+	Rows int64 `ion:"rows"`
+	// Error is set if the query failed.
+	Error string `ion:"error"`
+}
+
+func (c *Client) http() *http.Client {
+	if c.HTTPClient != nil {
+		return c.HTTPClient
+	}
+	return http.DefaultClient
+}
+
+func retryable(code int) bool {
+	switch code {
+	case http.StatusTooManyRequests, http.StatusBadGateway,
+		http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// Query runs query in the given database (which may
+// be empty if every table in query is qualified with
+// a database name) and returns the results.
+// The caller must call Rows.Close when it is done
+// with the results. Canceling ctx cancels the query.
+func (c *Client) Query(ctx context.Context, database, query string) (*Rows, error) {
+	u, err := url.Parse(c.Endpoint)
+	if err != nil {
+		return nil, err
+	}
+	u = u.JoinPath("streamQuery")
+	if database != "" {
+		u.RawQuery = url.Values{"database": {database}}.Encode()
+	}
+	delay := c.RetryDelay
+	for try := 0; ; try++ {
+		res, err := c.submit(ctx, u.String(), query)
+		if err == nil && res.StatusCode == http.StatusOK {
+			return newRows(res.Body, c.MaxRowSize), nil
+		}
+		if err == nil {
+			err = responseError(res)
+			if !retryable(res.StatusCode) {
+				return nil, err
+			}
+		}
+		if ctx.Err() != nil || try >= c.Retries {
+			return nil, err
+		}
+		t := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return nil, ctx.Err()
+		case <-t.C:
+		}
+		delay *= 2
+	}
+}
+
+func (c *Client) submit(ctx context.Context, u, query string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u, strings.NewReader(query))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.Token)
+	req.Header.Set("Accept", "application/ion")
+	return c.http().Do(req)
+}
+
+// responseError consumes the body of an
+// unsuccessful response and closes it
+func responseError(res *http.Response) error {
+	defer res.Body.Close()
+	msg, _ := io.ReadAll(io.LimitReader(res.Body, 4096))
+	return &Error{
+		StatusCode: res.StatusCode,
+		Message:    strings.TrimSpace(string(msg)),
+	}
+}
+
+// Rows is the result of a query.
+type Rows struct {
+	body io.ReadCloser
+	dec  *ion.Decoder
+
+	cur    ion.Datum
+	status ion.Datum // final_status::{...}
+	final  Status
+	done   bool
+	err    error
+	closed bool
+
+	// scratch space for Scan
+	st  ion.Symtab
+	buf ion.Buffer
+}
+
+func newRows(body io.ReadCloser, max int) *Rows {
+	if max <= 0 {
+		max = DefaultMaxRowSize
+	}
+	r := &Rows{
+		body: body,
+		dec:  ion.NewDecoder(body, max),
+	}
+	r.dec.ExtraAnnotations = map[string]any{
+		"final_status": &r.status,
+	}
+	return r
+}
+
+// Next advances to the next row and returns
+// true, or returns false if there are no more
+// rows or an error occurred; see Rows.Err.
+func (r *Rows) Next() bool {
+	if r.done || r.closed {
+		return false
+	}
+	r.cur = ion.Empty
+	err := r.dec.Decode(&r.cur)
+	if err == nil {
+		return true
+	}
+	r.done = true
+	if errors.Is(err, io.EOF) {
+		// the final status is always the
+		// last value in a complete response
+		if r.status.IsEmpty() {
+			r.err = io.ErrUnexpectedEOF
+		} else if r.err = r.unmarshal(r.status, &r.final); r.err == nil && r.final.Error != "" {
+			r.err = &Error{StatusCode: http.StatusOK, Message: r.final.Error}
+		}
+	} else {
+		r.err = err
+	}
+	r.body.Close()
+	return false
+}
+
+// Datum returns the current row.
+func (r *Rows) Datum() ion.Datum { return r.cur }
+
+// Scan unmarshals the current row into dst,
+// which must be a pointer to a value that
+// ion.Unmarshal can decode into. Struct fields
+// are matched by name or by the name in their
+// "ion" tag.
+func (r *Rows) Scan(dst any) error {
+	if r.cur.IsEmpty() {
+		return errors.New("client: Scan called without a row")
+	}
+	if d, ok := dst.(*ion.Datum); ok {
+		*d = r.cur
+		return nil
+	}
+	return r.unmarshal(r.cur, dst)
+}
+
+func (r *Rows) unmarshal(d ion.Datum, dst any) error {
+	r.st.Reset()
+	r.buf.Reset()
+	d.Encode(&r.buf, &r.st)
+	_, err := ion.Unmarshal(&r.st, r.buf.Bytes(), dst)
+	return err
+}
+
+// Err returns the error, if any, that
+// ended the iteration over the rows.
+func (r *Rows) Err() error { return r.err }
+
+// Status returns the final status of the query.
+// It is only valid once Next has returned false
+// and Err returns nil.
+func (r *Rows) Status() Status { return r.final }
+
+// Close releases the connection used by the
+// results. Closing rows before all of them have
+// been read cancels the rest of the query.
+func (r *Rows) Close() error {
+	if r.closed {
+		return nil
+	}
+	r.closed = true
+	if r.done {
+		return nil
+	}
+	return r.body.Close()
+}
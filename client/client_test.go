@@ -0,0 +1,199 @@
+// Copyright (C) 2022 Sneller, Inc.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package client
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/SnellerInc/sneller/ion"
+)
+
+type testRow struct {
+	Name  string `ion:"name"`
+	Count int64  `ion:"n"`
+}
+
+// results encodes rows followed by a heartbeat
+// and (if final is not empty) the final status
+func results(rows []testRow, final []ion.Field) []byte {
+	var st ion.Symtab
+	var body ion.Buffer
+	for i := range rows {
+		ion.NewStruct(&st, []ion.Field{
+			{Label: "name", Datum: ion.String(rows[i].Name)},
+			{Label: "n", Datum: ion.Int(rows[i].Count)},
+		}).Datum().Encode(&body, &st)
+	}
+	annotate := func(label string, fields []ion.Field) {
+		body.BeginAnnotation(1)
+		body.BeginField(st.Intern(label))
+		ion.NewStruct(&st, fields).Datum().Encode(&body, &st)
+		body.EndAnnotation()
+	}
+	annotate("heartbeat", []ion.Field{{Label: "elapsed_ms", Datum: ion.Int(10)}})
+	if final != nil {
+		annotate("final_status", final)
+	}
+	var out ion.Buffer
+	st.Marshal(&out, true)
+	return append(out.Bytes(), body.Bytes()...)
+}
+
+func testClient(t *testing.T, fn http.HandlerFunc) *Client {
+	srv := httptest.NewServer(fn)
+	t.Cleanup(srv.Close)
+	c := New(srv.URL, "token")
+	c.RetryDelay = time.Millisecond
+	return c
+}
+
+func TestQuery(t *testing.T) {
+	want := []testRow{{"foo", 1}, {"bar", 2}}
+	var attempts int32
+	c := testClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&attempts, 1) == 1 {
+			http.Error(w, "overloaded", http.StatusTooManyRequests)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		switch {
+		case r.URL.Path != "/streamQuery":
+			t.Errorf("path %q", r.URL.Path)
+		case r.URL.Query().Get("database") != "db":
+			t.Errorf("query %q", r.URL.RawQuery)
+		case r.Header.Get("Authorization") != "Bearer token":
+			t.Errorf("authorization %q", r.Header.Get("Authorization"))
+		case r.Header.Get("Accept") != "application/ion":
+			t.Errorf("accept %q", r.Header.Get("Accept"))
+		case string(body) != "SELECT * FROM t":
+			t.Errorf("body %q", body)
+		}
+		w.Write(results(want, []ion.Field{{Label: "rows", Datum: ion.Int(2)}}))
+	})
+	rows, err := c.Query(context.Background(), "db", "SELECT * FROM t")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	var got []testRow
+	for rows.Next() {
+		var row testRow
+		if err := rows.Scan(&row); err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, row)
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("got rows %v", got)
+	}
+	if rows.Status().Rows != 2 {
+		t.Errorf("got status %+v", rows.Status())
+	}
+	if attempts != 2 {
+		t.Errorf("%d attempts", attempts)
+	}
+}
+
+func TestQueryErrors(t *testing.T) {
+	run := func(t *testing.T, fn http.HandlerFunc) (*Rows, error) {
+		c := testClient(t, fn)
+		rows, err := c.Query(context.Background(), "", "SELECT 1")
+		if err != nil {
+			return nil, err
+		}
+		defer rows.Close()
+		for rows.Next() {
+		}
+		return rows, rows.Err()
+	}
+	t.Run("rejected", func(t *testing.T) {
+		var attempts int32
+		_, err := run(t, func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&attempts, 1)
+			http.Error(w, "bad query", http.StatusBadRequest)
+		})
+		var e *Error
+		if !errors.As(err, &e) || e.StatusCode != http.StatusBadRequest || e.Message != "bad query" {
+			t.Fatalf("got error %v", err)
+		}
+		if attempts != 1 {
+			t.Errorf("%d attempts", attempts)
+		}
+	})
+	t.Run("retries", func(t *testing.T) {
+		var attempts int32
+		_, err := run(t, func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&attempts, 1)
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+		})
+		var e *Error
+		if !errors.As(err, &e) || e.StatusCode != http.StatusServiceUnavailable {
+			t.Fatalf("got error %v", err)
+		}
+		if attempts != DefaultRetries+1 {
+			t.Errorf("%d attempts", attempts)
+		}
+	})
+	t.Run("failed", func(t *testing.T) {
+		_, err := run(t, func(w http.ResponseWriter, r *http.Request) {
+			w.Write(results([]testRow{{"x", 1}}, []ion.Field{{Label: "error", Datum: ion.String("boom")}}))
+		})
+		var e *Error
+		if !errors.As(err, &e) || e.StatusCode != http.StatusOK || e.Message != "boom" {
+			t.Fatalf("got error %v", err)
+		}
+	})
+	t.Run("truncated", func(t *testing.T) {
+		_, err := run(t, func(w http.ResponseWriter, r *http.Request) {
+			w.Write(results([]testRow{{"x", 1}}, nil))
+		})
+		if !errors.Is(err, io.ErrUnexpectedEOF) {
+			t.Fatalf("got error %v", err)
+		}
+	})
+	t.Run("empty", func(t *testing.T) {
+		rows, err := run(t, func(w http.ResponseWriter, r *http.Request) {
+			w.Write(results(nil, []ion.Field{}))
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if rows.Status() != (Status{}) {
+			t.Errorf("got status %+v", rows.Status())
+		}
+	})
+	t.Run("canceled", func(t *testing.T) {
+		c := testClient(t, func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "overloaded", http.StatusTooManyRequests)
+		})
+		c.RetryDelay = time.Hour
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		_, err := c.Query(ctx, "", "SELECT 1")
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("got error %v", err)
+		}
+	})
+}
@@ -28,7 +28,7 @@ import (
 	"sync"
 	"sync/atomic"
 
-	"golang.org/x/sys/cpu"
+	"github.com/SnellerInc/sneller/vm"
 )
 
 func exitf(err error) {
@@ -220,8 +220,8 @@ func init() {
 }
 
 func main() {
-	if !cpu.X86.HasAVX512 {
-		exitf(fmt.Errorf("CPU doesn't support AVX-512"))
+	if err := vm.Supported(); err != nil {
+		exitf(err)
 	}
 	flag.Parse()
 	log.Printf("retrieved param -testdir %v", dashTestDir)
@@ -0,0 +1,86 @@
+// Copyright (C) 2022 Sneller, Inc.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"os"
+	"strings"
+
+	"github.com/SnellerInc/sneller/date"
+	"github.com/SnellerInc/sneller/db"
+)
+
+// entry point for 'sdb alter ...'
+func alter(args []string) bool {
+	var alt db.Alteration
+	var retention, partitions string
+	flags := flag.NewFlagSet(args[0], flag.ExitOnError)
+	flags.StringVar(&retention, "retention", "", "set the retention policy to <field>=<valid_for> (for example timestamp=6m)")
+	flags.BoolVar(&alt.DropRetention, "drop-retention", false, "remove the retention policy")
+	flags.StringVar(&partitions, "partitions", "", "replace the partitions with the given JSON list")
+	flags.Parse(args[1:])
+	args = flags.Args()
+	if len(args) != 2 {
+		return false
+	}
+	if retention != "" {
+		field, valid, ok := strings.Cut(retention, "=")
+		if !ok || field == "" {
+			exitf("-retention: expected <field>=<valid_for>")
+		}
+		d, ok := date.ParseDuration(valid)
+		if !ok {
+			exitf("-retention: invalid duration %q", valid)
+		}
+		alt.Retention = &db.RetentionPolicy{Field: field, ValidFor: d}
+	}
+	if partitions != "" {
+		var lst []db.Partition
+		if err := json.Unmarshal([]byte(partitions), &lst); err != nil {
+			exitf("-partitions: %s", err)
+		}
+		alt.Partitions = &lst
+	}
+	dbname, table := args[0], args[1]
+	def, err := db.AlterTable(outfs(creds()), dbname, table, &alt)
+	if err != nil {
+		exitf("altering %s/%s: %s", dbname, table, err)
+	}
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "\t")
+	enc.Encode(def)
+	return true
+}
+
+func init() {
+	addApplet(applet{
+		name: "alter",
+		help: "[-retention <field>=<valid_for>] [-drop-retention] [-partitions <json>] <db> <table>",
+		desc: `alter a table definition
+The command
+  $ sdb alter -retention timestamp=6m <db> <table>
+sets the retention policy of <table>, and
+  $ sdb alter -partitions '[{"field": "region"}]' <db> <table>
+replaces the partitions of <table>. The updated
+definition is printed once it has been written.
+
+New partitions only apply to data that is
+ingested after the definition is altered.
+`,
+		run: alter,
+	})
+}
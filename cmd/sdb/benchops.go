@@ -0,0 +1,76 @@
+// Copyright (C) 2022 Sneller, Inc.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"time"
+
+	"github.com/SnellerInc/sneller/vm"
+)
+
+// entry point for 'sdb bench-ops ...'
+func benchops(args []string) bool {
+	var dur time.Duration
+	var par int
+	flags := flag.NewFlagSet(args[0], flag.ExitOnError)
+	flags.DurationVar(&dur, "t", time.Second, "duration of each benchmark")
+	flags.IntVar(&par, "par", 1, "number of goroutines running each benchmark")
+	flags.Parse(args[1:])
+	args = flags.Args()
+	if len(args) > 1 {
+		return false
+	}
+	match := ""
+	if len(args) == 1 {
+		match = args[0]
+	}
+
+	fmt.Println("kernel variants:")
+	for _, k := range vm.Kernels() {
+		state := "disabled"
+		if k.Selected {
+			state = "selected"
+		}
+		fmt.Printf("  %-20s %-8s (requires %s)\n", k.Op, state, k.Requires)
+	}
+	res, err := vm.BenchmarkOps(match, dur, par)
+	if err != nil {
+		exitf("%s", err)
+	}
+	fmt.Println("benchmarks:")
+	for i := range res {
+		fmt.Printf("  %s\n", &res[i])
+	}
+	return true
+}
+
+func init() {
+	addApplet(applet{
+		name: "bench-ops",
+		help: "[-t duration] [-par n] [match]",
+		desc: `measure the throughput of bytecode ops
+The command
+  $ sdb bench-ops -t 2s LIKE
+lists the kernel variants selected for this CPU
+and then runs each of the built-in bytecode op
+microbenchmarks whose expression contains "LIKE"
+for 2 seconds, reporting the rows per second and
+the bytecode ops that were executed.
+`,
+		run: benchops,
+	})
+}
@@ -0,0 +1,81 @@
+// Copyright (C) 2022 Sneller, Inc.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"path"
+	"time"
+
+	"github.com/SnellerInc/sneller/db"
+)
+
+func compact(creds db.Tenant, dbname, tblpat string) {
+	ofs := root(creds)
+	tables, err := db.Tables(ofs, dbname)
+	if err != nil {
+		exitf("listing db %s: %s", dbname, err)
+	}
+	c := db.Config{
+		Align:         1024 * 1024,
+		RangeMultiple: 100,
+		GCMinimumAge:  5 * time.Minute,
+	}
+	if dashv {
+		c.Logf = logf
+	}
+	for _, tab := range tables {
+		match, err := path.Match(tblpat, tab)
+		if err != nil {
+			exitf("bad pattern %q: %s", tblpat, err)
+		}
+		if !match {
+			continue
+		}
+		err = c.Compact(creds, dbname, tab)
+		if err != nil {
+			exitf("compacting %s/%s: %s", dbname, tab, err)
+		}
+	}
+}
+
+func init() {
+	addApplet(applet{
+		name: "compact",
+		help: "<db> <table-pattern?>",
+		desc: `merge small packfiles in a db (+ table-pattern)
+The command
+  $ sdb compact <db> <table-pattern>
+merges the small packfiles in each of the tables
+that match the glob pattern <table-pattern> into
+larger packfiles. The packfiles that are replaced
+are deleted by a subsequent sync or compaction
+once they are at least 5 minutes old.
+
+This command is intended to be run periodically
+alongside "sync" for tables that receive frequent
+small updates.
+`,
+		run: func(args []string) bool {
+			if len(args) < 2 || len(args) > 3 {
+				return false
+			}
+			if len(args) == 2 {
+				args = append(args, "*")
+			}
+			compact(creds(), args[1], args[2])
+			return true
+		},
+	})
+}
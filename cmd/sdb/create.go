@@ -37,6 +37,9 @@ func load(defpath string) *db.Definition {
 func create(creds db.Tenant, dbname, defpath string) {
 	ofs := outfs(creds)
 	s := load(defpath)
+	if err := s.Validate(); err != nil {
+		exitf("invalid definition: %s", err)
+	}
 	if dashv {
 		logf("creating table %q in db %q", s.Name, dbname)
 	}
@@ -0,0 +1,57 @@
+// Copyright (C) 2022 Sneller, Inc.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"github.com/SnellerInc/sneller/db"
+)
+
+// entry point for 'sdb drop ...'
+func drop(creds db.Tenant, dbname, table string) {
+	ofs := outfs(creds)
+	if _, err := db.OpenDefinition(ofs, dbname, table); err != nil {
+		exitf("opening definition: %s", err)
+	}
+	if dashv {
+		logf("dropping table %q in db %q", table, dbname)
+	}
+	err := db.DropTable(ofs, dbname, table)
+	if err != nil {
+		exitf("dropping %s/%s: %s", dbname, table, err)
+	}
+}
+
+func init() {
+	addApplet(applet{
+		name: "drop",
+		help: "<db> <table>",
+		desc: `drop a table
+The command
+  $ sdb drop <db> <table>
+deletes the definition, the index, and all of the
+packed data belonging to <table>. The objects matched
+by the table inputs are not deleted.
+
+The table should not be synced while it is being dropped.
+`,
+		run: func(args []string) bool {
+			if len(args) != 3 {
+				return false
+			}
+			drop(creds(), args[1], args[2])
+			return true
+		},
+	})
+}
@@ -15,13 +15,30 @@
 package main
 
 import (
+	"flag"
+	"fmt"
 	"path"
 	"time"
 
 	"github.com/SnellerInc/sneller/db"
 )
 
-func gc(creds db.Tenant, dbname, tblpat string) {
+func gc(args []string) {
+	var dryrun, orphans bool
+	flags := flag.NewFlagSet(args[0], flag.ExitOnError)
+	flags.BoolVar(&orphans, "orphans", false, "remove every unreferenced object in the table prefix and report the reclaimed bytes")
+	flags.BoolVar(&dryrun, "n", false, "dry run; only list the objects that would be removed (requires -orphans)")
+	flags.Parse(args[1:])
+	args = flags.Args()
+	if len(args) == 1 {
+		args = append(args, "*")
+	}
+	if len(args) != 2 || (dryrun && !orphans) {
+		flags.Usage()
+		return
+	}
+	dbname, tblpat := args[0], args[1]
+	creds := creds()
 	ofs := root(creds)
 	rmfs, ok := ofs.(db.RemoveFS)
 	if !ok {
@@ -33,6 +50,7 @@ func gc(creds db.Tenant, dbname, tblpat string) {
 	}
 	conf := db.GCConfig{
 		MinimumAge: 15 * time.Minute,
+		DryRun:     dryrun,
 	}
 	if dashv {
 		conf.Logf = logf
@@ -50,6 +68,17 @@ func gc(creds db.Tenant, dbname, tblpat string) {
 		if err != nil {
 			exitf("opening index for %s/%s: %s", dbname, tab, err)
 		}
+		if orphans {
+			rep, err := conf.RemoveOrphans(rmfs, dbname, idx)
+			if err != nil {
+				exitf("removing orphans from %s/%s: %s", dbname, tab, err)
+			}
+			for i := range rep.Removed {
+				fmt.Println(rep.Removed[i])
+			}
+			logf("%s/%s: %d objects, %s reclaimed", dbname, tab, len(rep.Removed), human(rep.Bytes))
+			continue
+		}
 		err = conf.Run(rmfs, dbname, idx)
 		if err != nil {
 			exitf("running gc on %s/%s: %s", dbname, tab, err)
@@ -60,7 +89,7 @@ func gc(creds db.Tenant, dbname, tblpat string) {
 func init() {
 	addApplet(applet{
 		name: "gc",
-		help: "<db> <table-pattern?>",
+		help: "[-orphans [-n]] <db> <table-pattern?>",
 		desc: `gc old objects from a db (+ table-pattern)
 The command
   $ sdb gc <db> <table-pattern>
@@ -70,15 +99,17 @@ in the set of tables that match the glob pattern <table-pattern>.
 A file is a candidate for garbage collection if
 it is not pointed to by the current index file
 and it was created more than 15 minutes ago.
+
+With -orphans, every object written by sync in the
+prefix of each table is listed, including objects
+left behind by failed ingests or compactions in
+partitions that the index no longer references,
+and the path of each object that is removed is printed
+along with the number of bytes reclaimed.
+With -n, the objects are listed but not removed.
 `,
 		run: func(args []string) bool {
-			if len(args) < 2 || len(args) > 3 {
-				return false
-			}
-			if len(args) == 2 {
-				args = append(args, "*")
-			}
-			gc(creds(), args[1], args[2])
+			gc(args)
 			return true
 		},
 	})
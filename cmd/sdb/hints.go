@@ -0,0 +1,103 @@
+// Copyright (C) 2022 Sneller, Inc.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"os"
+
+	"github.com/SnellerInc/sneller/db"
+)
+
+// entry point for 'sdb hints ...'
+func hints(args []string) bool {
+	var file string
+	var drop bool
+	flags := flag.NewFlagSet(args[0], flag.ExitOnError)
+	flags.StringVar(&file, "f", "", "set the type hints from the given JSON file")
+	flags.BoolVar(&drop, "drop", false, "remove the type hints")
+	flags.Parse(args[1:])
+	args = flags.Args()
+	if len(args) != 2 || (file != "" && drop) {
+		return false
+	}
+	dbname, table := args[0], args[1]
+	ofs := outfs(creds())
+	switch {
+	case drop:
+		err := db.RemoveTypeHints(ofs, dbname, table)
+		if err != nil {
+			exitf("removing type hints: %s", err)
+		}
+		return true
+	case file != "":
+		buf, err := os.ReadFile(file)
+		if err != nil {
+			exitf("%s", err)
+		}
+		th := new(db.TypeHints)
+		if err := json.Unmarshal(buf, th); err != nil {
+			exitf("%s: %s", file, err)
+		}
+		if err := db.WriteTypeHints(ofs, dbname, table, th); err != nil {
+			exitf("writing type hints: %s", err)
+		}
+		return true
+	}
+	th, err := db.OpenTypeHints(ofs, dbname, table)
+	if err != nil {
+		exitf("reading type hints: %s", err)
+	}
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "\t")
+	enc.Encode(th)
+	return true
+}
+
+func init() {
+	addApplet(applet{
+		name: "hints",
+		help: "[-f <hints.json>] [-drop] <db> <table>",
+		desc: `show, set or remove the type hints of a table
+The command
+  $ sdb hints -f hints.json <db> <table>
+uploads a copy of hints.json to
+the tenant root file system at
+  /db/<db>/<table>/hints.json
+and the query planner uses the declared types
+of the paths in the table from then on.
+Without -f, the current type hints are printed,
+and with -drop they are removed.
+
+The hints.json is expected to be a JSON
+document with the following structure:
+
+  {
+    "fields": {
+      "timestamp": ["timestamp"],
+      "status": ["int"],
+      "request.path": ["string", "missing"]
+    }
+  }
+
+A query that references a path with values that
+do not match the declared types may produce incorrect
+results, so a path that is absent from some rows
+must list "missing" among its types.
+`,
+		run: hints,
+	})
+}
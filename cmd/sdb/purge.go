@@ -0,0 +1,99 @@
+// Copyright (C) 2022 Sneller, Inc.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"path"
+	"time"
+
+	"github.com/SnellerInc/sneller/db"
+)
+
+func purge(args []string) {
+	var dryrun bool
+	flags := flag.NewFlagSet(args[0], flag.ExitOnError)
+	flags.BoolVar(&dryrun, "n", false, "dry run; only list the objects that would be purged")
+	flags.Parse(args[1:])
+	args = flags.Args()
+	if len(args) == 1 {
+		args = append(args, "*")
+	}
+	if len(args) != 2 {
+		flags.Usage()
+		return
+	}
+	dbname, tblpat := args[0], args[1]
+	creds := creds()
+	ofs := root(creds)
+	tables, err := db.Tables(ofs, dbname)
+	if err != nil {
+		exitf("listing db %s: %s", dbname, err)
+	}
+	c := db.Config{
+		GCMinimumAge: 5 * time.Minute,
+	}
+	if dashv {
+		c.Logf = logf
+	}
+	for _, tab := range tables {
+		match, err := path.Match(tblpat, tab)
+		if err != nil {
+			exitf("bad pattern %q: %s", tblpat, err)
+		}
+		if !match {
+			continue
+		}
+		def, err := db.OpenDefinition(ofs, dbname, tab)
+		if err != nil {
+			exitf("opening definition for %s/%s: %s", dbname, tab, err)
+		}
+		if def.Retention == nil {
+			continue
+		}
+		purged, err := c.Purge(creds, dbname, tab, dryrun)
+		if err != nil {
+			exitf("purging %s/%s: %s", dbname, tab, err)
+		}
+		for i := range purged {
+			fmt.Println(purged[i].Path)
+		}
+	}
+}
+
+func init() {
+	addApplet(applet{
+		name: "purge",
+		help: "[-n] <db> <table-pattern?>",
+		desc: `apply table retention policies
+The command
+  $ sdb purge <db> <table-pattern>
+drops the objects containing only expired data
+from each of the tables that match <table-pattern>
+and have a retention_policy in their definition,
+and prints the path of each object that was dropped.
+Dropped objects are deleted by a subsequent purge,
+sync, or compaction once they are at least 5 minutes old.
+
+With -n, the objects that would be dropped are
+listed, but the tables are not modified.
+`,
+		run: func(args []string) bool {
+			purge(args)
+			return true
+		},
+	})
+}
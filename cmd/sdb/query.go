@@ -38,8 +38,6 @@ import (
 	"github.com/SnellerInc/sneller/plan"
 	"github.com/SnellerInc/sneller/tenant/dcache"
 	"github.com/SnellerInc/sneller/vm"
-
-	"golang.org/x/sys/cpu"
 )
 
 // plan.TableHandle implementation for a local file
@@ -267,8 +265,8 @@ func query(args []string) bool {
 		vm.Trace(w, gv)
 	}
 
-	if !cpu.X86.HasAVX512 {
-		exitf("cannot execute query without AVX512 support")
+	if err := vm.Supported(); err != nil {
+		exitf("cannot execute query: %s", err)
 	}
 
 	var stats plan.ExecStats
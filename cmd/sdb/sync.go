@@ -23,10 +23,12 @@ import (
 )
 
 func sync(args []string) {
-	var force bool
+	var force, dashk, dashe bool
 	var dashm int64
 	flags := flag.NewFlagSet(args[0], flag.ExitOnError)
 	flags.BoolVar(&force, "f", false, "force rebuild")
+	flags.BoolVar(&dashk, "k", false, "record block checksums in new packfiles")
+	flags.BoolVar(&dashe, "e", false, "encrypt new packfiles with a per-table data key")
 	flags.Int64Var(&dashm, "m", 100*giga, "maximum input bytes read per index update")
 	flags.Parse(args[1:])
 	args = flags.Args()
@@ -45,6 +47,8 @@ func sync(args []string) {
 			Force:         force,
 			MaxScanBytes:  dashm,
 			GCMinimumAge:  5 * time.Minute,
+			Checksums:     dashk,
+			Encrypt:       dashe,
 		}
 		if dashv {
 			c.Logf = logf
@@ -62,7 +66,7 @@ func sync(args []string) {
 func init() {
 	addApplet(applet{
 		name: "sync",
-		help: "[-f] [-m max-scan-bytes] <db> <table-pattern?>",
+		help: "[-f] [-k] [-e] [-m max-scan-bytes] <db> <table-pattern?>",
 		desc: `sync a table index based on an existing def
 the command
   $ sdb sync <db> <pattern>
@@ -39,7 +39,6 @@ import (
 	"github.com/SnellerInc/sneller/plan"
 	"github.com/SnellerInc/sneller/tenant/dcache"
 	"github.com/SnellerInc/sneller/vm"
-	"golang.org/x/sys/cpu"
 )
 
 var (
@@ -355,8 +354,8 @@ func do(arg string) {
 		return
 	}
 
-	if !cpu.X86.HasAVX512 {
-		exitf("CPU doesn't support AVX-512")
+	if err := vm.Supported(); err != nil {
+		exit(err)
 	}
 
 	if dashbc {
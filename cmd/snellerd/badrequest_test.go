@@ -25,11 +25,13 @@ import (
 	"net/url"
 	"os"
 	"regexp"
+	"strings"
 	"sync"
 	"testing"
 	"time"
 
 	"github.com/SnellerInc/sneller/db"
+	"github.com/SnellerInc/sneller/expr"
 )
 
 // tsbuf is a threadsafe buffer;
@@ -115,6 +117,18 @@ func (r *requester) getInputs(db, table string) *http.Request {
 	return req
 }
 
+func (r *requester) getRepro(id string) *http.Request {
+	req := r.get("/repro/" + id)
+	req.Header.Set("Authorization", "Bearer snellerd-test")
+	return req
+}
+
+func (r *requester) getMetadata(path string, query url.Values) *http.Request {
+	req := r.get("/metadata/" + path + "?" + query.Encode())
+	req.Header.Set("Authorization", "Bearer snellerd-test")
+	return req
+}
+
 type testAuth struct {
 	self db.Tenant
 }
@@ -145,6 +159,7 @@ func empty(t *testing.T) *server {
 func TestBadRequest(t *testing.T) {
 	testFiles(t)
 	s := empty(t)
+	s.limits = expr.Limits{MaxTextSize: 200, MaxSubqueries: 1}
 
 	httpsock := listen(t)
 	go s.Serve(httpsock, nil)
@@ -184,4 +199,87 @@ func TestBadRequest(t *testing.T) {
 		}
 		t.Logf("error text: %q", bodytext)
 	}
+
+	// queries that exceed the limits
+	limited := []struct {
+		text, code string
+		status     int
+	}{
+		{"SELECT * FROM parking WHERE " + strings.Repeat("x = 1 OR ", 30) + "x = 2", "query_too_large", http.StatusRequestEntityTooLarge},
+		{"SELECT * FROM (SELECT * FROM (SELECT * FROM parking))", "too_many_subqueries", http.StatusBadRequest},
+	}
+	for i := range limited {
+		res, err := cl.Do(rqe.getQuery("default", limited[i].text))
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Body.Close()
+		if res.StatusCode != limited[i].status {
+			t.Errorf("%s: got status code %d", limited[i].code, res.StatusCode)
+		}
+		if got := res.Header.Get("X-Sneller-Error-Code"); got != limited[i].code {
+			t.Errorf("got error code %q, want %q", got, limited[i].code)
+		}
+	}
+}
+
+func TestScanLimit(t *testing.T) {
+	testFiles(t)
+	s := empty(t)
+
+	httpsock := listen(t)
+	go s.Serve(httpsock, nil)
+
+	rqe := &requester{
+		t:    t,
+		host: "http://" + httpsock.Addr().String(),
+	}
+	// COUNT(*) is answered from the per-block row counts
+	// in the index without scanning any data, so it is never
+	// subject to a scan limit (see TestCountStarIndex);
+	// the limit tests use COUNT(Make) on purpose so that
+	// the query has to scan the table
+	const (
+		countStar = "SELECT COUNT(*) FROM parking"
+		scanning  = "SELECT COUNT(Make) FROM parking"
+	)
+	tcs := []struct {
+		query  string
+		server uint64 // server-wide limit
+		param  string // max_scan_bytes
+		status int
+		match  string
+	}{
+		{query: scanning, param: "1", status: http.StatusBadRequest, match: "scan limit exceeded"},
+		{query: countStar, param: "-1", status: http.StatusBadRequest, match: "invalid 'max_scan_bytes'"},
+		{query: countStar, param: "foo", status: http.StatusBadRequest, match: "invalid 'max_scan_bytes'"},
+		// a query cannot raise the limit
+		{query: scanning, server: 1, param: "1000000000000", status: http.StatusBadRequest, match: "scan limit exceeded"},
+		{query: scanning, server: 1, status: http.StatusBadRequest, match: "scan limit exceeded"},
+	}
+	for i := range tcs {
+		s.maxScan = tcs[i].server
+		r := rqe.getQuery("default", tcs[i].query)
+		if tcs[i].param != "" {
+			q := r.URL.Query()
+			q.Set("max_scan_bytes", tcs[i].param)
+			r.URL.RawQuery = q.Encode()
+		}
+		res, err := http.DefaultClient.Do(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, err := io.ReadAll(res.Body)
+		res.Body.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.StatusCode != tcs[i].status {
+			t.Errorf("case %d: got status code %d (%s)", i, res.StatusCode, body)
+			continue
+		}
+		if tcs[i].match != "" && !strings.Contains(string(body), tcs[i].match) {
+			t.Errorf("case %d: body %q does not contain %q", i, body, tcs[i].match)
+		}
+	}
 }
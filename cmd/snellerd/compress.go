@@ -0,0 +1,97 @@
+// Copyright (C) 2022 Sneller, Inc.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/SnellerInc/sneller/tenant/tnproto"
+)
+
+// parseCompression parses a comma-separated
+// list of content-codings (in order of preference)
+// that the server may use to compress query results
+func parseCompression(list string) ([]tnproto.Compression, error) {
+	var out []tnproto.Compression
+	for _, name := range strings.Split(list, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		c, ok := tnproto.ParseCompression(name)
+		if !ok {
+			return nil, fmt.Errorf("unsupported compression %q", name)
+		}
+		if c != tnproto.CompressNone {
+			out = append(out, c)
+		}
+	}
+	return out, nil
+}
+
+// negotiate picks the first of the server's
+// supported compression algorithms that is
+// acceptable according to the Accept-Encoding
+// header values in accept
+func (s *server) negotiate(accept []string) tnproto.Encoding {
+	if len(s.compression) == 0 || len(accept) == 0 {
+		return tnproto.Encoding{}
+	}
+	ok := make(map[tnproto.Compression]bool)
+	wildcard := false
+	for _, hdr := range accept {
+		for _, coding := range strings.Split(hdr, ",") {
+			name, params, _ := strings.Cut(coding, ";")
+			name = strings.ToLower(strings.TrimSpace(name))
+			q := 1.0
+			for _, p := range strings.Split(params, ";") {
+				k, v, _ := strings.Cut(strings.TrimSpace(p), "=")
+				if k == "q" {
+					q, _ = strconv.ParseFloat(v, 64)
+				}
+			}
+			if name == "*" {
+				wildcard = q > 0
+				continue
+			}
+			if c, known := tnproto.ParseCompression(name); known {
+				ok[c] = q > 0
+			}
+		}
+	}
+	for _, c := range s.compression {
+		if accepted, listed := ok[c]; accepted || (!listed && wildcard) {
+			return tnproto.Encoding{Compression: c, Level: s.compressLevel}
+		}
+	}
+	return tnproto.Encoding{}
+}
+
+// writeEncoded calls fn with a writer that
+// compresses its output into w according to enc;
+// the compressed data forms a separate stream
+// that is appended to the stream produced by
+// the tenant
+func writeEncoded(w io.Writer, enc tnproto.Encoding, fn func(w io.Writer)) {
+	cw, err := enc.NewWriter(w)
+	if err != nil {
+		return
+	}
+	fn(cw)
+	cw.Close()
+}
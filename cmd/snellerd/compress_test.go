@@ -0,0 +1,59 @@
+// Copyright (C) 2022 Sneller, Inc.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"testing"
+
+	"github.com/SnellerInc/sneller/tenant/tnproto"
+)
+
+func TestNegotiate(t *testing.T) {
+	lst, err := parseCompression("zstd, gzip")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &server{compression: lst, compressLevel: 3}
+	testcases := []struct {
+		accept []string
+		want   tnproto.Compression
+	}{
+		{nil, tnproto.CompressNone},
+		{[]string{"identity"}, tnproto.CompressNone},
+		{[]string{"gzip"}, tnproto.CompressGzip},
+		{[]string{"gzip, deflate, br"}, tnproto.CompressGzip},
+		{[]string{"gzip", "zstd"}, tnproto.CompressZstd},
+		{[]string{"GZIP;q=0.5, zstd;q=0"}, tnproto.CompressGzip},
+		{[]string{"*"}, tnproto.CompressZstd},
+		{[]string{"zstd;q=0, *"}, tnproto.CompressGzip},
+		{[]string{"*;q=0"}, tnproto.CompressNone},
+	}
+	for i := range testcases {
+		got := s.negotiate(testcases[i].accept)
+		if got.Compression != testcases[i].want {
+			t.Errorf("%q: got %s, want %s", testcases[i].accept, got.Compression, testcases[i].want)
+		}
+		if got.Compression != tnproto.CompressNone && got.Level != 3 {
+			t.Errorf("%q: got level %d", testcases[i].accept, got.Level)
+		}
+	}
+	s.compression = nil
+	if got := s.negotiate([]string{"zstd"}); got.Compression != tnproto.CompressNone {
+		t.Errorf("compression disabled, but negotiated %s", got.Compression)
+	}
+	if _, err := parseCompression("zstd,br"); err == nil {
+		t.Error("expected an error for an unsupported content-coding")
+	}
+}
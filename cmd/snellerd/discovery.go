@@ -0,0 +1,180 @@
+// Copyright (C) 2022 Sneller, Inc.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// discovery is a source of peer addresses
+// that is polled by a peerWatcher
+type discovery interface {
+	// Peers returns the current list of
+	// peer addresses as host:port strings
+	Peers(ctx context.Context) ([]string, error)
+}
+
+// parseDiscovery parses a peer discovery
+// specification of the form
+//
+//	exec:<cmdline>    run cmdline and parse its JSON output
+//	srv:<name>        look up the DNS SRV records for name
+//	dns:<host>:<port> look up the addresses of host
+//
+// The dns: form is appropriate for a Kubernetes
+// headless service, which resolves to the
+// addresses of its ready endpoints.
+func parseDiscovery(spec string) (discovery, error) {
+	kind, arg, ok := strings.Cut(spec, ":")
+	if !ok || arg == "" {
+		return nil, fmt.Errorf("invalid peer discovery %q", spec)
+	}
+	switch kind {
+	case "exec":
+		cmd := strings.Fields(arg)
+		if len(cmd) == 0 {
+			return nil, fmt.Errorf("invalid peer discovery %q: empty command", spec)
+		}
+		return &cmdDiscovery{cmd: cmd}, nil
+	case "srv":
+		return &srvDiscovery{name: arg}, nil
+	case "dns":
+		host, port, err := net.SplitHostPort(arg)
+		if err != nil {
+			return nil, fmt.Errorf("invalid peer discovery %q: %w", spec, err)
+		}
+		if _, err := strconv.Atoi(port); err != nil {
+			return nil, fmt.Errorf("invalid peer discovery %q: bad port %q", spec, port)
+		}
+		return &dnsDiscovery{host: host, port: port}, nil
+	default:
+		return nil, fmt.Errorf("unknown peer discovery method %q", kind)
+	}
+}
+
+// cmdDiscovery runs a command that prints
+// the list of peers as JSON; see peerJSON
+type cmdDiscovery struct {
+	cmd []string
+}
+
+type peerDesc struct {
+	Addr string `json:"addr"`
+}
+
+type peerJSON struct {
+	Peers []peerDesc `json:"peers"`
+}
+
+func (c *cmdDiscovery) Peers(ctx context.Context) ([]string, error) {
+	cmd := exec.CommandContext(ctx, c.cmd[0], c.cmd[1:]...)
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	err := cmd.Run()
+	if err != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return nil, fmt.Errorf("peer command timed-out (killed): %s", stderr.String())
+		}
+
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return nil, fmt.Errorf("cmdline %v exited with code %d: %s", c.cmd, exitErr.ProcessState.ExitCode(), stderr.String())
+		}
+
+		return nil, fmt.Errorf("failed running command %q: %s", c.cmd[0], err)
+	}
+
+	var ret peerJSON
+	err = json.Unmarshal(stdout.Bytes(), &ret)
+	if err != nil {
+		return nil, err
+	}
+	lst := make([]string, len(ret.Peers))
+	for i := range ret.Peers {
+		lst[i] = ret.Peers[i].Addr
+	}
+	return lst, nil
+}
+
+// srvDiscovery finds peers using the
+// DNS SRV records for name
+type srvDiscovery struct {
+	name string
+	// for testing; defaults to net.DefaultResolver
+	lookupSRV  func(ctx context.Context, service, proto, name string) (string, []*net.SRV, error)
+	lookupHost func(ctx context.Context, host string) ([]string, error)
+}
+
+func (s *srvDiscovery) Peers(ctx context.Context) ([]string, error) {
+	lookupSRV, lookupHost := s.lookupSRV, s.lookupHost
+	if lookupSRV == nil {
+		lookupSRV = net.DefaultResolver.LookupSRV
+	}
+	if lookupHost == nil {
+		lookupHost = net.DefaultResolver.LookupHost
+	}
+	_, srvs, err := lookupSRV(ctx, "", "", s.name)
+	if err != nil {
+		return nil, err
+	}
+	var lst []string
+	for _, srv := range srvs {
+		port := strconv.Itoa(int(srv.Port))
+		addrs, err := lookupHost(ctx, strings.TrimSuffix(srv.Target, "."))
+		if err != nil {
+			return nil, fmt.Errorf("resolving SRV target %s: %w", srv.Target, err)
+		}
+		for i := range addrs {
+			lst = append(lst, net.JoinHostPort(addrs[i], port))
+		}
+	}
+	return lst, nil
+}
+
+// dnsDiscovery finds peers by resolving
+// host to a list of addresses that all
+// listen on the same port
+type dnsDiscovery struct {
+	host, port string
+	// for testing; defaults to net.DefaultResolver
+	lookupHost func(ctx context.Context, host string) ([]string, error)
+}
+
+func (d *dnsDiscovery) Peers(ctx context.Context) ([]string, error) {
+	lookupHost := d.lookupHost
+	if lookupHost == nil {
+		lookupHost = net.DefaultResolver.LookupHost
+	}
+	addrs, err := lookupHost(ctx, d.host)
+	if err != nil {
+		return nil, err
+	}
+	lst := make([]string, len(addrs))
+	for i := range addrs {
+		lst[i] = net.JoinHostPort(addrs[i], d.port)
+	}
+	return lst, nil
+}
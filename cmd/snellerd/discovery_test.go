@@ -0,0 +1,136 @@
+// Copyright (C) 2022 Sneller, Inc.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"reflect"
+	"testing"
+)
+
+func TestParseDiscovery(t *testing.T) {
+	good := []struct {
+		spec string
+		want discovery
+	}{
+		{"exec:cat peers.json", &cmdDiscovery{cmd: []string{"cat", "peers.json"}}},
+		{"srv:_sneller._tcp.example.com", &srvDiscovery{name: "_sneller._tcp.example.com"}},
+		{"dns:sneller.default.svc.cluster.local:9000", &dnsDiscovery{host: "sneller.default.svc.cluster.local", port: "9000"}},
+	}
+	for i := range good {
+		got, err := parseDiscovery(good[i].spec)
+		if err != nil {
+			t.Errorf("%s: %s", good[i].spec, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, good[i].want) {
+			t.Errorf("%s: got %#v", good[i].spec, got)
+		}
+	}
+	for _, spec := range []string{
+		"",
+		"exec:",
+		"exec: ",
+		"srv",
+		"dns:example.com",
+		"dns:example.com:http",
+		"gossip:foo",
+	} {
+		if _, err := parseDiscovery(spec); err == nil {
+			t.Errorf("%q: expected an error", spec)
+		}
+	}
+}
+
+func TestDNSDiscovery(t *testing.T) {
+	hosts := map[string][]string{
+		"a.example.com":   {"10.0.0.1"},
+		"b.example.com":   {"10.0.0.2", "fd00::2"},
+		"svc.example.com": {"10.0.0.3", "10.0.0.4"},
+	}
+	lookupHost := func(ctx context.Context, host string) ([]string, error) {
+		if lst, ok := hosts[host]; ok {
+			return lst, nil
+		}
+		return nil, fmt.Errorf("no such host %s", host)
+	}
+	srv := &srvDiscovery{
+		name: "_sneller._tcp.example.com",
+		lookupSRV: func(ctx context.Context, service, proto, name string) (string, []*net.SRV, error) {
+			return "", []*net.SRV{
+				{Target: "a.example.com.", Port: 9000},
+				{Target: "b.example.com.", Port: 9001},
+			}, nil
+		},
+		lookupHost: lookupHost,
+	}
+	got, err := srv.Peers(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"10.0.0.1:9000", "10.0.0.2:9001", "[fd00::2]:9001"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("srv: got %v, want %v", got, want)
+	}
+
+	dns := &dnsDiscovery{host: "svc.example.com", port: "9000", lookupHost: lookupHost}
+	got, err = dns.Peers(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = []string{"10.0.0.3:9000", "10.0.0.4:9000"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("dns: got %v, want %v", got, want)
+	}
+	dns.host = "missing.example.com"
+	if _, err := dns.Peers(context.Background()); err == nil {
+		t.Error("expected an error")
+	}
+}
+
+func TestPeerWatcherChanges(t *testing.T) {
+	addr := func(s string) *net.TCPAddr {
+		a, err := net.ResolveTCPAddr("tcp", s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return a
+	}
+	a, b, c := addr("10.0.0.1:9000"), addr("10.0.0.2:9000"), addr("10.0.0.3:9000")
+	var p peerWatcher
+	if p.Get() != nil {
+		t.Fatal("expected no peers before the first update")
+	}
+	var changes []peerChange
+	p.Watch(func(c peerChange) { changes = append(changes, c) })
+
+	p.update([]*net.TCPAddr{a, b})
+	p.update([]*net.TCPAddr{b, a}) // no change
+	p.update([]*net.TCPAddr{b, c})
+	p.update(nil)
+	want := []peerChange{
+		{Added: []*net.TCPAddr{a, b}},
+		{Added: []*net.TCPAddr{c}, Removed: []*net.TCPAddr{a}},
+		{Removed: []*net.TCPAddr{b, c}},
+	}
+	if !reflect.DeepEqual(changes, want) {
+		t.Errorf("got changes %v", changes)
+	}
+	if len(p.Get()) != 0 {
+		t.Errorf("got peers %v", p.Get())
+	}
+}
@@ -23,6 +23,7 @@ import (
 	"io"
 	"net"
 	"net/http"
+	"net/url"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -34,11 +35,18 @@ import (
 	"sync"
 	"testing"
 
+	"github.com/SnellerInc/sneller"
 	"github.com/SnellerInc/sneller/db"
+	"github.com/SnellerInc/sneller/expr"
+	"github.com/SnellerInc/sneller/expr/lint"
 	"github.com/SnellerInc/sneller/ion"
 	"github.com/SnellerInc/sneller/ion/blockfmt"
+	"github.com/SnellerInc/sneller/plan"
+	"github.com/SnellerInc/sneller/repro"
 	"github.com/SnellerInc/sneller/tenant"
+	"github.com/SnellerInc/sneller/tenant/tnproto"
 
+	"github.com/klauspost/compress/zstd"
 	"golang.org/x/exp/slices"
 )
 
@@ -209,6 +217,9 @@ func TestQueryError(t *testing.T) {
 			&net.TCPAddr{IP: net.ParseIP("127.0.0.1"), Port: 54423},
 		),
 		auth: testAuth{tt},
+		// the error from the peer should
+		// survive the compressed connection
+		peerCompression: true,
 	}
 	httpsock := listen(t)
 	// this second peer is just here
@@ -296,6 +307,47 @@ func TestQueryError(t *testing.T) {
 		t.Errorf("unexpected error message %q", msg)
 	}
 	t.Logf("error message: %s", msg)
+
+	// the failed query should have a repro bundle
+	id := res.Header.Get("X-Sneller-Query-ID")
+	res2, err := http.DefaultClient.Do(rq.getRepro(id))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res2.Body.Close()
+	if res2.StatusCode != http.StatusOK {
+		t.Fatalf("fetching repro bundle: status code %d", res2.StatusCode)
+	}
+	b, err := repro.Read(res2.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(b.Error, "subreplacement exceeds limit") {
+		t.Errorf("unexpected bundle error %q", b.Error)
+	}
+	if b.Plan == "" || b.Versions["snellerd"] != version {
+		t.Errorf("missing plan or versions in bundle: %+v", b)
+	}
+	var names []string
+	for i := range b.Inputs {
+		names = append(names, b.Inputs[i].Name)
+	}
+	// the tables in the query are followed by the
+	// (appended) table in the plan, which has a descriptor
+	if !slices.Equal(names, []string{"default.taxi", "default.parking2", "default.parking",
+		"(default.taxi ++ default.parking2 ++ default.parking)"}) ||
+		b.Inputs[3].Descriptor == nil {
+		t.Errorf("unexpected bundle inputs %v", names)
+	}
+	// ... but not for other queries
+	res3, err := http.DefaultClient.Do(rq.getRepro("no-such-query"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	res3.Body.Close()
+	if res3.StatusCode != http.StatusNotFound {
+		t.Errorf("unexpected status code %d", res3.StatusCode)
+	}
 }
 
 // test the server running on a tmpfs that
@@ -319,6 +371,14 @@ func TestSimpleFS(t *testing.T) {
 			&net.TCPAddr{IP: net.ParseIP("127.0.0.1"), Port: 54423},
 		),
 		auth: testAuth{tt},
+		// large enough not to reject any
+		// of the queries below, but still
+		// enforced (and encoded) at runtime
+		maxScan: 1 << 40,
+		// net/http requests (and transparently
+		// decompresses) gzip, so the queries below
+		// exercise compressed results
+		compression: []tnproto.Compression{tnproto.CompressZstd, tnproto.CompressGzip},
 	}
 	httpsock := listen(t)
 	// this second peer is just here
@@ -427,6 +487,61 @@ func TestSimpleFS(t *testing.T) {
 		}
 	}
 
+	{
+		// test the driver metadata endpoints
+		req := rq.getMetadata("columns", url.Values{
+			"schema": {"default"},
+			"table":  {"parking"},
+			"column": {"%e"},
+		})
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.StatusCode != http.StatusOK {
+			t.Fatalf("get /metadata/columns: %s", res.Status)
+		}
+		var cols []metadataColumn
+		err = json.NewDecoder(res.Body).Decode(&cols)
+		res.Body.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := make([]string, len(cols))
+		for i := range cols {
+			got[i] = fmt.Sprintf("%s %s %v", cols[i].Column, cols[i].TypeName, cols[i].Nullable)
+		}
+		want := []string{
+			"BodyStyle VARCHAR true",
+			"Fine BIGINT true",
+			"IssueTime BIGINT true",
+			"Latitude BIGINT false",
+			"Longitude BIGINT false",
+			"Make VARCHAR true",
+			"MarkedTime VARCHAR true",
+			"RPState VARCHAR false",
+			"Route VARCHAR true",
+			"ViolationCode VARCHAR false",
+		}
+		if !slices.Equal(got, want) {
+			t.Fatalf("got columns %q", got)
+		}
+		req = rq.getMetadata("tables", url.Values{"schema": {"def%"}, "table": {"park%"}})
+		res, err = http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var tables []metadataTable
+		err = json.NewDecoder(res.Body).Decode(&tables)
+		res.Body.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(tables) != 2 || tables[0].Table != "parking" || tables[0].Schema != "default" || tables[0].Type != "TABLE" {
+			t.Fatalf("got tables %v", tables)
+		}
+	}
+
 	checkTiming := func(t *testing.T, res *http.Response) {
 		t.Helper()
 		timings := res.Trailer.Get("Server-Timing")
@@ -483,10 +598,11 @@ func TestSimpleFS(t *testing.T) {
 
 	queries := []struct {
 		input, db string
-		output    string // exact output, or regular expression
-		partial   bool   // expect only a partial scan
-		rx        bool   // use regular expression
-		status    int    // if non-zero, expected HTTP status code
+		output    string   // exact output, or regular expression
+		partial   bool     // expect only a partial scan
+		rx        bool     // use regular expression
+		status    int      // if non-zero, expected HTTP status code
+		warnings  []string // lint rules expected in X-Sneller-Warning
 	}{
 		// get coverage of both empty db and default db
 		{input: "SELECT COUNT(*) FROM default.parking", output: `{"count": 1023}`},
@@ -515,6 +631,8 @@ func TestSimpleFS(t *testing.T) {
 		// these two should be satisfied w/o scanning
 		{input: "SELECT EARLIEST(tpep_pickup_datetime) FROM default.taxi", output: `{"min": "2009-01-01T00:35:23Z"}`, partial: true},
 		{input: "SELECT LATEST(tpep_pickup_datetime) FROM default.taxi", output: `{"max": "2009-01-31T23:55:00Z"}`, partial: true},
+		// this one too, but it also reports how many blocks the filter selects
+		{input: "SELECT TIME_RANGE(tpep_pickup_datetime) AS r FROM default.taxi WHERE tpep_pickup_datetime >= `2009-01-15T00:00:00Z`", output: `{"r": {"min": "2009-01-01T00:35:23Z", "max": "2009-01-31T23:55:00Z", "blocks": 15, "total_blocks": 25}}`, partial: true},
 
 		{input: "SELECT COUNT(*) FROM default.taxi WHERE tpep_pickup_datetime < `2009-01-01T00:35:23Z`", output: `{"count": 0}`, partial: true},
 		// about half of the entries satisfy this:
@@ -534,6 +652,13 @@ func TestSimpleFS(t *testing.T) {
 		// don't care much about the result here; this just
 		// exercises the vm scratch save+restore code
 		{input: "SELECT COUNT(*), tm FROM default.taxi GROUP BY DATE_TRUNC(DAY, tpep_pickup_datetime) AS tm", output: ".*", rx: true},
+		// filters on functions of timestamps produce a warning
+		{
+			input:    "SELECT COUNT(*) FROM default.taxi WHERE DATE_TRUNC(DAY, tpep_pickup_datetime) = `2009-01-15T00:00:00Z`",
+			output:   ".*",
+			rx:       true,
+			warnings: []string{lint.NonSargableTime},
+		},
 		{
 			// get coverage of the same table
 			// being referenced more than once
@@ -579,6 +704,19 @@ SELECT ROUND(SUM(total_amount)) AS "sum" FROM default.taxi WHERE VendorID = (SEL
 					// don't perform any more checks, query failed
 					return
 				}
+				warnings := res.Header.Values("X-Sneller-Warning")
+				if len(warnings) != len(q.warnings) {
+					t.Errorf("got warnings %q", warnings)
+				} else {
+					for i := range warnings {
+						if !strings.HasPrefix(warnings[i], q.warnings[i]+": ") {
+							t.Errorf("got warning %q; wanted rule %s", warnings[i], q.warnings[i])
+						}
+					}
+				}
+				if !res.Uncompressed {
+					t.Error("expected a gzip-compressed response")
+				}
 
 				var buf, body bytes.Buffer
 				_, err = ion.ToJSON(&buf, bufio.NewReader(io.TeeReader(res.Body, &body)))
@@ -624,6 +762,19 @@ SELECT ROUND(SUM(total_amount)) AS "sum" FROM default.taxi WHERE VendorID = (SEL
 			query:  `SELECT Ticket FROM default.parking WHERE Route = '2A75' AND IssueTime <= 1100`,
 			result: `[{"Ticket": 1106506402},{"Ticket": 1106506413},{"Ticket": 1106506424}]`,
 		},
+		// system tables
+		{
+			query:  `SELECT COUNT(*) FROM sneller_tables WHERE "database" = 'default' AND "table" = 'parking'`,
+			result: `[{"count": 1}]`,
+		},
+		{
+			query:  `SELECT types FROM sneller_columns WHERE "table" = 'parking' AND "column" = 'Ticket'`,
+			result: `[{"types": ["int"]}]`,
+		},
+		{
+			query:  `SELECT COUNT(*) FROM sneller_queries WHERE query LIKE '%sneller_queries%'`,
+			result: `[{"count": 1}]`,
+		},
 	}
 	for i := range jsqueries {
 		r := rq.getQueryJSON("", jsqueries[i].query)
@@ -644,4 +795,365 @@ SELECT ROUND(SUM(total_amount)) AS "sum" FROM default.taxi WHERE VendorID = (SEL
 		}
 		checkTiming(t, res)
 	}
+
+	// get coverage of ANSI NULL semantics
+	{
+		r := rq.getQueryJSON("", `SELECT Location, NoSuchField FROM default.parking WHERE Route = '2A75' AND IssueTime = 945`)
+		r.URL.RawQuery += "&ansi_nulls"
+		res, err := http.DefaultClient.Do(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.StatusCode != http.StatusOK {
+			t.Fatalf("status %s", res.Status)
+		}
+		got, err := io.ReadAll(res.Body)
+		res.Body.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := `[{"Location": "721 S WESTLAKE", "NoSuchField": null}]`
+		if string(got) != want {
+			t.Errorf("got %q, want %q", got, want)
+		}
+	}
+
+	// get coverage of the Trino dialect
+	{
+		r := rq.getQueryJSON("", `SELECT count_if(IssueTime = 945) AS n FROM default.parking WHERE Route = '2A75'`)
+		r.URL.RawQuery += "&dialect=trino"
+		res, err := http.DefaultClient.Do(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.StatusCode != http.StatusOK {
+			t.Fatalf("status %s", res.Status)
+		}
+		got, err := io.ReadAll(res.Body)
+		res.Body.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := `[{"n": 1}]`
+		if string(got) != want {
+			t.Errorf("got %q, want %q", got, want)
+		}
+	}
+
+	// get coverage of the per-query locale
+	{
+		r := rq.getQueryJSON("", `SELECT FORMAT(IssueTime * 100, 1) AS f FROM default.parking WHERE Route = '2A75' AND IssueTime = 945`)
+		r.URL.RawQuery += "&locale=de_DE"
+		res, err := http.DefaultClient.Do(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.StatusCode != http.StatusOK {
+			t.Fatalf("status %s", res.Status)
+		}
+		got, err := io.ReadAll(res.Body)
+		res.Body.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := `[{"f": "94.500,0"}]`
+		if string(got) != want {
+			t.Errorf("got %q, want %q", got, want)
+		}
+
+		r = rq.getQueryJSON("", `SELECT FORMAT(IssueTime, 1) FROM default.parking`)
+		r.URL.RawQuery += "&locale=xx"
+		res, err = http.DefaultClient.Do(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Body.Close()
+		if res.StatusCode != http.StatusBadRequest {
+			t.Errorf("unknown locale: status %s", res.Status)
+		}
+	}
+
+	// get coverage of the JSON formatting options
+	{
+		r := rq.getQueryJSON("", `SELECT IssueData FROM default.parking ORDER BY Ticket LIMIT 1`)
+		r.URL.RawQuery += "&json_timestamps=epoch_ms&json_nonfinite=null"
+		res, err := http.DefaultClient.Do(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(res.Body)
+		res.Body.Close()
+		if res.StatusCode != http.StatusOK {
+			t.Fatalf("status %s: %s", res.Status, body)
+		}
+		if !regexp.MustCompile(`^\[\{"IssueData": \d+\}\]$`).Match(body) {
+			t.Errorf("got %q", body)
+		}
+
+		r = rq.getQueryJSON("", `SELECT COUNT(*) FROM default.parking`)
+		r.URL.RawQuery += "&json_numbers=bogus"
+		res, err = http.DefaultClient.Do(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Body.Close()
+		if res.StatusCode != http.StatusBadRequest {
+			t.Errorf("invalid json_numbers: status %s", res.Status)
+		}
+	}
+
+	// COUNT(*) is answered from the index without
+	// scanning anything; COUNT(Ticket) is kept on purpose
+	// so that the same paths are exercised with a query
+	// that actually has to scan the table
+	countQueries := []struct {
+		query   string
+		maxscan int64
+	}{
+		{"SELECT COUNT(*) FROM default.parking", 0},
+		{"SELECT COUNT(Ticket) FROM default.parking", 1 << 40},
+	}
+
+	// get coverage of explicitly-requested zstd
+	for _, cq := range countQueries {
+		for _, accept := range []string{"application/ion", "application/json"} {
+			r := rq.getQuery("", cq.query)
+			r.Header.Set("Accept", accept)
+			r.Header.Set("Accept-Encoding", "zstd")
+			res, err := http.DefaultClient.Do(r)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if res.StatusCode != http.StatusOK {
+				t.Fatalf("status %s", res.Status)
+			}
+			if ce := res.Header.Get("Content-Encoding"); ce != "zstd" {
+				t.Fatalf("Content-Encoding: %q", ce)
+			}
+			zr, err := zstd.NewReader(res.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			body, err := io.ReadAll(zr)
+			zr.Close()
+			res.Body.Close()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if accept == "application/json" {
+				if string(body) != `[{"count": 1023}]` {
+					t.Errorf("got %q", body)
+				}
+				continue
+			}
+			var buf bytes.Buffer
+			_, err = ion.ToJSON(&buf, bufio.NewReader(bytes.NewReader(body)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := strings.TrimSpace(buf.String()); got != `{"count": 1023}` {
+				t.Errorf("got %s", got)
+			}
+			checkAnnotation(t, body, cq.maxscan)
+		}
+	}
+
+	// get coverage of the query_stats trailer
+	{
+		r := rq.getQuery("", `SELECT Ticket FROM default.parking WHERE Route = '2A75' AND IssueTime <= 1100`)
+		r.URL.RawQuery += "&stats"
+		res, err := http.DefaultClient.Do(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.StatusCode != http.StatusOK {
+			t.Fatalf("status %s", res.Status)
+		}
+		body, err := io.ReadAll(res.Body)
+		res.Body.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkAnnotation(t, body, 1<<40)
+		var d, qstats ion.Datum
+		dec := ion.NewDecoder(bytes.NewReader(body), 64*1024)
+		dec.ExtraAnnotations = map[string]any{
+			"query_stats": &qstats,
+		}
+		for {
+			err := dec.Decode(&d)
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		if qstats.IsEmpty() {
+			t.Fatal("missing query_stats trailer")
+		}
+		if rows, _ := qstats.Field("rows").Int(); rows != 3 {
+			t.Errorf("rows = %d, want 3", rows)
+		}
+		if scanned, _ := qstats.Field("scanned").Int(); scanned == 0 {
+			t.Error("scanned = 0")
+		}
+		if _, err := qstats.Field("blocks_skipped").Int(); err != nil {
+			t.Errorf("blocks_skipped: %s", err)
+		}
+		if _, err := qstats.Field("elapsed_ms").Float(); err != nil {
+			t.Errorf("elapsed_ms: %s", err)
+		}
+	}
+
+	// get coverage of /streamQuery
+	for _, cq := range countQueries {
+		for _, accept := range []string{"application/ion", "application/x-ndjson", "application/json"} {
+			r := rq.getQuery("", cq.query)
+			r.URL.Path = "/streamQuery"
+			r.Header.Set("Accept", accept)
+			res, err := http.DefaultClient.Do(r)
+			if err != nil {
+				t.Fatal(err)
+			}
+			body, err := io.ReadAll(res.Body)
+			res.Body.Close()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if accept == "application/json" {
+				// JSON arrays can't be streamed
+				if res.StatusCode != http.StatusBadRequest {
+					t.Fatalf("stream %s: status %s", accept, res.Status)
+				}
+				continue
+			}
+			if res.StatusCode != http.StatusOK {
+				t.Fatalf("stream %s: status %s", accept, res.Status)
+			}
+			if accept == "application/ion" {
+				checkAnnotation(t, body, cq.maxscan)
+				continue
+			}
+			lines := strings.Split(strings.TrimSpace(string(body)), "\n")
+			if len(lines) != 2 || lines[0] != `{"count": 1023}` {
+				t.Fatalf("got %q", body)
+			}
+			var final struct {
+				Status struct {
+					Scanned int64  `json:"scanned"`
+					Error   string `json:"error"`
+				} `json:"$ion_annotation$final_status"`
+			}
+			if err := json.Unmarshal([]byte(lines[1]), &final); err != nil {
+				t.Fatalf("final status %q: %s", lines[1], err)
+			}
+			if final.Status.Error != "" || (final.Status.Scanned == 0) != (cq.maxscan == 0) {
+				t.Fatalf("unexpected final status %q", lines[1])
+			}
+		}
+	}
+}
+
+// an invalid type hints file should
+// cause queries against the table to fail
+// rather than being silently ignored
+func TestBadTypeHints(t *testing.T) {
+	tt := testdirEnviron(t)
+	root, err := tt.Root()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = root.(db.OutputFS).WriteFile(db.TypeHintsPath("default", "parking"), []byte(`{"fields": {"Ticket": ["nope"]}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	env, err := sneller.Environ(tt, "default")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tbl := expr.Ident("parking")
+	env.Schema(tbl)
+	_, err = env.Stat(tbl, &plan.Hints{})
+	if err == nil || !strings.Contains(err.Error(), "default/parking") {
+		t.Fatalf("expected a type hints error; got %v", err)
+	}
+	t.Logf("error: %s", err)
+	// other tables are unaffected
+	taxi := expr.Ident("taxi")
+	env.Schema(taxi)
+	_, err = env.Stat(taxi, &plan.Hints{})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// COUNT(*) should be answered from the index
+// without scanning anything, so it should succeed
+// even under a scan limit that rejects the equivalent
+// query that has to scan the table
+func TestCountStarIndex(t *testing.T) {
+	tt := testdirEnviron(t)
+	s := server{
+		logger:    testlogger(t),
+		sandbox:   tenant.CanSandbox(),
+		cachedir:  t.TempDir(),
+		cgroot:    os.Getenv("CGROOT"),
+		tenantcmd: []string{"./snellerd-test-binary", "worker"},
+		peers:     noPeers{},
+		auth:      testAuth{tt},
+		maxScan:   1,
+	}
+	httpsock := listen(t)
+	var wg sync.WaitGroup
+	wg.Add(1)
+	s.aboutToServe = wg.Done
+	go s.Serve(httpsock, nil)
+	wg.Wait()
+	defer s.Close()
+
+	rq := &requester{
+		t:    t,
+		host: "http://" + httpsock.Addr().String(),
+	}
+	query := func(text string) (*http.Response, []byte) {
+		r := rq.getQuery("", text)
+		r.URL.Path = "/streamQuery"
+		r.Header.Set("Accept", "application/x-ndjson")
+		res, err := http.DefaultClient.Do(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, err := io.ReadAll(res.Body)
+		res.Body.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return res, body
+	}
+
+	res, body := query("SELECT COUNT(Ticket) FROM default.parking")
+	if res.StatusCode != http.StatusBadRequest || !strings.Contains(string(body), "scan limit exceeded") {
+		t.Fatalf("COUNT(Ticket): status %s, body %q", res.Status, body)
+	}
+	res, body = query("SELECT COUNT(*) FROM default.parking")
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("COUNT(*): status %s, body %q", res.Status, body)
+	}
+	lines := strings.Split(strings.TrimSpace(string(body)), "\n")
+	if len(lines) != 2 || lines[0] != `{"count": 1023}` {
+		t.Fatalf("got %q", body)
+	}
+	var final struct {
+		Status struct {
+			Scanned int64  `json:"scanned"`
+			Error   string `json:"error"`
+		} `json:"$ion_annotation$final_status"`
+	}
+	if err := json.Unmarshal([]byte(lines[1]), &final); err != nil {
+		t.Fatalf("final status %q: %s", lines[1], err)
+	}
+	if final.Status.Error != "" || final.Status.Scanned != 0 {
+		t.Fatalf("unexpected final status %q", lines[1])
+	}
 }
@@ -0,0 +1,239 @@
+// Copyright (C) 2022 Sneller, Inc.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+
+	"github.com/SnellerInc/sneller/date"
+	"github.com/SnellerInc/sneller/db"
+	"github.com/SnellerInc/sneller/expr"
+	"github.com/SnellerInc/sneller/expr/partiql"
+)
+
+// executeDDL handles a CREATE TABLE,
+// ALTER TABLE, or DROP TABLE statement;
+// the responses match those of the
+// equivalent /tables/{db}/{table} requests
+func (s *server) executeDDL(w http.ResponseWriter, r *http.Request, tenant db.Tenant, query []byte) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "CREATE, ALTER, and DROP require a POST request", http.StatusMethodNotAllowed)
+		return
+	}
+	stmt, err := partiql.ParseDDL(query)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	err = stmt.Check()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	var target *expr.Table
+	switch stmt := stmt.(type) {
+	case *expr.CreateTable:
+		target = stmt.Table
+	case *expr.AlterTable:
+		target = stmt.Table
+	case *expr.DropTable:
+		target = stmt.Table
+	}
+	dbname, table, ok := statementTarget(target, r.URL.Query().Get("database"))
+	if !ok {
+		http.Error(w, "no database specified", http.StatusBadRequest)
+		return
+	}
+	root, err := tenant.Root()
+	if err != nil {
+		http.Error(w, "couldn't open db+table", http.StatusInternalServerError)
+		return
+	}
+	ofs, ok := root.(db.OutputFS)
+	if !ok {
+		http.Error(w, "tenant storage is read-only", http.StatusForbidden)
+		return
+	}
+	switch stmt := stmt.(type) {
+	case *expr.CreateTable:
+		s.createTable(w, ofs, dbname, table, stmt)
+	case *expr.AlterTable:
+		alt, err := alteration(stmt)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		def, err := db.AlterTable(ofs, dbname, table, alt)
+		if err != nil {
+			tableError(w, err)
+			return
+		}
+		s.logger.Printf("altered table %s/%s", dbname, table)
+		writeResultResponse(w, http.StatusOK, def)
+	case *expr.DropTable:
+		_, err := db.OpenDefinition(ofs, dbname, table)
+		if err != nil {
+			if stmt.IfExists && errors.Is(err, fs.ErrNotExist) {
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+			tableError(w, err)
+			return
+		}
+		err = db.DropTable(ofs, dbname, table)
+		if err != nil {
+			s.logger.Printf("executing %s: %s", stmt.Redacted(), err)
+			http.Error(w, "drop failed: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		s.logger.Printf("dropped table %s/%s", dbname, table)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func (s *server) createTable(w http.ResponseWriter, ofs db.OutputFS, dbname, table string, stmt *expr.CreateTable) {
+	def := &db.Definition{
+		Name:     table,
+		SortKeys: sortKeys(stmt.SortBy),
+	}
+	for i := range stmt.Inputs {
+		def.Inputs = append(def.Inputs, db.Input{
+			Pattern:     stmt.Inputs[i].Pattern,
+			Format:      stmt.Inputs[i].Format,
+			Incremental: stmt.Inputs[i].Incremental,
+		})
+	}
+	def.Partitions = partitions(stmt.PartitionBy)
+	if stmt.Retention != nil {
+		var err error
+		def.Retention, err = retention(stmt.Retention)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	var hints *db.TypeHints
+	if len(stmt.Columns) > 0 {
+		hints = columnHints(stmt.Columns)
+		if err := hints.Validate(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	err := db.CreateTable(ofs, dbname, def)
+	if err != nil {
+		if !errors.Is(err, db.ErrTableExists) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if !stmt.IfNotExists {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		// the existing table is left unchanged
+		def, err = db.OpenDefinition(ofs, dbname, table)
+		if err != nil {
+			tableError(w, err)
+			return
+		}
+		writeResultResponse(w, http.StatusOK, def)
+		return
+	}
+	if hints != nil {
+		err = db.WriteTypeHints(ofs, dbname, table, hints)
+		if err != nil {
+			s.logger.Printf("executing %s: writing type hints: %s", stmt.Redacted(), err)
+			http.Error(w, "couldn't write column definitions", http.StatusInternalServerError)
+			return
+		}
+	}
+	s.logger.Printf("created table %s/%s", dbname, table)
+	writeResultResponse(w, http.StatusCreated, def)
+}
+
+// columnHints converts column definitions
+// into the equivalent type hints;
+// a column that is not declared NOT NULL
+// may also be null or missing
+func columnHints(cols []expr.ColumnDef) *db.TypeHints {
+	h := &db.TypeHints{Fields: make(map[string][]string, len(cols))}
+	for i := range cols {
+		types := []string{cols[i].Type}
+		if !cols[i].NotNull {
+			types = append(types, "null", "missing")
+		}
+		h.Fields[expr.ToString(cols[i].Path)] = types
+	}
+	return h
+}
+
+func partitions(lst []expr.PartitionDef) []db.Partition {
+	var out []db.Partition
+	for i := range lst {
+		out = append(out, db.Partition{
+			Field: lst[i].Field,
+			Type:  lst[i].Type,
+			Value: lst[i].Value,
+		})
+	}
+	return out
+}
+
+func sortKeys(lst []expr.Node) []string {
+	var out []string
+	for i := range lst {
+		out = append(out, expr.ToString(lst[i]))
+	}
+	return out
+}
+
+func retention(r *expr.RetentionDef) (*db.RetentionPolicy, error) {
+	d, ok := date.ParseDuration(r.ValidFor)
+	if !ok {
+		return nil, fmt.Errorf("invalid retention window %q", r.ValidFor)
+	}
+	return &db.RetentionPolicy{Field: expr.ToString(r.Field), ValidFor: d}, nil
+}
+
+// alteration converts an ALTER TABLE statement
+// into the equivalent db.Alteration
+func alteration(stmt *expr.AlterTable) (*db.Alteration, error) {
+	alt := &db.Alteration{DropRetention: stmt.DropRetention}
+	if stmt.PartitionBy != nil {
+		lst := partitions(*stmt.PartitionBy)
+		if lst == nil {
+			lst = []db.Partition{}
+		}
+		alt.Partitions = &lst
+	}
+	if stmt.SortBy != nil {
+		lst := sortKeys(*stmt.SortBy)
+		if lst == nil {
+			lst = []string{}
+		}
+		alt.SortKeys = &lst
+	}
+	if stmt.Retention != nil {
+		var err error
+		alt.Retention, err = retention(stmt.Retention)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return alt, nil
+}
@@ -0,0 +1,193 @@
+// Copyright (C) 2022 Sneller, Inc.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net"
+	"net/http"
+
+	"github.com/SnellerInc/sneller"
+	"github.com/SnellerInc/sneller/db"
+	"github.com/SnellerInc/sneller/expr"
+	"github.com/SnellerInc/sneller/expr/blob"
+	"github.com/SnellerInc/sneller/expr/partiql"
+	"github.com/SnellerInc/sneller/ion"
+	"github.com/SnellerInc/sneller/plan"
+	"github.com/google/uuid"
+)
+
+// statementTarget determines the database and table
+// referenced by a DELETE or DDL statement
+func statementTarget(t *expr.Table, defaultDatabase string) (string, string, bool) {
+	switch e := t.Expr.(type) {
+	case expr.Ident:
+		return defaultDatabase, string(e), defaultDatabase != ""
+	case *expr.Dot:
+		inner, ok := e.Inner.(expr.Ident)
+		return string(inner), e.Field, ok
+	default:
+		return "", "", false
+	}
+}
+
+// executeDelete handles a DELETE statement
+// submitted to the /query endpoint
+func (s *server) executeDelete(w http.ResponseWriter, r *http.Request, tenant db.Tenant, query []byte) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "DELETE requires a POST request", http.StatusMethodNotAllowed)
+		return
+	}
+	stmt, err := partiql.ParseDelete(query)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	err = stmt.Check()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	dbname, table, ok := statementTarget(stmt.Table, r.URL.Query().Get("database"))
+	if !ok {
+		http.Error(w, "no database specified", http.StatusBadRequest)
+		return
+	}
+	root, err := tenant.Root()
+	if err != nil {
+		http.Error(w, "couldn't open db+table", http.StatusInternalServerError)
+		return
+	}
+	if _, ok := root.(db.OutputFS); !ok {
+		http.Error(w, "tenant storage is read-only", http.StatusForbidden)
+		return
+	}
+	_, err = db.OpenDefinition(root, dbname, table)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			http.Error(w, "no such table", http.StatusNotFound)
+			return
+		}
+		s.logger.Printf("handling DELETE: OpenDefinition: %s", err)
+		http.Error(w, "couldn't open table definition", http.StatusInternalServerError)
+		return
+	}
+
+	where := stmt.Where
+	if where == nil {
+		where = expr.Bool(true)
+	}
+	item := &pushed{
+		where:  where,
+		filter: &tenantFilter{s: s, creds: tenant},
+		done:   make(chan error, 1),
+	}
+	s.ingest.push(tenant, dbname, table, item)
+	select {
+	case err = <-item.done:
+	case <-r.Context().Done():
+		// the rows may still be deleted;
+		// there is no-one left to tell
+		return
+	}
+	if err != nil {
+		if errors.Is(err, db.ErrBuildAgain) {
+			w.Header().Set("Retry-After", "10")
+			http.Error(w, "table is being rebuilt; try again later", http.StatusServiceUnavailable)
+			return
+		}
+		s.logger.Printf("executing %s: %s", stmt.Redacted(), err)
+		http.Error(w, "delete failed: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	writeResultResponse(w, http.StatusOK, struct {
+		Deleted int64 `json:"deleted"`
+	}{
+		Deleted: item.deleted,
+	})
+}
+
+// tenantFilter is a db.RowFilter that evaluates
+// the predicate of a DELETE statement by running
+// queries over the affected packfiles in the tenant,
+// so that the daemon never interprets the rows itself
+type tenantFilter struct {
+	s     *server
+	creds db.Tenant
+}
+
+// blobEnv is a plan.Env in which
+// every table is the list of blobs src
+type blobEnv struct {
+	src *blob.List
+}
+
+func (b *blobEnv) Stat(_ expr.Node, h *plan.Hints) (plan.TableHandle, error) {
+	return &sneller.FilterHandle{
+		Expr:      h.Filter,
+		Fields:    h.Fields,
+		AllFields: h.AllFields,
+		Blobs:     b.src,
+	}, nil
+}
+
+// run executes SELECT col FROM src WHERE where
+// and calls fn with the query output
+func (f *tenantFilter) run(src *blob.List, col expr.Binding, where expr.Node, fn func(conn net.Conn) error) error {
+	q := &expr.Query{
+		Body: &expr.Select{
+			Columns: []expr.Binding{col},
+			From:    &expr.Table{Binding: expr.Bind(expr.Ident("packfile"), "")},
+			Where:   where,
+		},
+	}
+	if err := q.Check(); err != nil {
+		return err
+	}
+	tree, err := plan.New(q, &blobEnv{src: src})
+	if err != nil {
+		return err
+	}
+	return f.s.runTree(f.creds, uuid.New(), tree, fn)
+}
+
+func (f *tenantFilter) Count(src *blob.List, where expr.Node) (int64, error) {
+	var n int64
+	err := f.run(src, expr.Bind(expr.Count(expr.Star{}), "count"), where, func(conn net.Conn) error {
+		return readValues(conn, func(d ion.Datum) error {
+			s, err := d.Struct()
+			if err != nil {
+				return err
+			}
+			c, ok := s.FieldByName("count")
+			if !ok {
+				return fmt.Errorf("unexpected COUNT(*) result %s", d)
+			}
+			n, err = c.Int()
+			return err
+		})
+	})
+	return n, err
+}
+
+func (f *tenantFilter) Select(src *blob.List, where expr.Node, dst io.Writer) error {
+	return f.run(src, expr.Bind(expr.Star{}, ""), where, func(conn net.Conn) error {
+		_, err := io.Copy(dst, conn)
+		return err
+	})
+}
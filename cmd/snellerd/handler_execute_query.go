@@ -21,8 +21,10 @@ import (
 	"fmt"
 	"io"
 	"io/fs"
+	"math"
 	"net"
 	"net/http"
+	"net/url"
 	"strconv"
 	"strings"
 	"time"
@@ -30,10 +32,12 @@ import (
 	"github.com/SnellerInc/sneller"
 	"github.com/SnellerInc/sneller/db"
 	"github.com/SnellerInc/sneller/expr"
+	"github.com/SnellerInc/sneller/expr/lint"
 	"github.com/SnellerInc/sneller/expr/partiql"
 	"github.com/SnellerInc/sneller/ion"
 	"github.com/SnellerInc/sneller/plan"
 	"github.com/SnellerInc/sneller/plan/pir"
+	"github.com/SnellerInc/sneller/repro"
 	"github.com/SnellerInc/sneller/tenant"
 	"github.com/SnellerInc/sneller/tenant/tnproto"
 	"github.com/google/uuid"
@@ -45,6 +49,25 @@ import (
 // without doing any scanning.
 const DefaultMaxScan = 0
 
+// scanLimit returns the maximum number of bytes
+// that a query may scan given the tenant
+// configuration cfg and the limit requested
+// for the query itself (0 if none); a query
+// may lower the limit but not raise it
+func (s *server) scanLimit(cfg *db.TenantConfig, query uint64) uint64 {
+	max := s.maxScan
+	if cfg != nil && cfg.MaxScanBytes > 0 {
+		max = cfg.MaxScanBytes
+	}
+	if query > 0 && (max == 0 || query < max) {
+		max = query
+	}
+	if max > math.MaxInt64 {
+		max = math.MaxInt64
+	}
+	return max
+}
+
 type errPlanLimit struct {
 	scan, max uint64
 }
@@ -81,6 +104,17 @@ func setTiming(w http.ResponseWriter, elapsed time.Duration, stats *plan.ExecSta
 		float64(elapsed)/float64(time.Millisecond), stats.CacheMisses, stats.CacheHits, stats.BytesScanned))
 }
 
+// tenantKeys returns the tenant process
+// ID and key used to run queries for creds
+func tenantKeys(creds db.Tenant) (id tnproto.ID, key tnproto.Key) {
+	tenantID := creds.ID()
+	hash := sha256.Sum256([]byte(tenantID))
+	copy(id[:], hash[:])
+	hash = sha256.Sum256([]byte(tenantID + string(creds.Key()[:])))
+	copy(key[:], hash[:])
+	return id, key
+}
+
 // after 15 minutes, stop waiting for a result
 // and SIGQUIT the child process
 const queryKillTimeout = 15 * time.Minute
@@ -89,6 +123,20 @@ const queryKillTimeout = 15 * time.Minute
 // curl -v -H 'Authorization: sneller' -H 'Accept: application/ion' 'http://localhost:8080/executeQuery?database=sf1-new&query=SELECT%20%2A%20FROM%20nation%20LIMIT%2010'
 // curl -v -X POST -H 'Authorization: sneller' -H 'Accept: application/ion' --data-raw 'SELECT * FROM nation LIMIT 10' 'http://localhost:8080/executeQuery?database=sf1-new'
 func (s *server) executeQueryHandler(w http.ResponseWriter, r *http.Request) {
+	s.executeQuery(w, r, false)
+}
+
+// streamQueryHandler is identical to executeQueryHandler,
+// except that the results are flushed to the client as
+// they are produced, a heartbeat::{elapsed_ms: ...} value
+// is written while the query produces no output,
+// and the output always ends with a final_status::{...}
+// value (in both ion and NDJSON output)
+func (s *server) streamQueryHandler(w http.ResponseWriter, r *http.Request) {
+	s.executeQuery(w, r, true)
+}
+
+func (s *server) executeQuery(w http.ResponseWriter, r *http.Request, stream bool) {
 	ctx := r.Context()
 	start := time.Now()
 	creds, err := s.getTenant(ctx, w, r)
@@ -110,7 +158,12 @@ func (s *server) executeQueryHandler(w http.ResponseWriter, r *http.Request) {
 
 	case http.MethodPost:
 		// restrict the size of the query text to something reasonable
-		body := http.MaxBytesReader(w, r.Body, 128*1024*1024)
+		body := io.Reader(http.MaxBytesReader(w, r.Body, 128*1024*1024))
+		if n := s.limits.MaxTextSize; n > 0 {
+			// read just enough to detect
+			// that the limit is exceeded
+			body = io.LimitReader(body, int64(n)+1)
+		}
 		query, err = io.ReadAll(body)
 		if err != nil {
 			http.Error(w, "cannot read query", http.StatusBadRequest)
@@ -118,6 +171,19 @@ func (s *server) executeQueryHandler(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	if err := s.limits.CheckText(query); err != nil {
+		planError(w, err)
+		return
+	}
+	if partiql.IsDelete(query) {
+		s.executeDelete(w, r, creds, query)
+		return
+	}
+	if partiql.IsDDL(query) {
+		s.executeDDL(w, r, creds, query)
+		return
+	}
+
 	// Determine the output format
 	explicitJSON := r.URL.Query().Has("json")
 	var encodingFormat tnproto.OutputFormat
@@ -146,37 +212,86 @@ func (s *server) executeQueryHandler(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "invalid 'Accept' header", http.StatusBadRequest)
 		return
 	}
+	jsonOpts, err := jsonOptions(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if stream {
+		switch encodingFormat {
+		case tnproto.OutputChunkedIon:
+			encodingFormat = tnproto.OutputStreamIon
+		case tnproto.OutputChunkedJSON:
+			encodingFormat = tnproto.OutputStreamJSON
+		default:
+			http.Error(w, fmt.Sprintf("can't stream %q output", acceptHeader), http.StatusBadRequest)
+			return
+		}
+	}
 
 	defaultDatabase := r.URL.Query().Get("database")
-	parsedQuery, err := partiql.Parse(query)
+	dialect, ok := partiql.DialectByName(r.URL.Query().Get("dialect"))
+	if !ok {
+		http.Error(w, "invalid 'dialect' parameter", http.StatusBadRequest)
+		return
+	}
+	parsedQuery, err := partiql.ParseDialect(query, dialect)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
+	err = s.limits.Check(parsedQuery)
+	if err != nil {
+		planError(w, err)
+		return
+	}
+	var cfg *db.TenantConfig
+	if ct, ok := creds.(db.TenantConfigurable); ok {
+		cfg = ct.Config()
+	}
+	if cfg != nil && cfg.FiscalYearStart != 0 {
+		err = parsedQuery.SetFiscalYearStart(cfg.FiscalYearStart)
+		if err != nil {
+			s.logger.Printf("tenant %s: %s", tenantID, err)
+			http.Error(w, "invalid tenant configuration", http.StatusInternalServerError)
+			return
+		}
+	}
+	if r.URL.Query().Has("ansi_nulls") {
+		parsedQuery.SetANSINulls()
+	}
+	if locale := r.URL.Query().Get("locale"); locale != "" {
+		err = parsedQuery.SetLocale(locale)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
 	err = parsedQuery.Check()
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
+	for _, warning := range lint.Check(parsedQuery) {
+		w.Header().Add("X-Sneller-Warning", warning.String())
+	}
+
 	normalized := parsedQuery.Text()
-	redacted := parsedQuery.Text()
+	redacted := parsedQuery.Redacted()
 
-	var id tnproto.ID
-	var key tnproto.Key
-	hash := sha256.Sum256([]byte(tenantID))
-	copy(id[:], hash[:])
-	hash = sha256.Sum256([]byte(tenantID + string(creds.Key()[:])))
-	copy(key[:], hash[:])
+	id, key := tenantKeys(creds)
 
 	// determine scan limit
-	maxScan := uint64(DefaultMaxScan)
-	if ct, ok := creds.(db.TenantConfigurable); ok {
-		cfg := ct.Config()
-		if cfg != nil && cfg.MaxScanBytes > 0 {
-			maxScan = cfg.MaxScanBytes
+	var queryScan uint64
+	if str := r.URL.Query().Get("max_scan_bytes"); str != "" {
+		queryScan, err = strconv.ParseUint(str, 10, 64)
+		if err != nil {
+			http.Error(w, "invalid 'max_scan_bytes' parameter", http.StatusBadRequest)
+			return
 		}
 	}
+	maxScan := s.scanLimit(cfg, queryScan)
 
 	planEnv, err := sneller.Environ(creds, defaultDatabase)
 	if err != nil {
@@ -185,9 +300,20 @@ func (s *server) executeQueryHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	endPoints := s.peers.Get()
+	wantStats := r.URL.Query().Has("stats")
+	planEnv.CountBlocks = wantStats
 
 	queryID := uuid.New()
 	w.Header().Add("X-Sneller-Query-ID", queryID.String())
+	defer s.queries.add(tenantID, sneller.QueryInfo{
+		ID:       queryID.String(),
+		Database: defaultDatabase,
+		Query:    redacted,
+		Start:    time.Now(),
+	})()
+	planEnv.Queries = func() []sneller.QueryInfo {
+		return s.queries.list(tenantID)
+	}
 
 	var tree *plan.Tree
 	start = time.Now()
@@ -199,6 +325,7 @@ func (s *server) executeQueryHandler(w http.ResponseWriter, r *http.Request) {
 	}
 	if err != nil {
 		s.logger.Printf("tenant %s query ID %s planning failed: %s", tenantID, queryID, err)
+		s.saveRepro(tenantID, queryID.String(), parsedQuery, redacted, nil, err)
 		planError(w, err)
 		return
 	}
@@ -208,23 +335,36 @@ func (s *server) executeQueryHandler(w http.ResponseWriter, r *http.Request) {
 		planError(w, &errPlanLimit{scan: willScan, max: maxScan})
 		return
 	}
+	// MaxScanned is an upper bound; enforce
+	// the limit during execution as well in
+	// case the bound is not accurate
+	tree.MaxScan = int64(maxScan)
 	s.logger.Printf("tenant %s query ID %s auth %s planning %s", tenantID, queryID, authElapsed, time.Since(start))
 
 	planHash, newestBlobTime := planEnv.CacheValues()
 
-	// hash the tenant/query/plan/format to an eTag
+	enc := s.negotiate(r.Header.Values("Accept-Encoding"))
+	switch encodingFormat {
+	case tnproto.OutputChunkedJSON, tnproto.OutputChunkedJSONArray, tnproto.OutputStreamJSON:
+		enc.JSON = jsonOpts
+	}
+
+	// hash the tenant/query/plan/format/encoding to an eTag
 	hasher := sha256.New()
 	hasher.Write([]byte(tenantID))
 	io.WriteString(hasher, normalized)
 	hasher.Write(planHash)
-	hasher.Write([]byte{byte(encodingFormat)})
+	hasher.Write([]byte{byte(encodingFormat), byte(enc.Compression)})
+	if enc.JSON != (ion.JSONOptions{}) {
+		fmt.Fprintf(hasher, "%+v", enc.JSON)
+	}
 	eTag := `"` + base64.RawStdEncoding.EncodeToString(hasher.Sum(nil)) + `"`
 
 	// Add the ETag to the response
 	w.Header().Add("ETag", eTag)
 	w.Header().Add("Last-Modified", newestBlobTime.UTC().Format(http.TimeFormat))
 	w.Header().Add("Cache-Control", "private, must-revalidate")
-	w.Header().Add("Vary", "Accept, Authentication")
+	w.Header().Add("Vary", "Accept, Accept-Encoding, Authentication")
 
 	if r.Method == http.MethodGet || r.Method == http.MethodHead {
 		// Check the 'If-None-Match' request header
@@ -256,6 +396,9 @@ func (s *server) executeQueryHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	w.Header().Add("Content-Type", acceptHeader)
+	if enc.Compression != tnproto.CompressNone {
+		w.Header().Set("Content-Encoding", enc.Compression.String())
+	}
 	if r.Method == http.MethodHead {
 		w.WriteHeader(http.StatusOK)
 		return
@@ -271,12 +414,13 @@ func (s *server) executeQueryHandler(w http.ResponseWriter, r *http.Request) {
 		res:   w,
 	}
 	startrun := time.Now()
-	rc, err := s.manager.Do(id, key, tree, encodingFormat, conn)
+	rc, err := s.manager.DoEncoded(id, key, tree, encodingFormat, enc, conn)
 	if err != nil {
 		if !conn.hijacked {
 			// didn't call w.WriteHeader() yet;
 			// we can write a plaintext error
 			w.Header().Del("Trailer")
+			w.Header().Del("Content-Encoding")
 			w.Header().Set("Content-Type", "text/plain")
 			if errors.Is(err, tenant.ErrOverloaded) {
 				w.WriteHeader(http.StatusTooManyRequests)
@@ -287,11 +431,12 @@ func (s *server) executeQueryHandler(w http.ResponseWriter, r *http.Request) {
 			if sendTrailer {
 				setError(w)
 			}
-			if encodingFormat == tnproto.OutputChunkedIon {
+			writeTrailer(w, enc, encodingFormat, func(w io.Writer) {
 				writeError(w, "error dispatching query")
-			}
+			})
 		}
 		s.logger.Printf("tenant %s query ID %s %q execution failed (do): %v", tenantID, queryID, redacted, err)
+		s.saveRepro(tenantID, queryID.String(), parsedQuery, redacted, tree, err)
 		return
 	}
 	go func() {
@@ -317,6 +462,12 @@ func (s *server) executeQueryHandler(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 		s.logger.Printf("tenant %s query ID %s %q execution failed (check): %v", tenantID, queryID, redacted, err)
+		s.saveRepro(tenantID, queryID.String(), parsedQuery, redacted, tree, err)
+		if encodingFormat.Streaming() {
+			writeTrailer(w, enc, encodingFormat, func(w io.Writer) {
+				writeError(w, err.Error())
+			})
+		}
 		if deadlined && isTimeout(err) {
 			s.logger.Printf("tenant %s query ID %s killing tenant worker %s due to timeout", tenantID, queryID, id)
 			s.manager.Quit(id)
@@ -327,13 +478,26 @@ func (s *server) executeQueryHandler(w http.ResponseWriter, r *http.Request) {
 	if sendTrailer {
 		setTiming(w, elapsed, &stats)
 	}
-	if encodingFormat == tnproto.OutputChunkedIon {
+	writeTrailer(w, enc, encodingFormat, func(w io.Writer) {
+		if wantStats {
+			writeQueryStats(w, &stats, planEnv.BlocksSkipped(), elapsed)
+		}
 		writeStatus(w, &stats)
-	}
+	})
 	s.logger.Printf("tenant %s query ID %s duration %s bytes %d hits %d misses %d",
 		tenantID, queryID, elapsed, stats.BytesScanned, stats.CacheHits, stats.CacheMisses)
 }
 
+// saveRepro records a repro bundle for a failed query
+func (s *server) saveRepro(tenantID, queryID string, q *expr.Query, redacted string, tree *plan.Tree, err error) {
+	b := repro.New(q, tree, err)
+	// planning may have rewritten q,
+	// so prefer the text of the original query
+	b.Query = redacted
+	b.Versions["snellerd"] = version
+	s.repros.add(tenantID, queryID, b)
+}
+
 // satisfied by net.Conn and friends
 type readDeadliner interface {
 	SetReadDeadline(time.Time) error
@@ -361,6 +525,18 @@ func isBadQuery(err error, w http.ResponseWriter) bool {
 	var emptyType *expr.TypeError
 	var emptyCompile *pir.CompileError
 	var emptyLimit *errPlanLimit
+	var emptyComplexity *expr.LimitError
+	if errors.As(err, &emptyComplexity) {
+		code := http.StatusBadRequest
+		if emptyComplexity.Kind == expr.LimitText {
+			code = http.StatusRequestEntityTooLarge
+		}
+		w.Header().Set("X-Sneller-Error-Code", emptyComplexity.Kind.String())
+		w.WriteHeader(code)
+		io.WriteString(w, emptyComplexity.Error())
+		io.WriteString(w, "\n")
+		return true
+	}
 	if errors.As(err, &emptySyntax) {
 		w.WriteHeader(http.StatusBadRequest)
 		io.WriteString(w, emptySyntax.Error())
@@ -393,8 +569,51 @@ func isBadQuery(err error, w http.ResponseWriter) bool {
 // to the user (and the status code ought to be 4xx)
 //
 // type and syntax errors are returned as 400,
+// queries that exceed the size and complexity limits
+// are returned as 413 or 400 with an X-Sneller-Error-Code header,
 // fs.ErrNotExist errors are returned as 404,
 // and others are returned as 500
+// jsonOptions parses the parameters that
+// control the formatting of JSON output:
+//
+//	json_timestamps=rfc3339|epoch_ms
+//	json_nonfinite=literal|null|string
+//	json_numbers=float_point,no_exponent,bigint_strings
+func jsonOptions(q url.Values) (ion.JSONOptions, error) {
+	var opts ion.JSONOptions
+	switch ts := q.Get("json_timestamps"); ts {
+	case "", "rfc3339":
+	case "epoch_ms":
+		opts.Timestamps = ion.TimestampEpochMillis
+	default:
+		return opts, fmt.Errorf("invalid 'json_timestamps' parameter %q", ts)
+	}
+	switch nf := q.Get("json_nonfinite"); nf {
+	case "", "literal":
+	case "null":
+		opts.NonFinite = ion.NonFiniteNull
+	case "string":
+		opts.NonFinite = ion.NonFiniteString
+	default:
+		return opts, fmt.Errorf("invalid 'json_nonfinite' parameter %q", nf)
+	}
+	if nums := q.Get("json_numbers"); nums != "" {
+		for _, flag := range strings.Split(nums, ",") {
+			switch flag {
+			case "float_point":
+				opts.FloatPoint = true
+			case "no_exponent":
+				opts.NoExponent = true
+			case "bigint_strings":
+				opts.BigIntsAsStrings = true
+			default:
+				return opts, fmt.Errorf("invalid 'json_numbers' flag %q", flag)
+			}
+		}
+	}
+	return opts, nil
+}
+
 func planError(w http.ResponseWriter, err error) {
 	w.Header().Set("Content-Type", "text/plain")
 	if errors.Is(err, fs.ErrNotExist) {
@@ -409,7 +628,23 @@ func planError(w http.ResponseWriter, err error) {
 	io.WriteString(w, "couldn't create query plan\n")
 }
 
-func writeError(w http.ResponseWriter, errtext string) {
+// writeTrailer writes the ion values written by fn
+// after the query output if the output format
+// has trailing status values
+func writeTrailer(w io.Writer, enc tnproto.Encoding, ofmt tnproto.OutputFormat, fn func(w io.Writer)) {
+	switch ofmt {
+	case tnproto.OutputChunkedIon, tnproto.OutputStreamIon:
+		writeEncoded(w, enc, fn)
+	case tnproto.OutputStreamJSON:
+		writeEncoded(w, enc, func(w io.Writer) {
+			jw := ion.NewJSONWriter(w, '\n')
+			jw.ShowAnnotations = true
+			fn(jw)
+		})
+	}
+}
+
+func writeError(w io.Writer, errtext string) {
 	var tmp ion.Buffer
 	var st ion.Symtab
 	resultsym := st.Intern("final_status")
@@ -425,7 +660,32 @@ func writeError(w http.ResponseWriter, errtext string) {
 	w.Write(tmp.Bytes())
 }
 
-func writeStatus(w http.ResponseWriter, stats *plan.ExecStats) {
+// writeQueryStats writes the query_stats trailer
+// that is requested with the "stats" parameter
+func writeQueryStats(w io.Writer, stats *plan.ExecStats, skipped int, elapsed time.Duration) {
+	var tmp ion.Buffer
+	var st ion.Symtab
+	resultsym := st.Intern("query_stats")
+	tmp.BeginAnnotation(1)
+	tmp.BeginField(resultsym)
+	tmp.BeginStruct(-1)
+	tmp.BeginField(st.Intern("scanned"))
+	tmp.WriteInt(stats.BytesScanned)
+	tmp.BeginField(st.Intern("blocks_skipped"))
+	tmp.WriteInt(int64(skipped))
+	tmp.BeginField(st.Intern("rows"))
+	tmp.WriteInt(stats.RowsEmitted)
+	tmp.BeginField(st.Intern("elapsed_ms"))
+	tmp.WriteFloat64(float64(elapsed) / float64(time.Millisecond))
+	tmp.EndStruct()
+	tmp.EndAnnotation()
+	split := tmp.Size()
+	st.Marshal(&tmp, true)
+	w.Write(tmp.Bytes()[split:])
+	w.Write(tmp.Bytes()[:split])
+}
+
+func writeStatus(w io.Writer, stats *plan.ExecStats) {
 	var tmp ion.Buffer
 	var st ion.Symtab
 	resultsym := st.Intern("final_status")
@@ -0,0 +1,406 @@
+// Copyright (C) 2022 Sneller, Inc.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/SnellerInc/sneller/db"
+	"github.com/SnellerInc/sneller/expr"
+	"github.com/SnellerInc/sneller/ion/blockfmt"
+)
+
+// maxIngestSize is the largest request body
+// accepted by the /ingest endpoint.
+const maxIngestSize = 64 * 1024 * 1024
+
+// ingestFormat determines the row format for
+// a pushed payload from its Content-Type and
+// Content-Encoding headers.
+func ingestFormat(r *http.Request) (blockfmt.RowFormat, error) {
+	ctype := r.Header.Get("Content-Type")
+	if ctype == "" {
+		ctype = "application/x-ndjson"
+	}
+	mt, _, err := mime.ParseMediaType(ctype)
+	if err != nil {
+		return nil, err
+	}
+	var suffix string
+	switch mt {
+	case "application/json", "application/x-ndjson":
+		suffix = ".json"
+	case "application/ion":
+		suffix = ".ion"
+	default:
+		return nil, fmt.Errorf("unsupported Content-Type %q", mt)
+	}
+	switch enc := r.Header.Get("Content-Encoding"); enc {
+	case "", "identity":
+	case "gzip":
+		suffix += ".gz"
+	case "zstd":
+		suffix += ".zst"
+	default:
+		return nil, fmt.Errorf("unsupported Content-Encoding %q", enc)
+	}
+	return blockfmt.MustSuffixToFormat(suffix), nil
+}
+
+// ingestTarget splits /ingest/{db}/{table}
+// into its database and table components.
+func ingestTarget(p string) (string, string, bool) {
+	return tableTarget("/ingest/", p)
+}
+
+// tableTarget splits {prefix}{db}/{table}
+// into its database and table components.
+func tableTarget(prefix, p string) (string, string, bool) {
+	rest, ok := strings.CutPrefix(p, prefix)
+	if !ok {
+		return "", "", false
+	}
+	dbname, table, ok := strings.Cut(rest, "/")
+	if !ok || dbname == "" || table == "" || strings.Contains(table, "/") {
+		return "", "", false
+	}
+	return dbname, table, true
+}
+
+func (s *server) ingestHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	tenant, err := s.getTenant(ctx, w, r)
+	if err != nil {
+		return
+	}
+	dbname, table, ok := ingestTarget(r.URL.Path)
+	if !ok {
+		http.Error(w, "expected /ingest/{db}/{table}", http.StatusBadRequest)
+		return
+	}
+	format, err := ingestFormat(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnsupportedMediaType)
+		return
+	}
+	root, err := tenant.Root()
+	if err != nil {
+		http.Error(w, "couldn't open db+table", http.StatusInternalServerError)
+		return
+	}
+	if _, ok := root.(db.OutputFS); !ok {
+		http.Error(w, "tenant storage is read-only", http.StatusForbidden)
+		return
+	}
+	_, err = db.OpenDefinition(root, dbname, table)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			http.Error(w, "no such table", http.StatusNotFound)
+			return
+		}
+		s.logger.Printf("handling /ingest: OpenDefinition: %s", err)
+		http.Error(w, "couldn't open table definition", http.StatusInternalServerError)
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxIngestSize))
+	if err != nil {
+		var maxerr *http.MaxBytesError
+		if errors.As(err, &maxerr) {
+			http.Error(w, "request body too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		http.Error(w, "reading request body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if len(body) == 0 {
+		http.Error(w, "empty request body", http.StatusBadRequest)
+		return
+	}
+
+	item := &pushed{
+		path:   pushPath(dbname, table),
+		body:   body,
+		format: format,
+		done:   make(chan error, 1),
+	}
+	s.ingest.push(tenant, dbname, table, item)
+	select {
+	case err = <-item.done:
+	case <-ctx.Done():
+		// the data may still be committed;
+		// there is no-one left to tell
+		return
+	}
+	if err != nil {
+		if errors.Is(err, db.ErrBuildAgain) {
+			w.Header().Set("Retry-After", "10")
+			http.Error(w, "table is being rebuilt; try again later", http.StatusServiceUnavailable)
+			return
+		}
+		s.logger.Printf("ingesting into %s/%s: %s", dbname, table, err)
+		http.Error(w, "ingest failed: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	writeResultResponse(w, http.StatusOK, struct {
+		Path string `json:"path"`
+		Size int    `json:"size"`
+	}{
+		Path: item.path,
+		Size: len(body),
+	})
+}
+
+// pushPath produces a unique input path for a
+// payload pushed to the /ingest endpoint.
+func pushPath(dbname, table string) string {
+	var buf [16]byte
+	_, err := rand.Read(buf[:])
+	if err != nil {
+		panic(err)
+	}
+	return "push://" + dbname + "/" + table + "/" + hex.EncodeToString(buf[:])
+}
+
+// pushed is a single payload submitted
+// to the /ingest endpoint, a DELETE
+// statement if where is non-nil, or
+// a compaction to be committed if
+// compact is non-nil
+type pushed struct {
+	path   string
+	body   []byte
+	format blockfmt.RowFormat
+	done   chan error
+
+	where   expr.Node
+	filter  db.RowFilter
+	deleted int64
+
+	compact *db.Compaction
+}
+
+// data returns whether p is a payload
+// that is appended to the table
+func (p *pushed) data() bool {
+	return p.where == nil && p.compact == nil
+}
+
+func (p *pushed) input() blockfmt.Input {
+	sum := sha256.Sum256(p.body)
+	return blockfmt.Input{
+		Path: p.path,
+		ETag: hex.EncodeToString(sum[:]),
+		Size: int64(len(p.body)),
+		R:    io.NopCloser(bytes.NewReader(p.body)),
+		F:    p.format,
+	}
+}
+
+type ingestKey struct {
+	tenant, db, table string
+}
+
+type ingestQueue struct {
+	pending []*pushed
+}
+
+// ingester serializes index updates for pushed
+// data on a per-table basis. Payloads that arrive
+// for a table while a previous update is in progress
+// are buffered and committed together, so a burst
+// of small pushes produces a single packfile
+// and a single index update. DELETE statements
+// are serialized with the pushed payloads in the
+// order in which they arrive.
+//
+// If compactInterval is positive, tables that
+// receive pushes are compacted in the background
+// at most once per compactInterval: the merged
+// packfiles are written concurrently with further
+// pushes, and the compaction is then committed
+// through the table's queue like any other update.
+type ingester struct {
+	conf            db.Config
+	compactInterval time.Duration
+
+	lock        sync.Mutex
+	tables      map[ingestKey]*ingestQueue
+	compactions map[ingestKey]*compactState
+	compactwg   sync.WaitGroup
+}
+
+// compactState tracks the background
+// compactions of one table
+type compactState struct {
+	last    time.Time
+	running bool
+}
+
+func (in *ingester) push(t db.Tenant, dbname, table string, p *pushed) {
+	key := ingestKey{tenant: t.ID(), db: dbname, table: table}
+	in.lock.Lock()
+	defer in.lock.Unlock()
+	if q := in.tables[key]; q != nil {
+		q.pending = append(q.pending, p)
+		return
+	}
+	if in.tables == nil {
+		in.tables = make(map[ingestKey]*ingestQueue)
+	}
+	q := &ingestQueue{pending: []*pushed{p}}
+	in.tables[key] = q
+	go in.run(t, key, q)
+}
+
+// run commits batches of pending payloads
+// for one table until there is nothing
+// left to commit
+func (in *ingester) run(t db.Tenant, key ingestKey, q *ingestQueue) {
+	for {
+		in.lock.Lock()
+		batch := q.pending
+		q.pending = nil
+		if len(batch) == 0 {
+			delete(in.tables, key)
+			in.lock.Unlock()
+			return
+		}
+		in.lock.Unlock()
+
+		for len(batch) > 0 {
+			if p := batch[0]; p.where != nil {
+				var err error
+				p.deleted, err = in.conf.Delete(t, key.db, key.table, p.where, p.filter)
+				p.done <- err
+				batch = batch[1:]
+				continue
+			} else if p.compact != nil {
+				p.done <- p.compact.Commit()
+				batch = batch[1:]
+				continue
+			}
+			n := 1
+			for n < len(batch) && batch[n].data() {
+				n++
+			}
+			// the compaction only considers packfiles
+			// that are already committed, so it can be
+			// started before appending this batch
+			in.maybeCompact(t, key)
+			in.appendAll(t, key, batch[:n])
+			batch = batch[n:]
+		}
+	}
+}
+
+// maybeCompact starts a background compaction
+// of a table if one is due and none is running
+func (in *ingester) maybeCompact(t db.Tenant, key ingestKey) {
+	if in.compactInterval <= 0 {
+		return
+	}
+	in.lock.Lock()
+	defer in.lock.Unlock()
+	cs := in.compactions[key]
+	if cs == nil {
+		if in.compactions == nil {
+			in.compactions = make(map[ingestKey]*compactState)
+		}
+		cs = &compactState{}
+		in.compactions[key] = cs
+	}
+	if cs.running || time.Since(cs.last) < in.compactInterval {
+		return
+	}
+	cs.running = true
+	cs.last = time.Now()
+	in.compactwg.Add(1)
+	go in.compact(t, key, cs)
+}
+
+// compact writes the merged packfiles for a table
+// and then commits the compaction through the
+// table's queue so that the index update is
+// serialized with pushes and deletes
+func (in *ingester) compact(t db.Tenant, key ingestKey, cs *compactState) {
+	defer in.compactwg.Done()
+	defer func() {
+		in.lock.Lock()
+		cs.running = false
+		in.lock.Unlock()
+	}()
+	cmp, err := in.conf.PrepareCompaction(t, key.db, key.table)
+	if err == nil && cmp != nil {
+		p := &pushed{
+			compact: cmp,
+			done:    make(chan error, 1),
+		}
+		in.push(t, key.db, key.table, p)
+		err = <-p.done
+	}
+	if err != nil && !errors.Is(err, db.ErrBuildAgain) && in.conf.Logf != nil {
+		in.conf.Logf("compacting %s/%s: %s", key.db, key.table, err)
+	}
+}
+
+// appendAll commits a batch of pushed payloads
+// and reports the result to each payload
+func (in *ingester) appendAll(t db.Tenant, key ingestKey, batch []*pushed) {
+	lst, err := in.commit(t, key, batch)
+	if err != nil && len(batch) > 1 && !errors.Is(err, db.ErrBuildAgain) {
+		// don't fail every payload in the batch
+		// because of a single malformed one:
+		// report conversion errors for the payloads
+		// that caused them and retry the rest
+		for i := range batch {
+			if lst[i].Err != nil {
+				batch[i].done <- lst[i].Err
+				continue
+			}
+			_, err := in.commit(t, key, batch[i:i+1])
+			batch[i].done <- err
+		}
+		return
+	}
+	for i := range batch {
+		batch[i].done <- err
+	}
+}
+
+// commit appends batch to the table index;
+// the returned inputs have Err populated
+// for any payload that failed conversion
+func (in *ingester) commit(t db.Tenant, key ingestKey, batch []*pushed) ([]blockfmt.Input, error) {
+	lst := make([]blockfmt.Input, len(batch))
+	for i := range batch {
+		lst[i] = batch[i].input()
+	}
+	err := in.conf.Append(t, key.db, key.table, lst)
+	return lst, err
+}
@@ -0,0 +1,347 @@
+// Copyright (C) 2022 Sneller, Inc.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"sort"
+
+	"github.com/SnellerInc/sneller/db"
+	"github.com/SnellerInc/sneller/expr"
+	"github.com/SnellerInc/sneller/ion/blockfmt"
+)
+
+// The /metadata endpoints describe the catalog in
+// the shape expected by JDBC and ODBC drivers
+// (DatabaseMetaData.getSchemas, getTables, getColumns
+// and getTypeInfo, or SQLTables, SQLColumns and
+// SQLGetTypeInfo respectively). Databases are reported
+// as schemas within the single catalog metadataCatalog.
+
+// metadataCatalog is the name of the only catalog
+const metadataCatalog = "sneller"
+
+// metadataType describes how values of one
+// ion type are presented to a driver
+type metadataType struct {
+	Name     string `json:"type_name"`
+	IonType  string `json:"ion_type,omitempty"`
+	JDBCType int    `json:"jdbc_type"` // java.sql.Types
+	ODBCType int    `json:"odbc_type"` // SQL_* data type
+}
+
+// metadataTypes is the type mapping; ion types that
+// are not listed here (and columns that hold values
+// of more than one type) are reported as OTHER
+var metadataTypes = []metadataType{
+	{Name: "BOOLEAN", IonType: "bool", JDBCType: 16, ODBCType: -7},
+	{Name: "BIGINT", IonType: "int", JDBCType: -5, ODBCType: -5},
+	{Name: "DOUBLE", IonType: "float", JDBCType: 8, ODBCType: 8},
+	{Name: "DECIMAL", IonType: "decimal", JDBCType: 3, ODBCType: 3},
+	{Name: "TIMESTAMP WITH TIME ZONE", IonType: "timestamp", JDBCType: 2014, ODBCType: 93},
+	{Name: "VARCHAR", IonType: "string", JDBCType: 12, ODBCType: 12},
+	{Name: "ARRAY", IonType: "list", JDBCType: 2003, ODBCType: -10},
+	{Name: "STRUCT", IonType: "struct", JDBCType: 2002, ODBCType: -10},
+	{Name: "VARBINARY", IonType: "blob", JDBCType: -3, ODBCType: -3},
+	{Name: "CLOB", IonType: "clob", JDBCType: 2005, ODBCType: -1},
+	{Name: "OTHER", JDBCType: 1111, ODBCType: -10},
+}
+
+// metadataTypeOf returns the type mapping for
+// a column with values of the given ion types
+func metadataTypeOf(types []string) *metadataType {
+	if len(types) == 2 && types[0] == "float" && types[1] == "int" {
+		types = types[:1] // numeric columns widen to DOUBLE
+	}
+	if len(types) == 1 {
+		for i := range metadataTypes {
+			if metadataTypes[i].IonType == types[0] {
+				return &metadataTypes[i]
+			}
+		}
+	}
+	return &metadataTypes[len(metadataTypes)-1]
+}
+
+type metadataSchema struct {
+	Catalog string `json:"catalog"`
+	Schema  string `json:"schema"`
+}
+
+type metadataTable struct {
+	Catalog string `json:"catalog"`
+	Schema  string `json:"schema"`
+	Table   string `json:"table"`
+	Type    string `json:"table_type"`
+}
+
+type metadataColumn struct {
+	Catalog  string `json:"catalog"`
+	Schema   string `json:"schema"`
+	Table    string `json:"table"`
+	Column   string `json:"column"`
+	Ordinal  int    `json:"ordinal"` // 1-based
+	TypeName string `json:"type_name"`
+	JDBCType int    `json:"jdbc_type"`
+	ODBCType int    `json:"odbc_type"`
+	Nullable bool   `json:"nullable"`
+	// Partition is set for partition fields
+	Partition bool `json:"partition,omitempty"`
+	// Types holds the ion types of the values
+	Types []string `json:"types,omitempty"`
+}
+
+func (s *server) metadataCatalogsHandler(w http.ResponseWriter, r *http.Request) {
+	if _, err := s.getTenant(r.Context(), w, r); err != nil {
+		return
+	}
+	writeResultResponse(w, http.StatusOK, []map[string]string{{"catalog": metadataCatalog}})
+}
+
+func (s *server) metadataTypesHandler(w http.ResponseWriter, r *http.Request) {
+	if _, err := s.getTenant(r.Context(), w, r); err != nil {
+		return
+	}
+	writeResultResponse(w, http.StatusOK, metadataTypes)
+}
+
+// metadataRoot returns the root of the tenant's storage
+// and the databases matching the schema pattern
+func (s *server) metadataRoot(w http.ResponseWriter, r *http.Request) (db.Tenant, fs.FS, []string, bool) {
+	creds, err := s.getTenant(r.Context(), w, r)
+	if err != nil {
+		return nil, nil, nil, false
+	}
+	q := r.URL.Query()
+	if c := q.Get("catalog"); c != "" && !matchPattern(metadataCatalog, c) {
+		return creds, nil, nil, true
+	}
+	root, err := creds.Root()
+	if err != nil {
+		s.logger.Printf("tenant %s: metadata: %s", creds.ID(), err)
+		http.Error(w, "bad tenant ID", http.StatusForbidden)
+		return nil, nil, nil, false
+	}
+	dbs, err := db.List(root)
+	if err != nil {
+		writeInternalServerResponse(w, err)
+		return nil, nil, nil, false
+	}
+	pattern := q.Get("schema")
+	out := dbs[:0]
+	for _, name := range dbs {
+		if matchPattern(name, pattern) {
+			out = append(out, name)
+		}
+	}
+	return creds, root, out, true
+}
+
+// metadataTables returns the tables in dbs matching
+// the table pattern in the request
+func metadataTables(w http.ResponseWriter, r *http.Request, root fs.FS, dbs []string) ([]metadataTable, bool) {
+	pattern := r.URL.Query().Get("table")
+	out := make([]metadataTable, 0)
+	for _, dbname := range dbs {
+		tables, err := db.Tables(root, dbname)
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+			http.Error(w, fmt.Sprintf("cannot list tables: %s", err), http.StatusInternalServerError)
+			return nil, false
+		}
+		for _, table := range tables {
+			if matchPattern(table, pattern) {
+				out = append(out, metadataTable{
+					Catalog: metadataCatalog,
+					Schema:  dbname,
+					Table:   table,
+					Type:    "TABLE",
+				})
+			}
+		}
+	}
+	return out, true
+}
+
+func (s *server) metadataSchemasHandler(w http.ResponseWriter, r *http.Request) {
+	_, _, dbs, ok := s.metadataRoot(w, r)
+	if !ok {
+		return
+	}
+	out := make([]metadataSchema, len(dbs))
+	for i := range dbs {
+		out[i] = metadataSchema{Catalog: metadataCatalog, Schema: dbs[i]}
+	}
+	writeResultResponse(w, http.StatusOK, out)
+}
+
+func (s *server) metadataTablesHandler(w http.ResponseWriter, r *http.Request) {
+	_, root, dbs, ok := s.metadataRoot(w, r)
+	if !ok {
+		return
+	}
+	out, ok := metadataTables(w, r, root, dbs)
+	if !ok {
+		return
+	}
+	writeResultResponse(w, http.StatusOK, out)
+}
+
+// metadataColumnsHandler returns the columns of the
+// tables matching the request; the columns of each table
+// are read from the schema recorded in the table index
+func (s *server) metadataColumnsHandler(w http.ResponseWriter, r *http.Request) {
+	creds, root, dbs, ok := s.metadataRoot(w, r)
+	if !ok {
+		return
+	}
+	tables, ok := metadataTables(w, r, root, dbs)
+	if !ok {
+		return
+	}
+	pattern := r.URL.Query().Get("column")
+	out := make([]metadataColumn, 0)
+	for i := range tables {
+		cols, err := tableColumns(creds, root, tables[i].Schema, tables[i].Table)
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				// tables without an index have no columns yet
+				continue
+			}
+			s.logger.Printf("tenant %s: metadata for %s/%s: %s", creds.ID(), tables[i].Schema, tables[i].Table, err)
+			http.Error(w, fmt.Sprintf("cannot determine columns of %s/%s", tables[i].Schema, tables[i].Table), http.StatusInternalServerError)
+			return
+		}
+		for j := range cols {
+			if matchPattern(cols[j].Column, pattern) {
+				out = append(out, cols[j])
+			}
+		}
+	}
+	writeResultResponse(w, http.StatusOK, out)
+}
+
+// tableColumns returns the columns of dbname.table
+func tableColumns(creds db.Tenant, root fs.FS, dbname, table string) ([]metadataColumn, error) {
+	idx, err := db.OpenPartialIndex(root, dbname, table, creds.Key())
+	if err != nil {
+		return nil, err
+	}
+	def, err := db.OpenDefinition(root, dbname, table)
+	if err != nil {
+		return nil, err
+	}
+	cols := schemaColumns(idx.Schema(), def)
+	for i := range cols {
+		cols[i].Catalog = metadataCatalog
+		cols[i].Schema = dbname
+		cols[i].Table = table
+		cols[i].Ordinal = i + 1
+	}
+	return cols, nil
+}
+
+// schemaColumns converts a table schema into
+// columns; nested fields are reported with dotted
+// names, and the partition fields are always included
+// (schema may be nil if the index predates schemas)
+func schemaColumns(schema *blockfmt.Schema, def *db.Definition) []metadataColumn {
+	var cols []metadataColumn
+	var walk func(prefix string, fields map[string]*blockfmt.SchemaField)
+	walk = func(prefix string, fields map[string]*blockfmt.SchemaField) {
+		for name, f := range fields {
+			cols = append(cols, schemaColumn(prefix+name, f.Types))
+			walk(prefix+name+".", f.Fields)
+		}
+	}
+	if schema != nil {
+		walk("", schema.Fields)
+	}
+	sort.Slice(cols, func(i, j int) bool {
+		return cols[i].Column < cols[j].Column
+	})
+	for i := range def.Partitions {
+		part := &def.Partitions[i]
+		j := sort.Search(len(cols), func(j int) bool {
+			return cols[j].Column >= part.Field
+		})
+		if j < len(cols) && cols[j].Column == part.Field {
+			cols[j].Partition = true
+			continue
+		}
+		// partition fields are not stored
+		// in the rows, so fill in the type
+		// from the definition
+		var it string
+		switch part.Type {
+		case "", "string":
+			it = "string"
+		case "int":
+			it = "int"
+		case "date", "datetime", "timestamp":
+			it = "timestamp"
+		}
+		t := metadataTypeOf([]string{it})
+		cols = append(cols, metadataColumn{
+			Column:    part.Field,
+			TypeName:  t.Name,
+			JDBCType:  t.JDBCType,
+			ODBCType:  t.ODBCType,
+			Partition: true,
+			Types:     []string{it},
+		})
+	}
+	return cols
+}
+
+// ionTypes maps the types of a schema
+// field to the names of ion types
+var ionTypes = []struct {
+	name string
+	set  expr.TypeSet
+}{
+	{"bool", expr.BoolType},
+	{"int", expr.IntegerType},
+	{"float", expr.FloatType},
+	{"decimal", expr.DecimalType},
+	{"timestamp", expr.TimeType},
+	{"string", expr.StringType | expr.SymbolType},
+	{"list", expr.ListType},
+	{"struct", expr.StructType},
+}
+
+func schemaColumn(path string, ts expr.TypeSet) metadataColumn {
+	col := metadataColumn{
+		Column: path,
+		// missing in some rows or null in some rows
+		Nullable: ts.AnyOf(expr.NullType | expr.MissingType),
+	}
+	for i := range ionTypes {
+		if ts.AnyOf(ionTypes[i].set) {
+			col.Types = append(col.Types, ionTypes[i].name)
+		}
+	}
+	sort.Strings(col.Types)
+	t := metadataTypeOf(col.Types)
+	col.TypeName = t.Name
+	col.JDBCType = t.JDBCType
+	col.ODBCType = t.ODBCType
+	return col
+}
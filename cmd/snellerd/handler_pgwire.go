@@ -0,0 +1,972 @@
+// Copyright (C) 2022 Sneller, Inc.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"math"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/SnellerInc/sneller/db"
+	"github.com/SnellerInc/sneller/expr"
+	"github.com/SnellerInc/sneller/expr/partiql"
+	"github.com/SnellerInc/sneller/ion"
+	"github.com/SnellerInc/sneller/tenant"
+)
+
+// pgServerVersion is the server_version
+// reported to PostgreSQL clients; drivers
+// parse it to determine which features
+// they can use
+const pgServerVersion = "14.0"
+
+// servePG accepts PostgreSQL protocol
+// connections on l until l is closed
+func (s *server) servePG(l net.Listener) error {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return err
+		}
+		go s.pgServeConn(conn)
+	}
+}
+
+// pgsession is the state of one
+// PostgreSQL protocol connection
+type pgsession struct {
+	s        *server
+	c        *pgconn
+	ctx      context.Context
+	creds    db.Tenant
+	database string
+	params   map[string]string
+
+	// set once the connection is encrypted
+	tls bool
+
+	stmts   map[string]*pgstmt
+	portals map[string]*pgportal
+	// set after an error in an extended query;
+	// messages are ignored until the next Sync
+	failed bool
+}
+
+// pgstmt is a prepared statement
+type pgstmt struct {
+	query string
+	res   *pgresult // result computed by Describe, if any
+}
+
+// pgportal is a bound prepared statement
+type pgportal struct {
+	stmt    *pgstmt
+	res     *pgresult
+	formats []int // result column formats
+}
+
+func (s *server) pgServeConn(conn net.Conn) {
+	defer conn.Close()
+	host, _, err := net.SplitHostPort(conn.RemoteAddr().String())
+	if err != nil {
+		host = conn.RemoteAddr().String()
+	}
+	s.logger.Printf("postgres connection from %s", host)
+	c := newPGConn(conn)
+	if s.ipLimit.conf.enabled() {
+		if rej := s.ipLimit.acquire(host, time.Now()); rej != nil {
+			s.logger.Printf("rate limiting %s: %s", host, rej.limit)
+			c.errorResponse("53300", "rate limit exceeded: "+rej.limit+" per client")
+			c.flush()
+			return
+		}
+		defer s.ipLimit.release(host)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	p := &pgsession{
+		s:       s,
+		c:       c,
+		ctx:     ctx,
+		stmts:   make(map[string]*pgstmt),
+		portals: make(map[string]*pgportal),
+	}
+	err = p.startup()
+	if err == nil {
+		err = p.serve()
+	}
+	// stop any queries whose
+	// results were never sent
+	for name := range p.stmts {
+		p.closeStmt(name)
+	}
+	for name := range p.portals {
+		p.closePortal(name)
+	}
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, net.ErrClosed) {
+		s.logger.Printf("postgres connection from %s: %s", host, err)
+	}
+}
+
+// startup handles the startup message
+// and authenticates the client; the password
+// sent by the client is the bearer token
+// that would be used with the HTTP API
+func (p *pgsession) startup() error {
+	for {
+		code, body, err := p.c.startup()
+		if err != nil {
+			return err
+		}
+		switch code {
+		case pgSSLRequest:
+			if p.s.pgTLS == nil || p.tls {
+				// the client may continue unencrypted
+				// (and will be refused below unless
+				// that is permitted)
+				if err := p.decline(); err != nil {
+					return err
+				}
+				continue
+			}
+			if _, err := p.c.w.Write([]byte{'S'}); err != nil {
+				return err
+			}
+			if err := p.c.flush(); err != nil {
+				return err
+			}
+			conn := tls.Server(p.c.conn, p.s.pgTLS)
+			if err := conn.HandshakeContext(p.ctx); err != nil {
+				return err
+			}
+			p.c = newPGConn(conn)
+			p.tls = true
+			continue
+		case pgGSSENCRequest:
+			// GSSAPI encryption is not supported
+			if err := p.decline(); err != nil {
+				return err
+			}
+			continue
+		case pgCancelRequest:
+			// queries are canceled when
+			// their connection is closed
+			return io.EOF
+		case pgProtocolVersion:
+		default:
+			p.c.errorResponse("08P01", fmt.Sprintf("unsupported protocol version %d.%d", code>>16, code&0xffff))
+			p.c.flush()
+			return fmt.Errorf("unsupported protocol version %x", code)
+		}
+		p.params = make(map[string]string)
+		r := pgreader{buf: body}
+		for {
+			name := r.string()
+			if name == "" || r.err != nil {
+				break
+			}
+			p.params[name] = r.string()
+		}
+		if r.err != nil {
+			return r.err
+		}
+		break
+	}
+	p.database = p.params["database"]
+
+	// the password is a bearer token,
+	// so it is never sent in the clear
+	// unless that is explicitly permitted
+	if !p.tls && !p.s.pgInsecure {
+		p.c.errorResponse("28000", "SSL is required")
+		p.c.flush()
+		return errors.New("refusing unencrypted connection")
+	}
+	if err := p.c.authRequest(3); err != nil { // cleartext password
+		return err
+	}
+	if err := p.c.flush(); err != nil {
+		return err
+	}
+	typ, body, err := p.c.read()
+	if err != nil {
+		return err
+	}
+	if typ != 'p' {
+		return fmt.Errorf("expected a password message; got %q", typ)
+	}
+	r := pgreader{buf: body}
+	token := r.string()
+	if r.err != nil {
+		return r.err
+	}
+	p.creds, err = p.s.auth.Authorize(p.ctx, token)
+	if err != nil {
+		p.c.errorResponse("28P01", "authentication failed")
+		p.c.flush()
+		return err
+	}
+
+	p.c.authRequest(0) // ok
+	for _, kv := range [][2]string{
+		{"server_version", pgServerVersion},
+		{"server_encoding", "UTF8"},
+		{"client_encoding", "UTF8"},
+		{"DateStyle", "ISO, MDY"},
+		{"TimeZone", "UTC"},
+		{"integer_datetimes", "on"},
+		{"standard_conforming_strings", "on"},
+		{"application_name", p.params["application_name"]},
+	} {
+		p.c.parameterStatus(kv[0], kv[1])
+	}
+	// cancellation is not supported,
+	// but some clients expect BackendKeyData
+	var secret [8]byte
+	rand.Read(secret[:])
+	p.c.begin('K')
+	p.c.bytes(secret[:])
+	p.c.end()
+	p.c.readyForQuery()
+	return p.c.flush()
+}
+
+// decline declines an encryption request
+func (p *pgsession) decline() error {
+	if _, err := p.c.w.Write([]byte{'N'}); err != nil {
+		return err
+	}
+	return p.c.flush()
+}
+
+// serve handles messages until the
+// client terminates the connection
+func (p *pgsession) serve() error {
+	for {
+		typ, body, err := p.c.read()
+		if err != nil {
+			return err
+		}
+		r := pgreader{buf: body}
+		switch typ {
+		case 'Q':
+			query := r.string()
+			if r.err != nil {
+				return r.err
+			}
+			p.simpleQuery(query)
+			p.c.readyForQuery()
+		case 'P', 'B', 'D', 'E', 'C':
+			if p.failed {
+				continue
+			}
+			if err := p.extended(typ, &r); err != nil {
+				p.fail(err)
+				p.failed = true
+			}
+			continue
+		case 'S':
+			p.failed = false
+			p.c.readyForQuery()
+		case 'H':
+		case 'X':
+			return nil
+		default:
+			p.c.errorResponse("08P01", fmt.Sprintf("unsupported message type %q", typ))
+			p.c.flush()
+			return fmt.Errorf("unsupported message type %q", typ)
+		}
+		if err := p.c.flush(); err != nil {
+			return err
+		}
+	}
+}
+
+func (p *pgsession) simpleQuery(query string) {
+	if strings.TrimSpace(query) == "" {
+		p.c.message('I') // EmptyQueryResponse
+		return
+	}
+	res, err := p.run(query)
+	if err != nil {
+		p.fail(err)
+		return
+	}
+	formats := []int{pgFormatText}
+	if len(res.cols) > 0 {
+		p.c.rowDescription(res, formats)
+	}
+	p.c.dataRows(res, formats)
+}
+
+// extended handles the messages of
+// the extended query protocol
+func (p *pgsession) extended(typ byte, r *pgreader) error {
+	switch typ {
+	case 'P': // Parse
+		name := r.string()
+		query := r.string()
+		nparams := r.int16()
+		if r.err != nil {
+			return r.err
+		}
+		if nparams > 0 || strings.Contains(query, "$1") {
+			return errors.New("query parameters are not supported")
+		}
+		p.closeStmt(name)
+		p.stmts[name] = &pgstmt{query: query}
+		return p.c.message('1')
+	case 'B': // Bind
+		portal := r.string()
+		stmt, ok := p.stmts[r.string()]
+		n := r.int16()
+		for i := 0; i < n; i++ {
+			r.int16() // parameter formats
+		}
+		if n = r.int16(); n > 0 {
+			return errors.New("query parameters are not supported")
+		}
+		n = r.int16()
+		formats := make([]int, n)
+		for i := range formats {
+			formats[i] = r.int16()
+			if formats[i] != pgFormatText && formats[i] != pgFormatBinary {
+				return fmt.Errorf("unsupported result format %d", formats[i])
+			}
+		}
+		if r.err != nil {
+			return r.err
+		}
+		if !ok {
+			return errors.New("prepared statement does not exist")
+		}
+		// a result computed by Describe
+		// belongs to the first portal
+		p.closePortal(portal)
+		p.portals[portal] = &pgportal{stmt: stmt, res: stmt.res, formats: formats}
+		stmt.res = nil
+		return p.c.message('2')
+	case 'D': // Describe
+		kind := r.take(1)
+		name := r.string()
+		if r.err != nil {
+			return r.err
+		}
+		var res *pgresult
+		formats := []int{pgFormatText}
+		var err error
+		if kind[0] == 'S' {
+			stmt, ok := p.stmts[name]
+			if !ok {
+				return errors.New("prepared statement does not exist")
+			}
+			if stmt.res == nil {
+				stmt.res, err = p.run(stmt.query)
+				if err != nil {
+					return err
+				}
+			}
+			res = stmt.res
+			p.c.begin('t') // ParameterDescription
+			p.c.int16(0)
+			p.c.end()
+		} else {
+			portal, ok := p.portals[name]
+			if !ok {
+				return errors.New("portal does not exist")
+			}
+			if portal.res == nil {
+				portal.res, err = p.run(portal.stmt.query)
+				if err != nil {
+					return err
+				}
+			}
+			res, formats = portal.res, portal.formats
+		}
+		if len(res.cols) == 0 {
+			return p.c.message('n') // NoData
+		}
+		return p.c.rowDescription(res, formats)
+	case 'E': // Execute
+		name := r.string()
+		r.int32() // row limit; results are always sent in full
+		if r.err != nil {
+			return r.err
+		}
+		portal, ok := p.portals[name]
+		if !ok {
+			return errors.New("portal does not exist")
+		}
+		res := portal.res
+		portal.res = nil
+		if res == nil {
+			var err error
+			res, err = p.run(portal.stmt.query)
+			if err != nil {
+				return err
+			}
+		}
+		return p.c.dataRows(res, portal.formats)
+	case 'C': // Close
+		kind := r.take(1)
+		name := r.string()
+		if r.err != nil {
+			return r.err
+		}
+		if kind[0] == 'S' {
+			p.closeStmt(name)
+		} else {
+			p.closePortal(name)
+		}
+		return p.c.message('3')
+	}
+	return nil
+}
+
+// closeStmt removes a prepared statement
+// and abandons its result, if any
+func (p *pgsession) closeStmt(name string) {
+	if stmt, ok := p.stmts[name]; ok {
+		stmt.res.close()
+		delete(p.stmts, name)
+	}
+}
+
+// closePortal removes a portal
+// and abandons its result, if any
+func (p *pgsession) closePortal(name string) {
+	if portal, ok := p.portals[name]; ok {
+		portal.res.close()
+		delete(p.portals, name)
+	}
+}
+
+// fail sends an ErrorResponse describing err
+func (p *pgsession) fail(err error) {
+	code := "XX000" // internal_error
+	var syntax *expr.SyntaxError
+	var typ *expr.TypeError
+	var limit *errPlanLimit
+	var complexity *expr.LimitError
+	var perr *pgError
+	switch {
+	case errors.As(err, &perr):
+		code = perr.code
+	case errors.As(err, &syntax):
+		code = "42601" // syntax_error
+	case errors.As(err, &typ):
+		code = "42804" // datatype_mismatch
+	case errors.As(err, &limit), errors.As(err, &complexity):
+		code = "54000" // program_limit_exceeded
+	case errors.Is(err, fs.ErrNotExist):
+		code = "42P01" // undefined_table
+		err = errors.New("table does not exist")
+	case errors.Is(err, errTenantDisallowed):
+		code = "42501" // insufficient_privilege
+	case errors.Is(err, errPGMessage):
+		code = "08P01" // protocol_violation
+	}
+	p.c.errorResponse(code, err.Error())
+}
+
+// pgError is an error with a SQLSTATE code
+type pgError struct {
+	code, msg string
+}
+
+func (e *pgError) Error() string { return e.msg }
+
+// pgCommand returns the command tag for the
+// session commands that are accepted (and ignored)
+// for compatibility with clients and drivers
+func pgCommand(query string) (string, bool) {
+	word, _, _ := strings.Cut(strings.TrimSpace(query), " ")
+	word = strings.ToUpper(strings.TrimSuffix(word, ";"))
+	switch word {
+	case "SET", "RESET", "BEGIN", "START", "COMMIT", "END", "ROLLBACK", "DISCARD", "DEALLOCATE":
+		return word, true
+	}
+	return "", false
+}
+
+// run executes query on behalf of the client
+func (p *pgsession) run(query string) (*pgresult, error) {
+	if tag, ok := pgCommand(query); ok {
+		return &pgresult{tag: tag}, nil
+	}
+	if res, ok := p.show(query); ok {
+		return res, nil
+	}
+	s := p.s
+	text := []byte(query)
+	if err := s.limits.CheckText(text); err != nil {
+		return nil, err
+	}
+	if partiql.IsDelete(text) {
+		return nil, &pgError{code: "0A000", msg: "DELETE is not supported over the postgres protocol"}
+	}
+	if partiql.IsDDL(text) {
+		return nil, &pgError{code: "0A000", msg: "CREATE, ALTER, and DROP are not supported over the postgres protocol"}
+	}
+	tenantID := p.creds.ID()
+	if s.tenantLimit.conf.enabled() {
+		if rej := s.tenantLimit.acquire(tenantID, time.Now()); rej != nil {
+			s.logger.Printf("rate limiting tenant %s: %s", tenantID, rej.limit)
+			return nil, &pgError{code: "53300", msg: "rate limit exceeded: " + rej.limit + " per tenant"}
+		}
+		defer s.tenantLimit.release(tenantID)
+	}
+	parsed, err := partiql.ParseDialect(text, partiql.PostgresDialect)
+	if err != nil {
+		return nil, &pgError{code: "42601", msg: err.Error()} // syntax_error
+	}
+	if err := s.limits.Check(parsed); err != nil {
+		return nil, err
+	}
+	res := newPGResult()
+	go func() {
+		res.done <- s.runQuery(p.creds, p.database, parsed, res.send)
+		close(res.next)
+	}()
+	err = res.start()
+	if err != nil {
+		if errors.Is(err, tenant.ErrOverloaded) {
+			return nil, &pgError{code: "53300", msg: err.Error()}
+		}
+		return nil, err
+	}
+	return res, nil
+}
+
+// show handles SHOW statements by returning
+// the value of a run-time parameter
+func (p *pgsession) show(query string) (*pgresult, bool) {
+	word, rest, _ := strings.Cut(strings.TrimSpace(query), " ")
+	if !strings.EqualFold(word, "SHOW") {
+		return nil, false
+	}
+	name := strings.ToLower(strings.TrimSuffix(strings.TrimSpace(rest), ";"))
+	value := p.params[name]
+	switch name {
+	case "server_version":
+		value = pgServerVersion
+	case "server_encoding", "client_encoding":
+		value = "UTF8"
+	case "timezone":
+		value = "UTC"
+	case "transaction isolation level":
+		value = "read committed"
+	}
+	return &pgresult{
+		cols: []pgcolumn{{name: name, oid: pgText}},
+		rows: [][]ion.Datum{{ion.String(value)}},
+		tag:  "SHOW",
+	}, true
+}
+
+// pgcolumn describes a result column
+type pgcolumn struct {
+	name string
+	oid  int
+}
+
+// pgPreviewRows is the number of rows of a query
+// result that are buffered to determine the columns
+// of the result before any rows are sent
+const pgPreviewRows = 100
+
+var errPGAbandoned = errors.New("query result abandoned")
+
+// pgresult is a query result with the rows of
+// the query output arranged into columns
+//
+// Since rows may have different fields, the
+// columns are the union of the fields of the
+// first pgPreviewRows rows. The remaining rows
+// are sent to the client as they arrive from
+// the query, so they must fit those columns.
+type pgresult struct {
+	cols []pgcolumn
+	rows [][]ion.Datum // rows[i][j] is empty if the field is missing
+	tag  string
+
+	index map[string]int // column index by name
+
+	// if next is non-nil, it carries the
+	// rows produced by the query, and the
+	// outcome of the query is sent on done
+	// once next is closed
+	next chan ion.Datum
+	done chan error
+	stop chan struct{}
+	// set once next has been drained
+	// or the result has been abandoned
+	ended bool
+}
+
+func newPGResult() *pgresult {
+	return &pgresult{
+		index: make(map[string]int),
+		next:  make(chan ion.Datum),
+		done:  make(chan error, 1),
+		stop:  make(chan struct{}),
+	}
+}
+
+// send passes a value from the query
+// output to the reader of the result
+func (r *pgresult) send(d ion.Datum) error {
+	select {
+	case r.next <- d:
+		return nil
+	case <-r.stop:
+		return errPGAbandoned
+	}
+}
+
+// start buffers the first rows of the result
+// and determines the columns from them
+func (r *pgresult) start() error {
+	for len(r.rows) < pgPreviewRows {
+		d, ok := <-r.next
+		if !ok {
+			r.ended = true
+			if err := <-r.done; err != nil {
+				return err
+			}
+			break
+		}
+		if err := r.add(d); err != nil {
+			r.close()
+			return err
+		}
+	}
+	for i := range r.cols {
+		r.cols[i].oid = r.coltype(i)
+	}
+	return nil
+}
+
+// close abandons the rest of the result
+func (r *pgresult) close() {
+	if r != nil && r.next != nil && !r.ended {
+		close(r.stop)
+		r.ended = true
+	}
+}
+
+// add adds a value from the query output
+// to the buffered rows; values other than
+// structures (annotations, for example) are ignored
+func (r *pgresult) add(d ion.Datum) error {
+	if !d.IsStruct() {
+		return nil
+	}
+	var row []ion.Datum
+	err := d.UnpackStruct(func(f ion.Field) error {
+		i, ok := r.index[f.Label]
+		if !ok {
+			i = len(r.cols)
+			r.index[f.Label] = i
+			r.cols = append(r.cols, pgcolumn{name: f.Label})
+		}
+		for len(row) <= i {
+			row = append(row, ion.Datum{})
+		}
+		row[i] = f.Datum
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	r.rows = append(r.rows, row)
+	return nil
+}
+
+// row arranges a value that arrives after the
+// columns have been described into a row
+func (r *pgresult) row(d ion.Datum) ([]ion.Datum, error) {
+	row := make([]ion.Datum, len(r.cols))
+	err := d.UnpackStruct(func(f ion.Field) error {
+		i, ok := r.index[f.Label]
+		if !ok {
+			return &pgError{code: "42703", msg: fmt.Sprintf("field %q is not in the first %d rows of the result", f.Label, pgPreviewRows)}
+		}
+		if !f.IsNull() && r.cols[i].oid != pgText && !fits(r.cols[i].oid, pgtype(f.Datum)) {
+			return &pgError{code: "42804", msg: fmt.Sprintf("field %q has values of more than one type", f.Label)}
+		}
+		row[i] = f.Datum
+		return nil
+	})
+	return row, err
+}
+
+// fits returns whether a value of type t
+// can be sent in a column of type oid
+func fits(oid, t int) bool {
+	return oid == t || (oid == pgFloat8 && t == pgInt8)
+}
+
+// coltype determines the type of column i
+// from the values in the column; columns with
+// values of more than one type are text
+func (r *pgresult) coltype(i int) int {
+	oid := 0
+	for _, row := range r.rows {
+		if i >= len(row) || row[i].IsEmpty() || row[i].IsNull() {
+			continue
+		}
+		t := pgtype(row[i])
+		switch {
+		case oid == 0 || oid == t:
+			oid = t
+		case oid == pgInt8 && t == pgFloat8, oid == pgFloat8 && t == pgInt8:
+			oid = pgFloat8
+		default:
+			return pgText
+		}
+	}
+	if oid == 0 {
+		return pgText
+	}
+	return oid
+}
+
+func pgtype(d ion.Datum) int {
+	switch d.Type() {
+	case ion.BoolType:
+		return pgBool
+	case ion.IntType:
+		return pgInt8
+	case ion.UintType:
+		if u, _ := d.Uint(); u > math.MaxInt64 {
+			return pgFloat8
+		}
+		return pgInt8
+	case ion.FloatType:
+		return pgFloat8
+	case ion.TimestampType:
+		return pgTimestamptz
+	case ion.BlobType:
+		return pgBytea
+	case ion.StructType, ion.ListType:
+		return pgJSON
+	default:
+		return pgText
+	}
+}
+
+// rowDescription describes the columns of res
+func (c *pgconn) rowDescription(res *pgresult, formats []int) error {
+	c.begin('T')
+	c.int16(len(res.cols))
+	for i := range res.cols {
+		c.string(res.cols[i].name)
+		c.int32(0) // table OID
+		c.int16(0) // column number
+		c.int32(res.cols[i].oid)
+		size := -1
+		switch res.cols[i].oid {
+		case pgBool:
+			size = 1
+		case pgInt8, pgFloat8, pgTimestamptz:
+			size = 8
+		}
+		c.int16(size)
+		c.int32(-1) // type modifier
+		c.int16(format(formats, i))
+	}
+	return c.end()
+}
+
+// format returns the format of column i;
+// a single format applies to all columns
+func format(formats []int, i int) int {
+	switch len(formats) {
+	case 0:
+		return pgFormatText
+	case 1:
+		return formats[0]
+	}
+	if i < len(formats) {
+		return formats[i]
+	}
+	return pgFormatText
+}
+
+// dataRows sends the rows of res
+// followed by CommandComplete
+func (c *pgconn) dataRows(res *pgresult, formats []int) error {
+	defer res.close()
+	n := 0
+	for _, row := range res.rows {
+		if err := c.dataRow(res, row, formats); err != nil {
+			return err
+		}
+		n++
+	}
+	res.rows = nil
+	if res.next != nil && !res.ended {
+		for d := range res.next {
+			if !d.IsStruct() {
+				continue
+			}
+			row, err := res.row(d)
+			if err == nil {
+				err = c.dataRow(res, row, formats)
+			}
+			if err != nil {
+				return err
+			}
+			n++
+		}
+		res.ended = true
+		if err := <-res.done; err != nil {
+			return err
+		}
+	}
+	tag := res.tag
+	if tag == "" {
+		tag = "SELECT " + strconv.Itoa(n)
+	}
+	return c.commandComplete(tag)
+}
+
+// dataRow sends one row of res
+func (c *pgconn) dataRow(res *pgresult, row []ion.Datum, formats []int) error {
+	c.begin('D')
+	c.int16(len(res.cols))
+	for i := range res.cols {
+		if i >= len(row) || row[i].IsEmpty() || row[i].IsNull() {
+			c.int32(-1)
+			continue
+		}
+		pos := len(c.out)
+		c.int32(0)
+		if format(formats, i) == pgFormatBinary {
+			c.out = appendBinary(c.out, res.cols[i].oid, row[i])
+		} else {
+			c.out = appendText(c.out, res.cols[i].oid, row[i])
+		}
+		binary.BigEndian.PutUint32(c.out[pos:], uint32(len(c.out)-pos-4))
+	}
+	return c.end()
+}
+
+// appendText appends the text representation
+// of d as a value of the given type
+func appendText(dst []byte, oid int, d ion.Datum) []byte {
+	switch d.Type() {
+	case ion.BoolType:
+		b, _ := d.Bool()
+		if oid != pgBool {
+			return strconv.AppendBool(dst, b)
+		}
+		if b {
+			return append(dst, 't')
+		}
+		return append(dst, 'f')
+	case ion.IntType:
+		i, _ := d.Int()
+		return strconv.AppendInt(dst, i, 10)
+	case ion.UintType:
+		u, _ := d.Uint()
+		return strconv.AppendUint(dst, u, 10)
+	case ion.FloatType:
+		f, _ := d.Float()
+		switch {
+		case math.IsNaN(f):
+			return append(dst, "NaN"...)
+		case math.IsInf(f, 1):
+			return append(dst, "Infinity"...)
+		case math.IsInf(f, -1):
+			return append(dst, "-Infinity"...)
+		}
+		return strconv.AppendFloat(dst, f, 'g', -1, 64)
+	case ion.StringType, ion.SymbolType:
+		s, _ := d.String()
+		return append(dst, s...)
+	case ion.TimestampType:
+		t, _ := d.Timestamp()
+		return t.Time().UTC().AppendFormat(dst, "2006-01-02 15:04:05.999999-07")
+	case ion.BlobType:
+		b, _ := d.Blob()
+		dst = append(dst, `\x`...)
+		n := len(dst)
+		dst = append(dst, make([]byte, hex.EncodedLen(len(b)))...)
+		hex.Encode(dst[n:], b)
+		return dst
+	default:
+		return append(dst, d.JSON()...)
+	}
+}
+
+// pgEpoch is the epoch of binary timestamps
+var pgEpoch = time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC).UnixMicro()
+
+// appendBinary appends the binary representation
+// of d as a value of the given type
+func appendBinary(dst []byte, oid int, d ion.Datum) []byte {
+	switch oid {
+	case pgBool:
+		b, _ := d.Bool()
+		if b {
+			return append(dst, 1)
+		}
+		return append(dst, 0)
+	case pgInt8:
+		i, err := d.Int()
+		if err != nil {
+			u, _ := d.Uint()
+			i = int64(u)
+		}
+		return binary.BigEndian.AppendUint64(dst, uint64(i))
+	case pgFloat8:
+		f, err := d.Float()
+		if err != nil {
+			if i, err := d.Int(); err == nil {
+				f = float64(i)
+			} else {
+				u, _ := d.Uint()
+				f = float64(u)
+			}
+		}
+		return binary.BigEndian.AppendUint64(dst, math.Float64bits(f))
+	case pgTimestamptz:
+		t, _ := d.Timestamp()
+		return binary.BigEndian.AppendUint64(dst, uint64(t.UnixMicro()-pgEpoch))
+	case pgBytea:
+		b, _ := d.BlobShared()
+		return append(dst, b...)
+	default:
+		// text and json are the same
+		// in text and binary formats
+		return appendText(dst, oid, d)
+	}
+}
@@ -0,0 +1,95 @@
+// Copyright (C) 2022 Sneller, Inc.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/SnellerInc/sneller/repro"
+)
+
+// maxRepros is the number of repro
+// bundles retained by a reproStore
+const maxRepros = 64
+
+type storedRepro struct {
+	tenant string
+	id     string
+	bundle *repro.Bundle
+}
+
+// reproStore retains repro bundles for
+// the most recent failed queries so that
+// they can be fetched from /repro/<query ID>
+type reproStore struct {
+	lock sync.Mutex
+	ring []storedRepro
+	next int
+}
+
+// add stores the bundle for the query id,
+// evicting the oldest bundle if necessary
+func (r *reproStore) add(tenant, id string, b *repro.Bundle) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	s := storedRepro{tenant: tenant, id: id, bundle: b}
+	if len(r.ring) < maxRepros {
+		r.ring = append(r.ring, s)
+		return
+	}
+	r.ring[r.next] = s
+	r.next = (r.next + 1) % maxRepros
+}
+
+// get returns the bundle for the query id
+// if it was produced on behalf of tenant
+func (r *reproStore) get(tenant, id string) *repro.Bundle {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	for i := range r.ring {
+		if r.ring[i].id == id && r.ring[i].tenant == tenant {
+			return r.ring[i].bundle
+		}
+	}
+	return nil
+}
+
+// reproHandler serves the repro bundle for a
+// failed query as a tar archive; the query ID
+// is the one from the X-Sneller-Query-ID header
+func (s *server) reproHandler(w http.ResponseWriter, r *http.Request) {
+	creds, err := s.getTenant(r.Context(), w, r)
+	if err != nil {
+		return
+	}
+	id := strings.TrimPrefix(r.URL.Path, "/repro/")
+	if id == "" || strings.Contains(id, "/") {
+		http.Error(w, "invalid query ID", http.StatusBadRequest)
+		return
+	}
+	b := s.repros.get(creds.ID(), id)
+	if b == nil {
+		http.Error(w, "no repro bundle for query "+id, http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/x-tar")
+	w.Header().Set("Content-Disposition", `attachment; filename="repro-`+id+`.tar"`)
+	w.WriteHeader(http.StatusOK)
+	if _, err := b.WriteTo(w); err != nil {
+		s.logger.Printf("writing repro bundle for query ID %s: %s", id, err)
+	}
+}
@@ -15,6 +15,7 @@
 package main
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io/fs"
@@ -63,3 +64,109 @@ func (s *server) tablesHandler(w http.ResponseWriter, r *http.Request) {
 	}
 	writeResultResponse(w, http.StatusOK, out)
 }
+
+// tableHandler handles requests to /tables/{db}/{table}:
+//
+//   - GET returns the table definition
+//   - PUT creates the table from the definition in the request body
+//   - PATCH alters the table according to the db.Alteration in the request body
+//   - DELETE drops the table and all of its data
+func (s *server) tableHandler(w http.ResponseWriter, r *http.Request) {
+	tenant, err := s.getTenant(r.Context(), w, r)
+	if err != nil {
+		return
+	}
+	dbname, table, ok := tableTarget("/tables/", r.URL.Path)
+	if !ok {
+		http.Error(w, "expected /tables/{db}/{table}", http.StatusBadRequest)
+		return
+	}
+	root, err := tenant.Root()
+	if err != nil {
+		http.Error(w, "couldn't open db+table", http.StatusInternalServerError)
+		return
+	}
+	if r.Method == http.MethodGet {
+		def, err := db.OpenDefinition(root, dbname, table)
+		if err != nil {
+			tableError(w, err)
+			return
+		}
+		writeResultResponse(w, http.StatusOK, def)
+		return
+	}
+	ofs, ok := root.(db.OutputFS)
+	if !ok {
+		http.Error(w, "tenant storage is read-only", http.StatusForbidden)
+		return
+	}
+	body := http.MaxBytesReader(w, r.Body, maxDefinitionSize)
+	switch r.Method {
+	case http.MethodPut:
+		def, err := db.DecodeDefinition(body)
+		if err != nil {
+			http.Error(w, "decoding definition: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		if def.Name == "" {
+			def.Name = table
+		} else if def.Name != table {
+			http.Error(w, fmt.Sprintf("definition name %q doesn't match %q", def.Name, table), http.StatusBadRequest)
+			return
+		}
+		err = db.CreateTable(ofs, dbname, def)
+		if err != nil {
+			if errors.Is(err, db.ErrTableExists) {
+				http.Error(w, err.Error(), http.StatusConflict)
+				return
+			}
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		s.logger.Printf("created table %s/%s", dbname, table)
+		writeResultResponse(w, http.StatusCreated, def)
+	case http.MethodPatch:
+		var alt db.Alteration
+		dec := json.NewDecoder(body)
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&alt); err != nil {
+			http.Error(w, "decoding alteration: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		def, err := db.AlterTable(ofs, dbname, table, &alt)
+		if err != nil {
+			tableError(w, err)
+			return
+		}
+		s.logger.Printf("altered table %s/%s", dbname, table)
+		writeResultResponse(w, http.StatusOK, def)
+	case http.MethodDelete:
+		_, err := db.OpenDefinition(root, dbname, table)
+		if err != nil {
+			tableError(w, err)
+			return
+		}
+		err = db.DropTable(ofs, dbname, table)
+		if err != nil {
+			s.logger.Printf("dropping %s/%s: %s", dbname, table, err)
+			http.Error(w, "drop failed: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		s.logger.Printf("dropped table %s/%s", dbname, table)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// maxDefinitionSize is the largest request
+// body accepted by the /tables/{db}/{table} endpoint
+const maxDefinitionSize = 1024 * 1024
+
+// tableError writes the response for an error
+// opening or altering a table definition
+func tableError(w http.ResponseWriter, err error) {
+	if errors.Is(err, fs.ErrNotExist) {
+		http.Error(w, "no such table", http.StatusNotFound)
+		return
+	}
+	http.Error(w, err.Error(), http.StatusBadRequest)
+}
@@ -50,14 +50,24 @@ func (s *server) handle(handler func(http.ResponseWriter, *http.Request), method
 		w.Header().Set("Access-Control-Allow-Credentials", "true")
 		w.Header().Set("Access-Control-Allow-Headers", "Accept, Authorization")
 		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST")
-		w.Header().Set("Access-Control-Expose-Headers", "Etag, X-Sneller-Max-Scanned-Bytes, X-Sneller-Query-ID, X-Sneller-Total-Table-Bytes, X-Sneller-Version")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE")
+		w.Header().Set("Access-Control-Expose-Headers", "Etag, Retry-After, X-Sneller-Max-Scanned-Bytes, X-Sneller-Query-ID, X-Sneller-Rate-Limit, X-Sneller-Total-Table-Bytes, X-Sneller-Version, X-Sneller-Warning")
 		if r.Method == http.MethodOptions {
 			w.WriteHeader(http.StatusOK)
 			return
 		}
 		for _, httpMethod := range methods {
 			if r.Method == httpMethod {
+				if r.URL.Path == "/" && !forwarded {
+					// don't rate-limit heartbeats
+					handler(w, r)
+					return
+				}
+				r, done := s.limitIP(w, r, remoteAddress)
+				if r == nil {
+					return
+				}
+				defer done()
 				handler(w, r)
 				return
 			}
@@ -88,6 +98,9 @@ func (s *server) getTenant(ctx context.Context, w http.ResponseWriter, r *http.R
 		w.Write([]byte(err.Error())) // TODO: we might want to remove this in production
 		return nil, err
 	}
+	if !s.limitTenant(w, r, creds.ID()) {
+		return nil, errors.New("rate limit exceeded")
+	}
 	return creds, nil
 }
 
@@ -0,0 +1,237 @@
+// Copyright (C) 2022 Sneller, Inc.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/SnellerInc/sneller/db"
+	"github.com/SnellerInc/sneller/ion/blockfmt"
+)
+
+func TestIngest(t *testing.T) {
+	testFiles(t)
+	s := empty(t)
+	// DELETE evaluates its predicate in the tenant
+	s.tenantcmd = []string{"./snellerd-test-binary", "worker"}
+
+	httpsock := listen(t)
+	go s.Serve(httpsock, nil)
+	host := "http://" + httpsock.Addr().String()
+
+	post := func(uri, ctype, body string) *http.Response {
+		req, err := http.NewRequest(http.MethodPost, host+uri, strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Authorization", "Bearer snellerd-test")
+		if ctype != "" {
+			req.Header.Set("Content-Type", ctype)
+		}
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { res.Body.Close() })
+		return res
+	}
+
+	bad := []struct {
+		uri, ctype, body string
+		code             int
+	}{
+		{"/ingest/default", "", `{"x": 1}`, http.StatusBadRequest},
+		{"/ingest/default/nonexistent", "", `{"x": 1}`, http.StatusNotFound},
+		{"/ingest/default/parking2", "text/csv", `x,y`, http.StatusUnsupportedMediaType},
+		{"/ingest/default/parking2", "", ``, http.StatusBadRequest},
+		{"/ingest/default/parking2", "", `{"x": `, http.StatusBadRequest},
+		{"/ingest/default/parking2", "application/ion", "\xe0\x01\x00\xea\xd3\x8a\x8c", http.StatusBadRequest},
+	}
+	for i := range bad {
+		res := post(bad[i].uri, bad[i].ctype, bad[i].body)
+		if res.StatusCode != bad[i].code {
+			t.Errorf("POST %s: got status %d, want %d", bad[i].uri, res.StatusCode, bad[i].code)
+		}
+	}
+
+	// push concurrently so that some of the
+	// payloads are committed in the same batch
+	const pushes = 8
+	paths := make([]string, pushes)
+	var wg sync.WaitGroup
+	wg.Add(pushes)
+	for i := 0; i < pushes; i++ {
+		go func(i int) {
+			defer wg.Done()
+			res := post("/ingest/default/parking2", "application/x-ndjson", `{"pushed": true, "n": 1}`+"\n"+`{"pushed": true, "n": 2}`)
+			if res.StatusCode != http.StatusOK {
+				t.Errorf("push %d: status %s", i, res.Status)
+				return
+			}
+			var ret struct {
+				Path string `json:"path"`
+			}
+			if err := json.NewDecoder(res.Body).Decode(&ret); err != nil {
+				t.Error(err)
+			}
+			paths[i] = ret.Path
+		}(i)
+	}
+	wg.Wait()
+	if t.Failed() {
+		return
+	}
+
+	tenant, err := s.auth.Authorize(context.Background(), "snellerd-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	root, err := tenant.Root()
+	if err != nil {
+		t.Fatal(err)
+	}
+	idx, err := db.OpenIndex(root, "default", "parking2", tenant.Key())
+	if err != nil {
+		t.Fatal(err)
+	}
+	idx.Inputs.Backing = root.(db.OutputFS)
+	found := make(map[string]bool)
+	err = idx.Inputs.Walk("", func(path, etag string, id int) bool {
+		found[path] = id >= 0
+		return true
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range paths {
+		if !found[p] {
+			t.Errorf("input %s not accepted", p)
+		}
+	}
+
+	// delete the pushed rows again
+	del := func(query string) int64 {
+		res := post("/executeQuery?database=default", "", query)
+		if res.StatusCode != http.StatusOK {
+			t.Fatalf("%s: status %s", query, res.Status)
+		}
+		var ret struct {
+			Deleted int64 `json:"deleted"`
+		}
+		if err := json.NewDecoder(res.Body).Decode(&ret); err != nil {
+			t.Fatal(err)
+		}
+		return ret.Deleted
+	}
+	if n := del("DELETE FROM parking2 WHERE pushed AND n = 1"); n != pushes {
+		t.Errorf("deleted %d rows; expected %d", n, pushes)
+	}
+	if n := del("DELETE FROM default.parking2 WHERE pushed"); n != pushes {
+		t.Errorf("deleted %d rows; expected %d", n, pushes)
+	}
+	if n := del("DELETE FROM parking2 WHERE pushed"); n != 0 {
+		t.Errorf("deleted %d rows; expected none", n)
+	}
+	res := post("/executeQuery", "", "DELETE FROM nonexistent")
+	if res.StatusCode != http.StatusBadRequest {
+		t.Errorf("DELETE without a database: got status %d", res.StatusCode)
+	}
+}
+
+func TestIngestCompact(t *testing.T) {
+	testFiles(t)
+	s := empty(t)
+	httpsock := listen(t)
+	go s.Serve(httpsock, nil)
+	tenant, err := s.auth.Authorize(context.Background(), "snellerd-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	root, err := tenant.Root()
+	if err != nil {
+		t.Fatal(err)
+	}
+	push := func(in *ingester, n int) {
+		p := &pushed{
+			path:   pushPath("default", "parking2"),
+			body:   []byte(fmt.Sprintf(`{"pushed": true, "n": %d}`, n)),
+			format: blockfmt.MustSuffixToFormat(".json"),
+			done:   make(chan error, 1),
+		}
+		in.push(tenant, "default", "parking2", p)
+		if err := <-p.done; err != nil {
+			t.Fatal(err)
+		}
+	}
+	// stat returns the number of inline packfiles
+	// and the total number of rows in the table
+	stat := func() (int, int64) {
+		idx, err := db.OpenIndex(root, "default", "parking2", tenant.Key())
+		if err != nil {
+			t.Fatal(err)
+		}
+		descs, err := idx.Indirect.Search(root.(db.OutputFS), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var rows int64
+		for _, d := range append(descs, idx.Inline...) {
+			n, ok := d.Trailer.Rows()
+			if !ok {
+				t.Fatalf("%s: no row counts", d.Path)
+			}
+			rows += n
+		}
+		return len(idx.Inline), rows
+	}
+
+	// produce one packfile per push
+	in := &ingester{conf: db.Config{MinMergeSize: 1, Logf: t.Logf}}
+	for i := 0; i < 4; i++ {
+		push(in, i)
+	}
+	before, rows := stat()
+	if before < 4 {
+		t.Fatalf("expected at least 4 inline packfiles; got %d", before)
+	}
+
+	in = &ingester{
+		conf:            db.Config{Logf: t.Logf},
+		compactInterval: time.Hour,
+	}
+	push(in, 4)
+	in.compactwg.Wait()
+	after, total := stat()
+	if after >= before {
+		t.Fatalf("%d inline packfiles before compaction, %d after", before, after)
+	}
+	if total != rows+1 {
+		t.Fatalf("%d rows after compaction; expected %d", total, rows+1)
+	}
+	// within compactInterval, pushes do not
+	// start another compaction
+	push(in, 5)
+	in.compactwg.Wait()
+	if _, total := stat(); total != rows+2 {
+		t.Fatalf("%d rows after second push; expected %d", total, rows+2)
+	}
+}
@@ -20,8 +20,7 @@ import (
 	"strings"
 
 	"github.com/SnellerInc/sneller"
-
-	"golang.org/x/sys/cpu"
+	"github.com/SnellerInc/sneller/vm"
 )
 
 var version = "development"
@@ -31,8 +30,8 @@ var version = "development"
 var testmode = false
 
 func main() {
-	if !cpu.X86.HasAVX512 {
-		fmt.Fprintln(os.Stderr, "CPU doesn't support AVX-512")
+	if err := vm.Supported(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
 		os.Exit(1)
 	}
 
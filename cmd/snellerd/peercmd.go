@@ -15,14 +15,11 @@
 package main
 
 import (
-	"bytes"
 	"context"
-	"encoding/json"
-	"errors"
 	"fmt"
 	"net"
-	"os/exec"
 	"strconv"
+	"sync"
 	"sync/atomic"
 	"time"
 
@@ -34,32 +31,41 @@ const cmdTimeout = 30 * time.Second
 type peerlist interface {
 	Start(interval time.Duration, logf func(f string, args ...interface{})) error
 	Get() []*net.TCPAddr
+	// Watch registers fn to be called
+	// whenever the set of peers changes
+	Watch(fn func(peerChange))
 	Stop()
 }
 
+// peerChange describes a change
+// in cluster membership
+type peerChange struct {
+	Added, Removed []*net.TCPAddr
+}
+
 type noPeers struct{}
 
 func (n noPeers) Get() []*net.TCPAddr                                     { return nil }
 func (n noPeers) Start(time.Duration, func(string, ...interface{})) error { return nil }
+func (n noPeers) Watch(func(peerChange))                                  {}
 func (n noPeers) Stop()                                                   {}
 
-type peerCmd struct {
-	cmd    []string
+// peerWatcher is a peerlist that polls
+// a discovery source, discards peers that
+// do not respond to a ping, and notifies
+// watchers when the set of peers changes
+type peerWatcher struct {
+	src    discovery
 	recent atomic.Value
 	ticker *time.Ticker
 	logf   func(f string, args ...interface{})
 	stop   chan struct{}
-}
-
-type peerDesc struct {
-	Addr string `json:"addr"`
-}
 
-type peerJSON struct {
-	Peers []peerDesc `json:"peers"`
+	lock     sync.Mutex
+	watchers []func(peerChange)
 }
 
-func (p *peerCmd) Start(interval time.Duration, logf func(f string, args ...interface{})) error {
+func (p *peerWatcher) Start(interval time.Duration, logf func(f string, args ...interface{})) error {
 	p.logf = logf
 	err := p.run()
 	if err != nil {
@@ -83,51 +89,35 @@ func (p *peerCmd) Start(interval time.Duration, logf func(f string, args ...inte
 	return nil
 }
 
-func (p *peerCmd) Stop() {
+func (p *peerWatcher) Stop() {
 	p.ticker.Stop()
 	close(p.stop)
 }
 
-func (p *peerCmd) Get() []*net.TCPAddr {
-	return p.recent.Load().([]*net.TCPAddr)
+func (p *peerWatcher) Get() []*net.TCPAddr {
+	lst, _ := p.recent.Load().([]*net.TCPAddr)
+	return lst
+}
+
+func (p *peerWatcher) Watch(fn func(peerChange)) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.watchers = append(p.watchers, fn)
 }
 
-func (p *peerCmd) run() error {
+func (p *peerWatcher) run() error {
 	ctx, cancel := context.WithTimeout(context.Background(), cmdTimeout)
 	defer cancel()
 
-	cmd := exec.CommandContext(ctx, p.cmd[0], p.cmd[1:]...)
-
-	var stdout, stderr bytes.Buffer
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
-
-	err := cmd.Run()
-	if err != nil {
-		if errors.Is(err, context.DeadlineExceeded) {
-			cmd.Process.Kill()
-			return fmt.Errorf("peer command timed-out (killed): %s", stderr.String())
-		}
-
-		var exitErr *exec.ExitError
-		if errors.As(err, &exitErr) {
-			return fmt.Errorf("cmdline %v exited with code %d: %s", p.cmd, exitErr.ProcessState.ExitCode(), stderr.String())
-		}
-
-		return fmt.Errorf("failed running command %q: %s", p.cmd[0], err)
-	}
-
-	var ret peerJSON
-	err = json.Unmarshal(stdout.Bytes(), &ret)
+	addrs, err := p.src.Peers(ctx)
 	if err != nil {
 		return err
 	}
-	lst := make([]*net.TCPAddr, 0, len(ret.Peers))
+	lst := make([]*net.TCPAddr, 0, len(addrs))
 	dl := net.Dialer{
 		Timeout: time.Second,
 	}
-	for i := range ret.Peers {
-		addr := ret.Peers[i].Addr
+	for i, addr := range addrs {
 		host, port, err := net.SplitHostPort(addr)
 		if err != nil {
 			return fmt.Errorf("couldn't parse peer %d: %w", i, err)
@@ -138,10 +128,10 @@ func (p *peerCmd) run() error {
 		}
 		ip := net.ParseIP(host)
 		if len(ip) == 0 {
-			return fmt.Errorf("couldn't parse peer %d IP: %w", i, err)
+			return fmt.Errorf("couldn't parse peer %d IP %q", i, host)
 		}
 		tcpaddr := &net.TCPAddr{IP: ip, Port: portnum}
-		conn, err := dl.Dial("tcp", tcpaddr.String())
+		conn, err := dl.DialContext(ctx, "tcp", tcpaddr.String())
 		if err != nil {
 			p.logf("discarding peer %s: %s", addr, err)
 			continue
@@ -154,6 +144,49 @@ func (p *peerCmd) run() error {
 		}
 		lst = append(lst, tcpaddr)
 	}
-	p.recent.Store(lst)
+	p.update(lst)
 	return nil
 }
+
+// update replaces the current list of peers
+// and notifies watchers of any changes
+func (p *peerWatcher) update(lst []*net.TCPAddr) {
+	// new queries will only be split across
+	// the peers in lst; queries already in flight
+	// keep their peers (and retry failed splits
+	// elsewhere), so removed peers are drained
+	old := p.Get()
+	p.recent.Store(lst)
+	c := diffPeers(old, lst)
+	if len(c.Added) == 0 && len(c.Removed) == 0 {
+		return
+	}
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	for _, fn := range p.watchers {
+		fn(c)
+	}
+}
+
+func diffPeers(old, cur []*net.TCPAddr) peerChange {
+	in := func(a *net.TCPAddr, lst []*net.TCPAddr) bool {
+		for i := range lst {
+			if lst[i].IP.Equal(a.IP) && lst[i].Port == a.Port {
+				return true
+			}
+		}
+		return false
+	}
+	var c peerChange
+	for i := range cur {
+		if !in(cur[i], old) {
+			c.Added = append(c.Added, cur[i])
+		}
+	}
+	for i := range old {
+		if !in(old[i], cur) {
+			c.Removed = append(c.Removed, old[i])
+		}
+	}
+	return c
+}
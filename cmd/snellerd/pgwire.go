@@ -0,0 +1,261 @@
+// Copyright (C) 2022 Sneller, Inc.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+)
+
+// codes of the PostgreSQL (frontend/backend
+// protocol version 3) startup messages
+const (
+	pgProtocolVersion = 3 << 16
+	pgCancelRequest   = 80877102
+	pgSSLRequest      = 80877103
+	pgGSSENCRequest   = 80877104
+)
+
+// pgMaxMessage is the maximum size of
+// a message accepted from a client
+const pgMaxMessage = 16 << 20
+
+// type OIDs for the types that query
+// results are described with
+const (
+	pgBool        = 16
+	pgBytea       = 17
+	pgInt8        = 20
+	pgText        = 25
+	pgJSON        = 114
+	pgFloat8      = 701
+	pgTimestamptz = 1184
+)
+
+// result column formats
+const (
+	pgFormatText   = 0
+	pgFormatBinary = 1
+)
+
+var errPGMessage = errors.New("malformed message")
+
+// pgconn reads and writes PostgreSQL protocol
+// messages; outgoing messages are buffered
+// until flush is called
+type pgconn struct {
+	conn net.Conn
+	r    *bufio.Reader
+	w    *bufio.Writer
+	in   []byte // body of the most recent message
+	out  []byte // message under construction
+}
+
+func newPGConn(conn net.Conn) *pgconn {
+	return &pgconn{
+		conn: conn,
+		r:    bufio.NewReader(conn),
+		w:    bufio.NewWriter(conn),
+	}
+}
+
+// body reads a message body of the
+// size indicated by a length word
+// (which includes the length itself)
+func (c *pgconn) body(size uint32) ([]byte, error) {
+	if size < 4 || size > pgMaxMessage {
+		return nil, fmt.Errorf("pgwire: message size %d out of range", size)
+	}
+	size -= 4
+	if cap(c.in) < int(size) {
+		c.in = make([]byte, size)
+	}
+	c.in = c.in[:size]
+	_, err := io.ReadFull(c.r, c.in)
+	return c.in, err
+}
+
+// startup reads a startup message, which
+// is the only message without a type byte
+func (c *pgconn) startup() (code uint32, body []byte, err error) {
+	var hdr [8]byte
+	if _, err := io.ReadFull(c.r, hdr[:]); err != nil {
+		return 0, nil, err
+	}
+	size := binary.BigEndian.Uint32(hdr[:])
+	if size < 8 {
+		return 0, nil, errPGMessage
+	}
+	code = binary.BigEndian.Uint32(hdr[4:])
+	body, err = c.body(size - 4)
+	return code, body, err
+}
+
+// read reads the next message from the client
+func (c *pgconn) read() (typ byte, body []byte, err error) {
+	var hdr [5]byte
+	if _, err := io.ReadFull(c.r, hdr[:]); err != nil {
+		return 0, nil, err
+	}
+	body, err = c.body(binary.BigEndian.Uint32(hdr[1:]))
+	return hdr[0], body, err
+}
+
+// begin starts a new outgoing message
+func (c *pgconn) begin(typ byte) {
+	c.out = append(c.out[:0], typ, 0, 0, 0, 0)
+}
+
+func (c *pgconn) int16(v int) {
+	c.out = binary.BigEndian.AppendUint16(c.out, uint16(v))
+}
+
+func (c *pgconn) int32(v int) {
+	c.out = binary.BigEndian.AppendUint32(c.out, uint32(v))
+}
+
+func (c *pgconn) string(s string) {
+	c.out = append(c.out, s...)
+	c.out = append(c.out, 0)
+}
+
+func (c *pgconn) bytes(b []byte) {
+	c.out = append(c.out, b...)
+}
+
+// end finishes the outgoing message
+// and queues it to be sent
+func (c *pgconn) end() error {
+	binary.BigEndian.PutUint32(c.out[1:], uint32(len(c.out)-1))
+	_, err := c.w.Write(c.out)
+	return err
+}
+
+func (c *pgconn) flush() error {
+	return c.w.Flush()
+}
+
+// message sends a message with an empty body
+func (c *pgconn) message(typ byte) error {
+	c.begin(typ)
+	return c.end()
+}
+
+func (c *pgconn) authRequest(code int) error {
+	c.begin('R')
+	c.int32(code)
+	return c.end()
+}
+
+func (c *pgconn) parameterStatus(name, value string) error {
+	c.begin('S')
+	c.string(name)
+	c.string(value)
+	return c.end()
+}
+
+func (c *pgconn) readyForQuery() error {
+	c.begin('Z')
+	c.bytes([]byte{'I'})
+	return c.end()
+}
+
+func (c *pgconn) commandComplete(tag string) error {
+	c.begin('C')
+	c.string(tag)
+	return c.end()
+}
+
+// errorResponse sends an ErrorResponse with
+// the given SQLSTATE code and message
+func (c *pgconn) errorResponse(code, msg string) error {
+	c.begin('E')
+	c.bytes([]byte{'S'})
+	c.string("ERROR")
+	c.bytes([]byte{'V'})
+	c.string("ERROR")
+	c.bytes([]byte{'C'})
+	c.string(code)
+	c.bytes([]byte{'M'})
+	c.string(msg)
+	c.bytes([]byte{0})
+	return c.end()
+}
+
+// pgreader decodes the fields of
+// a message received from a client;
+// decoding errors are sticky
+type pgreader struct {
+	buf []byte
+	err error
+}
+
+func (r *pgreader) take(n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if n < 0 || len(r.buf) < n {
+		r.err = errPGMessage
+		return nil
+	}
+	b := r.buf[:n]
+	r.buf = r.buf[n:]
+	return b
+}
+
+func (r *pgreader) int16() int {
+	b := r.take(2)
+	if b == nil {
+		return 0
+	}
+	return int(int16(binary.BigEndian.Uint16(b)))
+}
+
+func (r *pgreader) int32() int {
+	b := r.take(4)
+	if b == nil {
+		return 0
+	}
+	return int(int32(binary.BigEndian.Uint32(b)))
+}
+
+func (r *pgreader) string() string {
+	if r.err != nil {
+		return ""
+	}
+	for i := range r.buf {
+		if r.buf[i] == 0 {
+			s := string(r.buf[:i])
+			r.buf = r.buf[i+1:]
+			return s
+		}
+	}
+	r.err = errPGMessage
+	return ""
+}
+
+// value reads a length-prefixed value;
+// a length of -1 indicates NULL
+func (r *pgreader) value() ([]byte, bool) {
+	n := r.int32()
+	if n == -1 {
+		return nil, true
+	}
+	return r.take(n), false
+}
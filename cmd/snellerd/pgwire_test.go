@@ -0,0 +1,460 @@
+// Copyright (C) 2022 Sneller, Inc.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/binary"
+	"errors"
+	"math"
+	"math/big"
+	"net"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/SnellerInc/sneller/ion"
+	"github.com/SnellerInc/sneller/tenant"
+)
+
+// pgclient is just enough of a PostgreSQL
+// client to exercise the protocol adapter
+type pgclient struct {
+	t *testing.T
+	*pgconn
+}
+
+func (c *pgclient) send() {
+	c.t.Helper()
+	if err := c.end(); err != nil {
+		c.t.Fatal(err)
+	}
+}
+
+// expect reads the next message and
+// checks that it has the given type
+func (c *pgclient) expect(typ byte) *pgreader {
+	c.t.Helper()
+	got, body, err := c.read()
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	if got != typ {
+		r := pgreader{buf: body}
+		msg := ""
+		if got == 'E' {
+			for r.take(1) != nil && r.err == nil {
+				msg += r.string() + " "
+			}
+		}
+		c.t.Fatalf("got message %q (%s), want %q", got, msg, typ)
+	}
+	return &pgreader{buf: append([]byte(nil), body...)}
+}
+
+// errorCode reads an ErrorResponse
+// and returns its SQLSTATE code
+func (c *pgclient) errorCode() string {
+	c.t.Helper()
+	r := c.expect('E')
+	for {
+		field := r.take(1)
+		if r.err != nil || field[0] == 0 {
+			break
+		}
+		value := r.string()
+		if field[0] == 'C' {
+			return value
+		}
+	}
+	c.t.Fatal("no code in ErrorResponse")
+	return ""
+}
+
+func (c *pgclient) query(text string) {
+	c.t.Helper()
+	c.begin('Q')
+	c.string(text)
+	c.send()
+	if err := c.flush(); err != nil {
+		c.t.Fatal(err)
+	}
+}
+
+type pgcol struct {
+	name   string
+	oid    int
+	format int
+}
+
+func (c *pgclient) rowDescription() []pgcol {
+	c.t.Helper()
+	r := c.expect('T')
+	cols := make([]pgcol, r.int16())
+	for i := range cols {
+		cols[i].name = r.string()
+		r.int32()
+		r.int16()
+		cols[i].oid = r.int32()
+		r.int16()
+		r.int32()
+		cols[i].format = r.int16()
+	}
+	if r.err != nil {
+		c.t.Fatal(r.err)
+	}
+	return cols
+}
+
+func (c *pgclient) dataRow() [][]byte {
+	c.t.Helper()
+	r := c.expect('D')
+	row := make([][]byte, r.int16())
+	for i := range row {
+		row[i], _ = r.value()
+	}
+	if r.err != nil {
+		c.t.Fatal(r.err)
+	}
+	return row
+}
+
+func (c *pgclient) commandComplete(want string) {
+	c.t.Helper()
+	if got := c.expect('C').string(); got != want {
+		c.t.Fatalf("got command tag %q, want %q", got, want)
+	}
+}
+
+func TestPostgres(t *testing.T) {
+	tt := testdirEnviron(t)
+	s := server{
+		logger:    testlogger(t),
+		sandbox:   tenant.CanSandbox(),
+		cachedir:  t.TempDir(),
+		cgroot:    os.Getenv("CGROOT"),
+		tenantcmd: []string{"./snellerd-test-binary", "worker"},
+		peers:     noPeers{},
+		auth:      testAuth{tt},
+		pgsock:    listen(t),
+		// the test client doesn't use TLS
+		pgInsecure: true,
+	}
+	var wg sync.WaitGroup
+	wg.Add(1)
+	s.aboutToServe = wg.Done
+	go s.Serve(listen(t), nil)
+	wg.Wait()
+	defer s.Close()
+
+	connect := func(password string) *pgclient {
+		conn, err := net.Dial("tcp", s.pgsock.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { conn.Close() })
+		c := &pgclient{t: t, pgconn: newPGConn(conn)}
+		// SSLRequest should be declined
+		var msg []byte
+		msg = binary.BigEndian.AppendUint32(msg, 8)
+		msg = binary.BigEndian.AppendUint32(msg, pgSSLRequest)
+		c.w.Write(msg)
+		c.flush()
+		if b, err := c.r.ReadByte(); err != nil || b != 'N' {
+			t.Fatalf("SSLRequest: got %q %v", b, err)
+		}
+		msg = binary.BigEndian.AppendUint32(msg[:0], 0)
+		msg = binary.BigEndian.AppendUint32(msg, pgProtocolVersion)
+		for _, str := range []string{"user", "test", "database", "default", ""} {
+			msg = append(msg, str...)
+			msg = append(msg, 0)
+		}
+		binary.BigEndian.PutUint32(msg, uint32(len(msg)))
+		c.w.Write(msg)
+		c.flush()
+		if code := c.expect('R').int32(); code != 3 {
+			t.Fatalf("got authentication request %d", code)
+		}
+		c.begin('p')
+		c.string(password)
+		c.send()
+		c.flush()
+		return c
+	}
+
+	c := connect("wrong")
+	if code := c.errorCode(); code != "28P01" {
+		t.Fatalf("bad password: got code %s", code)
+	}
+
+	c = connect("snellerd-test")
+	if code := c.expect('R').int32(); code != 0 {
+		t.Fatalf("got authentication request %d", code)
+	}
+	params := make(map[string]string)
+	for {
+		typ, body, err := c.read()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if typ == 'Z' {
+			break
+		}
+		if typ == 'S' {
+			r := pgreader{buf: body}
+			params[r.string()] = r.string()
+		}
+	}
+	if params["server_version"] != pgServerVersion {
+		t.Errorf("server_version = %q", params["server_version"])
+	}
+
+	// simple query protocol
+	c.query("SELECT COUNT(*) AS n FROM parking")
+	cols := c.rowDescription()
+	if len(cols) != 1 || cols[0].name != "n" || cols[0].oid != pgInt8 || cols[0].format != pgFormatText {
+		t.Fatalf("unexpected columns %+v", cols)
+	}
+	if row := c.dataRow(); len(row) != 1 || string(row[0]) != "1023" {
+		t.Fatalf("unexpected row %q", row)
+	}
+	c.commandComplete("SELECT 1")
+	c.expect('Z')
+
+	// queries are parsed in the PostgreSQL dialect
+	c.query("SELECT LENGTH('abc') AS n")
+	c.rowDescription()
+	if row := c.dataRow(); len(row) != 1 || string(row[0]) != "3" {
+		t.Fatalf("unexpected row %q", row)
+	}
+	c.commandComplete("SELECT 1")
+	c.expect('Z')
+
+	c.query("SET extra_float_digits = 3")
+	c.commandComplete("SET")
+	c.expect('Z')
+
+	c.query("SELEC 1")
+	if code := c.errorCode(); code != "42601" {
+		t.Errorf("syntax error: got code %s", code)
+	}
+	c.expect('Z')
+
+	c.query("SELECT * FROM nonexistent")
+	if code := c.errorCode(); code != "42P01" {
+		t.Errorf("missing table: got code %s", code)
+	}
+	c.expect('Z')
+
+	// extended query protocol with binary results
+	c.begin('P')
+	c.string("")
+	c.string("SELECT Ticket, Fine FROM parking ORDER BY Ticket LIMIT 2")
+	c.int16(0)
+	c.send()
+	c.begin('B')
+	c.string("")
+	c.string("")
+	c.int16(0)
+	c.int16(0)
+	c.int16(1)
+	c.int16(pgFormatBinary)
+	c.send()
+	c.begin('D')
+	c.bytes([]byte{'P'})
+	c.string("")
+	c.send()
+	c.begin('E')
+	c.string("")
+	c.int32(0)
+	c.send()
+	c.begin('S')
+	c.send()
+	c.flush()
+	c.expect('1')
+	c.expect('2')
+	cols = c.rowDescription()
+	if len(cols) != 2 || cols[0].oid != pgInt8 || cols[1].oid != pgInt8 || cols[0].format != pgFormatBinary {
+		t.Fatalf("unexpected columns %+v", cols)
+	}
+	prev := int64(math.MinInt64)
+	for i := 0; i < 2; i++ {
+		row := c.dataRow()
+		if len(row) != 2 || len(row[0]) != 8 {
+			t.Fatalf("unexpected row %v", row)
+		}
+		ticket := int64(binary.BigEndian.Uint64(row[0]))
+		if ticket < prev {
+			t.Errorf("ticket %d after %d", ticket, prev)
+		}
+		prev = ticket
+	}
+	c.commandComplete("SELECT 2")
+	c.expect('Z')
+
+	// results larger than the preview
+	// are streamed as they arrive
+	c.query("SELECT Ticket FROM parking")
+	if cols := c.rowDescription(); len(cols) != 1 {
+		t.Fatalf("unexpected columns %+v", cols)
+	}
+	for i := 0; i < 1023; i++ {
+		c.dataRow()
+	}
+	c.commandComplete("SELECT 1023")
+	c.expect('Z')
+
+	c.begin('X')
+	c.send()
+	c.flush()
+}
+
+func TestPostgresTLS(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := empty(t)
+	s.pgsock = listen(t)
+	s.pgTLS = &tls.Config{
+		Certificates: []tls.Certificate{{Certificate: [][]byte{der}, PrivateKey: key}},
+	}
+	var wg sync.WaitGroup
+	wg.Add(1)
+	s.aboutToServe = wg.Done
+	go s.Serve(listen(t), nil)
+	wg.Wait()
+
+	connect := func(ssl bool) *pgclient {
+		conn, err := net.Dial("tcp", s.pgsock.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { conn.Close() })
+		c := &pgclient{t: t, pgconn: newPGConn(conn)}
+		var msg []byte
+		if ssl {
+			msg = binary.BigEndian.AppendUint32(msg, 8)
+			msg = binary.BigEndian.AppendUint32(msg, pgSSLRequest)
+			c.w.Write(msg)
+			c.flush()
+			if b, err := c.r.ReadByte(); err != nil || b != 'S' {
+				t.Fatalf("SSLRequest: got %q %v", b, err)
+			}
+			tc := tls.Client(conn, &tls.Config{InsecureSkipVerify: true})
+			if err := tc.Handshake(); err != nil {
+				t.Fatal(err)
+			}
+			c.pgconn = newPGConn(tc)
+		}
+		msg = binary.BigEndian.AppendUint32(msg[:0], 0)
+		msg = binary.BigEndian.AppendUint32(msg, pgProtocolVersion)
+		for _, str := range []string{"user", "test", "database", "default", ""} {
+			msg = append(msg, str...)
+			msg = append(msg, 0)
+		}
+		binary.BigEndian.PutUint32(msg, uint32(len(msg)))
+		c.w.Write(msg)
+		c.flush()
+		return c
+	}
+
+	// the token must not be
+	// requested without TLS
+	c := connect(false)
+	if code := c.errorCode(); code != "28000" {
+		t.Fatalf("unencrypted connection: got code %s", code)
+	}
+
+	c = connect(true)
+	if code := c.expect('R').int32(); code != 3 {
+		t.Fatalf("got authentication request %d", code)
+	}
+	c.begin('p')
+	c.string("snellerd-test")
+	c.send()
+	c.flush()
+	if code := c.expect('R').int32(); code != 0 {
+		t.Fatalf("got authentication request %d", code)
+	}
+}
+
+func TestPGResultStream(t *testing.T) {
+	rows := func(late int) *pgresult {
+		res := newPGResult()
+		go func() {
+			var err error
+			for i := 0; i < 2*pgPreviewRows && err == nil; i++ {
+				fields := []ion.Field{{Label: "x", Datum: ion.Int(int64(i))}}
+				if i == late {
+					fields = append(fields, ion.Field{Label: "late", Datum: ion.Int(1)})
+				}
+				err = res.send(ion.NewStruct(nil, fields).Datum())
+			}
+			res.done <- err
+			close(res.next)
+		}()
+		if err := res.start(); err != nil {
+			t.Fatal(err)
+		}
+		return res
+	}
+	var buf bytes.Buffer
+	c := &pgconn{w: bufio.NewWriter(&buf)}
+
+	// a field in the preview is a column
+	res := rows(pgPreviewRows - 1)
+	if len(res.cols) != 2 {
+		t.Fatalf("unexpected columns %+v", res.cols)
+	}
+	if err := c.dataRows(res, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// a field after the preview can't be sent
+	res = rows(pgPreviewRows + 1)
+	if len(res.cols) != 1 {
+		t.Fatalf("unexpected columns %+v", res.cols)
+	}
+	var perr *pgError
+	if err := c.dataRows(res, nil); !errors.As(err, &perr) || perr.code != "42703" {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	// an abandoned result stops the query
+	res = rows(-1)
+	res.close()
+	if err := <-res.done; !errors.Is(err, errPGAbandoned) {
+		t.Fatalf("unexpected error %v", err)
+	}
+}
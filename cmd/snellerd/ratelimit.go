@@ -0,0 +1,222 @@
+// Copyright (C) 2022 Sneller, Inc.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"context"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// rateLimit configures a limiter
+type rateLimit struct {
+	// Rate is the sustained number of
+	// requests per second; zero means unlimited
+	Rate float64
+	// Burst is the number of requests that may
+	// be made at once after a period of inactivity;
+	// if Burst is zero, it is derived from Rate
+	Burst int
+	// Streams is the maximum number of
+	// requests that may be in progress at once;
+	// zero means unlimited
+	Streams int
+}
+
+func (r *rateLimit) enabled() bool { return r.Rate > 0 || r.Streams > 0 }
+
+func (r *rateLimit) burst() float64 {
+	if r.Burst > 0 {
+		return float64(r.Burst)
+	}
+	return math.Max(1, math.Ceil(r.Rate))
+}
+
+// bucket is the state of one
+// client of a limiter
+type bucket struct {
+	tokens float64
+	last   time.Time
+	active int
+}
+
+// idleBucketAge is the amount of time
+// after which an idle bucket is discarded
+const idleBucketAge = 10 * time.Minute
+
+// limiter enforces a rateLimit
+// for each of a set of clients using
+// a token bucket per client
+type limiter struct {
+	conf rateLimit
+
+	lock    sync.Mutex
+	buckets map[string]*bucket
+	swept   time.Time
+}
+
+// rejection describes a request
+// that was rejected by a limiter
+type rejection struct {
+	retry time.Duration
+	limit string
+}
+
+// sweep discards buckets that have been idle
+// long enough that they would be full again;
+// the caller must hold l.lock
+func (l *limiter) sweep(now time.Time) {
+	if now.Sub(l.swept) < idleBucketAge {
+		return
+	}
+	l.swept = now
+	for k, b := range l.buckets {
+		if b.active == 0 && now.Sub(b.last) >= idleBucketAge {
+			delete(l.buckets, k)
+		}
+	}
+}
+
+// acquire admits one request for the client
+// identified by key; if the request is admitted,
+// the caller must call release(key) once the request
+// has completed
+func (l *limiter) acquire(key string, now time.Time) *rejection {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.sweep(now)
+	b := l.buckets[key]
+	if b == nil {
+		if l.buckets == nil {
+			l.buckets = make(map[string]*bucket)
+		}
+		b = &bucket{tokens: l.conf.burst(), last: now}
+		l.buckets[key] = b
+	}
+	if l.conf.Streams > 0 && b.active >= l.conf.Streams {
+		return &rejection{
+			retry: time.Second,
+			limit: strconv.Itoa(l.conf.Streams) + " concurrent requests",
+		}
+	}
+	if l.conf.Rate > 0 {
+		burst := l.conf.burst()
+		if elapsed := now.Sub(b.last); elapsed > 0 {
+			b.tokens = math.Min(burst, b.tokens+elapsed.Seconds()*l.conf.Rate)
+		}
+		b.last = now
+		if b.tokens < 1 {
+			wait := (1 - b.tokens) / l.conf.Rate
+			return &rejection{
+				retry: time.Duration(wait * float64(time.Second)),
+				limit: strconv.FormatFloat(l.conf.Rate, 'g', -1, 64) + " requests per second",
+			}
+		}
+		b.tokens--
+	}
+	b.last = now
+	b.active++
+	return nil
+}
+
+func (l *limiter) release(key string) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if b := l.buckets[key]; b != nil && b.active > 0 {
+		b.active--
+	}
+}
+
+// reject writes a 429 response for rej
+func reject(w http.ResponseWriter, rej *rejection, who string) {
+	secs := int(math.Ceil(rej.retry.Seconds()))
+	if secs < 1 {
+		secs = 1
+	}
+	w.Header().Set("Retry-After", strconv.Itoa(secs))
+	w.Header().Set("X-Sneller-Rate-Limit", rej.limit+" per "+who)
+	http.Error(w, "rate limit exceeded: "+rej.limit+" per "+who, http.StatusTooManyRequests)
+}
+
+// admitted tracks the limiter slots
+// held by one request
+type admitted struct {
+	lock  sync.Mutex
+	slots []func()
+}
+
+func (a *admitted) add(l *limiter, key string) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	a.slots = append(a.slots, func() { l.release(key) })
+}
+
+func (a *admitted) release() {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	for i := range a.slots {
+		a.slots[i]()
+	}
+	a.slots = nil
+}
+
+var admittedKey = &contextKey{key: "admitted"}
+
+// limitIP applies the per-IP limit to r
+// and returns the request to pass on to the
+// handler and a function that must be called
+// once the handler returns, or (nil, nil) if
+// the request has been rejected
+func (s *server) limitIP(w http.ResponseWriter, r *http.Request, remoteAddress string) (*http.Request, func()) {
+	a := &admitted{}
+	r = r.WithContext(context.WithValue(r.Context(), admittedKey, a))
+	if s.ipLimit.conf.enabled() {
+		host, _, err := net.SplitHostPort(remoteAddress)
+		if err != nil {
+			host = remoteAddress
+		}
+		if rej := s.ipLimit.acquire(host, time.Now()); rej != nil {
+			s.logger.Printf("rate limiting %s: %s", host, rej.limit)
+			reject(w, rej, "client")
+			return nil, nil
+		}
+		a.add(&s.ipLimit, host)
+	}
+	return r, a.release
+}
+
+// limitTenant applies the per-tenant limit
+// to r and returns false if the request
+// has been rejected
+func (s *server) limitTenant(w http.ResponseWriter, r *http.Request, id string) bool {
+	if !s.tenantLimit.conf.enabled() {
+		return true
+	}
+	if rej := s.tenantLimit.acquire(id, time.Now()); rej != nil {
+		s.logger.Printf("rate limiting tenant %s: %s", id, rej.limit)
+		reject(w, rej, "tenant")
+		return false
+	}
+	if a, ok := r.Context().Value(admittedKey).(*admitted); ok {
+		a.add(&s.tenantLimit, id)
+	} else {
+		s.tenantLimit.release(id)
+	}
+	return true
+}
@@ -0,0 +1,122 @@
+// Copyright (C) 2022 Sneller, Inc.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestLimiter(t *testing.T) {
+	l := limiter{conf: rateLimit{Rate: 2, Burst: 3, Streams: 4}}
+	now := time.Now()
+	// the burst is available immediately
+	for i := 0; i < 3; i++ {
+		if rej := l.acquire("a", now); rej != nil {
+			t.Fatalf("request %d rejected: %s", i, rej.limit)
+		}
+	}
+	rej := l.acquire("a", now)
+	if rej == nil {
+		t.Fatal("request beyond the burst admitted")
+	}
+	if rej.retry != 500*time.Millisecond {
+		t.Errorf("retry after %s; expected 500ms", rej.retry)
+	}
+	// other clients are unaffected
+	if rej := l.acquire("b", now); rej != nil {
+		t.Fatalf("client b rejected: %s", rej.limit)
+	}
+	l.release("b")
+	// tokens are replenished at Rate
+	now = now.Add(500 * time.Millisecond)
+	if rej := l.acquire("a", now); rej != nil {
+		t.Fatalf("replenished request rejected: %s", rej.limit)
+	}
+	// ... but the number of concurrent
+	// requests is limited independently
+	now = now.Add(time.Minute)
+	if rej := l.acquire("a", now); rej == nil {
+		t.Fatal("request beyond the stream limit admitted")
+	}
+	l.release("a")
+	if rej := l.acquire("a", now); rej != nil {
+		t.Fatalf("request rejected after release: %s", rej.limit)
+	}
+	for i := 0; i < 4; i++ {
+		l.release("a")
+	}
+	// idle clients are eventually forgotten
+	l.acquire("c", now)
+	l.release("c")
+	l.acquire("c", now.Add(2*idleBucketAge))
+	if len(l.buckets) != 1 {
+		t.Errorf("%d buckets left after sweep", len(l.buckets))
+	}
+}
+
+func TestRateLimitHTTP(t *testing.T) {
+	testFiles(t)
+	s := empty(t)
+	s.ipLimit.conf = rateLimit{Rate: 0.001, Burst: 2}
+	s.tenantLimit.conf = rateLimit{Rate: 0.001, Burst: 1}
+
+	httpsock := listen(t)
+	go s.Serve(httpsock, nil)
+	host := "http://" + httpsock.Addr().String()
+
+	get := func(uri string) *http.Response {
+		req, err := http.NewRequest(http.MethodGet, host+uri, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Authorization", "Bearer snellerd-test")
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Body.Close()
+		return res
+	}
+	testcases := []struct {
+		uri   string
+		code  int
+		limit string
+	}{
+		{"/databases", http.StatusOK, ""},
+		// passes the per-client limit,
+		// but not the per-tenant limit
+		{"/databases", http.StatusTooManyRequests, "0.001 requests per second per tenant"},
+		{"/ping", http.StatusTooManyRequests, "0.001 requests per second per client"},
+		// heartbeats are never limited
+		{"/", http.StatusOK, ""},
+	}
+	for i := range testcases {
+		res := get(testcases[i].uri)
+		if res.StatusCode != testcases[i].code {
+			t.Fatalf("request %d for %s: got status %d, want %d", i, testcases[i].uri, res.StatusCode, testcases[i].code)
+		}
+		if res.StatusCode != http.StatusTooManyRequests {
+			continue
+		}
+		if got := res.Header.Get("X-Sneller-Rate-Limit"); got != testcases[i].limit {
+			t.Errorf("request %d: got limit %q, want %q", i, got, testcases[i].limit)
+		}
+		if res.Header.Get("Retry-After") == "" {
+			t.Errorf("request %d: missing Retry-After", i)
+		}
+	}
+}
@@ -16,18 +16,24 @@ package main
 
 import (
 	"context"
+	"crypto/tls"
 	"flag"
 	"log"
 	"net"
 	"os"
 	"os/signal"
+	"strconv"
 	"strings"
 	"syscall"
 	"time"
 
+	"github.com/SnellerInc/sneller"
 	"github.com/SnellerInc/sneller/auth"
 	"github.com/SnellerInc/sneller/debug"
+	"github.com/SnellerInc/sneller/expr"
+	"github.com/SnellerInc/sneller/plan"
 	"github.com/SnellerInc/sneller/tenant"
+	"github.com/SnellerInc/sneller/vm"
 )
 
 func runDaemon(args []string) {
@@ -38,9 +44,40 @@ func runDaemon(args []string) {
 	authEndpoint := daemonCmd.String("a", "", "authorization specification (file://, http://, https://, empty uses environment)")
 	daemonEndpoint := daemonCmd.String("e", "127.0.0.1:8000", "endpoint to listen on (REST API)")
 	remoteEndpoint := daemonCmd.String("r", "127.0.0.1:9000", "endpoint to listen on for remote requests (inter-node)")
+	pgEndpoint := daemonCmd.String("pg", "", "endpoint to listen on for PostgreSQL protocol clients (empty disables)")
+	pgCert := daemonCmd.String("pg-cert", "", "TLS certificate file for PostgreSQL protocol clients")
+	pgKey := daemonCmd.String("pg-key", "", "TLS private key file for PostgreSQL protocol clients")
+	pgInsecure := daemonCmd.Bool("pg-insecure", false, "permit PostgreSQL protocol clients to send their token over unencrypted connections")
 	cgroupRoot := daemonCmd.String("cgroot", "", "delegated cgroup root for tenant processes")
 	peerExec := daemonCmd.String("x", "", "command to exec for fetching peers")
+	peerDiscovery := daemonCmd.String("peers", "", "peer discovery method (exec:<cmdline>, srv:<name>, dns:<host>:<port>)")
 	debugSock := daemonCmd.Int("debug", -1, "file descriptor to listen on for pprof debug activity")
+	compression := daemonCmd.String("compression", "", "comma-separated list of content-codings (zstd, gzip) used to compress query results for clients that accept them, in order of preference")
+	compressLevel := daemonCmd.Int("compression-level", 0, "compression level for query results (0 selects the default; lower levels use less CPU)")
+	peerCompression := daemonCmd.Bool("peer-compression", false, "compress query plans and results sent between peers with zstd (all peers must support it)")
+	placement := daemonCmd.String("placement", "", "policy for assigning blocks to peers (empty uses rendezvous hashing of block ETags)")
+	watchdog := daemonCmd.Duration("watchdog", 0, "abort queries that spend longer than this on one batch of rows (0 disables)")
+	tmpQuota := daemonCmd.Int64("tmp-quota", 0, "maximum bytes of temporary (spill) files per tenant (0 disables)")
+	tmpQueryQuota := daemonCmd.Int64("tmp-query-quota", 0, "maximum bytes of temporary (spill) files per query (0 disables)")
+	vmMemory := daemonCmd.String("vm-memory", "", "bytes of vm memory available to each process, with an optional K, M or G suffix (empty uses $SNELLER_VM_MEMORY or the default)")
+	vmQueryMemory := daemonCmd.String("vm-query-memory", "", "maximum bytes of vm memory used by each query, with an optional K, M or G suffix (empty disables)")
+	decompressParallel := daemonCmd.Int("decompress-parallel", 0, "number of goroutines decompressing each segment ahead of evaluation (0 uses one per evaluating goroutine)")
+	subqueryTimeout := daemonCmd.Duration("subquery-timeout", plan.DefaultSubqueryTimeout, "cancel (and retry) sub-queries that run for longer than this (0 disables)")
+	speculation := daemonCmd.Float64("speculation", 0, "re-execute sub-queries that run for longer than this multiple of the median sub-query time on another peer (0 disables)")
+	compactInterval := daemonCmd.Duration("compact-interval", 10*time.Minute, "minimum interval between background compactions of tables that receive pushed data (0 disables)")
+	maxScan := daemonCmd.Uint64("max-scan-bytes", DefaultMaxScan, "maximum bytes scanned by each query for tenants that do not configure a limit (0 disables)")
+	var limits expr.Limits
+	daemonCmd.IntVar(&limits.MaxTextSize, "max-query-bytes", 1024*1024, "maximum size of query text in bytes (0 disables)")
+	daemonCmd.IntVar(&limits.MaxNodes, "max-query-nodes", 100000, "maximum number of expressions in a query (0 disables)")
+	daemonCmd.IntVar(&limits.MaxDepth, "max-query-depth", 1000, "maximum nesting depth of query expressions (0 disables)")
+	daemonCmd.IntVar(&limits.MaxSubqueries, "max-subqueries", 100, "maximum number of subqueries in a query (0 disables)")
+	var ipLimit, tenantLimit rateLimit
+	daemonCmd.Float64Var(&ipLimit.Rate, "ip-rate", 0, "maximum sustained requests per second from each client address (0 disables)")
+	daemonCmd.IntVar(&ipLimit.Burst, "ip-burst", 0, "maximum burst of requests from each client address (0 derives the burst from -ip-rate)")
+	daemonCmd.IntVar(&ipLimit.Streams, "ip-streams", 0, "maximum concurrent requests from each client address (0 disables)")
+	daemonCmd.Float64Var(&tenantLimit.Rate, "tenant-rate", 0, "maximum sustained requests per second from each tenant (0 disables)")
+	daemonCmd.IntVar(&tenantLimit.Burst, "tenant-burst", 0, "maximum burst of requests from each tenant (0 derives the burst from -tenant-rate)")
+	daemonCmd.IntVar(&tenantLimit.Streams, "tenant-streams", 0, "maximum concurrent requests from each tenant (0 disables)")
 
 	if daemonCmd.Parse(args) != nil {
 		os.Exit(1)
@@ -63,6 +100,64 @@ func runDaemon(args []string) {
 		sandbox:   tenant.CanSandbox(),
 		tenantcmd: []string{exe, "worker"},
 		peers:     noPeers{},
+
+		peerCompression: *peerCompression,
+	}
+	if *placement != "" {
+		server.placement, err = sneller.LookupPlacement(*placement)
+		if err != nil {
+			logger.Fatalf("-placement: %s", err)
+		}
+	}
+	server.compression, err = parseCompression(*compression)
+	if err != nil {
+		logger.Fatalf("-compression: %s", err)
+	}
+	if *compressLevel < -128 || *compressLevel > 127 {
+		logger.Fatalf("-compression-level %d out of range", *compressLevel)
+	}
+	server.compressLevel = int8(*compressLevel)
+	if ipLimit.Rate < 0 || tenantLimit.Rate < 0 {
+		logger.Fatal("rate limits must not be negative")
+	}
+	server.limits = limits
+	server.ingest.compactInterval = *compactInterval
+	server.maxScan = *maxScan
+	server.ipLimit.conf = ipLimit
+	server.tenantLimit.conf = tenantLimit
+	if *watchdog > 0 {
+		server.tenantcmd = append(server.tenantcmd, "-watchdog", watchdog.String())
+	}
+	if *tmpQuota > 0 {
+		server.tenantcmd = append(server.tenantcmd, "-tmp-quota", strconv.FormatInt(*tmpQuota, 10))
+	}
+	if *tmpQueryQuota > 0 {
+		server.tenantcmd = append(server.tenantcmd, "-tmp-query-quota", strconv.FormatInt(*tmpQueryQuota, 10))
+	}
+	if *decompressParallel > 0 {
+		server.tenantcmd = append(server.tenantcmd, "-decompress-parallel", strconv.Itoa(*decompressParallel))
+	}
+	if *subqueryTimeout != plan.DefaultSubqueryTimeout {
+		server.tenantcmd = append(server.tenantcmd, "-subquery-timeout", subqueryTimeout.String())
+	}
+	if *speculation < 0 {
+		logger.Fatal("-speculation must not be negative")
+	} else if *speculation > 0 {
+		server.tenantcmd = append(server.tenantcmd, "-speculation", strconv.FormatFloat(*speculation, 'g', -1, 64))
+	}
+	if *vmMemory != "" {
+		n, err := vm.ParseMemorySize(*vmMemory)
+		if err != nil {
+			logger.Fatalf("-vm-memory: %s", err)
+		}
+		vm.SetMemoryLimit(n)
+		server.tenantcmd = append(server.tenantcmd, "-vm-memory", *vmMemory)
+	}
+	if *vmQueryMemory != "" {
+		if _, err := vm.ParseMemorySize(*vmQueryMemory); err != nil {
+			logger.Fatalf("-vm-query-memory: %s", err)
+		}
+		server.tenantcmd = append(server.tenantcmd, "-vm-query-memory", *vmQueryMemory)
 	}
 	httpl, err := net.Listen("tcp", *daemonEndpoint)
 	if err != nil {
@@ -75,6 +170,28 @@ func runDaemon(args []string) {
 			server.logger.Fatal(err)
 		}
 	}
+	if *pgEndpoint != "" {
+		if (*pgCert == "") != (*pgKey == "") {
+			server.logger.Fatal("-pg-cert and -pg-key must be used together")
+		}
+		if *pgCert != "" {
+			cert, err := tls.LoadX509KeyPair(*pgCert, *pgKey)
+			if err != nil {
+				server.logger.Fatalf("loading PostgreSQL TLS certificate: %s", err)
+			}
+			server.pgTLS = &tls.Config{
+				Certificates: []tls.Certificate{cert},
+				MinVersion:   tls.VersionTLS12,
+			}
+		} else if !*pgInsecure {
+			server.logger.Println("warning: -pg without -pg-cert refuses every client unless -pg-insecure is set")
+		}
+		server.pgInsecure = *pgInsecure
+		server.pgsock, err = net.Listen("tcp", *pgEndpoint)
+		if err != nil {
+			server.logger.Fatal(err)
+		}
+	}
 	provider, err := auth.Parse(*authEndpoint)
 	if err != nil {
 		if len(*authEndpoint) == 0 {
@@ -94,11 +211,23 @@ func runDaemon(args []string) {
 	if server.sandbox {
 		server.logger.Println("sandboxing enabled")
 	}
+	for _, msg := range vm.DisabledKernels() {
+		server.logger.Printf("warning: %s", msg)
+	}
 
+	if *peerExec != "" && *peerDiscovery != "" {
+		server.logger.Fatal("-x and -peers are mutually exclusive")
+	}
 	if *peerExec != "" {
-		server.peers = &peerCmd{
-			cmd: strings.Fields(*peerExec),
+		server.peers = &peerWatcher{
+			src: &cmdDiscovery{cmd: strings.Fields(*peerExec)},
+		}
+	} else if *peerDiscovery != "" {
+		src, err := parseDiscovery(*peerDiscovery)
+		if err != nil {
+			server.logger.Fatalf("-peers: %s", err)
 		}
+		server.peers = &peerWatcher{src: src}
 	}
 	go func() {
 		server.logger.Printf("Sneller daemon %s listening on %v\n", version, httpl.Addr())
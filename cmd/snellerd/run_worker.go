@@ -15,6 +15,7 @@
 package main
 
 import (
+	"expvar"
 	"flag"
 	"fmt"
 	"log"
@@ -26,6 +27,8 @@ import (
 
 	"github.com/SnellerInc/sneller"
 	"github.com/SnellerInc/sneller/debug"
+	"github.com/SnellerInc/sneller/plan"
+	"github.com/SnellerInc/sneller/tempstore"
 	"github.com/SnellerInc/sneller/tenant/dcache"
 	"github.com/SnellerInc/sneller/tenant/tnproto"
 	"github.com/SnellerInc/sneller/vm"
@@ -44,6 +47,14 @@ func runWorker(args []string) {
 	workerTenant := workerCmd.String("t", "", "tenant identifier")
 	workerControlSocket := workerCmd.Int("c", -1, "control socket")
 	eventfd := workerCmd.Int("e", -1, "eventfd")
+	watchdog := workerCmd.Duration("watchdog", 0, "abort queries that spend longer than this on one batch of rows (0 disables)")
+	tmpQuota := workerCmd.Int64("tmp-quota", 0, "maximum bytes of temporary (spill) files (0 disables)")
+	tmpQueryQuota := workerCmd.Int64("tmp-query-quota", 0, "maximum bytes of temporary (spill) files per query (0 disables)")
+	vmMemory := workerCmd.String("vm-memory", "", "bytes of vm memory, with an optional K, M or G suffix (empty uses $SNELLER_VM_MEMORY or the default)")
+	vmQueryMemory := workerCmd.String("vm-query-memory", "", "maximum bytes of vm memory used by each query, with an optional K, M or G suffix (empty disables)")
+	subqueryTimeout := workerCmd.Duration("subquery-timeout", plan.DefaultSubqueryTimeout, "cancel (and retry) sub-queries that run for longer than this (0 disables)")
+	workerCmd.Float64Var(&tnproto.Speculation, "speculation", 0, "re-execute sub-queries that run for longer than this multiple of the median sub-query time on another peer (0 disables)")
+	workerCmd.IntVar(&sneller.DecompressParallel, "decompress-parallel", 0, "number of goroutines decompressing each segment ahead of evaluation (0 uses one per evaluating goroutine)")
 	if workerCmd.Parse(args) != nil {
 		os.Exit(1)
 	}
@@ -61,6 +72,31 @@ func runWorker(args []string) {
 
 	// capture vm errors associated with this tenant
 	vm.Errorf = logger.Printf
+	if *vmMemory != "" {
+		n, err := vm.ParseMemorySize(*vmMemory)
+		if err != nil {
+			logger.Fatalf("-vm-memory: %s", err)
+		}
+		vm.SetMemoryLimit(n)
+	}
+	if *vmQueryMemory != "" {
+		n, err := vm.ParseMemorySize(*vmQueryMemory)
+		if err != nil {
+			logger.Fatalf("-vm-query-memory: %s", err)
+		}
+		tnproto.QueryMemoryLimit = n
+	}
+	if *watchdog > 0 {
+		vm.SetWatchdog(*watchdog)
+	}
+	tnproto.SubqueryTimeout = *subqueryTimeout
+	if *subqueryTimeout == 0 {
+		tnproto.SubqueryTimeout = -1 // disabled
+	}
+	// exported via /debug/vars on the debug socket
+	expvar.Publish("vm_watchdog_trips", expvar.Func(func() any {
+		return vm.WatchdogTrips()
+	}))
 	start := nfds()
 	defer func() {
 		http.DefaultClient.CloseIdleConnections()
@@ -101,6 +137,18 @@ func runWorker(args []string) {
 				return ucred.Uid == 0
 			}
 			debug.Path(filepath.Join(cachedir, "debug.sock"), ok, logger)
+
+			// temporary files live inside the cache directory
+			// so that they are removed by the tenant manager
+			// along with the rest of the cache if we crash
+			env.Temp, err = tempstore.Open(filepath.Join(cachedir, tempstore.DirName), *tmpQuota, *tmpQueryQuota)
+			if err != nil {
+				logger.Fatalf("cannot create temporary storage: %v", err)
+			}
+			defer env.Temp.Close()
+			expvar.Publish("tempstore", expvar.Func(func() any {
+				return env.Temp.Stats()
+			}))
 		}
 	}
 	err = tnproto.Serve(uc, &env)
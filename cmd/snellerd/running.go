@@ -0,0 +1,72 @@
+// Copyright (C) 2022 Sneller, Inc.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/SnellerInc/sneller"
+)
+
+// runningQuery is an entry in a queryRegistry
+type runningQuery struct {
+	tenant string
+	info   sneller.QueryInfo
+}
+
+// queryRegistry tracks the queries that are
+// currently being executed so that they can
+// be listed in the sneller_queries system table
+type queryRegistry struct {
+	lock    sync.Mutex
+	running map[string]*runningQuery
+}
+
+// add registers a query and returns
+// a function that unregisters it
+func (q *queryRegistry) add(tenant string, info sneller.QueryInfo) func() {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	if q.running == nil {
+		q.running = make(map[string]*runningQuery)
+	}
+	q.running[info.ID] = &runningQuery{tenant: tenant, info: info}
+	return func() {
+		q.lock.Lock()
+		defer q.lock.Unlock()
+		delete(q.running, info.ID)
+	}
+}
+
+// list returns the queries running on behalf
+// of the given tenant, ordered by start time
+func (q *queryRegistry) list(tenant string) []sneller.QueryInfo {
+	q.lock.Lock()
+	var out []sneller.QueryInfo
+	for _, rq := range q.running {
+		if rq.tenant == tenant {
+			out = append(out, rq.info)
+		}
+	}
+	q.lock.Unlock()
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Start.Equal(out[j].Start) {
+			return out[i].ID < out[j].ID
+		}
+		return out[i].Start.Before(out[j].Start)
+	})
+	return out
+}
@@ -0,0 +1,160 @@
+// Copyright (C) 2022 Sneller, Inc.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"time"
+
+	"github.com/SnellerInc/sneller"
+	"github.com/SnellerInc/sneller/db"
+	"github.com/SnellerInc/sneller/expr"
+	"github.com/SnellerInc/sneller/ion"
+	"github.com/SnellerInc/sneller/plan"
+	"github.com/SnellerInc/sneller/tenant"
+	"github.com/SnellerInc/sneller/tenant/tnproto"
+	"github.com/SnellerInc/sneller/usock"
+	"github.com/google/uuid"
+)
+
+// maxResultValue is the maximum size of a
+// value in the output of a query run by runQuery
+const maxResultValue = 16 << 20
+
+var errTenantDisallowed = errors.New("tenant ID disallowed")
+
+// runQuery executes a parsed query on behalf of creds
+// and calls fn with each value in the query output.
+// Unlike /executeQuery, which has the tenant write
+// the results directly to the client connection,
+// runQuery reads the results itself, so it is used
+// where the results need to be re-encoded
+// (for example, by the PostgreSQL protocol adapter).
+func (s *server) runQuery(creds db.Tenant, database string, parsed *expr.Query, fn func(ion.Datum) error) error {
+	tenantID := creds.ID()
+	var cfg *db.TenantConfig
+	if ct, ok := creds.(db.TenantConfigurable); ok {
+		cfg = ct.Config()
+	}
+	if cfg != nil && cfg.FiscalYearStart != 0 {
+		if err := parsed.SetFiscalYearStart(cfg.FiscalYearStart); err != nil {
+			s.logger.Printf("tenant %s: %s", tenantID, err)
+			return errors.New("invalid tenant configuration")
+		}
+	}
+	if err := parsed.Check(); err != nil {
+		return err
+	}
+	env, err := sneller.Environ(creds, database)
+	if err != nil {
+		s.logger.Printf("refusing query: %s", err)
+		return errTenantDisallowed
+	}
+	queryID := uuid.New()
+	defer s.queries.add(tenantID, sneller.QueryInfo{
+		ID:       queryID.String(),
+		Database: database,
+		Query:    parsed.Redacted(),
+		Start:    time.Now(),
+	})()
+	env.Queries = func() []sneller.QueryInfo {
+		return s.queries.list(tenantID)
+	}
+	var tree *plan.Tree
+	if peers := s.peers.Get(); len(peers) == 0 {
+		tree, err = plan.New(parsed, env)
+	} else {
+		id, key := tenantKeys(creds)
+		env.Splitter = s.newSplitter(id, key, peers)
+		tree, err = plan.NewSplit(parsed, env)
+	}
+	if err != nil {
+		s.logger.Printf("tenant %s query ID %s planning failed: %s", tenantID, queryID, err)
+		return err
+	}
+	maxScan := s.scanLimit(cfg, 0)
+	if willScan := uint64(tree.MaxScanned()); maxScan > 0 && willScan > maxScan {
+		return &errPlanLimit{scan: willScan, max: maxScan}
+	}
+	tree.MaxScan = int64(maxScan)
+	return s.runTree(creds, queryID, tree, func(conn net.Conn) error {
+		return readValues(conn, fn)
+	})
+}
+
+// runTree executes a query plan in the tenant
+// of creds and calls fn with the connection
+// from which the query output is read
+func (s *server) runTree(creds db.Tenant, queryID uuid.UUID, tree *plan.Tree, fn func(conn net.Conn) error) error {
+	tenantID := creds.ID()
+	id, key := tenantKeys(creds)
+	here, there, err := usock.SocketPair()
+	if err != nil {
+		return err
+	}
+	defer here.Close()
+	start := time.Now()
+	rc, err := s.manager.Do(id, key, tree, tnproto.OutputRaw, there)
+	there.Close()
+	if err != nil {
+		s.logger.Printf("tenant %s query ID %s execution failed (do): %v", tenantID, queryID, err)
+		if errors.Is(err, tenant.ErrOverloaded) {
+			return err
+		}
+		return errors.New("error dispatching query")
+	}
+	deadlined := setDeadline(rc, queryKillTimeout)
+	err = fn(here)
+	if err != nil {
+		// stop the query if fn gave up
+		here.Close()
+	}
+	var stats plan.ExecStats
+	if err2 := tenant.Check(rc, &stats); err == nil {
+		err = err2
+	}
+	if err != nil {
+		s.logger.Printf("tenant %s query ID %s execution failed (check): %v", tenantID, queryID, err)
+		if deadlined && isTimeout(err) {
+			s.manager.Quit(id)
+		}
+		return err
+	}
+	s.logger.Printf("tenant %s query ID %s duration %s bytes %d hits %d misses %d",
+		tenantID, queryID, time.Since(start), stats.BytesScanned, stats.CacheHits, stats.CacheMisses)
+	return nil
+}
+
+// readValues calls fn with each of
+// the ion values read from conn
+func readValues(conn net.Conn, fn func(ion.Datum) error) error {
+	dec := ion.NewDecoder(conn, maxResultValue)
+	for {
+		var d ion.Datum
+		err := dec.Decode(&d)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("reading query output: %w", err)
+		}
+		if err := fn(d); err != nil {
+			return err
+		}
+	}
+}
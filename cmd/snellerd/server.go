@@ -16,6 +16,7 @@ package main
 
 import (
 	"context"
+	"crypto/tls"
 	"log"
 	"net"
 	"net/http"
@@ -24,6 +25,7 @@ import (
 	"github.com/SnellerInc/sneller"
 	"github.com/SnellerInc/sneller/auth"
 	"github.com/SnellerInc/sneller/cgroup"
+	"github.com/SnellerInc/sneller/expr"
 	"github.com/SnellerInc/sneller/tenant"
 	"github.com/SnellerInc/sneller/tenant/tnproto"
 )
@@ -46,6 +48,34 @@ type server struct {
 	peers peerlist
 	auth  auth.Provider
 
+	// ingest buffers and commits
+	// data pushed to the /ingest endpoint
+	ingest ingester
+
+	// compression algorithms (in order of preference)
+	// that may be used to compress query results
+	// if the client accepts them; none if empty
+	compression []tnproto.Compression
+	// compression level for query results;
+	// zero selects the default level
+	compressLevel int8
+
+	// limits on the size and complexity
+	// of queries; zero fields are unlimited
+	limits expr.Limits
+
+	// request limits applied per client
+	// address and per authenticated tenant
+	ipLimit, tenantLimit limiter
+
+	// queries that are currently running;
+	// see the sneller_queries system table
+	queries queryRegistry
+
+	// repro bundles for recently failed
+	// queries; see the /repro/ endpoint
+	repros reproStore
+
 	// when we encounter an error
 	// listing peers, we fall back to
 	// this list (assuming it is non-nil)
@@ -54,17 +84,44 @@ type server struct {
 	// can be left 0 to use the default
 	splitSize int64
 
+	// compress connections to peers with zstd
+	peerCompression bool
+
+	// maxScan is the maximum number of bytes
+	// scanned by a query for tenants that do not
+	// configure their own limit (0 means no limit)
+	maxScan uint64
+
+	// placement assigns blobs to peers
+	// (nil uses sneller.DefaultPlacement)
+	placement sneller.Placement
+
 	// when started, the http server
 	srv http.Server
 	// when started, the address of the http listener
 	// and the tenant remote socket, respectively
 	bound, remote net.Addr
 
+	// if non-nil, the listener for
+	// PostgreSQL protocol clients
+	pgsock net.Listener
+	// if non-nil, the TLS configuration used
+	// when PostgreSQL clients request SSL;
+	// otherwise SSL requests are declined
+	pgTLS *tls.Config
+	// pgInsecure permits PostgreSQL clients
+	// to send their (cleartext) password
+	// over unencrypted connections
+	pgInsecure bool
+
 	// hack to avoid data races in testing
 	aboutToServe func()
 }
 
 func (s *server) Close() error {
+	if s.pgsock != nil {
+		s.pgsock.Close()
+	}
 	s.manager.Stop()
 	s.peers.Stop()
 	s.srv.Close()
@@ -72,6 +129,9 @@ func (s *server) Close() error {
 }
 
 func (s *server) Shutdown(ctx context.Context) error {
+	if s.pgsock != nil {
+		s.pgsock.Close()
+	}
 	if s.manager != nil {
 		s.manager.Stop()
 		s.manager = nil
@@ -84,9 +144,18 @@ func (s *server) handler() *http.ServeMux {
 	r.HandleFunc("/", s.handle(s.versionHandler, http.MethodGet))
 	r.HandleFunc("/ping", s.handle(s.pingHandler, http.MethodGet))
 	r.HandleFunc("/executeQuery", s.handle(s.executeQueryHandler, http.MethodHead, http.MethodGet, http.MethodPost))
+	r.HandleFunc("/streamQuery", s.handle(s.streamQueryHandler, http.MethodGet, http.MethodPost))
 	r.HandleFunc("/databases", s.handle(s.databasesHandler, http.MethodGet))
 	r.HandleFunc("/tables", s.handle(s.tablesHandler, http.MethodGet))
+	r.HandleFunc("/tables/", s.handle(s.tableHandler, http.MethodGet, http.MethodPut, http.MethodPatch, http.MethodDelete))
+	r.HandleFunc("/metadata/catalogs", s.handle(s.metadataCatalogsHandler, http.MethodGet))
+	r.HandleFunc("/metadata/schemas", s.handle(s.metadataSchemasHandler, http.MethodGet))
+	r.HandleFunc("/metadata/tables", s.handle(s.metadataTablesHandler, http.MethodGet))
+	r.HandleFunc("/metadata/columns", s.handle(s.metadataColumnsHandler, http.MethodGet))
+	r.HandleFunc("/metadata/types", s.handle(s.metadataTypesHandler, http.MethodGet))
 	r.HandleFunc("/inputs", s.handle(s.inputsHandler, http.MethodGet))
+	r.HandleFunc("/repro/", s.handle(s.reproHandler, http.MethodGet))
+	r.HandleFunc("/ingest/", s.handle(s.ingestHandler, http.MethodPost))
 	return r
 }
 
@@ -94,6 +163,9 @@ func (s *server) Serve(httpsock, tenantsock net.Listener) error {
 	opts := []tenant.Option{
 		tenant.WithLogger(s.logger),
 		tenant.WithRemote(tenantsock),
+		// the tenant command is the
+		// "worker" mode of this executable
+		tenant.WithHandshake(),
 	}
 	if s.cgroot != "" {
 		opts = append(opts, tenant.WithCgroup(func(id tnproto.ID) cgroup.Dir {
@@ -119,10 +191,19 @@ func (s *server) Serve(httpsock, tenantsock net.Listener) error {
 	}
 	// peers use the manager tenant socket, so this has
 	// to occur quite late:
+	s.peers.Watch(s.peersChanged)
 	err := s.peers.Start(5*time.Second, s.logger.Printf)
 	if err != nil {
 		s.logger.Fatal(err)
 	}
+	s.ingest.conf.Logf = s.logger.Printf
+	if s.pgsock != nil {
+		go func() {
+			if err := s.servePG(s.pgsock); err != nil {
+				s.logger.Printf("postgres listener: %s", err)
+			}
+		}()
+	}
 	s.srv.Handler = s.handler()
 	if s.aboutToServe != nil {
 		s.aboutToServe()
@@ -130,12 +211,27 @@ func (s *server) Serve(httpsock, tenantsock net.Listener) error {
 	return s.srv.Serve(httpsock)
 }
 
+// peersChanged logs changes in cluster membership;
+// peers that have been removed receive no new
+// queries, but queries already running on them
+// are allowed to complete
+func (s *server) peersChanged(c peerChange) {
+	for _, p := range c.Added {
+		s.logger.Printf("peer %s joined", p)
+	}
+	for _, p := range c.Removed {
+		s.logger.Printf("peer %s removed; draining", p)
+	}
+}
+
 func (s *server) newSplitter(id tnproto.ID, key tnproto.Key, peers []*net.TCPAddr) *sneller.Splitter {
 	split := &sneller.Splitter{
 		SplitSize: s.splitSize,
 		WorkerID:  id,
 		WorkerKey: key,
 		Peers:     peers,
+		Compress:  s.peerCompression,
+		Placement: s.placement,
 	}
 	if s.remote != nil {
 		split.SelfAddr = s.remote.String()
@@ -24,12 +24,12 @@ import (
 )
 
 // testPeers is a wrapper around the
-// "production" peerCmd implementation
+// "production" peerWatcher implementation
 // that writes a static list to a file
 // and makes the command implementation
 // just "cat <file>"
 type testPeers struct {
-	peerCmd
+	peerWatcher
 	tt   *testing.T
 	list []*net.TCPAddr
 }
@@ -55,6 +55,6 @@ func (t *testPeers) Start(interval time.Duration, logf func(string, ...interface
 	if err != nil {
 		t.tt.Fatal(err)
 	}
-	t.cmd = []string{"cat", name}
-	return t.peerCmd.Start(interval, logf)
+	t.src = &cmdDiscovery{cmd: []string{"cat", name}}
+	return t.peerWatcher.Start(interval, logf)
 }
@@ -0,0 +1,203 @@
+// Copyright (C) 2022 Sneller, Inc.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/fs"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/SnellerInc/sneller/db"
+
+	"golang.org/x/exp/slices"
+)
+
+func TestTableDDL(t *testing.T) {
+	testFiles(t)
+	s := empty(t)
+
+	httpsock := listen(t)
+	go s.Serve(httpsock, nil)
+	host := "http://" + httpsock.Addr().String()
+
+	do := func(method, uri, body string) (int, []byte) {
+		var rd io.Reader
+		if body != "" {
+			rd = strings.NewReader(body)
+		}
+		req, err := http.NewRequest(method, host+uri, rd)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Authorization", "Bearer snellerd-test")
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer res.Body.Close()
+		buf, err := io.ReadAll(res.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return res.StatusCode, buf
+	}
+
+	steps := []struct {
+		method, uri, body string
+		code              int
+	}{
+		{http.MethodGet, "/tables/default/ddl", "", http.StatusNotFound},
+		{http.MethodPut, "/tables/default/ddl", `{"partitions": [{"field": "region", "type": "float"}]}`, http.StatusBadRequest},
+		{http.MethodPut, "/tables/default/ddl", `{"name": "other"}`, http.StatusBadRequest},
+		{http.MethodPut, "/tables/default/ddl", `{"partitions": [{"field": "region"}]}`, http.StatusCreated},
+		{http.MethodPut, "/tables/default/ddl", `{}`, http.StatusConflict},
+		{http.MethodPatch, "/tables/default/ddl", `{"retention_policy": {"field": "ts", "valid_for": "30d"}}`, http.StatusOK},
+		{http.MethodPatch, "/tables/default/ddl", `{"bogus": true}`, http.StatusBadRequest},
+		{http.MethodPatch, "/tables/default/missing", `{"drop_retention_policy": true}`, http.StatusNotFound},
+		{http.MethodGet, "/tables/default/ddl", "", http.StatusOK},
+	}
+	var last []byte
+	for i := range steps {
+		code, body := do(steps[i].method, steps[i].uri, steps[i].body)
+		if code != steps[i].code {
+			t.Fatalf("%s %s %s: got %d, want %d (%s)", steps[i].method, steps[i].uri, steps[i].body, code, steps[i].code, body)
+		}
+		last = body
+	}
+	var def db.Definition
+	if err := json.Unmarshal(last, &def); err != nil {
+		t.Fatal(err)
+	}
+	if def.Name != "ddl" || len(def.Partitions) != 1 || def.Retention == nil || def.Retention.Field != "ts" {
+		t.Fatalf("unexpected definition %+v", def)
+	}
+	tables := func() []string {
+		code, body := do(http.MethodGet, "/tables?database=default", "")
+		if code != http.StatusOK {
+			t.Fatalf("listing tables: %d", code)
+		}
+		var out []string
+		if err := json.Unmarshal(body, &out); err != nil {
+			t.Fatal(err)
+		}
+		return out
+	}
+	// tables are listed once they contain data
+	if contains(tables(), "ddl") {
+		t.Fatal("empty table listed")
+	}
+	if code, body := do(http.MethodPost, "/ingest/default/ddl", `{"region": "us", "ts": "2022-01-01T00:00:00Z"}`); code != http.StatusOK {
+		t.Fatalf("ingest: got %d (%s)", code, body)
+	}
+	if !contains(tables(), "ddl") {
+		t.Fatal("created table not listed")
+	}
+	if code, _ := do(http.MethodDelete, "/tables/default/ddl", ""); code != http.StatusNoContent {
+		t.Fatalf("DROP: got %d", code)
+	}
+	if contains(tables(), "ddl") {
+		t.Fatal("dropped table still listed")
+	}
+	if code, _ := do(http.MethodDelete, "/tables/default/ddl", ""); code != http.StatusNotFound {
+		t.Fatalf("second DROP: got %d", code)
+	}
+}
+
+func TestSQLDDL(t *testing.T) {
+	testFiles(t)
+	s := empty(t)
+
+	httpsock := listen(t)
+	go s.Serve(httpsock, nil)
+	host := "http://" + httpsock.Addr().String()
+
+	exec := func(query string) (int, []byte) {
+		req, err := http.NewRequest(http.MethodPost, host+"/executeQuery?database=default", strings.NewReader(query))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Authorization", "Bearer snellerd-test")
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer res.Body.Close()
+		buf, err := io.ReadAll(res.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return res.StatusCode, buf
+	}
+
+	steps := []struct {
+		query string
+		code  int
+	}{
+		{"CREATE TABLE sqlddl PARTITION BY (region float)", http.StatusBadRequest},
+		{"CREATE TABLE sqlddl RETENTION ON ts FOR 'forever'", http.StatusBadRequest},
+		{"CREATE TABLE sqlddl (ts timestamp not null, user.id int) PARTITION BY (region) SORT BY (ts)", http.StatusCreated},
+		{"CREATE TABLE sqlddl", http.StatusConflict},
+		{"CREATE TABLE IF NOT EXISTS sqlddl", http.StatusOK},
+		{"ALTER TABLE sqlddl SET RETENTION ON ts FOR '30d', DROP PARTITION BY", http.StatusOK},
+		{"ALTER TABLE nosuch DROP RETENTION", http.StatusNotFound},
+		{"DROP TABLE nosuch", http.StatusNotFound},
+		{"DROP TABLE IF EXISTS nosuch", http.StatusNoContent},
+	}
+	var last []byte
+	for i := range steps {
+		code, body := exec(steps[i].query)
+		if code != steps[i].code {
+			t.Fatalf("%s: got %d, want %d (%s)", steps[i].query, code, steps[i].code, body)
+		}
+		if code == http.StatusOK {
+			last = body
+		}
+	}
+	var def db.Definition
+	if err := json.Unmarshal(last, &def); err != nil {
+		t.Fatal(err)
+	}
+	if def.Name != "sqlddl" || len(def.Partitions) != 0 || def.Retention == nil ||
+		def.Retention.Field != "ts" || len(def.SortKeys) != 1 {
+		t.Fatalf("unexpected definition %+v", def)
+	}
+	root, err := s.auth.Authorize(context.Background(), "snellerd-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rootfs, err := root.Root()
+	if err != nil {
+		t.Fatal(err)
+	}
+	hints, err := db.OpenTypeHints(rootfs, "default", "sqlddl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(hints.Fields["ts"], []string{"timestamp"}) ||
+		!slices.Equal(hints.Fields["user.id"], []string{"int", "null", "missing"}) {
+		t.Fatalf("unexpected type hints %v", hints.Fields)
+	}
+	if code, body := exec("DROP TABLE default.sqlddl"); code != http.StatusNoContent {
+		t.Fatalf("DROP: got %d (%s)", code, body)
+	}
+	if _, err := db.OpenDefinition(rootfs, "default", "sqlddl"); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("definition of dropped table: %v", err)
+	}
+}
@@ -0,0 +1,86 @@
+// Copyright (C) 2022 Sneller, Inc.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/SnellerInc/sneller/date"
+	"github.com/SnellerInc/sneller/ion/blockfmt"
+)
+
+// maxAppendAttempts is the number of times
+// that AppendDescriptors will reload the index
+// and try again after losing a race with
+// another writer
+const maxAppendAttempts = 5
+
+// AppendDescriptors atomically appends lst to
+// the inline portion of the index for db/table,
+// creating the index if it does not exist yet.
+// Each descriptor must refer to a packfile that
+// lives under the db/<db>/<table>/ prefix.
+//
+// If the index is modified by another process
+// between the time it is loaded and the time it is
+// written back, AppendDescriptors reloads the index
+// and tries again. If the conflict persists, the
+// returned error satisfies errors.Is(err, ErrConflict).
+func (c *Config) AppendDescriptors(who Tenant, db, table string, lst []blockfmt.Descriptor) error {
+	if !validName(db) || !validName(table) {
+		return fmt.Errorf("AppendDescriptors: invalid table %q/%q", db, table)
+	}
+	prefix := path.Join("db", db, table) + "/"
+	for i := range lst {
+		if !strings.HasPrefix(lst[i].Path, prefix) {
+			return fmt.Errorf("AppendDescriptors: %s is not under %s", lst[i].Path, prefix)
+		}
+	}
+	st, err := c.open(db, table, who)
+	if err != nil {
+		return err
+	}
+	for i := 0; i < maxAppendAttempts; i++ {
+		err = st.appendDescs(context.Background(), lst)
+		if !errors.Is(err, ErrConflict) {
+			break
+		}
+		st.logf("append: %s; retrying", err)
+	}
+	return err
+}
+
+func (st *tableState) appendDescs(ctx context.Context, lst []blockfmt.Descriptor) error {
+	idx, err := st.index(ctx)
+	if errors.Is(err, fs.ErrNotExist) {
+		idx = &blockfmt.Index{
+			Name: st.table,
+			Algo: "zstd",
+		}
+	} else if err != nil {
+		return err
+	}
+	// flush invalidates the cached index on failure,
+	// so a retry will always start from a fresh copy
+	idx.Inline = append(idx.Inline, lst...)
+	idx.Created = date.Now().Truncate(time.Microsecond)
+	return st.flush(ctx, idx)
+}
@@ -467,3 +467,144 @@ func TestAppendBadScan(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+func TestConfigAppend(t *testing.T) {
+	checkFiles(t)
+	dfs := newDirFS(t, t.TempDir())
+	owner := newTenant(dfs)
+	err := WriteDefinition(dfs, "default", &Definition{Name: "pushed"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := Config{
+		Align: 1024,
+		Logf:  t.Logf,
+	}
+	mkinput := func(name, text string) blockfmt.Input {
+		return blockfmt.Input{
+			Path: "push://default/pushed/" + name,
+			ETag: name,
+			Size: int64(len(text)),
+			R:    io.NopCloser(strings.NewReader(text)),
+			F:    blockfmt.MustSuffixToFormat(".json"),
+		}
+	}
+	err = c.Append(owner, "default", "pushed", []blockfmt.Input{
+		mkinput("a", `{"x": 1}`+"\n"+`{"x": 2}`),
+		mkinput("b", `{"x": 3}`),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	idx, err := OpenIndex(dfs, "default", "pushed", owner.Key())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(idx.Inline) != 1 {
+		t.Fatalf("expected 1 packfile; got %d", len(idx.Inline))
+	}
+	if len(idx.Inline[0].Trailer.Blocks) == 0 {
+		t.Error("packfile has no blocks")
+	}
+
+	// appending the same input again is a no-op
+	err = c.Append(owner, "default", "pushed", []blockfmt.Input{
+		mkinput("b", `{"x": 3}`),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	idx, err = OpenIndex(dfs, "default", "pushed", owner.Key())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(idx.Inline) != 1 {
+		t.Fatalf("expected 1 packfile; got %d", len(idx.Inline))
+	}
+
+	// a malformed input is reported
+	in := []blockfmt.Input{mkinput("c", `{"x": `)}
+	err = c.Append(owner, "default", "pushed", in)
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	if in[0].Err == nil {
+		t.Error("expected input error to be populated")
+	}
+}
+
+func TestAppendDescriptors(t *testing.T) {
+	dfs := newDirFS(t, t.TempDir())
+	owner := newTenant(dfs)
+	c := Config{Logf: t.Logf}
+	mkdescs := func(names ...string) []blockfmt.Descriptor {
+		out := make([]blockfmt.Descriptor, len(names))
+		for i := range names {
+			out[i].Path = "db/default/out/" + names[i]
+			out[i].ETag = "etag-" + names[i]
+			out[i].Size = 100
+		}
+		return out
+	}
+	check := func(want ...string) {
+		t.Helper()
+		idx, err := OpenIndex(dfs, "default", "out", owner.Key())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(idx.Inline) != len(want) {
+			t.Fatalf("got %d descriptors; expected %d", len(idx.Inline), len(want))
+		}
+		for i := range want {
+			if p := "db/default/out/" + want[i]; idx.Inline[i].Path != p {
+				t.Errorf("descriptor %d: got %s, expected %s", i, idx.Inline[i].Path, p)
+			}
+		}
+	}
+
+	// the first append creates the index
+	err := c.AppendDescriptors(owner, "default", "out", mkdescs("a", "b"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	check("a", "b")
+	err = c.AppendDescriptors(owner, "default", "out", mkdescs("c"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	check("a", "b", "c")
+
+	// descriptors must belong to the table
+	bad := mkdescs("d")
+	bad[0].Path = "db/default/other/d"
+	err = c.AppendDescriptors(owner, "default", "out", bad)
+	if err == nil {
+		t.Fatal("expected an error appending a foreign descriptor")
+	}
+	check("a", "b", "c")
+
+	// a concurrent writer causes a conflict
+	st, err := c.open("default", "out", owner)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = st.index(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = c.AppendDescriptors(owner, "default", "out", mkdescs("d"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = st.appendDescs(context.Background(), mkdescs("e"))
+	if !errors.Is(err, ErrConflict) {
+		t.Fatalf("expected ErrConflict; got %v", err)
+	}
+	check("a", "b", "c", "d")
+	// ... and retrying picks up the new index
+	err = st.appendDescs(context.Background(), mkdescs("e"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	check("a", "b", "c", "d", "e")
+}
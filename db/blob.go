@@ -79,10 +79,24 @@ func noIfMatch(fs FS) bool {
 // then the returned blob list will be comprised only
 // of blobs for which the filter condition is satisfied
 // by at least one row in the data pointed to by the blob.
+// The blobs for encrypted packfiles carry the
+// wrapped data key of the index so that it
+// can be resolved at execution time
+// (see BlobsWithKey).
 //
 // Note that the returned blob.List may consist
 // of zero blobs if the index has no contents.
 func Blobs(src FS, idx *blockfmt.Index, keep *blockfmt.Filter) (*blob.List, int64, error) {
+	return BlobsWithKey(src, idx, nil, keep)
+}
+
+// BlobsWithKey is identical to Blobs,
+// except that the blobs for encrypted packfiles
+// are decrypted with key, which should be the
+// unwrapped data key of the index
+// (see blockfmt.Index.DataKey).
+// If key is nil, BlobsWithKey is equivalent to Blobs.
+func BlobsWithKey(src FS, idx *blockfmt.Index, key *blockfmt.DataKey, keep *blockfmt.Filter) (*blob.List, int64, error) {
 	out := &blob.List{}
 	var size int64
 	var err error
@@ -90,7 +104,7 @@ func Blobs(src FS, idx *blockfmt.Index, keep *blockfmt.Filter) (*blob.List, int6
 		if idx.Inline[i].Format != blockfmt.Version {
 			return nil, 0, fmt.Errorf("don't know how to convert format %q into a blob", idx.Inline[i].Format)
 		}
-		out.Contents, err = descToBlobs(src, &idx.Inline[i], keep, out.Contents, &size)
+		out.Contents, err = descToBlobs(src, &idx.Inline[i], key, idx.DataKey, keep, out.Contents, &size)
 		if err != nil {
 			return nil, 0, err
 		}
@@ -101,7 +115,7 @@ func Blobs(src FS, idx *blockfmt.Index, keep *blockfmt.Filter) (*blob.List, int6
 		return out, size, err
 	}
 	for i := range descs {
-		out.Contents, err = descToBlobs(src, &descs[i], keep, out.Contents, &size)
+		out.Contents, err = descToBlobs(src, &descs[i], key, idx.DataKey, keep, out.Contents, &size)
 		if err != nil {
 			return out, size, err
 		}
@@ -109,7 +123,7 @@ func Blobs(src FS, idx *blockfmt.Index, keep *blockfmt.Filter) (*blob.List, int6
 	return out, size, nil
 }
 
-func descToBlobs(src FS, b *blockfmt.Descriptor, keep *blockfmt.Filter, into []blob.Interface, size *int64) ([]blob.Interface, error) {
+func descToBlobs(src FS, b *blockfmt.Descriptor, key *blockfmt.DataKey, wrapped []byte, keep *blockfmt.Filter, into []blob.Interface, size *int64) ([]blob.Interface, error) {
 	var self *blob.Compressed
 	info := (*descInfo)(b)
 	uri, err := src.URL(b.Path, info, b.ETag)
@@ -146,6 +160,13 @@ func descToBlobs(src FS, b *blockfmt.Descriptor, keep *blockfmt.Filter, into []b
 				},
 				Trailer: b.Trailer,
 			}
+			if b.Trailer.Encrypted {
+				self.Path = b.Path
+				self.Key = key
+				if key == nil {
+					self.WrappedKey = wrapped
+				}
+			}
 		}
 		// for now, just map blocks -> blobs 1:1
 		for i := start; i < end; i++ {
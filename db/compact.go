@@ -0,0 +1,477 @@
+// Copyright (C) 2022 Sneller, Inc.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package db
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"runtime/trace"
+	"sync"
+	"time"
+
+	"github.com/SnellerInc/sneller/date"
+	"github.com/SnellerInc/sneller/ion"
+	"github.com/SnellerInc/sneller/ion/blockfmt"
+
+	"golang.org/x/exp/slices"
+)
+
+// compaction is a run of packfiles
+// from one partition that should be
+// merged into a single packfile
+type compaction struct {
+	part  string
+	descs []blockfmt.Descriptor
+	size  int64
+	out   blockfmt.Descriptor
+}
+
+func (c *compaction) merged() bool { return len(c.descs) > 1 }
+
+func sameConsts(a, b *blockfmt.Descriptor) bool {
+	ca := a.Trailer.Sparse.Consts()
+	cb := b.Trailer.Sparse.Consts()
+	if len(ca) != len(cb) {
+		return false
+	}
+	return len(ca) == 0 ||
+		ion.NewStruct(nil, ca).Equal(ion.NewStruct(nil, cb))
+}
+
+// compactable returns the number of leading
+// entries in idx.Inline that are candidates
+// for compaction; the trailing run of packfiles
+// that are still accepting new inputs
+// (see findPrepend) is left in place
+func (st *tableState) compactable(idx *blockfmt.Index) int {
+	n := len(idx.Inline)
+	seen := make(map[string]bool)
+	for n > 0 {
+		d := &idx.Inline[n-1]
+		part, ok := st.partitionFor(d.Path)
+		if !ok || seen[part] || d.Size >= st.conf.minMergeSize() {
+			break
+		}
+		seen[part] = true
+		n--
+	}
+	// always leave at least one descriptor
+	// inline so that the index continues to
+	// advertise its partitions (see Index.HasPartition)
+	if n == len(idx.Inline) && n > 0 {
+		n--
+	}
+	return n
+}
+
+// planCompaction splits lst into a list of
+// compactions, preserving the order in which
+// the packfiles in each partition were written
+func (st *tableState) planCompaction(lst []blockfmt.Descriptor) []*compaction {
+	var out []*compaction
+	open := make(map[string]*compaction)
+	target := int64(st.conf.targetMerge())
+	for i := range lst {
+		d := &lst[i]
+		part, ok := st.partitionFor(d.Path)
+		if !ok || d.Size >= st.conf.minMergeSize() {
+			out = append(out, &compaction{descs: []blockfmt.Descriptor{*d}})
+			continue
+		}
+		cur := open[part]
+		if cur != nil && cur.size+d.Size <= target && sameConsts(&cur.descs[0], d) {
+			cur.descs = append(cur.descs, *d)
+			cur.size += d.Size
+			continue
+		}
+		cur = &compaction{
+			part:  part,
+			descs: []blockfmt.Descriptor{*d},
+			size:  d.Size,
+		}
+		open[part] = cur
+		out = append(out, cur)
+	}
+	return out
+}
+
+// openPacked returns a reader that produces
+// the decompressed contents of a packfile;
+// key is used to decrypt the packfile if necessary
+func (st *tableState) openPacked(d *blockfmt.Descriptor, key *blockfmt.DataKey) (io.ReadCloser, error) {
+	f, err := open(st.ofs, d.Path, d.ETag, d.Size)
+	if err != nil {
+		return nil, err
+	}
+	r, w := io.Pipe()
+	go func() {
+		defer f.Close()
+		dec := blockfmt.Decoder{Name: d.Path, Path: d.Path, Key: key}
+		dec.SetRange(&d.Trailer, 0, len(d.Trailer.Blocks))
+		_, err := dec.Copy(w, io.LimitReader(f, d.Trailer.Offset))
+		w.CloseWithError(err)
+	}()
+	return r, nil
+}
+
+func (st *tableState) compactPart(ctx context.Context, cmp *compaction, key *blockfmt.DataKey) error {
+	defer trace.StartRegion(ctx, "compact-part").End()
+	first := &cmp.descs[0]
+	c := blockfmt.Converter{
+		Align:     st.conf.align(),
+		FlushMeta: st.conf.flushMeta(),
+		Comp:      st.conf.comp(),
+		Checksums: st.conf.Checksums,
+		Key:       key,
+		Constants: first.Trailer.Sparse.Consts(),
+		SortKeys:  st.sortKeys(),
+		// use a single stream so that
+		// rows are written in their original order
+		Parallel: 1,
+	}
+	closeall := func() {
+		for i := range c.Inputs {
+			c.Inputs[i].R.Close()
+		}
+	}
+	for i := range cmp.descs[1:] {
+		d := &cmp.descs[i+1]
+		r, err := st.openPacked(d, key)
+		if err != nil {
+			closeall()
+			return fmt.Errorf("opening %s for compaction: %w", d.Path, err)
+		}
+		c.Inputs = append(c.Inputs, blockfmt.Input{
+			Path: d.Path,
+			ETag: d.ETag,
+			Size: d.Trailer.Decompressed(),
+			R:    r,
+			F:    blockfmt.UnsafeION(),
+		})
+	}
+	f, err := open(st.ofs, first.Path, first.ETag, first.Size)
+	if err != nil {
+		closeall()
+		return fmt.Errorf("opening %s for compaction: %w", first.Path, err)
+	}
+	defer f.Close()
+	c.Prepend.R = f
+	c.Prepend.Trailer = &first.Trailer
+	c.Prepend.Path = first.Path
+
+	name := "packed-" + uuid() + suffixForComp(c.Comp)
+	fp := path.Join("db", st.db, st.table, cmp.part, name)
+	out, err := st.ofs.Create(fp)
+	if err != nil {
+		closeall()
+		return err
+	}
+	c.Output = out
+	c.Path = fp
+	err = c.Run()
+	if err != nil {
+		closeall()
+		abort(out)
+		return err
+	}
+	etag, lastmod, err := getInfo(st.ofs, fp, out)
+	if err != nil {
+		closeall()
+		st.discard(fp)
+		return err
+	}
+	cmp.out = blockfmt.Descriptor{
+		ObjectInfo: blockfmt.ObjectInfo{
+			Path:         fp,
+			LastModified: date.FromTime(lastmod),
+			ETag:         etag,
+			Format:       blockfmt.Version,
+			Size:         out.Size(),
+		},
+		Trailer: *c.Trailer(),
+	}
+	return nil
+}
+
+// discard removes packfiles that were written
+// but are not (and will never be) referenced by the index
+func (st *tableState) discard(paths ...string) {
+	rfs, ok := st.ofs.(RemoveFS)
+	if !ok {
+		// left for GCConfig.RemoveOrphans
+		return
+	}
+	for _, p := range paths {
+		err := rfs.Remove(p)
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			st.logf("removing %s: %s", p, err)
+		}
+	}
+}
+
+// A Compaction is a compaction of a table
+// whose merged packfiles have been written
+// but not yet committed to the table index.
+//
+// See Config.PrepareCompaction.
+type Compaction struct {
+	st      *tableState
+	n       int                   // number of leading inline descriptors replaced
+	prefix  []blockfmt.Descriptor // idx.Inline[:n] when planned
+	plan    []*compaction
+	todo    []*compaction
+	wrapped []byte
+}
+
+// Packfiles returns the number of packfiles
+// that are replaced when c is committed.
+func (c *Compaction) Packfiles() int { return c.n }
+
+func (c *Compaction) outputs() []string {
+	var out []string
+	for _, cmp := range c.todo {
+		if cmp.out.Path != "" {
+			out = append(out, cmp.out.Path)
+		}
+	}
+	return out
+}
+
+// applies returns whether the packfiles
+// that c replaces are still the leading
+// inline packfiles in idx
+func (c *Compaction) applies(idx *blockfmt.Index) bool {
+	if len(idx.Inline) < c.n {
+		return false
+	}
+	for i := range c.prefix {
+		if idx.Inline[i].Path != c.prefix[i].Path ||
+			idx.Inline[i].ETag != c.prefix[i].ETag {
+			return false
+		}
+	}
+	return len(idx.DataKey) == 0 || bytes.Equal(idx.DataKey, c.wrapped)
+}
+
+// prepareCompaction plans a compaction of idx and
+// writes the merged packfiles; it returns a nil
+// *Compaction if there is nothing to compact
+func (st *tableState) prepareCompaction(ctx context.Context, idx *blockfmt.Index) (*Compaction, error) {
+	n := st.compactable(idx)
+	plan := st.planCompaction(idx.Inline[:n])
+	var todo []*compaction
+	for i := range plan {
+		if plan[i].merged() {
+			todo = append(todo, plan[i])
+		}
+	}
+	if len(todo) == 0 {
+		return nil, nil
+	}
+	key, wrapped, err := st.dataKey(idx)
+	if err != nil {
+		return nil, err
+	}
+	c := &Compaction{
+		st:      st,
+		n:       n,
+		prefix:  slices.Clone(idx.Inline[:n]),
+		plan:    plan,
+		todo:    todo,
+		wrapped: wrapped,
+	}
+	errs := make([]error, len(todo))
+	var wg sync.WaitGroup
+	wg.Add(len(todo))
+	for i := range todo {
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = st.compactPart(ctx, todo[i], key)
+		}(i)
+	}
+	wg.Wait()
+	if err := combine(errs); err != nil {
+		st.discard(c.outputs()...)
+		return nil, fmt.Errorf("compacting %s/%s: %w", st.db, st.table, err)
+	}
+	return c, nil
+}
+
+// commitCompaction replaces the packfiles
+// compacted by c in idx and writes the new index
+func (st *tableState) commitCompaction(ctx context.Context, idx *blockfmt.Index, c *Compaction) error {
+	if st.shouldScan() && idx.Scanning {
+		c.Abort()
+		return ErrBuildAgain
+	}
+	if !c.applies(idx) {
+		c.Abort()
+		return fmt.Errorf("%w: %s/%s was modified during compaction", ErrConflict, st.db, st.table)
+	}
+	idx.DataKey = c.wrapped
+	result := make([]blockfmt.Descriptor, 0, len(c.plan))
+	expiry := date.Now().Add(st.conf.GCMinimumAge)
+	merged := 0
+	for _, cmp := range c.plan {
+		if !cmp.merged() {
+			result = append(result, cmp.descs[0])
+			continue
+		}
+		result = append(result, cmp.out)
+		for i := range cmp.descs {
+			idx.ToDelete = append(idx.ToDelete, blockfmt.Quarantined{
+				Path:   cmp.descs[i].Path,
+				Expiry: expiry,
+			})
+		}
+		merged += len(cmp.descs)
+	}
+	ic := blockfmt.IndexConfig{
+		TargetSize:    int64(st.conf.targetMerge()),
+		TargetRefSize: st.conf.TargetRefSize,
+		Expiry:        st.conf.GCMinimumAge,
+	}
+	err := ic.Demote(idx, st.ofs, path.Join("db", st.db, st.table), c.n, result)
+	if err != nil {
+		// idx has been partially modified,
+		// and the outputs are unreferenced
+		st.invalidate()
+		c.Abort()
+		return err
+	}
+	st.logf("compacted %d packfiles into %d", merged, len(c.todo))
+	idx.Created = date.Now().Truncate(time.Microsecond)
+	err = st.flush(ctx, idx)
+	if errors.Is(err, ErrConflict) {
+		// the index was not written,
+		// so the outputs are unreferenced
+		c.Abort()
+	}
+	return err
+}
+
+// PrepareCompaction performs the expensive part
+// of Compact for db/table: it plans a compaction
+// and writes the merged packfiles, but it does not
+// modify the table index. It returns (nil, nil) if
+// there is nothing to compact.
+//
+// PrepareCompaction may run concurrently with
+// ingestion into the same table; the returned
+// Compaction can be committed as long as the
+// packfiles that it replaces have not been
+// modified in the meantime. Callers that run
+// compactions in the background should serialize
+// Compaction.Commit with other updates to the table.
+func (c *Config) PrepareCompaction(who Tenant, db, table string) (*Compaction, error) {
+	st, err := c.open(db, table, who)
+	if err != nil {
+		return nil, err
+	}
+	ctx := context.Background()
+	idx, err := st.index(ctx)
+	if err != nil {
+		return nil, err
+	}
+	idx.Inputs.Backing = st.ofs
+	if st.shouldScan() && idx.Scanning {
+		return nil, ErrBuildAgain
+	}
+	return st.prepareCompaction(ctx, idx)
+}
+
+// Commit replaces the compacted packfiles in
+// the table index with the merged packfiles.
+// If the index no longer begins with the packfiles
+// that were compacted (for example, because they
+// were compacted or deleted from by another process),
+// Commit removes the merged packfiles and returns
+// an error satisfying errors.Is(err, ErrConflict).
+func (c *Compaction) Commit() error {
+	// re-read the index; it may have been
+	// updated since the compaction was prepared
+	c.st.invalidate()
+	ctx := context.Background()
+	idx, err := c.st.index(ctx)
+	if err != nil {
+		return err
+	}
+	idx.Inputs.Backing = c.st.ofs
+	return c.st.commitCompaction(ctx, idx, c)
+}
+
+// Abort removes the merged packfiles
+// written for a compaction that will
+// not be committed.
+func (c *Compaction) Abort() {
+	c.st.discard(c.outputs()...)
+}
+
+// Compact merges undersized packfiles in
+// the index for db/table into larger packfiles.
+//
+// Packfiles that are referenced directly from
+// the index (blockfmt.Index.Inline) and are smaller
+// than MinMergeSize are merged with other packfiles
+// from the same partition (and with the same partition
+// constants) until the merged packfiles reach TargetMergeSize.
+// Rows are written to the merged packfiles in the same order
+// in which they were originally ingested. The compacted
+// descriptors are moved into the indirect portion of the
+// index, and the packfiles that they replace are scheduled
+// for deletion once GCMinimumAge has elapsed.
+// The most recent packfiles in each partition are
+// not compacted so that they can continue to absorb
+// new inputs during Sync.
+//
+// Compact only writes a new index if the index
+// was not modified by another process while the
+// compaction was in progress; otherwise, it returns
+// an error and the index is left unmodified.
+// The caller is responsible for serializing calls
+// to Compact that target the same table.
+func (c *Config) Compact(who Tenant, db, table string) error {
+	st, err := c.open(db, table, who)
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+	idx, err := st.index(ctx)
+	if err != nil {
+		return err
+	}
+	idx.Inputs.Backing = st.ofs
+	if st.shouldScan() && idx.Scanning {
+		return ErrBuildAgain
+	}
+	dirty := st.preciseGC(idx)
+	cmp, err := st.prepareCompaction(ctx, idx)
+	if err != nil {
+		st.invalidate()
+		return err
+	}
+	if cmp == nil {
+		if dirty {
+			return st.flush(ctx, idx)
+		}
+		return nil
+	}
+	return st.commitCompaction(ctx, idx, cmp)
+}
@@ -0,0 +1,347 @@
+// Copyright (C) 2022 Sneller, Inc.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/SnellerInc/sneller/ion"
+	"github.com/SnellerInc/sneller/ion/blockfmt"
+)
+
+// countRows returns the row values
+// of field "x" in the order they
+// appear in the descriptors
+func countRows(t *testing.T, st *tableState, key *blockfmt.DataKey, lst []blockfmt.Descriptor) []int64 {
+	var out []int64
+	for i := range lst {
+		r, err := st.openPacked(&lst[i], key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		buf, err := io.ReadAll(r)
+		r.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var st ion.Symtab
+		var d ion.Datum
+		for len(buf) > 0 {
+			d, buf, err = ion.ReadDatum(&st, buf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if d.IsEmpty() || !d.IsStruct() {
+				continue
+			}
+			s, _ := d.Struct()
+			f, ok := s.FieldByName("x")
+			if !ok {
+				t.Fatalf("row %s missing x", d)
+			}
+			x, _ := f.Int()
+			out = append(out, x)
+		}
+	}
+	return out
+}
+
+func TestCompact(t *testing.T) {
+	checkFiles(t)
+	dfs := newDirFS(t, t.TempDir())
+	owner := newTenant(dfs)
+	err := WriteDefinition(dfs, "default", &Definition{Name: "small"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := Config{
+		Align: 1024,
+		// produce a new packfile for every append
+		MinMergeSize: 1,
+		Logf:         t.Logf,
+	}
+	const appends = 6
+	for i := 0; i < appends; i++ {
+		text := fmt.Sprintf("{\"x\": %d}\n{\"x\": %d}", 2*i, 2*i+1)
+		err := c.Append(owner, "default", "small", []blockfmt.Input{{
+			Path: fmt.Sprintf("push://default/small/%d", i),
+			ETag: fmt.Sprintf("etag-%d", i),
+			Size: int64(len(text)),
+			R:    io.NopCloser(strings.NewReader(text)),
+			F:    blockfmt.MustSuffixToFormat(".json"),
+		}})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	idx, err := OpenIndex(dfs, "default", "small", owner.Key())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(idx.Inline) != appends {
+		t.Fatalf("expected %d packfiles; got %d", appends, len(idx.Inline))
+	}
+	before := idx.Objects()
+
+	c.MinMergeSize = 0
+	err = c.Compact(owner, "default", "small")
+	if err != nil {
+		t.Fatal(err)
+	}
+	idx, err = OpenIndex(dfs, "default", "small", owner.Key())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the most recent packfile stays inline;
+	// the rest are merged into one packfile
+	if len(idx.Inline) != 1 {
+		t.Fatalf("expected 1 inline packfile; got %d", len(idx.Inline))
+	}
+	if idx.Objects() != before {
+		t.Errorf("object numbering changed from %d to %d", before, idx.Objects())
+	}
+	if len(idx.ToDelete) != appends-1 {
+		t.Errorf("expected %d objects in ToDelete; got %d", appends-1, len(idx.ToDelete))
+	}
+	descs, err := idx.Indirect.Search(dfs, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(descs) != 1 {
+		t.Fatalf("expected 1 compacted packfile; got %d", len(descs))
+	}
+	st, err := c.open("default", "small", owner)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows := countRows(t, st, nil, append(descs, idx.Inline...))
+	if len(rows) != 2*appends {
+		t.Fatalf("got %d rows; expected %d", len(rows), 2*appends)
+	}
+	for i := range rows {
+		if rows[i] != int64(i) {
+			t.Fatalf("row %d has x=%d; order not preserved", i, rows[i])
+		}
+	}
+
+	// nothing left to compact, but the
+	// superseded packfiles can be removed
+	// (GCMinimumAge is zero)
+	err = c.Compact(owner, "default", "small")
+	if err != nil {
+		t.Fatal(err)
+	}
+	idx, err = OpenIndex(dfs, "default", "small", owner.Key())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(idx.Inline) != 1 {
+		t.Errorf("second compaction produced %d inline packfiles", len(idx.Inline))
+	}
+	if len(idx.ToDelete) != 0 {
+		t.Errorf("expected superseded packfiles to be removed; got %d left", len(idx.ToDelete))
+	}
+}
+
+// appendSmall appends n single-packfile
+// inputs to default/small
+func appendSmall(t *testing.T, c *Config, owner Tenant, n int) {
+	for i := 0; i < n; i++ {
+		text := fmt.Sprintf("{\"x\": %d}\n{\"x\": %d}", 2*i, 2*i+1)
+		err := c.Append(owner, "default", "small", []blockfmt.Input{{
+			Path: fmt.Sprintf("push://default/small/%d", i),
+			ETag: fmt.Sprintf("etag-%d", i),
+			Size: int64(len(text)),
+			R:    io.NopCloser(strings.NewReader(text)),
+			F:    blockfmt.MustSuffixToFormat(".json"),
+		}})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestCompactConflict(t *testing.T) {
+	checkFiles(t)
+	dfs := newDirFS(t, t.TempDir())
+	owner := newTenant(dfs)
+	err := WriteDefinition(dfs, "default", &Definition{Name: "small"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := Config{
+		Align:        1024,
+		MinMergeSize: 1,
+		Logf:         t.Logf,
+	}
+	appendSmall(t, &c, owner, 4)
+	c.MinMergeSize = 0
+	cmp, err := c.PrepareCompaction(owner, "default", "small")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cmp == nil || cmp.Packfiles() != 3 {
+		t.Fatalf("unexpected compaction %+v", cmp)
+	}
+	outputs := cmp.outputs()
+	// compacting the table in the meantime
+	// replaces the packfiles that cmp replaces
+	err = c.Compact(owner, "default", "small")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = cmp.Commit()
+	if !errors.Is(err, ErrConflict) {
+		t.Fatalf("expected ErrConflict; got %v", err)
+	}
+	for _, p := range outputs {
+		if _, err := fs.Stat(dfs, p); !errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("output %s of conflicting compaction not removed: %v", p, err)
+		}
+	}
+	idx, err := OpenIndex(dfs, "default", "small", owner.Key())
+	if err != nil {
+		t.Fatal(err)
+	}
+	descs, err := idx.Indirect.Search(dfs, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(descs) != 1 || len(idx.Inline) != 1 {
+		t.Fatalf("got %d indirect and %d inline packfiles", len(descs), len(idx.Inline))
+	}
+}
+
+// failIndirectFS is a DirFS that fails
+// to write indirect refs once fail is set
+type failIndirectFS struct {
+	*DirFS
+	fail bool
+}
+
+func (f *failIndirectFS) WriteFile(p string, buf []byte) (string, error) {
+	if f.fail && strings.Contains(p, "/indirect-") {
+		return "", fmt.Errorf("cannot write %s", p)
+	}
+	return f.DirFS.WriteFile(p, buf)
+}
+
+func TestCompactDemoteFailure(t *testing.T) {
+	checkFiles(t)
+	dfs := newDirFS(t, t.TempDir())
+	ffs := &failIndirectFS{DirFS: dfs}
+	owner := newTenant(ffs)
+	err := WriteDefinition(dfs, "default", &Definition{Name: "small"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := Config{
+		Align:        1024,
+		MinMergeSize: 1,
+		Logf:         t.Logf,
+	}
+	appendSmall(t, &c, owner, 4)
+	c.MinMergeSize = 0
+	cmp, err := c.PrepareCompaction(owner, "default", "small")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cmp == nil {
+		t.Fatal("nothing to compact")
+	}
+	outputs := cmp.outputs()
+	ffs.fail = true
+	if err := cmp.Commit(); err == nil {
+		t.Fatal("expected an error")
+	}
+	for _, p := range outputs {
+		if _, err := fs.Stat(dfs, p); !errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("output %s of failed compaction not removed: %v", p, err)
+		}
+	}
+}
+
+func TestBackgroundCompact(t *testing.T) {
+	checkFiles(t)
+	dfs := newDirFS(t, t.TempDir())
+	owner := newTenant(dfs)
+	err := WriteDefinition(dfs, "default", &Definition{Name: "small"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := Config{
+		Align:        1024,
+		MinMergeSize: 1,
+		Logf:         t.Logf,
+	}
+	appendSmall(t, &c, owner, 5)
+	c.MinMergeSize = 0
+	st, err := c.open("default", "small", owner)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := &QueueRunner{
+		Owner:           owner,
+		Conf:            c,
+		Logf:            t.Logf,
+		CompactInterval: time.Hour,
+	}
+	ti := &tableInfo{state: *st}
+	// load the index so that the compaction
+	// has to update the cached copy
+	if _, err := ti.state.index(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	q.maybeCompact(ti)
+	// a second call within CompactInterval is a no-op
+	q.maybeCompact(ti)
+	ti.compactwg.Wait()
+
+	idx, err := ti.state.index(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(idx.Inline) != 1 {
+		t.Fatalf("expected 1 inline packfile; got %d", len(idx.Inline))
+	}
+	// the cached index must be the one on disk
+	disk, err := OpenIndex(dfs, "default", "small", owner.Key())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(disk.Inline) != 1 || len(disk.ToDelete) != 4 {
+		t.Fatalf("on-disk index has %d inline, %d to delete", len(disk.Inline), len(disk.ToDelete))
+	}
+	descs, err := disk.Indirect.Search(dfs, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows := countRows(t, st, nil, append(descs, disk.Inline...))
+	if len(rows) != 10 {
+		t.Fatalf("got %d rows", len(rows))
+	}
+	for i := range rows {
+		if rows[i] != int64(i) {
+			t.Fatalf("row %d has x=%d; order not preserved", i, rows[i])
+		}
+	}
+}
@@ -0,0 +1,277 @@
+// Copyright (C) 2022 Sneller, Inc.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package db
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"strings"
+
+	"github.com/SnellerInc/sneller/ion/blockfmt"
+
+	"golang.org/x/exp/slices"
+)
+
+// ErrTableExists is returned from CreateTable
+// when the table being created already exists.
+var ErrTableExists = errors.New("table already exists")
+
+// validName returns whether name can be
+// used as the name of a database or table
+func validName(name string) bool {
+	return name != "" && name != "." && name != ".." &&
+		!strings.ContainsAny(name, "/\\*?[") && fs.ValidPath(name)
+}
+
+func validPartitionType(typ string) bool {
+	switch typ {
+	case "", "string", "int", "date", "datetime", "timestamp":
+		return true
+	default:
+		return false
+	}
+}
+
+// Validate checks that d is well-formed:
+// the table name must be a valid path segment,
+// the input formats and hints must be understood
+// by the corresponding row formats, inputs that
+// use an inventory must not also be incremental,
+// the partitions must have distinct names and
+// known types,
+// the sort keys must be distinct path expressions,
+// and the retention policy (if present) must
+// specify a field and a non-zero validity window.
+func (d *Definition) Validate() error {
+	if !validName(d.Name) {
+		return fmt.Errorf("invalid table name %q", d.Name)
+	}
+	for i := range d.Inputs {
+		in := &d.Inputs[i]
+		if in.Pattern == "" {
+			return fmt.Errorf("input %d has no pattern", i)
+		}
+		if in.Incremental && in.Inventory != "" {
+			return fmt.Errorf("input %q: an input with an inventory cannot be incremental", in.Pattern)
+		}
+		if in.Format != "" {
+			f := blockfmt.SuffixToFormat["."+in.Format]
+			if f == nil {
+				return fmt.Errorf("input %q: unknown format %q", in.Pattern, in.Format)
+			}
+			if _, err := f(in.Hints); err != nil {
+				return fmt.Errorf("input %q: invalid hints: %w", in.Pattern, err)
+			}
+			continue
+		}
+		if in.Hints == nil {
+			continue
+		}
+		for suff, f := range blockfmt.SuffixToFormat {
+			if !strings.HasSuffix(in.Pattern, suff) {
+				continue
+			}
+			if _, err := f(in.Hints); err != nil {
+				return fmt.Errorf("input %q: invalid hints: %w", in.Pattern, err)
+			}
+		}
+	}
+	if err := checkPartitions(d.Partitions); err != nil {
+		return err
+	}
+	for i := range d.Partitions {
+		if !validPartitionType(d.Partitions[i].Type) {
+			return fmt.Errorf("partition %q: invalid type %q", d.Partitions[i].Field, d.Partitions[i].Type)
+		}
+	}
+	for i, k := range d.SortKeys {
+		if _, err := parseSortKey(k); err != nil {
+			return err
+		}
+		if slices.Contains(d.SortKeys[:i], k) {
+			return fmt.Errorf("duplicate sort key %q", k)
+		}
+	}
+	if r := d.Retention; r != nil {
+		if r.Field == "" {
+			return fmt.Errorf("retention policy has no field")
+		}
+		if r.ValidFor.Zero() {
+			return fmt.Errorf("retention policy has no validity window")
+		}
+	}
+	return nil
+}
+
+// CreateTable validates def and writes it as
+// the definition of a new table in the given database.
+// CreateTable returns an error wrapping ErrTableExists
+// if the table already has a definition.
+//
+// The table is populated from the inputs in the
+// definition the next time the table is synced
+// (see Config.Sync), or by appending data directly
+// (see Config.Append).
+func CreateTable(dst OutputFS, db string, def *Definition) error {
+	if !validName(db) {
+		return fmt.Errorf("invalid database name %q", db)
+	}
+	if err := def.Validate(); err != nil {
+		return err
+	}
+	_, err := fs.Stat(dst, DefinitionPath(db, def.Name))
+	if err == nil {
+		return fmt.Errorf("creating %s/%s: %w", db, def.Name, ErrTableExists)
+	}
+	if !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return WriteDefinition(dst, db, def)
+}
+
+// Alteration describes a set of changes
+// to an existing table definition.
+// (See AlterTable.)
+type Alteration struct {
+	// Retention, if non-nil, replaces
+	// the retention policy of the table.
+	Retention *RetentionPolicy `json:"retention_policy,omitempty"`
+	// DropRetention, if true, removes the
+	// retention policy of the table.
+	DropRetention bool `json:"drop_retention_policy,omitempty"`
+	// Partitions, if non-nil, replaces the
+	// list of partitions of the table.
+	// A pointer to an empty list removes
+	// all of the partitions.
+	Partitions *[]Partition `json:"partitions,omitempty"`
+	// HivePartitions, if non-nil, replaces
+	// Definition.HivePartitions.
+	HivePartitions *bool `json:"hive_partitions,omitempty"`
+	// SortKeys, if non-nil, replaces the
+	// list of sort keys of the table.
+	// A pointer to an empty list removes
+	// all of the sort keys.
+	SortKeys *[]string `json:"sort_keys,omitempty"`
+}
+
+// Apply applies the changes in a to d.
+func (a *Alteration) Apply(d *Definition) error {
+	if a.Retention != nil && a.DropRetention {
+		return fmt.Errorf("cannot both set and drop the retention policy")
+	}
+	if a.Retention != nil {
+		r := *a.Retention
+		d.Retention = &r
+	} else if a.DropRetention {
+		d.Retention = nil
+	}
+	if a.Partitions != nil {
+		d.Partitions = append([]Partition(nil), *a.Partitions...)
+	}
+	if a.HivePartitions != nil {
+		d.HivePartitions = *a.HivePartitions
+	}
+	if a.SortKeys != nil {
+		d.SortKeys = append([]string(nil), *a.SortKeys...)
+	}
+	return nil
+}
+
+// AlterTable applies alt to the definition of
+// an existing table and returns the new definition.
+//
+// Changes to the partitions of a table only apply
+// to data that is ingested after the change;
+// the data that has already been ingested keeps its
+// original partitioning. Likewise, changes to the
+// sort keys only apply to data that is ingested or
+// compacted after the change. Changes to the retention
+// policy take effect the next time the table is purged
+// (see Config.Purge).
+func AlterTable(dst OutputFS, db, table string, alt *Alteration) (*Definition, error) {
+	def, err := OpenDefinition(dst, db, table)
+	if err != nil {
+		return nil, err
+	}
+	if err := alt.Apply(def); err != nil {
+		return nil, err
+	}
+	if err := def.Validate(); err != nil {
+		return nil, err
+	}
+	if err := WriteDefinition(dst, db, def); err != nil {
+		return nil, err
+	}
+	return def, nil
+}
+
+// DropTable removes a table: its definition,
+// its index, and all of the data that was
+// written by ingesting data into the table.
+// The source objects referenced by the
+// table inputs are not affected.
+//
+// DropTable removes the definition first, so
+// a partially-completed DropTable leaves a table
+// that is no longer visible and can be dropped again.
+// The caller is responsible for ensuring that the
+// table is not being updated concurrently.
+func DropTable(dst OutputFS, db, table string) error {
+	rfs, ok := dst.(RemoveFS)
+	if !ok {
+		return fmt.Errorf("DropTable: %T does not support Remove", dst)
+	}
+	if !validName(db) || !validName(table) {
+		return fmt.Errorf("DropTable: invalid table %q", path.Join(db, table))
+	}
+	err := rfs.Remove(DefinitionPath(db, table))
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	dir := path.Join("db", db, table)
+	var files []string
+	err = fs.WalkDir(dst, dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			files = append(files, p)
+		}
+		return nil
+	})
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("dropping %s/%s: %w", db, table, err)
+		}
+		return err
+	}
+	// remove the index before the data
+	// that it references
+	ipath := IndexPath(db, table)
+	for i := range files {
+		if files[i] == ipath {
+			files[0], files[i] = files[i], files[0]
+			break
+		}
+	}
+	for _, p := range files {
+		if err := rfs.Remove(p); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+	}
+	return nil
+}
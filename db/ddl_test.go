@@ -0,0 +1,166 @@
+// Copyright (C) 2022 Sneller, Inc.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package db
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"io/fs"
+	"strings"
+	"testing"
+
+	"golang.org/x/exp/slices"
+
+	"github.com/SnellerInc/sneller/date"
+	"github.com/SnellerInc/sneller/ion"
+	"github.com/SnellerInc/sneller/ion/blockfmt"
+)
+
+func TestDefinitionValidate(t *testing.T) {
+	bad := []Definition{
+		{Name: ""},
+		{Name: "a/b"},
+		{Name: ".."},
+		{Name: "t", Inputs: []Input{{Pattern: ""}}},
+		{Name: "t", Inputs: []Input{{Pattern: "file://*.json", Format: "xml"}}},
+		{Name: "t", Inputs: []Input{{Pattern: "file://*.json", Format: "json", Hints: json.RawMessage(`{"fields": 3}`)}}},
+		{Name: "t", Inputs: []Input{{Pattern: "file://*.json", Hints: json.RawMessage(`{"fields": 3}`)}}},
+		{Name: "t", Partitions: []Partition{{Field: "x"}, {Field: "x"}}},
+		{Name: "t", Partitions: []Partition{{Field: "x", Type: "float"}}},
+		{Name: "t", Retention: &RetentionPolicy{ValidFor: date.Duration{Day: 1}}},
+		{Name: "t", Retention: &RetentionPolicy{Field: "ts"}},
+		{Name: "t", Inputs: []Input{{Pattern: "s3://b/*.json", Incremental: true, Inventory: "s3://i/b/c/"}}},
+		{Name: "t", SortKeys: []string{"x", "x"}},
+		{Name: "t", SortKeys: []string{"x[0]"}},
+		{Name: "t", SortKeys: []string{""}},
+	}
+	for i := range bad {
+		if err := bad[i].Validate(); err == nil {
+			t.Errorf("definition %d: expected an error", i)
+		}
+	}
+	good := Definition{
+		Name: "t",
+		Inputs: []Input{{
+			Pattern: "s3://bucket/{region}/*.json",
+			Hints:   json.RawMessage(`{"ts": "datetime"}`),
+		}},
+		Partitions: []Partition{{Field: "region"}, {Field: "n", Type: "int", Value: "$region"}},
+		Retention:  &RetentionPolicy{Field: "ts", ValidFor: date.Duration{Month: 6}},
+		SortKeys:   []string{"user.id", "n"},
+	}
+	if err := good.Validate(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDDL(t *testing.T) {
+	checkFiles(t)
+	dfs := newDirFS(t, t.TempDir())
+	owner := newTenant(dfs)
+
+	def := &Definition{
+		Name:       "tbl",
+		Partitions: []Partition{{Field: "region"}},
+	}
+	if err := CreateTable(dfs, "default", def); err != nil {
+		t.Fatal(err)
+	}
+	err := CreateTable(dfs, "default", def)
+	if !errors.Is(err, ErrTableExists) {
+		t.Fatalf("creating a table twice: got %v", err)
+	}
+	if err := CreateTable(dfs, "a/b", def); err == nil {
+		t.Fatal("expected an error for an invalid database name")
+	}
+
+	// ALTER the retention, partitioning and sort keys
+	alt := &Alteration{
+		Retention:  &RetentionPolicy{Field: "ts", ValidFor: date.Duration{Day: 7}},
+		Partitions: &[]Partition{},
+		SortKeys:   &[]string{"x"},
+	}
+	got, err := AlterTable(dfs, "default", "tbl", alt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Partitions) != 0 || got.Retention == nil || got.Retention.Field != "ts" ||
+		!slices.Equal(got.SortKeys, []string{"x"}) {
+		t.Fatalf("unexpected altered definition %+v", got)
+	}
+	reread, err := OpenDefinition(dfs, "default", "tbl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reread.Equals(got) {
+		t.Fatalf("altered definition %+v not persisted (got %+v)", got, reread)
+	}
+	_, err = AlterTable(dfs, "default", "tbl", &Alteration{
+		Partitions: &[]Partition{{Field: "x", Type: "bogus"}},
+	})
+	if err == nil {
+		t.Fatal("expected an error for an invalid partition type")
+	}
+	got, err = AlterTable(dfs, "default", "tbl", &Alteration{DropRetention: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Retention != nil {
+		t.Fatal("retention policy not dropped")
+	}
+
+	// write some data and then DROP the table
+	c := Config{Align: 1024, Logf: t.Logf}
+	text := `{"x": 1}`
+	err = c.Append(owner, "default", "tbl", []blockfmt.Input{{
+		Path: "push://default/tbl/0",
+		ETag: "etag-0",
+		Size: int64(len(text)),
+		R:    io.NopCloser(strings.NewReader(text)),
+		F:    blockfmt.MustSuffixToFormat(".json"),
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	idx, err := OpenIndex(dfs, "default", "tbl", owner.Key())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the sort key is indexed
+	min, max, ok := idx.KeyRange([]string{"x"})
+	if !ok || !min.Equal(ion.Int(1)) || !max.Equal(ion.Int(1)) {
+		t.Fatalf("unexpected key range for x: %v %v %v", min, max, ok)
+	}
+	if err := DropTable(dfs, "default", "tbl"); err != nil {
+		t.Fatal(err)
+	}
+	err = fs.WalkDir(dfs, "db/default/tbl", func(p string, d fs.DirEntry, err error) error {
+		if err == nil && !d.IsDir() {
+			t.Errorf("file %s left after DROP", p)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := OpenDefinition(dfs, "default", "tbl"); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("definition still present: %v", err)
+	}
+	// the table can be created again
+	if err := CreateTable(dfs, "default", def); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -23,6 +23,7 @@ import (
 	"reflect"
 
 	"github.com/SnellerInc/sneller/date"
+	"github.com/SnellerInc/sneller/expr"
 	"github.com/SnellerInc/sneller/fsutil"
 )
 
@@ -45,6 +46,23 @@ type Input struct {
 	// eliminate some of the data as it is parsed.
 	// Hints data is format-specific.
 	Hints json.RawMessage `json:"hints,omitempty"`
+	// Incremental, if true, indicates that new
+	// objects matching Pattern always sort after
+	// the existing ones (e.g. because object names
+	// begin with a timestamp). Subsequent syncs
+	// of the table resume listing objects after the
+	// last object listed by the previous sync
+	// rather than listing every object again.
+	Incremental bool `json:"incremental,omitempty"`
+	// Inventory, if non-empty, is the URI of the
+	// destination of an S3 Inventory configuration
+	// for the bucket that Pattern refers to
+	// (e.g. "s3://inventory-bucket/source-bucket/config-id/").
+	// If Inventory is set, new objects are discovered
+	// by reading the most recent inventory manifest
+	// rather than by listing the objects matching Pattern.
+	// Only CSV inventories are supported.
+	Inventory string `json:"inventory,omitempty"`
 }
 
 // RetentionPolicy describes a policy for retaining data.
@@ -108,10 +126,19 @@ type Definition struct {
 	// are generated from components of the input
 	// URI and used to partition table data.
 	Partitions []Partition `json:"partitions,omitempty"`
+	// HivePartitions, if true, causes directory
+	// segments of input URIs of the form key=value
+	// (e.g. "dt=2024-01-01/region=us") to be treated
+	// as partitions in addition to Partitions, where
+	// key is the name of the partition field and
+	// value is its (string) value. Segments whose
+	// key is not an identifier or is already the
+	// name of one of Partitions are ignored.
+	HivePartitions bool `json:"hive_partitions,omitempty"`
 	// Retention is the expiration policy for data.
 	// Data older than the expiration window will
 	// be periodically purged from the backing
-	// store during table updates.
+	// store during table updates (see also Config.Purge).
 	Retention *RetentionPolicy `json:"retention_policy,omitempty"`
 	// Features is a list of feature flags that
 	// can be used to turn on features for beta-testing.
@@ -120,6 +147,31 @@ type Definition struct {
 	// to skip scanning the source bucket(s) for matching
 	// objects when the first objects are inserted into the table.
 	SkipBackfill bool `json:"skip_backfill,omitempty"`
+	// SortKeys is a list of path expressions
+	// (e.g. "user.id") for which the minimum and
+	// maximum value within each block of table data
+	// is recorded in the table index, in addition to
+	// the ranges of timestamps, so that queries
+	// comparing these paths with constants can skip
+	// blocks. Rows are clustered on the first sort key
+	// within each block as data is ingested or compacted.
+	//
+	// Only numbers and strings are indexed; paths
+	// holding timestamps are indexed regardless.
+	SortKeys []string `json:"sort_keys,omitempty"`
+}
+
+// parseSortKey parses one of Definition.SortKeys
+func parseSortKey(key string) ([]string, error) {
+	p, err := expr.ParsePath(key)
+	if err != nil {
+		return nil, err
+	}
+	flat, ok := expr.FlatPath(p)
+	if !ok {
+		return nil, fmt.Errorf("sort key %q is not a simple path", key)
+	}
+	return flat, nil
 }
 
 // just pick an upper limit to prevent DoS
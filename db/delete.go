@@ -0,0 +1,272 @@
+// Copyright (C) 2022 Sneller, Inc.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"runtime/trace"
+	"sync"
+	"time"
+
+	"github.com/SnellerInc/sneller/date"
+	"github.com/SnellerInc/sneller/expr"
+	"github.com/SnellerInc/sneller/expr/blob"
+	"github.com/SnellerInc/sneller/ion/blockfmt"
+)
+
+// deletion is a packfile from which
+// rows are being deleted
+type deletion struct {
+	in, out blockfmt.Descriptor
+}
+
+// A RowFilter evaluates predicates over the rows
+// of packfiles on behalf of Delete. The rows are
+// tenant data, so a RowFilter is expected to
+// evaluate the predicates in the tenant's sandbox.
+type RowFilter interface {
+	// Count returns the number of rows in src
+	// for which where evaluates to TRUE.
+	Count(src *blob.List, where expr.Node) (int64, error)
+	// Select writes the rows in src for which
+	// where evaluates to TRUE into dst as binary ion.
+	Select(src *blob.List, where expr.Node, dst io.Writer) error
+}
+
+// packBlobs returns the blobs that
+// make up the packfile d
+func (st *tableState) packBlobs(d *blockfmt.Descriptor, key *blockfmt.DataKey, wrapped []byte) (*blob.List, error) {
+	src, ok := st.ofs.(FS)
+	if !ok {
+		return nil, fmt.Errorf("%T cannot be used for reading", st.ofs)
+	}
+	var size int64
+	lst, err := descToBlobs(src, d, key, wrapped, nil, nil, &size)
+	if err != nil {
+		return nil, err
+	}
+	return &blob.List{Contents: lst}, nil
+}
+
+// deleteFrom writes a copy of del.in
+// that omits the rows for which where
+// is TRUE into del.out
+func (st *tableState) deleteFrom(ctx context.Context, del *deletion, key *blockfmt.DataKey, wrapped []byte, where expr.Node, rf RowFilter) error {
+	defer trace.StartRegion(ctx, "delete-from").End()
+	d := &del.in
+	// rows for which the predicate is
+	// FALSE or MISSING are retained
+	keep := expr.Simplify(expr.Is(where, expr.IsNotTrue), expr.NoHint)
+	r, w := io.Pipe()
+	if keep == expr.Bool(false) {
+		// every row is deleted
+		w.Close()
+	} else {
+		src, err := st.packBlobs(d, key, wrapped)
+		if err != nil {
+			return err
+		}
+		go func() {
+			w.CloseWithError(rf.Select(src, keep, w))
+		}()
+	}
+	defer r.Close()
+	c := blockfmt.Converter{
+		Align:     st.conf.align(),
+		FlushMeta: st.conf.flushMeta(),
+		Comp:      st.conf.comp(),
+		Checksums: st.conf.Checksums,
+		Key:       key,
+		Constants: d.Trailer.Sparse.Consts(),
+		SortKeys:  st.sortKeys(),
+		Parallel:  1,
+		Inputs: []blockfmt.Input{{
+			Path: d.Path,
+			ETag: d.ETag,
+			Size: d.Trailer.Decompressed(),
+			R:    r,
+			// the rows are produced by the filter,
+			// so they are validated like any other input
+			F: blockfmt.MustSuffixToFormat(".ion"),
+		}},
+	}
+	name := "packed-" + uuid() + suffixForComp(c.Comp)
+	fp := path.Join(path.Dir(d.Path), name)
+	out, err := st.ofs.Create(fp)
+	if err != nil {
+		return err
+	}
+	c.Output = out
+	c.Path = fp
+	err = c.Run()
+	if err != nil {
+		abort(out)
+		return err
+	}
+	etag, lastmod, err := getInfo(st.ofs, fp, out)
+	if err != nil {
+		st.discard(fp)
+		return err
+	}
+	del.out = blockfmt.Descriptor{
+		ObjectInfo: blockfmt.ObjectInfo{
+			Path:         fp,
+			LastModified: date.FromTime(lastmod),
+			ETag:         etag,
+			Format:       blockfmt.Version,
+			Size:         out.Size(),
+		},
+		Trailer: *c.Trailer(),
+	}
+	return nil
+}
+
+// Delete removes the rows in db/table for which
+// the predicate where evaluates to TRUE and returns
+// the number of rows that were removed.
+//
+// Packfiles are never modified in place; each packfile
+// that contains at least one matching row is rewritten
+// without the matching rows, and the new packfile replaces
+// the old one in the index. The packfiles that are replaced
+// are scheduled for deletion once GCMinimumAge has elapsed,
+// so queries that are already using the previous version of
+// the index continue to observe the deleted rows.
+// Packfiles that cannot contain matching rows according
+// to their sparse indexes are not read at all.
+//
+// Like Compact, Delete only writes a new index if the index
+// was not modified by another process in the meantime;
+// otherwise, it returns an error and the index is left
+// unmodified. The caller is responsible for serializing
+// calls to Delete that target the same table.
+//
+// The rows of the packfiles are only ever
+// read through rf.
+func (c *Config) Delete(who Tenant, db, table string, where expr.Node, rf RowFilter) (int64, error) {
+	st, err := c.open(db, table, who)
+	if err != nil {
+		return 0, err
+	}
+	ctx := context.Background()
+	idx, err := st.index(ctx)
+	if err != nil {
+		return 0, err
+	}
+	idx.Inputs.Backing = st.ofs
+	if st.shouldScan() && idx.Scanning {
+		return 0, ErrBuildAgain
+	}
+	var filt blockfmt.Filter
+	filt.Compile(where)
+	var candidates []blockfmt.Descriptor
+	for i := range idx.Inline {
+		if filt.Trivial() || filt.MatchesAny(&idx.Inline[i].Trailer.Sparse) {
+			candidates = append(candidates, idx.Inline[i])
+		}
+	}
+	indirect, err := idx.Indirect.Search(st.ofs, &filt)
+	if err != nil {
+		return 0, err
+	}
+	candidates = append(candidates, indirect...)
+	key, wrapped, err := st.dataKey(idx)
+	if err != nil {
+		return 0, err
+	}
+	idx.DataKey = wrapped
+
+	var todo []*deletion
+	var deleted int64
+	for i := range candidates {
+		src, err := st.packBlobs(&candidates[i], key, wrapped)
+		if err != nil {
+			return 0, err
+		}
+		n, err := rf.Count(src, where)
+		if err != nil {
+			return 0, fmt.Errorf("scanning %s: %w", candidates[i].Path, err)
+		}
+		if n > 0 {
+			todo = append(todo, &deletion{in: candidates[i]})
+			deleted += n
+		}
+	}
+	if len(todo) == 0 {
+		return 0, nil
+	}
+	errs := make([]error, len(todo))
+	var wg sync.WaitGroup
+	wg.Add(len(todo))
+	for i := range todo {
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = st.deleteFrom(ctx, todo[i], key, wrapped, where, rf)
+		}(i)
+	}
+	wg.Wait()
+	if err := combine(errs); err != nil {
+		st.invalidate()
+		st.discardOutputs(todo)
+		return 0, fmt.Errorf("deleting from %s/%s: %w", db, table, err)
+	}
+
+	repl := make(map[string]blockfmt.Descriptor, len(todo))
+	expiry := date.Now().Add(st.conf.GCMinimumAge)
+	for _, del := range todo {
+		repl[del.in.Path] = del.out
+		idx.ToDelete = append(idx.ToDelete, blockfmt.Quarantined{
+			Path:   del.in.Path,
+			Expiry: expiry,
+		})
+	}
+	ic := blockfmt.IndexConfig{
+		TargetSize:    int64(st.conf.targetMerge()),
+		TargetRefSize: st.conf.TargetRefSize,
+		Expiry:        st.conf.GCMinimumAge,
+	}
+	err = ic.Replace(idx, st.ofs, path.Join("db", st.db, st.table), &filt, repl)
+	if err != nil {
+		st.invalidate()
+		st.discardOutputs(todo)
+		return 0, err
+	}
+	st.logf("deleted %d rows from %d packfiles", deleted, len(todo))
+	idx.Created = date.Now().Truncate(time.Microsecond)
+	err = st.flush(ctx, idx)
+	if err != nil {
+		if errors.Is(err, ErrConflict) {
+			// the index was not written,
+			// so the outputs are unreferenced
+			st.discardOutputs(todo)
+		}
+		return 0, err
+	}
+	return deleted, nil
+}
+
+// discardOutputs removes the packfiles
+// that were written for the deletions in todo
+func (st *tableState) discardOutputs(todo []*deletion) {
+	for _, del := range todo {
+		if del.out.Path != "" {
+			st.discard(del.out.Path)
+		}
+	}
+}
@@ -0,0 +1,277 @@
+// Copyright (C) 2022 Sneller, Inc.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package db
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/SnellerInc/sneller/expr"
+	"github.com/SnellerInc/sneller/expr/blob"
+	"github.com/SnellerInc/sneller/ion/blockfmt"
+	"github.com/SnellerInc/sneller/vm"
+)
+
+// vmFilter is a RowFilter that
+// evaluates predicates in-process
+type vmFilter struct{}
+
+func vmMalloc(size int) []byte {
+	if size > vm.PageSize {
+		panic("cannot allocate page with size > vm.PageSize")
+	}
+	return vm.Malloc()[:size]
+}
+
+func (vmFilter) scan(src *blob.List, dst vm.QuerySink) error {
+	w, err := dst.Open()
+	if err != nil {
+		dst.Close()
+		return err
+	}
+	for _, b := range src.Contents {
+		p := b.(*blob.CompressedPart)
+		t := &p.Parent.Trailer
+		start, end := t.Blocks[p.StartBlock].Offset, t.Offset
+		if p.EndBlock < len(t.Blocks) {
+			end = t.Blocks[p.EndBlock].Offset
+		}
+		rd, err := p.Parent.From.Reader(start, end-start)
+		if err != nil {
+			w.Close()
+			dst.Close()
+			return err
+		}
+		dec := blockfmt.Decoder{Malloc: vmMalloc, Free: vm.Free, Key: p.Parent.Key, Path: p.Parent.Path}
+		dec.SetRange(t, p.StartBlock, p.EndBlock)
+		_, err = dec.Copy(w, rd)
+		rd.Close()
+		if err != nil {
+			w.Close()
+			dst.Close()
+			return err
+		}
+	}
+	err = w.Close()
+	err2 := dst.Close()
+	if err == nil {
+		err = err2
+	}
+	return err
+}
+
+func (f vmFilter) Count(src *blob.List, where expr.Node) (int64, error) {
+	var cnt vm.Count
+	filt, err := vm.NewFilter(where, &cnt)
+	if err != nil {
+		return 0, err
+	}
+	err = f.scan(src, filt)
+	if err != nil {
+		return 0, err
+	}
+	return cnt.Value(), nil
+}
+
+func (f vmFilter) Select(src *blob.List, where expr.Node, dst io.Writer) error {
+	filt, err := vm.NewFilter(where, vm.LockedSink(dst))
+	if err != nil {
+		return err
+	}
+	return f.scan(src, filt)
+}
+
+func TestDelete(t *testing.T) {
+	checkFiles(t)
+	dfs := newDirFS(t, t.TempDir())
+	owner := newTenant(dfs)
+	err := WriteDefinition(dfs, "default", &Definition{Name: "small"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := Config{
+		Align: 1024,
+		// produce a new packfile for every append
+		MinMergeSize: 1,
+		Logf:         t.Logf,
+	}
+	const appends = 4
+	for i := 0; i < appends; i++ {
+		text := fmt.Sprintf("{\"x\": %d}\n{\"x\": %d}", 2*i, 2*i+1)
+		err := c.Append(owner, "default", "small", []blockfmt.Input{{
+			Path: fmt.Sprintf("push://default/small/%d", i),
+			ETag: fmt.Sprintf("etag-%d", i),
+			Size: int64(len(text)),
+			R:    io.NopCloser(strings.NewReader(text)),
+			F:    blockfmt.MustSuffixToFormat(".json"),
+		}})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	// move all but one packfile into the indirect tree
+	c.MinMergeSize = 0
+	err = c.Compact(owner, "default", "small")
+	if err != nil {
+		t.Fatal(err)
+	}
+	idx, err := OpenIndex(dfs, "default", "small", owner.Key())
+	if err != nil {
+		t.Fatal(err)
+	}
+	before := idx.Objects()
+
+	contents := func() []int64 {
+		idx, err := OpenIndex(dfs, "default", "small", owner.Key())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if idx.Objects() != before {
+			t.Fatalf("object numbering changed from %d to %d", before, idx.Objects())
+		}
+		descs, err := idx.Indirect.Search(dfs, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		st, err := c.open("default", "small", owner)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return countRows(t, st, nil, append(descs, idx.Inline...))
+	}
+
+	x := expr.Ident("x")
+	testcases := []struct {
+		where expr.Node
+		count int64
+		rows  []int64
+	}{
+		{
+			// no matching rows
+			where: expr.Compare(expr.Greater, x, expr.Integer(100)),
+			count: 0,
+			rows:  []int64{0, 1, 2, 3, 4, 5, 6, 7},
+		},
+		{
+			// one row from an indirect packfile
+			where: expr.Compare(expr.Equals, x, expr.Integer(2)),
+			count: 1,
+			rows:  []int64{0, 1, 3, 4, 5, 6, 7},
+		},
+		{
+			// rows from both packfiles;
+			// the inline packfile becomes empty
+			where: expr.Or(
+				expr.Compare(expr.Less, x, expr.Integer(1)),
+				expr.Compare(expr.GreaterEquals, x, expr.Integer(5))),
+			count: 4,
+			rows:  []int64{1, 3, 4},
+		},
+		{
+			// every remaining row
+			// (DELETE without WHERE)
+			where: expr.Bool(true),
+			count: 3,
+			rows:  nil,
+		},
+	}
+	for i := range testcases {
+		where := testcases[i].where
+		n, err := c.Delete(owner, "default", "small", where, vmFilter{})
+		if err != nil {
+			t.Fatalf("DELETE WHERE %s: %s", expr.ToString(where), err)
+		}
+		if n != testcases[i].count {
+			t.Errorf("DELETE WHERE %s: deleted %d rows; expected %d", expr.ToString(where), n, testcases[i].count)
+		}
+		got := contents()
+		if fmt.Sprint(got) != fmt.Sprint(testcases[i].rows) {
+			t.Fatalf("DELETE WHERE %s: rows %v; expected %v", expr.ToString(where), got, testcases[i].rows)
+		}
+	}
+}
+
+// failFilter is a vmFilter that
+// fails every call to Select after
+// the first one
+type failFilter struct {
+	vmFilter
+	lock  sync.Mutex
+	calls int
+}
+
+func (f *failFilter) Select(src *blob.List, where expr.Node, dst io.Writer) error {
+	f.lock.Lock()
+	f.calls++
+	n := f.calls
+	f.lock.Unlock()
+	if n > 1 {
+		return fmt.Errorf("select failed")
+	}
+	return f.vmFilter.Select(src, where, dst)
+}
+
+func TestDeleteFailure(t *testing.T) {
+	checkFiles(t)
+	tmpdir := t.TempDir()
+	dfs := newDirFS(t, tmpdir)
+	owner := newTenant(dfs)
+	err := WriteDefinition(dfs, "default", &Definition{Name: "small"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := Config{
+		Align:        1024,
+		MinMergeSize: 1,
+		Logf:         t.Logf,
+	}
+	for i := 0; i < 2; i++ {
+		text := fmt.Sprintf("{\"x\": %d}\n{\"x\": %d}", 2*i, 2*i+1)
+		err := c.Append(owner, "default", "small", []blockfmt.Input{{
+			Path: fmt.Sprintf("push://default/small/%d", i),
+			ETag: fmt.Sprintf("etag-%d", i),
+			Size: int64(len(text)),
+			R:    io.NopCloser(strings.NewReader(text)),
+			F:    blockfmt.MustSuffixToFormat(".json"),
+		}})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	packed := func() []string {
+		lst, err := fs.Glob(dfs, "db/default/small/packed-*")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return lst
+	}
+	before := packed()
+	if len(before) != 2 {
+		t.Fatalf("expected 2 packfiles; got %v", before)
+	}
+	// every packfile has one matching row
+	where := expr.Compare(expr.Equals, expr.Mod(expr.Ident("x"), expr.Integer(2)), expr.Integer(0))
+	_, err = c.Delete(owner, "default", "small", where, &failFilter{})
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	if after := packed(); fmt.Sprint(after) != fmt.Sprint(before) {
+		t.Errorf("packfiles %v after failed delete; expected %v", after, before)
+	}
+}
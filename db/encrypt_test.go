@@ -0,0 +1,178 @@
+// Copyright (C) 2022 Sneller, Inc.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package db
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/fs"
+	"strings"
+	"testing"
+
+	"github.com/SnellerInc/sneller/expr"
+	"github.com/SnellerInc/sneller/expr/blob"
+	"github.com/SnellerInc/sneller/ion/blockfmt"
+)
+
+func TestEncrypt(t *testing.T) {
+	checkFiles(t)
+	dfs := newDirFS(t, t.TempDir())
+	owner := newTenant(dfs)
+	err := WriteDefinition(dfs, "default", &Definition{Name: "secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := Config{
+		Align:        1024,
+		MinMergeSize: 1,
+		Encrypt:      true,
+		Logf:         t.Logf,
+	}
+	const appends = 4
+	for i := 0; i < appends; i++ {
+		if i == appends-1 {
+			// a table that already has a data key
+			// stays encrypted
+			c.Encrypt = false
+		}
+		text := fmt.Sprintf("{\"x\": %d, \"name\": \"plaintext\"}\n{\"x\": %d}", 2*i, 2*i+1)
+		err := c.Append(owner, "default", "secret", []blockfmt.Input{{
+			Path: fmt.Sprintf("push://default/secret/%d", i),
+			ETag: fmt.Sprintf("etag-%d", i),
+			Size: int64(len(text)),
+			R:    io.NopCloser(strings.NewReader(text)),
+			F:    blockfmt.MustSuffixToFormat(".json"),
+		}})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	idx, err := OpenIndex(dfs, "default", "secret", owner.Key())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(idx.DataKey) == 0 {
+		t.Fatal("index has no data key")
+	}
+	key, err := blockfmt.UnwrapDataKey(owner.Key(), idx.DataKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range idx.Inline {
+		d := &idx.Inline[i]
+		if !d.Trailer.Encrypted {
+			t.Fatalf("packfile %s is not encrypted", d.Path)
+		}
+		buf, err := fs.ReadFile(dfs, d.Path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if bytes.Contains(buf, []byte("plaintext")) {
+			t.Fatalf("packfile %s contains plaintext", d.Path)
+		}
+	}
+
+	// compaction and deletion need
+	// to decrypt the existing packfiles
+	c.MinMergeSize = 0
+	err = c.Compact(owner, "default", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	n, err := c.Delete(owner, "default", "secret", expr.Compare(expr.Equals, expr.Ident("x"), expr.Integer(3)), vmFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 1 {
+		t.Fatalf("deleted %d rows", n)
+	}
+	idx, err = OpenIndex(dfs, "default", "secret", owner.Key())
+	if err != nil {
+		t.Fatal(err)
+	}
+	descs, err := idx.Indirect.Search(dfs, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	descs = append(descs, idx.Inline...)
+	for i := range descs {
+		if !descs[i].Trailer.Encrypted {
+			t.Fatalf("packfile %s is not encrypted", descs[i].Path)
+		}
+	}
+	st, err := c.open("default", "secret", owner)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows := countRows(t, st, key, descs)
+	if len(rows) != 2*appends-1 {
+		t.Fatalf("got %d rows; expected %d", len(rows), 2*appends-1)
+	}
+	for i := range rows {
+		want := int64(i)
+		if i >= 3 {
+			want++
+		}
+		if rows[i] != want {
+			t.Fatalf("row %d has x=%d; expected %d", i, rows[i], want)
+		}
+	}
+
+	// the blobs produced for queries
+	// carry the key needed to decrypt them
+	lst, _, err := BlobsWithKey(dfs, idx, key, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	total := int64(0)
+	for _, b := range lst.Contents {
+		cp := b.(*blob.CompressedPart)
+		if cp.Parent.Key == nil || *cp.Parent.Key != *key {
+			t.Fatal("blob has the wrong key")
+		}
+		rd, err := cp.Decompressor()
+		if err != nil {
+			t.Fatal(err)
+		}
+		n, err := io.Copy(io.Discard, rd)
+		rd.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		total += n
+	}
+	if total == 0 {
+		t.Fatal("no data decompressed")
+	}
+	// without a key, the blobs carry the
+	// wrapped key so it can be resolved later
+	lst, _, err = Blobs(dfs, idx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if wk := lst.Contents[0].(*blob.CompressedPart).Parent.WrappedKey; !bytes.Equal(wk, idx.DataKey) {
+		t.Fatal("blob doesn't carry the wrapped key")
+	}
+	rd, err := lst.Contents[0].(*blob.CompressedPart).Decompressor()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = io.Copy(io.Discard, rd)
+	rd.Close()
+	if err == nil || !strings.Contains(err.Error(), "no key") {
+		t.Fatalf("decompressing without a key: got error %v", err)
+	}
+}
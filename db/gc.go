@@ -83,6 +83,23 @@ type GCConfig struct {
 	// by only deleting objects that have been
 	// explicitly marked for deletion.
 	Precise bool
+
+	// DryRun, if true, causes RemoveOrphans to
+	// report the objects that it would remove
+	// without removing them.
+	DryRun bool
+}
+
+// GCReport summarizes the objects
+// removed by GCConfig.RemoveOrphans.
+type GCReport struct {
+	// Removed is the list of paths of the
+	// objects that were removed (or would have
+	// been removed if GCConfig.DryRun is set).
+	Removed []string
+	// Bytes is the total size of the
+	// objects in Removed.
+	Bytes int64
 }
 
 func (c *GCConfig) logf(f string, args ...interface{}) {
@@ -333,3 +350,106 @@ func (c *GCConfig) preciseGC(rfs RemoveFS, idx *blockfmt.Index) bool {
 	idx.ToDelete = saved
 	return true
 }
+
+// RemoveOrphans removes the objects in the
+// storage prefix of the table described by idx
+// that were written by Sync (packfiles, indirect
+// references, and lists of inputs) but are neither
+// referenced by idx nor quarantined in idx.ToDelete,
+// provided that they are older than c.MinimumAge
+// (or DefaultMinimumAge if c.MinimumAge is not set).
+//
+// Unlike Run, RemoveOrphans lists every object in
+// the table prefix, so it also finds objects that
+// are not near any object referenced by idx, like
+// the output of an ingest or compaction that failed
+// before the index was updated.
+//
+// If c.DryRun is set, the objects that would
+// be removed are reported but not removed.
+func (c *GCConfig) RemoveOrphans(rfs RemoveFS, dbname string, idx *blockfmt.Index) (*GCReport, error) {
+	ifs, ok := rfs.(blockfmt.InputFS)
+	if !ok {
+		return nil, fmt.Errorf("cannot scan indirect objects using %T", rfs)
+	}
+	start := time.Now()
+	min := c.MinimumAge
+	if min <= 0 {
+		min = DefaultMinimumAge
+	}
+	used := make(map[string]struct{})
+	for i := range idx.Inline {
+		used[idx.Inline[i].Path] = struct{}{}
+	}
+	for i := range idx.Indirect.Refs {
+		used[idx.Indirect.Refs[i].Path] = struct{}{}
+	}
+	descs, err := idx.Indirect.Search(ifs, nil)
+	if err != nil {
+		return nil, err
+	}
+	for i := range descs {
+		used[descs[i].Path] = struct{}{}
+	}
+	idx.Inputs.Backing = &readOnly{ifs}
+	err = idx.Inputs.EachFile(func(f string) {
+		used[f] = struct{}{}
+	})
+	if err != nil {
+		// we can't tell which inputs-* objects
+		// are in use, so we can't remove anything
+		return nil, fmt.Errorf("listing inputs: %w", err)
+	}
+	for i := range idx.ToDelete {
+		// these are removed by preciseGC
+		// once they have expired
+		used[idx.ToDelete[i].Path] = struct{}{}
+	}
+	orphan := func(name string) bool {
+		for _, pat := range []string{"packed-*", "indirect-*", "inputs-*"} {
+			if ok, _ := path.Match(pat, name); ok {
+				return true
+			}
+		}
+		return false
+	}
+	rep := new(GCReport)
+	dir := path.Join("db", dbname, idx.Name)
+	walk := func(p string, d fsutil.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if d.IsDir() || !orphan(d.Name()) {
+			return nil
+		}
+		if _, ok := used[p]; ok {
+			return nil
+		}
+		info, err := d.Info()
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		} else if err != nil {
+			c.logf("%s: %v", p, err)
+			return err
+		}
+		if start.Sub(info.ModTime()) < min {
+			return nil
+		}
+		if c.DryRun {
+			c.logf("would remove %s", p)
+		} else if err := rfs.Remove(p); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			c.logf("removing %s: %s", p, err)
+			return nil
+		} else {
+			c.logf("removed %s", p)
+		}
+		rep.Removed = append(rep.Removed, p)
+		rep.Bytes += info.Size()
+		return nil
+	}
+	err = fsutil.WalkDir(rfs, dir, "", "", walk)
+	return rep, err
+}
@@ -20,8 +20,11 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/SnellerInc/sneller/ion/blockfmt"
+
+	"golang.org/x/exp/slices"
 )
 
 func TestGC(t *testing.T) {
@@ -154,3 +157,138 @@ func TestGC(t *testing.T) {
 		}
 	}
 }
+
+func TestRemoveOrphans(t *testing.T) {
+	checkFiles(t)
+	tmpdir := t.TempDir()
+	err := os.MkdirAll(filepath.Join(tmpdir, "a-prefix/foo"), 0750)
+	if err != nil {
+		t.Fatal(err)
+	}
+	oldname, err := filepath.Abs("../testdata/parking.10n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.Symlink(oldname, filepath.Join(tmpdir, "a-prefix/foo/parking.10n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dfs := newDirFS(t, tmpdir)
+	err = WriteDefinition(dfs, "default", &Definition{
+		Name: "parking",
+		Inputs: []Input{
+			{Pattern: "file://a-prefix/{pre}/*.10n"},
+		},
+		Partitions: []Partition{
+			{Field: "pre"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	owner := newTenant(dfs)
+	c := Config{
+		Align: 1024,
+		Fallback: func(_ string) blockfmt.RowFormat {
+			return blockfmt.UnsafeION()
+		},
+		Logf: t.Logf,
+	}
+	err = c.Sync(owner, "default", "*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	idx, err := OpenIndex(dfs, "default", "parking", owner.Key())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	old := time.Now().Add(-time.Hour)
+	write := func(name string, size int, mtime time.Time) {
+		t.Helper()
+		_, err := dfs.WriteFile(name, make([]byte, size))
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = os.Chtimes(filepath.Join(tmpdir, name), mtime, mtime)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	orphans := []string{
+		"db/default/parking/foo/packed-orphan.ion.zst",
+		"db/default/parking/indirect-orphan",
+		"db/default/parking/inputs-orphan",
+		// a partition that the index
+		// doesn't reference at all
+		"db/default/parking/bar/packed-orphan.ion.zst",
+	}
+	for i, name := range orphans {
+		write(name, 100*(i+1), old)
+	}
+	keep := []string{
+		// too recent
+		"db/default/parking/bar/packed-recent.ion.zst",
+		// quarantined
+		"db/default/parking/bar/packed-quarantined.ion.zst",
+		// not written by Sync
+		"db/default/parking/other-file",
+	}
+	write(keep[0], 10, time.Now())
+	write(keep[1], 10, old)
+	write(keep[2], 10, old)
+	idx.ToDelete = append(idx.ToDelete, blockfmt.Quarantined{Path: keep[1]})
+
+	exists := func(name string) bool {
+		_, err := fs.Stat(dfs, name)
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			t.Fatal(err)
+		}
+		return err == nil
+	}
+	conf := GCConfig{
+		Logf:       t.Logf,
+		MinimumAge: time.Minute,
+		DryRun:     true,
+	}
+	for _, dryrun := range []bool{true, false} {
+		conf.DryRun = dryrun
+		rep, err := conf.RemoveOrphans(dfs, "default", idx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := slices.Clone(rep.Removed)
+		slices.Sort(got)
+		want := slices.Clone(orphans)
+		slices.Sort(want)
+		if !slices.Equal(got, want) {
+			t.Errorf("dry run %v: removed %q, wanted %q", dryrun, got, want)
+		}
+		if rep.Bytes != 100+200+300+400 {
+			t.Errorf("dry run %v: %d bytes removed", dryrun, rep.Bytes)
+		}
+		for _, name := range orphans {
+			if exists(name) != dryrun {
+				t.Errorf("dry run %v: %s exists = %v", dryrun, name, !dryrun)
+			}
+		}
+	}
+	for _, name := range keep {
+		if !exists(name) {
+			t.Errorf("%s was removed", name)
+		}
+	}
+	// everything referenced by
+	// the index still exists
+	for i := range idx.Inline {
+		if !exists(idx.Inline[i].Path) {
+			t.Errorf("%s was removed", idx.Inline[i].Path)
+		}
+	}
+	idx.Inputs.Backing = dfs
+	idx.Inputs.EachFile(func(name string) {
+		if !exists(name) {
+			t.Errorf("%s was removed", name)
+		}
+	})
+}
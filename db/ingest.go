@@ -0,0 +1,64 @@
+// Copyright (C) 2022 Sneller, Inc.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package db
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/SnellerInc/sneller/ion/blockfmt"
+)
+
+// Append converts the inputs in lst and appends
+// them to the index for db/table in a single
+// index update. Unlike Sync, Append does not match
+// the inputs against the input patterns in the table
+// definition, so it can be used to ingest data that
+// has been pushed directly by a client rather than
+// discovered in object storage.
+//
+// Each input must have a unique Path and ETag;
+// inputs that are already present in the index
+// are ignored. Since the inputs do not come from
+// a path that matches a partition template, all
+// of the inputs are written into the unpartitioned
+// portion of the table.
+//
+// If the table is currently being backfilled
+// (see Config.NewIndexScan), Append will perform
+// some of the scanning work and return ErrBuildAgain,
+// in which case the caller should try again later.
+//
+// The caller is responsible for serializing
+// calls to Append that target the same table.
+func (c *Config) Append(who Tenant, db, table string, lst []blockfmt.Input) error {
+	if len(lst) == 0 {
+		return nil
+	}
+	st, err := c.open(db, table, who)
+	if err != nil {
+		return err
+	}
+	for i := range lst {
+		if lst[i].F == nil {
+			return fmt.Errorf("db.Config.Append: input %s has no format", lst[i].Path)
+		}
+	}
+	ti := &tableInfo{state: *st}
+	return ti.append(context.Background(), []partition{{
+		prepend: -1,
+		lst:     lst,
+	}})
+}
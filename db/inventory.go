@@ -0,0 +1,226 @@
+// Copyright (C) 2022 Sneller, Inc.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package db
+
+import (
+	"compress/gzip"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/exp/slices"
+)
+
+// manifestName is the name of the manifest
+// in each dated directory of an S3 Inventory destination
+const manifestName = "manifest.json"
+
+// s3Manifest is the subset of an S3 Inventory
+// manifest that is used to discover objects
+type s3Manifest struct {
+	SourceBucket string `json:"sourceBucket"`
+	FileFormat   string `json:"fileFormat"`
+	FileSchema   string `json:"fileSchema"`
+	Files        []struct {
+		Key string `json:"key"`
+	} `json:"files"`
+}
+
+// inventoryEntry is one object listed in an inventory
+type inventoryEntry struct {
+	key      string
+	etag     string
+	size     int64
+	modified time.Time // zero if not in the inventory
+}
+
+// latestManifest returns the path of the most recent
+// manifest in the inventory destination dir, or ""
+// if there are no manifests
+func latestManifest(f fs.FS, dir string) (string, error) {
+	lst, err := fs.Glob(f, path.Join(dir, "*", manifestName))
+	if err != nil {
+		return "", err
+	}
+	if len(lst) == 0 {
+		return "", nil
+	}
+	// the directories are named after the time at
+	// which the inventory was produced, so the most
+	// recent manifest sorts last
+	slices.Sort(lst)
+	return lst[len(lst)-1], nil
+}
+
+func readManifest(f fs.FS, name string) (*s3Manifest, error) {
+	buf, err := fs.ReadFile(f, name)
+	if err != nil {
+		return nil, err
+	}
+	m := new(s3Manifest)
+	if err := json.Unmarshal(buf, m); err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	if m.FileFormat != "CSV" {
+		return nil, fmt.Errorf("%s: unsupported inventory format %q", name, m.FileFormat)
+	}
+	return m, nil
+}
+
+// columns returns the positions of the columns
+// of the inventory that are used to build an
+// inventoryEntry; modified is -1 if the inventory
+// does not include the modification time
+func (m *s3Manifest) columns() (key, size, etag, modified int, err error) {
+	key, size, etag, modified = -1, -1, -1, -1
+	for i, col := range strings.Split(m.FileSchema, ",") {
+		switch strings.TrimSpace(col) {
+		case "Key":
+			key = i
+		case "Size":
+			size = i
+		case "ETag":
+			etag = i
+		case "LastModifiedDate":
+			modified = i
+		}
+	}
+	if key < 0 || size < 0 || etag < 0 {
+		err = fmt.Errorf("inventory schema %q must include Key, Size, and ETag", m.FileSchema)
+	}
+	return key, size, etag, modified, err
+}
+
+// each calls fn for each object listed in the
+// inventory data files of m, which are read from f
+func (m *s3Manifest) each(f fs.FS, fn func(e *inventoryEntry) error) error {
+	key, size, etag, modified, err := m.columns()
+	if err != nil {
+		return err
+	}
+	var e inventoryEntry
+	for i := range m.Files {
+		err := readInventoryFile(f, m.Files[i].Key, func(row []string) error {
+			if len(row) <= key || len(row) <= size || len(row) <= etag {
+				return fmt.Errorf("inventory row has %d columns", len(row))
+			}
+			var err error
+			// keys are URL-encoded in CSV inventories
+			e.key, err = url.QueryUnescape(row[key])
+			if err != nil {
+				return err
+			}
+			e.etag = row[etag]
+			e.size, err = strconv.ParseInt(row[size], 10, 64)
+			if err != nil {
+				return err
+			}
+			e.modified = time.Time{}
+			if modified >= 0 && modified < len(row) && row[modified] != "" {
+				e.modified, err = time.Parse(time.RFC3339, row[modified])
+				if err != nil {
+					return err
+				}
+			}
+			return fn(&e)
+		})
+		if err != nil {
+			return fmt.Errorf("inventory file %s: %w", m.Files[i].Key, err)
+		}
+	}
+	return nil
+}
+
+// readInventoryFile calls fn for each
+// row of a gzip-compressed CSV file
+func readInventoryFile(f fs.FS, name string, fn func(row []string) error) error {
+	inf, err := f.Open(name)
+	if err != nil {
+		return err
+	}
+	defer inf.Close()
+	gz, err := gzip.NewReader(inf)
+	if err != nil {
+		return err
+	}
+	r := csv.NewReader(gz)
+	r.FieldsPerRecord = -1
+	r.ReuseRecord = true
+	for {
+		row, err := r.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := fn(row); err != nil {
+			return err
+		}
+	}
+}
+
+// scanInventory calls fn for each object listed
+// by the most recent manifest in the S3 Inventory
+// destination inv if that manifest is more recent
+// than the manifest at cursor. The inventory must
+// describe the objects in infs. It returns the path
+// of the manifest that was read, which should be
+// used as the cursor for the next call.
+//
+// If fn returns an error, scanInventory returns
+// cursor and the error so that the same manifest
+// is read again by the next call.
+func (st *tableState) scanInventory(infs InputFS, inv, cursor string, fn func(e *inventoryEntry) error) (string, error) {
+	ifs, dir, err := st.owner.Split(inv)
+	if err != nil {
+		return cursor, err
+	}
+	latest, err := latestManifest(ifs, strings.TrimSuffix(dir, "/"))
+	if err != nil {
+		return cursor, err
+	}
+	if latest == "" || latest <= cursor {
+		return cursor, nil
+	}
+	m, err := readManifest(ifs, latest)
+	if err != nil {
+		return cursor, err
+	}
+	if b, ok := infs.(*S3FS); ok && m.SourceBucket != "" && m.SourceBucket != b.Bucket {
+		return cursor, fmt.Errorf("inventory %s lists objects in bucket %s, not %s", latest, m.SourceBucket, b.Bucket)
+	}
+	if err := m.each(ifs, fn); err != nil {
+		return cursor, err
+	}
+	return latest, nil
+}
+
+// inventoryETag converts an ETag from an S3 inventory,
+// which is not quoted, into the form that is
+// produced by listing the objects in a bucket
+func inventoryETag(infs InputFS, etag string) string {
+	if _, ok := infs.(*S3FS); ok && !strings.HasPrefix(etag, `"`) {
+		return strconv.Quote(etag)
+	}
+	return etag
+}
@@ -0,0 +1,156 @@
+// Copyright (C) 2022 Sneller, Inc.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package db
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/csv"
+	"encoding/json"
+	"net/url"
+	"path"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestSyncInventory(t *testing.T) {
+	dfs := newDirFS(t, t.TempDir())
+	type object struct {
+		name, etag string
+		size       int
+	}
+	write := func(name string) object {
+		t.Helper()
+		buf := []byte(`{"name": "` + name + `"}`)
+		etag, err := dfs.WriteFile(name, buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return object{name: name, etag: etag, size: len(buf)}
+	}
+	const inv = "inventory/source/config"
+	// writeInventory writes an inventory
+	// in the directory for the given time
+	writeInventory := func(when string, lst ...object) {
+		t.Helper()
+		var buf bytes.Buffer
+		gz := gzip.NewWriter(&buf)
+		w := csv.NewWriter(gz)
+		for _, obj := range lst {
+			w.Write([]string{
+				"source",
+				url.QueryEscape(obj.name),
+				strconv.Itoa(obj.size),
+				time.Now().UTC().Format(time.RFC3339),
+				obj.etag,
+			})
+		}
+		w.Flush()
+		gz.Close()
+		data := path.Join(inv, "data", when+".csv.gz")
+		_, err := dfs.WriteFile(data, buf.Bytes())
+		if err != nil {
+			t.Fatal(err)
+		}
+		manifest, err := json.Marshal(map[string]any{
+			"sourceBucket": "source",
+			"fileFormat":   "CSV",
+			"fileSchema":   "Bucket, Key, Size, LastModifiedDate, ETag",
+			"files": []map[string]any{
+				{"key": data, "size": buf.Len()},
+			},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = dfs.WriteFile(path.Join(inv, when, manifestName), manifest)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	err := WriteDefinition(dfs, "default", &Definition{
+		Name: "table",
+		Inputs: []Input{{
+			Pattern:   "file://data/*.json",
+			Inventory: "file://" + inv + "/",
+		}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	owner := newTenant(dfs)
+	c := Config{
+		Align: 1024,
+		Logf:  t.Logf,
+	}
+	sync := func() {
+		t.Helper()
+		err := c.Sync(owner, "default", "table")
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	check := func(present, absent []string, cursor string) {
+		t.Helper()
+		idx, err := OpenIndex(dfs, "default", "table", owner.Key())
+		if err != nil {
+			t.Fatal(err)
+		}
+		idx.Inputs.Backing = dfs
+		for _, name := range present {
+			if !contains(t, idx, "file://"+name) {
+				t.Errorf("missing %s", name)
+			}
+		}
+		for _, name := range absent {
+			if contains(t, idx, "file://"+name) {
+				t.Errorf("unexpected %s", name)
+			}
+		}
+		if len(idx.Cursors) != 1 || idx.Cursors[0] != cursor {
+			t.Errorf("cursors %q, expected %q", idx.Cursors, cursor)
+		}
+	}
+
+	a := write("data/a.json")
+	b := write("data/b c.json")
+	other := write("other/x.json")
+	// objects that exist but are not
+	// in the inventory are not ingested
+	write("data/unlisted.json")
+	writeInventory("2024-01-01T00-00Z", a, b, other)
+	sync()
+	check([]string{"data/a.json", "data/b c.json"},
+		[]string{"other/x.json", "data/unlisted.json"},
+		path.Join(inv, "2024-01-01T00-00Z", manifestName))
+
+	// a new inventory lists new objects;
+	// deleted and overwritten objects are ignored
+	d := write("data/d.json")
+	e := write("data/e.json")
+	gone := object{name: "data/gone.json", etag: "etag", size: 10}
+	e.etag = "stale"
+	writeInventory("2024-01-02T00-00Z", a, b, d, e, gone)
+	sync()
+	check([]string{"data/a.json", "data/b c.json", "data/d.json"},
+		[]string{"data/e.json", "data/gone.json", "data/unlisted.json"},
+		path.Join(inv, "2024-01-02T00-00Z", manifestName))
+
+	// the same inventory is not read twice
+	owner.ro = true
+	sync()
+	owner.ro = false
+}
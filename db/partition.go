@@ -17,7 +17,9 @@ package db
 import (
 	"bytes"
 	"fmt"
+	"net/url"
 	"strconv"
+	"strings"
 	"unicode/utf8"
 
 	"github.com/SnellerInc/sneller/date"
@@ -25,6 +27,7 @@ import (
 	"github.com/SnellerInc/sneller/ion"
 	"github.com/SnellerInc/sneller/ion/blockfmt"
 	"golang.org/x/exp/maps"
+	"golang.org/x/exp/slices"
 )
 
 type partition struct {
@@ -38,6 +41,7 @@ type partition struct {
 // partition them.
 type collector struct {
 	def   []Partition
+	hive  bool // see Definition.HivePartitions
 	parts []partition
 	ind   map[string]int // index into parts
 	buf   []byte
@@ -70,8 +74,23 @@ func (c *collector) empty() bool {
 }
 
 // partition configures the collector to split
-// inputs into partitions.
-func (c *collector) init(parts []Partition) error {
+// inputs into partitions; if hive is set,
+// key=value segments of input paths are used
+// as additional partitions (see Definition.HivePartitions)
+func (c *collector) init(parts []Partition, hive bool) error {
+	if err := checkPartitions(parts); err != nil {
+		return err
+	}
+	c.def = parts
+	c.hive = hive
+	c.parts = c.parts[:0]
+	maps.Clear(c.ind)
+	return nil
+}
+
+// checkPartitions checks that the
+// partitions in a definition are well-formed
+func checkPartitions(parts []Partition) error {
 	for i := range parts {
 		field := parts[i].Field
 		if field == "" {
@@ -92,9 +111,6 @@ func (c *collector) init(parts []Partition) error {
 			}
 		}
 	}
-	c.def = parts
-	c.parts = c.parts[:0]
-	maps.Clear(c.ind)
 	return nil
 }
 
@@ -140,9 +156,59 @@ func (c *collector) match(glob, path string) ([]byte, error) {
 		}
 		c.buf = append(c.buf, seg...)
 	}
+	if c.hive {
+		c.hiveSegments(path, func(seg, _, _ string) {
+			if len(c.buf) > 0 {
+				c.buf = append(c.buf, '/')
+			}
+			c.buf = append(c.buf, seg...)
+		})
+	}
 	return c.buf, nil
 }
 
+// hiveSegments calls fn for each directory
+// segment of path of the form key=value, where
+// key is an identifier that is not already the
+// name of a partition and value is not empty;
+// val is the URL-unescaped value
+//
+// If the same key appears more than once,
+// only the first segment is used.
+func (c *collector) hiveSegments(path string, fn func(seg, key, val string)) {
+	// only directories are considered
+	i := strings.LastIndexByte(path, '/')
+	if i < 0 {
+		return
+	}
+	var seen []string
+	for _, seg := range strings.Split(path[:i], "/") {
+		key, val, ok := strings.Cut(seg, "=")
+		if !ok || key == "" || val == "" ||
+			!fsutil.ValidCaptureName(key) ||
+			!checkSegment([]byte(seg)) ||
+			c.isPartition(key) || slices.Contains(seen, key) {
+			continue
+		}
+		if v, err := url.PathUnescape(val); err == nil {
+			val = v
+		}
+		seen = append(seen, key)
+		fn(seg, key, val)
+	}
+}
+
+// isPartition returns whether field
+// is the name of a defined partition
+func (c *collector) isPartition(field string) bool {
+	for i := range c.def {
+		if c.def[i].Field == field {
+			return true
+		}
+	}
+	return false
+}
+
 func (c *collector) part(glob, path string) (*partition, error) {
 	name, err := c.match(glob, path)
 	if err != nil {
@@ -171,6 +237,14 @@ func (c *collector) part(glob, path string) (*partition, error) {
 			Datum: d,
 		})
 	}
+	if c.hive {
+		c.hiveSegments(path, func(_, key, val string) {
+			cons = append(cons, ion.Field{
+				Label: key,
+				Datum: ion.String(val),
+			})
+		})
+	}
 	str := string(c.buf)
 	if c.ind == nil {
 		c.ind = make(map[string]int)
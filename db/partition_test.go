@@ -26,7 +26,7 @@ func TestCollector(t *testing.T) {
 	good := func(conf []Partition, input, glob, name string, cons []ion.Field) {
 		t.Helper()
 		var c collector
-		err := c.init(conf)
+		err := c.init(conf, false)
 		if err != nil {
 			t.Error("init:", err)
 			return
@@ -46,7 +46,7 @@ func TestCollector(t *testing.T) {
 	bad := func(parts []Partition, errstr string) {
 		t.Helper()
 		var c collector
-		err := c.init(parts)
+		err := c.init(parts, false)
 		if err == nil {
 			t.Errorf("expected error")
 		} else if err.Error() != errstr {
@@ -125,6 +125,59 @@ func TestCollector(t *testing.T) {
 	}, `cannot use field name "!@#$" as value template`)
 }
 
+func TestCollectorHive(t *testing.T) {
+	run := func(parts []Partition, input, glob, name string, cons []ion.Field) {
+		t.Helper()
+		var c collector
+		err := c.init(parts, true)
+		if err != nil {
+			t.Fatal("init:", err)
+		}
+		part, err := c.part(glob, input)
+		if err != nil {
+			t.Error("part:", err)
+			return
+		}
+		if part.name != name {
+			t.Errorf("wanted name %q, got %q", name, part.name)
+		}
+		if !reflect.DeepEqual(cons, part.cons) {
+			t.Errorf("result mismatch: %v != %v", cons, part.cons)
+		}
+	}
+	run(nil,
+		"s3://bucket/logs/dt=2024-01-01/region=us/file.json",
+		"s3://bucket/logs/*/*/*.json",
+		"dt=2024-01-01/region=us",
+		[]ion.Field{
+			{Label: "dt", Datum: ion.String("2024-01-01")},
+			{Label: "region", Datum: ion.String("us")},
+		},
+	)
+	// file names, segments without a key or value,
+	// repeated keys and non-identifier keys are ignored;
+	// values are unescaped
+	run(nil,
+		"s3://bucket/a=x%20y/=z/b=/a=2/c-d=3/e=4.json",
+		"s3://bucket/*/*/*/*/*/*.json",
+		"a=x%20y",
+		[]ion.Field{
+			{Label: "a", Datum: ion.String("x y")},
+		},
+	)
+	// explicit partitions take precedence
+	run([]Partition{{Field: "dt", Type: "date"}},
+		"s3://bucket/2023-12-31/dt=2024-01-01/region=eu/file.json",
+		"s3://bucket/{dt}/*/*/*.json",
+		"2023-12-31/region=eu",
+		[]ion.Field{
+			{Label: "dt", Datum: ion.Timestamp(date.Date(2023, 12, 31, 0, 0, 0, 0))},
+			{Label: "region", Datum: ion.String("eu")},
+		},
+	)
+	run(nil, "s3://bucket/logs/file.json", "s3://bucket/logs/*.json", "", nil)
+}
+
 func TestCheckSegment(t *testing.T) {
 	run := func(s string, want bool) {
 		t.Helper()
@@ -165,6 +165,16 @@ type QueueRunner struct {
 	// will pause if it encounters an I/O error from
 	// the backing filesystem.
 	IOErrDelay time.Duration
+
+	// CompactInterval is the minimum amount of time
+	// between background compactions of each table
+	// (see Config.Compact). A compaction is started
+	// after new data has been appended to a table, and
+	// the merged packfiles are written while ingestion
+	// into the table continues.
+	// If CompactInterval is less than or equal to zero,
+	// tables are not compacted in the background.
+	CompactInterval time.Duration
 }
 
 type queueBatch struct {
@@ -256,7 +266,7 @@ func open(infs InputFS, name, etag string, size int64) (fs.File, error) {
 // this is supposed to be safe to call from multiple goroutines
 func (q *QueueRunner) filter(src *queueBatch, cfg *Config, def *Definition, dst *batch) error {
 	var mr fsutil.Matcher
-	dst.filtered.init(def.Partitions)
+	dst.filtered.init(def.Partitions, def.HivePartitions)
 	dst.indirect = dst.indirect[:0]
 outer:
 	for i := range src.inputs {
@@ -374,10 +384,13 @@ func (q *QueueRunner) runTable(ctx context.Context, src *queueBatch, ti *tableIn
 		} else {
 			batchstart := time.Now()
 			total, size := dst.filtered.total()
+			ti.lock.Lock()
 			err = ti.append(ctx, dst.filtered.parts)
+			ti.lock.Unlock()
 			if err == nil {
 				q.logf("table %s/%s inserted %d objects %d source bytes mindelay %s maxdelay %s wallclock %s",
 					ti.state.db, ti.state.table, total, size, time.Since(dst.latest), time.Since(dst.earliest), time.Since(batchstart))
+				q.maybeCompact(ti)
 			}
 		}
 	}
@@ -388,7 +401,9 @@ func (q *QueueRunner) runTable(ctx context.Context, src *queueBatch, ti *tableIn
 		if err == ErrBuildAgain {
 			q.logf("%s/%s: still scanning", ti.state.db, ti.state.table)
 		} else {
+			ti.lock.Lock()
 			ti.state.invalidate()
+			ti.lock.Unlock()
 		}
 		q.logf("updating %s/%s: %s", ti.state.db, ti.state.table, err)
 	}
@@ -458,8 +473,16 @@ type scanState struct {
 }
 
 type tableInfo struct {
+	// lock serializes updates to the index
+	// through state between runTable and
+	// background compactions
+	lock  sync.Mutex
 	state tableState
 	scan  atomic.Pointer[scanState]
+
+	compacting  atomic.Bool
+	compactwg   sync.WaitGroup
+	lastCompact time.Time // only accessed by runTable
 }
 
 func (ti *tableInfo) scanning() bool {
@@ -486,9 +509,78 @@ func startScan(ti *tableInfo) {
 	go ti.bgScan(state)
 }
 
-func stopScans(defs map[dbtable]*tableInfo) {
+// maybeCompact starts a background compaction
+// of ti if one is due and none is running
+func (q *QueueRunner) maybeCompact(ti *tableInfo) {
+	if q.CompactInterval <= 0 || ti.scanning() ||
+		time.Since(ti.lastCompact) < q.CompactInterval {
+		return
+	}
+	if !ti.compacting.CompareAndSwap(false, true) {
+		return
+	}
+	ti.lastCompact = time.Now()
+	ti.compactwg.Add(1)
+	go ti.bgCompact()
+}
+
+// bgCompact compacts a table in the background.
+// The merged packfiles are written from a snapshot
+// of the index without holding ti.lock, so appends
+// can proceed in the meantime; the compaction is
+// committed under ti.lock as long as the packfiles
+// that it replaces are still in the index.
+func (ti *tableInfo) bgCompact() {
+	defer ti.compactwg.Done()
+	defer ti.compacting.Store(false)
+	ctx, task := trace.NewTask(context.Background(), "bg-compact")
+	defer task.End()
+	// use a separate tableState for the snapshot
+	// so that its cached index isn't shared with runTable
+	snap := &tableState{
+		def:   ti.state.def,
+		conf:  ti.state.conf,
+		owner: ti.state.owner,
+		ofs:   ti.state.ofs,
+		db:    ti.state.db,
+		table: ti.state.table,
+	}
+	idx, err := snap.index(ctx)
+	if err != nil {
+		snap.logf("background compaction: %s", err)
+		return
+	}
+	idx.Inputs.Backing = snap.ofs
+	if idx.Scanning {
+		return
+	}
+	cmp, err := snap.prepareCompaction(ctx, idx)
+	if err != nil || cmp == nil {
+		if err != nil {
+			snap.logf("background compaction: %s", err)
+		}
+		return
+	}
+	ti.lock.Lock()
+	defer ti.lock.Unlock()
+	live, err := ti.state.index(ctx)
+	if err != nil {
+		cmp.Abort()
+	} else {
+		live.Inputs.Backing = ti.state.ofs
+		err = ti.state.commitCompaction(ctx, live, cmp)
+	}
+	if err != nil {
+		ti.state.logf("background compaction: %s", err)
+	}
+}
+
+// stopBackground cancels background scans and
+// waits for background compactions to finish
+func stopBackground(defs map[dbtable]*tableInfo) {
 	for _, ti := range defs {
 		ti.endScan()
+		ti.compactwg.Wait()
 	}
 }
 
@@ -497,7 +589,7 @@ func stopScans(defs map[dbtable]*tableInfo) {
 func (q *QueueRunner) Run(in Queue) error {
 	var lastRefresh time.Time
 	var ts tableStates
-	defer stopScans(ts.defs)
+	defer stopBackground(ts.defs)
 
 	// double-buffered queue batches
 	var batches [2]queueBatch
@@ -653,7 +745,7 @@ func (q *QueueRunner) updateDefs(ts *tableStates) error {
 }
 
 func (q *QueueRunner) finishUpdates(ts *tableStates) {
-	stopScans(ts.defs)
+	stopBackground(ts.defs)
 	for key, ti := range ts.update {
 		if ti == nil {
 			delete(ts.defs, key)
@@ -0,0 +1,81 @@
+// Copyright (C) 2022 Sneller, Inc.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package db
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/SnellerInc/sneller/date"
+	"github.com/SnellerInc/sneller/ion/blockfmt"
+
+	"golang.org/x/exp/slices"
+)
+
+// Purge applies the retention policy for db/table
+// (see Definition.Retention) and returns the list of
+// objects that were dropped from the index because
+// all of the data they contain has expired.
+//
+// The retention policy is also applied during
+// Sync, but Purge can be used to enforce it
+// on tables that are not being updated.
+// The dropped objects are deleted from the
+// backing store once GCMinimumAge has elapsed;
+// subsequent calls to Purge or Sync will remove
+// objects that were dropped by earlier calls.
+//
+// If dryrun is true, then Purge returns the list of
+// objects that would be dropped without modifying
+// the index or deleting any objects.
+func (c *Config) Purge(who Tenant, db, table string, dryrun bool) ([]blockfmt.Quarantined, error) {
+	st, err := c.open(db, table, who)
+	if err != nil {
+		return nil, err
+	}
+	if st.def.Retention == nil {
+		return nil, fmt.Errorf("table %s/%s has no retention policy", db, table)
+	}
+	ctx := context.Background()
+	idx, err := st.index(ctx)
+	if err != nil {
+		return nil, err
+	}
+	idx.Inputs.Backing = st.ofs
+	before := len(idx.ToDelete)
+	var purged []blockfmt.Quarantined
+	if st.purgeExpired(idx) {
+		purged = slices.Clone(idx.ToDelete[before:])
+	}
+	if dryrun {
+		// discard the modified index
+		st.invalidate()
+		return purged, nil
+	}
+	gc := false
+	if rmfs, ok := st.ofs.(RemoveFS); ok {
+		gcconf := GCConfig{Precise: true, Logf: st.logf}
+		gc = gcconf.preciseGC(rmfs, idx)
+	}
+	if len(purged) == 0 && !gc {
+		return nil, nil
+	}
+	for i := range purged {
+		st.logf("purging expired object %s", purged[i].Path)
+	}
+	idx.Created = date.Now().Truncate(time.Microsecond)
+	return purged, st.flush(ctx, idx)
+}
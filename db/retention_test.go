@@ -0,0 +1,131 @@
+// Copyright (C) 2022 Sneller, Inc.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package db
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/SnellerInc/sneller/date"
+	"github.com/SnellerInc/sneller/ion"
+	"github.com/SnellerInc/sneller/ion/blockfmt"
+)
+
+func TestPurge(t *testing.T) {
+	tmpdir := t.TempDir()
+	dfs := newDirFS(t, tmpdir)
+	owner := newTenant(dfs)
+	checkFiles(t)
+
+	now := date.Now()
+	const day = 24 * time.Hour
+	mksparse := func(from, to time.Duration) blockfmt.SparseIndex {
+		var s blockfmt.SparseIndex
+		rng := blockfmt.NewRange([]string{"date"},
+			ion.Timestamp(now.Add(-to)), ion.Timestamp(now.Add(-from)))
+		s.Push([]blockfmt.Range{rng})
+		return s
+	}
+	err := WriteDefinition(dfs, "default", &Definition{
+		Name: "table",
+		Retention: &RetentionPolicy{
+			Field:    "date",
+			ValidFor: date.Duration{Day: 10},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	root := "db/default/table"
+	err = os.MkdirAll(path.Join(tmpdir, root), 0750)
+	if err != nil {
+		t.Fatal(err)
+	}
+	idx := &blockfmt.Index{Name: "table"}
+	for _, d := range []struct {
+		name     string
+		from, to time.Duration
+	}{
+		{"inline-expired", 14 * day, 17 * day},
+		{"inline-retained", 8 * day, 11 * day},
+	} {
+		p := path.Join(root, d.name)
+		etag, err := dfs.WriteFile(p, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		idx.Inline = append(idx.Inline, blockfmt.Descriptor{
+			ObjectInfo: blockfmt.ObjectInfo{Path: p, ETag: etag},
+			Trailer: blockfmt.Trailer{
+				Algo:   "zstd",
+				Blocks: []blockfmt.Blockdesc{{Chunks: 1}},
+				Sparse: mksparse(d.from, d.to),
+			},
+		})
+	}
+	buf, err := blockfmt.Sign(owner.Key(), idx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = dfs.WriteFile(IndexPath("default", "table"), buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := Config{Logf: t.Logf}
+	check := func(dryrun bool) {
+		t.Helper()
+		purged, err := c.Purge(owner, "default", "table", dryrun)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(purged) != 1 || path.Base(purged[0].Path) != "inline-expired" {
+			t.Fatalf("unexpected purge result %v", purged)
+		}
+		idx, err := OpenIndex(dfs, "default", "table", owner.Key())
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := 2
+		if !dryrun {
+			want = 1
+		}
+		if len(idx.Inline) != want {
+			t.Fatalf("dryrun=%v: %d objects in index; expected %d", dryrun, len(idx.Inline), want)
+		}
+		_, err = fs.Stat(dfs, path.Join(root, "inline-expired"))
+		if exists := err == nil; exists != dryrun {
+			t.Fatalf("dryrun=%v: stat: %v", dryrun, err)
+		} else if !exists && !errors.Is(err, fs.ErrNotExist) {
+			t.Fatal(err)
+		}
+	}
+	check(true)
+	check(false)
+
+	// no policy => error
+	err = WriteDefinition(dfs, "default", &Definition{Name: "table"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = c.Purge(owner, "default", "table", true)
+	if err == nil {
+		t.Fatal("expected an error for a table without a retention policy")
+	}
+}
@@ -73,6 +73,28 @@ func (st *tableState) defChanged(idx *blockfmt.Index) bool {
 	return string(st.def.Hash()) != string(hash)
 }
 
+// restartScan prepares an index for which
+// scanning has completed to be scanned again.
+// The cursors of incremental and inventory-based
+// inputs are kept so that scanning resumes where
+// the previous scan ended, and the start of the
+// previous scan becomes the index checkpoint.
+func (st *tableState) restartScan(idx *blockfmt.Index) {
+	if len(idx.Cursors) != len(st.def.Inputs) || st.defChanged(idx) {
+		// scan starts from scratch
+		idx.Cursors = nil
+		return
+	}
+	for i := range st.def.Inputs {
+		if !st.def.Inputs[i].Incremental && st.def.Inputs[i].Inventory == "" {
+			idx.Cursors[i] = ""
+		}
+	}
+	idx.Checkpoint = idx.LastScan
+	idx.LastScan = date.Now()
+	idx.Scanning = true
+}
+
 func (st *tableState) scan(idx *blockfmt.Index, flushOnComplete bool) (int, error) {
 	changed := st.defChanged(idx)
 	if changed {
@@ -81,16 +103,23 @@ func (st *tableState) scan(idx *blockfmt.Index, flushOnComplete bool) (int, erro
 	if changed || len(idx.Cursors) != len(st.def.Inputs) {
 		idx.LastScan = date.Now()
 		idx.Cursors = make([]string, len(st.def.Inputs))
+		idx.Checkpoint = date.Time{}
 		idx.Scanning = true
 	}
 	if !idx.Scanning {
 		return 0, nil
 	}
+	// objects modified before cutoff have
+	// been listed by a previous complete scan
+	var cutoff time.Time
+	if slack := st.conf.CheckpointSlack; slack > 0 && !idx.Checkpoint.IsZero() {
+		cutoff = idx.Checkpoint.Time().Add(-slack)
+	}
 	idx.Inputs.Backing = st.ofs
 
 	var ctx context.Context // set if needed
 	var c collector
-	err := c.init(st.def.Partitions)
+	err := c.init(st.def.Partitions, st.def.HivePartitions)
 	if err != nil {
 		return 0, err
 	}
@@ -137,17 +166,9 @@ func (st *tableState) scan(idx *blockfmt.Index, flushOnComplete bool) (int, erro
 			}
 			walkfs = cfs.WithContext(ctx)
 		}
-		walk := func(p string, f fs.File, err error) error {
-			if err != nil {
-				return err
-			}
-			info, err := f.Stat()
-			if err != nil {
-				if errors.Is(err, fs.ErrNotExist) {
-					return nil
-				}
-				return err
-			}
+		// insert adds the object at p to the
+		// collector if it is not already in the index
+		insert := func(p string, f fs.File, info fs.FileInfo) error {
 			etag, err := infs.ETag(p, info)
 			if err != nil {
 				f.Close()
@@ -181,7 +202,6 @@ func (st *tableState) scan(idx *blockfmt.Index, flushOnComplete bool) (int, erro
 			}
 			if !ret {
 				// file is not new
-				seek = p
 				if time.Since(start) >= maxDuration {
 					return errStop
 				}
@@ -213,23 +233,75 @@ func (st *tableState) scan(idx *blockfmt.Index, flushOnComplete bool) (int, erro
 			if prepend >= 0 {
 				part.prepend = prepend
 			}
-			seek = p
 			if total >= maxInputs || size >= maxSize || time.Since(start) >= maxDuration {
 				return errStop
 			}
 			return nil
 		}
-		pat, err = fsutil.ToGlob(pat)
-		if err != nil {
-			return 0, err
-		}
-		if strings.HasSuffix(seek, "/") {
-			st.conf.logf("fixing bad cursor %q", seek)
-			seek = strings.TrimSuffix(seek, "/")
+		if inv := st.def.Inputs[i].Inventory; inv != "" {
+			var mr fsutil.Matcher
+			seek, err = st.scanInventory(infs, inv, seek, func(e *inventoryEntry) error {
+				if e.modified.Before(cutoff) {
+					return nil
+				}
+				if ok, _ := mr.Match(fullpat, prefix+e.key); !ok {
+					return nil
+				}
+				f, err := open(infs, e.key, inventoryETag(infs, e.etag), e.size)
+				if err != nil {
+					// the object may have been deleted or
+					// overwritten since the inventory was produced
+					if errors.Is(err, fs.ErrNotExist) || errors.Is(err, errETagChanged) {
+						return nil
+					}
+					return err
+				}
+				info, err := f.Stat()
+				if err != nil {
+					f.Close()
+					return err
+				}
+				return insert(e.key, f, info)
+			})
+		} else {
+			walk := func(p string, f fs.File, err error) error {
+				if err != nil {
+					return err
+				}
+				info, err := f.Stat()
+				if err != nil {
+					if errors.Is(err, fs.ErrNotExist) {
+						return nil
+					}
+					return err
+				}
+				if info.ModTime().Before(cutoff) {
+					// listed by a previous scan
+					f.Close()
+					seek = p
+					if time.Since(start) >= maxDuration {
+						return errStop
+					}
+					return nil
+				}
+				err = insert(p, f, info)
+				if err == nil || err == errStop {
+					seek = p
+				}
+				return err
+			}
+			pat, err = fsutil.ToGlob(pat)
+			if err != nil {
+				return 0, err
+			}
+			if strings.HasSuffix(seek, "/") {
+				st.conf.logf("fixing bad cursor %q", seek)
+				seek = strings.TrimSuffix(seek, "/")
+			}
+			err = fsutil.WalkGlob(walkfs, seek, pat, walk)
 		}
-		err = fsutil.WalkGlob(walkfs, seek, pat, walk)
 		idx.Cursors[i] = seek
-		if err == errStop || errors.Is(err, context.DeadlineExceeded) {
+		if errors.Is(err, errStop) || errors.Is(err, context.DeadlineExceeded) {
 			if pe, ok := err.(*fs.PathError); ok {
 				// we aborted early and know the path we
 				// were last scanning, so use that as
@@ -73,6 +73,11 @@ const DefaultAlgo = "zion"
 // ingested.
 var ErrBuildAgain = errors.New("partial db update")
 
+// ErrConflict is returned when an index could
+// not be written because it was modified by
+// another process after it was loaded.
+var ErrConflict = errors.New("synchronization violation detected")
+
 // Config is a set of configuration items
 // for synchronizing an Index to match
 // a specification from a Definition.
@@ -106,6 +111,11 @@ type Config struct {
 	// data would use 6 CPU cores (provided GOMAXPROCS is at least this high).
 	// See blockfmt.MinInputBytesPerCPU
 	MinInputBytesPerCPU int64
+	// UploadRetries is the number of times that
+	// the upload of a part of a packfile is retried
+	// before ingestion fails.
+	// See blockfmt.Converter.UploadRetries
+	UploadRetries int
 	// Force forces a full index rebuild
 	// even when the input appears to be up-to-date.
 	Force bool
@@ -128,6 +138,34 @@ type Config struct {
 	// to spend listing objects before deciding
 	// to bail out of a scan.
 	MaxScanTime time.Duration
+	// CheckpointSlack, if positive, enables
+	// checkpointed scanning: when a table is
+	// re-scanned after a complete scan, objects
+	// that were last modified more than CheckpointSlack
+	// before the start of the previous complete scan
+	// (see blockfmt.Index.Checkpoint) are assumed to
+	// have been ingested already and are skipped
+	// without consulting the index.
+	//
+	// CheckpointSlack should be larger than the clock
+	// skew between the object store and this process
+	// plus the duration of the longest upload, since
+	// some object stores (notably S3) report the time
+	// at which an upload began as the modification time.
+	CheckpointSlack time.Duration
+	// Checksums, if true, causes a checksum to be
+	// recorded for each block of new packfiles
+	// so that corruption can be detected on read.
+	// See blockfmt.Trailer.Checksums.
+	Checksums bool
+	// Encrypt, if true, causes a data key to be
+	// generated for each table that does not
+	// already have one. The blocks of every new
+	// packfile in a table with a data key are
+	// encrypted with that key, which is stored
+	// in the table index wrapped with the tenant key.
+	// See blockfmt.DataKey.
+	Encrypt bool
 
 	// NewIndexScan, if true, enables scanning
 	// for newly-created index objects.
@@ -553,7 +591,7 @@ func (c *Config) Sync(who Tenant, db, tblpat string) error {
 		}
 		restart := false
 		if !idx.Scanning {
-			idx.Cursors = nil
+			st.restartScan(idx)
 			restart = true
 		}
 		// we flush the new index on termination
@@ -728,6 +766,24 @@ func (st *tableState) updateFailed(ctx context.Context, empty bool, parts []part
 	}
 }
 
+// sortKeys returns the parsed paths of the
+// sort keys of the table; invalid paths are ignored
+func (st *tableState) sortKeys() [][]string {
+	if st.def == nil {
+		return nil
+	}
+	var out [][]string
+	for _, k := range st.def.SortKeys {
+		p, err := parseSortKey(k)
+		if err != nil {
+			st.logf("ignoring sort key: %s", err)
+			continue
+		}
+		out = append(out, p)
+	}
+	return out
+}
+
 // purgeExpired purges expired entries,
 // returning a value indicating whether or not
 // any entries were expired
@@ -825,13 +881,35 @@ func (st *tableState) addDefHash(d ion.Datum) ion.Datum {
 }
 
 func (st *tableState) writeIndex(idx *blockfmt.Index) error {
+	if err := st.checkIndex(); err != nil {
+		return err
+	}
+	buf, err := blockfmt.Sign(st.owner.Key(), idx)
+	if err != nil {
+		return err
+	}
+	if len(buf) > MaxIndexSize {
+		return fmt.Errorf("index would be %d bytes; greater than max %d", len(buf), MaxIndexSize)
+	}
+	etag, err := st.ofs.WriteFile(IndexPath(st.db, st.table), buf)
+	if err == nil {
+		st.overwrite(idx, etag)
+	}
+	return err
+}
+
+// checkIndex checks that the index on disk
+// is the one that was last loaded or written
+// (or that it doesn't exist if no index was loaded),
+// returning an error wrapping ErrConflict otherwise
+func (st *tableState) checkIndex() error {
 	idp := IndexPath(st.db, st.table)
 	info, err := fs.Stat(st.ofs, idp)
 	if st.cache.etag == "" {
 		// expect no file to exist
 		if err == nil || !errors.Is(err, fs.ErrNotExist) {
 			st.invalidate()
-			return fmt.Errorf("synchronization violation detected: fs.Stat for %s produced %v", idp, err)
+			return fmt.Errorf("%w: fs.Stat for %s produced %v", ErrConflict, idp, err)
 		}
 	} else {
 		if err != nil {
@@ -843,21 +921,10 @@ func (st *tableState) writeIndex(idx *blockfmt.Index) error {
 		}
 		if st.cache.etag != etag {
 			st.invalidate()
-			return fmt.Errorf("synchronization violation detected: found etag %s -> %s", st.cache.etag, etag)
+			return fmt.Errorf("%w: found etag %s -> %s", ErrConflict, st.cache.etag, etag)
 		}
 	}
-	buf, err := blockfmt.Sign(st.owner.Key(), idx)
-	if err != nil {
-		return err
-	}
-	if len(buf) > MaxIndexSize {
-		return fmt.Errorf("index would be %d bytes; greater than max %d", len(buf), MaxIndexSize)
-	}
-	etag, err := st.ofs.WriteFile(idp, buf)
-	if err == nil {
-		st.overwrite(idx, etag)
-	}
-	return err
+	return nil
 }
 
 // flush writes out the provided index
@@ -924,6 +991,10 @@ func (e *errUpdateFailed) Unwrap() error {
 }
 
 func (st *tableState) force(ctx context.Context, idx *blockfmt.Index, parts []partition) error {
+	key, wrapped, err := st.dataKey(idx)
+	if err != nil {
+		return err
+	}
 	extra := make([]blockfmt.Descriptor, 0, len(parts))
 	errs := make([]error, len(parts))
 	var wg sync.WaitGroup
@@ -939,7 +1010,7 @@ func (st *tableState) force(ctx context.Context, idx *blockfmt.Index, parts []pa
 		}
 		go func(i int) {
 			defer wg.Done()
-			errs[i] = st.forcePart(ctx, prepend, dst, &parts[i])
+			errs[i] = st.forcePart(ctx, key, prepend, dst, &parts[i])
 		}(i)
 	}
 	wg.Wait()
@@ -963,19 +1034,60 @@ func (st *tableState) force(ctx context.Context, idx *blockfmt.Index, parts []pa
 	}
 	idx.Algo = "zstd"
 	idx.Created = date.Now().Truncate(time.Microsecond)
+	idx.DataKey = wrapped
 	idx.Inline = append(idx.Inline, extra...)
 	return st.flush(ctx, idx)
 }
 
-func (st *tableState) forcePart(ctx context.Context, prepend, dst *blockfmt.Descriptor, part *partition) error {
+// dataKey returns the key used to encrypt the
+// packfiles of the table described by idx along
+// with its wrapped form to be stored in the index.
+// If the table doesn't have a data key yet and
+// st.conf.Encrypt is set, then a new one is generated.
+// If the table is not encrypted, dataKey returns nil.
+func (st *tableState) dataKey(idx *blockfmt.Index) (*blockfmt.DataKey, []byte, error) {
+	var wrapped []byte
+	if idx != nil {
+		wrapped = idx.DataKey
+	}
+	if len(wrapped) == 0 && !st.conf.Encrypt {
+		return nil, nil, nil
+	}
+	master := st.owner.Key()
+	if master == nil {
+		return nil, nil, fmt.Errorf("%s/%s: cannot encrypt packfiles without a tenant key", st.db, st.table)
+	}
+	if len(wrapped) > 0 {
+		key, err := blockfmt.UnwrapDataKey(master, wrapped)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s/%s: %w", st.db, st.table, err)
+		}
+		return key, wrapped, nil
+	}
+	key, err := blockfmt.NewDataKey()
+	if err != nil {
+		return nil, nil, err
+	}
+	wrapped, err = key.Wrap(master)
+	if err != nil {
+		return nil, nil, err
+	}
+	return key, wrapped, nil
+}
+
+func (st *tableState) forcePart(ctx context.Context, key *blockfmt.DataKey, prepend, dst *blockfmt.Descriptor, part *partition) error {
 	defer trace.StartRegion(ctx, "force-part").End()
 	c := blockfmt.Converter{
 		Inputs:              part.lst,
 		Align:               st.conf.align(),
 		FlushMeta:           st.conf.flushMeta(),
 		Comp:                st.conf.comp(),
+		Checksums:           st.conf.Checksums,
+		Key:                 key,
 		Constants:           part.cons,
 		MinInputBytesPerCPU: st.conf.MinInputBytesPerCPU,
+		UploadRetries:       st.conf.UploadRetries,
+		SortKeys:            st.sortKeys(),
 	}
 
 	if prepend != nil {
@@ -988,6 +1100,7 @@ func (st *tableState) forcePart(ctx context.Context, prepend, dst *blockfmt.Desc
 		// that way we can use server-side copy for some prepends
 		c.Prepend.R = f
 		c.Prepend.Trailer = &prepend.Trailer
+		c.Prepend.Path = prepend.Path
 	}
 
 	name := "packed-" + uuid() + suffixForComp(c.Comp)
@@ -997,6 +1110,7 @@ func (st *tableState) forcePart(ctx context.Context, prepend, dst *blockfmt.Desc
 		return err
 	}
 	c.Output = out
+	c.Path = fp
 	err = c.Run()
 	if err != nil {
 		abort(out)
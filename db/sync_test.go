@@ -31,6 +31,7 @@ import (
 
 	"github.com/SnellerInc/sneller/compr"
 	"github.com/SnellerInc/sneller/date"
+	"github.com/SnellerInc/sneller/expr"
 	"github.com/SnellerInc/sneller/expr/blob"
 	"github.com/SnellerInc/sneller/ion"
 	"github.com/SnellerInc/sneller/ion/blockfmt"
@@ -341,6 +342,14 @@ func TestSync(t *testing.T) {
 	}
 	checkContents(t, idx1, dfs)
 	checkNoGarbage(t, dfs, "db/default/parking", idx1)
+	// the schema should cover both inputs
+	schema := idx1.Schema()
+	if schema == nil {
+		t.Fatal("no schema for idx1")
+	}
+	if ts := schema.TypeOf(expr.MakePath([]string{"Issue", "Tick"})); ts&expr.IntegerType == 0 || ts&expr.MissingType == 0 {
+		t.Errorf("unexpected type for Issue.Tick: %s", ts)
+	}
 
 	// check that changing the definition for a
 	// table causes the index to be rewritten
@@ -519,6 +528,89 @@ func TestMaxBytesSync(t *testing.T) {
 	owner.ro = false
 }
 
+func TestSyncHivePartitions(t *testing.T) {
+	tmpdir := t.TempDir()
+	dfs := newDirFS(t, tmpdir)
+	type part struct{ dt, region string }
+	parts := []part{
+		{"2024-01-01", "us"},
+		{"2024-01-01", "eu"},
+		{"2024-01-02", "us"},
+		{"2024-01-02", "eu"},
+	}
+	for i, p := range parts {
+		name := fmt.Sprintf("logs/dt=%s/region=%s/data.json", p.dt, p.region)
+		_, err := dfs.WriteFile(name, []byte(fmt.Sprintf(`{"n": %d}`, i)))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	err := WriteDefinition(dfs, "default", &Definition{
+		Name:           "logs",
+		Inputs:         []Input{{Pattern: "file://logs/*/*/*.json"}},
+		HivePartitions: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	owner := newTenant(dfs)
+	c := Config{
+		Align: 1024,
+		Logf:  t.Logf,
+	}
+	err = c.Sync(owner, "default", "*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	idx, err := OpenIndex(dfs, "default", "logs", owner.Key())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(idx.Inline) != len(parts) {
+		t.Fatalf("expected %d packfiles; got %d", len(parts), len(idx.Inline))
+	}
+	for i := range idx.Inline {
+		d := &idx.Inline[i]
+		dt, ok := d.Trailer.Sparse.Const("dt")
+		if !ok {
+			t.Fatalf("%s: no dt constant", d.Path)
+		}
+		region, ok := d.Trailer.Sparse.Const("region")
+		if !ok {
+			t.Fatalf("%s: no region constant", d.Path)
+		}
+		dts, _ := dt.String()
+		rs, _ := region.String()
+		want := fmt.Sprintf("db/default/logs/dt=%s/region=%s/", dts, rs)
+		if !strings.HasPrefix(d.Path, want) {
+			t.Errorf("path %s does not have prefix %s", d.Path, want)
+		}
+	}
+	if !idx.HasPartition("region") || !idx.HasPartition("dt") {
+		t.Error("expected region and dt to be partitions")
+	}
+	min, max, ok := idx.KeyRange([]string{"region"})
+	if !ok || !min.Equal(ion.String("eu")) || !max.Equal(ion.String("us")) {
+		t.Errorf("KeyRange(region) = %v %v %v", min, max, ok)
+	}
+	// an equality filter on the partition
+	// values selects exactly one packfile
+	var f blockfmt.Filter
+	f.Compile(expr.And(
+		expr.Compare(expr.Equals, expr.Ident("dt"), expr.String("2024-01-02")),
+		expr.Compare(expr.Equals, expr.Ident("region"), expr.String("eu")),
+	))
+	var matched []string
+	for i := range idx.Inline {
+		if f.MatchesAny(&idx.Inline[i].Trailer.Sparse) {
+			matched = append(matched, idx.Inline[i].Path)
+		}
+	}
+	if len(matched) != 1 || !strings.HasPrefix(matched[0], "db/default/logs/dt=2024-01-02/region=eu/") {
+		t.Errorf("unexpected matches %v", matched)
+	}
+}
+
 func TestSyncRetention(t *testing.T) {
 	tmpdir := t.TempDir()
 	dfs := newDirFS(t, tmpdir)
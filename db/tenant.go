@@ -49,6 +49,12 @@ type TenantConfig struct {
 	// allowed to be scanned for each query. If
 	// this is 0, there is no limit.
 	MaxScanBytes uint64
+	// FiscalYearStart is the first month of the
+	// fiscal year (from 1 for January to 12 for December)
+	// used by FISCAL_YEAR and FISCAL_QUARTER in queries
+	// that do not specify it. If this is 0, the
+	// fiscal year starts in January.
+	FiscalYearStart int
 }
 
 // TenantConfigurable is a tenant that may provide
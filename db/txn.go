@@ -0,0 +1,394 @@
+// Copyright (C) 2022 Sneller, Inc.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package db
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/SnellerInc/sneller/date"
+	"github.com/SnellerInc/sneller/ion/blockfmt"
+
+	"golang.org/x/exp/slices"
+)
+
+// ErrTxnDone is returned when a Txn is used
+// after it has been committed or rolled back.
+var ErrTxnDone = errors.New("transaction already committed or rolled back")
+
+// Txn stages new packfiles for one or more
+// tables so that the corresponding index
+// updates can be applied together by Commit
+// or discarded together by Rollback.
+//
+// Staged packfiles are written immediately,
+// but they are not referenced by any index
+// until Commit is called. If Commit fails
+// partway through, the indexes that were
+// already updated are restored to their
+// previous contents.
+//
+// Note that a Txn spanning more than one table
+// is not atomic: each index is written separately,
+// so readers may observe some of the tables updated
+// before the others, and a process that dies during
+// Commit may leave only some of the indexes updated
+// (see Txn.Commit).
+//
+// A Txn may be used from multiple goroutines.
+type Txn struct {
+	conf  *Config
+	owner Tenant
+
+	lock   sync.Mutex
+	tables map[string]*txnTable
+	done   bool
+}
+
+type txnTable struct {
+	st    *tableState
+	descs []blockfmt.Descriptor
+	// inputs[i] are the inputs that were
+	// ingested into descs[inputs[i].desc]
+	inputs []txnInput
+	// wrapped data key used to
+	// encrypt the staged packfiles
+	// (once keyed is set)
+	wrapped []byte
+	keyed   bool
+
+	// state populated by Commit:
+	idx     *blockfmt.Index
+	prev    []byte // signed index before Commit; nil if none
+	written bool
+}
+
+type txnInput struct {
+	path, etag string
+	desc       int
+}
+
+// Begin starts a new transaction
+// on behalf of the tenant who.
+func (c *Config) Begin(who Tenant) *Txn {
+	return &Txn{
+		conf:   c,
+		owner:  who,
+		tables: make(map[string]*txnTable),
+	}
+}
+
+// table returns the staging state for db/table;
+// the caller must hold t.lock
+func (t *Txn) table(db, table string) (*txnTable, error) {
+	if t.done {
+		return nil, ErrTxnDone
+	}
+	if !validName(db) || !validName(table) {
+		return nil, fmt.Errorf("invalid table %q/%q", db, table)
+	}
+	name := path.Join(db, table)
+	if tt := t.tables[name]; tt != nil {
+		return tt, nil
+	}
+	st, err := t.conf.open(db, table, t.owner)
+	if err != nil {
+		return nil, err
+	}
+	tt := &txnTable{st: st}
+	t.tables[name] = tt
+	return tt, nil
+}
+
+// AppendDescriptors stages lst to be appended to
+// the index for db/table when the transaction is
+// committed. Each descriptor must refer to a
+// packfile that lives under the db/<db>/<table>/ prefix.
+func (t *Txn) AppendDescriptors(db, table string, lst []blockfmt.Descriptor) error {
+	prefix := path.Join("db", db, table) + "/"
+	for i := range lst {
+		if !strings.HasPrefix(lst[i].Path, prefix) {
+			return fmt.Errorf("Txn.AppendDescriptors: %s is not under %s", lst[i].Path, prefix)
+		}
+	}
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	tt, err := t.table(db, table)
+	if err != nil {
+		return err
+	}
+	tt.descs = append(tt.descs, lst...)
+	return nil
+}
+
+// Append converts lst into a new packfile
+// for db/table and stages it to be added to
+// the index when the transaction is committed.
+// Like Config.Append, the inputs are written
+// into the unpartitioned portion of the table.
+// If any of the inputs has already been ingested
+// into the table by the time the transaction
+// is committed, Commit fails with ErrConflict.
+func (t *Txn) Append(db, table string, lst []blockfmt.Input) error {
+	if len(lst) == 0 {
+		return nil
+	}
+	for i := range lst {
+		if lst[i].F == nil {
+			return fmt.Errorf("Txn.Append: input %s has no format", lst[i].Path)
+		}
+	}
+	t.lock.Lock()
+	tt, err := t.table(db, table)
+	if err == nil && !tt.keyed {
+		tt.wrapped, err = tt.dataKey()
+		tt.keyed = err == nil
+	}
+	t.lock.Unlock()
+	if err != nil {
+		return err
+	}
+	var key *blockfmt.DataKey
+	if tt.wrapped != nil {
+		key, err = blockfmt.UnwrapDataKey(t.owner.Key(), tt.wrapped)
+		if err != nil {
+			return err
+		}
+	}
+	var desc blockfmt.Descriptor
+	part := &partition{prepend: -1, lst: lst}
+	err = tt.st.forcePart(context.Background(), key, nil, &desc, part)
+	if err != nil {
+		return err
+	}
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if t.done {
+		// raced with Commit or Rollback
+		t.remove([]string{desc.Path})
+		return ErrTxnDone
+	}
+	n := len(tt.descs)
+	tt.descs = append(tt.descs, desc)
+	for i := range lst {
+		tt.inputs = append(tt.inputs, txnInput{
+			path: lst[i].Path,
+			etag: lst[i].ETag,
+			desc: n,
+		})
+	}
+	return nil
+}
+
+// dataKey returns the wrapped data key that
+// is used (or should be used) to encrypt the
+// packfiles belonging to the table
+func (tt *txnTable) dataKey() ([]byte, error) {
+	idx, err := tt.st.index(context.Background())
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+	// Commit re-loads the index
+	tt.st.invalidate()
+	_, wrapped, err := tt.st.dataKey(idx)
+	return wrapped, err
+}
+
+// Commit appends all of the staged packfiles
+// to their respective indexes. If any of the
+// indexes cannot be updated (for example, because
+// it was modified concurrently by another process,
+// in which case the error satisfies errors.Is(err, ErrConflict)),
+// then Commit restores the indexes that it has already
+// updated, removes the staged packfiles, and returns
+// the error. Either way, the transaction cannot be
+// used after Commit returns.
+//
+// Commit does not update multiple tables atomically.
+// There is no commit marker: the indexes are written
+// one table at a time (in order of table name), so a
+// concurrent reader may see the new contents of one
+// table and the old contents of another, and the
+// rollback of a failed Commit is best-effort. If an
+// index cannot be restored, or if the process exits
+// before Commit returns, the tables that were already
+// written keep the staged packfiles. Callers that need
+// all-or-nothing visibility across tables must detect
+// partially-committed transactions themselves, e.g. by
+// checking whether the index of each table already
+// records the ingested objects (see Index.Inputs).
+func (t *Txn) Commit() error {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if t.done {
+		return ErrTxnDone
+	}
+	t.done = true
+	ctx := context.Background()
+	names := make([]string, 0, len(t.tables))
+	for name := range t.tables {
+		names = append(names, name)
+	}
+	// process tables in a consistent order
+	// so that concurrent transactions touching
+	// the same tables are more likely to fail fast
+	slices.Sort(names)
+	for _, name := range names {
+		if err := t.tables[name].prepare(ctx); err != nil {
+			t.abort()
+			return fmt.Errorf("committing %s: %w", name, err)
+		}
+	}
+	for _, name := range names {
+		tt := t.tables[name]
+		if len(tt.descs) == 0 {
+			continue
+		}
+		if err := tt.st.flush(ctx, tt.idx); err != nil {
+			t.abort()
+			return fmt.Errorf("committing %s: %w", name, err)
+		}
+		tt.written = true
+	}
+	return nil
+}
+
+// prepare loads the current index and
+// adds the staged descriptors and inputs to it
+func (tt *txnTable) prepare(ctx context.Context) error {
+	if len(tt.descs) == 0 {
+		return nil
+	}
+	st := tt.st
+	st.invalidate()
+	idx, err := st.index(ctx)
+	if errors.Is(err, fs.ErrNotExist) {
+		idx = &blockfmt.Index{
+			Name: st.table,
+			Algo: "zstd",
+		}
+		st.overwrite(idx, "")
+	} else if err != nil {
+		return err
+	} else {
+		tt.prev, err = blockfmt.Sign(st.owner.Key(), idx)
+		if err != nil {
+			return err
+		}
+	}
+	if tt.wrapped != nil {
+		if len(idx.DataKey) == 0 {
+			idx.DataKey = tt.wrapped
+		} else if !bytes.Equal(idx.DataKey, tt.wrapped) {
+			return fmt.Errorf("%w: data key changed", ErrConflict)
+		}
+	}
+	idx.Inputs.Backing = st.ofs
+	base := idx.Objects()
+	for i := range tt.inputs {
+		in := &tt.inputs[i]
+		ok, err := idx.Inputs.Append(in.path, in.etag, base+in.desc)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return fmt.Errorf("%w: %s was already ingested", ErrConflict, in.path)
+		}
+	}
+	idx.Inline = append(idx.Inline, tt.descs...)
+	idx.Created = date.Now().Truncate(time.Microsecond)
+	tt.idx = idx
+	return nil
+}
+
+// Rollback discards the transaction
+// and removes the staged packfiles.
+func (t *Txn) Rollback() error {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if t.done {
+		return ErrTxnDone
+	}
+	t.done = true
+	t.abort()
+	return nil
+}
+
+// abort restores every index that was already
+// written and removes the staged packfiles;
+// the caller must hold t.lock
+func (t *Txn) abort() {
+	var paths []string
+	for _, tt := range t.tables {
+		if tt.written {
+			if err := tt.restore(); err != nil {
+				tt.st.logf("rolling back index: %s", err)
+				// the staged packfiles are still
+				// referenced, so don't remove them
+				continue
+			}
+		}
+		for i := range tt.descs {
+			paths = append(paths, tt.descs[i].Path)
+		}
+	}
+	t.remove(paths)
+}
+
+// restore writes back the index that
+// was present before the transaction
+func (tt *txnTable) restore() error {
+	st := tt.st
+	if err := st.checkIndex(); err != nil {
+		return err
+	}
+	p := IndexPath(st.db, st.table)
+	defer st.invalidate()
+	if tt.prev == nil {
+		rmfs, ok := st.ofs.(RemoveFS)
+		if !ok {
+			return fmt.Errorf("cannot remove %s from %T", p, st.ofs)
+		}
+		return rmfs.Remove(p)
+	}
+	_, err := st.ofs.WriteFile(p, tt.prev)
+	return err
+}
+
+// remove makes a best-effort attempt to
+// delete the staged packfiles in lst
+func (t *Txn) remove(lst []string) {
+	root, err := t.owner.Root()
+	if err != nil {
+		return
+	}
+	rmfs, ok := root.(RemoveFS)
+	if !ok {
+		return
+	}
+	for i := range lst {
+		err := rmfs.Remove(lst[i])
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			t.conf.logf("txn: removing %s: %s", lst[i], err)
+		}
+	}
+}
@@ -0,0 +1,164 @@
+// Copyright (C) 2022 Sneller, Inc.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package db
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"strings"
+	"testing"
+
+	"github.com/SnellerInc/sneller/ion/blockfmt"
+)
+
+// failWriteFS fails writes to a single path
+type failWriteFS struct {
+	*DirFS
+	fail string
+}
+
+func (f *failWriteFS) WriteFile(p string, buf []byte) (string, error) {
+	if p == f.fail {
+		return "", fmt.Errorf("cannot write %s", p)
+	}
+	return f.DirFS.WriteFile(p, buf)
+}
+
+func txnInputs(prefix string, n int) []blockfmt.Input {
+	var out []blockfmt.Input
+	for i := 0; i < n; i++ {
+		text := fmt.Sprintf("{\"x\": %d}", i)
+		out = append(out, blockfmt.Input{
+			Path: fmt.Sprintf("push://%s/%d", prefix, i),
+			ETag: fmt.Sprintf("etag-%d", i),
+			Size: int64(len(text)),
+			R:    io.NopCloser(strings.NewReader(text)),
+			F:    blockfmt.MustSuffixToFormat(".json"),
+		})
+	}
+	return out
+}
+
+func TestTxn(t *testing.T) {
+	checkFiles(t)
+	dfs := &failWriteFS{DirFS: newDirFS(t, t.TempDir())}
+	owner := newTenant(dfs)
+	c := Config{Align: 1024, Logf: t.Logf}
+
+	inline := func(table string) int {
+		t.Helper()
+		idx, err := OpenIndex(dfs, "default", table, owner.Key())
+		if errors.Is(err, fs.ErrNotExist) {
+			return -1
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		return len(idx.Inline)
+	}
+	exists := func(p string) bool {
+		_, err := fs.Stat(dfs, p)
+		return err == nil
+	}
+
+	// staged data is not visible until Commit
+	txn := c.Begin(owner)
+	if err := txn.Append("default", "a", txnInputs("a", 2)); err != nil {
+		t.Fatal(err)
+	}
+	if err := txn.Append("default", "b", txnInputs("b", 1)); err != nil {
+		t.Fatal(err)
+	}
+	if inline("a") != -1 || inline("b") != -1 {
+		t.Fatal("staged data visible before Commit")
+	}
+	if err := txn.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	if inline("a") != 1 || inline("b") != 1 {
+		t.Fatalf("got %d and %d descriptors after Commit", inline("a"), inline("b"))
+	}
+	if err := txn.Commit(); !errors.Is(err, ErrTxnDone) {
+		t.Fatalf("second Commit returned %v", err)
+	}
+
+	// Rollback removes the staged packfiles
+	txn = c.Begin(owner)
+	if err := txn.Append("default", "a", txnInputs("a2", 1)); err != nil {
+		t.Fatal(err)
+	}
+	staged := txn.tables["default/a"].descs[0].Path
+	if !exists(staged) {
+		t.Fatalf("staged packfile %s not written", staged)
+	}
+	if err := txn.Rollback(); err != nil {
+		t.Fatal(err)
+	}
+	if exists(staged) {
+		t.Fatalf("staged packfile %s not removed", staged)
+	}
+	if inline("a") != 1 {
+		t.Fatal("Rollback modified the index")
+	}
+
+	// re-ingesting an input is a conflict
+	txn = c.Begin(owner)
+	if err := txn.Append("default", "a", txnInputs("a3", 1)); err != nil {
+		t.Fatal(err)
+	}
+	if err := txn.Append("default", "b", txnInputs("b", 1)); err != nil {
+		t.Fatal(err)
+	}
+	if err := txn.Commit(); !errors.Is(err, ErrConflict) {
+		t.Fatalf("expected ErrConflict; got %v", err)
+	}
+	if inline("a") != 1 || inline("b") != 1 {
+		t.Fatal("failed Commit modified an index")
+	}
+
+	// a failure writing the second index
+	// rolls back the first index
+	txn = c.Begin(owner)
+	if err := txn.Append("default", "a", txnInputs("a4", 1)); err != nil {
+		t.Fatal(err)
+	}
+	if err := txn.Append("default", "c", txnInputs("c", 1)); err != nil {
+		t.Fatal(err)
+	}
+	dfs.fail = IndexPath("default", "c")
+	if err := txn.Commit(); err == nil {
+		t.Fatal("expected Commit to fail")
+	}
+	dfs.fail = ""
+	if inline("a") != 1 {
+		t.Fatalf("index for a not restored; got %d descriptors", inline("a"))
+	}
+	if inline("c") != -1 {
+		t.Fatal("index for c was written")
+	}
+	// ... and the inputs can be ingested again
+	txn = c.Begin(owner)
+	if err := txn.Append("default", "a", txnInputs("a4", 1)); err != nil {
+		t.Fatal(err)
+	}
+	if err := txn.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	if inline("a") != 2 {
+		t.Fatalf("got %d descriptors after re-ingesting", inline("a"))
+	}
+}
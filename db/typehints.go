@@ -0,0 +1,197 @@
+// Copyright (C) 2022 Sneller, Inc.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package db
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"strings"
+
+	"github.com/SnellerInc/sneller/expr"
+)
+
+// TypeHintsPath returns the path
+// at which the type hints file for the given
+// db and table would live relative
+// to the root of the FS.
+func TypeHintsPath(db, table string) string {
+	return path.Join("db", db, table, "hints.json")
+}
+
+// TypeHints is a user-provided description of
+// the types of the values in a table.
+//
+// The query planner treats the declared types
+// as authoritative: a query that references
+// a path with a value that does not match the
+// declared types may produce incorrect results.
+type TypeHints struct {
+	// Fields maps path expressions
+	// (like "a" or "a.b") to the list of types
+	// of the values associated with the path.
+	// The valid types are "null", "bool", "int",
+	// "float", "decimal", "timestamp", "symbol",
+	// "string", "list", "struct" and "missing";
+	// a path that may be absent from some rows
+	// must include "missing" in its list of types.
+	Fields map[string][]string `json:"fields"`
+}
+
+func hintType(name string) (expr.TypeSet, bool) {
+	switch name {
+	case "null":
+		return expr.NullType, true
+	case "bool":
+		return expr.BoolType, true
+	case "int":
+		return expr.IntegerType, true
+	case "float":
+		return expr.FloatType, true
+	case "decimal":
+		return expr.DecimalType, true
+	case "timestamp":
+		return expr.TimeType, true
+	case "symbol":
+		// symbols are evaluated as strings
+		return expr.SymbolType | expr.StringType, true
+	case "string":
+		return expr.StringType, true
+	case "list":
+		return expr.ListType, true
+	case "struct":
+		return expr.StructType, true
+	case "missing":
+		return expr.MissingType, true
+	default:
+		return 0, false
+	}
+}
+
+// typeHints implements expr.Hint
+// for a set of TypeHints
+type typeHints struct {
+	// types is keyed on the canonical
+	// text of each path expression
+	types map[string]expr.TypeSet
+	next  expr.Hint
+}
+
+func (t *typeHints) TypeOf(e expr.Node) expr.TypeSet {
+	if _, ok := expr.FlatPath(e); ok {
+		if ts, ok := t.types[expr.ToString(e)]; ok {
+			return ts
+		}
+	}
+	return t.next.TypeOf(e)
+}
+
+// Hint returns an expr.Hint that yields the
+// declared types of the paths in h.Fields
+// and defers to next for any other expression.
+// If next is nil, the types of the other expressions
+// are determined without any additional hints.
+func (h *TypeHints) Hint(next expr.Hint) (expr.Hint, error) {
+	if next == nil {
+		next = expr.NoHint
+	}
+	t := &typeHints{
+		types: make(map[string]expr.TypeSet, len(h.Fields)),
+		next:  next,
+	}
+	for p, lst := range h.Fields {
+		e, err := expr.ParsePath(p)
+		if err != nil {
+			return nil, fmt.Errorf("type hints: invalid path %q: %w", p, err)
+		}
+		if _, ok := expr.FlatPath(e); !ok {
+			return nil, fmt.Errorf("type hints: path %q is not a sequence of field names", p)
+		}
+		if len(lst) == 0 {
+			return nil, fmt.Errorf("type hints: no types for path %q", p)
+		}
+		var ts expr.TypeSet
+		for _, name := range lst {
+			typ, ok := hintType(strings.ToLower(name))
+			if !ok {
+				return nil, fmt.Errorf("type hints: path %q: unknown type %q", p, name)
+			}
+			ts |= typ
+		}
+		t.types[expr.ToString(e)] = ts
+	}
+	return t, nil
+}
+
+// Validate checks that h is well-formed.
+func (h *TypeHints) Validate() error {
+	_, err := h.Hint(nil)
+	return err
+}
+
+// OpenTypeHints opens the type hints
+// for the given database and table.
+// If the table has no type hints, the
+// returned error satisfies errors.Is(err, fs.ErrNotExist).
+func OpenTypeHints(s fs.FS, db, table string) (*TypeHints, error) {
+	f, err := s.Open(TypeHintsPath(db, table))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	if err := checkDef(f); err != nil {
+		return nil, err
+	}
+	h := new(TypeHints)
+	err = json.NewDecoder(f).Decode(h)
+	if err != nil {
+		return nil, fmt.Errorf("decoding type hints: %w", err)
+	}
+	return h, nil
+}
+
+// WriteTypeHints validates h and writes
+// it as the type hints of an existing table.
+func WriteTypeHints(dst OutputFS, db, table string, h *TypeHints) error {
+	if err := h.Validate(); err != nil {
+		return err
+	}
+	_, err := fs.Stat(dst, DefinitionPath(db, table))
+	if err != nil {
+		return err
+	}
+	buf, err := json.MarshalIndent(h, "", "\t")
+	if err != nil {
+		return err
+	}
+	_, err = dst.WriteFile(TypeHintsPath(db, table), buf)
+	return err
+}
+
+// RemoveTypeHints removes the type hints
+// of a table, if it has any.
+func RemoveTypeHints(dst OutputFS, db, table string) error {
+	rfs, ok := dst.(RemoveFS)
+	if !ok {
+		return fmt.Errorf("RemoveTypeHints: %T does not support Remove", dst)
+	}
+	err := rfs.Remove(TypeHintsPath(db, table))
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+// Copyright (C) 2022 Sneller, Inc.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package db
+
+import (
+	"errors"
+	"io/fs"
+	"reflect"
+	"testing"
+
+	"github.com/SnellerInc/sneller/expr"
+)
+
+type constHint expr.TypeSet
+
+func (c constHint) TypeOf(expr.Node) expr.TypeSet { return expr.TypeSet(c) }
+
+func TestTypeHints(t *testing.T) {
+	th := &TypeHints{
+		Fields: map[string][]string{
+			"x":          {"int"},
+			"y.z":        {"string", "missing"},
+			"ts":         {"TIMESTAMP", "null"},
+			"sym":        {"symbol"},
+			`"odd-name"`: {"float"},
+		},
+	}
+	h, err := th.Hint(constHint(expr.BoolType))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tcs := []struct {
+		path string
+		want expr.TypeSet
+	}{
+		{"x", expr.IntegerType},
+		{"y.z", expr.StringType | expr.MissingType},
+		{"ts", expr.TimeType | expr.NullType},
+		{"sym", expr.SymbolType | expr.StringType},
+		{`"odd-name"`, expr.FloatType},
+		// not declared; use the fallback
+		{"y", expr.BoolType},
+		{"x.y", expr.BoolType},
+	}
+	for i := range tcs {
+		p, err := expr.ParsePath(tcs[i].path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := h.TypeOf(p); got != tcs[i].want {
+			t.Errorf("TypeOf(%s) = %s, want %s", tcs[i].path, got, tcs[i].want)
+		}
+	}
+
+	invalid := []*TypeHints{
+		{Fields: map[string][]string{"x": {"integer"}}},
+		{Fields: map[string][]string{"x": {}}},
+		{Fields: map[string][]string{"x[0]": {"int"}}},
+	}
+	for i := range invalid {
+		if err := invalid[i].Validate(); err == nil {
+			t.Errorf("case %d: expected an error", i)
+		}
+	}
+}
+
+func TestWriteTypeHints(t *testing.T) {
+	checkFiles(t)
+	dfs := newDirFS(t, t.TempDir())
+	th := &TypeHints{
+		Fields: map[string][]string{
+			"x": {"int", "missing"},
+		},
+	}
+	err := WriteTypeHints(dfs, "foo", "bar", th)
+	if !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("writing hints without a table: got %v", err)
+	}
+	err = WriteDefinition(dfs, "foo", &Definition{Name: "bar"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = WriteTypeHints(dfs, "foo", "bar", th)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := OpenTypeHints(dfs, "foo", "bar")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, th) {
+		t.Errorf("got %#v, want %#v", got, th)
+	}
+	err = RemoveTypeHints(dfs, "foo", "bar")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = OpenTypeHints(dfs, "foo", "bar")
+	if !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("opening removed hints: got %v", err)
+	}
+}
@@ -0,0 +1,232 @@
+// Copyright (C) 2022 Sneller, Inc.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// Package engine is the stable entry point for
+// embedding the Sneller query engine in another
+// Go program.
+//
+// A query is run in three steps:
+//
+//	q, err := engine.Parse("SELECT COUNT(*) FROM 'rows.json'")
+//	p, err := engine.Plan(q, env)
+//	err = engine.Execute(ctx, p, os.Stdout, &engine.Options{Format: engine.JSON})
+//
+// The Env passed to Plan maps the tables named in the
+// FROM clause of a query to streams of ion or JSON data.
+//
+// The exported API of this package follows semantic
+// versioning: identifiers are only ever added to it.
+// The packages it is built on (expr, plan, vm, and so forth)
+// carry no such guarantee and change frequently, so
+// programs embedding the engine should depend only on this package.
+package engine
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/SnellerInc/sneller/expr"
+	"github.com/SnellerInc/sneller/expr/partiql"
+	"github.com/SnellerInc/sneller/ion"
+	"github.com/SnellerInc/sneller/jsonrl"
+	"github.com/SnellerInc/sneller/plan"
+	"github.com/SnellerInc/sneller/vm"
+)
+
+// Format is the encoding of a stream of rows.
+type Format int
+
+const (
+	// Ion is a stream of binary ion structures.
+	Ion Format = iota
+	// JSON is a stream of JSON objects, optionally
+	// separated by newlines (i.e. NDJSON) or wrapped
+	// in a top-level array.
+	JSON
+)
+
+func (f Format) String() string {
+	switch f {
+	case Ion:
+		return "ion"
+	case JSON:
+		return "json"
+	default:
+		return fmt.Sprintf("Format(%d)", int(f))
+	}
+}
+
+// Query is a parsed query.
+type Query struct {
+	q *expr.Query
+}
+
+// Parse parses and type-checks the query text.
+func Parse(text string) (*Query, error) {
+	q, err := partiql.Parse([]byte(text))
+	if err != nil {
+		return nil, err
+	}
+	if err := q.Check(); err != nil {
+		return nil, err
+	}
+	return &Query{q: q}, nil
+}
+
+// Text returns the normalized text of the query.
+func (q *Query) Text() string { return q.q.Text() }
+
+// Env resolves the tables referenced by a query.
+type Env interface {
+	// Open returns the contents of the table called
+	// name and the format in which they are encoded.
+	// The name is the string or identifier that
+	// appears in the FROM clause of the query.
+	//
+	// Open is called once each time a query
+	// referencing the table is executed, and
+	// the engine closes the returned stream
+	// once it has been read.
+	Open(ctx context.Context, name string) (io.ReadCloser, Format, error)
+}
+
+// Prepared is a planned query that is
+// ready to be executed.
+type Prepared struct {
+	tree *plan.Tree
+}
+
+// Plan produces an execution plan for q, using env to
+// resolve the tables that q references.
+//
+// A Prepared query may be executed more than once;
+// env is consulted again on each execution.
+func Plan(q *Query, env Env) (*Prepared, error) {
+	tree, err := plan.New(q.q, &planenv{env: env})
+	if err != nil {
+		return nil, err
+	}
+	return &Prepared{tree: tree}, nil
+}
+
+// Options are options for Execute.
+// The zero value of Options is valid.
+type Options struct {
+	// Format is the encoding of the rows
+	// written to the output. The JSON output
+	// is NDJSON.
+	Format Format
+	// Parallel is the maximum number of
+	// threads used to execute the query.
+	// If Parallel is zero, runtime.GOMAXPROCS(0)
+	// is used instead.
+	Parallel int
+}
+
+// Execute runs p and writes the result rows to dst.
+// If opts is nil, the default Options are used.
+//
+// Execution stops early if ctx is canceled.
+func Execute(ctx context.Context, p *Prepared, dst io.Writer, opts *Options) error {
+	if opts == nil {
+		opts = &Options{}
+	}
+	var jw *ion.JSONWriter
+	switch opts.Format {
+	case Ion:
+	case JSON:
+		jw = ion.NewJSONWriter(dst, '\n')
+		dst = jw
+	default:
+		return fmt.Errorf("engine: unsupported output format %s", opts.Format)
+	}
+	ep := &plan.ExecParams{
+		Output:   dst,
+		Parallel: opts.Parallel,
+		Context:  ctx,
+	}
+	err := (&plan.LocalTransport{}).Exec(p.tree, ep)
+	if jw != nil {
+		err2 := jw.Close()
+		if err == nil {
+			err = err2
+		}
+	}
+	return err
+}
+
+// align is the chunk alignment of table data;
+// every row of a table must fit in one chunk
+const align = vm.PageSize
+
+type planenv struct {
+	env Env
+}
+
+func (p *planenv) Stat(tbl expr.Node, h *plan.Hints) (plan.TableHandle, error) {
+	var name string
+	switch t := tbl.(type) {
+	case expr.String:
+		name = string(t)
+	case expr.Ident:
+		name = string(t)
+	default:
+		return nil, fmt.Errorf("engine: unexpected table expression %s", expr.ToString(tbl))
+	}
+	return &handle{env: p.env, name: name}, nil
+}
+
+// handle is a plan.TableHandle that converts
+// the contents of a table into ion chunks
+// when the query is executed
+type handle struct {
+	env  Env
+	name string
+}
+
+func (h *handle) Open(ctx context.Context) (vm.Table, error) {
+	rc, format, err := h.env.Open(ctx, h.name)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	var buf bytes.Buffer
+	cn := ion.Chunker{W: &buf, Align: align}
+	switch format {
+	case Ion:
+		_, err = cn.ReadFrom(rc, nil)
+	case JSON:
+		err = jsonrl.Convert(rc, &cn, nil, nil)
+		if err == nil {
+			err = cn.Flush()
+		}
+	default:
+		err = fmt.Errorf("unsupported format %s", format)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("engine: reading table %q: %w", h.name, err)
+	}
+	return vm.BufferTable(buf.Bytes(), align), nil
+}
+
+// Size returns zero, since the size of
+// the table isn't known until it is opened
+func (h *handle) Size() int64 { return 0 }
+
+func (h *handle) Encode(dst *ion.Buffer, st *ion.Symtab) error {
+	return errors.New("engine: table handles cannot be serialized")
+}
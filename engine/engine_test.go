@@ -0,0 +1,118 @@
+// Copyright (C) 2022 Sneller, Inc.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package engine
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+)
+
+type table struct {
+	data   []byte
+	format Format
+}
+
+type mapenv map[string]table
+
+func (m mapenv) Open(ctx context.Context, name string) (io.ReadCloser, Format, error) {
+	t, ok := m[name]
+	if !ok {
+		return nil, 0, fmt.Errorf("no table %q", name)
+	}
+	return io.NopCloser(bytes.NewReader(t.data)), t.format, nil
+}
+
+func run(t *testing.T, env Env, text string, format Format) []byte {
+	t.Helper()
+	q, err := Parse(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := Plan(q, env)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	err = Execute(context.Background(), p, &out, &Options{Format: format})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return out.Bytes()
+}
+
+const rows = `{"name": "a", "n": 1}
+{"name": "b", "n": 2}
+{"name": "c", "n": 3}
+`
+
+func TestExecuteJSON(t *testing.T) {
+	env := mapenv{"rows.json": {data: []byte(rows), format: JSON}}
+	got := run(t, env, "SELECT name FROM 'rows.json' WHERE n >= 2 ORDER BY name LIMIT 10", JSON)
+	want := "{\"name\": \"b\"}\n{\"name\": \"c\"}\n"
+	if string(got) != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+}
+
+func TestExecuteIon(t *testing.T) {
+	env := mapenv{"rows": {data: []byte(rows), format: JSON}}
+	// produce ion output and then query it
+	// again as an ion-formatted table
+	ion := run(t, env, "SELECT n * 2 AS m FROM rows", Ion)
+	env["doubled"] = table{data: ion, format: Ion}
+
+	// a prepared query can be executed repeatedly
+	q, err := Parse("SELECT SUM(m) AS total FROM doubled")
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := Plan(q, env)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		var out bytes.Buffer
+		err = Execute(context.Background(), p, &out, &Options{Format: JSON})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := out.String(), "{\"total\": 12}\n"; got != want {
+			t.Fatalf("execution %d: got %q, want %q", i, got, want)
+		}
+	}
+}
+
+func TestErrors(t *testing.T) {
+	_, err := Parse("SELECT FROM WHERE")
+	if err == nil {
+		t.Fatal("expected a parse error")
+	}
+	q, err := Parse("SELECT * FROM nosuchtable")
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := Plan(q, mapenv{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = Execute(context.Background(), p, io.Discard, nil)
+	if err == nil || !strings.Contains(err.Error(), "no table") {
+		t.Fatalf("unexpected error %v", err)
+	}
+}
@@ -164,6 +164,61 @@ func blobsSize(lst []blob.Interface) int64 {
 	return n
 }
 
+// Stats implements plan.StatsHandle.Stats
+// using the number of rows recorded in the
+// trailers of the blobs. The estimate does
+// not account for f.Expr, so it is an upper
+// bound on the number of rows scanned.
+func (f *FilterHandle) Stats() (plan.TableStats, bool) {
+	var st plan.TableStats
+	if f.Blobs == nil {
+		return st, true
+	}
+	for _, b := range f.Blobs.Contents {
+		var rows int64
+		var ok bool
+		switch c := b.(type) {
+		case *blob.Compressed:
+			rows, ok = c.Trailer.Rows()
+			st.Bytes += c.Trailer.Decompressed()
+		case *blob.CompressedPart:
+			rows, ok = c.Rows()
+			st.Bytes += c.Decompressed()
+		}
+		if !ok {
+			return plan.TableStats{}, false
+		}
+		st.Rows += rows
+	}
+	return st, true
+}
+
+// Count implements plan.CountHandle.Count
+// using the row counts and sparse indexes
+// recorded in the trailers of the blobs.
+// (See blockfmt.Trailer.CountMatching.)
+func (f *FilterHandle) Count(e expr.Node) (int64, bool) {
+	if f.Blobs == nil {
+		return 0, true
+	}
+	n := int64(0)
+	for _, b := range f.Blobs.Contents {
+		var rows int64
+		var ok bool
+		switch c := b.(type) {
+		case *blob.Compressed:
+			rows, ok = c.Trailer.CountMatching(e, 0, len(c.Trailer.Blocks))
+		case *blob.CompressedPart:
+			rows, ok = c.Parent.Trailer.CountMatching(e, c.StartBlock, c.EndBlock)
+		}
+		if !ok {
+			return 0, false
+		}
+		n += rows
+	}
+	return n, true
+}
+
 func (f *FilterHandle) Size() int64 {
 	if f.Blobs == nil {
 		return 0
@@ -0,0 +1,82 @@
+// Copyright (C) 2022 Sneller, Inc.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package sneller
+
+import (
+	"testing"
+
+	"github.com/SnellerInc/sneller/expr/blob"
+	"github.com/SnellerInc/sneller/ion/blockfmt"
+	"github.com/SnellerInc/sneller/plan"
+)
+
+func TestFilterHandleStats(t *testing.T) {
+	c := &blob.Compressed{
+		Trailer: blockfmt.Trailer{
+			BlockShift: 10,
+			Blocks:     []blockfmt.Blockdesc{{Chunks: 1}, {Chunks: 3}},
+			Schema:     &blockfmt.Schema{Rows: 40},
+		},
+	}
+	fh := &FilterHandle{
+		Blobs: &blob.List{Contents: []blob.Interface{
+			c,
+			&blob.CompressedPart{Parent: c, StartBlock: 1, EndBlock: 2},
+		}},
+	}
+	st, ok := plan.Stats(fh)
+	if !ok {
+		t.Fatal("expected stats")
+	}
+	want := plan.TableStats{Rows: 40 + 30, Bytes: 4096 + 3072}
+	if st != want {
+		t.Errorf("got %+v, want %+v", st, want)
+	}
+
+	// the number of rows is unknown
+	// for objects without a schema
+	fh.Blobs.Contents = append(fh.Blobs.Contents, &blob.Compressed{})
+	if _, ok := plan.Stats(fh); ok {
+		t.Error("unexpected stats")
+	}
+}
+
+func TestFilterHandleCount(t *testing.T) {
+	c := &blob.Compressed{
+		Trailer: blockfmt.Trailer{
+			BlockShift: 10,
+			Blocks:     []blockfmt.Blockdesc{{Chunks: 1, Rows: 10}, {Chunks: 3, Rows: 30}},
+			RowCounts:  true,
+		},
+	}
+	fh := &FilterHandle{
+		Blobs: &blob.List{Contents: []blob.Interface{
+			c,
+			&blob.CompressedPart{Parent: c, StartBlock: 1, EndBlock: 2},
+		}},
+	}
+	var ch plan.CountHandle = fh
+	n, ok := ch.Count(nil)
+	if !ok || n != 40+30 {
+		t.Errorf("got %d, %v", n, ok)
+	}
+
+	// the number of rows is unknown
+	// for objects without row counts
+	fh.Blobs.Contents = append(fh.Blobs.Contents, &blob.Compressed{})
+	if _, ok := ch.Count(nil); ok {
+		t.Error("unexpected count")
+	}
+}
@@ -0,0 +1,282 @@
+// Copyright (C) 2022 Sneller, Inc.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package expr
+
+import (
+	"github.com/SnellerInc/sneller/ion"
+)
+
+// In ANSI SQL, a comparison with NULL is neither
+// TRUE nor FALSE but UNKNOWN, and NOT UNKNOWN
+// is UNKNOWN as well, so
+//
+//	WHERE NOT (x = 1)
+//
+// does not match rows where x is NULL.
+// Our native semantics yield FALSE for x = 1
+// when x is NULL, so the same query matches those rows.
+//
+// The ANSI mode rewrites every logical expression e
+// into a pair of expressions that are TRUE exactly
+// when e is (respectively) TRUE or FALSE under ANSI
+// semantics; e is UNKNOWN when neither of them is TRUE.
+
+// isLogical returns whether e is
+// an expression that yields a boolean
+// (or UNKNOWN) under ANSI semantics
+func isLogical(e Node) bool {
+	switch e := e.(type) {
+	case *Comparison, *Logical, *Not, *Member, *StringMatch:
+		return true
+	case *IsKey:
+		return e.Key != IsMissing && e.Key != IsNotMissing
+	}
+	return false
+}
+
+// isNullConst returns whether e is
+// the NULL or MISSING constant
+func isNullConst(e Node) bool {
+	switch e.(type) {
+	case Null, Missing:
+		return true
+	}
+	return false
+}
+
+// notNull returns an expression that is
+// TRUE when e is neither NULL nor MISSING,
+// or nil if e is a constant that is never NULL
+func notNull(e Node) Node {
+	if IsConstant(e) && !isNullConst(e) {
+		return nil
+	}
+	return Is(e, IsNotNull)
+}
+
+// guard returns (e AND the NOT NULL
+// condition for each of args)
+func guard(e Node, args ...Node) Node {
+	for i := range args {
+		if isNullConst(args[i]) {
+			return Bool(false)
+		}
+		if nn := notNull(args[i]); nn != nil {
+			e = And(e, nn)
+		}
+	}
+	return e
+}
+
+func bagHasNull(b *ion.Bag) bool {
+	null := false
+	b.Each(func(d ion.Datum) bool {
+		null = d.IsNull()
+		return !null
+	})
+	return null
+}
+
+// ansiTrue returns an expression that
+// is TRUE when e is TRUE under ANSI semantics
+// and FALSE otherwise
+func ansiTrue(e Node) Node {
+	t, _ := ansiTruth(e)
+	return t
+}
+
+// ansiTruth returns a pair of expressions that are
+// TRUE when e is TRUE and FALSE, respectively,
+// under ANSI semantics, and FALSE otherwise
+func ansiTruth(e Node) (Node, Node) {
+	switch n := e.(type) {
+	case *Comparison:
+		t := Compare(n.Op, n.Left, n.Right)
+		f := Compare(n.Op.invert(), n.Left, n.Right)
+		return guard(t, n.Left, n.Right), guard(f, n.Left, n.Right)
+	case *Not:
+		t, f := ansiTruth(n.Expr)
+		return f, t
+	case *Logical:
+		lt, lf := ansiTruth(n.Left)
+		rt, rf := ansiTruth(n.Right)
+		switch n.Op {
+		case OpAnd:
+			return And(lt, rt), Or(lf, rf)
+		case OpOr:
+			return Or(lt, rt), And(lf, rf)
+		case OpXor:
+			return Or(And(lt, rf), And(lf, rt)), Or(And(lt, rt), And(lf, rf))
+		case OpXnor:
+			return Or(And(lt, rt), And(lf, rf)), Or(And(lt, rf), And(lf, rt))
+		}
+	case *IsKey:
+		switch n.Key {
+		case IsNull:
+			return Or(Is(n.Expr, IsNull), Is(n.Expr, IsMissing)), Is(n.Expr, IsNotNull)
+		case IsNotNull:
+			return Is(n.Expr, IsNotNull), Or(Is(n.Expr, IsNull), Is(n.Expr, IsMissing))
+		case IsTrue, IsNotFalse, IsFalse, IsNotTrue:
+			if !isLogical(n.Expr) {
+				break
+			}
+			// IS [NOT] TRUE and IS [NOT] FALSE
+			// are never UNKNOWN
+			t, f := ansiTruth(n.Expr)
+			var out Node
+			switch n.Key {
+			case IsTrue:
+				out = t
+			case IsFalse:
+				out = f
+			case IsNotTrue:
+				out = Is(t, IsNotTrue)
+			case IsNotFalse:
+				out = Is(f, IsNotTrue)
+			}
+			return out, Is(out, IsNotTrue)
+		}
+	case *Member:
+		// x IN (..., NULL) is never FALSE
+		t := guard(Is(n, IsTrue), n.Arg)
+		if bagHasNull(&n.Set) {
+			return t, Bool(false)
+		}
+		return t, guard(Is(n, IsFalse), n.Arg)
+	case *StringMatch:
+		return guard(Is(n, IsTrue), n.Expr), guard(Is(n, IsFalse), n.Expr)
+	}
+	return Is(e, IsTrue), Is(e, IsFalse)
+}
+
+// ansiValue returns a logical expression e
+// as a value that is TRUE, FALSE, or NULL
+// (when e is UNKNOWN)
+func ansiValue(e Node) Node {
+	t, f := ansiTruth(e)
+	return &Case{
+		Limbs: []CaseLimb{
+			{When: t, Then: Bool(true)},
+			{When: f, Then: Bool(false)},
+		},
+		Else: Null{},
+	}
+}
+
+// missingToNull returns e with MISSING
+// values replaced with NULL
+func missingToNull(e Node) Node {
+	if isLogical(e) {
+		return ansiValue(e)
+	}
+	switch e.(type) {
+	case Missing:
+		return Null{}
+	case Star, *Aggregate:
+		// SELECT * can't be rewritten,
+		// and aggregates are never MISSING
+		return e
+	}
+	if TypeOf(e, NoHint)&MissingType == 0 {
+		return e
+	}
+	return &Case{
+		Limbs: []CaseLimb{{When: Is(e, IsMissing), Then: Null{}}},
+		Else:  Copy(e),
+	}
+}
+
+type ansiRewriter struct{}
+
+func (a *ansiRewriter) Walk(Node) Rewriter { return a }
+
+func (a *ansiRewriter) Rewrite(n Node) Node {
+	switch n := n.(type) {
+	case *Select:
+		if n.Where != nil {
+			n.Where = ansiTrue(n.Where)
+		}
+		if n.Having != nil {
+			n.Having = ansiTrue(n.Having)
+		}
+		if n.Qualify != nil {
+			n.Qualify = ansiTrue(n.Qualify)
+		}
+		for i := range n.Columns {
+			if isLogical(n.Columns[i].Expr) {
+				n.Columns[i].Expr = ansiValue(n.Columns[i].Expr)
+			}
+		}
+		// rows where a grouping column is
+		// MISSING belong to the NULL group
+		nullBindings(n.GroupBy)
+	case *Join:
+		if n.On != nil {
+			n.On = ansiTrue(n.On)
+		}
+	case *Case:
+		for i := range n.Limbs {
+			n.Limbs[i].When = ansiTrue(n.Limbs[i].When)
+		}
+	case *Aggregate:
+		if n.Filter != nil {
+			n.Filter = ansiTrue(n.Filter)
+		}
+	}
+	return n
+}
+
+// nullBindings replaces MISSING
+// with NULL in each of lst
+func nullBindings(lst []Binding) {
+	for i := range lst {
+		// preserve the implicit result name
+		// of a binding like 'x' or 'a.b'
+		if name := lst[i].Result(); name != "" {
+			lst[i].As(name)
+		}
+		lst[i].Expr = missingToNull(lst[i].Expr)
+	}
+}
+
+// outputNulls replaces MISSING with NULL
+// in the columns produced by body
+func outputNulls(body Node) {
+	switch b := body.(type) {
+	case *Select:
+		nullBindings(b.Columns)
+	case *Union:
+		outputNulls(b.Left)
+		outputNulls(b.Right)
+	}
+}
+
+// SetANSINulls rewrites q so that it follows
+// the ANSI SQL semantics for NULL values:
+// MISSING values are treated as NULL, comparisons
+// involving NULL are UNKNOWN rather than FALSE,
+// and the columns produced by the query are NULL
+// rather than MISSING when they have no value.
+//
+// The columns produced by SELECT * are
+// not rewritten, so they may still be MISSING.
+func (q *Query) SetANSINulls() {
+	r := &ansiRewriter{}
+	for i := range q.With {
+		q.With[i].As = Rewrite(r, q.With[i].As).(*Select)
+	}
+	q.Body = Rewrite(r, q.Body)
+	outputNulls(q.Body)
+}
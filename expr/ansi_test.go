@@ -0,0 +1,77 @@
+// Copyright (C) 2022 Sneller, Inc.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package expr_test
+
+import (
+	"testing"
+
+	"github.com/SnellerInc/sneller/expr"
+	"github.com/SnellerInc/sneller/expr/partiql"
+)
+
+func TestSetANSINulls(t *testing.T) {
+	testcases := []struct {
+		query, want string
+	}{
+		{
+			"SELECT COUNT(*) FROM t WHERE NOT (x = 1)",
+			"SELECT COUNT(*) AS \"count\" FROM t WHERE x <> 1 AND x IS NOT NULL",
+		},
+		{
+			"SELECT COUNT(*) FROM t WHERE x < y OR x IS NULL",
+			"SELECT COUNT(*) AS \"count\" FROM t WHERE x < y AND x IS NOT NULL AND y IS NOT NULL OR (x IS NULL OR x IS MISSING)",
+		},
+		{
+			"SELECT COUNT(*) FROM t WHERE NOT (x IN (1, NULL))",
+			"SELECT COUNT(*) AS \"count\" FROM t WHERE FALSE",
+		},
+		{
+			"SELECT COUNT(*) FROM t WHERE x = NULL",
+			"SELECT COUNT(*) AS \"count\" FROM t WHERE FALSE",
+		},
+		{
+			"SELECT x, x = 1 AS b, 'foo' AS s FROM t",
+			"SELECT CASE WHEN x IS MISSING THEN NULL ELSE x END AS x, " +
+				"CASE WHEN x = 1 AND x IS NOT NULL THEN TRUE WHEN x <> 1 AND x IS NOT NULL THEN FALSE ELSE NULL END AS b, 'foo' AS s FROM t",
+		},
+		{
+			"SELECT CASE WHEN NOT (x = 1) THEN 'a' END AS c, COUNT(*) AS n FROM t GROUP BY x",
+			"SELECT CASE WHEN x <> 1 AND x IS NOT NULL THEN 'a' END AS c, COUNT(*) AS n FROM t GROUP BY " +
+				"CASE WHEN x IS MISSING THEN NULL ELSE x END AS x",
+		},
+		{
+			"SELECT b, COUNT(*) AS n FROM t GROUP BY x = 1 AS b",
+			"SELECT CASE WHEN b IS MISSING THEN NULL ELSE b END AS b, COUNT(*) AS n FROM t GROUP BY " +
+				"CASE WHEN x = 1 AND x IS NOT NULL THEN TRUE WHEN x <> 1 AND x IS NOT NULL THEN FALSE ELSE NULL END AS b",
+		},
+		{
+			"SELECT * FROM t WHERE x > 0",
+			"SELECT * FROM t WHERE x > 0 AND x IS NOT NULL",
+		},
+	}
+	for i := range testcases {
+		q, err := partiql.Parse([]byte(testcases[i].query))
+		if err != nil {
+			t.Fatal(err)
+		}
+		q.SetANSINulls()
+		if err := q.Check(); err != nil {
+			t.Fatal(err)
+		}
+		if got := expr.ToString(q); got != testcases[i].want {
+			t.Errorf("got  %s\nwant %s", got, testcases[i].want)
+		}
+	}
+}
@@ -92,6 +92,33 @@ func Use(i Interface, client *http.Client) {
 	}
 }
 
+// Name returns a name for i that is suitable
+// for use in error messages. The query string
+// of a URL is omitted, since it may contain
+// credentials.
+func Name(i Interface) string {
+	switch b := i.(type) {
+	case *URL:
+		u, err := url.Parse(b.Value)
+		if err != nil {
+			return b.Info.ETag
+		}
+		u.User = nil
+		u.RawQuery = ""
+		u.Fragment = ""
+		return u.String()
+	case *Compressed:
+		return Name(b.From)
+	case *CompressedPart:
+		return Name(b.Parent)
+	}
+	info, err := i.Stat()
+	if err != nil {
+		return ""
+	}
+	return info.ETag
+}
+
 // URL is a blob that is fetched
 // using ranged reads of an HTTP(S) URL
 type URL struct {
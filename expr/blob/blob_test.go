@@ -175,6 +175,32 @@ func TestSerialization(t *testing.T) {
 				},
 				Trailer: blockfmt.Trailer{Version: 1, Algo: "zstd"},
 			},
+			&Compressed{
+				From: &URL{
+					Value: "http://abc.xyz/345",
+					Info: Info{
+						Size:         rand.Int63(),
+						Align:        100,
+						LastModified: now,
+					},
+				},
+				Trailer: blockfmt.Trailer{Version: 1, Algo: "zstd", Encrypted: true},
+				Key:     &blockfmt.DataKey{1, 2, 3},
+				Path:    "db/foo/bar/packed-345.ion.zst",
+			},
+			&Compressed{
+				From: &URL{
+					Value: "http://abc.xyz/678",
+					Info: Info{
+						Size:         rand.Int63(),
+						Align:        100,
+						LastModified: now,
+					},
+				},
+				Trailer:    blockfmt.Trailer{Version: 1, Algo: "zstd", Encrypted: true},
+				WrappedKey: []byte("wrapped key"),
+				Path:       "db/foo/bar/packed-678.ion.zst",
+			},
 			&URL{
 				Value: "http://foo.bar/baz",
 				Info: Info{
@@ -19,6 +19,7 @@ import (
 	"encoding/base64"
 	"fmt"
 	"io"
+	"sort"
 
 	"github.com/SnellerInc/sneller/ion"
 	"github.com/SnellerInc/sneller/ion/blockfmt"
@@ -33,6 +34,23 @@ type Compressed struct {
 	// describes how to unpack the
 	// compressed contents of From.
 	Trailer blockfmt.Trailer
+	// Key is the key used to decrypt
+	// the contents of From if Trailer.Encrypted
+	// is set. Note that Key is encoded in
+	// plaintext along with the rest of the blob,
+	// so it should only be sent to trusted peers
+	// (like the URLs of presigned blobs).
+	Key *blockfmt.DataKey
+	// WrappedKey, if non-nil, is the wrapped
+	// form of Key (see blockfmt.Index.DataKey).
+	// It is sent in place of Key when the data key
+	// is meant to be resolved at execution time
+	// (see plan.KeyProvider).
+	WrappedKey []byte
+	// Path is the path of the object in its table,
+	// which authenticates the encrypted blocks of
+	// From (see blockfmt.Decoder.Path).
+	Path string
 	// etext is additional text used
 	// to compute the ETag of the object
 	// if the trailer has been manipulated
@@ -86,6 +104,21 @@ func (d *decodeComp) SetField(f ion.Field) error {
 		err = d.parent.td.Decode(f.Datum, &d.comp.Trailer)
 	case "etext":
 		d.comp.etext, err = f.String()
+	case "key":
+		var b []byte
+		b, err = f.BlobShared()
+		if err != nil {
+			return err
+		}
+		if len(b) != blockfmt.DataKeyLength {
+			return fmt.Errorf("unexpected key length %d", len(b))
+		}
+		d.comp.Key = new(blockfmt.DataKey)
+		copy(d.comp.Key[:], b)
+	case "wrapped-key":
+		d.comp.WrappedKey, err = f.Blob()
+	case "path":
+		d.comp.Path, err = f.String()
 	case "skip":
 		// ignore
 	case "iid":
@@ -123,6 +156,18 @@ func (c *Compressed) encode(be *blobEncoder, dst *ion.Buffer, st *ion.Symtab) {
 		dst.BeginField(st.Intern("etext"))
 		dst.WriteString(c.etext)
 	}
+	if c.Key != nil {
+		dst.BeginField(st.Intern("key"))
+		dst.WriteBlob(c.Key[:])
+	}
+	if c.WrappedKey != nil {
+		dst.BeginField(st.Intern("wrapped-key"))
+		dst.WriteBlob(c.WrappedKey)
+	}
+	if c.Path != "" {
+		dst.BeginField(st.Intern("path"))
+		dst.WriteString(c.Path)
+	}
 	if id, ok := be.id(c); ok {
 		dst.BeginField(st.Intern("iid"))
 		dst.WriteInt(int64(id))
@@ -191,7 +236,10 @@ func (c *Compressed) Decompressor() (io.ReadCloser, error) {
 	}
 	dd := &decompressor{}
 	dd.src = rd
-	dd.dec.Set(&c.Trailer, len(c.Trailer.Blocks))
+	dd.dec.Name = Name(c)
+	dd.dec.Key = c.Key
+	dd.dec.Path = c.Path
+	dd.dec.SetRange(&c.Trailer, 0, len(c.Trailer.Blocks))
 	return dd, nil
 }
 
@@ -203,10 +251,29 @@ func (c *Compressed) Reader(start, size int64) (io.ReadCloser, error) {
 	}
 	cr := &compressedReader{}
 	cr.ReadCloser = rd
-	cr.dec.Set(&c.Trailer, len(c.Trailer.Blocks))
+	cr.dec.Name = Name(c)
+	cr.dec.Key = c.Key
+	cr.dec.Path = c.Path
+	setRangeAt(&cr.dec, &c.Trailer, start, len(c.Trailer.Blocks))
 	return cr, nil
 }
 
+// setRangeAt prepares dec for reading the blocks of t
+// from offset start up to (but not including) block end;
+// if start is the offset of a block, then the block checksums
+// are verified and the blocks of an encrypted object can be
+// decrypted (see blockfmt.Decoder.SetRange)
+func setRangeAt(dec *blockfmt.Decoder, t *blockfmt.Trailer, start int64, end int) {
+	i := sort.Search(end, func(i int) bool {
+		return t.Blocks[i].Offset >= start
+	})
+	if i < end && t.Blocks[i].Offset == start {
+		dec.SetRange(t, i, end)
+	} else {
+		dec.Set(t, end)
+	}
+}
+
 // CompressedPart is a range of blocks
 // within a Compressed blob.
 type CompressedPart struct {
@@ -214,6 +281,14 @@ type CompressedPart struct {
 	StartBlock, EndBlock int
 }
 
+// Rows returns the estimated number of rows
+// in this compressed part, or false if the
+// number of rows is unknown.
+// (See blockfmt.Trailer.BlockRows.)
+func (c *CompressedPart) Rows() (int64, bool) {
+	return c.Parent.Trailer.BlockRows(c.StartBlock, c.EndBlock)
+}
+
 // Decompressed returns the decompressed
 // size of this compressed part.
 func (c *CompressedPart) Decompressed() (bytes int64) {
@@ -234,7 +309,10 @@ func (c *CompressedPart) Reader(start, size int64) (io.ReadCloser, error) {
 	}
 	cr := &compressedReader{}
 	cr.ReadCloser = rd
-	cr.dec.Set(&c.Parent.Trailer, c.EndBlock)
+	cr.dec.Name = Name(c)
+	cr.dec.Key = c.Parent.Key
+	cr.dec.Path = c.Parent.Path
+	setRangeAt(&cr.dec, &c.Parent.Trailer, start, c.EndBlock)
 	return cr, nil
 }
 
@@ -250,7 +328,10 @@ func (c *CompressedPart) Decompressor() (io.ReadCloser, error) {
 	}
 	dd := &decompressor{}
 	dd.src = rd
-	dd.dec.Set(&c.Parent.Trailer, c.EndBlock)
+	dd.dec.Name = Name(c)
+	dd.dec.Key = c.Parent.Key
+	dd.dec.Path = c.Parent.Path
+	dd.dec.SetRange(&c.Parent.Trailer, c.StartBlock, c.EndBlock)
 	return dd, nil
 }
 
@@ -100,7 +100,8 @@ const (
 	// are aliases, the names are provied in the comment,
 	// after "sql:" prefix.
 	// See _generate/builtin_names.go
-	Concat BuiltinOp = iota
+	Concat   BuiltinOp = iota
+	ConcatWS           // sql:CONCAT_WS
 	Trim
 	Ltrim
 	Rtrim
@@ -188,8 +189,10 @@ const (
 	DateExtractMinute
 	DateExtractHour
 	DateExtractDay
-	DateExtractDOW // sql:DATE_EXTRACT_DOW
-	DateExtractDOY // sql:DATE_EXTRACT_DOY
+	DateExtractDOW     // sql:DATE_EXTRACT_DOW
+	DateExtractDOY     // sql:DATE_EXTRACT_DOY
+	DateExtractISODOW  // sql:DATE_EXTRACT_ISODOW
+	DateExtractISOYear // sql:DATE_EXTRACT_ISOYEAR
 	DateExtractMonth
 	DateExtractQuarter
 	DateExtractYear
@@ -207,6 +210,19 @@ const (
 
 	ToUnixEpoch
 	ToUnixMicro
+	ToUnixTime       // sql:TO_UNIXTIME
+	ToUnixTimeMillis // sql:TO_UNIXTIME_MILLIS
+	FromUnixTime     // sql:FROM_UNIXTIME
+
+	FiscalYear
+	FiscalQuarter
+
+	FormatDuration
+	ParseDuration
+
+	ToChar
+	Format
+	Printf
 
 	GeoHash
 	GeoTileX
@@ -215,12 +231,22 @@ const (
 	GeoDistance
 
 	ObjectSize // sql:SIZE
+	ObjectSet
+	ObjectDelete
+	ObjectMerge
 	ArrayContains
 	ArraySize
 	ArrayPosition
+	ArrayElement // ARRAY_ELEMENT(list, i) is list[i] for a non-constant i
+
+	Annotations
 
 	TableGlob
 	TablePattern
+	TableRange  // sql:RANGE
+	TableSeries // sql:SERIES
+
+	TimeRange // TIME_RANGE(path) is replaced with index metadata by the query planner
 
 	// used by query planner:
 	InSubquery        // matches IN (SELECT ...)
@@ -323,6 +349,10 @@ func (b BuiltinOp) TimePart() (Timepart, bool) {
 		return DOW, true
 	case DateExtractDOY:
 		return DOY, true
+	case DateExtractISODOW:
+		return ISODOW, true
+	case DateExtractISOYear:
+		return ISOYear, true
 	case DateExtractMonth:
 		return Month, true
 	case DateExtractQuarter:
@@ -547,6 +577,85 @@ func checkSplitPart(h Hint, args []Node) error {
 	return nil
 }
 
+func checkConcatWS(h Hint, args []Node) error {
+	if len(args) < 2 {
+		return errsyntaxf("CONCAT_WS expects a separator and at least one argument, but found %d arguments", len(args))
+	}
+	if !TypeOf(args[0], h).AnyOf(StringType) {
+		return errtype(args[0], "separator is not a string")
+	}
+	for _, arg := range args[1:] {
+		// NULL and MISSING are allowed
+		// here, since they are skipped
+		if !TypeOf(arg, h).AnyOf(StringType | NullType | MissingType) {
+			return errtype(arg, "not a string")
+		}
+	}
+	return nil
+}
+
+// simplifyConcatWS drops the arguments to
+// CONCAT_WS(sep, args...) that are never strings
+// (and thus always skipped), and, when sep is
+// a constant, joins adjacent constant arguments.
+// If all of the arguments are always strings,
+// the whole expression is lowered into a plain
+// concatenation.
+func simplifyConcatWS(h Hint, args []Node) Node {
+	if len(args) < 2 {
+		return nil
+	}
+	switch args[0].(type) {
+	case Null, Missing:
+		return Missing{}
+	}
+	sep, sepok := args[0].(String)
+	out := []Node{args[0]}
+	changed := false
+	for _, arg := range args[1:] {
+		if !TypeOf(arg, h).AnyOf(StringType) {
+			changed = true
+			continue
+		}
+		if str, ok := arg.(String); ok && sepok && len(out) > 1 {
+			if prev, ok := out[len(out)-1].(String); ok {
+				out[len(out)-1] = prev + sep + str
+				changed = true
+				continue
+			}
+		}
+		out = append(out, arg)
+	}
+	if !sepok {
+		// an empty list of arguments still
+		// yields MISSING when sep is missing,
+		// so it can't be folded into a constant
+		if !changed || len(out) == 1 {
+			return nil
+		}
+		return Call(ConcatWS, out...)
+	}
+	if len(out) == 1 {
+		return String("")
+	}
+	for _, arg := range out[1:] {
+		if TypeOf(arg, h)&^StringType != 0 {
+			if !changed {
+				return nil
+			}
+			return Call(ConcatWS, out...)
+		}
+	}
+	ret := out[1]
+	for _, arg := range out[2:] {
+		if sep != "" {
+			ret = Call(Concat, ret, sep)
+		}
+		ret = Call(Concat, ret, arg)
+	}
+	return ret
+}
+
 var unaryStringArgs = fixedArgs(StringType)
 var variadicNumeric = variadicArgs(NumericType)
 var fixedTime = fixedArgs(TimeType)
@@ -564,6 +673,33 @@ func simplifyDateTrunc(part Timepart) func(Hint, []Node) Node {
 	}
 }
 
+// simplifyDateExtractISO folds the extraction
+// of an ISO 8601 week-date part from a constant timestamp
+func simplifyDateExtractISO(part Timepart) func(Hint, []Node) Node {
+	return func(h Hint, args []Node) Node {
+		if len(args) != 1 {
+			return nil
+		}
+		ts, ok := args[0].(*Timestamp)
+		if !ok {
+			return nil
+		}
+		t := ts.Value.Time()
+		switch part {
+		case ISODOW:
+			dow := int(t.Weekday())
+			if dow == 0 {
+				dow = 7
+			}
+			return Integer(dow)
+		case ISOYear:
+			year, _ := t.ISOWeek()
+			return Integer(year)
+		}
+		return nil
+	}
+}
+
 func checkInSubquery(h Hint, args []Node) error {
 	if len(args) != 2 {
 		return mismatch(2, len(args))
@@ -574,6 +710,22 @@ func checkInSubquery(h Hint, args []Node) error {
 	return nil
 }
 
+// inSubqueryText prints IN_SUBQUERY(x, (SELECT ...))
+// as x IN (SELECT ...) so that it can be parsed again
+func inSubqueryText(args []Node, dst *strings.Builder, redact bool) {
+	if len(args) != 2 {
+		defaultText("IN_SUBQUERY", args, dst, redact)
+		return
+	}
+	if _, ok := args[1].(*Select); !ok {
+		defaultText("IN_SUBQUERY", args, dst, redact)
+		return
+	}
+	args[0].text(dst, redact)
+	dst.WriteString(" IN ")
+	args[1].text(dst, redact)
+}
+
 func checkInReplacement(h Hint, args []Node) error {
 	if len(args) != 2 {
 		return mismatch(2, len(args))
@@ -628,6 +780,121 @@ func checkObjectSize(h Hint, args []Node) error {
 	return nil
 }
 
+func checkObjectSet(h Hint, args []Node) error {
+	if len(args) != 3 {
+		return errsyntaxf("OBJECT_SET expects three arguments, but found %d", len(args))
+	}
+	if !TypeOf(args[0], h).AnyOf(StructType) {
+		return errtype(args[0], "first argument to OBJECT_SET must be a structure")
+	}
+	if _, ok := args[1].(String); !ok {
+		return errtype(args[1], "second argument to OBJECT_SET must be a constant string")
+	}
+	return nil
+}
+
+func checkObjectDelete(h Hint, args []Node) error {
+	if len(args) != 2 {
+		return errsyntaxf("OBJECT_DELETE expects two arguments, but found %d", len(args))
+	}
+	if !TypeOf(args[0], h).AnyOf(StructType) {
+		return errtype(args[0], "first argument to OBJECT_DELETE must be a structure")
+	}
+	if _, ok := args[1].(String); !ok {
+		return errtype(args[1], "second argument to OBJECT_DELETE must be a constant string")
+	}
+	return nil
+}
+
+func checkObjectMerge(h Hint, args []Node) error {
+	if len(args) != 2 {
+		return errsyntaxf("OBJECT_MERGE expects two arguments, but found %d", len(args))
+	}
+	for i := range args {
+		if !TypeOf(args[i], h).AnyOf(StructType) {
+			return errtype(args[i], "arguments to OBJECT_MERGE must be structures")
+		}
+	}
+	return nil
+}
+
+// structSet returns a copy of s where
+// the field label is set to v
+func structSet(s *Struct, label string, v Constant) *Struct {
+	out := &Struct{Fields: make([]Field, 0, len(s.Fields)+1)}
+	for i := range s.Fields {
+		if s.Fields[i].Label != label {
+			out.Fields = append(out.Fields, s.Fields[i])
+		}
+	}
+	out.Fields = append(out.Fields, Field{Label: label, Value: v})
+	return out
+}
+
+// OBJECT_SET({...}, 'k', const) -> {..., k: const}
+func simplifyObjectSet(h Hint, args []Node) Node {
+	if len(args) != 3 {
+		return nil
+	}
+	s, ok := args[0].(*Struct)
+	if !ok {
+		return nil
+	}
+	k, ok := args[1].(String)
+	if !ok {
+		return nil
+	}
+	if _, ok := args[2].(Missing); ok {
+		return s
+	}
+	v, ok := args[2].(Constant)
+	if !ok {
+		return nil
+	}
+	return structSet(s, string(k), v)
+}
+
+// OBJECT_DELETE({...}, 'k') -> {...}
+func simplifyObjectDelete(h Hint, args []Node) Node {
+	if len(args) != 2 {
+		return nil
+	}
+	s, ok := args[0].(*Struct)
+	if !ok {
+		return nil
+	}
+	k, ok := args[1].(String)
+	if !ok {
+		return nil
+	}
+	out := &Struct{}
+	for i := range s.Fields {
+		if s.Fields[i].Label != string(k) {
+			out.Fields = append(out.Fields, s.Fields[i])
+		}
+	}
+	return out
+}
+
+// OBJECT_MERGE({...}, {...}) -> {...}
+func simplifyObjectMerge(h Hint, args []Node) Node {
+	if len(args) != 2 {
+		return nil
+	}
+	a, ok := args[0].(*Struct)
+	if !ok {
+		return nil
+	}
+	b, ok := args[1].(*Struct)
+	if !ok {
+		return nil
+	}
+	for i := range b.Fields {
+		a = structSet(a, b.Fields[i].Label, b.Fields[i].Value)
+	}
+	return a
+}
+
 func checkArrayContains(h Hint, args []Node) error {
 	if len(args) != 2 {
 		return errsyntaxf("ARRAY_CONTAINS expects two arguments, but found %d", len(args))
@@ -648,6 +915,35 @@ func checkArraySize(h Hint, args []Node) error {
 	return nil
 }
 
+func checkAnnotations(h Hint, args []Node) error {
+	if len(args) != 1 {
+		return errsyntaxf("ANNOTATIONS expects one argument, but found %d", len(args))
+	}
+	if _, ok := FlatPath(args[0]); !ok {
+		return errsyntaxf("ANNOTATIONS expects a path argument, but found %s", ToString(args[0]))
+	}
+	return nil
+}
+
+// simplifyAnnotations rewrites ANNOTATIONS(a.b)
+// into a."$annotations".b, which is where
+// the ingestion process stores the labels of
+// annotated values (see ion.UnwrapAnnotations)
+func simplifyAnnotations(h Hint, args []Node) Node {
+	if len(args) != 1 {
+		return nil
+	}
+	switch p := args[0].(type) {
+	case Ident:
+		return &Dot{Inner: Ident(ion.AnnotationsField), Field: string(p)}
+	case *Dot:
+		if _, ok := FlatPath(p); ok {
+			return &Dot{Inner: &Dot{Inner: p.Inner, Field: ion.AnnotationsField}, Field: p.Field}
+		}
+	}
+	return nil
+}
+
 func checkArrayPosition(h Hint, args []Node) error {
 	if len(args) != 2 {
 		return errsyntaxf("ARRAY_POSITION expects two arguments, but found %d", len(args))
@@ -658,6 +954,41 @@ func checkArrayPosition(h Hint, args []Node) error {
 	return nil
 }
 
+func checkArrayElement(h Hint, args []Node) error {
+	if len(args) != 2 {
+		return errsyntaxf("ARRAY_ELEMENT expects two arguments, but found %d", len(args))
+	}
+	if HasWildcard(args[0]) {
+		return errtype(args[0], "cannot index the result of a wildcard path")
+	}
+	if !TypeOf(args[0], h).AnyOf(ListType) {
+		return errtype(args[0], "cannot index non-list value")
+	}
+	if !TypeOf(args[1], h).AnyOf(IntegerType) {
+		return errtype(args[1], "list index must be an integer")
+	}
+	return nil
+}
+
+// simplifyArrayElement turns ARRAY_ELEMENT(list, n)
+// back into list[n] once n is a constant
+func simplifyArrayElement(h Hint, args []Node) Node {
+	if len(args) != 2 {
+		return nil
+	}
+	if i, ok := args[1].(Integer); ok {
+		return (&Index{Inner: args[0], Offset: int(i)}).simplify(h)
+	}
+	return nil
+}
+
+func arrayElementText(args []Node, dst *strings.Builder, redact bool) {
+	args[0].text(dst, redact)
+	dst.WriteByte('[')
+	args[1].text(dst, redact)
+	dst.WriteByte(']')
+}
+
 func checkTableGlob(h Hint, args []Node) error {
 	if len(args) != 1 {
 		return mismatch(1, len(args))
@@ -678,6 +1009,43 @@ func checkTablePattern(h Hint, args []Node) error {
 	return nil
 }
 
+func checkTableRange(h Hint, args []Node) error {
+	if len(args) != 2 && len(args) != 3 {
+		return errsyntaxf("RANGE expects two or three arguments, but found %d", len(args))
+	}
+	for i := range args {
+		if !TypeOf(args[i], h).AnyOf(IntegerType) {
+			return errtype(args[i], "argument to RANGE must be an integer")
+		}
+	}
+	return nil
+}
+
+func checkTableSeries(h Hint, args []Node) error {
+	if len(args) != 3 {
+		return mismatch(3, len(args))
+	}
+	for i := range args[:2] {
+		if !TypeOf(args[i], h).AnyOf(IntegerType | TimeType) {
+			return errtype(args[i], "bound of SERIES must be an integer or a timestamp")
+		}
+	}
+	if !TypeOf(args[2], h).AnyOf(IntegerType) {
+		return errtype(args[2], "step of SERIES must be an integer")
+	}
+	return nil
+}
+
+func checkTimeRange(h Hint, args []Node) error {
+	if len(args) != 1 {
+		return mismatch(1, len(args))
+	}
+	if _, ok := FlatPath(args[0]); !ok {
+		return errsyntaxf("argument to TIME_RANGE is %q", ToString(args[0]))
+	}
+	return nil
+}
+
 func checkAssertIonType(h Hint, args []Node) error {
 	if len(args) < 2 {
 		return errsyntaxf("requires at least 2 arguments")
@@ -1019,6 +1387,7 @@ func exp10(x float64) float64 {
 
 var builtinInfo = [maxBuiltin]binfo{
 	Concat:               {check: fixedArgs(StringType, StringType), private: true, ret: StringType | MissingType},
+	ConcatWS:             {check: checkConcatWS, ret: StringType | MissingType, simplify: simplifyConcatWS},
 	Trim:                 {check: checkTrim(Trim), ret: StringType | MissingType},
 	Ltrim:                {check: checkTrim(Ltrim), ret: StringType | MissingType},
 	Rtrim:                {check: checkTrim(Rtrim), ret: StringType | MissingType},
@@ -1101,6 +1470,8 @@ var builtinInfo = [maxBuiltin]binfo{
 	DateExtractDay:         {check: fixedArgs(TimeType), private: true, ret: IntegerType | MissingType},
 	DateExtractDOW:         {check: fixedArgs(TimeType), private: true, ret: IntegerType | MissingType},
 	DateExtractDOY:         {check: fixedArgs(TimeType), private: true, ret: IntegerType | MissingType},
+	DateExtractISODOW:      {check: fixedTime, private: true, ret: IntegerType | MissingType, simplify: simplifyDateExtractISO(ISODOW)},
+	DateExtractISOYear:     {check: fixedTime, private: true, ret: IntegerType | MissingType, simplify: simplifyDateExtractISO(ISOYear)},
 	DateExtractMonth:       {check: fixedArgs(TimeType), private: true, ret: IntegerType | MissingType},
 	DateExtractQuarter:     {check: fixedArgs(TimeType), private: true, ret: IntegerType | MissingType},
 	DateExtractYear:        {check: fixedArgs(TimeType), private: true, ret: IntegerType | MissingType},
@@ -1116,6 +1487,16 @@ var builtinInfo = [maxBuiltin]binfo{
 	DateTruncYear:          {check: fixedTime, private: true, ret: TimeType | MissingType, simplify: simplifyDateTrunc(Year)},
 	ToUnixEpoch:            {check: fixedTime, ret: IntegerType | MissingType},
 	ToUnixMicro:            {check: fixedTime, ret: IntegerType | MissingType},
+	ToUnixTime:             {check: fixedTime, ret: IntegerType | MissingType, simplify: simplifyToUnixTime},
+	ToUnixTimeMillis:       {check: fixedTime, ret: IntegerType | MissingType, simplify: simplifyToUnixTimeMillis},
+	FromUnixTime:           {check: checkFromUnixTime, ret: TimeType | MissingType, simplify: simplifyFromUnixTime},
+	FiscalYear:             {check: checkFiscal(FiscalYear), ret: IntegerType | MissingType, simplify: simplifyFiscal(Year)},
+	FiscalQuarter:          {check: checkFiscal(FiscalQuarter), ret: IntegerType | MissingType, simplify: simplifyFiscal(Quarter)},
+	FormatDuration:         {check: fixedArgs(NumericType), ret: StringType | MissingType, simplify: simplifyFormatDuration},
+	ParseDuration:          {check: checkParseDuration, ret: IntegerType, simplify: simplifyParseDuration},
+	ToChar:                 {check: checkToChar, ret: StringType | MissingType, simplify: simplifyToChar},
+	Format:                 {check: checkFormat, ret: StringType | MissingType, simplify: simplifyFormat},
+	Printf:                 {check: checkPrintf, ret: StringType | MissingType, simplify: simplifyPrintf},
 
 	GeoHash:     {check: fixedArgs(NumericType, NumericType, IntegerType), ret: StringType | MissingType},
 	GeoTileX:    {check: fixedArgs(NumericType, IntegerType), ret: StringType | MissingType},
@@ -1124,11 +1505,17 @@ var builtinInfo = [maxBuiltin]binfo{
 	GeoDistance: {check: fixedArgs(NumericType, NumericType, NumericType, NumericType), ret: FloatType | MissingType},
 
 	ObjectSize:    {check: checkObjectSize, ret: NumericType | MissingType},
+	ObjectSet:     {check: checkObjectSet, ret: StructType | MissingType, simplify: simplifyObjectSet},
+	ObjectDelete:  {check: checkObjectDelete, ret: StructType | MissingType, simplify: simplifyObjectDelete},
+	ObjectMerge:   {check: checkObjectMerge, ret: StructType | MissingType, simplify: simplifyObjectMerge},
 	ArraySize:     {check: checkArraySize, ret: NumericType | MissingType},
 	ArrayContains: {check: checkArrayContains, ret: LogicalType | MissingType},
 	ArrayPosition: {check: checkArrayPosition, ret: NumericType | MissingType},
+	ArrayElement:  {check: checkArrayElement, private: true, ret: AnyType, text: arrayElementText, simplify: simplifyArrayElement},
+
+	Annotations: {check: checkAnnotations, ret: ListType | MissingType, simplify: simplifyAnnotations},
 
-	InSubquery:        {check: checkInSubquery, private: true, ret: LogicalType},
+	InSubquery:        {check: checkInSubquery, private: true, ret: LogicalType, text: inSubqueryText},
 	InReplacement:     {check: checkInReplacement, private: true, ret: LogicalType},
 	HashReplacement:   {check: checkHashReplacement, private: true, ret: AnyType},
 	ScalarReplacement: {check: checkScalarReplacement, private: true, ret: AnyType},
@@ -1144,6 +1531,9 @@ var builtinInfo = [maxBuiltin]binfo{
 	AssertIonType:  {check: checkAssertIonType, ret: AnyType, simplify: simplifyAssertIonType, private: true},
 	TableGlob:      {check: checkTableGlob, ret: AnyType, isTable: true},
 	TablePattern:   {check: checkTablePattern, ret: AnyType, isTable: true},
+	TableRange:     {check: checkTableRange, ret: AnyType, isTable: true},
+	TableSeries:    {check: checkTableSeries, ret: AnyType, isTable: true},
+	TimeRange:      {check: checkTimeRange, ret: StructType},
 	PartitionValue: {ret: AnyType, private: true},
 }
 
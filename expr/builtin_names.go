@@ -2,8 +2,9 @@ package expr
 
 // Code generated automatically; DO NOT EDIT
 
-var builtin2Name = [119]string{
+var builtin2Name = [140]string{
 	"CONCAT",                   // Concat
+	"CONCAT_WS",                // ConcatWS
 	"TRIM",                     // Trim
 	"LTRIM",                    // Ltrim
 	"RTRIM",                    // Rtrim
@@ -84,6 +85,8 @@ var builtin2Name = [119]string{
 	"DATE_EXTRACT_DAY",         // DateExtractDay
 	"DATE_EXTRACT_DOW",         // DateExtractDOW
 	"DATE_EXTRACT_DOY",         // DateExtractDOY
+	"DATE_EXTRACT_ISODOW",      // DateExtractISODOW
+	"DATE_EXTRACT_ISOYEAR",     // DateExtractISOYear
 	"DATE_EXTRACT_MONTH",       // DateExtractMonth
 	"DATE_EXTRACT_QUARTER",     // DateExtractQuarter
 	"DATE_EXTRACT_YEAR",        // DateExtractYear
@@ -99,17 +102,35 @@ var builtin2Name = [119]string{
 	"DATE_TRUNC_YEAR",          // DateTruncYear
 	"TO_UNIX_EPOCH",            // ToUnixEpoch
 	"TO_UNIX_MICRO",            // ToUnixMicro
+	"TO_UNIXTIME",              // ToUnixTime
+	"TO_UNIXTIME_MILLIS",       // ToUnixTimeMillis
+	"FROM_UNIXTIME",            // FromUnixTime
+	"FISCAL_YEAR",              // FiscalYear
+	"FISCAL_QUARTER",           // FiscalQuarter
+	"FORMAT_DURATION",          // FormatDuration
+	"PARSE_DURATION",           // ParseDuration
+	"TO_CHAR",                  // ToChar
+	"FORMAT",                   // Format
+	"PRINTF",                   // Printf
 	"GEO_HASH",                 // GeoHash
 	"GEO_TILE_X",               // GeoTileX
 	"GEO_TILE_Y",               // GeoTileY
 	"GEO_TILE_ES",              // GeoTileES
 	"GEO_DISTANCE",             // GeoDistance
 	"SIZE",                     // ObjectSize
+	"OBJECT_SET",               // ObjectSet
+	"OBJECT_DELETE",            // ObjectDelete
+	"OBJECT_MERGE",             // ObjectMerge
 	"ARRAY_CONTAINS",           // ArrayContains
 	"ARRAY_SIZE",               // ArraySize
 	"ARRAY_POSITION",           // ArrayPosition
+	"ARRAY_ELEMENT",            // ArrayElement
+	"ANNOTATIONS",              // Annotations
 	"TABLE_GLOB",               // TableGlob
 	"TABLE_PATTERN",            // TablePattern
+	"RANGE",                    // TableRange
+	"SERIES",                   // TableSeries
+	"TIME_RANGE",               // TimeRange
 	"IN_SUBQUERY",              // InSubquery
 	"IN_REPLACEMENT",           // InReplacement
 	"HASH_REPLACEMENT",         // HashReplacement
@@ -128,6 +149,8 @@ func name2Builtin(s string) BuiltinOp {
 	switch s {
 	case "CONCAT":
 		return Concat
+	case "CONCAT_WS":
+		return ConcatWS
 	case "TRIM":
 		return Trim
 	case "LTRIM":
@@ -294,6 +317,10 @@ func name2Builtin(s string) BuiltinOp {
 		return DateExtractDOW
 	case "DATE_EXTRACT_DOY":
 		return DateExtractDOY
+	case "DATE_EXTRACT_ISODOW":
+		return DateExtractISODOW
+	case "DATE_EXTRACT_ISOYEAR":
+		return DateExtractISOYear
 	case "DATE_EXTRACT_MONTH":
 		return DateExtractMonth
 	case "DATE_EXTRACT_QUARTER":
@@ -324,6 +351,26 @@ func name2Builtin(s string) BuiltinOp {
 		return ToUnixEpoch
 	case "TO_UNIX_MICRO":
 		return ToUnixMicro
+	case "TO_UNIXTIME":
+		return ToUnixTime
+	case "TO_UNIXTIME_MILLIS":
+		return ToUnixTimeMillis
+	case "FROM_UNIXTIME":
+		return FromUnixTime
+	case "FISCAL_YEAR":
+		return FiscalYear
+	case "FISCAL_QUARTER":
+		return FiscalQuarter
+	case "FORMAT_DURATION":
+		return FormatDuration
+	case "PARSE_DURATION":
+		return ParseDuration
+	case "TO_CHAR":
+		return ToChar
+	case "FORMAT":
+		return Format
+	case "PRINTF":
+		return Printf
 	case "GEO_HASH":
 		return GeoHash
 	case "GEO_TILE_X":
@@ -336,16 +383,32 @@ func name2Builtin(s string) BuiltinOp {
 		return GeoDistance
 	case "SIZE":
 		return ObjectSize
+	case "OBJECT_SET":
+		return ObjectSet
+	case "OBJECT_DELETE":
+		return ObjectDelete
+	case "OBJECT_MERGE":
+		return ObjectMerge
 	case "ARRAY_CONTAINS":
 		return ArrayContains
 	case "ARRAY_SIZE":
 		return ArraySize
 	case "ARRAY_POSITION":
 		return ArrayPosition
+	case "ARRAY_ELEMENT":
+		return ArrayElement
+	case "ANNOTATIONS":
+		return Annotations
 	case "TABLE_GLOB":
 		return TableGlob
 	case "TABLE_PATTERN":
 		return TablePattern
+	case "RANGE":
+		return TableRange
+	case "SERIES":
+		return TableSeries
+	case "TIME_RANGE":
+		return TimeRange
 	case "IN_SUBQUERY":
 		return InSubquery
 	case "IN_REPLACEMENT":
@@ -374,4 +437,4 @@ func name2Builtin(s string) BuiltinOp {
 	return Unspecified
 }
 
-// checksum: b7402d29e3afbafc67203e277321d787
+// checksum: 84b3958d79719800f0974df063c5b2dc
@@ -30,6 +30,9 @@ type TypeError struct {
 	At   Node
 	Msg  string
 	Hint string
+	// Pos, if valid, is the location
+	// of At in the query text
+	Pos Position
 }
 
 // SyntaxError is the error type
@@ -38,18 +41,21 @@ type TypeError struct {
 type SyntaxError struct {
 	At  Node
 	Msg string
+	// Pos, if valid, is the location
+	// of At in the query text
+	Pos Position
 }
 
 // Error implements error
 func (t *TypeError) Error() string {
-	return fmt.Sprintf("%q is ill-typed: %s", ToString(t.At), t.Msg)
+	return fmt.Sprintf("%q is ill-typed: %s%s", ToString(t.At), t.Msg, at(t.Pos))
 }
 
 func (s *SyntaxError) Error() string {
 	if s.At != nil {
-		return fmt.Sprintf("%q %s", ToString(s.At), s.Msg)
+		return fmt.Sprintf("%q %s%s", ToString(s.At), s.Msg, at(s.Pos))
 	}
-	return s.Msg
+	return s.Msg + at(s.Pos)
 }
 
 func errat(err error, whence Node) {
@@ -125,11 +131,17 @@ func (c *checktable) Visit(n Node) Visitor {
 	if n == nil || IsPath(n) {
 		return nil
 	}
-	// TODO: allow list literals in table position
 	switch t := n.(type) {
 	case *Builtin:
 		if !t.isTable() {
 			c.errorf("cannot use %s in table position", ToString(n))
+		} else if t.info() != nil {
+			// (opaque table functions are
+			// interpreted by the environment)
+			if err := t.check(c.parent.hint); err != nil {
+				c.parent.adderror(err)
+				return nil
+			}
 		}
 		return c.parent
 	case *Select:
@@ -140,6 +152,15 @@ func (c *checktable) Visit(n Node) Visitor {
 		return nil
 	case *Appended:
 		return &checktable{parent: c.parent}
+	case *List:
+		// constant table (i.e. VALUES)
+		for i := range t.Values {
+			if _, ok := t.Values[i].(*Struct); !ok {
+				c.errorf("constant table row %s is not a structure", ToString(t.Values[i]))
+				return nil
+			}
+		}
+		return nil
 	case *Unpivot:
 		return c.parent
 	default:
@@ -173,6 +194,32 @@ func (c *checkwalk) Visit(n Node) Visitor {
 
 	case *Table:
 		return &checktable{parent: c}
+
+	case *Join:
+		// the right-hand side of a cross join
+		// is a path within the left-hand side,
+		// but for other joins it is a table
+		if t.Kind != CrossJoin {
+			if t.On != nil {
+				Walk(c, t.On)
+			}
+			Walk(c, t.Left)
+			Walk(&checktable{parent: c}, t.Right.Expr)
+			return nil
+		}
+		if u, ok := t.Right.Expr.(*Unnest); ok {
+			Walk(c, t.Left)
+			if err := u.check(c.hint); err != nil {
+				c.adderror(err)
+				return nil
+			}
+			u.walk(c)
+			return nil
+		}
+
+	case *Unnest:
+		c.errorf("cannot use %q outside of a cross join", ToString(n))
+		return nil
 	}
 	return c
 }
@@ -313,7 +360,11 @@ func (a *Aggregate) check(h Hint) error {
 		if a.Filter != nil {
 			return errsyntax(a, "FILTER not supported")
 		}
-		if a.Inner != nil {
+		if a.Op == OpNtile {
+			if n, ok := a.Inner.(Integer); !ok || n <= 0 {
+				return errsyntax(a, "NTILE requires a positive integer constant")
+			}
+		} else if a.Inner != nil {
 			return errsyntax(a, "aggregate does not accept an argument")
 		}
 		if a.Over == nil {
@@ -403,6 +454,11 @@ func (s *Select) check(h Hint) error {
 }
 
 func (d *Dot) check(h Hint) error {
+	if HasWildcard(d.Inner) {
+		// the field is selected from
+		// each element of the wildcard
+		return nil
+	}
 	it := TypeOf(d.Inner, h)
 	if !it.Contains(ion.StructType) {
 		return errtype(d.Inner, "cannot use '.' operator on non-struct type")
@@ -442,15 +498,30 @@ func (i *Index) check(h Hint) error {
 			return 0, false
 		}
 	}
-	if i.Offset < 0 {
-		return errtype(i, "cannot perform negative index operation")
+	if HasWildcard(i.Inner) {
+		return errtype(i, "cannot index the result of a wildcard path")
 	}
 	t := TypeOf(i.Inner, h)
 	if t&ListType == 0 {
 		return errtype(i.Inner, "cannot index non-list value")
 	}
-	if llen, ok := listLen(i.Inner); ok && i.Offset >= llen {
+	if llen, ok := listLen(i.Inner); ok && (i.Offset >= llen || i.Offset < -llen) {
 		return errtype(i, "cannot index a list of length %d at offset %d", llen, i.Offset)
 	}
 	return nil
 }
+
+func (w *Wildcard) check(h Hint) error {
+	if HasWildcard(w.Inner) {
+		return nil
+	}
+	t := TypeOf(w.Inner, h)
+	if w.Struct {
+		if !t.Contains(ion.StructType) {
+			return errtype(w.Inner, "cannot use '.*' on non-struct type")
+		}
+	} else if t&ListType == 0 {
+		return errtype(w.Inner, "cannot use '[*]' on non-list value")
+	}
+	return nil
+}
@@ -55,6 +55,36 @@ func TestCheckInvalidQuery(t *testing.T) {
 			`SELECT (a ++ b ++ c)`,
 			"non-table",
 		},
+		{
+			`SELECT RANGE(0, 10) FROM foo`,
+			"non-table position",
+		},
+		{
+			// table functions have their
+			// arguments checked in table position
+			`SELECT * FROM RANGE('a', 10)`,
+			"argument to RANGE must be an integer",
+		},
+		{
+			`SELECT * FROM RANGE(10)`,
+			"RANGE expects two or three arguments",
+		},
+		{
+			"SELECT * FROM foo JOIN SERIES(`2023-01-01T00:00:00Z`, `2023-01-02T00:00:00Z`, 'hour') AS s ON foo.t = s.series",
+			"step of SERIES must be an integer",
+		},
+		{
+			`SELECT * FROM UNNEST(a, b) AS (x, y)`,
+			"in table position",
+		},
+		{
+			`SELECT UNNEST(a, b) AS (x, y) FROM foo`,
+			"outside of a cross join",
+		},
+		{
+			`SELECT * FROM foo AS f, UNNEST(f.a, f.b) AS (x, x)`,
+			"UNNEST binds \"x\" more than once",
+		},
 		{
 			"SELECT * FROM table WHERE 3 = `2022-01-02T03:04:05.67Z`",
 			"lhs and rhs.*never comparable",
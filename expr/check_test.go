@@ -57,6 +57,38 @@ func TestCheckExpressions(t *testing.T) {
 			expr: &Cast{From: path("x"), To: DecimalType},
 			kind: &SyntaxError{},
 		},
+		{
+			expr: Call(FromUnixTime),
+			kind: &SyntaxError{},
+		},
+		{
+			expr: Call(FromUnixTime, path("x"), Integer(1), Integer(2)),
+			kind: &SyntaxError{},
+		},
+		{
+			expr: Call(FromUnixTime, String("xyz")),
+			kind: &TypeError{},
+		},
+		{
+			expr: Call(ToUnixTime, Integer(1)),
+			kind: &TypeError{},
+		},
+		{
+			expr: Call(FormatDuration, String("1h")),
+			kind: &TypeError{},
+		},
+		{
+			expr: Call(ParseDuration, path("x")),
+			kind: &SyntaxError{},
+		},
+		{
+			expr: Call(Annotations, Add(path("x"), Integer(1))),
+			kind: &SyntaxError{},
+		},
+		{
+			expr: Call(ParseDuration, String("1 hour")),
+			kind: &SyntaxError{},
+		},
 		{
 			expr: &Cast{From: path("y"), To: SymbolType},
 			kind: &SyntaxError{},
@@ -134,9 +166,24 @@ func TestCheckExpressions(t *testing.T) {
 			"SIZE expects",
 		},
 		{
-			&Index{Inner: Ident("z"), Offset: -1},
+			&Index{Inner: &List{Values: []Constant{Null{}, Null{}}}, Offset: -3},
+			&TypeError{},
+			"index",
+		},
+		{
+			Call(ArrayElement, path("x"), String("y")),
 			&TypeError{},
-			"negative",
+			"integer",
+		},
+		{
+			Call(ObjectSet, path("x"), path("y"), Integer(1)),
+			&TypeError{},
+			"constant string",
+		},
+		{
+			Call(ObjectMerge, path("x"), Integer(1)),
+			&TypeError{},
+			"structures",
 		},
 		{
 			&Index{Inner: &List{Values: []Constant{Null{}, Null{}}}, Offset: 3},
@@ -148,6 +195,21 @@ func TestCheckExpressions(t *testing.T) {
 			&TypeError{},
 			"index",
 		},
+		{
+			&Index{Inner: &Wildcard{Inner: path("x")}, Offset: 0},
+			&TypeError{},
+			"wildcard",
+		},
+		{
+			&Wildcard{Inner: Integer(3)},
+			&TypeError{},
+			"non-list",
+		},
+		{
+			&Wildcard{Inner: String("foo"), Struct: true},
+			&TypeError{},
+			"non-struct",
+		},
 		{
 			// SELECT ASSERT_ION_TYPE()
 			Call(AssertIonType),
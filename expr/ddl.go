@@ -0,0 +1,388 @@
+// Copyright (C) 2022 Sneller, Inc.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package expr
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DDL is a data-definition statement:
+// a *CreateTable, *AlterTable, or *DropTable.
+//
+// Like a Delete, a DDL statement is not planned
+// and executed like an ordinary query; it is executed
+// by rewriting the definition of the table
+// (see db.CreateTable, db.AlterTable, and db.DropTable).
+type DDL interface {
+	Printable
+	// Text returns the unredacted statement text.
+	Text() string
+	// Redacted returns the redacted statement text.
+	Redacted() string
+	// Check checks the statement for errors.
+	Check() error
+}
+
+// ColumnDef is a column definition
+// in a CREATE TABLE statement.
+type ColumnDef struct {
+	// Path is the path expression
+	// that names the column.
+	Path Node
+	// Type is the (lower-case) name
+	// of the declared type of the column.
+	Type string
+	// NotNull is set if the column was
+	// declared NOT NULL, which means that
+	// every row has a non-null value for the column.
+	NotNull bool
+}
+
+func (c *ColumnDef) text(dst *strings.Builder, redact bool) {
+	c.Path.text(dst, redact)
+	dst.WriteByte(' ')
+	dst.WriteString(strings.ToUpper(c.Type))
+	if c.NotNull {
+		dst.WriteString(" NOT NULL")
+	}
+}
+
+// PartitionDef is a partition specification
+// in a CREATE TABLE or ALTER TABLE statement.
+type PartitionDef struct {
+	// Field is the name of the partition field.
+	Field string
+	// Type is the (lower-case) type of the
+	// partition field, or the empty string
+	// if the type was not specified.
+	Type string
+	// Value is the template for the
+	// partition value, or the empty string
+	// if the template was not specified.
+	Value string
+}
+
+func (p *PartitionDef) text(dst *strings.Builder, redact bool) {
+	dst.WriteString(QuoteID(p.Field))
+	if p.Type != "" {
+		dst.WriteByte(' ')
+		dst.WriteString(strings.ToUpper(p.Type))
+	}
+	if p.Value != "" {
+		dst.WriteString(" AS ")
+		String(p.Value).text(dst, redact)
+	}
+}
+
+// RetentionDef is a retention policy in
+// a CREATE TABLE or ALTER TABLE statement.
+type RetentionDef struct {
+	// Field is the path expression for the
+	// field that determines the age of a row.
+	Field Node
+	// ValidFor is the validity window
+	// (see date.ParseDuration).
+	ValidFor string
+}
+
+func (r *RetentionDef) text(dst *strings.Builder, redact bool) {
+	dst.WriteString("RETENTION ON ")
+	r.Field.text(dst, redact)
+	dst.WriteString(" FOR ")
+	String(r.ValidFor).text(dst, redact)
+}
+
+// InputDef is an input specification
+// in a CREATE TABLE statement.
+type InputDef struct {
+	// Pattern is the glob pattern
+	// for the input objects.
+	Pattern string
+	// Format is the format of the input objects,
+	// or the empty string if the format is
+	// determined by the object names.
+	Format string
+	// Incremental is set if new objects
+	// always sort after the existing ones.
+	Incremental bool
+}
+
+func (in *InputDef) text(dst *strings.Builder, redact bool) {
+	String(in.Pattern).text(dst, redact)
+	if in.Format != "" {
+		// the format is not a user-provided value,
+		// so it is never redacted
+		dst.WriteString(" FORMAT ")
+		String(in.Format).text(dst, false)
+	}
+	if in.Incremental {
+		dst.WriteString(" INCREMENTAL")
+	}
+}
+
+// CreateTable is a statement of the form
+//
+//	CREATE TABLE [IF NOT EXISTS] table [(column type [NOT NULL], ...)]
+//	  [PARTITION BY (field [type] [AS 'template'], ...)]
+//	  [SORT BY (path, ...)]
+//	  [RETENTION ON path FOR 'window']
+//	  [FROM 'pattern' [FORMAT format] [INCREMENTAL], ...]
+type CreateTable struct {
+	// Table is the table being created.
+	Table *Table
+	// IfNotExists is set if the statement
+	// should succeed when the table exists.
+	IfNotExists bool
+	// Columns is the list of column definitions.
+	Columns []ColumnDef
+	// PartitionBy is the list of partitions.
+	PartitionBy []PartitionDef
+	// SortBy is the list of sort key paths.
+	SortBy []Node
+	// Retention is the retention policy, if any.
+	Retention *RetentionDef
+	// Inputs is the list of table inputs.
+	Inputs []InputDef
+}
+
+// Text implements DDL.Text
+func (c *CreateTable) Text() string { return ToString(c) }
+
+// Redacted implements DDL.Redacted
+func (c *CreateTable) Redacted() string { return ToRedacted(c) }
+
+func (c *CreateTable) text(dst *strings.Builder, redact bool) {
+	dst.WriteString("CREATE TABLE ")
+	if c.IfNotExists {
+		dst.WriteString("IF NOT EXISTS ")
+	}
+	c.Table.Expr.text(dst, redact)
+	if len(c.Columns) > 0 {
+		dst.WriteString(" (")
+		for i := range c.Columns {
+			if i > 0 {
+				dst.WriteString(", ")
+			}
+			c.Columns[i].text(dst, redact)
+		}
+		dst.WriteByte(')')
+	}
+	if len(c.PartitionBy) > 0 {
+		dst.WriteByte(' ')
+		partitionsText(c.PartitionBy, dst, redact)
+	}
+	if len(c.SortBy) > 0 {
+		dst.WriteByte(' ')
+		sortByText(c.SortBy, dst, redact)
+	}
+	if c.Retention != nil {
+		dst.WriteByte(' ')
+		c.Retention.text(dst, redact)
+	}
+	for i := range c.Inputs {
+		if i == 0 {
+			dst.WriteString(" FROM ")
+		} else {
+			dst.WriteString(", ")
+		}
+		c.Inputs[i].text(dst, redact)
+	}
+}
+
+func partitionsText(lst []PartitionDef, dst *strings.Builder, redact bool) {
+	dst.WriteString("PARTITION BY (")
+	for i := range lst {
+		if i > 0 {
+			dst.WriteString(", ")
+		}
+		lst[i].text(dst, redact)
+	}
+	dst.WriteByte(')')
+}
+
+func sortByText(lst []Node, dst *strings.Builder, redact bool) {
+	dst.WriteString("SORT BY (")
+	for i := range lst {
+		if i > 0 {
+			dst.WriteString(", ")
+		}
+		lst[i].text(dst, redact)
+	}
+	dst.WriteByte(')')
+}
+
+func checkPaths(what string, lst ...Node) error {
+	for _, p := range lst {
+		if _, ok := FlatPath(p); !ok {
+			return errsyntaxf("%s %s is not a sequence of field names", what, ToString(p))
+		}
+	}
+	return nil
+}
+
+// Check implements DDL.Check
+func (c *CreateTable) Check() error {
+	if c.Table == nil {
+		return fmt.Errorf("CREATE TABLE without a table")
+	}
+	for i := range c.Columns {
+		if err := checkPaths("column", c.Columns[i].Path); err != nil {
+			return err
+		}
+		for j := range c.Columns[:i] {
+			if c.Columns[j].Path.Equals(c.Columns[i].Path) {
+				return errsyntaxf("duplicate column %s", ToString(c.Columns[i].Path))
+			}
+		}
+	}
+	if err := checkPaths("sort key", c.SortBy...); err != nil {
+		return err
+	}
+	if c.Retention != nil {
+		return checkPaths("retention field", c.Retention.Field)
+	}
+	return nil
+}
+
+// AlterTable is a statement of the form
+//
+//	ALTER TABLE table action, ...
+//
+// where each action is one of
+//
+//	SET PARTITION BY (field [type] [AS 'template'], ...)
+//	DROP PARTITION BY
+//	SET SORT BY (path, ...)
+//	DROP SORT BY
+//	SET RETENTION ON path FOR 'window'
+//	DROP RETENTION
+type AlterTable struct {
+	// Table is the table being altered.
+	Table *Table
+	// PartitionBy, if non-nil, replaces the
+	// partitions of the table; a pointer to
+	// an empty list removes the partitions.
+	PartitionBy *[]PartitionDef
+	// SortBy, if non-nil, replaces the
+	// sort keys of the table; a pointer to
+	// an empty list removes the sort keys.
+	SortBy *[]Node
+	// Retention, if non-nil, replaces
+	// the retention policy of the table.
+	Retention *RetentionDef
+	// DropRetention is set if the retention
+	// policy of the table is removed.
+	DropRetention bool
+}
+
+// Text implements DDL.Text
+func (a *AlterTable) Text() string { return ToString(a) }
+
+// Redacted implements DDL.Redacted
+func (a *AlterTable) Redacted() string { return ToRedacted(a) }
+
+func (a *AlterTable) text(dst *strings.Builder, redact bool) {
+	dst.WriteString("ALTER TABLE ")
+	a.Table.Expr.text(dst, redact)
+	sep := " "
+	action := func() {
+		dst.WriteString(sep)
+		sep = ", "
+	}
+	if a.PartitionBy != nil {
+		action()
+		if len(*a.PartitionBy) == 0 {
+			dst.WriteString("DROP PARTITION BY")
+		} else {
+			dst.WriteString("SET ")
+			partitionsText(*a.PartitionBy, dst, redact)
+		}
+	}
+	if a.SortBy != nil {
+		action()
+		if len(*a.SortBy) == 0 {
+			dst.WriteString("DROP SORT BY")
+		} else {
+			dst.WriteString("SET ")
+			sortByText(*a.SortBy, dst, redact)
+		}
+	}
+	if a.Retention != nil {
+		action()
+		dst.WriteString("SET ")
+		a.Retention.text(dst, redact)
+	}
+	if a.DropRetention {
+		action()
+		dst.WriteString("DROP RETENTION")
+	}
+}
+
+// Check implements DDL.Check
+func (a *AlterTable) Check() error {
+	if a.Table == nil {
+		return fmt.Errorf("ALTER TABLE without a table")
+	}
+	if a.PartitionBy == nil && a.SortBy == nil && a.Retention == nil && !a.DropRetention {
+		return errsyntaxf("ALTER TABLE without any changes")
+	}
+	if a.Retention != nil && a.DropRetention {
+		return errsyntaxf("cannot both set and drop the retention policy")
+	}
+	if a.SortBy != nil {
+		if err := checkPaths("sort key", *a.SortBy...); err != nil {
+			return err
+		}
+	}
+	if a.Retention != nil {
+		return checkPaths("retention field", a.Retention.Field)
+	}
+	return nil
+}
+
+// DropTable is a statement of the form
+//
+//	DROP TABLE [IF EXISTS] table
+type DropTable struct {
+	// Table is the table being dropped.
+	Table *Table
+	// IfExists is set if the statement
+	// should succeed when the table
+	// does not exist.
+	IfExists bool
+}
+
+// Text implements DDL.Text
+func (d *DropTable) Text() string { return ToString(d) }
+
+// Redacted implements DDL.Redacted
+func (d *DropTable) Redacted() string { return ToRedacted(d) }
+
+func (d *DropTable) text(dst *strings.Builder, redact bool) {
+	dst.WriteString("DROP TABLE ")
+	if d.IfExists {
+		dst.WriteString("IF EXISTS ")
+	}
+	d.Table.Expr.text(dst, redact)
+}
+
+// Check implements DDL.Check
+func (d *DropTable) Check() error {
+	if d.Table == nil {
+		return fmt.Errorf("DROP TABLE without a table")
+	}
+	return nil
+}
@@ -90,6 +90,8 @@ func getEmpty(name string) (composite, bool) {
 		return &Dot{}, true
 	case "index":
 		return &Index{}, true
+	case "wildcard":
+		return &Wildcard{}, true
 	case "cmp":
 		return &Comparison{}, true
 	case "stringmatch":
@@ -130,6 +132,8 @@ func getEmpty(name string) (composite, bool) {
 		return &List{}, true
 	case "unpivot":
 		return &Unpivot{}, true
+	case "unnest":
+		return &Unnest{}, true
 	case "union":
 		return &Union{}, true
 	default:
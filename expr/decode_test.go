@@ -29,6 +29,7 @@ func TestDecodeFailures(t *testing.T) {
 		"star",
 		"dot",
 		"index",
+		"wildcard",
 		"cmp",
 		"stringmatch",
 		"not",
@@ -0,0 +1,78 @@
+// Copyright (C) 2022 Sneller, Inc.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package expr
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Delete is a DELETE FROM ... WHERE ... statement.
+//
+// Unlike a Query, a Delete is not planned and
+// executed like an ordinary query; it is executed
+// by rewriting the storage backing the table
+// (see db.Config.Delete).
+type Delete struct {
+	// Table is the table from which
+	// rows are deleted.
+	Table *Table
+	// Where is the condition that determines
+	// which rows are deleted. If Where is nil,
+	// every row in the table is deleted.
+	Where Node
+}
+
+// Text returns the unredacted statement text.
+// See also: Redacted.
+func (d *Delete) Text() string {
+	var dst strings.Builder
+	d.text(&dst, false)
+	return dst.String()
+}
+
+// Redacted returns the redacted statement text.
+func (d *Delete) Redacted() string {
+	var dst strings.Builder
+	d.text(&dst, true)
+	return dst.String()
+}
+
+func (d *Delete) text(dst *strings.Builder, redact bool) {
+	dst.WriteString("DELETE FROM ")
+	d.Table.Expr.text(dst, redact)
+	if d.Where != nil {
+		dst.WriteString(" WHERE ")
+		d.Where.text(dst, redact)
+	}
+}
+
+// Check checks the statement for errors.
+func (d *Delete) Check() error {
+	if d.Table == nil {
+		return fmt.Errorf("DELETE without a table")
+	}
+	if d.Where == nil {
+		return nil
+	}
+	err := Check(d.Where)
+	if err != nil {
+		return err
+	}
+	if !TypeOf(d.Where, NoHint).Logical() {
+		return errtype(d.Where, "WHERE clause not a logical expression")
+	}
+	return nil
+}
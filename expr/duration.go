@@ -0,0 +1,142 @@
+// Copyright (C) 2022 Sneller, Inc.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package expr
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FORMAT_DURATION(ms) formats a number of milliseconds
+// as the concatenation of its non-zero hour, minute,
+// second and millisecond components, so 5405250
+// is formatted as '1h30m5s250ms' and 0 as '0ms'.
+//
+// PARSE_DURATION(str) parses a constant duration
+// string in the format accepted by time.ParseDuration
+// (which includes the output of FORMAT_DURATION)
+// and returns the number of milliseconds it represents.
+// There is no bytecode for parsing durations, so
+// PARSE_DURATION is always folded into an integer
+// and non-constant arguments are rejected by the checker.
+
+// durationUnits are the components of
+// a formatted duration, from largest to smallest
+var durationUnits = []struct {
+	ms   int64
+	unit string
+}{
+	{3600000, "h"},
+	{60000, "m"},
+	{1000, "s"},
+	{1, "ms"},
+}
+
+func formatDuration(ms int64) string {
+	if ms == 0 {
+		return "0ms"
+	}
+	var b strings.Builder
+	// negating math.MinInt64 overflows,
+	// so work with the unsigned magnitude
+	abs := uint64(ms)
+	if ms < 0 {
+		b.WriteByte('-')
+		abs = -abs
+	}
+	for i := range durationUnits {
+		unit := uint64(durationUnits[i].ms)
+		n := abs / unit
+		abs %= unit
+		if n != 0 {
+			b.WriteString(strconv.FormatUint(n, 10))
+			b.WriteString(durationUnits[i].unit)
+		}
+	}
+	return b.String()
+}
+
+func simplifyFormatDuration(h Hint, args []Node) Node {
+	if len(args) != 1 {
+		return nil
+	}
+	if i, ok := args[0].(Integer); ok {
+		return String(formatDuration(int64(i)))
+	}
+	ms := args[0]
+	if TypeOf(ms, h)&^(IntegerType|MissingType) != 0 {
+		ms = &Cast{From: ms, To: IntegerType}
+	}
+	// the sign is MISSING if ms is MISSING,
+	// which makes the whole result MISSING
+	ret := Node(&Case{
+		Limbs: []CaseLimb{
+			{When: Compare(Less, ms, Integer(0)), Then: String("-")},
+			{When: Compare(GreaterEquals, ms, Integer(0)), Then: String("")},
+		},
+	})
+	for i := range durationUnits {
+		// the components have the sign of ms;
+		// taking ABS(ms) instead would overflow
+		// when ms is math.MinInt64
+		n := ms
+		if i > 0 {
+			n = Mod(n, Integer(durationUnits[i-1].ms))
+		}
+		if durationUnits[i].ms != 1 {
+			n = Div(n, Integer(durationUnits[i].ms))
+		}
+		n = Call(Abs, n)
+		when := Compare(NotEquals, n, Integer(0))
+		if i == len(durationUnits)-1 {
+			// produce '0ms' rather than ''
+			when = Or(when, Compare(Equals, ms, Integer(0)))
+		}
+		str := Call(Concat, &Cast{From: n, To: StringType}, String(durationUnits[i].unit))
+		ret = Call(Concat, ret, IfThenElse(when, str, String("")))
+	}
+	return Simplify(ret, h)
+}
+
+func checkParseDuration(h Hint, args []Node) error {
+	if len(args) != 1 {
+		return mismatch(1, len(args))
+	}
+	str, ok := args[0].(String)
+	if !ok {
+		return errsyntaxf("%s requires a constant string argument", ParseDuration)
+	}
+	_, err := time.ParseDuration(string(str))
+	if err != nil {
+		return errsyntaxf("%s: %s", ParseDuration, err)
+	}
+	return nil
+}
+
+func simplifyParseDuration(h Hint, args []Node) Node {
+	if len(args) != 1 {
+		return nil
+	}
+	str, ok := args[0].(String)
+	if !ok {
+		return nil
+	}
+	d, err := time.ParseDuration(string(str))
+	if err != nil {
+		return nil
+	}
+	return Integer(d.Round(time.Millisecond).Milliseconds())
+}
@@ -50,5 +50,9 @@ func TestEquals(t *testing.T) {
 		if !tests[i].out.Equals(tests[i].out) {
 			t.Errorf("case %d: %s not equal to itself", i, tests[i].out)
 		}
+		// equal nodes must hash equally
+		if tests[i].in.Hash() != tests[i].out.Hash() {
+			t.Errorf("case %d: %s and %s have different hashes", i, tests[i].in, tests[i].out)
+		}
 	}
 }
@@ -0,0 +1,108 @@
+// Copyright (C) 2022 Sneller, Inc.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package expr
+
+import (
+	"fmt"
+)
+
+// FISCAL_YEAR(ts [, start]) and FISCAL_QUARTER(ts [, start])
+// compute the fiscal year and quarter of a timestamp
+// for a fiscal year that begins on the first day
+// of the month start (from 1 for January to 12 for December).
+// A fiscal year is named after the calendar year in which it ends,
+// so with start = 10 the timestamp 2022-10-01 is in fiscal year 2023.
+//
+// If start is not provided, it is determined by
+// Query.SetFiscalYearStart, or it defaults to January
+// (in which case the fiscal year is the calendar year).
+
+func checkFiscal(op BuiltinOp) func(Hint, []Node) error {
+	return func(h Hint, args []Node) error {
+		if len(args) != 1 && len(args) != 2 {
+			return errsyntaxf("%s expects 1 or 2 arguments, but found %d", op, len(args))
+		}
+		if !TypeOf(args[0], h).AnyOf(TimeType) {
+			return errtype(args[0], "not a timestamp")
+		}
+		if len(args) == 2 {
+			m, ok := args[1].(Integer)
+			if !ok || m < 1 || m > 12 {
+				return errsyntaxf("%s requires a constant month between 1 and 12 as its second argument", op)
+			}
+		}
+		return nil
+	}
+}
+
+// simplifyFiscal lowers FISCAL_YEAR and FISCAL_QUARTER
+// into the extraction of the calendar year or quarter
+// of the timestamp shifted forward by the number of
+// months between the start of the fiscal year and
+// the following January.
+func simplifyFiscal(part Timepart) func(Hint, []Node) Node {
+	return func(h Hint, args []Node) Node {
+		start := Integer(1)
+		switch len(args) {
+		case 1:
+		case 2:
+			m, ok := args[1].(Integer)
+			if !ok || m < 1 || m > 12 {
+				return nil
+			}
+			start = m
+		default:
+			return nil
+		}
+		// truncate to the first day of the month
+		// so that adding months never overflows
+		// into the following month
+		shifted := DateTrunc(Month, args[0])
+		if months := (13 - start) % 12; months != 0 {
+			shifted = DateAdd(Month, months, shifted)
+		}
+		return Simplify(DateExtract(part, shifted), h)
+	}
+}
+
+type fiscalRewriter struct {
+	start int
+}
+
+func (f *fiscalRewriter) Walk(Node) Rewriter { return f }
+
+func (f *fiscalRewriter) Rewrite(n Node) Node {
+	b, ok := n.(*Builtin)
+	if !ok || (b.Func != FiscalYear && b.Func != FiscalQuarter) || len(b.Args) != 1 {
+		return n
+	}
+	return Call(b.Func, b.Args[0], Integer(f.start))
+}
+
+// SetFiscalYearStart sets the first month of the fiscal
+// year (from 1 for January to 12 for December) used by every
+// FISCAL_YEAR and FISCAL_QUARTER call in q that does not
+// specify it explicitly.
+func (q *Query) SetFiscalYearStart(month int) error {
+	if month < 1 || month > 12 {
+		return fmt.Errorf("invalid fiscal year start month %d", month)
+	}
+	r := &fiscalRewriter{start: month}
+	for i := range q.With {
+		q.With[i].As = Rewrite(r, q.With[i].As).(*Select)
+	}
+	q.Body = Rewrite(r, q.Body)
+	return nil
+}
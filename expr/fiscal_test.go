@@ -0,0 +1,118 @@
+// Copyright (C) 2022 Sneller, Inc.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package expr_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/SnellerInc/sneller/date"
+	"github.com/SnellerInc/sneller/expr"
+	"github.com/SnellerInc/sneller/expr/partiql"
+)
+
+func TestFiscalCalendar(t *testing.T) {
+	// every day of 2022 and 2023 for every start month
+	for start := 1; start <= 12; start++ {
+		day := time.Date(2022, 1, 1, 12, 0, 0, 0, time.UTC)
+		for day.Year() < 2024 {
+			// months since the start of the fiscal year
+			// that ends in the calendar year 'want'
+			want := day.Year()
+			if start > 1 && int(day.Month()) >= start {
+				want++
+			}
+			elapsed := (int(day.Month()) - start + 12) % 12
+			ts := &expr.Timestamp{Value: date.FromTime(day)}
+			got := expr.Simplify(expr.Call(expr.FiscalYear, ts, expr.Integer(start)), expr.NoHint)
+			if got != expr.Integer(want) {
+				t.Fatalf("FISCAL_YEAR(%s, %d): got %s, want %d", day.Format("2006-01-02"), start, expr.ToString(got), want)
+			}
+			got = expr.Simplify(expr.Call(expr.FiscalQuarter, ts, expr.Integer(start)), expr.NoHint)
+			if got != expr.Integer(elapsed/3+1) {
+				t.Fatalf("FISCAL_QUARTER(%s, %d): got %s, want %d", day.Format("2006-01-02"), start, expr.ToString(got), elapsed/3+1)
+			}
+			day = day.AddDate(0, 0, 1)
+		}
+	}
+}
+
+func TestSetFiscalYearStart(t *testing.T) {
+	testcases := []struct {
+		query, want string
+	}{
+		{
+			"SELECT FISCAL_YEAR(x), FISCAL_QUARTER(x, 7) FROM t",
+			"SELECT FISCAL_YEAR(x, 4), FISCAL_QUARTER(x, 7) FROM t",
+		},
+		{
+			"WITH a AS (SELECT FISCAL_QUARTER(x) AS q, y FROM t) SELECT * FROM a WHERE FISCAL_YEAR(y) = 2022",
+			"WITH a AS (SELECT FISCAL_QUARTER(x, 4) AS q, y FROM t) SELECT * FROM a WHERE FISCAL_YEAR(y, 4) = 2022",
+		},
+	}
+	for i := range testcases {
+		q, err := partiql.Parse([]byte(testcases[i].query))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := q.SetFiscalYearStart(4); err != nil {
+			t.Fatal(err)
+		}
+		if err := q.Check(); err != nil {
+			t.Fatal(err)
+		}
+		if got := expr.ToString(q); got != testcases[i].want {
+			t.Errorf("got  %s\nwant %s", got, testcases[i].want)
+		}
+	}
+	q, err := partiql.Parse([]byte("SELECT FISCAL_YEAR(x) FROM t"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := q.SetFiscalYearStart(13); err == nil {
+		t.Fatal("expected an error")
+	}
+}
+
+func TestFiscalCheck(t *testing.T) {
+	bad := []string{
+		"SELECT FISCAL_YEAR() FROM t",
+		"SELECT FISCAL_YEAR(x, 0) FROM t",
+		"SELECT FISCAL_YEAR(x, 13) FROM t",
+		"SELECT FISCAL_QUARTER(x, y) FROM t",
+		"SELECT FISCAL_QUARTER('foo') FROM t",
+		"SELECT FISCAL_QUARTER(x, 4, 5) FROM t",
+	}
+	for i := range bad {
+		q, err := partiql.Parse([]byte(bad[i]))
+		if err == nil {
+			err = q.Check()
+		}
+		if err == nil {
+			t.Errorf("%s: expected an error", bad[i])
+		}
+	}
+	for start := 1; start <= 12; start++ {
+		text := fmt.Sprintf("SELECT FISCAL_YEAR(x, %d), FISCAL_QUARTER(x, %d) FROM t", start, start)
+		q, err := partiql.Parse([]byte(text))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := q.Check(); err != nil {
+			t.Errorf("%s: %s", text, err)
+		}
+	}
+}
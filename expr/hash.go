@@ -0,0 +1,365 @@
+// Copyright (C) 2022 Sneller, Inc.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package expr
+
+import (
+	"hash/maphash"
+	"math"
+	"math/big"
+)
+
+// Structural hashing
+//
+// Every Node implements Hash, which returns a
+// 64-bit hash of the node and all of its children.
+// Hash is consistent with Equals: if a.Equals(b),
+// then a.Hash() == b.Hash(). (The converse does
+// not hold; some node types only hash a subset
+// of the fields that Equals compares.)
+//
+// Hashes are only meaningful within a single
+// process and must not be persisted.
+
+// hash tags distinguish node types
+const (
+	tagNumber uint64 = iota + 1
+	tagBool
+	tagString
+	tagIdent
+	tagDot
+	tagIndex
+	tagWildcard
+	tagStar
+	tagMissing
+	tagNull
+	tagMember
+	tagLookup
+	tagComparison
+	tagStringMatch
+	tagNot
+	tagLogical
+	tagBuiltin
+	tagUnaryArith
+	tagArithmetic
+	tagAppended
+	tagIsKey
+	tagCase
+	tagCast
+	tagTimestamp
+	tagStruct
+	tagList
+	tagUnpivot
+	tagUnnest
+	tagUnion
+	tagAggregate
+	tagTable
+	tagJoin
+	tagSelect
+	tagNil
+)
+
+var hashSeed = maphash.MakeSeed()
+
+// hashMix mixes v into the hash h
+func hashMix(h, v uint64) uint64 {
+	h ^= v
+	h *= 0x9e3779b97f4a7c15
+	return h ^ (h >> 32)
+}
+
+func hashString(h uint64, s string) uint64 {
+	return hashMix(h, maphash.String(hashSeed, s))
+}
+
+// hashNode is Hash with support for nil nodes
+func hashNode(h uint64, n Node) uint64 {
+	if n == nil {
+		return hashMix(h, tagNil)
+	}
+	return hashMix(h, n.Hash())
+}
+
+func hashNodes(h uint64, lst []Node) uint64 {
+	h = hashMix(h, uint64(len(lst)))
+	for i := range lst {
+		h = hashNode(h, lst[i])
+	}
+	return h
+}
+
+// hashNumber hashes a numeric constant
+//
+// Integer, Float and Rational constants compare
+// equal when their values are (nearly) equal,
+// so they all hash their float64 value. Since
+// Rational.Equals tolerates a difference of up
+// to 1e-16, all values with a magnitude below 1
+// (where that tolerance spans more than one
+// float64) share the same hash.
+func hashNumber(f float64) uint64 {
+	if math.Abs(f) < 1 {
+		return hashMix(tagNumber, 0)
+	}
+	return hashMix(tagNumber, math.Float64bits(f))
+}
+
+// Hash implements Node.Hash
+func (i Integer) Hash() uint64 { return hashNumber(float64(int64(i))) }
+
+// Hash implements Node.Hash
+func (f Float) Hash() uint64 { return hashNumber(float64(f)) }
+
+// Hash implements Node.Hash
+func (r *Rational) Hash() uint64 {
+	f, _ := (*big.Rat)(r).Float64()
+	return hashNumber(f)
+}
+
+// Hash implements Node.Hash
+func (b Bool) Hash() uint64 {
+	if b {
+		return hashMix(tagBool, 1)
+	}
+	return hashMix(tagBool, 0)
+}
+
+// Hash implements Node.Hash
+func (s String) Hash() uint64 { return hashString(tagString, string(s)) }
+
+// Hash implements Node.Hash
+func (i Ident) Hash() uint64 { return hashString(tagIdent, string(i)) }
+
+// Hash implements Node.Hash
+func (d *Dot) Hash() uint64 { return hashString(hashNode(tagDot, d.Inner), d.Field) }
+
+// Hash implements Node.Hash
+func (i *Index) Hash() uint64 { return hashMix(hashNode(tagIndex, i.Inner), uint64(i.Offset)) }
+
+// Hash implements Node.Hash
+func (w *Wildcard) Hash() uint64 {
+	h := hashNode(tagWildcard, w.Inner)
+	if w.Struct {
+		h = hashMix(h, 1)
+	}
+	return h
+}
+
+// Hash implements Node.Hash
+func (s Star) Hash() uint64 { return hashMix(tagStar, 0) }
+
+// Hash implements Node.Hash
+func (m Missing) Hash() uint64 { return hashMix(tagMissing, 0) }
+
+// Hash implements Node.Hash
+func (n Null) Hash() uint64 { return hashMix(tagNull, 0) }
+
+// Hash implements Node.Hash
+func (m *Member) Hash() uint64 { return hashMix(hashNode(tagMember, m.Arg), uint64(m.Set.Len())) }
+
+// Hash implements Node.Hash
+func (l *Lookup) Hash() uint64 {
+	h := hashNode(tagLookup, l.Expr)
+	h = hashNode(h, l.Else)
+	return hashMix(h, uint64(l.Keys.Len()))
+}
+
+// Hash implements Node.Hash
+func (c *Comparison) Hash() uint64 {
+	h := hashMix(tagComparison, uint64(c.Op))
+	return hashNode(hashNode(h, c.Left), c.Right)
+}
+
+// Hash implements Node.Hash
+func (s *StringMatch) Hash() uint64 {
+	h := hashMix(tagStringMatch, uint64(s.Op))
+	h = hashNode(h, s.Expr)
+	return hashString(hashString(h, s.Pattern), s.Escape)
+}
+
+// Hash implements Node.Hash
+func (n *Not) Hash() uint64 { return hashNode(tagNot, n.Expr) }
+
+// Hash implements Node.Hash
+func (l *Logical) Hash() uint64 {
+	h := hashMix(tagLogical, uint64(l.Op))
+	return hashNode(hashNode(h, l.Left), l.Right)
+}
+
+// Hash implements Node.Hash
+func (b *Builtin) Hash() uint64 { return hashNodes(hashMix(tagBuiltin, uint64(b.Func)), b.Args) }
+
+// Hash implements Node.Hash
+func (u *UnaryArith) Hash() uint64 { return hashNode(hashMix(tagUnaryArith, uint64(u.Op)), u.Child) }
+
+// Hash implements Node.Hash
+func (a *Arithmetic) Hash() uint64 {
+	h := hashMix(tagArithmetic, uint64(a.Op))
+	return hashNode(hashNode(h, a.Left), a.Right)
+}
+
+// Hash implements Node.Hash
+func (a *Appended) Hash() uint64 { return hashNodes(tagAppended, a.Values) }
+
+// Hash implements Node.Hash
+func (i *IsKey) Hash() uint64 { return hashNode(hashMix(tagIsKey, uint64(i.Key)), i.Expr) }
+
+// Hash implements Node.Hash
+func (c *Case) Hash() uint64 {
+	h := hashMix(tagCase, uint64(len(c.Limbs)))
+	for i := range c.Limbs {
+		h = hashNode(h, c.Limbs[i].When)
+		h = hashNode(h, c.Limbs[i].Then)
+	}
+	return hashNode(h, c.Else)
+}
+
+// Hash implements Node.Hash
+func (c *Cast) Hash() uint64 { return hashNode(hashMix(tagCast, uint64(c.To)), c.From) }
+
+// Hash implements Node.Hash
+func (t *Timestamp) Hash() uint64 { return hashMix(tagTimestamp, uint64(t.Value.UnixNano())) }
+
+// Hash implements Node.Hash
+func (s *Struct) Hash() uint64 {
+	// fields are unordered, so combine
+	// them with a commutative operation
+	var sum uint64
+	for i := range s.Fields {
+		sum += hashNode(hashString(tagStruct, s.Fields[i].Label), s.Fields[i].Value)
+	}
+	return hashMix(hashMix(tagStruct, uint64(len(s.Fields))), sum)
+}
+
+// Hash implements Node.Hash
+func (l *List) Hash() uint64 {
+	h := hashMix(tagList, uint64(len(l.Values)))
+	for i := range l.Values {
+		h = hashNode(h, l.Values[i])
+	}
+	return h
+}
+
+// Hash implements Node.Hash
+func (u *Unpivot) Hash() uint64 { return hashNode(tagUnpivot, u.TupleRef) }
+
+// Hash implements Node.Hash
+func (u *Unnest) Hash() uint64 {
+	h := hashMix(tagUnnest, uint64(len(u.Values)))
+	for i := range u.Values {
+		h = hashNode(h, u.Values[i].Expr)
+	}
+	return h
+}
+
+// Hash implements Node.Hash
+func (u *Union) Hash() uint64 {
+	h := hashMix(tagUnion, uint64(u.Type))
+	return hashNode(hashNode(h, u.Left), u.Right)
+}
+
+// Hash implements Node.Hash
+func (a *Aggregate) Hash() uint64 {
+	h := hashMix(tagAggregate, uint64(a.Op))
+	h = hashMix(h, uint64(a.Precision))
+	h = hashMix(h, uint64(a.Datashape))
+	h = hashNode(h, a.Inner)
+	h = hashNode(h, a.Filter)
+	if a.Over != nil {
+		h = hashNodes(h, a.Over.PartitionBy)
+		for i := range a.Over.OrderBy {
+			h = hashNode(h, a.Over.OrderBy[i].Column)
+		}
+	}
+	return h
+}
+
+// Hash implements Node.Hash
+func (t *Table) Hash() uint64 { return hashNode(tagTable, t.Expr) }
+
+// Hash implements Node.Hash
+func (j *Join) Hash() uint64 {
+	h := hashMix(tagJoin, uint64(j.Kind))
+	h = hashNode(h, j.On)
+	h = hashNode(h, j.Left)
+	return hashNode(h, j.Right.Expr)
+}
+
+// Hash implements Node.Hash
+func (s *Select) Hash() uint64 {
+	h := hashMix(tagSelect, uint64(len(s.Columns)))
+	for i := range s.Columns {
+		h = hashNode(h, s.Columns[i].Expr)
+	}
+	if s.From != nil {
+		h = hashNode(h, s.From)
+	}
+	return hashNode(h, s.Where)
+}
+
+// HashCache memoizes the structural
+// hashes of nodes. Since nodes may be
+// modified in place, a HashCache should
+// only be used while the nodes that it
+// has hashed are not being modified.
+//
+// The zero value of HashCache is ready to use.
+type HashCache struct {
+	memo map[Node]uint64
+}
+
+// Hash returns n.Hash(), re-using
+// the result of a previous call if
+// n is a pointer node that has
+// already been hashed.
+func (c *HashCache) Hash(n Node) uint64 {
+	if !cacheable(n) {
+		return n.Hash()
+	}
+	if h, ok := c.memo[n]; ok {
+		return h
+	}
+	h := n.Hash()
+	if c.memo == nil {
+		c.memo = make(map[Node]uint64)
+	}
+	c.memo[n] = h
+	return h
+}
+
+// Equivalent is equivalent to Equivalent(a, b),
+// but it compares the (cached) hashes of a and b
+// before performing a deep comparison.
+func (c *HashCache) Equivalent(a, b Node) bool {
+	if a == b {
+		return true
+	}
+	return c.Hash(a) == c.Hash(b) && a.Equals(b)
+}
+
+// cacheable returns whether n can
+// be used as a key in a HashCache;
+// value nodes are cheap to hash and
+// may not be comparable
+func cacheable(n Node) bool {
+	switch n.(type) {
+	case *Comparison, *Logical, *Builtin, *Arithmetic,
+		*Not, *StringMatch, *Member, *Case, *Cast,
+		*IsKey, *Dot, *Index, *UnaryArith, *Aggregate,
+		*Appended, *Lookup, *Struct, *List, *Select:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,84 @@
+// Copyright (C) 2022 Sneller, Inc.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package expr
+
+import (
+	"testing"
+)
+
+func TestHash(t *testing.T) {
+	equal := []struct {
+		a, b Node
+	}{
+		{Add(path("x"), Integer(1)), Add(path("x"), Float(1.0))},
+		{
+			&Struct{Fields: []Field{{"a", Integer(1)}, {"b", String("x")}}},
+			&Struct{Fields: []Field{{"b", String("x")}, {"a", Integer(1)}}},
+		},
+		{
+			Call(Upper, Call(Lower, path("x.y"))),
+			Call(Upper, Call(Lower, path("x.y"))),
+		},
+	}
+	for i := range equal {
+		a, b := equal[i].a, equal[i].b
+		if !a.Equals(b) {
+			t.Fatalf("case %d: %s != %s", i, a, b)
+		}
+		if a.Hash() != b.Hash() {
+			t.Errorf("case %d: %s and %s have different hashes", i, a, b)
+		}
+	}
+
+	// these are not required to differ,
+	// but a collision here would indicate
+	// a hash that ignores important fields
+	different := []struct {
+		a, b Node
+	}{
+		{Add(path("x"), path("y")), Sub(path("x"), path("y"))},
+		{Add(path("x"), path("y")), Add(path("y"), path("x"))},
+		{path("x"), path("y")},
+		{path("x.y"), path("x.z")},
+		{Integer(1), Integer(2)},
+		{String("1"), Integer(1)},
+		{Count(path("x")), Sum(path("x"))},
+		{Call(Upper, path("x")), Call(Lower, path("x"))},
+	}
+	for i := range different {
+		a, b := different[i].a, different[i].b
+		if a.Hash() == b.Hash() {
+			t.Errorf("case %d: %s and %s have the same hash", i, a, b)
+		}
+	}
+}
+
+func TestHashCache(t *testing.T) {
+	var c HashCache
+	a := Add(path("x"), Integer(1))
+	b := Add(path("x"), Integer(1))
+	if !c.Equivalent(a, b) {
+		t.Fatalf("%s not equivalent to %s", a, b)
+	}
+	if c.Equivalent(a, Add(path("x"), Integer(2))) {
+		t.Fatal("unexpected equivalence")
+	}
+	if c.Hash(a) != a.Hash() {
+		t.Fatal("cached hash differs")
+	}
+	if len(c.memo) != 3 {
+		t.Errorf("expected 3 cached hashes; got %d", len(c.memo))
+	}
+}
@@ -0,0 +1,181 @@
+// Copyright (C) 2022 Sneller, Inc.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package expr
+
+import (
+	"fmt"
+)
+
+// LimitKind identifies the limit
+// that was exceeded by a query.
+type LimitKind int
+
+const (
+	// LimitText is the limit on
+	// the size of the query text in bytes.
+	LimitText LimitKind = iota
+	// LimitNodes is the limit on the
+	// number of nodes in the query AST.
+	LimitNodes
+	// LimitDepth is the limit on the
+	// nesting depth of the query AST.
+	LimitDepth
+	// LimitSubqueries is the limit on the
+	// number of subqueries in the query.
+	LimitSubqueries
+)
+
+// String returns the error code
+// associated with the limit.
+func (k LimitKind) String() string {
+	switch k {
+	case LimitText:
+		return "query_too_large"
+	case LimitNodes:
+		return "too_many_nodes"
+	case LimitDepth:
+		return "expression_too_deep"
+	case LimitSubqueries:
+		return "too_many_subqueries"
+	default:
+		return fmt.Sprintf("LimitKind(%d)", int(k))
+	}
+}
+
+func (k LimitKind) describe() string {
+	switch k {
+	case LimitText:
+		return "query text size"
+	case LimitNodes:
+		return "number of expressions"
+	case LimitDepth:
+		return "expression depth"
+	case LimitSubqueries:
+		return "number of subqueries"
+	default:
+		return k.String()
+	}
+}
+
+// LimitError is the error type
+// returned from Limits.Check and
+// Limits.CheckText when a query
+// exceeds one of the configured limits.
+type LimitError struct {
+	Kind LimitKind
+	// Limit is the configured limit and
+	// Actual is the value that exceeded it.
+	// Actual may be a lower bound on the
+	// true value, since checking stops
+	// as soon as the limit is exceeded.
+	Limit, Actual int
+}
+
+// Error implements error
+func (l *LimitError) Error() string {
+	return fmt.Sprintf("query exceeds the maximum %s (%d > %d)", l.Kind.describe(), l.Actual, l.Limit)
+}
+
+// Limits describes the maximum complexity
+// of a query. A zero value for any of the
+// fields means that there is no limit.
+type Limits struct {
+	// MaxTextSize is the maximum size
+	// of the query text in bytes.
+	MaxTextSize int
+	// MaxNodes is the maximum number
+	// of nodes in the query AST.
+	MaxNodes int
+	// MaxDepth is the maximum nesting
+	// depth of the query AST.
+	MaxDepth int
+	// MaxSubqueries is the maximum number of
+	// SELECT expressions in the query other than
+	// the outermost one, including common table
+	// expressions and the arms of a UNION.
+	MaxSubqueries int
+}
+
+// CheckText checks the size of
+// the query text against l.MaxTextSize.
+// CheckText should be called before the
+// query text is parsed.
+func (l *Limits) CheckText(text []byte) error {
+	if l.MaxTextSize > 0 && len(text) > l.MaxTextSize {
+		return &LimitError{Kind: LimitText, Limit: l.MaxTextSize, Actual: len(text)}
+	}
+	return nil
+}
+
+// Check checks the complexity of q against l.
+func (l *Limits) Check(q *Query) error {
+	c := &complexity{limits: l, selects: -1}
+	if _, ok := q.Body.(*Select); !ok {
+		// the arms of a UNION are all subqueries
+		c.selects = 0
+	}
+	for i := range q.With {
+		if c.walk(q.With[i].As, 1) {
+			return c.err
+		}
+	}
+	c.walk(q.Body, 1)
+	return c.err
+}
+
+// complexity measures the complexity of a query
+type complexity struct {
+	limits  *Limits
+	nodes   int
+	selects int
+	err     error
+}
+
+func (c *complexity) exceeds(kind LimitKind, limit, actual int) bool {
+	if limit > 0 && actual > limit {
+		c.err = &LimitError{Kind: kind, Limit: limit, Actual: actual}
+		return true
+	}
+	return false
+}
+
+// walk walks n at the given depth and
+// returns true if a limit was exceeded
+func (c *complexity) walk(n Node, depth int) bool {
+	if c.err != nil {
+		return true
+	}
+	if c.exceeds(LimitDepth, c.limits.MaxDepth, depth) {
+		return true
+	}
+	c.nodes++
+	if c.exceeds(LimitNodes, c.limits.MaxNodes, c.nodes) {
+		return true
+	}
+	if _, ok := n.(*Select); ok {
+		c.selects++
+		if c.exceeds(LimitSubqueries, c.limits.MaxSubqueries, c.selects) {
+			return true
+		}
+	}
+	n.walk(WalkFunc(func(child Node) bool {
+		if child != nil {
+			c.walk(child, depth+1)
+		}
+		// children are walked explicitly
+		return false
+	}))
+	return c.err != nil
+}
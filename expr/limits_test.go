@@ -0,0 +1,111 @@
+// Copyright (C) 2022 Sneller, Inc.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package expr_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/SnellerInc/sneller/expr"
+	"github.com/SnellerInc/sneller/expr/partiql"
+)
+
+func TestLimits(t *testing.T) {
+	deep := "SELECT * FROM t WHERE " + strings.Repeat("(", 40) + "x" + strings.Repeat(")", 40) + " = 1"
+	testcases := []struct {
+		query  string
+		limits expr.Limits
+		kind   expr.LimitKind // -1 for no error
+	}{
+		{
+			query:  "SELECT x, y FROM t WHERE x < 3",
+			limits: expr.Limits{MaxNodes: 20, MaxDepth: 5, MaxSubqueries: 0},
+			kind:   -1,
+		},
+		{
+			query:  "SELECT x, y FROM t WHERE x < 3",
+			limits: expr.Limits{MaxNodes: 3},
+			kind:   expr.LimitNodes,
+		},
+		{
+			query:  "SELECT x FROM t WHERE x = 1 + 2 * 3 - 4",
+			limits: expr.Limits{MaxDepth: 3},
+			kind:   expr.LimitDepth,
+		},
+		{
+			// parentheses do not produce AST nodes
+			query:  deep,
+			limits: expr.Limits{MaxDepth: 5},
+			kind:   -1,
+		},
+		{
+			query:  "SELECT * FROM (SELECT * FROM t) WHERE x IN (SELECT y FROM u)",
+			limits: expr.Limits{MaxSubqueries: 2},
+			kind:   -1,
+		},
+		{
+			query:  "SELECT * FROM (SELECT * FROM t) WHERE x IN (SELECT y FROM u)",
+			limits: expr.Limits{MaxSubqueries: 1},
+			kind:   expr.LimitSubqueries,
+		},
+		{
+			query:  "WITH a AS (SELECT * FROM t) SELECT * FROM a",
+			limits: expr.Limits{MaxSubqueries: 0},
+			kind:   -1, // zero means unlimited
+		},
+		{
+			query:  "WITH a AS (SELECT * FROM t) SELECT * FROM a",
+			limits: expr.Limits{MaxSubqueries: 1},
+			kind:   -1,
+		},
+		{
+			query:  "SELECT x FROM t UNION ALL SELECT y FROM u",
+			limits: expr.Limits{MaxSubqueries: 1},
+			kind:   expr.LimitSubqueries,
+		},
+	}
+	for i := range testcases {
+		q, err := partiql.Parse([]byte(testcases[i].query))
+		if err != nil {
+			t.Fatalf("%s: %s", testcases[i].query, err)
+		}
+		err = testcases[i].limits.Check(q)
+		if testcases[i].kind < 0 {
+			if err != nil {
+				t.Errorf("case %d: unexpected error %s", i, err)
+			}
+			continue
+		}
+		var lerr *expr.LimitError
+		if !errors.As(err, &lerr) {
+			t.Errorf("case %d: expected a LimitError; got %v", i, err)
+			continue
+		}
+		if lerr.Kind != testcases[i].kind {
+			t.Errorf("case %d: got %s, want %s", i, lerr.Kind, testcases[i].kind)
+		}
+	}
+
+	l := expr.Limits{MaxTextSize: 10}
+	if err := l.CheckText([]byte("SELECT 1")); err != nil {
+		t.Error(err)
+	}
+	err := l.CheckText([]byte("SELECT * FROM t"))
+	var lerr *expr.LimitError
+	if !errors.As(err, &lerr) || lerr.Kind != expr.LimitText || lerr.Actual != 15 {
+		t.Errorf("unexpected CheckText result %v", err)
+	}
+}
@@ -0,0 +1,318 @@
+// Copyright (C) 2022 Sneller, Inc.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// Package lint implements checks for
+// queries that are valid but that likely
+// do not do what the author intended or
+// that cannot be executed efficiently.
+package lint
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/SnellerInc/sneller/expr"
+
+	"golang.org/x/exp/slices"
+)
+
+const (
+	// NonSargableTime is the rule that flags comparisons
+	// between constants and functions of timestamp fields
+	// (e.g. DATE_TRUNC(DAY, ts) = `2022-01-01T00:00:00Z`),
+	// which prevent the query planner from using the
+	// sparse index to skip data.
+	NonSargableTime = "non-sargable-time-filter"
+	// SelectStar is the rule that flags a sub-query
+	// or CTE that uses SELECT * when the enclosing
+	// query only uses some of the fields.
+	SelectStar = "select-star"
+	// CrossProduct is the rule that flags joins
+	// between two tables that have no join condition.
+	CrossProduct = "cross-product"
+)
+
+// Warning is a single problem
+// detected in a query.
+type Warning struct {
+	// Rule is the name of the rule
+	// that produced the warning.
+	Rule string
+	// Message describes the problem.
+	Message string
+	// Pos is the position of the
+	// offending expression, if known.
+	Pos expr.Position
+}
+
+func (w *Warning) String() string {
+	if w.Pos.IsValid() {
+		return fmt.Sprintf("%s: %s (at %s)", w.Rule, w.Message, w.Pos)
+	}
+	return w.Rule + ": " + w.Message
+}
+
+type linter struct {
+	q   *expr.Query
+	out []Warning
+	// ctes maps CTE names
+	// to their definitions
+	ctes map[string]*expr.Select
+}
+
+func (l *linter) warn(rule string, at expr.Node, f string, args ...any) {
+	pos, _ := l.q.Positions.Of(at)
+	l.out = append(l.out, Warning{
+		Rule:    rule,
+		Message: fmt.Sprintf(f, args...),
+		Pos:     pos,
+	})
+}
+
+// Check returns the list of warnings for q.
+// Check expects a query that has been
+// successfully parsed and checked; it
+// never modifies q.
+func Check(q *expr.Query) []Warning {
+	l := &linter{q: q}
+	var sels []*expr.Select
+	collect := expr.WalkFunc(func(e expr.Node) bool {
+		if s, ok := e.(*expr.Select); ok {
+			sels = append(sels, s)
+		}
+		return true
+	})
+	for i := range q.With {
+		if l.ctes == nil {
+			l.ctes = make(map[string]*expr.Select)
+		}
+		l.ctes[q.With[i].Table] = q.With[i].As
+		expr.Walk(collect, q.With[i].As)
+	}
+	expr.Walk(collect, q.Body)
+	for _, s := range sels {
+		l.sargable(s)
+		l.star(s)
+		l.cross(s)
+	}
+	return l.out
+}
+
+// timeArg returns the timestamp
+// argument of a date/time function
+func timeArg(b *expr.Builtin) expr.Node {
+	switch {
+	case b.Func.IsDateAdd():
+		if len(b.Args) == 2 {
+			return b.Args[1]
+		}
+	case b.Func.IsDateTrunc(), b.Func.IsDateExtract(),
+		b.Func == expr.ToUnixEpoch, b.Func == expr.ToUnixMicro:
+		if len(b.Args) > 0 {
+			return b.Args[0]
+		}
+	}
+	return nil
+}
+
+// conjunctions returns the
+// terms of an AND expression
+func conjunctions(e expr.Node, lst []expr.Node) []expr.Node {
+	if l, ok := e.(*expr.Logical); ok && l.Op == expr.OpAnd {
+		return conjunctions(l.Right, conjunctions(l.Left, lst))
+	}
+	return append(lst, e)
+}
+
+func (l *linter) sargable(s *expr.Select) {
+	if s.Where == nil {
+		return
+	}
+	check := func(fn, other expr.Node) {
+		b, ok := fn.(*expr.Builtin)
+		if !ok {
+			return
+		}
+		if _, ok := other.(expr.Constant); !ok {
+			return
+		}
+		arg := timeArg(b)
+		if arg == nil || !expr.IsPath(arg) {
+			return
+		}
+		l.warn(NonSargableTime, arg,
+			"the filter %s cannot use the sparse index for %s; compare %[2]s directly to a range of timestamps instead",
+			expr.ToString(b), expr.ToString(arg))
+	}
+	for _, e := range conjunctions(s.Where, nil) {
+		c, ok := e.(*expr.Comparison)
+		if !ok {
+			continue
+		}
+		check(c.Left, c.Right)
+		check(c.Right, c.Left)
+	}
+}
+
+// fields returns the fields of the table bound
+// to alias that are referenced by the expressions
+// in s, or false if the whole row is referenced
+func fields(s *expr.Select, alias string) ([]string, bool) {
+	var out []string
+	whole := false
+	var visit expr.WalkFunc
+	visit = func(e expr.Node) bool {
+		switch e := e.(type) {
+		case *expr.Select, *expr.Table, *expr.Join:
+			// sub-queries and the FROM
+			// clause have their own scope
+			return false
+		case *expr.Aggregate:
+			// COUNT(*) doesn't use any fields
+			if e.Inner == (expr.Star{}) {
+				if e.Filter != nil {
+					expr.Walk(visit, e.Filter)
+				}
+				return false
+			}
+		case expr.Star:
+			whole = true
+		case *expr.Dot:
+			if e.Inner == expr.Ident(alias) {
+				out = append(out, e.Field)
+				return false
+			}
+		case expr.Ident:
+			if string(e) == alias {
+				whole = true
+			} else {
+				out = append(out, string(e))
+			}
+		}
+		return !whole
+	}
+	var nodes []expr.Node
+	nodes = append(nodes, expr.BindingValues(s.Columns)...)
+	nodes = append(nodes, s.DistinctExpr...)
+	nodes = append(nodes, s.Where, s.Having, s.Qualify)
+	nodes = append(nodes, expr.BindingValues(s.GroupBy)...)
+	for i := range s.OrderBy {
+		nodes = append(nodes, s.OrderBy[i].Column)
+	}
+	for _, n := range nodes {
+		if n != nil && !whole {
+			expr.Walk(visit, n)
+		}
+	}
+	if whole {
+		return nil, false
+	}
+	slices.Sort(out)
+	return slices.Compact(out), true
+}
+
+func isStar(s *expr.Select) bool {
+	return len(s.Columns) == 1 && s.Columns[0].Expr == expr.Star{}
+}
+
+func (l *linter) star(s *expr.Select) {
+	t, ok := s.From.(*expr.Table)
+	if !ok {
+		return
+	}
+	var inner *expr.Select
+	var what string
+	switch e := t.Expr.(type) {
+	case *expr.Select:
+		inner, what = e, "sub-query"
+	case expr.Ident:
+		inner, what = l.ctes[string(e)], fmt.Sprintf("WITH %s", string(e))
+	}
+	if inner == nil || !isStar(inner) {
+		return
+	}
+	lst, ok := fields(s, t.Result())
+	if !ok || len(lst) == 0 {
+		return
+	}
+	l.warn(SelectStar, inner.From,
+		"%s uses SELECT * but only the fields %s are used; select them explicitly",
+		what, strings.Join(lst, ", "))
+}
+
+// refs returns whether e
+// references the binding name
+func refs(e expr.Node, name string) bool {
+	found := false
+	expr.Walk(expr.WalkFunc(func(e expr.Node) bool {
+		if e == expr.Ident(name) {
+			found = true
+		}
+		return !found
+	}), e)
+	return found
+}
+
+// joined returns whether where contains
+// a comparison that references both a
+// binding in left and the binding right
+func joined(where expr.Node, left []expr.Binding, right string) bool {
+	if where == nil {
+		return false
+	}
+	for _, e := range conjunctions(where, nil) {
+		c, ok := e.(*expr.Comparison)
+		if !ok || !refs(c, right) {
+			continue
+		}
+		for i := range left {
+			if refs(c, left[i].Result()) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (l *linter) cross(s *expr.Select) {
+	j, _ := s.From.(*expr.Join)
+	for ; j != nil; j, _ = j.Left.(*expr.Join) {
+		if j.Kind == expr.CrossJoin && !iterates(j) && !joined(s.Where, j.Left.Tables(), j.Right.Result()) {
+			l.warn(CrossProduct, j.Right.Expr,
+				"%s is joined without a join condition, which produces the cross product of the tables",
+				expr.ToString(j.Right.Expr))
+		}
+	}
+}
+
+// iterates returns whether the right-hand-side
+// of j iterates over the left-hand-side
+// (e.g. FROM t, t.list AS x) rather than
+// producing a cross product
+func iterates(j *expr.Join) bool {
+	if _, ok := j.Right.Expr.(*expr.Unnest); ok {
+		return true
+	}
+	p, ok := expr.FlatPath(j.Right.Expr)
+	if !ok {
+		return false
+	}
+	left := j.Left.Tables()
+	for i := range left {
+		if p[0] == left[i].Result() {
+			return true
+		}
+	}
+	return false
+}
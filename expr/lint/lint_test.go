@@ -0,0 +1,90 @@
+// Copyright (C) 2022 Sneller, Inc.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package lint
+
+import (
+	"testing"
+
+	"github.com/SnellerInc/sneller/expr/partiql"
+)
+
+func TestCheck(t *testing.T) {
+	tcs := []struct {
+		query string
+		rules []string
+		pos   string // position of the first warning
+	}{
+		{query: "SELECT x FROM foo WHERE ts > `2022-01-01T00:00:00Z`"},
+		{
+			query: "SELECT x FROM foo\nWHERE DATE_TRUNC(DAY, ts) = `2022-01-01T00:00:00Z`",
+			rules: []string{NonSargableTime},
+			pos:   "2:23",
+		},
+		{
+			query: "SELECT x FROM foo WHERE x > 3 AND EXTRACT(YEAR FROM ts) >= 2022",
+			rules: []string{NonSargableTime},
+		},
+		{
+			// not a comparison against a constant
+			query: "SELECT x FROM foo WHERE EXTRACT(YEAR FROM ts) = y",
+		},
+		{
+			query: "SELECT x, y FROM (SELECT * FROM foo)",
+			rules: []string{SelectStar},
+		},
+		{
+			query: "WITH t AS (SELECT * FROM foo) SELECT t.x, COUNT(*) FROM t GROUP BY t.x",
+			rules: []string{SelectStar},
+		},
+		{query: "SELECT * FROM (SELECT * FROM foo)"},
+		{query: "SELECT t FROM (SELECT * FROM foo) AS t"},
+		{query: "SELECT x FROM (SELECT x, y FROM foo)"},
+		{
+			query: "SELECT a.x, b.y FROM foo AS a, bar AS b",
+			rules: []string{CrossProduct},
+		},
+		{
+			query: "SELECT a.x, c.z FROM foo AS a, bar AS b, baz AS c WHERE a.x = b.x",
+			rules: []string{CrossProduct},
+		},
+		{query: "SELECT a.x, b.y FROM foo AS a, bar AS b WHERE a.x = b.x"},
+		{query: "SELECT a.x, b.y FROM foo AS a, a.lst AS b"},
+		{query: "SELECT a.x, b.y FROM foo AS a JOIN bar AS b ON a.x = b.x"},
+		{
+			// checks apply to every sub-query
+			query: "SELECT x FROM foo WHERE x IN (SELECT y FROM bar WHERE DATE_TRUNC(DAY, ts) = `2022-01-01T00:00:00Z`)",
+			rules: []string{NonSargableTime},
+		},
+	}
+	for i := range tcs {
+		q, err := partiql.Parse([]byte(tcs[i].query))
+		if err != nil {
+			t.Fatalf("%q: %s", tcs[i].query, err)
+		}
+		w := Check(q)
+		if len(w) != len(tcs[i].rules) {
+			t.Errorf("%q: got warnings %v", tcs[i].query, w)
+			continue
+		}
+		for j := range w {
+			if w[j].Rule != tcs[i].rules[j] {
+				t.Errorf("%q: warning %d: got rule %s, want %s", tcs[i].query, j, w[j].Rule, tcs[i].rules[j])
+			}
+		}
+		if tcs[i].pos != "" && w[0].Pos.String() != tcs[i].pos {
+			t.Errorf("%q: got position %s, want %s", tcs[i].query, w[0].Pos, tcs[i].pos)
+		}
+	}
+}
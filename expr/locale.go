@@ -0,0 +1,134 @@
+// Copyright (C) 2022 Sneller, Inc.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package expr
+
+import (
+	"strings"
+)
+
+// locale holds the conventions used by TO_CHAR
+// and FORMAT to produce locale-specific output.
+//
+// The data below is taken from the Unicode CLDR
+// (the stand-alone wide and abbreviated month and
+// day names and the decimal and grouping symbols
+// of the latn number system).
+type locale struct {
+	decimal, group string
+	months         [12]string
+	monthsAbbr     [12]string
+	days           [7]string // starting on Sunday
+	daysAbbr       [7]string
+}
+
+// locales is indexed by language or by
+// language and region (lowercase and separated
+// by an underscore); regional entries only
+// override the number symbols of the language
+var locales = map[string]*locale{
+	"en": {
+		decimal:    ".",
+		group:      ",",
+		months:     [12]string{"January", "February", "March", "April", "May", "June", "July", "August", "September", "October", "November", "December"},
+		monthsAbbr: [12]string{"Jan", "Feb", "Mar", "Apr", "May", "Jun", "Jul", "Aug", "Sep", "Oct", "Nov", "Dec"},
+		days:       [7]string{"Sunday", "Monday", "Tuesday", "Wednesday", "Thursday", "Friday", "Saturday"},
+		daysAbbr:   [7]string{"Sun", "Mon", "Tue", "Wed", "Thu", "Fri", "Sat"},
+	},
+	"de": {
+		decimal:    ",",
+		group:      ".",
+		months:     [12]string{"Januar", "Februar", "März", "April", "Mai", "Juni", "Juli", "August", "September", "Oktober", "November", "Dezember"},
+		monthsAbbr: [12]string{"Jan", "Feb", "Mär", "Apr", "Mai", "Jun", "Jul", "Aug", "Sep", "Okt", "Nov", "Dez"},
+		days:       [7]string{"Sonntag", "Montag", "Dienstag", "Mittwoch", "Donnerstag", "Freitag", "Samstag"},
+		daysAbbr:   [7]string{"So", "Mo", "Di", "Mi", "Do", "Fr", "Sa"},
+	},
+	"fr": {
+		decimal:    ",",
+		group:      "\u202f", // narrow no-break space
+		months:     [12]string{"janvier", "février", "mars", "avril", "mai", "juin", "juillet", "août", "septembre", "octobre", "novembre", "décembre"},
+		monthsAbbr: [12]string{"janv.", "févr.", "mars", "avr.", "mai", "juin", "juil.", "août", "sept.", "oct.", "nov.", "déc."},
+		days:       [7]string{"dimanche", "lundi", "mardi", "mercredi", "jeudi", "vendredi", "samedi"},
+		daysAbbr:   [7]string{"dim.", "lun.", "mar.", "mer.", "jeu.", "ven.", "sam."},
+	},
+	"es": {
+		decimal:    ",",
+		group:      ".",
+		months:     [12]string{"enero", "febrero", "marzo", "abril", "mayo", "junio", "julio", "agosto", "septiembre", "octubre", "noviembre", "diciembre"},
+		monthsAbbr: [12]string{"ene", "feb", "mar", "abr", "may", "jun", "jul", "ago", "sept", "oct", "nov", "dic"},
+		days:       [7]string{"domingo", "lunes", "martes", "miércoles", "jueves", "viernes", "sábado"},
+		daysAbbr:   [7]string{"dom", "lun", "mar", "mié", "jue", "vie", "sáb"},
+	},
+	"it": {
+		decimal:    ",",
+		group:      ".",
+		months:     [12]string{"gennaio", "febbraio", "marzo", "aprile", "maggio", "giugno", "luglio", "agosto", "settembre", "ottobre", "novembre", "dicembre"},
+		monthsAbbr: [12]string{"gen", "feb", "mar", "apr", "mag", "giu", "lug", "ago", "set", "ott", "nov", "dic"},
+		days:       [7]string{"domenica", "lunedì", "martedì", "mercoledì", "giovedì", "venerdì", "sabato"},
+		daysAbbr:   [7]string{"dom", "lun", "mar", "mer", "gio", "ven", "sab"},
+	},
+	"nl": {
+		decimal:    ",",
+		group:      ".",
+		months:     [12]string{"januari", "februari", "maart", "april", "mei", "juni", "juli", "augustus", "september", "oktober", "november", "december"},
+		monthsAbbr: [12]string{"jan", "feb", "mrt", "apr", "mei", "jun", "jul", "aug", "sep", "okt", "nov", "dec"},
+		days:       [7]string{"zondag", "maandag", "dinsdag", "woensdag", "donderdag", "vrijdag", "zaterdag"},
+		daysAbbr:   [7]string{"zo", "ma", "di", "wo", "do", "vr", "za"},
+	},
+	"pt": {
+		decimal:    ",",
+		group:      ".",
+		months:     [12]string{"janeiro", "fevereiro", "março", "abril", "maio", "junho", "julho", "agosto", "setembro", "outubro", "novembro", "dezembro"},
+		monthsAbbr: [12]string{"jan", "fev", "mar", "abr", "mai", "jun", "jul", "ago", "set", "out", "nov", "dez"},
+		days:       [7]string{"domingo", "segunda-feira", "terça-feira", "quarta-feira", "quinta-feira", "sexta-feira", "sábado"},
+		daysAbbr:   [7]string{"dom", "seg", "ter", "qua", "qui", "sex", "sáb"},
+	},
+	"sv": {
+		decimal:    ",",
+		group:      "\u00a0", // no-break space
+		months:     [12]string{"januari", "februari", "mars", "april", "maj", "juni", "juli", "augusti", "september", "oktober", "november", "december"},
+		monthsAbbr: [12]string{"jan", "feb", "mar", "apr", "maj", "jun", "jul", "aug", "sep", "okt", "nov", "dec"},
+		days:       [7]string{"söndag", "måndag", "tisdag", "onsdag", "torsdag", "fredag", "lördag"},
+		daysAbbr:   [7]string{"sön", "mån", "tis", "ons", "tors", "fre", "lör"},
+	},
+
+	// regional number symbols
+	"de_at": {decimal: ",", group: "\u00a0"},
+	"de_ch": {decimal: ".", group: "\u2019"},
+	"es_mx": {decimal: ".", group: ","},
+	"pt_pt": {decimal: ",", group: "\u00a0"},
+}
+
+// lookupLocale returns the locale with the given name,
+// which is a language tag like 'de', 'de-CH' or 'de_CH'
+// (optionally followed by an encoding, as in 'de_CH.UTF-8');
+// unknown regions fall back to the language
+func lookupLocale(name string) (*locale, bool) {
+	name, _, _ = strings.Cut(name, ".")
+	name = strings.ReplaceAll(strings.ToLower(name), "-", "_")
+	lang, _, _ := strings.Cut(name, "_")
+	base, ok := locales[lang]
+	if !ok || base.months[0] == "" {
+		return nil, false
+	}
+	if lang == name {
+		return base, true
+	}
+	if r, ok := locales[name]; ok {
+		l := *base
+		l.decimal, l.group = r.decimal, r.group
+		return &l, true
+	}
+	return base, true
+}
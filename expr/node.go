@@ -203,6 +203,21 @@ const (
 	// aggregates.
 	OpSystemDatashapeMerge
 
+	// OpNtile corresponds to NTILE(n)
+	OpNtile
+
+	// OpPercentRank corresponds to PERCENT_RANK()
+	OpPercentRank
+
+	// OpCumeDist corresponds to CUME_DIST()
+	OpCumeDist
+
+	// Describes SQL SUM(DISTINCT ...) operation
+	OpSumDistinct
+
+	// Describes SQL AVG(DISTINCT ...) operation
+	OpAvgDistinct
+
 	maxAggregateOp
 )
 
@@ -212,13 +227,46 @@ const (
 	ApproxCountDistinctDefaultPrecision = 11
 )
 
+// DatashapeMode selects the statistics
+// collected by SNELLER_DATASHAPE
+type DatashapeMode uint8
+
+const (
+	// DatashapeTypes collects the counts of
+	// each type for each path (the default)
+	DatashapeTypes DatashapeMode = iota
+	// DatashapeHistograms additionally collects
+	// value statistics (min/max values, the approximate
+	// number of distinct values, and the fraction of
+	// null values) for each path; it is selected
+	// with SNELLER_DATASHAPE(*, 'histograms')
+	DatashapeHistograms
+	// DatashapeHistogramsPartial is DatashapeHistograms
+	// for partial results that are combined with
+	// SNELLER_DATASHAPE_MERGE
+	DatashapeHistogramsPartial
+)
+
+func (m DatashapeMode) String() string {
+	switch m {
+	case DatashapeTypes:
+		return "types"
+	case DatashapeHistograms:
+		return "histograms"
+	case DatashapeHistogramsPartial:
+		return "histograms_partial"
+	default:
+		return fmt.Sprintf("<DatashapeMode=%d>", int(m))
+	}
+}
+
 func (a AggregateOp) defaultResult() string {
 	switch a {
 	case OpCount, OpCountDistinct, OpSumCount, OpApproxCountDistinct:
 		return "count"
-	case OpSum, OpSumInt:
+	case OpSum, OpSumInt, OpSumDistinct:
 		return "sum"
-	case OpAvg:
+	case OpAvg, OpAvgDistinct:
 		return "avg"
 	case OpVariancePop:
 		return "variance_pop"
@@ -236,6 +284,12 @@ func (a AggregateOp) defaultResult() string {
 		return "rank"
 	case OpDenseRank:
 		return "dense_rank"
+	case OpNtile:
+		return "ntile"
+	case OpPercentRank:
+		return "percent_rank"
+	case OpCumeDist:
+		return "cume_dist"
 	default:
 		return ""
 	}
@@ -259,6 +313,10 @@ func (a AggregateOp) String() string {
 		return "MAX"
 	case OpCountDistinct:
 		return "COUNT DISTINCT"
+	case OpSumDistinct:
+		return "SUM DISTINCT"
+	case OpAvgDistinct:
+		return "AVG DISTINCT"
 	case OpSumInt:
 		return "SUM_INT"
 	case OpSumCount:
@@ -293,6 +351,12 @@ func (a AggregateOp) String() string {
 		return "SNELLER_DATASHAPE"
 	case OpSystemDatashapeMerge:
 		return "SNELLER_DATASHAPE_MERGE"
+	case OpNtile:
+		return "NTILE"
+	case OpPercentRank:
+		return "PERCENT_RANK"
+	case OpCumeDist:
+		return "CUME_DIST"
 	default:
 		return fmt.Sprintf("<AggregateOp=%d>", int(a))
 	}
@@ -302,7 +366,8 @@ func (a AggregateOp) private() bool {
 	switch a {
 	case OpCount, OpSum, OpAvg, OpVariancePop, OpStdDevPop, OpMin, OpMax, OpEarliest, OpLatest,
 		OpBitAnd, OpBitOr, OpBitXor, OpBoolAnd, OpBoolOr,
-		OpApproxCountDistinct, OpSystemDatashape, OpRowNumber, OpRank, OpDenseRank:
+		OpApproxCountDistinct, OpSystemDatashape, OpRowNumber, OpRank, OpDenseRank,
+		OpNtile, OpPercentRank, OpCumeDist:
 		return false
 	}
 
@@ -313,7 +378,19 @@ func (a AggregateOp) private() bool {
 // is only valid when used with a window function
 func (a AggregateOp) WindowOnly() bool {
 	switch a {
-	case OpRowNumber, OpRank, OpDenseRank:
+	case OpRowNumber, OpRank, OpDenseRank, OpNtile, OpPercentRank, OpCumeDist:
+		return true
+	default:
+		return false
+	}
+}
+
+// Cumulative returns whether or not the aggregate op
+// can be computed as a running aggregate when used
+// with a window that has an ORDER BY clause
+func (a AggregateOp) Cumulative() bool {
+	switch a {
+	case OpCount, OpSum, OpSumInt, OpMin, OpMax, OpBitAnd, OpBitOr, OpBitXor:
 		return true
 	default:
 		return false
@@ -323,13 +400,42 @@ func (a AggregateOp) WindowOnly() bool {
 // AcceptDistinct returns true if the aggregate can be used with DISTINCT keyword.
 func (a AggregateOp) AcceptDistinct() bool {
 	switch a {
-	case OpCount, OpMin, OpMax, OpCountDistinct, OpEarliest, OpLatest:
+	case OpCount, OpSum, OpAvg, OpMin, OpMax, OpCountDistinct, OpSumDistinct, OpAvgDistinct, OpEarliest, OpLatest:
 		return true
 	}
 
 	return false
 }
 
+// WithDistinct returns the DISTINCT variant of the aggregate op,
+// or the op itself if DISTINCT does not change its result.
+func (a AggregateOp) WithDistinct() AggregateOp {
+	switch a {
+	case OpCount:
+		return OpCountDistinct
+	case OpSum:
+		return OpSumDistinct
+	case OpAvg:
+		return OpAvgDistinct
+	}
+	return a
+}
+
+// WithoutDistinct returns the aggregate op that
+// is applied to the distinct inputs of a DISTINCT
+// aggregate op, or the op itself otherwise.
+func (a AggregateOp) WithoutDistinct() AggregateOp {
+	switch a {
+	case OpCountDistinct:
+		return OpCount
+	case OpSumDistinct:
+		return OpSum
+	case OpAvgDistinct:
+		return OpAvg
+	}
+	return a
+}
+
 // AcceptStar returns true if the aggregate can be used with '*'.
 func (a AggregateOp) AcceptStar() bool {
 	switch a {
@@ -352,6 +458,9 @@ type Aggregate struct {
 	Op AggregateOp
 	// Precision is the parameter for OpApproxCountDistinct
 	Precision uint8
+	// Datashape is the parameter for OpSystemDatashape
+	// and OpSystemDatashapeMerge
+	Datashape DatashapeMode
 	// Inner is the expression to be aggregated;
 	// this may be nil when the operation is a window function
 	Inner Node
@@ -371,7 +480,7 @@ func (a *Aggregate) Equals(e Node) bool {
 		(a.Inner != nil && !a.Inner.Equals(ea.Inner)) {
 		return false
 	}
-	if ea.Precision != a.Precision {
+	if ea.Precision != a.Precision || ea.Datashape != a.Datashape {
 		return false
 	}
 
@@ -405,6 +514,10 @@ func (a *Aggregate) Encode(dst *ion.Buffer, st *ion.Symtab) {
 		dst.BeginField(st.Intern("precision"))
 		dst.WriteUint(uint64(a.Precision))
 	}
+	if a.Datashape != DatashapeTypes {
+		dst.BeginField(st.Intern("datashape"))
+		dst.WriteUint(uint64(a.Datashape))
+	}
 	if a.Inner != nil {
 		dst.BeginField(st.Intern("inner"))
 		a.Inner.Encode(dst, st)
@@ -472,6 +585,12 @@ func (a *Aggregate) SetField(f ion.Field) error {
 			return err
 		}
 		a.Precision = uint8(p)
+	case "datashape":
+		m, err := f.Uint()
+		if err != nil {
+			return err
+		}
+		a.Datashape = DatashapeMode(m)
 	default:
 		return errUnexpectedField
 	}
@@ -480,8 +599,9 @@ func (a *Aggregate) SetField(f ion.Field) error {
 
 func (a *Aggregate) text(dst *strings.Builder, redact bool) {
 	switch a.Op {
-	case OpCountDistinct:
-		dst.WriteString("COUNT(DISTINCT ")
+	case OpCountDistinct, OpSumDistinct, OpAvgDistinct:
+		dst.WriteString(a.Op.WithoutDistinct().String())
+		dst.WriteString("(DISTINCT ")
 		a.Inner.text(dst, redact)
 		dst.WriteByte(')')
 
@@ -494,6 +614,15 @@ func (a *Aggregate) text(dst *strings.Builder, redact bool) {
 		}
 		dst.WriteByte(')')
 
+	case OpSystemDatashape, OpSystemDatashapeMerge:
+		dst.WriteString(a.Op.String())
+		dst.WriteByte('(')
+		a.Inner.text(dst, redact)
+		if a.Datashape != DatashapeTypes {
+			fmt.Fprintf(dst, ", '%s'", a.Datashape)
+		}
+		dst.WriteByte(')')
+
 	default:
 		dst.WriteString(a.Op.String())
 		dst.WriteByte('(')
@@ -571,8 +700,10 @@ func (a *Aggregate) rewrite(r Rewriter) Node {
 
 func (a *Aggregate) typeof(h Hint) TypeSet {
 	switch a.Op {
-	case OpCount, OpCountDistinct, OpSumCount, OpApproxCountDistinct, OpRowNumber, OpRank, OpDenseRank:
+	case OpCount, OpCountDistinct, OpSumCount, OpApproxCountDistinct, OpRowNumber, OpRank, OpDenseRank, OpNtile:
 		return UnsignedType
+	case OpPercentRank, OpCumeDist:
+		return NumericType
 	case OpSumInt:
 		// if the inner type is only ever unsigned,
 		// then the result is only ever unsigned,
@@ -589,7 +720,18 @@ func (a *Aggregate) typeof(h Hint) TypeSet {
 
 // IsDistinct returns if the aggregate has DISTINCT clause.
 func (a *Aggregate) IsDistinct() bool {
-	return a.Op == OpCountDistinct
+	return a.Op.WithoutDistinct() != a.Op
+}
+
+// Windowed returns whether the aggregate is evaluated
+// over a window after all of the groups have been computed;
+// this is true for the window-only functions and for
+// cumulative aggregates with OVER (... ORDER BY ...)
+func (a *Aggregate) Windowed() bool {
+	if a.Op.WindowOnly() {
+		return true
+	}
+	return a.Over != nil && len(a.Over.OrderBy) > 0 && a.Op.Cumulative()
 }
 
 // Count produces the COUNT(e) aggregate
@@ -645,6 +787,11 @@ func Latest(e Node) *Aggregate { return &Aggregate{Op: OpLatest, Inner: e} }
 // Two nodes are equal if they are
 // equivalent numbers (i.e. '0' and '0.0')
 // or if they are identical.
+//
+// Callers that compare the same nodes
+// repeatedly should use HashCache.Equivalent,
+// which can reject unequal nodes without
+// performing a deep comparison.
 func Equivalent(a, b Node) bool {
 	if a == b {
 		return true
@@ -759,6 +906,11 @@ type Node interface {
 	// to equal numeric values.
 	Equals(Node) bool
 
+	// Hash returns a structural hash of
+	// the node that is consistent with Equals:
+	// nodes that are Equal have the same Hash.
+	Hash() uint64
+
 	Encode(dst *ion.Buffer, st *ion.Symtab)
 
 	walk(Visitor)
@@ -1207,9 +1359,14 @@ func (d *Dot) simplify(h Hint) Node {
 //	Inner '[' Offset ']'
 //
 // The Inner value within Index should be list-typed.
+// A negative Offset counts from the end of the list,
+// so Offset -1 selects the last element.
+//
+// Indexing with a non-constant offset is represented
+// with the ArrayElement built-in instead.
 type Index struct {
 	Inner  Node
-	Offset int // offset is constant for now
+	Offset int
 }
 
 func (i *Index) text(dst *strings.Builder, redact bool) {
@@ -1244,16 +1401,23 @@ func (i *Index) SetField(f ion.Field) (err error) {
 }
 
 // [ v ][0] -> v
+// [ v ][-1] -> v
 func (i *Index) simplify(h Hint) Node {
+	offset := func(n int) (int, bool) {
+		if i.Offset < 0 {
+			return n + i.Offset, n+i.Offset >= 0
+		}
+		return i.Offset, i.Offset < n
+	}
 	if b, ok := i.Inner.(*Builtin); ok && b.Func == MakeList {
-		if i.Offset < len(b.Args) {
-			return b.Args[i.Offset]
+		if j, ok := offset(len(b.Args)); ok {
+			return b.Args[j]
 		}
 		return Missing{}
 	}
 	if l, ok := i.Inner.(*List); ok {
-		if i.Offset < len(l.Values) {
-			return l.Values[i.Offset]
+		if j, ok := offset(len(l.Values)); ok {
+			return l.Values[j]
 		}
 		return Missing{}
 	}
@@ -1275,6 +1439,91 @@ func (i *Index) rewrite(r Rewriter) Node {
 	return i
 }
 
+// Wildcard represents a wildcard path step, i.e.
+//
+//	Inner '[' '*' ']'
+//	Inner '.' '*'
+//
+// The first form expands over the elements of
+// a list and the second form (Struct == true)
+// expands over the values of a structure.
+// A Wildcard evaluates to a list, and any
+// further Dot or Wildcard steps applied to it
+// are applied to each of its elements in turn.
+type Wildcard struct {
+	Inner  Node
+	Struct bool
+}
+
+func (w *Wildcard) text(dst *strings.Builder, redact bool) {
+	w.Inner.text(dst, redact)
+	if w.Struct {
+		dst.WriteString(".*")
+	} else {
+		dst.WriteString("[*]")
+	}
+}
+
+func (w *Wildcard) Encode(dst *ion.Buffer, st *ion.Symtab) {
+	dst.BeginStruct(-1)
+	settype(dst, st, "wildcard")
+	dst.BeginField(st.Intern("inner"))
+	w.Inner.Encode(dst, st)
+	if w.Struct {
+		dst.BeginField(st.Intern("struct"))
+		dst.WriteBool(true)
+	}
+	dst.EndStruct()
+}
+
+func (w *Wildcard) SetField(f ion.Field) (err error) {
+	switch f.Label {
+	case "inner":
+		w.Inner, err = Decode(f.Datum)
+	case "struct":
+		w.Struct, err = f.Bool()
+	default:
+		return errUnexpectedField
+	}
+	return err
+}
+
+func (w *Wildcard) Equals(x Node) bool {
+	w2, ok := x.(*Wildcard)
+	return ok && w.Struct == w2.Struct &&
+		w.Inner.Equals(w2.Inner)
+}
+
+func (w *Wildcard) Type() TypeSet { return ListType | MissingType }
+
+func (w *Wildcard) walk(v Visitor) {
+	Walk(v, w.Inner)
+}
+
+func (w *Wildcard) rewrite(r Rewriter) Node {
+	w.Inner = Rewrite(r, w.Inner)
+	return w
+}
+
+// HasWildcard returns whether or not the
+// path expression e contains a Wildcard step,
+// in which case it evaluates to a list of
+// the values matched by the path.
+func HasWildcard(e Node) bool {
+	for {
+		switch n := e.(type) {
+		case *Wildcard:
+			return true
+		case *Dot:
+			e = n.Inner
+		case *Index:
+			e = n.Inner
+		default:
+			return false
+		}
+	}
+}
+
 // Star represents the '*' path component
 type Star struct{}
 
@@ -2172,11 +2421,17 @@ func (b *Builtin) text(dst *strings.Builder, redact bool) {
 		info.text(b.Args, dst, redact)
 		return
 	}
-	dst.WriteString(b.Name())
+	defaultText(b.Name(), b.Args, dst, redact)
+}
+
+// defaultText writes a builtin
+// function call as NAME(args...)
+func defaultText(name string, args []Node, dst *strings.Builder, redact bool) {
+	dst.WriteString(name)
 	dst.WriteByte('(')
-	for i := range b.Args {
-		b.Args[i].text(dst, redact)
-		if i != len(b.Args)-1 {
+	for i := range args {
+		args[i].text(dst, redact)
+		if i != len(args)-1 {
 			dst.WriteString(", ")
 		}
 	}
@@ -3091,11 +3346,12 @@ func (c *Cast) text(dst *strings.Builder, redact bool) {
 
 func (c *Cast) typeof(h Hint) TypeSet {
 	ft := TypeOf(c.From, h)
-	if ft&c.To == 0 {
+	possible := converts(c.To)
+	if ft&possible == 0 {
 		return MissingType
 	}
 	out := c.To
-	if ft&c.To != ft {
+	if ft&possible != ft {
 		out |= MissingType
 	}
 	return out
@@ -3286,6 +3542,11 @@ const (
 	Month
 	Quarter
 	Year
+
+	// ISO 8601 week-date parts
+	ISODOW  // day of the ISO week, from Monday (1) to Sunday (7)
+	ISOYear // year of the ISO week
+	ISOWeek // ISO week, starting on Monday
 )
 
 // time part -> string LUT
@@ -3302,6 +3563,9 @@ var partstring = []string{
 	Month:       "MONTH",
 	Quarter:     "QUARTER",
 	Year:        "YEAR",
+	ISODOW:      "ISODOW",
+	ISOYear:     "ISOYEAR",
+	ISOWeek:     "ISOWEEK",
 }
 
 func (t Timepart) String() string {
@@ -3328,6 +3592,10 @@ func DateTrunc(part Timepart, from Node) Node {
 	if part == Week {
 		return DateTruncWeekday(from, Sunday)
 	}
+	// an ISO week always starts on Monday
+	if part == ISOWeek {
+		return DateTruncWeekday(from, Monday)
+	}
 	return CallByName("DATE_TRUNC_"+part.String(), from)
 }
 
@@ -3356,11 +3624,20 @@ func (s *Struct) Equals(e Node) bool {
 	if len(s.Fields) != len(s2.Fields) {
 		return false
 	}
+	// structure fields are unordered
+	// (and the ion encoding of a structure
+	// orders fields by their symbol IDs)
 	for i := range s.Fields {
-		if s2.Fields[i].Label != s.Fields[i].Label {
-			return false
+		j := i
+		if s2.Fields[j].Label != s.Fields[i].Label {
+			j = slices.IndexFunc(s2.Fields, func(f Field) bool {
+				return f.Label == s.Fields[i].Label
+			})
+			if j < 0 {
+				return false
+			}
 		}
-		if !s2.Fields[i].Value.Equals(s.Fields[i].Value) {
+		if !s2.Fields[j].Value.Equals(s.Fields[i].Value) {
 			return false
 		}
 	}
@@ -3661,6 +3938,83 @@ func (u *Unpivot) text(dst *strings.Builder, redact bool) {
 	}
 }
 
+// Unnest captures the UNNEST(a, b, ...) AS (x, y, ...)
+// expression on the right-hand side of a cross join,
+// which iterates over the lists a, b, ... in lock-step
+// and binds their elements to x, y, ...
+type Unnest struct {
+	Values []Binding
+}
+
+func (u *Unnest) Equals(x Node) bool {
+	xu, ok := x.(*Unnest)
+	return ok && slices.EqualFunc(u.Values, xu.Values, Binding.Equals)
+}
+
+func (u *Unnest) Encode(dst *ion.Buffer, st *ion.Symtab) {
+	dst.BeginStruct(-1)
+	settype(dst, st, "unnest")
+	dst.BeginField(st.Intern("values"))
+	EncodeBindings(u.Values, dst, st)
+	dst.EndStruct()
+}
+
+func (u *Unnest) SetField(f ion.Field) error {
+	var err error
+	switch f.Label {
+	case "values":
+		u.Values, err = DecodeBindings(f.Datum)
+	default:
+		return errUnexpectedField
+	}
+	return err
+}
+
+func (u *Unnest) check(h Hint) error {
+	if len(u.Values) == 0 {
+		return errsyntaxf("UNNEST expects at least one argument")
+	}
+	for i := range u.Values {
+		for j := range u.Values[:i] {
+			if u.Values[i].Result() == u.Values[j].Result() {
+				return errsyntaxf("UNNEST binds %q more than once", u.Values[i].Result())
+			}
+		}
+	}
+	return nil
+}
+
+func (u *Unnest) walk(v Visitor) {
+	for i := range u.Values {
+		Walk(v, u.Values[i].Expr)
+	}
+}
+
+func (u *Unnest) rewrite(r Rewriter) Node {
+	for i := range u.Values {
+		u.Values[i].Expr = Rewrite(r, u.Values[i].Expr)
+	}
+	return u
+}
+
+func (u *Unnest) text(dst *strings.Builder, redact bool) {
+	dst.WriteString("UNNEST(")
+	for i := range u.Values {
+		if i > 0 {
+			dst.WriteString(", ")
+		}
+		u.Values[i].Expr.text(dst, redact)
+	}
+	dst.WriteString(") AS (")
+	for i := range u.Values {
+		if i > 0 {
+			dst.WriteString(", ")
+		}
+		dst.WriteString(QuoteID(u.Values[i].Result()))
+	}
+	dst.WriteString(")")
+}
+
 func equalPointed[T comparable](lhs, rhs *T) bool {
 	if lhs != nil {
 		return (rhs != nil) && ((lhs == rhs) || (*lhs == *rhs))
@@ -3779,7 +4133,7 @@ func (u *Union) text(dst *strings.Builder, redact bool) {
 		}
 
 		if sel, ok := n.(*Select); ok {
-			sel.write(dst, redact, nil)
+			sel.write(dst, redact, nil, nil)
 		} else {
 			n.text(dst, redact)
 		}
@@ -0,0 +1,101 @@
+// Copyright (C) 2022 Sneller, Inc.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package partiql
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/SnellerInc/sneller/expr"
+)
+
+// IsDDL returns true if in is a CREATE TABLE,
+// ALTER TABLE, or DROP TABLE statement (that
+// should be parsed with ParseDDL) or false otherwise.
+func IsDDL(in []byte) bool {
+	s := &scanner{from: in}
+	var l yySymType
+	switch s.Lex(&l) {
+	case CREATE, ALTER, DROP:
+		return s.Lex(&l) == TABLE
+	}
+	return false
+}
+
+// ParseDDL parses a CREATE TABLE, ALTER TABLE,
+// or DROP TABLE statement (see expr.CreateTable,
+// expr.AlterTable, and expr.DropTable for the syntax)
+// and returns the result, or an error if one is encountered.
+func ParseDDL(in []byte) (expr.DDL, error) {
+	s := &scanner{from: in}
+	p := newParser()
+	ret := p.Parse(s)
+	dropParser(p)
+	if s.err != nil && s.err != io.EOF {
+		return nil, s.wrap(s.err)
+	}
+	if ret != 0 {
+		return nil, fmt.Errorf("parse error %d", ret)
+	}
+	if s.ddl == nil {
+		return nil, fmt.Errorf("statement is not a CREATE, ALTER, or DROP statement")
+	}
+	return s.ddl, nil
+}
+
+// columnType returns the type hint name
+// (see db.TypeHints) for a SQL type name
+func columnType(name string) (string, bool) {
+	switch name {
+	case "bool", "boolean":
+		return "bool", true
+	case "int", "integer", "bigint":
+		return "int", true
+	case "float", "double", "real":
+		return "float", true
+	case "decimal", "timestamp", "symbol", "string", "list", "struct":
+		return name, true
+	case "varchar", "text":
+		return "string", true
+	default:
+		return "", false
+	}
+}
+
+// mergeAlter merges the ALTER TABLE action
+// in src into dst, or returns an error if
+// dst already alters the same property
+func mergeAlter(dst, src *expr.AlterTable) error {
+	if src.PartitionBy != nil {
+		if dst.PartitionBy != nil {
+			return fmt.Errorf("duplicate PARTITION BY")
+		}
+		dst.PartitionBy = src.PartitionBy
+	}
+	if src.SortBy != nil {
+		if dst.SortBy != nil {
+			return fmt.Errorf("duplicate SORT BY")
+		}
+		dst.SortBy = src.SortBy
+	}
+	if src.Retention != nil || src.DropRetention {
+		if dst.Retention != nil || dst.DropRetention {
+			return fmt.Errorf("duplicate RETENTION")
+		}
+		dst.Retention = src.Retention
+		dst.DropRetention = src.DropRetention
+	}
+	return nil
+}
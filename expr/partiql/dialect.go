@@ -0,0 +1,324 @@
+// Copyright (C) 2022 Sneller, Inc.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package partiql
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/SnellerInc/sneller/expr"
+)
+
+// Dialect selects the flavor of SQL
+// accepted by ParseDialect.
+type Dialect int
+
+const (
+	// DefaultDialect accepts only Sneller SQL.
+	DefaultDialect Dialect = iota
+	// TrinoDialect additionally accepts the names
+	// (and argument conventions) of a number of common
+	// Presto/Trino functions and translates them
+	// into the equivalent Sneller SQL expressions.
+	TrinoDialect
+	// MySQLDialect additionally accepts a number
+	// of common MySQL functions.
+	MySQLDialect
+	// PostgresDialect additionally accepts a number
+	// of common PostgreSQL functions.
+	PostgresDialect
+)
+
+// ParseDialect is equivalent to Parse,
+// but accepts the given dialect of SQL.
+func ParseDialect(in []byte, d Dialect) (*expr.Query, error) {
+	s := &scanner{from: in, dialect: d}
+	return parse(s)
+}
+
+// DialectByName returns the Dialect with the given
+// (case-insensitive) name: one of "sneller", "trino",
+// "mysql" or "postgres". The names "presto" and
+// "postgresql" are accepted as synonyms for "trino"
+// and "postgres", respectively.
+func DialectByName(name string) (Dialect, bool) {
+	switch strings.ToLower(name) {
+	case "", "sneller":
+		return DefaultDialect, true
+	case "trino", "presto":
+		return TrinoDialect, true
+	case "mysql":
+		return MySQLDialect, true
+	case "postgres", "postgresql":
+		return PostgresDialect, true
+	}
+	return 0, false
+}
+
+// dialectFunc translates a call to a function
+// of another dialect into the equivalent expression
+type dialectFunc func(s *scanner, args []expr.Node) (expr.Node, error)
+
+// dialectFuncs holds the functions that are
+// translated at parse time in each dialect
+var dialectFuncs = map[Dialect]map[string]dialectFunc{
+	TrinoDialect:    trinoFuncs,
+	MySQLDialect:    mysqlFuncs,
+	PostgresDialect: postgresFuncs,
+}
+
+// rename returns a dialectFunc that calls op
+func rename(op expr.BuiltinOp) dialectFunc {
+	return func(s *scanner, args []expr.Node) (expr.Node, error) {
+		return expr.Call(op, args...), nil
+	}
+}
+
+func now(s *scanner, args []expr.Node) (expr.Node, error) {
+	if len(args) != 0 {
+		return nil, fmt.Errorf("NOW expects no arguments")
+	}
+	return s.utcnow(), nil
+}
+
+var trinoFuncs = map[string]dialectFunc{
+	"APPROX_DISTINCT": func(s *scanner, args []expr.Node) (expr.Node, error) {
+		return toAggregate(expr.OpApproxCountDistinct, false, args, nil, nil)
+	},
+	"CARDINALITY": rename(expr.ArraySize),
+	"LENGTH":      rename(expr.CharLength),
+	"ELEMENT_AT":  trinoElementAt,
+	"REGEXP_LIKE": func(s *scanner, args []expr.Node) (expr.Node, error) {
+		if len(args) != 2 {
+			return nil, fmt.Errorf("REGEXP_LIKE expects 2 arguments")
+		}
+		pat, ok := args[1].(expr.String)
+		if !ok {
+			return nil, fmt.Errorf("REGEXP_LIKE expects a constant pattern")
+		}
+		return &expr.StringMatch{Op: expr.RegexpMatch, Expr: args[0], Pattern: string(pat)}, nil
+	},
+}
+
+// trinoElementAt translates ELEMENT_AT(x, i), which
+// indexes a list starting from 1 (or from -1 at the end)
+// or looks up a key in a map (which is a structure here)
+func trinoElementAt(s *scanner, args []expr.Node) (expr.Node, error) {
+	if len(args) != 2 {
+		return nil, fmt.Errorf("ELEMENT_AT expects 2 arguments")
+	}
+	switch i := args[1].(type) {
+	case expr.Integer:
+		if i == 0 {
+			return nil, fmt.Errorf("ELEMENT_AT index %d not supported", i)
+		}
+		if i < 0 {
+			return &expr.Index{Inner: args[0], Offset: int(i)}, nil
+		}
+		return &expr.Index{Inner: args[0], Offset: int(i - 1)}, nil
+	case expr.String:
+		return &expr.Dot{Inner: args[0], Field: string(i)}, nil
+	}
+	return nil, fmt.Errorf("ELEMENT_AT expects a constant index or key")
+}
+
+var mysqlFuncs = map[string]dialectFunc{
+	"IFNULL": func(s *scanner, args []expr.Node) (expr.Node, error) {
+		if len(args) != 2 {
+			return nil, fmt.Errorf("IFNULL expects 2 arguments")
+		}
+		return expr.Coalesce(args), nil
+	},
+	"NOW":             now,
+	"LENGTH":          rename(expr.OctetLength),
+	"DATE_FORMAT":     mysqlDateFormat,
+	"SUBSTRING_INDEX": mysqlSubstringIndex,
+}
+
+// pad returns the decimal representation of the
+// integer e zero-padded to the given number of digits
+// (e must be less than 10^digits)
+func pad(e expr.Node, digits int) expr.Node {
+	base := int64(1)
+	for i := 0; i < digits; i++ {
+		base *= 10
+	}
+	str := &expr.Cast{From: expr.Add(expr.Integer(base), e), To: expr.StringType}
+	return expr.Call(expr.Substring, str, expr.Integer(2))
+}
+
+// concat concatenates a list of string expressions
+func concat(lst []expr.Node) expr.Node {
+	if len(lst) == 0 {
+		return expr.String("")
+	}
+	out := lst[0]
+	for _, e := range lst[1:] {
+		out = expr.Call(expr.Concat, out, e)
+	}
+	return out
+}
+
+// mysqlDateFormat translates DATE_FORMAT(ts, fmt)
+// into a concatenation of the components of ts
+func mysqlDateFormat(s *scanner, args []expr.Node) (expr.Node, error) {
+	if len(args) != 2 {
+		return nil, fmt.Errorf("DATE_FORMAT expects 2 arguments")
+	}
+	format, ok := args[1].(expr.String)
+	if !ok {
+		return nil, fmt.Errorf("DATE_FORMAT expects a constant format string")
+	}
+	ts := args[0]
+	var lst []expr.Node
+	var lit []byte
+	flush := func() {
+		if len(lit) > 0 {
+			lst = append(lst, expr.String(lit))
+			lit = nil
+		}
+	}
+	extract := func(part expr.Timepart) expr.Node {
+		return expr.DateExtract(part, expr.Copy(ts))
+	}
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' || i+1 == len(format) {
+			lit = append(lit, format[i])
+			continue
+		}
+		i++
+		var e expr.Node
+		switch format[i] {
+		case '%':
+			lit = append(lit, '%')
+			continue
+		case 'Y':
+			e = &expr.Cast{From: extract(expr.Year), To: expr.StringType}
+		case 'y':
+			e = pad(expr.Mod(extract(expr.Year), expr.Integer(100)), 2)
+		case 'm':
+			e = pad(extract(expr.Month), 2)
+		case 'c':
+			e = &expr.Cast{From: extract(expr.Month), To: expr.StringType}
+		case 'd':
+			e = pad(extract(expr.Day), 2)
+		case 'e':
+			e = &expr.Cast{From: extract(expr.Day), To: expr.StringType}
+		case 'H':
+			e = pad(extract(expr.Hour), 2)
+		case 'k':
+			e = &expr.Cast{From: extract(expr.Hour), To: expr.StringType}
+		case 'i':
+			e = pad(extract(expr.Minute), 2)
+		case 's', 'S':
+			e = pad(extract(expr.Second), 2)
+		case 'j':
+			e = pad(extract(expr.DOY), 3)
+		default:
+			return nil, fmt.Errorf("DATE_FORMAT specifier %%%c not supported", format[i])
+		}
+		flush()
+		lst = append(lst, e)
+	}
+	flush()
+	return concat(lst), nil
+}
+
+// mysqlSubstringIndex translates SUBSTRING_INDEX(str, sep, n),
+// which returns the part of str that precedes the nth
+// occurrence of sep (or all of str if there are fewer
+// than n occurrences)
+func mysqlSubstringIndex(s *scanner, args []expr.Node) (expr.Node, error) {
+	if len(args) != 3 {
+		return nil, fmt.Errorf("SUBSTRING_INDEX expects 3 arguments")
+	}
+	n, ok := args[2].(expr.Integer)
+	if !ok || n < 1 {
+		return nil, fmt.Errorf("SUBSTRING_INDEX expects a positive constant count")
+	}
+	str, sep := args[0], args[1]
+	if n == 1 {
+		return expr.Call(expr.SplitPart, str, sep, expr.Integer(1)), nil
+	}
+	// when there are fewer than n separators,
+	// the trailing parts are empty, so the joined
+	// prefix is longer than the string itself
+	lst := make([]expr.Node, 0, 2*n-1)
+	for i := expr.Integer(1); i <= n; i++ {
+		if i > 1 {
+			lst = append(lst, expr.Copy(sep))
+		}
+		lst = append(lst, expr.Call(expr.SplitPart, expr.Copy(str), expr.Copy(sep), i))
+	}
+	prefix := concat(lst)
+	shorter := expr.Compare(expr.Less,
+		expr.Call(expr.CharLength, prefix),
+		expr.Call(expr.CharLength, expr.Copy(str)))
+	return expr.IfThenElse(shorter, expr.Copy(prefix), str), nil
+}
+
+var postgresFuncs = map[string]dialectFunc{
+	"NOW":       now,
+	"LENGTH":    rename(expr.CharLength),
+	"DATE_PART": postgresDatePart,
+}
+
+// postgresDatePart translates DATE_PART('part', ts)
+func postgresDatePart(s *scanner, args []expr.Node) (expr.Node, error) {
+	if len(args) != 2 {
+		return nil, fmt.Errorf("DATE_PART expects 2 arguments")
+	}
+	str, ok := args[0].(expr.String)
+	if !ok {
+		return nil, fmt.Errorf("DATE_PART expects a constant part")
+	}
+	if isEpochPart(string(str)) {
+		return expr.Call(expr.ToUnixEpoch, args[1]), nil
+	}
+	part, ok := timePartFor(string(str), "EXTRACT")
+	if !ok {
+		return nil, fmt.Errorf("bad DATE_PART part %q", str)
+	}
+	return expr.DateExtract(part, args[1]), nil
+}
+
+// call yields the expression for a call
+// to the function fn with the given arguments
+func (s *scanner) call(fn string, args []expr.Node) (expr.Node, error) {
+	if df, ok := dialectFuncs[s.dialect][strings.ToUpper(fn)]; ok {
+		return df(s, args)
+	}
+	op := expr.CallByName(fn, args...)
+	if op.Private() {
+		return nil, fmt.Errorf("cannot use reserved builtin %q", fn)
+	}
+	return op, nil
+}
+
+// stringPart returns the time part named by the
+// string literal str, which is accepted in place
+// of an identifier in TrinoDialect (and, for
+// DATE_TRUNC, in PostgresDialect)
+func (s *scanner) stringPart(str, fn string) (expr.Timepart, error) {
+	ok := s.dialect == TrinoDialect || (s.dialect == PostgresDialect && fn == "DATE_TRUNC")
+	if !ok {
+		return 0, fmt.Errorf("%s part must be an identifier, not the string %q", fn, str)
+	}
+	part, ok := timePartFor(str, fn)
+	if !ok {
+		return 0, fmt.Errorf("bad %s part %q", fn, str)
+	}
+	return part, nil
+}
@@ -0,0 +1,287 @@
+// Copyright (C) 2022 Sneller, Inc.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package partiql
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/SnellerInc/sneller/expr"
+)
+
+// Printer prints queries as canonical PartiQL text.
+//
+// The text produced by a Printer always parses
+// back into a query that is equivalent to the
+// query that was printed, so it is suitable for
+// reformatting, diffing, and storing queries in
+// a normalized form.
+type Printer struct {
+	// Indent is the string used for
+	// each level of indentation.
+	// If Indent is empty, the query
+	// is printed on a single line.
+	Indent string
+}
+
+// Print returns the text of q. An error is returned
+// if the text does not parse into a query equivalent to
+// q, which can happen if q was not produced by Parse
+// (for example, if it was produced by the query planner).
+func (p *Printer) Print(q *expr.Query) (string, error) {
+	pr := printer{indent: p.Indent}
+	pr.query(q)
+	text := pr.out.String()
+	res, err := Parse([]byte(text))
+	if err != nil {
+		return "", fmt.Errorf("partiql.Printer: output does not parse: %w", err)
+	}
+	if res.Explain != q.Explain || !res.Equals(q) {
+		return "", fmt.Errorf("partiql.Printer: query %q does not round-trip", text)
+	}
+	return text, nil
+}
+
+// Format parses text and prints
+// it using the Printer p.
+func (p *Printer) Format(text []byte) (string, error) {
+	q, err := Parse(text)
+	if err != nil {
+		return "", err
+	}
+	return p.Print(q)
+}
+
+type printer struct {
+	indent string
+	depth  int
+	out    strings.Builder
+}
+
+// brk begins a new line if the printer
+// is indenting its output, or writes sep otherwise
+func (p *printer) brk(sep string) {
+	if p.indent == "" {
+		p.out.WriteString(sep)
+		return
+	}
+	p.out.WriteByte('\n')
+	for i := 0; i < p.depth; i++ {
+		p.out.WriteString(p.indent)
+	}
+}
+
+// list writes each of the items in lst
+// (written by the item function) after
+// the keyword kw; lists with more than one
+// item have one item per line when indenting
+func (p *printer) list(kw string, n int, item func(i int)) {
+	p.out.WriteString(kw)
+	if n == 1 || p.indent == "" {
+		p.out.WriteByte(' ')
+		for i := 0; i < n; i++ {
+			if i > 0 {
+				p.out.WriteString(", ")
+			}
+			item(i)
+		}
+		return
+	}
+	p.depth++
+	for i := 0; i < n; i++ {
+		if i > 0 {
+			p.out.WriteByte(',')
+		}
+		p.brk(" ")
+		item(i)
+	}
+	p.depth--
+}
+
+func (p *printer) expr(e expr.Node) {
+	p.out.WriteString(expr.ToString(e))
+}
+
+// paren writes a parenthesized sub-query
+func (p *printer) paren(body expr.Node) {
+	p.out.WriteByte('(')
+	p.depth++
+	p.brk("")
+	p.body(body)
+	p.depth--
+	p.brk("")
+	p.out.WriteByte(')')
+}
+
+func (p *printer) query(q *expr.Query) {
+	switch q.Explain {
+	case expr.ExplainDefault:
+		p.out.WriteString("EXPLAIN ")
+	case expr.ExplainText:
+		p.out.WriteString("EXPLAIN AS text ")
+	case expr.ExplainList:
+		p.out.WriteString("EXPLAIN AS list ")
+	case expr.ExplainGraphviz:
+		p.out.WriteString("EXPLAIN AS graphviz ")
+	}
+	if len(q.With) > 0 {
+		p.out.WriteString("WITH ")
+		for i := range q.With {
+			if i > 0 {
+				p.out.WriteString(", ")
+			}
+			p.out.WriteString(q.With[i].Table)
+			p.out.WriteString(" AS ")
+			p.paren(q.With[i].As)
+		}
+		p.brk(" ")
+	}
+	if s, ok := q.Body.(*expr.Select); ok && q.Append {
+		p.out.WriteString("INSERT INTO ")
+		p.expr(q.Into)
+		p.partitions(q.PartitionBy)
+		p.brk(" ")
+		p.sel(s, nil, nil)
+	} else if ok {
+		p.sel(s, q.Into, q.PartitionBy)
+	} else {
+		p.body(q.Body)
+	}
+}
+
+func (p *printer) partitions(lst []string) {
+	if len(lst) == 0 {
+		return
+	}
+	p.out.WriteString(" PARTITIONED BY (")
+	for i := range lst {
+		if i > 0 {
+			p.out.WriteString(", ")
+		}
+		p.out.WriteString(expr.QuoteID(lst[i]))
+	}
+	p.out.WriteByte(')')
+}
+
+// body writes a SELECT or UNION
+func (p *printer) body(e expr.Node) {
+	switch e := e.(type) {
+	case *expr.Select:
+		p.sel(e, nil, nil)
+	case *expr.Union:
+		p.body(e.Left)
+		p.brk(" ")
+		p.out.WriteString(e.Type.String())
+		p.brk(" ")
+		p.body(e.Right)
+	default:
+		p.expr(e)
+	}
+}
+
+func (p *printer) binding(b *expr.Binding) {
+	if s, ok := b.Expr.(*expr.Select); ok {
+		p.paren(s)
+	} else {
+		p.expr(b.Expr)
+	}
+	if b.Explicit() {
+		p.out.WriteString(" AS ")
+		p.out.WriteString(expr.QuoteID(b.Result()))
+	}
+}
+
+func (p *printer) from(f expr.From) {
+	switch f := f.(type) {
+	case *expr.Table:
+		p.binding(&f.Binding)
+	case *expr.Join:
+		p.from(f.Left)
+		p.brk(" ")
+		p.out.WriteString(f.Kind.String())
+		p.out.WriteByte(' ')
+		p.binding(&f.Right)
+		if f.On != nil {
+			p.out.WriteString(" ON ")
+			p.expr(f.On)
+		}
+	default:
+		p.expr(f)
+	}
+}
+
+func (p *printer) sel(s *expr.Select, into expr.Node, partitions []string) {
+	kw := "SELECT"
+	if s.Distinct {
+		kw = "SELECT DISTINCT"
+	} else if s.DistinctExpr != nil {
+		var tmp strings.Builder
+		tmp.WriteString("SELECT DISTINCT ON (")
+		for i := range s.DistinctExpr {
+			if i > 0 {
+				tmp.WriteString(", ")
+			}
+			tmp.WriteString(expr.ToString(s.DistinctExpr[i]))
+		}
+		tmp.WriteByte(')')
+		kw = tmp.String()
+	}
+	p.list(kw, len(s.Columns), func(i int) { p.binding(&s.Columns[i]) })
+	if into != nil {
+		p.brk(" ")
+		p.out.WriteString("INTO ")
+		p.expr(into)
+		p.partitions(partitions)
+	}
+	if s.From != nil {
+		p.brk(" ")
+		p.out.WriteString("FROM ")
+		p.from(s.From)
+	}
+	if s.Where != nil {
+		p.brk(" ")
+		p.out.WriteString("WHERE ")
+		p.expr(s.Where)
+	}
+	if s.GroupBy != nil {
+		p.brk(" ")
+		p.list("GROUP BY", len(s.GroupBy), func(i int) { p.binding(&s.GroupBy[i]) })
+	}
+	if s.Having != nil {
+		p.brk(" ")
+		p.out.WriteString("HAVING ")
+		p.expr(s.Having)
+	}
+	if s.Qualify != nil {
+		p.brk(" ")
+		p.out.WriteString("QUALIFY ")
+		p.expr(s.Qualify)
+	}
+	if s.OrderBy != nil {
+		p.brk(" ")
+		p.list("ORDER BY", len(s.OrderBy), func(i int) { p.out.WriteString(expr.ToString(&s.OrderBy[i])) })
+		if s.Preserve {
+			p.out.WriteString(" PRESERVE")
+		}
+	}
+	if s.Limit != nil {
+		p.brk(" ")
+		fmt.Fprintf(&p.out, "LIMIT %d", int64(*s.Limit))
+	}
+	if s.Offset != nil {
+		p.brk(" ")
+		fmt.Fprintf(&p.out, "OFFSET %d", int64(*s.Offset))
+	}
+}
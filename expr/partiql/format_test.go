@@ -0,0 +1,97 @@
+// Copyright (C) 2022 Sneller, Inc.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package partiql
+
+import (
+	"testing"
+
+	"github.com/SnellerInc/sneller/expr"
+)
+
+func TestPrinterRoundTrip(t *testing.T) {
+	queries := append([]string{
+		`WITH t AS (SELECT x, y FROM table WHERE x > 0), u AS (SELECT DISTINCT y FROM t) SELECT * FROM u UNION ALL SELECT y FROM t`,
+		`SELECT a.x, b.y FROM (SELECT x FROM foo WHERE x IS NOT NULL) AS a JOIN bar AS b ON a.x = b.x`,
+		`SELECT DISTINCT ON (x, y) x, y, z FROM table ORDER BY x DESC NULLS LAST, y ASC LIMIT 10 OFFSET 5`,
+		`SELECT x, COUNT(*) FROM table GROUP BY x HAVING COUNT(*) > 1`,
+		`SELECT * INTO db.out PARTITIONED BY (x, y) FROM table`,
+		`INSERT INTO db.out SELECT x FROM table WHERE x IN (SELECT y FROM other)`,
+	}, sameq...)
+	indents := []string{"", "  ", "\t"}
+	for i := range queries {
+		q, err := Parse([]byte(queries[i]))
+		if err != nil {
+			t.Fatalf("%q: %s", queries[i], err)
+		}
+		for _, indent := range indents {
+			p := Printer{Indent: indent}
+			text, err := p.Print(q)
+			if err != nil {
+				t.Errorf("%q with indent %q: %s", queries[i], indent, err)
+				continue
+			}
+			if indent == "" && text != q.Text() {
+				t.Errorf("single-line output %q != %q", text, q.Text())
+			}
+			// printing is idempotent
+			again, err := p.Format([]byte(text))
+			if err != nil {
+				t.Errorf("%q: %s", text, err)
+			} else if again != text {
+				t.Errorf("re-formatting %q produced %q", text, again)
+			}
+		}
+	}
+}
+
+func TestPrinterIndent(t *testing.T) {
+	in := `with t as (select x, y from table where x > 0) select x, sum(y) as s from t group by x order by s desc limit 3`
+	want := `WITH t AS (
+  SELECT
+    x,
+    y
+  FROM table
+  WHERE x > 0
+)
+SELECT
+  x,
+  SUM(y) AS s
+FROM t
+GROUP BY x
+ORDER BY s DESC NULLS FIRST
+LIMIT 3`
+	p := Printer{Indent: "  "}
+	got, err := p.Format([]byte(in))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestPrinterRejects(t *testing.T) {
+	q, err := Parse([]byte(`SELECT x FROM table`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// builtins introduced by the query planner
+	// cannot be expressed in the query text
+	q.Body.(*expr.Select).Where = expr.Compare(expr.Equals, expr.Ident("x"), expr.Call(expr.ScalarReplacement, expr.Integer(0)))
+	var p Printer
+	if _, err := p.Print(q); err == nil {
+		t.Fatal("expected an error")
+	}
+}
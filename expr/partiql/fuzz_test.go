@@ -31,3 +31,32 @@ func FuzzParse(f *testing.F) {
 		Parse(text)
 	})
 }
+
+func FuzzPrint(f *testing.F) {
+	// test that the printer doesn't panic
+	// and that its output is stable; Print
+	// itself checks that its output parses
+	// into an equivalent query
+	for i := range sameq {
+		f.Add([]byte(sameq[i]))
+	}
+	f.Add([]byte("SELECT '\x12\xff'"))
+	f.Fuzz(func(t *testing.T, text []byte) {
+		q, err := Parse(text)
+		if err != nil {
+			return
+		}
+		p := Printer{Indent: "  "}
+		out, err := p.Print(q)
+		if err != nil {
+			return
+		}
+		again, err := p.Format([]byte(out))
+		if err != nil {
+			t.Fatalf("%q: %s", out, err)
+		}
+		if again != out {
+			t.Fatalf("%q re-formatted as %q", out, again)
+		}
+	})
+}
@@ -21,10 +21,12 @@ package partiql
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"io"
 	"math/big"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/SnellerInc/sneller/date"
@@ -46,9 +48,24 @@ var faketime *expr.Timestamp
 type scanner struct {
 	from []byte
 	pos  int
+	// tokpos is the starting offset
+	// of the most recently lexed token
+	tokpos int
+	// idents records the positions of
+	// every occurrence of each identifier
+	idents expr.Positions
 
 	err    error
 	result *expr.Query
+	// delete is the result if the
+	// statement is a DELETE
+	delete *expr.Delete
+	// ddl is the result if the statement
+	// is a CREATE, ALTER, or DROP statement
+	ddl expr.DDL
+	// ddlmode is set once the first word of
+	// the statement has introduced a DDL statement
+	ddlmode bool
 	// notkw is set when
 	// we are not in keyword context
 	notkw bool
@@ -60,6 +77,9 @@ type scanner struct {
 	// to produce the same time exactly,
 	// so we can't call time.Now() more than once)
 	now *expr.Timestamp
+
+	// dialect of SQL to accept
+	dialect Dialect
 }
 
 func (s *scanner) utcnow() *expr.Timestamp {
@@ -240,9 +260,11 @@ func (s *scanner) Lex(l *yySymType) int {
 
 func (s *scanner) lex(l *yySymType) int {
 	if s.err != nil || s.pos >= len(s.from) {
+		s.tokpos = s.pos
 		return eof
 	}
 	s.chompws()
+	s.tokpos = s.pos
 	if s.err != nil || s.pos >= len(s.from) {
 		return eof
 	}
@@ -397,12 +419,29 @@ func (s *scanner) lexIdent(l *yySymType) int {
 		s.pos++
 	}
 	wordend := s.pos == len(s.from) || issep(s.from[s.pos])
+	if s.lastsym == 0 && wordend {
+		// keywords that introduce statements other
+		// than queries are only recognized as the first
+		// word of a statement, so they remain
+		// available as identifiers elsewhere
+		if term, ok := statementKeywords[strings.ToUpper(string(s.from[startpos:s.pos]))]; ok {
+			s.ddlmode = term != DELETE
+			return term
+		}
+	}
+	if s.ddlmode && !s.notkw && wordend {
+		// the words of a DDL statement are only
+		// keywords inside DDL statements
+		if term, ok := ddlKeywords[strings.ToUpper(string(s.from[startpos:s.pos]))]; ok {
+			return term
+		}
+	}
 	if !s.notkw && wordend {
 		// don't perform string allocation if we have a keyword
 		term, enum := lookupKeyword(s.from[startpos:s.pos])
-		if term == AGGREGATE {
+		if term == AGGREGATE || term == AGGREGATE_IF {
 			l.integer = enum
-			return AGGREGATE
+			return term
 		} else if term != -1 {
 			// SQL keyword following AS or BY, interpret the
 			// next word as a case-sensitive identifier
@@ -415,9 +454,51 @@ func (s *scanner) lexIdent(l *yySymType) int {
 	}
 	s.notkw = s.notkw || !wordend
 	l.str = string(s.from[startpos:s.pos])
+	s.ident(startpos, l.str)
 	return ID
 }
 
+// statementKeywords are the keywords that
+// may only appear at the start of a statement
+var statementKeywords = map[string]int{
+	"DELETE": DELETE,
+	"CREATE": CREATE,
+	"ALTER":  ALTER,
+	"DROP":   DROP,
+}
+
+// ddlKeywords are the keywords that are
+// only recognized inside DDL statements
+var ddlKeywords = map[string]int{
+	"TABLE":       TABLE,
+	"IF":          IF,
+	"SET":         SET,
+	"DROP":        DROP,
+	"SORT":        SORT,
+	"RETENTION":   RETENTION,
+	"FOR":         FOR,
+	"FORMAT":      FORMAT,
+	"INCREMENTAL": INCREMENTAL,
+}
+
+// ident records the position of an
+// occurrence of identifier str starting
+// at offset pos
+func (s *scanner) ident(pos int, str string) {
+	lst := s.idents[str]
+	if len(lst) > 0 && lst[len(lst)-1].Offset >= pos {
+		return // already seen
+	}
+	line, col, ok := s.position(pos)
+	if !ok {
+		return
+	}
+	if s.idents == nil {
+		s.idents = make(expr.Positions)
+	}
+	s.idents[str] = append(lst, expr.Position{Offset: pos, Line: line, Column: col})
+}
+
 // lexNumber lexes a number-like thing
 // (NOTE: this is too permissive; we do the actual
 // checking for valid numbers at parse time)
@@ -514,6 +595,7 @@ func (s *scanner) lexQuotedIdent(l *yySymType) int {
 	s.pos++
 	if !needquote {
 		l.str = string(s.from[startpos+1 : s.pos-1])
+		s.ident(startpos, l.str)
 		return ID
 	}
 	out, err := strconv.Unquote(string(s.from[startpos:s.pos]))
@@ -522,6 +604,7 @@ func (s *scanner) lexQuotedIdent(l *yySymType) int {
 		return ERROR
 	}
 	l.str = out
+	s.ident(startpos, l.str)
 	return ID
 }
 
@@ -605,31 +688,83 @@ func toint(e expr.Node) (int, error) {
 	return int(r.Num().Int64()), nil
 }
 
+// toIndex produces inner[idx]; a constant string
+// index is a field lookup and a constant integer
+// index (including a negated one) is an expr.Index
+func toIndex(inner, idx expr.Node, yylex yyLexer) expr.Node {
+	switch i := idx.(type) {
+	case expr.String:
+		return &expr.Dot{Inner: inner, Field: string(i)}
+	case expr.Integer, expr.Float, *expr.Rational:
+		n, err := toint(i)
+		if err != nil {
+			yylex.Error(err.Error())
+		}
+		return &expr.Index{Inner: inner, Offset: n}
+	case *expr.UnaryArith:
+		if n, ok := i.Child.(expr.Integer); ok && i.Op == expr.NegOp {
+			return &expr.Index{Inner: inner, Offset: -int(n)}
+		}
+	}
+	return expr.Call(expr.ArrayElement, inner, idx)
+}
+
 func (s *scanner) mkerror(length int, msg string, args ...any) *LexerError {
+	return s.mkerrorAt(s.pos, length, fmt.Sprintf(msg, args...))
+}
+
+func (s *scanner) mkerrorAt(pos, length int, msg string) *LexerError {
 	err := &LexerError{}
-	err.Message = fmt.Sprintf(msg, args...)
-	err.Position = s.pos
+	err.Message = msg
+	err.Position = pos
 	err.Length = length
 	err.Line, err.Column, _ = s.position(err.Position)
-
+	if length > 0 && pos+length <= len(s.from) {
+		err.Token = string(s.from[pos : pos+length])
+	}
 	return err
 }
 
+// Error is called by the parser
+// on a syntax error; the error is
+// attributed to the most recent token
 func (s *scanner) Error(msg string) {
 	if s.err != nil {
 		return
 	}
 
-	s.err = s.mkerror(0, msg)
+	s.err = s.mkerrorAt(s.tokpos, s.pos-s.tokpos, msg)
+}
+
+// wrap converts an error produced while
+// lexing the most recent token into a *LexerError
+func (s *scanner) wrap(err error) error {
+	var lexerr *LexerError
+	if errors.As(err, &lexerr) {
+		return err
+	}
+	ret := s.mkerrorAt(s.tokpos, s.pos-s.tokpos, err.Error())
+	ret.Err = err
+	return ret
 }
 
-// LexerError describes a lexing error
+// LexerError describes a lexing or parsing error
 type LexerError struct {
 	Position int    // offset in the input string
 	Line     int    // line
 	Column   int    // column
 	Length   int    // length of wrong substring (0 if unknown)
+	Token    string // the offending token (empty if unknown)
 	Message  string // textual description of an error
+	Err      error  // underlying error, if any
+}
+
+// Unwrap returns the underlying error, if any.
+func (e *LexerError) Unwrap() error { return e.Err }
+
+// Pos returns the location of the error.
+func (e *LexerError) Pos() expr.Position {
+	return expr.Position{Offset: e.Position, Line: e.Line, Column: e.Column}
 }
 
 func (e *LexerError) Error() string {
@@ -651,6 +786,28 @@ func toAggregate(op expr.AggregateOp, distinct bool, args []expr.Node, filter ex
 	return agg, nil
 }
 
+// toConditionalAggregate desugars COUNT_IF(cond),
+// SUM_IF(x, cond) and AVG_IF(x, cond) into the
+// equivalent aggregate with a FILTER clause
+func toConditionalAggregate(op expr.AggregateOp, args []expr.Node, filter expr.Node, over *expr.Window) (*expr.Aggregate, error) {
+	want := 2
+	if op == expr.OpCount {
+		want = 1
+	}
+	if len(args) != want {
+		return nil, fmt.Errorf("%v_IF: expected %d argument(s), got %d", op, want, len(args))
+	}
+	cond := args[want-1]
+	if filter != nil {
+		cond = expr.And(cond, filter)
+	}
+	var inner expr.Node = exprstar
+	if op != expr.OpCount {
+		inner = args[0]
+	}
+	return toAggregate(op, false, []expr.Node{inner}, cond, over)
+}
+
 func toAggregateAux(op expr.AggregateOp, distinct bool, args []expr.Node, filter expr.Node, over *expr.Window) (*expr.Aggregate, error) {
 	var body expr.Node
 	if len(args) > 0 {
@@ -659,12 +816,10 @@ func toAggregateAux(op expr.AggregateOp, distinct bool, args []expr.Node, filter
 	}
 
 	if distinct {
-		if op == expr.OpCount {
-			op = expr.OpCountDistinct
-		}
 		if !op.AcceptDistinct() {
 			return nil, fmt.Errorf("does not accept DISTINCT")
 		}
+		op = op.WithDistinct()
 	}
 
 	if expr.Equal(body, exprstar) {
@@ -681,6 +836,9 @@ func toAggregateAux(op expr.AggregateOp, distinct bool, args []expr.Node, filter
 	case expr.OpApproxCountDistinct:
 		return createApproxCountDistinct(body, args, filter, over)
 
+	case expr.OpSystemDatashape:
+		return createDatashape(body, args, filter, over)
+
 	default:
 		if len(args) > 0 {
 			return nil, fmt.Errorf("does not accept arguments")
@@ -717,6 +875,36 @@ func createApproxCountDistinct(body expr.Node, args []expr.Node, filter expr.Nod
 		Filter:    filter}, nil
 }
 
+func createDatashape(body expr.Node, args []expr.Node, filter expr.Node, over *expr.Window) (*expr.Aggregate, error) {
+	if len(args) > 1 {
+		return nil, fmt.Errorf("accepts at most 1 argument")
+	}
+
+	mode := expr.DatashapeTypes
+	if len(args) == 1 {
+		str, ok := args[0].(expr.String)
+		if !ok {
+			return nil, fmt.Errorf("mode has to be a constant string")
+		}
+
+		switch strings.ToLower(string(str)) {
+		case expr.DatashapeTypes.String():
+			mode = expr.DatashapeTypes
+		case expr.DatashapeHistograms.String():
+			mode = expr.DatashapeHistograms
+		default:
+			return nil, fmt.Errorf("mode has to be either %q or %q", expr.DatashapeTypes, expr.DatashapeHistograms)
+		}
+	}
+
+	return &expr.Aggregate{
+		Op:        expr.OpSystemDatashape,
+		Datashape: mode,
+		Inner:     body,
+		Over:      over,
+		Filter:    filter}, nil
+}
+
 func createCase(optionalExpr expr.Node, limbs []expr.CaseLimb, elseExpr expr.Node) expr.Node {
 	if optionalExpr != nil {
 		// "simplified" CASE
@@ -15,7 +15,13 @@
 package partiql
 
 import (
+	"errors"
+	"io"
 	"testing"
+
+	"github.com/SnellerInc/sneller/expr"
+
+	"golang.org/x/exp/slices"
 )
 
 func TestScannerPosition(t *testing.T) {
@@ -46,3 +52,101 @@ func TestScannerPosition(t *testing.T) {
 		}
 	}
 }
+
+func TestErrorPosition(t *testing.T) {
+	testcases := []struct {
+		query       string
+		line, col   int
+		offset      int
+		token       string
+		unexpectEOF bool
+	}{
+		{
+			query:  "SELECT x\nFROM t WHERE y = = 3",
+			line:   2,
+			col:    18,
+			offset: 26,
+			token:  "=",
+		},
+		{
+			query:  "SELECT x FROM t WHERE ;",
+			line:   1,
+			col:    23,
+			offset: 22,
+			token:  ";",
+		},
+		{
+			// unexpected end of input
+			query:  "SELECT x FROM t WHERE # comment",
+			line:   1,
+			col:    32,
+			offset: 31,
+		},
+		{
+			query:       "SELECT x,\n  \"abc FROM t",
+			line:        2,
+			col:         3,
+			offset:      12,
+			unexpectEOF: true,
+		},
+	}
+	for i := range testcases {
+		tc := &testcases[i]
+		_, err := Parse([]byte(tc.query))
+		var lexerr *LexerError
+		if !errors.As(err, &lexerr) {
+			t.Errorf("%q: got error %v (%T)", tc.query, err, err)
+			continue
+		}
+		pos := lexerr.Pos()
+		if pos.Line != tc.line || pos.Column != tc.col || pos.Offset != tc.offset {
+			t.Errorf("%q: got position %+v", tc.query, pos)
+		}
+		if tc.token != "" && lexerr.Token != tc.token {
+			t.Errorf("%q: got token %q, expected %q", tc.query, lexerr.Token, tc.token)
+		}
+		if tc.unexpectEOF && !errors.Is(err, io.ErrUnexpectedEOF) {
+			t.Errorf("%q: expected io.ErrUnexpectedEOF; got %v", tc.query, err)
+		}
+	}
+}
+
+func TestIdentPositions(t *testing.T) {
+	q, err := Parse([]byte("SELECT x,\n  \"y\" FROM t WHERE x > 0"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := expr.Positions{
+		"x": {
+			{Offset: 7, Line: 1, Column: 8},
+			{Offset: 29, Line: 2, Column: 20},
+		},
+		"y": {{Offset: 12, Line: 2, Column: 3}},
+		"t": {{Offset: 21, Line: 2, Column: 12}},
+	}
+	for id, p := range want {
+		if got := q.Positions[id]; !slices.Equal(got, p) {
+			t.Errorf("%s: got %+v, expected %+v", id, got, p)
+		}
+	}
+	pos, ok := q.Positions.Of(expr.Add(expr.Integer(1), expr.MakePath([]string{"y", "z"})))
+	if !ok || pos != want["y"][0] {
+		t.Errorf("Of: got %+v, %v", pos, ok)
+	}
+
+	// an expression is located by the occurrence
+	// of its first identifier that is followed
+	// most closely by its other identifiers
+	q, err = Parse([]byte("SELECT x, y FROM t WHERE y + x > 0"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pos, ok = q.Positions.Of(expr.Add(expr.Ident("y"), expr.Ident("x")))
+	if !ok || pos.Offset != 25 {
+		t.Errorf("Of(y + x): got %+v, %v", pos, ok)
+	}
+	pos, ok = q.Positions.Of(expr.Add(expr.Ident("x"), expr.Ident("y")))
+	if !ok || pos.Offset != 7 {
+		t.Errorf("Of(x + y): got %+v, %v", pos, ok)
+	}
+}
@@ -195,6 +195,9 @@ func lookupKeyword(word []byte) (int, int) {
 			if equalASCIILetters5([5]byte(word), [5]byte{'N', 'U', 'L', 'L', 'S'}) {
 				return NULLS, -1
 			}
+			if equalASCIILetters5([5]byte(word), [5]byte{'N', 'T', 'I', 'L', 'E'}) {
+				return AGGREGATE, int(expr.OpNtile)
+			}
 		case 'O':
 			if equalASCIILetters5([5]byte(word), [5]byte{'O', 'R', 'D', 'E', 'R'}) {
 				return ORDER, -1
@@ -218,6 +221,10 @@ func lookupKeyword(word []byte) (int, int) {
 		}
 	case 6:
 		switch asciiUpper(word[0]) {
+		case 'A':
+			if equalASCII(word, []byte("AVG_IF")) {
+				return AGGREGATE_IF, int(expr.OpAvg)
+			}
 		case 'B':
 			if equalASCII(word, []byte("BIT_OR")) {
 				return AGGREGATE, int(expr.OpBitOr)
@@ -241,6 +248,10 @@ func lookupKeyword(word []byte) (int, int) {
 			if equalASCIILetters6([6]byte(word), [6]byte{'H', 'A', 'V', 'I', 'N', 'G'}) {
 				return HAVING, -1
 			}
+		case 'I':
+			if equalASCIILetters6([6]byte(word), [6]byte{'I', 'N', 'S', 'E', 'R', 'T'}) {
+				return INSERT, -1
+			}
 		case 'L':
 			if equalASCIILetters6([6]byte(word), [6]byte{'L', 'A', 'T', 'E', 'S', 'T'}) {
 				return AGGREGATE, int(expr.OpLatest)
@@ -260,49 +271,68 @@ func lookupKeyword(word []byte) (int, int) {
 			if equalASCIILetters6([6]byte(word), [6]byte{'S', 'T', 'D', 'D', 'E', 'V'}) {
 				return AGGREGATE, int(expr.OpStdDevPop)
 			}
+			if equalASCII(word, []byte("SUM_IF")) {
+				return AGGREGATE_IF, int(expr.OpSum)
+			}
 		case 'U':
 			if equalASCIILetters6([6]byte(word), [6]byte{'U', 'T', 'C', 'N', 'O', 'W'}) {
 				return UTCNOW, -1
 			}
+			if equalASCIILetters6([6]byte(word), [6]byte{'U', 'N', 'N', 'E', 'S', 'T'}) {
+				return UNNEST, -1
+			}
+		case 'V':
+			if equalASCIILetters6([6]byte(word), [6]byte{'V', 'A', 'L', 'U', 'E', 'S'}) {
+				return VALUES, -1
+			}
 		}
 	case 7:
-		switch asciiUpper(word[3]) {
-		case 'D':
-			if equalASCIILetters7([7]byte(word), [7]byte{'L', 'E', 'A', 'D', 'I', 'N', 'G'}) {
-				return LEADING, -1
-			}
-		case 'I':
-			if equalASCIILetters7([7]byte(word), [7]byte{'S', 'I', 'M', 'I', 'L', 'A', 'R'}) {
-				return SIMILAR, -1
+		switch asciiUpper(word[0]) {
+		case 'B':
+			switch asciiUpper(word[4]) {
+			case 'A':
+				if equalASCII(word, []byte("BIT_AND")) {
+					return AGGREGATE, int(expr.OpBitAnd)
+				}
+			case 'E':
+				if equalASCIILetters7([7]byte(word), [7]byte{'B', 'E', 'T', 'W', 'E', 'E', 'N'}) {
+					return BETWEEN, -1
+				}
+			case 'X':
+				if equalASCII(word, []byte("BIT_XOR")) {
+					return AGGREGATE, int(expr.OpBitXor)
+				}
+			case '_':
+				if equalASCII(word, []byte("BOOL_OR")) {
+					return AGGREGATE, int(expr.OpBoolOr)
+				}
 			}
-			if equalASCIILetters7([7]byte(word), [7]byte{'U', 'N', 'P', 'I', 'V', 'O', 'T'}) {
-				return UNPIVOT, -1
+		case 'E':
+			if equalASCIILetters7([7]byte(word), [7]byte{'E', 'X', 'T', 'R', 'A', 'C', 'T'}) {
+				return EXTRACT, -1
 			}
-		case 'L':
 			if equalASCIILetters7([7]byte(word), [7]byte{'E', 'X', 'P', 'L', 'A', 'I', 'N'}) {
 				return EXPLAIN, -1
 			}
-			if equalASCII(word, []byte("BOOL_OR")) {
-				return AGGREGATE, int(expr.OpBoolOr)
-			}
-		case 'R':
-			if equalASCIILetters7([7]byte(word), [7]byte{'E', 'X', 'T', 'R', 'A', 'C', 'T'}) {
-				return EXTRACT, -1
+		case 'L':
+			if equalASCIILetters7([7]byte(word), [7]byte{'L', 'E', 'A', 'D', 'I', 'N', 'G'}) {
+				return LEADING, -1
 			}
-		case 'S':
+		case 'M':
 			if equalASCIILetters7([7]byte(word), [7]byte{'M', 'I', 'S', 'S', 'I', 'N', 'G'}) {
 				return MISSING, -1
 			}
-		case 'W':
-			if equalASCIILetters7([7]byte(word), [7]byte{'B', 'E', 'T', 'W', 'E', 'E', 'N'}) {
-				return BETWEEN, -1
+		case 'Q':
+			if equalASCIILetters7([7]byte(word), [7]byte{'Q', 'U', 'A', 'L', 'I', 'F', 'Y'}) {
+				return QUALIFY, -1
 			}
-		case '_':
-			if equalASCII(word, []byte("BIT_AND")) {
-				return AGGREGATE, int(expr.OpBitAnd)
+		case 'S':
+			if equalASCIILetters7([7]byte(word), [7]byte{'S', 'I', 'M', 'I', 'L', 'A', 'R'}) {
+				return SIMILAR, -1
 			}
-			if equalASCII(word, []byte("BIT_XOR")) {
-				return AGGREGATE, int(expr.OpBitXor)
+		case 'U':
+			if equalASCIILetters7([7]byte(word), [7]byte{'U', 'N', 'P', 'I', 'V', 'O', 'T'}) {
+				return UNPIVOT, -1
 			}
 		}
 	case 8:
@@ -315,6 +345,9 @@ func lookupKeyword(word []byte) (int, int) {
 			if equalASCIILetters8([8]byte(word), [8]byte{'C', 'O', 'A', 'L', 'E', 'S', 'C', 'E'}) {
 				return COALESCE, -1
 			}
+			if equalASCII(word, []byte("COUNT_IF")) {
+				return AGGREGATE_IF, int(expr.OpCount)
+			}
 		case 'D':
 			if equalASCII(word, []byte("DATE_ADD")) {
 				return DATE_ADD, -1
@@ -342,6 +375,9 @@ func lookupKeyword(word []byte) (int, int) {
 		if equalASCIILetters9([9]byte(word), [9]byte{'P', 'A', 'R', 'T', 'I', 'T', 'I', 'O', 'N'}) {
 			return PARTITION, -1
 		}
+		if equalASCII(word, []byte("CUME_DIST")) {
+			return AGGREGATE, int(expr.OpCumeDist)
+		}
 	case 10:
 		switch asciiUpper(word[1]) {
 		case 'A':
@@ -361,10 +397,17 @@ func lookupKeyword(word []byte) (int, int) {
 				return AGGREGATE, int(expr.OpStdDevPop)
 			}
 		}
+	case 11:
+		if equalASCIILetters11([11]byte(word), [11]byte{'P', 'A', 'R', 'T', 'I', 'T', 'I', 'O', 'N', 'E', 'D'}) {
+			return PARTITIONED, -1
+		}
 	case 12:
 		if equalASCII(word, []byte("VARIANCE_POP")) {
 			return AGGREGATE, int(expr.OpVariancePop)
 		}
+		if equalASCII(word, []byte("PERCENT_RANK")) {
+			return AGGREGATE, int(expr.OpPercentRank)
+		}
 	case 17:
 		if equalASCII(word, []byte("SNELLER_DATASHAPE")) {
 			return AGGREGATE, int(expr.OpSystemDatashape)
@@ -431,4 +474,13 @@ func equalASCIILetters9(anyCase [9]byte, upperCaseLetters [9]byte) bool {
 	return true
 }
 
-// checksum: 7590873419eae19568dda65d569a9ada
+func equalASCIILetters11(anyCase [11]byte, upperCaseLetters [11]byte) bool {
+	for i := range upperCaseLetters {
+		if (upperCaseLetters[i]^anyCase[i])&0xdf != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// checksum: 728cc8288a468fd10e3da6cc5d7783a1
@@ -45,19 +45,83 @@ func dropParser(p *yyParserImpl) {
 // and returns the result, or an error if one
 // is encountered.
 func Parse(in []byte) (*expr.Query, error) {
-	s := &scanner{from: in}
+	return parse(&scanner{from: in})
+}
+
+func parse(s *scanner) (*expr.Query, error) {
 	p := newParser()
 	ret := p.Parse(s)
 	dropParser(p)
 	if s.err != nil && s.err != io.EOF {
-		return nil, s.err
+		return nil, s.wrap(s.err)
 	}
 	if ret != 0 {
 		return nil, fmt.Errorf("parse error %d", ret)
 	}
+	if s.result == nil {
+		return nil, fmt.Errorf("statement is not a query")
+	}
+	s.result.Positions = s.idents
 	return s.result, nil
 }
 
+// leadingToken returns the first token
+// of the statement in
+func leadingToken(in []byte) int {
+	s := &scanner{from: in}
+	var l yySymType
+	return s.Lex(&l)
+}
+
+// IsDelete returns true if in is a DELETE statement
+// (that should be parsed with ParseDelete)
+// or false otherwise.
+func IsDelete(in []byte) bool {
+	return leadingToken(in) == DELETE
+}
+
+// ParseDelete parses a PartiQL statement of the form
+//
+//	DELETE FROM table [WHERE condition]
+//
+// and returns the result, or an error if one
+// is encountered.
+func ParseDelete(in []byte) (*expr.Delete, error) {
+	s := &scanner{from: in}
+	p := newParser()
+	ret := p.Parse(s)
+	dropParser(p)
+	if s.err != nil && s.err != io.EOF {
+		return nil, s.wrap(s.err)
+	}
+	if ret != 0 {
+		return nil, fmt.Errorf("parse error %d", ret)
+	}
+	if s.delete == nil {
+		return nil, fmt.Errorf("statement is not a DELETE")
+	}
+	return s.delete, nil
+}
+
+// buildDelete builds a DELETE statement
+// from the table expression and condition
+func buildDelete(table, where expr.Node) (*expr.Delete, error) {
+	ok := false
+	switch e := table.(type) {
+	case expr.Ident:
+		ok = true
+	case *expr.Dot:
+		_, ok = e.Inner.(expr.Ident)
+	}
+	if !ok {
+		return nil, fmt.Errorf("DELETE FROM requires a table name")
+	}
+	return &expr.Delete{
+		Table: &expr.Table{Binding: expr.Bind(table, "")},
+		Where: where,
+	}, nil
+}
+
 // we parse CAST() using identifiers
 // rather than keywords so that we can
 // preserve the invariant that the token
@@ -118,6 +182,14 @@ func weekday(id string) (expr.Weekday, bool) {
 	}
 }
 
+// isEpochPart returns true if id is the EPOCH
+// part of EXTRACT(EPOCH FROM ts), which is
+// the number of seconds since the Unix epoch
+// rather than a component of the timestamp
+func isEpochPart(id string) bool {
+	return strings.EqualFold(id, "EPOCH")
+}
+
 func timePart(id string) (expr.Timepart, bool) {
 	var part expr.Timepart
 	switch strings.ToUpper(id) {
@@ -145,6 +217,12 @@ func timePart(id string) (expr.Timepart, bool) {
 		part = expr.Quarter
 	case "YEAR":
 		part = expr.Year
+	case "ISODOW":
+		part = expr.ISODOW
+	case "ISOYEAR":
+		part = expr.ISOYear
+	case "ISOWEEK":
+		part = expr.ISOWeek
 	default:
 		return 0, false
 	}
@@ -165,19 +243,19 @@ func timePartFor(id, fn string) (expr.Timepart, bool) {
 	// reject parts that are not supported by some timestamp related functions
 	switch fn {
 	case "DATE_ADD":
-		if part == expr.DOW || part == expr.DOY {
+		if part == expr.DOW || part == expr.DOY || part >= expr.ISODOW {
 			return 0, false
 		}
 	case "DATE_DIFF":
-		if part == expr.DOW || part == expr.DOY {
+		if part == expr.DOW || part == expr.DOY || part >= expr.ISODOW {
 			return 0, false
 		}
 	case "DATE_TRUNC":
-		if part == expr.DOW || part == expr.DOY {
+		if part == expr.DOW || part == expr.DOY || part == expr.ISODOW || part == expr.ISOYear {
 			return 0, false
 		}
 	case "EXTRACT":
-		if part == expr.Week {
+		if part == expr.Week || part == expr.ISOWeek {
 			return 0, false
 		}
 	}
@@ -217,6 +295,51 @@ func decodeDistinct(nodes []expr.Node) (distinct bool, distinctExpr []expr.Node)
 	return false, nodes
 }
 
+// valuesTable converts the rows of a VALUES clause
+// into a constant list of structures with fields
+// named cols, or column1, column2, ... when cols is nil
+func valuesTable(rows [][]expr.Node, cols []string) (expr.Node, error) {
+	width := len(rows[0])
+	if cols == nil {
+		cols = make([]string, width)
+		for i := range cols {
+			cols[i] = fmt.Sprintf("column%d", i+1)
+		}
+	} else if len(cols) != width {
+		return &expr.List{}, fmt.Errorf("VALUES has %d columns but %d column names were given", width, len(cols))
+	}
+	lst := &expr.List{Values: make([]expr.Constant, len(rows))}
+	for i := range rows {
+		if len(rows[i]) != width {
+			return lst, fmt.Errorf("VALUES lists must all be the same length")
+		}
+		fields := make([]expr.Field, width)
+		for j := range rows[i] {
+			c, ok := expr.Simplify(rows[i][j], expr.NoHint).(expr.Constant)
+			if !ok {
+				return lst, fmt.Errorf("VALUES requires constant expressions; %s is not constant", expr.ToString(rows[i][j]))
+			}
+			fields[j] = expr.Field{Label: cols[j], Value: c}
+		}
+		lst.Values[i] = &expr.Struct{Fields: fields}
+	}
+	return lst, nil
+}
+
+// unnestValues binds each of the lists in
+// UNNEST(a, b, ...) AS (x, y, ...) to its name
+func unnestValues(lists []expr.Node, names []string) (*expr.Unnest, error) {
+	u := &expr.Unnest{}
+	if len(lists) != len(names) {
+		return u, fmt.Errorf("UNNEST has %d arguments but %d names were given", len(lists), len(names))
+	}
+	u.Values = make([]expr.Binding, len(lists))
+	for i := range lists {
+		u.Values[i] = expr.Bind(lists[i], names[i])
+	}
+	return u, nil
+}
+
 const (
 	trimLeading = iota
 	trimTrailing
@@ -247,8 +370,9 @@ func createTrimInvocation(trimType int, str, charset expr.Node) (expr.Node, erro
 }
 
 type selectWithInto struct {
-	sel  *expr.Select
-	into expr.Node
+	sel        *expr.Select
+	into       expr.Node
+	partitions []string
 }
 
 type unionItem struct {
@@ -275,16 +399,34 @@ func buildUnion(n expr.Node, unions []unionItem) expr.Node {
 	}
 }
 
+func buildInsert(explain string, into expr.Node, partitions []string, sel *expr.Select) (*expr.Query, error) {
+	exp, err := parseExplain(explain)
+	if err != nil {
+		return nil, err
+	}
+	return &expr.Query{
+		Explain:     exp,
+		Into:        into,
+		PartitionBy: partitions,
+		Append:      true,
+		Body:        sel,
+	}, nil
+}
+
 func buildQuery(explain string, with []expr.CTE, selinto selectWithInto, unions []unionItem) (*expr.Query, error) {
 	exp, err := parseExplain(explain)
 	if err != nil {
 		return nil, err
 	}
 
+	if selinto.partitions != nil && selinto.into == nil {
+		return nil, fmt.Errorf("PARTITIONED BY requires INTO")
+	}
 	return &expr.Query{
-		Explain: exp,
-		With:    with,
-		Into:    selinto.into,
-		Body:    buildUnion(selinto.sel, unions),
+		Explain:     exp,
+		With:        with,
+		Into:        selinto.into,
+		PartitionBy: selinto.partitions,
+		Body:        buildUnion(selinto.sel, unions),
 	}, nil
 }
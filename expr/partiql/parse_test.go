@@ -33,6 +33,10 @@ var sameq = []string{
 	"SELECT x FROM table WHERE x[0] = 'foo'",
 	"SELECT x FROM table WHERE x[0][1] = 'foo'",
 	"SELECT x FROM 'string' WHERE x[0].y[3] = 'foo'",
+	"SELECT t.x[*].y AS ys, t.s.*.z AS zs FROM table AS t",
+	"SELECT ARRAY_SIZE(x[*].y[*]) FROM table",
+	"SELECT x[-1], x[-2].y FROM table",
+	"SELECT x[i], x[i + 1].y, x[ARRAY_SIZE(x) - 1] FROM table",
 	"SELECT x FROM table AS t WHERE 'foo' = 'bar'",
 	`SELECT * FROM NDJSON('{"foo": 1, "bar": 2}')`,
 	// test that identifiers matching keywords are double-quoted when displayed:
@@ -49,6 +53,8 @@ var sameq = []string{
 	"SELECT x, x LIKE 'foo%' FROM table AS t",
 	"SELECT COUNT(*) FROM table WHERE x + y <= z",
 	"SELECT COUNT(DISTINCT x) FROM y",
+	"SELECT SUM(DISTINCT x) FROM y",
+	"SELECT AVG(DISTINCT x) FROM y GROUP BY z",
 	"SELECT SUM(foo) FROM table WHERE x = y AND y = z AND z IS NULL",
 	"SELECT MIN(lo), MAX(hi) AS \"limit\" FROM table WHERE x <> 3 GROUP BY x LIMIT 100",
 	"SELECT l.x, r.y FROM 'first' AS l JOIN second AS r ON l.id = r.id",
@@ -71,6 +77,9 @@ var sameq = []string{
 	"WITH foo AS (SELECT x, y FROM table), bar AS (SELECT z, a FROM table) SELECT x FROM foo CROSS JOIN bar",
 	"SELECT * FROM (t1 ++ t2 ++ t3)",
 	"SELECT x, y INTO db.xyz FROM db.foo WHERE x = 'foo' AND y = 'bar'",
+	"SELECT x, y, dt INTO db.xyz PARTITIONED BY (dt, y) FROM db.foo WHERE x = 'foo'",
+	"INSERT INTO db.xyz SELECT x, y FROM db.foo WHERE x = 'foo'",
+	"INSERT INTO db.xyz PARTITIONED BY (dt) SELECT x, dt FROM db.foo",
 	"SELECT x, SUM(x) OVER (PARTITION BY y, z ORDER BY col0 ASC NULLS FIRST, col1 DESC NULLS FIRST) FROM db.foo",
 	"SELECT COUNT(*) FROM table",
 	"SELECT COUNT(*) AS total, COUNT(x) FILTER (WHERE x > 0) AS greater FROM table",
@@ -92,10 +101,14 @@ var sameq = []string{
 	`EXPLAIN AS list SELECT * FROM table`,
 	`EXPLAIN AS graphviz SELECT * FROM table`,
 	`SELECT SNELLER_DATASHAPE(*) FROM table`,
+	`SELECT SNELLER_DATASHAPE(*, 'histograms') FROM table`,
 	`SELECT * FROM table1 UNION SELECT * FROM table2`,
 	`SELECT * FROM table1 UNION ALL SELECT * FROM table2`,
 	`SELECT * FROM table1 UNION SELECT * FROM table2 UNION ALL SELECT * FROM table3 UNION SELECT * FROM table4`,
 	`SELECT agg, SUM(x), ROW_NUMBER() OVER (ORDER BY SUM(x) ASC NULLS FIRST) FROM table GROUP BY agg`,
+	`SELECT agg, SUM(x), NTILE(4) OVER (ORDER BY SUM(x) ASC NULLS FIRST) FROM table GROUP BY agg`,
+	`SELECT agg, SUM(x), PERCENT_RANK() OVER (ORDER BY SUM(x) ASC NULLS FIRST), CUME_DIST() OVER (ORDER BY SUM(x) ASC NULLS FIRST) FROM table GROUP BY agg`,
+	`SELECT agg, SUM(x), ROW_NUMBER() OVER (ORDER BY SUM(x) DESC NULLS FIRST) AS rn FROM table GROUP BY agg QUALIFY rn <= 3`,
 }
 
 func TestParseSFW(t *testing.T) {
@@ -202,6 +215,20 @@ func TestParseNormalization(t *testing.T) {
 			"select {'x': 2}.x",
 			"SELECT 2",
 		},
+		{
+			// VALUES is a constant list of structures
+			"select t.x, t.y from (values (1, 'a'), (-2 + 1, null)) as t(x, y)",
+			"SELECT t.x, t.y FROM [{'x': 1, 'y': 'a'}, {'x': -1, 'y': NULL}] AS t",
+		},
+		{
+			"select column1, column2 from (values (1, 2), (3, 4)) t",
+			"SELECT column1, column2 FROM [{'column1': 1, 'column2': 2}, {'column1': 3, 'column2': 4}] AS t",
+		},
+		{
+			// UNNEST zips lists from the same row
+			"select x, y from foo as f, unnest(f.a, f.b) as (x, y)",
+			"SELECT x, y FROM foo AS f CROSS JOIN UNNEST(f.a, f.b) AS (x, y)",
+		},
 		{
 			// test parens
 			"select * from foo where ((a IS NULL) AND b IS NULL) OR c IS NULL",
@@ -212,6 +239,15 @@ func TestParseNormalization(t *testing.T) {
 			`select x || y || z from foo`,
 			`SELECT CONCAT(CONCAT(x, y), z) FROM foo`,
 		},
+		{
+			// conditional aggregates desugar to FILTER
+			"select count_if(x > 0), sum_if(y, x > 0), avg_if(y, x > 0) filter (where z) from foo",
+			"SELECT COUNT(*) FILTER (WHERE x > 0), SUM(y) FILTER (WHERE x > 0), AVG(y) FILTER (WHERE x > 0 AND z) FROM foo",
+		},
+		{
+			"select count_if(x > 0) over (partition by y) from foo",
+			"SELECT COUNT(*) FILTER (WHERE x > 0) OVER (PARTITION BY y) FROM foo",
+		},
 		{
 			// test IN
 			`select * from table where x IN (1)`,
@@ -242,9 +278,29 @@ func TestParseNormalization(t *testing.T) {
 			"SELECT DATE_TRUNC(minute, UTCNOW()) FROM foo",
 			"SELECT `2006-01-02T15:04:00Z` FROM foo",
 		},
+		{
+			"SELECT EXTRACT(isodow FROM x), EXTRACT(isoyear FROM x) FROM foo",
+			"SELECT DATE_EXTRACT_ISODOW(x), DATE_EXTRACT_ISOYEAR(x) FROM foo",
+		},
+		{
+			"SELECT EXTRACT(isodow FROM UTCNOW()), EXTRACT(isoyear FROM UTCNOW()) FROM foo",
+			"SELECT 1, 2006 FROM foo",
+		},
+		{
+			"SELECT EXTRACT(epoch FROM x), TO_UNIXTIME(x), FROM_UNIXTIME(y, 500) FROM foo",
+			"SELECT TO_UNIX_EPOCH(x), TO_UNIX_EPOCH(x), DATE_ADD_MILLISECOND(500, DATE_ADD_SECOND(y, `1970-01-01T00:00:00Z`)) FROM foo",
+		},
+		{
+			"SELECT EXTRACT(EPOCH FROM UTCNOW()), FROM_UNIXTIME(1136214245) FROM foo",
+			"SELECT 1136214245, `2006-01-02T15:04:05Z` FROM foo",
+		},
+		{
+			"SELECT DATE_TRUNC(isoweek, x) FROM foo",
+			"SELECT DATE_TRUNC_DOW(x, 1) FROM foo",
+		},
 		{
 			"SELECT * FROM foo WHERE x IN (SELECT COUNT(x) FROM foo ORDER BY COUNT(x) DESC NULLS FIRST LIMIT 5)",
-			"SELECT * FROM foo WHERE IN_SUBQUERY(x, (SELECT COUNT(x) FROM foo ORDER BY COUNT(x) DESC NULLS FIRST LIMIT 5))",
+			"SELECT * FROM foo WHERE x IN (SELECT COUNT(x) FROM foo ORDER BY COUNT(x) DESC NULLS FIRST LIMIT 5)",
 		},
 		{
 			"SELECT * FROM t1 ++ t2 ++ t3 WHERE foo = bar",
@@ -403,6 +459,10 @@ func TestParseErrors(t *testing.T) {
 			query: `SELECT x.foo[9999999999999999999] FROM table`,
 			msg:   `cannot use 1e+19 as an index`,
 		},
+		{
+			query: `SELECT x PARTITIONED BY (x) FROM table`,
+			msg:   `PARTITIONED BY requires INTO`,
+		},
 		{
 			query: `SELECT DATE_ADD(TEST, x, y)`,
 			msg:   `bad DATE_ADD part "TEST"`,
@@ -419,6 +479,26 @@ func TestParseErrors(t *testing.T) {
 			query: `SELECT EXTRACT(TEST FROM x)`,
 			msg:   `bad EXTRACT part "TEST"`,
 		},
+		{
+			query: `SELECT EXTRACT(ISOWEEK FROM x)`,
+			msg:   `bad EXTRACT part "ISOWEEK"`,
+		},
+		{
+			query: `SELECT DATE_TRUNC(ISOYEAR, x)`,
+			msg:   `bad DATE_TRUNC part "ISOYEAR"`,
+		},
+		{
+			query: `SELECT DATE_ADD(ISODOW, 1, x)`,
+			msg:   `bad DATE_ADD part "ISODOW"`,
+		},
+		{
+			query: `SELECT SUM_IF(x) FROM table`,
+			msg:   `SUM_IF: expected 2 argument(s), got 1`,
+		},
+		{
+			query: `SELECT COUNT_IF(x, y) FROM table`,
+			msg:   `COUNT_IF: expected 1 argument(s), got 2`,
+		},
 		{
 			query: `SELECT CONTAINS(x)`,
 			msg:   `cannot use reserved builtin`,
@@ -428,12 +508,20 @@ func TestParseErrors(t *testing.T) {
 			msg:   `cannot use reserved builtin`,
 		},
 		{
-			query: `SELECT SUM(DISTINCT x)`,
-			msg:   `SUM: does not accept DISTINCT`,
+			query: `SELECT * FROM (VALUES (1, 2), (3))`,
+			msg:   `VALUES lists must all be the same length`,
 		},
 		{
-			query: `SELECT AVG(DISTINCT x)`,
-			msg:   `AVG: does not accept DISTINCT`,
+			query: `SELECT * FROM (VALUES (1, 2)) AS t(x)`,
+			msg:   `VALUES has 2 columns but 1 column names were given`,
+		},
+		{
+			query: `SELECT * FROM (VALUES (x)) AS t`,
+			msg:   `VALUES requires constant expressions`,
+		},
+		{
+			query: `SELECT * FROM foo AS f, UNNEST(f.a, f.b) AS (x)`,
+			msg:   `UNNEST has 2 arguments but 1 names were given`,
 		},
 		{
 			query: `SELECT BOOL_OR(DISTINCT x)`,
@@ -503,6 +591,14 @@ func TestParseErrors(t *testing.T) {
 			query: `SELECT sneller_datashape(x) FROM table`,
 			msg:   `SNELLER_DATASHAPE: accepts only *`,
 		},
+		{
+			query: `SELECT sneller_datashape(*, 'foo') FROM table`,
+			msg:   `SNELLER_DATASHAPE: mode has to be either "types" or "histograms"`,
+		},
+		{
+			query: `SELECT sneller_datashape(*, 1) FROM table`,
+			msg:   `SNELLER_DATASHAPE: mode has to be a constant string`,
+		},
 		{
 			query: `SELECT SUM(x, 'test') FROM table`,
 			msg:   `SUM: does not accept arguments`,
@@ -672,3 +768,306 @@ func testEquivalence(t *testing.T, e expr.Node) {
 		t.Errorf("output: %s", res)
 	}
 }
+
+func TestParseDelete(t *testing.T) {
+	testcases := []struct {
+		in, text, literal string
+	}{
+		{
+			in:   "DELETE FROM db.table",
+			text: "DELETE FROM db.table",
+		},
+		{
+			in:      "  delete from tbl where x = 'foo' AND y < 3",
+			text:    "DELETE FROM tbl WHERE x = 'foo' AND y < 3",
+			literal: "foo",
+		},
+	}
+	for i := range testcases {
+		in := []byte(testcases[i].in)
+		if !IsDelete(in) {
+			t.Errorf("IsDelete(%q) = false", in)
+			continue
+		}
+		d, err := ParseDelete(in)
+		if err != nil {
+			t.Errorf("%q: %s", in, err)
+			continue
+		}
+		if err := d.Check(); err != nil {
+			t.Errorf("%q: check: %s", in, err)
+		}
+		if got := d.Text(); got != testcases[i].text {
+			t.Errorf("%q: got text %q, want %q", in, got, testcases[i].text)
+		}
+		if lit := testcases[i].literal; lit != "" && strings.Contains(d.Redacted(), lit) {
+			t.Errorf("%q: redacted text %q contains %q", in, d.Redacted(), lit)
+		}
+	}
+
+	bad := []string{
+		"DELETE x FROM tbl",
+		"DELETE FROM tbl AS t WHERE t.x = 1",
+		"DELETE FROM tbl WHERE x = 1 LIMIT 1",
+		"DELETE FROM tbl WHERE x = 1 ORDER BY x",
+		"DELETE FROM (SELECT * FROM tbl)",
+		"DELETE FROM tbl WHERE 'foo'",
+		"DELETE",
+		"SELECT * FROM tbl",
+	}
+	for _, str := range bad {
+		d, err := ParseDelete([]byte(str))
+		if err == nil {
+			err = d.Check()
+		}
+		if err == nil {
+			t.Errorf("%q: expected an error", str)
+		}
+	}
+	for _, str := range []string{"SELECT * FROM tbl", "DELETED", "SELECT delete FROM tbl"} {
+		if IsDelete([]byte(str)) {
+			t.Errorf("IsDelete(%q) = true", str)
+		}
+	}
+	// DELETE is only a keyword at the start of a statement
+	if _, err := Parse([]byte("SELECT delete FROM tbl")); err != nil {
+		t.Errorf("parsing delete as an identifier: %s", err)
+	}
+	if _, err := Parse([]byte("DELETE FROM tbl")); err == nil {
+		t.Error("Parse accepted a DELETE statement")
+	}
+}
+
+func TestParseDDL(t *testing.T) {
+	testcases := []struct {
+		in, text, literal string
+	}{
+		{
+			in:   "DROP TABLE db.tbl",
+			text: "DROP TABLE db.tbl",
+		},
+		{
+			in:   "drop table if exists tbl",
+			text: "DROP TABLE IF EXISTS tbl",
+		},
+		{
+			in:   "CREATE TABLE db.tbl",
+			text: "CREATE TABLE db.tbl",
+		},
+		{
+			in: `create table if not exists db.tbl (ts timestamp not null, a.b integer, "select" varchar)
+			 partition by (region, day date as '$y-$m-$d') sort by (ts)
+			 retention on ts for '6m'
+			 from 's3://bucket/{region}/{y}/{m}/{d}/*.json.gz' format 'json.gz' incremental, 's3://other/*.json'`,
+			text: "CREATE TABLE IF NOT EXISTS db.tbl (ts TIMESTAMP NOT NULL, a.b INT, \"select\" STRING) " +
+				"PARTITION BY (region, day DATE AS '$y-$m-$d') SORT BY (ts) RETENTION ON ts FOR '6m' " +
+				`FROM 's3:\/\/bucket\/{region}\/{y}\/{m}\/{d}\/*.json.gz' FORMAT 'json.gz' INCREMENTAL, 's3:\/\/other\/*.json'`,
+			literal: "s3://bucket",
+		},
+		{
+			in:   "ALTER TABLE db.tbl SET PARTITION BY (region string), DROP RETENTION",
+			text: "ALTER TABLE db.tbl SET PARTITION BY (region STRING), DROP RETENTION",
+		},
+		{
+			in:      "ALTER TABLE tbl DROP PARTITION BY, SET SORT BY (a.b, c), SET RETENTION ON ts FOR '1y'",
+			text:    "ALTER TABLE tbl DROP PARTITION BY, SET SORT BY (a.b, c), SET RETENTION ON ts FOR '1y'",
+			literal: "1y",
+		},
+	}
+	for i := range testcases {
+		in := []byte(testcases[i].in)
+		if !IsDDL(in) {
+			t.Errorf("IsDDL(%q) = false", in)
+			continue
+		}
+		d, err := ParseDDL(in)
+		if err != nil {
+			t.Errorf("%q: %s", in, err)
+			continue
+		}
+		if err := d.Check(); err != nil {
+			t.Errorf("%q: check: %s", in, err)
+		}
+		if got := d.Text(); got != testcases[i].text {
+			t.Errorf("%q: got text %q, want %q", in, got, testcases[i].text)
+		}
+		if lit := testcases[i].literal; lit != "" && strings.Contains(d.Redacted(), lit) {
+			t.Errorf("%q: redacted text %q contains %q", in, d.Redacted(), lit)
+		}
+	}
+
+	bad := []string{
+		"CREATE tbl",
+		"CREATE TABLE",
+		"CREATE TABLE tbl (x)",
+		"CREATE TABLE tbl (x blob)",
+		"CREATE TABLE tbl (x int, x string)",
+		"CREATE TABLE tbl (x int) LIMIT 1",
+		"CREATE TABLE tbl PARTITION BY ()",
+		"CREATE TABLE tbl RETENTION ON ts FOR 6",
+		"CREATE TABLE tbl FROM pattern",
+		"ALTER TABLE tbl",
+		"ALTER TABLE tbl SET RETENTION ON ts FOR '1y', DROP RETENTION",
+		"ALTER TABLE tbl DROP SORT BY, DROP SORT BY",
+		"DROP TABLE db.tbl.x",
+		"DROP TABLE IF tbl",
+	}
+	for _, str := range bad {
+		d, err := ParseDDL([]byte(str))
+		if err == nil {
+			err = d.Check()
+		}
+		if err == nil {
+			t.Errorf("%q: expected an error", str)
+		}
+	}
+	for _, str := range []string{"SELECT * FROM tbl", "CREATED", "DROP", "DELETE FROM tbl"} {
+		if IsDDL([]byte(str)) {
+			t.Errorf("IsDDL(%q) = true", str)
+		}
+	}
+	// DDL keywords are not reserved in queries
+	for _, str := range []string{
+		"SELECT table, set, format FROM tbl",
+		"SELECT * FROM drop WHERE create = alter",
+	} {
+		if _, err := Parse([]byte(str)); err != nil {
+			t.Errorf("%q: %s", str, err)
+		}
+	}
+}
+
+func TestParseTrino(t *testing.T) {
+	testcases := []struct {
+		in, text string
+	}{
+		{
+			in:   "SELECT approx_distinct(x) FROM foo",
+			text: "SELECT APPROX_COUNT_DISTINCT(x) FROM foo",
+		},
+		{
+			in:   "SELECT count_if(x > 1) FROM foo",
+			text: "SELECT COUNT(*) FILTER (WHERE x > 1) FROM foo",
+		},
+		{
+			in:   "SELECT date_trunc('hour', ts) FROM foo",
+			text: "SELECT DATE_TRUNC_HOUR(ts) FROM foo",
+		},
+		{
+			in:   "SELECT date_add('day', 1, ts), date_diff('minute', a, b) FROM foo",
+			text: "SELECT DATE_ADD_DAY(1, ts), DATE_DIFF_MINUTE(a, b) FROM foo",
+		},
+		{
+			in:   "SELECT element_at(lst, 1), element_at(m, 'key'), cardinality(lst) FROM foo",
+			text: "SELECT lst[0], m.key, ARRAY_SIZE(lst) FROM foo",
+		},
+		{
+			in:   "SELECT element_at(lst, -1) FROM foo",
+			text: "SELECT lst[-1] FROM foo",
+		},
+		{
+			in:   "SELECT length(s) FROM foo WHERE regexp_like(s, 'a+b')",
+			text: "SELECT CHAR_LENGTH(s) FROM foo WHERE s ~ 'a+b'",
+		},
+	}
+	for i := range testcases {
+		in := []byte(testcases[i].in)
+		q, err := ParseDialect(in, TrinoDialect)
+		if err != nil {
+			t.Errorf("%q: %s", in, err)
+			continue
+		}
+		if got := q.Text(); got != testcases[i].text {
+			t.Errorf("%q: got text %q, want %q", in, got, testcases[i].text)
+		}
+		testEquivalence(t, q.Body)
+	}
+
+	// without the dialect, none of the
+	// Trino-specific conventions are accepted
+	bad := []string{
+		"SELECT date_trunc('hour', ts) FROM foo",
+		"SELECT date_add('day', 1, ts) FROM foo",
+	}
+	for _, str := range bad {
+		if _, err := Parse([]byte(str)); err == nil {
+			t.Errorf("%q: expected an error", str)
+		}
+	}
+	q, err := Parse([]byte("SELECT cardinality(lst) FROM foo"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := q.Text(), "SELECT CARDINALITY(lst) FROM foo"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	bad = []string{
+		"SELECT element_at(lst, 0) FROM foo",
+		"SELECT element_at(lst, i) FROM foo",
+		"SELECT regexp_like(s, p) FROM foo",
+		"SELECT date_trunc('fortnight', ts) FROM foo",
+	}
+	for _, str := range bad {
+		if _, err := ParseDialect([]byte(str), TrinoDialect); err == nil {
+			t.Errorf("%q: expected an error", str)
+		}
+	}
+}
+
+func TestParseMySQLPostgres(t *testing.T) {
+	testcases := []struct {
+		dialect  Dialect
+		in, text string
+	}{
+		{
+			dialect: MySQLDialect,
+			in:      "SELECT IFNULL(x, 0), LENGTH(s) FROM foo",
+			text:    "SELECT CASE WHEN x IS NOT NULL THEN x WHEN 0 IS NOT NULL THEN 0 ELSE NULL END, OCTET_LENGTH(s) FROM foo",
+		},
+		{
+			dialect: MySQLDialect,
+			in:      "SELECT SUBSTRING_INDEX(s, '.', 1) FROM foo",
+			text:    "SELECT SPLIT_PART(s, '.', 1) FROM foo",
+		},
+		{
+			dialect: MySQLDialect,
+			in:      "SELECT DATE_FORMAT(ts, '%Y-%m') FROM foo",
+			text:    "SELECT CONCAT(CONCAT(CAST(DATE_EXTRACT_YEAR(ts) AS STRING), '-'), SUBSTRING(CAST(100 + DATE_EXTRACT_MONTH(ts) AS STRING), 2)) FROM foo",
+		},
+		{
+			dialect: PostgresDialect,
+			in:      "SELECT DATE_PART('hour', ts), DATE_TRUNC('day', ts), LENGTH(s) FROM foo",
+			text:    "SELECT DATE_EXTRACT_HOUR(ts), DATE_TRUNC_DAY(ts), CHAR_LENGTH(s) FROM foo",
+		},
+	}
+	for i := range testcases {
+		in := []byte(testcases[i].in)
+		q, err := ParseDialect(in, testcases[i].dialect)
+		if err != nil {
+			t.Errorf("%q: %s", in, err)
+			continue
+		}
+		if got := q.Text(); got != testcases[i].text {
+			t.Errorf("%q: got text %q, want %q", in, got, testcases[i].text)
+		}
+		testEquivalence(t, q.Body)
+	}
+
+	bad := []struct {
+		dialect Dialect
+		in      string
+	}{
+		{MySQLDialect, "SELECT DATE_FORMAT(ts, '%W') FROM foo"},
+		{MySQLDialect, "SELECT DATE_FORMAT(ts, fmt) FROM foo"},
+		{MySQLDialect, "SELECT SUBSTRING_INDEX(s, '.', -1) FROM foo"},
+		{MySQLDialect, "SELECT DATE_TRUNC('day', ts) FROM foo"},
+		{PostgresDialect, "SELECT DATE_ADD('day', 1, ts) FROM foo"},
+		{PostgresDialect, "SELECT DATE_PART('fortnight', ts) FROM foo"},
+	}
+	for _, b := range bad {
+		if _, err := ParseDialect([]byte(b.in), b.dialect); err == nil {
+			t.Errorf("%q: expected an error", b.in)
+		}
+	}
+}
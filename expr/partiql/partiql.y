@@ -55,14 +55,28 @@ import (
     values   []expr.Node
     orders   []expr.Order
     unions   []unionItem
+    rows     [][]expr.Node
+    idents   []string
+    ddl      expr.DDL
+    table    *expr.Table
+    alter    *expr.AlterTable
+    column   expr.ColumnDef
+    columns  []expr.ColumnDef
+    part     expr.PartitionDef
+    parts    []expr.PartitionDef
+    retain   *expr.RetentionDef
+    input    expr.InputDef
+    inputs   []expr.InputDef
 }
 
 %token ERROR EOF
 %left UNION
-%token SELECT FROM WHERE GROUP ORDER BY HAVING LIMIT OFFSET WITH INTO EXPLAIN
-%token DISTINCT ALL AS EXISTS NULLS FIRST LAST ASC DESC UNPIVOT AT
-%token PARTITION
-%token VALUE
+%token SELECT FROM WHERE GROUP ORDER BY HAVING QUALIFY LIMIT OFFSET WITH INTO EXPLAIN INSERT
+%token DELETE
+%token CREATE ALTER DROP TABLE IF SET SORT RETENTION FOR FORMAT INCREMENTAL
+%token DISTINCT ALL AS EXISTS NULLS FIRST LAST ASC DESC UNPIVOT UNNEST AT
+%token PARTITION PARTITIONED
+%token VALUE VALUES
 %token LEADING TRAILING BOTH
 %right COALESCE NULLIF EXTRACT DATE_TRUNC
 %right CAST UTCNOW
@@ -70,7 +84,7 @@ import (
 %left JOIN LEFT RIGHT CROSS INNER OUTER FULL
 %left ON
 %left APPROX_COUNT_DISTINCT
-%token <integer> AGGREGATE
+%token <integer> AGGREGATE AGGREGATE_IF
 %token <str> ID
 %token <empty> '(' ',' ')' '[' ']' '{' '}'
 %token <empty> NULL TRUE FALSE MISSING
@@ -96,7 +110,7 @@ import (
 
 %type <query> query
 %type <expr> expr datum datum_or_parens maybe_into
-%type <expr> where_expr having_expr case_optional_expr case_optional_else parenthesized_expr
+%type <expr> where_expr having_expr qualify_expr case_optional_expr case_optional_else parenthesized_expr
 %type <expr> optional_filter
 %type <expr> unpivot unpivot_source
 %type <with> maybe_cte_bindings cte_bindings
@@ -118,11 +132,164 @@ import (
 %type <wind> maybe_window
 %type <integer> trim_type
 %type <str> maybe_explain
+%type <rows> values_table values_rows
+%type <idents> maybe_column_names identifier_list maybe_partitioned
 %type <unions> maybe_union
-%start query
+%type <ddl> ddl_stmt
+%type <table> ddl_table
+%type <alter> alter_actions alter_action
+%type <column> column_def
+%type <columns> maybe_column_defs column_defs
+%type <part> partition_def
+%type <parts> maybe_partition_by partition_defs
+%type <values> maybe_sort_by ddl_paths
+%type <expr> ddl_path
+%type <retain> maybe_retention retention
+%type <input> input_def
+%type <inputs> maybe_inputs input_defs
+%type <yesno> maybe_if_not_exists maybe_if_exists maybe_not_null maybe_incremental
+%type <str> maybe_partition_type maybe_partition_value maybe_format
+%start statement
 
 %%
 
+statement:
+query
+| DELETE FROM datum where_expr
+{
+  stmt, err := buildDelete($3, $4)
+  if err != nil {
+    yylex.Error(err.Error())
+  }
+
+  yylex.(*scanner).delete = stmt
+}
+| ddl_stmt
+{
+  yylex.(*scanner).ddl = $1
+}
+
+ddl_stmt:
+CREATE TABLE maybe_if_not_exists ddl_table maybe_column_defs maybe_partition_by maybe_sort_by maybe_retention maybe_inputs
+{
+  $$ = &expr.CreateTable{Table: $4, IfNotExists: $3, Columns: $5, PartitionBy: $6, SortBy: $7, Retention: $8, Inputs: $9}
+}
+| ALTER TABLE ddl_table alter_actions
+{
+  $4.Table = $3
+  $$ = $4
+}
+| DROP TABLE maybe_if_exists ddl_table
+{
+  $$ = &expr.DropTable{Table: $4, IfExists: $3}
+}
+
+maybe_if_not_exists:
+IF NOT EXISTS { $$ = true } | { $$ = false }
+
+maybe_if_exists:
+IF EXISTS { $$ = true } | { $$ = false }
+
+ddl_table:
+ID { $$ = &expr.Table{Binding: expr.Bind(expr.Ident($1), "")} }
+| ID '.' ID { $$ = &expr.Table{Binding: expr.Bind(&expr.Dot{Inner: expr.Ident($1), Field: $3}, "")} }
+
+ddl_path:
+ID { $$ = expr.Ident($1) }
+| ddl_path '.' ID { $$ = &expr.Dot{Inner: $1, Field: $3} }
+
+ddl_paths:
+ddl_path { $$ = []expr.Node{$1} }
+| ddl_paths ',' ddl_path { $$ = append($1, $3) }
+
+maybe_column_defs:
+'(' column_defs ')' { $$ = $2 } | { $$ = nil }
+
+column_defs:
+column_def { $$ = []expr.ColumnDef{$1} }
+| column_defs ',' column_def { $$ = append($1, $3) }
+
+column_def:
+ddl_path ID maybe_not_null
+{
+  typ, ok := columnType(strings.ToLower($2))
+  if !ok {
+    yylex.Error(__yyfmt__.Sprintf("column %s: unknown type %q", expr.ToString($1), $2))
+  }
+  $$ = expr.ColumnDef{Path: $1, Type: typ, NotNull: $3}
+}
+
+maybe_not_null:
+NOT NULL { $$ = true } | { $$ = false }
+
+maybe_partition_by:
+PARTITION BY '(' partition_defs ')' { $$ = $4 } | { $$ = nil }
+
+partition_defs:
+partition_def { $$ = []expr.PartitionDef{$1} }
+| partition_defs ',' partition_def { $$ = append($1, $3) }
+
+partition_def:
+ID maybe_partition_type maybe_partition_value
+{
+  $$ = expr.PartitionDef{Field: $1, Type: $2, Value: $3}
+}
+
+maybe_partition_type:
+ID { $$ = strings.ToLower($1) } | { $$ = "" }
+
+maybe_partition_value:
+AS STRING { $$ = $2 } | { $$ = "" }
+
+maybe_sort_by:
+SORT BY '(' ddl_paths ')' { $$ = $4 } | { $$ = nil }
+
+maybe_retention:
+RETENTION retention { $$ = $2 } | { $$ = nil }
+
+retention:
+ON ddl_path FOR STRING { $$ = &expr.RetentionDef{Field: $2, ValidFor: $4} }
+
+maybe_inputs:
+FROM input_defs { $$ = $2 } | { $$ = nil }
+
+input_defs:
+input_def { $$ = []expr.InputDef{$1} }
+| input_defs ',' input_def { $$ = append($1, $3) }
+
+input_def:
+STRING maybe_format maybe_incremental
+{
+  $$ = expr.InputDef{Pattern: $1, Format: $2, Incremental: $3}
+}
+
+maybe_format:
+FORMAT STRING { $$ = $2 }
+| FORMAT ID { $$ = strings.ToLower($2) }
+| { $$ = "" }
+
+maybe_incremental:
+INCREMENTAL { $$ = true } | { $$ = false }
+
+alter_actions:
+alter_action { $$ = $1 }
+| alter_actions ',' alter_action
+{
+  if err := mergeAlter($1, $3); err != nil {
+    yylex.Error(err.Error())
+  }
+  $$ = $1
+}
+
+alter_action:
+SET PARTITION BY '(' partition_defs ')' { lst := $5; $$ = &expr.AlterTable{PartitionBy: &lst} }
+| DROP PARTITION BY { $$ = &expr.AlterTable{PartitionBy: &[]expr.PartitionDef{}} }
+| SET SORT BY '(' ddl_paths ')' { lst := $5; $$ = &expr.AlterTable{SortBy: &lst} }
+| DROP SORT BY { $$ = &expr.AlterTable{SortBy: &[]expr.Node{}} }
+| SET RETENTION retention { $$ = &expr.AlterTable{Retention: $3} }
+| DROP RETENTION { $$ = &expr.AlterTable{DropRetention: true} }
+
+
 query:
 maybe_explain maybe_cte_bindings select_with_into_stmt maybe_union
 {
@@ -133,20 +300,30 @@ maybe_explain maybe_cte_bindings select_with_into_stmt maybe_union
 
   yylex.(*scanner).result = query
 }
+| maybe_explain INSERT INTO datum maybe_partitioned select_stmt
+{
+  query, err := buildInsert($1, $4, $5, $6)
+  if err != nil {
+    yylex.Error(err.Error())
+  }
+
+  yylex.(*scanner).result = query
+}
 
 select_with_into_stmt:
-SELECT maybe_toplevel_distinct binding_list maybe_into from_expr where_expr group_expr having_expr order_expr limit_expr offset_expr
+SELECT maybe_toplevel_distinct binding_list maybe_into maybe_partitioned from_expr where_expr group_expr having_expr qualify_expr order_expr limit_expr offset_expr
 {
     distinct, distinctExpr := decodeDistinct($2)
-    $$.sel = &expr.Select{Distinct: distinct, DistinctExpr: distinctExpr, Columns: $3, From: $5, Where: $6, GroupBy: $7, Having: $8, OrderBy: $9, Limit: $10, Offset: $11}
+    $$.sel = &expr.Select{Distinct: distinct, DistinctExpr: distinctExpr, Columns: $3, From: $6, Where: $7, GroupBy: $8, Having: $9, Qualify: $10, OrderBy: $11, Limit: $12, Offset: $13}
     $$.into = $4
+    $$.partitions = $5
 }
 
 select_stmt:
-SELECT maybe_toplevel_distinct binding_list from_expr where_expr group_expr having_expr order_expr limit_expr offset_expr
+SELECT maybe_toplevel_distinct binding_list from_expr where_expr group_expr having_expr qualify_expr order_expr limit_expr offset_expr
 {
     distinct, distinctExpr := decodeDistinct($2)
-    $$ = &expr.Select{Distinct: distinct, DistinctExpr: distinctExpr, Columns: $3, From: $4, Where: $5, GroupBy: $6, Having: $7, OrderBy: $8, Limit: $9, Offset: $10}
+    $$ = &expr.Select{Distinct: distinct, DistinctExpr: distinctExpr, Columns: $3, From: $4, Where: $5, GroupBy: $6, Having: $7, Qualify: $8, OrderBy: $9, Limit: $10, Offset: $11}
 }
 
 maybe_explain:
@@ -157,6 +334,9 @@ maybe_explain:
 maybe_into:
 INTO datum { $$ = $2 } | { $$ = nil }
 
+maybe_partitioned:
+PARTITIONED BY '(' identifier_list ')' { $$ = $4 } | { $$ = nil }
+
 maybe_cte_bindings:
 cte_bindings { $$ = $1 } | { $$ = nil }
 
@@ -183,7 +363,55 @@ expr AS identifier { $$ = expr.Bind($1, $3) } |
 expr identifier { $$ = expr.Bind($1, $2) } |
 expr { $$ = expr.Bind($1, "") } |
 '*' { $$ = expr.Bind(expr.Star{}, "") } |
-unpivot { $$ = expr.Bind($1, "") }
+unpivot { $$ = expr.Bind($1, "") } |
+values_table AS identifier maybe_column_names
+{
+  t, err := valuesTable($1, $4)
+  if err != nil {
+    yylex.Error(err.Error())
+  }
+  $$ = expr.Bind(t, $3)
+} |
+values_table identifier maybe_column_names
+{
+  t, err := valuesTable($1, $3)
+  if err != nil {
+    yylex.Error(err.Error())
+  }
+  $$ = expr.Bind(t, $2)
+} |
+values_table
+{
+  t, err := valuesTable($1, nil)
+  if err != nil {
+    yylex.Error(err.Error())
+  }
+  $$ = expr.Bind(t, "")
+} |
+UNNEST '(' value_list ')' AS '(' identifier_list ')'
+{
+  u, err := unnestValues($3, $7)
+  if err != nil {
+    yylex.Error(err.Error())
+  }
+  $$ = expr.Bind(u, "")
+}
+
+// match (VALUES (a, b, ...), (c, d, ...), ...)
+values_table:
+'(' VALUES values_rows ')' { $$ = $3 }
+
+values_rows:
+'(' value_list ')' { $$ = [][]expr.Node{$2} } |
+values_rows ',' '(' value_list ')' { $$ = append($1, $4) }
+
+maybe_column_names:
+'(' identifier_list ')' { $$ = $2 } |
+{ $$ = nil }
+
+identifier_list:
+identifier { $$ = []string{$1} } |
+identifier_list ',' identifier { $$ = append($1, $3) }
 
 // match exactly a single datum
 datum:
@@ -198,8 +426,9 @@ ION { $$ = $1 } |
 '{' field_value_list '}' { $$ = expr.Call(expr.MakeStruct, $2...) } |
 '[' any_value_list ']' { $$ = expr.Call(expr.MakeList, $2...) } |
 datum '.' identifier { $$ = &expr.Dot{Inner: $1, Field: $3} } |
-datum '[' literal_int ']' { $$ = &expr.Index{Inner: $1, Offset: $3} } |
-datum '[' STRING ']' { $$ = &expr.Dot{Inner: $1, Field: $3} }
+datum '[' expr ']' { $$ = toIndex($1, $3, yylex) } |
+datum '[' '*' ']' { $$ = &expr.Wildcard{Inner: $1} } |
+datum '.' '*' { $$ = &expr.Wildcard{Inner: $1, Struct: true} }
 
 // datum_or_parens is guaranteed to
 // avoid shift-reduce conflicts with BETWEEN,
@@ -249,6 +478,14 @@ datum_or_parens
   }
   $$ = agg
 }
+| AGGREGATE_IF '(' value_list ')' optional_filter maybe_window
+{
+  agg, err := toConditionalAggregate(expr.AggregateOp($1), $3, $5, $6)
+  if err != nil {
+    yylex.Error(err.Error())
+  }
+  $$ = agg
+}
 | CASE case_optional_expr case_limbs case_optional_else END
 {
   $$ = createCase($2, $3, $4)
@@ -285,6 +522,30 @@ datum_or_parens
   }
   $$ = expr.DateDiff(part, $5, $7)
 }
+| DATE_ADD '(' STRING ',' expr ',' expr ')'
+{
+  part, err := yylex.(*scanner).stringPart($3, "DATE_ADD")
+  if err != nil {
+    yylex.Error(err.Error())
+  }
+  $$ = expr.DateAdd(part, $5, $7)
+}
+| DATE_DIFF '(' STRING ',' expr ',' expr ')'
+{
+  part, err := yylex.(*scanner).stringPart($3, "DATE_DIFF")
+  if err != nil {
+    yylex.Error(err.Error())
+  }
+  $$ = expr.DateDiff(part, $5, $7)
+}
+| DATE_TRUNC '(' STRING ',' expr ')'
+{
+  part, err := yylex.(*scanner).stringPart($3, "DATE_TRUNC")
+  if err != nil {
+    yylex.Error(err.Error())
+  }
+  $$ = expr.DateTrunc(part, $5)
+}
 | DATE_TRUNC '(' ID '(' ID ')' ',' expr ')'
 {
   dow, ok := weekday($5)
@@ -303,11 +564,15 @@ datum_or_parens
 }
 | EXTRACT '(' ID FROM expr ')'
 {
-  part, ok := timePartFor($3, "EXTRACT")
-  if !ok {
-    yylex.Error(__yyfmt__.Sprintf("bad EXTRACT part %q", $3))
+  if isEpochPart($3) {
+    $$ = expr.Call(expr.ToUnixEpoch, $5)
+  } else {
+    part, ok := timePartFor($3, "EXTRACT")
+    if !ok {
+      yylex.Error(__yyfmt__.Sprintf("bad EXTRACT part %q", $3))
+    }
+    $$ = expr.DateExtract(part, $5)
   }
-  $$ = expr.DateExtract(part, $5)
 }
 | UTCNOW '(' ')'
 {
@@ -347,19 +612,19 @@ datum_or_parens
 }
 | identifier '(' ')'
 {
-  op := expr.CallByName($1)
-  if op.Private() {
-    yylex.Error(__yyfmt__.Sprintf("cannot use reserved builtin %q", $1))
+  node, err := yylex.(*scanner).call($1, nil)
+  if err != nil {
+    yylex.Error(err.Error())
   }
-  $$ = op
+  $$ = node
 }
 | identifier '(' value_list ')'
 {
-  op := expr.CallByName($1, $3...)
-  if op.Private() {
-    yylex.Error(__yyfmt__.Sprintf("cannot use reserved builtin %q", $1))
+  node, err := yylex.(*scanner).call($1, $3)
+  if err != nil {
+    yylex.Error(err.Error())
   }
-  $$ = op
+  $$ = node
 }
 | expr IN '(' select_stmt ')'
 {
@@ -665,6 +930,10 @@ having_expr:
 { $$ = nil } |
 HAVING expr { $$ = $2 }
 
+qualify_expr:
+{ $$ = nil } |
+QUALIFY expr { $$ = $2 }
+
 group_expr:
 { $$ = nil } |
 GROUP BY binding_list { $$ = $3 }
@@ -46,6 +46,18 @@ type yySymType struct {
 	values   []expr.Node
 	orders   []expr.Order
 	unions   []unionItem
+	rows     [][]expr.Node
+	idents   []string
+	ddl      expr.DDL
+	table    *expr.Table
+	alter    *expr.AlterTable
+	column   expr.ColumnDef
+	columns  []expr.ColumnDef
+	part     expr.PartitionDef
+	parts    []expr.PartitionDef
+	retain   *expr.RetentionDef
+	input    expr.InputDef
+	inputs   []expr.InputDef
 }
 
 const ERROR = 57346
@@ -58,87 +70,105 @@ const GROUP = 57352
 const ORDER = 57353
 const BY = 57354
 const HAVING = 57355
-const LIMIT = 57356
-const OFFSET = 57357
-const WITH = 57358
-const INTO = 57359
-const EXPLAIN = 57360
-const DISTINCT = 57361
-const ALL = 57362
-const AS = 57363
-const EXISTS = 57364
-const NULLS = 57365
-const FIRST = 57366
-const LAST = 57367
-const ASC = 57368
-const DESC = 57369
-const UNPIVOT = 57370
-const AT = 57371
-const PARTITION = 57372
-const VALUE = 57373
-const LEADING = 57374
-const TRAILING = 57375
-const BOTH = 57376
-const COALESCE = 57377
-const NULLIF = 57378
-const EXTRACT = 57379
-const DATE_TRUNC = 57380
-const CAST = 57381
-const UTCNOW = 57382
-const DATE_ADD = 57383
-const DATE_DIFF = 57384
-const EARLIEST = 57385
-const LATEST = 57386
-const JOIN = 57387
-const LEFT = 57388
-const RIGHT = 57389
-const CROSS = 57390
-const INNER = 57391
-const OUTER = 57392
-const FULL = 57393
-const ON = 57394
-const APPROX_COUNT_DISTINCT = 57395
-const AGGREGATE = 57396
-const ID = 57397
-const NULL = 57398
-const TRUE = 57399
-const FALSE = 57400
-const MISSING = 57401
-const OR = 57402
-const AND = 57403
-const NOT = 57404
-const BETWEEN = 57405
-const CASE = 57406
-const WHEN = 57407
-const THEN = 57408
-const ELSE = 57409
-const END = 57410
-const TO = 57411
-const TRIM = 57412
-const EQ = 57413
-const NE = 57414
-const LT = 57415
-const LE = 57416
-const GT = 57417
-const GE = 57418
-const SIMILAR = 57419
-const REGEXP_MATCH_CI = 57420
-const ILIKE = 57421
-const LIKE = 57422
-const IN = 57423
-const IS = 57424
-const OVER = 57425
-const FILTER = 57426
-const ESCAPE = 57427
-const SHIFT_LEFT_LOGICAL = 57428
-const SHIFT_RIGHT_ARITHMETIC = 57429
-const SHIFT_RIGHT_LOGICAL = 57430
-const CONCAT = 57431
-const APPEND = 57432
-const NEGATION_PRECEDENCE = 57433
-const NUMBER = 57434
-const ION = 57435
-const STRING = 57436
+const QUALIFY = 57356
+const LIMIT = 57357
+const OFFSET = 57358
+const WITH = 57359
+const INTO = 57360
+const EXPLAIN = 57361
+const INSERT = 57362
+const DELETE = 57363
+const CREATE = 57364
+const ALTER = 57365
+const DROP = 57366
+const TABLE = 57367
+const IF = 57368
+const SET = 57369
+const SORT = 57370
+const RETENTION = 57371
+const FOR = 57372
+const FORMAT = 57373
+const INCREMENTAL = 57374
+const DISTINCT = 57375
+const ALL = 57376
+const AS = 57377
+const EXISTS = 57378
+const NULLS = 57379
+const FIRST = 57380
+const LAST = 57381
+const ASC = 57382
+const DESC = 57383
+const UNPIVOT = 57384
+const UNNEST = 57385
+const AT = 57386
+const PARTITION = 57387
+const PARTITIONED = 57388
+const VALUE = 57389
+const VALUES = 57390
+const LEADING = 57391
+const TRAILING = 57392
+const BOTH = 57393
+const COALESCE = 57394
+const NULLIF = 57395
+const EXTRACT = 57396
+const DATE_TRUNC = 57397
+const CAST = 57398
+const UTCNOW = 57399
+const DATE_ADD = 57400
+const DATE_DIFF = 57401
+const EARLIEST = 57402
+const LATEST = 57403
+const JOIN = 57404
+const LEFT = 57405
+const RIGHT = 57406
+const CROSS = 57407
+const INNER = 57408
+const OUTER = 57409
+const FULL = 57410
+const ON = 57411
+const APPROX_COUNT_DISTINCT = 57412
+const AGGREGATE = 57413
+const AGGREGATE_IF = 57414
+const ID = 57415
+const NULL = 57416
+const TRUE = 57417
+const FALSE = 57418
+const MISSING = 57419
+const OR = 57420
+const AND = 57421
+const NOT = 57422
+const BETWEEN = 57423
+const CASE = 57424
+const WHEN = 57425
+const THEN = 57426
+const ELSE = 57427
+const END = 57428
+const TO = 57429
+const TRIM = 57430
+const EQ = 57431
+const NE = 57432
+const LT = 57433
+const LE = 57434
+const GT = 57435
+const GE = 57436
+const SIMILAR = 57437
+const REGEXP_MATCH_CI = 57438
+const ILIKE = 57439
+const LIKE = 57440
+const IN = 57441
+const IS = 57442
+const OVER = 57443
+const FILTER = 57444
+const ESCAPE = 57445
+const SHIFT_LEFT_LOGICAL = 57446
+const SHIFT_RIGHT_ARITHMETIC = 57447
+const SHIFT_RIGHT_LOGICAL = 57448
+const CONCAT = 57449
+const APPEND = 57450
+const NEGATION_PRECEDENCE = 57451
+const NUMBER = 57452
+const ION = 57453
+const STRING = 57454
 
 var yyToknames = [...]string{
 	"$end",
@@ -154,11 +184,25 @@ var yyToknames = [...]string{
 	"ORDER",
 	"BY",
 	"HAVING",
+	"QUALIFY",
 	"LIMIT",
 	"OFFSET",
 	"WITH",
 	"INTO",
 	"EXPLAIN",
+	"INSERT",
+	"DELETE",
+	"CREATE",
+	"ALTER",
+	"DROP",
+	"TABLE",
+	"IF",
+	"SET",
+	"SORT",
+	"RETENTION",
+	"FOR",
+	"FORMAT",
+	"INCREMENTAL",
 	"DISTINCT",
 	"ALL",
 	"AS",
@@ -169,9 +213,12 @@ var yyToknames = [...]string{
 	"ASC",
 	"DESC",
 	"UNPIVOT",
+	"UNNEST",
 	"AT",
 	"PARTITION",
+	"PARTITIONED",
 	"VALUE",
+	"VALUES",
 	"LEADING",
 	"TRAILING",
 	"BOTH",
@@ -195,6 +242,7 @@ var yyToknames = [...]string{
 	"ON",
 	"APPROX_COUNT_DISTINCT",
 	"AGGREGATE",
+	"AGGREGATE_IF",
 	"ID",
 	"'('",
 	"','",
@@ -271,400 +319,535 @@ var yyExca = [...]int8{
 
 const yyPrivate = 57344
 
-const yyLast = 1803
+const yyLast = 2456
 
 var yyAct = [...]int16{
-	25, 375, 202, 371, 182, 346, 361, 319, 296, 242,
-	276, 28, 215, 123, 208, 132, 204, 326, 203, 24,
-	23, 75, 76, 77, 78, 79, 80, 81, 40, 325,
-	295, 100, 235, 291, 290, 11, 13, 124, 20, 18,
-	237, 236, 234, 112, 113, 114, 116, 233, 121, 231,
-	157, 156, 154, 153, 67, 204, 118, 126, 80, 81,
-	61, 77, 78, 79, 80, 81, 294, 293, 230, 229,
-	140, 141, 142, 143, 144, 145, 146, 147, 148, 149,
-	150, 151, 152, 131, 135, 243, 120, 297, 158, 159,
-	160, 161, 162, 163, 137, 138, 170, 171, 129, 155,
-	301, 181, 183, 184, 185, 164, 117, 168, 12, 47,
-	191, 183, 56, 232, 55, 197, 51, 49, 50, 52,
-	269, 268, 137, 167, 169, 166, 165, 179, 183, 248,
-	14, 249, 211, 207, 172, 175, 176, 174, 206, 377,
-	183, 46, 173, 337, 228, 210, 201, 214, 209, 334,
-	198, 60, 300, 299, 226, 238, 240, 241, 239, 252,
-	289, 252, 273, 48, 54, 53, 177, 212, 72, 74,
-	73, 75, 76, 77, 78, 79, 80, 81, 227, 245,
-	252, 264, 250, 252, 251, 84, 86, 82, 83, 68,
-	97, 288, 130, 263, 69, 70, 71, 72, 74, 73,
-	75, 76, 77, 78, 79, 80, 81, 136, 274, 271,
-	265, 272, 221, 223, 224, 220, 222, 278, 225, 257,
-	258, 270, 213, 205, 219, 327, 190, 275, 266, 267,
-	252, 382, 322, 64, 358, 256, 22, 279, 280, 255,
-	134, 10, 298, 139, 292, 128, 127, 65, 111, 302,
-	303, 41, 110, 305, 306, 109, 308, 309, 108, 311,
-	312, 137, 313, 314, 31, 32, 37, 36, 33, 38,
-	34, 35, 71, 72, 74, 73, 75, 76, 77, 78,
-	79, 80, 81, 29, 12, 47, 318, 64, 56, 64,
-	55, 107, 51, 49, 50, 52, 106, 105, 104, 44,
-	43, 330, 30, 103, 102, 332, 101, 98, 39, 329,
-	59, 12, 310, 307, 189, 188, 342, 187, 186, 57,
-	285, 348, 283, 350, 324, 286, 345, 284, 323, 353,
-	287, 42, 355, 282, 352, 281, 356, 357, 354, 48,
-	54, 53, 349, 316, 12, 343, 344, 388, 56, 16,
-	55, 360, 51, 49, 50, 52, 317, 364, 22, 369,
-	199, 389, 390, 58, 376, 373, 183, 370, 200, 19,
-	378, 21, 62, 7, 380, 381, 41, 17, 3, 6,
-	372, 362, 320, 376, 386, 365, 193, 194, 195, 31,
-	32, 37, 36, 33, 38, 34, 35, 363, 321, 48,
-	54, 53, 347, 277, 328, 216, 259, 134, 29, 12,
-	47, 22, 9, 56, 15, 55, 217, 51, 49, 50,
-	52, 2, 192, 180, 44, 43, 218, 30, 374, 244,
-	122, 125, 351, 39, 133, 41, 8, 178, 387, 383,
-	5, 45, 4, 115, 27, 119, 247, 99, 31, 32,
-	37, 36, 33, 38, 34, 35, 42, 63, 262, 1,
-	0, 0, 0, 0, 48, 54, 53, 29, 12, 47,
-	0, 0, 56, 0, 55, 0, 51, 49, 50, 52,
-	0, 0, 0, 44, 43, 0, 30, 0, 0, 0,
-	0, 0, 39, 70, 71, 72, 74, 73, 75, 76,
-	77, 78, 79, 80, 81, 0, 0, 261, 260, 0,
-	0, 0, 0, 0, 0, 42, 26, 96, 95, 0,
-	85, 94, 93, 48, 54, 53, 384, 385, 0, 0,
-	87, 88, 89, 90, 91, 92, 84, 86, 82, 83,
-	68, 97, 0, 0, 0, 69, 70, 71, 72, 74,
-	73, 75, 76, 77, 78, 79, 80, 81, 0, 0,
-	0, 0, 0, 0, 0, 0, 0, 96, 95, 0,
-	85, 94, 93, 0, 0, 0, 0, 0, 0, 0,
-	87, 88, 89, 90, 91, 92, 84, 86, 82, 83,
-	68, 97, 0, 0, 0, 69, 70, 71, 72, 74,
-	73, 75, 76, 77, 78, 79, 80, 81, 41, 0,
+	224, 561, 559, 544, 504, 553, 531, 496, 152, 513,
+	387, 223, 407, 270, 409, 478, 153, 358, 275, 406,
+	43, 294, 269, 329, 382, 70, 72, 542, 162, 82,
+	51, 74, 52, 95, 497, 526, 19, 235, 232, 466,
+	147, 413, 65, 412, 357, 353, 90, 92, 352, 289,
+	288, 286, 285, 20, 283, 200, 131, 35, 230, 76,
+	199, 42, 197, 196, 49, 562, 45, 411, 143, 144,
+	145, 346, 148, 345, 85, 154, 20, 77, 356, 110,
+	111, 355, 30, 71, 541, 30, 29, 88, 28, 46,
+	24, 22, 23, 25, 234, 233, 180, 295, 181, 282,
+	183, 184, 185, 186, 187, 188, 189, 190, 191, 192,
+	193, 194, 195, 281, 150, 231, 44, 287, 201, 202,
+	203, 204, 205, 206, 163, 345, 213, 214, 107, 108,
+	109, 110, 111, 89, 228, 229, 198, 21, 27, 26,
+	359, 238, 365, 226, 227, 105, 106, 107, 108, 109,
+	110, 111, 302, 244, 303, 45, 207, 45, 48, 30,
+	259, 148, 405, 29, 345, 28, 20, 24, 22, 23,
+	25, 211, 215, 218, 219, 217, 80, 248, 284, 459,
+	216, 247, 97, 245, 178, 96, 94, 210, 212, 209,
+	208, 93, 249, 527, 280, 447, 537, 254, 428, 272,
+	256, 464, 525, 262, 422, 44, 265, 44, 290, 292,
+	293, 291, 300, 521, 21, 27, 26, 397, 460, 499,
+	464, 465, 297, 279, 460, 461, 350, 304, 100, 101,
+	102, 104, 103, 105, 106, 107, 108, 109, 110, 111,
+	319, 439, 441, 442, 438, 440, 339, 443, 154, 447,
+	453, 321, 154, 179, 437, 549, 322, 101, 102, 104,
+	103, 105, 106, 107, 108, 109, 110, 111, 325, 331,
+	300, 452, 384, 336, 447, 446, 323, 222, 326, 324,
+	102, 104, 103, 105, 106, 107, 108, 109, 110, 111,
+	349, 300, 395, 393, 392, 20, 327, 252, 328, 300,
+	389, 364, 246, 366, 367, 338, 237, 369, 251, 371,
+	372, 373, 374, 375, 300, 377, 378, 354, 379, 380,
+	220, 363, 362, 361, 300, 351, 522, 20, 344, 343,
+	300, 320, 300, 305, 300, 299, 313, 314, 476, 251,
+	312, 311, 310, 309, 308, 169, 34, 394, 396, 385,
+	491, 457, 386, 255, 251, 451, 414, 401, 330, 360,
+	348, 347, 410, 417, 337, 335, 264, 402, 261, 420,
+	257, 182, 167, 388, 165, 390, 391, 142, 141, 140,
+	388, 418, 433, 416, 139, 154, 138, 137, 136, 135,
+	134, 30, 516, 133, 132, 129, 128, 30, 271, 408,
+	463, 444, 403, 434, 376, 370, 445, 236, 176, 39,
+	276, 161, 486, 518, 484, 517, 469, 487, 456, 485,
+	471, 458, 488, 483, 472, 473, 474, 475, 482, 174,
+	175, 171, 172, 163, 470, 468, 154, 154, 267, 449,
+	332, 558, 38, 168, 564, 565, 173, 87, 170, 333,
+	502, 450, 480, 481, 448, 164, 78, 149, 18, 75,
+	540, 489, 524, 494, 399, 341, 84, 41, 17, 83,
+	37, 410, 498, 500, 16, 33, 15, 512, 503, 79,
+	560, 554, 14, 86, 151, 12, 532, 514, 528, 515,
+	490, 506, 492, 493, 149, 400, 342, 278, 277, 519,
+	274, 9, 520, 3, 6, 7, 8, 273, 263, 505,
+	479, 46, 415, 455, 384, 533, 154, 535, 529, 315,
+	10, 32, 73, 435, 534, 1, 536, 523, 501, 545,
+	538, 462, 539, 547, 388, 404, 546, 40, 36, 495,
+	454, 548, 398, 340, 266, 268, 166, 81, 4, 66,
+	545, 334, 157, 556, 555, 159, 158, 5, 563, 239,
+	225, 436, 566, 543, 296, 56, 57, 62, 61, 58,
+	63, 59, 60, 47, 50, 467, 383, 31, 221, 557,
+	550, 13, 11, 258, 53, 54, 30, 160, 156, 146,
+	29, 301, 28, 130, 24, 22, 23, 25, 250, 2,
+	0, 69, 68, 0, 55, 0, 0, 0, 0, 66,
+	64, 0, 0, 0, 0, 0, 0, 0, 0, 0,
+	0, 0, 240, 241, 242, 56, 57, 62, 61, 58,
+	63, 59, 60, 67, 155, 0, 0, 0, 0, 0,
+	149, 21, 27, 26, 53, 54, 30, 71, 0, 0,
+	29, 0, 28, 0, 24, 22, 23, 25, 0, 0,
+	0, 69, 68, 0, 55, 0, 0, 0, 0, 66,
+	64, 0, 0, 0, 318, 0, 0, 0, 0, 0,
+	0, 260, 0, 0, 0, 56, 57, 62, 61, 58,
+	63, 59, 60, 67, 0, 0, 0, 0, 0, 0,
+	0, 21, 27, 26, 53, 54, 30, 71, 0, 0,
+	29, 0, 28, 0, 24, 22, 23, 25, 0, 0,
+	0, 69, 68, 0, 55, 0, 0, 0, 0, 0,
+	64, 0, 0, 0, 0, 0, 0, 0, 0, 0,
+	0, 317, 316, 0, 0, 0, 0, 0, 0, 0,
+	0, 126, 125, 67, 115, 124, 123, 0, 0, 0,
+	0, 21, 27, 26, 117, 118, 119, 120, 121, 122,
+	114, 116, 112, 113, 98, 127, 66, 0, 0, 99,
+	100, 101, 102, 104, 103, 105, 106, 107, 108, 109,
+	110, 111, 56, 57, 62, 61, 58, 63, 59, 60,
+	0, 0, 0, 0, 0, 0, 0, 149, 0, 0,
+	0, 53, 54, 30, 71, 0, 0, 29, 0, 28,
+	0, 24, 22, 23, 25, 0, 0, 0, 69, 68,
+	0, 55, 0, 0, 0, 0, 66, 64, 0, 0,
 	0, 0, 0, 0, 0, 0, 0, 0, 0, 0,
-	0, 31, 32, 37, 36, 33, 38, 34, 35, 0,
+	0, 0, 56, 57, 62, 61, 58, 63, 59, 60,
+	67, 298, 0, 0, 0, 0, 0, 0, 21, 27,
+	26, 53, 54, 30, 71, 0, 0, 29, 0, 28,
+	0, 24, 22, 23, 25, 0, 0, 0, 69, 68,
+	0, 55, 0, 0, 0, 0, 66, 64, 0, 0,
 	0, 0, 0, 0, 0, 0, 0, 0, 0, 0,
-	29, 12, 47, 0, 0, 56, 0, 55, 0, 51,
-	49, 50, 52, 0, 0, 0, 44, 43, 0, 30,
-	0, 0, 0, 0, 0, 39, 0, 41, 0, 0,
+	0, 0, 56, 57, 62, 61, 58, 63, 59, 60,
+	67, 0, 0, 0, 0, 0, 0, 0, 21, 27,
+	26, 53, 54, 30, 71, 0, 243, 29, 0, 28,
+	0, 24, 22, 23, 25, 0, 0, 0, 69, 68,
+	0, 55, 0, 0, 0, 0, 66, 64, 0, 0,
 	0, 0, 0, 0, 0, 0, 0, 0, 0, 0,
-	31, 32, 37, 36, 33, 38, 34, 35, 42, 246,
-	0, 0, 0, 0, 0, 0, 48, 54, 53, 29,
-	12, 47, 66, 196, 56, 0, 55, 0, 51, 49,
-	50, 52, 0, 0, 0, 44, 43, 0, 30, 0,
-	0, 0, 0, 0, 39, 0, 0, 0, 0, 0,
-	0, 0, 0, 0, 0, 0, 12, 0, 0, 0,
-	0, 0, 0, 0, 0, 0, 0, 42, 96, 95,
-	0, 85, 94, 93, 0, 48, 54, 53, 0, 0,
-	0, 87, 88, 89, 90, 91, 92, 84, 86, 82,
-	83, 68, 97, 0, 0, 0, 69, 70, 71, 72,
-	74, 73, 75, 76, 77, 78, 79, 80, 81, 41,
+	0, 0, 56, 57, 62, 61, 58, 63, 59, 60,
+	67, 0, 0, 0, 0, 0, 0, 0, 21, 27,
+	26, 53, 54, 30, 71, 0, 0, 29, 0, 28,
+	0, 24, 22, 23, 25, 0, 0, 0, 69, 68,
+	0, 55, 0, 0, 0, 0, 66, 64, 0, 0,
 	0, 0, 0, 0, 0, 0, 0, 0, 0, 0,
-	0, 0, 31, 32, 37, 36, 33, 38, 34, 35,
-	0, 0, 0, 0, 0, 0, 0, 0, 0, 0,
-	0, 29, 12, 47, 0, 0, 56, 0, 55, 0,
-	51, 49, 50, 52, 0, 0, 0, 44, 43, 0,
-	30, 0, 0, 0, 0, 0, 39, 0, 0, 0,
-	0, 0, 0, 0, 0, 0, 0, 0, 379, 0,
-	0, 0, 0, 0, 0, 0, 0, 96, 95, 42,
-	85, 94, 93, 0, 0, 0, 0, 48, 54, 53,
-	87, 88, 89, 90, 91, 92, 84, 86, 82, 83,
-	68, 97, 0, 0, 0, 69, 70, 71, 72, 74,
-	73, 75, 76, 77, 78, 79, 80, 81, 368, 0,
-	0, 0, 0, 0, 0, 0, 0, 96, 95, 0,
-	85, 94, 93, 0, 0, 0, 0, 0, 0, 0,
-	87, 88, 89, 90, 91, 92, 84, 86, 82, 83,
-	68, 97, 0, 0, 0, 69, 70, 71, 72, 74,
-	73, 75, 76, 77, 78, 79, 80, 81, 367, 0,
-	0, 0, 0, 0, 0, 0, 0, 96, 95, 0,
-	85, 94, 93, 0, 0, 0, 0, 0, 0, 0,
-	87, 88, 89, 90, 91, 92, 84, 86, 82, 83,
-	68, 97, 0, 0, 0, 69, 70, 71, 72, 74,
-	73, 75, 76, 77, 78, 79, 80, 81, 366, 0,
-	0, 0, 0, 0, 0, 0, 0, 96, 95, 0,
-	85, 94, 93, 0, 0, 0, 0, 0, 0, 0,
-	87, 88, 89, 90, 91, 92, 84, 86, 82, 83,
-	68, 97, 0, 0, 0, 69, 70, 71, 72, 74,
-	73, 75, 76, 77, 78, 79, 80, 81, 359, 0,
-	0, 0, 0, 0, 0, 0, 0, 96, 95, 0,
-	85, 94, 93, 0, 0, 0, 0, 0, 0, 0,
-	87, 88, 89, 90, 91, 92, 84, 86, 82, 83,
-	68, 97, 0, 0, 0, 69, 70, 71, 72, 74,
-	73, 75, 76, 77, 78, 79, 80, 81, 341, 0,
-	0, 0, 0, 0, 0, 0, 0, 96, 95, 0,
-	85, 94, 93, 0, 0, 0, 0, 0, 0, 0,
-	87, 88, 89, 90, 91, 92, 84, 86, 82, 83,
-	68, 97, 0, 0, 0, 69, 70, 71, 72, 74,
-	73, 75, 76, 77, 78, 79, 80, 81, 340, 0,
-	0, 0, 0, 0, 0, 0, 0, 96, 95, 0,
-	85, 94, 93, 0, 0, 0, 0, 0, 0, 0,
-	87, 88, 89, 90, 91, 92, 84, 86, 82, 83,
-	68, 97, 0, 0, 0, 69, 70, 71, 72, 74,
-	73, 75, 76, 77, 78, 79, 80, 81, 339, 0,
-	0, 0, 0, 0, 0, 0, 0, 96, 95, 0,
-	85, 94, 93, 0, 0, 0, 0, 0, 0, 0,
-	87, 88, 89, 90, 91, 92, 84, 86, 82, 83,
-	68, 97, 0, 0, 0, 69, 70, 71, 72, 74,
-	73, 75, 76, 77, 78, 79, 80, 81, 338, 0,
-	0, 0, 0, 0, 0, 0, 0, 96, 95, 0,
-	85, 94, 93, 0, 0, 0, 0, 0, 0, 0,
-	87, 88, 89, 90, 91, 92, 84, 86, 82, 83,
-	68, 97, 0, 0, 0, 69, 70, 71, 72, 74,
-	73, 75, 76, 77, 78, 79, 80, 81, 336, 0,
-	0, 0, 0, 0, 0, 0, 0, 0, 96, 95,
-	0, 85, 94, 93, 0, 0, 0, 0, 0, 0,
-	0, 87, 88, 89, 90, 91, 92, 84, 86, 82,
-	83, 68, 97, 0, 0, 0, 69, 70, 71, 72,
-	74, 73, 75, 76, 77, 78, 79, 80, 81, 335,
-	0, 0, 0, 0, 0, 0, 0, 0, 0, 96,
-	95, 0, 85, 94, 93, 0, 0, 0, 0, 0,
-	0, 0, 87, 88, 89, 90, 91, 92, 84, 86,
-	82, 83, 68, 97, 0, 0, 0, 69, 70, 71,
-	72, 74, 73, 75, 76, 77, 78, 79, 80, 81,
-	333, 0, 0, 0, 0, 0, 0, 0, 0, 96,
-	95, 0, 85, 94, 93, 0, 0, 0, 0, 0,
-	0, 0, 87, 88, 89, 90, 91, 92, 84, 86,
-	82, 83, 68, 97, 315, 0, 0, 69, 70, 71,
-	72, 74, 73, 75, 76, 77, 78, 79, 80, 81,
-	96, 95, 0, 85, 94, 93, 0, 0, 331, 0,
-	0, 0, 0, 87, 88, 89, 90, 91, 92, 84,
-	86, 82, 83, 68, 97, 0, 0, 0, 69, 70,
-	71, 72, 74, 73, 75, 76, 77, 78, 79, 80,
-	81, 0, 0, 96, 95, 0, 85, 94, 93, 0,
-	0, 0, 0, 0, 0, 0, 87, 88, 89, 90,
-	91, 92, 84, 86, 82, 83, 68, 97, 0, 0,
-	0, 69, 70, 71, 72, 74, 73, 75, 76, 77,
-	78, 79, 80, 81, 96, 95, 254, 85, 94, 93,
-	0, 0, 304, 0, 0, 0, 0, 87, 88, 89,
-	90, 91, 92, 84, 86, 82, 83, 68, 97, 0,
-	0, 0, 69, 70, 71, 72, 74, 73, 75, 76,
-	77, 78, 79, 80, 81, 0, 0, 0, 0, 0,
-	0, 0, 96, 95, 0, 85, 94, 93, 0, 0,
-	0, 0, 0, 0, 0, 87, 88, 89, 90, 91,
-	92, 84, 86, 82, 83, 68, 97, 0, 0, 0,
-	69, 70, 71, 72, 74, 73, 75, 76, 77, 78,
-	79, 80, 81, 253, 0, 0, 0, 0, 0, 0,
-	0, 0, 0, 96, 95, 0, 85, 94, 93, 0,
-	0, 0, 0, 0, 0, 0, 87, 88, 89, 90,
-	91, 92, 84, 86, 82, 83, 68, 97, 0, 0,
-	0, 69, 70, 71, 72, 74, 73, 75, 76, 77,
-	78, 79, 80, 81, 96, 95, 0, 85, 94, 93,
-	0, 0, 0, 0, 0, 0, 0, 87, 88, 89,
-	90, 91, 92, 84, 86, 82, 83, 68, 97, 0,
-	0, 0, 69, 70, 71, 72, 74, 73, 75, 76,
-	77, 78, 79, 80, 81, 95, 0, 85, 94, 93,
-	0, 0, 0, 0, 0, 0, 0, 87, 88, 89,
-	90, 91, 92, 84, 86, 82, 83, 68, 97, 0,
-	0, 0, 69, 70, 71, 72, 74, 73, 75, 76,
-	77, 78, 79, 80, 81, 85, 94, 93, 0, 0,
-	0, 0, 0, 0, 0, 87, 88, 89, 90, 91,
-	92, 84, 86, 82, 83, 68, 97, 0, 0, 0,
-	69, 70, 71, 72, 74, 73, 75, 76, 77, 78,
-	79, 80, 81,
+	0, 0, 56, 57, 62, 61, 58, 63, 59, 60,
+	67, 91, 0, 0, 0, 0, 0, 0, 21, 27,
+	26, 53, 54, 30, 71, 0, 0, 29, 0, 28,
+	0, 24, 22, 23, 25, 0, 551, 552, 69, 68,
+	0, 55, 0, 0, 0, 0, 0, 64, 114, 116,
+	112, 113, 98, 127, 0, 0, 0, 99, 100, 101,
+	102, 104, 103, 105, 106, 107, 108, 109, 110, 111,
+	67, 0, 0, 0, 0, 0, 0, 0, 21, 27,
+	26, 126, 125, 0, 115, 124, 123, 253, 0, 0,
+	0, 0, 0, 0, 117, 118, 119, 120, 121, 122,
+	114, 116, 112, 113, 98, 127, 0, 0, 0, 99,
+	100, 101, 102, 104, 103, 105, 106, 107, 108, 109,
+	110, 111, 0, 0, 0, 30, 0, 0, 0, 0,
+	0, 0, 0, 0, 0, 0, 0, 126, 125, 0,
+	115, 124, 123, 0, 0, 0, 0, 0, 0, 0,
+	117, 118, 119, 120, 121, 122, 114, 116, 112, 113,
+	98, 127, 0, 0, 0, 99, 100, 101, 102, 104,
+	103, 105, 106, 107, 108, 109, 110, 111, 530, 0,
+	0, 0, 0, 0, 0, 0, 0, 126, 125, 0,
+	115, 124, 123, 0, 0, 0, 0, 0, 0, 0,
+	117, 118, 119, 120, 121, 122, 114, 116, 112, 113,
+	98, 127, 0, 0, 0, 99, 100, 101, 102, 104,
+	103, 105, 106, 107, 108, 109, 110, 111, 511, 0,
+	0, 0, 0, 0, 0, 0, 0, 126, 125, 0,
+	115, 124, 123, 0, 0, 0, 0, 0, 0, 0,
+	117, 118, 119, 120, 121, 122, 114, 116, 112, 113,
+	98, 127, 0, 0, 0, 99, 100, 101, 102, 104,
+	103, 105, 106, 107, 108, 109, 110, 111, 510, 0,
+	0, 0, 0, 0, 0, 0, 0, 126, 125, 0,
+	115, 124, 123, 0, 0, 0, 0, 0, 0, 0,
+	117, 118, 119, 120, 121, 122, 114, 116, 112, 113,
+	98, 127, 0, 0, 0, 99, 100, 101, 102, 104,
+	103, 105, 106, 107, 108, 109, 110, 111, 509, 0,
+	0, 0, 0, 0, 0, 0, 0, 126, 125, 0,
+	115, 124, 123, 0, 0, 0, 0, 0, 0, 0,
+	117, 118, 119, 120, 121, 122, 114, 116, 112, 113,
+	98, 127, 0, 0, 0, 99, 100, 101, 102, 104,
+	103, 105, 106, 107, 108, 109, 110, 111, 508, 0,
+	0, 0, 0, 0, 0, 0, 0, 126, 125, 0,
+	115, 124, 123, 0, 0, 0, 0, 0, 0, 0,
+	117, 118, 119, 120, 121, 122, 114, 116, 112, 113,
+	98, 127, 0, 0, 0, 99, 100, 101, 102, 104,
+	103, 105, 106, 107, 108, 109, 110, 111, 507, 0,
+	0, 0, 0, 0, 0, 0, 0, 126, 125, 0,
+	115, 124, 123, 0, 0, 0, 0, 0, 0, 0,
+	117, 118, 119, 120, 121, 122, 114, 116, 112, 113,
+	98, 127, 0, 0, 0, 99, 100, 101, 102, 104,
+	103, 105, 106, 107, 108, 109, 110, 111, 477, 0,
+	0, 0, 0, 0, 0, 0, 0, 126, 125, 0,
+	115, 124, 123, 0, 0, 0, 0, 0, 0, 0,
+	117, 118, 119, 120, 121, 122, 114, 116, 112, 113,
+	98, 127, 0, 0, 0, 99, 100, 101, 102, 104,
+	103, 105, 106, 107, 108, 109, 110, 111, 432, 0,
+	0, 0, 0, 0, 0, 0, 0, 126, 125, 0,
+	115, 124, 123, 0, 0, 0, 0, 0, 0, 0,
+	117, 118, 119, 120, 121, 122, 114, 116, 112, 113,
+	98, 127, 0, 0, 0, 99, 100, 101, 102, 104,
+	103, 105, 106, 107, 108, 109, 110, 111, 431, 0,
+	0, 0, 0, 0, 0, 0, 0, 126, 125, 0,
+	115, 124, 123, 0, 0, 0, 0, 0, 0, 0,
+	117, 118, 119, 120, 121, 122, 114, 116, 112, 113,
+	98, 127, 0, 0, 0, 99, 100, 101, 102, 104,
+	103, 105, 106, 107, 108, 109, 110, 111, 430, 0,
+	0, 0, 0, 0, 0, 0, 0, 126, 125, 0,
+	115, 124, 123, 0, 0, 0, 0, 0, 0, 0,
+	117, 118, 119, 120, 121, 122, 114, 116, 112, 113,
+	98, 127, 0, 0, 0, 99, 100, 101, 102, 104,
+	103, 105, 106, 107, 108, 109, 110, 111, 429, 0,
+	0, 0, 0, 0, 0, 0, 0, 126, 125, 0,
+	115, 124, 123, 0, 0, 0, 0, 0, 0, 0,
+	117, 118, 119, 120, 121, 122, 114, 116, 112, 113,
+	98, 127, 0, 0, 0, 99, 100, 101, 102, 104,
+	103, 105, 106, 107, 108, 109, 110, 111, 427, 0,
+	0, 0, 0, 0, 0, 0, 0, 126, 125, 0,
+	115, 124, 123, 0, 0, 0, 0, 0, 0, 0,
+	117, 118, 119, 120, 121, 122, 114, 116, 112, 113,
+	98, 127, 0, 0, 0, 99, 100, 101, 102, 104,
+	103, 105, 106, 107, 108, 109, 110, 111, 426, 0,
+	0, 0, 0, 0, 0, 0, 0, 0, 126, 125,
+	0, 115, 124, 123, 0, 0, 0, 0, 0, 0,
+	0, 117, 118, 119, 120, 121, 122, 114, 116, 112,
+	113, 98, 127, 0, 0, 0, 99, 100, 101, 102,
+	104, 103, 105, 106, 107, 108, 109, 110, 111, 425,
+	0, 0, 0, 0, 0, 0, 0, 0, 0, 126,
+	125, 0, 115, 124, 123, 0, 0, 0, 0, 0,
+	0, 0, 117, 118, 119, 120, 121, 122, 114, 116,
+	112, 113, 98, 127, 0, 0, 0, 99, 100, 101,
+	102, 104, 103, 105, 106, 107, 108, 109, 110, 111,
+	424, 0, 0, 0, 0, 0, 0, 0, 0, 0,
+	126, 125, 0, 115, 124, 123, 0, 0, 0, 0,
+	0, 0, 0, 117, 118, 119, 120, 121, 122, 114,
+	116, 112, 113, 98, 127, 0, 0, 0, 99, 100,
+	101, 102, 104, 103, 105, 106, 107, 108, 109, 110,
+	111, 423, 0, 0, 0, 0, 0, 0, 0, 0,
+	0, 126, 125, 0, 115, 124, 123, 0, 0, 0,
+	0, 0, 0, 0, 117, 118, 119, 120, 121, 122,
+	114, 116, 112, 113, 98, 127, 0, 0, 0, 99,
+	100, 101, 102, 104, 103, 105, 106, 107, 108, 109,
+	110, 111, 421, 0, 0, 0, 0, 0, 0, 0,
+	0, 126, 125, 0, 115, 124, 123, 381, 0, 0,
+	0, 0, 0, 0, 117, 118, 119, 120, 121, 122,
+	114, 116, 112, 113, 98, 127, 0, 0, 0, 99,
+	100, 101, 102, 104, 103, 105, 106, 107, 108, 109,
+	110, 111, 126, 125, 0, 115, 124, 123, 0, 0,
+	419, 0, 0, 0, 0, 117, 118, 119, 120, 121,
+	122, 114, 116, 112, 113, 98, 127, 0, 0, 0,
+	99, 100, 101, 102, 104, 103, 105, 106, 107, 108,
+	109, 110, 111, 0, 126, 125, 0, 115, 124, 123,
+	0, 0, 0, 0, 0, 0, 0, 117, 118, 119,
+	120, 121, 122, 114, 116, 112, 113, 98, 127, 0,
+	0, 0, 99, 100, 101, 102, 104, 103, 105, 106,
+	107, 108, 109, 110, 111, 126, 125, 307, 115, 124,
+	123, 0, 0, 368, 0, 0, 0, 0, 117, 118,
+	119, 120, 121, 122, 114, 116, 112, 113, 98, 127,
+	0, 0, 0, 99, 100, 101, 102, 104, 103, 105,
+	106, 107, 108, 109, 110, 111, 0, 0, 0, 0,
+	0, 0, 0, 0, 0, 0, 0, 126, 125, 0,
+	115, 124, 123, 0, 0, 0, 0, 0, 0, 0,
+	117, 118, 119, 120, 121, 122, 114, 116, 112, 113,
+	98, 127, 0, 0, 0, 99, 100, 101, 102, 104,
+	103, 105, 106, 107, 108, 109, 110, 111, 306, 0,
+	0, 0, 0, 0, 0, 0, 0, 0, 126, 125,
+	0, 115, 124, 123, 0, 0, 0, 0, 0, 0,
+	0, 117, 118, 119, 120, 121, 122, 114, 116, 112,
+	113, 98, 127, 0, 0, 0, 99, 100, 101, 102,
+	104, 103, 105, 106, 107, 108, 109, 110, 111, 177,
+	0, 0, 0, 0, 0, 0, 126, 125, 0, 115,
+	124, 123, 0, 0, 0, 0, 0, 0, 0, 117,
+	118, 119, 120, 121, 122, 114, 116, 112, 113, 98,
+	127, 0, 0, 0, 99, 100, 101, 102, 104, 103,
+	105, 106, 107, 108, 109, 110, 111, 126, 125, 0,
+	115, 124, 123, 0, 0, 0, 0, 0, 0, 0,
+	117, 118, 119, 120, 121, 122, 114, 116, 112, 113,
+	98, 127, 0, 0, 0, 99, 100, 101, 102, 104,
+	103, 105, 106, 107, 108, 109, 110, 111, 125, 0,
+	115, 124, 123, 0, 0, 0, 0, 0, 0, 0,
+	117, 118, 119, 120, 121, 122, 114, 116, 112, 113,
+	98, 127, 0, 0, 0, 99, 100, 101, 102, 104,
+	103, 105, 106, 107, 108, 109, 110, 111, 115, 124,
+	123, 0, 0, 0, 0, 0, 0, 0, 117, 118,
+	119, 120, 121, 122, 114, 116, 112, 113, 98, 127,
+	0, 0, 0, 99, 100, 101, 102, 104, 103, 105,
+	106, 107, 108, 109, 110, 111,
 }
 
 var yyPact = [...]int16{
-	360, -1000, 363, 352, 405, 184, 256, 256, 408, 358,
-	256, 348, -1000, -1000, -1000, 351, 413, 267, 342, 254,
-	408, 404, 358, 230, -1000, 681, -1000, -1000, -1000, 251,
-	767, 250, 248, 247, 242, 241, 240, 235, 202, 199,
-	196, 192, 767, 767, 767, 767, -3, 229, -1000, -1000,
-	-1000, -1000, -1000, -1000, -1000, -75, 767, 190, 189, 404,
-	-1000, 408, 413, 399, 413, 289, 256, -1000, 187, 767,
-	767, 767, 767, 767, 767, 767, 767, 767, 767, 767,
-	767, 767, -59, -60, 21, -61, -62, 767, 767, 767,
-	767, 767, 767, 53, 37, 767, 767, 71, 108, 27,
-	1617, 767, 767, 767, 263, 262, 260, 259, 168, 354,
-	645, 404, -1000, 1695, 1695, 339, 1617, 256, -94, 165,
-	-1000, 1617, 76, -1000, -99, 88, 1617, 767, 404, 164,
-	-1000, 232, 396, 167, 413, -1000, -3, -1000, -1000, 229,
-	397, 175, 70, -80, -80, -80, -42, -42, -48, -48,
-	-48, -1000, -1000, -25, -26, -63, -1000, -1000, 99, 99,
-	99, 99, 99, 99, 45, -65, -70, -46, -71, -72,
-	1695, 1657, -1000, 92, -1000, -1000, -1000, -8, 586, -1000,
-	55, 767, 126, 1617, 1576, 1525, 182, 178, 163, 398,
-	-1000, 450, 767, -1000, -1000, -1000, -1000, 123, 152, 256,
-	256, -1000, 61, 60, -1000, -1000, -1000, -75, 767, -1000,
-	767, 104, 150, -1000, 396, 393, 767, 413, 413, -1000,
-	290, -1000, 288, 277, 275, 285, -1000, 133, 102, -78,
-	-79, -1000, 53, -27, -28, -82, -1000, -1000, -1000, -1000,
-	-1000, -1000, -5, 186, 95, 1617, -1000, 23, 767, 767,
-	1477, -1000, 767, 767, 258, 767, 767, 257, 767, 767,
-	-1000, 767, 767, 1436, -1000, -1000, 314, 335, -1000, -1000,
-	-1000, 1617, 1617, -1000, -1000, 393, 369, 386, 1617, -1000,
-	180, -1000, -1000, -1000, 283, -1000, 279, -1000, -1000, -1000,
-	-1000, -1000, -1000, -83, -95, -1000, -1000, 169, 395, -8,
-	767, -1000, 1393, 1617, 767, 1617, 1352, 91, 1302, 1251,
-	85, 1200, 1150, 1100, 1050, 767, 256, 256, 369, 391,
-	767, 413, 767, -1000, -1000, -1000, -1000, 304, 767, -5,
-	1617, 767, 1617, -1000, -1000, 767, 767, 177, -1000, -1000,
-	-1000, -1000, 1000, -1000, -1000, 391, 367, 385, 1617, 176,
-	1617, 391, 373, 950, -1000, 1617, 900, 850, 767, -1000,
-	367, 365, -55, 767, 81, 767, -1000, -1000, -1000, 800,
-	365, -1000, -55, -1000, 174, -1000, 500, -1000, 173, -1000,
-	-1000, -1000, 767, 324, -1000, -1000, -1000, -1000, 337, -1000,
-	-1000,
+	482, -1000, -1000, 512, -1000, 465, 451, 449, 443, 423,
+	86, 514, 457, 271, 324, 444, 336, 441, 324, 80,
+	-1000, -1000, -1000, -1000, -1000, -1000, -1000, -1000, -66, 980,
+	-1000, 516, 426, 86, 324, 421, 336, 87, 442, -53,
+	336, 411, -1000, -1000, 12, 920, 980, 111, -1000, -98,
+	107, 2252, -1000, 322, 321, 980, 320, 319, 316, 315,
+	314, 313, 312, 310, 305, 304, 303, 980, 980, 980,
+	-11, 800, -1000, 450, 513, 342, 78, 420, 300, 298,
+	407, 270, -1000, 403, 401, 335, -1000, -1000, -1000, -1000,
+	2211, 106, 2252, -1000, -66, 980, -1000, 980, 297, 980,
+	980, 980, 980, 980, 980, 980, 980, 980, 980, 980,
+	980, 980, -67, -68, 40, -70, -75, 980, 980, 980,
+	980, 980, 980, 9, 83, 980, 980, 91, 244, 980,
+	51, 2252, 980, 980, 980, -15, -35, -36, 334, 230,
+	573, 860, 487, -1000, 2330, 2330, 226, -1000, 2252, 426,
+	516, 487, 279, -1000, 1082, -1000, -1000, 318, 296, 980,
+	633, 294, 487, 496, 292, 487, 393, 325, -1000, 442,
+	495, 488, 341, 486, 485, -1000, -1000, -1000, -1000, -1000,
+	2252, 2252, 800, 114, 142, 164, 26, 26, 26, 7,
+	7, -45, -45, -45, -1000, -1000, 1, -13, -76, -1000,
+	-1000, 974, 974, 974, 974, 974, 974, 92, -78, -79,
+	21, -80, -81, 2330, 2292, -1000, 127, -1000, -1000, -1000,
+	-14, 740, -1000, 259, 2252, 60, 980, 257, 2163, 2112,
+	269, 268, 267, 266, 265, 262, 511, -1000, 666, 980,
+	-1000, -1000, -1000, -1000, 255, 175, -1000, 513, -1000, 516,
+	387, 513, 86, 324, -1000, 324, 284, 980, 405, 2252,
+	291, 980, -1000, 290, 487, 170, 437, 484, 253, -1000,
+	-2, -1000, -1000, 287, 286, -1000, 325, -1000, -1000, 150,
+	249, -82, -85, -1000, 9, -31, -34, -86, -1000, -1000,
+	-1000, -1000, -1000, -1000, 30, 285, 247, 2252, -1000, -14,
+	980, 47, 980, 980, 2060, -1000, 980, 332, 980, 980,
+	980, 980, 980, 331, 980, 980, -1000, 980, 980, 2019,
+	-1000, -1000, 264, -1000, 506, -1000, -11, -1000, 284, -1000,
+	324, 224, 324, 324, 218, 980, 216, 324, 141, -1000,
+	435, 483, 283, -1000, 325, 329, 73, 326, 325, 37,
+	-1000, -1000, -1000, -1000, -1000, -87, -89, -1000, -1000, 282,
+	503, -14, 980, 30, 2252, -1000, 1977, 2252, 980, 1936,
+	128, 1886, 1835, 1784, 1733, 1682, 122, 1632, 1582, 1532,
+	1482, 980, 502, 179, 513, 502, -1000, 199, -1000, 419,
+	395, 416, -1000, 281, 195, -1000, 174, -1000, 505, 341,
+	277, 326, -1000, -1000, -1000, 98, 149, -1000, 327, 145,
+	-54, -91, -1000, -1000, 390, 980, 30, 2252, -1000, 980,
+	2252, -1000, -1000, 980, 980, 980, 980, -1000, 263, -1000,
+	-1000, -1000, -1000, 1432, 500, 513, 513, -1000, 366, -1000,
+	361, 352, 350, 360, -1000, 500, -1000, 324, 276, 324,
+	324, 980, -1000, -1000, -1000, -96, -1000, 325, 143, -1000,
+	326, -1000, 415, -1000, 325, -1000, -1000, 498, 479, 1382,
+	-1000, 2252, 1332, 1282, 1232, 1182, 980, -1000, 474, 477,
+	-1000, 323, -1000, -1000, -1000, 353, -1000, 351, -1000, 474,
+	-1000, 324, -1000, -1000, 137, 251, -1000, 431, 126, -1000,
+	-1000, -1000, -95, -54, 117, 476, 980, -1000, -1000, -1000,
+	-1000, -1000, 1132, 472, 980, 513, 980, -1000, -1000, 472,
+	120, -1000, -96, 428, -46, -1000, -1000, -1000, 980, 239,
+	-1000, 498, 980, 2252, 233, 2252, 498, -1000, -1000, -1000,
+	-1000, -1000, -1000, 180, -1000, 1026, 466, 2252, 466, 980,
+	404, -1000, -1000, 464, -63, 464, -1000, -1000, 406, -1000,
+	-63, -1000, -1000, -1000, -1000, -1000, -1000,
 }
 
 var yyPgo = [...]int16{
-	0, 459, 0, 141, 11, 457, 12, 7, 447, 446,
-	445, 9, 444, 443, 442, 440, 439, 438, 437, 28,
-	2, 38, 436, 10, 20, 19, 15, 434, 432, 4,
-	431, 430, 13, 429, 349, 1, 5, 428, 426, 6,
-	3, 423, 8, 422, 421, 130, 416,
+	0, 599, 0, 25, 32, 598, 20, 9, 6, 593,
+	591, 589, 21, 588, 583, 582, 581, 580, 579, 578,
+	42, 1, 40, 577, 15, 8, 16, 24, 576, 575,
+	11, 574, 573, 158, 564, 31, 3, 4, 563, 561,
+	5, 2, 560, 17, 559, 557, 552, 551, 23, 10,
+	28, 26, 548, 442, 547, 29, 22, 546, 545, 12,
+	544, 19, 543, 14, 13, 542, 18, 7, 540, 539,
+	538, 537, 535, 532, 531, 528, 527, 525, 523,
 }
 
 var yyR1 = [...]int8{
-	0, 1, 22, 21, 44, 44, 44, 5, 5, 14,
-	14, 45, 45, 45, 15, 15, 25, 25, 25, 25,
-	25, 3, 3, 3, 3, 3, 3, 3, 3, 3,
-	3, 3, 3, 3, 4, 4, 10, 10, 18, 18,
-	34, 34, 34, 2, 2, 2, 2, 2, 2, 2,
+	0, 77, 77, 77, 52, 52, 52, 70, 70, 71,
+	71, 53, 53, 64, 64, 63, 63, 57, 57, 58,
+	58, 56, 72, 72, 60, 60, 61, 61, 59, 74,
+	74, 75, 75, 62, 62, 65, 65, 66, 68, 68,
+	69, 69, 67, 76, 76, 76, 73, 73, 54, 54,
+	55, 55, 55, 55, 55, 55, 1, 1, 23, 22,
+	45, 45, 45, 5, 5, 50, 50, 15, 15, 51,
+	51, 51, 16, 16, 26, 26, 26, 26, 26, 26,
+	26, 26, 26, 46, 47, 47, 48, 48, 49, 49,
+	3, 3, 3, 3, 3, 3, 3, 3, 3, 3,
+	3, 3, 3, 3, 4, 4, 11, 11, 19, 19,
+	35, 35, 35, 2, 2, 2, 2, 2, 2, 2,
 	2, 2, 2, 2, 2, 2, 2, 2, 2, 2,
 	2, 2, 2, 2, 2, 2, 2, 2, 2, 2,
 	2, 2, 2, 2, 2, 2, 2, 2, 2, 2,
 	2, 2, 2, 2, 2, 2, 2, 2, 2, 2,
 	2, 2, 2, 2, 2, 2, 2, 2, 2, 2,
 	2, 2, 2, 2, 2, 2, 2, 2, 2, 2,
-	2, 2, 24, 24, 29, 29, 33, 33, 33, 30,
-	30, 30, 31, 31, 31, 32, 28, 28, 42, 42,
-	38, 38, 38, 38, 38, 38, 38, 46, 46, 26,
-	26, 27, 27, 27, 20, 19, 9, 9, 41, 41,
-	8, 8, 11, 11, 6, 6, 7, 7, 23, 23,
-	17, 17, 17, 16, 16, 16, 35, 37, 37, 36,
-	36, 39, 39, 40, 40, 12, 12, 12, 12, 13,
-	43, 43, 43,
+	2, 2, 2, 2, 2, 2, 25, 25, 30, 30,
+	34, 34, 34, 31, 31, 31, 32, 32, 32, 33,
+	29, 29, 43, 43, 39, 39, 39, 39, 39, 39,
+	39, 78, 78, 27, 27, 28, 28, 28, 21, 20,
+	10, 10, 42, 42, 9, 9, 12, 12, 6, 6,
+	7, 7, 8, 8, 24, 24, 18, 18, 18, 17,
+	17, 17, 36, 38, 38, 37, 37, 40, 40, 41,
+	41, 13, 13, 13, 13, 14, 44, 44, 44,
 }
 
 var yyR2 = [...]int8{
-	0, 4, 11, 10, 1, 3, 0, 2, 0, 1,
-	0, 0, 3, 4, 6, 7, 3, 2, 1, 1,
-	1, 1, 1, 1, 1, 1, 1, 1, 1, 3,
-	3, 3, 4, 4, 1, 3, 1, 1, 1, 0,
-	5, 1, 0, 1, 5, 7, 5, 4, 6, 6,
-	8, 8, 9, 6, 6, 3, 4, 6, 6, 7,
-	3, 4, 5, 5, 4, 3, 3, 3, 3, 3,
-	3, 3, 3, 3, 3, 3, 3, 3, 2, 5,
-	3, 5, 3, 4, 3, 3, 3, 3, 3, 3,
-	3, 3, 5, 4, 6, 4, 6, 5, 4, 4,
-	2, 2, 3, 3, 3, 4, 3, 4, 3, 4,
-	3, 4, 1, 3, 1, 3, 1, 1, 3, 1,
-	3, 0, 1, 3, 0, 3, 3, 0, 5, 0,
-	1, 2, 2, 3, 2, 3, 2, 1, 2, 1,
-	0, 2, 3, 5, 1, 1, 0, 2, 4, 5,
-	0, 1, 0, 5, 0, 2, 0, 2, 0, 3,
-	0, 2, 2, 0, 1, 1, 3, 3, 1, 0,
-	3, 0, 2, 0, 2, 6, 6, 4, 4, 1,
-	1, 1, 1,
+	0, 1, 4, 1, 9, 4, 4, 3, 0, 2,
+	0, 1, 3, 1, 3, 1, 3, 3, 0, 1,
+	3, 3, 2, 0, 5, 0, 1, 3, 3, 1,
+	0, 2, 0, 5, 0, 2, 0, 4, 2, 0,
+	1, 3, 3, 2, 2, 0, 1, 0, 1, 3,
+	6, 3, 6, 3, 3, 2, 4, 6, 13, 11,
+	1, 3, 0, 2, 0, 5, 0, 1, 0, 0,
+	3, 4, 6, 7, 3, 2, 1, 1, 1, 4,
+	3, 1, 8, 4, 3, 5, 3, 0, 1, 3,
+	1, 1, 1, 1, 1, 1, 1, 1, 3, 3,
+	3, 4, 4, 3, 1, 3, 1, 1, 1, 0,
+	5, 1, 0, 1, 5, 7, 6, 5, 4, 6,
+	6, 8, 8, 8, 8, 6, 9, 6, 6, 3,
+	4, 6, 6, 7, 3, 4, 5, 5, 4, 3,
+	3, 3, 3, 3, 3, 3, 3, 3, 3, 3,
+	3, 3, 2, 5, 3, 5, 3, 4, 3, 3,
+	3, 3, 3, 3, 3, 3, 5, 4, 6, 4,
+	6, 5, 4, 4, 2, 2, 3, 3, 3, 4,
+	3, 4, 3, 4, 3, 4, 1, 3, 1, 3,
+	1, 1, 3, 1, 3, 0, 1, 3, 0, 3,
+	3, 0, 5, 0, 1, 2, 2, 3, 2, 3,
+	2, 1, 2, 1, 0, 2, 3, 5, 1, 1,
+	0, 2, 4, 5, 0, 1, 0, 5, 0, 2,
+	0, 2, 0, 2, 0, 3, 0, 2, 2, 0,
+	1, 1, 3, 3, 1, 0, 3, 0, 2, 0,
+	2, 6, 6, 4, 4, 1, 1, 1, 1,
 }
 
 var yyChk = [...]int16{
-	-1000, -1, -44, 18, -14, -15, 16, 21, -22, 7,
-	57, -19, 55, -19, -45, 6, -34, 19, -19, 21,
-	-21, 20, 7, -24, -25, -2, 103, -12, -4, 54,
-	73, 35, 36, 39, 41, 42, 38, 37, 40, 79,
-	-19, 22, 102, 71, 70, 28, -3, 56, 110, 64,
-	65, 63, 66, 112, 111, 61, 59, 52, 21, 56,
-	-45, -21, -34, -5, 57, 17, 21, -19, 90, 95,
-	96, 97, 98, 100, 99, 101, 102, 103, 104, 105,
-	106, 107, 88, 89, 86, 70, 87, 80, 81, 82,
-	83, 84, 85, 72, 71, 68, 67, 91, 56, -8,
-	-2, 56, 56, 56, 56, 56, 56, 56, 56, 56,
-	56, 56, -2, -2, -2, -13, -2, 109, 59, -10,
-	-21, -2, -31, -32, 112, -30, -2, 56, 56, -21,
-	-45, -24, -26, -27, 8, -25, -3, -19, -19, 56,
-	-2, -2, -2, -2, -2, -2, -2, -2, -2, -2,
-	-2, -2, -2, 112, 112, 78, 112, 112, -2, -2,
-	-2, -2, -2, -2, -4, 89, 88, 86, 70, 87,
-	-2, -2, 63, 71, 66, 64, 65, 58, -18, 19,
-	-41, 74, -29, -2, -2, -2, 55, 55, 55, 55,
-	58, -2, -43, 32, 33, 34, 58, -29, -21, 21,
-	29, -19, -20, 112, 110, 58, 62, 57, 113, 60,
-	57, -29, -21, 58, -26, -6, 9, -46, -38, 57,
-	48, 45, 49, 46, 47, 51, -25, -21, -29, 94,
-	94, 112, 68, 112, 112, 78, 112, 112, 63, 66,
-	64, 65, -11, 93, -33, -2, 103, -9, 74, 76,
-	-2, 58, 57, 57, 21, 57, 57, 56, 57, 8,
-	58, 57, 8, -2, 58, 58, -19, -19, 60, 60,
-	-32, -2, -2, 58, 58, -6, -23, 10, -2, -25,
-	-25, 45, 45, 45, 50, 45, 50, 45, 58, 58,
-	112, 112, -4, 94, 94, 112, -42, 92, 56, 58,
-	57, 77, -2, -2, 75, -2, -2, 55, -2, -2,
-	55, -2, -2, -2, -2, 8, 29, 21, -23, -7,
-	13, 12, 52, 45, 45, 112, 112, 56, 9, -11,
-	-2, 75, -2, 58, 58, 57, 57, 58, 58, 58,
-	58, 58, -2, -19, -19, -7, -36, 11, -2, -24,
-	-2, -28, 30, -2, -42, -2, -2, -2, 57, 58,
-	-36, -39, 14, 12, -36, 12, 58, 58, 58, -2,
-	-39, -40, 15, -20, -37, -35, -2, 58, -29, 58,
-	-40, -20, 57, -16, 26, 27, -35, -17, 23, 24,
-	25,
+	-1000, -77, -1, 21, -52, -45, 22, 23, 24, 19,
+	8, -15, 20, -16, 17, 25, 25, 25, 35, -3,
+	-20, 128, 82, 83, 81, 84, 130, 129, 79, 77,
+	73, -23, 7, 18, 75, -20, -70, 26, -53, 73,
+	-71, 26, -20, -6, 127, 77, 9, -32, -33, 130,
+	-31, -2, -4, 71, 72, 91, 52, 53, 56, 58,
+	59, 55, 54, 57, 97, -20, 36, 120, 89, 88,
+	-3, 74, -51, 6, -35, 33, -3, -20, 35, -53,
+	89, -54, -55, 27, 24, 127, -53, 36, -20, 121,
+	-2, 121, -2, 80, 75, 131, 78, 75, 108, 113,
+	114, 115, 116, 118, 117, 119, 120, 121, 122, 123,
+	124, 125, 106, 107, 104, 88, 105, 98, 99, 100,
+	101, 102, 103, 90, 89, 86, 85, 109, 74, 74,
+	-9, -2, 74, 74, 74, 74, 74, 74, 74, 74,
+	74, 74, 74, -2, -2, -2, -11, -22, -2, 7,
+	-22, 34, -25, -26, -2, 121, -13, -46, 43, 42,
+	74, 69, -50, 46, 35, 74, -57, 74, 36, 75,
+	45, 28, 29, 45, 28, 29, 73, 78, 78, -33,
+	-2, -2, 74, -2, -2, -2, -2, -2, -2, -2,
+	-2, -2, -2, -2, -2, -2, 130, 130, 96, 130,
+	130, -2, -2, -2, -2, -2, -2, -4, 107, 106,
+	104, 88, 105, -2, -2, 81, 89, 84, 82, 83,
+	76, -19, 33, -30, -2, -42, 92, -30, -2, -2,
+	73, 130, 73, 130, 130, 73, 73, 76, -2, -44,
+	49, 50, 51, 76, -30, -22, 76, -35, -51, -22,
+	-5, 75, 18, 35, -20, 35, -20, 74, -14, -2,
+	48, 74, -22, 12, 74, -22, -60, 45, -58, -56,
+	-64, 73, -55, 12, 12, -66, 69, 12, 12, -22,
+	-30, 112, 112, 130, 86, 130, 130, 96, 130, 130,
+	81, 84, 82, 83, -12, 111, -34, -2, 121, 76,
+	75, -10, 92, 94, -2, 76, 75, 35, 75, 75,
+	75, 75, 75, 74, 75, 8, 76, 75, 8, -2,
+	76, 76, -25, -51, -50, -26, -3, -20, -20, -48,
+	74, -30, 35, 44, -47, 74, -30, 74, -22, 76,
+	-62, 28, 12, 76, 75, 127, 73, 74, 74, -64,
+	76, 76, 130, 130, -4, 112, 112, 130, -43, 110,
+	74, 76, 75, -12, -2, 95, -2, -2, 93, -2,
+	73, -2, -2, -2, -2, -2, 73, -2, -2, -2,
+	-2, 8, -27, -28, 8, -27, -48, -49, -20, 76,
+	-20, -20, 76, 75, -30, 76, -49, 76, -65, 29,
+	12, 74, -56, 73, -72, 89, -61, -59, 73, -63,
+	-64, 30, 130, 130, 74, 9, -12, -2, -43, 93,
+	-2, 76, 76, 75, 75, 75, 75, 76, 76, 76,
+	76, 76, 76, -2, -6, -78, -39, 75, 65, 62,
+	66, 63, 64, 68, -26, -6, 76, 75, 35, 44,
+	35, 74, 76, 76, -68, 8, -66, 74, -61, 81,
+	75, 76, -74, 73, 75, 76, 130, -29, 45, -2,
+	-43, -2, -2, -2, -2, -2, 75, 76, -24, 10,
+	-26, -26, 62, 62, 62, 67, 62, 67, 62, -24,
+	-20, 74, -20, -20, -30, -69, -67, 130, -63, 76,
+	-59, -75, 35, -64, -37, 11, 12, 76, 76, 76,
+	76, 76, -2, -7, 13, 12, 69, 62, 62, -7,
+	-49, 76, 75, -76, 31, 76, 130, 76, 12, -30,
+	76, -8, 14, -2, -25, -2, -8, 76, -67, -73,
+	32, 130, 73, -38, -36, -2, -37, -2, -37, 75,
+	-17, 40, 41, -40, 15, -40, -36, -18, 37, -41,
+	16, -21, 128, -41, 38, 39, -21,
 }
 
 var yyDef = [...]int16{
-	6, -2, 10, 4, 0, 9, 0, 0, 11, 42,
-	0, 0, 145, 5, 1, 0, 0, 41, 0, 0,
-	11, 0, 42, 8, 112, 18, 19, 20, 43, 0,
-	150, 0, 0, 0, 0, 0, 0, 0, 0, 0,
-	21, 0, 0, 0, 0, 0, 34, 0, 22, 23,
-	24, 25, 26, 27, 28, 124, 121, 0, 0, 0,
-	12, 11, 0, 140, 0, 0, 0, 17, 0, 0,
+	62, -2, 1, 0, 3, 68, 0, 0, 0, 60,
+	0, 0, 0, 67, 0, 8, 0, 10, 0, 228,
+	90, 91, 92, 93, 94, 95, 96, 97, 198, 195,
+	219, 69, 112, 0, 0, 0, 0, 0, 0, 11,
+	0, 0, 61, 2, 0, 0, 0, 0, 196, 0,
+	0, 193, 113, 0, 0, 224, 0, 0, 0, 0,
+	0, 0, 0, 0, 0, 90, 0, 0, 0, 0,
+	104, 0, 56, 0, 0, 111, 66, 0, 0, 18,
+	0, 5, 48, 0, 0, 0, 6, 9, 100, 103,
+	0, 0, 229, 98, 0, 0, 99, 0, 0, 0,
+	0, 0, 0, 0, 0, 0, 0, 0, 0, 0,
 	0, 0, 0, 0, 0, 0, 0, 0, 0, 0,
+	0, 0, 0, 0, 0, 0, 0, 0, 109, 0,
+	0, 225, 0, 0, 0, 0, 0, 0, 0, 0,
+	0, 0, 0, 152, 174, 175, 0, 106, 107, 112,
+	69, 0, 64, 186, 76, 77, 78, 81, 0, 0,
+	0, 0, 0, 0, 0, 0, 25, 0, 7, 0,
+	0, 0, 0, 0, 0, 55, 12, 101, 102, 197,
+	199, 194, 0, 139, 140, 141, 142, 143, 144, 145,
+	146, 147, 148, 149, 150, 151, 154, 156, 0, 158,
+	159, 160, 161, 162, 163, 164, 165, 0, 0, 0,
+	0, 0, 0, 176, 177, 178, 0, 180, 182, 184,
+	226, 0, 108, 0, 188, 220, 0, 0, 0, 0,
+	0, 0, 0, 0, 0, 0, 0, 129, 0, 0,
+	256, 257, 258, 134, 0, 0, 105, 0, 70, 69,
+	66, 0, 0, 0, 75, 0, 87, 0, 0, 255,
+	0, 0, 57, 0, 0, 0, 34, 0, 0, 19,
+	0, 13, 49, 0, 0, 54, 0, 51, 53, 0,
+	0, 0, 0, 157, 0, 167, 169, 0, 172, 173,
+	179, 181, 183, 185, 203, 0, 0, 190, 191, 226,
+	0, 0, 0, 0, 0, 118, 0, 0, 0, 0,
+	0, 0, 0, 0, 0, 0, 130, 0, 0, 0,
+	135, 138, 214, 71, 214, 187, 63, 74, 87, 80,
+	0, 0, 0, 0, 0, 0, 0, 0, 0, 72,
+	36, 0, 0, 17, 0, 0, 23, 0, 0, 0,
+	136, 137, 153, 155, 166, 0, 0, 171, 114, 0,
+	0, 226, 0, 203, 189, 117, 0, 221, 0, 0,
 	0, 0, 0, 0, 0, 0, 0, 0, 0, 0,
-	0, 0, 0, 0, 0, 0, 0, 0, 39, 0,
-	151, 0, 0, 0, 0, 0, 0, 0, 0, 0,
-	0, 0, 78, 100, 101, 0, 179, 0, 0, 0,
-	36, 37, 0, 122, 0, 0, 119, 0, 0, 0,
-	13, 140, 154, 139, 0, 113, 7, 21, 16, 0,
-	65, 66, 67, 68, 69, 70, 71, 72, 73, 74,
-	75, 76, 77, 80, 82, 0, 84, 85, 86, 87,
-	88, 89, 90, 91, 0, 0, 0, 0, 0, 0,
-	102, 103, 104, 0, 106, 108, 110, 152, 0, 38,
-	146, 0, 0, 114, 0, 0, 0, 0, 0, 0,
-	55, 0, 0, 180, 181, 182, 60, 0, 0, 0,
-	0, 31, 0, 0, 144, 35, 29, 0, 0, 30,
-	0, 0, 0, 14, 154, 158, 0, 0, 0, 137,
-	0, 130, 0, 0, 0, 0, 141, 0, 0, 0,
-	0, 83, 0, 93, 95, 0, 98, 99, 105, 107,
-	109, 111, 129, 0, 0, 116, 117, 0, 0, 0,
-	0, 47, 0, 0, 0, 0, 0, 0, 0, 0,
-	56, 0, 0, 0, 61, 64, 177, 178, 32, 33,
-	123, 125, 120, 40, 15, 158, 156, 0, 155, 142,
-	0, 138, 131, 132, 0, 134, 0, 136, 62, 63,
-	79, 81, 92, 0, 0, 97, 44, 0, 0, 152,
-	0, 46, 0, 147, 0, 115, 0, 0, 0, 0,
-	0, 0, 0, 0, 0, 0, 0, 0, 156, 169,
-	0, 0, 0, 133, 135, 94, 96, 127, 0, 129,
-	118, 0, 148, 48, 49, 0, 0, 0, 53, 54,
-	57, 58, 0, 175, 176, 169, 171, 0, 157, 159,
-	143, 169, 0, 0, 45, 149, 0, 0, 0, 59,
-	171, 173, 0, 0, 0, 0, 153, 50, 51, 0,
-	173, 2, 0, 172, 170, 168, 163, 128, 126, 52,
-	3, 174, 0, 160, 164, 165, 167, 166, 0, 161,
-	162,
+	0, 0, 228, 213, 0, 228, 79, 0, 88, 0,
+	253, 254, 83, 0, 0, 110, 0, 73, 39, 0,
+	0, 0, 20, 14, 21, 0, 0, 26, 30, 0,
+	15, 0, 168, 170, 201, 0, 203, 192, 116, 0,
+	222, 119, 120, 0, 0, 0, 0, 125, 0, 127,
+	128, 131, 132, 0, 234, 0, 0, 211, 0, 204,
+	0, 0, 0, 0, 215, 234, 86, 0, 0, 0,
+	0, 0, 84, 65, 4, 0, 35, 0, 0, 22,
+	0, 50, 32, 29, 0, 52, 37, 245, 0, 0,
+	115, 223, 0, 0, 0, 0, 0, 133, 230, 0,
+	216, 0, 212, 205, 206, 0, 208, 0, 210, 230,
+	89, 0, 251, 252, 0, 38, 40, 45, 0, 24,
+	27, 28, 0, 16, 0, 0, 0, 227, 121, 123,
+	122, 124, 0, 232, 0, 0, 0, 207, 209, 232,
+	0, 85, 0, 47, 0, 33, 31, 202, 0, 200,
+	126, 245, 0, 231, 235, 217, 245, 82, 41, 42,
+	46, 43, 44, 246, 244, 239, 247, 233, 247, 0,
+	236, 240, 241, 249, 0, 249, 243, 242, 0, 59,
+	0, 248, 218, 58, 237, 238, 250,
 }
 
-var yyTok1 = [...]int8{
+var yyTok1 = [...]uint8{
 	1, 3, 3, 3, 3, 3, 3, 3, 3, 3,
 	3, 3, 3, 3, 3, 3, 3, 3, 3, 3,
 	3, 3, 3, 3, 3, 3, 3, 3, 3, 3,
-	3, 3, 3, 69, 3, 3, 3, 105, 97, 3,
-	56, 58, 103, 101, 57, 102, 109, 104, 3, 3,
-	3, 3, 3, 3, 3, 3, 3, 3, 113, 3,
+	3, 3, 3, 87, 3, 3, 3, 123, 115, 3,
+	74, 76, 121, 119, 75, 120, 127, 122, 3, 3,
+	3, 3, 3, 3, 3, 3, 3, 3, 131, 3,
 	3, 3, 3, 3, 3, 3, 3, 3, 3, 3,
 	3, 3, 3, 3, 3, 3, 3, 3, 3, 3,
 	3, 3, 3, 3, 3, 3, 3, 3, 3, 3,
-	3, 59, 3, 60, 96, 3, 3, 3, 3, 3,
+	3, 77, 3, 78, 114, 3, 3, 3, 3, 3,
 	3, 3, 3, 3, 3, 3, 3, 3, 3, 3,
 	3, 3, 3, 3, 3, 3, 3, 3, 3, 3,
-	3, 3, 3, 61, 95, 62, 70,
+	3, 3, 3, 79, 113, 80, 88,
 }
 
-var yyTok2 = [...]int8{
+var yyTok2 = [...]uint8{
 	2, 3, 4, 5, 6, 7, 8, 9, 10, 11,
 	12, 13, 14, 15, 16, 17, 18, 19, 20, 21,
 	22, 23, 24, 25, 26, 27, 28, 29, 30, 31,
 	32, 33, 34, 35, 36, 37, 38, 39, 40, 41,
 	42, 43, 44, 45, 46, 47, 48, 49, 50, 51,
-	52, 53, 54, 55, 63, 64, 65, 66, 67, 68,
-	71, 72, 73, 74, 75, 76, 77, 78, 79, 80,
-	81, 82, 83, 84, 85, 86, 87, 88, 89, 90,
-	91, 92, 93, 94, 98, 99, 100, 106, 107, 108,
-	110, 111, 112,
+	52, 53, 54, 55, 56, 57, 58, 59, 60, 61,
+	62, 63, 64, 65, 66, 67, 68, 69, 70, 71,
+	72, 73, 81, 82, 83, 84, 85, 86, 89, 90,
+	91, 92, 93, 94, 95, 96, 97, 98, 99, 100,
+	101, 102, 103, 104, 105, 106, 107, 108, 109, 110,
+	111, 112, 116, 117, 118, 124, 125, 126, 128, 129,
+	130,
 }
 
 var yyTok3 = [...]int8{
@@ -1008,921 +1191,1416 @@ yydefault:
 	// dummy call; replaced with literal code
 	switch yynt {
 
-	case 1:
+	case 2:
 		yyDollar = yyS[yypt-4 : yypt+1]
-//line partiql.y:128
+//line partiql.y:159
 		{
-			query, err := buildQuery(yyDollar[1].str, yyDollar[2].with, yyDollar[3].selinto, yyDollar[4].unions)
+			stmt, err := buildDelete(yyDollar[3].expr, yyDollar[4].expr)
 			if err != nil {
 				yylex.Error(err.Error())
 			}
 
-			yylex.(*scanner).result = query
-		}
-	case 2:
-		yyDollar = yyS[yypt-11 : yypt+1]
-//line partiql.y:139
-		{
-			distinct, distinctExpr := decodeDistinct(yyDollar[2].values)
-			yyVAL.selinto.sel = &expr.Select{Distinct: distinct, DistinctExpr: distinctExpr, Columns: yyDollar[3].bindings, From: yyDollar[5].from, Where: yyDollar[6].expr, GroupBy: yyDollar[7].bindings, Having: yyDollar[8].expr, OrderBy: yyDollar[9].orders, Limit: yyDollar[10].exprint, Offset: yyDollar[11].exprint}
-			yyVAL.selinto.into = yyDollar[4].expr
+			yylex.(*scanner).delete = stmt
 		}
 	case 3:
-		yyDollar = yyS[yypt-10 : yypt+1]
-//line partiql.y:147
+		yyDollar = yyS[yypt-1 : yypt+1]
+//line partiql.y:168
 		{
-			distinct, distinctExpr := decodeDistinct(yyDollar[2].values)
-			yyVAL.sel = &expr.Select{Distinct: distinct, DistinctExpr: distinctExpr, Columns: yyDollar[3].bindings, From: yyDollar[4].from, Where: yyDollar[5].expr, GroupBy: yyDollar[6].bindings, Having: yyDollar[7].expr, OrderBy: yyDollar[8].orders, Limit: yyDollar[9].exprint, Offset: yyDollar[10].exprint}
+			yylex.(*scanner).ddl = yyDollar[1].ddl
 		}
 	case 4:
-		yyDollar = yyS[yypt-1 : yypt+1]
-//line partiql.y:153
+		yyDollar = yyS[yypt-9 : yypt+1]
+//line partiql.y:174
 		{
-			yyVAL.str = "default"
+			yyVAL.ddl = &expr.CreateTable{Table: yyDollar[4].table, IfNotExists: yyDollar[3].yesno, Columns: yyDollar[5].columns, PartitionBy: yyDollar[6].parts, SortBy: yyDollar[7].values, Retention: yyDollar[8].retain, Inputs: yyDollar[9].inputs}
 		}
 	case 5:
-		yyDollar = yyS[yypt-3 : yypt+1]
-//line partiql.y:154
+		yyDollar = yyS[yypt-4 : yypt+1]
+//line partiql.y:178
 		{
-			yyVAL.str = yyDollar[3].str
+			yyDollar[4].alter.Table = yyDollar[3].table
+			yyVAL.ddl = yyDollar[4].alter
 		}
 	case 6:
-		yyDollar = yyS[yypt-0 : yypt+1]
-//line partiql.y:155
+		yyDollar = yyS[yypt-4 : yypt+1]
+//line partiql.y:183
 		{
-			yyVAL.str = ""
+			yyVAL.ddl = &expr.DropTable{Table: yyDollar[4].table, IfExists: yyDollar[3].yesno}
 		}
 	case 7:
-		yyDollar = yyS[yypt-2 : yypt+1]
-//line partiql.y:158
+		yyDollar = yyS[yypt-3 : yypt+1]
+//line partiql.y:188
 		{
-			yyVAL.expr = yyDollar[2].expr
+			yyVAL.yesno = true
 		}
 	case 8:
 		yyDollar = yyS[yypt-0 : yypt+1]
-//line partiql.y:158
+//line partiql.y:188
 		{
-			yyVAL.expr = nil
+			yyVAL.yesno = false
 		}
 	case 9:
-		yyDollar = yyS[yypt-1 : yypt+1]
-//line partiql.y:161
+		yyDollar = yyS[yypt-2 : yypt+1]
+//line partiql.y:191
 		{
-			yyVAL.with = yyDollar[1].with
+			yyVAL.yesno = true
 		}
 	case 10:
 		yyDollar = yyS[yypt-0 : yypt+1]
-//line partiql.y:161
+//line partiql.y:191
 		{
-			yyVAL.with = nil
+			yyVAL.yesno = false
 		}
 	case 11:
-		yyDollar = yyS[yypt-0 : yypt+1]
-//line partiql.y:164
+		yyDollar = yyS[yypt-1 : yypt+1]
+//line partiql.y:194
 		{
-			yyVAL.unions = []unionItem{}
+			yyVAL.table = &expr.Table{Binding: expr.Bind(expr.Ident(yyDollar[1].str), "")}
 		}
 	case 12:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line partiql.y:165
+//line partiql.y:195
 		{
-			yyVAL.unions = append(yyVAL.unions, unionItem{typ: expr.UnionDistinct, sel: yyDollar[2].sel})
-			yyVAL.unions = append(yyVAL.unions, yyDollar[3].unions...)
+			yyVAL.table = &expr.Table{Binding: expr.Bind(&expr.Dot{Inner: expr.Ident(yyDollar[1].str), Field: yyDollar[3].str}, "")}
 		}
 	case 13:
-		yyDollar = yyS[yypt-4 : yypt+1]
-//line partiql.y:169
+		yyDollar = yyS[yypt-1 : yypt+1]
+//line partiql.y:198
 		{
-			yyVAL.unions = append(yyVAL.unions, unionItem{typ: expr.UnionAll, sel: yyDollar[3].sel})
-			yyVAL.unions = append(yyVAL.unions, yyDollar[4].unions...)
+			yyVAL.expr = expr.Ident(yyDollar[1].str)
 		}
 	case 14:
-		yyDollar = yyS[yypt-6 : yypt+1]
-//line partiql.y:175
+		yyDollar = yyS[yypt-3 : yypt+1]
+//line partiql.y:199
 		{
-			yyVAL.with = []expr.CTE{{Table: yyDollar[2].str, As: yyDollar[5].sel}}
+			yyVAL.expr = &expr.Dot{Inner: yyDollar[1].expr, Field: yyDollar[3].str}
 		}
 	case 15:
-		yyDollar = yyS[yypt-7 : yypt+1]
-//line partiql.y:176
+		yyDollar = yyS[yypt-1 : yypt+1]
+//line partiql.y:202
 		{
-			yyVAL.with = append(yyDollar[1].with, expr.CTE{Table: yyDollar[3].str, As: yyDollar[6].sel})
+			yyVAL.values = []expr.Node{yyDollar[1].expr}
 		}
 	case 16:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line partiql.y:182
+//line partiql.y:203
 		{
-			yyVAL.bind = expr.Bind(yyDollar[1].expr, yyDollar[3].str)
+			yyVAL.values = append(yyDollar[1].values, yyDollar[3].expr)
 		}
 	case 17:
-		yyDollar = yyS[yypt-2 : yypt+1]
-//line partiql.y:183
+		yyDollar = yyS[yypt-3 : yypt+1]
+//line partiql.y:206
 		{
-			yyVAL.bind = expr.Bind(yyDollar[1].expr, yyDollar[2].str)
+			yyVAL.columns = yyDollar[2].columns
 		}
 	case 18:
-		yyDollar = yyS[yypt-1 : yypt+1]
-//line partiql.y:184
+		yyDollar = yyS[yypt-0 : yypt+1]
+//line partiql.y:206
 		{
-			yyVAL.bind = expr.Bind(yyDollar[1].expr, "")
+			yyVAL.columns = nil
 		}
 	case 19:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line partiql.y:185
+//line partiql.y:209
 		{
-			yyVAL.bind = expr.Bind(expr.Star{}, "")
+			yyVAL.columns = []expr.ColumnDef{yyDollar[1].column}
 		}
 	case 20:
-		yyDollar = yyS[yypt-1 : yypt+1]
-//line partiql.y:186
+		yyDollar = yyS[yypt-3 : yypt+1]
+//line partiql.y:210
 		{
-			yyVAL.bind = expr.Bind(yyDollar[1].expr, "")
+			yyVAL.columns = append(yyDollar[1].columns, yyDollar[3].column)
 		}
 	case 21:
-		yyDollar = yyS[yypt-1 : yypt+1]
-//line partiql.y:190
+		yyDollar = yyS[yypt-3 : yypt+1]
+//line partiql.y:214
 		{
-			yyVAL.expr = expr.Ident(yyDollar[1].str)
+			typ, ok := columnType(strings.ToLower(yyDollar[2].str))
+			if !ok {
+				yylex.Error(__yyfmt__.Sprintf("column %s: unknown type %q", expr.ToString(yyDollar[1].expr), yyDollar[2].str))
+			}
+			yyVAL.column = expr.ColumnDef{Path: yyDollar[1].expr, Type: typ, NotNull: yyDollar[3].yesno}
 		}
 	case 22:
-		yyDollar = yyS[yypt-1 : yypt+1]
-//line partiql.y:191
+		yyDollar = yyS[yypt-2 : yypt+1]
+//line partiql.y:223
 		{
-			yyVAL.expr = yyDollar[1].expr
+			yyVAL.yesno = true
 		}
 	case 23:
-		yyDollar = yyS[yypt-1 : yypt+1]
-//line partiql.y:192
+		yyDollar = yyS[yypt-0 : yypt+1]
+//line partiql.y:223
 		{
-			yyVAL.expr = expr.Bool(true)
+			yyVAL.yesno = false
 		}
 	case 24:
-		yyDollar = yyS[yypt-1 : yypt+1]
-//line partiql.y:193
+		yyDollar = yyS[yypt-5 : yypt+1]
+//line partiql.y:226
 		{
-			yyVAL.expr = expr.Bool(false)
+			yyVAL.parts = yyDollar[4].parts
 		}
 	case 25:
-		yyDollar = yyS[yypt-1 : yypt+1]
-//line partiql.y:194
+		yyDollar = yyS[yypt-0 : yypt+1]
+//line partiql.y:226
 		{
-			yyVAL.expr = expr.Null{}
+			yyVAL.parts = nil
 		}
 	case 26:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line partiql.y:195
+//line partiql.y:229
 		{
-			yyVAL.expr = expr.Missing{}
+			yyVAL.parts = []expr.PartitionDef{yyDollar[1].part}
 		}
 	case 27:
-		yyDollar = yyS[yypt-1 : yypt+1]
-//line partiql.y:196
+		yyDollar = yyS[yypt-3 : yypt+1]
+//line partiql.y:230
 		{
-			yyVAL.expr = expr.String(yyDollar[1].str)
+			yyVAL.parts = append(yyDollar[1].parts, yyDollar[3].part)
 		}
 	case 28:
-		yyDollar = yyS[yypt-1 : yypt+1]
-//line partiql.y:197
+		yyDollar = yyS[yypt-3 : yypt+1]
+//line partiql.y:234
 		{
-			yyVAL.expr = yyDollar[1].expr
+			yyVAL.part = expr.PartitionDef{Field: yyDollar[1].str, Type: yyDollar[2].str, Value: yyDollar[3].str}
 		}
 	case 29:
-		yyDollar = yyS[yypt-3 : yypt+1]
-//line partiql.y:198
+		yyDollar = yyS[yypt-1 : yypt+1]
+//line partiql.y:239
 		{
-			yyVAL.expr = expr.Call(expr.MakeStruct, yyDollar[2].values...)
+			yyVAL.str = strings.ToLower(yyDollar[1].str)
 		}
 	case 30:
-		yyDollar = yyS[yypt-3 : yypt+1]
-//line partiql.y:199
+		yyDollar = yyS[yypt-0 : yypt+1]
+//line partiql.y:239
 		{
-			yyVAL.expr = expr.Call(expr.MakeList, yyDollar[2].values...)
+			yyVAL.str = ""
 		}
 	case 31:
-		yyDollar = yyS[yypt-3 : yypt+1]
-//line partiql.y:200
+		yyDollar = yyS[yypt-2 : yypt+1]
+//line partiql.y:242
 		{
-			yyVAL.expr = &expr.Dot{Inner: yyDollar[1].expr, Field: yyDollar[3].str}
+			yyVAL.str = yyDollar[2].str
 		}
 	case 32:
-		yyDollar = yyS[yypt-4 : yypt+1]
-//line partiql.y:201
+		yyDollar = yyS[yypt-0 : yypt+1]
+//line partiql.y:242
 		{
-			yyVAL.expr = &expr.Index{Inner: yyDollar[1].expr, Offset: yyDollar[3].integer}
+			yyVAL.str = ""
 		}
 	case 33:
-		yyDollar = yyS[yypt-4 : yypt+1]
-//line partiql.y:202
+		yyDollar = yyS[yypt-5 : yypt+1]
+//line partiql.y:245
 		{
-			yyVAL.expr = &expr.Dot{Inner: yyDollar[1].expr, Field: yyDollar[3].str}
+			yyVAL.values = yyDollar[4].values
 		}
 	case 34:
-		yyDollar = yyS[yypt-1 : yypt+1]
-//line partiql.y:214
+		yyDollar = yyS[yypt-0 : yypt+1]
+//line partiql.y:245
 		{
-			yyVAL.expr = yyDollar[1].expr
+			yyVAL.values = nil
 		}
 	case 35:
-		yyDollar = yyS[yypt-3 : yypt+1]
-//line partiql.y:215
+		yyDollar = yyS[yypt-2 : yypt+1]
+//line partiql.y:248
 		{
-			yyVAL.expr = yyDollar[2].expr
+			yyVAL.retain = yyDollar[2].retain
 		}
 	case 36:
-		yyDollar = yyS[yypt-1 : yypt+1]
-//line partiql.y:218
+		yyDollar = yyS[yypt-0 : yypt+1]
+//line partiql.y:248
 		{
-			yyVAL.expr = yyDollar[1].sel
+			yyVAL.retain = nil
 		}
 	case 37:
-		yyDollar = yyS[yypt-1 : yypt+1]
-//line partiql.y:219
+		yyDollar = yyS[yypt-4 : yypt+1]
+//line partiql.y:251
 		{
-			yyVAL.expr = yyDollar[1].expr
+			yyVAL.retain = &expr.RetentionDef{Field: yyDollar[2].expr, ValidFor: yyDollar[4].str}
 		}
 	case 38:
-		yyDollar = yyS[yypt-1 : yypt+1]
-//line partiql.y:222
+		yyDollar = yyS[yypt-2 : yypt+1]
+//line partiql.y:254
 		{
-			yyVAL.yesno = true
+			yyVAL.inputs = yyDollar[2].inputs
 		}
 	case 39:
 		yyDollar = yyS[yypt-0 : yypt+1]
-//line partiql.y:222
+//line partiql.y:254
 		{
-			yyVAL.yesno = false
+			yyVAL.inputs = nil
 		}
 	case 40:
-		yyDollar = yyS[yypt-5 : yypt+1]
-//line partiql.y:225
+		yyDollar = yyS[yypt-1 : yypt+1]
+//line partiql.y:257
 		{
-			yyVAL.values = yyDollar[4].values
+			yyVAL.inputs = []expr.InputDef{yyDollar[1].input}
 		}
 	case 41:
-		yyDollar = yyS[yypt-1 : yypt+1]
-//line partiql.y:226
+		yyDollar = yyS[yypt-3 : yypt+1]
+//line partiql.y:258
 		{
-			yyVAL.values = []expr.Node{}
+			yyVAL.inputs = append(yyDollar[1].inputs, yyDollar[3].input)
 		}
 	case 42:
-		yyDollar = yyS[yypt-0 : yypt+1]
-//line partiql.y:227
+		yyDollar = yyS[yypt-3 : yypt+1]
+//line partiql.y:262
 		{
-			yyVAL.values = nil
+			yyVAL.input = expr.InputDef{Pattern: yyDollar[1].str, Format: yyDollar[2].str, Incremental: yyDollar[3].yesno}
 		}
 	case 43:
-		yyDollar = yyS[yypt-1 : yypt+1]
-//line partiql.y:233
+		yyDollar = yyS[yypt-2 : yypt+1]
+//line partiql.y:267
 		{
-			yyVAL.expr = yyDollar[1].expr
+			yyVAL.str = yyDollar[2].str
 		}
 	case 44:
-		yyDollar = yyS[yypt-5 : yypt+1]
-//line partiql.y:237
+		yyDollar = yyS[yypt-2 : yypt+1]
+//line partiql.y:268
 		{
-			agg, err := toAggregate(expr.AggregateOp(yyDollar[1].integer), false, nil, yyDollar[4].expr, yyDollar[5].wind)
-			if err != nil {
-				yylex.Error(err.Error())
-			}
-			yyVAL.expr = agg
+			yyVAL.str = strings.ToLower(yyDollar[2].str)
 		}
 	case 45:
-		yyDollar = yyS[yypt-7 : yypt+1]
-//line partiql.y:245
+		yyDollar = yyS[yypt-0 : yypt+1]
+//line partiql.y:269
 		{
-			agg, err := toAggregate(expr.AggregateOp(yyDollar[1].integer), yyDollar[3].yesno, yyDollar[4].values, yyDollar[6].expr, yyDollar[7].wind)
-			if err != nil {
-				yylex.Error(err.Error())
-			}
-			yyVAL.expr = agg
+			yyVAL.str = ""
 		}
 	case 46:
-		yyDollar = yyS[yypt-5 : yypt+1]
-//line partiql.y:253
+		yyDollar = yyS[yypt-1 : yypt+1]
+//line partiql.y:272
 		{
-			yyVAL.expr = createCase(yyDollar[2].expr, yyDollar[3].limbs, yyDollar[4].expr)
+			yyVAL.yesno = true
 		}
 	case 47:
-		yyDollar = yyS[yypt-4 : yypt+1]
-//line partiql.y:257
+		yyDollar = yyS[yypt-0 : yypt+1]
+//line partiql.y:272
 		{
-			yyVAL.expr = expr.Coalesce(yyDollar[3].values)
+			yyVAL.yesno = false
 		}
 	case 48:
-		yyDollar = yyS[yypt-6 : yypt+1]
-//line partiql.y:261
+		yyDollar = yyS[yypt-1 : yypt+1]
+//line partiql.y:275
 		{
-			yyVAL.expr = expr.NullIf(yyDollar[3].expr, yyDollar[5].expr)
+			yyVAL.alter = yyDollar[1].alter
 		}
 	case 49:
-		yyDollar = yyS[yypt-6 : yypt+1]
-//line partiql.y:265
+		yyDollar = yyS[yypt-3 : yypt+1]
+//line partiql.y:277
 		{
-			nod, ok := buildCast(yyDollar[3].expr, yyDollar[5].str)
-			if !ok {
-				yylex.Error(__yyfmt__.Sprintf("bad CAST type %q", yyDollar[5].str))
+			if err := mergeAlter(yyDollar[1].alter, yyDollar[3].alter); err != nil {
+				yylex.Error(err.Error())
 			}
-			yyVAL.expr = nod
+			yyVAL.alter = yyDollar[1].alter
 		}
 	case 50:
-		yyDollar = yyS[yypt-8 : yypt+1]
-//line partiql.y:273
+		yyDollar = yyS[yypt-6 : yypt+1]
+//line partiql.y:285
 		{
-			part, ok := timePartFor(yyDollar[3].str, "DATE_ADD")
-			if !ok {
-				yylex.Error(__yyfmt__.Sprintf("bad DATE_ADD part %q", yyDollar[3].str))
-			}
-			yyVAL.expr = expr.DateAdd(part, yyDollar[5].expr, yyDollar[7].expr)
+			lst := yyDollar[5].parts
+			yyVAL.alter = &expr.AlterTable{PartitionBy: &lst}
 		}
 	case 51:
-		yyDollar = yyS[yypt-8 : yypt+1]
-//line partiql.y:281
+		yyDollar = yyS[yypt-3 : yypt+1]
+//line partiql.y:286
 		{
-			part, ok := timePartFor(yyDollar[3].str, "DATE_DIFF")
-			if !ok {
-				yylex.Error(__yyfmt__.Sprintf("bad DATE_DIFF part %q", yyDollar[3].str))
-			}
-			yyVAL.expr = expr.DateDiff(part, yyDollar[5].expr, yyDollar[7].expr)
+			yyVAL.alter = &expr.AlterTable{PartitionBy: &[]expr.PartitionDef{}}
 		}
 	case 52:
-		yyDollar = yyS[yypt-9 : yypt+1]
-//line partiql.y:289
+		yyDollar = yyS[yypt-6 : yypt+1]
+//line partiql.y:287
 		{
-			dow, ok := weekday(yyDollar[5].str)
-			if strings.ToUpper(yyDollar[3].str) != "WEEK" || !ok {
-				yylex.Error(__yyfmt__.Sprintf("bad DATE_TRUNC part %q(%q)", yyDollar[3].str, yyDollar[5].str))
-			}
-			yyVAL.expr = expr.DateTruncWeekday(yyDollar[8].expr, dow)
+			lst := yyDollar[5].values
+			yyVAL.alter = &expr.AlterTable{SortBy: &lst}
 		}
 	case 53:
-		yyDollar = yyS[yypt-6 : yypt+1]
-//line partiql.y:297
+		yyDollar = yyS[yypt-3 : yypt+1]
+//line partiql.y:288
 		{
-			part, ok := timePartFor(yyDollar[3].str, "DATE_TRUNC")
-			if !ok {
-				yylex.Error(__yyfmt__.Sprintf("bad DATE_TRUNC part %q", yyDollar[3].str))
-			}
-			yyVAL.expr = expr.DateTrunc(part, yyDollar[5].expr)
+			yyVAL.alter = &expr.AlterTable{SortBy: &[]expr.Node{}}
 		}
 	case 54:
-		yyDollar = yyS[yypt-6 : yypt+1]
-//line partiql.y:305
+		yyDollar = yyS[yypt-3 : yypt+1]
+//line partiql.y:289
 		{
-			part, ok := timePartFor(yyDollar[3].str, "EXTRACT")
-			if !ok {
-				yylex.Error(__yyfmt__.Sprintf("bad EXTRACT part %q", yyDollar[3].str))
-			}
-			yyVAL.expr = expr.DateExtract(part, yyDollar[5].expr)
+			yyVAL.alter = &expr.AlterTable{Retention: yyDollar[3].retain}
 		}
 	case 55:
-		yyDollar = yyS[yypt-3 : yypt+1]
-//line partiql.y:313
+		yyDollar = yyS[yypt-2 : yypt+1]
+//line partiql.y:290
 		{
-			yyVAL.expr = yylex.(*scanner).utcnow()
+			yyVAL.alter = &expr.AlterTable{DropRetention: true}
 		}
 	case 56:
 		yyDollar = yyS[yypt-4 : yypt+1]
-//line partiql.y:317
+//line partiql.y:295
 		{
-			node, err := createTrimInvocation(trimBoth, yyDollar[3].expr, nil)
+			query, err := buildQuery(yyDollar[1].str, yyDollar[2].with, yyDollar[3].selinto, yyDollar[4].unions)
 			if err != nil {
 				yylex.Error(err.Error())
 			}
-			yyVAL.expr = node
+
+			yylex.(*scanner).result = query
 		}
 	case 57:
 		yyDollar = yyS[yypt-6 : yypt+1]
-//line partiql.y:325
+//line partiql.y:304
 		{
-			node, err := createTrimInvocation(trimBoth, yyDollar[3].expr, yyDollar[5].expr)
+			query, err := buildInsert(yyDollar[1].str, yyDollar[4].expr, yyDollar[5].idents, yyDollar[6].sel)
 			if err != nil {
 				yylex.Error(err.Error())
 			}
-			yyVAL.expr = node
+
+			yylex.(*scanner).result = query
 		}
 	case 58:
-		yyDollar = yyS[yypt-6 : yypt+1]
-//line partiql.y:333
+		yyDollar = yyS[yypt-13 : yypt+1]
+//line partiql.y:315
 		{
-			node, err := createTrimInvocation(trimBoth, yyDollar[5].expr, yyDollar[3].expr)
-			if err != nil {
-				yylex.Error(err.Error())
-			}
-			yyVAL.expr = node
+			distinct, distinctExpr := decodeDistinct(yyDollar[2].values)
+			yyVAL.selinto.sel = &expr.Select{Distinct: distinct, DistinctExpr: distinctExpr, Columns: yyDollar[3].bindings, From: yyDollar[6].from, Where: yyDollar[7].expr, GroupBy: yyDollar[8].bindings, Having: yyDollar[9].expr, Qualify: yyDollar[10].expr, OrderBy: yyDollar[11].orders, Limit: yyDollar[12].exprint, Offset: yyDollar[13].exprint}
+			yyVAL.selinto.into = yyDollar[4].expr
+			yyVAL.selinto.partitions = yyDollar[5].idents
 		}
 	case 59:
-		yyDollar = yyS[yypt-7 : yypt+1]
-//line partiql.y:341
+		yyDollar = yyS[yypt-11 : yypt+1]
+//line partiql.y:324
 		{
-			node, err := createTrimInvocation(yyDollar[3].integer, yyDollar[6].expr, yyDollar[4].expr)
-			if err != nil {
-				yylex.Error(err.Error())
+			distinct, distinctExpr := decodeDistinct(yyDollar[2].values)
+			yyVAL.sel = &expr.Select{Distinct: distinct, DistinctExpr: distinctExpr, Columns: yyDollar[3].bindings, From: yyDollar[4].from, Where: yyDollar[5].expr, GroupBy: yyDollar[6].bindings, Having: yyDollar[7].expr, Qualify: yyDollar[8].expr, OrderBy: yyDollar[9].orders, Limit: yyDollar[10].exprint, Offset: yyDollar[11].exprint}
+		}
+	case 60:
+		yyDollar = yyS[yypt-1 : yypt+1]
+//line partiql.y:330
+		{
+			yyVAL.str = "default"
+		}
+	case 61:
+		yyDollar = yyS[yypt-3 : yypt+1]
+//line partiql.y:331
+		{
+			yyVAL.str = yyDollar[3].str
+		}
+	case 62:
+		yyDollar = yyS[yypt-0 : yypt+1]
+//line partiql.y:332
+		{
+			yyVAL.str = ""
+		}
+	case 63:
+		yyDollar = yyS[yypt-2 : yypt+1]
+//line partiql.y:335
+		{
+			yyVAL.expr = yyDollar[2].expr
+		}
+	case 64:
+		yyDollar = yyS[yypt-0 : yypt+1]
+//line partiql.y:335
+		{
+			yyVAL.expr = nil
+		}
+	case 65:
+		yyDollar = yyS[yypt-5 : yypt+1]
+//line partiql.y:338
+		{
+			yyVAL.idents = yyDollar[4].idents
+		}
+	case 66:
+		yyDollar = yyS[yypt-0 : yypt+1]
+//line partiql.y:338
+		{
+			yyVAL.idents = nil
+		}
+	case 67:
+		yyDollar = yyS[yypt-1 : yypt+1]
+//line partiql.y:341
+		{
+			yyVAL.with = yyDollar[1].with
+		}
+	case 68:
+		yyDollar = yyS[yypt-0 : yypt+1]
+//line partiql.y:341
+		{
+			yyVAL.with = nil
+		}
+	case 69:
+		yyDollar = yyS[yypt-0 : yypt+1]
+//line partiql.y:344
+		{
+			yyVAL.unions = []unionItem{}
+		}
+	case 70:
+		yyDollar = yyS[yypt-3 : yypt+1]
+//line partiql.y:345
+		{
+			yyVAL.unions = append(yyVAL.unions, unionItem{typ: expr.UnionDistinct, sel: yyDollar[2].sel})
+			yyVAL.unions = append(yyVAL.unions, yyDollar[3].unions...)
+		}
+	case 71:
+		yyDollar = yyS[yypt-4 : yypt+1]
+//line partiql.y:349
+		{
+			yyVAL.unions = append(yyVAL.unions, unionItem{typ: expr.UnionAll, sel: yyDollar[3].sel})
+			yyVAL.unions = append(yyVAL.unions, yyDollar[4].unions...)
+		}
+	case 72:
+		yyDollar = yyS[yypt-6 : yypt+1]
+//line partiql.y:355
+		{
+			yyVAL.with = []expr.CTE{{Table: yyDollar[2].str, As: yyDollar[5].sel}}
+		}
+	case 73:
+		yyDollar = yyS[yypt-7 : yypt+1]
+//line partiql.y:356
+		{
+			yyVAL.with = append(yyDollar[1].with, expr.CTE{Table: yyDollar[3].str, As: yyDollar[6].sel})
+		}
+	case 74:
+		yyDollar = yyS[yypt-3 : yypt+1]
+//line partiql.y:362
+		{
+			yyVAL.bind = expr.Bind(yyDollar[1].expr, yyDollar[3].str)
+		}
+	case 75:
+		yyDollar = yyS[yypt-2 : yypt+1]
+//line partiql.y:363
+		{
+			yyVAL.bind = expr.Bind(yyDollar[1].expr, yyDollar[2].str)
+		}
+	case 76:
+		yyDollar = yyS[yypt-1 : yypt+1]
+//line partiql.y:364
+		{
+			yyVAL.bind = expr.Bind(yyDollar[1].expr, "")
+		}
+	case 77:
+		yyDollar = yyS[yypt-1 : yypt+1]
+//line partiql.y:365
+		{
+			yyVAL.bind = expr.Bind(expr.Star{}, "")
+		}
+	case 78:
+		yyDollar = yyS[yypt-1 : yypt+1]
+//line partiql.y:366
+		{
+			yyVAL.bind = expr.Bind(yyDollar[1].expr, "")
+		}
+	case 79:
+		yyDollar = yyS[yypt-4 : yypt+1]
+//line partiql.y:368
+		{
+			t, err := valuesTable(yyDollar[1].rows, yyDollar[4].idents)
+			if err != nil {
+				yylex.Error(err.Error())
+			}
+			yyVAL.bind = expr.Bind(t, yyDollar[3].str)
+		}
+	case 80:
+		yyDollar = yyS[yypt-3 : yypt+1]
+//line partiql.y:376
+		{
+			t, err := valuesTable(yyDollar[1].rows, yyDollar[3].idents)
+			if err != nil {
+				yylex.Error(err.Error())
+			}
+			yyVAL.bind = expr.Bind(t, yyDollar[2].str)
+		}
+	case 81:
+		yyDollar = yyS[yypt-1 : yypt+1]
+//line partiql.y:384
+		{
+			t, err := valuesTable(yyDollar[1].rows, nil)
+			if err != nil {
+				yylex.Error(err.Error())
+			}
+			yyVAL.bind = expr.Bind(t, "")
+		}
+	case 82:
+		yyDollar = yyS[yypt-8 : yypt+1]
+//line partiql.y:392
+		{
+			u, err := unnestValues(yyDollar[3].values, yyDollar[7].idents)
+			if err != nil {
+				yylex.Error(err.Error())
+			}
+			yyVAL.bind = expr.Bind(u, "")
+		}
+	case 83:
+		yyDollar = yyS[yypt-4 : yypt+1]
+//line partiql.y:402
+		{
+			yyVAL.rows = yyDollar[3].rows
+		}
+	case 84:
+		yyDollar = yyS[yypt-3 : yypt+1]
+//line partiql.y:405
+		{
+			yyVAL.rows = [][]expr.Node{yyDollar[2].values}
+		}
+	case 85:
+		yyDollar = yyS[yypt-5 : yypt+1]
+//line partiql.y:406
+		{
+			yyVAL.rows = append(yyDollar[1].rows, yyDollar[4].values)
+		}
+	case 86:
+		yyDollar = yyS[yypt-3 : yypt+1]
+//line partiql.y:409
+		{
+			yyVAL.idents = yyDollar[2].idents
+		}
+	case 87:
+		yyDollar = yyS[yypt-0 : yypt+1]
+//line partiql.y:410
+		{
+			yyVAL.idents = nil
+		}
+	case 88:
+		yyDollar = yyS[yypt-1 : yypt+1]
+//line partiql.y:413
+		{
+			yyVAL.idents = []string{yyDollar[1].str}
+		}
+	case 89:
+		yyDollar = yyS[yypt-3 : yypt+1]
+//line partiql.y:414
+		{
+			yyVAL.idents = append(yyDollar[1].idents, yyDollar[3].str)
+		}
+	case 90:
+		yyDollar = yyS[yypt-1 : yypt+1]
+//line partiql.y:418
+		{
+			yyVAL.expr = expr.Ident(yyDollar[1].str)
+		}
+	case 91:
+		yyDollar = yyS[yypt-1 : yypt+1]
+//line partiql.y:419
+		{
+			yyVAL.expr = yyDollar[1].expr
+		}
+	case 92:
+		yyDollar = yyS[yypt-1 : yypt+1]
+//line partiql.y:420
+		{
+			yyVAL.expr = expr.Bool(true)
+		}
+	case 93:
+		yyDollar = yyS[yypt-1 : yypt+1]
+//line partiql.y:421
+		{
+			yyVAL.expr = expr.Bool(false)
+		}
+	case 94:
+		yyDollar = yyS[yypt-1 : yypt+1]
+//line partiql.y:422
+		{
+			yyVAL.expr = expr.Null{}
+		}
+	case 95:
+		yyDollar = yyS[yypt-1 : yypt+1]
+//line partiql.y:423
+		{
+			yyVAL.expr = expr.Missing{}
+		}
+	case 96:
+		yyDollar = yyS[yypt-1 : yypt+1]
+//line partiql.y:424
+		{
+			yyVAL.expr = expr.String(yyDollar[1].str)
+		}
+	case 97:
+		yyDollar = yyS[yypt-1 : yypt+1]
+//line partiql.y:425
+		{
+			yyVAL.expr = yyDollar[1].expr
+		}
+	case 98:
+		yyDollar = yyS[yypt-3 : yypt+1]
+//line partiql.y:426
+		{
+			yyVAL.expr = expr.Call(expr.MakeStruct, yyDollar[2].values...)
+		}
+	case 99:
+		yyDollar = yyS[yypt-3 : yypt+1]
+//line partiql.y:427
+		{
+			yyVAL.expr = expr.Call(expr.MakeList, yyDollar[2].values...)
+		}
+	case 100:
+		yyDollar = yyS[yypt-3 : yypt+1]
+//line partiql.y:428
+		{
+			yyVAL.expr = &expr.Dot{Inner: yyDollar[1].expr, Field: yyDollar[3].str}
+		}
+	case 101:
+		yyDollar = yyS[yypt-4 : yypt+1]
+//line partiql.y:429
+		{
+			yyVAL.expr = toIndex(yyDollar[1].expr, yyDollar[3].expr, yylex)
+		}
+	case 102:
+		yyDollar = yyS[yypt-4 : yypt+1]
+//line partiql.y:430
+		{
+			yyVAL.expr = &expr.Wildcard{Inner: yyDollar[1].expr}
+		}
+	case 103:
+		yyDollar = yyS[yypt-3 : yypt+1]
+//line partiql.y:431
+		{
+			yyVAL.expr = &expr.Wildcard{Inner: yyDollar[1].expr, Struct: true}
+		}
+	case 104:
+		yyDollar = yyS[yypt-1 : yypt+1]
+//line partiql.y:443
+		{
+			yyVAL.expr = yyDollar[1].expr
+		}
+	case 105:
+		yyDollar = yyS[yypt-3 : yypt+1]
+//line partiql.y:444
+		{
+			yyVAL.expr = yyDollar[2].expr
+		}
+	case 106:
+		yyDollar = yyS[yypt-1 : yypt+1]
+//line partiql.y:447
+		{
+			yyVAL.expr = yyDollar[1].sel
+		}
+	case 107:
+		yyDollar = yyS[yypt-1 : yypt+1]
+//line partiql.y:448
+		{
+			yyVAL.expr = yyDollar[1].expr
+		}
+	case 108:
+		yyDollar = yyS[yypt-1 : yypt+1]
+//line partiql.y:451
+		{
+			yyVAL.yesno = true
+		}
+	case 109:
+		yyDollar = yyS[yypt-0 : yypt+1]
+//line partiql.y:451
+		{
+			yyVAL.yesno = false
+		}
+	case 110:
+		yyDollar = yyS[yypt-5 : yypt+1]
+//line partiql.y:454
+		{
+			yyVAL.values = yyDollar[4].values
+		}
+	case 111:
+		yyDollar = yyS[yypt-1 : yypt+1]
+//line partiql.y:455
+		{
+			yyVAL.values = []expr.Node{}
+		}
+	case 112:
+		yyDollar = yyS[yypt-0 : yypt+1]
+//line partiql.y:456
+		{
+			yyVAL.values = nil
+		}
+	case 113:
+		yyDollar = yyS[yypt-1 : yypt+1]
+//line partiql.y:462
+		{
+			yyVAL.expr = yyDollar[1].expr
+		}
+	case 114:
+		yyDollar = yyS[yypt-5 : yypt+1]
+//line partiql.y:466
+		{
+			agg, err := toAggregate(expr.AggregateOp(yyDollar[1].integer), false, nil, yyDollar[4].expr, yyDollar[5].wind)
+			if err != nil {
+				yylex.Error(err.Error())
+			}
+			yyVAL.expr = agg
+		}
+	case 115:
+		yyDollar = yyS[yypt-7 : yypt+1]
+//line partiql.y:474
+		{
+			agg, err := toAggregate(expr.AggregateOp(yyDollar[1].integer), yyDollar[3].yesno, yyDollar[4].values, yyDollar[6].expr, yyDollar[7].wind)
+			if err != nil {
+				yylex.Error(err.Error())
+			}
+			yyVAL.expr = agg
+		}
+	case 116:
+		yyDollar = yyS[yypt-6 : yypt+1]
+//line partiql.y:482
+		{
+			agg, err := toConditionalAggregate(expr.AggregateOp(yyDollar[1].integer), yyDollar[3].values, yyDollar[5].expr, yyDollar[6].wind)
+			if err != nil {
+				yylex.Error(err.Error())
+			}
+			yyVAL.expr = agg
+		}
+	case 117:
+		yyDollar = yyS[yypt-5 : yypt+1]
+//line partiql.y:490
+		{
+			yyVAL.expr = createCase(yyDollar[2].expr, yyDollar[3].limbs, yyDollar[4].expr)
+		}
+	case 118:
+		yyDollar = yyS[yypt-4 : yypt+1]
+//line partiql.y:494
+		{
+			yyVAL.expr = expr.Coalesce(yyDollar[3].values)
+		}
+	case 119:
+		yyDollar = yyS[yypt-6 : yypt+1]
+//line partiql.y:498
+		{
+			yyVAL.expr = expr.NullIf(yyDollar[3].expr, yyDollar[5].expr)
+		}
+	case 120:
+		yyDollar = yyS[yypt-6 : yypt+1]
+//line partiql.y:502
+		{
+			nod, ok := buildCast(yyDollar[3].expr, yyDollar[5].str)
+			if !ok {
+				yylex.Error(__yyfmt__.Sprintf("bad CAST type %q", yyDollar[5].str))
+			}
+			yyVAL.expr = nod
+		}
+	case 121:
+		yyDollar = yyS[yypt-8 : yypt+1]
+//line partiql.y:510
+		{
+			part, ok := timePartFor(yyDollar[3].str, "DATE_ADD")
+			if !ok {
+				yylex.Error(__yyfmt__.Sprintf("bad DATE_ADD part %q", yyDollar[3].str))
+			}
+			yyVAL.expr = expr.DateAdd(part, yyDollar[5].expr, yyDollar[7].expr)
+		}
+	case 122:
+		yyDollar = yyS[yypt-8 : yypt+1]
+//line partiql.y:518
+		{
+			part, ok := timePartFor(yyDollar[3].str, "DATE_DIFF")
+			if !ok {
+				yylex.Error(__yyfmt__.Sprintf("bad DATE_DIFF part %q", yyDollar[3].str))
+			}
+			yyVAL.expr = expr.DateDiff(part, yyDollar[5].expr, yyDollar[7].expr)
+		}
+	case 123:
+		yyDollar = yyS[yypt-8 : yypt+1]
+//line partiql.y:526
+		{
+			part, err := yylex.(*scanner).stringPart(yyDollar[3].str, "DATE_ADD")
+			if err != nil {
+				yylex.Error(err.Error())
+			}
+			yyVAL.expr = expr.DateAdd(part, yyDollar[5].expr, yyDollar[7].expr)
+		}
+	case 124:
+		yyDollar = yyS[yypt-8 : yypt+1]
+//line partiql.y:534
+		{
+			part, err := yylex.(*scanner).stringPart(yyDollar[3].str, "DATE_DIFF")
+			if err != nil {
+				yylex.Error(err.Error())
+			}
+			yyVAL.expr = expr.DateDiff(part, yyDollar[5].expr, yyDollar[7].expr)
+		}
+	case 125:
+		yyDollar = yyS[yypt-6 : yypt+1]
+//line partiql.y:542
+		{
+			part, err := yylex.(*scanner).stringPart(yyDollar[3].str, "DATE_TRUNC")
+			if err != nil {
+				yylex.Error(err.Error())
+			}
+			yyVAL.expr = expr.DateTrunc(part, yyDollar[5].expr)
+		}
+	case 126:
+		yyDollar = yyS[yypt-9 : yypt+1]
+//line partiql.y:550
+		{
+			dow, ok := weekday(yyDollar[5].str)
+			if strings.ToUpper(yyDollar[3].str) != "WEEK" || !ok {
+				yylex.Error(__yyfmt__.Sprintf("bad DATE_TRUNC part %q(%q)", yyDollar[3].str, yyDollar[5].str))
+			}
+			yyVAL.expr = expr.DateTruncWeekday(yyDollar[8].expr, dow)
+		}
+	case 127:
+		yyDollar = yyS[yypt-6 : yypt+1]
+//line partiql.y:558
+		{
+			part, ok := timePartFor(yyDollar[3].str, "DATE_TRUNC")
+			if !ok {
+				yylex.Error(__yyfmt__.Sprintf("bad DATE_TRUNC part %q", yyDollar[3].str))
+			}
+			yyVAL.expr = expr.DateTrunc(part, yyDollar[5].expr)
+		}
+	case 128:
+		yyDollar = yyS[yypt-6 : yypt+1]
+//line partiql.y:566
+		{
+			if isEpochPart(yyDollar[3].str) {
+				yyVAL.expr = expr.Call(expr.ToUnixEpoch, yyDollar[5].expr)
+			} else {
+				part, ok := timePartFor(yyDollar[3].str, "EXTRACT")
+				if !ok {
+					yylex.Error(__yyfmt__.Sprintf("bad EXTRACT part %q", yyDollar[3].str))
+				}
+				yyVAL.expr = expr.DateExtract(part, yyDollar[5].expr)
+			}
+		}
+	case 129:
+		yyDollar = yyS[yypt-3 : yypt+1]
+//line partiql.y:578
+		{
+			yyVAL.expr = yylex.(*scanner).utcnow()
+		}
+	case 130:
+		yyDollar = yyS[yypt-4 : yypt+1]
+//line partiql.y:582
+		{
+			node, err := createTrimInvocation(trimBoth, yyDollar[3].expr, nil)
+			if err != nil {
+				yylex.Error(err.Error())
+			}
+			yyVAL.expr = node
+		}
+	case 131:
+		yyDollar = yyS[yypt-6 : yypt+1]
+//line partiql.y:590
+		{
+			node, err := createTrimInvocation(trimBoth, yyDollar[3].expr, yyDollar[5].expr)
+			if err != nil {
+				yylex.Error(err.Error())
 			}
 			yyVAL.expr = node
 		}
-	case 60:
+	case 132:
+		yyDollar = yyS[yypt-6 : yypt+1]
+//line partiql.y:598
+		{
+			node, err := createTrimInvocation(trimBoth, yyDollar[5].expr, yyDollar[3].expr)
+			if err != nil {
+				yylex.Error(err.Error())
+			}
+			yyVAL.expr = node
+		}
+	case 133:
+		yyDollar = yyS[yypt-7 : yypt+1]
+//line partiql.y:606
+		{
+			node, err := createTrimInvocation(yyDollar[3].integer, yyDollar[6].expr, yyDollar[4].expr)
+			if err != nil {
+				yylex.Error(err.Error())
+			}
+			yyVAL.expr = node
+		}
+	case 134:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line partiql.y:349
+//line partiql.y:614
 		{
-			op := expr.CallByName(yyDollar[1].str)
-			if op.Private() {
-				yylex.Error(__yyfmt__.Sprintf("cannot use reserved builtin %q", yyDollar[1].str))
+			node, err := yylex.(*scanner).call(yyDollar[1].str, nil)
+			if err != nil {
+				yylex.Error(err.Error())
 			}
-			yyVAL.expr = op
+			yyVAL.expr = node
 		}
-	case 61:
+	case 135:
 		yyDollar = yyS[yypt-4 : yypt+1]
-//line partiql.y:357
+//line partiql.y:622
 		{
-			op := expr.CallByName(yyDollar[1].str, yyDollar[3].values...)
-			if op.Private() {
-				yylex.Error(__yyfmt__.Sprintf("cannot use reserved builtin %q", yyDollar[1].str))
+			node, err := yylex.(*scanner).call(yyDollar[1].str, yyDollar[3].values)
+			if err != nil {
+				yylex.Error(err.Error())
 			}
-			yyVAL.expr = op
+			yyVAL.expr = node
 		}
-	case 62:
+	case 136:
 		yyDollar = yyS[yypt-5 : yypt+1]
-//line partiql.y:365
+//line partiql.y:630
 		{
 			yyVAL.expr = expr.Call(expr.InSubquery, yyDollar[1].expr, yyDollar[4].sel)
 		}
-	case 63:
+	case 137:
 		yyDollar = yyS[yypt-5 : yypt+1]
-//line partiql.y:369
+//line partiql.y:634
 		{
 			yyVAL.expr = expr.In(yyDollar[1].expr, yyDollar[4].values...)
 		}
-	case 64:
+	case 138:
 		yyDollar = yyS[yypt-4 : yypt+1]
-//line partiql.y:373
+//line partiql.y:638
 		{
 			yyVAL.expr = exists(yyDollar[3].sel)
 		}
-	case 65:
+	case 139:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line partiql.y:377
+//line partiql.y:642
 		{
 			yyVAL.expr = expr.BitOr(yyDollar[1].expr, yyDollar[3].expr)
 		}
-	case 66:
+	case 140:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line partiql.y:381
+//line partiql.y:646
 		{
 			yyVAL.expr = expr.BitXor(yyDollar[1].expr, yyDollar[3].expr)
 		}
-	case 67:
+	case 141:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line partiql.y:385
+//line partiql.y:650
 		{
 			yyVAL.expr = expr.BitAnd(yyDollar[1].expr, yyDollar[3].expr)
 		}
-	case 68:
+	case 142:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line partiql.y:389
+//line partiql.y:654
 		{
 			yyVAL.expr = expr.ShiftLeftLogical(yyDollar[1].expr, yyDollar[3].expr)
 		}
-	case 69:
+	case 143:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line partiql.y:393
+//line partiql.y:658
 		{
 			yyVAL.expr = expr.ShiftRightLogical(yyDollar[1].expr, yyDollar[3].expr)
 		}
-	case 70:
+	case 144:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line partiql.y:397
+//line partiql.y:662
 		{
 			yyVAL.expr = expr.ShiftRightArithmetic(yyDollar[1].expr, yyDollar[3].expr)
 		}
-	case 71:
+	case 145:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line partiql.y:401
+//line partiql.y:666
 		{
 			yyVAL.expr = expr.Add(yyDollar[1].expr, yyDollar[3].expr)
 		}
-	case 72:
+	case 146:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line partiql.y:405
+//line partiql.y:670
 		{
 			yyVAL.expr = expr.Sub(yyDollar[1].expr, yyDollar[3].expr)
 		}
-	case 73:
+	case 147:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line partiql.y:409
+//line partiql.y:674
 		{
 			yyVAL.expr = expr.Mul(yyDollar[1].expr, yyDollar[3].expr)
 		}
-	case 74:
+	case 148:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line partiql.y:413
+//line partiql.y:678
 		{
 			yyVAL.expr = expr.Div(yyDollar[1].expr, yyDollar[3].expr)
 		}
-	case 75:
+	case 149:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line partiql.y:417
+//line partiql.y:682
 		{
 			yyVAL.expr = expr.Mod(yyDollar[1].expr, yyDollar[3].expr)
 		}
-	case 76:
+	case 150:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line partiql.y:421
+//line partiql.y:686
 		{
 			yyVAL.expr = expr.Call(expr.Concat, yyDollar[1].expr, yyDollar[3].expr)
 		}
-	case 77:
+	case 151:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line partiql.y:425
+//line partiql.y:690
 		{
 			yyVAL.expr = expr.Append(yyDollar[1].expr, yyDollar[3].expr)
 		}
-	case 78:
+	case 152:
 		yyDollar = yyS[yypt-2 : yypt+1]
-//line partiql.y:429
+//line partiql.y:694
 		{
 			yyVAL.expr = expr.Neg(yyDollar[2].expr)
 		}
-	case 79:
+	case 153:
 		yyDollar = yyS[yypt-5 : yypt+1]
-//line partiql.y:433
+//line partiql.y:698
 		{
 			yyVAL.expr = &expr.StringMatch{Op: expr.Ilike, Expr: yyDollar[1].expr, Pattern: yyDollar[3].str, Escape: yyDollar[5].str}
 		}
-	case 80:
+	case 154:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line partiql.y:437
+//line partiql.y:702
 		{
 			yyVAL.expr = &expr.StringMatch{Op: expr.Ilike, Expr: yyDollar[1].expr, Pattern: yyDollar[3].str}
 		}
-	case 81:
+	case 155:
 		yyDollar = yyS[yypt-5 : yypt+1]
-//line partiql.y:441
+//line partiql.y:706
 		{
 			yyVAL.expr = &expr.StringMatch{Op: expr.Like, Expr: yyDollar[1].expr, Pattern: yyDollar[3].str, Escape: yyDollar[5].str}
 		}
-	case 82:
+	case 156:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line partiql.y:445
+//line partiql.y:710
 		{
 			yyVAL.expr = &expr.StringMatch{Op: expr.Like, Expr: yyDollar[1].expr, Pattern: yyDollar[3].str}
 		}
-	case 83:
+	case 157:
 		yyDollar = yyS[yypt-4 : yypt+1]
-//line partiql.y:449
+//line partiql.y:714
 		{
 			yyVAL.expr = &expr.StringMatch{Op: expr.SimilarTo, Expr: yyDollar[1].expr, Pattern: yyDollar[4].str}
 		}
-	case 84:
+	case 158:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line partiql.y:453
+//line partiql.y:718
 		{
 			yyVAL.expr = &expr.StringMatch{Op: expr.RegexpMatch, Expr: yyDollar[1].expr, Pattern: yyDollar[3].str}
 		}
-	case 85:
+	case 159:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line partiql.y:457
+//line partiql.y:722
 		{
 			yyVAL.expr = &expr.StringMatch{Op: expr.RegexpMatchCi, Expr: yyDollar[1].expr, Pattern: yyDollar[3].str}
 		}
-	case 86:
+	case 160:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line partiql.y:461
+//line partiql.y:726
 		{
 			yyVAL.expr = expr.Compare(expr.Equals, yyDollar[1].expr, yyDollar[3].expr)
 		}
-	case 87:
+	case 161:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line partiql.y:465
+//line partiql.y:730
 		{
 			yyVAL.expr = expr.Compare(expr.NotEquals, yyDollar[1].expr, yyDollar[3].expr)
 		}
-	case 88:
+	case 162:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line partiql.y:469
+//line partiql.y:734
 		{
 			yyVAL.expr = expr.Compare(expr.Less, yyDollar[1].expr, yyDollar[3].expr)
 		}
-	case 89:
+	case 163:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line partiql.y:473
+//line partiql.y:738
 		{
 			yyVAL.expr = expr.Compare(expr.LessEquals, yyDollar[1].expr, yyDollar[3].expr)
 		}
-	case 90:
+	case 164:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line partiql.y:477
+//line partiql.y:742
 		{
 			yyVAL.expr = expr.Compare(expr.Greater, yyDollar[1].expr, yyDollar[3].expr)
 		}
-	case 91:
+	case 165:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line partiql.y:481
+//line partiql.y:746
 		{
 			yyVAL.expr = expr.Compare(expr.GreaterEquals, yyDollar[1].expr, yyDollar[3].expr)
 		}
-	case 92:
+	case 166:
 		yyDollar = yyS[yypt-5 : yypt+1]
-//line partiql.y:485
+//line partiql.y:750
 		{
 			yyVAL.expr = expr.Between(yyDollar[1].expr, yyDollar[3].expr, yyDollar[5].expr)
 		}
-	case 93:
+	case 167:
 		yyDollar = yyS[yypt-4 : yypt+1]
-//line partiql.y:489
+//line partiql.y:754
 		{
 			yyVAL.expr = &expr.Not{Expr: &expr.StringMatch{Op: expr.Like, Expr: yyDollar[1].expr, Pattern: yyDollar[4].str}}
 		}
-	case 94:
+	case 168:
 		yyDollar = yyS[yypt-6 : yypt+1]
-//line partiql.y:493
+//line partiql.y:758
 		{
 			yyVAL.expr = &expr.Not{Expr: &expr.StringMatch{Op: expr.Like, Expr: yyDollar[1].expr, Pattern: yyDollar[4].str, Escape: yyDollar[6].str}}
 		}
-	case 95:
+	case 169:
 		yyDollar = yyS[yypt-4 : yypt+1]
-//line partiql.y:497
+//line partiql.y:762
 		{
 			yyVAL.expr = &expr.Not{Expr: &expr.StringMatch{Op: expr.Like, Expr: yyDollar[1].expr, Pattern: yyDollar[4].str}}
 		}
-	case 96:
+	case 170:
 		yyDollar = yyS[yypt-6 : yypt+1]
-//line partiql.y:501
+//line partiql.y:766
 		{
 			yyVAL.expr = &expr.Not{Expr: &expr.StringMatch{Op: expr.Ilike, Expr: yyDollar[1].expr, Pattern: yyDollar[4].str, Escape: yyDollar[6].str}}
 		}
-	case 97:
+	case 171:
 		yyDollar = yyS[yypt-5 : yypt+1]
-//line partiql.y:505
+//line partiql.y:770
 		{
 			yyVAL.expr = &expr.Not{Expr: &expr.StringMatch{Op: expr.SimilarTo, Expr: yyDollar[1].expr, Pattern: yyDollar[5].str}}
 		}
-	case 98:
+	case 172:
 		yyDollar = yyS[yypt-4 : yypt+1]
-//line partiql.y:509
+//line partiql.y:774
 		{
 			yyVAL.expr = &expr.Not{Expr: &expr.StringMatch{Op: expr.RegexpMatch, Expr: yyDollar[1].expr, Pattern: yyDollar[4].str}}
 		}
-	case 99:
+	case 173:
 		yyDollar = yyS[yypt-4 : yypt+1]
-//line partiql.y:513
+//line partiql.y:778
 		{
 			yyVAL.expr = &expr.Not{Expr: &expr.StringMatch{Op: expr.RegexpMatchCi, Expr: yyDollar[1].expr, Pattern: yyDollar[4].str}}
 		}
-	case 100:
+	case 174:
 		yyDollar = yyS[yypt-2 : yypt+1]
-//line partiql.y:517
+//line partiql.y:782
 		{
 			yyVAL.expr = &expr.Not{Expr: yyDollar[2].expr}
 		}
-	case 101:
+	case 175:
 		yyDollar = yyS[yypt-2 : yypt+1]
-//line partiql.y:521
+//line partiql.y:786
 		{
 			yyVAL.expr = expr.BitNot(yyDollar[2].expr)
 		}
-	case 102:
+	case 176:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line partiql.y:525
+//line partiql.y:790
 		{
 			yyVAL.expr = expr.And(yyDollar[1].expr, yyDollar[3].expr)
 		}
-	case 103:
+	case 177:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line partiql.y:529
+//line partiql.y:794
 		{
 			yyVAL.expr = expr.Or(yyDollar[1].expr, yyDollar[3].expr)
 		}
-	case 104:
+	case 178:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line partiql.y:533
+//line partiql.y:798
 		{
 			yyVAL.expr = &expr.IsKey{Key: expr.IsNull, Expr: yyDollar[1].expr}
 		}
-	case 105:
+	case 179:
 		yyDollar = yyS[yypt-4 : yypt+1]
-//line partiql.y:537
+//line partiql.y:802
 		{
 			yyVAL.expr = &expr.IsKey{Key: expr.IsNotNull, Expr: yyDollar[1].expr}
 		}
-	case 106:
+	case 180:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line partiql.y:541
+//line partiql.y:806
 		{
 			yyVAL.expr = &expr.IsKey{Key: expr.IsMissing, Expr: yyDollar[1].expr}
 		}
-	case 107:
+	case 181:
 		yyDollar = yyS[yypt-4 : yypt+1]
-//line partiql.y:545
+//line partiql.y:810
 		{
 			yyVAL.expr = &expr.IsKey{Key: expr.IsNotMissing, Expr: yyDollar[1].expr}
 		}
-	case 108:
+	case 182:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line partiql.y:549
+//line partiql.y:814
 		{
 			yyVAL.expr = &expr.IsKey{Key: expr.IsTrue, Expr: yyDollar[1].expr}
 		}
-	case 109:
+	case 183:
 		yyDollar = yyS[yypt-4 : yypt+1]
-//line partiql.y:553
+//line partiql.y:818
 		{
 			yyVAL.expr = &expr.IsKey{Key: expr.IsNotTrue, Expr: yyDollar[1].expr}
 		}
-	case 110:
+	case 184:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line partiql.y:557
+//line partiql.y:822
 		{
 			yyVAL.expr = &expr.IsKey{Key: expr.IsFalse, Expr: yyDollar[1].expr}
 		}
-	case 111:
+	case 185:
 		yyDollar = yyS[yypt-4 : yypt+1]
-//line partiql.y:561
+//line partiql.y:826
 		{
 			yyVAL.expr = &expr.IsKey{Key: expr.IsNotFalse, Expr: yyDollar[1].expr}
 		}
-	case 112:
+	case 186:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line partiql.y:567
+//line partiql.y:832
 		{
 			yyVAL.bindings = []expr.Binding{yyDollar[1].bind}
 		}
-	case 113:
+	case 187:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line partiql.y:568
+//line partiql.y:833
 		{
 			yyVAL.bindings = append(yyDollar[1].bindings, yyDollar[3].bind)
 		}
-	case 114:
+	case 188:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line partiql.y:572
+//line partiql.y:837
 		{
 			yyVAL.values = []expr.Node{yyDollar[1].expr}
 		}
-	case 115:
+	case 189:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line partiql.y:573
+//line partiql.y:838
 		{
 			yyVAL.values = append(yyDollar[1].values, yyDollar[3].expr)
 		}
-	case 116:
+	case 190:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line partiql.y:577
+//line partiql.y:842
 		{
 			yyVAL.values = []expr.Node{yyDollar[1].expr}
 		}
-	case 117:
+	case 191:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line partiql.y:578
+//line partiql.y:843
 		{
 			yyVAL.values = []expr.Node{expr.Star{}}
 		}
-	case 118:
+	case 192:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line partiql.y:579
+//line partiql.y:844
 		{
 			yyVAL.values = append(yyDollar[1].values, yyDollar[3].expr)
 		}
-	case 119:
+	case 193:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line partiql.y:583
+//line partiql.y:848
 		{
 			yyVAL.values = []expr.Node{yyDollar[1].expr}
 		}
-	case 120:
+	case 194:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line partiql.y:584
+//line partiql.y:849
 		{
 			yyVAL.values = append(yyDollar[1].values, yyDollar[3].expr)
 		}
-	case 121:
+	case 195:
 		yyDollar = yyS[yypt-0 : yypt+1]
-//line partiql.y:585
+//line partiql.y:850
 		{
 			yyVAL.values = nil
 		}
-	case 122:
+	case 196:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line partiql.y:589
+//line partiql.y:854
 		{
 			yyVAL.values = yyDollar[1].values
 		}
-	case 123:
+	case 197:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line partiql.y:590
+//line partiql.y:855
 		{
 			yyVAL.values = append(yyDollar[1].values, yyDollar[3].values...)
 		}
-	case 124:
+	case 198:
 		yyDollar = yyS[yypt-0 : yypt+1]
-//line partiql.y:591
+//line partiql.y:856
 		{
 			yyVAL.values = nil
 		}
-	case 125:
+	case 199:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line partiql.y:595
+//line partiql.y:860
 		{
 			yyVAL.values = []expr.Node{expr.String(yyDollar[1].str), yyDollar[3].expr}
 		}
-	case 126:
+	case 200:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line partiql.y:599
+//line partiql.y:864
 		{
 			yyVAL.values = yyDollar[3].values
 		}
-	case 127:
+	case 201:
 		yyDollar = yyS[yypt-0 : yypt+1]
-//line partiql.y:602
+//line partiql.y:867
 		{
 			yyVAL.values = nil
 		}
-	case 128:
+	case 202:
 		yyDollar = yyS[yypt-5 : yypt+1]
-//line partiql.y:606
+//line partiql.y:871
 		{
 			yyVAL.wind = &expr.Window{PartitionBy: yyDollar[3].values, OrderBy: yyDollar[4].orders}
 		}
-	case 129:
+	case 203:
 		yyDollar = yyS[yypt-0 : yypt+1]
-//line partiql.y:609
+//line partiql.y:874
 		{
 			yyVAL.wind = nil
 		}
-	case 130:
+	case 204:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line partiql.y:612
+//line partiql.y:877
 		{
 			yyVAL.jk = expr.InnerJoin
 		}
-	case 131:
+	case 205:
 		yyDollar = yyS[yypt-2 : yypt+1]
-//line partiql.y:613
+//line partiql.y:878
 		{
 			yyVAL.jk = expr.InnerJoin
 		}
-	case 132:
+	case 206:
 		yyDollar = yyS[yypt-2 : yypt+1]
-//line partiql.y:614
+//line partiql.y:879
 		{
 			yyVAL.jk = expr.LeftJoin
 		}
-	case 133:
+	case 207:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line partiql.y:615
+//line partiql.y:880
 		{
 			yyVAL.jk = expr.LeftJoin
 		}
-	case 134:
+	case 208:
 		yyDollar = yyS[yypt-2 : yypt+1]
-//line partiql.y:616
+//line partiql.y:881
 		{
 			yyVAL.jk = expr.RightJoin
 		}
-	case 135:
+	case 209:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line partiql.y:617
+//line partiql.y:882
 		{
 			yyVAL.jk = expr.RightJoin
 		}
-	case 136:
+	case 210:
 		yyDollar = yyS[yypt-2 : yypt+1]
-//line partiql.y:618
+//line partiql.y:883
 		{
 			yyVAL.jk = expr.FullJoin
 		}
-	case 139:
+	case 213:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line partiql.y:623
+//line partiql.y:888
 		{
 			yyVAL.from = yyDollar[1].from
 		}
-	case 140:
+	case 214:
 		yyDollar = yyS[yypt-0 : yypt+1]
-//line partiql.y:624
+//line partiql.y:889
 		{
 			yyVAL.from = nil
 		}
-	case 141:
+	case 215:
 		yyDollar = yyS[yypt-2 : yypt+1]
-//line partiql.y:627
+//line partiql.y:892
 		{
 			yyVAL.from = &expr.Table{Binding: yyDollar[2].bind}
 		}
-	case 142:
+	case 216:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line partiql.y:628
+//line partiql.y:893
 		{
 			yyVAL.from = &expr.Join{Kind: expr.CrossJoin, Left: yyDollar[1].from, Right: yyDollar[3].bind}
 		}
-	case 143:
+	case 217:
 		yyDollar = yyS[yypt-5 : yypt+1]
-//line partiql.y:630
+//line partiql.y:895
 		{
 			yyVAL.from = &expr.Join{Kind: yyDollar[2].jk, Left: yyDollar[1].from, Right: yyDollar[3].bind, On: yyDollar[5].expr}
 		}
-	case 144:
+	case 218:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line partiql.y:633
+//line partiql.y:898
 		{
 			var idxerr error
 			yyVAL.integer, idxerr = toint(yyDollar[1].expr)
@@ -1930,239 +2608,251 @@ yydefault:
 				yylex.Error(idxerr.Error())
 			}
 		}
-	case 145:
+	case 219:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line partiql.y:642
+//line partiql.y:907
 		{
 			yyVAL.str = yyDollar[1].str
 		}
-	case 146:
+	case 220:
 		yyDollar = yyS[yypt-0 : yypt+1]
-//line partiql.y:645
+//line partiql.y:910
 		{
 			yyVAL.expr = nil
 		}
-	case 147:
+	case 221:
 		yyDollar = yyS[yypt-2 : yypt+1]
-//line partiql.y:646
+//line partiql.y:911
 		{
 			yyVAL.expr = yyDollar[2].expr
 		}
-	case 148:
+	case 222:
 		yyDollar = yyS[yypt-4 : yypt+1]
-//line partiql.y:649
+//line partiql.y:914
 		{
 			yyVAL.limbs = []expr.CaseLimb{{When: yyDollar[2].expr, Then: yyDollar[4].expr}}
 		}
-	case 149:
+	case 223:
 		yyDollar = yyS[yypt-5 : yypt+1]
-//line partiql.y:650
+//line partiql.y:915
 		{
 			yyVAL.limbs = append(yyDollar[1].limbs, expr.CaseLimb{When: yyDollar[3].expr, Then: yyDollar[5].expr})
 		}
-	case 150:
+	case 224:
 		yyDollar = yyS[yypt-0 : yypt+1]
-//line partiql.y:653
+//line partiql.y:918
 		{
 			yyVAL.expr = nil
 		}
-	case 151:
+	case 225:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line partiql.y:654
+//line partiql.y:919
 		{
 			yyVAL.expr = yyDollar[1].expr
 		}
-	case 152:
+	case 226:
 		yyDollar = yyS[yypt-0 : yypt+1]
-//line partiql.y:657
+//line partiql.y:922
 		{
 			yyVAL.expr = nil
 		}
-	case 153:
+	case 227:
 		yyDollar = yyS[yypt-5 : yypt+1]
-//line partiql.y:658
+//line partiql.y:923
 		{
 			yyVAL.expr = yyDollar[4].expr
 		}
-	case 154:
+	case 228:
 		yyDollar = yyS[yypt-0 : yypt+1]
-//line partiql.y:661
+//line partiql.y:926
 		{
 			yyVAL.expr = nil
 		}
-	case 155:
+	case 229:
 		yyDollar = yyS[yypt-2 : yypt+1]
-//line partiql.y:662
+//line partiql.y:927
 		{
 			yyVAL.expr = yyDollar[2].expr
 		}
-	case 156:
+	case 230:
 		yyDollar = yyS[yypt-0 : yypt+1]
-//line partiql.y:665
+//line partiql.y:930
 		{
 			yyVAL.expr = nil
 		}
-	case 157:
+	case 231:
 		yyDollar = yyS[yypt-2 : yypt+1]
-//line partiql.y:666
+//line partiql.y:931
 		{
 			yyVAL.expr = yyDollar[2].expr
 		}
-	case 158:
+	case 232:
+		yyDollar = yyS[yypt-0 : yypt+1]
+//line partiql.y:934
+		{
+			yyVAL.expr = nil
+		}
+	case 233:
+		yyDollar = yyS[yypt-2 : yypt+1]
+//line partiql.y:935
+		{
+			yyVAL.expr = yyDollar[2].expr
+		}
+	case 234:
 		yyDollar = yyS[yypt-0 : yypt+1]
-//line partiql.y:669
+//line partiql.y:938
 		{
 			yyVAL.bindings = nil
 		}
-	case 159:
+	case 235:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line partiql.y:670
+//line partiql.y:939
 		{
 			yyVAL.bindings = yyDollar[3].bindings
 		}
-	case 160:
+	case 236:
 		yyDollar = yyS[yypt-0 : yypt+1]
-//line partiql.y:674
+//line partiql.y:943
 		{
 			yyVAL.yesno = false
 		}
-	case 161:
+	case 237:
 		yyDollar = yyS[yypt-2 : yypt+1]
-//line partiql.y:675
+//line partiql.y:944
 		{
 			yyVAL.yesno = false
 		}
-	case 162:
+	case 238:
 		yyDollar = yyS[yypt-2 : yypt+1]
-//line partiql.y:676
+//line partiql.y:945
 		{
 			yyVAL.yesno = true
 		}
-	case 163:
+	case 239:
 		yyDollar = yyS[yypt-0 : yypt+1]
-//line partiql.y:680
+//line partiql.y:949
 		{
 			yyVAL.yesno = false
 		}
-	case 164:
+	case 240:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line partiql.y:681
+//line partiql.y:950
 		{
 			yyVAL.yesno = false
 		}
-	case 165:
+	case 241:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line partiql.y:682
+//line partiql.y:951
 		{
 			yyVAL.yesno = true
 		}
-	case 166:
+	case 242:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line partiql.y:686
+//line partiql.y:955
 		{
 			yyVAL.order = expr.Order{Column: yyDollar[1].expr, Desc: yyDollar[2].yesno, NullsLast: yyDollar[3].yesno}
 		}
-	case 167:
+	case 243:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line partiql.y:689
+//line partiql.y:958
 		{
 			yyVAL.orders = append(yyDollar[1].orders, yyDollar[3].order)
 		}
-	case 168:
+	case 244:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line partiql.y:690
+//line partiql.y:959
 		{
 			yyVAL.orders = []expr.Order{yyDollar[1].order}
 		}
-	case 169:
+	case 245:
 		yyDollar = yyS[yypt-0 : yypt+1]
-//line partiql.y:693
+//line partiql.y:962
 		{
 			yyVAL.orders = nil
 		}
-	case 170:
+	case 246:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line partiql.y:694
+//line partiql.y:963
 		{
 			yyVAL.orders = yyDollar[3].orders
 		}
-	case 171:
+	case 247:
 		yyDollar = yyS[yypt-0 : yypt+1]
-//line partiql.y:697
+//line partiql.y:966
 		{
 			yyVAL.exprint = nil
 		}
-	case 172:
+	case 248:
 		yyDollar = yyS[yypt-2 : yypt+1]
-//line partiql.y:698
+//line partiql.y:967
 		{
 			n := expr.Integer(yyDollar[2].integer)
 			yyVAL.exprint = &n
 		}
-	case 173:
+	case 249:
 		yyDollar = yyS[yypt-0 : yypt+1]
-//line partiql.y:701
+//line partiql.y:970
 		{
 			yyVAL.exprint = nil
 		}
-	case 174:
+	case 250:
 		yyDollar = yyS[yypt-2 : yypt+1]
-//line partiql.y:702
+//line partiql.y:971
 		{
 			n := expr.Integer(yyDollar[2].integer)
 			yyVAL.exprint = &n
 		}
-	case 175:
+	case 251:
 		yyDollar = yyS[yypt-6 : yypt+1]
-//line partiql.y:705
+//line partiql.y:974
 		{ /*Cloning, as the buffer gets overwritten*/
 			as := yyDollar[4].str
 			at := yyDollar[6].str
 			yyVAL.expr = &expr.Unpivot{TupleRef: yyDollar[2].expr, As: &as, At: &at}
 		}
-	case 176:
+	case 252:
 		yyDollar = yyS[yypt-6 : yypt+1]
-//line partiql.y:706
+//line partiql.y:975
 		{ /*Cloning, as the buffer gets overwritten*/
 			as := yyDollar[6].str
 			at := yyDollar[4].str
 			yyVAL.expr = &expr.Unpivot{TupleRef: yyDollar[2].expr, As: &as, At: &at}
 		}
-	case 177:
+	case 253:
 		yyDollar = yyS[yypt-4 : yypt+1]
-//line partiql.y:707
+//line partiql.y:976
 		{ /*Cloning, as the buffer gets overwritten*/
 			as := yyDollar[4].str
 			yyVAL.expr = &expr.Unpivot{TupleRef: yyDollar[2].expr, As: &as, At: nil}
 		}
-	case 178:
+	case 254:
 		yyDollar = yyS[yypt-4 : yypt+1]
-//line partiql.y:708
+//line partiql.y:977
 		{ /*Cloning, as the buffer gets overwritten*/
 			at := yyDollar[4].str
 			yyVAL.expr = &expr.Unpivot{TupleRef: yyDollar[2].expr, As: nil, At: &at}
 		}
-	case 179:
+	case 255:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line partiql.y:711
+//line partiql.y:980
 		{
 			yyVAL.expr = &expr.Table{Binding: expr.Bind(yyDollar[1].expr, "")}
 		}
-	case 180:
+	case 256:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line partiql.y:715
+//line partiql.y:984
 		{
 			yyVAL.integer = trimLeading
 		}
-	case 181:
+	case 257:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line partiql.y:716
+//line partiql.y:985
 		{
 			yyVAL.integer = trimTrailing
 		}
-	case 182:
+	case 258:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line partiql.y:717
+//line partiql.y:986
 		{
 			yyVAL.integer = trimBoth
 		}
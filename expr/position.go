@@ -0,0 +1,125 @@
+// Copyright (C) 2022 Sneller, Inc.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package expr
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Position is a location in the text of a query.
+type Position struct {
+	Offset int // byte offset, starting at 0
+	Line   int // line number, starting at 1
+	Column int // column number, starting at 1
+}
+
+// IsValid returns whether p refers
+// to an actual location.
+func (p Position) IsValid() bool { return p.Line > 0 }
+
+func (p Position) String() string {
+	if !p.IsValid() {
+		return "-"
+	}
+	return fmt.Sprintf("%d:%d", p.Line, p.Column)
+}
+
+// Positions maps each identifier in the text
+// of a query to the positions of all of its
+// occurrences, in the order in which they appear.
+//
+// Since the planner freely rewrites expressions,
+// positions are tracked by identifier name rather
+// than by node, so an expression is located by
+// the identifiers that it references (see Of).
+type Positions map[string][]Position
+
+// Of returns the position of the expression n,
+// or false if n does not reference an identifier
+// with a known position.
+//
+// The position is that of an occurrence of the first
+// identifier referenced by n; if that identifier
+// occurs more than once, Of picks the occurrence that
+// begins the shortest stretch of text that contains
+// the other identifiers referenced by n in order.
+func (p Positions) Of(n Node) (Position, bool) {
+	if len(p) == 0 || n == nil {
+		return Position{}, false
+	}
+	var ids []string
+	Walk(WalkFunc(func(e Node) bool {
+		if id, ok := e.(Ident); ok {
+			if _, ok := p[string(id)]; ok {
+				ids = append(ids, string(id))
+			}
+		}
+		return true
+	}), n)
+	if len(ids) == 0 {
+		return Position{}, false
+	}
+	first := p[ids[0]]
+	best, span := first[0], -1
+	for _, start := range first {
+		end, ok := start.Offset, true
+		for _, id := range ids[1:] {
+			end, ok = p.after(id, end)
+			if !ok {
+				break
+			}
+		}
+		if ok && (span < 0 || end-start.Offset < span) {
+			best, span = start, end-start.Offset
+		}
+	}
+	return best, true
+}
+
+// after returns the offset of the first
+// occurrence of id after offset off
+func (p Positions) after(id string, off int) (int, bool) {
+	for _, pos := range p[id] {
+		if pos.Offset > off {
+			return pos.Offset, true
+		}
+	}
+	return 0, false
+}
+
+// at returns the suffix used to
+// describe the position of an error
+func at(p Position) string {
+	if !p.IsValid() {
+		return ""
+	}
+	return " at " + p.String()
+}
+
+// Locate attaches the position of the
+// node associated with err (if err is a
+// *TypeError or *SyntaxError) using pos.
+// Errors that already have a position
+// are left unmodified.
+func Locate(err error, pos Positions) {
+	var te *TypeError
+	var se *SyntaxError
+	if errors.As(err, &te) && !te.Pos.IsValid() {
+		te.Pos, _ = pos.Of(te.At)
+	} else if errors.As(err, &se) && !se.Pos.IsValid() {
+		se.Pos, _ = pos.Of(se.At)
+	}
+}
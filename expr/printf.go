@@ -0,0 +1,317 @@
+// Copyright (C) 2022 Sneller, Inc.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package expr
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// maxPrintfWidth is the largest field
+// width accepted by PRINTF
+const maxPrintfWidth = 64
+
+// printfVerb is one verb in a format string
+type printfVerb struct {
+	verb  byte // 's', 'd' or 0 for literal text
+	text  string
+	left  bool
+	zero  bool
+	width int
+}
+
+// spec returns the verb in the syntax
+// accepted by package fmt
+func (v *printfVerb) spec() string {
+	var b strings.Builder
+	b.WriteByte('%')
+	if v.left {
+		b.WriteByte('-')
+	}
+	if v.zero {
+		b.WriteByte('0')
+	}
+	if v.width > 0 {
+		b.WriteString(strconv.Itoa(v.width))
+	}
+	b.WriteByte(v.verb)
+	return b.String()
+}
+
+// parsePrintf splits a format string into
+// literal text and verbs
+func parsePrintf(f string) ([]printfVerb, error) {
+	var out []printfVerb
+	var text strings.Builder
+	for len(f) > 0 {
+		i := strings.IndexByte(f, '%')
+		if i < 0 {
+			text.WriteString(f)
+			break
+		}
+		text.WriteString(f[:i])
+		f = f[i+1:]
+		var v printfVerb
+	flags:
+		for len(f) > 0 {
+			switch f[0] {
+			case '-':
+				v.left = true
+			case '0':
+				v.zero = true
+			default:
+				break flags
+			}
+			f = f[1:]
+		}
+		for len(f) > 0 && f[0] >= '0' && f[0] <= '9' {
+			v.width = v.width*10 + int(f[0]-'0')
+			if v.width > maxPrintfWidth {
+				return nil, errsyntaxf("%s: width exceeds %d", Printf, maxPrintfWidth)
+			}
+			f = f[1:]
+		}
+		if len(f) == 0 {
+			return nil, errsyntaxf("%s: format string ends with an incomplete verb", Printf)
+		}
+		switch f[0] {
+		case '%':
+			if v != (printfVerb{}) {
+				return nil, errsyntaxf("%s: %%%% does not accept flags or a width", Printf)
+			}
+			text.WriteByte('%')
+			f = f[1:]
+			continue
+		case 's', 'd':
+			v.verb = f[0]
+		default:
+			return nil, errsyntaxf("%s: unsupported verb %%%c", Printf, f[0])
+		}
+		f = f[1:]
+		if text.Len() > 0 {
+			out = append(out, printfVerb{text: text.String()})
+			text.Reset()
+		}
+		out = append(out, v)
+	}
+	if text.Len() > 0 {
+		out = append(out, printfVerb{text: text.String()})
+	}
+	return out, nil
+}
+
+// countVerbs returns the number of arguments
+// consumed by a format string
+func countVerbs(lst []printfVerb) int {
+	n := 0
+	for i := range lst {
+		if lst[i].verb != 0 {
+			n++
+		}
+	}
+	return n
+}
+
+func checkPrintf(h Hint, args []Node) error {
+	if len(args) == 0 {
+		return errsyntaxf("%s expects at least 1 argument", Printf)
+	}
+	f, ok := args[0].(String)
+	if !ok {
+		return errsyntaxf("%s requires a constant string as its first argument", Printf)
+	}
+	lst, err := parsePrintf(string(f))
+	if err != nil {
+		return err
+	}
+	args = args[1:]
+	if n := countVerbs(lst); n != len(args) {
+		return errsyntaxf("%s: format string expects %d arguments, but found %d", Printf, n, len(args))
+	}
+	j := 0
+	for i := range lst {
+		switch lst[i].verb {
+		case 's':
+			if !TypeOf(args[j], h).AnyOf(StringType | IntegerType) {
+				return errtype(args[j], "not a string or an integer")
+			}
+		case 'd':
+			if !TypeOf(args[j], h).AnyOf(NumericType) {
+				return errtype(args[j], "not a number")
+			}
+		default:
+			continue
+		}
+		j++
+	}
+	return nil
+}
+
+// pad pads str to the width of v
+func (v *printfVerb) pad(str Node) Node {
+	if v.width == 0 {
+		return str
+	}
+	fill := " "
+	if v.zero && !v.left {
+		fill = "0"
+	}
+	return padTo(str, v.width, v.left, fill)
+}
+
+// padTo pads str with fill up to width characters
+func padTo(str Node, width int, left bool, fill string) Node {
+	fills := String(strings.Repeat(fill, width))
+	n := Call(CharLength, str)
+	var padded Node
+	if left {
+		padded = Call(Substring, Call(Concat, Copy(str), fills), Integer(1), Integer(width))
+	} else {
+		// the last width characters of fills+str
+		padded = Call(Substring, Call(Concat, fills, Copy(str)), Add(Copy(n), Integer(1)))
+	}
+	return &Case{
+		Limbs: []CaseLimb{{
+			When: Compare(Less, n, Integer(width)),
+			Then: padded,
+		}},
+		Else: Copy(str),
+	}
+}
+
+// toStr converts a string or an integer to a string
+func toStr(arg Node, h Hint) Node {
+	t := TypeOf(arg, h) &^ MissingType
+	if t&^StringType == 0 {
+		return &Cast{From: arg, To: StringType}
+	}
+	n := &Cast{From: arg, To: IntegerType}
+	if t&^IntegerType == 0 {
+		return &Cast{From: n, To: StringType}
+	}
+	// CAST(x AS STRING) only accepts strings
+	// when the type of x is not known in advance,
+	// so integers are tested for separately
+	return &Case{
+		Limbs: []CaseLimb{{
+			When: Is(&Cast{From: arg, To: StringType}, IsNotMissing),
+			Then: &Cast{From: Copy(arg), To: StringType},
+		}, {
+			When: Compare(Equals, Sub(Copy(arg), n), Integer(0)),
+			Then: &Cast{From: Copy(n), To: StringType},
+		}},
+		Else: Missing{},
+	}
+}
+
+// expr produces the expression that formats arg
+func (v *printfVerb) expr(arg Node, h Hint) Node {
+	if v.verb == 's' {
+		return v.pad(toStr(arg, h))
+	}
+	n := &Cast{From: arg, To: IntegerType}
+	if !v.zero || v.left || v.width == 0 {
+		return v.pad(&Cast{From: n, To: StringType})
+	}
+	// zeros go between the sign and the digits
+	abs := &Cast{From: Call(Abs, Copy(n)), To: StringType}
+	return &Case{
+		Limbs: []CaseLimb{{
+			When: Compare(Less, n, Integer(0)),
+			Then: Call(Concat, String("-"), padTo(abs, v.width-1, false, "0")),
+		}},
+		Else: padTo(Copy(abs), v.width, false, "0"),
+	}
+}
+
+// constant formats a constant argument,
+// or returns false if arg is not a constant
+// that can be formatted in advance
+func (v *printfVerb) constant(arg Node) (string, bool) {
+	switch arg := arg.(type) {
+	case String:
+		if v.verb == 's' {
+			return fmt.Sprintf(v.spec(), string(arg)), true
+		}
+	case Integer:
+		if v.verb == 's' {
+			return fmt.Sprintf(v.spec(), strconv.FormatInt(int64(arg), 10)), true
+		}
+		return fmt.Sprintf(v.spec(), int64(arg)), true
+	}
+	return "", false
+}
+
+// simplifyPrintf lowers PRINTF(fmt, args...),
+// which constructs a string in the
+// style of printf(3). Only a small set of verbs
+// is accepted:
+//
+//	%s     a string or an integer
+//	%d     a number, truncated to an integer
+//	%%     a literal '%'
+//
+// Each verb may be preceded by a '-' flag
+// (align to the left), a '0' flag (pad with zeros
+// instead of spaces) and a width of at most
+// maxPrintfWidth characters.
+//
+// Like TO_CHAR, the result is a concatenation
+// of the parts of the format string, so it costs
+// about as much as the equivalent chain of
+// CONCAT and CAST expressions.
+func simplifyPrintf(h Hint, args []Node) Node {
+	if len(args) == 0 {
+		return nil
+	}
+	f, ok := args[0].(String)
+	if !ok {
+		return nil
+	}
+	lst, err := parsePrintf(string(f))
+	if err != nil || countVerbs(lst) != len(args)-1 {
+		return nil
+	}
+	args = args[1:]
+	var parts []Node
+	var text strings.Builder
+	j := 0
+	for i := range lst {
+		if lst[i].verb == 0 {
+			text.WriteString(lst[i].text)
+			continue
+		}
+		arg := args[j]
+		j++
+		if str, ok := lst[i].constant(arg); ok {
+			text.WriteString(str)
+			continue
+		}
+		if text.Len() > 0 {
+			parts = append(parts, String(text.String()))
+			text.Reset()
+		}
+		parts = append(parts, lst[i].expr(arg, h))
+	}
+	if text.Len() > 0 || len(parts) == 0 {
+		parts = append(parts, String(text.String()))
+	}
+	ret := parts[0]
+	for _, p := range parts[1:] {
+		ret = Call(Concat, ret, p)
+	}
+	return Simplify(ret, h)
+}
@@ -0,0 +1,93 @@
+// Copyright (C) 2022 Sneller, Inc.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package expr_test
+
+import (
+	"testing"
+
+	"github.com/SnellerInc/sneller/expr"
+	"github.com/SnellerInc/sneller/expr/partiql"
+)
+
+func TestPrintfConstant(t *testing.T) {
+	testcases := []struct {
+		format string
+		args   []expr.Node
+		want   string
+	}{
+		{"%s-%04d", []expr.Node{expr.String("abc"), expr.Integer(7)}, "abc-0007"},
+		{"%04d", []expr.Node{expr.Integer(-7)}, "-007"},
+		{"[%5s|%-5s]", []expr.Node{expr.String("ü"), expr.Integer(12)}, "[    ü|12   ]"},
+		{"%-05d|%3d", []expr.Node{expr.Integer(42), expr.Integer(12345)}, "42   |12345"},
+		{"100%%", nil, "100%"},
+		{"", nil, ""},
+	}
+	for i := range testcases {
+		tc := &testcases[i]
+		args := append([]expr.Node{expr.String(tc.format)}, tc.args...)
+		got := expr.Simplify(expr.Call(expr.Printf, args...), expr.NoHint)
+		if got != expr.String(tc.want) {
+			t.Errorf("PRINTF(%q, ...): got %s, want %q", tc.format, expr.ToString(got), tc.want)
+		}
+	}
+}
+
+func TestPrintfCheck(t *testing.T) {
+	good := []string{
+		"SELECT PRINTF('%s-%04d', x, y) FROM t",
+		"SELECT PRINTF('%-10s|%%', x) FROM t",
+	}
+	for i := range good {
+		q, err := partiql.Parse([]byte(good[i]))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := q.Check(); err != nil {
+			t.Errorf("%s: %s", good[i], err)
+		}
+		// the locale does not apply to
+		// printf-style formatting
+		if err := q.SetLocale("de"); err != nil {
+			t.Fatal(err)
+		}
+		if err := q.Check(); err != nil {
+			t.Errorf("%s: after SetLocale: %s", good[i], err)
+		}
+	}
+	bad := []string{
+		"SELECT PRINTF('%s-%d', x) FROM t",
+		"SELECT PRINTF('%s', x, y) FROM t",
+		"SELECT PRINTF('%x', x) FROM t",
+		"SELECT PRINTF('%f', x) FROM t",
+		"SELECT PRINTF('%5%', x) FROM t",
+		"SELECT PRINTF('%', x) FROM t",
+		"SELECT PRINTF('%100s', x) FROM t",
+		"SELECT PRINTF('%d', 'foo') FROM t",
+		"SELECT PRINTF('%s', CAST(x AS FLOAT)) FROM t",
+		"SELECT PRINTF(x, y) FROM t",
+		"SELECT PRINTF() FROM t",
+		// FORMAT only formats numbers
+		"SELECT FORMAT('%s', x) FROM t",
+	}
+	for i := range bad {
+		q, err := partiql.Parse([]byte(bad[i]))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := q.Check(); err == nil {
+			t.Errorf("%s: expected an error", bad[i])
+		}
+	}
+}
@@ -19,6 +19,8 @@ import (
 	"strings"
 
 	"github.com/SnellerInc/sneller/ion"
+
+	"golang.org/x/exp/slices"
 )
 
 // CTE is one arm of a "common table expression"
@@ -51,12 +53,29 @@ type Query struct {
 	// a SELECT-FROM-WHERE that includes
 	// an INTO clause.
 	Into Node
+	// PartitionBy, if non-empty, is the list of
+	// columns named in the PARTITIONED BY portion
+	// of the INTO clause. The output of the query
+	// is split into one partition for each distinct
+	// combination of the values of these columns.
+	PartitionBy []string
+	// Append, if true, indicates that the query is
+	// an INSERT INTO statement that appends its output
+	// to the existing table Into rather than creating
+	// a new table.
+	Append bool
 	// Body is the body of the query.
 	// Body can be:
 	//   - A SELECT expression
 	//   - A UNION expression
 	//   - A UNION ALL expression
 	Body Node
+	// Positions, if non-nil, records the
+	// location of identifiers in the original
+	// query text. Positions is not part of the
+	// query text or its serialized form and is
+	// ignored by Equals.
+	Positions Positions
 }
 
 // Text returns the unredacted query text.
@@ -93,9 +112,15 @@ func (q *Query) text(dst *strings.Builder, redact bool) {
 		}
 		dst.WriteByte(' ')
 	}
-	if s, ok := q.Body.(*Select); ok {
+	if s, ok := q.Body.(*Select); ok && q.Append {
+		dst.WriteString("INSERT INTO ")
+		q.Into.text(dst, redact)
+		writePartitionBy(dst, q.PartitionBy)
+		dst.WriteByte(' ')
+		s.write(dst, redact, nil, nil)
+	} else if ok {
 		// do not parenthesize final SELECT
-		s.write(dst, redact, q.Into)
+		s.write(dst, redact, q.Into, q.PartitionBy)
 	} else {
 		q.Body.text(dst, redact)
 	}
@@ -121,6 +146,12 @@ func (q *Query) Equals(other *Query) bool {
 			return false
 		}
 	}
+	if (q.Into == nil) != (other.Into == nil) ||
+		q.Into != nil && !q.Into.Equals(other.Into) ||
+		!slices.Equal(q.PartitionBy, other.PartitionBy) ||
+		q.Append != other.Append {
+		return false
+	}
 	return q.Body.Equals(other.Body)
 }
 
@@ -150,6 +181,18 @@ func (q *Query) Encode(dst *ion.Buffer, st *ion.Symtab) {
 		field("into")
 		q.Into.Encode(dst, st)
 	}
+	if len(q.PartitionBy) > 0 {
+		field("partition_by")
+		dst.BeginList(-1)
+		for i := range q.PartitionBy {
+			dst.WriteString(q.PartitionBy[i])
+		}
+		dst.EndList()
+	}
+	if q.Append {
+		field("append")
+		dst.WriteBool(true)
+	}
 	field("body")
 	q.Body.Encode(dst, st)
 	dst.EndStruct()
@@ -215,6 +258,17 @@ func (q *Query) SetField(f ion.Field) error {
 		}
 	case "into":
 		q.Into, err = Decode(f.Datum)
+	case "partition_by":
+		err = f.UnpackList(func(d ion.Datum) error {
+			s, err := d.String()
+			if err != nil {
+				return err
+			}
+			q.PartitionBy = append(q.PartitionBy, s)
+			return nil
+		})
+	case "append":
+		q.Append, err = f.Bool()
 	case "body":
 		q.Body, err = Decode(f.Datum)
 	default:
@@ -34,7 +34,8 @@ func Quote(s string) string {
 func quote(out *strings.Builder, s string) {
 	var tmp []byte
 	out.WriteByte('\'')
-	for _, r := range s {
+	for i := 0; i < len(s); {
+		r, size := utf8.DecodeRuneInString(s[i:])
 		switch {
 		case r == '\'' || r == '/' || r == '\\': // non-standard escaped chars
 			out.WriteRune('\\')
@@ -43,10 +44,22 @@ func quote(out *strings.Builder, s string) {
 		case (r < utf8.RuneSelf && strconv.IsPrint(r)) || r == '"':
 			out.WriteRune(r)
 
+		case r == utf8.RuneError && size == 1, r > 0xffff:
+			// invalid UTF-8 and runes outside of the
+			// BMP cannot be written using \u escapes
+			out.WriteString(s[i : i+size])
+
 		default:
 			tmp = strconv.AppendQuoteRuneToASCII(tmp[:0], r)
-			out.Write(tmp[1 : len(tmp)-1])
+			esc := tmp[1 : len(tmp)-1]
+			if esc[1] == 'x' {
+				// Unescape only supports \u escapes
+				out.WriteString("\\u00")
+				esc = esc[2:]
+			}
+			out.Write(esc)
 		}
+		i += size
 	}
 	out.WriteByte('\'')
 }
@@ -28,6 +28,7 @@ func TestQuoteUnquote(t *testing.T) {
 		{"a \t\n\r\v\f\a\b", `'a \t\n\r\v\f\a\b'`},
 		{"b '/\\ c", `'b \'\/\\ c'`},
 		{"żółw", `'\u017c\u00f3\u0142w'`},
+		{"\x12\x7f", `'\u0012\u007f'`},
 		{"'xyz'", "'\\'xyz\\''"},
 	}
 
@@ -0,0 +1,479 @@
+// Copyright (C) 2022 Sneller, Inc.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package expr
+
+import (
+	"sort"
+
+	"github.com/SnellerInc/sneller/ion"
+)
+
+// The rules in this file normalize conjunctions
+// and disjunctions of comparisons of a path against
+// constants. They are only legal in a logical context
+// (where MISSING and NULL are treated as FALSE), since
+// a comparison against a value of the wrong type is
+// MISSING rather than FALSE.
+//
+//	x > 1 AND x > 3            -> x > 3
+//	x <= 9 AND x >= 3          -> x BETWEEN 3 AND 9
+//	x > 5 AND x < 3            -> FALSE
+//	x < 3 OR x BETWEEN 1 AND 5 -> x <= 5
+//	x = 1 OR x = 2 OR ...      -> x IN (1, 2, ...)
+
+// rangeKind is the class of constants
+// that can be ordered against one another
+type rangeKind int
+
+const (
+	rangeNone rangeKind = iota
+	rangeNumber
+	rangeTimestamp
+)
+
+func kindOf(c Node) rangeKind {
+	switch c := c.(type) {
+	case *Timestamp:
+		return rangeTimestamp
+	case number:
+		if c.rat() != nil {
+			return rangeNumber
+		}
+	}
+	return rangeNone
+}
+
+// cmpconst compares two constants of the same rangeKind
+func cmpconst(a, b Node) int {
+	if ta, ok := a.(*Timestamp); ok {
+		tb := b.(*Timestamp)
+		switch {
+		case ta.Value.Before(tb.Value):
+			return -1
+		case ta.Value.After(tb.Value):
+			return 1
+		}
+		return 0
+	}
+	return asrational(a).Cmp(asrational(b))
+}
+
+// interval is a set of values compared against
+// a path; a nil bound is unbounded
+type interval struct {
+	lo, hi       Node
+	loinc, hiinc bool
+}
+
+// lower reports whether the lower bound of x
+// is below the lower bound of y
+func (x *interval) lower(y *interval) bool {
+	if x.lo == nil || y.lo == nil {
+		return x.lo == nil && y.lo != nil
+	}
+	c := cmpconst(x.lo, y.lo)
+	return c < 0 || (c == 0 && x.loinc && !y.loinc)
+}
+
+// intersect narrows x to the values also in y
+func (x *interval) intersect(y *interval) {
+	if y.lo != nil {
+		if x.lo == nil {
+			x.lo, x.loinc = y.lo, y.loinc
+		} else if c := cmpconst(y.lo, x.lo); c > 0 || (c == 0 && !y.loinc) {
+			x.lo, x.loinc = y.lo, y.loinc
+		}
+	}
+	if y.hi != nil {
+		if x.hi == nil {
+			x.hi, x.hiinc = y.hi, y.hiinc
+		} else if c := cmpconst(y.hi, x.hi); c < 0 || (c == 0 && !y.hiinc) {
+			x.hi, x.hiinc = y.hi, y.hiinc
+		}
+	}
+}
+
+// empty returns true if x contains no values
+func (x *interval) empty() bool {
+	if x.lo == nil || x.hi == nil {
+		return false
+	}
+	c := cmpconst(x.lo, x.hi)
+	return c > 0 || (c == 0 && !(x.loinc && x.hiinc))
+}
+
+// touches returns true if the union of x and y
+// (where y does not start below x) is a single interval
+func (x *interval) touches(y *interval) bool {
+	if x.hi == nil || y.lo == nil {
+		return true
+	}
+	c := cmpconst(y.lo, x.hi)
+	return c < 0 || (c == 0 && (x.hiinc || y.loinc))
+}
+
+// extend widens x to cover y,
+// provided that x.touches(y)
+func (x *interval) extend(y *interval) {
+	if x.hi == nil {
+		return
+	}
+	if y.hi == nil {
+		x.hi = nil
+		return
+	}
+	if c := cmpconst(y.hi, x.hi); c > 0 || (c == 0 && y.hiinc) {
+		x.hi, x.hiinc = y.hi, y.hiinc
+	}
+}
+
+// terms returns the comparisons of p that
+// are equivalent to membership in x
+func (x *interval) terms(p Node) []Node {
+	if x.lo != nil && x.hi != nil && x.loinc && x.hiinc && cmpconst(x.lo, x.hi) == 0 {
+		return []Node{Compare(Equals, Copy(p), Copy(x.lo))}
+	}
+	var out []Node
+	if x.lo != nil {
+		op := Greater
+		if x.loinc {
+			op = GreaterEquals
+		}
+		out = append(out, Compare(op, Copy(p), Copy(x.lo)))
+	}
+	if x.hi != nil {
+		op := Less
+		if x.hiinc {
+			op = LessEquals
+		}
+		out = append(out, Compare(op, Copy(p), Copy(x.hi)))
+	}
+	return out
+}
+
+// rangeOf determines if n is a comparison of a path
+// against an orderable constant (or a conjunction of
+// two such comparisons of the same path, i.e. BETWEEN)
+// and returns the path and the corresponding interval
+func rangeOf(n Node) (Node, rangeKind, interval, bool) {
+	var iv interval
+	switch n := n.(type) {
+	case *Comparison:
+		p, c, op := n.Left, n.Right, n.Op
+		if IsConstant(p) && !IsConstant(c) {
+			p, c, op = c, p, op.Flip()
+		}
+		if !IsPath(p) {
+			break
+		}
+		k := kindOf(c)
+		if k == rangeNone {
+			break
+		}
+		switch op {
+		case Equals:
+			iv = interval{lo: c, hi: c, loinc: true, hiinc: true}
+		case Less, LessEquals:
+			iv = interval{hi: c, hiinc: op == LessEquals}
+		case Greater, GreaterEquals:
+			iv = interval{lo: c, loinc: op == GreaterEquals}
+		default:
+			return nil, rangeNone, iv, false
+		}
+		return p, k, iv, true
+	case *Logical:
+		if n.Op != OpAnd {
+			break
+		}
+		lp, lk, liv, ok := rangeOf(n.Left)
+		if !ok {
+			break
+		}
+		rp, rk, riv, ok := rangeOf(n.Right)
+		if !ok || lk != rk || !Equal(lp, rp) {
+			break
+		}
+		liv.intersect(&riv)
+		if liv.empty() {
+			break
+		}
+		return lp, lk, liv, true
+	}
+	return nil, rangeNone, iv, false
+}
+
+// flatlogic appends the operands of the
+// chain of op (AND or OR) at n to lst
+func flatlogic(op LogicalOp, n Node, lst []Node) []Node {
+	if l, ok := n.(*Logical); ok && l.Op == op {
+		lst = flatlogic(op, l.Left, lst)
+		return flatlogic(op, l.Right, lst)
+	}
+	return append(lst, n)
+}
+
+// unflatlogic is the inverse of flatlogic
+func unflatlogic(op LogicalOp, lst []Node) Node {
+	top := lst[0]
+	for _, n := range lst[1:] {
+		top = &Logical{Op: op, Left: top, Right: n}
+	}
+	return top
+}
+
+func sameterms(a, b []Node) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !Equal(a[i], b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// rangeGroup is the set of terms
+// that compare the same path against
+// constants of the same kind
+type rangeGroup struct {
+	path  Node
+	kind  rangeKind
+	terms []int
+	ivs   []interval
+}
+
+func rangeGroups(lst []Node) []rangeGroup {
+	var groups []rangeGroup
+outer:
+	for i := range lst {
+		p, k, iv, ok := rangeOf(lst[i])
+		if !ok {
+			continue
+		}
+		for j := range groups {
+			if groups[j].kind == k && Equal(groups[j].path, p) {
+				groups[j].terms = append(groups[j].terms, i)
+				groups[j].ivs = append(groups[j].ivs, iv)
+				continue outer
+			}
+		}
+		groups = append(groups, rangeGroup{
+			path:  p,
+			kind:  k,
+			terms: []int{i},
+			ivs:   []interval{iv},
+		})
+	}
+	return groups
+}
+
+// regroup replaces the terms of each group in lst
+// with the nodes produced by emit for that group,
+// placing them at the position of the first term;
+// groups for which emit reports no change are
+// left in place
+func regroup(lst []Node, groups []rangeGroup, emit func(g *rangeGroup) ([]Node, bool)) []Node {
+	first := make(map[int][]Node)
+	drop := make(map[int]bool)
+	for i := range groups {
+		if len(groups[i].terms) < 2 {
+			continue
+		}
+		nodes, ok := emit(&groups[i])
+		if !ok {
+			continue
+		}
+		first[groups[i].terms[0]] = nodes
+		for _, t := range groups[i].terms {
+			drop[t] = true
+		}
+	}
+	if len(first) == 0 {
+		return lst
+	}
+	out := make([]Node, 0, len(lst))
+	for i := range lst {
+		if nodes, ok := first[i]; ok {
+			out = append(out, nodes...)
+		} else if !drop[i] {
+			out = append(out, lst[i])
+		}
+	}
+	return out
+}
+
+func (g *rangeGroup) nodes(lst []Node) []Node {
+	out := make([]Node, len(g.terms))
+	for i, t := range g.terms {
+		out[i] = lst[t]
+	}
+	return out
+}
+
+// conjoinRanges intersects the ranges of
+// each path within a conjunction
+func conjoinRanges(l *Logical) Node {
+	lst := flatlogic(OpAnd, l, nil)
+	empty := false
+	out := regroup(lst, rangeGroups(lst), func(g *rangeGroup) ([]Node, bool) {
+		iv := g.ivs[0]
+		for i := range g.ivs[1:] {
+			iv.intersect(&g.ivs[i+1])
+		}
+		if iv.empty() {
+			empty = true
+			return nil, true
+		}
+		terms := iv.terms(g.path)
+		return terms, !sameterms(g.nodes(lst), terms)
+	})
+	if empty {
+		return Bool(false)
+	}
+	if len(out) == len(lst) && sameterms(lst, out) {
+		return l
+	}
+	return unflatlogic(OpAnd, out)
+}
+
+// disjoinRanges merges the overlapping ranges
+// of each path within a disjunction and then
+// turns the remaining equality comparisons
+// into set membership
+func disjoinRanges(l *Logical) Node {
+	lst := flatlogic(OpOr, l, nil)
+	out := regroup(lst, rangeGroups(lst), func(g *rangeGroup) ([]Node, bool) {
+		ivs := g.ivs
+		sort.SliceStable(ivs, func(i, j int) bool {
+			return ivs[i].lower(&ivs[j])
+		})
+		merged := ivs[:1]
+		for i := range ivs[1:] {
+			last := &merged[len(merged)-1]
+			if last.touches(&ivs[i+1]) {
+				last.extend(&ivs[i+1])
+			} else {
+				merged = append(merged, ivs[i+1])
+			}
+		}
+		if len(merged) == len(g.terms) || (merged[0].lo == nil && merged[0].hi == nil) {
+			// nothing to merge, or the union
+			// matches any value of this kind,
+			// which cannot be expressed as a range
+			return nil, false
+		}
+		nodes := make([]Node, len(merged))
+		for i := range merged {
+			nodes[i] = unflatlogic(OpAnd, merged[i].terms(g.path))
+		}
+		return nodes, true
+	})
+	out = membership(out)
+	if len(out) == len(lst) && sameterms(lst, out) {
+		return l
+	}
+	return unflatlogic(OpOr, out)
+}
+
+// membership turns disjunctions of at least
+// minMemberArguments equality comparisons of
+// the same path into a single Member expression
+// (below that threshold, Member.simplify would
+// explode the set back into comparisons)
+func membership(lst []Node) []Node {
+	type eqgroup struct {
+		path  Node
+		terms []int
+		set   ion.Bag
+	}
+	var groups []eqgroup
+	add := func(i int, p Node, fn func(set *ion.Bag)) {
+		for j := range groups {
+			if Equal(groups[j].path, p) {
+				groups[j].terms = append(groups[j].terms, i)
+				fn(&groups[j].set)
+				return
+			}
+		}
+		groups = append(groups, eqgroup{path: p, terms: []int{i}})
+		fn(&groups[len(groups)-1].set)
+	}
+	for i := range lst {
+		switch n := lst[i].(type) {
+		case *Member:
+			if IsPath(n.Arg) {
+				add(i, n.Arg, func(set *ion.Bag) {
+					n.Set.Each(func(d ion.Datum) bool {
+						addDistinct(set, d)
+						return true
+					})
+				})
+			}
+		case *Comparison:
+			if n.Op != Equals {
+				continue
+			}
+			p, c := n.Left, n.Right
+			if IsConstant(p) {
+				p, c = c, p
+			}
+			k, ok := c.(Constant)
+			if !ok || !IsPath(p) {
+				continue
+			}
+			if _, ok := k.(Null); ok {
+				continue
+			}
+			add(i, p, func(set *ion.Bag) {
+				addDistinct(set, k.Datum())
+			})
+		}
+	}
+	first := make(map[int]int)
+	drop := make(map[int]bool)
+	for i := range groups {
+		if len(groups[i].terms) < 2 || groups[i].set.Len() < minMemberArguments {
+			continue
+		}
+		first[groups[i].terms[0]] = i
+		for _, t := range groups[i].terms {
+			drop[t] = true
+		}
+	}
+	if len(first) == 0 {
+		return lst
+	}
+	out := make([]Node, 0, len(lst))
+	for i := range lst {
+		if g, ok := first[i]; ok {
+			out = append(out, &Member{Arg: Copy(groups[g].path), Set: groups[g].set})
+		} else if !drop[i] {
+			out = append(out, lst[i])
+		}
+	}
+	return out
+}
+
+func addDistinct(set *ion.Bag, d ion.Datum) {
+	dup := false
+	set.Each(func(x ion.Datum) bool {
+		dup = x.Equal(d)
+		return !dup
+	})
+	if !dup {
+		set.AddDatum(d)
+	}
+}
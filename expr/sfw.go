@@ -87,6 +87,8 @@ func DefaultBinding(e Node) string {
 		case *Index:
 			suffix = "_" + strconv.Itoa(v.Offset)
 			e = v.Inner
+		case *Wildcard:
+			e = v.Inner
 		case *Aggregate:
 			return v.Op.defaultResult()
 		default:
@@ -383,6 +385,9 @@ type Select struct {
 	GroupBy []Binding
 	// HAVING clause, or nil
 	Having Node
+	// QUALIFY clause, or nil;
+	// filters the results of window functions
+	Qualify Node
 	// ORDER BY clauses, or nil
 	OrderBy []Order
 	// When OrderBy is non-nil,
@@ -406,6 +411,9 @@ func (s *Select) walk(v Visitor) {
 	if s.Having != nil {
 		Walk(v, s.Having)
 	}
+	if s.Qualify != nil {
+		Walk(v, s.Qualify)
+	}
 	for i := range s.GroupBy {
 		walkbind(v, &s.GroupBy[i])
 	}
@@ -448,6 +456,7 @@ func (s *Select) rewrite(r Rewriter) Node {
 	}
 	s.Where = Rewrite(r, s.Where)
 	s.Having = Rewrite(r, s.Having)
+	s.Qualify = Rewrite(r, s.Qualify)
 	for i := range s.GroupBy {
 		s.GroupBy[i] = rewritebind(r, &s.GroupBy[i])
 	}
@@ -466,6 +475,7 @@ func (s *Select) Equals(x Node) bool {
 		(s.From == nil) != (xs.From == nil) ||
 		(s.Where == nil) != (xs.Where == nil) ||
 		(s.Having == nil) != (xs.Having == nil) ||
+		(s.Qualify == nil) != (xs.Qualify == nil) ||
 		(s.Limit == nil) != (xs.Limit == nil) ||
 		(s.Offset == nil) != (xs.Offset == nil) ||
 		(s.Distinct != xs.Distinct) {
@@ -486,6 +496,9 @@ func (s *Select) Equals(x Node) bool {
 	if s.Having != nil && !s.Having.Equals(xs.Having) {
 		return false
 	}
+	if s.Qualify != nil && !s.Qualify.Equals(xs.Qualify) {
+		return false
+	}
 	if !slices.EqualFunc(s.OrderBy, xs.OrderBy, Order.Equals) {
 		return false
 	}
@@ -545,6 +558,7 @@ func (s *Select) Encode(dst *ion.Buffer, st *ion.Symtab) {
 	addfield(dst, st, "from", s.From)
 	addfield(dst, st, "where", s.Where)
 	addfield(dst, st, "having", s.Having)
+	addfield(dst, st, "qualify", s.Qualify)
 	if len(s.GroupBy) > 0 {
 		dst.BeginField(st.Intern("group_by"))
 		EncodeBindings(s.GroupBy, dst, st)
@@ -586,7 +600,7 @@ func fmtbinding(lst []Binding, dst *strings.Builder, redact bool) {
 
 func (s *Select) text(out *strings.Builder, redact bool) {
 	out.WriteByte('(')
-	s.write(out, redact, nil)
+	s.write(out, redact, nil, nil)
 	out.WriteByte(')')
 }
 
@@ -595,11 +609,27 @@ func (s *Select) text(out *strings.Builder, redact bool) {
 // the query.
 func (s *Select) Text() string {
 	var out strings.Builder
-	s.write(&out, false, nil)
+	s.write(&out, false, nil, nil)
 	return out.String()
 }
 
-func (s *Select) write(out *strings.Builder, redact bool, into Node) {
+// writePartitionBy writes the PARTITIONED BY
+// portion of an INTO clause
+func writePartitionBy(out *strings.Builder, partitions []string) {
+	if len(partitions) == 0 {
+		return
+	}
+	out.WriteString(" PARTITIONED BY (")
+	for i := range partitions {
+		if i > 0 {
+			out.WriteString(", ")
+		}
+		out.WriteString(QuoteID(partitions[i]))
+	}
+	out.WriteString(")")
+}
+
+func (s *Select) write(out *strings.Builder, redact bool, into Node, partitions []string) {
 	out.WriteString("SELECT ")
 	if s.Distinct {
 		out.WriteString("DISTINCT ")
@@ -619,6 +649,7 @@ func (s *Select) write(out *strings.Builder, redact bool, into Node) {
 		out.WriteString(" INTO ")
 		into.text(out, redact)
 	}
+	writePartitionBy(out, partitions)
 	if s.From != nil {
 		out.WriteString(" FROM ")
 		s.From.text(out, redact)
@@ -640,6 +671,10 @@ func (s *Select) write(out *strings.Builder, redact bool, into Node) {
 		out.WriteString(" HAVING ")
 		s.Having.text(out, redact)
 	}
+	if s.Qualify != nil {
+		out.WriteString(" QUALIFY ")
+		s.Qualify.text(out, redact)
+	}
 	if s.OrderBy != nil {
 		out.WriteString(" ORDER BY ")
 		for i := range s.OrderBy {
@@ -787,6 +822,8 @@ func (s *Select) SetField(f ion.Field) error {
 		s.Where, err = Decode(f.Datum)
 	case "having":
 		s.Having, err = Decode(f.Datum)
+	case "qualify":
+		s.Qualify, err = Decode(f.Datum)
 	case "group_by":
 		s.GroupBy, err = decodeBindings(f.Datum)
 	case "order_by":
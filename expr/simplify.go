@@ -273,6 +273,12 @@ func (l *Logical) isTrue(h Hint) Node {
 			return SimplifyLogic(Is(l.Left, IsNotMissing), h)
 		}
 	}
+	switch l.Op {
+	case OpAnd:
+		return conjoinRanges(l)
+	case OpOr:
+		return disjoinRanges(l)
+	}
 	return l
 }
 
@@ -790,7 +796,7 @@ func (a *Aggregate) simplify(h Hint) Node {
 		variance := Sub(avgSQ, Mul(avgS, avgS))
 		stddev := Call(Sqrt, variance)
 		return IfThenElse(Compare(Equals, cnt, Integer(0)), Null{}, stddev)
-	case OpMin, OpMax, OpSum, OpAvg:
+	case OpMin, OpMax, OpSum, OpAvg, OpSumDistinct, OpAvgDistinct:
 		a.Inner = missingUnless(a.Inner, h, NumericType)
 	}
 	// convert SUM(x) where 'x' is always an integer
@@ -748,6 +748,20 @@ func simplifyClass2(src *Comparison, h Hint) Node {
 				}
 			}
 		}
+		// (eq (date_add_second x (ts y)) (ts z)), "isUnixEpoch(y)", "_, ok := epochSeconds(y, z); ok" -> (eq x (int "epochSecondsFloor(y, z)"))
+		if _tmp001000, ok := (src.Left).(*Builtin); ok && _tmp001000.Func == DateAddSecond && len(_tmp001000.Args) == 2 {
+			if z, ok := (src.Right).(*Timestamp); ok {
+				if x := _tmp001000.Args[0]; true {
+					if y, ok := (_tmp001000.Args[1]).(*Timestamp); ok {
+						if isUnixEpoch(y) {
+							if _, ok := epochSeconds(y, z); ok {
+								return &Comparison{Op: Equals, Left: x, Right: Integer(epochSecondsFloor(y, z))}
+							}
+						}
+					}
+				}
+			}
+		}
 	case Greater:
 		// (gt (ts x) (ts y)) -> (bool "y.Value.Before(x.Value)")
 		if x, ok := (src.Left).(*Timestamp); ok {
@@ -755,6 +769,18 @@ func simplifyClass2(src *Comparison, h Hint) Node {
 				return Bool(y.Value.Before(x.Value))
 			}
 		}
+		// (gt (date_add_second x (ts y)) (ts z)), "isUnixEpoch(y)" -> (gt x (int "epochSecondsFloor(y, z)"))
+		if _tmp001000, ok := (src.Left).(*Builtin); ok && _tmp001000.Func == DateAddSecond && len(_tmp001000.Args) == 2 {
+			if z, ok := (src.Right).(*Timestamp); ok {
+				if x := _tmp001000.Args[0]; true {
+					if y, ok := (_tmp001000.Args[1]).(*Timestamp); ok {
+						if isUnixEpoch(y) {
+							return &Comparison{Op: Greater, Left: x, Right: Integer(epochSecondsFloor(y, z))}
+						}
+					}
+				}
+			}
+		}
 	case GreaterEquals:
 		// (gte x x), "TypeOf(x, h)&MissingType == 0" -> (bool "true")
 		if x := src.Left; true {
@@ -770,6 +796,18 @@ func simplifyClass2(src *Comparison, h Hint) Node {
 				return Bool(y.Value.Before(x.Value) || x.Value == y.Value)
 			}
 		}
+		// (gte (date_add_second x (ts y)) (ts z)), "isUnixEpoch(y)" -> (gte x (int "epochSecondsCeil(y, z)"))
+		if _tmp001000, ok := (src.Left).(*Builtin); ok && _tmp001000.Func == DateAddSecond && len(_tmp001000.Args) == 2 {
+			if z, ok := (src.Right).(*Timestamp); ok {
+				if x := _tmp001000.Args[0]; true {
+					if y, ok := (_tmp001000.Args[1]).(*Timestamp); ok {
+						if isUnixEpoch(y) {
+							return &Comparison{Op: GreaterEquals, Left: x, Right: Integer(epochSecondsCeil(y, z))}
+						}
+					}
+				}
+			}
+		}
 	case Less:
 		// (lt (ts x) (ts y)) -> (bool "x.Value.Before(y.Value)")
 		if x, ok := (src.Left).(*Timestamp); ok {
@@ -777,6 +815,18 @@ func simplifyClass2(src *Comparison, h Hint) Node {
 				return Bool(x.Value.Before(y.Value))
 			}
 		}
+		// (lt (date_add_second x (ts y)) (ts z)), "isUnixEpoch(y)" -> (lt x (int "epochSecondsCeil(y, z)"))
+		if _tmp001000, ok := (src.Left).(*Builtin); ok && _tmp001000.Func == DateAddSecond && len(_tmp001000.Args) == 2 {
+			if z, ok := (src.Right).(*Timestamp); ok {
+				if x := _tmp001000.Args[0]; true {
+					if y, ok := (_tmp001000.Args[1]).(*Timestamp); ok {
+						if isUnixEpoch(y) {
+							return &Comparison{Op: Less, Left: x, Right: Integer(epochSecondsCeil(y, z))}
+						}
+					}
+				}
+			}
+		}
 	case LessEquals:
 		// (lte x x), "TypeOf(x, h)&MissingType == 0" -> (bool "true")
 		if x := src.Left; true {
@@ -792,6 +842,18 @@ func simplifyClass2(src *Comparison, h Hint) Node {
 				return Bool(x.Value.Before(y.Value) || x.Value == y.Value)
 			}
 		}
+		// (lte (date_add_second x (ts y)) (ts z)), "isUnixEpoch(y)" -> (lte x (int "epochSecondsFloor(y, z)"))
+		if _tmp001000, ok := (src.Left).(*Builtin); ok && _tmp001000.Func == DateAddSecond && len(_tmp001000.Args) == 2 {
+			if z, ok := (src.Right).(*Timestamp); ok {
+				if x := _tmp001000.Args[0]; true {
+					if y, ok := (_tmp001000.Args[1]).(*Timestamp); ok {
+						if isUnixEpoch(y) {
+							return &Comparison{Op: LessEquals, Left: x, Right: Integer(epochSecondsFloor(y, z))}
+						}
+					}
+				}
+			}
+		}
 	case NotEquals:
 		// (neq x x), "TypeOf(x, h)&MissingType == 0" -> (bool "true")
 		if x := src.Left; true {
@@ -1162,4 +1224,4 @@ func simplify1(src Node, h Hint) Node {
 	return nil
 }
 
-// checksum: edc905b3969e625001fe97cd6fb5537e
+// checksum: 4c6f18453f81b7383e8109a16f37d710
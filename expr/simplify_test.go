@@ -22,6 +22,7 @@ import (
 	"math/big"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/SnellerInc/sneller/date"
 	"github.com/SnellerInc/sneller/ion"
@@ -572,6 +573,33 @@ func TestSimplify(t *testing.T) {
 			Call(ObjectSize, mktestlist(String("a"), String("b"), String("c"), String("d"))),
 			Integer(4),
 		},
+		{
+			// OBJECT_SET({foo:1, bar:2}, 'foo', 3) => {bar:2, foo:3}
+			Call(ObjectSet, &Struct{Fields: []Field{{"foo", Integer(1)}, {"bar", Integer(2)}}}, String("foo"), Integer(3)),
+			&Struct{Fields: []Field{{"bar", Integer(2)}, {"foo", Integer(3)}}},
+		},
+		{
+			// OBJECT_SET({foo:1}, 'bar', MISSING) => {foo:1}
+			Call(ObjectSet, &Struct{Fields: []Field{{"foo", Integer(1)}}}, String("bar"), Missing{}),
+			&Struct{Fields: []Field{{"foo", Integer(1)}}},
+		},
+		{
+			// OBJECT_SET(x, 'foo', 3) is unchanged
+			Call(ObjectSet, path("x"), String("foo"), Integer(3)),
+			Call(ObjectSet, path("x"), String("foo"), Integer(3)),
+		},
+		{
+			// OBJECT_DELETE({foo:1, bar:2}, 'foo') => {bar:2}
+			Call(ObjectDelete, &Struct{Fields: []Field{{"foo", Integer(1)}, {"bar", Integer(2)}}}, String("foo")),
+			&Struct{Fields: []Field{{"bar", Integer(2)}}},
+		},
+		{
+			// OBJECT_MERGE({foo:1, bar:2}, {bar:3, baz:4}) => {foo:1, bar:3, baz:4}
+			Call(ObjectMerge,
+				&Struct{Fields: []Field{{"foo", Integer(1)}, {"bar", Integer(2)}}},
+				&Struct{Fields: []Field{{"bar", Integer(3)}, {"baz", Integer(4)}}}),
+			&Struct{Fields: []Field{{"foo", Integer(1)}, {"bar", Integer(3)}, {"baz", Integer(4)}}},
+		},
 		{
 			// x+0 is *not* the same as x unless
 			// x can be proven to always be a number,
@@ -598,6 +626,31 @@ func TestSimplify(t *testing.T) {
 			Call(Concat, String("xyz"), String("abc")),
 			String("xyzabc"),
 		},
+		{
+			// CONCAT_WS(', ', 'a', NULL, 'b', MISSING) => 'a, b'
+			Call(ConcatWS, String(", "), String("a"), Null{}, String("b"), Missing{}),
+			String("a, b"),
+		},
+		{
+			// CONCAT_WS(NULL, x) => MISSING
+			Call(ConcatWS, Null{}, path("x")),
+			Missing{},
+		},
+		{
+			// CONCAT_WS('-', 'a', 'b', x, NULL) => CONCAT_WS('-', 'a-b', x)
+			Call(ConcatWS, String("-"), String("a"), String("b"), path("x"), Null{}),
+			Call(ConcatWS, String("-"), String("a-b"), path("x")),
+		},
+		{
+			// CONCAT_WS(x, 'a', NULL) => CONCAT_WS(x, 'a')
+			Call(ConcatWS, path("x"), String("a"), Null{}),
+			Call(ConcatWS, path("x"), String("a")),
+		},
+		{
+			// CONCAT_WS('-', NULL) => ''
+			Call(ConcatWS, String("-"), Null{}),
+			String(""),
+		},
 		{
 			Count(casen(Is(path("x"), IsNotMissing), Null{}, Missing{})),
 			Count(casen(Is(path("x"), IsNotMissing), Null{}, Missing{})),
@@ -705,6 +758,106 @@ func TestSimplify(t *testing.T) {
 			DateAdd(Minute, Integer(1), ts("2017-01-02T03:04:05.006Z")),
 			ts("2017-01-02T03:05:05.006Z"),
 		},
+		{
+			Call(FromUnixTime, Integer(1483326245)),
+			ts("2017-01-02T03:04:05Z"),
+		},
+		{
+			Call(FromUnixTime, Integer(1483326245), Integer(-1)),
+			ts("2017-01-02T03:04:04.999Z"),
+		},
+		{
+			Call(ToUnixTime, ts("2017-01-02T03:04:05.999Z")),
+			Integer(1483326245),
+		},
+		{
+			Call(ToUnixTime, path("t")),
+			Call(ToUnixEpoch, path("t")),
+		},
+		{
+			Call(ToUnixTimeMillis, ts("2017-01-02T03:04:05.999Z")),
+			Integer(1483326245999),
+		},
+		{
+			Call(ToUnixTimeMillis, path("t")),
+			DateDiff(Millisecond, ts("1970-01-01T00:00:00Z"), DateTrunc(Millisecond, path("t"))),
+		},
+		{
+			Call(ToUnixTimeMillis, ts("1969-12-31T23:59:59.9995Z")),
+			Integer(-1),
+		},
+		{
+			// only FROM_UNIXTIME(x) is compared as an integer
+			Compare(Less, DateAdd(Second, path("x"), ts("2017-01-01T00:00:00Z")), ts("2017-01-02T03:04:05Z")),
+			Compare(Less, DateAdd(Second, path("x"), ts("2017-01-01T00:00:00Z")), ts("2017-01-02T03:04:05Z")),
+		},
+		{
+			// FROM_UNIXTIME(x) < `2017-01-02T03:04:05.5Z` -> x < 1483326246
+			Compare(Less, Call(FromUnixTime, path("x")), ts("2017-01-02T03:04:05.5Z")),
+			Compare(Less, path("x"), Integer(1483326246)),
+		},
+		{
+			// FROM_UNIXTIME(x) <= `2017-01-02T03:04:05.5Z` -> x <= 1483326245
+			Compare(LessEquals, Call(FromUnixTime, path("x")), ts("2017-01-02T03:04:05.5Z")),
+			Compare(LessEquals, path("x"), Integer(1483326245)),
+		},
+		{
+			// FROM_UNIXTIME(x) > `1969-12-31T23:59:59.5Z` -> x > -1
+			Compare(Greater, Call(FromUnixTime, path("x")), ts("1969-12-31T23:59:59.5Z")),
+			Compare(Greater, path("x"), Integer(-1)),
+		},
+		{
+			// FROM_UNIXTIME(x) >= `1969-12-31T23:59:59.5Z` -> x >= 0
+			Compare(GreaterEquals, Call(FromUnixTime, path("x")), ts("1969-12-31T23:59:59.5Z")),
+			Compare(GreaterEquals, path("x"), Integer(0)),
+		},
+		{
+			Compare(Equals, Call(FromUnixTime, path("x")), ts("2017-01-02T03:04:05Z")),
+			Compare(Equals, path("x"), Integer(1483326245)),
+		},
+		{
+			// not a whole number of seconds; left as-is
+			Compare(Equals, Call(FromUnixTime, path("x")), ts("2017-01-02T03:04:05.5Z")),
+			Compare(Equals, DateAdd(Second, path("x"), ts("1970-01-01T00:00:00Z")), ts("2017-01-02T03:04:05.5Z")),
+		},
+		{
+			Call(FormatDuration, Integer(5405250)),
+			String("1h30m5s250ms"),
+		},
+		{
+			Call(FormatDuration, Integer(-61000)),
+			String("-1m1s"),
+		},
+		{
+			Call(FormatDuration, Integer(0)),
+			String("0ms"),
+		},
+		{
+			// -math.MinInt64 overflows
+			Call(FormatDuration, Integer(math.MinInt64)),
+			String("-2562047788015h12m55s808ms"),
+		},
+		{
+			Call(ParseDuration, String("1h30m")),
+			Integer(5400000),
+		},
+		{
+			Call(ParseDuration, String("-1.5s")),
+			Integer(-1500),
+		},
+		{
+			// the output of FORMAT_DURATION round-trips
+			Call(ParseDuration, Call(FormatDuration, Integer(5405250))),
+			Integer(5405250),
+		},
+		{
+			Call(Annotations, path("x")),
+			path("$annotations", "x"),
+		},
+		{
+			Call(Annotations, path("x", "y", "z")),
+			path("x", "y", "$annotations", "z"),
+		},
 		{
 			Call(Upper, String("sneller")),
 			String("SNELLER"),
@@ -992,6 +1145,21 @@ func TestSimplify(t *testing.T) {
 			&Index{Inner: mktestlist(String("a"), String("b"), String("c")), Offset: 1},
 			String("b"),
 		},
+		{
+			// ["a", "b", "c"][-1] => "c"
+			&Index{Inner: Call(MakeList, String("a"), String("b"), String("c")), Offset: -1},
+			String("c"),
+		},
+		{
+			// ["a", "b", "c"][-4] => MISSING
+			&Index{Inner: mktestlist(String("a"), String("b"), String("c")), Offset: -4},
+			Missing{},
+		},
+		{
+			// ARRAY_ELEMENT(x, 2) => x[2]
+			Call(ArrayElement, path("x"), Integer(2)),
+			&Index{Inner: path("x"), Offset: 2},
+		},
 		{
 			// SELECT * FROM ... ORDER BY const1, ..., constN => drop ORDER BY
 			&Select{OrderBy: []Order{
@@ -1149,6 +1317,143 @@ func TestSimplify(t *testing.T) {
 	}
 }
 
+func TestSimplifyLogicRanges(t *testing.T) {
+	eqchain := func(n int) Node {
+		var top Node
+		for i := 0; i < n; i++ {
+			eq := Compare(Equals, path("x"), Integer(i))
+			if top == nil {
+				top = eq
+			} else {
+				top = Or(top, eq)
+			}
+		}
+		return top
+	}
+	members := func(n int) Node {
+		var lst []Node
+		for i := 0; i < n; i++ {
+			lst = append(lst, Integer(i))
+		}
+		return In(path("x"), lst...)
+	}
+	testcases := []struct {
+		before, after Node
+	}{
+		{
+			// x > 1 AND x > 3 -> x > 3
+			And(Compare(Greater, path("x"), Integer(1)), Compare(Greater, path("x"), Integer(3))),
+			Compare(Greater, path("x"), Integer(3)),
+		},
+		{
+			// x <= 9 AND x >= 3 -> x BETWEEN 3 AND 9
+			And(Compare(LessEquals, path("x"), Integer(9)), Compare(GreaterEquals, path("x"), Integer(3))),
+			Between(path("x"), Integer(3), Integer(9)),
+		},
+		{
+			// 9 >= x AND x >= 3 -> x BETWEEN 3 AND 9
+			And(Compare(GreaterEquals, Integer(9), path("x")), Compare(GreaterEquals, path("x"), Integer(3))),
+			Between(path("x"), Integer(3), Integer(9)),
+		},
+		{
+			// x BETWEEN 3 AND 9 -> no change
+			Between(path("x"), Integer(3), Integer(9)),
+			Between(path("x"), Integer(3), Integer(9)),
+		},
+		{
+			// x > 5 AND x < 3 -> FALSE
+			And(Compare(Greater, path("x"), Integer(5)), Compare(Less, path("x"), Integer(3))),
+			Bool(false),
+		},
+		{
+			// x >= 3 AND x <= 3.0 -> x = 3
+			And(Compare(GreaterEquals, path("x"), Integer(3)), Compare(LessEquals, path("x"), Float(3))),
+			Compare(Equals, path("x"), Integer(3)),
+		},
+		{
+			// x > 1 AND y = 2 AND x > 3 -> x > 3 AND y = 2
+			And(And(Compare(Greater, path("x"), Integer(1)), Compare(Equals, path("y"), Integer(2))),
+				Compare(Greater, path("x"), Integer(3))),
+			And(Compare(Greater, path("x"), Integer(3)), Compare(Equals, path("y"), Integer(2))),
+		},
+		{
+			// t >= ts1 AND t >= ts2 -> t >= ts2
+			And(Compare(GreaterEquals, path("t"), ts("2022-01-01T00:00:00Z")),
+				Compare(GreaterEquals, path("t"), ts("2022-01-02T00:00:00Z"))),
+			Compare(GreaterEquals, path("t"), ts("2022-01-02T00:00:00Z")),
+		},
+		{
+			// x > 1 AND x > ts -> no change (different kinds)
+			And(Compare(Greater, path("x"), Integer(1)), Compare(Greater, path("x"), ts("2022-01-01T00:00:00Z"))),
+			And(Compare(Greater, path("x"), Integer(1)), Compare(Greater, path("x"), ts("2022-01-01T00:00:00Z"))),
+		},
+		{
+			// x < 3 OR x BETWEEN 1 AND 5 -> x <= 5
+			Or(Compare(Less, path("x"), Integer(3)), Between(path("x"), Integer(1), Integer(5))),
+			Compare(LessEquals, path("x"), Integer(5)),
+		},
+		{
+			// x = 1 OR x > 1 -> x >= 1
+			Or(Compare(Equals, path("x"), Integer(1)), Compare(Greater, path("x"), Integer(1))),
+			Compare(GreaterEquals, path("x"), Integer(1)),
+		},
+		{
+			// x BETWEEN 1 AND 3 OR x BETWEEN 2 AND 5 OR x BETWEEN 7 AND 8
+			// -> x BETWEEN 1 AND 5 OR x BETWEEN 7 AND 8
+			Or(Or(Between(path("x"), Integer(1), Integer(3)), Between(path("x"), Integer(2), Integer(5))),
+				Between(path("x"), Integer(7), Integer(8))),
+			Or(Between(path("x"), Integer(1), Integer(5)), Between(path("x"), Integer(7), Integer(8))),
+		},
+		{
+			// x < 1 OR x > 0 -> no change (unbounded)
+			Or(Compare(Less, path("x"), Integer(1)), Compare(Greater, path("x"), Integer(0))),
+			Or(Compare(Less, path("x"), Integer(1)), Compare(Greater, path("x"), Integer(0))),
+		},
+		{
+			// x = 0 OR ... OR x = 9 -> x IN (0, ..., 9)
+			eqchain(10),
+			members(10),
+		},
+		{
+			// x = 0 OR ... OR x = 8 -> no change
+			eqchain(9),
+			eqchain(9),
+		},
+		{
+			// x = 0 OR ... OR x = 8 OR y = 1 OR x = 9
+			// -> x IN (0, ..., 9) OR y = 1
+			Or(Or(eqchain(9), Compare(Equals, path("y"), Integer(1))), Compare(Equals, path("x"), Integer(9))),
+			Or(members(10), Compare(Equals, path("y"), Integer(1))),
+		},
+		{
+			// x IN (0, ..., 9) OR x = 10 OR x = 3 -> x IN (0, ..., 10)
+			Or(Or(members(10), Compare(Equals, path("x"), Integer(10))), Compare(Equals, path("x"), Integer(3))),
+			members(11),
+		},
+	}
+
+	for i := range testcases {
+		tc := &testcases[i]
+
+		t.Run(fmt.Sprintf("case-%d", i), func(t *testing.T) {
+			before := Copy(tc.before)
+			after := tc.after
+			opt := SimplifyLogic(before, NoHint)
+			if !opt.Equals(after) {
+				t.Logf("original:   %q (%T)", ToString(tc.before), tc.before)
+				t.Logf("simplified: %q (%T)", ToString(opt), opt)
+				t.Logf("wanted:     %q (%T)", ToString(after), after)
+				t.Errorf("wrong simplification result")
+			}
+			// the result should be a fixed point
+			again := SimplifyLogic(Copy(opt), NoHint)
+			if !again.Equals(opt) {
+				t.Errorf("not a fixed point: %q -> %q", ToString(opt), ToString(again))
+			}
+		})
+	}
+}
+
 // check cases when ret() might return nil
 func TestSimplifyWithNaN(t *testing.T) {
 	expressions := []Node{
@@ -1189,6 +1494,41 @@ func TestSimplifyWithNaN(t *testing.T) {
 	}
 }
 
+// TestSimplifyISOWeekDate checks constant folding of
+// ISO 8601 week-date parts for every day around
+// January 1 using the "week 1 contains January 4" rule
+func TestSimplifyISOWeekDate(t *testing.T) {
+	isodow := func(tm time.Time) int {
+		return (int(tm.Weekday())+6)%7 + 1
+	}
+	// week1 returns the Monday that starts week 1 of year
+	week1 := func(year int) time.Time {
+		jan4 := time.Date(year, 1, 4, 0, 0, 0, 0, time.UTC)
+		return jan4.AddDate(0, 0, 1-isodow(jan4))
+	}
+	for year := 1900; year <= 2400; year++ {
+		start := time.Date(year-1, 12, 22, 0, 0, 0, 0, time.UTC)
+		for d := 0; d < 20; d++ {
+			day := start.AddDate(0, 0, d)
+			want := day.Year()
+			if day.Before(week1(want)) {
+				want--
+			} else if !day.Before(week1(want + 1)) {
+				want++
+			}
+			stamp := &Timestamp{Value: date.FromTime(day.Add(23*time.Hour + 59*time.Minute))}
+			got := Simplify(DateExtract(ISOYear, stamp), NoHint)
+			if got != Integer(want) {
+				t.Fatalf("ISOYEAR of %s: got %s, want %d", day.Format("2006-01-02"), ToString(got), want)
+			}
+			got = Simplify(DateExtract(ISODOW, stamp), NoHint)
+			if got != Integer(isodow(day)) {
+				t.Fatalf("ISODOW of %s: got %s, want %d", day.Format("2006-01-02"), ToString(got), isodow(day))
+			}
+		}
+	}
+}
+
 func testEquivalence(e Node, t *testing.T) {
 	t.Helper()
 
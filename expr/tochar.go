@@ -0,0 +1,494 @@
+// Copyright (C) 2022 Sneller, Inc.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package expr
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// defaultLocale is used when no locale is provided
+const defaultLocale = "en"
+
+// caseForm is the capitalization of a name
+// in a template, as in 'MONTH', 'Month' or 'month'
+type caseForm int
+
+const (
+	upperCase caseForm = iota
+	titleCase
+	lowerCase
+)
+
+func (c caseForm) apply(s string) string {
+	switch c {
+	case upperCase:
+		return strings.ToUpper(s)
+	case lowerCase:
+		return strings.ToLower(s)
+	}
+	r, size := utf8.DecodeRuneInString(s)
+	return string(unicode.ToUpper(r)) + strings.ToLower(s[size:])
+}
+
+// caseOf determines the caseForm of a pattern
+// from its first two letters
+func caseOf(pat string) caseForm {
+	if unicode.IsLower(rune(pat[0])) {
+		return lowerCase
+	}
+	if unicode.IsLower(rune(pat[1])) {
+		return titleCase
+	}
+	return upperCase
+}
+
+// zeroPad returns the decimal representation of the
+// non-negative integer e zero-padded to the given
+// number of digits (e must be less than 10^digits)
+func zeroPad(e Node, digits int) Node {
+	str := &Cast{From: Add(Integer(pow10(digits)), e), To: StringType}
+	return Call(Substring, str, Integer(2))
+}
+
+func pow10(n int) int64 {
+	out := int64(1)
+	for i := 0; i < n; i++ {
+		out *= 10
+	}
+	return out
+}
+
+// nameOf yields names[n-first] for the integer n
+func nameOf(n Node, first int, names []string, form caseForm) Node {
+	c := &Case{}
+	for i := range names {
+		c.Limbs = append(c.Limbs, CaseLimb{
+			When: Compare(Equals, Copy(n), Integer(first+i)),
+			Then: String(form.apply(names[i])),
+		})
+	}
+	return c
+}
+
+// toCharPattern is a template pattern accepted by TO_CHAR;
+// it is replaced either by a component of the timestamp
+// (plus offset, modulo mod if mod is non-zero) that is
+// zero-padded to the given number of digits or by
+// the name of that component
+type toCharPattern struct {
+	pat    string
+	part   Timepart
+	mod    int64
+	offset int64
+	digits int
+	names  func(l *locale) []string
+}
+
+// toCharPatterns are the template patterns
+// accepted by TO_CHAR; patterns that are prefixes
+// of other patterns must follow them
+var toCharPatterns = []toCharPattern{
+	{pat: "YYYY", part: Year, digits: 4},
+	{pat: "YY", part: Year, mod: 100, digits: 2},
+	{pat: "MONTH", part: Month, names: func(l *locale) []string { return l.months[:] }},
+	{pat: "MON", part: Month, names: func(l *locale) []string { return l.monthsAbbr[:] }},
+	{pat: "MM", part: Month, digits: 2},
+	{pat: "MI", part: Minute, digits: 2},
+	{pat: "MS", part: Millisecond, mod: 1000, digits: 3},
+	{pat: "DAY", part: DOW, names: func(l *locale) []string { return l.days[:] }},
+	{pat: "DDD", part: DOY, digits: 3},
+	{pat: "DD", part: Day, digits: 2},
+	{pat: "DY", part: DOW, names: func(l *locale) []string { return l.daysAbbr[:] }},
+	{pat: "D", part: DOW, offset: 1},
+	{pat: "HH24", part: Hour, digits: 2},
+	{pat: "SS", part: Second, digits: 2},
+	{pat: "US", part: Microsecond, mod: 1000000, digits: 6},
+}
+
+// first returns the value of the first name
+func (p *toCharPattern) first() int {
+	if p.part == DOW {
+		return 0
+	}
+	return 1
+}
+
+// value returns the component of a constant timestamp
+func (p *toCharPattern) value(t time.Time) int64 {
+	var v int64
+	switch p.part {
+	case Year:
+		v = int64(t.Year())
+	case Month:
+		v = int64(t.Month())
+	case Day:
+		v = int64(t.Day())
+	case DOW:
+		v = int64(t.Weekday())
+	case DOY:
+		v = int64(t.YearDay())
+	case Hour:
+		v = int64(t.Hour())
+	case Minute:
+		v = int64(t.Minute())
+	case Second:
+		v = int64(t.Second())
+	case Millisecond:
+		v = int64(t.Nanosecond() / 1000000)
+	case Microsecond:
+		v = int64(t.Nanosecond() / 1000)
+	}
+	if p.mod != 0 {
+		v %= p.mod
+	}
+	return v + p.offset
+}
+
+// format formats the component of a constant timestamp
+func (p *toCharPattern) format(t time.Time, l *locale, form caseForm) string {
+	v := p.value(t)
+	if p.names != nil {
+		return form.apply(p.names(l)[int(v)-p.first()])
+	}
+	str := strconv.FormatInt(v, 10)
+	for len(str) < p.digits {
+		str = "0" + str
+	}
+	return str
+}
+
+// expr produces the expression that
+// formats the component of ts
+func (p *toCharPattern) expr(ts Node, l *locale, form caseForm) Node {
+	v := DateExtract(p.part, ts)
+	if p.mod != 0 {
+		v = Mod(v, Integer(p.mod))
+	}
+	if p.offset != 0 {
+		v = Add(v, Integer(p.offset))
+	}
+	if p.names != nil {
+		return nameOf(v, p.first(), p.names(l), form)
+	}
+	if p.digits != 0 {
+		return zeroPad(v, p.digits)
+	}
+	return &Cast{From: v, To: StringType}
+}
+
+// toChar lowers TO_CHAR(ts, tmpl) into the
+// concatenation of its parts (or a string
+// if ts is a constant)
+func toChar(ts Node, tmpl string, loc *locale) (Node, error) {
+	var parts []Node
+	var lit strings.Builder
+	flush := func() {
+		if lit.Len() > 0 {
+			parts = append(parts, String(lit.String()))
+			lit.Reset()
+		}
+	}
+	constant, isConstant := ts.(*Timestamp)
+outer:
+	for len(tmpl) > 0 {
+		if tmpl[0] == '"' {
+			// quoted text is copied literally
+			end := strings.IndexByte(tmpl[1:], '"')
+			if end < 0 {
+				return nil, errsyntaxf("%s: unterminated quoted text in template", ToChar)
+			}
+			lit.WriteString(tmpl[1 : end+1])
+			tmpl = tmpl[end+2:]
+			continue
+		}
+		for i := range toCharPatterns {
+			p := &toCharPatterns[i]
+			if len(tmpl) >= len(p.pat) && strings.EqualFold(tmpl[:len(p.pat)], p.pat) {
+				form := upperCase
+				if len(p.pat) > 1 {
+					form = caseOf(tmpl)
+				}
+				if isConstant {
+					lit.WriteString(p.format(constant.Value.Time(), loc, form))
+				} else {
+					flush()
+					parts = append(parts, p.expr(Copy(ts), loc, form))
+				}
+				tmpl = tmpl[len(p.pat):]
+				continue outer
+			}
+		}
+		// like PostgreSQL, copy anything
+		// that is not a pattern literally
+		_, size := utf8.DecodeRuneInString(tmpl)
+		lit.WriteString(tmpl[:size])
+		tmpl = tmpl[size:]
+	}
+	flush()
+	if len(parts) == 0 {
+		return String(""), nil
+	}
+	out := parts[0]
+	for _, p := range parts[1:] {
+		out = Call(Concat, out, p)
+	}
+	return out, nil
+}
+
+// localeArg returns the locale named by args[i],
+// or the default locale if there are only i arguments
+func localeArg(op BuiltinOp, args []Node, i int) (*locale, error) {
+	name := String(defaultLocale)
+	if len(args) > i {
+		s, ok := args[i].(String)
+		if !ok {
+			return nil, errsyntaxf("%s requires a constant locale string", op)
+		}
+		name = s
+	}
+	loc, ok := lookupLocale(string(name))
+	if !ok {
+		return nil, errsyntaxf("%s: unsupported locale %q", op, string(name))
+	}
+	return loc, nil
+}
+
+func checkToChar(h Hint, args []Node) error {
+	if len(args) != 2 && len(args) != 3 {
+		return errsyntaxf("%s expects 2 or 3 arguments, but found %d", ToChar, len(args))
+	}
+	if !TypeOf(args[0], h).AnyOf(TimeType) {
+		return errtype(args[0], "not a timestamp")
+	}
+	tmpl, ok := args[1].(String)
+	if !ok {
+		return errsyntaxf("%s requires a constant template string", ToChar)
+	}
+	loc, err := localeArg(ToChar, args, 2)
+	if err != nil {
+		return err
+	}
+	_, err = toChar(args[0], string(tmpl), loc)
+	return err
+}
+
+// simplifyToChar lowers TO_CHAR(ts, template [, locale]),
+// which formats a timestamp according to a constant
+// template string that uses a subset of the PostgreSQL
+// template patterns, into a concatenation of the
+// components of ts that the template uses, so it is
+// only as expensive as those components.
+// Month and day names are taken from the locale
+// (see lookupLocale), which defaults to the one set
+// by Query.SetLocale or to English.
+func simplifyToChar(h Hint, args []Node) Node {
+	if len(args) != 2 && len(args) != 3 {
+		return nil
+	}
+	tmpl, ok := args[1].(String)
+	if !ok {
+		return nil
+	}
+	loc, err := localeArg(ToChar, args, 2)
+	if err != nil {
+		return nil
+	}
+	ret, err := toChar(args[0], string(tmpl), loc)
+	if err != nil {
+		return nil
+	}
+	return Simplify(ret, h)
+}
+
+// maxFormatDecimals is the largest number
+// of decimal places accepted by FORMAT
+const maxFormatDecimals = 9
+
+func checkFormat(h Hint, args []Node) error {
+	if len(args) != 2 && len(args) != 3 {
+		return errsyntaxf("%s expects 2 or 3 arguments, but found %d", Format, len(args))
+	}
+	if !TypeOf(args[0], h).AnyOf(NumericType) {
+		return errtype(args[0], "not a number")
+	}
+	d, ok := args[1].(Integer)
+	if !ok || d < 0 || d > maxFormatDecimals {
+		return errsyntaxf("%s requires a constant number of decimals between 0 and %d", Format, maxFormatDecimals)
+	}
+	_, err := localeArg(Format, args, 2)
+	return err
+}
+
+// formatNumber formats the non-negative integer n
+// (which is the absolute value of the input scaled
+// by 10^decimals) like FORMAT
+func formatNumber(neg bool, n uint64, decimals int, l *locale) string {
+	scale := uint64(pow10(decimals))
+	digits := strconv.FormatUint(n/scale, 10)
+	var b strings.Builder
+	if neg && n != 0 {
+		b.WriteByte('-')
+	}
+	for i := range digits {
+		if i > 0 && (len(digits)-i)%3 == 0 {
+			b.WriteString(l.group)
+		}
+		b.WriteByte(digits[i])
+	}
+	if decimals > 0 {
+		b.WriteString(l.decimal)
+		frac := strconv.FormatUint(n%scale, 10)
+		b.WriteString(strings.Repeat("0", decimals-len(frac)))
+		b.WriteString(frac)
+	}
+	return b.String()
+}
+
+// formatLimit is the bound on the magnitude of
+// the input of FORMAT scaled by 10^decimals
+const formatLimit = 9e18
+
+func formatFloat(x float64, decimals int, l *locale) Node {
+	f := math.Abs(x) * float64(pow10(decimals))
+	if !(f < formatLimit) {
+		return Missing{}
+	}
+	return String(formatNumber(x < 0, uint64(math.Round(f)), decimals, l))
+}
+
+// groupDigits formats the non-negative integer n
+// with sep between each group of three digits
+func groupDigits(n Node, sep string) Node {
+	// the largest int64 has seven groups
+	const groups = 7
+	c := &Case{}
+	for k := 1; k <= groups; k++ {
+		out := Node(&Cast{From: Div(Copy(n), Integer(pow10(3*(k-1)))), To: StringType})
+		for j := k - 1; j >= 1; j-- {
+			g := Mod(Div(Copy(n), Integer(pow10(3*(j-1)))), Integer(1000))
+			out = Call(Concat, Call(Concat, out, String(sep)), zeroPad(g, 3))
+		}
+		if k == groups {
+			c.Else = out
+			break
+		}
+		c.Limbs = append(c.Limbs, CaseLimb{
+			When: Compare(Less, Copy(n), Integer(pow10(3*k))),
+			Then: out,
+		})
+	}
+	return c
+}
+
+// simplifyFormat lowers FORMAT(x, decimals [, locale]),
+// which formats a number with grouping separators and
+// the given constant number of decimal places (like
+// MySQL's FORMAT), into a concatenation of its digit
+// groups. The decimal and grouping separators are taken
+// from the locale, like the names used by TO_CHAR.
+// (For printf-style formatting, see PRINTF.)
+func simplifyFormat(h Hint, args []Node) Node {
+	if len(args) != 2 && len(args) != 3 {
+		return nil
+	}
+	d, ok := args[1].(Integer)
+	if !ok || d < 0 || d > maxFormatDecimals {
+		return nil
+	}
+	loc, err := localeArg(Format, args, 2)
+	if err != nil {
+		return nil
+	}
+	x := args[0]
+	scale := pow10(int(d))
+	switch x := x.(type) {
+	case Integer:
+		n := x
+		if n < 0 {
+			n = -n
+		}
+		if float64(n)*float64(scale) >= formatLimit {
+			return Missing{}
+		}
+		return String(formatNumber(x < 0, uint64(n)*uint64(scale), int(d), loc))
+	case Float:
+		return formatFloat(float64(x), int(d), loc)
+	case *Rational:
+		f, _ := x.rat().Float64()
+		return formatFloat(f, int(d), loc)
+	}
+	// n is |x| scaled by 10^d and rounded
+	// to the nearest integer
+	abs := Call(Abs, x)
+	var n Node
+	if TypeOf(x, h)&^(IntegerType|MissingType) == 0 {
+		n = Mul(abs, Integer(scale))
+	} else {
+		n = &Cast{From: Call(Round, Mul(abs, Float(float64(scale)))), To: IntegerType}
+	}
+	// scaled values that do not fit in
+	// an integer (and non-numbers) produce MISSING
+	fits := Compare(Less, Copy(abs), Float(formatLimit/float64(scale)))
+	sign := &Case{
+		Limbs: []CaseLimb{
+			{When: And(fits, And(Compare(Less, Copy(x), Integer(0)), Compare(NotEquals, n, Integer(0)))), Then: String("-")},
+			{When: Copy(fits), Then: String("")},
+		},
+		Else: Missing{},
+	}
+	var ret Node
+	if d == 0 {
+		ret = Call(Concat, sign, groupDigits(n, loc.group))
+	} else {
+		ret = Call(Concat, sign, groupDigits(Div(Copy(n), Integer(scale)), loc.group))
+		ret = Call(Concat, ret, String(loc.decimal))
+		ret = Call(Concat, ret, zeroPad(Mod(Copy(n), Integer(scale)), int(d)))
+	}
+	return Simplify(ret, h)
+}
+
+type localeRewriter struct {
+	locale string
+}
+
+func (l *localeRewriter) Walk(Node) Rewriter { return l }
+
+func (l *localeRewriter) Rewrite(n Node) Node {
+	b, ok := n.(*Builtin)
+	if !ok || (b.Func != ToChar && b.Func != Format) || len(b.Args) != 2 {
+		return n
+	}
+	return Call(b.Func, b.Args[0], b.Args[1], String(l.locale))
+}
+
+// SetLocale sets the locale (a language tag like
+// 'de' or 'de_CH') used by every TO_CHAR and FORMAT
+// call in q that does not specify it explicitly.
+func (q *Query) SetLocale(name string) error {
+	if _, ok := lookupLocale(name); !ok {
+		return errsyntaxf("unsupported locale %q", name)
+	}
+	r := &localeRewriter{locale: name}
+	for i := range q.With {
+		q.With[i].As = Rewrite(r, q.With[i].As).(*Select)
+	}
+	q.Body = Rewrite(r, q.Body)
+	return nil
+}
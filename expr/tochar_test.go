@@ -0,0 +1,148 @@
+// Copyright (C) 2022 Sneller, Inc.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package expr_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/SnellerInc/sneller/date"
+	"github.com/SnellerInc/sneller/expr"
+	"github.com/SnellerInc/sneller/expr/partiql"
+)
+
+func TestToCharConstant(t *testing.T) {
+	// every day of 2024 (a leap year)
+	day := time.Date(2024, 1, 1, 13, 4, 5, 6007000, time.UTC)
+	for day.Year() < 2025 {
+		ts := &expr.Timestamp{Value: date.FromTime(day)}
+		got := expr.Simplify(expr.Call(expr.ToChar, ts, expr.String(`Dy, DD Mon YYYY HH24:MI:SS.US "day" DDD`)), expr.NoHint)
+		want := day.Format("Mon, 02 Jan 2006 15:04:05.000000") + " day " + day.Format("002")
+		if got != expr.String(want) {
+			t.Fatalf("got %s, want %q", expr.ToString(got), want)
+		}
+		day = day.AddDate(0, 0, 1)
+	}
+
+	ts := &expr.Timestamp{Value: date.Date(2021, 3, 4, 5, 6, 7, 0)}
+	testcases := []struct {
+		tmpl, locale, want string
+	}{
+		{"Day, DD. Month YYYY", "de", "Donnerstag, 04. März 2021"},
+		{"DAY DD MONTH", "de_AT", "DONNERSTAG 04 MÄRZ"},
+		{"day dd month yy", "fr-FR", "jeudi 04 mars 21"},
+		{"Dy Mon", "es", "Jue Mar"},
+		{"Day", "pt_BR", "Quinta-feira"},
+		{"D MM", "sv", "5 03"},
+		{"", "en", ""},
+	}
+	for i := range testcases {
+		tc := &testcases[i]
+		got := expr.Simplify(expr.Call(expr.ToChar, ts, expr.String(tc.tmpl), expr.String(tc.locale)), expr.NoHint)
+		if got != expr.String(tc.want) {
+			t.Errorf("TO_CHAR(%q, %q): got %s, want %q", tc.tmpl, tc.locale, expr.ToString(got), tc.want)
+		}
+	}
+}
+
+func TestFormatConstant(t *testing.T) {
+	testcases := []struct {
+		x        expr.Node
+		decimals int
+		locale   string
+		want     expr.Node
+	}{
+		{expr.Float(1234567.891), 2, "en", expr.String("1,234,567.89")},
+		{expr.Float(-1234567.891), 2, "de_DE", expr.String("-1.234.567,89")},
+		{expr.Float(1234.5), 0, "de_CH", expr.String("1’235")},
+		{expr.Float(1234.5), 1, "fr", expr.String("1\u202f234,5")},
+		{expr.Float(-0.004), 2, "en", expr.String("0.00")},
+		{expr.Integer(-1000000), 0, "sv", expr.String("-1\u00a0000\u00a0000")},
+		{expr.Integer(999), 3, "es_MX", expr.String("999.000")},
+		{expr.Integer(100), 0, "es", expr.String("100")},
+		{expr.Float(1e30), 0, "en", expr.Missing{}},
+	}
+	for i := range testcases {
+		tc := &testcases[i]
+		e := expr.Call(expr.Format, tc.x, expr.Integer(tc.decimals), expr.String(tc.locale))
+		got := expr.Simplify(e, expr.NoHint)
+		if !got.Equals(tc.want) {
+			t.Errorf("%s: got %s, want %s", expr.ToString(e), expr.ToString(got), expr.ToString(tc.want))
+		}
+	}
+}
+
+func TestSetLocale(t *testing.T) {
+	testcases := []struct {
+		query, want string
+	}{
+		{
+			"SELECT TO_CHAR(x, 'Month'), TO_CHAR(x, 'Month', 'fr'), FORMAT(y, 2) FROM t",
+			"SELECT TO_CHAR(x, 'Month', 'de_CH'), TO_CHAR(x, 'Month', 'fr'), FORMAT(y, 2, 'de_CH') FROM t",
+		},
+		{
+			"WITH a AS (SELECT FORMAT(x, 0) AS f, y FROM t) SELECT * FROM a WHERE TO_CHAR(y, 'Dy') = 'Mo'",
+			"WITH a AS (SELECT FORMAT(x, 0, 'de_CH') AS f, y FROM t) SELECT * FROM a WHERE TO_CHAR(y, 'Dy', 'de_CH') = 'Mo'",
+		},
+	}
+	for i := range testcases {
+		q, err := partiql.Parse([]byte(testcases[i].query))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := q.SetLocale("de_CH"); err != nil {
+			t.Fatal(err)
+		}
+		if err := q.Check(); err != nil {
+			t.Fatal(err)
+		}
+		if got := expr.ToString(q); got != testcases[i].want {
+			t.Errorf("got  %s\nwant %s", got, testcases[i].want)
+		}
+	}
+	q, err := partiql.Parse([]byte("SELECT FORMAT(x, 2) FROM t"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := q.SetLocale("xx_YY"); err == nil {
+		t.Fatal("expected an error")
+	}
+}
+
+func TestToCharCheck(t *testing.T) {
+	bad := []string{
+		"SELECT TO_CHAR(x) FROM t",
+		"SELECT TO_CHAR(x, y) FROM t",
+		"SELECT TO_CHAR(x, 'YYYY', y) FROM t",
+		"SELECT TO_CHAR(x, 'YYYY', 'tlh') FROM t",
+		"SELECT TO_CHAR(x, 'YYYY \"unterminated') FROM t",
+		"SELECT TO_CHAR('foo', 'YYYY') FROM t",
+		"SELECT FORMAT(x) FROM t",
+		"SELECT FORMAT(x, y) FROM t",
+		"SELECT FORMAT(x, -1) FROM t",
+		"SELECT FORMAT(x, 10) FROM t",
+		"SELECT FORMAT(x, 2, 'xx') FROM t",
+		"SELECT FORMAT('foo', 2) FROM t",
+	}
+	for i := range bad {
+		q, err := partiql.Parse([]byte(bad[i]))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := q.Check(); err == nil {
+			t.Errorf("%s: expected an error", bad[i])
+		}
+	}
+}
@@ -0,0 +1,122 @@
+// Copyright (C) 2022 Sneller, Inc.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package expr
+
+import (
+	"github.com/SnellerInc/sneller/date"
+)
+
+// TO_UNIXTIME(ts) is an alias of TO_UNIX_EPOCH(ts),
+// TO_UNIXTIME_MILLIS(ts) is lowered into DATE_DIFF(MILLISECOND,
+// 1970-01-01T00:00:00Z, DATE_TRUNC(MILLISECOND, ts)),
+// and FROM_UNIXTIME(secs [, millis]) is lowered into
+// DATE_ADD(SECOND, secs, 1970-01-01T00:00:00Z), optionally
+// followed by DATE_ADD(MILLISECOND, millis, ...), so that
+// both functions share the vectorized implementation,
+// the constant folding and the sparse index pruning
+// of the functions they are rewritten into.
+
+var unixEpoch = &Timestamp{Value: date.Unix(0, 0)}
+
+func simplifyToUnixTime(h Hint, args []Node) Node {
+	if len(args) != 1 {
+		return nil
+	}
+	return Simplify(Call(ToUnixEpoch, args[0]), h)
+}
+
+func simplifyToUnixTimeMillis(h Hint, args []Node) Node {
+	if len(args) != 1 {
+		return nil
+	}
+	if ts, ok := args[0].(*Timestamp); ok {
+		ms, _ := epochUnits(unixEpoch, ts, 1000)
+		return ms
+	}
+	// truncate first so that the milliseconds are rounded
+	// towards negative infinity like TO_UNIX_EPOCH rounds seconds
+	return Simplify(DateDiff(Millisecond, unixEpoch, DateTrunc(Millisecond, args[0])), h)
+}
+
+func checkFromUnixTime(h Hint, args []Node) error {
+	if len(args) != 1 && len(args) != 2 {
+		return errsyntaxf("%s expects 1 or 2 arguments, but found %d", FromUnixTime, len(args))
+	}
+	for i := range args {
+		if !TypeOf(args[i], h).AnyOf(IntegerType) {
+			return errtype(args[i], "not an integer")
+		}
+	}
+	return nil
+}
+
+func simplifyFromUnixTime(h Hint, args []Node) Node {
+	var ret Node
+	switch len(args) {
+	case 1:
+		ret = DateAdd(Second, args[0], unixEpoch)
+	case 2:
+		ret = DateAdd(Millisecond, args[1], DateAdd(Second, args[0], unixEpoch))
+	default:
+		return nil
+	}
+	return Simplify(ret, h)
+}
+
+// epochSeconds returns the number of seconds
+// between two timestamps rounded towards negative
+// infinity, and whether the difference is
+// a whole number of seconds.
+//
+// It is used to rewrite comparisons of
+// FROM_UNIXTIME(x) (i.e. DATE_ADD(SECOND, x, base)
+// where base is the Unix epoch) against a constant
+// timestamp into comparisons of x against
+// a constant integer.
+func epochSeconds(base, ts *Timestamp) (Integer, bool) {
+	return epochUnits(base, ts, 1000000)
+}
+
+// epochUnits returns the number of units of the
+// given number of microseconds between two timestamps
+// rounded towards negative infinity, and whether the
+// difference is a whole number of units
+func epochUnits(base, ts *Timestamp, micros int64) (Integer, bool) {
+	d := ts.Value.UnixMicro() - base.Value.UnixMicro()
+	q, r := d/micros, d%micros
+	if r < 0 {
+		q--
+		r += micros
+	}
+	return Integer(q), r == 0
+}
+
+// isUnixEpoch returns whether ts is the Unix epoch
+func isUnixEpoch(ts *Timestamp) bool {
+	return ts.Value.Equal(unixEpoch.Value)
+}
+
+func epochSecondsFloor(base, ts *Timestamp) Integer {
+	s, _ := epochSeconds(base, ts)
+	return s
+}
+
+func epochSecondsCeil(base, ts *Timestamp) Integer {
+	s, exact := epochSeconds(base, ts)
+	if !exact {
+		s++
+	}
+	return s
+}
@@ -0,0 +1,105 @@
+// Copyright (C) 2022 Sneller, Inc.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package expr
+
+// WalkCtxFunc is the callback for WalkCtx.
+//
+// The ancestors of n are provided in order
+// from the root of the traversal to the
+// immediate parent of n. The ancestors slice
+// is only valid for the duration of the call.
+//
+// If the function returns false, the
+// children of n are not visited.
+type WalkCtxFunc func(n Node, ancestors []Node) bool
+
+// ctxwalker is a Visitor that
+// maintains the stack of ancestors
+type ctxwalker struct {
+	fn    WalkCtxFunc
+	stack []Node
+}
+
+func (w *ctxwalker) Visit(n Node) Visitor {
+	if n == nil {
+		// done with the children
+		// of the top of the stack
+		w.stack = w.stack[:len(w.stack)-1]
+		return nil
+	}
+	if !w.fn(n, w.stack) {
+		return nil
+	}
+	w.stack = append(w.stack, n)
+	return w
+}
+
+// WalkCtx traverses n in depth-first order
+// like Walk, but it also provides fn with
+// the path of ancestors of each node.
+func WalkCtx(n Node, fn WalkCtxFunc) {
+	Walk(&ctxwalker{fn: fn}, n)
+}
+
+// Parent returns the immediate parent
+// from a list of ancestors provided to
+// a WalkCtxFunc, or nil if there is none.
+func Parent(ancestors []Node) Node {
+	if len(ancestors) == 0 {
+		return nil
+	}
+	return ancestors[len(ancestors)-1]
+}
+
+// whererw is the Rewriter used by RewriteWhere
+type whererw struct {
+	r     Rewriter
+	match func(Node) bool
+	// hit is set when Walk finds a
+	// matching node; the next call to
+	// Rewrite is always for that node
+	hit bool
+}
+
+func (w *whererw) Walk(n Node) Rewriter {
+	if w.match(n) {
+		w.hit = true
+		return nil
+	}
+	return w
+}
+
+func (w *whererw) Rewrite(n Node) Node {
+	if w.hit {
+		w.hit = false
+		return Rewrite(w.r, n)
+	}
+	// leaf nodes are not passed to Walk
+	if _, ok := n.(nonleaf); !ok && w.match(n) {
+		return Rewrite(w.r, n)
+	}
+	return n
+}
+
+// RewriteWhere applies r to each of the
+// outermost subtrees of n for which match
+// returns true and returns the result.
+// Nodes outside of the matching subtrees
+// are traversed but not rewritten, so
+// match is never called on a node that
+// is inside a matching subtree.
+func RewriteWhere(r Rewriter, match func(Node) bool, n Node) Node {
+	return Rewrite(&whererw{r: r, match: match}, n)
+}
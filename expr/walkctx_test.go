@@ -0,0 +1,124 @@
+// Copyright (C) 2022 Sneller, Inc.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package expr
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestWalkCtx(t *testing.T) {
+	// x + UPPER(y) < 3
+	e := Compare(Less, Add(path("x"), Call(Upper, path("y"))), Integer(3))
+
+	var paths []string
+	WalkCtx(e, func(n Node, ancestors []Node) bool {
+		if id, ok := n.(Ident); ok {
+			var parts []string
+			for i := range ancestors {
+				parts = append(parts, ToString(ancestors[i]))
+			}
+			paths = append(paths, string(id)+": "+strings.Join(parts, " / "))
+		}
+		// don't descend into function calls
+		_, ok := n.(*Builtin)
+		return !ok
+	})
+	want := []string{
+		"x: x + UPPER(y) < 3 / x + UPPER(y)",
+	}
+	if len(paths) != len(want) {
+		t.Fatalf("got %q, want %q", paths, want)
+	}
+	for i := range want {
+		if paths[i] != want[i] {
+			t.Errorf("got %q, want %q", paths[i], want[i])
+		}
+	}
+
+	var parent Node
+	WalkCtx(e, func(n Node, ancestors []Node) bool {
+		if n == Ident("y") {
+			parent = Parent(ancestors)
+		}
+		return true
+	})
+	if b, ok := parent.(*Builtin); !ok || b.Func != Upper {
+		t.Errorf("unexpected parent %v", parent)
+	}
+	if Parent(nil) != nil {
+		t.Error("Parent(nil) != nil")
+	}
+}
+
+// renamer renames identifiers
+type renamer map[Ident]Ident
+
+func (r renamer) Walk(Node) Rewriter { return r }
+
+func (r renamer) Rewrite(n Node) Node {
+	if id, ok := n.(Ident); ok {
+		if to, ok := r[id]; ok {
+			return to
+		}
+	}
+	return n
+}
+
+func TestRewriteWhere(t *testing.T) {
+	r := renamer{"x": "y"}
+	inAgg := func(n Node) bool {
+		_, ok := n.(*Aggregate)
+		return ok
+	}
+	tcs := []struct {
+		match   func(Node) bool
+		in, out Node
+	}{
+		{
+			// only the aggregate is rewritten
+			match: inAgg,
+			in:    Add(path("x"), Sum(path("x"))),
+			out:   Add(path("x"), Sum(path("y"))),
+		},
+		{
+			// leaf nodes can match
+			match: func(n Node) bool { return n == Ident("x") },
+			in:    Add(path("x"), Sum(path("x"))),
+			out:   Add(path("y"), Sum(path("y"))),
+		},
+		{
+			match: func(Node) bool { return false },
+			in:    Add(path("x"), Sum(path("x"))),
+			out:   Add(path("x"), Sum(path("x"))),
+		},
+	}
+	for i := range tcs {
+		got := RewriteWhere(r, tcs[i].match, tcs[i].in)
+		if !got.Equals(tcs[i].out) {
+			t.Errorf("case %d: got %s, want %s", i, ToString(got), ToString(tcs[i].out))
+		}
+	}
+
+	// match is not called within a matching subtree
+	calls := 0
+	RewriteWhere(r, func(n Node) bool {
+		calls++
+		return inAgg(n)
+	}, Sum(Add(path("x"), path("x"))))
+	if calls != 1 {
+		t.Errorf("match called %d times", calls)
+	}
+}
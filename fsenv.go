@@ -15,9 +15,12 @@
 package sneller
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	"hash"
 	"io"
+	"io/fs"
 	"path"
 	"time"
 
@@ -38,6 +41,10 @@ type CachedEnv interface {
 type savedIndex struct {
 	db, table string
 	index     *blockfmt.Index
+	// hintErr is the error encountered
+	// while loading the type hints
+	// of the table, if any
+	hintErr error
 }
 
 type savedList struct {
@@ -50,6 +57,26 @@ type FSEnv struct {
 	Root     db.FS
 	Splitter *Splitter
 
+	// Queries, if non-nil, returns the list of
+	// queries that are currently running on
+	// behalf of the tenant. It populates the
+	// sneller_queries system table.
+	Queries func() []QueryInfo
+
+	// CountBlocks, if set, causes Stat to count
+	// the blocks that are excluded from the scan
+	// of each table by the query filter.
+	// See BlocksSkipped.
+	CountBlocks bool
+
+	// DeferKeys, if set, causes Stat to leave
+	// the data keys of encrypted tables wrapped
+	// so that they are resolved by the plan.KeyProvider
+	// of the environment that executes the query
+	// (see TenantEnv.Keys) rather than being sent
+	// along with the query plan.
+	DeferKeys bool
+
 	db     string
 	tenant db.Tenant
 
@@ -62,6 +89,7 @@ type FSEnv struct {
 	modtime date.Time
 
 	maxscan int64
+	skipped int
 }
 
 func Environ(t db.Tenant, dbname string) (*FSEnv, error) {
@@ -96,34 +124,58 @@ func (f *FSEnv) CacheValues() ([]byte, time.Time) {
 var _ plan.Indexer = (*FSEnv)(nil)
 
 func (f *FSEnv) Index(p expr.Node) (plan.Index, error) {
-	return f.index(p)
+	if _, ok := systemTable(p); ok {
+		return nil, nil
+	}
+	index, err := f.index(p)
+	if err != nil {
+		return nil, err
+	}
+	return fsIndex{Index: index, root: f.Root}, nil
 }
 
-func (f *FSEnv) index(e expr.Node) (*blockfmt.Index, error) {
-	var dbname, table string
-	var err error
+// fsIndex is the plan.Index returned by FSEnv.Index
+type fsIndex struct {
+	*blockfmt.Index
+	root db.FS
+}
+
+var _ plan.BlockCounter = fsIndex{}
 
+// Blocks implements plan.BlockCounter.Blocks
+func (f fsIndex) Blocks(filter expr.Node) (matching, total int, err error) {
+	var keep blockfmt.Filter
+	keep.Compile(filter)
+	return f.Index.Blocks(f.root, &keep)
+}
+
+// table returns the database and table
+// names referenced by a table expression
+func (f *FSEnv) table(e expr.Node) (dbname, table string, err error) {
 	switch e := e.(type) {
 	case expr.Ident:
-		dbname = f.db
-		table = string(e)
+		return f.db, string(e), nil
 	case *expr.Dot:
 		id, ok := e.Inner.(expr.Ident)
 		if !ok {
-			return nil, syntax("trailing path expression %q in table not supported", expr.ToString(e.Inner))
+			return "", "", syntax("trailing path expression %q in table not supported", expr.ToString(e.Inner))
 		}
-		dbname = string(id)
-		table = e.Field
+		return string(id), e.Field, nil
 	default:
-		return nil, syntax("unexpected table expression %q", expr.ToString(e))
+		return "", "", syntax("unexpected table expression %q", expr.ToString(e))
+	}
+}
+
+func (f *FSEnv) index(e expr.Node) (*blockfmt.Index, error) {
+	dbname, table, err := f.table(e)
+	if err != nil {
+		return nil, err
 	}
 	// if a query references the same table
 	// more than once (common with CTEs, nested SELECTs, etc.),
 	// then don't load the index more than once; it is expensive
-	for i := range f.recent {
-		if f.recent[i].db == dbname && f.recent[i].table == table {
-			return f.recent[i].index, nil
-		}
+	if saved := f.saved(dbname, table); saved != nil {
+		return saved.index, nil
 	}
 	index, err := db.OpenPartialIndex(f.Root, dbname, table, f.tenant.Key())
 	if err != nil {
@@ -146,16 +198,83 @@ func (f *FSEnv) index(e expr.Node) (*blockfmt.Index, error) {
 	return index, nil
 }
 
+func (f *FSEnv) saved(dbname, table string) *savedIndex {
+	for i := range f.recent {
+		if f.recent[i].db == dbname && f.recent[i].table == table {
+			return &f.recent[i]
+		}
+	}
+	return nil
+}
+
+var _ plan.Schemer = (*FSEnv)(nil)
+
+// Schema implements plan.Schemer.Schema
+//
+// The returned hint is derived from the type
+// hints attached to the table (see db.TypeHints),
+// if there are any, and from the schema collected
+// while the table was ingested.
+//
+// Schema cannot return an error, so a type hints
+// file that cannot be loaded is reported by
+// the subsequent call to Stat for the table.
+func (f *FSEnv) Schema(e expr.Node) expr.Hint {
+	if _, ok := systemTable(e); ok {
+		return nil
+	}
+	index, err := f.index(e)
+	if err != nil {
+		return nil
+	}
+	var hint expr.Hint
+	if schema := index.Schema(); schema != nil {
+		hint = schema
+	}
+	dbname, table, _ := f.table(e)
+	th, err := db.OpenTypeHints(f.Root, dbname, table)
+	if errors.Is(err, fs.ErrNotExist) {
+		// the hints are optional; a table
+		// without hints uses the ingestion schema
+		return hint
+	}
+	var ret expr.Hint
+	if err == nil {
+		ret, err = th.Hint(hint)
+	}
+	if err != nil {
+		f.saved(dbname, table).hintErr = fmt.Errorf("table %s/%s: %w", dbname, table, err)
+		return hint
+	}
+	// the hints affect the query plan,
+	// so they have to be part of the cache key
+	json.NewEncoder(f.hash).Encode(th)
+	return ret
+}
+
 // MaxScanned returns the maximum number of
 // bytes that need to be scanned to satisfy this query.
 func (f *FSEnv) MaxScanned() int64 { return f.maxscan }
 
+// BlocksSkipped returns the number of blocks
+// that the query does not need to scan because
+// they are excluded by the query filter.
+// The count is only maintained if CountBlocks is set.
+func (f *FSEnv) BlocksSkipped() int { return f.skipped }
+
 // Stat implements plan.Env.Stat
 func (f *FSEnv) Stat(e expr.Node, h *plan.Hints) (plan.TableHandle, error) {
+	if name, ok := systemTable(e); ok {
+		return f.systemStat(name)
+	}
 	index, err := f.index(e)
 	if err != nil {
 		return nil, err
 	}
+	dbname, table, _ := f.table(e)
+	if err := f.saved(dbname, table).hintErr; err != nil {
+		return nil, err
+	}
 	fh := &FilterHandle{
 		Splitter:  f.Splitter,
 		Expr:      h.Filter,
@@ -163,12 +282,29 @@ func (f *FSEnv) Stat(e expr.Node, h *plan.Hints) (plan.TableHandle, error) {
 		AllFields: h.AllFields,
 	}
 	fh.compiled.Compile(fh.Expr)
-	blobs, size, err := db.Blobs(f.Root, index, &fh.compiled)
+	var key *blockfmt.DataKey
+	if len(index.DataKey) > 0 && !f.DeferKeys {
+		if f.Key() == nil {
+			return nil, fmt.Errorf("table %s is encrypted, but no key is available", expr.ToString(e))
+		}
+		key, err = blockfmt.UnwrapDataKey(f.Key(), index.DataKey)
+		if err != nil {
+			return nil, err
+		}
+	}
+	blobs, size, err := db.BlobsWithKey(f.Root, index, key, &fh.compiled)
 	if err != nil {
 		return nil, err
 	}
 	f.maxscan += size
 	fh.Blobs = blobs
+	if f.CountBlocks {
+		matching, total, err := index.Blocks(f.Root, &fh.compiled)
+		if err != nil {
+			return nil, err
+		}
+		f.skipped += total - matching
+	}
 	return fh, nil
 }
 
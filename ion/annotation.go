@@ -0,0 +1,216 @@
+// Copyright (C) 2022 Sneller, Inc.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package ion
+
+import (
+	"fmt"
+)
+
+const (
+	// AnnotationsField is the name of the field
+	// that holds the annotations of the other
+	// fields of a structure after UnwrapAnnotations
+	// has removed them from the field values.
+	AnnotationsField = "$annotations"
+	// RowAnnotationsLabel is the label within
+	// AnnotationsField that holds the annotations
+	// of a top-level structure.
+	RowAnnotationsLabel = "$row"
+)
+
+// ReadAnnotationLabels reads an annotation
+// and returns all of its labels appended to dst,
+// the contents of the annotation, and the
+// remaining bytes in buf (in that order).
+func ReadAnnotationLabels(buf []byte, dst []Symbol) ([]Symbol, []byte, []byte, error) {
+	if t := TypeOf(buf); t != AnnotationType {
+		return dst, nil, nil, fmt.Errorf("ion.ReadAnnotationLabels: got type %s", t)
+	}
+	size := SizeOf(buf)
+	if size <= 0 || size > len(buf) {
+		return dst, nil, nil, fmt.Errorf("ion.ReadAnnotationLabels: size %d > %d", size, len(buf))
+	}
+	labels, contents, rest, err := annotationLabels(buf)
+	if err != nil {
+		return dst, nil, rest, fmt.Errorf("ion.ReadAnnotationLabels: %w", err)
+	}
+	for len(labels) > 0 {
+		var sym uint
+		var ok bool
+		sym, labels, ok = readuv(labels)
+		if !ok {
+			return dst, nil, rest, fmt.Errorf("ion.ReadAnnotationLabels: could not read label")
+		}
+		dst = append(dst, Symbol(sym))
+	}
+	return dst, contents, rest, nil
+}
+
+// annotationLabels splits the contents of an
+// annotation into the encoded labels and the
+// wrapped value
+func annotationLabels(buf []byte) (labels, contents, rest []byte, err error) {
+	contents, rest = Contents(buf)
+	if contents == nil {
+		return nil, nil, rest, errInvalidIon
+	}
+	// annot_length is the length
+	// of the labels in bytes
+	n, contents, ok := readuv(contents)
+	if !ok {
+		return nil, nil, rest, fmt.Errorf("could not read annot_length")
+	}
+	if n == 0 {
+		return nil, nil, rest, fmt.Errorf("0 labels disallowed")
+	}
+	if n > uint(len(contents)) {
+		return nil, nil, rest, fmt.Errorf("annot_length %d exceeds annotation size %d", n, len(contents))
+	}
+	return contents[:n], contents[n:], rest, nil
+}
+
+// isSymbolTable returns whether buf begins
+// with an annotation that should be interpreted
+// as a symbol table (rather than as a value).
+// Annotations that cannot be decoded are
+// treated as symbol tables so that the caller
+// reports the error from Symtab.Unmarshal.
+func isSymbolTable(buf []byte) bool {
+	if TypeOf(buf) != AnnotationType {
+		return false
+	}
+	sym, _, _, err := ReadAnnotation(buf)
+	return err != nil || sym == SystemSymSymbolTable
+}
+
+// UnwrapAnnotations returns d with every
+// annotated value inside d replaced with
+// the value it wraps.
+//
+// The labels of an annotated structure field
+// are recorded as a list of strings in the
+// AnnotationsField of the structure containing
+// the field, so
+//
+//	{x: a::b::1, y: {z: c::2}}
+//
+// becomes
+//
+//	{x: 1, y: {z: 2, $annotations: {z: ["c"]}}, $annotations: {x: ["a", "b"]}}
+//
+// If d itself is an annotated structure,
+// its labels are recorded in the RowAnnotationsLabel
+// field of its AnnotationsField.
+// The labels of annotated list elements
+// and of annotated values that are not
+// structures at the top level are discarded.
+func UnwrapAnnotations(st *Symtab, d Datum) (Datum, error) {
+	out, labels, _, err := unwrap(st, d)
+	if err != nil || len(labels) == 0 || !out.IsStruct() {
+		return out, err
+	}
+	s, _ := out.Struct()
+	var fields []Field
+	if f, ok := s.FieldByName(AnnotationsField); ok && f.IsStruct() {
+		inner, _ := f.Struct()
+		fields = inner.Fields(nil)
+	}
+	fields = append(fields, Field{Label: RowAnnotationsLabel, Datum: labelList(st, labels)})
+	s = s.WithField(Field{Label: AnnotationsField, Datum: NewStruct(st, fields).Datum()})
+	return s.Datum(), nil
+}
+
+func labelList(st *Symtab, labels []string) Datum {
+	items := make([]Datum, len(labels))
+	for i := range labels {
+		items[i] = String(labels[i])
+	}
+	return NewList(st, items).Datum()
+}
+
+// unwrap removes the annotations from d
+// and any values inside d and returns the
+// unwrapped value, the labels of d itself,
+// and whether or not anything changed
+func unwrap(st *Symtab, d Datum) (Datum, []string, bool, error) {
+	var labels []string
+	changed := false
+	for d.IsAnnotation() {
+		syms, body, _, err := ReadAnnotationLabels(d.buf, nil)
+		if err != nil {
+			return Empty, nil, false, err
+		}
+		dst := d.symtab()
+		for _, sym := range syms {
+			str, ok := dst.Lookup(sym)
+			if !ok {
+				return Empty, nil, false, fmt.Errorf("annotation label: symbol %d not in symbol table", sym)
+			}
+			labels = append(labels, str)
+		}
+		d = Datum{st: d.st, buf: body}
+		changed = true
+	}
+	switch d.Type() {
+	case StructType:
+		s, _ := d.Struct()
+		var fields []Field
+		var annotated []Field
+		inner := false
+		err := s.Each(func(f Field) error {
+			val, lbl, ok, err := unwrap(st, f.Datum)
+			if err != nil {
+				return err
+			}
+			inner = inner || ok
+			if len(lbl) > 0 {
+				annotated = append(annotated, Field{Label: f.Label, Datum: labelList(st, lbl)})
+			}
+			fields = append(fields, Field{Label: f.Label, Datum: val})
+			return nil
+		})
+		if err != nil {
+			return Empty, nil, false, err
+		}
+		if !inner {
+			return d, labels, changed, nil
+		}
+		if len(annotated) > 0 {
+			fields = append(fields, Field{Label: AnnotationsField, Datum: NewStruct(st, annotated).Datum()})
+		}
+		return NewStruct(st, fields).Datum(), labels, true, nil
+	case ListType:
+		l, _ := d.List()
+		var items []Datum
+		inner := false
+		err := l.Each(func(item Datum) error {
+			val, _, ok, err := unwrap(st, item)
+			if err != nil {
+				return err
+			}
+			inner = inner || ok
+			items = append(items, val)
+			return nil
+		})
+		if err != nil {
+			return Empty, nil, false, err
+		}
+		if !inner {
+			return d, labels, changed, nil
+		}
+		return NewList(st, items).Datum(), labels, true, nil
+	}
+	return d, labels, changed, nil
+}
@@ -0,0 +1,137 @@
+// Copyright (C) 2022 Sneller, Inc.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package ion
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+// annotate wraps body in an annotation
+// with the given (single-byte) labels
+func annotate(body []byte, labels ...Symbol) []byte {
+	inner := []byte{0x80 | byte(len(labels))}
+	for _, sym := range labels {
+		inner = append(inner, 0x80|byte(sym))
+	}
+	inner = append(inner, body...)
+	if len(inner) < 14 {
+		return append([]byte{0xe0 | byte(len(inner))}, inner...)
+	}
+	return append([]byte{0xee, 0x80 | byte(len(inner))}, inner...)
+}
+
+func TestReadAnnotationLabels(t *testing.T) {
+	var st Symtab
+	a, b := st.Intern("a"), st.Intern("b")
+	var buf Buffer
+	buf.WriteInt(1)
+	mem := annotate(buf.Bytes(), a, b)
+	mem = append(mem, 0x0f) // trailing data
+
+	labels, body, rest, err := ReadAnnotationLabels(mem, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(labels, []Symbol{a, b}) {
+		t.Errorf("got labels %v", labels)
+	}
+	if !bytes.Equal(body, buf.Bytes()) {
+		t.Errorf("got body %x", body)
+	}
+	if !bytes.Equal(rest, []byte{0x0f}) {
+		t.Errorf("got rest %x", rest)
+	}
+	sym, body, _, err := ReadAnnotation(mem)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sym != a || !bytes.Equal(body, buf.Bytes()) {
+		t.Errorf("ReadAnnotation: got %d, %x", sym, body)
+	}
+}
+
+func TestUnwrapAnnotations(t *testing.T) {
+	var st Symtab
+	syms := make(map[string]Symbol)
+	for _, s := range []string{"row", "x", "y", "z", "l", "a", "b", "c", "d"} {
+		syms[s] = st.Intern(s)
+	}
+	value := func(fn func(b *Buffer)) []byte {
+		var b Buffer
+		fn(&b)
+		return b.Bytes()
+	}
+
+	// row::{x: a::b::1, y: {z: c::"s"}, l: [d::2, 3]}
+	var buf Buffer
+	st.Marshal(&buf, true)
+	var row Buffer
+	row.BeginStruct(-1)
+	row.BeginField(syms["x"])
+	row.UnsafeAppend(annotate(value(func(b *Buffer) { b.WriteInt(1) }), syms["a"], syms["b"]))
+	row.BeginField(syms["y"])
+	row.BeginStruct(-1)
+	row.BeginField(syms["z"])
+	row.UnsafeAppend(annotate(value(func(b *Buffer) { b.WriteString("s") }), syms["c"]))
+	row.EndStruct()
+	row.BeginField(syms["l"])
+	row.BeginList(-1)
+	row.UnsafeAppend(annotate(value(func(b *Buffer) { b.WriteInt(2) }), syms["d"]))
+	row.WriteInt(3)
+	row.EndList()
+	row.EndStruct()
+	buf.UnsafeAppend(annotate(row.Bytes(), syms["row"]))
+
+	var rd Symtab
+	d, rest, err := ReadDatum(&rd, buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rest) != 0 {
+		t.Fatalf("%d bytes left over", len(rest))
+	}
+	if !d.IsAnnotation() {
+		t.Fatalf("got datum of type %s", d.Type())
+	}
+	out, err := UnwrapAnnotations(&rd, d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"x": 1, "y": {"z": "s", "$annotations": {"z": ["c"]}}, "$annotations": {"x": ["a", "b"], "$row": ["row"]}, "l": [2, 3]}`
+	if got := out.JSON(); got != want {
+		t.Errorf("got  %s", got)
+		t.Errorf("want %s", want)
+	}
+
+	// values without annotations are unchanged
+	d, _, err = ReadDatum(&rd, value(func(b *Buffer) {
+		b.BeginStruct(-1)
+		b.BeginField(syms["x"])
+		b.WriteInt(1)
+		b.EndStruct()
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err = UnwrapAnnotations(&rd, d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(out.buf, d.buf) {
+		t.Errorf("got %s from %s", out.JSON(), d.JSON())
+	}
+}
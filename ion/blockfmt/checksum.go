@@ -0,0 +1,169 @@
+// Copyright (C) 2022 Sneller, Inc.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package blockfmt
+
+import (
+	"fmt"
+	"hash/crc32"
+)
+
+// crcTable is the table used for computing
+// per-block checksums (see Trailer.Checksums)
+var crcTable = crc32.MakeTable(crc32.Castagnoli)
+
+// ChecksumError is the error returned by a Decoder
+// when the compressed contents of a block do not
+// match the checksum recorded for it in the Trailer.
+type ChecksumError struct {
+	// Object is the name of the object
+	// that was being decoded (see Decoder.Name).
+	Object string
+	// Block is the index of the block
+	// within the trailer.
+	Block int
+	// Want is the checksum recorded in the trailer,
+	// and Got is the checksum of the data that was read.
+	Want, Got uint32
+}
+
+func (c *ChecksumError) Error() string {
+	name := c.Object
+	if name == "" {
+		name = "<unknown>"
+	}
+	return fmt.Sprintf("blockfmt: checksum mismatch in object %s block %d: want %08x, got %08x", name, c.Block, c.Want, c.Got)
+}
+
+// verifier tracks the checksum state of a
+// Decoder that has been configured with SetRange
+type verifier struct {
+	blocks []Blockdesc // remaining blocks to verify
+	first  int         // index of blocks[0] in the trailer
+	end    int64       // end offset of the final block
+	pos    int64       // current offset in the input
+	crc    uint32      // checksum of blocks[0] so far
+}
+
+// remaining returns the number of bytes
+// left in the current block
+func (v *verifier) remaining() int64 {
+	end := v.end
+	if len(v.blocks) > 1 {
+		end = v.blocks[1].Offset
+	}
+	return end - v.pos
+}
+
+// update adds buf to the running checksum
+// and verifies the checksum of the current
+// block if buf finishes it
+func (v *verifier) update(name string, buf []byte) error {
+	if len(v.blocks) == 0 {
+		return nil
+	}
+	v.crc = crc32.Update(v.crc, crcTable, buf)
+	v.pos += int64(len(buf))
+	if left := v.remaining(); left > 0 {
+		return nil
+	} else if left < 0 || v.crc != v.blocks[0].Checksum {
+		// a frame that crosses a block boundary
+		// is just as corrupt as a bad checksum
+		return &ChecksumError{
+			Object: name,
+			Block:  v.first,
+			Want:   v.blocks[0].Checksum,
+			Got:    v.crc,
+		}
+	}
+	v.blocks = v.blocks[1:]
+	v.first++
+	v.crc = 0
+	return nil
+}
+
+// updateAll verifies every block that
+// is fully contained in src
+func (v *verifier) updateAll(name string, src []byte) error {
+	for len(src) > 0 && len(v.blocks) > 0 {
+		n := v.remaining()
+		if n > int64(len(src)) {
+			n = int64(len(src))
+		}
+		if err := v.update(name, src[:n]); err != nil {
+			return err
+		}
+		src = src[n:]
+	}
+	return nil
+}
+
+// crcCombine computes the CRC of the concatenation
+// of A and B given crc(A), crc(B), and len(B);
+// this is how we compute the checksum of blocks
+// that are coalesced after they have been written
+//
+// (see crc32_combine() in zlib)
+func crcCombine(crc1, crc2 uint32, len2 int64) uint32 {
+	if len2 <= 0 {
+		return crc1
+	}
+	var even, odd [32]uint32
+	// operator for one zero bit
+	odd[0] = crc32.Castagnoli
+	row := uint32(1)
+	for n := 1; n < 32; n++ {
+		odd[n] = row
+		row <<= 1
+	}
+	gf2square(&even, &odd) // two zero bits
+	gf2square(&odd, &even) // four zero bits
+	// apply len2 zeros to crc1
+	for {
+		gf2square(&even, &odd)
+		if len2&1 != 0 {
+			crc1 = gf2times(&even, crc1)
+		}
+		len2 >>= 1
+		if len2 == 0 {
+			break
+		}
+		gf2square(&odd, &even)
+		if len2&1 != 0 {
+			crc1 = gf2times(&odd, crc1)
+		}
+		len2 >>= 1
+		if len2 == 0 {
+			break
+		}
+	}
+	return crc1 ^ crc2
+}
+
+func gf2times(mat *[32]uint32, vec uint32) uint32 {
+	sum := uint32(0)
+	for i := 0; vec != 0; i++ {
+		if vec&1 != 0 {
+			sum ^= mat[i]
+		}
+		vec >>= 1
+	}
+	return sum
+}
+
+func gf2square(square, mat *[32]uint32) {
+	for n := range mat {
+		square[n] = gf2times(mat, mat[n])
+	}
+}
@@ -0,0 +1,229 @@
+// Copyright (C) 2022 Sneller, Inc.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package blockfmt
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"math/rand"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/SnellerInc/sneller/ion"
+)
+
+func TestCRCCombine(t *testing.T) {
+	buf := make([]byte, 4096)
+	rand.New(rand.NewSource(0)).Read(buf)
+	want := crc32.Checksum(buf, crcTable)
+	for _, split := range []int{0, 1, 7, 64, 1000, 4095, 4096} {
+		a := crc32.Checksum(buf[:split], crcTable)
+		b := crc32.Checksum(buf[split:], crcTable)
+		got := crcCombine(a, b, int64(len(buf)-split))
+		if got != want {
+			t.Errorf("split %d: got %08x, want %08x", split, got, want)
+		}
+	}
+}
+
+func TestCoalesceChecksums(t *testing.T) {
+	buf := make([]byte, 1000)
+	rand.New(rand.NewSource(1)).Read(buf)
+	bounds := []int{0, 100, 150, 400, 900, len(buf)}
+	var parts []blockpart
+	for i := 0; i < len(bounds)-1; i++ {
+		off, end := bounds[i], bounds[i+1]
+		parts = append(parts, blockpart{
+			offset: int64(off),
+			chunks: 1,
+			size:   int64(end - off),
+			crc:    crc32.Checksum(buf[off:end], crcTable),
+		})
+	}
+	parts = coalesce(parts, 2)
+	if len(parts) != 2 {
+		t.Fatalf("got %d parts after coalescing", len(parts))
+	}
+	for i := range parts {
+		end := int64(len(buf))
+		if i < len(parts)-1 {
+			end = parts[i+1].offset
+		}
+		want := crc32.Checksum(buf[parts[i].offset:end], crcTable)
+		if parts[i].size != end-parts[i].offset || parts[i].crc != want {
+			t.Errorf("part %d: size %d crc %08x, want %d %08x", i, parts[i].size, parts[i].crc, end-parts[i].offset, want)
+		}
+	}
+}
+
+func testChecksums(t *testing.T, multi bool) {
+	var inputs []Input
+	for _, name := range []string{"parking2.json", "parking3.json"} {
+		f, err := os.Open("../../testdata/" + name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		inputs = append(inputs, Input{
+			R: f,
+			F: MustSuffixToFormat(".json"),
+		})
+	}
+	var out BufferUploader
+	align := 4096
+	out.PartSize = 2 * align
+	c := Converter{
+		Output:    &out,
+		Comp:      "zstd",
+		Inputs:    inputs,
+		Align:     align,
+		FlushMeta: align * 3,
+		Checksums: true,
+	}
+	// call runMulti directly so that the
+	// MultiWriter is exercised regardless
+	// of the number of available CPUs
+	var err error
+	if multi {
+		err = c.runMulti(2)
+	} else {
+		err = c.runSingle()
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf := out.Bytes()
+	trailer, err := ReadTrailer(bytes.NewReader(buf), int64(len(buf)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !trailer.Checksums {
+		t.Fatal("trailer does not have checksums")
+	}
+	for i := range trailer.Blocks {
+		start := trailer.Blocks[i].Offset
+		end := trailer.Offset
+		if i < len(trailer.Blocks)-1 {
+			end = trailer.Blocks[i+1].Offset
+		}
+		got := crc32.Checksum(buf[start:end], crcTable)
+		if want := trailer.Blocks[i].Checksum; got != want {
+			t.Fatalf("block %d: checksum %08x, want %08x", i, got, want)
+		}
+	}
+	if len(trailer.Blocks) < 3 {
+		t.Fatalf("only %d blocks?", len(trailer.Blocks))
+	}
+	var diag bytes.Buffer
+	Validate(bytes.NewReader(buf), trailer, &diag)
+	if diag.Len() > 0 {
+		t.Fatalf("unexpected diagnostics: %s", diag.String())
+	}
+
+	// decode a range of blocks from the middle
+	first, last := 1, len(trailer.Blocks)-1
+	data := buf[trailer.Blocks[first].Offset:trailer.Blocks[last].Offset]
+	dec := Decoder{Name: "test-object"}
+	dec.SetRange(trailer, first, last)
+	_, err = dec.CopyBytes(io.Discard, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// corrupt one byte of block 'last-1'
+	// and confirm that every decoding path
+	// reports it
+	bad := last - 1
+	corrupt := bytes.Clone(buf)
+	corrupt[trailer.Blocks[bad].Offset+7] ^= 0x01
+	data = corrupt[trailer.Blocks[first].Offset:trailer.Blocks[last].Offset]
+	checkErr := func(err error) {
+		t.Helper()
+		var cerr *ChecksumError
+		if !errors.As(err, &cerr) {
+			t.Fatalf("got error %v, not a *ChecksumError", err)
+		}
+		if cerr.Object != "test-object" || cerr.Block != bad {
+			t.Fatalf("unexpected error %v", err)
+		}
+	}
+	dec.SetRange(trailer, first, last)
+	_, err = dec.CopyBytes(io.Discard, data)
+	checkErr(err)
+	dec.SetRange(trailer, first, last)
+	_, err = dec.Copy(io.Discard, bytes.NewReader(data))
+	checkErr(err)
+	dec.SetRange(trailer, first, last)
+	dst := make([]byte, (len(trailer.Blocks)*64)<<trailer.BlockShift)
+	_, err = dec.Decompress(bytes.NewReader(data), dst)
+	checkErr(err)
+
+	// without a range, nothing is verified
+	dec.Set(trailer, last)
+	_, err = dec.CopyBytes(io.Discard, data[:trailer.Blocks[bad].Offset-trailer.Blocks[first].Offset])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	diag.Reset()
+	Validate(bytes.NewReader(corrupt[trailer.Blocks[0].Offset:]), trailer, &diag)
+	if !bytes.Contains(diag.Bytes(), []byte(fmt.Sprintf("block %d:", bad))) {
+		t.Fatalf("unexpected diagnostics: %q", diag.String())
+	}
+}
+
+func TestChecksums(t *testing.T) {
+	t.Run("single", func(t *testing.T) {
+		testChecksums(t, false)
+	})
+	t.Run("multi", func(t *testing.T) {
+		testChecksums(t, true)
+	})
+}
+
+func TestChecksumTrailerRoundtrip(t *testing.T) {
+	tr := Trailer{
+		Version:    1,
+		Offset:     300,
+		Algo:       "zstd",
+		BlockShift: 20,
+		Blocks: []Blockdesc{
+			{Offset: 0, Chunks: 10, Checksum: 0xdeadbeef},
+			{Offset: 100, Chunks: 10, Checksum: 0},
+			{Offset: 200, Chunks: 5, Checksum: 0xffffffff},
+		},
+		Checksums: true,
+	}
+	tr.Sparse.Push(nil)
+	tr.Sparse.Push(nil)
+	tr.Sparse.Push(nil)
+	var st ion.Symtab
+	var buf ion.Buffer
+	tr.Encode(&buf, &st)
+	var out Trailer
+	err := out.Decode(&st, buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !out.Checksums {
+		t.Fatal("Checksums not set")
+	}
+	if !reflect.DeepEqual(out.Blocks, tr.Blocks) {
+		t.Fatalf("got %v, want %v", out.Blocks, tr.Blocks)
+	}
+}
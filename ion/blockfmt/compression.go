@@ -19,6 +19,7 @@ import (
 	"encoding/binary"
 	"errors"
 	"fmt"
+	"hash/crc32"
 	"io"
 	"math/bits"
 	"sync"
@@ -37,13 +38,19 @@ type blockpart struct {
 	offset int64
 	chunks int
 	ranges []TimeRange
+	keys   []datumRange // sort key ranges
+	size   int64        // compressed size
+	crc    uint32       // checksum, if enabled
+	rows   int64        // number of rows
 }
 
 func toDescs(dst []Blockdesc, src []blockpart) []Blockdesc {
 	for i := range src {
 		dst = append(dst, Blockdesc{
-			src[i].offset,
-			src[i].chunks,
+			Offset:   src[i].offset,
+			Chunks:   src[i].chunks,
+			Checksum: src[i].crc,
+			Rows:     src[i].rows,
 		})
 	}
 	return dst
@@ -138,6 +145,15 @@ type CompressionWriter struct {
 	// into adjacent blocks.
 	// See also MultiWriter.MinChunksPerBlock
 	MinChunksPerBlock int
+	// Key, if non-nil, is used to encrypt
+	// each block of the output.
+	// See also Trailer.Encrypted.
+	Key *DataKey
+	// Path is the path of the output object.
+	// Path must be set if Key is set, since
+	// each encrypted frame is bound to the path
+	// of its object (see Decoder.Path).
+	Path string
 
 	// intermediate blocks, before we have
 	// merged them and stuck them in Trailer
@@ -151,6 +167,10 @@ type CompressionWriter struct {
 	lastblock   int64
 	flushblocks int
 	skipChecks  bool
+	crc         uint32 // checksum of current block
+	rows        int64  // rows in current block
+	norows      bool   // some block has an unknown row count
+	sealer      sealer // encrypts blocks if Key is set
 
 	// metadata to be attached
 	// to the next block
@@ -164,6 +184,7 @@ func (w *CompressionWriter) WrittenBlocks() int { return len(w.blocks) }
 
 type futureRange struct {
 	buffered []TimeRange
+	keys     []datumRange
 }
 
 type minMaxer interface {
@@ -174,18 +195,27 @@ var _ minMaxer = &futureRange{}
 
 // SetMinMax Sets the `min` and `max` values for the next ION chunk.
 // This method should only be called once for each path.
+// Ranges of timestamps are stored in time indices;
+// ranges of numbers or strings are stored as sort key ranges,
+// and ranges of any other values are ignored.
 func (f *futureRange) SetMinMax(path []string, min, max ion.Datum) {
-	ts, ok := NewRange(path, min, max).(*TimeRange)
-	if !ok {
-		return // only supporting timestamp ranges right now
+	switch r := NewRange(path, min, max).(type) {
+	case *TimeRange:
+		f.buffered = append(f.buffered, *r)
+	case *datumRange:
+		if isKey(min) && isKey(max) {
+			r.min = min.Clone()
+			r.max = max.Clone()
+			f.keys = append(f.keys, *r)
+		}
 	}
-	f.buffered = append(f.buffered, *ts)
 }
 
-func (f *futureRange) pop() []TimeRange {
-	ret := f.buffered
+func (f *futureRange) pop() ([]TimeRange, []datumRange) {
+	ret, keys := f.buffered, f.keys
 	f.buffered = nil
-	return ret
+	f.keys = nil
+	return ret, keys
 }
 
 func (w *CompressionWriter) target() int {
@@ -205,13 +235,20 @@ func (w *CompressionWriter) Flush() error {
 		}
 		return nil
 	}
+	ranges, keys := w.futureRange.pop()
 	w.blocks = append(w.blocks, blockpart{
 		offset: w.lastblock,
 		chunks: w.flushblocks,
-		ranges: w.futureRange.pop(),
+		ranges: ranges,
+		keys:   keys,
+		size:   w.offset - w.lastblock,
+		crc:    w.crc,
+		rows:   w.rows,
 	})
 	w.lastblock = w.offset
 	w.flushblocks = 0
+	w.crc = 0
+	w.rows = 0
 	return nil
 }
 
@@ -237,7 +274,10 @@ func pickPrefix(t *Trailer, minchunks int) (index int, offset int64) {
 // consume maybe *some* of an existing object
 // without doing any heavy lifting w.r.t compression
 func (w *CompressionWriter) writeStart(r io.Reader, t *Trailer) error {
-	if t.Algo != w.Comp.Name() || 1<<t.BlockShift != w.InputAlign {
+	// encrypted frames are bound to their
+	// object, so they are never copied
+	if t.Algo != w.Comp.Name() || 1<<t.BlockShift != w.InputAlign ||
+		(w.Checksums && !t.Checksums) || t.Encrypted || w.Key != nil {
 		return nil // not directly compatible
 	}
 	j, offset := pickPrefix(t, w.MinChunksPerBlock)
@@ -254,6 +294,7 @@ func (w *CompressionWriter) writeStart(r io.Reader, t *Trailer) error {
 	// set the currently-output state:
 	w.Trailer.Blocks = t.Blocks[:j]
 	w.Trailer.Sparse = t.Sparse.Trim(j)
+	w.norows = w.norows || !t.RowCounts
 	// set the state of what we expect to consume:
 	t.Blocks = t.Blocks[j:]
 	t.Sparse = t.Sparse.Slice(j, t.Sparse.Blocks())
@@ -290,9 +331,17 @@ func (w *CompressionWriter) setSymbols(st *ion.Symtab) {
 	w.Comp.(*zionCompressor).enc.SetSymbols(st)
 }
 
-func (w *CompressionWriter) writeCompressed(p []byte) error {
+func (w *CompressionWriter) writeCompressed(p []byte, rows int64) error {
+	w.rows += rows
 	before := len(w.buffer)
 	w.buffer = appendRawFrame(w.buffer, p)
+	if w.Key != nil {
+		var err error
+		w.buffer, err = w.sealer.seal(w.Key, w.Path, w.buffer, before)
+		if err != nil {
+			return err
+		}
+	}
 	return w.checkFlush(before)
 }
 
@@ -308,17 +357,50 @@ func (w *CompressionWriter) Write(p []byte) (n int, err error) {
 	if w.flushblocks == 0 && !w.skipChecks && !ion.IsBVM(p) {
 		return 0, fmt.Errorf("blockfmt.CompressionWriter.Write: blocks flushed, but no BVM")
 	}
+	if !w.skipChecks {
+		w.rows += countRows(p)
+	}
 	before := len(w.buffer)
 	w.buffer, err = appendFrame(w.buffer, w.Comp, p)
 	if err != nil {
 		return
 	}
+	if w.Key != nil {
+		w.buffer, err = w.sealer.seal(w.Key, w.Path, w.buffer, before)
+		if err != nil {
+			return 0, err
+		}
+	}
 	return len(p), w.checkFlush(before)
 }
 
+// countRows returns the number of top-level
+// structures (rows) in the chunk p
+func countRows(p []byte) int64 {
+	n := int64(0)
+	for len(p) > 0 {
+		if ion.IsBVM(p) {
+			p = p[4:]
+			continue
+		}
+		size := ion.SizeOf(p)
+		if size <= 0 || size > len(p) {
+			break
+		}
+		if ion.TypeOf(p) == ion.StructType {
+			n++
+		}
+		p = p[size:]
+	}
+	return n
+}
+
 func (w *CompressionWriter) checkFlush(before int) error {
 	w.flushblocks++
 	w.offset += int64(len(w.buffer) - before)
+	if w.Checksums {
+		w.crc = crc32.Update(w.crc, crcTable, w.buffer[before:])
+	}
 	if len(w.buffer) >= w.target() {
 		err := w.upload()
 		if err != nil {
@@ -338,6 +420,10 @@ func finalize(dst *Trailer, src []blockpart, min int) {
 			r := &src[i].ranges[j]
 			dst.Sparse.push(r.path, r.min, r.max)
 		}
+		for j := range src[i].keys {
+			k := &src[i].keys[j]
+			dst.Sparse.pushKey(k.path, k.min, k.max)
+		}
 		dst.Sparse.bump()
 	}
 	dst.Blocks = toDescs(dst.Blocks, src)
@@ -360,6 +446,8 @@ func (w *CompressionWriter) Close() error {
 	}
 	finalize(&w.Trailer, w.blocks, w.MinChunksPerBlock)
 	w.Trailer.Offset = w.offset
+	w.Trailer.Encrypted = w.Key != nil
+	w.Trailer.RowCounts = !w.norows && !w.skipChecks
 	trailer := w.Trailer.trailer(w.Comp.Name(), w.InputAlign)
 	w.offset += int64(len(trailer))
 	w.buffer = append(w.buffer, trailer...)
@@ -468,14 +556,19 @@ func appendFrame(dst []byte, comp Compressor, src []byte) ([]byte, error) {
 	if err != nil {
 		return nil, err
 	}
-	size := len(dst) - base - 5
-	dst[base+1] = byte(size>>21) & 0x7f
-	dst[base+2] = byte(size>>14) & 0x7f
-	dst[base+3] = byte(size>>7) & 0x7f
-	dst[base+4] = byte(size&0x7f) | 0x80
+	setFrameSize(dst[base:], len(dst)-base-5)
 	return dst, nil
 }
 
+// setFrameSize sets the size in the
+// header of the frame beginning at dst[0]
+func setFrameSize(dst []byte, size int) {
+	dst[1] = byte(size>>21) & 0x7f
+	dst[2] = byte(size>>14) & 0x7f
+	dst[3] = byte(size>>7) & 0x7f
+	dst[4] = byte(size&0x7f) | 0x80
+}
+
 // ReadTrailer reads a trailer from an io.ReaderAt
 // that has a backing size of 'size'.
 func ReadTrailer(src io.ReaderAt, size int64) (*Trailer, error) {
@@ -608,9 +701,28 @@ type Decoder struct {
 	// data allocated via Malloc.
 	Free func([]byte)
 
-	decomp decompressor
-	frame  [5]byte
-	tmp    []byte
+	// Name, if non-empty, identifies the object
+	// being decoded in a *ChecksumError.
+	Name string
+
+	// Key is the key used to decrypt the blocks
+	// of an encrypted object (see Trailer.Encrypted).
+	// Key is ignored if the object is not encrypted.
+	Key *DataKey
+	// Path is the path of the object being decoded.
+	// Each frame of an encrypted object is authenticated
+	// with the path that the object was written to
+	// (see CompressionWriter.Path), so Path must be set
+	// in order to decode an encrypted object.
+	Path string
+
+	decomp    decompressor
+	frame     [5]byte
+	tmp       []byte
+	sums      verifier
+	encrypted bool
+	sealer    sealer
+	plain     []byte // decrypted frame, for in-memory inputs
 }
 
 // Set sets fields in the decoder in order
@@ -626,6 +738,123 @@ func (d *Decoder) Set(t *Trailer, lastblock int) {
 	} else {
 		d.Offset = t.Blocks[lastblock].Offset
 	}
+	d.sums = verifier{}
+	d.encrypted = t.Encrypted
+	d.sealer = sealer{}
+}
+
+// firstFrame returns the index of the
+// first frame of block i in t
+func firstFrame(t *Trailer, i int) int64 {
+	n := int64(0)
+	for j := range t.Blocks[:i] {
+		n += int64(t.Blocks[j].Chunks)
+	}
+	return n
+}
+
+// SetRange is equivalent to Set(t, lastblock),
+// but it additionally indicates that the input
+// data will begin at firstblock so that the
+// checksum of each block can be verified as
+// it is decoded. If the trailer does not
+// include checksums (see Trailer.Checksums),
+// then no verification is performed.
+// The input of an encrypted object must be read
+// with SetRange unless it begins at the first block,
+// since each frame is bound to its position.
+//
+// A block that fails verification causes
+// the decoder to return a *ChecksumError.
+func (d *Decoder) SetRange(t *Trailer, firstblock, lastblock int) {
+	d.Set(t, lastblock)
+	if t.Encrypted && firstblock > 0 && firstblock <= len(t.Blocks) {
+		d.sealer.next = firstFrame(t, firstblock)
+	}
+	if !t.Checksums || firstblock >= lastblock || firstblock >= len(t.Blocks) {
+		return
+	}
+	if lastblock > len(t.Blocks) {
+		lastblock = len(t.Blocks)
+	}
+	d.sums = verifier{
+		blocks: t.Blocks[firstblock:lastblock],
+		first:  firstblock,
+		end:    d.Offset,
+		pos:    t.Blocks[firstblock].Offset,
+	}
+}
+
+// checkFrame updates the block checksums
+// with a frame read from a stream, where
+// the frame header is in d.frame
+func (d *Decoder) checkFrame(body []byte) error {
+	if len(d.sums.blocks) == 0 {
+		return nil
+	}
+	err := d.sums.update(d.Name, d.frame[:])
+	if err != nil {
+		return err
+	}
+	return d.sums.update(d.Name, body)
+}
+
+// checkError is called when decoding fails while
+// block checksums are being verified; it consumes
+// the remainder of the current block from src so
+// that corrupt data is reported as a *ChecksumError
+// rather than as an opaque decoding error
+func (d *Decoder) checkError(src io.Reader, err error) error {
+	const maxread = 64 * 1024
+	v := &d.sums
+	for len(v.blocks) > 0 {
+		n := v.remaining()
+		if n > maxread {
+			n = maxread
+		}
+		buf := d.realloc(int(n))
+		_, rerr := io.ReadFull(src, buf)
+		if rerr != nil {
+			break
+		}
+		block := v.first
+		if cerr := v.update(d.Name, buf); cerr != nil {
+			return cerr
+		}
+		if v.first != block {
+			break // checksum was fine
+		}
+	}
+	return err
+}
+
+// decrypt decrypts the body of a frame if the
+// object being decoded is encrypted; body is
+// decrypted in place unless shared is set, since
+// the caller may not own the memory behind body
+func (d *Decoder) decrypt(body []byte, shared bool) ([]byte, error) {
+	if !d.encrypted {
+		return body, nil
+	}
+	name := d.Name
+	if name == "" {
+		name = "<unknown>"
+	}
+	if d.Key == nil {
+		return nil, fmt.Errorf("blockfmt: object %s is encrypted, but no key was provided", name)
+	}
+	var out []byte
+	var err error
+	if shared {
+		out, err = d.sealer.open(d.Key, d.Path, d.plain[:0], body)
+		d.plain = out
+	} else {
+		out, err = d.sealer.openInPlace(d.Key, d.Path, body)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("blockfmt: decrypting object %s: %w", name, err)
+	}
+	return out, nil
 }
 
 func (d *Decoder) realloc(size int) []byte {
@@ -694,8 +923,19 @@ func (d *Decoder) decompressBlocks(src io.Reader, upto int, dst []byte) (int, er
 		if err != nil {
 			return off, err
 		}
+		err = d.checkFrame(buf)
+		if err != nil {
+			return off, err
+		}
+		buf, err = d.decrypt(buf, false)
+		if err != nil {
+			return 0, d.checkError(src, err)
+		}
 		err = d.decomp.Decompress(buf, dst[off:off+bs])
 		if err != nil {
+			if err := d.checkError(src, nil); err != nil {
+				return 0, err
+			}
 			return 0, fmt.Errorf("decompress @ offset %d of %d block %d size %d: %w", count-n, upto, block, size, err)
 		}
 		off += bs
@@ -742,6 +982,9 @@ func (d *Decoder) Decompress(src io.Reader, dst []byte) (int, error) {
 func (d *Decoder) copyZion(w io.Writer, src []byte) (int64, error) {
 	nn := int64(0)
 	for len(src) > 0 {
+		if err := throttle(w); err != nil {
+			return nn, err
+		}
 		if ion.TypeOf(src) != ion.BlobType {
 			return nn, fmt.Errorf("decoding data: expected a blob; got %s", ion.TypeOf(src))
 		}
@@ -749,7 +992,11 @@ func (d *Decoder) copyZion(w io.Writer, src []byte) (int64, error) {
 		if size < 5 || size > len(src) {
 			return nn, fmt.Errorf("unexpected frame size %d", size)
 		}
-		_, err := w.Write(src[5:size])
+		buf, err := d.decrypt(src[5:size], true)
+		if err != nil {
+			return nn, err
+		}
+		_, err = w.Write(buf)
 		if err != nil {
 			return nn, err
 		}
@@ -764,6 +1011,9 @@ func (d *Decoder) copyZionFrom(w io.Writer, src io.Reader) (int64, error) {
 	nn := int64(0)
 	defer d.free()
 	for {
+		if err := throttle(w); err != nil {
+			return nn, err
+		}
 		_, err := io.ReadFull(src, d.frame[:])
 		if err == io.EOF {
 			// we are done
@@ -784,12 +1034,19 @@ func (d *Decoder) copyZionFrom(w io.Writer, src io.Reader) (int64, error) {
 		if n != len(buf) && err == nil {
 			err = io.ErrUnexpectedEOF
 		}
+		if err == nil {
+			err = d.checkFrame(buf)
+		}
 		if err != nil {
 			return nn, err
 		}
+		buf, err = d.decrypt(buf, false)
+		if err != nil {
+			return nn, d.checkError(src, err)
+		}
 		_, err = w.Write(buf)
 		if err != nil {
-			return nn, err
+			return nn, d.checkError(src, err)
 		}
 		nn += 1 << d.BlockShift
 	}
@@ -809,6 +1066,22 @@ type ZionWriter interface {
 	ConfigureZion(fields []string) bool
 }
 
+// throttler is implemented by io.Writers
+// that can apply backpressure to their input
+// (see vm.Throttler)
+type throttler interface {
+	Throttle() error
+}
+
+// throttle waits for dst to be ready for
+// another block if it implements throttler
+func throttle(dst io.Writer) error {
+	if t, ok := dst.(throttler); ok {
+		return t.Throttle()
+	}
+	return nil
+}
+
 func (d *Decoder) acceptsZion(w io.Writer) bool {
 	zw, ok := w.(ZionWriter)
 	return ok && zw.ConfigureZion(d.Fields)
@@ -821,6 +1094,12 @@ func (d *Decoder) acceptsZion(w io.Writer) bool {
 // then compressed data may be passed directly to dst
 // (see ZionWriter for more details).
 func (d *Decoder) CopyBytes(dst io.Writer, src []byte) (int64, error) {
+	// src is already in memory, so we can
+	// verify every block before decoding any
+	err := d.sums.updateAll(d.Name, src)
+	if err != nil {
+		return 0, err
+	}
 	size := 1 << d.BlockShift
 	if d.Algo == "zion" {
 		if d.acceptsZion(dst) {
@@ -836,12 +1115,15 @@ func (d *Decoder) CopyBytes(dst io.Writer, src []byte) (int64, error) {
 	if algo == "zstd" {
 		algo = "zstd-nocrc"
 	}
-	err := d.getDecomp(algo)
+	err = d.getDecomp(algo)
 	if err != nil {
 		return 0, err
 	}
 	nn := int64(0)
 	for len(src) > 0 {
+		if err := throttle(dst); err != nil {
+			return nn, err
+		}
 		if ion.TypeOf(src) != ion.BlobType {
 			return nn, fmt.Errorf("decoding data: expected a blob; got %s", ion.TypeOf(src))
 		}
@@ -849,7 +1131,11 @@ func (d *Decoder) CopyBytes(dst io.Writer, src []byte) (int64, error) {
 		if size < 5 || size > len(src) {
 			return nn, fmt.Errorf("unexpected frame size %d", size)
 		}
-		err := d.decomp.Decompress(src[5:size], vmm)
+		buf, err := d.decrypt(src[5:size], true)
+		if err != nil {
+			return nn, err
+		}
+		err = d.decomp.Decompress(buf, vmm)
 		if err != nil {
 			return nn, err
 		}
@@ -889,6 +1175,9 @@ func (d *Decoder) Copy(dst io.Writer, src io.Reader) (int64, error) {
 	vmm := d.malloc(size)
 	defer d.drop(vmm)
 	for {
+		if err := throttle(dst); err != nil {
+			return nn, err
+		}
 		_, err := io.ReadFull(src, d.frame[:])
 		if err == io.EOF {
 			// we are done
@@ -909,12 +1198,19 @@ func (d *Decoder) Copy(dst io.Writer, src io.Reader) (int64, error) {
 		if n != len(buf) && err == nil {
 			err = io.ErrUnexpectedEOF
 		}
+		if err == nil {
+			err = d.checkFrame(buf)
+		}
 		if err != nil {
 			return nn, err
 		}
+		buf, err = d.decrypt(buf, false)
+		if err != nil {
+			return nn, d.checkError(src, err)
+		}
 		err = d.decomp.Decompress(buf, vmm)
 		if err != nil {
-			return nn, err
+			return nn, d.checkError(src, err)
 		}
 		n, err = dst.Write(vmm)
 		nn += int64(n)
@@ -46,15 +46,22 @@ type concat struct {
 // Descriptors are "compatible" if they encode
 // data in precisely the same way *and* their
 // sparse indexing metadata covers the same
-// constants and time ranges (see SparseIndex.Append)
+// constants and time ranges (see SparseIndex.Append).
+// Encrypted objects are never compatible, since their
+// frames are bound to the object that contains them.
 func (c *concat) add(src *Descriptor) bool {
 	t := &src.Trailer
+	if t.Encrypted {
+		return false
+	}
 	dt := &c.output.Trailer
 	if len(c.inputs) == 0 {
 		c.output.Format = src.Format
 		dt.Algo = t.Algo
 		dt.Version = t.Version
 		dt.BlockShift = t.BlockShift
+		dt.Checksums = t.Checksums
+		dt.RowCounts = t.RowCounts
 		dt.Sparse = t.Sparse.Clone()
 	} else {
 		dt := &c.output.Trailer
@@ -62,14 +69,18 @@ func (c *concat) add(src *Descriptor) bool {
 		if t.Version != dt.Version ||
 			t.Algo != dt.Algo ||
 			t.BlockShift != dt.BlockShift ||
+			t.Checksums != dt.Checksums ||
 			!dt.Sparse.Append(&t.Sparse) {
 			return false
 		}
+		dt.RowCounts = dt.RowCounts && t.RowCounts
 	}
 	for i := range t.Blocks {
 		dt.Blocks = append(dt.Blocks, Blockdesc{
-			Offset: dt.Offset + t.Blocks[i].Offset,
-			Chunks: t.Blocks[i].Chunks,
+			Offset:   dt.Offset + t.Blocks[i].Offset,
+			Chunks:   t.Blocks[i].Chunks,
+			Checksum: t.Blocks[i].Checksum,
+			Rows:     t.Blocks[i].Rows,
 		})
 	}
 	c.inputs = append(c.inputs, *src)
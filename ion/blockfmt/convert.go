@@ -177,12 +177,53 @@ func UnsafeION() RowFormat {
 	return ionConverter{}
 }
 
+// checkedIONConverter converts binary ion
+// from an untrusted source. Unlike ionConverter,
+// a malformed value is reported as an error
+// rather than a panic.
+type checkedIONConverter struct {
+	name   string
+	decomp func(r io.Reader) (io.Reader, error)
+}
+
+func (i *checkedIONConverter) Name() string { return i.name }
+
+func (i *checkedIONConverter) Convert(r io.Reader, dst *ion.Chunker, cons []ion.Field) (err error) {
+	rc := r
+	if i.decomp != nil {
+		rc, err = i.decomp(r)
+		if err != nil {
+			return err
+		}
+	}
+	defer func() {
+		// the ion decoding functions assume
+		// that their input is well-formed
+		// and may panic when it is not
+		if p := recover(); p != nil {
+			err = fmt.Errorf("converting %s: malformed input: %v", i.name, p)
+		}
+	}()
+	_, err = dst.ReadFrom(rc, cons)
+	if err != nil {
+		return fmt.Errorf("converting %s: %w", i.name, err)
+	}
+	if i.decomp != nil {
+		if cc, ok := rc.(io.Closer); ok {
+			return cc.Close()
+		}
+	}
+	return nil
+}
+
 func isCompressed(r RowFormat) bool {
 	switch r := r.(type) {
 	case *jsonConverter:
 		return r.decomp != nil
 	case *xsvConverter:
 		return r.decomp != nil
+	case *checkedIONConverter:
+		return r.decomp != nil
 	default:
 		return false
 	}
@@ -243,6 +284,23 @@ func init() {
 		}
 	}
 
+	// binary ion; this is decoded and re-encoded
+	// like UnsafeION, but with malformed input
+	// reported as an error
+	for dn, dc := range decompressors {
+		decName := dn
+		decomp := dc
+		SuffixToFormat[".ion"+decName] = func(h []byte) (RowFormat, error) {
+			if h != nil {
+				return nil, errors.New("ion doesn't support hints")
+			}
+			return &checkedIONConverter{
+				name:   "ion" + decName,
+				decomp: decomp,
+			}, nil
+		}
+	}
+
 	// Cloudtrail JSON format (only GZIP needed)
 	SuffixToFormat[".cloudtrail.json.gz"] = func(h []byte) (RowFormat, error) {
 		if h != nil {
@@ -327,6 +385,10 @@ type Converter struct {
 		// Converter will read bytes up to Trailer.Offset.
 		R       io.ReadCloser
 		Trailer *Trailer
+		// Path is the path of the object read
+		// by R, which is required if the object
+		// is encrypted (see Decoder.Path).
+		Path string
 	}
 	// Constants is the list of templated constants
 	// to be inserted into the ingested data.
@@ -342,6 +404,10 @@ type Converter struct {
 	// or MultiWriter depending on the number
 	// of input streams and the parallelism setting.
 	Output Uploader
+	// Path is the path of the object written
+	// to Output. Path must be set if Key is set
+	// (see CompressionWriter.Path).
+	Path string
 	// Comp is the name of the compression
 	// algorithm used for uploaded data blocks.
 	Comp string
@@ -377,10 +443,39 @@ type Converter struct {
 	// prefetched reads in flight. If this is less
 	// than or equal to zero, then DefaultMaxReadsInFlight is used.
 	MaxReadsInFlight int
+	// UploadRetries is the number of times that a
+	// failed upload of an output part is retried when
+	// multiple streams are used for conversion.
+	// (See MultiWriter.MaxRetries.)
+	UploadRetries int
 
 	// DisablePrefetch, if true, disables
 	// prefetching of inputs.
 	DisablePrefetch bool
+	// Checksums, if true, causes a checksum
+	// to be recorded for each output block
+	// (see Trailer.Checksums).
+	Checksums bool
+	// Key, if non-nil, is used to encrypt the
+	// output blocks (see Trailer.Encrypted).
+	// Key is also used to decrypt Prepend.R
+	// if it is encrypted.
+	//
+	// Encrypted output is always written by a
+	// single stream, since each encrypted frame
+	// is bound to its position in the output,
+	// which isn't known in advance when multiple
+	// streams are written in parallel.
+	Key *DataKey
+	// SortKeys is a list of paths for which the
+	// minimum and maximum values of each output
+	// block are recorded in the sparse index
+	// (in addition to the ranges of timestamps),
+	// so that a Filter can exclude blocks based on
+	// comparisons with these paths. Rows are
+	// clustered on the first sort key by sorting
+	// the rows within each output chunk.
+	SortKeys [][]string
 
 	// trailer built by the writer. This is only
 	// set if the object was written successfully.
@@ -431,6 +526,9 @@ func IsFatal(err error) bool {
 }
 
 func (c *Converter) parallel() int {
+	if c.Key != nil {
+		return 1 // see Converter.Key
+	}
 	p := c.Parallel
 	if p == 0 {
 		p = runtime.GOMAXPROCS(0)
@@ -522,15 +620,20 @@ func (c *Converter) runSingle() error {
 		// try to make the blocks at least
 		// half the target size
 		MinChunksPerBlock: c.FlushMeta / (c.Align * 2),
+		Key:               c.Key,
+		Path:              c.Path,
 	}
+	w.Trailer.Checksums = c.Checksums
 	if len(c.Constants) > 0 {
 		w.Trailer.Sparse.consts = ion.NewStruct(nil, c.Constants)
 	}
+	var schema Schema
 	cn := ion.Chunker{
 		W:          w,
 		Align:      w.InputAlign,
 		RangeAlign: c.FlushMeta,
 	}
+	c.setKeys(&cn)
 	err := c.fastPrepend(w)
 	if err != nil {
 		return err
@@ -539,6 +642,9 @@ func (c *Converter) runSingle() error {
 	if err != nil {
 		return err
 	}
+	// prepended data is described by
+	// c.Prepend.Trailer.Schema; see c.schema
+	cn.Observe = schema.observe
 	ready := make([]chan struct{}, len(c.Inputs))
 	next := 1
 	inflight := int64(0) // # bytes being prefetched
@@ -597,11 +703,35 @@ func (c *Converter) runSingle() error {
 	if err != nil {
 		return err
 	}
+	w.Trailer.Schema = c.schema([]Schema{schema})
 	err = w.Close()
 	c.trailer = &w.Trailer
 	return err
 }
 
+// schema merges the schemas collected
+// from each output stream with the schema
+// of the prepended data (if any); prepended
+// data is not observed, since it may be copied
+// without being passed through an ion.Chunker
+func (c *Converter) schema(streams []Schema) *Schema {
+	ret := new(Schema)
+	if c.Prepend.Trailer != nil {
+		if c.Prepend.Trailer.Schema == nil {
+			// the prepended data was written
+			// without a schema, so we cannot
+			// produce a complete one
+			return nil
+		}
+		ret.Merge(c.Prepend.Trailer.Schema)
+	}
+	for i := range streams {
+		streams[i].finish()
+		ret.Merge(&streams[i])
+	}
+	return ret
+}
+
 type trailerWriter interface {
 	writeStart(r io.Reader, t *Trailer) error
 }
@@ -614,7 +744,7 @@ func (c *Converter) fastPrepend(tw trailerWriter) error {
 }
 
 type compressWriter interface {
-	writeCompressed(p []byte) error
+	writeCompressed(p []byte, rows int64) error
 	setSymbols(st *ion.Symtab)
 }
 
@@ -676,7 +806,7 @@ func (f *fastWriter) Write(p []byte) (int, error) {
 			}
 			f.skipped += f.dst.Align
 			f.maxchunks--
-			return len(p), f.inner.writeCompressed(p)
+			return len(p), f.inner.writeCompressed(p, countRows(f.tmp))
 		}
 		f.slowpath = true
 		if f.skipped > 0 {
@@ -688,18 +818,45 @@ func (f *fastWriter) Write(p []byte) (int, error) {
 					f.dst.SetTimeRange(p, min, max)
 				}
 			}
+			for _, p := range f.dst.KeyRanges {
+				min, max, ok := f.trailer.Sparse.KeyRange(p)
+				if ok {
+					f.dst.SetKeyRange(p, min, max)
+				}
+			}
 		}
 	}
 	return f.dst.Write(f.tmp)
 }
 
+// setKeys configures cn to track the ranges
+// of c.SortKeys and cluster rows on the first one
+func (c *Converter) setKeys(cn *ion.Chunker) {
+	if len(c.SortKeys) == 0 {
+		return
+	}
+	cn.KeyRanges = c.SortKeys
+	cn.SortBy = c.SortKeys[0]
+}
+
+// keysKnown returns whether the range of each
+// of c.SortKeys is known for every block in t
+func (c *Converter) keysKnown(t *Trailer) bool {
+	for _, p := range c.SortKeys {
+		if _, _, ok := t.Sparse.KeyRange(p); !ok {
+			return false
+		}
+	}
+	return true
+}
+
 func (c *Converter) runPrepend(cn *ion.Chunker) error {
 	if c.Prepend.R == nil {
 		return nil
 	}
 	t := c.Prepend.Trailer
 	cn.WalkTimeRanges = collectRanges(t)
-	d := Decoder{}
+	d := Decoder{Key: c.Key, Path: c.Prepend.Path}
 	size := int64(0)
 	if len(t.Blocks) > 0 {
 		size = t.Offset - t.Blocks[0].Offset
@@ -715,7 +872,8 @@ func (c *Converter) runPrepend(cn *ion.Chunker) error {
 		c.Comp == "zion" && t.Algo == "zion" && // not changing compression
 		cn.Align == 1<<t.BlockShift && // not changing block size
 		t.Blocks[0].Chunks > 1 && // more than 1 chunk to use fast-path
-		cn.RangeAlign >= t.Blocks[0].Chunks<<t.BlockShift {
+		cn.RangeAlign >= t.Blocks[0].Chunks<<t.BlockShift &&
+		c.keysKnown(t) { // skipped chunks must be covered by key ranges
 		dst = &fastWriter{
 			dst:       cn,
 			trailer:   t,
@@ -747,7 +905,9 @@ func (c *Converter) runMulti(p int) error {
 		// try to make the blocks at least
 		// half the target size
 		MinChunksPerBlock: c.FlushMeta / (c.Align * 2),
+		MaxRetries:        c.UploadRetries,
 	}
+	w.Trailer.Checksums = c.Checksums
 	if len(c.Constants) > 0 {
 		w.Trailer.Sparse.consts = ion.NewStruct(nil, c.Constants)
 	}
@@ -767,6 +927,7 @@ func (c *Converter) runMulti(p int) error {
 		readyc = doPrefetch(startc, max, DefaultMaxBytesInFlight)
 	}
 	errs := make(chan error, p)
+	schemas := make([]Schema, p)
 	// NOTE: consume must be called
 	// before the send on errs so that
 	// the consumption of inputs happens
@@ -789,6 +950,7 @@ func (c *Converter) runMulti(p int) error {
 				Align:      w.InputAlign,
 				RangeAlign: c.FlushMeta,
 			}
+			c.setKeys(&cn)
 			if i == 0 {
 				err := c.runPrepend(&cn)
 				if err != nil {
@@ -797,6 +959,7 @@ func (c *Converter) runMulti(p int) error {
 					return
 				}
 			}
+			cn.Observe = schemas[i].observe
 			for in := range startc {
 				err := in.F.Convert(in.R, &cn, c.Constants)
 				err2 := in.R.Close()
@@ -843,6 +1006,7 @@ func (c *Converter) runMulti(p int) error {
 	}
 	// don't finalize unless everything
 	// up to this point succeeded
+	w.Trailer.Schema = c.schema(schemas)
 	if err := w.Close(); err != nil {
 		return err
 	}
@@ -22,6 +22,11 @@ import (
 	"os"
 	"strings"
 	"testing"
+
+	"github.com/SnellerInc/sneller/expr"
+	"github.com/SnellerInc/sneller/ion"
+
+	"golang.org/x/exp/slices"
 )
 
 func testConvertMulti(t *testing.T, algo string, meta int) {
@@ -173,6 +178,59 @@ func TestConvertSingle(t *testing.T) {
 	}
 }
 
+func TestConvertSortKeys(t *testing.T) {
+	var in strings.Builder
+	const rows = 5000
+	for i := 0; i < rows; i++ {
+		fmt.Fprintf(&in, "{\"n\": %d, \"s\": \"row-%05d\", \"pad\": \"%s\"}\n", i, i, strings.Repeat("x", 32))
+	}
+	inputs := []Input{{
+		R: io.NopCloser(strings.NewReader(in.String())),
+		F: MustSuffixToFormat(".json"),
+	}}
+	var out BufferUploader
+	align := 2048
+	out.PartSize = 4 * align
+	c := Converter{
+		Output:    &out,
+		Comp:      "zion",
+		Inputs:    inputs,
+		Align:     align,
+		FlushMeta: 2 * align,
+		SortKeys:  [][]string{{"n"}, {"s"}},
+	}
+	err := c.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	check(t, &out)
+	r := bytes.NewReader(out.Bytes())
+	trailer, err := ReadTrailer(r, r.Size())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(trailer.Blocks) < 2 {
+		t.Fatalf("only %d blocks", len(trailer.Blocks))
+	}
+	min, max, ok := trailer.Sparse.KeyRange([]string{"n"})
+	if !ok || !min.Equal(ion.Int(0)) || !max.Equal(ion.Int(rows-1)) {
+		t.Fatalf("range of n: %v %v %v", min, max, ok)
+	}
+	min, max, ok = trailer.Sparse.KeyRange([]string{"s"})
+	if !ok || !min.Equal(ion.String("row-00000")) || !max.Equal(ion.String(fmt.Sprintf("row-%05d", rows-1))) {
+		t.Fatalf("range of s: %v %v %v", min, max, ok)
+	}
+	var f Filter
+	f.Compile(expr.Compare(expr.Less, expr.Ident("n"), expr.Integer(10)))
+	var visited [][2]int
+	f.Visit(&trailer.Sparse, func(start, end int) {
+		visited = append(visited, [2]int{start, end})
+	})
+	if len(visited) != 1 || visited[0] != [2]int{0, 1} {
+		t.Fatalf("n < 10 visited %v", visited)
+	}
+}
+
 func TestConvertEmpty(t *testing.T) {
 	inputs := []Input{{
 		R: io.NopCloser(strings.NewReader("")),
@@ -251,3 +309,61 @@ func check(t *testing.T, buf *BufferUploader) int {
 	}
 	return n
 }
+
+func TestConvertMalformedION(t *testing.T) {
+	var buf ion.Buffer
+	var st ion.Symtab
+	buf.BeginStruct(-1)
+	buf.BeginField(st.Intern("x"))
+	buf.WriteString("hello, world")
+	buf.EndStruct()
+	var good ion.Buffer
+	st.Marshal(&good, true)
+	good.UnsafeAppend(buf.Bytes())
+
+	symtab := good.Bytes()[:len(good.Bytes())-len(buf.Bytes())]
+	with := func(rest ...byte) []byte {
+		return append(slices.Clone(symtab), rest...)
+	}
+	inputs := [][]byte{
+		// the string overruns the struct
+		with(0xd3, 0x8a, 0x8c, 'h'),
+		// the field symbol is not in the symbol table
+		with(0xd2, 0xfe, 0x20),
+		// truncated
+		good.Bytes()[:len(good.Bytes())-1],
+	}
+	for i := range inputs {
+		var out BufferUploader
+		out.PartSize = 4096
+		c := Converter{
+			Output: &out,
+			Comp:   "zstd",
+			Inputs: []Input{{
+				R: io.NopCloser(bytes.NewReader(inputs[i])),
+				F: MustSuffixToFormat(".ion"),
+			}},
+			Align: 4096,
+		}
+		if c.Run() == nil {
+			t.Errorf("input %d: no error", i)
+		}
+	}
+
+	// the well-formed input is converted
+	var out BufferUploader
+	out.PartSize = 4096
+	c := Converter{
+		Output: &out,
+		Comp:   "zstd",
+		Inputs: []Input{{
+			R: io.NopCloser(bytes.NewReader(good.Bytes())),
+			F: MustSuffixToFormat(".ion"),
+		}},
+		Align: 4096,
+	}
+	if err := c.Run(); err != nil {
+		t.Fatal(err)
+	}
+	check(t, &out)
+}
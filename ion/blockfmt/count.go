@@ -0,0 +1,152 @@
+// Copyright (C) 2022 Sneller, Inc.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package blockfmt
+
+import (
+	"time"
+
+	"github.com/SnellerInc/sneller/date"
+	"github.com/SnellerInc/sneller/expr"
+)
+
+// blockBound describes the blocks
+// selected by one time comparison
+type blockBound struct {
+	// all is the half-open interval of blocks
+	// in which every row satisfies the comparison
+	all [2]int
+	// any is the half-open interval of blocks
+	// in which some rows may satisfy the comparison
+	any [2]int
+}
+
+func within(k int, span [2]int) bool {
+	return k >= span[0] && k < span[1]
+}
+
+// p >= when
+func boundAfterEq(ti *TimeIndex, when date.Time) blockBound {
+	n := ti.Blocks()
+	return blockBound{
+		all: [2]int{ti.minAtLeast(when), n},
+		any: [2]int{ti.maxAtMost(when.Add(-time.Microsecond)), n},
+	}
+}
+
+// p <= when
+func boundBeforeEq(ti *TimeIndex, when date.Time) blockBound {
+	return blockBound{
+		all: [2]int{0, ti.maxAtMost(when)},
+		any: [2]int{0, ti.minAtLeast(when.Add(time.Microsecond))},
+	}
+}
+
+// timeBounds appends the bounds for each of the
+// conjunctions in e to lst, or returns false if
+// any of the conjunctions is not a comparison of
+// a timestamp field that is present in every
+// row against a constant timestamp
+func (t *Trailer) timeBounds(e expr.Node, lst []blockBound) ([]blockBound, bool) {
+	if l, ok := e.(*expr.Logical); ok && l.Op == expr.OpAnd {
+		lst, ok = t.timeBounds(l.Left, lst)
+		if !ok {
+			return nil, false
+		}
+		return t.timeBounds(l.Right, lst)
+	}
+	// note: expr normalizes constant comparisons
+	// such that the constant appears on the rhs
+	cmp, ok := e.(*expr.Comparison)
+	if !ok {
+		return nil, false
+	}
+	p, ok := expr.FlatPath(cmp.Left)
+	if !ok {
+		return nil, false
+	}
+	ts, ok := cmp.Right.(*expr.Timestamp)
+	if !ok {
+		return nil, false
+	}
+	// rows without a timestamp are not
+	// described by the sparse index
+	if t.Schema == nil || t.Schema.TypeOf(cmp.Left) != expr.TimeType {
+		return nil, false
+	}
+	ti := t.Sparse.Get(p)
+	if ti == nil || ti.Blocks() != len(t.Blocks) {
+		return nil, false
+	}
+	const epsilon = time.Microsecond
+	switch cmp.Op {
+	case expr.Equals:
+		lst = append(lst, boundAfterEq(ti, ts.Value))
+		return append(lst, boundBeforeEq(ti, ts.Value)), true
+	case expr.Less:
+		return append(lst, boundBeforeEq(ti, ts.Value.Add(-epsilon))), true
+	case expr.LessEquals:
+		return append(lst, boundBeforeEq(ti, ts.Value)), true
+	case expr.Greater:
+		return append(lst, boundAfterEq(ti, ts.Value.Add(epsilon))), true
+	case expr.GreaterEquals:
+		return append(lst, boundAfterEq(ti, ts.Value)), true
+	}
+	return nil, false
+}
+
+// CountMatching returns the exact number of rows
+// within Blocks[start:end] that satisfy the
+// predicate e (or all of the rows if e is nil),
+// or false if the number of rows cannot be
+// determined from the trailer alone.
+//
+// The number of rows can only be determined
+// when t has per-block row counts (see RowCounts),
+// e is a conjunction of comparisons between
+// timestamp fields and constant timestamps,
+// every row has a timestamp for each of the
+// compared fields (according to t.Schema),
+// and the sparse index places each block either
+// entirely inside or entirely outside of the
+// range of times selected by e.
+func (t *Trailer) CountMatching(e expr.Node, start, end int) (int64, bool) {
+	if e == nil {
+		return t.ExactRows(start, end)
+	}
+	if !t.RowCounts {
+		return 0, false
+	}
+	bounds, ok := t.timeBounds(e, nil)
+	if !ok {
+		return 0, false
+	}
+	rows := int64(0)
+blocks:
+	for k := start; k < end; k++ {
+		for i := range bounds {
+			if within(k, bounds[i].all) {
+				continue
+			}
+			if within(k, bounds[i].any) {
+				// some but perhaps not all
+				// of the rows match
+				return 0, false
+			}
+			continue blocks
+		}
+		rows += t.Blocks[k].Rows
+	}
+	return rows, true
+}
@@ -0,0 +1,90 @@
+// Copyright (C) 2022 Sneller, Inc.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package blockfmt
+
+import (
+	"testing"
+
+	"github.com/SnellerInc/sneller/date"
+	"github.com/SnellerInc/sneller/expr"
+	"github.com/SnellerInc/sneller/expr/partiql"
+)
+
+func TestCountMatching(t *testing.T) {
+	hour := func(h int) date.Time {
+		return date.Date(2023, 1, 1, h, 0, 0, 0)
+	}
+	// four blocks of 10, 20, 30, and 40 rows
+	// covering the hours [0, 1], [2, 3], [4, 5], [6, 7]
+	tr := &Trailer{RowCounts: true}
+	path := []string{"ts"}
+	for i := 0; i < 4; i++ {
+		tr.Blocks = append(tr.Blocks, Blockdesc{Chunks: 1, Rows: int64(10 * (i + 1))})
+		tr.Sparse.push(path, hour(2*i), hour(2*i+1))
+		tr.Sparse.bump()
+	}
+	tr.Schema = &Schema{
+		Rows: 100,
+		Fields: map[string]*SchemaField{
+			"ts": {Types: expr.TimeType},
+			"x":  {Types: expr.TimeType | expr.MissingType},
+		},
+	}
+	tcs := []struct {
+		where string
+		start int
+		end   int
+		rows  int64
+		ok    bool
+	}{
+		{"", 0, 4, 100, true},
+		{"", 1, 3, 50, true},
+		{"ts >= `2023-01-01T02:00:00Z`", 0, 4, 90, true},
+		{"ts > `2023-01-01T01:00:00Z`", 0, 4, 90, true},
+		{"ts < `2023-01-01T04:00:00Z`", 0, 4, 30, true},
+		{"ts <= `2023-01-01T03:00:00Z`", 0, 4, 30, true},
+		{"ts >= `2023-01-01T02:00:00Z` AND ts < `2023-01-01T06:00:00Z`", 0, 4, 50, true},
+		{"ts >= `2023-01-01T02:00:00Z` AND ts < `2023-01-01T06:00:00Z`", 3, 4, 0, true},
+		{"ts >= `2023-01-01T09:00:00Z`", 0, 4, 0, true},
+		// block 1 is split by the comparison
+		{"ts >= `2023-01-01T03:00:00Z`", 0, 4, 0, false},
+		{"ts >= `2023-01-01T03:00:00Z`", 2, 4, 70, true},
+		// x may be missing
+		{"x >= `2023-01-01T02:00:00Z`", 0, 4, 0, false},
+		// no index for y
+		{"y >= `2023-01-01T02:00:00Z`", 0, 4, 0, false},
+		{"ts >= `2023-01-01T02:00:00Z` AND z = 1", 0, 4, 0, false},
+		{"ts >= `2023-01-01T02:00:00Z` OR ts < `2023-01-01T01:00:00Z`", 0, 4, 0, false},
+	}
+	for i := range tcs {
+		var e expr.Node
+		if tcs[i].where != "" {
+			q, err := partiql.Parse([]byte("SELECT * FROM t WHERE " + tcs[i].where))
+			if err != nil {
+				t.Fatal(err)
+			}
+			e = q.Body.(*expr.Select).Where
+		}
+		rows, ok := tr.CountMatching(e, tcs[i].start, tcs[i].end)
+		if ok != tcs[i].ok || rows != tcs[i].rows {
+			t.Errorf("case %d: %q [%d, %d): got %d, %v; want %d, %v",
+				i, tcs[i].where, tcs[i].start, tcs[i].end, rows, ok, tcs[i].rows, tcs[i].ok)
+		}
+	}
+	tr.RowCounts = false
+	if _, ok := tr.CountMatching(nil, 0, 4); ok {
+		t.Error("expected no count without row counts")
+	}
+}
@@ -0,0 +1,203 @@
+// Copyright (C) 2022 Sneller, Inc.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package blockfmt
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"golang.org/x/crypto/blake2b"
+)
+
+// DataKeyLength is the length of a DataKey.
+const DataKeyLength = 32
+
+// DataKey is a secret key used to encrypt
+// the block data of objects with AES-256-GCM.
+// (See CompressionWriter.Key and Decoder.Key.)
+//
+// A DataKey is not stored in plaintext;
+// it is wrapped with the Key used to sign
+// the Index that references the encrypted
+// objects (see Index.DataKey).
+type DataKey [DataKeyLength]byte
+
+// ErrBadDataKey is returned by UnwrapDataKey
+// when a wrapped key cannot be authenticated
+// with the provided Key.
+var ErrBadDataKey = errors.New("blockfmt: cannot unwrap data key")
+
+// NewDataKey returns a new random DataKey.
+func NewDataKey() (*DataKey, error) {
+	k := new(DataKey)
+	if _, err := rand.Read(k[:]); err != nil {
+		return nil, err
+	}
+	return k, nil
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	c, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(c)
+}
+
+// wrapper returns the cipher used to wrap
+// data keys; the wrapping key is derived from
+// the signing key so that the same key is
+// never used with two different primitives
+func wrapper(master *Key) (cipher.AEAD, error) {
+	h, err := blake2b.New256(master[:])
+	if err != nil {
+		return nil, err
+	}
+	h.Write([]byte("blockfmt data key"))
+	return newAEAD(h.Sum(nil))
+}
+
+// Wrap encrypts k with a key derived from master.
+// The returned bytes can be stored in Index.DataKey
+// and decrypted with UnwrapDataKey.
+func (k *DataKey) Wrap(master *Key) ([]byte, error) {
+	aead, err := wrapper(master)
+	if err != nil {
+		return nil, err
+	}
+	ns := aead.NonceSize()
+	nonce := make([]byte, ns, ns+len(k)+aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, k[:], nil), nil
+}
+
+// UnwrapDataKey decrypts a DataKey
+// that was wrapped with DataKey.Wrap.
+func UnwrapDataKey(master *Key, wrapped []byte) (*DataKey, error) {
+	aead, err := wrapper(master)
+	if err != nil {
+		return nil, err
+	}
+	ns := aead.NonceSize()
+	if len(wrapped) != ns+DataKeyLength+aead.Overhead() {
+		return nil, ErrBadDataKey
+	}
+	k := new(DataKey)
+	_, err = aead.Open(k[:0], wrapped[:ns], wrapped[ns:], nil)
+	if err != nil {
+		return nil, ErrBadDataKey
+	}
+	return k, nil
+}
+
+// errNoPath is returned when an encrypted
+// frame is sealed or opened without the path
+// of the object that it belongs to
+var errNoPath = errors.New("blockfmt: encrypted blocks require the object path")
+
+// sealer lazily constructs the cipher
+// used to encrypt frames with a DataKey
+//
+// Each frame is authenticated with additional
+// data consisting of the path of the object
+// that contains it and the index of the frame
+// within that object, so that frames cannot be
+// reordered or moved between objects that are
+// encrypted with the same DataKey. As a consequence,
+// encrypted frames cannot be copied verbatim into
+// a different object or to a different position.
+type sealer struct {
+	aead cipher.AEAD
+	ad   []byte // path followed by the frame index
+	next int64  // index of the next frame
+}
+
+func (s *sealer) setup(k *DataKey, path string) error {
+	if s.aead != nil {
+		return nil
+	}
+	if path == "" {
+		return errNoPath
+	}
+	aead, err := newAEAD(k[:])
+	if err != nil {
+		return err
+	}
+	s.aead = aead
+	s.ad = append([]byte(path), make([]byte, 8)...)
+	return nil
+}
+
+// frameAD returns the additional data
+// for the next frame and advances s.next
+func (s *sealer) frameAD() []byte {
+	binary.BigEndian.PutUint64(s.ad[len(s.ad)-8:], uint64(s.next))
+	s.next++
+	return s.ad
+}
+
+// seal encrypts the contents of the frame
+// beginning at dst[base:] in place; the
+// encrypted frame body is the nonce followed
+// by the sealed contents of the original frame
+func (s *sealer) seal(k *DataKey, path string, dst []byte, base int) ([]byte, error) {
+	if err := s.setup(k, path); err != nil {
+		return nil, err
+	}
+	ns := s.aead.NonceSize()
+	n := len(dst) - base - 5
+	dst = append(dst, make([]byte, ns+s.aead.Overhead())...)
+	body := dst[base+5:]
+	copy(body[ns:], body[:n])
+	nonce := body[:ns]
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	s.aead.Seal(body[ns:ns], nonce, body[ns:ns+n], s.frameAD())
+	setFrameSize(dst[base:], len(body))
+	return dst, nil
+}
+
+// open decrypts a frame body produced by seal,
+// appending the contents to dst
+func (s *sealer) open(k *DataKey, path string, dst, body []byte) ([]byte, error) {
+	if err := s.setup(k, path); err != nil {
+		return nil, err
+	}
+	ns := s.aead.NonceSize()
+	if len(body) < ns+s.aead.Overhead() {
+		return nil, fmt.Errorf("encrypted frame size %d too small", len(body))
+	}
+	return s.aead.Open(dst, body[:ns], body[ns:], s.frameAD())
+}
+
+// openInPlace is equivalent to open, but
+// the frame body is decrypted in place
+func (s *sealer) openInPlace(k *DataKey, path string, body []byte) ([]byte, error) {
+	if err := s.setup(k, path); err != nil {
+		return nil, err
+	}
+	ns := s.aead.NonceSize()
+	if len(body) < ns {
+		return nil, fmt.Errorf("encrypted frame size %d too small", len(body))
+	}
+	return s.open(k, path, body[ns:ns], body)
+}
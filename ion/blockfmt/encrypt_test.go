@@ -0,0 +1,322 @@
+// Copyright (C) 2022 Sneller, Inc.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package blockfmt
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/SnellerInc/sneller/ion"
+
+	"golang.org/x/exp/slices"
+)
+
+func TestDataKeyWrap(t *testing.T) {
+	var master, other Key
+	master[0] = 1
+	other[0] = 2
+	k, err := NewDataKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	wrapped, err := k.Wrap(&master)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(wrapped, k[:]) {
+		t.Fatal("wrapped key contains the plaintext key")
+	}
+	got, err := UnwrapDataKey(&master, wrapped)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *got != *k {
+		t.Fatal("unwrapped key doesn't match")
+	}
+	_, err = UnwrapDataKey(&other, wrapped)
+	if !errors.Is(err, ErrBadDataKey) {
+		t.Fatalf("unwrapping with the wrong key: got error %v", err)
+	}
+	wrapped[len(wrapped)-1] ^= 1
+	_, err = UnwrapDataKey(&master, wrapped)
+	if !errors.Is(err, ErrBadDataKey) {
+		t.Fatalf("unwrapping a corrupt key: got error %v", err)
+	}
+
+	// the wrapped key should survive Sign+DecodeIndex
+	wrapped[len(wrapped)-1] ^= 1
+	idx := &Index{Name: "foo", Algo: "zstd", DataKey: wrapped}
+	buf, err := Sign(&master, idx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	idx2, err := DecodeIndex(&master, buf, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(idx2.DataKey, wrapped) {
+		t.Fatal("data key not preserved")
+	}
+}
+
+const encryptedPath = "db/default/secret/packed-0.ion.zst"
+
+func convertEncrypted(t *testing.T, algo string, key *DataKey) ([]byte, *Trailer) {
+	var inputs []Input
+	for _, name := range []string{"parking2.json", "parking3.json"} {
+		f, err := os.Open("../../testdata/" + name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		inputs = append(inputs, Input{
+			R: f,
+			F: MustSuffixToFormat(".json"),
+		})
+	}
+	var out BufferUploader
+	align := 4096
+	out.PartSize = 2 * align
+	c := Converter{
+		Output:    &out,
+		Path:      encryptedPath,
+		Comp:      algo,
+		Inputs:    inputs,
+		Align:     align,
+		FlushMeta: align * 3,
+		Parallel:  2,
+		Checksums: true,
+		Key:       key,
+	}
+	// encrypted output is written by one stream
+	if c.MultiStream() != (key == nil) {
+		t.Fatalf("MultiStream() = %v with key %v", c.MultiStream(), key != nil)
+	}
+	if err := c.Run(); err != nil {
+		t.Fatal(err)
+	}
+	buf := out.Bytes()
+	trailer, err := ReadTrailer(bytes.NewReader(buf), int64(len(buf)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return buf, trailer
+}
+
+// toJSON returns the rows written by fn as sorted
+// lines of JSON with sorted fields, since neither
+// the row order nor the field order of the output
+// is identical across conversions
+func toJSON(t *testing.T, fn func(w io.Writer) error) []byte {
+	t.Helper()
+	var out bytes.Buffer
+	w := ion.NewJSONWriter(&out, '\n')
+	if err := fn(w); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	lines := bytes.Split(bytes.TrimSpace(out.Bytes()), []byte{'\n'})
+	for i := range lines {
+		var v any
+		if err := json.Unmarshal(lines[i], &v); err != nil {
+			t.Fatal(err)
+		}
+		lines[i], _ = json.Marshal(v)
+	}
+	slices.SortFunc(lines, func(a, b []byte) bool {
+		return bytes.Compare(a, b) < 0
+	})
+	return bytes.Join(lines, []byte{'\n'})
+}
+
+func testEncrypt(t *testing.T, algo string) {
+	key, err := NewDataKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	plain, ptrailer := convertEncrypted(t, algo, nil)
+	if ptrailer.Encrypted {
+		t.Fatal("plaintext object has Encrypted set")
+	}
+	var dec Decoder
+	dec.Set(ptrailer, len(ptrailer.Blocks))
+	want := toJSON(t, func(w io.Writer) error {
+		_, err := dec.Copy(w, bytes.NewReader(plain[:ptrailer.Offset]))
+		return err
+	})
+
+	buf, trailer := convertEncrypted(t, algo, key)
+	if !trailer.Encrypted {
+		t.Fatal("trailer is not marked as encrypted")
+	}
+	if len(trailer.Blocks) < 3 {
+		t.Fatalf("only %d blocks?", len(trailer.Blocks))
+	}
+	data := buf[:trailer.Offset]
+	needle := []byte("BodyStyle")
+	if !bytes.Contains(plain[:ptrailer.Offset], needle) {
+		t.Fatalf("%q not present in plaintext object?", needle)
+	}
+	if bytes.Contains(data, needle) {
+		t.Fatal("found plaintext in encrypted object")
+	}
+
+	dec = Decoder{Name: "test-object", Path: encryptedPath, Key: key}
+	dec.SetRange(trailer, 0, len(trailer.Blocks))
+	got := toJSON(t, func(w io.Writer) error {
+		_, err := dec.Copy(w, bytes.NewReader(data))
+		return err
+	})
+	if !bytes.Equal(got, want) {
+		t.Fatal("Copy: output doesn't match")
+	}
+	dec.SetRange(trailer, 0, len(trailer.Blocks))
+	got = toJSON(t, func(w io.Writer) error {
+		_, err := dec.CopyBytes(w, data)
+		return err
+	})
+	if !bytes.Equal(got, want) {
+		t.Fatal("CopyBytes: output doesn't match")
+	}
+	if bytes.Contains(data, needle) {
+		t.Fatal("CopyBytes modified its input")
+	}
+	dec.SetRange(trailer, 0, len(trailer.Blocks))
+	dst := make([]byte, trailer.Decompressed())
+	_, err = dec.Decompress(bytes.NewReader(data), dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got = toJSON(t, func(w io.Writer) error {
+		_, err := w.Write(dst)
+		return err
+	})
+	if !bytes.Equal(got, want) {
+		t.Fatal("Decompress: output doesn't match")
+	}
+
+	// decoding a range of blocks that doesn't
+	// start at the first block should work
+	// with SetRange
+	dec.SetRange(trailer, 1, len(trailer.Blocks))
+	_, err = dec.CopyParallel([]io.Writer{io.Discard, io.Discard}, data[trailer.Blocks[1].Offset:], 2)
+	if err != nil {
+		t.Fatalf("decoding from block 1: %s", err)
+	}
+	// ... but not if the frames are moved
+	dec.Set(trailer, len(trailer.Blocks))
+	_, err = dec.CopyBytes(io.Discard, data[trailer.Blocks[1].Offset:])
+	if err == nil {
+		t.Fatal("decoding block 1 as block 0 succeeded")
+	}
+	first := ion.SizeOf(data)
+	second := ion.SizeOf(data[first:])
+	swapped := slices.Clone(data[first : first+second])
+	swapped = append(swapped, data[:first]...)
+	dec.Set(trailer, len(trailer.Blocks))
+	_, err = dec.CopyBytes(io.Discard, swapped)
+	if err == nil {
+		t.Fatal("decoding swapped frames succeeded")
+	}
+	// ... or moved to another object
+	dec.Path = "db/default/secret/packed-1.ion.zst"
+	dec.Set(trailer, len(trailer.Blocks))
+	_, err = dec.CopyBytes(io.Discard, data)
+	if err == nil || !strings.Contains(err.Error(), "decrypting object test-object") {
+		t.Fatalf("decoding with the wrong path: got error %v", err)
+	}
+	dec.Path = ""
+	dec.Set(trailer, len(trailer.Blocks))
+	_, err = dec.CopyBytes(io.Discard, data)
+	if !errors.Is(err, errNoPath) {
+		t.Fatalf("decoding without a path: got error %v", err)
+	}
+	dec.Path = encryptedPath
+
+	// decoding without the key or with
+	// the wrong key should fail
+	dec.Key = nil
+	dec.Set(trailer, len(trailer.Blocks))
+	_, err = dec.CopyBytes(io.Discard, data)
+	if err == nil || !strings.Contains(err.Error(), "no key") {
+		t.Fatalf("decoding without a key: got error %v", err)
+	}
+	dec.Key, _ = NewDataKey()
+	dec.Set(trailer, len(trailer.Blocks))
+	_, err = dec.Copy(io.Discard, bytes.NewReader(data))
+	if err == nil || !strings.Contains(err.Error(), "decrypting object test-object") {
+		t.Fatalf("decoding with the wrong key: got error %v", err)
+	}
+
+	// appending to an encrypted object
+	// with the same key should work
+	const newPath = "db/default/secret/packed-1.ion.zst"
+	var out BufferUploader
+	out.PartSize = 4096 * 2
+	c := Converter{
+		Output:    &out,
+		Path:      newPath,
+		Comp:      algo,
+		Align:     4096,
+		FlushMeta: 4096 * 3,
+		Key:       key,
+	}
+	c.Prepend.R = io.NopCloser(bytes.NewReader(data))
+	c.Prepend.Trailer = trailer
+	c.Prepend.Path = encryptedPath
+	if err := c.Run(); err != nil {
+		t.Fatal(err)
+	}
+	buf = out.Bytes()
+	trailer, err = ReadTrailer(bytes.NewReader(buf), int64(len(buf)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !trailer.Encrypted {
+		t.Fatal("trailer is not marked as encrypted")
+	}
+	dec = Decoder{Path: newPath, Key: key}
+	dec.Set(trailer, len(trailer.Blocks))
+	got = toJSON(t, func(w io.Writer) error {
+		_, err := dec.Copy(w, bytes.NewReader(buf[:trailer.Offset]))
+		return err
+	})
+	if !bytes.Equal(got, want) {
+		t.Fatal("output after prepend doesn't match")
+	}
+}
+
+func TestEncrypt(t *testing.T) {
+	for _, algo := range []string{"zstd", "zion"} {
+		algo := algo
+		t.Run(algo, func(t *testing.T) {
+			testEncrypt(t, algo)
+		})
+	}
+}
+
+func TestConcatEncrypted(t *testing.T) {
+	var c concat
+	d := Descriptor{Trailer: Trailer{Encrypted: true}}
+	if c.add(&d) {
+		t.Fatal("encrypted object was added to a concatenation")
+	}
+}
@@ -56,8 +56,12 @@ func (f *Filter) Trivial() bool { return f.eval == nil }
 // the intersection of the two ranges computed by
 // left and right
 func filtintersect(left, right expr.Node) evalfn {
-	lhs := filtcompile(left)
-	rhs := filtcompile(right)
+	return filtand(filtcompile(left), filtcompile(right))
+}
+
+// filtand produces the intersection
+// of the ranges computed by lhs and rhs
+func filtand(lhs, rhs evalfn) evalfn {
 	if lhs == nil {
 		return rhs
 	} else if rhs == nil {
@@ -201,6 +205,170 @@ func filtcontains(p []string, set *ion.Bag) evalfn {
 	}
 }
 
+// filtkey produces a filter for p <op> val
+// using the per-block ranges of the sort key p;
+// blocks for which the range is unknown always match
+func filtkey(p []string, op expr.CmpOp, val ion.Datum) evalfn {
+	// match returns whether any value in [min, max]
+	// could satisfy the comparison
+	match := func(min, max ion.Datum) bool {
+		lo, ok := compareKeys(min, val)
+		if !ok {
+			// a comparison between a number and a string
+			// never matches, but the rows could also hold
+			// values of other types that compare unequal
+			return op == expr.NotEquals
+		}
+		hi, _ := compareKeys(max, val)
+		switch op {
+		case expr.Equals:
+			return lo <= 0 && hi >= 0
+		case expr.NotEquals:
+			return lo != 0 || hi != 0
+		case expr.Less:
+			return lo < 0
+		case expr.LessEquals:
+			return lo <= 0
+		case expr.Greater:
+			return hi > 0
+		case expr.GreaterEquals:
+			return hi >= 0
+		}
+		return true
+	}
+	return func(f *Filter, si *SparseIndex, rest cont) {
+		k := si.searchKey(p)
+		if k == nil {
+			rest(f, 0, si.Blocks())
+			return
+		}
+		start := -1
+		for i := range k.min {
+			if k.min[i].IsEmpty() || match(k.min[i], k.max[i]) {
+				if start < 0 {
+					start = i
+				}
+				continue
+			}
+			if start >= 0 {
+				rest(f, start, i)
+				start = -1
+			}
+		}
+		if start >= 0 {
+			rest(f, start, len(k.min))
+		}
+	}
+}
+
+// keyconst returns the datum for a constant
+// that can be compared against sort key ranges
+func keyconst(e expr.Node) (ion.Datum, bool) {
+	switch e := e.(type) {
+	case expr.Integer:
+		return ion.Int(int64(e)), true
+	case expr.Float:
+		d := ion.Float(float64(e))
+		return d, isKey(d)
+	case expr.String:
+		d := ion.String(string(e))
+		return d, isKey(d)
+	}
+	return ion.Empty, false
+}
+
+// filteqconst produces a filter for p = c
+// using the constant fields
+func filteqconst(p []string, c expr.Node) evalfn {
+	switch c := c.(type) {
+	case expr.String:
+		return filteqstring(p, c)
+	case expr.Integer:
+		return filteqint(p, c)
+	}
+	return nil
+}
+
+// filtcmpconst produces a filter for p <op> c,
+// where c is an integer, float or string constant,
+// that checks both the constant fields and
+// the sort key ranges
+func filtcmpconst(p []string, op expr.CmpOp, c expr.Node, val ion.Datum) evalfn {
+	var consts evalfn
+	if op == expr.Equals {
+		consts = filteqconst(p, c)
+	}
+	return filtand(consts, filtkey(p, op, val))
+}
+
+// negate returns the comparison that
+// is equivalent to NOT (a <op> b)
+// when a and b are comparable
+func negate(op expr.CmpOp) expr.CmpOp {
+	switch op {
+	case expr.Equals:
+		return expr.NotEquals
+	case expr.NotEquals:
+		return expr.Equals
+	case expr.Less:
+		return expr.GreaterEquals
+	case expr.LessEquals:
+		return expr.Greater
+	case expr.Greater:
+		return expr.LessEquals
+	default: // expr.GreaterEquals
+		return expr.Less
+	}
+}
+
+// filtmember produces a filter for p IN (set ...);
+// a set of timestamps is the union of the ranges
+// for each timestamp, and any other set is
+// checked against the constant fields and
+// the sort key ranges
+func filtmember(p []string, set *ion.Bag) evalfn {
+	var within []evalfn
+	set.Each(func(d ion.Datum) bool {
+		ts, err := d.Timestamp()
+		if err != nil {
+			within = nil
+			return false
+		}
+		within = append(within, filtwithin(p, ts))
+		return true
+	})
+	if len(within) == 0 {
+		return filtand(filtcontains(p, set), filtkeymember(p, set))
+	}
+	return func(f *Filter, si *SparseIndex, rest cont) {
+		for i := range within {
+			within[i](f, si, rest)
+		}
+	}
+}
+
+// filtkeymember produces a filter for p IN (set ...)
+// using the sort key ranges of p
+func filtkeymember(p []string, set *ion.Bag) evalfn {
+	var eq []evalfn
+	set.Each(func(d ion.Datum) bool {
+		if !isKey(d) {
+			eq = nil
+			return false
+		}
+		eq = append(eq, filtkey(p, expr.Equals, d))
+		return true
+	})
+	if len(eq) == 0 {
+		return nil
+	}
+	return func(f *Filter, si *SparseIndex, rest cont) {
+		for i := range eq {
+			eq[i](f, si, rest)
+		}
+	}
+}
+
 // filter where !e
 func filtnegate(e expr.Node) evalfn {
 	// we expect DNF ("disjunctive normal form"),
@@ -215,7 +383,33 @@ func filtnegate(e expr.Node) evalfn {
 		//   (A-right AND B-left) OR (A-right AND B-right)
 		return filtintersect(&expr.Not{or.Left}, &expr.Not{or.Right})
 	}
-	inner := filtcompile(e)
+	// the sort key ranges only determine which blocks
+	// *may* match, so their complement is meaningless;
+	// negate the comparison instead, and only take
+	// the complement of the (exact) constant fields
+	switch e := e.(type) {
+	case *expr.Comparison:
+		if p, ok := expr.FlatPath(e.Left); ok {
+			if val, ok := keyconst(e.Right); ok {
+				var consts evalfn
+				if e.Op == expr.Equals {
+					consts = filtcomplement(filteqconst(p, e.Right))
+				}
+				return filtand(consts, filtkey(p, negate(e.Op), val))
+			}
+		}
+	case *expr.Member:
+		if p, ok := expr.FlatPath(e.Arg); ok && filtkeymember(p, &e.Set) != nil {
+			return filtcomplement(filtcontains(p, &e.Set))
+		}
+	}
+	return filtcomplement(filtcompile(e))
+}
+
+// filtcomplement produces a filter that
+// matches the complement of the ranges
+// matched by inner
+func filtcomplement(inner evalfn) evalfn {
 	if inner == nil {
 		return nil
 	}
@@ -264,7 +458,7 @@ func filtcompile(e expr.Node) evalfn {
 	case *expr.Member:
 		p, ok := expr.FlatPath(e.Arg)
 		if ok {
-			return filtcontains(p, &e.Set)
+			return filtmember(p, &e.Set)
 		}
 	case *expr.Not:
 		return filtnegate(e.Expr)
@@ -304,20 +498,9 @@ func filtcompile(e expr.Node) evalfn {
 			} else {
 				return nil
 			}
-		} else if e.Op == expr.Equals {
-			// special handling for row constants
-			switch rhs := e.Right.(type) {
-			case *expr.Timestamp:
-				// continue on to timestamp handling
-			case expr.String:
-				return filteqstring(p, rhs)
-			case expr.Integer:
-				// TODO: support more than just
-				// equality comparisons
-				return filteqint(p, rhs)
-			default:
-				return nil
-			}
+		} else if val, ok := keyconst(e.Right); ok {
+			// row constants and sort keys
+			return filtcmpconst(p, e.Op, e.Right, val)
 		}
 		ts := conv(e.Right)
 		if ts == nil {
@@ -189,6 +189,8 @@ func testFilter(t testing.TB, f *Filter, si *SparseIndex, run func(filt string,
 	run(sprintf("!(timestamp < %s or timestamp >= %s)", minute(1), minute(59)), [][2]int{{1, 59}})
 	run(sprintf("timestamp = %s", minute(1)), [][2]int{{1, 2}})
 	run(sprintf("to_unix_epoch(timestamp) = %d", unixminute(1)), [][2]int{{1, 2}})
+	run(sprintf("extract(epoch from timestamp) >= %d", unixminute(1)), [][2]int{{1, 60}})
+	run(sprintf("to_unixtime(timestamp) < %d", unixminute(1)), [][2]int{{0, 1}})
 	run(sprintf("timestamp < %s and (timestamp >= %s or timestamp > %s)", minute(10), minute(0), minute(60)), [][2]int{{0, 10}})
 	run(sprintf("!(timestamp = %s or timestamp = %s)", minute(10), minute(20)), [][2]int{{0, 10}, {11, 20}, {21, 60}})
 	run(sprintf("timestamp < %s and (timestamp >= %s or timestamp > %s)", minute(10), minute(0), minute(60)), [][2]int{{0, 10}})
@@ -197,12 +199,23 @@ func testFilter(t testing.TB, f *Filter, si *SparseIndex, run func(filt string,
 	run(sprintf("foo IN ('foo', 'bar', 'baz', 'quux', 0, 1, 2, 3, 4, 5, 6)"), [][2]int{{0, 60}})
 	run(sprintf("foo IN ('food', 'bar', 'baz', 'quux', 0, 1, 2, 3, 4, 5, 6)"), [][2]int{{0, 0}})
 	run(sprintf("foo = 'bar'"), [][2]int{{0, 0}})
+	// a set of timestamps is a union of ranges
+	run(sprintf("timestamp IN (%s, %s, %s, %s, %s, %s, %s, %s, %s, %s, %s)",
+		minute(1), minute(2), minute(3), minute(4), minute(5), minute(6),
+		minute(7), minute(8), minute(9), minute(10), minute(30)),
+		[][2]int{{1, 11}, {30, 31}})
+	run(sprintf("!(timestamp IN (%s, %s, %s, %s, %s, %s, %s, %s, %s, %s, %s))",
+		minute(1), minute(2), minute(3), minute(4), minute(5), minute(6),
+		minute(7), minute(8), minute(9), minute(10), minute(30)),
+		[][2]int{{0, 60}})
 	run(sprintf("foo != 'bar'"), [][2]int{{0, 60}})
 	run(sprintf("foo.x = 'bar'"), [][2]int{{0, 60}})
 	run(sprintf("foo = 100"), [][2]int{{0, 0}})
 	run(sprintf("bar = 100"), [][2]int{{0, 60}})
 	run(sprintf("bar = 999"), [][2]int{{0, 0}})
 	run(sprintf("bar = 'foo'"), [][2]int{{0, 0}})
+	run(sprintf("from_unixtime(bar) = `1970-01-01T00:01:40Z`"), [][2]int{{0, 60}})
+	run(sprintf("from_unixtime(bar) = `1970-01-01T00:01:41Z`"), [][2]int{{0, 0}})
 	run(sprintf("foo = 'foo' or bar = 'bar'"), [][2]int{{0, 60}})
 	run(sprintf("timestamp < %s and foo = 'foo'", minute(10)), [][2]int{{0, 10}})
 	run(sprintf("timestamp < %s and foo = 'bar'", minute(10)), [][2]int{{0, 0}})
@@ -210,3 +223,114 @@ func testFilter(t testing.TB, f *Filter, si *SparseIndex, run func(filt string,
 	run(sprintf("foo = 'bar' and timestamp < %s", minute(10)), [][2]int{{0, 0}})
 	run(sprintf("timestamp < %s and (foo = 'foo' or foo = 'bar')", minute(10)), [][2]int{{0, 10}})
 }
+
+func TestFilterKeys(t *testing.T) {
+	var si SparseIndex
+	x := []string{"x"}
+	s := []string{"s"}
+	push := func(rng ...Range) { si.Push(rng) }
+	push(NewRange(x, ion.Int(0), ion.Int(9)), NewRange(s, ion.String("a"), ion.String("c")))
+	push(NewRange(s, ion.String("d"), ion.String("f")))
+	push(NewRange(x, ion.Int(20), ion.Int(29)))
+	push(NewRange(x, ion.Int(5), ion.Uint(25)), NewRange(s, ion.String("b"), ion.String("e")))
+	push(NewRange(x, ion.Float(1.5), ion.Float(2.5)), NewRange(s, ion.String("z"), ion.String("z")))
+	if si.Blocks() != 5 {
+		t.Fatalf("%d blocks?", si.Blocks())
+	}
+
+	run := func(filt string, ranges [][2]int) {
+		t.Helper()
+		q, err := partiql.Parse([]byte("SELECT * WHERE " + filt))
+		if err != nil {
+			t.Fatal(err)
+		}
+		q.Body = expr.Simplify(q.Body, expr.NoHint)
+		var f Filter
+		f.Compile(q.Body.(*expr.Select).Where)
+		var out [][2]int
+		f.Visit(&si, func(start, end int) {
+			out = append(out, [2]int{start, end})
+		})
+		if !slices.Equal(out, ranges) {
+			t.Errorf("%s: got %v; wanted %v", filt, out, ranges)
+		}
+	}
+	run("x < 5", [][2]int{{0, 2}, {4, 5}})
+	run("x <= 5", [][2]int{{0, 2}, {3, 5}})
+	run("x = 2", [][2]int{{0, 2}, {4, 5}})
+	run("x = 22", [][2]int{{1, 4}})
+	run("x > 100", [][2]int{{1, 2}})
+	run("!(x > 100)", [][2]int{{0, 5}})
+	run("!(x < 20)", [][2]int{{1, 4}})
+	run("!(x = 22)", [][2]int{{0, 5}})
+	run("x <> 22", [][2]int{{0, 5}})
+	run("x = 'foo'", [][2]int{{1, 2}})
+	run("x >= 2.5", [][2]int{{0, 5}})
+	run("x > 2.5", [][2]int{{0, 4}})
+	run("x IN (22, 100)", [][2]int{{1, 4}})
+	run("s = 'e'", [][2]int{{1, 4}})
+	run("s < 'b'", [][2]int{{0, 1}, {2, 3}})
+	run("x < 5 AND s = 'z'", [][2]int{{4, 5}})
+	run("x < 5 OR s = 'z'", [][2]int{{0, 3}, {4, 5}})
+	run("y < 5", [][2]int{{0, 5}})
+	run("x > 1000 AND s > 'zz'", [][2]int{{0, 0}})
+}
+
+func TestFilterConstSummary(t *testing.T) {
+	x := []string{"x"}
+	mk := func(region string, day int) SparseIndex {
+		var si SparseIndex
+		si.consts = ion.NewStruct(nil, []ion.Field{
+			{Label: "region", Datum: ion.String(region)},
+			{Label: "day", Datum: ion.Int(int64(day))},
+		})
+		si.Push([]Range{NewRange(x, ion.Int(0), ion.Int(9))})
+		return si
+	}
+	parts := []SparseIndex{
+		mk("us", 1),
+		mk("eu", 1),
+		mk("us", 2),
+		mk("ap", 3),
+	}
+	if min, max, ok := parts[0].KeyRange([]string{"region"}); !ok ||
+		!min.Equal(ion.String("us")) || !max.Equal(ion.String("us")) {
+		t.Fatalf("KeyRange(region) = %v %v %v", min, max, ok)
+	}
+	// the summary of each set of constants
+	// is indexed as a key range
+	var sum SparseIndex
+	for i := range parts {
+		sum.pushSummary(&parts[i])
+	}
+	run := func(filt string, ranges [][2]int) {
+		t.Helper()
+		q, err := partiql.Parse([]byte("SELECT * WHERE " + filt))
+		if err != nil {
+			t.Fatal(err)
+		}
+		q.Body = expr.Simplify(q.Body, expr.NoHint)
+		var f Filter
+		f.Compile(q.Body.(*expr.Select).Where)
+		var out [][2]int
+		f.Visit(&sum, func(start, end int) {
+			out = append(out, [2]int{start, end})
+		})
+		if !slices.Equal(out, ranges) {
+			t.Errorf("%s: got %v; wanted %v", filt, out, ranges)
+		}
+	}
+	run("region = 'us'", [][2]int{{0, 1}, {2, 3}})
+	run("region = 'us' AND day = 2", [][2]int{{2, 3}})
+	run("region IN ('eu', 'ap')", [][2]int{{1, 2}, {3, 4}})
+	run("region <> 'us'", [][2]int{{1, 2}, {3, 4}})
+	run("day > 1", [][2]int{{2, 4}})
+	run("region = 'sa'", [][2]int{{0, 0}})
+	run("x = 5", [][2]int{{0, 4}})
+
+	// merging descriptors from different
+	// partitions widens the range
+	sum.updateSummary(&parts[0])
+	run("region = 'us'", [][2]int{{0, 1}, {2, 4}})
+	run("region = 'eu'", [][2]int{{1, 2}, {3, 4}})
+}
@@ -163,6 +163,18 @@ type Index struct {
 	// Scanning indicates that scanning has
 	// not yet completed.
 	Scanning bool
+	// Checkpoint, if non-zero, is the time at which
+	// the most recent complete scan started.
+	// Every object that existed before Checkpoint
+	// has been listed by a scan.
+	Checkpoint date.Time
+
+	// DataKey, if non-empty, is the DataKey
+	// used to encrypt the objects referenced
+	// by the index, wrapped with the same Key
+	// that is used to sign the index.
+	// (See DataKey.Wrap and UnwrapDataKey.)
+	DataKey []byte
 }
 
 const (
@@ -222,6 +234,7 @@ func Sign(key *Key, idx *Index) ([]byte, error) {
 		lastscan = st.Intern("last-scan")
 		scanning = st.Intern("scanning")
 		cursors  = st.Intern("cursors")
+		chkpoint = st.Intern("checkpoint")
 		algo     = st.Intern("algo")
 		size     = st.Intern("size")
 		contents = st.Intern("contents")
@@ -229,6 +242,7 @@ func Sign(key *Key, idx *Index) ([]byte, error) {
 		expiry   = st.Intern("expiry")
 		indirect = st.Intern("indirect")
 		inputs   = st.Intern("inputs")
+		datakey  = st.Intern("data-key")
 	)
 	var ibuf ion.Buffer
 	buf.BeginStruct(-1)
@@ -278,6 +292,14 @@ func Sign(key *Key, idx *Index) ([]byte, error) {
 		}
 		buf.EndList()
 	}
+	if !idx.Checkpoint.IsZero() {
+		buf.BeginField(chkpoint)
+		buf.WriteTime(idx.Checkpoint)
+	}
+	if len(idx.DataKey) > 0 {
+		buf.BeginField(datakey)
+		buf.WriteBlob(idx.DataKey)
+	}
 	if len(idx.Inline) == 0 {
 		// Do nothing...
 	} else if idx.Algo != "" {
@@ -599,6 +621,10 @@ func DecodeIndex(key *Key, index []byte, opts Flag) (*Index, error) {
 			})
 		case "last-scan":
 			idx.LastScan, err = f.Timestamp()
+		case "checkpoint":
+			idx.Checkpoint, err = f.Timestamp()
+		case "data-key":
+			idx.DataKey, err = f.Blob()
 		default:
 			err = fmt.Errorf("unexpected field %q", f.Label)
 		}
@@ -732,17 +758,113 @@ func (c *IndexConfig) SyncOutputs(idx *Index, ofs UploadFS, dir string) error {
 	// take the bottom half of the inline list and
 	// compact the results into larger packfiles
 	half := len(idx.Inline) / 2
-	lo, hi := idx.Inline[:half], idx.Inline[half:]
+	lo := idx.Inline[:half]
 	compacted, toRemove, err := c.Compact(ofs, lo)
 	if err != nil {
 		return err
 	}
-	err = c.append(idx, ofs, dir, compacted, len(lo))
+	err = c.Demote(idx, ofs, dir, len(lo), compacted)
 	if err != nil {
 		return err
 	}
 	idx.ToDelete = append(idx.ToDelete, toRemove...)
-	idx.Inline = hi
+	return nil
+}
+
+// Demote removes the first n entries of idx.Inline
+// and adds lst to the indirect tree in their place.
+// The descriptors in lst should reference exactly the
+// same data as the n descriptors they replace (for example,
+// the result of compacting those descriptors), since
+// the object numbering used by idx.Inputs is preserved.
+func (c *IndexConfig) Demote(idx *Index, ofs UploadFS, dir string, n int, lst []Descriptor) error {
+	if n > len(idx.Inline) {
+		return fmt.Errorf("blockfmt.IndexConfig.Demote: %d entries > %d inline", n, len(idx.Inline))
+	}
+	if n == 0 {
+		return nil
+	}
+	err := c.append(idx, ofs, dir, lst, n)
+	if err != nil {
+		return err
+	}
+	idx.Inline = idx.Inline[n:]
+	return nil
+}
+
+// Replace replaces each descriptor in idx (either
+// inline or in the indirect tree) whose path is a key
+// in repl with the corresponding value in repl.
+// Only the indirect refs that may contain descriptors
+// matching filt are searched; a nil filt searches every ref.
+//
+// The replacement descriptors should reference a subset
+// of the data referenced by the descriptors they replace
+// (for example, the result of deleting rows from those
+// descriptors), since the sparse index of the indirect tree
+// is not recomputed and the object numbering used by idx.Inputs
+// is preserved. The indirect refs that are rewritten are added
+// to idx.ToDelete; the replaced descriptors are not.
+//
+// Replace returns an error if any of the paths
+// in repl is not referenced by idx.
+func (c *IndexConfig) Replace(idx *Index, ofs UploadFS, dir string, filt *Filter, repl map[string]Descriptor) error {
+	found := 0
+	for i := range idx.Inline {
+		if d, ok := repl[idx.Inline[i].Path]; ok {
+			idx.Inline[i] = d
+			found++
+		}
+	}
+	var err error
+	rewrite := func(r *IndirectRef) {
+		if err != nil || found == len(repl) {
+			return
+		}
+		var lst []Descriptor
+		lst, err = idx.Indirect.decode(ofs, r, nil, nil)
+		if err != nil {
+			return
+		}
+		n := 0
+		for i := range lst {
+			if d, ok := repl[lst[i].Path]; ok {
+				lst[i] = d
+				n++
+			}
+		}
+		if n == 0 {
+			return
+		}
+		prev := r.Path
+		err = writeRef(ofs, dir, r, lst)
+		if err != nil {
+			return
+		}
+		idx.ToDelete = append(idx.ToDelete, Quarantined{
+			Path:   prev,
+			Expiry: date.Now().Add(c.Expiry).Truncate(time.Microsecond),
+		})
+		found += n
+	}
+	refs := idx.Indirect.Refs
+	if filt == nil || filt.Trivial() {
+		for i := range refs {
+			rewrite(&refs[i])
+		}
+	} else {
+		filt.Visit(&idx.Indirect.Sparse, func(start, end int) {
+			for i := start; i < end; i++ {
+				rewrite(&refs[i])
+			}
+		})
+	}
+	if err != nil {
+		return err
+	}
+	if found != len(repl) {
+		return fmt.Errorf("blockfmt.IndexConfig.Replace: found %d of %d descriptors", found, len(repl))
+	}
 	return nil
 }
 
@@ -779,6 +901,87 @@ func (idx *Index) TimeRange(path []string) (min, max date.Time, ok bool) {
 	return min, max, ok
 }
 
+// KeyRange returns the inclusive range of values
+// of the sort key at path across the whole table.
+// Unlike TimeRange, KeyRange only returns ok = true
+// if the range is known for every indexed block.
+func (idx *Index) KeyRange(path []string) (min, max ion.Datum, ok bool) {
+	add := func(s *SparseIndex) bool {
+		if s.Blocks() == 0 {
+			return true
+		}
+		kmin, kmax, kok := s.KeyRange(path)
+		if !kok {
+			return false
+		}
+		if ok {
+			min, max = keyUnion(min, max, kmin, kmax)
+			return !min.IsEmpty()
+		}
+		min, max, ok = kmin, kmax, true
+		return true
+	}
+	for i := range idx.Inline {
+		if !add(&idx.Inline[i].Trailer.Sparse) {
+			return ion.Empty, ion.Empty, false
+		}
+	}
+	if !add(&idx.Indirect.Sparse) {
+		return ion.Empty, ion.Empty, false
+	}
+	return min, max, ok
+}
+
+// Blocks returns the number of blocks that
+// filt does not exclude from the table and
+// the total number of blocks in the table.
+// If filt is nil, all of the blocks are matched.
+// The descriptors in the indirect tree (if any)
+// are read from ifs.
+func (idx *Index) Blocks(ifs InputFS, filt *Filter) (matching, total int, err error) {
+	count := func(d *Descriptor) {
+		n := len(d.Trailer.Blocks)
+		total += n
+		if filt == nil {
+			matching += n
+			return
+		}
+		filt.Visit(&d.Trailer.Sparse, func(start, end int) {
+			matching += end - start
+		})
+	}
+	for i := range idx.Inline {
+		count(&idx.Inline[i])
+	}
+	if len(idx.Indirect.Refs) == 0 {
+		return matching, total, nil
+	}
+	// we need every descriptor to compute the
+	// total, so don't filter the indirect refs
+	descs, err := idx.Indirect.Search(ifs, nil)
+	if err != nil {
+		return 0, 0, err
+	}
+	for i := range descs {
+		count(&descs[i])
+	}
+	return matching, total, nil
+}
+
+// Schema returns the schema of all the rows in
+// the table, or nil if the schema of some of the
+// packed objects is not known.
+func (idx *Index) Schema() *Schema {
+	ret := new(Schema)
+	if len(idx.Indirect.Refs) > 0 {
+		if idx.Indirect.Schema == nil {
+			return nil
+		}
+		ret.Merge(idx.Indirect.Schema)
+	}
+	return mergeSchema(ret, idx.Inline)
+}
+
 // Objects returns the number of packed objects
 // that are pointed to by this Index.
 func (idx *Index) Objects() int {
@@ -111,12 +111,13 @@ func TestLargeIndexEncoding(t *testing.T) {
 		&TimeRange{[]string{"a", "b"}, time0.Add(2 * time.Minute), time0.Add(4 * time.Minute)},
 	})
 	idx := Index{
-		Name:     "the-index",
-		Created:  time0,
-		Algo:     "zstd",
-		Scanning: true,
-		Cursors:  []string{"a/b/c", "x/y/z"},
-		LastScan: time0,
+		Name:       "the-index",
+		Created:    time0,
+		Algo:       "zstd",
+		Scanning:   true,
+		Cursors:    []string{"a/b/c", "x/y/z"},
+		LastScan:   time0,
+		Checkpoint: time0.Add(-time.Hour),
 		Inline: []Descriptor{
 			{
 				ObjectInfo: ObjectInfo{
@@ -0,0 +1,433 @@
+// Copyright (C) 2022 Sneller, Inc.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package blockfmt
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"golang.org/x/exp/slices"
+
+	"github.com/SnellerInc/sneller/ion"
+)
+
+// maxKeyString is the maximum length of a string
+// that is stored as the min or max of a sort key;
+// ranges with longer strings are not indexed
+const maxKeyString = 256
+
+// keyIndex holds the inclusive range of values
+// of a sort key within each block.
+//
+// Unlike a TimeIndex, the ranges of successive
+// blocks are not assumed to be monotonic, so
+// the range of every block is stored explicitly.
+// The range of a block is ion.Empty if it is not known.
+type keyIndex struct {
+	path     []string
+	min, max []ion.Datum
+}
+
+// isKey returns whether d can be
+// the min or max of a key range
+func isKey(d ion.Datum) bool {
+	switch d.Type() {
+	case ion.IntType, ion.UintType:
+		return true
+	case ion.FloatType:
+		f, _ := d.Float()
+		return !math.IsNaN(f)
+	case ion.StringType:
+		s, _ := d.StringShared()
+		return len(s) <= maxKeyString
+	}
+	return false
+}
+
+// compareKeys compares two key values.
+// The result is only valid if ok is true,
+// which is the case when both a and b
+// are numbers or both are strings.
+func compareKeys(a, b ion.Datum) (cmp int, ok bool) {
+	if a.IsString() || b.IsString() {
+		if !a.IsString() || !b.IsString() {
+			return 0, false
+		}
+		as, _ := a.StringShared()
+		bs, _ := b.StringShared()
+		switch {
+		case string(as) < string(bs):
+			return -1, true
+		case string(as) > string(bs):
+			return 1, true
+		}
+		return 0, true
+	}
+	if a.IsFloat() || b.IsFloat() {
+		af, ok := keyFloat(a)
+		if !ok {
+			return 0, false
+		}
+		bf, ok := keyFloat(b)
+		if !ok {
+			return 0, false
+		}
+		switch {
+		case af < bf:
+			return -1, true
+		case af > bf:
+			return 1, true
+		}
+		return 0, true
+	}
+	ai, aneg, ok := keyInt(a)
+	if !ok {
+		return 0, false
+	}
+	bi, bneg, ok := keyInt(b)
+	if !ok {
+		return 0, false
+	}
+	switch {
+	case aneg && !bneg:
+		return -1, true
+	case !aneg && bneg:
+		return 1, true
+	case ai < bi:
+		return -1, true
+	case ai > bi:
+		return 1, true
+	}
+	return 0, true
+}
+
+func keyFloat(d ion.Datum) (float64, bool) {
+	switch d.Type() {
+	case ion.FloatType:
+		f, _ := d.Float()
+		return f, true
+	case ion.IntType:
+		i, _ := d.Int()
+		return float64(i), true
+	case ion.UintType:
+		u, _ := d.Uint()
+		return float64(u), true
+	}
+	return 0, false
+}
+
+// keyInt returns the two's complement
+// representation of an integer and its sign
+func keyInt(d ion.Datum) (u uint64, neg, ok bool) {
+	switch d.Type() {
+	case ion.IntType:
+		i, _ := d.Int()
+		return uint64(i), i < 0, true
+	case ion.UintType:
+		u, _ := d.Uint()
+		return u, false, true
+	}
+	return 0, false, false
+}
+
+// keyUnion returns the union of two ranges;
+// the result is empty if either range is
+// empty or the ranges are not comparable
+func keyUnion(min1, max1, min2, max2 ion.Datum) (min, max ion.Datum) {
+	if min1.IsEmpty() || min2.IsEmpty() {
+		return ion.Empty, ion.Empty
+	}
+	lo, ok := compareKeys(min1, min2)
+	if !ok {
+		return ion.Empty, ion.Empty
+	}
+	hi, ok := compareKeys(max1, max2)
+	if !ok {
+		return ion.Empty, ion.Empty
+	}
+	min, max = min1, max1
+	if lo > 0 {
+		min = min2
+	}
+	if hi < 0 {
+		max = max2
+	}
+	return min, max
+}
+
+// UnionKeyRange returns the union of two ranges
+// of sort key values (see Index.KeyRange), or
+// ok = false if the ranges are not comparable.
+func UnionKeyRange(min1, max1, min2, max2 ion.Datum) (min, max ion.Datum, ok bool) {
+	min, max = keyUnion(min1, max1, min2, max2)
+	return min, max, !min.IsEmpty()
+}
+
+func (k *keyIndex) blocks() int { return len(k.min) }
+
+func (k *keyIndex) push(min, max ion.Datum) {
+	k.min = append(k.min, min)
+	k.max = append(k.max, max)
+}
+
+func (k *keyIndex) pushEmpty(n int) {
+	for i := 0; i < n; i++ {
+		k.push(ion.Empty, ion.Empty)
+	}
+}
+
+// editLatest extends the range of the latest block
+func (k *keyIndex) editLatest(min, max ion.Datum) {
+	n := len(k.min) - 1
+	k.min[n], k.max[n] = keyUnion(k.min[n], k.max[n], min, max)
+}
+
+// summary returns the union of
+// the ranges of all of the blocks
+func (k *keyIndex) summary() (min, max ion.Datum, ok bool) {
+	if len(k.min) == 0 {
+		return ion.Empty, ion.Empty, false
+	}
+	min, max = k.min[0], k.max[0]
+	for i := 1; i < len(k.min) && !min.IsEmpty(); i++ {
+		min, max = keyUnion(min, max, k.min[i], k.max[i])
+	}
+	return min, max, !min.IsEmpty()
+}
+
+func (k *keyIndex) slice(i, j int) keyIndex {
+	return keyIndex{
+		path: k.path,
+		min:  slices.Clip(k.min[i:j]),
+		max:  slices.Clip(k.max[i:j]),
+	}
+}
+
+func (k *keyIndex) clone() keyIndex {
+	return keyIndex{
+		path: k.path,
+		min:  slices.Clone(k.min),
+		max:  slices.Clone(k.max),
+	}
+}
+
+func (k *keyIndex) encode(dst *ion.Buffer, st *ion.Symtab) {
+	dst.BeginStruct(-1)
+	dst.BeginField(st.Intern("path"))
+	dst.BeginList(-1)
+	for i := range k.path {
+		dst.WriteSymbol(st.Intern(k.path[i]))
+	}
+	dst.EndList()
+	lst := func(name string, vals []ion.Datum) {
+		dst.BeginField(st.Intern(name))
+		dst.BeginList(-1)
+		for i := range vals {
+			if vals[i].IsEmpty() {
+				dst.WriteNull()
+			} else {
+				vals[i].Encode(dst, st)
+			}
+		}
+		dst.EndList()
+	}
+	lst("min", k.min)
+	lst("max", k.max)
+	dst.EndStruct()
+}
+
+func (d *TrailerDecoder) decodeKey(k *keyIndex, v ion.Datum) error {
+	lst := func(dst *[]ion.Datum, v ion.Datum) error {
+		return v.UnpackList(func(v ion.Datum) error {
+			if v.IsNull() {
+				*dst = append(*dst, ion.Empty)
+			} else {
+				*dst = append(*dst, v.Clone())
+			}
+			return nil
+		})
+	}
+	err := v.UnpackStruct(func(f ion.Field) error {
+		var err error
+		switch f.Label {
+		case "path":
+			k.path, err = d.path(f.Datum)
+		case "min":
+			err = lst(&k.min, f.Datum)
+		case "max":
+			err = lst(&k.max, f.Datum)
+		}
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	if len(k.min) != len(k.max) {
+		return fmt.Errorf("key index %v: %d min values but %d max values", k.path, len(k.min), len(k.max))
+	}
+	return nil
+}
+
+// KeyRange returns the inclusive range of
+// values of the sort key at path across all
+// of the blocks in s. The returned values are
+// either both numbers or both strings.
+// KeyRange returns ok = false if the range
+// is not known for every block.
+//
+// Constant fields (see Const) that hold
+// numbers or strings have a range that
+// consists of just their value.
+func (s *SparseIndex) KeyRange(path []string) (min, max ion.Datum, ok bool) {
+	if v, ok := s.constKey(path); ok {
+		return v, v, true
+	}
+	k := s.searchKey(path)
+	if k == nil {
+		return ion.Empty, ion.Empty, false
+	}
+	return k.summary()
+}
+
+// constKey returns the value of the constant
+// field at path if it can be used as a key
+func (s *SparseIndex) constKey(path []string) (ion.Datum, bool) {
+	if len(path) != 1 {
+		return ion.Empty, false
+	}
+	v, ok := s.Const(path[0])
+	if !ok || !isKey(v) {
+		return ion.Empty, false
+	}
+	return v, true
+}
+
+// eachKey calls fn with the path of each key
+// for which s may know a range (see KeyRange)
+func (s *SparseIndex) eachKey(fn func(path []string)) {
+	if !s.consts.IsEmpty() {
+		s.consts.Each(func(f ion.Field) error {
+			if isKey(f.Datum) {
+				fn([]string{f.Label})
+			}
+			return nil
+		})
+	}
+	for n := range s.keys {
+		if _, ok := s.constKey(s.keys[n].path); !ok {
+			fn(s.keys[n].path)
+		}
+	}
+}
+
+func (s *SparseIndex) searchKey(path []string) *keyIndex {
+	j := sort.Search(len(s.keys), func(i int) bool {
+		return !pathless(s.keys[i].path, path)
+	})
+	if j < len(s.keys) && slices.Equal(path, s.keys[j].path) {
+		return &s.keys[j]
+	}
+	return nil
+}
+
+// insertKey inserts a new key index for path,
+// with every existing block marked as unknown
+func (s *SparseIndex) insertKey(path []string) *keyIndex {
+	j := sort.Search(len(s.keys), func(i int) bool {
+		return !pathless(s.keys[i].path, path)
+	})
+	s.keys = append(s.keys, keyIndex{})
+	copy(s.keys[j+1:], s.keys[j:])
+	s.keys[j] = keyIndex{path: path}
+	s.keys[j].pushEmpty(s.blocks)
+	return &s.keys[j]
+}
+
+// pushKey sets the range of the key at
+// path for the next block (see bump)
+func (s *SparseIndex) pushKey(path []string, min, max ion.Datum) {
+	if !isKey(min) || !isKey(max) {
+		return
+	}
+	k := s.searchKey(path)
+	if k == nil {
+		k = s.insertKey(path)
+	} else if k.blocks() > s.blocks {
+		return // already pushed
+	}
+	k.push(min.Clone(), max.Clone())
+}
+
+// appendKeys appends the key ranges of blocks
+// [i:j] of next to s; keys that are only present
+// in one of the two indexes are marked as
+// unknown for the blocks of the other index
+func (s *SparseIndex) appendKeys(next *SparseIndex, i, j int) {
+	for n := range next.keys {
+		if s.searchKey(next.keys[n].path) == nil {
+			s.insertKey(next.keys[n].path)
+		}
+	}
+	for n := range s.keys {
+		k := &s.keys[n]
+		from := next.searchKey(k.path)
+		if from == nil {
+			k.pushEmpty(j - i)
+			continue
+		}
+		k.min = append(k.min, from.min[i:j]...)
+		k.max = append(k.max, from.max[i:j]...)
+	}
+}
+
+// pushKeySummary pushes the union of the key
+// ranges of all of the blocks in from as the
+// range of the next block (see bump)
+//
+// The constants of from are pushed as key ranges
+// as well, so that an index that summarizes
+// descriptors from different partitions (i.e.
+// the IndirectTree) can be filtered on the
+// partition values without reading the descriptors.
+func (s *SparseIndex) pushKeySummary(from *SparseIndex) {
+	from.eachKey(func(path []string) {
+		if min, max, ok := from.KeyRange(path); ok {
+			s.pushKey(path, min, max)
+		}
+	})
+}
+
+// updateKeySummary extends the key ranges
+// of the latest block with the union of the
+// key ranges of all of the blocks in from
+func (s *SparseIndex) updateKeySummary(from *SparseIndex) {
+	if s.blocks == 0 {
+		panic("SparseIndex.updateKeySummary with zero blocks")
+	}
+	for n := range s.keys {
+		k := &s.keys[n]
+		min, max, _ := from.KeyRange(k.path)
+		k.editLatest(min, max)
+	}
+	// keys that appear for the first time
+	// are unknown for the latest block, since
+	// it covers data that was not indexed
+	from.eachKey(func(path []string) {
+		if s.searchKey(path) == nil {
+			s.insertKey(path)
+		}
+	})
+}
@@ -16,10 +16,12 @@ package blockfmt
 
 import (
 	"fmt"
+	"hash/crc32"
 	"io"
 	"sort"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/SnellerInc/sneller/ion"
 )
@@ -58,6 +60,18 @@ type MultiWriter struct {
 	// MinChunksPerBlock).
 	MinChunksPerBlock int
 
+	// MaxRetries is the number of times that
+	// a failed upload of an output part (or the
+	// final call to Output.Close) is retried before
+	// the error is returned. Errors for which
+	// IsFatal returns true are never retried.
+	MaxRetries int
+	// RetryDelay is the delay before the first
+	// retry of a failed upload. The delay doubles
+	// after each retry. If RetryDelay is zero,
+	// DefaultRetryDelay is used.
+	RetryDelay time.Duration
+
 	// Trailer is the trailer that
 	// is appended to the output stream.
 	// The fields in Trailer are only
@@ -87,8 +101,25 @@ type MultiWriter struct {
 	}
 	refcount   int32
 	skipChecks bool
+	// norows is set if some block
+	// has an unknown number of rows
+	norows bool
+
+	// uploaded is the size of each part
+	// that has been uploaded successfully
+	uploaded map[int64]int64
+	// final and size are set once Close
+	// has computed the final part and the
+	// expected size of the output, so that
+	// a failed Close can be resumed
+	final []byte
+	size  int64
 }
 
+// DefaultRetryDelay is the default value
+// of MultiWriter.RetryDelay.
+const DefaultRetryDelay = 100 * time.Millisecond
+
 type span struct {
 	// id of the stream that produced the span
 	tid int
@@ -115,6 +146,7 @@ type singleStream struct {
 	comp        Compressor
 	lastblock   int64
 	flushblocks int
+	rows        int64 // rows since the last block
 
 	bg chan error
 }
@@ -172,7 +204,8 @@ func (m *MultiWriter) Open() (io.WriteCloser, error) {
 
 func (m *MultiWriter) writeStart(r io.Reader, t *Trailer) error {
 	m.init()
-	if t.Algo != m.Algo || 1<<t.BlockShift != m.InputAlign {
+	if t.Algo != m.Algo || 1<<t.BlockShift != m.InputAlign ||
+		(m.Checksums && !t.Checksums) || t.Encrypted {
 		return nil // not directly compatible
 	}
 	j, offset := pickPrefix(t, m.MinChunksPerBlock)
@@ -187,6 +220,7 @@ func (m *MultiWriter) writeStart(r io.Reader, t *Trailer) error {
 	m.base = offset
 	m.Trailer.Blocks = t.Blocks[:j]
 	m.Trailer.Sparse = t.Sparse.Trim(j)
+	m.norows = m.norows || !t.RowCounts
 	t.Blocks = t.Blocks[j:]
 	t.Sparse = t.Sparse.Slice(j, t.Sparse.Blocks())
 	return nil
@@ -213,26 +247,70 @@ func (s *singleStream) upload() error {
 	// swap buffers; buf2 is always the
 	// one that is "owned" by the background upload
 	s.buf, s.buf2 = s.buf2[:0], s.buf[:0]
-	up := s.parent.Output
+	m := s.parent
 	num := s.curspan.partnum
 	go func() {
-		s.bg <- up.Upload(num, output)
+		s.bg <- m.uploadPart(num, output)
 	}()
 	return nil
 }
 
+// retry calls fn until it succeeds, it returns
+// a fatal error, or m.MaxRetries retries have failed
+func (m *MultiWriter) retry(fn func() error) error {
+	delay := m.RetryDelay
+	if delay <= 0 {
+		delay = DefaultRetryDelay
+	}
+	err := fn()
+	for i := 0; err != nil && i < m.MaxRetries && !IsFatal(err); i++ {
+		time.Sleep(delay)
+		delay *= 2
+		err = fn()
+	}
+	return err
+}
+
+// uploadPart uploads contents as the given part
+// and records the size of the part once it has
+// been uploaded so that Close can verify the output
+func (m *MultiWriter) uploadPart(num int64, contents []byte) error {
+	err := m.retry(func() error {
+		return m.Output.Upload(num, contents)
+	})
+	if err != nil {
+		return fmt.Errorf("blockfmt.MultiWriter: uploading part %d: %w", num, err)
+	}
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if m.uploaded == nil {
+		m.uploaded = make(map[int64]int64)
+	}
+	m.uploaded[num] = int64(len(contents))
+	return nil
+}
+
 // Flush implements ion.Flusher
 func (s *singleStream) Flush() error {
 	if s.flushblocks > 0 {
 		// add any recent metadata
 		// to the blocks written since the last Flush
-		s.curspan.blockmap = append(s.curspan.blockmap, blockpart{
+		ranges, keys := s.futureRange.pop()
+		part := blockpart{
 			offset: s.lastblock,
 			chunks: s.flushblocks,
-			ranges: s.futureRange.pop(),
-		})
+			ranges: ranges,
+			keys:   keys,
+			size:   int64(len(s.buf)) - s.lastblock,
+			rows:   s.rows,
+		}
+		if s.parent.Checksums {
+			part.crc = crc32.Checksum(s.buf[s.lastblock:], crcTable)
+		}
+		s.curspan.blockmap = append(s.curspan.blockmap, part)
 		s.lastblock = int64(len(s.buf))
 		s.flushblocks = 0
+		s.rows = 0
 	}
 	// actually flush only if we've buffered
 	// enough to satisfy the upload invariants
@@ -267,6 +345,9 @@ func (s *singleStream) Write(p []byte) (int, error) {
 		return 0, fmt.Errorf("blockfmt.MultiWriter: flush, but then no BVM")
 	}
 	s.flushblocks++
+	if !s.parent.skipChecks {
+		s.rows += countRows(p)
+	}
 	var err error
 	s.buf, err = appendFrame(s.buf, s.comp, p)
 	return len(p), err
@@ -276,8 +357,9 @@ func (s *singleStream) setSymbols(st *ion.Symtab) {
 	s.comp.(*zionCompressor).enc.SetSymbols(st)
 }
 
-func (s *singleStream) writeCompressed(p []byte) error {
+func (s *singleStream) writeCompressed(p []byte, rows int64) error {
 	s.flushblocks++
+	s.rows += rows
 	s.buf = appendRawFrame(s.buf, p)
 	return nil
 }
@@ -435,6 +517,10 @@ func (m *MultiWriter) finalize() {
 				offset: block.offset + offset,
 				chunks: block.chunks,
 				ranges: block.ranges,
+				keys:   block.keys,
+				size:   block.size,
+				crc:    block.crc,
+				rows:   block.rows,
 			})
 			prev = block.offset
 		}
@@ -487,18 +573,57 @@ func coalesce(blocks []blockpart, min int) []blockpart {
 // will be populated with the trailer that
 // was assembled from all the constituent
 // spans of input data.
+//
+// Before returning successfully, Close verifies
+// that every part was uploaded in full and that
+// the size of the output object is the size of
+// the data written to it. If Close returns an
+// error that is not the result of a failed
+// verification, it may be called again to resume
+// the upload of the final part of the output.
 func (m *MultiWriter) Close() error {
 	if atomic.LoadInt32(&m.refcount) != 0 {
 		panic("race between stream Close() and MultiWriter Close()")
 	}
-	m.finalize()
-	finalcomp := getCompressor(m.Algo)
-	if finalcomp == nil {
-		return fmt.Errorf("blockfmt: no such compression algorithm %q", m.Algo)
+	if m.final == nil {
+		m.finalize()
+		m.Trailer.RowCounts = !m.norows && !m.skipChecks
+		finalcomp := getCompressor(m.Algo)
+		if finalcomp == nil {
+			return fmt.Errorf("blockfmt: no such compression algorithm %q", m.Algo)
+		}
+
+		// compute the final sparse index:
+		trailer := m.Trailer.trailer(finalcomp.Name(), m.InputAlign)
+		finalcomp.Close()
+		m.final = append(m.unallocated.buf, trailer...)
+		m.size = m.Trailer.Offset + int64(len(trailer))
+	}
+	err := m.retry(func() error {
+		return m.Output.Close(m.final)
+	})
+	if err != nil {
+		return err
 	}
+	return m.verify()
+}
 
-	// compute the final sparse index:
-	m.unallocated.buf = append(m.unallocated.buf, m.Trailer.trailer(finalcomp.Name(), m.InputAlign)...)
-	finalcomp.Close()
-	return m.Output.Close(m.unallocated.buf)
+// verify checks that each span was uploaded
+// in full and that the size of the output
+// matches the size of the data written to it
+func (m *MultiWriter) verify() error {
+	for i := range m.spans {
+		s := &m.spans[i]
+		if s.tid == -1 {
+			// uploaded as part of the final part
+			continue
+		}
+		if size, ok := m.uploaded[s.partnum]; !ok || size != s.outsize {
+			return fmt.Errorf("blockfmt.MultiWriter: part %d: uploaded %d bytes; expected %d", s.partnum, size, s.outsize)
+		}
+	}
+	if size := m.Output.Size(); size != m.size {
+		return fmt.Errorf("blockfmt.MultiWriter: output size %d; expected %d", size, m.size)
+	}
+	return nil
 }
@@ -37,6 +37,12 @@ type IndirectTree struct {
 	// Sparse describes the intervals within refs
 	// that correspond to particular time ranges.
 	Sparse SparseIndex
+
+	// Schema, if non-nil, describes the fields
+	// present in the objects referenced by refs.
+	// Schema is nil if any of the objects
+	// were written without a schema.
+	Schema *Schema
 }
 
 // IndirectRef references an object
@@ -99,6 +105,11 @@ func (i *IndirectTree) encode(st *ion.Symtab, buf *ion.Buffer) {
 	buf.BeginField(st.Intern("sparse"))
 	i.Sparse.Encode(buf, st)
 
+	if i.Schema != nil {
+		buf.BeginField(st.Intern("schema"))
+		i.Schema.Encode(buf, st)
+	}
+
 	buf.EndStruct()
 }
 
@@ -157,6 +168,13 @@ func (i *IndirectTree) parse(td *TrailerDecoder, d ion.Datum) error {
 				err = fmt.Errorf("Indirect.Sparse.Decode: %w", err)
 			}
 			return err
+		case "schema":
+			i.Schema = new(Schema)
+			err := i.Schema.Decode(f.Datum)
+			if err != nil {
+				err = fmt.Errorf("Indirect.Schema.Decode: %w", err)
+			}
+			return err
 		default:
 			return fmt.Errorf("IndirectTree.parse: unexpected field name %q", f.Label)
 		}
@@ -376,6 +394,19 @@ func updateSummary(dst *SparseIndex, lst []Descriptor) {
 	}
 }
 
+// mergeSchema merges the schemas of lst[*].Trailer
+// into dst; the result is nil if dst or any of the
+// descriptors lacks a schema
+func mergeSchema(dst *Schema, lst []Descriptor) *Schema {
+	for i := range lst {
+		if dst == nil || lst[i].Trailer.Schema == nil {
+			return nil
+		}
+		dst.Merge(lst[i].Trailer.Schema)
+	}
+	return dst
+}
+
 // append appends a list of descriptors to the tree
 // and writes any new decriptor lists to files in basedir
 // relative to the root of ofs.
@@ -393,6 +424,11 @@ func (c *IndexConfig) append(idx *Index, ofs UploadFS, basedir string, lst []Des
 		targetRefSize = defaultTargetRefSize
 	}
 	i := &idx.Indirect
+	if len(i.Refs) == 0 {
+		i.Schema = mergeSchema(new(Schema), lst)
+	} else {
+		i.Schema = mergeSchema(i.Schema, lst)
+	}
 	if len(i.Refs) > 0 && i.Refs[len(i.Refs)-1].Size < targetRefSize {
 		r = &i.Refs[len(i.Refs)-1]
 		prev = r.Path
@@ -407,7 +443,23 @@ func (c *IndexConfig) append(idx *Index, ofs UploadFS, basedir string, lst []Des
 		pushSummary(&i.Sparse, lst)
 	}
 	all := append(prepend, lst...)
+	err = writeRef(ofs, basedir, r, all)
+	if err != nil {
+		return err
+	}
+	r.OrigObjects += delta
+	if prev != "" {
+		idx.ToDelete = append(idx.ToDelete, Quarantined{
+			Path:   prev,
+			Expiry: date.Now().Add(c.Expiry).Truncate(time.Microsecond),
+		})
+	}
+	return nil
+}
 
+// writeRef writes the list of descriptors in all
+// to a new object in basedir and points r at it
+func writeRef(ofs UploadFS, basedir string, r *IndirectRef, all []Descriptor) error {
 	// encode the list of objects:
 	var buf ion.Buffer
 	var st ion.Symtab
@@ -431,7 +483,6 @@ func (c *IndexConfig) append(idx *Index, ofs UploadFS, basedir string, lst []Des
 	r.ETag = etag
 	r.Size = int64(len(compressed))
 	r.Objects = len(all)
-	r.OrigObjects += delta
 
 	info, err := fs.Stat(ofs, p)
 	if err != nil {
@@ -445,11 +496,5 @@ func (c *IndexConfig) append(idx *Index, ofs UploadFS, basedir string, lst []Des
 		return fmt.Errorf("stored etag is %s instead of %s?", storedEtag, etag)
 	}
 	r.LastModified = date.FromTime(info.ModTime()).Truncate(time.Microsecond)
-	if prev != "" {
-		idx.ToDelete = append(idx.ToDelete, Quarantined{
-			Path:   prev,
-			Expiry: date.Now().Add(c.Expiry).Truncate(time.Microsecond),
-		})
-	}
 	return nil
 }
@@ -0,0 +1,379 @@
+// Copyright (C) 2022 Sneller, Inc.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package blockfmt
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+
+	"github.com/SnellerInc/sneller/ion"
+)
+
+// SymtabWriter is an optional interface implemented by
+// an io.Writer passed to Decoder.CopyParallel.
+// CopyParallel decodes the symbol table at the start of
+// each block in the background before the block reaches
+// a SymtabWriter, so that the writer does not have to
+// stop and parse the symbol table itself.
+type SymtabWriter interface {
+	io.Writer
+	// WriteSymtab is equivalent to Write(buf),
+	// except that buf begins with a BVM and
+	// a symbol table that occupies buf[:size]
+	// and has already been decoded into st.
+	// The caller does not reuse st, so the callee
+	// may retain it, but it must not modify it.
+	WriteSymtab(buf []byte, st *ion.Symtab, size int) (int, error)
+}
+
+// frameResult is the result of decompressing
+// a single frame; a frameResult with a nil buf
+// and a nil err is produced for frames that were
+// abandoned after the pipeline was stopped
+type frameResult struct {
+	buf []byte
+	err error
+	// st is the decoded symbol table
+	// at the start of buf, if any,
+	// and stsize is its encoded size
+	st     *ion.Symtab
+	stsize int
+}
+
+type frameJob struct {
+	src   []byte           // frame body (without the blob header)
+	frame int64            // index of the frame in its object
+	res   chan frameResult // always has capacity 1
+}
+
+// pipeline is the shared state of
+// a call to Decoder.CopyParallel
+type pipeline struct {
+	once sync.Once
+	quit chan struct{}
+	err  error
+	nn   int64
+	// symtabs is set if any of the
+	// writers is a SymtabWriter
+	symtabs bool
+}
+
+func (p *pipeline) fail(err error) {
+	p.once.Do(func() {
+		p.err = err
+		close(p.quit)
+	})
+}
+
+func (p *pipeline) stopped() bool {
+	select {
+	case <-p.quit:
+		return true
+	default:
+		return false
+	}
+}
+
+// clone returns a Decoder with the same
+// configuration as d but with its own
+// decompression and decryption state
+func (d *Decoder) clone() *Decoder {
+	return &Decoder{
+		BlockShift: d.BlockShift,
+		Offset:     d.Offset,
+		Algo:       d.Algo,
+		Fields:     d.Fields,
+		Malloc:     d.Malloc,
+		Free:       d.Free,
+		Name:       d.Name,
+		Key:        d.Key,
+		Path:       d.Path,
+		encrypted:  d.encrypted,
+	}
+}
+
+// CopyParallel is like CopyBytes, but it decouples
+// decompression from the writes to dst: frames are
+// decompressed by up to decompressors goroutines and
+// handed to the writers in dst through a bounded queue,
+// so that decompression can run ahead of (and in parallel
+// with) the evaluation performed by the writers. When the
+// writers fall behind, the queue fills up and decompression
+// stops until there is room again.
+//
+// Each writer in dst is only ever called from one goroutine.
+// Frames are delivered in order, and the frames that follow
+// a frame beginning with a new symbol table are passed to
+// the same writer as that frame, so each writer observes
+// a well-formed ion stream. The writers must not retain the
+// buffers passed to Write after Write returns.
+//
+// If any of the writers in dst implements SymtabWriter,
+// the decompression goroutines also decode the symbol
+// tables of the frames (see SymtabWriter), and a single
+// writer with a single decompressor still goes through
+// the pipeline so that the next block is prepared while
+// the writer consumes the current one.
+//
+// Unlike CopyBytes, CopyParallel does not pass
+// zion-compressed data to a ZionWriter unless there
+// is exactly one writer, in which case it is
+// equivalent to CopyBytes.
+func (d *Decoder) CopyParallel(dst []io.Writer, src []byte, decompressors int) (int64, error) {
+	if len(dst) == 0 {
+		panic("blockfmt.Decoder.CopyParallel: no writers")
+	}
+	if decompressors < 1 {
+		decompressors = 1
+	}
+	symtabs := false
+	for i := range dst {
+		if _, ok := dst[i].(SymtabWriter); ok {
+			symtabs = true
+		}
+	}
+	if len(dst) == 1 {
+		if (decompressors == 1 && !symtabs) || (d.Algo == "zion" && d.acceptsZion(dst[0])) {
+			return d.CopyBytes(dst[0], src)
+		}
+	}
+	err := d.sums.updateAll(d.Name, src)
+	if err != nil {
+		return 0, err
+	}
+	algo := d.Algo
+	// see CopyBytes
+	if algo == "zstd" {
+		algo = "zstd-nocrc"
+	}
+	p := &pipeline{quit: make(chan struct{}), symtabs: symtabs}
+	jobs := make(chan frameJob)
+	// pending holds the results of in-flight frames
+	// in input order; its capacity bounds the number
+	// of frames that can be decompressed ahead of
+	// the frame that is next to be written
+	pending := make(chan chan frameResult, 2*decompressors)
+	runs := make(chan chan frameResult)
+
+	var wg sync.WaitGroup
+	wg.Add(decompressors + len(dst))
+	for i := 0; i < decompressors; i++ {
+		go func() {
+			defer wg.Done()
+			d.clone().decompressFrames(algo, jobs, p)
+		}()
+	}
+	for i := range dst {
+		go func(w io.Writer) {
+			defer wg.Done()
+			d.writeRuns(w, runs, p)
+		}(dst[i])
+	}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		d.dispatch(pending, runs, p)
+	}()
+	d.sendFrames(src, jobs, pending, p)
+	close(jobs)
+	close(pending)
+	<-done
+	wg.Wait()
+	return atomic.LoadInt64(&p.nn), p.err
+}
+
+// sendFrames splits src into frames and queues them
+// for decompression; every frame that is queued in
+// pending is guaranteed to produce a result
+func (d *Decoder) sendFrames(src []byte, jobs chan<- frameJob, pending chan<- chan frameResult, p *pipeline) {
+	for len(src) > 0 {
+		if ion.TypeOf(src) != ion.BlobType {
+			p.fail(fmt.Errorf("decoding data: expected a blob; got %s", ion.TypeOf(src)))
+			return
+		}
+		size := ion.SizeOf(src)
+		if size < 5 || size > len(src) {
+			p.fail(fmt.Errorf("unexpected frame size %d", size))
+			return
+		}
+		res := make(chan frameResult, 1)
+		select {
+		case pending <- res:
+		case <-p.quit:
+			return
+		}
+		job := frameJob{src: src[5:size], frame: d.sealer.next, res: res}
+		d.sealer.next++
+		select {
+		case jobs <- job:
+		case <-p.quit:
+			res <- frameResult{}
+			return
+		}
+		src = src[size:]
+	}
+}
+
+// decompressFrames decompresses frames from jobs
+// into buffers allocated with d.Malloc and, if
+// p.symtabs is set, decodes their symbol tables
+func (d *Decoder) decompressFrames(algo string, jobs <-chan frameJob, p *pipeline) {
+	err := d.getDecomp(algo)
+	if err == nil {
+		defer d.free()
+	}
+	size := 1 << d.BlockShift
+	for j := range jobs {
+		if err != nil || p.stopped() {
+			j.res <- frameResult{err: err}
+			continue
+		}
+		j.res <- d.decompressFrame(j.src, j.frame, size, p.symtabs)
+	}
+}
+
+// decompressFrame decompresses a single frame;
+// a panic while decrypting or decompressing the
+// frame is returned as an error so that the
+// pipeline is stopped rather than the process
+func (d *Decoder) decompressFrame(src []byte, frame int64, size int, symtabs bool) (r frameResult) {
+	var out []byte
+	defer func() {
+		if e := recover(); e != nil {
+			if out != nil {
+				d.drop(out)
+			}
+			r = frameResult{err: panicError("decompressing", e)}
+		}
+	}()
+	d.sealer.next = frame
+	buf, err := d.decrypt(src, true)
+	if err != nil {
+		return frameResult{err: err}
+	}
+	out = d.malloc(size)
+	err = d.decomp.Decompress(buf, out)
+	if err != nil {
+		d.drop(out)
+		out = nil
+		return frameResult{err: err}
+	}
+	r = frameResult{buf: out}
+	if symtabs && ion.IsBVM(out) {
+		r.st, r.stsize = prepareSymtab(out)
+	}
+	return r
+}
+
+// panicError converts a value recovered from
+// a panic in a pipeline goroutine into an error
+func panicError(what string, e any) error {
+	if err, ok := e.(error); ok {
+		return fmt.Errorf("blockfmt: panic while %s: %w", what, err)
+	}
+	return fmt.Errorf("blockfmt: panic while %s: %v", what, e)
+}
+
+// prepareSymtab decodes the symbol table at the
+// start of buf; it returns a nil symbol table if
+// the symbol table cannot be decoded, in which case
+// the error is left for the writer to report
+func prepareSymtab(buf []byte) (*ion.Symtab, int) {
+	st := new(ion.Symtab)
+	rest, err := st.Unmarshal(buf)
+	if err != nil {
+		return nil, 0
+	}
+	return st, len(buf) - len(rest)
+}
+
+// dispatch collects decompressed frames in input
+// order and passes them to the writers; a frame
+// that begins with a new symbol table starts a new
+// run of frames, and each run is consumed by one writer
+func (d *Decoder) dispatch(pending <-chan chan frameResult, runs chan<- chan frameResult, p *pipeline) {
+	var cur chan frameResult
+	for res := range pending {
+		r := <-res
+		if r.err != nil {
+			p.fail(r.err)
+		}
+		if r.buf == nil {
+			continue
+		}
+		if p.stopped() {
+			d.drop(r.buf)
+			continue
+		}
+		if cur == nil || ion.IsBVM(r.buf) {
+			if cur != nil {
+				close(cur)
+			}
+			cur = make(chan frameResult, 1)
+			select {
+			case runs <- cur:
+			case <-p.quit:
+				close(cur)
+				cur = nil
+				d.drop(r.buf)
+				continue
+			}
+		}
+		select {
+		case cur <- r:
+		case <-p.quit:
+			d.drop(r.buf)
+		}
+	}
+	if cur != nil {
+		close(cur)
+	}
+	close(runs)
+}
+
+// writeRuns writes runs of frames to w until runs is closed
+func (d *Decoder) writeRuns(w io.Writer, runs <-chan chan frameResult, p *pipeline) {
+	sw, _ := w.(SymtabWriter)
+	for run := range runs {
+		for r := range run {
+			if !p.stopped() {
+				n, err := writeFrame(w, sw, &r)
+				atomic.AddInt64(&p.nn, int64(n))
+				if err != nil {
+					p.fail(err)
+				}
+			}
+			d.drop(r.buf)
+		}
+	}
+}
+
+// writeFrame writes a single frame to w (or sw, if
+// the frame has a prepared symbol table); a panic
+// in the writer is returned as an error so that
+// the remaining frames are still released
+func writeFrame(w io.Writer, sw SymtabWriter, r *frameResult) (n int, err error) {
+	defer func() {
+		if e := recover(); e != nil {
+			err = panicError("writing", e)
+		}
+	}()
+	if sw != nil && r.st != nil {
+		return sw.WriteSymtab(r.buf, r.st, r.stsize)
+	}
+	return w.Write(r.buf)
+}
@@ -0,0 +1,263 @@
+// Copyright (C) 2022 Sneller, Inc.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package blockfmt
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"sort"
+	"sync/atomic"
+	"testing"
+
+	"github.com/SnellerInc/sneller/ion"
+)
+
+// runCollector collects the frames written to it,
+// grouped into runs beginning with a new symbol table
+type runCollector struct {
+	runs  [][]byte
+	fail  int // if non-zero, fail the fail'th write
+	calls int
+	panic bool // panic instead of failing
+}
+
+var errCollector = errors.New("runCollector: write failed")
+
+func (r *runCollector) Write(p []byte) (int, error) {
+	r.calls++
+	if r.calls == r.fail {
+		if r.panic {
+			panic(errCollector)
+		}
+		return 0, errCollector
+	}
+	if len(r.runs) == 0 || ion.IsBVM(p) {
+		r.runs = append(r.runs, nil)
+	}
+	r.runs[len(r.runs)-1] = append(r.runs[len(r.runs)-1], p...)
+	return len(p), nil
+}
+
+func sortedRuns(lst []*runCollector) [][]byte {
+	var out [][]byte
+	for i := range lst {
+		out = append(out, lst[i].runs...)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return bytes.Compare(out[i], out[j]) < 0
+	})
+	return out
+}
+
+// symtabCollector is a runCollector
+// that checks the symbol tables passed
+// to it through WriteSymtab
+type symtabCollector struct {
+	runCollector
+	t       *testing.T
+	symtabs int
+}
+
+func (s *symtabCollector) WriteSymtab(p []byte, st *ion.Symtab, size int) (int, error) {
+	s.symtabs++
+	var want ion.Symtab
+	rest, err := want.Unmarshal(p)
+	if err != nil {
+		s.t.Fatal(err)
+	}
+	if size != len(p)-len(rest) {
+		s.t.Errorf("symbol table size %d; want %d", size, len(p)-len(rest))
+	}
+	if !st.Equal(&want) {
+		s.t.Error("prepared symbol table does not match the block")
+	}
+	return s.Write(p)
+}
+
+func parallelTestData(t *testing.T) (*Trailer, []byte) {
+	f, err := os.Open("../../testdata/parking3.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var out BufferUploader
+	align := 4096
+	out.PartSize = 2 * align
+	c := Converter{
+		Output:    &out,
+		Comp:      "zstd",
+		Inputs:    []Input{{R: f, F: MustSuffixToFormat(".json")}},
+		Align:     align,
+		FlushMeta: align * 3,
+	}
+	err = c.runSingle()
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf := out.Bytes()
+	trailer, err := ReadTrailer(bytes.NewReader(buf), int64(len(buf)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return trailer, buf[:trailer.Offset]
+}
+
+func TestCopyParallel(t *testing.T) {
+	trailer, data := parallelTestData(t)
+	var allocs int64
+	dec := Decoder{
+		Malloc: func(size int) []byte {
+			atomic.AddInt64(&allocs, 1)
+			return make([]byte, size)
+		},
+		Free: func([]byte) {
+			atomic.AddInt64(&allocs, -1)
+		},
+	}
+	dec.Set(trailer, len(trailer.Blocks))
+	var ref runCollector
+	want, err := dec.CopyBytes(&ref, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ref.runs) < 2 {
+		t.Fatalf("only %d runs?", len(ref.runs))
+	}
+	wantRuns := sortedRuns([]*runCollector{&ref})
+
+	for _, writers := range []int{1, 2, 5} {
+		for _, decompressors := range []int{2, 4} {
+			dst := make([]io.Writer, writers)
+			cols := make([]*runCollector, writers)
+			for i := range dst {
+				cols[i] = new(runCollector)
+				dst[i] = cols[i]
+			}
+			dec.Set(trailer, len(trailer.Blocks))
+			n, err := dec.CopyParallel(dst, data, decompressors)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if n != want {
+				t.Errorf("%d writers, %d decompressors: wrote %d bytes; want %d", writers, decompressors, n, want)
+			}
+			got := sortedRuns(cols)
+			if len(got) != len(wantRuns) {
+				t.Fatalf("%d writers, %d decompressors: got %d runs; want %d", writers, decompressors, len(got), len(wantRuns))
+			}
+			for i := range got {
+				if !bytes.Equal(got[i], wantRuns[i]) {
+					t.Fatalf("%d writers, %d decompressors: run %d differs", writers, decompressors, i)
+				}
+			}
+			if allocs != 0 {
+				t.Fatalf("%d buffers not freed", allocs)
+			}
+		}
+	}
+
+	// a failing writer stops the pipeline
+	// and every buffer is still released
+	for _, writers := range []int{1, 3} {
+		dst := make([]io.Writer, writers)
+		for i := range dst {
+			dst[i] = &runCollector{fail: 2}
+		}
+		dec.Set(trailer, len(trailer.Blocks))
+		_, err := dec.CopyParallel(dst, data, 3)
+		if !errors.Is(err, errCollector) {
+			t.Fatalf("got error %v", err)
+		}
+		if allocs != 0 {
+			t.Fatalf("%d buffers not freed", allocs)
+		}
+	}
+
+	// a panicking writer is reported as an error
+	for _, writers := range []int{2, 3} {
+		dst := make([]io.Writer, writers)
+		for i := range dst {
+			dst[i] = &runCollector{fail: 2, panic: true}
+		}
+		dec.Set(trailer, len(trailer.Blocks))
+		_, err := dec.CopyParallel(dst, data, 3)
+		if !errors.Is(err, errCollector) {
+			t.Fatalf("got error %v", err)
+		}
+		if allocs != 0 {
+			t.Fatalf("%d buffers not freed", allocs)
+		}
+	}
+
+	// corrupt input is reported
+	dec.Set(trailer, len(trailer.Blocks))
+	_, err = dec.CopyParallel([]io.Writer{io.Discard, io.Discard}, data[:len(data)-1], 2)
+	if err == nil {
+		t.Fatal("expected an error for truncated input")
+	}
+	if allocs != 0 {
+		t.Fatalf("%d buffers not freed", allocs)
+	}
+}
+
+func TestCopyParallelSymtabs(t *testing.T) {
+	trailer, data := parallelTestData(t)
+	var dec Decoder
+	dec.Set(trailer, len(trailer.Blocks))
+	var ref runCollector
+	want, err := dec.CopyBytes(&ref, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantRuns := sortedRuns([]*runCollector{&ref})
+
+	// a single SymtabWriter with a single
+	// decompressor also goes through the pipeline
+	for _, writers := range []int{1, 3} {
+		for _, decompressors := range []int{1, 2} {
+			dst := make([]io.Writer, writers)
+			cols := make([]*runCollector, writers)
+			for i := range dst {
+				sc := &symtabCollector{t: t}
+				cols[i] = &sc.runCollector
+				dst[i] = sc
+			}
+			dec.Set(trailer, len(trailer.Blocks))
+			n, err := dec.CopyParallel(dst, data, decompressors)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if n != want {
+				t.Errorf("%d writers, %d decompressors: wrote %d bytes; want %d", writers, decompressors, n, want)
+			}
+			got := sortedRuns(cols)
+			if len(got) != len(wantRuns) {
+				t.Fatalf("%d writers, %d decompressors: got %d runs; want %d", writers, decompressors, len(got), len(wantRuns))
+			}
+			for i := range got {
+				if !bytes.Equal(got[i], wantRuns[i]) {
+					t.Fatalf("%d writers, %d decompressors: run %d differs", writers, decompressors, i)
+				}
+			}
+			for i := range cols {
+				if sc := dst[i].(*symtabCollector); sc.symtabs != len(sc.runs) {
+					t.Errorf("%d writers, %d decompressors: %d symbol tables for %d runs", writers, decompressors, sc.symtabs, len(sc.runs))
+				}
+			}
+		}
+	}
+}
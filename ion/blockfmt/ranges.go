@@ -138,9 +138,34 @@ func union(a, b []TimeRange) []TimeRange {
 	return a
 }
 
+// keyUnions computes the union of the key ranges
+// in a and b; keys that are not present in both
+// (or whose ranges are not comparable) are dropped
+func keyUnions(a, b []datumRange) []datumRange {
+	out := a[:0]
+	for i := range a {
+		j := slices.IndexFunc(b, func(r datumRange) bool {
+			return slices.Equal(r.path, a[i].path)
+		})
+		if j < 0 {
+			continue
+		}
+		min, max := keyUnion(a[i].min, a[i].max, b[j].min, b[j].max)
+		if min.IsEmpty() {
+			continue
+		}
+		out = append(out, datumRange{path: a[i].path, min: min, max: max})
+	}
+	return out
+}
+
 func (b *blockpart) merge(from *blockpart) {
 	b.chunks += from.chunks
 	b.ranges = union(b.ranges, from.ranges)
+	b.keys = keyUnions(b.keys, from.keys)
+	b.crc = crcCombine(b.crc, from.crc, from.size)
+	b.size += from.size
+	b.rows += from.rows
 }
 
 func collectRanges(t *Trailer) [][]string {
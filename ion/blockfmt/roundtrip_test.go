@@ -17,11 +17,13 @@ package blockfmt_test
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"io"
 	"math/rand"
 	"os"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/SnellerInc/sneller/ion"
 	"github.com/SnellerInc/sneller/ion/blockfmt"
@@ -231,6 +233,126 @@ func TestMultiRoundtrip(t *testing.T) {
 	check(t, contents)
 }
 
+// flakyUploader is an Uploader that fails
+// every other call to Upload and the first
+// call to Close; if lossy is set, the failed
+// uploads are silently dropped instead
+type flakyUploader struct {
+	blockfmt.BufferUploader
+	lossy bool
+
+	lock    sync.Mutex
+	uploads int
+	closes  int
+}
+
+var errFlaky = errors.New("flaky upload")
+
+func (f *flakyUploader) Upload(part int64, contents []byte) error {
+	f.lock.Lock()
+	f.uploads++
+	fail := f.uploads%2 == 1
+	f.lock.Unlock()
+	if fail {
+		if f.lossy {
+			return nil
+		}
+		return errFlaky
+	}
+	return f.BufferUploader.Upload(part, contents)
+}
+
+func (f *flakyUploader) Close(final []byte) error {
+	f.closes++
+	if f.closes == 1 {
+		return errFlaky
+	}
+	return f.BufferUploader.Close(final)
+}
+
+func TestMultiRetry(t *testing.T) {
+	f, err := os.Open("../../testdata/parking2.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, _, err := versify.FromJSON(json.NewDecoder(f))
+	f.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	write := func(up blockfmt.Uploader, retries int) (*blockfmt.MultiWriter, error) {
+		mw := &blockfmt.MultiWriter{
+			Output:     up,
+			Algo:       "zstd",
+			InputAlign: 4 * 1024,
+			TargetSize: 8 * 1024,
+			MaxRetries: retries,
+			RetryDelay: time.Microsecond,
+		}
+		streams := make([]io.WriteCloser, 2)
+		for i := range streams {
+			streams[i], err = mw.Open()
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		errs := make([]error, len(streams))
+		var wg sync.WaitGroup
+		for i := range streams {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				src := rand.New(rand.NewSource(int64(i)))
+				cn := ion.Chunker{W: streams[i], Align: mw.InputAlign, RangeAlign: mw.InputAlign * 10}
+				errs[i] = fastVersify(u, src, &cn, 100, 5000)
+				if err := streams[i].Close(); errs[i] == nil {
+					errs[i] = err
+				}
+			}(i)
+		}
+		wg.Wait()
+		for i := range errs {
+			if errs[i] != nil {
+				return mw, errs[i]
+			}
+		}
+		return mw, mw.Close()
+	}
+
+	// without retries, the first failure
+	// aborts the upload
+	up := &flakyUploader{}
+	up.PartSize = 8 * 1024
+	_, err = write(up, 0)
+	if !errors.Is(err, errFlaky) {
+		t.Fatalf("expected errFlaky; got %v", err)
+	}
+
+	up = &flakyUploader{}
+	up.PartSize = 8 * 1024
+	mw, err := write(up, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if up.uploads < 2 || up.closes != 2 {
+		t.Errorf("%d uploads, %d closes", up.uploads, up.closes)
+	}
+	if int64(len(up.Bytes())) <= mw.Trailer.Offset {
+		t.Errorf("output size %d below trailer offset %d", len(up.Bytes()), mw.Trailer.Offset)
+	}
+	check(t, up.Bytes())
+
+	// a part that is lost is detected
+	// when the output is closed
+	up = &flakyUploader{lossy: true}
+	up.PartSize = 8 * 1024
+	_, err = write(up, 2)
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	t.Log(err)
+}
+
 func TestMultiRanges(t *testing.T) {
 	var inputs []blockfmt.Input
 	for i := 0; i < 3; i++ {
@@ -0,0 +1,326 @@
+// Copyright (C) 2022 Sneller, Inc.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package blockfmt
+
+import (
+	"fmt"
+
+	"github.com/SnellerInc/sneller/expr"
+	"github.com/SnellerInc/sneller/ion"
+
+	"golang.org/x/exp/maps"
+	"golang.org/x/exp/slices"
+)
+
+// maxSchemaFields is the maximum number of
+// distinct fields (at any level of nesting)
+// tracked by a Schema
+const maxSchemaFields = 10000
+
+// Schema is a summary of the fields present
+// in a collection of rows and of the types
+// of the values associated with each field.
+//
+// A Schema is collected for each packfile
+// during conversion (see Trailer.Schema)
+// and the schemas of all the packfiles in
+// a table are merged into Index.Schema.
+// A Schema may describe more types than
+// are actually present in the data, but
+// it never describes fewer.
+//
+// Schema implements expr.Hint.
+type Schema struct {
+	// Rows is the number of rows summarized
+	// by the schema. Merging the schemas of
+	// overlapping collections of rows makes
+	// this an upper bound.
+	Rows int64
+	// Partial is set if some fields were
+	// not tracked because there were too many
+	// distinct fields present in the rows.
+	Partial bool
+	// Fields is the set of top-level fields.
+	Fields map[string]*SchemaField
+
+	nfields int
+}
+
+// SchemaField describes a field within a Schema.
+type SchemaField struct {
+	// Types is the set of types of the values
+	// associated with the field. MissingType is
+	// included if the field is absent from some
+	// of the rows (or structures) that contain
+	// its parent.
+	Types expr.TypeSet
+	// Fields is the set of fields present in
+	// the structures associated with this field.
+	Fields map[string]*SchemaField
+
+	// counters used while collecting;
+	// always zero after Schema.finish
+	count, structs int64
+}
+
+// observe adds a row encoded with st to the schema
+func (s *Schema) observe(row []byte, st *ion.Symtab) {
+	if ion.TypeOf(row) != ion.StructType {
+		return
+	}
+	s.Rows++
+	s.observeStruct(&s.Fields, row, st)
+}
+
+func (s *Schema) observeStruct(dst *map[string]*SchemaField, body []byte, st *ion.Symtab) {
+	body, _ = ion.Contents(body)
+	for len(body) > 0 {
+		sym, rest, err := ion.ReadLabel(body)
+		if err != nil || len(rest) == 0 {
+			return
+		}
+		size := ion.SizeOf(rest)
+		if size <= 0 || size > len(rest) {
+			return
+		}
+		val := rest[:size]
+		body = rest[size:]
+		typ := ion.TypeOf(val)
+		null := val[0]&0x0f == 0x0f
+		if typ == ion.NullType && !null {
+			continue // nop pad
+		}
+		name, ok := st.Lookup(sym)
+		if !ok {
+			continue
+		}
+		f := (*dst)[name]
+		if f == nil {
+			if s.nfields >= maxSchemaFields {
+				s.Partial = true
+				continue
+			}
+			if *dst == nil {
+				*dst = make(map[string]*SchemaField)
+			}
+			f = new(SchemaField)
+			(*dst)[name] = f
+			s.nfields++
+		}
+		f.count++
+		if null {
+			f.Types |= expr.NullType
+			continue
+		}
+		f.Types |= 1 << typ
+		if typ == ion.StructType {
+			f.structs++
+			s.observeStruct(&f.Fields, val, st)
+		}
+	}
+}
+
+// finish computes which of the collected
+// fields may be missing and resets the counters
+func (s *Schema) finish() {
+	finishFields(s.Fields, s.Rows)
+}
+
+func finishFields(fields map[string]*SchemaField, parent int64) {
+	for _, f := range fields {
+		if f.count < parent {
+			f.Types |= expr.MissingType
+		}
+		finishFields(f.Fields, f.structs)
+		f.count, f.structs = 0, 0
+	}
+}
+
+// Merge merges the schema of another
+// collection of rows into s.
+func (s *Schema) Merge(o *Schema) {
+	s.Fields = s.mergeFields(s.Fields, s.Rows > 0, o.Fields, o.Rows > 0)
+	s.Rows += o.Rows
+	s.Partial = s.Partial || o.Partial
+}
+
+// mergeFields merges src into dst; dstAny and srcAny
+// indicate whether or not the rows described by dst
+// and src (respectively) contain any structures
+func (s *Schema) mergeFields(dst map[string]*SchemaField, dstAny bool, src map[string]*SchemaField, srcAny bool) map[string]*SchemaField {
+	if srcAny {
+		for name, f := range dst {
+			if _, ok := src[name]; !ok {
+				f.Types |= expr.MissingType
+			}
+		}
+	}
+	for name, f := range src {
+		d := dst[name]
+		if d == nil {
+			if s.nfields >= maxSchemaFields {
+				s.Partial = true
+				continue
+			}
+			if dst == nil {
+				dst = make(map[string]*SchemaField)
+			}
+			d = new(SchemaField)
+			if dstAny {
+				d.Types = expr.MissingType
+			}
+			dst[name] = d
+			s.nfields++
+		}
+		d.Fields = s.mergeFields(d.Fields, d.Types&expr.StructType != 0, f.Fields, f.Types&expr.StructType != 0)
+		d.Types |= f.Types
+	}
+	return dst
+}
+
+// TypeOf implements expr.Hint.TypeOf
+//
+// The types of paths that do not appear
+// in the schema are MissingType, unless
+// the schema is partial.
+func (s *Schema) TypeOf(e expr.Node) expr.TypeSet {
+	p, ok := expr.FlatPath(e)
+	if !ok {
+		return expr.AnyType
+	}
+	fields := s.Fields
+	missing := expr.TypeSet(0)
+	for i := range p {
+		f := fields[p[i]]
+		if f == nil {
+			if s.Partial {
+				return expr.AnyType
+			}
+			return expr.MissingType
+		}
+		if i == len(p)-1 {
+			return f.typeset() | missing
+		}
+		// the remainder of the path is
+		// MISSING unless this is a structure
+		if f.Types&^expr.StructType != 0 {
+			missing = expr.MissingType
+		}
+		fields = f.Fields
+	}
+	return expr.AnyType
+}
+
+func (f *SchemaField) typeset() expr.TypeSet {
+	t := f.Types
+	if t&(1<<ion.AnnotationType) != 0 {
+		return expr.AnyType
+	}
+	// symbols are evaluated as strings
+	if t&expr.SymbolType != 0 {
+		t |= expr.StringType
+	}
+	return t
+}
+
+// Encode encodes the schema to dst
+// using the provided symbol table.
+func (s *Schema) Encode(dst *ion.Buffer, st *ion.Symtab) {
+	dst.BeginStruct(-1)
+	dst.BeginField(st.Intern("rows"))
+	dst.WriteInt(s.Rows)
+	if s.Partial {
+		dst.BeginField(st.Intern("partial"))
+		dst.WriteBool(true)
+	}
+	if len(s.Fields) > 0 {
+		dst.BeginField(st.Intern("fields"))
+		encodeSchemaFields(dst, st, s.Fields)
+	}
+	dst.EndStruct()
+}
+
+func encodeSchemaFields(dst *ion.Buffer, st *ion.Symtab, fields map[string]*SchemaField) {
+	types := st.Intern("types")
+	sub := st.Intern("fields")
+	// encode fields in a deterministic order
+	names := maps.Keys(fields)
+	slices.Sort(names)
+	dst.BeginStruct(-1)
+	for _, name := range names {
+		f := fields[name]
+		dst.BeginField(st.Intern(name))
+		dst.BeginStruct(-1)
+		dst.BeginField(types)
+		dst.WriteUint(uint64(f.Types))
+		if len(f.Fields) > 0 {
+			dst.BeginField(sub)
+			encodeSchemaFields(dst, st, f.Fields)
+		}
+		dst.EndStruct()
+	}
+	dst.EndStruct()
+}
+
+// Decode decodes a schema encoded with Schema.Encode.
+func (s *Schema) Decode(d ion.Datum) error {
+	*s = Schema{}
+	err := d.UnpackStruct(func(f ion.Field) error {
+		var err error
+		switch f.Label {
+		case "rows":
+			s.Rows, err = f.Int()
+		case "partial":
+			s.Partial, err = f.Bool()
+		case "fields":
+			s.Fields, err = s.decodeFields(f.Datum)
+		default:
+			return fmt.Errorf("unexpected field %q", f.Label)
+		}
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("Schema.Decode: %w", err)
+	}
+	return nil
+}
+
+func (s *Schema) decodeFields(d ion.Datum) (map[string]*SchemaField, error) {
+	fields := make(map[string]*SchemaField)
+	err := d.UnpackStruct(func(f ion.Field) error {
+		sf := new(SchemaField)
+		err := f.UnpackStruct(func(f ion.Field) error {
+			switch f.Label {
+			case "types":
+				t, err := f.Uint()
+				sf.Types = expr.TypeSet(t)
+				return err
+			case "fields":
+				var err error
+				sf.Fields, err = s.decodeFields(f.Datum)
+				return err
+			default:
+				return fmt.Errorf("unexpected field %q", f.Label)
+			}
+		})
+		if err != nil {
+			return err
+		}
+		fields[f.Label] = sf
+		s.nfields++
+		return nil
+	})
+	return fields, err
+}
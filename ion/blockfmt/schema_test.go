@@ -0,0 +1,212 @@
+// Copyright (C) 2022 Sneller, Inc.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package blockfmt
+
+import (
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/SnellerInc/sneller/expr"
+	"github.com/SnellerInc/sneller/ion"
+)
+
+var schemaInputs = []string{
+	`{"a": 1, "b": "x", "c": {"d": 1.5}}
+{"a": "str", "c": {"d": null, "e": true}}`,
+	`{"a": 2, "c": 3}`,
+}
+
+func checkSchema(t *testing.T, s *Schema) {
+	t.Helper()
+	if s == nil {
+		t.Fatal("nil schema")
+	}
+	if s.Rows != 3 {
+		t.Errorf("got %d rows; expected 3", s.Rows)
+	}
+	tcs := []struct {
+		path string
+		want expr.TypeSet
+	}{
+		{"a", expr.UnsignedType | expr.StringType},
+		{"b", expr.StringType | expr.MissingType},
+		{"c", expr.UnsignedType | expr.StructType},
+		{"c.d", expr.FloatType | expr.NullType | expr.MissingType},
+		{"c.e", expr.BoolType | expr.MissingType},
+		{"c.d.x", expr.MissingType},
+		{"z", expr.MissingType},
+	}
+	for i := range tcs {
+		p, err := expr.ParsePath(tcs[i].path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := s.TypeOf(p); got != tcs[i].want {
+			t.Errorf("TypeOf(%s) = %s, want %s", tcs[i].path, got, tcs[i].want)
+		}
+	}
+}
+
+func checkRowCounts(t *testing.T, tr *Trailer) {
+	t.Helper()
+	if !tr.RowCounts {
+		t.Fatal("trailer has no row counts")
+	}
+	rows, ok := tr.ExactRows(0, len(tr.Blocks))
+	if !ok || rows != 3 {
+		t.Errorf("ExactRows = %d, %v; expected 3", rows, ok)
+	}
+}
+
+func TestConvertSchema(t *testing.T) {
+	for _, parallel := range []int{1, 2} {
+		var inputs []Input
+		for _, str := range schemaInputs {
+			inputs = append(inputs, Input{
+				R: io.NopCloser(strings.NewReader(str)),
+				F: MustSuffixToFormat(".json"),
+			})
+		}
+		var out BufferUploader
+		out.PartSize = 4096
+		c := Converter{
+			Output:   &out,
+			Comp:     "zion",
+			Inputs:   inputs,
+			Align:    4096,
+			Parallel: parallel,
+		}
+		err := c.Run()
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkSchema(t, c.Trailer().Schema)
+		checkRowCounts(t, c.Trailer())
+
+		// the schema should survive encoding
+		var buf ion.Buffer
+		var st ion.Symtab
+		c.Trailer().Encode(&buf, &st)
+		tr := new(Trailer)
+		err = tr.Decode(&st, buf.Bytes())
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkSchema(t, tr.Schema)
+		checkRowCounts(t, tr)
+		if !reflect.DeepEqual(tr.Schema.Fields, c.Trailer().Schema.Fields) {
+			t.Error("decoded schema not equivalent")
+		}
+	}
+}
+
+func TestSchemaMerge(t *testing.T) {
+	var parts []*Schema
+	for _, str := range schemaInputs {
+		var out BufferUploader
+		out.PartSize = 4096
+		c := Converter{
+			Output: &out,
+			Comp:   "zion",
+			Inputs: []Input{{
+				R: io.NopCloser(strings.NewReader(str)),
+				F: MustSuffixToFormat(".json"),
+			}},
+			Align: 4096,
+		}
+		err := c.Run()
+		if err != nil {
+			t.Fatal(err)
+		}
+		parts = append(parts, c.Trailer().Schema)
+	}
+	merged := new(Schema)
+	for i := range parts {
+		merged.Merge(parts[i])
+	}
+	checkSchema(t, merged)
+
+	// the index schema merges the inline
+	// and indirect schemas, and it is unknown
+	// if any of the descriptors lacks a schema
+	idx := &Index{
+		Inline: []Descriptor{
+			{Trailer: Trailer{Schema: parts[0]}},
+			{Trailer: Trailer{Schema: parts[1]}},
+		},
+	}
+	checkSchema(t, idx.Schema())
+	idx.Indirect.Refs = []IndirectRef{{}}
+	if idx.Schema() != nil {
+		t.Error("expected nil schema with unknown indirect schema")
+	}
+	idx.Indirect.Schema = parts[0]
+	idx.Inline = idx.Inline[1:]
+	checkSchema(t, idx.Schema())
+	idx.Inline = append(idx.Inline, Descriptor{})
+	if idx.Schema() != nil {
+		t.Error("expected nil schema with unknown inline schema")
+	}
+}
+
+func TestTrailerRows(t *testing.T) {
+	tr := &Trailer{
+		Blocks: []Blockdesc{{Chunks: 1}, {Chunks: 3}, {Chunks: 4}},
+	}
+	if _, ok := tr.Rows(); ok {
+		t.Fatal("expected unknown rows without a schema")
+	}
+	if _, ok := tr.BlockRows(0, 1); ok {
+		t.Fatal("expected unknown rows without a schema")
+	}
+	tr.Schema = &Schema{Rows: 800}
+	tcs := []struct {
+		start, end int
+		rows       int64
+	}{
+		{0, 3, 800},
+		{0, 1, 100},
+		{1, 3, 700},
+		{2, 3, 400},
+		{1, 1, 0},
+	}
+	for _, tc := range tcs {
+		rows, ok := tr.BlockRows(tc.start, tc.end)
+		if !ok || rows != tc.rows {
+			t.Errorf("BlockRows(%d, %d) = %d, %v; want %d", tc.start, tc.end, rows, ok, tc.rows)
+		}
+	}
+	tr.RowCounts = true
+	tr.Blocks[0].Rows = 5
+	tr.Blocks[1].Rows = 500
+	tr.Blocks[2].Rows = 295
+	tcs = []struct {
+		start, end int
+		rows       int64
+	}{
+		{0, 3, 800},
+		{0, 1, 5},
+		{1, 3, 795},
+		{2, 3, 295},
+	}
+	for _, tc := range tcs {
+		rows, ok := tr.BlockRows(tc.start, tc.end)
+		if !ok || rows != tc.rows {
+			t.Errorf("BlockRows(%d, %d) = %d, %v; want %d", tc.start, tc.end, rows, ok, tc.rows)
+		}
+	}
+}
@@ -33,6 +33,7 @@ type timeIndex struct {
 type SparseIndex struct {
 	consts  ion.Struct
 	indices []timeIndex
+	keys    []keyIndex // sort keys; see keyindex.go
 	blocks  int
 }
 
@@ -48,6 +49,27 @@ func (s *SparseIndex) Const(x string) (ion.Datum, bool) {
 	return f.Datum, true
 }
 
+// Consts returns the list of constant fields
+// that are associated with every row covered
+// by the sparse index.
+func (s *SparseIndex) Consts() []ion.Field {
+	if s.consts.IsEmpty() {
+		return nil
+	}
+	return s.consts.Fields(nil)
+}
+
+// SetConsts sets the list of constant fields
+// that are associated with every row covered
+// by the sparse index. (See also Consts.)
+func (s *SparseIndex) SetConsts(lst []ion.Field) {
+	if len(lst) == 0 {
+		s.consts = ion.Struct{}
+		return
+	}
+	s.consts = ion.NewStruct(nil, lst)
+}
+
 func (t *timeIndex) slice(i, j int) timeIndex {
 	return timeIndex{
 		path:   t.path,
@@ -67,10 +89,18 @@ func (s *SparseIndex) Slice(i, j int) SparseIndex {
 	for k := range indices {
 		indices[k] = s.indices[k].slice(i, j)
 	}
+	var keys []keyIndex
+	if len(s.keys) > 0 {
+		keys = make([]keyIndex, len(s.keys))
+		for k := range keys {
+			keys[k] = s.keys[k].slice(i, j)
+		}
+	}
 	return SparseIndex{
 		consts:  s.consts,
 		indices: indices,
-		blocks:  j,
+		keys:    keys,
+		blocks:  j - i,
 	}
 }
 
@@ -89,9 +119,17 @@ func (s *SparseIndex) Clone() SparseIndex {
 	for i := range indices {
 		indices[i].ranges = indices[i].ranges.Clone()
 	}
+	var keys []keyIndex
+	if len(s.keys) > 0 {
+		keys = make([]keyIndex, len(s.keys))
+		for i := range keys {
+			keys[i] = s.keys[i].clone()
+		}
+	}
 	return SparseIndex{
 		consts:  s.consts,
 		indices: indices,
+		keys:    keys,
 		blocks:  s.blocks,
 	}
 }
@@ -106,13 +144,21 @@ func (s *SparseIndex) emptyClone() SparseIndex {
 	for i := range s.indices {
 		out.indices[i].path = s.indices[i].path
 	}
+	if len(s.keys) > 0 {
+		out.keys = make([]keyIndex, len(s.keys))
+		for i := range s.keys {
+			out.keys[i].path = s.keys[i].path
+		}
+	}
 	return out
 }
 
 // Append tries to append next to s and returns
 // true if the append operation was successful,
 // or false otherwise. (Append will fail if the
-// set of indices tracked in each SparseIndex is not the same.)
+// set of time indices tracked in each SparseIndex is not the same;
+// sort keys that are only tracked in one of the two indexes
+// are considered unknown for the blocks of the other.)
 // The block positions in next are assumed to start
 // at s.Blocks().
 func (s *SparseIndex) Append(next *SparseIndex) bool {
@@ -136,16 +182,17 @@ func (s *SparseIndex) AppendBlocks(next *SparseIndex, i, j int) bool {
 	if !slices.EqualFunc(s.indices, next.indices, eq) {
 		return false
 	}
-	for i := range s.indices {
-		s.indices[i].ranges.appendBlocks(&next.indices[i].ranges, i, j)
+	for k := range s.indices {
+		s.indices[k].ranges.appendBlocks(&next.indices[k].ranges, i, j)
 	}
+	s.appendKeys(next, i, j)
 	s.blocks += j - i
 	return true
 }
 
 // Fields returns the number of individually
-// indexed fields.
-func (s *SparseIndex) Fields() int { return len(s.indices) }
+// indexed fields, including sort keys.
+func (s *SparseIndex) Fields() int { return len(s.indices) + len(s.keys) }
 
 // FieldNames returns the list of field names
 // using '.' as a separator between the path components.
@@ -153,10 +200,13 @@ func (s *SparseIndex) Fields() int { return len(s.indices) }
 // inside field names themselves, so the textual result
 // of each field name may be ambiguous.
 func (s *SparseIndex) FieldNames() []string {
-	o := make([]string, 0, len(s.indices))
+	o := make([]string, 0, s.Fields())
 	for i := range s.indices {
 		o = append(o, strings.Join(s.indices[i].path, "."))
 	}
+	for i := range s.keys {
+		o = append(o, strings.Join(s.keys[i].path, "."))
+	}
 	return o
 }
 
@@ -184,6 +234,14 @@ func (s *SparseIndex) Encode(dst *ion.Buffer, st *ion.Symtab) {
 		dst.EndStruct()
 	}
 	dst.EndList()
+	if len(s.keys) > 0 {
+		dst.BeginField(st.Intern("keys"))
+		dst.BeginList(-1)
+		for i := range s.keys {
+			s.keys[i].encode(dst, st)
+		}
+		dst.EndList()
+	}
 	dst.EndStruct()
 }
 
@@ -226,6 +284,15 @@ func (d *TrailerDecoder) decodeSparse(s *SparseIndex, v ion.Datum) error {
 				return nil
 			})
 			return err
+		case "keys":
+			return f.UnpackList(func(v ion.Datum) error {
+				var val keyIndex
+				if err := d.decodeKey(&val, v); err != nil {
+					return err
+				}
+				s.keys = append(s.keys, val)
+				return nil
+			})
 		}
 		return nil
 	})
@@ -246,6 +313,7 @@ func (s *SparseIndex) Push(rng []Range) {
 	for i := range rng {
 		tr, ok := rng[i].(*TimeRange)
 		if !ok {
+			s.pushKey(rng[i].Path(), rng[i].Min(), rng[i].Max())
 			continue
 		}
 		s.push(tr.path, tr.min, tr.max)
@@ -318,6 +386,14 @@ func (s *SparseIndex) bump() {
 			panic("bad block bookkeeping")
 		}
 	}
+	for i := range s.keys {
+		if b := s.keys[i].blocks(); b < s.blocks {
+			s.keys[i].pushEmpty(s.blocks - b)
+		} else if b > s.blocks {
+			println(b, ">", s.blocks)
+			panic("bad key block bookkeeping")
+		}
+	}
 }
 
 // update the most recent min/max values associated
@@ -329,6 +405,7 @@ func (s *SparseIndex) updateSummary(from *SparseIndex) {
 			s.update(from.indices[i].path, min, max)
 		}
 	}
+	s.updateKeySummary(from)
 }
 
 // push the min/max values associated with a sparse index
@@ -339,6 +416,7 @@ func (s *SparseIndex) pushSummary(from *SparseIndex) {
 			s.push(from.indices[i].path, min, max)
 		}
 	}
+	s.pushKeySummary(from)
 	s.bump()
 }
 
@@ -122,3 +122,100 @@ func TestSparseCorruption(t *testing.T) {
 		t.Fatal("consts was corrupted")
 	}
 }
+
+func TestSparseKeys(t *testing.T) {
+	x := []string{"x"}
+	y := []string{"y", "z"}
+	keyRange := func(si *SparseIndex, p []string) (lo, hi ion.Datum) {
+		t.Helper()
+		lo, hi, ok := si.KeyRange(p)
+		if !ok {
+			t.Fatalf("no key range for %v", p)
+		}
+		return lo, hi
+	}
+	check := func(lo, hi, wantlo, wanthi ion.Datum) {
+		t.Helper()
+		if !lo.Equal(wantlo) || !hi.Equal(wanthi) {
+			t.Fatalf("got [%s, %s]; wanted [%s, %s]", lo.JSON(), hi.JSON(), wantlo.JSON(), wanthi.JSON())
+		}
+	}
+
+	var si SparseIndex
+	si.Push([]Range{NewRange(x, ion.Int(10), ion.Int(20))})
+	si.Push([]Range{NewRange(x, ion.Int(-5), ion.Float(1.5))})
+	// ranges of unsupported types are ignored
+	si.Push([]Range{NewRange(x, ion.Bool(false), ion.Bool(true))})
+	si.Push([]Range{NewRange(x, ion.Int(100), ion.Int(200))})
+	if si.Fields() != 1 || si.Blocks() != 4 {
+		t.Fatalf("fields = %d, blocks = %d", si.Fields(), si.Blocks())
+	}
+	if _, _, ok := si.KeyRange(x); ok {
+		t.Fatal("key range should be unknown")
+	}
+	sl := si.Slice(0, 2)
+	lo, hi := keyRange(&sl, x)
+	check(lo, hi, ion.Int(-5), ion.Int(20))
+	sl = si.Slice(3, 4)
+	lo, hi = keyRange(&sl, x)
+	check(lo, hi, ion.Int(100), ion.Int(200))
+
+	// encoding round-trip
+	var buf ion.Buffer
+	var st ion.Symtab
+	var out SparseIndex
+	si.Encode(&buf, &st)
+	if err := out.Decode(&st, buf.Bytes()); err != nil {
+		t.Fatal(err)
+	}
+	if out.Blocks() != si.Blocks() || len(out.keys) != 1 {
+		t.Fatalf("decoded %d blocks, %d keys", out.Blocks(), len(out.keys))
+	}
+	for i := range si.keys[0].min {
+		if si.keys[0].min[i].IsEmpty() {
+			if !out.keys[0].min[i].IsEmpty() {
+				t.Fatalf("block %d: expected unknown range", i)
+			}
+			continue
+		}
+		check(out.keys[0].min[i], out.keys[0].max[i], si.keys[0].min[i], si.keys[0].max[i])
+	}
+
+	// appending an index with a different
+	// set of keys leaves the missing ranges unknown
+	var next SparseIndex
+	next.Push([]Range{NewRange(y, ion.String("a"), ion.String("b"))})
+	next.Push([]Range{NewRange(y, ion.String("c"), ion.String("d"))})
+	all := si.Clone()
+	if !all.Append(&next) {
+		t.Fatal("append failed")
+	}
+	if all.Blocks() != 6 || len(all.keys) != 2 {
+		t.Fatalf("%d blocks, %d keys after append", all.Blocks(), len(all.keys))
+	}
+	tail := all.Slice(4, 6)
+	lo, hi = keyRange(&tail, y)
+	check(lo, hi, ion.String("a"), ion.String("d"))
+	if _, _, ok := tail.KeyRange(x); ok {
+		t.Fatal("appended blocks should not have a range for x")
+	}
+
+	// summaries are only known if
+	// every summarized block is known
+	var sum SparseIndex
+	sum.pushSummary(&next)
+	lo, hi = keyRange(&sum, y)
+	check(lo, hi, ion.String("a"), ion.String("d"))
+	sum.updateSummary(&si)
+	if _, _, ok := sum.KeyRange(y); ok {
+		t.Fatal("summary of y should be unknown")
+	}
+	if _, _, ok := sum.KeyRange(x); ok {
+		t.Fatal("summary of x should be unknown")
+	}
+	sl = si.Slice(0, 2)
+	sum.pushSummary(&sl)
+	last := sum.Slice(1, 2)
+	lo, hi = keyRange(&last, x)
+	check(lo, hi, ion.Int(-5), ion.Int(20))
+}
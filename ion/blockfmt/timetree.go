@@ -215,6 +215,40 @@ func (t *TimeIndex) End(when date.Time) int {
 	return t.min[j].offset
 }
 
+// minAtLeast produces the lowest offset at which
+// every subsequent block is known to contain only
+// times at or after 'when', or Blocks() if there
+// is no such offset.
+func (t *TimeIndex) minAtLeast(when date.Time) int {
+	// the mins are strictly increasing, and
+	// each one is a lower bound for all of
+	// the blocks at or above its offset
+	j := sort.Search(len(t.min), func(i int) bool {
+		return !t.min[i].when.Before(when)
+	})
+	if j == len(t.min) {
+		return t.Blocks()
+	}
+	return t.min[j].offset
+}
+
+// maxAtMost produces the highest offset (exclusive)
+// below which every block is known to contain only
+// times at or before 'when', or 0 if there is
+// no such offset.
+func (t *TimeIndex) maxAtMost(when date.Time) int {
+	// the maxes are strictly increasing, and
+	// each one is an upper bound for all of
+	// the blocks below its offset
+	j := sort.Search(len(t.max), func(i int) bool {
+		return t.max[i].when.After(when)
+	})
+	if j == 0 {
+		return 0
+	}
+	return t.max[j-1].offset
+}
+
 // Append concatenates t and next so that the
 // ranges indexed by next occur immediately after
 // the ranges indexed by t.
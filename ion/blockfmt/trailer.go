@@ -46,6 +46,14 @@ type Blockdesc struct {
 	// 1 << Trailer.BlockShift) within
 	// this block
 	Chunks int
+	// Checksum is the CRC-32C of the compressed
+	// bytes of this block. Checksum is only
+	// meaningful if Trailer.Checksums is set.
+	Checksum uint32
+	// Rows is the number of rows within
+	// this block. Rows is only meaningful
+	// if Trailer.RowCounts is set.
+	Rows int64
 }
 
 // Trailer is a collection
@@ -73,6 +81,31 @@ type Trailer struct {
 	// of timestamp ranges and constant fields
 	// within Blocks.
 	Sparse SparseIndex
+	// Schema, if non-nil, describes the
+	// fields present in the rows of Blocks.
+	Schema *Schema
+	// Checksums indicates that each of Blocks
+	// has a valid Checksum. Setting Checksums
+	// on the Trailer of a CompressionWriter or
+	// MultiWriter before writing any data causes
+	// checksums to be computed for each block.
+	//
+	// Note that trailers with checksums cannot
+	// be decoded by older versions of this package.
+	Checksums bool
+	// RowCounts indicates that each of Blocks
+	// has a valid Rows count. RowCounts is set
+	// automatically by a CompressionWriter or
+	// MultiWriter when every block it produced
+	// (or retained from a prepended object)
+	// has a known number of rows.
+	RowCounts bool
+	// Encrypted indicates that the data in each
+	// of Blocks has been encrypted with a DataKey.
+	// Encrypted is set automatically by a
+	// CompressionWriter or MultiWriter that
+	// has been provided a DataKey.
+	Encrypted bool
 }
 
 // Encode encodes a trailer to the provided buffer
@@ -104,6 +137,11 @@ func (t *Trailer) Encode(dst *ion.Buffer, st *ion.Symtab) {
 	dst.BeginField(st.Intern("sparse"))
 	t.Sparse.Encode(dst, st)
 
+	if t.Schema != nil {
+		dst.BeginField(st.Intern("schema"))
+		t.Schema.Encode(dst, st)
+	}
+
 	// block offsets are double-differential-encoded
 	// (because they tend to be evenly spaced),
 	// and chunk counts are delta-encoded (because
@@ -123,6 +161,33 @@ func (t *Trailer) Encode(dst *ion.Buffer, st *ion.Symtab) {
 	}
 	dst.EndList()
 
+	// checksums always follow blocks
+	// so that they can be attached to
+	// the blocks as they are decoded
+	if t.Checksums {
+		dst.BeginField(st.Intern("checksums"))
+		dst.BeginList(-1)
+		for i := range t.Blocks {
+			dst.WriteUint(uint64(t.Blocks[i].Checksum))
+		}
+		dst.EndList()
+	}
+
+	// row counts likewise follow blocks
+	if t.RowCounts {
+		dst.BeginField(st.Intern("block-rows"))
+		dst.BeginList(-1)
+		for i := range t.Blocks {
+			dst.WriteInt(t.Blocks[i].Rows)
+		}
+		dst.EndList()
+	}
+
+	if t.Encrypted {
+		dst.BeginField(st.Intern("encrypted"))
+		dst.WriteBool(true)
+	}
+
 	dst.EndStruct()
 }
 
@@ -327,6 +392,9 @@ func (d *TrailerDecoder) Decode(v ion.Datum, dst *Trailer) error {
 		case "sparse":
 			seenSparse = true
 			return d.decodeSparse(&dst.Sparse, f.Datum)
+		case "schema":
+			dst.Schema = new(Schema)
+			return dst.Schema.Decode(f.Datum)
 		case "blocks-delta":
 			// smaller delta-encoded block list format
 			n, err := countList(f.Datum)
@@ -335,6 +403,54 @@ func (d *TrailerDecoder) Decode(v ion.Datum, dst *Trailer) error {
 			}
 			dst.Blocks = d.makeBlocks(n / 2)[:0]
 			dst.unpackBlocks(f.Raw())
+		case "checksums":
+			i := 0
+			err := f.UnpackList(func(v ion.Datum) error {
+				sum, err := v.Uint()
+				if err != nil {
+					return err
+				}
+				if i >= len(dst.Blocks) {
+					return fmt.Errorf("%d checksums for %d blocks", i+1, len(dst.Blocks))
+				}
+				dst.Blocks[i].Checksum = uint32(sum)
+				i++
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			if i != len(dst.Blocks) {
+				return fmt.Errorf("%d checksums for %d blocks", i, len(dst.Blocks))
+			}
+			dst.Checksums = true
+		case "block-rows":
+			i := 0
+			err := f.UnpackList(func(v ion.Datum) error {
+				rows, err := v.Int()
+				if err != nil {
+					return err
+				}
+				if i >= len(dst.Blocks) {
+					return fmt.Errorf("%d row counts for %d blocks", i+1, len(dst.Blocks))
+				}
+				dst.Blocks[i].Rows = rows
+				i++
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			if i != len(dst.Blocks) {
+				return fmt.Errorf("%d row counts for %d blocks", i, len(dst.Blocks))
+			}
+			dst.RowCounts = true
+		case "encrypted":
+			b, err := f.Bool()
+			if err != nil {
+				return err
+			}
+			dst.Encrypted = b
 		case "blocks":
 			// old-format block lists
 			n, err := countList(f.Datum)
@@ -454,3 +570,62 @@ func (t *Trailer) Decompressed() int64 {
 	}
 	return int64(chunks) * int64(1<<t.BlockShift)
 }
+
+// Rows returns the number of rows within the
+// trailer blocks, or false if the number of rows
+// is unknown because the trailer has neither
+// per-block row counts nor a Schema.
+func (t *Trailer) Rows() (int64, bool) {
+	if t.RowCounts {
+		return t.ExactRows(0, len(t.Blocks))
+	}
+	if t.Schema == nil {
+		return 0, false
+	}
+	return t.Schema.Rows, true
+}
+
+// ExactRows returns the number of rows
+// within Blocks[start:end], or false if
+// the trailer does not have per-block
+// row counts (see RowCounts).
+func (t *Trailer) ExactRows(start, end int) (int64, bool) {
+	if !t.RowCounts {
+		return 0, false
+	}
+	rows := int64(0)
+	for i := start; i < end; i++ {
+		rows += t.Blocks[i].Rows
+	}
+	return rows, true
+}
+
+// BlockRows returns an estimate of the number
+// of rows within Blocks[start:end], or false
+// if the number of rows is unknown.
+// The estimate is exact if t.RowCounts is set;
+// otherwise rows are assumed to be distributed
+// evenly across chunks.
+func (t *Trailer) BlockRows(start, end int) (int64, bool) {
+	if t.RowCounts {
+		return t.ExactRows(start, end)
+	}
+	rows, ok := t.Rows()
+	if !ok {
+		return 0, false
+	}
+	if start == 0 && end == len(t.Blocks) {
+		return rows, true
+	}
+	total, part := 0, 0
+	for i := range t.Blocks {
+		total += t.Blocks[i].Chunks
+		if i >= start && i < end {
+			part += t.Blocks[i].Chunks
+		}
+	}
+	if total == 0 {
+		return 0, true
+	}
+	return rows * int64(part) / int64(total), true
+}
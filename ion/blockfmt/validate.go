@@ -15,6 +15,7 @@
 package blockfmt
 
 import (
+	"errors"
 	"fmt"
 	"io"
 
@@ -32,9 +33,17 @@ func Validate(src io.Reader, t *Trailer, diag io.Writer) int {
 	if t.Sparse.Blocks() != len(t.Blocks) {
 		fmt.Fprintf(diag, "sparse has %d blocks; trailer has %d", t.Sparse.Blocks(), len(t.Blocks))
 	}
-	d.Set(t, len(t.Blocks))
+	if t.Encrypted {
+		fmt.Fprintf(diag, "object is encrypted; cannot validate block contents\n")
+		return 0
+	}
+	d.SetRange(t, 0, len(t.Blocks))
 	w := checkWriter{dst: diag, blocks: t.Blocks, sparse: &t.Sparse}
-	d.Copy(&w, src)
+	_, err := d.Copy(&w, src)
+	var cerr *ChecksumError
+	if errors.As(err, &cerr) {
+		fmt.Fprintf(diag, "%s\n", cerr)
+	}
 	return w.rows
 }
 
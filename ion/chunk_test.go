@@ -215,6 +215,87 @@ func TestChunkerRange(t *testing.T) {
 	}
 }
 
+func TestChunkerKeyRanges(t *testing.T) {
+	rw := new(rangeWriter)
+	c := &ion.Chunker{
+		W:          rw,
+		Align:      1024,
+		RangeAlign: 4 * 1024,
+		KeyRanges:  [][]string{{"num"}, {"a", "str"}, {"mixed"}, {"when"}},
+	}
+	t0 := date.Date(2022, 1, 1, 0, 0, 0, 0)
+
+	const rows = 2000
+	for i := 0; i < rows; i++ {
+		// the symbol table is reset when
+		// ranges are flushed, so symbols
+		// have to be interned for every row
+		c.BeginStruct(-1)
+		c.BeginField(c.Symbols.Intern("num"))
+		c.WriteInt(int64(rows - i))
+		c.BeginField(c.Symbols.Intern("a"))
+		c.BeginStruct(-1)
+		c.BeginField(c.Symbols.Intern("str"))
+		c.WriteString(fmt.Sprintf("str-%05d", i))
+		c.EndStruct()
+		c.BeginField(c.Symbols.Intern("mixed"))
+		if i%2 == 0 {
+			c.WriteInt(int64(i))
+		} else {
+			c.WriteString("odd")
+		}
+		c.BeginField(c.Symbols.Intern("when"))
+		c.WriteTime(t0.Add(time.Duration(i) * time.Second))
+		c.EndStruct()
+		if err := c.Commit(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := c.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if len(rw.allRanges) < 2 {
+		t.Fatalf("only %d range flushes", len(rw.allRanges))
+	}
+	next := int64(rows)
+	for i, rng := range rw.allRanges {
+		var num, str, when *ranges
+		for j := range rng {
+			switch strings.Join(rng[j].path, ".") {
+			case "num":
+				num = &rng[j]
+			case "a.str":
+				str = &rng[j]
+			case "when":
+				when = &rng[j]
+			case "mixed":
+				t.Fatalf("flush %d: unexpected range for values of mixed types", i)
+			}
+		}
+		if num == nil || str == nil || when == nil {
+			t.Fatalf("flush %d: missing ranges in %v", i, rng)
+		}
+		lo, _ := num.min.Int()
+		hi, _ := num.max.Int()
+		if hi != next || lo > hi {
+			t.Fatalf("flush %d: num range [%d, %d] (expected max %d)", i, lo, hi, next)
+		}
+		next = lo - 1
+		// the string ranges should match the numbers
+		first, _ := str.min.String()
+		last, _ := str.max.String()
+		if first != fmt.Sprintf("str-%05d", rows-hi) || last != fmt.Sprintf("str-%05d", rows-lo) {
+			t.Fatalf("flush %d: str range [%s, %s] for num [%d, %d]", i, first, last, lo, hi)
+		}
+		if !when.min.IsTimestamp() || !when.max.IsTimestamp() {
+			t.Fatalf("flush %d: expected a time range for when", i)
+		}
+	}
+	if next != 0 {
+		t.Fatalf("missing ranges below %d", next)
+	}
+}
+
 func checkEncoding(t *testing.T, buf *rangeBuf, align int) {
 	mem := buf.Bytes()
 	var st ion.Symtab
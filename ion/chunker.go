@@ -74,6 +74,10 @@ type Chunker struct {
 	// Ranges stores field ranges for the current
 	// chunk.
 	Ranges Ranges
+	// Observe, if non-nil, is called from Commit
+	// with each committed object and the symbol
+	// table used to encode it.
+	Observe func(obj []byte, st *Symtab)
 
 	writesyms Symtab       // symbol table for Write()
 	rs        resymbolizer // resymbolizer for Write()
@@ -87,6 +91,29 @@ type Chunker struct {
 	// symbolized WalkTimeRanges
 	rangeSyms [][]Symbol
 
+	// SortBy, if non-empty, is the path of a value
+	// by which the rows within each output chunk
+	// are sorted. Rows are sorted as each chunk is
+	// flushed, so the order of rows is only changed
+	// within a chunk, never across chunks.
+	// Rows that do not have a value at SortBy are
+	// placed after all other rows, and rows with
+	// equal values keep the order in which they
+	// were committed.
+	// (See sortRows for the ordering of values.)
+	SortBy []string
+
+	// KeyRanges is a list of paths for which the
+	// minimum and maximum values are tracked within
+	// each range of output (see RangeAlign). Ranges of
+	// numbers, strings and timestamps are reported
+	// to W via SetMinMax just like time ranges;
+	// ranges of values of mixed types are discarded.
+	KeyRanges [][]string
+
+	fieldOrder []string // see SetFieldOrder
+	sortbuf    []byte   // scratch buffer for sortRows
+
 	tmpbuf  Buffer // scratch buffer
 	lastoff int    // last committed object offset
 	lastst  int    // last symbol table size
@@ -320,6 +347,9 @@ func (c *Chunker) forceFlush(final bool) error {
 		cur = cur[:c.lastoff]
 		tail = c.tmpbuf.Bytes()
 	}
+	if len(c.SortBy) > 0 {
+		c.sortRows(cur[c.lastst:])
+	}
 	cur = pad(cur, c.Align)
 	_, err := c.W.Write(cur)
 	if err != nil {
@@ -349,7 +379,7 @@ func (c *Chunker) forceFlush(final bool) error {
 			// if we are going to need a full symbol table
 			// in the next block, resymbolize so that we
 			// don't carry over old symbols
-			resymbolize(&c.Buffer, &c.Ranges, &c.Symbols, tail)
+			resymbolize(&c.Buffer, &c.Ranges, &c.Symbols, c.fieldOrder, tail)
 			c.symEpoch++
 			c.tmpID = 0
 			c.flushID = 0
@@ -412,6 +442,12 @@ func (c *Chunker) Commit() error {
 	if lastsize > c.Align {
 		return err2big(c.Align)
 	}
+	if len(c.KeyRanges) > 0 {
+		c.walkKeyRanges(cur[c.lastoff:])
+	}
+	if c.Observe != nil {
+		c.Observe(cur[c.lastoff:], &c.Symbols)
+	}
 	c.compressed = false
 	if len(cur) <= c.Align && c.adjustSyms() {
 		c.lastoff = c.Buffer.Size()
@@ -459,6 +495,10 @@ func (c *Chunker) Flush() error {
 // If cons is provided, these fields will be
 // added to each structure.
 //
+// Annotated values are replaced with the values
+// they wrap, and their labels are preserved
+// as described in UnwrapAnnotations.
+//
 // BUGS: ReadFrom only indexes data from the top-level
 // of each structure.
 func (c *Chunker) ReadFrom(r io.Reader, cons []Field) (int64, error) {
@@ -512,6 +552,10 @@ func (c *Chunker) ReadFrom(r io.Reader, cons []Field) (int64, error) {
 			return n, err
 		}
 		if !dat.IsEmpty() {
+			dat, err = UnwrapAnnotations(&st, dat)
+			if err != nil {
+				return n, err
+			}
 			if dat.IsStruct() {
 				s, _ := dat.Struct()
 				dat = s.mergeFields(&st, cons).Datum()
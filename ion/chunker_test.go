@@ -15,8 +15,10 @@
 package ion
 
 import (
+	"bytes"
 	"fmt"
 	"io"
+	"math/rand"
 	"testing"
 )
 
@@ -81,3 +83,151 @@ func TestUniqueFields(t *testing.T) {
 		}
 	}
 }
+
+// chunkRows decodes the rows
+// in each chunk of buf
+func chunkRows(t *testing.T, buf []byte, align int) [][]Struct {
+	var st Symtab
+	var out [][]Struct
+	for len(buf) > 0 {
+		var rows []Struct
+		chunk := buf[:align]
+		buf = buf[align:]
+		for len(chunk) > 0 {
+			d, rest, err := ReadDatum(&st, chunk)
+			if err != nil {
+				t.Fatal(err)
+			}
+			chunk = rest
+			if s, err := d.Struct(); err == nil {
+				rows = append(rows, s)
+			}
+		}
+		out = append(out, rows)
+	}
+	return out
+}
+
+func TestChunkerSortBy(t *testing.T) {
+	var out bytes.Buffer
+	cn := Chunker{
+		W:      &out,
+		Align:  1024,
+		SortBy: []string{"a", "k"},
+	}
+	src := rand.New(rand.NewSource(0))
+	const rows = 1000
+	for i := 0; i < rows; i++ {
+		var fields []Field
+		switch i % 5 {
+		case 0:
+			// no sort key
+		case 1:
+			k := String(fmt.Sprintf("s%d", src.Intn(100)))
+			fields = append(fields, Field{Label: "a", Datum: NewStruct(nil, []Field{{Label: "k", Datum: k}}).Datum()})
+		default:
+			k := Int(int64(src.Intn(1000) - 500))
+			if i%5 == 2 {
+				k = Float(float64(src.Intn(1000)) + 0.5)
+			}
+			fields = append(fields, Field{Label: "a", Datum: NewStruct(nil, []Field{{Label: "k", Datum: k}}).Datum()})
+		}
+		fields = append(fields, Field{Label: "row", Datum: Int(int64(i))})
+		NewStruct(nil, fields).Encode(&cn.Buffer, &cn.Symbols)
+		if err := cn.Commit(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := cn.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	chunks := chunkRows(t, out.Bytes(), cn.Align)
+	if len(chunks) < 2 {
+		t.Fatalf("only %d chunks", len(chunks))
+	}
+	seen := make(map[int64]bool)
+	// keys are numbers, then strings,
+	// then missing values
+	type key struct {
+		class int
+		num   float64
+		str   string
+	}
+	getkey := func(s Struct) key {
+		k := s.Datum().Get("a", "k")
+		if str, err := k.String(); err == nil {
+			return key{class: 1, str: str}
+		}
+		if f, err := k.Float(); err == nil {
+			return key{num: f}
+		}
+		if i, err := k.Int(); err == nil {
+			return key{num: float64(i)}
+		}
+		return key{class: 2}
+	}
+	for i, chunk := range chunks {
+		for j, s := range chunk {
+			row, err := s.Datum().Field("row").Int()
+			if err != nil {
+				t.Fatal(err)
+			}
+			seen[row] = true
+			if j == 0 {
+				continue
+			}
+			prev, cur := getkey(chunk[j-1]), getkey(s)
+			if cur.class < prev.class ||
+				cur.class == prev.class && (cur.num < prev.num || cur.str < prev.str) {
+				t.Fatalf("chunk %d row %d: %s after %s", i, j, s.Datum().JSON(), chunk[j-1].Datum().JSON())
+			}
+		}
+	}
+	if len(seen) != rows {
+		t.Fatalf("got %d distinct rows; expected %d", len(seen), rows)
+	}
+}
+
+func TestChunkerFieldOrder(t *testing.T) {
+	var out bytes.Buffer
+	cn := Chunker{
+		W:     &out,
+		Align: 1024,
+	}
+	order := []string{"zzz", "yyy"}
+	if err := cn.SetFieldOrder(order); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 1000; i++ {
+		// unique field names force the
+		// symbol table to be reset periodically
+		NewStruct(nil, []Field{
+			{Label: fmt.Sprintf("f%d", i), Datum: Int(int64(i))},
+			{Label: "aaa", Datum: Int(int64(i))},
+			{Label: "yyy", Datum: Int(1)},
+			{Label: "zzz", Datum: Int(0)},
+		}).Encode(&cn.Buffer, &cn.Symbols)
+		if err := cn.Commit(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := cn.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if err := cn.SetFieldOrder(order); err == nil {
+		t.Fatal("SetFieldOrder after writing data succeeded")
+	}
+	n := 0
+	for _, chunk := range chunkRows(t, out.Bytes(), cn.Align) {
+		for _, s := range chunk {
+			fields := s.Fields(nil)
+			if len(fields) != 4 || fields[0].Label != "zzz" || fields[1].Label != "yyy" {
+				t.Fatalf("unexpected field order in %s", s.Datum().JSON())
+			}
+			n++
+		}
+	}
+	if n != 1000 {
+		t.Fatalf("got %d rows", n)
+	}
+}
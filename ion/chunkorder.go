@@ -0,0 +1,278 @@
+// Copyright (C) 2022 Sneller, Inc.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package ion
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/SnellerInc/sneller/date"
+)
+
+// SetFieldOrder sets the canonical order of
+// the given structure fields.
+//
+// Structure fields are always encoded in the
+// order of their symbol IDs, so the field order
+// of rows written to a Chunker otherwise depends
+// on the order in which field names are first seen
+// (and may change whenever the symbol table is
+// reset between chunks). SetFieldOrder assigns the
+// first symbol IDs of every symbol table produced
+// by the Chunker to fields, so that these fields
+// always appear first in every structure, in the
+// order in which they are listed. All other fields
+// follow them in the order of their symbol IDs.
+//
+// SetFieldOrder must be called before any data
+// has been written to c.
+func (c *Chunker) SetFieldOrder(fields []string) error {
+	if c.Buffer.Size() > 0 || c.Symbols.MaxID() > len(systemsyms) {
+		return fmt.Errorf("ion.Chunker.SetFieldOrder called after data was written")
+	}
+	for i := range fields {
+		c.Symbols.Intern(fields[i])
+	}
+	c.fieldOrder = append(c.fieldOrder[:0], fields...)
+	return nil
+}
+
+// sortKey is the part of a value
+// that determines its sorting order
+type sortKey struct {
+	class int // see key classes below
+	num   float64
+	i     int64 // exact value of integers
+	exact bool  // i is valid
+	str   string
+	ts    date.Time
+	raw   []byte
+}
+
+// key classes, in sorting order
+const (
+	keyBool = iota
+	keyNumber
+	keyTimestamp
+	keyString
+	keyOther
+	keyNull
+	keyMissing
+)
+
+func makeSortKey(st *Symtab, val []byte) sortKey {
+	if val == nil {
+		return sortKey{class: keyMissing}
+	}
+	d, _, err := ReadDatum(st, val)
+	if err != nil {
+		return sortKey{class: keyOther, raw: val}
+	}
+	return datumSortKey(d, val)
+}
+
+// datumSortKey returns the sort key of d,
+// where raw is the encoded representation of d
+func datumSortKey(d Datum, raw []byte) sortKey {
+	switch d.Type() {
+	case NullType:
+		return sortKey{class: keyNull}
+	case BoolType:
+		b, _ := d.Bool()
+		k := sortKey{class: keyBool}
+		if b {
+			k.num = 1
+		}
+		return k
+	case IntType:
+		i, _ := d.Int()
+		return sortKey{class: keyNumber, num: float64(i), i: i, exact: true}
+	case UintType:
+		u, _ := d.Uint()
+		if u <= math.MaxInt64 {
+			return sortKey{class: keyNumber, num: float64(u), i: int64(u), exact: true}
+		}
+		return sortKey{class: keyNumber, num: float64(u)}
+	case FloatType:
+		f, _ := d.Float()
+		return sortKey{class: keyNumber, num: f}
+	case TimestampType:
+		ts, _ := d.Timestamp()
+		return sortKey{class: keyTimestamp, ts: ts}
+	case StringType, SymbolType:
+		s, _ := d.String()
+		return sortKey{class: keyString, str: s}
+	}
+	return sortKey{class: keyOther, raw: raw}
+}
+
+// ranged returns whether k can be
+// part of a key range (see Chunker.KeyRanges)
+func (k *sortKey) ranged() bool {
+	return k.class == keyString || (k.class == keyNumber && !math.IsNaN(k.num))
+}
+
+// datum returns the value of a number or string key
+func (k *sortKey) datum() Datum {
+	switch k.class {
+	case keyNumber:
+		if k.exact {
+			return Int(k.i)
+		}
+		return Float(k.num)
+	case keyString:
+		return String(k.str)
+	}
+	panic("ion: sortKey.datum on unsupported key class")
+}
+
+func (k *sortKey) less(o *sortKey) bool {
+	if k.class != o.class {
+		return k.class < o.class
+	}
+	switch k.class {
+	case keyBool, keyNumber:
+		if k.exact && o.exact {
+			return k.i < o.i
+		}
+		return k.num < o.num
+	case keyTimestamp:
+		return k.ts.Before(o.ts)
+	case keyString:
+		return k.str < o.str
+	case keyOther:
+		return bytes.Compare(k.raw, o.raw) < 0
+	}
+	return false
+}
+
+// sortRows sorts the rows in body (the committed
+// rows of a chunk following its symbol table)
+// by the value at c.SortBy
+//
+// Booleans sort before numbers, which sort
+// before timestamps and then strings and symbols;
+// values of any other type are ordered by their
+// encoding and are followed by nulls and finally
+// by missing values.
+func (c *Chunker) sortRows(body []byte) {
+	path := make([]Symbol, len(c.SortBy))
+	for i := range c.SortBy {
+		sym, ok := c.Symbols.Symbolize(c.SortBy[i])
+		if !ok {
+			// no row in this chunk has the path
+			return
+		}
+		path[i] = sym
+	}
+	type row struct {
+		mem []byte
+		key sortKey
+	}
+	var rows []row
+	for rest := body; len(rest) > 0; {
+		size := SizeOf(rest)
+		if size <= 0 || size > len(rest) {
+			panic("ion.Chunker.sortRows: invalid row")
+		}
+		rows = append(rows, row{
+			mem: rest[:size],
+			key: makeSortKey(&c.Symbols, lookupPath(path, rest[:size])),
+		})
+		rest = rest[size:]
+	}
+	sort.SliceStable(rows, func(i, j int) bool {
+		return rows[i].key.less(&rows[j].key)
+	})
+	c.sortbuf = c.sortbuf[:0]
+	for i := range rows {
+		c.sortbuf = append(c.sortbuf, rows[i].mem...)
+	}
+	copy(body, c.sortbuf)
+}
+
+// SetKeyRange clobbers the currently-stored range
+// of values of the key at p (see KeyRanges).
+// Both min and max must be either numbers or strings.
+func (c *Chunker) SetKeyRange(p []string, min, max Datum) {
+	var sb Symbuf
+	sb.Prepare(len(p))
+	for i := range p {
+		sb.Push(c.Symbols.Intern(p[i]))
+	}
+	kmin, kmax := datumSortKey(min, nil), datumSortKey(max, nil)
+	if !kmin.ranged() || !kmax.ranged() {
+		return
+	}
+	c.Ranges.addKey(sb, kmin)
+	c.Ranges.commit()
+	c.Ranges.addKey(sb, kmax)
+	c.Ranges.commit()
+}
+
+// walkKeyRanges adds the values of c.KeyRanges
+// within the (uncommitted) record rec to c.Ranges
+func (c *Chunker) walkKeyRanges(rec []byte) {
+	var sb Symbuf
+outer:
+	for _, path := range c.KeyRanges {
+		sb.Prepare(len(path))
+		syms := make([]Symbol, len(path))
+		for i := range path {
+			sym, ok := c.Symbols.Symbolize(path[i])
+			if !ok {
+				continue outer
+			}
+			syms[i] = sym
+			sb.Push(sym)
+		}
+		val := lookupPath(syms, rec)
+		if val == nil {
+			continue
+		}
+		if TypeOf(val) == TimestampType {
+			if ts, _, err := ReadTime(val); err == nil {
+				c.Ranges.AddTime(sb, ts)
+			}
+			continue
+		}
+		if k := makeSortKey(&c.Symbols, val); k.ranged() {
+			c.Ranges.addKey(sb, k)
+		}
+	}
+}
+
+// lookupPath returns the value at path
+// within the structure rec, or nil if
+// there is no such value
+func lookupPath(path []Symbol, rec []byte) []byte {
+	val := rec
+	for i := range path {
+		if TypeOf(val) != StructType {
+			return nil
+		}
+		body, _ := Contents(val)
+		if body == nil {
+			return nil
+		}
+		_, val = seek(path[i], body)
+		if val == nil {
+			return nil
+		}
+	}
+	return val
+}
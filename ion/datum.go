@@ -1363,7 +1363,7 @@ func decodeAnnotationDatum(st *Symtab, b []byte) (Datum, []byte, error) {
 // will not be modified until it is no longer needed.
 func ReadDatum(st *Symtab, buf []byte) (Datum, []byte, error) {
 	var err error
-	if IsBVM(buf) || TypeOf(buf) == AnnotationType {
+	if IsBVM(buf) || isSymbolTable(buf) {
 		buf, err = st.Unmarshal(buf)
 		if err != nil {
 			return Empty, nil, err
@@ -1415,7 +1415,7 @@ func ReadDatum(st *Symtab, buf []byte) (Datum, []byte, error) {
 // interpretting it. This also handles symbol tables
 // the same way that ReadDatum does.
 func validateDatum(st *Symtab, buf []byte) (next []byte, err error) {
-	if IsBVM(buf) || TypeOf(buf) == AnnotationType {
+	if IsBVM(buf) || isSymbolTable(buf) {
 		buf, err = st.Unmarshal(buf)
 		if err != nil {
 			return nil, err
@@ -0,0 +1,39 @@
+// Copyright (C) 2022 Sneller, Inc.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+//go:build amd64
+
+package ion
+
+import (
+	"golang.org/x/sys/cpu"
+)
+
+//go:noescape
+func safeprefixAVX2(buf []byte) int
+
+var useAVX2 = cpu.X86.HasAVX2
+
+// safeprefix returns the length of the longest
+// prefix of buf that can be copied into a JSON
+// string without escaping
+func safeprefix(buf []byte) int {
+	n := 0
+	if useAVX2 && len(buf) >= 32 {
+		// the assembly only examines
+		// whole 32-byte blocks
+		n = safeprefixAVX2(buf)
+	}
+	return n + safeprefixGeneric(buf[n:])
+}
@@ -0,0 +1,62 @@
+// Copyright (C) 2022 Sneller, Inc.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+#include "textflag.h"
+
+// func safeprefixAVX2(buf []byte) int
+//
+// Returns the offset of the first byte in buf
+// that is not printable ASCII or that is a quote
+// or a backslash. Only whole 32-byte blocks are
+// examined, so the result is a multiple of 32
+// if no such byte is found.
+TEXT ·safeprefixAVX2(SB), NOSPLIT, $0-32
+    MOVQ         buf_base+0(FP), SI
+    MOVQ         buf_len+8(FP), CX
+    ANDQ         $-32, CX          // CX = length of whole blocks
+    XORL         DX, DX            // DX = offset
+    MOVL         $0x1f1f1f1f, AX
+    VMOVD        AX, X3
+    VPBROADCASTD X3, Y3            // Y3 = 0x1f
+    MOVL         $0x22222222, AX
+    VMOVD        AX, X4
+    VPBROADCASTD X4, Y4            // Y4 = '"'
+    MOVL         $0x5c5c5c5c, AX
+    VMOVD        AX, X5
+    VPBROADCASTD X5, Y5            // Y5 = '\'
+    JMP          loop_tail
+loop:
+    VMOVDQU      0(SI)(DX*1), Y0
+    VPCMPGTB     Y3, Y0, Y1        // Y1 = 0x20 <= c < 0x80 (signed compare)
+    VPCMPEQB     Y4, Y0, Y2        // Y2 = c == '"'
+    VPCMPEQB     Y5, Y0, Y0        // Y0 = c == '\'
+    VPOR         Y2, Y0, Y0
+    VPANDN       Y1, Y0, Y1        // Y1 = safe bytes
+    VPMOVMSKB    Y1, AX
+    NOTL         AX                // AX = unsafe bytes
+    TESTL        AX, AX
+    JNZ          found
+    ADDQ         $32, DX
+loop_tail:
+    CMPQ         DX, CX
+    JB           loop
+    MOVQ         DX, ret+24(FP)
+    VZEROUPPER
+    RET
+found:
+    BSFL         AX, AX
+    ADDQ         AX, DX
+    MOVQ         DX, ret+24(FP)
+    VZEROUPPER
+    RET
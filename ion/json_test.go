@@ -20,10 +20,13 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"math"
 	"os"
 	"strings"
 	"testing"
 	"time"
+
+	"github.com/SnellerInc/sneller/date"
 )
 
 func TestTicketsToJSON(t *testing.T) {
@@ -86,7 +89,7 @@ func TestToJSON(t *testing.T) {
 			// x::y -> {"$ion_annotation$x":y}
 			item:     Annotation(nil, "foo", Int(10)),
 			annotate: true,
-			want:     `{"$ion_annotation$foo":10}}`,
+			want:     `{"$ion_annotation$foo":10}`,
 		},
 	}
 	contents := func(item Datum) []byte {
@@ -132,6 +135,63 @@ func TestToJSON(t *testing.T) {
 	}
 }
 
+func TestJSONOptions(t *testing.T) {
+	ts := date.Date(1969, 12, 31, 23, 59, 59, 999500000)
+	item := NewStruct(nil, []Field{
+		{Label: "ts", Datum: Timestamp(ts)},
+		{Label: "f", Datum: Float(3)},
+		{Label: "big", Datum: Float(1e21)},
+		{Label: "nan", Datum: Float(math.NaN())},
+		{Label: "inf", Datum: Float(math.Inf(-1))},
+		{Label: "i", Datum: Int(-(1 << 54))},
+		{Label: "u", Datum: Uint(1 << 63)},
+		{Label: "small", Datum: Int(42)},
+	}).Datum()
+	var dst Buffer
+	var st Symtab
+	item.Encode(&dst, &st)
+	body := dst.Bytes()
+	dst.Set(nil)
+	st.Marshal(&dst, true)
+	mem := append(dst.Bytes(), body...)
+
+	cases := []struct {
+		opts JSONOptions
+		want string
+	}{
+		{
+			want: `{"ts": "1969-12-31T23:59:59.9995Z", "f": 3, "big": 1e+21, "nan": NaN, "inf": -Inf, "i": -18014398509481984, "u": 9223372036854775808, "small": 42}`,
+		},
+		{
+			opts: JSONOptions{
+				Timestamps:       TimestampEpochMillis,
+				NonFinite:        NonFiniteNull,
+				FloatPoint:       true,
+				NoExponent:       true,
+				BigIntsAsStrings: true,
+			},
+			want: `{"ts": -1, "f": 3.0, "big": 1000000000000000000000.0, "nan": null, "inf": null, "i": "-18014398509481984", "u": "9223372036854775808", "small": 42}`,
+		},
+		{
+			opts: JSONOptions{NonFinite: NonFiniteString, FloatPoint: true},
+			want: `{"ts": "1969-12-31T23:59:59.9995Z", "f": 3.0, "big": 1e+21, "nan": "NaN", "inf": "-Infinity", "i": -18014398509481984, "u": 9223372036854775808, "small": 42}`,
+		},
+	}
+	for i := range cases {
+		var out bytes.Buffer
+		w := NewJSONWriter(&out, '\n')
+		w.Options = cases[i].opts
+		if _, err := w.Write(mem); err != nil {
+			t.Fatal(err)
+		}
+		got := strings.TrimSpace(out.String())
+		if got != cases[i].want {
+			t.Errorf("case %d: got  %s", i, got)
+			t.Errorf("case %d: want %s", i, cases[i].want)
+		}
+	}
+}
+
 func TestJSONArray(t *testing.T) {
 	st0 := NewStruct(nil,
 		[]Field{
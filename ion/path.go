@@ -0,0 +1,300 @@
+// Copyright (C) 2022 Sneller, Inc.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package ion
+
+import (
+	"fmt"
+)
+
+// A path is a sequence of path elements, each of
+// which is either a string, which selects a structure
+// field by name, or an int, which selects a list
+// item by its (zero-based) index.
+
+func checkPath(path []any) {
+	for i := range path {
+		switch path[i].(type) {
+		case string, int:
+		default:
+			panic(fmt.Sprintf("ion: path element %v has type %T (want string or int)", path[i], path[i]))
+		}
+	}
+}
+
+// Index returns the i-th item in l
+// or Empty if l has i or fewer items.
+func (l List) Index(i int) Datum {
+	if i < 0 {
+		return Empty
+	}
+	var out Datum
+	l.Each(func(d Datum) error {
+		if i == 0 {
+			out = d
+			return Stop
+		}
+		i--
+		return nil
+	})
+	return out
+}
+
+// Get returns the value at the given path
+// within d, or Empty if the path is not present.
+// Each element of path must be a string, which
+// selects a structure field by name, or an int,
+// which selects a list item by index; Get panics
+// if it encounters any other type.
+//
+// Get does not decode any part of d other than the
+// containers along the path, and the returned Datum
+// shares its storage with d.
+func (d Datum) Get(path ...any) Datum {
+	checkPath(path)
+	for _, p := range path {
+		switch p := p.(type) {
+		case string:
+			d = d.Field(p)
+		case int:
+			l, err := d.List()
+			if err != nil {
+				return Empty
+			}
+			d = l.Index(p)
+		}
+		if d.IsEmpty() {
+			return Empty
+		}
+	}
+	return d
+}
+
+// A Builder accumulates changes to a Datum
+// and produces the updated Datum.
+//
+// Changes are applied copy-on-write: the original
+// Datum is never modified, and only the structures
+// and lists along the paths that have been changed
+// are re-encoded. All other values are copied
+// from the original as-is.
+type Builder struct {
+	root Datum
+	edit edit
+}
+
+// edit describes the changes to one value;
+// either fields or items may be populated,
+// but not both
+type edit struct {
+	value   Datum // replacement for the original value
+	replace bool  // value replaces the original
+	remove  bool  // the value is deleted
+	fields  []fieldEdit
+	items   []itemEdit
+}
+
+type fieldEdit struct {
+	label string
+	edit  *edit
+}
+
+type itemEdit struct {
+	index int
+	edit  *edit
+}
+
+// NewBuilder returns a Builder that
+// applies changes to d.
+func NewBuilder(d Datum) *Builder {
+	return &Builder{root: d}
+}
+
+func (e *edit) child(p any) *edit {
+	switch p := p.(type) {
+	case string:
+		for i := range e.fields {
+			if e.fields[i].label == p {
+				return e.fields[i].edit
+			}
+		}
+		c := &edit{}
+		e.fields = append(e.fields, fieldEdit{label: p, edit: c})
+		return c
+	case int:
+		for i := range e.items {
+			if e.items[i].index == p {
+				return e.items[i].edit
+			}
+		}
+		c := &edit{}
+		e.items = append(e.items, itemEdit{index: p, edit: c})
+		return c
+	}
+	panic("unreachable")
+}
+
+func (b *Builder) walk(path []any) *edit {
+	checkPath(path)
+	e := &b.edit
+	for _, p := range path {
+		if e.remove {
+			// changes within a deleted value
+			// start from a missing value
+			*e = edit{replace: true}
+		}
+		e = e.child(p)
+	}
+	return e
+}
+
+// Set sets the value at the given path to v.
+// Each element of path is a string or an int,
+// as with Datum.Get. Structures along the path
+// that do not have the field named by the path
+// have the field added, and missing values along
+// the path are created as empty structures.
+// List indexes always refer to positions in the
+// original list; they must refer to an existing
+// item, or to the position just past the end of
+// the list in order to append an item.
+//
+// Setting a value replaces any previous
+// changes to the value or to values within it.
+func (b *Builder) Set(v Datum, path ...any) *Builder {
+	e := b.walk(path)
+	*e = edit{value: v, replace: true}
+	return b
+}
+
+// Delete removes the value at the given path.
+// Deleting a structure field removes the field,
+// and deleting a list item removes the item, so
+// the subsequent items are shifted down by one
+// in the result.
+// Deleting a path that does not exist has no effect.
+// Deleting the empty path produces Empty.
+func (b *Builder) Delete(path ...any) *Builder {
+	e := b.walk(path)
+	*e = edit{remove: true}
+	return b
+}
+
+// Datum returns the result of applying
+// the changes to the original Datum.
+// Datum returns an error if a path element
+// conflicts with the type of the value it is
+// applied to (for example, if a field is set
+// within a value that is not a structure).
+func (b *Builder) Datum() (Datum, error) {
+	if b.edit.remove {
+		return Empty, nil
+	}
+	var st Symtab
+	return b.edit.apply(&st, b.root)
+}
+
+func (e *edit) apply(st *Symtab, d Datum) (Datum, error) {
+	if e.replace {
+		d = e.value
+	}
+	switch {
+	case len(e.fields) > 0 && len(e.items) > 0:
+		return Empty, fmt.Errorf("ion.Builder: path uses both fields and indexes of the same value")
+	case len(e.fields) > 0:
+		return e.applyFields(st, d)
+	case len(e.items) > 0:
+		return e.applyItems(st, d)
+	}
+	return d, nil
+}
+
+func (e *edit) applyFields(st *Symtab, d Datum) (Datum, error) {
+	var fields []Field
+	if !d.IsEmpty() && !d.IsNull() {
+		s, err := d.Struct()
+		if err != nil {
+			return Empty, fmt.Errorf("ion.Builder: cannot set field %q: %w", e.fields[0].label, err)
+		}
+		fields = s.Fields(nil)
+	}
+	for _, fe := range e.fields {
+		j := -1
+		for i := range fields {
+			if fields[i].Label == fe.label {
+				j = i
+				break
+			}
+		}
+		if fe.edit.remove {
+			if j >= 0 {
+				fields = append(fields[:j], fields[j+1:]...)
+			}
+			continue
+		}
+		var old Datum
+		if j >= 0 {
+			old = fields[j].Datum
+		}
+		v, err := fe.edit.apply(st, old)
+		if err != nil {
+			return Empty, err
+		}
+		if j >= 0 {
+			fields[j].Datum = v
+		} else {
+			fields = append(fields, Field{Label: fe.label, Datum: v})
+		}
+	}
+	return NewStruct(st, fields).Datum(), nil
+}
+
+func (e *edit) applyItems(st *Symtab, d Datum) (Datum, error) {
+	l, err := d.List()
+	if err != nil {
+		return Empty, fmt.Errorf("ion.Builder: cannot set item %d: %w", e.items[0].index, err)
+	}
+	items := l.Items(nil)
+	n := len(items)
+	removed := false
+	for _, ie := range e.items {
+		j := ie.index
+		if j < 0 || j > n {
+			return Empty, fmt.Errorf("ion.Builder: list index %d out of range [0, %d]", j, n)
+		}
+		if j == n {
+			items = append(items, Empty)
+		}
+		if ie.edit.remove {
+			items[j] = Empty
+			removed = true
+			continue
+		}
+		v, err := ie.edit.apply(st, items[j])
+		if err != nil {
+			return Empty, err
+		}
+		items[j] = v
+	}
+	if removed {
+		out := items[:0]
+		for i := range items {
+			if !items[i].IsEmpty() {
+				out = append(out, items[i])
+			}
+		}
+		items = out
+	}
+	return NewList(st, items).Datum(), nil
+}
@@ -0,0 +1,170 @@
+// Copyright (C) 2022 Sneller, Inc.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package ion
+
+import (
+	"bytes"
+	"testing"
+)
+
+// pathTestDatum returns
+//
+//	{a: {b: 1, c: [10, {d: "x"}, 30]}, e: "y"}
+func pathTestDatum() Datum {
+	var st Symtab
+	inner := NewStruct(&st, []Field{{Label: "d", Datum: String("x")}}).Datum()
+	list := NewList(&st, []Datum{Int(10), inner, Int(30)}).Datum()
+	a := NewStruct(&st, []Field{
+		{Label: "b", Datum: Int(1)},
+		{Label: "c", Datum: list},
+	}).Datum()
+	return NewStruct(&st, []Field{
+		{Label: "a", Datum: a},
+		{Label: "e", Datum: String("y")},
+	}).Datum()
+}
+
+func TestDatumGet(t *testing.T) {
+	d := pathTestDatum()
+	testcases := []struct {
+		path []any
+		want string // JSON; "" for Empty
+	}{
+		{nil, d.JSON()},
+		{[]any{"e"}, `"y"`},
+		{[]any{"a", "b"}, `1`},
+		{[]any{"a", "c", 0}, `10`},
+		{[]any{"a", "c", 1, "d"}, `"x"`},
+		{[]any{"a", "c", 2}, `30`},
+		{[]any{"a", "c", 3}, ""},
+		{[]any{"a", "c", -1}, ""},
+		{[]any{"a", "c", "d"}, ""},
+		{[]any{"a", 0}, ""},
+		{[]any{"x", "y"}, ""},
+		{[]any{"e", "f"}, ""},
+	}
+	for _, tc := range testcases {
+		got := d.Get(tc.path...)
+		if tc.want == "" {
+			if !got.IsEmpty() {
+				t.Errorf("Get(%v): got %s, want Empty", tc.path, got.JSON())
+			}
+			continue
+		}
+		if got.JSON() != tc.want {
+			t.Errorf("Get(%v): got %s, want %s", tc.path, got.JSON(), tc.want)
+		}
+	}
+}
+
+func TestBuilder(t *testing.T) {
+	orig := pathTestDatum()
+	raw := bytes.Clone(orig.Raw())
+	testcases := []struct {
+		name  string
+		build func(b *Builder)
+		want  string
+	}{
+		{
+			name:  "none",
+			build: func(b *Builder) {},
+			want:  `{"a": {"b": 1, "c": [10, {"d": "x"}, 30]}, "e": "y"}`,
+		},
+		{
+			name: "set-field",
+			build: func(b *Builder) {
+				b.Set(Int(2), "a", "b").Set(String("z"), "e")
+			},
+			want: `{"a": {"b": 2, "c": [10, {"d": "x"}, 30]}, "e": "z"}`,
+		},
+		{
+			name: "add-fields",
+			build: func(b *Builder) {
+				b.Set(Bool(true), "f", "g", "h")
+			},
+			want: `{"a": {"b": 1, "c": [10, {"d": "x"}, 30]}, "e": "y", "f": {"g": {"h": true}}}`,
+		},
+		{
+			name: "set-item",
+			build: func(b *Builder) {
+				b.Set(String("w"), "a", "c", 1, "d").Set(Int(40), "a", "c", 3)
+			},
+			want: `{"a": {"b": 1, "c": [10, {"d": "w"}, 30, 40]}, "e": "y"}`,
+		},
+		{
+			name: "delete",
+			build: func(b *Builder) {
+				// indexes refer to the original list
+				b.Delete("a", "c", 0).Delete("a", "c", 2).Delete("e").Delete("missing")
+			},
+			want: `{"a": {"b": 1, "c": [{"d": "x"}]}}`,
+		},
+		{
+			name: "set-after-delete",
+			build: func(b *Builder) {
+				b.Delete("a").Set(Int(3), "a", "x")
+			},
+			want: `{"a": {"x": 3}, "e": "y"}`,
+		},
+		{
+			name: "set-replaces-edits",
+			build: func(b *Builder) {
+				b.Set(Int(2), "a", "b").Set(String("v"), "a")
+			},
+			want: `{"a": "v", "e": "y"}`,
+		},
+		{
+			name: "edit-replacement",
+			build: func(b *Builder) {
+				b.Set(NewStruct(nil, []Field{{Label: "q", Datum: Int(1)}}).Datum(), "a").Set(Int(2), "a", "r")
+			},
+			want: `{"a": {"q": 1, "r": 2}, "e": "y"}`,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			b := NewBuilder(orig)
+			tc.build(b)
+			got, err := b.Datum()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.JSON() != tc.want {
+				t.Errorf("got  %s", got.JSON())
+				t.Errorf("want %s", tc.want)
+			}
+			if !bytes.Equal(orig.Raw(), raw) {
+				t.Fatal("original datum modified")
+			}
+		})
+	}
+
+	// errors
+	for _, build := range []func(b *Builder){
+		func(b *Builder) { b.Set(Int(1), "e", "x") },
+		func(b *Builder) { b.Set(Int(1), "a", 0) },
+		func(b *Builder) { b.Set(Int(1), "a", "c", 5) },
+		func(b *Builder) { b.Set(Int(1), "a", "c", 0).Set(Int(1), "a", "c", "x") },
+	} {
+		b := NewBuilder(orig)
+		build(b)
+		if d, err := b.Datum(); err == nil {
+			t.Errorf("expected an error; got %s", d.JSON())
+		}
+	}
+	if d, _ := NewBuilder(orig).Delete().Datum(); !d.IsEmpty() {
+		t.Errorf("deleting the root produced %s", d.JSON())
+	}
+}
@@ -49,6 +49,22 @@ func (rs *Ranges) AddTime(p Symbuf, t date.Time) {
 	rs.m[k] = r
 }
 
+// addKey adds a number or string to the range tracker.
+func (rs *Ranges) addKey(p Symbuf, k sortKey) {
+	if rs.m == nil {
+		rs.m = make(map[symstr]dataRange)
+	} else if r := rs.m[symstr(p)]; r != nil {
+		switch r := r.(type) {
+		case *keyRange:
+			r.add(k)
+		}
+		return
+	}
+	ks := symstr(p)
+	rs.paths = append(rs.paths, ks)
+	rs.m[ks] = &keyRange{pending: k, hasPending: true}
+}
+
 // commit is called after each object is added to
 // commit any uncommitted range values.
 func (rs *Ranges) commit() {
@@ -154,6 +170,57 @@ func (r *timeRange) add(t date.Time) {
 	r.hasPending = true
 }
 
+// keyRange is a range of either numbers
+// or strings (see Chunker.KeyRanges)
+type keyRange struct {
+	commits    int     // committed count
+	min, max   sortKey // committed range
+	hasRange   bool
+	mixed      bool // committed values have different classes
+	pending    sortKey
+	hasPending bool
+}
+
+func (r *keyRange) ranges() (min, max Datum, ok bool) {
+	if !r.hasRange || r.mixed {
+		return Datum{}, Datum{}, false
+	}
+	return r.min.datum(), r.max.datum(), true
+}
+
+func (r *keyRange) commit() {
+	if !r.hasPending {
+		return
+	}
+	if !r.hasRange {
+		r.min = r.pending
+		r.max = r.pending
+		r.hasRange = true
+	} else if r.pending.class != r.min.class {
+		r.mixed = true
+	} else if r.pending.less(&r.min) {
+		r.min = r.pending
+	} else if r.max.less(&r.pending) {
+		r.max = r.pending
+	}
+	r.commits++
+	r.hasPending = false
+}
+
+func (r *keyRange) count() int { return r.commits }
+
+func (r *keyRange) flush() bool {
+	r.hasRange = false
+	r.mixed = false
+	r.commits = 0
+	return r.hasPending
+}
+
+func (r *keyRange) add(k sortKey) {
+	r.pending = k
+	r.hasPending = true
+}
+
 // Symbuf is an encoded list of symtab indices.
 type Symbuf []byte
 
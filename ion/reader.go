@@ -16,9 +16,11 @@ package ion
 
 import (
 	"bufio"
+	"bytes"
 	"encoding/base64"
 	"fmt"
 	"io"
+	"math"
 	"strconv"
 
 	"github.com/SnellerInc/sneller/date"
@@ -87,6 +89,10 @@ func toJSON(st *Symtab, w jswriter, buf []byte, s *scratch, annotate bool) (int,
 		if err != nil {
 			return 0, rest, fmt.Errorf("ToJSON: %w", err)
 		}
+		if s.opts.BigIntsAsStrings && u > maxExactInt {
+			n, err := w.Write(s.quote(s.uint(u)))
+			return n, rest, err
+		}
 		n, err := w.Write(s.uint(u))
 		return n, rest, err
 	case IntType:
@@ -94,6 +100,10 @@ func toJSON(st *Symtab, w jswriter, buf []byte, s *scratch, annotate bool) (int,
 		if err != nil {
 			return 0, rest, fmt.Errorf("ToJSON: %w", err)
 		}
+		if s.opts.BigIntsAsStrings && (i > maxExactInt || i < -maxExactInt) {
+			n, err := w.Write(s.quote(s.int(i)))
+			return n, rest, err
+		}
 		n, err := w.Write(s.int(i))
 		return n, rest, err
 	case FloatType:
@@ -258,7 +268,7 @@ func toJSON(st *Symtab, w jswriter, buf []byte, s *scratch, annotate bool) (int,
 				if err != nil {
 					return nn, rest, err
 				}
-				n, err = w.WriteString("}}")
+				n, err = w.WriteString("}")
 				nn += n
 				if err != nil {
 					return nn, rest, err
@@ -275,18 +285,111 @@ func toJSON(st *Symtab, w jswriter, buf []byte, s *scratch, annotate bool) (int,
 	}
 }
 
+// TimestampFormat selects how
+// timestamps are written as JSON.
+type TimestampFormat uint8
+
+const (
+	// TimestampRFC3339 writes timestamps as
+	// RFC3339 strings with nanosecond precision.
+	TimestampRFC3339 TimestampFormat = iota
+	// TimestampEpochMillis writes timestamps as the
+	// integer number of milliseconds since the Unix epoch.
+	TimestampEpochMillis
+)
+
+// NonFiniteFormat selects how
+// NaN and infinite floats are written as JSON.
+type NonFiniteFormat uint8
+
+const (
+	// NonFiniteLiteral writes NaN, +Inf and -Inf
+	// as bare words, which many JSON parsers reject.
+	NonFiniteLiteral NonFiniteFormat = iota
+	// NonFiniteNull writes non-finite floats as null.
+	NonFiniteNull
+	// NonFiniteString writes non-finite floats as
+	// the strings "NaN", "Infinity" and "-Infinity".
+	NonFiniteString
+)
+
+// JSONOptions controls how values are written
+// by JSONWriter. The zero value of JSONOptions
+// selects the default formatting that is
+// described in ToJSON.
+type JSONOptions struct {
+	// Timestamps selects the timestamp format.
+	Timestamps TimestampFormat
+	// NonFinite selects the format of NaN and Inf.
+	NonFinite NonFiniteFormat
+	// FloatPoint causes floats with integral
+	// values to be written with a trailing ".0"
+	// so that they can be distinguished from integers.
+	FloatPoint bool
+	// NoExponent causes floats to be written
+	// without an exponent.
+	NoExponent bool
+	// BigIntsAsStrings causes integers that
+	// cannot be represented exactly by a float64
+	// to be written as strings.
+	BigIntsAsStrings bool
+}
+
+// maxExactInt is the largest integer that can be
+// represented exactly by a float64 (and therefore
+// by JSON parsers that treat all numbers as float64)
+const maxExactInt = 1 << 53
+
 // helper for formatting json objects
 type scratch struct {
-	buf []byte
+	buf  []byte
+	opts JSONOptions
 }
 
 func (s *scratch) f32(f float32) []byte {
-	s.buf = strconv.AppendFloat(s.buf[:0], float64(f), 'g', -1, 32)
-	return s.buf
+	return s.float(float64(f), 32)
 }
 
 func (s *scratch) f64(f float64) []byte {
-	s.buf = strconv.AppendFloat(s.buf[:0], f, 'g', -1, 64)
+	return s.float(f, 64)
+}
+
+func (s *scratch) float(f float64, bits int) []byte {
+	finite := !math.IsNaN(f) && !math.IsInf(f, 0)
+	if !finite {
+		switch s.opts.NonFinite {
+		case NonFiniteNull:
+			s.buf = append(s.buf[:0], "null"...)
+			return s.buf
+		case NonFiniteString:
+			switch {
+			case math.IsNaN(f):
+				s.buf = append(s.buf[:0], `"NaN"`...)
+			case f > 0:
+				s.buf = append(s.buf[:0], `"Infinity"`...)
+			default:
+				s.buf = append(s.buf[:0], `"-Infinity"`...)
+			}
+			return s.buf
+		}
+	}
+	format := byte('g')
+	if s.opts.NoExponent {
+		format = 'f'
+	}
+	s.buf = strconv.AppendFloat(s.buf[:0], f, format, -1, bits)
+	if s.opts.FloatPoint && finite && bytes.IndexAny(s.buf, ".e") < 0 {
+		s.buf = append(s.buf, ".0"...)
+	}
+	return s.buf
+}
+
+// quote wraps the contents of s.buf
+// (returned as b) in double quotes
+func (s *scratch) quote(b []byte) []byte {
+	s.buf = append(b, 0, '"')
+	copy(s.buf[1:], b)
+	s.buf[0] = '"'
 	return s.buf
 }
 
@@ -301,6 +404,15 @@ func (s *scratch) uint(u uint64) []byte {
 }
 
 func (s *scratch) time(t date.Time) []byte {
+	if s.opts.Timestamps == TimestampEpochMillis {
+		us := t.UnixMicro()
+		ms := us / 1000
+		if us%1000 < 0 {
+			ms-- // round toward negative infinity
+		}
+		s.buf = strconv.AppendInt(s.buf[:0], ms, 10)
+		return s.buf
+	}
 	s.buf = append(s.buf[:0], '"')
 	s.buf = t.AppendRFC3339Nano(s.buf)
 	s.buf = append(s.buf, '"')
@@ -447,6 +559,8 @@ type JSONWriter struct {
 	// will always begin with "$ion_annotation$"
 	// followed by the annotation label.
 	ShowAnnotations bool
+	// Options controls the formatting of values.
+	Options JSONOptions
 
 	s  scratch
 	b  *bufio.Writer
@@ -515,6 +629,7 @@ func (w *JSONWriter) invisible(src []byte) bool {
 // The buffer passed to Write must contain complete ion objects.
 func (w *JSONWriter) Write(src []byte) (int, error) {
 	p := len(src)
+	w.s.opts = w.Options
 	var size int
 	for len(src) > 0 {
 		comma := w.anyout && !w.nd
@@ -140,13 +140,26 @@ var safeSet = [utf8.RuneSelf]bool{
 
 var hex = "0123456789abcdef"
 
+// safeprefixGeneric is the portable
+// implementation of safeprefix
+func safeprefixGeneric(buf []byte) int {
+	for i, b := range buf {
+		if b >= utf8.RuneSelf || !safeSet[b] {
+			return i
+		}
+	}
+	return len(buf)
+}
+
 func (s *scratch) quoted(in []byte) []byte {
 	s.buf = append(s.buf[:0], '"')
 	start := 0
 	for i := 0; i < len(in); {
 		if b := in[i]; b < utf8.RuneSelf {
 			if safeSet[b] {
-				i++
+				// skip over the whole run of
+				// bytes that need no escaping
+				i += safeprefix(in[i:])
 				continue
 			}
 			if start < i {
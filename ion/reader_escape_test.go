@@ -0,0 +1,134 @@
+// Copyright (C) 2022 Sneller, Inc.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package ion
+
+import (
+	"bytes"
+	"encoding/json"
+	"math/rand"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+// adversarial bytes for the JSON escaper
+var escapeSpecial = []string{
+	"\x00", "\x1f", "\n", "\r", "\t", "\"", "\\",
+	"\x7f", "\x80", "\xff", "\xc3", "\xe2\x80", // invalid UTF-8
+	"é", " ", " ", "\U0001F600",
+}
+
+// unescapedRef returns the string that
+// should be produced by decoding the JSON
+// string produced for in
+func unescapedRef(in []byte) string {
+	var out strings.Builder
+	for len(in) > 0 {
+		r, size := utf8.DecodeRune(in)
+		out.WriteRune(r) // (RuneError for invalid bytes)
+		in = in[size:]
+	}
+	return out.String()
+}
+
+func testQuoted(t *testing.T, in []byte) {
+	t.Helper()
+	var s, ref scratch
+	got := s.quoted(in)
+	// the string path does not use safeprefix
+	want := ref.string(string(in))
+	if !bytes.Equal(got, want) {
+		t.Fatalf("quoted(%q) = %s, want %s", in, got, want)
+	}
+	var out string
+	if err := json.Unmarshal(got, &out); err != nil {
+		t.Fatalf("quoted(%q) = %s: %s", in, got, err)
+	}
+	if out != unescapedRef(in) {
+		t.Fatalf("quoted(%q) decodes to %q", in, out)
+	}
+}
+
+func TestSafeprefix(t *testing.T) {
+	for size := 0; size < 100; size++ {
+		safe := bytes.Repeat([]byte{'a'}, size)
+		if n := safeprefix(safe); n != size {
+			t.Fatalf("safeprefix of %d safe bytes = %d", size, n)
+		}
+		for pos := 0; pos < size; pos++ {
+			for c := 0; c < 256; c++ {
+				buf := append([]byte{}, safe...)
+				buf[pos] = byte(c)
+				want := safeprefixGeneric(buf)
+				if got := safeprefix(buf); got != want {
+					t.Fatalf("safeprefix(%q) = %d, want %d", buf, got, want)
+				}
+			}
+		}
+	}
+}
+
+func TestQuotedAdversarial(t *testing.T) {
+	fill := strings.Repeat("abcdefgh", 12)
+	for _, sp := range escapeSpecial {
+		// place the special sequence at every position
+		// relative to the 32-byte blocks, including
+		// straddling a block boundary
+		for pos := 0; pos <= len(fill); pos++ {
+			testQuoted(t, []byte(fill[:pos]+sp+fill[pos:]))
+		}
+		testQuoted(t, []byte(strings.Repeat(sp, 70)))
+	}
+	// all control characters at once
+	var ctl []byte
+	for c := 0; c < 0x20; c++ {
+		ctl = append(ctl, byte(c))
+	}
+	testQuoted(t, ctl)
+	testQuoted(t, append([]byte(fill), ctl...))
+
+	rng := rand.New(rand.NewSource(0))
+	for i := 0; i < 1000; i++ {
+		var buf []byte
+		for len(buf) < 200 {
+			if rng.Intn(4) == 0 {
+				buf = append(buf, escapeSpecial[rng.Intn(len(escapeSpecial))]...)
+			} else {
+				buf = append(buf, fill[:rng.Intn(len(fill))]...)
+			}
+		}
+		testQuoted(t, buf)
+	}
+}
+
+func BenchmarkQuoted(b *testing.B) {
+	for _, in := range []struct {
+		name string
+		str  string
+	}{
+		{"ascii", strings.Repeat("the quick brown fox jumps over the lazy dog ", 100)},
+		{"escaped", strings.Repeat("line one\n\"quoted\"\tline two\\n", 100)},
+		{"utf8", strings.Repeat("zażółć gęślą jaźń ", 100)},
+	} {
+		buf := []byte(in.str)
+		b.Run(in.name, func(b *testing.B) {
+			var s scratch
+			b.SetBytes(int64(len(buf)))
+			for i := 0; i < b.N; i++ {
+				s.quoted(buf)
+			}
+		})
+	}
+}
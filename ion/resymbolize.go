@@ -15,10 +15,14 @@
 package ion
 
 // take a buffer (must be valid, sorted symbols, etc.)
-// and resymbolize it starting with the empty symbol table,
-// and set st to the new (hopefully smaller) symbol table
-func resymbolize(dst *Buffer, rng *Ranges, st *Symtab, buf []byte) {
+// and resymbolize it starting with a symbol table containing
+// just the symbols in seed, and set st to the new
+// (hopefully smaller) symbol table
+func resymbolize(dst *Buffer, rng *Ranges, st *Symtab, seed []string, buf []byte) {
 	var newst Symtab
+	for i := range seed {
+		newst.Intern(seed[i])
+	}
 	rs := resymbolizer{
 		srctab: st,
 		dsttab: &newst,
@@ -262,8 +262,14 @@ func (s *Symtab) Merge(o *Symtab) (modified bool, ok bool) {
 	}
 
 	// copy new symbols
+	if n1 < n2 && s.toindex == nil {
+		s.init()
+	}
 	for i := n1; i < n2; i++ {
-		s.append(o.interned[i])
+		str := o.interned[i]
+		s.toindex[str] = len(s.interned) + len(systemsyms)
+		s.append(str)
+		s.memsize += len(str)
 	}
 
 	return (n1 < n2), true
@@ -278,6 +278,15 @@ func TestMergeSymtabs(t *testing.T) {
 				t.Logf("got %s", existing)
 				t.Error("wrong merged symtab")
 			}
+			for j, str := range testcases[i].merged {
+				if !ok {
+					break
+				}
+				sym, found := existing.Symbolize(str)
+				if !found || int(sym) != j+len(systemsyms) {
+					t.Errorf("Symbolize(%q) = %d, %v after merge", str, sym, found)
+				}
+			}
 		})
 	}
 }
@@ -710,7 +710,7 @@ func ReadTime(msg []byte) (date.Time, []byte, error) {
 }
 
 // ReadAnnotation reads an annotation
-// and returns the associated label,
+// and returns the first associated label,
 // the contents of the annotation, and
 // the remaining bytes in buf (in that order).
 func ReadAnnotation(buf []byte) (Symbol, []byte, []byte, error) {
@@ -721,28 +721,15 @@ func ReadAnnotation(buf []byte) (Symbol, []byte, []byte, error) {
 	if size <= 0 || size > len(buf) {
 		return 0, nil, nil, fmt.Errorf("ion.ReadAnnotation: size %d > %d", size, len(buf))
 	}
-	var labels, first uint
-	var ok bool
-	contents, rest := Contents(buf)
-	labels, contents, ok = readuv(contents)
-	if !ok {
-		return 0, nil, rest, fmt.Errorf("ion.ReadAnnotation: could not read #labels")
-	}
-	if labels == 0 {
-		return 0, nil, rest, fmt.Errorf("ion.ReadAnnotation: 0 labels disallowed")
+	labels, contents, rest, err := annotationLabels(buf)
+	if err != nil {
+		return 0, nil, rest, fmt.Errorf("ion.ReadAnnotation: %w", err)
 	}
-	first, contents, ok = readuv(contents)
+	// strip other labels
+	first, _, ok := readuv(labels)
 	if !ok {
 		return 0, nil, rest, fmt.Errorf("ion.ReadAnnotation: could not read 1st label")
 	}
-	labels--
-	for labels > 0 {
-		// strip other labels
-		_, contents, ok = readuv(contents)
-		if !ok {
-			return 0, nil, rest, fmt.Errorf("ion.ReadAnnotation: could not read auxilliary labels")
-		}
-	}
 	return Symbol(first), contents, rest, nil
 }
 
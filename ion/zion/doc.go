@@ -23,6 +23,8 @@
 // and then writes field contents into these buckets
 // and compresses each bucket separately, along with
 // a "shape" bitstream that is also compressed.
+// (Buckets that do not compress well may instead be
+// stored uncompressed; see Encoder.Codec.)
 // Decoder.Decode decompresses the shape from the
 // input bitstream and then uses a user-provided
 // field selection to determine which buckets need
@@ -45,6 +45,21 @@ type Encoder struct {
 	enc  shapeEncoder
 	buck [zll.NumBuckets]bucket
 	seed uint32
+
+	// Codec determines how buckets are compressed.
+	// The zero value, zll.CodecZstd, compresses every
+	// bucket with zstd. zll.CodecAuto stores buckets
+	// that zstd does not make smaller (for example,
+	// buckets that mostly hold compressed blobs)
+	// uncompressed, so each bucket in a frame may use
+	// a different codec. (The shape is always
+	// compressed with zstd.)
+	Codec zll.Codec
+	// Stats, if non-nil, accumulates compression
+	// statistics for each bucket and each
+	// top-level field on each call to Encode.
+	Stats  *Stats
+	fields fieldSizes
 }
 
 // SetSymbols sets the current state of the
@@ -57,6 +72,7 @@ func (e *Encoder) SetSymbols(st *ion.Symtab) {
 
 // Reset resets the Encoder's internal
 // symbol table and its seed.
+// Reset does not modify e.Codec or e.Stats.
 func (e *Encoder) Reset() {
 	e.st.Reset()
 	e.sym2bucket = e.sym2bucket[:0]
@@ -80,6 +96,7 @@ func (e *Encoder) Encode(src, dst []byte) ([]byte, error) {
 		e.buck[i].mem = e.buck[i].mem[:0]
 		e.buck[i].base = 0
 	}
+	e.fields.reset()
 	isBVM := ion.IsBVM(src)
 	var body []byte
 	var err error
@@ -119,15 +136,30 @@ func (e *Encoder) Encode(src, dst []byte) ([]byte, error) {
 	// the one that produces the most even distribution
 	// of compressed bucket sizes?
 	dst = zll.AppendMagic(dst, e.seed)
+	off := len(dst)
 	dst, err = zll.Compress(e.shape, dst)
 	if err != nil {
 		return nil, err
 	}
+	if e.Stats != nil {
+		e.Stats.Shape.add(len(e.shape), len(dst)-off, zll.CodecZstd)
+	}
+	var size, compressed [zll.NumBuckets]int
 	for i := 0; i < zll.NumBuckets; i++ {
-		dst, err = zll.Compress(e.buck[i].mem, dst)
+		var codec zll.Codec
+		off = len(dst)
+		dst, codec, err = zll.CompressWith(e.Codec, e.buck[i].mem, dst)
 		if err != nil {
 			return nil, err
 		}
+		if e.Stats != nil {
+			size[i] = len(e.buck[i].mem)
+			compressed[i] = len(dst) - off
+			e.Stats.Buckets[i].add(size[i], compressed[i], codec)
+		}
+	}
+	if e.Stats != nil {
+		e.flushStats(&size, &compressed)
 	}
 	return dst, nil
 }
@@ -213,6 +245,9 @@ func (e *Encoder) encodeFlat(sym ion.Symbol, fieldval []byte) {
 	b := e.sym2bucket[sym]
 	e.enc.emit(b)
 	e.buck[b].append(fieldval)
+	if e.Stats != nil {
+		e.fields.add(sym, len(fieldval))
+	}
 }
 
 func (e *Encoder) encodeField(mem []byte) ([]byte, error) {
@@ -22,6 +22,7 @@ import (
 
 	"github.com/SnellerInc/sneller/expr"
 	"github.com/SnellerInc/sneller/ion"
+	"github.com/SnellerInc/sneller/ion/zion/zll"
 	"github.com/SnellerInc/sneller/jsonrl"
 	"github.com/SnellerInc/sneller/vm"
 
@@ -200,3 +201,72 @@ func testProjectEquivalent(t *testing.T, fields []string, src *os.File) {
 		t.Fatal(err)
 	}
 }
+
+type transcodeTester struct {
+	t      *testing.T
+	fields []string
+	enc    Encoder
+	tc     Transcoder
+	dec    Decoder // decodes the original blocks
+	dec2   Decoder // decodes the transcoded blocks
+}
+
+func (p *transcodeTester) Write(block []byte) (int, error) {
+	compressed, err := p.enc.Encode(block, nil)
+	if err != nil {
+		p.t.Fatal(err)
+	}
+	want, err := p.dec.Decode(compressed, nil)
+	if err != nil {
+		p.t.Fatal(err)
+	}
+	decomps := p.tc.buckets.Decomps
+	transcoded, err := p.tc.Transcode(compressed, nil)
+	if err != nil {
+		p.t.Fatal(err)
+	}
+	if n := p.tc.buckets.Decomps - decomps; n > len(p.fields) {
+		p.t.Errorf("decompressed %d buckets for %d fields", n, len(p.fields))
+	}
+	if len(transcoded) > len(compressed) {
+		p.t.Errorf("transcoded block is %d bytes; original is %d bytes", len(transcoded), len(compressed))
+	}
+	got, err := p.dec2.Decode(transcoded, nil)
+	if err != nil {
+		p.t.Fatal(err)
+	}
+	if !bytes.Equal(toNDJSON(p.t, got), toNDJSON(p.t, want)) {
+		p.t.Fatal("transcoded output not equivalent to projected output")
+	}
+	return len(block), nil
+}
+
+func TestTranscode(t *testing.T) {
+	f, err := os.Open("../../testdata/cloudtrail.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	for _, fields := range [][]string{
+		{"eventTime"},
+		{"eventTime", "userIdentity", "readOnly"},
+		{"missing"},
+	} {
+		f.Seek(0, 0)
+		tt := &transcodeTester{
+			t:      t,
+			fields: fields,
+		}
+		tt.dec.SetComponents(fields)
+		tt.tc.SetComponents(fields)
+		tt.tc.Codec = zll.CodecAuto
+		cn := ion.Chunker{
+			W:     tt,
+			Align: 128 * 1024,
+		}
+		err := jsonrl.Convert(f, &cn, nil, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
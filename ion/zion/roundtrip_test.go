@@ -18,6 +18,7 @@ import (
 	"bytes"
 	"fmt"
 	"io"
+	"math/rand"
 	"os"
 	"path/filepath"
 	"strings"
@@ -385,6 +386,85 @@ func TestRoundtrip(t *testing.T) {
 	}
 }
 
+func TestEmptyStructs(t *testing.T) {
+	tw := testOut(t)
+	cn := ion.Chunker{
+		W:     tw,
+		Align: 128 * 1024,
+	}
+	for i := 0; i < 20000; i++ {
+		ion.NewStruct(nil, nil).Encode(&cn.Buffer, &cn.Symbols)
+		if err := cn.Commit(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := cn.Flush(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCodecStats(t *testing.T) {
+	const rows = 2000
+	encode := func(t *testing.T, codec zll.Codec) *Stats {
+		tw := &testWriter{t: t}
+		tw.enc.Codec = codec
+		tw.enc.Stats = &Stats{}
+		cn := ion.Chunker{
+			W:     tw,
+			Align: 32 * 1024,
+		}
+		src := rand.New(rand.NewSource(0))
+		payload := make([]byte, 64)
+		for i := 0; i < rows; i++ {
+			src.Read(payload)
+			ion.NewStruct(nil, []ion.Field{
+				{Label: "name", Datum: ion.String(fmt.Sprintf("host-%d", i%4))},
+				{Label: "payload", Datum: ion.Blob(payload)},
+			}).Encode(&cn.Buffer, &cn.Symbols)
+			if err := cn.Commit(); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := cn.Flush(); err != nil {
+			t.Fatal(err)
+		}
+		return tw.enc.Stats
+	}
+	zstd := encode(t, zll.CodecZstd)
+	auto := encode(t, zll.CodecAuto)
+	stored := int64(0)
+	for i := range auto.Buckets {
+		if zstd.Buckets[i].Stored != 0 {
+			t.Errorf("bucket %d: %d frames stored with CodecZstd", i, zstd.Buckets[i].Stored)
+		}
+		if auto.Buckets[i].Size != zstd.Buckets[i].Size {
+			t.Errorf("bucket %d: size %d != %d", i, auto.Buckets[i].Size, zstd.Buckets[i].Size)
+		}
+		stored += auto.Buckets[i].Stored
+	}
+	if stored == 0 {
+		t.Error("no frames stored uncompressed with CodecAuto")
+	}
+	if auto.Compressed() > zstd.Compressed() {
+		t.Errorf("CodecAuto output (%d bytes) larger than CodecZstd output (%d bytes)", auto.Compressed(), zstd.Compressed())
+	}
+	top := auto.Largest(-1)
+	if len(top) != 2 || top[0].Name != "payload" || top[1].Name != "name" {
+		t.Fatalf("unexpected fields %+v", top)
+	}
+	for i := range top {
+		if top[i].Values != rows {
+			t.Errorf("field %s: %d values", top[i].Name, top[i].Values)
+		}
+	}
+	if top[0].Compressed < top[0].Size {
+		t.Errorf("payload compressed to %d of %d bytes", top[0].Compressed, top[0].Size)
+	}
+	if top[1].Compressed >= top[1].Size/2 {
+		t.Errorf("name compressed to %d of %d bytes", top[1].Compressed, top[1].Size)
+	}
+}
+
 func trimnop(buf []byte) []byte {
 	off := 0
 	for off < len(buf) {
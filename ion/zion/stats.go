@@ -0,0 +1,169 @@
+// Copyright (C) 2022 Sneller, Inc.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package zion
+
+import (
+	"sort"
+
+	"github.com/SnellerInc/sneller/ion"
+	"github.com/SnellerInc/sneller/ion/zion/zll"
+)
+
+// BucketStats are the compression
+// statistics for one bucket.
+type BucketStats struct {
+	// Frames is the number of frames
+	// written for the bucket.
+	Frames int64
+	// Stored is the number of frames that
+	// were stored uncompressed.
+	Stored int64
+	// Size is the total size of the
+	// bucket contents before compression.
+	Size int64
+	// Compressed is the total size of
+	// the compressed frames, including
+	// the frame headers.
+	Compressed int64
+}
+
+func (b *BucketStats) add(size, compressed int, codec zll.Codec) {
+	b.Frames++
+	if codec == zll.CodecRaw {
+		b.Stored++
+	}
+	b.Size += int64(size)
+	b.Compressed += int64(compressed)
+}
+
+// FieldStats are the compression statistics
+// for one top-level structure field.
+type FieldStats struct {
+	// Name is the name of the field.
+	Name string
+	// Values is the number of values of the field.
+	Values int64
+	// Size is the total encoded size of
+	// the field labels and values.
+	Size int64
+	// Compressed is the estimated compressed size
+	// of the field, which is the field's share of
+	// the compressed size of the bucket it was
+	// stored in, in proportion to Size.
+	Compressed int64
+}
+
+// Stats are the statistics accumulated by an
+// Encoder across calls to Encoder.Encode.
+// See Encoder.Stats.
+type Stats struct {
+	// Shape is the statistics for the shape frames.
+	Shape BucketStats
+	// Buckets is the statistics for each bucket.
+	Buckets [zll.NumBuckets]BucketStats
+	// Fields is the statistics for each
+	// top-level field, indexed by name.
+	Fields map[string]*FieldStats
+}
+
+// Reset resets s to its zero state.
+func (s *Stats) Reset() {
+	*s = Stats{}
+}
+
+// Compressed returns the total compressed
+// size of all the frames in s.
+func (s *Stats) Compressed() int64 {
+	n := s.Shape.Compressed
+	for i := range s.Buckets {
+		n += s.Buckets[i].Compressed
+	}
+	return n
+}
+
+// Largest returns up to n fields with the
+// largest estimated compressed size, from
+// largest to smallest. If n is negative,
+// Largest returns all of the fields.
+func (s *Stats) Largest(n int) []FieldStats {
+	out := make([]FieldStats, 0, len(s.Fields))
+	for _, f := range s.Fields {
+		out = append(out, *f)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Compressed != out[j].Compressed {
+			return out[i].Compressed > out[j].Compressed
+		}
+		return out[i].Name < out[j].Name
+	})
+	if n >= 0 && n < len(out) {
+		out = out[:n]
+	}
+	return out
+}
+
+// fieldSizes accumulates the size of
+// each field (by symbol) within one frame
+type fieldSizes struct {
+	size   []int
+	values []int
+	used   []ion.Symbol
+}
+
+func (f *fieldSizes) reset() {
+	for _, sym := range f.used {
+		f.size[sym] = 0
+		f.values[sym] = 0
+	}
+	f.used = f.used[:0]
+}
+
+func (f *fieldSizes) add(sym ion.Symbol, size int) {
+	for int(sym) >= len(f.size) {
+		f.size = append(f.size, 0)
+		f.values = append(f.values, 0)
+	}
+	if f.values[sym] == 0 {
+		f.used = append(f.used, sym)
+	}
+	f.size[sym] += size
+	f.values[sym]++
+}
+
+// flushStats records the field sizes for the current
+// frame in e.Stats, given the uncompressed and compressed
+// size of each bucket
+func (e *Encoder) flushStats(size, compressed *[zll.NumBuckets]int) {
+	s := e.Stats
+	if s.Fields == nil {
+		s.Fields = make(map[string]*FieldStats)
+	}
+	for _, sym := range e.fields.used {
+		name := e.st.Get(sym)
+		f := s.Fields[name]
+		if f == nil {
+			f = &FieldStats{Name: name}
+			s.Fields[name] = f
+		}
+		n := e.fields.size[sym]
+		f.Values += int64(e.fields.values[sym])
+		f.Size += int64(n)
+		b := e.sym2bucket[sym]
+		if size[b] > 0 {
+			f.Compressed += int64(n) * int64(compressed[b]) / int64(size[b])
+		}
+	}
+	e.fields.reset()
+}
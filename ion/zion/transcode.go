@@ -0,0 +1,191 @@
+// Copyright (C) 2022 Sneller, Inc.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package zion
+
+import (
+	"fmt"
+
+	"github.com/SnellerInc/sneller/ion"
+	"github.com/SnellerInc/sneller/ion/zion/zll"
+)
+
+// Transcoder re-encodes blocks produced by
+// Encoder.Encode into blocks that contain only
+// a subset of the top-level structure fields.
+//
+// Transcode only decompresses the buckets that
+// hold the selected fields; the buckets that do
+// not hold any selected fields are dropped without
+// being decompressed. The output blocks use the same
+// symbol tables and seeds as the input blocks, so
+// they can be decoded with a Decoder just like the
+// input blocks.
+//
+// Like Decoder, Transcoder is stateful: blocks must
+// be presented to Transcode in the order in which
+// they were encoded, and the output blocks must be
+// decoded in the same order.
+type Transcoder struct {
+	// Codec determines how the rewritten
+	// buckets are compressed. See Encoder.Codec.
+	Codec zll.Codec
+
+	shape   zll.Shape
+	buckets zll.Buckets
+	st      symtab
+
+	enc     shapeEncoder
+	buck    [zll.NumBuckets][]byte
+	base    [zll.NumBuckets]int
+	dropped [zll.NumBuckets]bool
+}
+
+// SetComponents sets the top-level fields
+// that are preserved by Transcode.
+// SetComponents must be called before the
+// first call to Transcode.
+func (t *Transcoder) SetComponents(x []string) {
+	t.st.components = make([]component, len(x))
+	for i := range t.st.components {
+		t.st.components[i].name = x[i]
+		t.st.components[i].symbol = ^ion.Symbol(0)
+	}
+	t.st.reset()
+}
+
+// Reset resets the internal symbol
+// table of the Transcoder. The selected
+// fields and t.Codec are not modified.
+func (t *Transcoder) Reset() {
+	t.st.reset()
+}
+
+// Transcode re-encodes the block in src so that
+// it only contains the fields selected with
+// SetComponents and appends the new block to dst.
+// Every structure in src is preserved (even if
+// none of its fields are selected), so the output
+// contains the same number of structures as src.
+func (t *Transcoder) Transcode(src, dst []byte) ([]byte, error) {
+	t.shape.Symtab = &t.st
+	body, err := t.shape.Decode(src)
+	if err != nil {
+		return nil, err
+	}
+	t.buckets.Reset(&t.shape, body)
+	t.buckets.SkipPadding = true
+	err = t.buckets.SelectSymbols(t.st.selected)
+	if err != nil {
+		return nil, err
+	}
+	for i := range t.buck {
+		t.buck[i] = t.buck[i][:0]
+		t.base[i] = 0
+		t.dropped[i] = false
+	}
+	prefix := t.shape.Bits[:t.shape.Start]
+	t.enc.output = append(t.enc.output[:0], prefix...)
+	err = t.walk(t.shape.Bits[t.shape.Start:])
+	if err != nil {
+		return nil, err
+	}
+
+	dst = zll.AppendMagic(dst, t.shape.Seed)
+	dst, err = zll.Compress(t.enc.output, dst)
+	if err != nil {
+		return nil, err
+	}
+	for i := 0; i < zll.NumBuckets; i++ {
+		size, err := zll.FrameSize(body)
+		if err != nil {
+			return nil, err
+		}
+		if t.buckets.Pos[i] >= 0 && !t.dropped[i] {
+			// every field in the bucket was kept,
+			// so the original frame can be copied as-is
+			dst = append(dst, body[:size]...)
+		} else {
+			dst, _, err = zll.CompressWith(t.Codec, t.buck[i], dst)
+			if err != nil {
+				return nil, err
+			}
+		}
+		body = body[size:]
+	}
+	return dst, nil
+}
+
+// walk re-encodes the shape bitstream while
+// copying the selected fields into t.buck
+func (t *Transcoder) walk(shape []byte) error {
+	instruct := false
+	size := 0
+	for len(shape) > 0 {
+		fc := shape[0] & 0x1f
+		if fc > 16 {
+			return fmt.Errorf("zion.Transcoder.walk: fc = %x", fc)
+		}
+		skip := int((fc + 3) / 2)
+		if len(shape) < skip {
+			return fmt.Errorf("zion.Transcoder.walk: skip %d > len(shape)=%d", skip, len(shape))
+		}
+		if !instruct {
+			t.enc.start(0)
+			instruct = true
+			size = 0
+		}
+		nibbles := load64(shape[1:])
+		shape = shape[skip:]
+		for i := 0; i < int(fc); i++ {
+			b := nibbles & 0xf
+			nibbles >>= 4
+			if t.buckets.Pos[b] < 0 {
+				// bucket not decompressed, so
+				// the field is not selected
+				continue
+			}
+			buf := t.buckets.Decompressed[int(t.buckets.Pos[b])+t.base[b]:]
+			if len(buf) == 0 {
+				return fmt.Errorf("zion.Transcoder.walk: unexpected bucket EOF")
+			}
+			sym, rest, err := ion.ReadLabel(buf)
+			if err != nil {
+				return fmt.Errorf("zion.Transcoder.walk: %w (%d bytes remaining)", err, len(buf))
+			}
+			fieldsize := ion.SizeOf(rest)
+			if fieldsize <= 0 || fieldsize > len(rest) {
+				return fmt.Errorf("zion.Transcoder.walk: SizeOf=%d", fieldsize)
+			}
+			fieldsize += len(buf) - len(rest)
+			t.base[b] += fieldsize
+			if !t.buckets.Selected(sym) {
+				t.dropped[b] = true
+				continue
+			}
+			t.buck[b] = append(t.buck[b], buf[:fieldsize]...)
+			t.enc.emit(byte(b))
+			size += fieldsize
+		}
+		if fc < 16 {
+			t.enc.class = class(size)
+			t.enc.finish()
+			instruct = false
+		}
+	}
+	if instruct {
+		return fmt.Errorf("zion.Transcoder.walk: missing terminal 0x10 fc marker")
+	}
+	return nil
+}
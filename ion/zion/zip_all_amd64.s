@@ -142,6 +142,15 @@ skip_bucket:
     CMPQ    R10, $16          // loop again if shape[0] == 16
     JEQ     top
 done:
+    // it's possible that we haven't actually
+    // done a bounds-check yet if we are outputting
+    // exactly zero fields; in that case we still
+    // need to check that the descriptor can fit!
+    MOVQ      dst_len+32(FP), R8
+    ADDQ      dst_base+24(FP), R8
+    CMPQ      DI, R8
+    JA        ret_toolarge
+
     // structure is complete; commit updates
     MOVQ      8(SP), R10       // get a copy of the original dst ptr
     MOVQ      DI, 8(SP)        // we are about to clobber DI
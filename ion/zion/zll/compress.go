@@ -70,18 +70,80 @@ func put24(i int, dst []byte) {
 	dst[2] = byte(i >> 16)
 }
 
+// Codec selects how a frame is compressed.
+type Codec uint8
+
+const (
+	// CodecZstd compresses frames with zstd.
+	CodecZstd Codec = iota
+	// CodecRaw stores frames uncompressed.
+	CodecRaw
+	// CodecAuto compresses frames with zstd
+	// unless doing so does not make the frame
+	// smaller, in which case the frame is
+	// stored uncompressed.
+	CodecAuto
+)
+
+func (c Codec) String() string {
+	switch c {
+	case CodecZstd:
+		return "zstd"
+	case CodecRaw:
+		return "raw"
+	case CodecAuto:
+		return "auto"
+	default:
+		return fmt.Sprintf("Codec(%d)", uint8(c))
+	}
+}
+
+// rawFrame is set in the 24-bit frame header
+// of frames that are stored uncompressed;
+// since frames are always smaller than MaxBucketSize,
+// the high bits of the header are otherwise unused
+const (
+	rawFrame  = 1 << 23
+	frameMask = MaxBucketSize - 1
+)
+
 // Compress compresses data from src and appends it to dst,
 // returning the new dst slice or an error.
+// Compress is equivalent to CompressWith(CodecZstd, src, dst).
 func Compress(src, dst []byte) ([]byte, error) {
+	dst, _, err := CompressWith(CodecZstd, src, dst)
+	return dst, err
+}
+
+// CompressWith compresses data from src using codec
+// and appends it to dst, returning the new dst slice,
+// the codec that was actually used (either CodecZstd
+// or CodecRaw), and the first error encountered, if any.
+func CompressWith(codec Codec, src, dst []byte) ([]byte, Codec, error) {
 	off := len(dst)
-	dst = append(dst, 0, 0, 0)
-	dst = enc.EncodeAll(src, dst)
-	size := len(dst) - off - 3
-	if size >= MaxBucketSize {
-		return nil, fmt.Errorf("compressed segment length %d exceeds max size %d", size, MaxBucketSize)
+	switch codec {
+	case CodecZstd, CodecAuto:
+		dst = append(dst, 0, 0, 0)
+		dst = enc.EncodeAll(src, dst)
+		size := len(dst) - off - 3
+		if codec == CodecZstd || size < len(src) {
+			if size >= MaxBucketSize {
+				return nil, codec, fmt.Errorf("compressed segment length %d exceeds max size %d", size, MaxBucketSize)
+			}
+			put24(size, dst[off:])
+			return dst, CodecZstd, nil
+		}
+		dst = dst[:off]
+	case CodecRaw:
+	default:
+		return nil, codec, fmt.Errorf("zll.CompressWith: unknown codec %s", codec)
+	}
+	if len(src) >= MaxBucketSize {
+		return nil, CodecRaw, fmt.Errorf("uncompressed segment length %d exceeds max size %d", len(src), MaxBucketSize)
 	}
-	put24(size, dst[off:])
-	return dst, nil
+	dst = append(dst, 0, 0, 0)
+	put24(len(src)|rawFrame, dst[off:])
+	return append(dst, src...), CodecRaw, nil
 }
 
 // FrameSize returns the number compressed bytes
@@ -92,13 +154,25 @@ func FrameSize(src []byte) (int, error) {
 	if len(src) < 3 {
 		return 0, fmt.Errorf("zion.frameSize: illegal frame size")
 	}
-	size := le24(src) + 3
+	size := (le24(src) & frameMask) + 3
 	if size > len(src) {
 		return 0, fmt.Errorf("zion.frameSize: size %d > len %d", size, len(src))
 	}
 	return size, nil
 }
 
+// FrameCodec returns the codec used to
+// compress the next frame in src.
+func FrameCodec(src []byte) (Codec, error) {
+	if len(src) < 3 {
+		return 0, fmt.Errorf("zion.frameCodec: illegal frame size")
+	}
+	if le24(src)&rawFrame != 0 {
+		return CodecRaw, nil
+	}
+	return CodecZstd, nil
+}
+
 // Decompress decompressed data from src, appending it to dst.
 // Decompress returns the new dst, the number of compressed bytes consumed,
 // and the first error encountered, if any.
@@ -106,10 +180,14 @@ func Decompress(src, dst []byte) ([]byte, int, error) {
 	if len(src) < 3 {
 		return nil, 0, fmt.Errorf("zion.decompress: illegal frame size")
 	}
-	size := le24(src) + 3
+	hdr := le24(src)
+	size := (hdr & frameMask) + 3
 	if size > len(src) {
 		return nil, 0, fmt.Errorf("zion.decompress: segment size %d exceeds slice len %d", size, len(src))
 	}
+	if hdr&rawFrame != 0 {
+		return append(dst, src[3:size]...), size, nil
+	}
 	out, err := dec.DecodeAll(src[3:size], dst)
 	if err != nil {
 		return nil, 0, err
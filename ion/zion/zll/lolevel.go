@@ -182,7 +182,7 @@ func (b *Buckets) setBit(sym ion.Symbol) {
 func (b *Buckets) Selected(sym ion.Symbol) bool {
 	v := uint(sym)
 	word := int(v >> 6)
-	if len(b.SymbolBits) < word {
+	if len(b.SymbolBits) <= word {
 		return false
 	}
 	return (b.SymbolBits[word] & (1 << (v & 63))) != 0
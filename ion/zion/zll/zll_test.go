@@ -15,9 +15,13 @@
 package zll
 
 import (
+	"bytes"
 	"io"
+	"math/rand"
 	"os"
 	"testing"
+
+	"github.com/SnellerInc/sneller/ion"
 )
 
 func TestShapecount(t *testing.T) {
@@ -83,3 +87,65 @@ func readfile(t testing.TB, path string) []byte {
 
 	return res
 }
+
+func TestSelected(t *testing.T) {
+	var shape Shape
+	var b Buckets
+	b.Reset(&shape, nil)
+	b.setBit(3)
+	for _, sym := range []ion.Symbol{64, 100, 1000} {
+		if b.Selected(sym) {
+			t.Errorf("symbol %d selected", sym)
+		}
+	}
+	if !b.Selected(3) {
+		t.Error("symbol 3 not selected")
+	}
+}
+
+func TestCompressWith(t *testing.T) {
+	random := make([]byte, 4096)
+	rand.New(rand.NewSource(0)).Read(random)
+	repeated := bytes.Repeat([]byte("abcdefgh"), 512)
+	testcases := []struct {
+		codec Codec
+		src   []byte
+		want  Codec
+	}{
+		{CodecZstd, repeated, CodecZstd},
+		{CodecZstd, random, CodecZstd},
+		{CodecRaw, repeated, CodecRaw},
+		{CodecRaw, nil, CodecRaw},
+		{CodecAuto, repeated, CodecZstd},
+		{CodecAuto, random, CodecRaw},
+		{CodecAuto, nil, CodecRaw},
+	}
+	for _, tc := range testcases {
+		prefix := []byte("prefix")
+		buf, used, err := CompressWith(tc.codec, tc.src, prefix)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if used != tc.want {
+			t.Errorf("%s: used codec %s, want %s", tc.codec, used, tc.want)
+		}
+		frame := buf[len(prefix):]
+		if c, _ := FrameCodec(frame); c != tc.want {
+			t.Errorf("%s: FrameCodec returned %s, want %s", tc.codec, c, tc.want)
+		}
+		size, err := FrameSize(frame)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if size != len(frame) {
+			t.Errorf("%s: FrameSize returned %d, want %d", tc.codec, size, len(frame))
+		}
+		out, n, err := Decompress(frame, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n != len(frame) || !bytes.Equal(out, tc.src) {
+			t.Errorf("%s: round-trip failed", tc.codec)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+// Copyright (C) 2022 Sneller, Inc.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package sneller
+
+import (
+	"fmt"
+	"net"
+	"sync"
+
+	"github.com/SnellerInc/sneller/expr/blob"
+	"github.com/dchest/siphash"
+)
+
+// Placement is a policy for assigning
+// blobs to the peers that scan them.
+//
+// Since a Splitter is serialized along
+// with the query, a Placement is identified
+// by its name and must be registered with
+// RegisterPlacement in every process that
+// may split a query.
+type Placement interface {
+	// Name returns the name under
+	// which the Placement is registered.
+	Name() string
+	// Place returns the index into peers
+	// of the peer that should scan the blob
+	// described by info. The list of peers
+	// is never empty.
+	Place(info *blob.Info, peers []*net.TCPAddr) int
+}
+
+// DefaultPlacement is the Placement used
+// by a Splitter with no Placement set.
+var DefaultPlacement Placement = RendezvousPlacement{}
+
+var (
+	placementLock sync.Mutex
+	placements    = map[string]Placement{
+		RendezvousPlacement{}.Name(): RendezvousPlacement{},
+	}
+)
+
+// RegisterPlacement makes p available
+// to LookupPlacement under p.Name().
+// It panics if a Placement has already
+// been registered under the same name.
+func RegisterPlacement(p Placement) {
+	placementLock.Lock()
+	defer placementLock.Unlock()
+	name := p.Name()
+	if _, ok := placements[name]; ok {
+		panic("sneller: duplicate placement " + name)
+	}
+	placements[name] = p
+}
+
+// LookupPlacement returns the
+// Placement registered under name.
+func LookupPlacement(name string) (Placement, error) {
+	placementLock.Lock()
+	defer placementLock.Unlock()
+	p, ok := placements[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown placement %q", name)
+	}
+	return p, nil
+}
+
+// RendezvousPlacement assigns blobs to peers
+// using rendezvous (highest random weight)
+// hashing of the blob ETag and peer address.
+//
+// Each blob is always assigned to the same peer
+// for a given set of peers, so repeated queries
+// hit the same peer's cache, and adding or removing
+// a peer only moves the blobs assigned to that peer.
+type RendezvousPlacement struct{}
+
+// Name implements Placement.Name.
+func (RendezvousPlacement) Name() string { return "rendezvous" }
+
+// Place implements Placement.Place.
+func (RendezvousPlacement) Place(info *blob.Info, peers []*net.TCPAddr) int {
+	// just two fixed random values
+	key0 := uint64(0x5d1ec810)
+	key1 := uint64(0xfebed702)
+
+	var buf []byte
+	best, bestw := 0, uint64(0)
+	for i := range peers {
+		buf = append(buf[:0], info.ETag...)
+		buf = append(buf, 0)
+		buf = append(buf, peers[i].String()...)
+		w := siphash.Hash(key0, key1, buf)
+		if i == 0 || w > bestw {
+			best, bestw = i, w
+		}
+	}
+	return best
+}
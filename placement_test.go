@@ -0,0 +1,109 @@
+// Copyright (C) 2022 Sneller, Inc.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package sneller
+
+import (
+	"fmt"
+	"net"
+	"testing"
+
+	"github.com/SnellerInc/sneller/expr/blob"
+	"github.com/SnellerInc/sneller/ion"
+)
+
+func testPeers(n int) []*net.TCPAddr {
+	peers := make([]*net.TCPAddr, n)
+	for i := range peers {
+		peers[i] = &net.TCPAddr{IP: net.IPv4(10, 0, 0, byte(i+1)), Port: 9000}
+	}
+	return peers
+}
+
+func TestRendezvousPlacement(t *testing.T) {
+	const blobs = 10000
+	peers := testPeers(8)
+	var p RendezvousPlacement
+	assign := make([]*net.TCPAddr, blobs)
+	count := make(map[int]int)
+	for i := range assign {
+		info := &blob.Info{ETag: fmt.Sprintf("etag-%d", i)}
+		n := p.Place(info, peers)
+		if n2 := p.Place(info, peers); n2 != n {
+			t.Fatalf("blob %d placed on %d and then %d", i, n, n2)
+		}
+		assign[i] = peers[n]
+		count[n]++
+	}
+	for i := range peers {
+		if c := count[i]; c < blobs/len(peers)/2 || c > 2*blobs/len(peers) {
+			t.Errorf("peer %d assigned %d of %d blobs", i, c, blobs)
+		}
+	}
+
+	// removing a peer only moves
+	// the blobs assigned to that peer;
+	// the order of peers is irrelevant
+	removed := peers[3]
+	rest := append([]*net.TCPAddr{}, peers[4:]...)
+	rest = append(rest, peers[:3]...)
+	for i := range assign {
+		info := &blob.Info{ETag: fmt.Sprintf("etag-%d", i)}
+		got := rest[p.Place(info, rest)]
+		if assign[i] != removed && got != assign[i] {
+			t.Fatalf("blob %d moved from %s to %s", i, assign[i], got)
+		}
+	}
+}
+
+// firstPlacement puts every blob on the first peer
+type firstPlacement struct{}
+
+func (firstPlacement) Name() string                         { return "test-first" }
+func (firstPlacement) Place(*blob.Info, []*net.TCPAddr) int { return 0 }
+
+func TestSplitterPlacement(t *testing.T) {
+	RegisterPlacement(firstPlacement{})
+	if _, err := LookupPlacement("no-such-placement"); err == nil {
+		t.Fatal("expected an error")
+	}
+
+	s := &Splitter{
+		Peers:     testPeers(3),
+		Placement: firstPlacement{},
+	}
+	var st ion.Symtab
+	var buf ion.Buffer
+	s.encode(&buf, &st)
+	d, _, err := ion.ReadDatum(&st, buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	s2 := new(Splitter)
+	if err := d.UnpackStruct(s2.setField); err != nil {
+		t.Fatal(err)
+	}
+	if s2.Placement != (firstPlacement{}) {
+		t.Fatalf("decoded placement %#v", s2.Placement)
+	}
+	for i := 0; i < 10; i++ {
+		n, err := s2.partition(&blob.URL{Info: blob.Info{ETag: fmt.Sprint(i)}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n != 0 {
+			t.Errorf("blob %d placed on peer %d", i, n)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+// Copyright (C) 2022 Sneller, Inc.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package plan
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+)
+
+// ScanLimitError is the error returned
+// when the execution of a Tree scans more
+// than Tree.MaxScan bytes.
+type ScanLimitError struct {
+	// Scanned is the number of bytes
+	// scanned when the limit was reached.
+	Scanned int64
+	// Max is the value of Tree.MaxScan.
+	Max int64
+}
+
+func (e *ScanLimitError) Error() string {
+	return fmt.Sprintf("scan limit exceeded (scanned %d > %d bytes)", e.Scanned, e.Max)
+}
+
+// scanBudget tracks the number of bytes
+// scanned during the execution of a Tree
+type scanBudget struct {
+	parent *scanBudget
+	used   int64 // updated atomically
+
+	// the following are only set
+	// for the root of a tree of budgets:
+	max    int64
+	cancel context.CancelFunc
+	once   sync.Once
+	err    error
+}
+
+// child returns a budget that charges
+// both itself and b
+func (b *scanBudget) child() *scanBudget {
+	if b == nil {
+		return nil
+	}
+	return &scanBudget{parent: b}
+}
+
+// charge records n scanned bytes and returns
+// a *ScanLimitError if the budget is exhausted,
+// in which case the execution of the rest of
+// the tree is canceled
+func (b *scanBudget) charge(n int64) error {
+	if b == nil || n == 0 {
+		return nil
+	}
+	used := atomic.AddInt64(&b.used, n)
+	if b.parent != nil {
+		return b.parent.charge(n)
+	}
+	if used <= b.max {
+		return nil
+	}
+	return b.trip(used)
+}
+
+func (b *scanBudget) trip(used int64) error {
+	b.once.Do(func() {
+		b.err = &ScanLimitError{Scanned: used, Max: b.max}
+		b.cancel()
+	})
+	return b.err
+}
+
+// exceeded returns the error produced
+// when the budget was exhausted, if any
+func (b *scanBudget) exceeded() error {
+	for b != nil && b.parent != nil {
+		b = b.parent
+	}
+	if b == nil {
+		return nil
+	}
+	used := atomic.LoadInt64(&b.used)
+	if used <= b.max {
+		return nil
+	}
+	return b.trip(used)
+}
@@ -0,0 +1,86 @@
+// Copyright (C) 2022 Sneller, Inc.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package plan
+
+import (
+	"strings"
+
+	"github.com/SnellerInc/sneller/vm"
+)
+
+// crashlog writes the diagnostics for a
+// query that panicked to vm.Errorf, which
+// is the process-wide diagnostic log.
+// The plan is redacted so that no literal
+// values from the query text end up in the log.
+func crashlog(t *Tree, pe *vm.PanicError) {
+	if vm.Errorf == nil {
+		return
+	}
+	vm.Errorf("query panicked: %v\n%s", pe.Value, pe.Stack)
+	vm.Errorf("query plan (redacted):\n%s", redact(t.String()))
+}
+
+// watchdoglog writes the (redacted) plan
+// of a query that was aborted by the vm watchdog;
+// the operator diagnostics are logged by the vm
+func watchdoglog(t *Tree, we *vm.WatchdogError) {
+	if vm.Errorf == nil {
+		return
+	}
+	vm.Errorf("%s", we)
+	vm.Errorf("query plan (redacted):\n%s", redact(t.String()))
+}
+
+func isident(c byte) bool {
+	return c == '_' || c == '$' || c == '.' ||
+		(c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
+
+// redact replaces the literals in a plan
+// description (quoted strings, timestamps,
+// and numbers) with placeholders.
+func redact(text string) string {
+	var out strings.Builder
+	out.Grow(len(text))
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case c == '\'' || c == '`':
+			// skip to the matching (unescaped) quote
+			j := i + 1
+			for j < len(text) && text[j] != c {
+				if text[j] == '\\' {
+					j++
+				}
+				j++
+			}
+			out.WriteByte(c)
+			out.WriteByte('?')
+			out.WriteByte(c)
+			i = j
+		case c >= '0' && c <= '9' && (i == 0 || !isident(text[i-1])):
+			j := i + 1
+			for j < len(text) && isident(text[j]) {
+				j++
+			}
+			out.WriteByte('?')
+			i = j - 1
+		default:
+			out.WriteByte(c)
+		}
+	}
+	return out.String()
+}
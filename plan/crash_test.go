@@ -0,0 +1,108 @@
+// Copyright (C) 2022 Sneller, Inc.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package plan
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/SnellerInc/sneller/expr"
+	"github.com/SnellerInc/sneller/expr/partiql"
+	"github.com/SnellerInc/sneller/ion"
+	"github.com/SnellerInc/sneller/vm"
+)
+
+type panicenv struct {
+	*testenv
+}
+
+func (p *panicenv) Stat(tbl expr.Node, h *Hints) (TableHandle, error) {
+	return panicHandle{}, nil
+}
+
+type panicHandle struct{}
+
+func (panicHandle) Size() int64 { return 1 }
+
+func (panicHandle) Open(_ context.Context) (vm.Table, error) {
+	return panicTable{}, nil
+}
+
+func (panicHandle) Encode(dst *ion.Buffer, st *ion.Symtab) error {
+	dst.WriteNull()
+	return nil
+}
+
+type panicTable struct{}
+
+func (panicTable) WriteChunks(dst vm.QuerySink, parallel int) error {
+	panic("panicTable.WriteChunks")
+}
+
+func TestLocalPanic(t *testing.T) {
+	env := &panicenv{&testenv{t: t}}
+	s, err := partiql.Parse([]byte(`select * from 'secret.json' where x = 'hunter2' limit 1`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tree, err := New(s, env)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var logged strings.Builder
+	saved := vm.Errorf
+	vm.Errorf = func(f string, args ...any) {
+		logged.WriteString(strings.TrimSpace(fmt.Sprintf(f, args...)) + "\n")
+	}
+	defer func() { vm.Errorf = saved }()
+
+	var lt LocalTransport
+	err = lt.Exec(tree, &ExecParams{Output: io.Discard, Context: context.Background()})
+	var pe *vm.PanicError
+	if !errors.As(err, &pe) {
+		t.Fatalf("got error %v; expected a *vm.PanicError", err)
+	}
+	if pe.Value != "panicTable.WriteChunks" {
+		t.Errorf("unexpected panic value %v", pe.Value)
+	}
+	if !strings.Contains(logged.String(), "query plan (redacted)") {
+		t.Errorf("plan not logged:\n%s", logged.String())
+	}
+	if strings.Contains(logged.String(), "hunter2") {
+		t.Errorf("literal not redacted:\n%s", logged.String())
+	}
+}
+
+func TestRedact(t *testing.T) {
+	run := []struct {
+		in, out string
+	}{
+		{`WHERE x = 'foo'`, `WHERE x = '?'`},
+		{`WHERE x = 'it\'s' AND y = 3`, `WHERE x = '?' AND y = ?`},
+		{`LIMIT 100`, `LIMIT ?`},
+		{`col2 < 1.5e3`, `col2 < ?`},
+		{"ts > `2022-01-01T00:00:00Z`", "ts > `?`"},
+	}
+	for i := range run {
+		if got := redact(run[i].in); got != run[i].out {
+			t.Errorf("redact(%q) = %q, want %q", run[i].in, got, run[i].out)
+		}
+	}
+}
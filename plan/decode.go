@@ -45,11 +45,19 @@ type UploaderDecoder interface {
 }
 
 // decodeHandle calls d.DecodeHandle with special
-// handling for lists.
+// handling for lists and constant tables.
 func decodeHandle(d Decoder, v ion.Datum) (TableHandle, error) {
 	if v.IsList() {
 		return decodeHandles(d, v)
 	}
+	if isValuesHandle(v) {
+		vh := &ValuesHandle{}
+		return vh, vh.decode(v)
+	}
+	if isSeriesHandle(v) {
+		sh := &SeriesHandle{}
+		return sh, sh.decode(v)
+	}
 	return d.DecodeHandle(v)
 }
 
@@ -58,7 +66,7 @@ func (i *Input) decode(d Decoder, v ion.Datum) error {
 	err := v.UnpackStruct(func(f ion.Field) error {
 		switch f.Label {
 		case "table":
-			e, err := expr.Decode(f.Datum)
+			e, err := decodeExpr(d, f.Datum)
 			if err != nil {
 				return err
 			}
@@ -68,7 +76,11 @@ func (i *Input) decode(d Decoder, v ion.Datum) error {
 			}
 			i.Table = t
 		case "handle":
-			th, err := decodeHandle(d, f.Datum)
+			v, err := resolve(d, f.Datum)
+			if err != nil {
+				return err
+			}
+			th, err := decodeHandle(d, v)
 			if err != nil {
 				return err
 			}
@@ -98,8 +110,20 @@ func Decode(d Decoder, st *ion.Symtab, buf []byte) (*Tree, error) {
 }
 
 func DecodeDatum(d Decoder, v ion.Datum) (*Tree, error) {
+	// the shared values are needed
+	// before anything else can be decoded
+	s, err := v.Struct()
+	if err != nil {
+		return nil, err
+	}
+	if f, ok := s.FieldByName("shared"); ok {
+		d, err = withShared(d, f.Datum)
+		if err != nil {
+			return nil, err
+		}
+	}
 	t := &Tree{}
-	err := v.UnpackStruct(func(f ion.Field) error {
+	err = v.UnpackStruct(func(f ion.Field) error {
 		switch f.Label {
 		case "inputs":
 			return f.UnpackList(func(v ion.Datum) error {
@@ -108,6 +132,10 @@ func DecodeDatum(d Decoder, v ion.Datum) (*Tree, error) {
 			})
 		case "root":
 			return t.Root.decode(d, f.Datum)
+		case "max_scan":
+			var err error
+			t.MaxScan, err = f.Int()
+			return err
 		default:
 			return nil
 		}
@@ -205,6 +233,8 @@ func empty(name string) Op {
 		return &Limit{}
 	case "count(*)":
 		return &CountStar{}
+	case "indexcount":
+		return &IndexCount{}
 	case "hashagg":
 		return &HashAggregate{}
 	case "order":
@@ -139,3 +139,108 @@ func (b *benchenv) Stat(_ expr.Node, _ *Hints) (TableHandle, error) {
 	}
 	return &blobHandle{&blob.List{Contents: lst}}, nil
 }
+
+func TestSharedValues(t *testing.T) {
+	be := benchenv{blocks: 100}
+	lst := make([]blob.Interface, be.blocks)
+	for i := range lst {
+		lst[i] = &blob.URL{
+			Value: fmt.Sprintf("https://s3.amazonaws.com/a-very-long/path-to-the-object/%d.ion.zst", i),
+			Info:  blob.Info{ETag: "\"abc123\"", Size: 1234567},
+		}
+	}
+	h := &structHandle{blobHandle{&blob.List{Contents: lst}}}
+	filter := expr.And(
+		expr.Compare(expr.Greater, expr.Ident("x"), expr.Integer(1000)),
+		expr.Compare(expr.Equals, expr.Ident("a_long_field_name"), expr.String("a long string constant")),
+	)
+	table := &expr.Table{Binding: expr.Bind(expr.Ident("a_table"), "")}
+	tree := &Tree{
+		Inputs: []Input{{Table: table, Handle: h}, {Table: table, Handle: h}},
+		Root: Node{
+			Input: 0,
+			Op: &Substitute{
+				Nonterminal: Nonterminal{From: &Leaf{Orig: table, Filter: filter}},
+				Inner:       []*Node{{Input: 1, Op: &Leaf{Orig: table, Filter: filter}}},
+			},
+		},
+	}
+	var buf, hbuf ion.Buffer
+	var st ion.Symtab
+	err := tree.Encode(&buf, &st)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.Encode(&hbuf, &st)
+	// the handle should only be encoded once
+	if buf.Size() >= 2*hbuf.Size() {
+		t.Errorf("encoded tree is %d bytes; one handle is %d bytes", buf.Size(), hbuf.Size())
+	}
+	env := structenv{&be}
+	tree2, err := Decode(env, &st, buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tree.String() != tree2.String() {
+		t.Errorf("input : %s", tree.String())
+		t.Errorf("output: %s", tree2.String())
+	}
+	for i := range tree2.Inputs {
+		bh, ok := tree2.Inputs[i].Handle.(*structHandle)
+		if !ok {
+			t.Fatalf("input %d: handle is %T", i, tree2.Inputs[i].Handle)
+		}
+		if n := len(bh.Contents); n != be.blocks {
+			t.Errorf("input %d: %d blobs", i, n)
+		}
+	}
+	inner := tree2.Root.Op.(*Substitute).Inner[0].Op.(*Leaf)
+	if !expr.Equivalent(inner.Filter, filter) {
+		t.Errorf("inner filter %s", expr.ToString(inner.Filter))
+	}
+
+	// a reference without a shared value is an error
+	buf.Reset()
+	buf.BeginStruct(-1)
+	buf.BeginField(st.Intern("inputs"))
+	buf.BeginList(-1)
+	buf.BeginStruct(-1)
+	buf.BeginField(st.Intern("handle"))
+	buf.BeginStruct(-1)
+	buf.BeginField(st.Intern("$ref"))
+	buf.WriteInt(0)
+	buf.EndStruct()
+	buf.EndStruct()
+	buf.EndList()
+	buf.EndStruct()
+	_, err = Decode(env, &st, buf.Bytes())
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+}
+
+// structHandle is a blobHandle that
+// is encoded as a structure
+type structHandle struct {
+	blobHandle
+}
+
+func (s *structHandle) Encode(dst *ion.Buffer, st *ion.Symtab) error {
+	dst.BeginStruct(-1)
+	dst.BeginField(st.Intern("blobs"))
+	s.List.Encode(dst, st)
+	dst.EndStruct()
+	return nil
+}
+
+type structenv struct {
+	*benchenv
+}
+
+func (s structenv) DecodeHandle(v ion.Datum) (TableHandle, error) {
+	l, err := blob.DecodeList(v.Field("blobs"))
+	if err != nil {
+		return nil, err
+	}
+	return &structHandle{blobHandle{l}}, nil
+}
@@ -15,6 +15,8 @@
 package plan
 
 import (
+	"context"
+
 	"github.com/SnellerInc/sneller/vm"
 )
 
@@ -22,7 +24,31 @@ func (t *Tree) exec(dst vm.QuerySink, ep *ExecParams) error {
 	ep.get = func(i int) TableHandle {
 		return t.Inputs[i].Handle
 	}
-	return t.Root.exec(dst, ep)
+	if t.MaxScan <= 0 || ep.budget != nil {
+		return t.Root.exec(dst, ep)
+	}
+	// enforce t.MaxScan for the duration of
+	// this execution; once the budget is exhausted,
+	// the rest of the query is canceled
+	parent := ep.Context
+	if parent == nil {
+		parent = context.Background()
+	}
+	ctx, cancel := context.WithCancel(parent)
+	defer cancel()
+	ep.Context = ctx
+	ep.budget = &scanBudget{max: t.MaxScan, cancel: cancel}
+	defer func() {
+		ep.Context = parent
+		ep.budget = nil
+	}()
+	err := t.Root.exec(dst, ep)
+	// prefer the scan limit error to
+	// any cancellation errors it produced
+	if err2 := ep.budget.exceeded(); err2 != nil {
+		err = err2
+	}
+	return err
 }
 
 func (n *Node) exec(dst vm.QuerySink, ep *ExecParams) error {
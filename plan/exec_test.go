@@ -29,7 +29,9 @@ import (
 	"regexp"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/SnellerInc/sneller/date"
 	"github.com/SnellerInc/sneller/expr"
@@ -734,6 +736,17 @@ where out.Make = 'CHRY' and entry.BodyStyle = 'PA'
 			rows:     12,
 			firstrow: `{"make": "CHRY", "ticket": 1106506435, "color": "GO"}`,
 		},
+		{
+			// zipping a list with itself pairs
+			// each element with itself
+			query: `
+select out.Make as make, a.Color as color
+from 'parking3.ion' as out, unnest(out.Entries, out.Entries) as (a, b)
+where a.Color = 'BK' and a.Ticket = b.Ticket
+`,
+			rows:     221,
+			firstrow: `{"make": "ACUR", "color": "BK"}`,
+		},
 		{
 			query:    `select min(passenger_count), sum(fare_amount) as sum from 'nyc-taxi.block'`,
 			rows:     1,
@@ -1639,6 +1652,41 @@ where Make in (
 				`{"Make": "VOLV", "Color": "SL", "count": 3, "row_number": 1}`,
 			},
 		},
+		{
+			// constant table
+			query: `select t.x + 1 as y from (values (1, 'a'), (2, 'b')) as t(x, s) where t.s = 'b'`,
+			expectedRows: []string{
+				`{"y": 3}`,
+			},
+		},
+		{
+			// join against a constant table
+			query: `select d.name, count(*) as n
+from 'parking.10n' p join (values ('Toyota', 'TOYT'), ('Volkswagen', 'VOLK')) as d(name, make)
+on p.Make = d.make
+group by d.name order by d.name`,
+			expectedRows: []string{
+				`{"name": "Toyota", "n": 83}`,
+				`{"name": "Volkswagen", "n": 36}`,
+			},
+		},
+		{
+			// generated table
+			query: `select count(*) as n, sum(range) as s from range(0, 100000, 7) where range % 2 = 0`,
+			expectedRows: []string{
+				`{"n": 7143, "s": 357107142}`,
+			},
+		},
+		{
+			// generated timestamps; the bounds
+			// are simplified to constants
+			query: "select s.series as hour from series(`2023-01-01T00:00:00Z`, date_add(hour, 2, `2023-01-01T00:00:00Z`), 60*60) s order by hour limit 5",
+			expectedRows: []string{
+				`{"hour": "2023-01-01T00:00:00Z"}`,
+				`{"hour": "2023-01-01T01:00:00Z"}`,
+				`{"hour": "2023-01-01T02:00:00Z"}`,
+			},
+		},
 	}
 
 	for i := range tcs {
@@ -2069,6 +2117,35 @@ func TestServerError(t *testing.T) {
 	}
 }
 
+// test that Reject produces a client error
+func TestReject(t *testing.T) {
+	remote, local := net.Pipe()
+	defer local.Close()
+	env := &testenv{t: t}
+
+	// (net.Pipe is unbuffered, so the
+	// query sent by the client must be
+	// consumed concurrently)
+	go io.Copy(io.Discard, remote)
+	go Reject(remote, fmt.Errorf("go away"))
+
+	s, err := partiql.Parse([]byte(`select * from 'parking.10n' limit 1`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tree, err := New(s, env)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cl := Client{Pipe: local}
+	var out bytes.Buffer
+	err = cl.Exec(tree, &ExecParams{Output: &out, Context: context.Background()})
+	if err == nil || !strings.HasSuffix(err.Error(), "go away") {
+		t.Fatalf("unexpected error %v", err)
+	}
+	remote.Close()
+}
+
 type hangenv struct {
 	*testenv
 }
@@ -2195,3 +2272,164 @@ func dates(min, max string) [2]date.Time {
 	}
 	return [2]date.Time{dmin, dmax}
 }
+
+// blockingOp is a terminal Op that records
+// the number of concurrent calls to exec
+type blockingOp struct {
+	NoOutput
+	running, peak *int32
+	fail          bool
+}
+
+func (b *blockingOp) exec(dst vm.QuerySink, src TableHandle, ep *ExecParams) error {
+	n := atomic.AddInt32(b.running, 1)
+	defer atomic.AddInt32(b.running, -1)
+	for {
+		p := atomic.LoadInt32(b.peak)
+		if n <= p || atomic.CompareAndSwapInt32(b.peak, p, n) {
+			break
+		}
+	}
+	if b.fail {
+		return fmt.Errorf("replacement failed")
+	}
+	select {
+	case <-time.After(20 * time.Millisecond):
+	case <-ep.Context.Done():
+		return ep.Context.Err()
+	}
+	return b.NoOutput.exec(dst, src, ep)
+}
+
+func TestSubstituteParallel(t *testing.T) {
+	run := func(inner, limit int, fail bool) (int32, error) {
+		var running, peak int32
+		s := &Substitute{Nonterminal: Nonterminal{From: NoOutput{}}}
+		for i := 0; i < inner; i++ {
+			s.Inner = append(s.Inner, &Node{
+				Input: -1,
+				Op:    &blockingOp{running: &running, peak: &peak, fail: fail && i == 0},
+			})
+		}
+		ep := &ExecParams{
+			Parallel:      8,
+			Substitutions: limit,
+			Context:       context.Background(),
+		}
+		err := s.exec(&replacement{}, nil, ep)
+		return peak, err
+	}
+	for _, limit := range []int{1, 2, 5} {
+		peak, err := run(10, limit, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if peak > int32(limit) {
+			t.Errorf("limit %d: %d replacements executed concurrently", limit, peak)
+		}
+	}
+	// Parallel is the default limit
+	peak, err := run(16, 0, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if peak > 8 {
+		t.Errorf("%d replacements executed concurrently", peak)
+	}
+	// the first error is returned
+	start := time.Now()
+	_, err = run(100, 1, true)
+	if err == nil || !strings.Contains(err.Error(), "replacement failed") {
+		t.Fatalf("unexpected error %v", err)
+	}
+	// ... and the remaining replacements are not executed
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("replacements took %s after an error", elapsed)
+	}
+}
+
+// scanTransport is a Transport that
+// reports scanning n bytes remotely
+type scanTransport struct {
+	n int64
+}
+
+func (s *scanTransport) Exec(t *Tree, ep *ExecParams) error {
+	ep.Stats.BytesScanned += s.n
+	return nil
+}
+
+func TestMaxScan(t *testing.T) {
+	env := &testenv{t: t}
+	s, err := partiql.Parse([]byte(`select COUNT(*) from 'parking.10n'`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	size := env.stat("../testdata/parking.10n")
+	for _, max := range []int64{size - 1, size, 0} {
+		tree, err := New(s, env)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tree.MaxScan = max
+		// MaxScan must survive serialization
+		var buf ion.Buffer
+		var st ion.Symtab
+		if err := tree.Encode(&buf, &st); err != nil {
+			t.Fatal(err)
+		}
+		tree, err = Decode(env, &st, buf.Bytes())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tree.MaxScan != max {
+			t.Fatalf("decoded MaxScan %d; want %d", tree.MaxScan, max)
+		}
+		var out bytes.Buffer
+		var stats ExecStats
+		err = Exec(tree, &out, &stats)
+		var limit *ScanLimitError
+		if max > 0 && max < size {
+			if !errors.As(err, &limit) {
+				t.Fatalf("MaxScan %d: got error %v", max, err)
+			}
+			if limit.Scanned != size || limit.Max != max {
+				t.Errorf("unexpected error %+v", limit)
+			}
+		} else if err != nil {
+			t.Fatalf("MaxScan %d: %s", max, err)
+		}
+	}
+
+	// bytes scanned by remote sub-queries
+	// are charged once they complete, and
+	// the query is canceled once the
+	// budget is exhausted
+	var calls int32
+	lst := SubtableList{
+		{Transport: &scanTransport{n: 100}, Handle: emptyenv{}},
+		{Transport: &scanTransport{n: 100}, Handle: emptyenv{}},
+		{Transport: &markTransport{mark: "x", delay: -1, calls: &calls}, Handle: emptyenv{}},
+	}
+	tree := &Tree{
+		Inputs: []Input{{Handle: &retryHandle{tbls: lst}}},
+		Root: Node{
+			Op:    &UnionMap{Nonterminal: Nonterminal{From: NoOutput{}}},
+			Input: 0,
+		},
+		MaxScan: 150,
+	}
+	var out bytes.Buffer
+	var stats ExecStats
+	err = Exec(tree, &out, &stats)
+	var limit *ScanLimitError
+	if !errors.As(err, &limit) {
+		t.Fatalf("got error %v", err)
+	}
+	if limit.Max != 150 || limit.Scanned != 200 {
+		t.Errorf("unexpected error %+v", limit)
+	}
+	if calls != 1 {
+		t.Errorf("got %d calls", calls)
+	}
+}
@@ -55,7 +55,7 @@ func (f *Filter) encode(dst *ion.Buffer, st *ion.Symtab, rw expr.Rewriter) error
 	dst.BeginStruct(-1)
 	settype("filter", dst, st)
 	dst.BeginField(st.Intern("expr"))
-	expr.Rewrite(rw, f.Expr).Encode(dst, st)
+	encodeExpr(f.Expr, dst, st, rw)
 	dst.EndStruct()
 	return nil
 }
@@ -63,7 +63,7 @@ func (f *Filter) encode(dst *ion.Buffer, st *ion.Symtab, rw expr.Rewriter) error
 func (f *Filter) setfield(d Decoder, sf ion.Field) error {
 	switch sf.Label {
 	case "expr":
-		e, err := expr.Decode(sf.Datum)
+		e, err := decodeExpr(d, sf.Datum)
 		if err != nil {
 			return err
 		}
@@ -0,0 +1,126 @@
+// Copyright (C) 2022 Sneller, Inc.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package plan
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/SnellerInc/sneller/ion/blockfmt"
+)
+
+// KeyProvider resolves the data keys of encrypted
+// tables at execution time. A TableHandle that
+// carries wrapped data keys rather than the data
+// keys themselves (see db.Blobs) can be opened
+// by an environment that has a KeyProvider,
+// which makes it possible for the data keys to be
+// wrapped by a customer-managed key (e.g. in a KMS)
+// that is never made available to the query planner.
+type KeyProvider interface {
+	// DataKey returns the data key corresponding
+	// to the wrapped data key stored in a table index
+	// (see blockfmt.Index.DataKey).
+	DataKey(ctx context.Context, wrapped []byte) (*blockfmt.DataKey, error)
+}
+
+// KeyUse describes one request
+// for a data key made through a KeyCache.
+type KeyUse struct {
+	// Wrapped is the wrapped data key.
+	Wrapped []byte
+	// Cached is set if the data key was
+	// produced from the cache rather than
+	// by the underlying KeyProvider.
+	Cached bool
+	// Err is the error returned by the
+	// underlying KeyProvider, if any.
+	Err error
+}
+
+// KeyCache is a KeyProvider that caches
+// the data keys produced by another KeyProvider.
+type KeyCache struct {
+	// Provider is the KeyProvider used
+	// to resolve keys that are not cached.
+	Provider KeyProvider
+	// TTL, if non-zero, is the amount of time
+	// for which a data key is cached.
+	TTL time.Duration
+	// Audit, if non-nil, is called
+	// every time a data key is requested.
+	Audit func(*KeyUse)
+
+	lock    sync.Mutex
+	entries map[string]keyEntry
+}
+
+type keyEntry struct {
+	key     *blockfmt.DataKey
+	expires time.Time
+}
+
+// DataKey implements KeyProvider.DataKey
+func (k *KeyCache) DataKey(ctx context.Context, wrapped []byte) (*blockfmt.DataKey, error) {
+	now := time.Now()
+	k.lock.Lock()
+	ent, ok := k.entries[string(wrapped)]
+	if ok && k.TTL > 0 && now.After(ent.expires) {
+		delete(k.entries, string(wrapped))
+		ok = false
+	}
+	k.lock.Unlock()
+	if ok {
+		k.audit(&KeyUse{Wrapped: wrapped, Cached: true})
+		return ent.key, nil
+	}
+	key, err := k.Provider.DataKey(ctx, wrapped)
+	k.audit(&KeyUse{Wrapped: wrapped, Err: err})
+	if err != nil {
+		return nil, err
+	}
+	k.lock.Lock()
+	defer k.lock.Unlock()
+	if k.entries == nil {
+		k.entries = make(map[string]keyEntry)
+	}
+	k.entries[string(wrapped)] = keyEntry{key: key, expires: now.Add(k.TTL)}
+	return key, nil
+}
+
+func (k *KeyCache) audit(use *KeyUse) {
+	if k.Audit != nil {
+		k.Audit(use)
+	}
+}
+
+// Flush evicts every data key from the cache.
+func (k *KeyCache) Flush() {
+	k.lock.Lock()
+	defer k.lock.Unlock()
+	k.entries = nil
+}
+
+// MasterKeyProvider is a KeyProvider that unwraps
+// data keys using a master key that is available locally.
+type MasterKeyProvider struct {
+	Key *blockfmt.Key
+}
+
+// DataKey implements KeyProvider.DataKey
+func (m *MasterKeyProvider) DataKey(_ context.Context, wrapped []byte) (*blockfmt.DataKey, error) {
+	return blockfmt.UnwrapDataKey(m.Key, wrapped)
+}
@@ -0,0 +1,124 @@
+// Copyright (C) 2022 Sneller, Inc.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package plan
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/SnellerInc/sneller/ion/blockfmt"
+)
+
+type countingProvider struct {
+	calls int
+	err   error
+}
+
+func (c *countingProvider) DataKey(_ context.Context, wrapped []byte) (*blockfmt.DataKey, error) {
+	c.calls++
+	if c.err != nil {
+		return nil, c.err
+	}
+	k := new(blockfmt.DataKey)
+	copy(k[:], wrapped)
+	return k, nil
+}
+
+func TestKeyCache(t *testing.T) {
+	ctx := context.Background()
+	p := &countingProvider{}
+	var uses []KeyUse
+	kc := &KeyCache{
+		Provider: p,
+		Audit:    func(u *KeyUse) { uses = append(uses, *u) },
+	}
+	for i := 0; i < 3; i++ {
+		k, err := kc.DataKey(ctx, []byte("foo"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(k[:3]) != "foo" {
+			t.Fatalf("unexpected key %x", k[:])
+		}
+	}
+	if p.calls != 1 {
+		t.Fatalf("provider called %d times", p.calls)
+	}
+	if _, err := kc.DataKey(ctx, []byte("bar")); err != nil {
+		t.Fatal(err)
+	}
+	if p.calls != 2 {
+		t.Fatalf("provider called %d times", p.calls)
+	}
+	if len(uses) != 4 {
+		t.Fatalf("got %d uses audited", len(uses))
+	}
+	for i, cached := range []bool{false, true, true, false} {
+		if uses[i].Cached != cached {
+			t.Errorf("use %d: cached = %v", i, uses[i].Cached)
+		}
+	}
+
+	// expired entries are fetched again
+	kc.TTL = time.Nanosecond
+	kc.Flush()
+	kc.DataKey(ctx, []byte("foo"))
+	time.Sleep(time.Millisecond)
+	kc.DataKey(ctx, []byte("foo"))
+	if p.calls != 4 {
+		t.Fatalf("provider called %d times", p.calls)
+	}
+
+	// errors are audited but not cached
+	kc.TTL = 0
+	p.err = errors.New("access denied")
+	uses = uses[:0]
+	for i := 0; i < 2; i++ {
+		_, err := kc.DataKey(ctx, []byte("baz"))
+		if !errors.Is(err, p.err) {
+			t.Fatalf("unexpected error %v", err)
+		}
+	}
+	if p.calls != 6 || len(uses) != 2 || uses[1].Err == nil {
+		t.Fatalf("calls=%d uses=%v", p.calls, uses)
+	}
+}
+
+func TestMasterKeyProvider(t *testing.T) {
+	var master blockfmt.Key
+	master[0] = 1
+	dk, err := blockfmt.NewDataKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	wrapped, err := dk.Wrap(&master)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mk := &MasterKeyProvider{Key: &master}
+	got, err := mk.DataKey(context.Background(), wrapped)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *got != *dk {
+		t.Fatal("unwrapped key doesn't match")
+	}
+	master[0] = 2
+	if _, err := mk.DataKey(context.Background(), wrapped); !errors.Is(err, blockfmt.ErrBadDataKey) {
+		t.Fatalf("unexpected error %v", err)
+	}
+}
@@ -20,6 +20,7 @@ import (
 
 	"golang.org/x/exp/slices"
 
+	"github.com/SnellerInc/sneller/db"
 	"github.com/SnellerInc/sneller/expr"
 	"github.com/SnellerInc/sneller/ion"
 	"github.com/SnellerInc/sneller/ion/blockfmt"
@@ -43,6 +44,7 @@ func lowerIterValue(in *pir.IterValue, from Op) (Op, error) {
 		},
 		Expr:   in.Value,
 		Result: in.Result,
+		Zip:    in.Zip,
 	}, nil
 }
 
@@ -114,7 +116,7 @@ func iscountstar(a vm.Aggregation) bool {
 func splitWindows(lst vm.Aggregation) (agg vm.Aggregation, window vm.Aggregation) {
 	agg = lst[:0]
 	for i := range lst {
-		if lst[i].Expr.Op.WindowOnly() {
+		if lst[i].Expr.Windowed() {
 			window = append(window, lst[i])
 		} else {
 			agg = append(agg, lst[i])
@@ -229,12 +231,16 @@ slowpath:
 	}
 
 	// find possible duplicates
+	seen := make(map[uint64][]expr.Node, len(columns))
 	for i := range columns {
-		for j := i + 1; j < len(columns); j++ {
-			if expr.Equivalent(columns[i].Node, columns[j].Node) {
-				return nil, fmt.Errorf("duplicate order by expression %q", expr.ToString(columns[j].Node))
+		e := columns[i].Node
+		h := e.Hash()
+		for _, prev := range seen[h] {
+			if expr.Equivalent(prev, e) {
+				return nil, fmt.Errorf("duplicate order by expression %q", expr.ToString(e))
 			}
 		}
+		seen[h] = append(seen[h], e)
 	}
 
 	return &OrderBy{
@@ -303,12 +309,33 @@ type UploadEnv interface {
 	Key() *blockfmt.Key
 }
 
+// CompactEnv can be implemented by an UploadEnv
+// to request that tables targeted by INSERT INTO
+// are compacted after new data is appended.
+type CompactEnv interface {
+	// CompactAfterAppend returns whether or
+	// not to compact a table after appending to it.
+	CompactAfterAppend() bool
+}
+
+// TxnEnv can be implemented by an UploadEnv
+// to stage the output of INSERT INTO statements
+// in a transaction instead of appending it to
+// the target table immediately.
+type TxnEnv interface {
+	// Txn returns the transaction in which
+	// to stage appended data, or nil if
+	// the data should be appended immediately.
+	Txn() *db.Txn
+}
+
 func lowerOutputPart(n *pir.OutputPart, env Env, input Op) (Op, error) {
 	if e, ok := env.(UploadEnv); ok {
 		if up := e.Uploader(); up != nil {
 			op := &OutputPart{
-				Basename: n.Basename,
-				Store:    up,
+				Basename:   n.Basename,
+				Partitions: n.Partitions,
+				Store:      up,
 			}
 			op.From = input
 			return op, nil
@@ -325,11 +352,19 @@ func lowerOutputIndex(n *pir.OutputIndex, env Env, input Op) (Op, error) {
 				return nil, fmt.Errorf("invalid table expression %s", expr.ToString(n.Table))
 			}
 			op := &OutputIndex{
-				DB:       parts[0],
-				Table:    parts[1],
-				Basename: n.Basename,
-				Store:    up,
-				Key:      e.Key(),
+				DB:         parts[0],
+				Table:      parts[1],
+				Basename:   n.Basename,
+				Partitions: n.Partitions,
+				Append:     n.Append,
+				Store:      up,
+				Key:        e.Key(),
+			}
+			if c, ok := env.(CompactEnv); ok && n.Append {
+				op.Compact = c.CompactAfterAppend()
+			}
+			if t, ok := env.(TxnEnv); ok && n.Append {
+				op.Txn = t.Txn()
 			}
 			op.From = input
 			return op, nil
@@ -426,20 +461,32 @@ func mergeFilterHint(x, y *input) bool {
 	if y.hints.Filter != nil {
 		yconj = conjunctions(y.hints.Filter, nil)
 	}
-	var overlap []expr.Node
-	i := 0
-outer:
-	for ; i < len(xconj) && len(yconj) > 0; i++ {
-		v := xconj[i]
-		for j := range yconj {
-			if expr.Equivalent(yconj[j], v) {
-				yconj[j], yconj = yconj[len(yconj)-1], yconj[:len(yconj)-1]
-				xconj[i], xconj = xconj[len(xconj)-1], xconj[:len(xconj)-1]
-				overlap = append(overlap, v)
-				i--
-				continue outer
+	// bucket the rhs conjunctions by hash
+	// so that generated queries with many
+	// terms don't require a quadratic number
+	// of deep comparisons
+	var hc expr.HashCache
+	byhash := make(map[uint64][]int, len(yconj))
+	for j := range yconj {
+		h := hc.Hash(yconj[j])
+		byhash[h] = append(byhash[h], j)
+	}
+	used := make([]bool, len(yconj))
+	match := func(v expr.Node) bool {
+		for _, j := range byhash[hc.Hash(v)] {
+			if !used[j] && expr.Equivalent(yconj[j], v) {
+				used[j] = true
+				return true
 			}
 		}
+		return false
+	}
+	var overlap []expr.Node
+	for _, v := range xconj {
+		if match(v) {
+			overlap = append(overlap, v)
+			continue
+		}
 		// not part of an overlap, so
 		// make sure we are allowed to
 		// eliminate this hint
@@ -447,15 +494,10 @@ outer:
 			return false
 		}
 	}
-	for _, v := range xconj[i:] {
-		if !canRemoveHint(v) {
-			return false
-		}
-	}
 	// make sure any remaining rhs values
 	// can be safely eliminated as well
-	for _, v := range yconj {
-		if !canRemoveHint(v) {
+	for j, v := range yconj {
+		if !used[j] && !canRemoveHint(v) {
 			return false
 		}
 	}
@@ -637,9 +679,73 @@ func toTree(in *pir.Trace, env Env) (*Tree, error) {
 	if err != nil {
 		return nil, err
 	}
+	countFromIndex(t, &t.Root)
 	return t, nil
 }
 
+// countFromIndex replaces COUNT(*) over a table
+// (with an optional filter) in n and its
+// sub-queries with an IndexCount when the table
+// can count the matching rows from its metadata
+// alone (see CountHandle)
+func countFromIndex(t *Tree, n *Node) {
+	for op := n.Op; op != nil; op = op.input() {
+		if s, ok := op.(*Substitute); ok {
+			for i := range s.Inner {
+				countFromIndex(t, s.Inner[i])
+			}
+		}
+	}
+	if n.Input < 0 || n.Input >= len(t.Inputs) {
+		return
+	}
+	ch, ok := t.Inputs[n.Input].Handle.(CountHandle)
+	if !ok {
+		return
+	}
+	var parent Op
+	for op := n.Op; op != nil; parent, op = op, op.input() {
+		if _, ok := op.(*UnionPartition); ok {
+			// the ops below are executed once
+			// per partition rather than once
+			// for the whole table
+			return
+		}
+		// in a split query, the whole UNION MAP
+		// of partial counts is replaced
+		cs, ok := op.(*CountStar)
+		if u, isunion := op.(*UnionMap); isunion {
+			cs, ok = u.From.(*CountStar)
+		}
+		if !ok {
+			continue
+		}
+		var filter expr.Node
+		from := cs.From
+		if f, ok := from.(*Filter); ok {
+			filter = f.Expr
+			from = f.From
+		}
+		leaf, ok := from.(*Leaf)
+		if !ok || leaf.Filter != nil || len(leaf.OnEqual) > 0 {
+			return
+		}
+		count, ok := ch.Count(filter)
+		if !ok {
+			return
+		}
+		ic := &IndexCount{As: cs.name(), Count: count}
+		if parent == nil {
+			n.Op = ic
+		} else {
+			parent.setinput(ic)
+		}
+		// the table is no longer scanned
+		n.Input = -1
+		return
+	}
+}
+
 func (w *walker) addReplace(op Op, in *pir.Trace, env Env) (Op, error) {
 	if len(in.Replacements) == 0 {
 		return op, nil
@@ -29,6 +29,7 @@ import (
 	"github.com/SnellerInc/sneller/expr"
 	"github.com/SnellerInc/sneller/fsutil"
 	"github.com/SnellerInc/sneller/ion"
+	"github.com/SnellerInc/sneller/ion/blockfmt"
 	"github.com/SnellerInc/sneller/vm"
 )
 
@@ -36,6 +37,21 @@ type tableHandles []TableHandle
 
 var _ PartitionHandle = tableHandles(nil)
 
+// Stats implements StatsHandle.Stats
+// if all of the handles have stats
+func (h tableHandles) Stats() (TableStats, bool) {
+	var ret TableStats
+	for i := range h {
+		st, ok := Stats(h[i])
+		if !ok {
+			return TableStats{}, false
+		}
+		ret.Rows += st.Rows
+		ret.Bytes += st.Bytes
+	}
+	return ret, true
+}
+
 func (h tableHandles) Size() int64 {
 	n := int64(0)
 	for i := range h {
@@ -123,6 +139,49 @@ func (m multiIndex) TimeRange(p []string) (min, max date.Time, ok bool) {
 	return min, max, len(m) > 0
 }
 
+// KeyRange implements KeyRanger.KeyRange by
+// computing the union of the key ranges for p
+// in all the contained indexes.
+func (m multiIndex) KeyRange(p []string) (min, max ion.Datum, ok bool) {
+	for i := range m {
+		kr, ok := m[i].(KeyRanger)
+		if !ok {
+			return ion.Empty, ion.Empty, false
+		}
+		min0, max0, ok := kr.KeyRange(p)
+		if !ok {
+			return ion.Empty, ion.Empty, false
+		}
+		if i == 0 {
+			min, max = min0, max0
+			continue
+		}
+		min, max, ok = blockfmt.UnionKeyRange(min, max, min0, max0)
+		if !ok {
+			return ion.Empty, ion.Empty, false
+		}
+	}
+	return min, max, len(m) > 0
+}
+
+// Blocks implements BlockCounter.Blocks by
+// summing the block counts of the contained indexes.
+func (m multiIndex) Blocks(filter expr.Node) (matching, total int, err error) {
+	for i := range m {
+		bc, ok := m[i].(BlockCounter)
+		if !ok {
+			return 0, 0, fmt.Errorf("cannot count blocks in index %T", m[i])
+		}
+		n, t, err := bc.Blocks(filter)
+		if err != nil {
+			return 0, 0, err
+		}
+		matching += n
+		total += t
+	}
+	return matching, total, nil
+}
+
 func (m multiIndex) HasPartition(x string) bool {
 	for i := range m {
 		if !m[i].HasPartition(x) {
@@ -17,12 +17,16 @@ package plan
 import (
 	"crypto/rand"
 	"encoding/base32"
+	"errors"
 	"fmt"
 	"io"
 	"io/fs"
+	"net/url"
 	"path"
+	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/SnellerInc/sneller/date"
 	"github.com/SnellerInc/sneller/db"
@@ -30,6 +34,9 @@ import (
 	"github.com/SnellerInc/sneller/ion"
 	"github.com/SnellerInc/sneller/ion/blockfmt"
 	"github.com/SnellerInc/sneller/vm"
+
+	"golang.org/x/exp/maps"
+	"golang.org/x/exp/slices"
 )
 
 // uploadSink is a vm.QuerySink that uploads
@@ -92,6 +99,17 @@ func statdesc(ofs blockfmt.UploadFS, path string, up blockfmt.Uploader, into *bl
 }
 
 func (up *uploadSink) Close() error {
+	err := up.finish()
+	if err != nil {
+		return err
+	}
+	return up.dst.Close()
+}
+
+// finish closes the upload and writes
+// the descriptor of the uploaded packfile
+// to up.dst without closing up.dst
+func (up *uploadSink) finish() error {
 	err := up.mw.Close()
 	if err != nil {
 		return err
@@ -107,7 +125,7 @@ func (up *uploadSink) Close() error {
 	// if we don't need to
 	if is, ok := up.dst.(*indexSink); ok {
 		is.rawAppend(&desc)
-		return up.dst.Close()
+		return nil
 	}
 
 	// write the descriptor
@@ -127,11 +145,7 @@ func (up *uploadSink) Close() error {
 		w.Close()
 		return err
 	}
-	err = w.Close()
-	if err != nil {
-		return err
-	}
-	return up.dst.Close()
+	return w.Close()
 }
 
 // OutputPart is a nonterminal plan node
@@ -139,10 +153,20 @@ func (up *uploadSink) Close() error {
 // for each thread of execution that points
 // to an uploaded file containing all the data
 // written into this node.
+//
+// If Partitions is non-empty, rows are split by
+// the values of the Partitions columns and each
+// partition is written to its own file in a
+// subdirectory of Basename named after the
+// partition values (like "dt=2023-01-01"), and
+// the partition values are recorded as constants
+// in the descriptor of each file. Producing more
+// than maxOutputPartitions partitions is an error.
 type OutputPart struct {
 	Nonterminal
-	Basename string
-	Store    UploadFS
+	Basename   string
+	Partitions []string
+	Store      UploadFS
 }
 
 func uuid() string {
@@ -162,11 +186,29 @@ func (o *OutputPart) exec(dst vm.QuerySink, src TableHandle, ep *ExecParams) err
 	} else if o.Store == nil {
 		return fmt.Errorf("OutputPart: store not set")
 	}
-	name := path.Join(o.Basename, "packed-"+uuid())
-	up, err := o.Store.Create(name)
+	if len(o.Partitions) > 0 {
+		ps := &partitionSink{
+			parent: o,
+			dst:    dst,
+			sinks:  make(map[string]*uploadSink),
+		}
+		return o.From.exec(ps, src, ep)
+	}
+	us, err := o.sink(o.Basename, dst)
 	if err != nil {
 		return err
 	}
+	return o.From.exec(us, src, ep)
+}
+
+// sink creates an uploadSink that writes
+// a new packfile in dir
+func (o *OutputPart) sink(dir string, dst vm.QuerySink) (*uploadSink, error) {
+	name := path.Join(dir, "packed-"+uuid())
+	up, err := o.Store.Create(name)
+	if err != nil {
+		return nil, err
+	}
 	us := &uploadSink{
 		store: o.Store,
 		name:  name,
@@ -175,7 +217,245 @@ func (o *OutputPart) exec(dst vm.QuerySink, src TableHandle, ep *ExecParams) err
 	us.mw.Output = up
 	us.mw.Algo = "zstd" // FIXME: grab this from elsewhere
 	us.mw.InputAlign = 1 << 20
-	return o.From.exec(us, src, ep)
+	return us, nil
+}
+
+// maxOutputPartitions is the maximum number
+// of partitions that OutputPart will write
+var maxOutputPartitions = 1000
+
+// partitionSink is a vm.QuerySink that
+// splits rows by the values of the partition
+// columns and uploads each partition to
+// a separate packfile
+type partitionSink struct {
+	parent *OutputPart
+	dst    vm.QuerySink
+	lock   sync.Mutex
+	sinks  map[string]*uploadSink
+}
+
+// partitionWriter is the io.WriteCloser
+// returned from partitionSink.Open()
+type partitionWriter struct {
+	parent *partitionSink
+	syms   ion.Symtab
+	// epoch is incremented each time
+	// the symbol table changes
+	epoch    int
+	streams  map[string]*partitionStream
+	symbuf   ion.Buffer // syms, marshaled at symepoch
+	symepoch int
+	buf      []byte
+}
+
+// partitionStream is a stream to
+// the packfile of one partition
+type partitionStream struct {
+	w     io.WriteCloser
+	epoch int // epoch of the last symbol table written to w
+}
+
+func (ps *partitionSink) Open() (io.WriteCloser, error) {
+	return &partitionWriter{
+		parent:   ps,
+		streams:  make(map[string]*partitionStream),
+		symepoch: -1,
+	}, nil
+}
+
+// sink returns the uploadSink for the partition
+// with the given name, creating it if necessary
+func (ps *partitionSink) sink(name string, cons []ion.Field) (*uploadSink, error) {
+	ps.lock.Lock()
+	defer ps.lock.Unlock()
+	if us := ps.sinks[name]; us != nil {
+		return us, nil
+	}
+	if len(ps.sinks) >= maxOutputPartitions {
+		return nil, fmt.Errorf("OutputPart: more than %d partitions", maxOutputPartitions)
+	}
+	us, err := ps.parent.sink(path.Join(ps.parent.Basename, name), ps.dst)
+	if err != nil {
+		return nil, err
+	}
+	us.mw.Trailer.Sparse.SetConsts(cons)
+	ps.sinks[name] = us
+	return us, nil
+}
+
+func (ps *partitionSink) Close() error {
+	names := maps.Keys(ps.sinks)
+	slices.Sort(names)
+	for _, name := range names {
+		err := ps.sinks[name].finish()
+		if err != nil {
+			return err
+		}
+	}
+	return ps.dst.Close()
+}
+
+// partitionValue returns the path segment
+// and the constant for a partition value
+func partitionValue(d ion.Datum) (string, ion.Datum, error) {
+	switch d.Type() {
+	case ion.StringType, ion.SymbolType:
+		s, err := d.String()
+		if err != nil {
+			return "", ion.Empty, err
+		}
+		return s, ion.String(s), nil
+	case ion.IntType:
+		i, err := d.Int()
+		if err != nil {
+			return "", ion.Empty, err
+		}
+		return strconv.FormatInt(i, 10), ion.Int(i), nil
+	case ion.UintType:
+		u, err := d.Uint()
+		if err != nil {
+			return "", ion.Empty, err
+		}
+		return strconv.FormatUint(u, 10), ion.Uint(u), nil
+	case ion.BoolType:
+		b, err := d.Bool()
+		if err != nil {
+			return "", ion.Empty, err
+		}
+		return strconv.FormatBool(b), ion.Bool(b), nil
+	case ion.TimestampType:
+		t, err := d.Timestamp()
+		if err != nil {
+			return "", ion.Empty, err
+		}
+		return t.Time().Format(time.RFC3339Nano), ion.Timestamp(t), nil
+	default:
+		return "", ion.Empty, fmt.Errorf("cannot partition on a value of type %s", d.Type())
+	}
+}
+
+// partition returns the name of the
+// partition of row and the partition constants;
+// only the partition fields of row are decoded
+func (o *OutputPart) partition(st *ion.Symtab, row []byte) (string, []ion.Field, error) {
+	segs := make([]string, len(o.Partitions))
+	cons := make([]ion.Field, len(o.Partitions))
+	found := 0
+	body, _ := ion.Contents(row)
+	for len(body) > 0 && found < len(o.Partitions) {
+		sym, rest, err := ion.ReadLabel(body)
+		if err != nil {
+			return "", nil, err
+		}
+		size := ion.SizeOf(rest)
+		if size <= 0 || size > len(rest) {
+			return "", nil, fmt.Errorf("OutputPart: invalid datum size %d", size)
+		}
+		body = rest[size:]
+		name, ok := st.Lookup(sym)
+		if !ok {
+			continue
+		}
+		i := slices.Index(o.Partitions, name)
+		if i < 0 || segs[i] != "" {
+			continue
+		}
+		d, _, err := ion.ReadDatum(st, rest[:size])
+		if err != nil {
+			return "", nil, err
+		}
+		seg, v, err := partitionValue(d)
+		if err != nil {
+			return "", nil, fmt.Errorf("OutputPart: partition column %q: %w", name, err)
+		}
+		segs[i] = name + "=" + url.PathEscape(seg)
+		cons[i] = ion.Field{Label: name, Datum: v}
+		found++
+	}
+	for i, p := range o.Partitions {
+		if segs[i] == "" {
+			return "", nil, fmt.Errorf("OutputPart: row is missing partition column %q", p)
+		}
+	}
+	return path.Join(segs...), cons, nil
+}
+
+func (w *partitionWriter) Write(p []byte) (int, error) {
+	n := len(p)
+	var err error
+	for len(p) > 0 {
+		if ion.IsBVM(p) || ion.TypeOf(p) == ion.AnnotationType {
+			p, err = w.syms.Unmarshal(p)
+			if err != nil {
+				return n - len(p), err
+			}
+			w.epoch++
+			continue
+		}
+		size := ion.SizeOf(p)
+		if size <= 0 || size > len(p) {
+			return n - len(p), fmt.Errorf("OutputPart: invalid datum size %d", size)
+		}
+		row := p[:size]
+		p = p[size:]
+		if ion.TypeOf(row) != ion.StructType {
+			continue // ignore nop pads
+		}
+		name, cons, err := w.parent.parent.partition(&w.syms, row)
+		if err != nil {
+			return n - len(p), err
+		}
+		s, err := w.stream(name, cons)
+		if err != nil {
+			return n - len(p), err
+		}
+		// each stream gets its own copy of the
+		// symbol table when the symbol table changes
+		w.buf = w.buf[:0]
+		if s.epoch != w.epoch {
+			if w.symepoch != w.epoch {
+				w.symbuf.Reset()
+				w.syms.Marshal(&w.symbuf, true)
+				w.symepoch = w.epoch
+			}
+			w.buf = append(w.buf, w.symbuf.Bytes()...)
+			s.epoch = w.epoch
+		}
+		w.buf = append(w.buf, row...)
+		_, err = s.w.Write(w.buf)
+		if err != nil {
+			return n - len(p), err
+		}
+	}
+	return n, nil
+}
+
+func (w *partitionWriter) stream(name string, cons []ion.Field) (*partitionStream, error) {
+	if s := w.streams[name]; s != nil {
+		return s, nil
+	}
+	us, err := w.parent.sink(name, cons)
+	if err != nil {
+		return nil, err
+	}
+	sw, err := us.Open()
+	if err != nil {
+		return nil, err
+	}
+	s := &partitionStream{w: sw, epoch: -1}
+	w.streams[name] = s
+	return s, nil
+}
+
+func (w *partitionWriter) Close() error {
+	var err error
+	for _, s := range w.streams {
+		if err2 := s.w.Close(); err == nil {
+			err = err2
+		}
+	}
+	return err
 }
 
 func (o *OutputPart) encode(dst *ion.Buffer, st *ion.Symtab, rw expr.Rewriter) error {
@@ -183,6 +463,7 @@ func (o *OutputPart) encode(dst *ion.Buffer, st *ion.Symtab, rw expr.Rewriter) e
 	settype("outpart", dst, st)
 	dst.BeginField(st.Intern("basename"))
 	dst.WriteString(o.Basename)
+	encodePartitions(dst, st, o.Partitions)
 	dst.BeginField(st.Intern("store"))
 	if err := o.Store.Encode(dst, st); err != nil {
 		return err
@@ -191,6 +472,31 @@ func (o *OutputPart) encode(dst *ion.Buffer, st *ion.Symtab, rw expr.Rewriter) e
 	return nil
 }
 
+func encodePartitions(dst *ion.Buffer, st *ion.Symtab, parts []string) {
+	if len(parts) == 0 {
+		return
+	}
+	dst.BeginField(st.Intern("partitions"))
+	dst.BeginList(-1)
+	for i := range parts {
+		dst.WriteString(parts[i])
+	}
+	dst.EndList()
+}
+
+func decodePartitions(f ion.Field) ([]string, error) {
+	var parts []string
+	err := f.UnpackList(func(d ion.Datum) error {
+		s, err := d.String()
+		if err != nil {
+			return err
+		}
+		parts = append(parts, s)
+		return nil
+	})
+	return parts, err
+}
+
 func (o *OutputPart) setfield(d Decoder, f ion.Field) error {
 	switch f.Label {
 	case "basename":
@@ -199,6 +505,12 @@ func (o *OutputPart) setfield(d Decoder, f ion.Field) error {
 			return err
 		}
 		o.Basename = basename
+	case "partitions":
+		parts, err := decodePartitions(f)
+		if err != nil {
+			return err
+		}
+		o.Partitions = parts
 	case "store":
 		up, ok := d.(UploaderDecoder)
 		if !ok {
@@ -216,7 +528,20 @@ func (o *OutputPart) setfield(d Decoder, f ion.Field) error {
 }
 
 func (o *OutputPart) String() string {
-	return "OUTPUT PART " + o.Basename
+	return "OUTPUT PART " + o.Basename + describePartitions(o.Partitions)
+}
+
+// describePartitions returns the PARTITIONED BY
+// suffix of the string form of Output* nodes
+func describePartitions(parts []string) string {
+	if len(parts) == 0 {
+		return ""
+	}
+	ids := make([]string, len(parts))
+	for i := range parts {
+		ids[i] = expr.QuoteID(parts[i])
+	}
+	return " PARTITIONED BY (" + strings.Join(ids, ", ") + ")"
 }
 
 // OutputIndex is a nonterminal plan node
@@ -224,12 +549,49 @@ func (o *OutputPart) String() string {
 // them into an Index object. OutputIndex writes
 // one output row containing the autogenerated
 // table name.
+//
+// If Partitions is non-empty, OutputIndex checks
+// that each descriptor produced by OutputPart
+// records a constant for each partition column.
+//
+// If Append is set, OutputIndex appends the
+// descriptors to the index of the existing table
+// DB.Table (creating it if necessary) instead of
+// writing a new table, and the output row contains
+// the name of that table. If Compact is also set,
+// the table is compacted after the append succeeds.
+// If Txn is also set, the descriptors are staged
+// in Txn rather than appended immediately, and
+// Compact is ignored.
 type OutputIndex struct {
 	Nonterminal
-	DB, Table string
-	Basename  string
-	Store     UploadFS
-	Key       *blockfmt.Key
+	DB, Table  string
+	Basename   string
+	Partitions []string
+	Append     bool
+	Compact    bool
+	Store      UploadFS
+	Key        *blockfmt.Key
+	// Txn is not serialized with the plan,
+	// so it only takes effect when the plan
+	// is executed in the same process in which
+	// it was lowered.
+	Txn *db.Txn
+}
+
+// storeTenant is a db.Tenant that
+// is rooted at an OutputIndex's store
+type storeTenant struct {
+	store UploadFS
+	key   *blockfmt.Key
+}
+
+func (s *storeTenant) ID() string                { return "" }
+func (s *storeTenant) Key() *blockfmt.Key        { return s.key }
+func (s *storeTenant) Root() (db.InputFS, error) { return s.store, nil }
+
+func (s *storeTenant) Split(pattern string) (db.InputFS, string, error) {
+	return nil, "", fmt.Errorf("storeTenant: cannot split %q", pattern)
 }
 
 // indexSink is a vm.QuerySink that collects
@@ -289,13 +651,23 @@ func (is *indexSink) Close() error {
 		return nil
 	}
 	is.closed = true
-	idxmem, err := blockfmt.Sign(is.parent.Key, is.idx)
-	if err != nil {
-		return err
+	if parts := is.parent.Partitions; len(parts) > 0 {
+		// keep the order of the descriptors
+		// independent of the order in which
+		// the partitions were finished
+		slices.SortFunc(is.idx.Inline, func(x, y blockfmt.Descriptor) bool {
+			return x.Path < y.Path
+		})
+		for i := range is.idx.Inline {
+			desc := &is.idx.Inline[i]
+			for _, p := range parts {
+				if _, ok := desc.Trailer.Sparse.Const(p); !ok {
+					return fmt.Errorf("OutputIndex: %s has no constant for partition %q", desc.Path, p)
+				}
+			}
+		}
 	}
-	idxpath := db.IndexPath(is.db, is.tbl)
-	_, err = is.parent.Store.WriteFile(idxpath, idxmem)
-	if err != nil {
+	if err := is.writeIndex(); err != nil {
 		return err
 	}
 	var buf ion.Buffer
@@ -318,6 +690,37 @@ func (is *indexSink) Close() error {
 	return w.Close()
 }
 
+// writeIndex writes a new index for is.tbl or,
+// in append mode, appends the collected descriptors
+// to the existing index for is.tbl
+func (is *indexSink) writeIndex() error {
+	o := is.parent
+	if !o.Append {
+		idxmem, err := blockfmt.Sign(o.Key, is.idx)
+		if err != nil {
+			return err
+		}
+		_, err = o.Store.WriteFile(db.IndexPath(is.db, is.tbl), idxmem)
+		return err
+	}
+	if o.Txn != nil {
+		return o.Txn.AppendDescriptors(is.db, is.tbl, is.idx.Inline)
+	}
+	who := &storeTenant{store: o.Store, key: o.Key}
+	var conf db.Config
+	err := conf.AppendDescriptors(who, is.db, is.tbl, is.idx.Inline)
+	if err != nil || !o.Compact {
+		return err
+	}
+	err = conf.Compact(who, is.db, is.tbl)
+	if errors.Is(err, db.ErrBuildAgain) {
+		// the table is still being scanned;
+		// the next append will try again
+		err = nil
+	}
+	return err
+}
+
 func (o *OutputIndex) exec(dst vm.QuerySink, src TableHandle, ep *ExecParams) error {
 	if o.Basename == "" {
 		return fmt.Errorf("OutputIndex: basename not set")
@@ -328,7 +731,10 @@ func (o *OutputIndex) exec(dst vm.QuerySink, src TableHandle, ep *ExecParams) er
 	}
 	tbl := &expr.Dot{
 		Inner: expr.Ident(o.DB),
-		Field: o.Table + "-" + uuid(),
+		Field: o.Table,
+	}
+	if !o.Append {
+		tbl.Field += "-" + uuid()
 	}
 	idx := &blockfmt.Index{
 		Name: tbl.Field,
@@ -353,6 +759,12 @@ func (o *OutputIndex) setfield(d Decoder, f ion.Field) error {
 		o.Table, err = f.String()
 	case "basename":
 		o.Basename, err = f.String()
+	case "partitions":
+		o.Partitions, err = decodePartitions(f)
+	case "append":
+		o.Append, err = f.Bool()
+	case "compact":
+		o.Compact, err = f.Bool()
 	case "store":
 		up, ok := d.(UploaderDecoder)
 		if !ok {
@@ -385,6 +797,15 @@ func (o *OutputIndex) encode(dst *ion.Buffer, st *ion.Symtab, _ expr.Rewriter) e
 	dst.WriteString(o.Table)
 	dst.BeginField(st.Intern("basename"))
 	dst.WriteString(o.Basename)
+	encodePartitions(dst, st, o.Partitions)
+	if o.Append {
+		dst.BeginField(st.Intern("append"))
+		dst.WriteBool(true)
+	}
+	if o.Compact {
+		dst.BeginField(st.Intern("compact"))
+		dst.WriteBool(true)
+	}
 	dst.BeginField(st.Intern("store"))
 	if err := o.Store.Encode(dst, st); err != nil {
 		return err
@@ -397,5 +818,9 @@ func (o *OutputIndex) encode(dst *ion.Buffer, st *ion.Symtab, _ expr.Rewriter) e
 
 func (o *OutputIndex) String() string {
 	e := expr.MakePath([]string{o.DB, o.Table})
-	return fmt.Sprintf("OUTPUT INDEX %s AT %s", expr.ToString(e), o.Basename)
+	mode := ""
+	if o.Append {
+		mode = " APPEND"
+	}
+	return fmt.Sprintf("OUTPUT INDEX %s AT %s%s%s", expr.ToString(e), o.Basename, describePartitions(o.Partitions), mode)
 }
@@ -17,7 +17,12 @@ package plan
 import (
 	"bytes"
 	"crypto/rand"
+	"errors"
 	"fmt"
+	"io/fs"
+	"net/url"
+	"path"
+	"strings"
 	"testing"
 
 	"github.com/SnellerInc/sneller/db"
@@ -29,9 +34,13 @@ import (
 
 func TestOutput(t *testing.T) {
 	cases := []struct {
-		text string // create temp table
+		text  string // create temp table
+		parts []string
 	}{{
 		text: "SELECT * INTO foo.bar FROM 'parking.10n'",
+	}, {
+		text:  "SELECT Make, Color, Ticket INTO foo.bar PARTITIONED BY (Color, Make) FROM 'parking.10n' WHERE Color IS NOT NULL AND Make IS NOT NULL",
+		parts: []string{"Color", "Make"},
 	}}
 	for i := range cases {
 		c := &cases[i]
@@ -93,19 +102,197 @@ func TestOutput(t *testing.T) {
 				t.Fatal(err)
 			}
 			t.Log("index:", idx)
+			if c.parts == nil {
+				return
+			}
+			if len(idx.Inline) < 2 {
+				t.Fatalf("expected multiple partitions; got %d", len(idx.Inline))
+			}
+			for i := range idx.Inline {
+				desc := &idx.Inline[i]
+				dir, _ := path.Split(desc.Path)
+				segs := strings.Split(strings.Trim(strings.TrimPrefix(dir, "db/foo/bar/"), "/"), "/")
+				if len(segs) != len(c.parts) {
+					t.Fatalf("unexpected path %s", desc.Path)
+				}
+				for j, p := range c.parts {
+					d, ok := desc.Trailer.Sparse.Const(p)
+					if !ok {
+						t.Fatalf("%s: no constant for %s", desc.Path, p)
+					}
+					v, err := d.String()
+					if err != nil {
+						t.Fatal(err)
+					}
+					if want := p + "=" + url.PathEscape(v); segs[j] != want {
+						t.Errorf("%s: segment %q, expected %q", desc.Path, segs[j], want)
+					}
+				}
+				if i > 0 && idx.Inline[i-1].Path >= desc.Path {
+					t.Errorf("descriptors not sorted: %s before %s", idx.Inline[i-1].Path, desc.Path)
+				}
+			}
+			if !idx.HasPartition(c.parts[0]) {
+				t.Errorf("index has no partition %s", c.parts[0])
+			}
 		})
 	}
 }
 
+func TestOutputTooManyPartitions(t *testing.T) {
+	saved := maxOutputPartitions
+	maxOutputPartitions = 2
+	defer func() { maxOutputPartitions = saved }()
+
+	q, err := partiql.Parse([]byte("SELECT Make, Color INTO foo.bar PARTITIONED BY (Color) FROM 'parking.10n' WHERE Color IS NOT NULL"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	env := mkoutenv(t, t.TempDir())
+	tree, err := New(q, env)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var dst bytes.Buffer
+	var stat ExecStats
+	err = Exec(tree, &dst, &stat)
+	if err == nil || !strings.Contains(err.Error(), "more than 2 partitions") {
+		t.Fatalf("expected a partition limit error; got %v", err)
+	}
+}
+
+func TestOutputAppend(t *testing.T) {
+	tmp := t.TempDir()
+	env := mkoutenv(t, tmp)
+	run := func(text string) {
+		q, err := partiql.Parse([]byte(text))
+		if err != nil {
+			t.Fatal(err)
+		}
+		tree, err := New(q, env)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Logf("plan:\n%s", tree)
+		var dst bytes.Buffer
+		var stat ExecStats
+		err = Exec(tree, &dst, &stat)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var st ion.Symtab
+		rest, err := st.Unmarshal(dst.Bytes())
+		if err != nil {
+			t.Fatal(err)
+		}
+		var tbl string
+		_, err = ion.UnpackStruct(&st, rest, func(field string, buf []byte) error {
+			var err error
+			if field == "table" {
+				tbl, _, err = ion.ReadString(buf)
+			}
+			return err
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tbl != "foo.bar" {
+			t.Fatalf("output table %q, expected foo.bar", tbl)
+		}
+	}
+	const query = "INSERT INTO foo.bar SELECT * FROM 'parking.10n'"
+	run(query)
+	idx, err := db.OpenIndex(env.fs, "foo", "bar", env.Key())
+	if err != nil {
+		t.Fatal(err)
+	}
+	first := len(idx.Inline)
+	if first == 0 {
+		t.Fatal("no descriptors after first INSERT")
+	}
+	run(query)
+	idx, err = db.OpenIndex(env.fs, "foo", "bar", env.Key())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(idx.Inline) != 2*first {
+		t.Fatalf("got %d descriptors after second INSERT; expected %d", len(idx.Inline), 2*first)
+	}
+	for i := range idx.Inline {
+		if !strings.HasPrefix(idx.Inline[i].Path, "db/foo/bar/") {
+			t.Errorf("unexpected path %s", idx.Inline[i].Path)
+		}
+	}
+
+	// with compaction enabled, undersized
+	// packfiles are merged after the append
+	env.compact = true
+	run(query)
+	idx, err = db.OpenIndex(env.fs, "foo", "bar", env.Key())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(idx.ToDelete) == 0 {
+		t.Fatal("expected compaction to quarantine merged packfiles")
+	}
+}
+
+func TestOutputAppendTxn(t *testing.T) {
+	env := mkoutenv(t, t.TempDir())
+	var conf db.Config
+	env.txn = conf.Begin(&storeTenant{store: env.fs, key: env.key})
+	for _, text := range []string{
+		"INSERT INTO foo.bar SELECT * FROM 'parking.10n'",
+		"INSERT INTO foo.baz SELECT * FROM 'parking.10n'",
+	} {
+		q, err := partiql.Parse([]byte(text))
+		if err != nil {
+			t.Fatal(err)
+		}
+		tree, err := New(q, env)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var dst bytes.Buffer
+		var stat ExecStats
+		err = Exec(tree, &dst, &stat)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, tbl := range []string{"bar", "baz"} {
+		_, err := db.OpenIndex(env.fs, "foo", tbl, env.Key())
+		if !errors.Is(err, fs.ErrNotExist) {
+			t.Fatalf("foo.%s: expected no index before Commit; got %v", tbl, err)
+		}
+	}
+	if err := env.txn.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	for _, tbl := range []string{"bar", "baz"} {
+		idx, err := db.OpenIndex(env.fs, "foo", tbl, env.Key())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(idx.Inline) == 0 {
+			t.Errorf("foo.%s: no descriptors after Commit", tbl)
+		}
+	}
+}
+
 var _ interface {
 	UploadEnv
 	UploaderDecoder
+	CompactEnv
+	TxnEnv
 } = (*outputenv)(nil)
 
 type outputenv struct {
 	testenv
-	fs  UploadFS
-	key *blockfmt.Key
+	fs      UploadFS
+	key     *blockfmt.Key
+	compact bool
+	txn     *db.Txn
 }
 
 func mkoutenv(t *testing.T, dir string) *outputenv {
@@ -120,6 +307,9 @@ func mkoutenv(t *testing.T, dir string) *outputenv {
 func (o *outputenv) Uploader() UploadFS { return o.fs }
 func (o *outputenv) Key() *blockfmt.Key { return o.key }
 
+func (o *outputenv) CompactAfterAppend() bool { return o.compact }
+func (o *outputenv) Txn() *db.Txn             { return o.txn }
+
 func (o *outputenv) DecodeUploader(d ion.Datum) (UploadFS, error) {
 	return db.DecodeDirFS(d)
 }
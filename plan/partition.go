@@ -170,6 +170,22 @@ func Serve(rw io.ReadWriteCloser, dec Decoder) error {
 	return err
 }
 
+// Reject responds to a connection that would
+// otherwise have been passed to Serve with an error,
+// so that Client.Exec on the other end of the
+// connection returns an error with the same text.
+func Reject(w io.Writer, err error) error {
+	text := err.Error()
+	if len(text) > maxframe {
+		text = text[:maxframe]
+	}
+	buf := make([]byte, framesize, framesize+len(text))
+	mkframe(frameerr, len(text)).put(buf)
+	buf = append(buf, text...)
+	_, err = w.Write(buf)
+	return err
+}
+
 func (s *server) frame() (frame, error) {
 	buf, err := s.rd.Peek(framesize)
 	if err != nil {
@@ -509,6 +525,14 @@ func (c *Client) output(dst io.Writer, size int) error {
 	return nil
 }
 
+// queryError is an error reported
+// by the remote end of a Client
+type queryError struct {
+	text string
+}
+
+func (q *queryError) Error() string { return q.text }
+
 func (c *Client) queryerr(size int) error {
 	var bld strings.Builder
 	bld.WriteString("remote error: ")
@@ -516,7 +540,7 @@ func (c *Client) queryerr(size int) error {
 	if err != nil {
 		return err
 	}
-	return errors.New(bld.String())
+	return &queryError{text: bld.String()}
 }
 
 func (c *Client) decodestat(stat *ExecStats, size int) error {
@@ -68,9 +68,14 @@ func aggfiltersimplify(b *Trace) *aggFilterQuery {
 	modified := false
 	if k > 0 && q.filter != nil {
 		conj := conjunctions(q.filter.Where, []expr.Node{})
+		hint := &stepHint{parent: q.table}
 		// Note: unfortunately, O(n^2) complexity
 		for i := range q.agg.Agg {
-			if exprExists(q.agg.Agg[i].Expr.Filter, conj) {
+			f := q.agg.Agg[i].Expr.Filter
+			if f == nil {
+				continue
+			}
+			if exprExists(f, conj) || impliedBy(q.filter.Where, f, hint) {
 				q.agg.Agg[i].Expr.Filter = nil
 				modified = true
 			}
@@ -132,9 +137,10 @@ func aggFilterMatchQuery(top Step, query *aggFilterQuery) bool {
 // adjusting the binding at the same time.
 func aggremoveduplicates(b *Trace, agg *Aggregate, bind *Bind) {
 	var uniqagg vm.Aggregation
+	byhash := make(map[uint64][]int)
 
 	findExisting := func(agg vm.AggBinding) *vm.AggBinding {
-		for i := range uniqagg {
+		for _, i := range byhash[agg.Expr.Hash()] {
 			if expr.Equivalent(uniqagg[i].Expr, agg.Expr) {
 				return &uniqagg[i]
 			}
@@ -151,6 +157,8 @@ func aggremoveduplicates(b *Trace, agg *Aggregate, bind *Bind) {
 		if existing != nil {
 			duplicates[agg.Agg[i].Result] = existing.Result
 		} else {
+			h := agg.Agg[i].Expr.Hash()
+			byhash[h] = append(byhash[h], len(uniqagg))
 			uniqagg = append(uniqagg, agg.Agg[i])
 		}
 	}
@@ -227,6 +235,15 @@ func aggregatesCommonFilter(agg *Aggregate) expr.Node {
 	return common
 }
 
+// impliedBy returns true if where AND e
+// simplifies to where, which is the case
+// when e is a range that contains the range
+// of the same path that is matched by where
+func impliedBy(where, e expr.Node, h expr.Hint) bool {
+	both := expr.SimplifyLogic(expr.And(expr.Copy(where), expr.Copy(e)), h)
+	return expr.Equivalent(both, expr.SimplifyLogic(expr.Copy(where), h))
+}
+
 func exprExists(e expr.Node, lst []expr.Node) bool {
 	for i := range lst {
 		if expr.Equivalent(lst[i], e) {
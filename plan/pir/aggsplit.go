@@ -109,15 +109,18 @@ func rejectNestedAggregates(columns []expr.Binding, order []expr.Order, fn func(
 			return nil
 		}
 		agg, ok := e.(*expr.Aggregate)
-		if ok && agg.Over != nil {
-			err = errorf(agg, "cannot handle nested aggregate %s", expr.ToString(agg))
-			return nil
+		if ok {
+			if agg.Over != nil {
+				err = errorf(agg, "cannot handle nested aggregate %s", expr.ToString(agg))
+				return nil
+			}
+			return walkinner
 		}
 		_, ok = e.(*expr.Select)
 		if ok {
 			return walkouter
 		}
-		return walkinner
+		return walkwindow
 	}
 
 	for i := range columns {
@@ -291,10 +294,10 @@ func flattenIntoFunc(x []expr.Binding, n int, item func(int) *expr.Node) {
 	}
 }
 
-// splitAggregate generates the aggregation (and HAVING) step(s)
+// splitAggregate generates the aggregation (and HAVING and QUALIFY) step(s)
 // and rewrites the order and distinct expressions to use
 // the bindings produced by the aggregation step
-func (b *Trace) splitAggregate(order []expr.Order, distinct []expr.Node, columns, groups []expr.Binding, having expr.Node) error {
+func (b *Trace) splitAggregate(order []expr.Order, distinct []expr.Node, columns, groups []expr.Binding, having, qualify expr.Node) error {
 	hasaggregate := false
 	iterall := false // an aggregate needs all columns
 	err := rejectNestedAggregates(columns, order, func(agg *expr.Aggregate) {
@@ -309,7 +312,24 @@ func (b *Trace) splitAggregate(order []expr.Order, distinct []expr.Node, columns
 	if anyHasAggregate(groups) {
 		return fmt.Errorf("GROUP BY cannot contain aggregates")
 	}
+	if qualify != nil {
+		// QUALIFY is evaluated against the same
+		// set of bindings as the SELECT list
+		qualify = flattenOne(columns, qualify)
+		if hasAggregate(qualify) {
+			hasaggregate = true
+		}
+	}
 	if !hasaggregate {
+		if qualify != nil {
+			// any window functions have already been
+			// hoisted, so QUALIFY can only reference
+			// the grouping columns
+			err = b.Where(qualify)
+			if err != nil {
+				return err
+			}
+		}
 		flattenIntoExprs(groups, distinct)
 		err = b.DistinctFromBindings(groups)
 		if err != nil {
@@ -326,7 +346,7 @@ func (b *Trace) splitAggregate(order []expr.Order, distinct []expr.Node, columns
 	symno := 0
 
 	rewriteAggregate := func(age *expr.Aggregate, allowOver bool) expr.Node {
-		if !allowOver && age.Over != nil && !age.Op.WindowOnly() {
+		if !allowOver && age.Over != nil && !age.Windowed() {
 			err = errorf(age, "window function in illegal position")
 			return age
 		}
@@ -402,6 +422,12 @@ func (b *Trace) splitAggregate(order []expr.Order, distinct []expr.Node, columns
 	for i := range distinct {
 		distinct[i] = expr.Rewrite(rw, distinct[i])
 	}
+	if qualify != nil {
+		qualify = expr.Rewrite(rw, qualify)
+		if err != nil {
+			return err
+		}
+	}
 	// now we can push these to the builder
 	// in the correct order of evaluation
 	err = b.Aggregate(aggcols, groups)
@@ -410,6 +436,15 @@ func (b *Trace) splitAggregate(order []expr.Order, distinct []expr.Node, columns
 	}
 	if having != nil {
 		err = b.Where(having)
+		if err != nil {
+			return err
+		}
+	}
+	// QUALIFY is a separate filter so that it
+	// is applied after the window functions are
+	// evaluated (and after HAVING)
+	if qualify != nil {
+		err = b.Where(qualify)
 	}
 	return err
 }
@@ -491,7 +526,10 @@ func aggelim(b *Trace) {
 }
 
 func agg2const(tbl *IterTable, agg *expr.Aggregate) expr.Constant {
-	if agg.Inner == nil {
+	// the index describes every row,
+	// so it cannot answer filtered
+	// or windowed aggregates
+	if agg.Inner == nil || agg.Filter != nil || agg.Over != nil {
 		return nil
 	}
 	p, ok := expr.FlatPath(agg.Inner)
@@ -509,6 +547,23 @@ func agg2const(tbl *IterTable, agg *expr.Aggregate) expr.Constant {
 		if ok {
 			return &expr.Timestamp{Value: max}
 		}
+	case expr.OpMin, expr.OpMax:
+		// the range of a sort key is exact
+		// (blocks with values of mixed types
+		// have no range), so MIN and MAX of a
+		// numeric key can be read from the index
+		min, max, ok := tbl.keyRange(p)
+		if !ok || min.IsString() {
+			return nil
+		}
+		v := min
+		if agg.Op == expr.OpMax {
+			v = max
+		}
+		c, ok := expr.AsConstant(v)
+		if ok {
+			return c
+		}
 	}
 	return nil
 }
@@ -15,12 +15,16 @@
 package pir
 
 import (
+	"errors"
 	"fmt"
 	"io"
 	"path"
 
 	"github.com/SnellerInc/sneller/date"
 	"github.com/SnellerInc/sneller/expr"
+	"github.com/SnellerInc/sneller/ion"
+
+	"golang.org/x/exp/slices"
 )
 
 // CompileError is an error associated
@@ -28,10 +32,18 @@ import (
 type CompileError struct {
 	In  expr.Node
 	Err string
+	// Pos, if valid, is the location
+	// of In in the query text
+	Pos expr.Position
 }
 
 // Error implements error
-func (c *CompileError) Error() string { return c.Err }
+func (c *CompileError) Error() string {
+	if c.Pos.IsValid() {
+		return c.Err + " at " + c.Pos.String()
+	}
+	return c.Err
+}
 
 // WriteTo implements io.WriterTo
 //
@@ -167,13 +179,59 @@ type Index interface {
 	HasPartition(field string) bool
 }
 
+// BlockCounter may optionally be implemented
+// by an Index to report the number of blocks
+// in a table that a filter would select.
+type BlockCounter interface {
+	// Blocks returns the number of blocks
+	// that filter does not exclude and the
+	// total number of blocks in the table.
+	// A nil filter matches every block.
+	Blocks(filter expr.Node) (matching, total int, err error)
+}
+
+// KeyRanger may optionally be implemented
+// by an Index to report the range of values
+// of a sort key in a table.
+type KeyRanger interface {
+	// KeyRange returns the inclusive range of
+	// values for the given path expression across
+	// the given table. The returned values are
+	// either both numbers or both strings.
+	KeyRange(path []string) (min, max ion.Datum, ok bool)
+}
+
 // Build walks the provided Query
 // and lowers it into the optimized query IR.
 // If the provided SchemaHint is non-nil,
 // then it will be used to provide additional
 // type information that can be used to type-check
 // and optimize the query.
+// If q.Positions is set, errors that refer
+// to a particular expression are annotated
+// with its location in the query text.
 func Build(q *expr.Query, e Env) (*Trace, error) {
+	t, err := buildQuery(q, e)
+	if err != nil && q.Positions != nil {
+		locate(err, q.Positions)
+	}
+	return t, err
+}
+
+// locate attaches the position of the
+// expression that caused err, if known
+func locate(err error, pos expr.Positions) {
+	var ce *CompileError
+	if errors.As(err, &ce) {
+		if !ce.Pos.IsValid() {
+			ce.Pos, _ = pos.Of(ce.In)
+		}
+		return
+	}
+	expr.Locate(err, pos)
+}
+
+func buildQuery(q *expr.Query, e Env) (*Trace, error) {
 	body := q.Body
 	var err error
 	if len(q.With) > 0 {
@@ -193,7 +251,10 @@ func Build(q *expr.Query, e Env) (*Trace, error) {
 			if !ok || len(p) != 2 {
 				return nil, fmt.Errorf("unsupported INTO: %q", expr.ToString(q.Into))
 			}
-			t.Into(q.Into, path.Join("db", p[0], p[1]))
+			if err := checkPartitionBy(t, q.PartitionBy); err != nil {
+				return nil, err
+			}
+			t.Into(q.Into, path.Join("db", p[0], p[1]), q.PartitionBy, q.Append)
 		}
 		return t, nil
 	}
@@ -201,10 +262,43 @@ func Build(q *expr.Query, e Env) (*Trace, error) {
 	return nil, errorf(body, "cannot pir.Build %T", body)
 }
 
+// checkPartitionBy checks that each of the
+// PARTITIONED BY columns is a distinct output
+// column of the query
+func checkPartitionBy(t *Trace, parts []string) error {
+	if len(parts) == 0 {
+		return nil
+	}
+	final := t.FinalBindings()
+	if final == nil {
+		return fmt.Errorf("PARTITIONED BY: cannot determine the output columns of the query")
+	}
+	for i, p := range parts {
+		if slices.Contains(parts[:i], p) {
+			return fmt.Errorf("PARTITIONED BY: duplicate column %q", p)
+		}
+		found := false
+		for j := range final {
+			if final[j].Result() == p {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("PARTITIONED BY: %q is not an output column", p)
+		}
+	}
+	return nil
+}
+
 func build(parent *Trace, s *expr.Select, e Env) (*Trace, error) {
 	b := &Trace{Parent: parent}
+	err := resolvePositions(s)
+	if err != nil {
+		return nil, err
+	}
 	s = expr.Simplify(s, expr.NoHint).(*expr.Select)
-	err := expr.Check(s)
+	err = expr.Check(s)
 	if err != nil {
 		return nil, err
 	}
@@ -339,6 +433,111 @@ func pickOutputs(s *expr.Select) {
 	}
 }
 
+// selectColumn returns the expression of the n'th
+// (0-based) output column of s with any references
+// to previous output columns flattened (see flattenBind)
+func selectColumn(s *expr.Select, n int) expr.Node {
+	f := newFlattener(n + 1)
+	var col expr.Binding
+	for i := 0; i <= n; i++ {
+		col = s.Columns[i]
+		col.Expr = expr.Rewrite(f, expr.Copy(col.Expr))
+		f.add(col)
+	}
+	return col.Expr
+}
+
+// selectPosition returns the output column expression
+// for a reference to the n'th (1-based) output column
+func selectPosition(s *expr.Select, n expr.Integer, clause string) (expr.Node, error) {
+	if isselectall(s) {
+		return nil, errorf(n, "%s position %d cannot refer to *", clause, n)
+	}
+	if n < 1 || int(n) > len(s.Columns) {
+		return nil, errorf(n, "%s position %d is not in the select list", clause, n)
+	}
+	return selectColumn(s, int(n)-1), nil
+}
+
+// references returns whether or not e references
+// the top-level binding name
+func references(e expr.Node, name string) bool {
+	found := false
+	expr.Walk(expr.WalkFunc(func(e expr.Node) bool {
+		if id, ok := e.(expr.Ident); ok && string(id) == name {
+			found = true
+		}
+		return !found
+	}), e)
+	return found
+}
+
+// resolvePositions replaces references to output
+// columns by position (GROUP BY 1, ORDER BY 2) in
+// GROUP BY and ORDER BY and references to output
+// column names in GROUP BY with the corresponding
+// output column expression in s and all of its sub-queries
+//
+// As in PostgreSQL, a GROUP BY name refers to an input
+// column rather than an output column when the output
+// column is computed from an input column with that name.
+//
+// This has to happen before simplification,
+// since constant GROUP BY and ORDER BY
+// expressions are otherwise eliminated.
+func resolvePositions(s *expr.Select) error {
+	var err error
+	expr.Walk(expr.WalkFunc(func(e expr.Node) bool {
+		if err != nil {
+			return false
+		}
+		if s, ok := e.(*expr.Select); ok {
+			err = resolveSelectPositions(s)
+		}
+		return err == nil
+	}), s)
+	return err
+}
+
+func resolveSelectPositions(s *expr.Select) error {
+	for i := range s.GroupBy {
+		if s.GroupBy[i].Explicit() {
+			continue
+		}
+		switch e := s.GroupBy[i].Expr.(type) {
+		case expr.Integer:
+			col, err := selectPosition(s, e, "GROUP BY")
+			if err != nil {
+				return err
+			}
+			s.GroupBy[i].Expr = col
+		case expr.Ident:
+			if isselectall(s) {
+				continue
+			}
+			for j := range s.Columns {
+				if s.Columns[j].Result() != string(e) {
+					continue
+				}
+				if col := selectColumn(s, j); !references(col, string(e)) {
+					s.GroupBy[i].Expr = col
+				}
+				break
+			}
+		}
+	}
+	for i := range s.OrderBy {
+		if n, ok := s.OrderBy[i].Column.(expr.Integer); ok {
+			col, err := selectPosition(s, n, "ORDER BY")
+			if err != nil {
+				return err
+			}
+			s.OrderBy[i].Column = col
+		}
+	}
+	return nil
+}
+
 // adjust ORDER BY and DISTINCT ON(...) so that it can be computed
 // before the final SELECT binding step
 func normalizeOrderBy(s *expr.Select) {
@@ -672,10 +871,10 @@ func (w *windowHoist) Rewrite(e expr.Node) expr.Node {
 	if !ok {
 		return e
 	}
-	// if we have COUNT(DISTINCT ...) along with
-	// other aggregates, we can rewrite it to
-	// work more like a window function:
-	if agg.Op == expr.OpCountDistinct &&
+	// if we have COUNT(DISTINCT ...) (or SUM, AVG)
+	// along with other aggregates, we can rewrite it
+	// to work more like a window function:
+	if agg.IsDistinct() &&
 		len(w.outer.GroupBy) > 0 &&
 		!hasOnlyOneAggregate(w.outer) {
 		agg.Over = &expr.Window{
@@ -685,10 +884,14 @@ func (w *windowHoist) Rewrite(e expr.Node) expr.Node {
 	if agg.Over == nil {
 		return e
 	}
-	if agg.Op.WindowOnly() {
+	if agg.Windowed() {
 		// handled natively by the core
 		return e
 	}
+	if len(agg.Over.OrderBy) > 0 {
+		w.err = fmt.Errorf("ORDER BY in a window is not supported for %s", agg.Op)
+		return e
+	}
 	if len(agg.Over.PartitionBy) == 0 {
 		w.err = fmt.Errorf("PARTITION BY has 0 partition elements")
 		return e
@@ -696,10 +899,24 @@ func (w *windowHoist) Rewrite(e expr.Node) expr.Node {
 
 	partitions := agg.Over.PartitionBy
 	self := copyForWindow(w.outer)
-	if agg.Op == expr.OpCountDistinct {
+	if agg.IsDistinct() {
 		self.GroupBy = append(self.GroupBy, expr.Bind(agg.Inner, "$__distinct"))
-		agg.Op = expr.OpCount
-		agg.Inner = expr.Star{}
+		agg.Op = agg.Op.WithoutDistinct()
+		if agg.Op == expr.OpCount {
+			agg.Inner = expr.Star{}
+		} else {
+			agg.Inner = expr.Ident("$__distinct")
+		}
+		// the filter has to be applied before
+		// the distinct values are grouped
+		if agg.Filter != nil {
+			if self.Where == nil {
+				self.Where = agg.Filter
+			} else {
+				self.Where = expr.And(self.Where, agg.Filter)
+			}
+			agg.Filter = nil
+		}
 	}
 	// outerkey is the lookup expression to yield in the outer query
 	outerkey := partitions[0]
@@ -806,7 +1023,37 @@ func (b *Trace) hoistWindows(s *expr.Select, e Env) error {
 			return rw.err
 		}
 	}
-	return nil
+	if s.Qualify != nil {
+		s.Qualify = expr.Rewrite(rw, s.Qualify)
+	}
+	return rw.err
+}
+
+// hasWindow returns whether any of the
+// SELECT bindings or the QUALIFY clause
+// contains a window function
+func hasWindow(s *expr.Select) bool {
+	found := false
+	visit := expr.WalkFunc(func(e expr.Node) bool {
+		if found {
+			return false
+		}
+		if _, ok := e.(*expr.Select); ok {
+			return false
+		}
+		if agg, ok := e.(*expr.Aggregate); ok && agg.Over != nil {
+			found = true
+			return false
+		}
+		return true
+	})
+	for i := range s.Columns {
+		expr.Walk(visit, s.Columns[i].Expr)
+	}
+	if s.Qualify != nil {
+		expr.Walk(visit, s.Qualify)
+	}
+	return found
 }
 
 func (b *Trace) walkSelect(s *expr.Select, e Env) error {
@@ -814,6 +1061,9 @@ func (b *Trace) walkSelect(s *expr.Select, e Env) error {
 	pickOutputs(s)
 	selectall := isselectall(s)
 	s.Columns = flattenBind(s.Columns)
+	if s.Qualify != nil && !hasWindow(s) {
+		return errorf(s.Qualify, "QUALIFY requires a window function")
+	}
 	err := b.hoistWindows(s, e)
 	if err != nil {
 		return err
@@ -839,11 +1089,17 @@ func (b *Trace) walkSelect(s *expr.Select, e Env) error {
 	}
 
 	// if we are doing aggregation anywhere, then split it:
-	if s.Having != nil || s.GroupBy != nil || anyHasAggregate(s.Columns) || anyOrderHasAggregate(s.OrderBy) {
+	if s.Having != nil || s.GroupBy != nil || anyHasAggregate(s.Columns) || anyOrderHasAggregate(s.OrderBy) ||
+		(s.Qualify != nil && hasAggregate(s.Qualify)) {
 		// s.OrderBy and s.Columns are rewritten to reference
 		// the generated aggregate expression
-		// (and also HAVING is taken care of)
-		err = b.splitAggregate(s.OrderBy, s.DistinctExpr, s.Columns, s.GroupBy, s.Having)
+		// (and also HAVING and QUALIFY are taken care of)
+		err = b.splitAggregate(s.OrderBy, s.DistinctExpr, s.Columns, s.GroupBy, s.Having, s.Qualify)
+		if err != nil {
+			return err
+		}
+	} else if s.Qualify != nil {
+		err = b.Where(flattenOne(s.Columns, s.Qualify))
 		if err != nil {
 			return err
 		}
@@ -53,6 +53,25 @@ func TestBuildError(t *testing.T) {
 		rx     string
 		schema expr.Hint
 	}{
+		{
+			// TIME_RANGE requires a table index
+			input: "select TIME_RANGE(ts) from table",
+			rx:    "no index available",
+		},
+		{
+			// PARTITIONED BY columns must be output columns
+			input: "select x, y into db.tbl partitioned by (z) from table",
+			rx:    `"z" is not an output column`,
+		},
+		{
+			input: "select x, y into db.tbl partitioned by (x, x) from table",
+			rx:    `duplicate column "x"`,
+		},
+		{
+			// TIME_RANGE cannot be mixed with other outputs
+			input: "select TIME_RANGE(ts), x from table",
+			rx:    "TIME_RANGE may only be used",
+		},
 		{
 			// when a table has been bound explicitly,
 			// require every variable reference that terminates
@@ -160,6 +179,59 @@ func TestBuildError(t *testing.T) {
 			input: `SELECT x, SUM(y), ROW_NUMBER() OVER (PARTITION BY x+100 ORDER BY SUM(y)) FROM tbl GROUP BY x`,
 			rx:    "bound outside the window",
 		},
+		{
+			// legal but not supported: SUM(y) isn't part of the outer aggregation
+			input: `SELECT x, SUM(SUM(y)) OVER (ORDER BY x) FROM tbl GROUP BY x`,
+			rx:    "window argument SUM\\(y\\) is not bound",
+		},
+		{
+			input: `SELECT x, AVG(y) OVER (PARTITION BY z ORDER BY x) FROM tbl GROUP BY x, z`,
+			rx:    "ORDER BY in a window is not supported",
+		},
+		{
+			input: `SELECT x, COUNT(*) FILTER (WHERE y > 0) OVER (ORDER BY x) FROM tbl GROUP BY x`,
+			rx:    "window FILTER references y, which is not bound",
+		},
+		{
+			input: `SELECT x, COUNT(*) FILTER (WHERE SUM(y) > 0) OVER (ORDER BY x) FROM tbl GROUP BY x`,
+			rx:    "window FILTER references SUM\\(y\\), which is not bound",
+		},
+		{
+			input: `SELECT x, SUM(y) FROM tbl GROUP BY x QUALIFY SUM(y) > 0`,
+			rx:    "QUALIFY requires a window function",
+		},
+		{
+			input: `SELECT x, y FROM tbl QUALIFY ROW_NUMBER() OVER (PARTITION BY x ORDER BY y) = 1`,
+			rx:    "window function disallowed without GROUP BY",
+		},
+		{
+			input: `SELECT x, SUM(y), NTILE(0) OVER (ORDER BY SUM(y)) FROM tbl GROUP BY x`,
+			rx:    "NTILE requires a positive integer constant",
+		},
+		{
+			input: `SELECT x, SUM(y), NTILE(y) OVER (ORDER BY SUM(y)) FROM tbl GROUP BY x`,
+			rx:    "NTILE requires a positive integer constant",
+		},
+		{
+			input: `SELECT x, SUM(y), CUME_DIST(y) OVER (ORDER BY SUM(y)) FROM tbl GROUP BY x`,
+			rx:    "does not accept an argument",
+		},
+		{
+			input: `SELECT x, COUNT(*) FROM tbl GROUP BY 3`,
+			rx:    "GROUP BY position 3 is not in the select list",
+		},
+		{
+			input: `SELECT x, COUNT(*) FROM tbl GROUP BY 2`,
+			rx:    "GROUP BY cannot contain aggregates",
+		},
+		{
+			input: `SELECT x, y FROM tbl ORDER BY 0 LIMIT 1`,
+			rx:    "ORDER BY position 0 is not in the select list",
+		},
+		{
+			input: `SELECT * FROM tbl ORDER BY 1 LIMIT 1`,
+			rx:    "ORDER BY position 1 cannot refer to \\*",
+		},
 		{
 			// implicit recursive aggregate via window functions:
 			input: `SELECT x, COUNT(*), ROW_NUMBER() OVER (ORDER BY COUNT(*)) AS rn, RANK() OVER (ORDER BY rn)`,
@@ -188,6 +260,11 @@ func TestBuildError(t *testing.T) {
 			input: `SELECT 1 + (SELECT 1 + (SELECT X) FROM table1) FROM table2`,
 			rx:    `path X references an unbound variable`,
 		},
+		{
+			// errors carry the position of the expression
+			input: "SELECT a,\n  1 + (SELECT 1 + (SELECT X) FROM table1) FROM table2",
+			rx:    `path X references an unbound variable at 2:27$`,
+		},
 	}
 	for i := range tests {
 		in := tests[i].input
@@ -236,6 +313,22 @@ func (t *testindex) TimeRange(path []string) (min, max date.Time, ok bool) {
 	return t.idx.TimeRange(path)
 }
 
+func (t *testindex) KeyRange(path []string) (min, max ion.Datum, ok bool) {
+	if t.idx == nil {
+		return ion.Empty, ion.Empty, false
+	}
+	return t.idx.KeyRange(path)
+}
+
+func (t *testindex) Blocks(filter expr.Node) (matching, total int, err error) {
+	if t.idx == nil {
+		return 0, 0, nil
+	}
+	var keep blockfmt.Filter
+	keep.Compile(filter)
+	return t.idx.Blocks(nil, &keep)
+}
+
 func (t *testindex) HasPartition(x string) bool {
 	return slices.Contains(t.parts, x)
 }
@@ -465,6 +558,21 @@ func TestBuild(t *testing.T) {
 				"PROJECT CASE WHEN $_1_0 = 0 THEN NULL ELSE \"avg\" / $_1_0 END AS \"avg\", y AS y",
 			},
 		},
+		{
+			// the FILTER of a window is evaluated
+			// over the outputs of the reduction
+			input: `select x, count(*) as n, sum(count(*)) filter (where count(*) > 1 and x <> 'b') over (order by x) as run from foo group by x`,
+			expect: []string{
+				"ITERATE foo FIELDS [x]",
+				"AGGREGATE COUNT(*) AS n, SUM_INT(COUNT(*)) FILTER (WHERE COUNT(*) > 1 AND x <> 'b') OVER (ORDER BY x ASC NULLS FIRST) AS run BY x AS x",
+			},
+			split: []string{
+				"UNION MAP foo (",
+				"	ITERATE PART foo FIELDS [x]",
+				"	AGGREGATE COUNT(*) AS $_2_0 BY x AS x)",
+				"AGGREGATE SUM_COUNT($_2_0) AS n, SUM_INT(n) FILTER (WHERE n > 1 AND x <> 'b') OVER (ORDER BY x ASC NULLS FIRST) AS run BY x AS x",
+			},
+		},
 		{
 			input: "select o.x, i.y from foo as o, o.field as i where o.x <> i.y",
 			expect: []string{
@@ -485,6 +593,18 @@ func TestBuild(t *testing.T) {
 				"PROJECT x AS x, i.y AS y",
 			},
 		},
+		{
+			// UNNEST of multiple arrays iterates them
+			// in lock-step, and filters that reference
+			// any of the zipped bindings stay below it
+			input: `select o.x, i, j from foo as o, unnest(o.a, o.b) as (i, j) where o.x < 3 and j < 3`,
+			expect: []string{
+				"ITERATE foo AS o FIELDS [a, b, x] WHERE x < 3",
+				"ITERATE FIELD a AS i, b AS j",
+				"FILTER j < 3",
+				"PROJECT x AS x, i AS i, j AS j",
+			},
+		},
 		{
 			// test that nested projection is flattened
 			input: `select x+2 as x, y - 3 as y from (select 3 + a as x, b - 5 as y from foo)`,
@@ -676,6 +796,21 @@ where out.Make = 'CHRY' and entry.BodyStyle = 'PA'`,
 			},
 			results: []expr.TypeSet{countType, expr.AnyType},
 		},
+		{
+			input: `select avg(distinct t.x) filter (where t.z > 0), t.y from table as t group by t.y`,
+			expect: []string{
+				"ITERATE table AS t FIELDS [x, y, z]",
+				"FILTER DISTINCT [x, y, z > 0]",
+				"AGGREGATE AVG(x) FILTER (WHERE z > 0) AS \"avg\" BY y AS y",
+			},
+			split: []string{
+				"UNION MAP table AS t (",
+				"	ITERATE PART table AS t FIELDS [x, y, z]",
+				"	FILTER DISTINCT [x, y, z > 0])",
+				"FILTER DISTINCT [x, y, z > 0]",
+				"AGGREGATE AVG(x) FILTER (WHERE z > 0) AS \"avg\" BY y AS y",
+			},
+		},
 		{
 			// since count(*) does not reference any columns,
 			// any projections that immediate precede it can
@@ -939,6 +1074,45 @@ select * from (select c from ccount) where c is not missing`,
 				expr.TimeType,
 			},
 		},
+		{
+			// MIN and MAX of a numeric sort key
+			// are answered from the index
+			input: `select MIN(k), MAX(k) from table`,
+			index: mkindex([][]blockfmt.Range{{
+				blockfmt.NewRange([]string{"k"}, ion.Int(3), ion.Int(5)),
+			}, {
+				blockfmt.NewRange([]string{"k"}, ion.Int(1), ion.Int(9)),
+			}}),
+			expect: []string{
+				"[{}]",
+				"PROJECT 1 AS \"min\", 9 AS \"max\"",
+			},
+		},
+		{
+			// ... but not when the aggregate is filtered
+			input: `select MAX(k) FILTER (WHERE x > 0) AS m from table`,
+			index: mkindex([][]blockfmt.Range{{
+				blockfmt.NewRange([]string{"k"}, ion.Int(3), ion.Int(5)),
+			}}),
+			expect: []string{
+				"ITERATE table FIELDS [k, x] WHERE x > 0",
+				"AGGREGATE MAX(k) AS m",
+			},
+		},
+		{
+			// TIME_RANGE reports the index metadata
+			// and the number of blocks selected by the filter
+			input: "select TIME_RANGE(t.ts) as r from table where t.ts >= `2022-02-22T21:30:00Z`",
+			index: mkindex([][]blockfmt.Range{{
+				timeRange("t.ts", now(0), now(1)),
+			}, {
+				timeRange("t.ts", now(1), now(2)),
+			}}),
+			expect: []string{
+				"[{}]",
+				"PROJECT {'min': `2022-02-22T20:22:22Z`, 'max': `2022-02-22T22:22:22Z`, 'blocks': 1, 'total_blocks': 2} AS r",
+			},
+		},
 		{
 			// test that aggregates can be
 			// partially eliminated using the index
@@ -1046,6 +1220,164 @@ ORDER BY m, d, h`,
 				"PROJECT x AS x, HASH_REPLACEMENT(0, 'struct', '$_0_0', x) AS z",
 			},
 		},
+		{
+			// constants are propagated across steps
+			input: `select k*x as v from (select 2 as k, x from foo) order by v limit 3`,
+			expect: []string{
+				"ITERATE foo FIELDS [x]",
+				"PROJECT x AS x",
+				"ORDER BY x * 2 ASC NULLS FIRST",
+				"LIMIT 3",
+				"PROJECT x * 2 AS v",
+			},
+		},
+		{
+			// identical aggregates are computed once
+			input: `select sum(a) as s, count(*), sum(b) as t, g from (select x*2 as a, x*2 as b, g from foo) group by g`,
+			expect: []string{
+				"ITERATE foo FIELDS [g, x]",
+				"AGGREGATE SUM(x * 2) AS s, COUNT(*) AS \"count\" BY g AS g",
+				"PROJECT s AS s, \"count\" AS \"count\", s AS t, g AS g",
+			},
+		},
+		{
+			// duplicate bindings are referenced only once
+			input: `select a + b as c from (select x + 1 as a, x + 1 as b, y from foo) order by y limit 10`,
+			expect: []string{
+				"ITERATE foo FIELDS [x, y]",
+				"PROJECT x + 1 AS a, y AS y",
+				"ORDER BY y ASC NULLS FIRST",
+				"LIMIT 10",
+				"PROJECT a + a AS c",
+			},
+		},
+		{
+			// once the CASE is folded, the filter no longer
+			// depends on the iterated value, so it is hoisted
+			// out of the iteration and into the table scan
+			input: `select y from (select arr, x, 3 as k from foo) as f, f.arr as y where case when f.k > 2 then f.x > 1 else y.v > 0 end`,
+			expect: []string{
+				"ITERATE foo FIELDS [arr, x] WHERE x > 1",
+				"PROJECT arr AS arr",
+				"ITERATE FIELD arr AS y",
+				"PROJECT y AS y",
+			},
+		},
+		{
+			// a WHERE clause folded to TRUE is dropped
+			input: `select y from (select arr, 3 as k from foo) as f, f.arr as y where y.v > 0 and f.k > 2`,
+			expect: []string{
+				"ITERATE foo FIELDS [arr]",
+				"PROJECT arr AS arr",
+				"ITERATE FIELD arr AS y",
+				"FILTER y.v > 0",
+				"PROJECT y AS y",
+			},
+		},
+		{
+			// cheap, selective predicates are evaluated first
+			input: `select x from foo where x ~ 'a.*b' and y like '%z%' and z = 1`,
+			expect: []string{
+				"ITERATE foo FIELDS [x, y, z] WHERE z = 1 AND y LIKE '%z%' AND x ~ 'a.*b'",
+				"PROJECT x AS x",
+			},
+		},
+		{
+			// the sparse index makes a narrow
+			// time range more selective than an equality
+			input: "select x from table where x = 'a' and t.ts >= `2022-02-22T22:20:00Z`",
+			index: mkindex([][]blockfmt.Range{{
+				timeRange("t.ts", now(0), now(1)),
+			}, {
+				timeRange("t.ts", now(1), now(2)),
+			}}),
+			expect: []string{
+				"ITERATE table FIELDS [t, x] WHERE t.ts >= `2022-02-22T22:20:00Z` AND x = 'a'",
+				"PROJECT x AS x",
+			},
+		},
+		{
+			// ... but without the index the equality is preferred
+			input: "select x from table where t.ts >= `2022-02-22T22:20:00Z` and x = 'a'",
+			expect: []string{
+				"ITERATE table FIELDS [t, x] WHERE x = 'a' AND t.ts >= `2022-02-22T22:20:00Z`",
+				"PROJECT x AS x",
+			},
+		},
+		{
+			// an expensive predicate that does not depend on
+			// the iterated value is evaluated after a cheap,
+			// selective one that does
+			input: `select y from foo as f, f.arr as y where f.s ~ 'a.*b' and y.v = 0`,
+			expect: []string{
+				"ITERATE foo AS f FIELDS [arr, s]",
+				"ITERATE FIELD arr AS y",
+				"FILTER y.v = 0 AND s ~ 'a.*b'",
+				"PROJECT y AS y",
+			},
+		},
+		{
+			// ... whereas a cheap one is always hoisted
+			input: `select y from foo as f, f.arr as y where f.s = 'x' and y.v ~ 'a.*b'`,
+			expect: []string{
+				"ITERATE foo AS f FIELDS [arr, s] WHERE s = 'x'",
+				"ITERATE FIELD arr AS y",
+				"FILTER y.v ~ 'a.*b'",
+				"PROJECT y AS y",
+			},
+		},
+		{
+			// ranges on the same path are merged
+			input: `select x from foo where x <= 9 and x > 1 and x >= 3 and y < 5 and x < 10`,
+			expect: []string{
+				"ITERATE foo FIELDS [x, y] WHERE x >= 3 AND x <= 9 AND y < 5",
+				"PROJECT x AS x",
+			},
+		},
+		{
+			// a chain of equalities becomes IN
+			input: `select x from foo where x = 0 or x = 1 or x = 2 or x = 3 or x = 4 or x = 5 or x = 6 or x = 7 or x = 8 or x = 9`,
+			expect: []string{
+				"ITERATE foo FIELDS [x] WHERE x IN (0, 1, 2, 3, 4, 5, 6, 7, 8, 9)",
+				"PROJECT x AS x",
+			},
+		},
+		{
+			input: `select x, (select count(*) + 1 from bar where x = y) as c from foo`,
+			expect: []string{
+				"WITH (",
+				"	ITERATE bar FIELDS [y]",
+				"	AGGREGATE COUNT(*) AS $_0_0 BY y AS $_0_1",
+				"	PROJECT $_0_0 + 1 AS _1, $_0_1 AS $_0_1",
+				") AS REPLACEMENT(0)",
+				"ITERATE foo FIELDS [x]",
+				"PROJECT x AS x, HASH_REPLACEMENT(0, 'scalar', '$_0_1', x) AS c",
+			},
+		},
+		{
+			input: `select x, (select sum(z) as s from bar where x = y having sum(z) > 3 order by s) as s from foo`,
+			expect: []string{
+				"WITH (",
+				"	ITERATE bar FIELDS [y, z]",
+				"	AGGREGATE SUM(z) AS $_0_0 BY y AS $_0_1",
+				"	FILTER $_0_0 > 3",
+				"	PROJECT $_0_0 AS s, $_0_1 AS $_0_1",
+				") AS REPLACEMENT(0)",
+				"ITERATE foo FIELDS [x]",
+				"PROJECT x AS x, HASH_REPLACEMENT(0, 'scalar', '$_0_1', x) AS s",
+			},
+		},
+		{
+			input: `select x, (select g, count(*) from bar where x = y group by g) as z from foo`,
+			expect: []string{
+				"WITH (",
+				"	ITERATE bar FIELDS [g, y]",
+				"	AGGREGATE COUNT(*) AS \"count\" BY g AS g, y AS $_0_0",
+				") AS REPLACEMENT(0)",
+				"ITERATE foo FIELDS [x]",
+				"PROJECT x AS x, HASH_REPLACEMENT(0, 'list', '$_0_0', x) AS z",
+			},
+		},
 		{
 			input: "SELECT TIME_BUCKET(timestamp, 864000) AS _tmbucket1, COUNT(*), AVG(AvgTicketPrice) AS _sum1 FROM kibana_sample_data_flights WHERE timestamp BETWEEN `2022-03-01T00:00:00.000Z` AND `2022-07-01T00:00:00.000Z` GROUP BY TIME_BUCKET(timestamp, 864000) ORDER BY _tmbucket1",
 			expect: []string{
@@ -1099,6 +1431,42 @@ ORDER BY m, d, h`,
 			},
 			results: []expr.TypeSet{expr.StringType},
 		},
+		{
+			// INTO with partitioned output
+			input: `SELECT x, y, dt INTO db.table PARTITIONED BY (dt) FROM foo`,
+			expect: []string{
+				"ITERATE foo FIELDS [dt, x, y]",
+				"PROJECT x AS x, y AS y, dt AS dt",
+				"OUTPUT PART db/db/table PARTITIONED BY (dt)",
+				"OUTPUT INDEX db.table AT db/db/table PARTITIONED BY (dt)",
+			},
+			split: []string{
+				"UNION MAP foo (",
+				"	ITERATE PART foo FIELDS [dt, x, y]",
+				"	PROJECT x AS x, y AS y, dt AS dt",
+				"	OUTPUT PART db/db/table PARTITIONED BY (dt))",
+				"OUTPUT INDEX db.table AT db/db/table PARTITIONED BY (dt)",
+			},
+			results: []expr.TypeSet{expr.StringType},
+		},
+		{
+			// INSERT INTO appends to an existing table
+			input: `INSERT INTO db.table SELECT x, y FROM foo`,
+			expect: []string{
+				"ITERATE foo FIELDS [x, y]",
+				"PROJECT x AS x, y AS y",
+				"OUTPUT PART db/db/table",
+				"OUTPUT INDEX db.table AT db/db/table APPEND",
+			},
+			split: []string{
+				"UNION MAP foo (",
+				"	ITERATE PART foo FIELDS [x, y]",
+				"	PROJECT x AS x, y AS y",
+				"	OUTPUT PART db/db/table)",
+				"OUTPUT INDEX db.table AT db/db/table APPEND",
+			},
+			results: []expr.TypeSet{expr.StringType},
+		},
 		{
 			// EXISTS -> semi-join
 			input: `SELECT x, EXISTS(SELECT * FROM other WHERE key = x) AS has_other FROM input`,
@@ -1339,6 +1707,19 @@ z = (SELECT a FROM bar LIMIT 1)`,
 				"AGGREGATE SNELLER_DATASHAPE_MERGE($_2_0) AS datashape",
 			},
 		},
+		{
+			input: "SELECT sneller_datashape(*, 'histograms') FROM table",
+			expect: []string{
+				"ITERATE table FIELDS *",
+				"AGGREGATE SNELLER_DATASHAPE(*, 'histograms') AS datashape",
+			},
+			split: []string{
+				"UNION MAP table (",
+				"	ITERATE PART table FIELDS *",
+				"	AGGREGATE SNELLER_DATASHAPE(*, 'histograms_partial') AS $_2_0)",
+				"AGGREGATE SNELLER_DATASHAPE_MERGE($_2_0, 'histograms') AS datashape",
+			},
+		},
 		{
 			// eliminate redundant LIMIT 1
 			input: `SELECT COUNT(*) FROM table WHERE x LIKE '%foo%' LIMIT 1`,
@@ -21,15 +21,16 @@ import (
 
 // walk a set of expressions and see if we can
 // match the index of a single aggregate which is
-// a COUNT(DISTINCT ...) aggregate
+// a COUNT(DISTINCT ...), SUM(DISTINCT ...),
+// or AVG(DISTINCT ...) aggregate
 //
 // returns (index, true) if there is exactly one match,
 // or (-1, false) if there are no matches, more than one match,
 // or there are other aggregate expressions present
-func singleCountDistinct(agg vm.Aggregation) (int, bool) {
+func singleDistinct(agg vm.Aggregation) (int, bool) {
 	idx := -1
 	for i := range agg {
-		if agg[i].Expr.Op != expr.OpCountDistinct || idx != -1 {
+		if !agg[i].Expr.IsDistinct() || idx != -1 {
 			return -1, false
 		}
 		idx = i
@@ -40,19 +41,20 @@ func singleCountDistinct(agg vm.Aggregation) (int, bool) {
 // convert SELECT COUNT(DISTINCT x), y...
 // into SELECT COUNT(x), y... FROM (SELECT DISTINCT x, y...)
 // since we do not natively support COUNT DISTINCT
+// (and likewise for SUM DISTINCT and AVG DISTINCT)
 func countdistinct2count(b *Trace) {
 	for s := b.top; s != nil; s = s.parent() {
 		a, ok := s.(*Aggregate)
 		if !ok {
 			continue
 		}
-		idx, ok := singleCountDistinct(a.Agg)
+		idx, ok := singleDistinct(a.Agg)
 		if !ok {
 			continue
 		}
-		// rewrite CountDistinct -> Count
+		// rewrite CountDistinct -> Count, etc.
 		cd := a.Agg[idx].Expr
-		cd.Op = expr.OpCount
+		cd.Op = cd.Op.WithoutDistinct()
 		distinct := &Distinct{
 			Columns: []expr.Node{cd.Inner},
 		}
@@ -60,6 +62,11 @@ func countdistinct2count(b *Trace) {
 		for i := range a.GroupBy {
 			distinct.Columns = append(distinct.Columns, a.GroupBy[i].Expr)
 		}
+		// the filter is evaluated after DISTINCT,
+		// so it has to be one of the distinct columns
+		if cd.Filter != nil {
+			distinct.Columns = append(distinct.Columns, cd.Filter)
+		}
 		// splice in new Distinct node
 		distinct.setparent(s.parent())
 		a.setparent(distinct)
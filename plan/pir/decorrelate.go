@@ -104,43 +104,196 @@ func (b *Trace) decorrelate() (k, v expr.Node, x string, err error) {
 	if y == nil {
 		return nil, nil, "", decorrerr(v, x)
 	}
-	// the top step must either be a Bind or
-	// Aggregate with at least one output
-	switch s := b.top.(type) {
-	case *Bind:
-		if len(s.bind) == 0 {
+	// if the result is computed by an aggregate,
+	// then the aggregate is grouped by y and the
+	// key is threaded through the steps above it
+	if agg := aggregateResult(b.top); agg != nil {
+		k, ok := b.decorrelateAggregate(agg, x, y)
+		if !ok {
 			return nil, nil, "", decorrerr(v, x)
 		}
-		for i := range s.bind {
-			if hasReference(x, s.bind[i].Expr) {
-				return nil, nil, "", decorrerr(v, x)
-			}
-		}
-		key := expr.Bind(y, gensym(0, 0))
-		s.bind = append(s.bind, key)
-		// insert "FILTER DISTINCT y" before
-		// the bind step
-		di := &Distinct{
-			Columns: []expr.Node{y},
-		}
-		di.setparent(s.parent())
-		s.setparent(di)
-		k = expr.String(key.Result())
-	case *Aggregate:
-		if len(s.Agg) == 0 || s.GroupBy != nil || hasReference(x, s.Agg[0].Expr) {
+		delete(it.free, x)
+		return k, v, x, nil
+	}
+	// otherwise the top step must be
+	// a Bind with at least one output
+	s, ok := b.top.(*Bind)
+	if !ok || len(s.bind) == 0 {
+		return nil, nil, "", decorrerr(v, x)
+	}
+	for i := range s.bind {
+		if hasReference(x, s.bind[i].Expr) {
 			return nil, nil, "", decorrerr(v, x)
 		}
-		by := expr.Bind(y, gensym(0, 0))
-		s.GroupBy = append(s.GroupBy, by)
-		k = expr.String(by.Result())
-	default:
-		return nil, nil, "", decorrerr(v, x)
 	}
+	key := expr.Bind(y, gensym(0, 0))
+	s.bind = append(s.bind, key)
+	// insert "FILTER DISTINCT y" before
+	// the bind step
+	di := &Distinct{
+		Columns: []expr.Node{y},
+	}
+	di.setparent(s.parent())
+	s.setparent(di)
+	k = expr.String(key.Result())
 	// do some bookkeeping
 	delete(it.free, x)
 	return k, v, x, nil
 }
 
+// aggregateResult returns the Aggregate step that
+// produces the results of the trace starting at top,
+// provided that it is only followed by the steps
+// that decorrelateAggregate knows how to rewrite
+func aggregateResult(top Step) *Aggregate {
+	for s := top; s != nil; s = s.parent() {
+		switch s := s.(type) {
+		case *Aggregate:
+			return s
+		case *Bind, *Filter, *Order:
+		default:
+			return nil
+		}
+	}
+	return nil
+}
+
+// decorrelation is the state of the
+// decorrelateAggregate rewrite
+type decorrelation struct {
+	agg   *Aggregate
+	y     expr.Node
+	key   string        // the name of the key, once agg is grouped by y
+	above map[Step]bool // the steps following agg
+}
+
+// decorrelateAggregate rewrites a subquery of the form
+//
+//	SELECT ... FROM t WHERE y = x [GROUP BY ...] [HAVING ...] [ORDER BY ...]
+//
+// where the result is produced by the aggregate agg
+// into
+//
+//	SELECT ..., y AS k FROM t [GROUP BY ...,] y AS k [HAVING ...]
+//
+// so that the results can be looked up by k with
+// HASH_REPLACEMENT. The rewrite is performed with
+// a fixed-point optimizer:
+//
+//   - the aggregate is grouped by y,
+//   - every projection above the aggregate
+//     forwards the key,
+//   - ORDER BY is dropped when the aggregate
+//     produces a single row per key.
+//
+// No step may reference x after the
+// correlated comparison has been removed.
+func (b *Trace) decorrelateAggregate(agg *Aggregate, x string, y expr.Node) (expr.Node, bool) {
+	if len(agg.Agg) == 0 {
+		return nil, false
+	}
+	for i := range agg.Agg {
+		if hasReference(x, agg.Agg[i].Expr) {
+			return nil, false
+		}
+	}
+	for i := range agg.GroupBy {
+		if hasReference(x, agg.GroupBy[i].Expr) {
+			return nil, false
+		}
+	}
+	d := &decorrelation{
+		agg:   agg,
+		y:     y,
+		above: make(map[Step]bool),
+	}
+	for s := b.top; s != agg; s = s.parent() {
+		var refs bool
+		switch s := s.(type) {
+		case *Bind:
+			for i := range s.bind {
+				refs = refs || hasReference(x, s.bind[i].Expr)
+			}
+		case *Filter:
+			refs = hasReference(x, s.Where)
+		case *Order:
+			for i := range s.Columns {
+				refs = refs || hasReference(x, s.Columns[i].Column)
+			}
+		}
+		if refs {
+			return nil, false
+		}
+		d.above[s] = true
+	}
+	fpo := newFixedPointOptimizer(d.group, d.forward, d.unorder)
+	fpo.optimize(b)
+	return expr.String(d.key), true
+}
+
+// group adds "GROUP BY y AS k" to the aggregate
+func (d *decorrelation) group(a *Aggregate) (Step, fpoStatus) {
+	if a != d.agg || d.key != "" {
+		return nil, fpoIntact
+	}
+	by := expr.Bind(d.y, d.fresh())
+	d.key = by.Result()
+	// if the aggregate was already grouped,
+	// then each key yields a list of groups
+	a.GroupBy = append(a.GroupBy, by)
+	return a, fpoUpdate
+}
+
+// fresh returns a name for the key that
+// does not collide with any of the names
+// bound by the aggregate or the projections
+// that follow it
+func (d *decorrelation) fresh() string {
+	used := make(map[string]bool)
+	for i := range d.agg.Agg {
+		used[d.agg.Agg[i].Result] = true
+	}
+	for i := range d.agg.GroupBy {
+		used[d.agg.GroupBy[i].Result()] = true
+	}
+	for s := range d.above {
+		if b, ok := s.(*Bind); ok {
+			for i := range b.bind {
+				used[b.bind[i].Result()] = true
+			}
+		}
+	}
+	for i := 0; ; i++ {
+		if name := gensym(0, i); !used[name] {
+			return name
+		}
+	}
+}
+
+// forward adds "k AS k" to a projection
+// following the aggregate
+func (d *decorrelation) forward(b *Bind) (Step, fpoStatus) {
+	if !d.above[b] || d.key == "" {
+		return nil, fpoIntact
+	}
+	for i := range b.bind {
+		if b.bind[i].Result() == d.key {
+			return nil, fpoIntact
+		}
+	}
+	b.bind = append(b.bind, expr.Bind(expr.Ident(d.key), d.key))
+	return b, fpoUpdate
+}
+
+// unorder removes ORDER BY following an aggregate
+// that produces at most one row per key
+func (d *decorrelation) unorder(o *Order) (Step, fpoStatus) {
+	if !d.above[o] || len(d.agg.GroupBy) > 1 || d.key == "" {
+		return nil, fpoIntact
+	}
+	return o.parent(), fpoReplace
+}
+
 func decorrerr(e expr.Node, x string) error {
 	return errorf(e, "cannot support correlated reference to %q", x)
 }
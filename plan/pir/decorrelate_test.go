@@ -35,6 +35,8 @@ func TestUnsupported(t *testing.T) {
 		`select x, (select a from bar where x = y AND x = z limit 1) from foo`,
 		`select x, (select a from bar where x = y AND x > 10 limit 1) from foo`,
 		`select x, (select x+y from bar where x = z limit 1) from foo`,
+		`select x, (select sum(z) from bar where x = y having sum(z) > x) from foo`,
+		`select x, (select g, count(*) from bar where x = y group by g + x) from foo`,
 	}
 	for i := range tests {
 		query := tests[i]
@@ -16,6 +16,8 @@ package pir
 
 import (
 	"github.com/SnellerInc/sneller/expr"
+
+	"golang.org/x/exp/slices"
 )
 
 func filterelim(b *Trace) {
@@ -51,18 +53,18 @@ func conjoin(x, y expr.Node, scope *Trace, at Step) expr.Node {
 	return expr.SimplifyLogic(expr.And(x, y), &stepHint{parent: at.parent()})
 }
 
-// determine if 'e' has no references to 'step'
+// determine if 'e' has no references to any of 'bind'
 //
 // n.b. false negatives are okay here; they will
 // just end up inhibiting optimizations
-func doesNotReference(e expr.Node, bind string) bool {
+func doesNotReference(e expr.Node, bind ...string) bool {
 	ref := false
 	visit := expr.WalkFunc(func(e expr.Node) bool {
 		if ref {
 			return false
 		}
 		id, ok := e.(expr.Ident)
-		if ok && string(id) == bind {
+		if ok && slices.Contains(bind, string(id)) {
 			ref = true
 		}
 		return !ok
@@ -172,30 +174,8 @@ func push(f *Filter, dst Step, s *Trace) bool {
 	// this is an unusual case because we
 	// can only push down *part* of the filter:
 	if iv, ok := dst.(*IterValue); ok {
-		conj := conjunctions(f.Where, nil)
-		par := iv.parent()
-		newparent := false
-		var remaining expr.Node
-		for j := range conj {
-			if doesNotReference(conj[j], iv.Result) {
-				par = forcepush(conj[j], par, s)
-				newparent = true
-			} else {
-				if remaining == nil {
-					remaining = conj[j]
-				} else {
-					remaining = conjoin(remaining, conj[j], s, iv)
-				}
-			}
-		}
-		if newparent {
-			iv.setparent(par)
-		}
-		if remaining == nil {
-			return true
-		}
-		f.Where = remaining
-		return false
+		done, _ := hoistinvariant(f, iv, s)
+		return done
 	}
 
 	// in some cases we can always push:
@@ -206,6 +186,53 @@ func push(f *Filter, dst Step, s *Trace) bool {
 	return false
 }
 
+// hoistinvariant pushes the conjunctions in f that
+// do not depend on the value being iterated by iv
+// ahead of iv, so that they are evaluated once per
+// outer row rather than once per iterated value,
+// unless the cost model determines that an expensive
+// predicate is better evaluated after the conjunctions
+// that do depend on the iterated value.
+// It returns whether all of f was pushed and
+// whether anything was pushed at all.
+func hoistinvariant(f *Filter, iv *IterValue, s *Trace) (all, some bool) {
+	conj := conjunctions(f.Where, nil)
+	var inner expr.Node
+	for j := range conj {
+		if !doesNotReference(conj[j], iv.results()...) {
+			inner = conjoin(inner, expr.Copy(conj[j]), s, iv)
+		}
+	}
+	var est estimator
+	var innerest estimate
+	if inner != nil {
+		innerest = est.estimate(inner)
+	}
+	par := iv.parent()
+	var remaining expr.Node
+	for j := range conj {
+		if doesNotReference(conj[j], iv.results()...) &&
+			(inner == nil || hoistprofitable(est.estimate(conj[j]), innerest)) {
+			par = forcepush(conj[j], par, s)
+			some = true
+		} else {
+			if remaining == nil {
+				remaining = conj[j]
+			} else {
+				remaining = conjoin(remaining, conj[j], s, iv)
+			}
+		}
+	}
+	if some {
+		iv.setparent(par)
+	}
+	if remaining == nil {
+		return true, some
+	}
+	f.Where = remaining
+	return false, some
+}
+
 // simple filter push-down:
 // merge adjacent filter steps into single ones,
 // and merge filters into table iteration steps
@@ -0,0 +1,206 @@
+// Copyright (C) 2022 Sneller, Inc.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package pir
+
+import (
+	"github.com/SnellerInc/sneller/expr"
+	"github.com/SnellerInc/sneller/vm"
+)
+
+// fold applies constant folding and common
+// sub-expression elimination rules across steps
+// until fixed point is achieved
+func fold(b *Trace) {
+	foldFPO.optimize(b)
+}
+
+// Fixed-Point Optimizer for constant folding and CSE
+var foldFPO fixedPointOptimizer
+
+func init() {
+	foldFPO = newFixedPointOptimizer(
+		foldBind,
+		foldFilter,
+		foldOrder,
+		foldAggregate,
+		foldDuplicateAggregates,
+		foldPushFilter,
+		foldIterTable,
+	)
+}
+
+// foldrw substitutes the references to bindings
+// produced by the nearest projection when the
+// bound expression is a constant or a duplicate
+// of another binding in the same projection
+type foldrw struct {
+	from    Step
+	changed bool
+}
+
+func (f *foldrw) Walk(e expr.Node) expr.Rewriter {
+	return f
+}
+
+func (f *foldrw) Rewrite(e expr.Node) expr.Node {
+	id, ok := e.(expr.Ident)
+	if !ok || id == "*" {
+		return e
+	}
+	origin, node := f.from.get(string(id))
+	bi, ok := origin.(*Bind)
+	if !ok || node == nil {
+		return e
+	}
+	if c, ok := node.(expr.Constant); ok {
+		f.changed = true
+		return expr.Copy(c)
+	}
+	if _, ok := node.(expr.Ident); ok {
+		// cheap enough already
+		return e
+	}
+	// if this is a duplicate of an earlier
+	// binding that is visible from here,
+	// then reference that one instead so
+	// that this one can be eliminated
+	for i := range bi.bind {
+		name := bi.bind[i].Result()
+		if name == string(id) {
+			break
+		}
+		if !expr.Equal(bi.bind[i].Expr, node) {
+			continue
+		}
+		if o2, n2 := f.from.get(name); o2 == origin && expr.Equal(n2, node) {
+			f.changed = true
+			return expr.Ident(name)
+		}
+	}
+	return e
+}
+
+// foldstep applies foldrw to the expressions in s
+func foldstep(s Step) (Step, fpoStatus) {
+	par := s.parent()
+	if par == nil {
+		return nil, fpoIntact
+	}
+	rw := &foldrw{from: par}
+	h := &stepHint{parent: par}
+	changed := false
+	s.rewrite(func(e expr.Node, logic bool) expr.Node {
+		rw.changed = false
+		e = expr.Rewrite(rw, e)
+		if !rw.changed {
+			return e
+		}
+		changed = true
+		e = expr.Simplify(e, h)
+		if logic {
+			return expr.SimplifyLogic(e, h)
+		}
+		return e
+	})
+	if changed {
+		return s, fpoUpdate
+	}
+	return nil, fpoIntact
+}
+
+func foldBind(b *Bind) (Step, fpoStatus)           { return foldstep(b) }
+func foldFilter(f *Filter) (Step, fpoStatus)       { return foldstep(f) }
+func foldOrder(o *Order) (Step, fpoStatus)         { return foldstep(o) }
+func foldAggregate(a *Aggregate) (Step, fpoStatus) { return foldstep(a) }
+
+// foldDuplicateAggregates computes identical
+// aggregate expressions only once; the duplicates
+// are re-bound by a projection following the aggregate:
+//
+//	AGGREGATE SUM(x) AS a, SUM(x) AS b BY g AS g
+//	=>
+//	AGGREGATE SUM(x) AS a BY g AS g
+//	PROJECT a AS a, a AS b, g AS g
+func foldDuplicateAggregates(a *Aggregate) (Step, fpoStatus) {
+	var outer []expr.Binding
+	var uniq vm.Aggregation
+	byhash := make(map[uint64][]int, len(a.Agg))
+	dup := false
+	for i := range a.Agg {
+		first := -1
+		h := a.Agg[i].Expr.Hash()
+		for _, j := range byhash[h] {
+			if uniq[j].Expr.Equals(a.Agg[i].Expr) {
+				first = j
+				break
+			}
+		}
+		if first == -1 {
+			uniq = append(uniq, a.Agg[i])
+			first = len(uniq) - 1
+			byhash[h] = append(byhash[h], first)
+		} else {
+			dup = true
+		}
+		outer = append(outer, expr.Bind(expr.Ident(uniq[first].Result), a.Agg[i].Result))
+	}
+	if !dup {
+		return nil, fpoIntact
+	}
+	for i := range a.GroupBy {
+		name := a.GroupBy[i].Result()
+		outer = append(outer, expr.Bind(expr.Ident(name), name))
+	}
+	a.Agg = uniq
+	bi := &Bind{binds: binds{outer}, complete: true}
+	bi.setparent(a)
+	return bi, fpoReplace
+}
+
+// foldPushFilter pushes filters as far ahead as
+// possible once folding has simplified them; in
+// particular, the parts of a filter following an
+// iteration that do not depend on the iterated
+// value are hoisted out of the iteration so that
+// they are evaluated once per outer row
+func foldPushFilter(f *Filter) (Step, fpoStatus) {
+	if f.Where == expr.Bool(true) {
+		return f.parent(), fpoReplace
+	}
+	if iv, ok := f.parent().(*IterValue); ok {
+		all, some := hoistinvariant(f, iv, nil)
+		if all {
+			return iv, fpoReplace
+		}
+		if some {
+			return f, fpoUpdate
+		}
+		return nil, fpoIntact
+	}
+	if push(f, f.parent(), nil) {
+		return f.parent(), fpoReplace
+	}
+	return nil, fpoIntact
+}
+
+// foldIterTable drops a WHERE clause
+// that has been folded to TRUE
+func foldIterTable(i *IterTable) (Step, fpoStatus) {
+	if i.Filter == expr.Bool(true) {
+		i.Filter = nil
+		return i, fpoUpdate
+	}
+	return nil, fpoIntact
+}
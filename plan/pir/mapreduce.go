@@ -222,6 +222,36 @@ func windowMatch(e expr.Node, srcagg, dstagg vm.Aggregation, groups []expr.Bindi
 	return "", false
 }
 
+// windowFilter rewrites the FILTER clause of a window
+// function so that the aggregates and grouping columns
+// it references point to the outputs of the reduction
+type windowFilter struct {
+	src, dst vm.Aggregation
+	groups   []expr.Binding
+	err      error
+}
+
+func (w *windowFilter) Walk(e expr.Node) expr.Rewriter {
+	if w.err != nil {
+		return nil
+	}
+	if _, ok := windowMatch(e, w.src, w.dst, w.groups); ok {
+		return nil
+	}
+	if agg, ok := e.(*expr.Aggregate); ok {
+		w.err = fmt.Errorf("window FILTER references aggregate %s not in outer aggregation", expr.ToString(agg))
+		return nil
+	}
+	return w
+}
+
+func (w *windowFilter) Rewrite(e expr.Node) expr.Node {
+	if id, ok := windowMatch(e, w.src, w.dst, w.groups); ok {
+		return expr.Ident(id)
+	}
+	return e
+}
+
 // take an aggregate expression and re-write it
 // so that the output bindings are sufficient
 // for the reduction step to produce the correct
@@ -239,6 +269,13 @@ func reduceAggregate(a *Aggregate, mapping, reduce *Trace) error {
 	needsFinalProjection := false
 	for i := range a.Agg {
 		switch a.Agg[i].Expr.Op {
+		case expr.OpSystemDatashape:
+			// The partial histograms carry the sketches
+			// that are needed to merge distinct counts.
+			if a.Agg[i].Expr.Datashape == expr.DatashapeHistograms {
+				a.Agg[i].Expr.Datashape = expr.DatashapeHistogramsPartial
+			}
+
 		case expr.OpApproxCountDistinct:
 			// All APPROX_COUNT_DISTINCT becomes its partial version.
 			//
@@ -322,6 +359,13 @@ func reduceAggregate(a *Aggregate, mapping, reduce *Trace) error {
 		gen := gensym(2, i)
 		current[i].Result = gen
 		innerref := expr.Identifier(gen)
+		if age.Windowed() {
+			// window functions are evaluated
+			// entirely in the reduction step
+			current[i].Expr = nil // delete this op
+			out = append(out, vm.AggBinding{Expr: age, Result: result})
+			continue
+		}
 		var newagg *expr.Aggregate
 		switch age.Op {
 		case expr.OpCount:
@@ -347,9 +391,9 @@ func reduceAggregate(a *Aggregate, mapping, reduce *Trace) error {
 			newagg = &expr.Aggregate{
 				Op:    expr.OpSystemDatashapeMerge,
 				Inner: innerref}
-		case expr.OpRowNumber, expr.OpRank, expr.OpDenseRank:
-			newagg = current[i].Expr
-			current[i].Expr = nil // delete this op
+			if age.Datashape != expr.DatashapeTypes {
+				newagg.Datashape = expr.DatashapeHistograms
+			}
 		}
 
 		if newagg == nil {
@@ -370,13 +414,21 @@ func reduceAggregate(a *Aggregate, mapping, reduce *Trace) error {
 			continue
 		}
 		into := out[i].Expr
+		// match the argument of a cumulative aggregate
+		if _, ok := into.Inner.(expr.Star); into.Op.Cumulative() && !ok {
+			id, ok := windowMatch(into.Inner, a.Agg, out, a.GroupBy)
+			if !ok {
+				return fmt.Errorf("window argument %s not in outer aggregation", expr.ToString(into.Inner))
+			}
+			into.Inner = expr.Ident(id)
+		}
 		// match PARTITION BY to corresponding columns
 		for j := range into.Over.PartitionBy {
 			if id, ok := windowMatch(into.Over.PartitionBy[j], a.Agg, out, a.GroupBy); ok {
 				into.Over.PartitionBy[j] = expr.Ident(id)
 				continue
 			}
-			return fmt.Errorf("window PARTITION BY references aggregate %s not in outer aggregation", expr.ToString(age.Over.PartitionBy[j]))
+			return fmt.Errorf("window PARTITION BY references aggregate %s not in outer aggregation", expr.ToString(into.Over.PartitionBy[j]))
 		}
 		// match ORDER BY to corresponding columns
 		for j := range into.Over.OrderBy {
@@ -385,7 +437,15 @@ func reduceAggregate(a *Aggregate, mapping, reduce *Trace) error {
 				into.Over.OrderBy[j].Column = expr.Ident(id)
 				continue
 			}
-			return fmt.Errorf("window ORDER BY references aggregate %s not in outer aggregation", expr.ToString(age.Over.OrderBy[j].Column))
+			return fmt.Errorf("window ORDER BY references aggregate %s not in outer aggregation", expr.ToString(into.Over.OrderBy[j].Column))
+		}
+		// match the terms of FILTER to corresponding columns
+		if into.Filter != nil {
+			wf := &windowFilter{src: a.Agg, dst: out, groups: a.GroupBy}
+			into.Filter = expr.Rewrite(wf, into.Filter)
+			if wf.err != nil {
+				return wf.err
+			}
 		}
 	}
 	a.Agg = newaggs
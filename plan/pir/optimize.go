@@ -81,9 +81,15 @@ func (b *Trace) optimize() error {
 	if err != nil {
 		return err
 	}
+	err = timerangeelim(b) // substitute constants for TIME_RANGE()
+	if err != nil {
+		return err
+	}
+	fold(b)            // fold constants and common sub-expressions across steps
 	projectelim(b)     // drop un-used bindings
 	projectpushdown(b) // merge adjacent projections
 	simplify(b)        // final simplification pass
+	reorderfilters(b)  // evaluate cheap, selective predicates first
 	if err := postcheck(b); err != nil {
 		return err
 	}
@@ -241,7 +241,7 @@ func aggByPartition(b *Trace, agg *Aggregate) (*UnionMap, bool) {
 	// on being able to see all the groups for the partition,
 	// then we can't split this grouping operation:
 	for i := range agg.Agg {
-		if agg.Agg[i].Expr.Op.WindowOnly() {
+		if agg.Agg[i].Expr.Windowed() {
 			return nil, false
 		}
 	}
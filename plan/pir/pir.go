@@ -23,6 +23,7 @@ import (
 
 	"github.com/SnellerInc/sneller/date"
 	"github.com/SnellerInc/sneller/expr"
+	"github.com/SnellerInc/sneller/ion"
 	"github.com/SnellerInc/sneller/vm"
 
 	"golang.org/x/exp/maps"
@@ -126,6 +127,14 @@ func (i *IterTable) timeRange(path []string) (min, max date.Time, ok bool) {
 	return i.Index.TimeRange(path)
 }
 
+func (i *IterTable) keyRange(path []string) (min, max ion.Datum, ok bool) {
+	kr, ok := i.Index.(KeyRanger)
+	if !ok {
+		return ion.Empty, ion.Empty, false
+	}
+	return kr.KeyRange(path)
+}
+
 // Wildcard returns true if the table
 // is referenced by the '*' operator
 // (in other words, if all column bindings
@@ -212,24 +221,50 @@ type IterValue struct {
 	parented
 	Value  expr.Node // the expression to be iterated
 	Result string    // the binding produced by iteration
+
+	// Zip, if present, is a list of additional
+	// expressions that are iterated in lock-step
+	// with Value (i.e. UNNEST(a, b) AS (x, y))
+	Zip []expr.Binding
 }
 
 func (i *IterValue) walk(v expr.Visitor) {
 	expr.Walk(v, i.Value)
+	for j := range i.Zip {
+		expr.Walk(v, i.Zip[j].Expr)
+	}
 }
 
 func (i *IterValue) equals(x Step) bool {
 	i2, ok := x.(*IterValue)
 	return ok && (i == i2 ||
-		(expr.Equal(i.Value, i2.Value) && i.Result == i2.Result))
+		(expr.Equal(i.Value, i2.Value) && i.Result == i2.Result &&
+			slices.EqualFunc(i.Zip, i2.Zip, expr.Binding.Equals)))
 }
 
 func (i *IterValue) describe(dst io.Writer) {
-	fmt.Fprintf(dst, "ITERATE FIELD %s AS %s\n", expr.ToString(i.Value), i.Result)
+	fmt.Fprintf(dst, "ITERATE FIELD %s AS %s", expr.ToString(i.Value), i.Result)
+	for j := range i.Zip {
+		fmt.Fprintf(dst, ", %s AS %s", expr.ToString(i.Zip[j].Expr), i.Zip[j].Result())
+	}
+	fmt.Fprintf(dst, "\n")
 }
 
 func (i *IterValue) rewrite(rw func(expr.Node, bool) expr.Node) {
 	i.Value = rw(i.Value, false)
+	for j := range i.Zip {
+		i.Zip[j].Expr = rw(i.Zip[j].Expr, false)
+	}
+}
+
+// results returns the bindings
+// produced by iteration
+func (i *IterValue) results() []string {
+	out := []string{i.Result}
+	for j := range i.Zip {
+		out = append(out, i.Zip[j].Result())
+	}
+	return out
 }
 
 type EquiJoin struct {
@@ -385,6 +420,11 @@ func (i *IterValue) get(x string) (Step, expr.Node) {
 	if x == i.Result {
 		return i, i.Value
 	}
+	for j := range i.Zip {
+		if x == i.Zip[j].Result() {
+			return i, i.Zip[j].Expr
+		}
+	}
 	return i.par.get(x)
 }
 
@@ -716,19 +756,44 @@ func (l *Limit) describe(dst io.Writer) {
 // a single part and returns a row like
 //
 //	{"part": "path/to/packed-XXXXX.ion.zst"}
+//
+// If Partitions is non-empty, output rows are
+// written into one part for each distinct
+// combination of the values of the Partitions
+// columns, and one row is returned for each part.
 type OutputPart struct {
-	Basename string
+	Basename   string
+	Partitions []string
 	parented
 	noexprs
 }
 
 func (o *OutputPart) equals(x Step) bool {
 	o2, ok := x.(*OutputPart)
-	return ok && (o == o2 || o.Basename == o2.Basename)
+	return ok && (o == o2 || o.Basename == o2.Basename &&
+		slices.Equal(o.Partitions, o2.Partitions))
 }
 
 func (o *OutputPart) describe(dst io.Writer) {
-	fmt.Fprintf(dst, "OUTPUT PART %s\n", o.Basename)
+	fmt.Fprintf(dst, "OUTPUT PART %s%s\n", o.Basename, describePartitions(o.Partitions))
+}
+
+// describePartitions returns the
+// PARTITIONED BY suffix for Output* steps
+func describePartitions(parts []string) string {
+	if len(parts) == 0 {
+		return ""
+	}
+	var out strings.Builder
+	out.WriteString(" PARTITIONED BY (")
+	for i := range parts {
+		if i > 0 {
+			out.WriteString(", ")
+		}
+		out.WriteString(expr.QuoteID(parts[i]))
+	}
+	out.WriteString(")")
+	return out.String()
 }
 
 func (o *OutputPart) get(x string) (Step, expr.Node) {
@@ -747,9 +812,15 @@ func (o *OutputPart) get(x string) (Step, expr.Node) {
 // returning a single row like
 //
 //	{"table": "db.table-XXXXXX"}
+//
+// If Append is set, the results are appended
+// to the existing table rather than written
+// to a new table.
 type OutputIndex struct {
-	Table    expr.Node
-	Basename string
+	Table      expr.Node
+	Basename   string
+	Partitions []string
+	Append     bool
 	parented
 	noexprs
 }
@@ -757,11 +828,17 @@ type OutputIndex struct {
 func (o *OutputIndex) equals(x Step) bool {
 	o2, ok := x.(*OutputIndex)
 	return ok && (o == o2 || o.Table.Equals(o2.Table) &&
-		o.Basename == o2.Basename)
+		o.Basename == o2.Basename &&
+		slices.Equal(o.Partitions, o2.Partitions) &&
+		o.Append == o2.Append)
 }
 
 func (o *OutputIndex) describe(dst io.Writer) {
-	fmt.Fprintf(dst, "OUTPUT INDEX %s AT %s\n", expr.ToString(o.Table), o.Basename)
+	mode := ""
+	if o.Append {
+		mode = " APPEND"
+	}
+	fmt.Fprintf(dst, "OUTPUT INDEX %s AT %s%s%s\n", expr.ToString(o.Table), o.Basename, describePartitions(o.Partitions), mode)
 }
 
 func (o *OutputIndex) get(x string) (Step, expr.Node) {
@@ -871,6 +948,18 @@ func stepsEqual(a, b Step) bool {
 	}
 }
 
+// isSynthetic returns whether a table expression
+// produces a table that does not come from the environment
+func isSynthetic(e expr.Node) bool {
+	switch e := e.(type) {
+	case *expr.List:
+		return true
+	case *expr.Builtin:
+		return e.Func == expr.TableRange || e.Func == expr.TableSeries
+	}
+	return false
+}
+
 func (b *Trace) Begin(f *expr.Table, e Env) error {
 	it := &IterTable{Table: f}
 	it.definite = make(map[string]struct{})
@@ -879,7 +968,10 @@ func (b *Trace) Begin(f *expr.Table, e Env) error {
 	if f.Explicit() {
 		it.Bind = f.Result()
 	}
-	if e != nil {
+	// constant tables (i.e. VALUES) and generated
+	// tables (i.e. RANGE) do not have a schema
+	// or index in the environment
+	if !isSynthetic(f.Expr) && e != nil {
 		it.Schema = e.Schema(f.Expr)
 		idx, err := e.Index(f.Expr)
 		if err != nil {
@@ -956,6 +1048,15 @@ func (b *Trace) Where(e expr.Node) error {
 
 // Iterate pushes an implicit iteration to the stack
 func (b *Trace) Iterate(bind *expr.Binding) error {
+	var zip []expr.Binding
+	if u, ok := bind.Expr.(*expr.Unnest); ok {
+		// UNNEST(a, b, ...) AS (x, y, ...)
+		// iterates a and zips in b, ...
+		if len(u.Values) == 0 {
+			return errorf(u, "UNNEST expects at least one argument")
+		}
+		bind, zip = &u.Values[0], slices.Clone(u.Values[1:])
+	}
 	iv := &IterValue{Value: bind.Expr}
 	iv.Result = bind.Result()
 	// walk with the current scope
@@ -968,8 +1069,17 @@ func (b *Trace) Iterate(bind *expr.Binding) error {
 		return err
 	}
 	iv.Value = val
+	for i := range zip {
+		val, err := b.pathwalk(zip[i].Expr)
+		if err != nil {
+			return err
+		}
+		zip[i].Expr = val
+	}
+	iv.Zip = zip
 	b.cur = iv
 	b.final = append(b.final, *bind)
+	b.final = append(b.final, zip...)
 	return b.push()
 }
 
@@ -1097,6 +1207,28 @@ func (b *Trace) Aggregate(agg vm.Aggregation, groups []expr.Binding) error {
 					return fmt.Errorf("ORDER BY %s in window is not also bound outside the window", expr.ToString(wind.OrderBy[j].Column))
 				}
 			}
+			if inner := ag.Agg[i].Expr.Inner; ag.Agg[i].Expr.Windowed() && ag.Agg[i].Expr.Op.Cumulative() {
+				if _, ok := inner.(expr.Star); !ok && !isExisting(inner) {
+					return fmt.Errorf("window argument %s is not bound outside the window", expr.ToString(inner))
+				}
+			}
+			if filter := ag.Agg[i].Expr.Filter; filter != nil {
+				var unbound expr.Node
+				expr.Walk(expr.WalkFunc(func(e expr.Node) bool {
+					if unbound != nil || isExisting(e) {
+						return false
+					}
+					switch e.(type) {
+					case *expr.Aggregate, expr.Ident:
+						unbound = e
+						return false
+					}
+					return true
+				}), filter)
+				if unbound != nil {
+					return fmt.Errorf("window FILTER references %s, which is not bound outside the window", expr.ToString(unbound))
+				}
+			}
 		}
 	}
 	ag.complete = true
@@ -1192,12 +1324,18 @@ func (b *Trace) innerJoin(bind *expr.Binding, on expr.Node, env Env) error {
 
 // Into handles the INTO clause by pushing
 // the appropriate OutputIndex and OutputPart nodes.
-func (b *Trace) Into(table expr.Node, basepath string) {
-	op := &OutputPart{Basename: basepath}
+// If partitions is non-empty, the output is
+// partitioned by the values of those columns.
+// If appending is set, the output is appended
+// to the existing table rather than to a new table.
+func (b *Trace) Into(table expr.Node, basepath string, partitions []string, appending bool) {
+	op := &OutputPart{Basename: basepath, Partitions: partitions}
 	op.setparent(b.top)
 	oi := &OutputIndex{
-		Table:    table,
-		Basename: basepath,
+		Table:      table,
+		Basename:   basepath,
+		Partitions: partitions,
+		Append:     appending,
 	}
 	oi.setparent(op)
 	b.top = oi
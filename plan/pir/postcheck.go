@@ -20,6 +20,7 @@ import (
 
 var rules = []func(t *Trace) error{
 	checkSortSize,
+	checkTimeRange,
 }
 
 func checkAggregateWorkInProgress(e expr.Node) error {
@@ -35,7 +36,7 @@ func checkAggregateWorkInProgress(e expr.Node) error {
 		}
 		agg, ok := e.(*expr.Aggregate)
 		if ok {
-			if !agg.Op.WindowOnly() && agg.Over != nil {
+			if !agg.Windowed() && agg.Over != nil {
 				err = errorf(agg, "window function in unexpected position")
 				return false
 			}
@@ -19,6 +19,7 @@ import (
 	"github.com/SnellerInc/sneller/vm"
 
 	"golang.org/x/exp/maps"
+	"golang.org/x/exp/slices"
 )
 
 func deleteAt[T any](x []T, i int) []T {
@@ -146,7 +147,10 @@ loop:
 		case *IterTable:
 			s.trim(used)
 		case *IterValue:
-			if _, ok := used[s.Result]; !ok {
+			if !slices.ContainsFunc(s.results(), func(r string) bool {
+				_, ok := used[r]
+				return ok
+			}) {
 				// cross-join result isn't used
 				parent.setparent(s.parent())
 				continue loop
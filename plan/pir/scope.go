@@ -35,12 +35,20 @@ func (s *stepHint) TypeOf(e expr.Node) expr.TypeSet {
 		return expr.NoHint.TypeOf(e)
 	}
 	if orig, ok := origin.(*IterTable); ok {
+		if node != nil {
+			// p is bound to the table row itself
+			return expr.NoHint.TypeOf(e)
+		}
 		schema := orig.Schema
 		if schema == nil {
 			schema = expr.NoHint
 		}
 		return expr.TypeOf(e, schema)
 	}
+	if _, ok := origin.(*EquiJoin); ok && node != nil {
+		// p is bound to a row of the joined table
+		return expr.NoHint.TypeOf(e)
+	}
 	next := origin.parent()
 	if node == nil || next == nil {
 		return expr.NoHint.TypeOf(e)
@@ -0,0 +1,356 @@
+// Copyright (C) 2022 Sneller, Inc.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package pir
+
+import (
+	"math"
+	"strings"
+
+	"github.com/SnellerInc/sneller/expr"
+	"github.com/SnellerInc/sneller/ion"
+	"golang.org/x/exp/slices"
+)
+
+// default selectivities for predicates
+// for which we have no better information
+const (
+	selUnknown    = 0.5
+	selEquals     = 0.05
+	selRange      = 0.3
+	selLikePrefix = 0.1
+	selLike       = 0.25
+	selRegex      = 0.25
+	selIsNull     = 0.1
+)
+
+// relative per-row costs of evaluating
+// the different kinds of expressions
+const (
+	costSimple  = 1
+	costBuiltin = 4
+	costLike    = 4
+	costRegex   = 16
+)
+
+// unnestFanout is the assumed average
+// number of values produced by iterating
+// over an array with IterValue
+const unnestFanout = 4
+
+// estimate is the estimated selectivity
+// and per-row evaluation cost of a predicate
+type estimate struct {
+	sel  float64 // fraction of rows for which the predicate is TRUE
+	cost float64 // relative cost of evaluating the predicate
+}
+
+// rank orders predicates so that the cheapest
+// and most selective ones come first
+func (e estimate) rank() float64 {
+	return (e.sel - 1) / math.Max(e.cost, costSimple)
+}
+
+// estimator produces estimates for predicates;
+// if tbl is non-nil, the paths in the predicates
+// are the fields of tbl and its sparse index is
+// used to refine the estimates for time ranges
+type estimator struct {
+	tbl *IterTable
+}
+
+func (s *estimator) estimate(e expr.Node) estimate {
+	switch e := e.(type) {
+	case expr.Bool:
+		if e {
+			return estimate{sel: 1}
+		}
+		return estimate{sel: 0}
+	case *expr.Logical:
+		l := s.estimate(e.Left)
+		r := s.estimate(e.Right)
+		switch e.Op {
+		case expr.OpAnd:
+			return estimate{sel: l.sel * r.sel, cost: l.cost + l.sel*r.cost}
+		case expr.OpOr:
+			return estimate{sel: l.sel + r.sel - l.sel*r.sel, cost: l.cost + (1-l.sel)*r.cost}
+		}
+		return estimate{sel: selUnknown, cost: l.cost + r.cost}
+	case *expr.Not:
+		in := s.estimate(e.Expr)
+		return estimate{sel: 1 - in.sel, cost: in.cost}
+	case *expr.Comparison:
+		cost := costSimple + exprcost(e.Left) + exprcost(e.Right)
+		switch e.Op {
+		case expr.Equals:
+			return estimate{sel: s.timeRange(e, selEquals), cost: cost}
+		case expr.NotEquals:
+			return estimate{sel: 1 - selEquals, cost: cost}
+		case expr.Less, expr.LessEquals, expr.Greater, expr.GreaterEquals:
+			return estimate{sel: s.timeRange(e, selRange), cost: cost}
+		}
+		return estimate{sel: selUnknown, cost: cost}
+	case *expr.Member:
+		sel := math.Min(float64(e.Set.Len())*selEquals, 1)
+		return estimate{sel: sel, cost: 2*costSimple + exprcost(e.Arg)}
+	case *expr.StringMatch:
+		cost := exprcost(e.Expr)
+		switch e.Op {
+		case expr.Like, expr.Ilike:
+			return estimate{sel: likeSelectivity(e.Pattern), cost: cost + costLike}
+		}
+		return estimate{sel: selRegex, cost: cost + costRegex}
+	case *expr.IsKey:
+		cost := costSimple + exprcost(e.Expr)
+		switch e.Key {
+		case expr.IsNull, expr.IsMissing:
+			return estimate{sel: selIsNull, cost: cost}
+		case expr.IsNotNull, expr.IsNotMissing:
+			return estimate{sel: 1 - selIsNull, cost: cost}
+		}
+		return estimate{sel: selUnknown, cost: cost}
+	}
+	return estimate{sel: selUnknown, cost: math.Max(exprcost(e), costSimple)}
+}
+
+// likeSelectivity estimates the selectivity
+// of a LIKE pattern from its wildcards
+func likeSelectivity(pattern string) float64 {
+	i := strings.IndexAny(pattern, "%_")
+	switch {
+	case i == -1:
+		return selEquals
+	case i > 0 && i == len(pattern)-1 && pattern[i] == '%':
+		return selLikePrefix
+	}
+	return selLike
+}
+
+// timeRange refines the selectivity def of a comparison
+// of a path against a timestamp using the range of
+// times stored in the sparse index for the path
+// (or a comparison against a number using the range
+// of values of a sort key; see keyRange)
+func (s *estimator) timeRange(c *expr.Comparison, def float64) float64 {
+	if s.tbl == nil {
+		return def
+	}
+	p, ok := expr.FlatPath(c.Left)
+	if !ok {
+		return def
+	}
+	ts, ok := c.Right.(*expr.Timestamp)
+	if !ok {
+		return s.keyRange(p, c, def)
+	}
+	lo, hi, ok := s.tbl.timeRange(p)
+	if !ok {
+		return def
+	}
+	min, max, t := lo.UnixMicro(), hi.UnixMicro(), ts.Value.UnixMicro()
+	if t < min || t > max {
+		switch c.Op {
+		case expr.Less, expr.LessEquals:
+			if t > max {
+				return 1
+			}
+		case expr.Greater, expr.GreaterEquals:
+			if t < min {
+				return 1
+			}
+		}
+		return 0
+	}
+	span := float64(max - min + 1)
+	switch c.Op {
+	case expr.Less, expr.LessEquals:
+		return float64(t-min+1) / span
+	case expr.Greater, expr.GreaterEquals:
+		return float64(max-t+1) / span
+	}
+	return def
+}
+
+// keyRange refines the selectivity def of a comparison
+// of the path p against a number using the range of
+// values of p if p is a sort key of the table
+func (s *estimator) keyRange(p []string, c *expr.Comparison, def float64) float64 {
+	var t float64
+	switch n := c.Right.(type) {
+	case expr.Integer:
+		t = float64(n)
+	case expr.Float:
+		t = float64(n)
+	default:
+		return def
+	}
+	lo, hi, ok := s.tbl.keyRange(p)
+	if !ok {
+		return def
+	}
+	min, ok := number(lo)
+	if !ok {
+		return def
+	}
+	max, _ := number(hi)
+	if t < min || t > max {
+		switch c.Op {
+		case expr.Less, expr.LessEquals:
+			if t > max {
+				return 1
+			}
+		case expr.Greater, expr.GreaterEquals:
+			if t < min {
+				return 1
+			}
+		}
+		return 0
+	}
+	span := max - min
+	if span <= 0 {
+		return def
+	}
+	switch c.Op {
+	case expr.Less, expr.LessEquals:
+		return (t - min) / span
+	case expr.Greater, expr.GreaterEquals:
+		return (max - t) / span
+	}
+	return def
+}
+
+// number returns the value of a numeric datum
+func number(d ion.Datum) (float64, bool) {
+	switch d.Type() {
+	case ion.IntType:
+		i, _ := d.Int()
+		return float64(i), true
+	case ion.UintType:
+		u, _ := d.Uint()
+		return float64(u), true
+	case ion.FloatType:
+		f, _ := d.Float()
+		return f, true
+	}
+	return 0, false
+}
+
+// exprcost estimates the per-row
+// cost of evaluating e
+func exprcost(e expr.Node) float64 {
+	cost := 0.0
+	visit := expr.WalkFunc(func(e expr.Node) bool {
+		switch e := e.(type) {
+		case *expr.Builtin:
+			cost += costBuiltin
+		case *expr.StringMatch:
+			switch e.Op {
+			case expr.Like, expr.Ilike:
+				cost += costLike
+			default:
+				cost += costRegex
+			}
+		case *expr.Arithmetic, *expr.Comparison, *expr.Logical,
+			*expr.Not, *expr.IsKey, *expr.Case, *expr.Member:
+			cost += costSimple
+		}
+		return true
+	})
+	expr.Walk(visit, e)
+	return cost
+}
+
+// reorderconj sorts the conjunctions in e
+// so that the predicates that are cheapest
+// to evaluate and eliminate the most rows
+// are evaluated first
+func (s *estimator) reorderconj(e expr.Node) expr.Node {
+	conj := conjunctions(e, nil)
+	if len(conj) < 2 {
+		return e
+	}
+	type ranked struct {
+		node expr.Node
+		pos  int
+		rank float64
+	}
+	// conjunctions returns the terms in reverse
+	lst := make([]ranked, len(conj))
+	for i := range conj {
+		c := conj[len(conj)-1-i]
+		lst[i] = ranked{node: c, pos: i, rank: s.estimate(c).rank()}
+	}
+	slices.SortStableFunc(lst, func(x, y ranked) bool {
+		return x.rank < y.rank
+	})
+	if slices.IsSortedFunc(lst, func(x, y ranked) bool { return x.pos < y.pos }) {
+		return e
+	}
+	out := lst[0].node
+	for _, c := range lst[1:] {
+		out = expr.And(out, c.node)
+	}
+	return out
+}
+
+// tableOf returns the table that produces
+// the rows at s if s is an IterTable or a
+// chain of Filter steps above an IterTable
+func tableOf(s Step) *IterTable {
+	for {
+		switch t := s.(type) {
+		case *IterTable:
+			return t
+		case *Filter:
+			s = t.parent()
+		default:
+			return nil
+		}
+	}
+}
+
+// reorderfilters sorts the conjunctions in
+// the filters in b by their estimated cost
+// and selectivity
+func reorderfilters(b *Trace) {
+	for s := b.top; s != nil; s = s.parent() {
+		switch s := s.(type) {
+		case *Filter:
+			est := estimator{tbl: tableOf(s.parent())}
+			s.Where = est.reorderconj(s.Where)
+		case *IterTable:
+			if s.Filter != nil {
+				est := estimator{tbl: s}
+				s.Filter = est.reorderconj(s.Filter)
+			}
+		}
+	}
+}
+
+// hoistprofitable returns whether a predicate inv
+// that does not depend on the iterated value should
+// be evaluated ahead of an IterValue rather than
+// after it alongside the predicate inner that does
+// depend on the iterated value; predicates that
+// are no more expensive than inner are always
+// hoisted so that they remain visible to the
+// sparse index
+func hoistprofitable(inv, inner estimate) bool {
+	if inv.cost <= inner.cost {
+		return true
+	}
+	before := inv.cost + inv.sel*unnestFanout*inner.cost
+	after := unnestFanout * (inner.cost + inner.sel*inv.cost)
+	return before <= after
+}
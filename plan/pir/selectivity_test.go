@@ -0,0 +1,134 @@
+// Copyright (C) 2022 Sneller, Inc.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package pir
+
+import (
+	"math"
+	"testing"
+
+	"github.com/SnellerInc/sneller/date"
+	"github.com/SnellerInc/sneller/expr"
+	"github.com/SnellerInc/sneller/expr/partiql"
+	"github.com/SnellerInc/sneller/ion"
+	"golang.org/x/exp/slices"
+)
+
+func TestSelectivityOrder(t *testing.T) {
+	// each predicate should rank strictly
+	// ahead of the one that follows it
+	preds := []string{
+		"x = 1",
+		"x < 3",
+		"x IN (1, 2, 3)",
+		"x LIKE 'foo%'",
+		"x LIKE '%foo%'",
+		"x IS NOT NULL",
+		"x <> 3",
+		"x ~ 'a.*b'",
+	}
+	var est estimator
+	prev := -1.0
+	for i := range preds {
+		q, err := partiql.Parse([]byte("SELECT * FROM foo WHERE " + preds[i]))
+		if err != nil {
+			t.Fatal(err)
+		}
+		where := expr.Simplify(q.Body.(*expr.Select).Where, expr.NoHint)
+		rank := est.estimate(where).rank()
+		if i > 0 && rank <= prev {
+			t.Errorf("%s: rank %g not above %s: %g", preds[i], rank, preds[i-1], prev)
+		}
+		prev = rank
+	}
+}
+
+func TestLikeSelectivity(t *testing.T) {
+	cases := []struct {
+		pattern string
+		want    float64
+	}{
+		{"foo", selEquals},
+		{"foo%", selLikePrefix},
+		{"%foo", selLike},
+		{"f_o%", selLike},
+		{"%", selLike},
+	}
+	for i := range cases {
+		if got := likeSelectivity(cases[i].pattern); got != cases[i].want {
+			t.Errorf("%q: got %g, want %g", cases[i].pattern, got, cases[i].want)
+		}
+	}
+}
+
+func TestHoistProfitable(t *testing.T) {
+	cheap := estimate{sel: 0.9, cost: costSimple}
+	regex := estimate{sel: selRegex, cost: costRegex}
+	eq := estimate{sel: selEquals, cost: costSimple}
+	if !hoistprofitable(cheap, regex) {
+		t.Error("a cheap predicate should always be hoisted")
+	}
+	if hoistprofitable(regex, eq) {
+		t.Error("an expensive predicate should follow a cheap, selective one")
+	}
+	if !hoistprofitable(regex, estimate{sel: 1, cost: 2 * costSimple}) {
+		t.Error("an expensive predicate should precede a non-selective one")
+	}
+}
+
+// keyindex is an Index with a single
+// sort key x in the range [min, max]
+type keyindex struct {
+	min, max ion.Datum
+}
+
+func (k keyindex) TimeRange([]string) (min, max date.Time, ok bool) { return }
+func (k keyindex) HasPartition(string) bool                         { return false }
+
+func (k keyindex) KeyRange(path []string) (min, max ion.Datum, ok bool) {
+	if slices.Equal(path, []string{"x"}) {
+		return k.min, k.max, true
+	}
+	return ion.Empty, ion.Empty, false
+}
+
+func TestKeyRangeSelectivity(t *testing.T) {
+	est := estimator{
+		tbl: &IterTable{Index: keyindex{min: ion.Int(0), max: ion.Float(100)}},
+	}
+	cases := []struct {
+		pred string
+		want float64
+	}{
+		{"x < 25", 0.25},
+		{"x >= 75", 0.25},
+		{"x > 200", 0},
+		{"x < 200", 1},
+		{"x = -1", 0},
+		{"x = 50", selEquals},
+		{"y < 25", selRange},
+		{"x < 'foo'", selRange},
+	}
+	for i := range cases {
+		q, err := partiql.Parse([]byte("SELECT * FROM foo WHERE " + cases[i].pred))
+		if err != nil {
+			t.Fatal(err)
+		}
+		where := expr.Simplify(q.Body.(*expr.Select).Where, expr.NoHint)
+		got := est.estimate(where).sel
+		if math.Abs(got-cases[i].want) > 1e-9 {
+			t.Errorf("%s: got selectivity %g, want %g", cases[i].pred, got, cases[i].want)
+		}
+	}
+}
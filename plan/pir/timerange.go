@@ -0,0 +1,110 @@
+// Copyright (C) 2022 Sneller, Inc.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package pir
+
+import (
+	"github.com/SnellerInc/sneller/expr"
+)
+
+func isTimeRange(e expr.Node) bool {
+	b, ok := e.(*expr.Builtin)
+	return ok && b.Func == expr.TimeRange
+}
+
+// timerangeelim replaces a query of the form
+//
+//	SELECT TIME_RANGE(path), ... FROM table WHERE filter
+//
+// with a single row of constants describing
+// the index metadata for the table, so that
+// users can inspect the time ranges and the
+// number of blocks the query planner would
+// use for the filter without running a query
+func timerangeelim(b *Trace) error {
+	bi, ok := b.top.(*Bind)
+	if !ok {
+		return nil
+	}
+	tbl, ok := bi.parent().(*IterTable)
+	if !ok {
+		return nil
+	}
+	for i := range bi.bind {
+		if !isTimeRange(bi.bind[i].Expr) {
+			return nil
+		}
+	}
+	if tbl.Index == nil {
+		return errorf(bi.bind[0].Expr, "TIME_RANGE: no index available for table %s", expr.ToString(tbl.Table))
+	}
+	var blocks []expr.Field
+	if bc, ok := tbl.Index.(BlockCounter); ok {
+		matching, total, err := bc.Blocks(tbl.Filter)
+		if err != nil {
+			return err
+		}
+		blocks = []expr.Field{
+			{Label: "blocks", Value: expr.Integer(matching)},
+			{Label: "total_blocks", Value: expr.Integer(total)},
+		}
+	}
+	out := &Bind{}
+	out.bind = make([]expr.Binding, len(bi.bind))
+	for i := range bi.bind {
+		call := bi.bind[i].Expr.(*expr.Builtin)
+		p, _ := expr.FlatPath(call.Args[0])
+		var fields []expr.Field
+		if min, max, ok := tbl.timeRange(p); ok {
+			fields = append(fields,
+				expr.Field{Label: "min", Value: &expr.Timestamp{Value: min}},
+				expr.Field{Label: "max", Value: &expr.Timestamp{Value: max}})
+		}
+		fields = append(fields, blocks...)
+		c := &expr.Struct{Fields: fields}
+		for j := range b.final {
+			if b.final[j].Expr == call {
+				b.final[j].Expr = c
+			}
+		}
+		out.bind[i] = expr.Bind(c, bi.bind[i].Result())
+	}
+	out.setparent(DummyOutput{})
+	b.top = out
+	return nil
+}
+
+// checkTimeRange rejects any TIME_RANGE
+// expressions that were not eliminated
+// by timerangeelim
+func checkTimeRange(t *Trace) error {
+	var err error
+	check := expr.WalkFunc(func(e expr.Node) bool {
+		if err != nil {
+			return false
+		}
+		if isTimeRange(e) {
+			err = errorf(e, "TIME_RANGE may only be used in the projection of a SELECT from a single table")
+			return false
+		}
+		return true
+	})
+	for s := t.top; s != nil; s = s.parent() {
+		s.rewrite(func(e expr.Node, _ bool) expr.Node {
+			expr.Walk(check, e)
+			return e
+		})
+	}
+	return err
+}
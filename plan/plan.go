@@ -104,6 +104,61 @@ type TableHandle interface {
 	Encode(dst *ion.Buffer, st *ion.Symtab) error
 }
 
+// TableStats are estimated statistics
+// about the rows in a table.
+type TableStats struct {
+	// Rows is the estimated number of rows.
+	Rows int64
+	// Bytes is the estimated total size
+	// of the rows in bytes (as uncompressed ion).
+	Bytes int64
+}
+
+// AvgRowSize returns the estimated
+// average size of a row in bytes, or 0
+// if the table has no rows.
+func (t *TableStats) AvgRowSize() int64 {
+	if t.Rows == 0 {
+		return 0
+	}
+	return t.Bytes / t.Rows
+}
+
+// StatsHandle is a TableHandle that
+// can estimate statistics about its rows
+// beyond its size in bytes.
+type StatsHandle interface {
+	TableHandle
+	// Stats returns the estimated statistics
+	// for the table, or false if they are not
+	// available.
+	Stats() (TableStats, bool)
+}
+
+// Stats returns the estimated statistics
+// for the table h, or false if h does not
+// implement StatsHandle or cannot produce
+// an estimate.
+func Stats(h TableHandle) (TableStats, bool) {
+	if sh, ok := h.(StatsHandle); ok {
+		return sh.Stats()
+	}
+	return TableStats{}, false
+}
+
+// CountHandle is a TableHandle that can
+// count the rows matching a filter from
+// its metadata without scanning any data.
+type CountHandle interface {
+	TableHandle
+	// Count returns the exact number of rows
+	// that satisfy the filter e (or all of the
+	// rows if e is nil), or false if the number
+	// of rows cannot be determined without
+	// scanning the table.
+	Count(e expr.Node) (int64, bool)
+}
+
 // SplitHandle is a TableHandle that can be split.
 type SplitHandle interface {
 	TableHandle
@@ -158,7 +213,7 @@ type Env interface {
 
 // stat handles calling env.Stat(tbl, flt), with
 // special handling for certain table expressions
-// (TABLE_GLOB, TABLE_PATTERN, ++ operator).
+// (TABLE_GLOB, TABLE_PATTERN, RANGE, SERIES, ++ operator).
 func stat(env Env, tbl expr.Node, h *Hints) (TableHandle, error) {
 	switch e := tbl.(type) {
 	case *expr.Appended:
@@ -171,6 +226,9 @@ func stat(env Env, tbl expr.Node, h *Hints) (TableHandle, error) {
 			ths[i] = th
 		}
 		return ths, nil
+	case *expr.List:
+		// constant table (i.e. VALUES)
+		return NewValuesHandle(e)
 	case *expr.Builtin:
 		switch e.Func {
 		case expr.TableGlob, expr.TablePattern:
@@ -179,6 +237,9 @@ func stat(env Env, tbl expr.Node, h *Hints) (TableHandle, error) {
 				return nil, fmt.Errorf("listing not supported")
 			}
 			return statGlob(tl, env, e, h)
+		case expr.TableRange, expr.TableSeries:
+			// generated table
+			return NewSeriesHandle(e)
 		}
 	}
 	return env.Stat(tbl, h)
@@ -208,6 +269,16 @@ type Indexer interface {
 // optimization.
 type Index = pir.Index
 
+// A BlockCounter may optionally be implemented
+// by an Index to report the number of blocks in
+// a table that a filter would select.
+type BlockCounter = pir.BlockCounter
+
+// A KeyRanger may optionally be implemented
+// by an Index to report the range of values
+// of a sort key in a table.
+type KeyRanger = pir.KeyRanger
+
 // index calls idx.Index(tbl), with special handling
 // for certain table expressions.
 func index(idx Indexer, tbl expr.Node) (Index, error) {
@@ -312,13 +383,13 @@ func (s *SimpleAggregate) String() string {
 }
 
 func (s *SimpleAggregate) exec(dst vm.QuerySink, src TableHandle, ep *ExecParams) error {
-	var sysagg expr.AggregateOp
+	var sysagg *expr.Aggregate
 	system := 0
 	regular := 0
 	for i := range s.Outputs {
 		switch op := s.Outputs[i].Expr.Op; op {
 		case expr.OpSystemDatashape, expr.OpSystemDatashapeMerge:
-			sysagg = op
+			sysagg = s.Outputs[i].Expr
 			system += 1
 		default:
 			regular += 1
@@ -334,8 +405,14 @@ func (s *SimpleAggregate) exec(dst vm.QuerySink, src TableHandle, ep *ExecParams
 			return fmt.Errorf("using more than one system aggregate is not supported")
 		}
 
-		switch sysagg {
+		switch sysagg.Op {
 		case expr.OpSystemDatashape:
+			switch sysagg.Datashape {
+			case expr.DatashapeHistograms:
+				return s.From.exec(vm.NewSystemDatashapeHistograms(dst, false), src, ep)
+			case expr.DatashapeHistogramsPartial:
+				return s.From.exec(vm.NewSystemDatashapeHistograms(dst, true), src, ep)
+			}
 			return s.From.exec(vm.NewSystemDatashape(dst), src, ep)
 
 		case expr.OpSystemDatashapeMerge:
@@ -455,12 +532,15 @@ func (l *Leaf) exec(dst vm.QuerySink, src TableHandle, ep *ExecParams) error {
 	if err != nil {
 		return err
 	}
-	err = tbl.WriteChunks(dst, ep.Parallel)
-	ep.Stats.observe(tbl)
+	err = tbl.WriteChunks(vm.WithAccount(dst, ep.Memory), ep.Parallel)
+	scanned := ep.Stats.observe(tbl)
 	err2 := dst.Close()
 	if err == nil {
 		err = err2
 	}
+	if err3 := ep.budget.charge(scanned); err3 != nil {
+		err = err3
+	}
 	if errors.Is(err, io.EOF) {
 		err = nil
 	}
@@ -472,11 +552,11 @@ func (l *Leaf) encode(dst *ion.Buffer, st *ion.Symtab, rw expr.Rewriter) error {
 	settype("leaf", dst, st)
 	if l.Orig != nil {
 		dst.BeginField(st.Intern("orig"))
-		expr.Rewrite(rw, l.Orig).Encode(dst, st)
+		encodeExpr(l.Orig, dst, st, rw)
 	}
 	if l.Filter != nil {
 		dst.BeginField(st.Intern("filter"))
-		expr.Rewrite(rw, l.Filter).Encode(dst, st)
+		encodeExpr(l.Filter, dst, st, rw)
 	}
 	if len(l.OnEqual) > 0 {
 		dst.BeginField(st.Intern("on_equal"))
@@ -488,7 +568,7 @@ func (l *Leaf) encode(dst *ion.Buffer, st *ion.Symtab, rw expr.Rewriter) error {
 		dst.BeginField(st.Intern("equal_expr"))
 		dst.BeginList(-1)
 		for i := range l.EqualExpr {
-			expr.Rewrite(rw, l.EqualExpr[i]).Encode(dst, st)
+			encodeExpr(l.EqualExpr[i], dst, st, rw)
 		}
 		dst.EndList()
 	}
@@ -499,13 +579,13 @@ func (l *Leaf) encode(dst *ion.Buffer, st *ion.Symtab, rw expr.Rewriter) error {
 func (l *Leaf) setfield(d Decoder, f ion.Field) error {
 	switch f.Label {
 	case "orig":
-		n, err := expr.Decode(f.Datum)
+		n, err := decodeExpr(d, f.Datum)
 		if err != nil {
 			return err
 		}
 		l.Orig = n.(*expr.Table)
 	case "filter":
-		f, err := expr.Decode(f.Datum)
+		f, err := decodeExpr(d, f.Datum)
 		if err != nil {
 			return err
 		}
@@ -520,8 +600,8 @@ func (l *Leaf) setfield(d Decoder, f ion.Field) error {
 			return nil
 		})
 	case "equal_expr":
-		return f.Datum.UnpackList(func(d ion.Datum) error {
-			e, err := expr.Decode(d)
+		return f.Datum.UnpackList(func(v ion.Datum) error {
+			e, err := decodeExpr(d, v)
 			if err != nil {
 				return err
 			}
@@ -737,6 +817,61 @@ func (c *CountStar) setfield(d Decoder, f ion.Field) error {
 	return nil
 }
 
+// IndexCount is the result of COUNT(*)
+// computed from the metadata of the input
+// table (see CountHandle) at planning time.
+type IndexCount struct {
+	As    string // output count name
+	Count int64  // number of matching rows
+}
+
+func (c *IndexCount) rewrite(rw expr.Rewriter) {}
+func (c *IndexCount) input() Op                { return nil }
+func (c *IndexCount) setinput(o Op) {
+	panic("IndexCount: cannot setinput()")
+}
+
+func (c *IndexCount) String() string {
+	return fmt.Sprintf("INDEX COUNT(*) = %d AS %s", c.Count, c.As)
+}
+
+func (c *IndexCount) exec(dst vm.QuerySink, src TableHandle, ep *ExecParams) error {
+	var b ion.Buffer
+	var st ion.Symtab
+
+	field := st.Intern(c.As)
+	st.Marshal(&b, true)
+	b.BeginStruct(-1)
+	b.BeginField(field)
+	b.WriteInt(c.Count)
+	b.EndStruct()
+	return writeIon(&b, dst)
+}
+
+func (c *IndexCount) encode(dst *ion.Buffer, st *ion.Symtab, rw expr.Rewriter) error {
+	dst.BeginStruct(-1)
+	settype("indexcount", dst, st)
+	dst.BeginField(st.Intern("as"))
+	dst.WriteString(c.As)
+	dst.BeginField(st.Intern("count"))
+	dst.WriteInt(c.Count)
+	dst.EndStruct()
+	return nil
+}
+
+func (c *IndexCount) setfield(d Decoder, f ion.Field) error {
+	var err error
+	switch f.Label {
+	case "as":
+		c.As, err = f.String()
+	case "count":
+		c.Count, err = f.Int()
+	default:
+		return errUnexpectedField
+	}
+	return err
+}
+
 type HashAggregate struct {
 	Nonterminal
 	Agg     vm.Aggregation
@@ -1261,17 +1396,25 @@ func (t *Tree) Encode(dst *ion.Buffer, st *ion.Symtab) error {
 }
 
 func (t *Tree) encode(dst *ion.Buffer, st *ion.Symtab, rw expr.Rewriter) error {
+	enc := newEncoder(rw)
 	dst.BeginStruct(-1)
 	dst.BeginField(st.Intern("inputs"))
 	dst.BeginList(-1)
 	for i := range t.Inputs {
-		t.Inputs[i].encode(dst, st)
+		if err := t.Inputs[i].encode(dst, st, enc); err != nil {
+			return err
+		}
 	}
 	dst.EndList()
 	dst.BeginField(st.Intern("root"))
-	if err := t.Root.encode(dst, st, rw); err != nil {
+	if err := t.Root.encode(dst, st, enc); err != nil {
 		return err
 	}
+	if t.MaxScan > 0 {
+		dst.BeginField(st.Intern("max_scan"))
+		dst.WriteInt(t.MaxScan)
+	}
+	enc.finish(dst, st)
 	dst.EndStruct()
 	return nil
 }
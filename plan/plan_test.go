@@ -15,7 +15,10 @@
 package plan
 
 import (
+	"bufio"
+	"bytes"
 	"context"
+	"strings"
 	"testing"
 
 	"github.com/SnellerInc/sneller/expr"
@@ -60,6 +63,43 @@ func TestDistribute(t *testing.T) {
 	run([]int64{102478}, 8, []int{8})
 }
 
+// rowsHandle is a sizeHandle with
+// an estimated number of rows
+type rowsHandle struct {
+	sizeHandle
+	rows int64
+}
+
+func (r rowsHandle) Stats() (TableStats, bool) {
+	return TableStats{Rows: r.rows, Bytes: int64(r.sizeHandle)}, true
+}
+
+func TestDistributeRows(t *testing.T) {
+	// parts with many small rows get
+	// more parallelism than parts with
+	// the same number of bytes in large rows
+	parts := []TablePart{
+		{Handle: rowsHandle{sizeHandle: 300, rows: 10}},
+		{Handle: rowsHandle{sizeHandle: 300, rows: 20}},
+	}
+	if got := distribute(parts, 3); !slices.Equal(got, []int{1, 2}) {
+		t.Errorf("got %v", got)
+	}
+	st, ok := Stats(tableHandles{parts[0].Handle, parts[1].Handle})
+	if !ok || st.Rows != 30 || st.Bytes != 600 || st.AvgRowSize() != 20 {
+		t.Errorf("got stats %+v, %v", st, ok)
+	}
+	// without stats for every part,
+	// sizes in bytes are used instead
+	parts = append(parts, TablePart{Handle: sizeHandle(300)})
+	if got := distribute(parts, 3); !slices.Equal(got, []int{1, 1, 1}) {
+		t.Errorf("got %v", got)
+	}
+	if _, ok := Stats(tableHandles{parts[0].Handle, parts[2].Handle}); ok {
+		t.Error("unexpected stats")
+	}
+}
+
 func TestNewSplit(t *testing.T) {
 	testcases := []struct {
 		name  string
@@ -105,3 +145,117 @@ func (f testHandle) Open(_ context.Context) (vm.Table, error) {
 func (f testEnv) Stat(_ expr.Node, _ *Hints) (TableHandle, error) {
 	return testHandle{}, nil
 }
+
+// countHandle is a sizeHandle that can count
+// the rows selected by timestamp comparisons
+type countHandle struct {
+	sizeHandle
+	rows int64
+}
+
+func (c countHandle) Count(e expr.Node) (int64, bool) {
+	if e == nil {
+		return c.rows, true
+	}
+	cmp, ok := e.(*expr.Comparison)
+	if !ok {
+		return 0, false
+	}
+	_, ok = cmp.Right.(*expr.Timestamp)
+	return c.rows / 2, ok
+}
+
+func (c countHandle) Encode(dst *ion.Buffer, st *ion.Symtab) error {
+	dst.WriteInt(c.rows)
+	return nil
+}
+
+func (c countHandle) Split() (Subtables, error) {
+	return SubtableList{
+		{Transport: &LocalTransport{}, Handle: c},
+		{Transport: &LocalTransport{}, Handle: c},
+	}, nil
+}
+
+type countenv struct {
+	handle countHandle
+}
+
+func (c countenv) Stat(_ expr.Node, _ *Hints) (TableHandle, error) {
+	return c.handle, nil
+}
+
+func (c countenv) DecodeHandle(d ion.Datum) (TableHandle, error) {
+	return c.handle, nil
+}
+
+func TestCountFromIndex(t *testing.T) {
+	env := countenv{handle: countHandle{sizeHandle: 1000, rows: 84}}
+	tcs := []struct {
+		query string
+		want  string // expected output; empty if not eligible
+	}{
+		{`SELECT COUNT(*) FROM t`, `{"count": 84}`},
+		{`SELECT COUNT(*) AS n FROM t WHERE ts < ` + "`2023-01-01T00:00:00Z`", `{"n": 42}`},
+		{`SELECT COUNT(*) FROM t WHERE x = 1`, ``},
+		{`SELECT COUNT(x) FROM t`, ``},
+		{`SELECT x, COUNT(*) FROM t GROUP BY x`, ``},
+	}
+	for i := range tcs {
+		for _, split := range []bool{false, true} {
+			q, err := partiql.Parse([]byte(tcs[i].query))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var tree *Tree
+			if split {
+				tree, err = NewSplit(q, env)
+			} else {
+				tree, err = New(q, env)
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			var ic *IndexCount
+			for op := tree.Root.Op; op != nil; op = op.input() {
+				if c, ok := op.(*IndexCount); ok {
+					ic = c
+				}
+			}
+			if tcs[i].want == "" {
+				if ic != nil {
+					t.Errorf("%s: unexpected index count in\n%s", tcs[i].query, tree)
+				}
+				continue
+			}
+			if ic == nil {
+				t.Fatalf("%s: no index count in\n%s", tcs[i].query, tree)
+			}
+			if tree.MaxScanned() != 0 {
+				t.Errorf("%s: MaxScanned = %d", tcs[i].query, tree.MaxScanned())
+			}
+			// the count must survive serialization
+			var buf ion.Buffer
+			var st ion.Symtab
+			if err := tree.Encode(&buf, &st); err != nil {
+				t.Fatal(err)
+			}
+			tree, err = Decode(env, &st, buf.Bytes())
+			if err != nil {
+				t.Fatal(err)
+			}
+			var out bytes.Buffer
+			var stats ExecStats
+			if err := Exec(tree, &out, &stats); err != nil {
+				t.Fatal(err)
+			}
+			var got strings.Builder
+			if _, err := ion.ToJSON(&got, bufio.NewReader(&out)); err != nil {
+				t.Fatal(err)
+			}
+			if s := strings.TrimSpace(got.String()); s != tcs[i].want {
+				t.Errorf("%s: got %s; want %s", tcs[i].query, s, tcs[i].want)
+			}
+		}
+	}
+}
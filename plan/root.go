@@ -16,8 +16,10 @@ package plan
 
 import (
 	"context"
+	"errors"
 	"io"
 	"runtime"
+	"time"
 
 	"github.com/SnellerInc/sneller/expr"
 	"github.com/SnellerInc/sneller/ion"
@@ -38,6 +40,39 @@ type ExecParams struct {
 	// of plan execution. If Parallel is unset, then
 	// runtime.GOMAXPROCS(0) is used instead.
 	Parallel int
+	// Substitutions is the maximum number of
+	// replacement sub-queries (see Substitute)
+	// that are executed concurrently. If
+	// Substitutions is unset, then Parallel
+	// is used instead.
+	Substitutions int
+	// Retries is the maximum number of times
+	// that a failed sub-query of a split plan
+	// is re-dispatched to a different peer
+	// (see Retrier). If Retries is unset, then
+	// DefaultRetries is used instead; a negative
+	// value disables retries.
+	Retries int
+	// SubqueryTimeout is the maximum amount of
+	// time that each sub-query of a split plan
+	// may run before it is canceled (and possibly
+	// retried). If SubqueryTimeout is unset, then
+	// DefaultSubqueryTimeout is used instead; a
+	// negative value disables the timeout.
+	SubqueryTimeout time.Duration
+	// Speculation, if positive, enables speculative
+	// re-execution of straggling sub-queries of a
+	// split plan: once half of the sub-queries
+	// have completed, a sub-query that has run
+	// for longer than Speculation times the median
+	// completion time is also dispatched to another
+	// peer, and the output of whichever copy finishes
+	// first is used (see Retrier). Since the output of
+	// only one copy may be used, the output of each
+	// sub-query is buffered until it completes or
+	// until it exceeds a fixed size, at which point
+	// that copy is used and the other is abandoned.
+	Speculation float64
 	// Rewriter is a rewrite that should be applied
 	// to each expression in the query plan before
 	// the query begins execution.
@@ -46,8 +81,15 @@ type ExecParams struct {
 	// of the query. Transports are expected to
 	// stop processing queries after Context is canceled.
 	Context context.Context
+	// Memory, if non-nil, is charged for the vm
+	// memory used to scan the query inputs
+	// (see vm.WithAccount). If Memory.Limit is set,
+	// the query fails with vm.ErrOutOfMemory once
+	// the limit is reached.
+	Memory *vm.Account
 
-	get func(i int) TableHandle
+	get    func(i int) TableHandle
+	budget *scanBudget // see Tree.MaxScan
 }
 
 type multiRewriter struct {
@@ -141,11 +183,17 @@ func (ep *ExecParams) rewriteBind(lst []expr.Binding) []expr.Binding {
 // clone everything except ep.Stats
 func (ep *ExecParams) clone() *ExecParams {
 	return &ExecParams{
-		Output:   ep.Output,
-		Parallel: ep.Parallel,
-		Context:  ep.Context,
-		Rewriter: ep.Rewriter,
-		get:      ep.get,
+		Output:          ep.Output,
+		Parallel:        ep.Parallel,
+		Substitutions:   ep.Substitutions,
+		Retries:         ep.Retries,
+		SubqueryTimeout: ep.SubqueryTimeout,
+		Speculation:     ep.Speculation,
+		Context:         ep.Context,
+		Memory:          ep.Memory,
+		Rewriter:        ep.Rewriter,
+		get:             ep.get,
+		budget:          ep.budget,
 	}
 }
 
@@ -183,7 +231,23 @@ func (l *LocalTransport) Encode(dst *ion.Buffer, st *ion.Symtab) {
 }
 
 // Exec implements Transport.Exec
-func (l *LocalTransport) Exec(t *Tree, ep *ExecParams) error {
+//
+// A panic during query execution is returned
+// as a *vm.PanicError, and the panic diagnostics
+// are logged via vm.Errorf. Queries aborted by
+// the vm watchdog (see vm.SetWatchdog) also have
+// their (redacted) plan logged via vm.Errorf.
+func (l *LocalTransport) Exec(t *Tree, ep *ExecParams) (err error) {
+	defer func() {
+		var pe *vm.PanicError
+		var we *vm.WatchdogError
+		if errors.As(err, &pe) {
+			crashlog(t, pe)
+		} else if errors.As(err, &we) {
+			watchdoglog(t, we)
+		}
+	}()
+	defer vm.HandlePanic(&err)
 	s := vm.LockedSink(ep.Output)
 	if ep.Parallel == 0 {
 		ep.Parallel = l.Threads
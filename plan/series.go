@@ -0,0 +1,283 @@
+// Copyright (C) 2022 Sneller, Inc.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package plan
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync/atomic"
+
+	"github.com/SnellerInc/sneller/date"
+	"github.com/SnellerInc/sneller/expr"
+	"github.com/SnellerInc/sneller/ion"
+	"github.com/SnellerInc/sneller/vm"
+)
+
+// SeriesHandle is the TableHandle for a
+// generated table, such as the one produced
+// by RANGE or SERIES. Each row of the table
+// is a structure with a single field.
+// The rows are generated while the table is
+// scanned, so the handle is small regardless
+// of the number of rows in the table.
+type SeriesHandle struct {
+	// Field is the name of the field
+	// that holds the value of each row.
+	Field string
+	// Start is the value of the first row.
+	Start int64
+	// Step is the difference between
+	// the values of consecutive rows.
+	Step int64
+	// Count is the number of rows.
+	Count int64
+	// Time indicates that the values are
+	// timestamps, in which case Start and Step
+	// are expressed in microseconds.
+	Time bool
+}
+
+var _ TableHandle = (*SeriesHandle)(nil)
+
+// seriesBatch is the number of rows
+// generated by one writer at a time
+const seriesBatch = 64 * 1024
+
+// NewSeriesHandle creates a SeriesHandle from
+// a call to RANGE(start, stop[, step]), which
+// produces the integers from start up to but
+// not including stop, or from a call to
+// SERIES(start, stop, step), which produces the
+// integers or timestamps from start up to and
+// including stop. The step of a timestamp
+// series is given in seconds. The value of each row
+// is stored in a field named after the function
+// (i.e. "range" or "series").
+func NewSeriesHandle(b *expr.Builtin) (*SeriesHandle, error) {
+	name := b.Func.String()
+	args := make([]expr.Node, len(b.Args))
+	for i := range b.Args {
+		args[i] = expr.Simplify(b.Args[i], expr.NoHint)
+	}
+	var start, stop int64
+	step := int64(1)
+	sh := &SeriesHandle{Field: strings.ToLower(name)}
+	switch b.Func {
+	case expr.TableRange:
+		if len(args) != 2 && len(args) != 3 {
+			return nil, fmt.Errorf("%s expects two or three arguments, but found %d", name, len(args))
+		}
+	case expr.TableSeries:
+		if len(args) != 3 {
+			return nil, fmt.Errorf("%s expects three arguments, but found %d", name, len(args))
+		}
+		if ts, ok := args[0].(*expr.Timestamp); ok {
+			te, ok := args[1].(*expr.Timestamp)
+			if !ok {
+				return nil, fmt.Errorf("%s: %s is not a constant timestamp", name, expr.ToString(args[1]))
+			}
+			sh.Time = true
+			start = ts.Value.UnixMicro()
+			stop = te.Value.UnixMicro()
+		}
+	default:
+		return nil, fmt.Errorf("%s is not a generated table", name)
+	}
+	ints := args
+	if sh.Time {
+		ints = args[2:]
+	}
+	for i := range ints {
+		// (integral constants may have been
+		// folded into rationals, so we use
+		// the datum representation)
+		c, ok := ints[i].(expr.Constant)
+		if !ok {
+			return nil, fmt.Errorf("%s: %s is not a constant integer", name, expr.ToString(ints[i]))
+		}
+		n, err := c.Datum().Int()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s is not a constant integer", name, expr.ToString(ints[i]))
+		}
+		switch {
+		case sh.Time:
+			step = n * 1000000
+		case i == 0:
+			start = n
+		case i == 1:
+			stop = n
+		default:
+			step = n
+		}
+	}
+	if step == 0 {
+		return nil, fmt.Errorf("%s: step must not be zero", name)
+	}
+	sh.Start = start
+	sh.Step = step
+	sh.Count = seriesCount(start, stop, step, b.Func == expr.TableSeries)
+	return sh, nil
+}
+
+// seriesCount returns the number of values
+// start + n*step that lie between start and
+// stop, including stop if inclusive is set
+func seriesCount(start, stop, step int64, inclusive bool) int64 {
+	var diff, size uint64
+	if step > 0 {
+		if stop < start || (stop == start && !inclusive) {
+			return 0
+		}
+		diff, size = uint64(stop-start), uint64(step)
+	} else {
+		if stop > start || (stop == start && !inclusive) {
+			return 0
+		}
+		diff, size = uint64(start-stop), uint64(-step)
+	}
+	if !inclusive {
+		diff--
+	}
+	return int64(diff/size + 1)
+}
+
+// Open implements TableHandle.Open
+func (s *SeriesHandle) Open(ctx context.Context) (vm.Table, error) {
+	return &seriesTable{handle: s}, nil
+}
+
+// Size implements TableHandle.Size
+//
+// The size is an estimate of the
+// number of bytes in the generated table.
+func (s *SeriesHandle) Size() int64 {
+	per := int64(8)
+	if s.Time {
+		per = 16
+	}
+	return s.Count * per
+}
+
+// Encode implements TableHandle.Encode
+func (s *SeriesHandle) Encode(dst *ion.Buffer, st *ion.Symtab) error {
+	dst.BeginStruct(-1)
+	dst.BeginField(st.Intern("series"))
+	dst.BeginStruct(-1)
+	dst.BeginField(st.Intern("field"))
+	dst.WriteString(s.Field)
+	dst.BeginField(st.Intern("start"))
+	dst.WriteInt(s.Start)
+	dst.BeginField(st.Intern("step"))
+	dst.WriteInt(s.Step)
+	dst.BeginField(st.Intern("count"))
+	dst.WriteInt(s.Count)
+	if s.Time {
+		dst.BeginField(st.Intern("time"))
+		dst.WriteBool(true)
+	}
+	dst.EndStruct()
+	dst.EndStruct()
+	return nil
+}
+
+// isSeriesHandle returns whether d
+// was produced by SeriesHandle.Encode
+func isSeriesHandle(d ion.Datum) bool {
+	s, err := d.Struct()
+	if err != nil {
+		return false
+	}
+	_, ok := s.FieldByName("series")
+	return ok && s.Len() == 1
+}
+
+func (s *SeriesHandle) decode(d ion.Datum) error {
+	return d.UnpackStruct(func(f ion.Field) error {
+		if f.Label != "series" {
+			return errUnexpectedField
+		}
+		return f.UnpackStruct(func(f ion.Field) error {
+			var err error
+			switch f.Label {
+			case "field":
+				s.Field, err = f.String()
+			case "start":
+				s.Start, err = f.Int()
+			case "step":
+				s.Step, err = f.Int()
+			case "count":
+				s.Count, err = f.Int()
+			case "time":
+				s.Time, err = f.Bool()
+			default:
+				return errUnexpectedField
+			}
+			return err
+		})
+	})
+}
+
+// seriesTable is the vm.Table
+// returned by SeriesHandle.Open
+type seriesTable struct {
+	handle *SeriesHandle
+	next   int64 // next batch
+}
+
+// WriteChunks implements vm.Table.WriteChunks
+func (t *seriesTable) WriteChunks(dst vm.QuerySink, parallel int) error {
+	batches := (t.handle.Count + seriesBatch - 1) / seriesBatch
+	if int64(parallel) > batches {
+		parallel = int(batches)
+	}
+	return vm.SplitInput(dst, parallel, t.write)
+}
+
+func (t *seriesTable) write(w io.Writer) error {
+	h := t.handle
+	cn := ion.Chunker{W: w, Align: vm.PageSize}
+	field := cn.Symbols.Intern(h.Field)
+	for {
+		first := (atomic.AddInt64(&t.next, 1) - 1) * seriesBatch
+		if first >= h.Count {
+			break
+		}
+		end := first + seriesBatch
+		if end > h.Count {
+			end = h.Count
+		}
+		for i := first; i < end; i++ {
+			n := h.Start + i*h.Step
+			cn.Buffer.BeginStruct(-1)
+			cn.Buffer.BeginField(field)
+			if h.Time {
+				cn.Buffer.WriteTime(date.UnixMicro(n))
+			} else {
+				cn.Buffer.WriteInt(n)
+			}
+			cn.Buffer.EndStruct()
+			if err := cn.Commit(); err != nil {
+				return err
+			}
+		}
+	}
+	if err := cn.Flush(); err != nil {
+		return err
+	}
+	vm.HintEndSegment(w)
+	return nil
+}
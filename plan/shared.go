@@ -0,0 +1,212 @@
+// Copyright (C) 2022 Sneller, Inc.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package plan
+
+import (
+	"bytes"
+	"fmt"
+	"hash/maphash"
+
+	"github.com/SnellerInc/sneller/expr"
+	"github.com/SnellerInc/sneller/ion"
+)
+
+// Plans that are split across peers tend to
+// repeat the same table handles (with the same
+// blob lists) and the same filter expressions
+// many times over, so the encoder for a Tree
+// stores each distinct large value once in the
+// "shared" list of the encoded Tree and writes
+// a reference to its position elsewhere:
+//
+//	{inputs: [{handle: {"$ref": 0}}, {handle: {"$ref": 0}}],
+//	 root: {...},
+//	 shared: [{blobs: [...], ...}]}
+//
+// Trees encoded without any shared values
+// are identical to those produced before
+// shared values were introduced.
+
+// minShared is the smallest encoded value
+// that is stored in the shared list;
+// smaller values are written inline
+const minShared = 32
+
+// encoder is the expr.Rewriter passed to the
+// encode methods of each Op in a Tree;
+// it wraps the caller's rewriter and
+// tracks the shared values of the Tree
+type encoder struct {
+	expr.Rewriter
+
+	seed   maphash.Seed
+	tmp    ion.Buffer
+	shared ion.Buffer
+	// offsets of each shared value
+	// (plus the final size) in shared
+	offsets []int
+	index   map[uint64][]int
+}
+
+func newEncoder(rw expr.Rewriter) *encoder {
+	// a nested Tree (see Explain)
+	// gets its own shared values
+	if e, ok := rw.(*encoder); ok {
+		rw = e.Rewriter
+	}
+	return &encoder{
+		Rewriter: rw,
+		seed:     maphash.MakeSeed(),
+		offsets:  []int{0},
+	}
+}
+
+func (e *encoder) value(i int) []byte {
+	return e.shared.Bytes()[e.offsets[i]:e.offsets[i+1]]
+}
+
+// put writes the value produced by fn to dst,
+// either inline or as a reference to a shared value
+func (e *encoder) put(dst *ion.Buffer, st *ion.Symtab, fn func(*ion.Buffer, *ion.Symtab) error) error {
+	e.tmp.Reset()
+	if err := fn(&e.tmp, st); err != nil {
+		return err
+	}
+	buf := e.tmp.Bytes()
+	if len(buf) < minShared {
+		dst.UnsafeAppend(buf)
+		return nil
+	}
+	h := maphash.Bytes(e.seed, buf)
+	id := -1
+	for _, i := range e.index[h] {
+		if bytes.Equal(e.value(i), buf) {
+			id = i
+			break
+		}
+	}
+	if id < 0 {
+		if e.index == nil {
+			e.index = make(map[uint64][]int)
+		}
+		id = len(e.offsets) - 1
+		e.shared.UnsafeAppend(buf)
+		e.offsets = append(e.offsets, e.shared.Size())
+		e.index[h] = append(e.index[h], id)
+	}
+	dst.BeginStruct(-1)
+	dst.BeginField(st.Intern("$ref"))
+	dst.WriteInt(int64(id))
+	dst.EndStruct()
+	return nil
+}
+
+// finish writes the shared values,
+// if there are any, as the "shared" field
+// of the enclosing structure
+func (e *encoder) finish(dst *ion.Buffer, st *ion.Symtab) {
+	if e.shared.Size() == 0 {
+		return
+	}
+	dst.BeginField(st.Intern("shared"))
+	dst.BeginList(-1)
+	dst.UnsafeAppend(e.shared.Bytes())
+	dst.EndList()
+}
+
+// encodeExpr encodes e after applying rw,
+// sharing it with other identical
+// expressions in the same Tree
+func encodeExpr(e expr.Node, dst *ion.Buffer, st *ion.Symtab, rw expr.Rewriter) {
+	e = expr.Rewrite(rw, e)
+	enc, ok := rw.(*encoder)
+	if !ok {
+		e.Encode(dst, st)
+		return
+	}
+	enc.put(dst, st, func(dst *ion.Buffer, st *ion.Symtab) error {
+		e.Encode(dst, st)
+		return nil
+	})
+}
+
+// sharedDecoder is the Decoder used for
+// a Tree that contains shared values
+type sharedDecoder struct {
+	Decoder
+	shared []ion.Datum
+}
+
+// DecodeUploader implements UploaderDecoder
+// if the wrapped Decoder does
+func (s *sharedDecoder) DecodeUploader(d ion.Datum) (UploadFS, error) {
+	up, ok := s.Decoder.(UploaderDecoder)
+	if !ok {
+		return nil, fmt.Errorf("Decoder doesn't support UploaderDecoder: %T", s.Decoder)
+	}
+	return up.DecodeUploader(d)
+}
+
+// withShared returns the Decoder for a
+// Tree with the given list of shared values
+func withShared(d Decoder, lst ion.Datum) (Decoder, error) {
+	if s, ok := d.(*sharedDecoder); ok {
+		d = s.Decoder
+	}
+	s := &sharedDecoder{Decoder: d}
+	err := lst.UnpackList(func(v ion.Datum) error {
+		s.shared = append(s.shared, v)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("plan.Decode: shared values: %w", err)
+	}
+	return s, nil
+}
+
+// resolve returns the value that v refers to
+// if v is a reference to a shared value
+func resolve(d Decoder, v ion.Datum) (ion.Datum, error) {
+	if !v.IsStruct() {
+		return v, nil
+	}
+	s, err := v.Struct()
+	if err != nil || s.Len() != 1 {
+		return v, nil
+	}
+	f, ok := s.FieldByName("$ref")
+	if !ok {
+		return v, nil
+	}
+	i, err := f.Int()
+	if err != nil {
+		return ion.Empty, fmt.Errorf("plan.Decode: bad shared value reference: %w", err)
+	}
+	sd, ok := d.(*sharedDecoder)
+	if !ok || i < 0 || i >= int64(len(sd.shared)) {
+		return ion.Empty, fmt.Errorf("plan.Decode: reference to undefined shared value %d", i)
+	}
+	return sd.shared[i], nil
+}
+
+// decodeExpr decodes an expression
+// encoded with encodeExpr
+func decodeExpr(d Decoder, v ion.Datum) (expr.Node, error) {
+	v, err := resolve(d, v)
+	if err != nil {
+		return nil, err
+	}
+	return expr.Decode(v)
+}
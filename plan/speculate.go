@@ -0,0 +1,261 @@
+// Copyright (C) 2022 Sneller, Inc.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package plan
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/SnellerInc/sneller/vm"
+
+	"golang.org/x/exp/slices"
+)
+
+// speculator tracks the completion times
+// of the sub-queries of a UnionMap in order
+// to determine when a sub-query is straggling
+type speculator struct {
+	n      int     // total number of sub-queries
+	factor float64 // see ExecParams.Speculation
+
+	lock    sync.Mutex
+	done    []time.Duration
+	changed chan struct{} // closed when done changes
+}
+
+func newSpeculator(n int, factor float64) *speculator {
+	return &speculator{
+		n:       n,
+		factor:  factor,
+		changed: make(chan struct{}),
+	}
+}
+
+// finish records the completion time
+// of a sub-query and wakes up any waiters
+func (s *speculator) finish(d time.Duration) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.done = append(s.done, d)
+	close(s.changed)
+	s.changed = make(chan struct{})
+}
+
+// threshold returns the running time after
+// which a sub-query is considered to be a
+// straggler, or false if too few sub-queries
+// have completed to tell; the returned channel
+// is closed when the threshold may have changed
+func (s *speculator) threshold() (time.Duration, bool, <-chan struct{}) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if len(s.done) == 0 || 2*len(s.done) < s.n {
+		return 0, false, s.changed
+	}
+	sorted := slices.Clone(s.done)
+	slices.Sort(sorted)
+	median := sorted[len(sorted)/2]
+	return time.Duration(float64(median) * s.factor), true, s.changed
+}
+
+// maxSpeculativeOutput is the number of bytes
+// of output that each copy of a speculatively
+// executed sub-query may buffer; a copy that
+// produces more output than this wins the race
+// and writes the rest of its output directly
+var maxSpeculativeOutput = 16 << 20
+
+// errSpeculationLost is returned from
+// bufferedWriter.Write when another copy
+// of the sub-query has already won
+var errSpeculationLost = errors.New("another copy of the sub-query has already produced output")
+
+// specOutput picks the copy of a speculatively
+// executed sub-query whose output is used
+type specOutput struct {
+	dst    io.Writer
+	lock   sync.Mutex
+	winner *bufferedWriter
+}
+
+// commit makes b the winning copy and writes
+// its buffered output to dst, or returns false
+// if another copy has already won
+func (s *specOutput) commit(b *bufferedWriter) (bool, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.winner != nil {
+		return s.winner == b, nil
+	}
+	s.winner = b
+	return true, b.flush(s.dst)
+}
+
+// decided returns whether a copy has won
+func (s *specOutput) decided() bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.winner != nil
+}
+
+// bufferedWriter saves each call to Write
+// so that the writes can be replayed on the
+// destination if this copy wins; once the
+// copy has won, writes go straight through
+type bufferedWriter struct {
+	parent *specOutput
+	writes [][]byte
+	size   int
+	direct bool
+}
+
+func (b *bufferedWriter) Write(p []byte) (int, error) {
+	if b.direct {
+		return b.parent.dst.Write(p)
+	}
+	b.writes = append(b.writes, slices.Clone(p))
+	b.size += len(p)
+	if b.size > maxSpeculativeOutput {
+		won, err := b.parent.commit(b)
+		if !won {
+			return 0, errSpeculationLost
+		}
+		if err != nil {
+			return 0, err
+		}
+		b.direct = true
+	}
+	return len(p), nil
+}
+
+func (b *bufferedWriter) flush(dst io.Writer) error {
+	writes := b.writes
+	b.writes = nil
+	for i := range writes {
+		if _, err := dst.Write(writes[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// execSpeculative executes sub, and if it
+// becomes a straggler, executes a second copy
+// of it on the peer following the one that the
+// first copy is using (see Retrier). The output
+// of each copy is buffered, and only the output
+// of the first copy to complete (or to exceed
+// maxSpeculativeOutput) is written to dst; since
+// both copies scan exactly the same blocks, each
+// block is reflected in the output exactly once.
+func (u *UnionMap) execSpeculative(stub *Tree, sub *Subtable, rt Retrier, i int, dst io.Writer, ep *ExecParams, sp *speculator) error {
+	parent := ep.Context
+	if parent == nil {
+		parent = context.Background()
+	}
+	ctx, cancel := context.WithCancel(parent)
+	defer cancel()
+
+	type result struct {
+		out *bufferedWriter
+		err error
+	}
+	results := make(chan result, 2)
+	running := 0
+	var attempt atomic.Int32 // current attempt of the first copy
+	so := &specOutput{dst: dst}
+	launch := func(sub Subtable, first int, tries *atomic.Int32) {
+		running++
+		go func() {
+			var err error
+			out := &bufferedWriter{parent: so}
+			defer func() {
+				results <- result{out: out, err: err}
+			}()
+			defer vm.HandlePanic(&err)
+			err = u.execRetry(stub, sub, rt, i, first, tries, out, ep, ctx)
+		}()
+	}
+
+	start := time.Now()
+	launch(*sub, 0, &attempt)
+	speculated := false
+	var winner *bufferedWriter
+	var errlist []error
+	for running > 0 && winner == nil {
+		var timer *time.Timer
+		var timeout <-chan time.Time
+		var changed <-chan struct{}
+		if !speculated {
+			var limit time.Duration
+			var ok bool
+			limit, ok, changed = sp.threshold()
+			if ok {
+				timer = time.NewTimer(limit - time.Since(start))
+				timeout = timer.C
+			}
+		}
+		select {
+		case r := <-results:
+			running--
+			if r.err != nil {
+				if !errors.Is(r.err, errSpeculationLost) {
+					errlist = append(errlist, r.err)
+				}
+				break
+			}
+			won, err := so.commit(r.out)
+			if err != nil {
+				errlist = append(errlist, err)
+			} else if won {
+				winner = r.out
+			}
+		case <-timeout:
+			speculated = true
+			if so.decided() {
+				break // too late; output has been written
+			}
+			next := int(attempt.Load()) + 1
+			if tp, ok := rt.Retry(i, next); ok {
+				spec := *sub
+				spec.Transport = tp
+				launch(spec, next, nil)
+			}
+		case <-changed:
+		}
+		if timer != nil {
+			timer.Stop()
+		}
+	}
+	// stop the other copy, if any,
+	// and wait for it to exit so that
+	// its stats have been collected
+	cancel()
+	for ; running > 0; running-- {
+		<-results
+	}
+	if winner == nil {
+		if len(errlist) == 1 {
+			return errlist[0]
+		}
+		return errors.Join(errlist...)
+	}
+	sp.finish(time.Since(start))
+	return nil
+}
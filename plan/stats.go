@@ -38,6 +38,12 @@ type ExecStats struct {
 	// BytesScanned is the number
 	// of bytes scanned.
 	BytesScanned int64
+	// RowsEmitted is the number of rows
+	// in the output of the query. It is
+	// only populated by executors that
+	// count the rows as they are written
+	// (see tenant/tnproto).
+	RowsEmitted int64
 }
 
 // CachedTable is an interface optionally
@@ -57,16 +63,21 @@ func (e *ExecStats) atomicAdd(tmp *ExecStats) {
 	atomic.AddInt64(&e.CacheHits, tmp.CacheHits)
 	atomic.AddInt64(&e.CacheMisses, tmp.CacheMisses)
 	atomic.AddInt64(&e.BytesScanned, tmp.BytesScanned)
+	atomic.AddInt64(&e.RowsEmitted, tmp.RowsEmitted)
 }
 
-func (e *ExecStats) observe(table vm.Table) {
+// observe adds the stats for table to e
+// and returns the number of bytes scanned
+func (e *ExecStats) observe(table vm.Table) int64 {
 	ct, ok := table.(CachedTable)
 	if !ok {
-		return
+		return 0
 	}
+	n := ct.Bytes()
 	atomic.AddInt64(&e.CacheHits, ct.Hits())
 	atomic.AddInt64(&e.CacheMisses, ct.Misses())
-	atomic.AddInt64(&e.BytesScanned, ct.Bytes())
+	atomic.AddInt64(&e.BytesScanned, n)
+	return n
 }
 
 // Marshal is identical to Encode except
@@ -92,6 +103,10 @@ func (e *ExecStats) Encode(dst *ion.Buffer, st *ion.Symtab) {
 		dst.BeginField(st.Intern("scanned"))
 		dst.WriteInt(e.BytesScanned)
 	}
+	if e.RowsEmitted != 0 {
+		dst.BeginField(st.Intern("rows"))
+		dst.WriteInt(e.RowsEmitted)
+	}
 	dst.EndStruct()
 }
 
@@ -105,6 +120,8 @@ func (e *ExecStats) Decode(buf []byte, st *ion.Symtab) error {
 			e.CacheMisses, _, err = ion.ReadInt(body)
 		case "scanned":
 			e.BytesScanned, _, err = ion.ReadInt(body)
+		case "rows":
+			e.RowsEmitted, _, err = ion.ReadInt(body)
 		default:
 			return errUnexpectedField
 		}
@@ -132,6 +149,7 @@ func init() {
 		"hits",
 		"misses",
 		"scanned",
+		"rows",
 	} {
 		statsSymtab.Intern(s)
 	}
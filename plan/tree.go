@@ -15,8 +15,10 @@
 package plan
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"runtime"
 	"strings"
 	"sync"
 
@@ -33,16 +35,16 @@ type Input struct {
 	Handle TableHandle
 }
 
-func (i *Input) encode(dst *ion.Buffer, st *ion.Symtab) error {
+func (i *Input) encode(dst *ion.Buffer, st *ion.Symtab, enc *encoder) error {
 	dst.BeginStruct(-1)
 	tbl, handle := i.Table, i.Handle
 	if tbl != nil {
 		dst.BeginField(st.Intern("table"))
-		tbl.Encode(dst, st)
+		encodeExpr(tbl, dst, st, enc)
 	}
 	if handle != nil {
 		dst.BeginField(st.Intern("handle"))
-		err := handle.Encode(dst, st)
+		err := enc.put(dst, st, handle.Encode)
 		if err != nil {
 			return err
 		}
@@ -66,6 +68,14 @@ type Tree struct {
 	Inputs []Input
 	// Root is the root node of the plan tree.
 	Root Node
+	// MaxScan, if positive, is the maximum number
+	// of bytes that the execution of the tree may
+	// scan. Execution is canceled and fails with
+	// a *ScanLimitError once the number of bytes
+	// scanned exceeds MaxScan. (See also MaxScanned
+	// for the number of bytes that the tree
+	// may scan in the worst case.)
+	MaxScan int64
 }
 
 func tabify(n int, dst *strings.Builder) {
@@ -144,15 +154,52 @@ type Substitute struct {
 	Inner []*Node
 }
 
+// substitutions returns the number of
+// replacements to execute concurrently
+func (ep *ExecParams) substitutions() int {
+	n := ep.Substitutions
+	if n <= 0 {
+		n = ep.Parallel
+	}
+	if n <= 0 {
+		n = runtime.GOMAXPROCS(0)
+	}
+	return n
+}
+
 func (s *Substitute) exec(dst vm.QuerySink, src TableHandle, ep *ExecParams) error {
 	rp := make([]replacement, len(s.Inner))
-	var wg sync.WaitGroup
-	wg.Add(len(s.Inner))
 	errlist := make([]error, len(s.Inner))
+	// the replacements are independent, so they
+	// are executed concurrently (up to the limit
+	// given by ep.Substitutions), and the first
+	// failure cancels the remaining replacements
+	parent := ep.Context
+	if parent == nil {
+		parent = context.Background()
+	}
+	ctx, cancel := context.WithCancel(parent)
+	defer cancel()
+	sem := make(chan struct{}, ep.substitutions())
+	var wg sync.WaitGroup
 	for i := range s.Inner {
+		sem <- struct{}{}
+		if ctx.Err() != nil {
+			<-sem
+			break
+		}
 		subex := ep.clone()
+		subex.Context = ctx
+		wg.Add(1)
 		go func(i int) {
-			defer wg.Done()
+			defer func() {
+				if errlist[i] != nil {
+					cancel()
+				}
+				<-sem
+				wg.Done()
+			}()
+			defer vm.HandlePanic(&errlist[i])
 			errlist[i] = s.Inner[i].exec(&rp[i], subex)
 			ep.Stats.atomicAdd(&subex.Stats)
 		}(i)
@@ -161,6 +208,9 @@ func (s *Substitute) exec(dst vm.QuerySink, src TableHandle, ep *ExecParams) err
 	if err := errors.Join(errlist...); err != nil {
 		return err
 	}
+	if err := parent.Err(); err != nil {
+		return err
+	}
 	ep.AddRewrite(&replacer{inputs: rp})
 	defer ep.PopRewrite()
 	return s.From.exec(dst, src, ep)
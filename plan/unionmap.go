@@ -15,11 +15,13 @@
 package plan
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/SnellerInc/sneller/expr"
 	"github.com/SnellerInc/sneller/ion"
@@ -157,32 +159,20 @@ func (u *UnionMap) exec(dst vm.QuerySink, src TableHandle, ep *ExecParams) error
 	// does not benefit substantially from having
 	// parallelism, so we union all the output bytes
 	// into a single thread here
+	var sp *speculator
+	if ep.Speculation > 0 && tbls.Len() > 1 {
+		if _, ok := tbls.(Retrier); ok {
+			sp = newSpeculator(tbls.Len(), ep.Speculation)
+		}
+	}
 	errors := make([]error, tbls.Len())
 	var wg sync.WaitGroup
 	wg.Add(tbls.Len())
 	for i := 0; i < tbls.Len(); i++ {
 		go func(i int) {
 			defer wg.Done()
-			var sub Subtable
-			tbls.Subtable(i, &sub)
-			// wrap the rest of the query in a Tree;
-			// this makes it look to the Transport
-			// like we are executing a sub-query, which
-			// is approximately true
-			stub := &Tree{
-				Inputs: []Input{{
-					Handle: sub.Handle,
-				}},
-				Root: Node{
-					Op:    u.From,
-					Input: 0,
-				},
-			}
-			subep := ep.clone()
-			subep.Output = s
-			// subep.get will be clobbered by Exec here:
-			errors[i] = sub.Exec(stub, subep)
-			ep.Stats.atomicAdd(&subep.Stats)
+			defer vm.HandlePanic(&errors[i])
+			errors[i] = u.execSub(tbls, i, s, ep, sp)
 		}(i)
 	}
 	wg.Wait()
@@ -203,6 +193,147 @@ func (u *UnionMap) exec(dst vm.QuerySink, src TableHandle, ep *ExecParams) error
 	return err
 }
 
+// DefaultRetries is the default value
+// for ExecParams.Retries
+const DefaultRetries = 2
+
+// Retrier is implemented by Subtables that
+// can re-dispatch a subtable to a different
+// Transport if its original Transport fails.
+//
+// Since the inputs to a subtable are immutable,
+// a subtable can be executed again on any peer,
+// provided that the failed attempt did not
+// produce any output.
+type Retrier interface {
+	// Retry returns the Transport that should
+	// be used for the ith subtable after the
+	// given number of failed attempts (starting
+	// at 1), or false if there are no other
+	// transports to try.
+	Retry(i, attempt int) (Transport, bool)
+}
+
+func (ep *ExecParams) retries() int {
+	if ep.Retries == 0 {
+		return DefaultRetries
+	}
+	return ep.Retries
+}
+
+// DefaultSubqueryTimeout is the default
+// value for ExecParams.SubqueryTimeout
+const DefaultSubqueryTimeout = 10 * time.Minute
+
+func (ep *ExecParams) subqueryTimeout() time.Duration {
+	if ep.SubqueryTimeout == 0 {
+		return DefaultSubqueryTimeout
+	}
+	return ep.SubqueryTimeout
+}
+
+// retryable returns whether a sub-query that
+// failed with err may succeed on another peer;
+// errors reported by the query itself are
+// assumed to be deterministic
+func retryable(err error) bool {
+	var qe *queryError
+	return !errors.As(err, &qe) && !errors.Is(err, context.Canceled)
+}
+
+// countingWriter tracks whether
+// any output has been written
+type countingWriter struct {
+	w     io.Writer
+	wrote atomic.Bool
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	c.wrote.Store(true)
+	return c.w.Write(p)
+}
+
+// execSub executes the ith subtable of tbls,
+// re-dispatching it if it fails before it
+// produces any output, and speculatively
+// re-executing it if sp is non-nil
+func (u *UnionMap) execSub(tbls Subtables, i int, dst io.Writer, ep *ExecParams, sp *speculator) error {
+	var sub Subtable
+	tbls.Subtable(i, &sub)
+	// wrap the rest of the query in a Tree;
+	// this makes it look to the Transport
+	// like we are executing a sub-query, which
+	// is approximately true
+	stub := &Tree{
+		Inputs: []Input{{
+			Handle: sub.Handle,
+		}},
+		Root: Node{
+			Op:    u.From,
+			Input: 0,
+		},
+	}
+	rt, _ := tbls.(Retrier)
+	if sp != nil {
+		return u.execSpeculative(stub, &sub, rt, i, dst, ep, sp)
+	}
+	return u.execRetry(stub, sub, rt, i, 0, nil, dst, ep, ep.Context)
+}
+
+// execRetry executes sub with the context ctx,
+// retrying it with the transports provided by rt;
+// sub is attempt first, and the current attempt
+// is stored into tries if it is non-nil
+func (u *UnionMap) execRetry(stub *Tree, sub Subtable, rt Retrier, i, first int, tries *atomic.Int32, dst io.Writer, ep *ExecParams, ctx context.Context) error {
+	var errlist []error
+	for attempt := first; ; attempt++ {
+		if tries != nil {
+			tries.Store(int32(attempt))
+		}
+		out := &countingWriter{w: dst}
+		subep := ep.clone()
+		subep.Output = out
+		subep.Context = ctx
+		subep.budget = ep.budget.child()
+		cancel := context.CancelFunc(func() {})
+		if timeout := ep.subqueryTimeout(); timeout > 0 && ctx != nil {
+			subep.Context, cancel = context.WithTimeout(ctx, timeout)
+		}
+		// subep.get will be clobbered by Exec here:
+		err := sub.Exec(stub, subep)
+		cancel()
+		ep.Stats.atomicAdd(&subep.Stats)
+		// bytes scanned by a remote transport
+		// have not been charged to the budget yet
+		if subep.budget != nil {
+			remote := subep.Stats.BytesScanned - atomic.LoadInt64(&subep.budget.used)
+			if err2 := ep.budget.charge(remote); err2 != nil {
+				return err2
+			}
+		}
+		if err == nil {
+			return nil
+		}
+		if attempt > first {
+			err = fmt.Errorf("retry %d: %w", attempt-first, err)
+		}
+		errlist = append(errlist, err)
+		if rt == nil || attempt-first >= ep.retries() || out.wrote.Load() ||
+			!retryable(err) || (ctx != nil && ctx.Err() != nil) {
+			break
+		}
+		tp, ok := rt.Retry(i, attempt+1)
+		if !ok {
+			break
+		}
+		sub.Transport = tp
+	}
+	if len(errlist) == 1 {
+		return errlist[0]
+	}
+	return errors.Join(errlist...)
+}
+
 func (u *UnionMap) encode(dst *ion.Buffer, st *ion.Symtab, _ expr.Rewriter) error {
 	dst.BeginStruct(-1)
 	settype("unionmap", dst, st)
@@ -328,6 +459,7 @@ func (u *UnionPartition) exec(dst vm.QuerySink, src TableHandle, ep *ExecParams)
 		wg.Add(1)
 		go func(i int) {
 			defer wg.Done()
+			defer vm.HandlePanic(&errs[i])
 			err := u.From.exec(mw, parts[i].Handle, subep)
 			errs[i] = err
 			ep.Stats.atomicAdd(&subep.Stats)
@@ -349,12 +481,24 @@ func (u *UnionPartition) exec(dst vm.QuerySink, src TableHandle, ep *ExecParams)
 
 // produce a histogram with a sum equal to value
 // with each element proportional to h[i]
+//
+// the size of each part is its estimated
+// number of rows if every part can provide
+// one (see StatsHandle), or its size in bytes
+// otherwise
 func distribute(h []TablePart, value int) []int {
 	hist := make([]int64, len(h))
 	out := make([]int, len(h))
 	sum := int64(0)
+	size := func(h TableHandle) int64 { return h.Size() }
+	if allStats(h) {
+		size = func(h TableHandle) int64 {
+			st, _ := Stats(h)
+			return st.Rows
+		}
+	}
 	for i := range h {
-		sz := h[i].Handle.Size()
+		sz := size(h[i].Handle)
 		if sz == 0 {
 			sz = 1
 		}
@@ -378,6 +522,15 @@ func distribute(h []TablePart, value int) []int {
 	return out
 }
 
+func allStats(h []TablePart) bool {
+	for i := range h {
+		if _, ok := Stats(h[i].Handle); !ok {
+			return false
+		}
+	}
+	return true
+}
+
 // openSink is a QuerySink that collects a list
 // of io.WriteClosers from src (up to max) and stores
 // them internally. If max is less than or equal to zero,
@@ -0,0 +1,359 @@
+// Copyright (C) 2022 Sneller, Inc.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package plan
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/SnellerInc/sneller/vm"
+
+	"golang.org/x/exp/slices"
+)
+
+// flakyTransport is a Transport that fails
+// the first fails calls to Exec
+type flakyTransport struct {
+	calls *int32
+	fails int32
+	err   error
+	// write output before failing
+	partial bool
+	// block until the context is canceled
+	// instead of returning err
+	hang bool
+}
+
+func (f *flakyTransport) Exec(t *Tree, ep *ExecParams) error {
+	n := atomic.AddInt32(f.calls, 1)
+	if n > f.fails {
+		return (&LocalTransport{}).Exec(t, ep)
+	}
+	if f.hang {
+		<-ep.Context.Done()
+		return ep.Context.Err()
+	}
+	if f.partial {
+		if err := (&LocalTransport{}).Exec(t, ep); err != nil {
+			return err
+		}
+	}
+	return f.err
+}
+
+// retrySubtables is a SubtableList
+// that implements Retrier by re-using
+// the same transport
+type retrySubtables struct {
+	SubtableList
+}
+
+func (r *retrySubtables) Retry(i, attempt int) (Transport, bool) {
+	return r.SubtableList[i].Transport, true
+}
+
+type retryHandle struct {
+	emptyenv
+	tbls Subtables
+}
+
+func (r *retryHandle) Split() (Subtables, error) { return r.tbls, nil }
+
+func TestUnionMapRetry(t *testing.T) {
+	errConn := errors.New("connection refused")
+	tcs := []struct {
+		name    string
+		tp      flakyTransport
+		retrier bool
+		retries int
+		timeout time.Duration
+
+		calls int32  // expected calls to Exec
+		err   string // expected error text, if any
+	}{
+		{name: "retried", tp: flakyTransport{fails: 1, err: errConn}, retrier: true, calls: 2},
+		{name: "no retrier", tp: flakyTransport{fails: 1, err: errConn}, calls: 1, err: "connection refused"},
+		{name: "disabled", tp: flakyTransport{fails: 1, err: errConn}, retrier: true, retries: -1, calls: 1, err: "connection refused"},
+		{
+			name:    "query error",
+			tp:      flakyTransport{fails: 1, err: &queryError{text: "remote error: bad query"}},
+			retrier: true,
+			calls:   1,
+			err:     "bad query",
+		},
+		{
+			name:    "partial output",
+			tp:      flakyTransport{fails: 1, err: errConn, partial: true},
+			retrier: true,
+			calls:   1,
+			err:     "connection refused",
+		},
+		{
+			name:    "too many failures",
+			tp:      flakyTransport{fails: 5, err: errConn},
+			retrier: true,
+			retries: 2,
+			calls:   3,
+			err:     "retry 2: connection refused",
+		},
+		{
+			name:    "timeout",
+			tp:      flakyTransport{fails: 1, hang: true},
+			retrier: true,
+			timeout: 50 * time.Millisecond,
+			calls:   2,
+		},
+	}
+	for i := range tcs {
+		tc := &tcs[i]
+		t.Run(tc.name, func(t *testing.T) {
+			var calls int32
+			tp := tc.tp
+			tp.calls = &calls
+			lst := SubtableList{{Transport: &tp, Handle: emptyenv{}}}
+			var tbls Subtables = lst
+			if tc.retrier {
+				tbls = &retrySubtables{lst}
+			}
+			u := &UnionMap{Nonterminal: Nonterminal{From: NoOutput{}}}
+			ep := &ExecParams{
+				Context:         context.Background(),
+				Retries:         tc.retries,
+				SubqueryTimeout: tc.timeout,
+			}
+			var out vm.QueryBuffer
+			err := u.exec(&out, &retryHandle{tbls: tbls}, ep)
+			if tc.err == "" && err != nil {
+				t.Fatal(err)
+			}
+			if tc.err != "" && (err == nil || !strings.Contains(err.Error(), tc.err)) {
+				t.Fatalf("got error %v; want %q", err, tc.err)
+			}
+			if calls != tc.calls {
+				t.Errorf("got %d calls; want %d", calls, tc.calls)
+			}
+		})
+	}
+}
+
+// markTransport is a Transport that
+// writes mark to the output after delay,
+// or that blocks until the context is canceled
+// if delay is negative; it then waits for
+// linger before returning
+type markTransport struct {
+	mark   string
+	delay  time.Duration
+	linger time.Duration
+	calls  *int32
+}
+
+func (m *markTransport) Exec(t *Tree, ep *ExecParams) error {
+	if m.calls != nil {
+		atomic.AddInt32(m.calls, 1)
+	}
+	if m.delay < 0 {
+		<-ep.Context.Done()
+		return ep.Context.Err()
+	}
+	select {
+	case <-time.After(m.delay):
+	case <-ep.Context.Done():
+		return ep.Context.Err()
+	}
+	if _, err := ep.Output.Write([]byte(m.mark)); err != nil {
+		return err
+	}
+	select {
+	case <-time.After(m.linger):
+		return nil
+	case <-ep.Context.Done():
+		return ep.Context.Err()
+	}
+}
+
+// specSubtables is a SubtableList that
+// retries each subtable with the transport spec
+type specSubtables struct {
+	SubtableList
+	spec Transport
+}
+
+func (s *specSubtables) Retry(i, attempt int) (Transport, bool) {
+	return s.spec, true
+}
+
+// attemptSubtables is a SubtableList that
+// retries each subtable with the transport
+// for the given attempt
+type attemptSubtables struct {
+	SubtableList
+	attempts []Transport
+}
+
+func (a *attemptSubtables) Retry(i, attempt int) (Transport, bool) {
+	if attempt > len(a.attempts) {
+		return nil, false
+	}
+	return a.attempts[attempt-1], true
+}
+
+// markSink collects the marks written
+// by markTransports
+type markSink struct {
+	lock  sync.Mutex
+	marks []string
+}
+
+func (m *markSink) Open() (io.WriteCloser, error) { return m, nil }
+func (m *markSink) Close() error                  { return nil }
+
+func (m *markSink) Write(p []byte) (int, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.marks = append(m.marks, string(p))
+	return len(p), nil
+}
+
+func TestUnionMapSpeculation(t *testing.T) {
+	const fast = 5 * time.Millisecond
+	tcs := []struct {
+		name        string
+		last        time.Duration // delay of the last subtable
+		spec        time.Duration // delay of the speculative copy
+		speculation float64
+		linger      time.Duration // linger of the last subtable
+		limit       int           // maxSpeculativeOutput, if non-zero
+
+		specCalls int32
+		marks     []string
+	}{
+		{
+			// the last subtable never completes,
+			// so the speculative copy is used
+			name:        "straggler",
+			last:        -1,
+			spec:        fast,
+			speculation: 2,
+			specCalls:   1,
+			marks:       []string{"a", "b", "c", "spec"},
+		},
+		{
+			// the speculative copy never completes,
+			// so the original output is used
+			name:        "original wins",
+			last:        100 * time.Millisecond,
+			spec:        -1,
+			speculation: 2,
+			specCalls:   1,
+			marks:       []string{"a", "b", "c", "d"},
+		},
+		{
+			name:  "disabled",
+			last:  100 * time.Millisecond,
+			spec:  fast,
+			marks: []string{"a", "b", "c", "d"},
+		},
+		{
+			// the last subtable exceeds the buffer
+			// limit before it becomes a straggler,
+			// so it is never speculated
+			name:        "buffer limit",
+			last:        0,
+			linger:      100 * time.Millisecond,
+			spec:        fast,
+			speculation: 2,
+			limit:       -1,
+			marks:       []string{"a", "b", "c", "d"},
+		},
+	}
+	for i := range tcs {
+		tc := &tcs[i]
+		t.Run(tc.name, func(t *testing.T) {
+			var calls int32
+			lst := SubtableList{
+				{Transport: &markTransport{mark: "a", delay: fast}, Handle: emptyenv{}},
+				{Transport: &markTransport{mark: "b", delay: fast}, Handle: emptyenv{}},
+				{Transport: &markTransport{mark: "c", delay: fast}, Handle: emptyenv{}},
+				{Transport: &markTransport{mark: "d", delay: tc.last, linger: tc.linger}, Handle: emptyenv{}},
+			}
+			if tc.limit != 0 {
+				saved := maxSpeculativeOutput
+				maxSpeculativeOutput = tc.limit
+				defer func() { maxSpeculativeOutput = saved }()
+			}
+			tbls := &specSubtables{
+				SubtableList: lst,
+				spec:         &markTransport{mark: "spec", delay: tc.spec, calls: &calls},
+			}
+			u := &UnionMap{Nonterminal: Nonterminal{From: NoOutput{}}}
+			ep := &ExecParams{
+				Context:     context.Background(),
+				Speculation: tc.speculation,
+			}
+			var out markSink
+			if err := u.exec(&out, &retryHandle{tbls: tbls}, ep); err != nil {
+				t.Fatal(err)
+			}
+			slices.Sort(out.marks)
+			if !slices.Equal(out.marks, tc.marks) {
+				t.Errorf("got output %v; want %v", out.marks, tc.marks)
+			}
+			if calls != tc.specCalls {
+				t.Errorf("got %d speculative calls; want %d", calls, tc.specCalls)
+			}
+		})
+	}
+}
+
+func TestUnionMapSpeculationPeer(t *testing.T) {
+	const fast = 5 * time.Millisecond
+	var calls int32
+	lst := SubtableList{
+		{Transport: &markTransport{mark: "a", delay: fast}, Handle: emptyenv{}},
+		{Transport: &markTransport{mark: "b", delay: fast}, Handle: emptyenv{}},
+		{Transport: &markTransport{mark: "c", delay: fast}, Handle: emptyenv{}},
+		{Transport: &flakyTransport{calls: &calls, fails: 1, err: errors.New("connection refused")}, Handle: emptyenv{}},
+	}
+	// the last subtable fails on its first peer and
+	// then straggles on its second peer, so the
+	// speculative copy must run on the third peer
+	tbls := &attemptSubtables{
+		SubtableList: lst,
+		attempts: []Transport{
+			&markTransport{mark: "second", delay: -1},
+			&markTransport{mark: "third", delay: fast},
+		},
+	}
+	u := &UnionMap{Nonterminal: Nonterminal{From: NoOutput{}}}
+	ep := &ExecParams{
+		Context:     context.Background(),
+		Speculation: 2,
+	}
+	var out markSink
+	if err := u.exec(&out, &retryHandle{tbls: tbls}, ep); err != nil {
+		t.Fatal(err)
+	}
+	slices.Sort(out.marks)
+	if want := []string{"a", "b", "c", "third"}; !slices.Equal(out.marks, want) {
+		t.Errorf("got output %v; want %v", out.marks, want)
+	}
+}
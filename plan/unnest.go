@@ -29,20 +29,43 @@ type Unnest struct {
 	Nonterminal // source op
 	Expr        expr.Node
 	Result      string
+
+	// Zip, if present, is a list of additional
+	// list-like fields that are joined in lock-step
+	// with Expr; rows are produced for the longest
+	// list, and the missing elements of the shorter
+	// lists are MISSING
+	Zip []expr.Binding
 }
 
 func (u *Unnest) rewrite(rw expr.Rewriter) {
 	u.From.rewrite(rw)
 	u.Expr = expr.Rewrite(rw, u.Expr)
+	for i := range u.Zip {
+		u.Zip[i].Expr = expr.Rewrite(rw, u.Zip[i].Expr)
+	}
 }
 
 func (u *Unnest) encode(dst *ion.Buffer, st *ion.Symtab, rw expr.Rewriter) error {
 	dst.BeginStruct(-1)
 	settype("unnest", dst, st)
 	dst.BeginField(st.Intern("expr"))
-	expr.Rewrite(rw, u.Expr).Encode(dst, st)
+	encodeExpr(u.Expr, dst, st, rw)
 	dst.BeginField(st.Intern("result"))
 	dst.WriteString(u.Result)
+	if len(u.Zip) > 0 {
+		dst.BeginField(st.Intern("zip"))
+		dst.BeginList(-1)
+		for i := range u.Zip {
+			dst.BeginStruct(-1)
+			dst.BeginField(st.Intern("expr"))
+			encodeExpr(u.Zip[i].Expr, dst, st, rw)
+			dst.BeginField(st.Intern("result"))
+			dst.WriteString(u.Zip[i].Result())
+			dst.EndStruct()
+		}
+		dst.EndList()
+	}
 	dst.EndStruct()
 	return nil
 }
@@ -65,11 +88,33 @@ func (u *Unnest) setfield(d Decoder, f ion.Field) error {
 		}
 		u.Result = s
 	case "expr":
-		e, err := expr.Decode(f.Datum)
+		e, err := decodeExpr(d, f.Datum)
 		if err != nil {
 			return err
 		}
 		u.Expr = e
+	case "zip":
+		return f.UnpackList(func(v ion.Datum) error {
+			var e expr.Node
+			var result string
+			err := v.UnpackStruct(func(f ion.Field) error {
+				var err error
+				switch f.Label {
+				case "expr":
+					e, err = decodeExpr(d, f.Datum)
+				case "result":
+					result, err = f.String()
+				default:
+					return errUnexpectedField
+				}
+				return err
+			})
+			if err != nil {
+				return err
+			}
+			u.Zip = append(u.Zip, expr.Bind(e, result))
+			return nil
+		})
 	default:
 		return errUnexpectedField
 	}
@@ -82,11 +127,29 @@ func (u *Unnest) String() string {
 	out.WriteString(expr.ToString(u.Expr))
 	out.WriteString(" AS ")
 	out.WriteString(u.Result)
+	for i := range u.Zip {
+		out.WriteString(", ")
+		out.WriteString(expr.ToString(u.Zip[i].Expr))
+		out.WriteString(" AS ")
+		out.WriteString(u.Zip[i].Result())
+	}
 	return out.String()
 }
 
 func (u *Unnest) exec(dst vm.QuerySink, src TableHandle, ep *ExecParams) error {
-	op, err := vm.NewUnnest(dst, ep.rewrite(u.Expr), u.Result)
+	var op vm.QuerySink
+	var err error
+	if len(u.Zip) == 0 {
+		op, err = vm.NewUnnest(dst, ep.rewrite(u.Expr), u.Result)
+	} else {
+		fields := []expr.Node{ep.rewrite(u.Expr)}
+		results := []string{u.Result}
+		for i := range u.Zip {
+			fields = append(fields, ep.rewrite(u.Zip[i].Expr))
+			results = append(results, u.Zip[i].Result())
+		}
+		op, err = vm.NewUnnestZip(dst, fields, results)
+	}
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,103 @@
+// Copyright (C) 2022 Sneller, Inc.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package plan
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+
+	"github.com/SnellerInc/sneller/expr"
+	"github.com/SnellerInc/sneller/ion"
+	"github.com/SnellerInc/sneller/vm"
+)
+
+// ValuesHandle is the TableHandle for a
+// constant table, such as the one produced
+// by a VALUES clause. The rows of the table
+// are carried in the handle itself, so it
+// can be decoded without consulting the Decoder.
+type ValuesHandle struct {
+	// Rows are the rows of the table.
+	Rows ion.Bag
+}
+
+var _ TableHandle = (*ValuesHandle)(nil)
+
+// NewValuesHandle creates a ValuesHandle
+// from a list of constant structures.
+func NewValuesHandle(lst *expr.List) (*ValuesHandle, error) {
+	vh := &ValuesHandle{}
+	for i := range lst.Values {
+		if _, ok := lst.Values[i].(*expr.Struct); !ok {
+			return nil, fmt.Errorf("constant table row %s is not a structure", expr.ToString(lst.Values[i]))
+		}
+		vh.Rows.AddDatum(lst.Values[i].Datum())
+	}
+	return vh, nil
+}
+
+// Open implements TableHandle.Open
+func (v *ValuesHandle) Open(ctx context.Context) (vm.Table, error) {
+	var out bytes.Buffer
+	cn := ion.Chunker{W: &out, Align: vm.PageSize}
+	var err error
+	v.Rows.Each(func(d ion.Datum) bool {
+		d.Encode(&cn.Buffer, &cn.Symbols)
+		err = cn.Commit()
+		return err == nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := cn.Flush(); err != nil {
+		return nil, err
+	}
+	return vm.BufferTable(out.Bytes(), vm.PageSize), nil
+}
+
+// Size implements TableHandle.Size
+func (v *ValuesHandle) Size() int64 { return int64(v.Rows.Size()) }
+
+// Encode implements TableHandle.Encode
+func (v *ValuesHandle) Encode(dst *ion.Buffer, st *ion.Symtab) error {
+	dst.BeginStruct(-1)
+	dst.BeginField(st.Intern("values"))
+	dst.BeginList(-1)
+	v.Rows.Encode(dst, st)
+	dst.EndList()
+	dst.EndStruct()
+	return nil
+}
+
+// isValuesHandle returns whether d
+// was produced by ValuesHandle.Encode
+func isValuesHandle(d ion.Datum) bool {
+	s, err := d.Struct()
+	if err != nil {
+		return false
+	}
+	_, ok := s.FieldByName("values")
+	return ok && s.Len() == 1
+}
+
+func (v *ValuesHandle) decode(d ion.Datum) error {
+	return d.UnpackStruct(func(f ion.Field) error {
+		if f.Label != "values" {
+			return errUnexpectedField
+		}
+		return v.Rows.AddList(f.Datum)
+	})
+}
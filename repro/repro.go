@@ -0,0 +1,369 @@
+// Copyright (C) 2022 Sneller, Inc.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// Package repro implements "repro bundles,"
+// which are archives that capture enough
+// information about a failed query that
+// it can be replayed outside of the
+// environment in which it failed.
+//
+// A bundle is a tar archive containing
+// the following files:
+//
+//	query.sql         the redacted query text
+//	error.txt         the text of the error
+//	plan.txt          the query plan (if planning succeeded)
+//	versions.json     the versions of the software that ran the query
+//	inputs/N.json     a description of the Nth input table
+//	inputs/N.rows     (optional) sample rows from the Nth input, as NDJSON
+//
+// See testquery.ReadBundle for replaying a bundle.
+package repro
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"runtime"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/SnellerInc/sneller/expr"
+	"github.com/SnellerInc/sneller/ion"
+	"github.com/SnellerInc/sneller/plan"
+)
+
+// Bundle is the contents of a repro bundle.
+type Bundle struct {
+	// Query is the redacted text of the query.
+	Query string
+	// Error is the text of the error
+	// produced by the query.
+	Error string
+	// Plan is the text representation
+	// of the query plan, or the empty string
+	// if the query could not be planned.
+	Plan string
+	// Versions describes the software
+	// that produced the bundle.
+	// See Versions.
+	Versions map[string]string
+	// Inputs are the tables
+	// referenced by the query.
+	Inputs []Input
+}
+
+// Input describes one table referenced by a query.
+type Input struct {
+	// Name is the name of the table,
+	// with path components joined by '.'
+	Name string `json:"name"`
+	// Descriptor, if non-nil, is the JSON
+	// representation of the table handle
+	// that was produced by the query planner,
+	// which includes the descriptors
+	// of the blocks that would be scanned.
+	Descriptor json.RawMessage `json:"descriptor,omitempty"`
+	// Rows, if non-nil, holds sample rows
+	// from the table as NDJSON.
+	Rows []byte `json:"-"`
+}
+
+// Versions returns the default
+// value for Bundle.Versions, which
+// describes the running binary.
+func Versions() map[string]string {
+	v := map[string]string{
+		"go":   runtime.Version(),
+		"os":   runtime.GOOS,
+		"arch": runtime.GOARCH,
+	}
+	bi, ok := debug.ReadBuildInfo()
+	if !ok {
+		return v
+	}
+	v["module"] = bi.Main.Path + "@" + bi.Main.Version
+	for i := range bi.Settings {
+		switch bi.Settings[i].Key {
+		case "vcs.revision", "vcs.time", "vcs.modified":
+			v[bi.Settings[i].Key] = bi.Settings[i].Value
+		}
+	}
+	return v
+}
+
+// New constructs a bundle for the query q
+// that failed with the error err. The
+// tree should be the plan for q, or nil
+// if q failed during planning.
+//
+// Only the redacted form of the query text
+// is captured, and input rows are not
+// captured; the caller may set Input.Rows
+// explicitly if it has access to the data.
+func New(q *expr.Query, tree *plan.Tree, err error) *Bundle {
+	b := &Bundle{
+		Query:    q.Redacted(),
+		Versions: Versions(),
+	}
+	if err != nil {
+		b.Error = err.Error()
+	}
+	if tree != nil {
+		b.Plan = tree.String()
+	}
+	add := func(name string) *Input {
+		for i := range b.Inputs {
+			if b.Inputs[i].Name == name {
+				return &b.Inputs[i]
+			}
+		}
+		b.Inputs = append(b.Inputs, Input{Name: name})
+		return &b.Inputs[len(b.Inputs)-1]
+	}
+	table := func(e expr.Node) {
+		if isCTE(q, e) {
+			return
+		}
+		// list each of the tables in
+		// table1 ++ table2 separately
+		if app, ok := e.(*expr.Appended); ok {
+			for i := range app.Values {
+				add(tableName(app.Values[i]))
+			}
+			return
+		}
+		add(tableName(e))
+	}
+	visit := expr.WalkFunc(func(n expr.Node) bool {
+		switch n := n.(type) {
+		case *expr.Table:
+			table(n.Expr)
+		case *expr.Join:
+			// the right-hand side of an explicit
+			// JOIN is a table; otherwise it is
+			// usually a path in the left-hand side
+			if n.On != nil {
+				table(n.Right.Expr)
+			}
+		}
+		return true
+	})
+	for i := range q.With {
+		expr.Walk(visit, q.With[i].As)
+	}
+	expr.Walk(visit, q.Body)
+	if tree != nil {
+		for i := range tree.Inputs {
+			in := &tree.Inputs[i]
+			if in.Table == nil || in.Handle == nil {
+				continue
+			}
+			dst := add(tableName(in.Table.Expr))
+			if dst.Descriptor == nil {
+				// ignore handles that cannot be encoded;
+				// the descriptor is informational only
+				dst.Descriptor, _ = describe(in.Handle)
+			}
+		}
+	}
+	return b
+}
+
+func isCTE(q *expr.Query, e expr.Node) bool {
+	id, ok := e.(expr.Ident)
+	if !ok {
+		return false
+	}
+	for i := range q.With {
+		if q.With[i].Table == string(id) {
+			return true
+		}
+	}
+	return false
+}
+
+func tableName(e expr.Node) string {
+	if p, ok := expr.FlatPath(e); ok {
+		return strings.Join(p, ".")
+	}
+	return expr.ToString(e)
+}
+
+// describe returns the JSON representation of h
+func describe(h plan.TableHandle) (json.RawMessage, error) {
+	var st ion.Symtab
+	var body ion.Buffer
+	if err := h.Encode(&body, &st); err != nil {
+		return nil, err
+	}
+	d, _, err := ion.ReadDatum(&st, body.Bytes())
+	if err != nil {
+		return nil, err
+	}
+	var chunk ion.Buffer
+	chunk.StartChunk(&st)
+	d.Encode(&chunk, &st)
+	var out bytes.Buffer
+	_, err = ion.ToJSON(&out, bufio.NewReader(bytes.NewReader(chunk.Bytes())))
+	if err != nil {
+		return nil, err
+	}
+	return bytes.TrimSpace(out.Bytes()), nil
+}
+
+// WriteTo writes b to w as a tar archive.
+func (b *Bundle) WriteTo(w io.Writer) (int64, error) {
+	cw := &countingWriter{w: w}
+	tw := tar.NewWriter(cw)
+	put := func(name string, body []byte) error {
+		// the modification time is left as the
+		// epoch so that archives are reproducible
+		err := tw.WriteHeader(&tar.Header{
+			Typeflag: tar.TypeReg,
+			Name:     name,
+			Size:     int64(len(body)),
+			Mode:     0644,
+			ModTime:  time.Unix(0, 0),
+		})
+		if err != nil {
+			return err
+		}
+		_, err = tw.Write(body)
+		return err
+	}
+	versions, err := json.MarshalIndent(b.Versions, "", "  ")
+	if err != nil {
+		return cw.n, err
+	}
+	type file struct {
+		name string
+		body []byte
+	}
+	files := []file{
+		{"query.sql", []byte(b.Query)},
+		{"error.txt", []byte(b.Error)},
+		{"versions.json", versions},
+	}
+	if b.Plan != "" {
+		files = append(files, file{"plan.txt", []byte(b.Plan)})
+	}
+	for i := range files {
+		if err := put(files[i].name, files[i].body); err != nil {
+			return cw.n, err
+		}
+	}
+	for i := range b.Inputs {
+		desc, err := json.MarshalIndent(&b.Inputs[i], "", "  ")
+		if err != nil {
+			return cw.n, err
+		}
+		if err := put(inputFile(i, ".json"), desc); err != nil {
+			return cw.n, err
+		}
+		if b.Inputs[i].Rows != nil {
+			if err := put(inputFile(i, ".rows"), b.Inputs[i].Rows); err != nil {
+				return cw.n, err
+			}
+		}
+	}
+	err = tw.Close()
+	return cw.n, err
+}
+
+func inputFile(i int, suffix string) string {
+	return "inputs/" + strconv.Itoa(i) + suffix
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// Read reads a bundle written by Bundle.WriteTo.
+func Read(r io.Reader) (*Bundle, error) {
+	b := &Bundle{}
+	rows := make(map[int][]byte)
+	inputs := make(map[int]*Input)
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		body, err := io.ReadAll(tr)
+		if err != nil {
+			return nil, err
+		}
+		switch hdr.Name {
+		case "query.sql":
+			b.Query = string(body)
+		case "error.txt":
+			b.Error = string(body)
+		case "plan.txt":
+			b.Plan = string(body)
+		case "versions.json":
+			if err := json.Unmarshal(body, &b.Versions); err != nil {
+				return nil, fmt.Errorf("repro: %s: %w", hdr.Name, err)
+			}
+		default:
+			dir, file := path.Split(hdr.Name)
+			if dir != "inputs/" {
+				continue // allow extra files
+			}
+			ext := path.Ext(file)
+			n, err := strconv.Atoi(strings.TrimSuffix(file, ext))
+			if err != nil || n < 0 {
+				continue
+			}
+			switch ext {
+			case ".json":
+				in := new(Input)
+				if err := json.Unmarshal(body, in); err != nil {
+					return nil, fmt.Errorf("repro: %s: %w", hdr.Name, err)
+				}
+				inputs[n] = in
+			case ".rows":
+				rows[n] = body
+			}
+		}
+	}
+	for i := 0; i < len(inputs); i++ {
+		in, ok := inputs[i]
+		if !ok {
+			return nil, fmt.Errorf("repro: missing %s", inputFile(i, ".json"))
+		}
+		in.Rows = rows[i]
+		b.Inputs = append(b.Inputs, *in)
+	}
+	if b.Query == "" {
+		return nil, fmt.Errorf("repro: bundle has no query.sql")
+	}
+	return b, nil
+}
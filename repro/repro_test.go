@@ -0,0 +1,148 @@
+// Copyright (C) 2022 Sneller, Inc.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package repro_test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/SnellerInc/sneller/expr/partiql"
+	"github.com/SnellerInc/sneller/ion"
+	"github.com/SnellerInc/sneller/plan"
+	"github.com/SnellerInc/sneller/repro"
+	"github.com/SnellerInc/sneller/testquery"
+)
+
+func TestRoundTrip(t *testing.T) {
+	q, err := partiql.Parse([]byte(`WITH c AS (SELECT x FROM foo.bar WHERE y = 'secret') SELECT * FROM c JOIN baz ON c.x = baz.x`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := repro.New(q, nil, errors.New("query failed"))
+	b.Inputs[0].Rows = []byte("{\"x\": 1}\n")
+	if strings.Contains(b.Query, "secret") {
+		t.Errorf("bundle query %q is not redacted", b.Query)
+	}
+	if len(b.Inputs) != 2 || b.Inputs[0].Name != "foo.bar" || b.Inputs[1].Name != "baz" {
+		t.Fatalf("unexpected inputs %+v", b.Inputs)
+	}
+	if b.Versions["go"] == "" {
+		t.Error("missing go version")
+	}
+
+	var buf bytes.Buffer
+	n, err := b.WriteTo(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != int64(buf.Len()) {
+		t.Errorf("WriteTo returned %d; wrote %d bytes", n, buf.Len())
+	}
+	// writing is deterministic
+	var buf2 bytes.Buffer
+	if _, err := b.WriteTo(&buf2); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf.Bytes(), buf2.Bytes()) {
+		t.Error("archives are not identical")
+	}
+	b2, err := repro.Read(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(b, b2) {
+		t.Errorf("got  %+v\nwant %+v", b2, b)
+	}
+}
+
+// encodedHandle is a Bufhandle that can be encoded
+type encodedHandle struct {
+	testquery.Bufhandle
+}
+
+func (h encodedHandle) Encode(dst *ion.Buffer, st *ion.Symtab) error {
+	dst.BeginStruct(-1)
+	dst.BeginField(st.Intern("size"))
+	dst.WriteInt(h.Size())
+	dst.EndStruct()
+	return nil
+}
+
+func TestReplay(t *testing.T) {
+	var st ion.Symtab
+	var rows ion.Buffer
+	rows.StartChunk(&st)
+	ion.NewStruct(&st, []ion.Field{{Label: "x", Datum: ion.Int(1)}}).Encode(&rows, &st)
+	env := &testquery.Queryenv{In: []plan.TableHandle{encodedHandle{testquery.Bufhandle(rows.Bytes())}}}
+
+	// a query that plans successfully
+	// produces a plan and a descriptor
+	q, err := partiql.Parse([]byte(`SELECT x FROM input WHERE x > 0`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tree, err := plan.New(q, env)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := repro.New(q, tree, errors.New("execution failed"))
+	if b.Plan == "" || len(b.Inputs) != 1 {
+		t.Fatalf("unexpected bundle %+v", b)
+	}
+	if want := fmt.Sprintf(`{"size": %d}`, rows.Size()); string(b.Inputs[0].Descriptor) != want {
+		t.Errorf("got descriptor %s; want %s", b.Inputs[0].Descriptor, want)
+	}
+
+	// a query that fails to plan
+	// can be replayed as a test case
+	q, err = partiql.Parse([]byte(`SELECT TIME_RANGE(ts) AS r, x FROM input WHERE y = 'foo'`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = plan.New(q, env)
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	b = repro.New(q, nil, err)
+	b.Inputs[0].Rows = []byte("{\"x\": 1, \"ts\": \"2022-01-01T00:00:00Z\"}\n")
+	var buf bytes.Buffer
+	if _, err := b.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	b, err = repro.Read(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tci, err := testquery.BundleTestCase(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tci.Execute(0); err != nil {
+		t.Fatal(err)
+	}
+	// ... but not if the error is different
+	b.Error = "something else"
+	tci, err = testquery.BundleTestCase(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tci.Execute(0); err == nil {
+		t.Fatal("expected replay to fail")
+	}
+}
@@ -25,7 +25,6 @@ import (
 	"github.com/SnellerInc/sneller/ion"
 	"github.com/SnellerInc/sneller/plan"
 	"github.com/SnellerInc/sneller/tenant/tnproto"
-	"github.com/dchest/siphash"
 )
 
 const DefaultSplitSize = int64(100 * 1024 * 1024)
@@ -36,6 +35,14 @@ type Splitter struct {
 	WorkerKey tnproto.Key
 	Peers     []*net.TCPAddr
 	SelfAddr  string
+	// Compress, if set, compresses the
+	// connections to remote peers with zstd
+	// (see tnproto.Remote.Compress)
+	Compress bool
+	// Placement, if non-nil, determines
+	// which peer scans each blob;
+	// otherwise DefaultPlacement is used
+	Placement Placement
 }
 
 func (s *Splitter) encode(dst *ion.Buffer, st *ion.Symtab) {
@@ -54,6 +61,14 @@ func (s *Splitter) encode(dst *ion.Buffer, st *ion.Symtab) {
 	dst.EndList()
 	dst.BeginField(st.Intern("SelfAddr"))
 	dst.WriteString(s.SelfAddr)
+	if s.Compress {
+		dst.BeginField(st.Intern("Compress"))
+		dst.WriteBool(true)
+	}
+	if s.Placement != nil {
+		dst.BeginField(st.Intern("Placement"))
+		dst.WriteString(s.Placement.Name())
+	}
 	dst.EndStruct()
 }
 
@@ -104,6 +119,14 @@ func (s *Splitter) setField(f ion.Field) error {
 		})
 	case "SelfAddr":
 		s.SelfAddr, err = f.String()
+	case "Compress":
+		s.Compress, err = f.Bool()
+	case "Placement":
+		var name string
+		name, err = f.String()
+		if err == nil {
+			s.Placement, err = LookupPlacement(name)
+		}
 	default:
 		err = fmt.Errorf("Splitter: unexpected field %q", f.Label)
 	}
@@ -115,6 +138,7 @@ func (s *Splitter) split(fh *TenantHandle) (plan.Subtables, error) {
 	splits := make([]split, len(s.Peers))
 	for i := range splits {
 		splits[i].tp = s.transport(i)
+		splits[i].peer = i
 	}
 	insert := func(b blob.Interface) error {
 		i, err := s.partition(b)
@@ -131,6 +155,7 @@ func (s *Splitter) split(fh *TenantHandle) (plan.Subtables, error) {
 		}
 	}
 	return &Subtables{
+		splitter:  s,
 		parent:    fh.parent,
 		table:     expr.Null{},
 		splits:    compact(splits),
@@ -160,15 +185,15 @@ func (s *Splitter) partition(b blob.Interface) (int, error) {
 	if err != nil {
 		return 0, err
 	}
-
-	// just two fixed random values
-	key0 := uint64(0x5d1ec810)
-	key1 := uint64(0xfebed702)
-
-	hash := siphash.Hash(key0, key1, []byte(info.ETag))
-	maxUint64 := ^uint64(0)
-	idx := hash / (maxUint64 / uint64(len(s.Peers)))
-	return int(idx), nil
+	p := s.Placement
+	if p == nil {
+		p = DefaultPlacement
+	}
+	i := p.Place(info, s.Peers)
+	if i < 0 || i >= len(s.Peers) {
+		return 0, fmt.Errorf("placement %s returned invalid peer %d of %d", p.Name(), i, len(s.Peers))
+	}
+	return i, nil
 }
 
 func (s *Splitter) transport(i int) plan.Transport {
@@ -177,26 +202,29 @@ func (s *Splitter) transport(i int) plan.Transport {
 		return &plan.LocalTransport{}
 	}
 	return &tnproto.Remote{
-		ID:      s.WorkerID,
-		Key:     s.WorkerKey,
-		Net:     "tcp",
-		Addr:    nodeID,
-		Timeout: 3 * time.Second,
+		ID:       s.WorkerID,
+		Key:      s.WorkerKey,
+		Net:      "tcp",
+		Addr:     nodeID,
+		Timeout:  3 * time.Second,
+		Compress: s.Compress,
 	}
 }
 
 type split struct {
 	tp    plan.Transport
+	peer  int // index into Splitter.Peers
 	blobs []int
 }
 
 // Subtables is the plan.Subtables implementation
 // returned by TenantHandle.Split
 type Subtables struct {
-	parent *TenantEnv // for local execution
-	splits []split
-	table  expr.Node
-	blobs  []blob.Interface
+	splitter *Splitter  // for retries
+	parent   *TenantEnv // for local execution
+	splits   []split
+	table    expr.Node
+	blobs    []blob.Interface
 
 	// from plan.Hints:
 	filter    expr.Node
@@ -242,6 +270,22 @@ func (s *Subtables) Subtable(i int, sub *plan.Subtable) {
 	}
 }
 
+var _ plan.Retrier = (*Subtables)(nil)
+
+// Retry implements plan.Retrier.Retry
+// by assigning the subtable to the
+// peers following its original peer
+func (s *Subtables) Retry(i, attempt int) (plan.Transport, bool) {
+	if s.next != nil && i >= len(s.splits) {
+		return s.next.Retry(i-len(s.splits), attempt)
+	}
+	if s.splitter == nil || attempt >= len(s.splitter.Peers) {
+		return nil, false
+	}
+	peer := (s.splits[i].peer + attempt) % len(s.splitter.Peers)
+	return s.splitter.transport(peer), true
+}
+
 // Filter implements plan.Subtables.Filter.
 func (s *Subtables) Filter(e expr.Node) {
 	s.filter = e
@@ -0,0 +1,308 @@
+// Copyright (C) 2022 Sneller, Inc.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package sneller
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/SnellerInc/sneller/date"
+	"github.com/SnellerInc/sneller/db"
+	"github.com/SnellerInc/sneller/expr"
+	"github.com/SnellerInc/sneller/ion"
+	"github.com/SnellerInc/sneller/ion/blockfmt"
+	"github.com/SnellerInc/sneller/plan"
+	"github.com/SnellerInc/sneller/vm"
+)
+
+// Names of the system tables.
+//
+// The system tables are virtual tables that
+// describe the tables visible to the tenant
+// and the queries that are currently running.
+// They can be queried like any other table
+// (using an unqualified table name) from
+// an environment produced by Environ.
+const (
+	// TablesTable has one row per table with the
+	// fields database, table, created, objects
+	// and (when known) rows.
+	TablesTable = "sneller_tables"
+	// ColumnsTable has one row per column of
+	// each table with a known schema, with the
+	// fields database, table, column and types.
+	// Nested columns are named with their
+	// full path, like "a.b".
+	ColumnsTable = "sneller_columns"
+	// QueriesTable has one row per running
+	// query with the fields id, database,
+	// query and start. See FSEnv.Queries.
+	QueriesTable = "sneller_queries"
+)
+
+// QueryInfo describes a running query.
+type QueryInfo struct {
+	// ID is the query ID.
+	ID string
+	// Database is the default database of the query.
+	Database string
+	// Query is the (redacted) query text.
+	Query string
+	// Start is the time the query started.
+	Start time.Time
+}
+
+// systemTable returns the name of the system
+// table referenced by e, if e refers to one
+func systemTable(e expr.Node) (string, bool) {
+	id, ok := e.(expr.Ident)
+	if !ok {
+		return "", false
+	}
+	for _, name := range []string{TablesTable, ColumnsTable, QueriesTable} {
+		if strings.EqualFold(string(id), name) {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+// systemStat produces the SystemHandle for
+// the given system table
+func (f *FSEnv) systemStat(name string) (*SystemHandle, error) {
+	var st ion.Symtab
+	var rows []ion.Datum
+	var err error
+	switch name {
+	case TablesTable, ColumnsTable:
+		err = f.eachTable(func(dbname, table string, index *blockfmt.Index) {
+			if name == TablesTable {
+				rows = append(rows, tableRow(&st, dbname, table, index))
+			} else {
+				rows = columnRows(&st, rows, dbname, table, index.Schema())
+			}
+		})
+	case QueriesTable:
+		// the contents change from one moment
+		// to the next, so they should never be cached
+		now := date.Now().Truncate(time.Microsecond)
+		io.WriteString(f.hash, now.String())
+		f.modtime = now
+		if f.Queries != nil {
+			for _, q := range f.Queries() {
+				rows = append(rows, ion.NewStruct(&st, []ion.Field{
+					{Label: "id", Datum: ion.String(q.ID)},
+					{Label: "database", Datum: ion.String(q.Database)},
+					{Label: "query", Datum: ion.String(q.Query)},
+					{Label: "start", Datum: ion.Timestamp(date.FromTime(q.Start))},
+				}).Datum())
+			}
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &SystemHandle{Name: name, Rows: rows}, nil
+}
+
+// eachTable calls fn for each table
+// in each database visible to the tenant
+func (f *FSEnv) eachTable(fn func(dbname, table string, index *blockfmt.Index)) error {
+	dbs, err := db.List(f.Root)
+	if err != nil {
+		return err
+	}
+	sort.Strings(dbs)
+	for _, dbname := range dbs {
+		tables, err := db.Tables(f.Root, dbname)
+		if err != nil {
+			return err
+		}
+		sort.Strings(tables)
+		for _, table := range tables {
+			index, err := f.index(&expr.Dot{Inner: expr.Ident(dbname), Field: table})
+			if err != nil {
+				return err
+			}
+			fn(dbname, table, index)
+		}
+	}
+	return nil
+}
+
+func tableRow(st *ion.Symtab, dbname, table string, index *blockfmt.Index) ion.Datum {
+	fields := []ion.Field{
+		{Label: "database", Datum: ion.String(dbname)},
+		{Label: "table", Datum: ion.String(table)},
+		{Label: "created", Datum: ion.Timestamp(index.Created)},
+		{Label: "objects", Datum: ion.Int(int64(index.Objects()))},
+	}
+	if schema := index.Schema(); schema != nil {
+		fields = append(fields, ion.Field{Label: "rows", Datum: ion.Int(schema.Rows)})
+	}
+	return ion.NewStruct(st, fields).Datum()
+}
+
+// typeNames returns the names of the types
+// in ts, using the same names as db.TypeHints
+func typeNames(ts expr.TypeSet) []string {
+	var out []string
+	for _, t := range []struct {
+		name string
+		set  expr.TypeSet
+	}{
+		{"null", expr.NullType},
+		{"bool", expr.BoolType},
+		{"int", expr.IntegerType},
+		{"float", expr.FloatType},
+		{"decimal", expr.DecimalType},
+		{"timestamp", expr.TimeType},
+		{"symbol", expr.SymbolType},
+		{"string", expr.StringType},
+		{"list", expr.ListType},
+		{"struct", expr.StructType},
+		{"missing", expr.MissingType},
+	} {
+		if ts.AnyOf(t.set) {
+			out = append(out, t.name)
+		}
+	}
+	return out
+}
+
+func columnRows(st *ion.Symtab, dst []ion.Datum, dbname, table string, schema *blockfmt.Schema) []ion.Datum {
+	if schema == nil {
+		return dst
+	}
+	var walk func(prefix string, fields map[string]*blockfmt.SchemaField)
+	walk = func(prefix string, fields map[string]*blockfmt.SchemaField) {
+		names := make([]string, 0, len(fields))
+		for name := range fields {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			sf := fields[name]
+			col := prefix + name
+			var types []ion.Datum
+			for _, t := range typeNames(sf.Types) {
+				types = append(types, ion.String(t))
+			}
+			dst = append(dst, ion.NewStruct(st, []ion.Field{
+				{Label: "database", Datum: ion.String(dbname)},
+				{Label: "table", Datum: ion.String(table)},
+				{Label: "column", Datum: ion.String(col)},
+				{Label: "types", Datum: ion.NewList(st, types).Datum()},
+			}).Datum())
+			walk(col+".", sf.Fields)
+		}
+	}
+	walk("", schema.Fields)
+	return dst
+}
+
+// SystemHandle is the plan.TableHandle
+// for one of the system tables. The rows
+// of the table are computed when the
+// query is planned and are carried in
+// the handle itself.
+type SystemHandle struct {
+	// Name is the name of the system table.
+	Name string
+	// Rows are the rows of the table.
+	Rows []ion.Datum
+}
+
+var _ plan.TableHandle = (*SystemHandle)(nil)
+
+// Open implements plan.TableHandle.Open
+func (s *SystemHandle) Open(ctx context.Context) (vm.Table, error) {
+	if len(s.Rows) == 0 {
+		return emptyTable{}, nil
+	}
+	var out bytes.Buffer
+	cn := ion.Chunker{W: &out, Align: vm.PageSize}
+	for i := range s.Rows {
+		s.Rows[i].Encode(&cn.Buffer, &cn.Symbols)
+		if err := cn.Commit(); err != nil {
+			return nil, err
+		}
+	}
+	if err := cn.Flush(); err != nil {
+		return nil, err
+	}
+	return vm.BufferTable(out.Bytes(), vm.PageSize), nil
+}
+
+// Size implements plan.TableHandle.Size
+func (s *SystemHandle) Size() int64 { return 0 }
+
+// Encode implements plan.TableHandle.Encode
+func (s *SystemHandle) Encode(dst *ion.Buffer, st *ion.Symtab) error {
+	dst.BeginStruct(-1)
+	dst.BeginField(st.Intern("system"))
+	dst.WriteString(s.Name)
+	dst.BeginField(st.Intern("rows"))
+	dst.BeginList(-1)
+	for i := range s.Rows {
+		s.Rows[i].Encode(dst, st)
+	}
+	dst.EndList()
+	dst.EndStruct()
+	return nil
+}
+
+// isSystemHandle returns whether d
+// was produced by SystemHandle.Encode
+func isSystemHandle(d ion.Datum) bool {
+	s, err := d.Struct()
+	if err != nil {
+		return false
+	}
+	_, ok := s.FieldByName("system")
+	return ok
+}
+
+// Decode decodes a SystemHandle
+// produced by SystemHandle.Encode.
+func (s *SystemHandle) Decode(d ion.Datum) error {
+	err := d.UnpackStruct(func(f ion.Field) error {
+		switch f.Label {
+		case "system":
+			str, err := f.String()
+			if err != nil {
+				return err
+			}
+			s.Name = str
+		case "rows":
+			return f.UnpackList(func(d ion.Datum) error {
+				s.Rows = append(s.Rows, d.Clone())
+				return nil
+			})
+		default:
+			return fmt.Errorf("unrecognized field %q", f.Label)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("decoding SystemHandle: %w", err)
+	}
+	return nil
+}
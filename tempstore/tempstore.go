@@ -0,0 +1,346 @@
+// Copyright (C) 2022 Sneller, Inc.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// Package tempstore manages the temporary files
+// created by query operators that spill to disk.
+//
+// A Store owns a directory and enforces a disk quota
+// for every file created within it; typically there is
+// exactly one Store per tenant process, so the Store
+// quota is the per-tenant quota. Each query acquires
+// a Query from the Store, which enforces an additional
+// per-query quota and removes all of the files
+// it created when it is closed.
+package tempstore
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// DirName is the conventional name of
+// the Store directory inside a tenant's
+// cache directory. The cache eviction logic
+// ignores the contents of this directory.
+const DirName = "tmp"
+
+var (
+	// ErrQuota is returned when a write
+	// would exceed either the Query or
+	// the Store disk quota.
+	ErrQuota = errors.New("tempstore: disk quota exceeded")
+	// ErrClosed is returned when a Query
+	// or Store is used after it has been closed.
+	ErrClosed = errors.New("tempstore: already closed")
+)
+
+// Stats is a snapshot of the
+// disk usage of a Store.
+type Stats struct {
+	// Queries is the number of open queries.
+	Queries int64
+	// Files is the number of open files.
+	Files int64
+	// Bytes is the number of bytes
+	// currently used by open files.
+	Bytes int64
+	// PeakBytes is the largest value
+	// of Bytes observed so far.
+	PeakBytes int64
+	// Created is the total number
+	// of files created.
+	Created int64
+	// Rejected is the total number
+	// of writes that were rejected
+	// because of a quota.
+	Rejected int64
+}
+
+// Store is a directory of temporary files
+// with a disk quota.
+type Store struct {
+	dir    string
+	limit  int64
+	qlimit int64
+
+	lock   sync.Mutex // guards everything below
+	stats  Stats
+	seq    int64
+	live   map[*Query]struct{}
+	closed bool
+}
+
+// Open creates a Store rooted at dir.
+// Any existing contents of dir are removed,
+// since they can only have been left behind
+// by a process that exited without cleaning up.
+//
+// The limit argument determines the maximum
+// number of bytes used by all of the files
+// in the Store, and the querylimit argument
+// determines the maximum number of bytes
+// used by the files belonging to a single Query.
+// A limit of zero indicates no limit.
+func Open(dir string, limit, querylimit int64) (*Store, error) {
+	err := os.RemoveAll(dir)
+	if err != nil {
+		return nil, err
+	}
+	err = os.MkdirAll(dir, 0750)
+	if err != nil {
+		return nil, err
+	}
+	return &Store{
+		dir:    dir,
+		limit:  limit,
+		qlimit: querylimit,
+		live:   make(map[*Query]struct{}),
+	}, nil
+}
+
+// Dir returns the root directory of the Store.
+func (s *Store) Dir() string { return s.dir }
+
+// Stats returns a snapshot of
+// the current Store statistics.
+func (s *Store) Stats() Stats {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.stats
+}
+
+// Query creates a new Query.
+// The caller must call Query.Close
+// once the query has finished executing.
+func (s *Store) Query() (*Query, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.closed {
+		return nil, ErrClosed
+	}
+	s.seq++
+	dir := filepath.Join(s.dir, fmt.Sprintf("q%d", s.seq))
+	if err := os.Mkdir(dir, 0750); err != nil {
+		return nil, err
+	}
+	q := &Query{store: s, dir: dir}
+	s.live[q] = struct{}{}
+	s.stats.Queries++
+	return q, nil
+}
+
+// Close closes every open Query
+// and removes the Store directory.
+func (s *Store) Close() error {
+	s.lock.Lock()
+	s.closed = true
+	live := make([]*Query, 0, len(s.live))
+	for q := range s.live {
+		live = append(live, q)
+	}
+	s.lock.Unlock()
+	for i := range live {
+		live[i].Close()
+	}
+	return os.RemoveAll(s.dir)
+}
+
+// reserve accounts for n more bytes
+// written to a file belonging to q
+func (s *Store) reserve(q *Query, n int64) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if q.closed {
+		return ErrClosed
+	}
+	if (s.qlimit > 0 && q.used+n > s.qlimit) ||
+		(s.limit > 0 && s.stats.Bytes+n > s.limit) {
+		s.stats.Rejected++
+		return ErrQuota
+	}
+	q.used += n
+	s.stats.Bytes += n
+	if s.stats.Bytes > s.stats.PeakBytes {
+		s.stats.PeakBytes = s.stats.Bytes
+	}
+	return nil
+}
+
+// release gives back n bytes reserved by q
+//
+// the caller must hold s.lock
+func (s *Store) release(q *Query, n int64) {
+	q.used -= n
+	s.stats.Bytes -= n
+}
+
+// Query is a set of temporary files
+// that belong to one query.
+type Query struct {
+	store *Store
+	dir   string
+
+	// guarded by store.lock
+	used   int64
+	files  []*File
+	closed bool
+}
+
+// Used returns the number of bytes
+// currently used by the files of the query.
+func (q *Query) Used() int64 {
+	q.store.lock.Lock()
+	defer q.store.lock.Unlock()
+	return q.used
+}
+
+// Create creates a new temporary file.
+// The pattern argument is interpreted
+// as in os.CreateTemp.
+func (q *Query) Create(pattern string) (*File, error) {
+	s := q.store
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if q.closed {
+		return nil, ErrClosed
+	}
+	f, err := os.CreateTemp(q.dir, pattern)
+	if err != nil {
+		return nil, err
+	}
+	tf := &File{q: q, f: f}
+	q.files = append(q.files, tf)
+	s.stats.Files++
+	s.stats.Created++
+	return tf, nil
+}
+
+// Close removes all of the files
+// created by the query and releases
+// their disk usage. Close is idempotent.
+func (q *Query) Close() error {
+	s := q.store
+	s.lock.Lock()
+	if q.closed {
+		s.lock.Unlock()
+		return nil
+	}
+	q.closed = true
+	for _, f := range q.files {
+		f.drop()
+	}
+	q.files = nil
+	delete(s.live, q)
+	s.stats.Queries--
+	s.lock.Unlock()
+	return os.RemoveAll(q.dir)
+}
+
+// File is a temporary file
+// that belongs to a Query.
+//
+// Data is appended to a File with Write
+// and read back with ReadAt. A File is
+// removed from disk when it is closed.
+type File struct {
+	q *Query
+	f *os.File
+
+	// guarded by q.store.lock
+	size int64
+	gone bool
+}
+
+// Name returns the name of the file.
+func (f *File) Name() string { return f.f.Name() }
+
+// Size returns the number of bytes
+// written to the file.
+func (f *File) Size() int64 {
+	f.q.store.lock.Lock()
+	defer f.q.store.lock.Unlock()
+	return f.size
+}
+
+// Write implements io.Writer.
+//
+// Write returns ErrQuota without writing
+// any data if the write would exceed
+// the Query or Store quota.
+func (f *File) Write(p []byte) (int, error) {
+	want := int64(len(p))
+	if err := f.q.store.reserve(f.q, want); err != nil {
+		return 0, err
+	}
+	n, err := f.f.Write(p)
+	s := f.q.store
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if f.gone {
+		// the query was closed concurrently
+		// and the reservation was already released
+		// along with the rest of the file
+		s.release(f.q, want)
+		return n, ErrClosed
+	}
+	f.size += int64(n)
+	if short := want - int64(n); short > 0 {
+		s.release(f.q, short)
+	}
+	return n, err
+}
+
+// ReadAt implements io.ReaderAt.
+func (f *File) ReadAt(p []byte, off int64) (int, error) {
+	return f.f.ReadAt(p, off)
+}
+
+// Close closes and removes the file
+// and releases its disk usage.
+// Close is idempotent.
+func (f *File) Close() error {
+	s := f.q.store
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if f.gone {
+		return nil
+	}
+	files := f.q.files
+	for i := range files {
+		if files[i] == f {
+			files[i] = files[len(files)-1]
+			f.q.files = files[:len(files)-1]
+			break
+		}
+	}
+	return f.drop()
+}
+
+// drop closes and removes the file
+//
+// the caller must hold q.store.lock
+func (f *File) drop() error {
+	f.gone = true
+	s := f.q.store
+	s.release(f.q, f.size)
+	s.stats.Files--
+	err := f.f.Close()
+	if err2 := os.Remove(f.f.Name()); err == nil {
+		err = err2
+	}
+	return err
+}
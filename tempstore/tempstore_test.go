@@ -0,0 +1,153 @@
+// Copyright (C) 2022 Sneller, Inc.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package tempstore
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestQuota(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), DirName)
+	s, err := Open(dir, 100, 60)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	q0, err := s.Query()
+	if err != nil {
+		t.Fatal(err)
+	}
+	q1, err := s.Query()
+	if err != nil {
+		t.Fatal(err)
+	}
+	f0, err := q0.Create("spill*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf := make([]byte, 50)
+	if _, err := f0.Write(buf); err != nil {
+		t.Fatal(err)
+	}
+	// exceeds the per-query quota
+	if _, err := f0.Write(buf[:20]); !errors.Is(err, ErrQuota) {
+		t.Fatalf("expected ErrQuota; got %v", err)
+	}
+	f1, err := q1.Create("spill*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// fits in the per-query quota,
+	// but exceeds the per-store quota
+	if _, err := f1.Write(buf[:10]); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f1.Write(buf); !errors.Is(err, ErrQuota) {
+		t.Fatalf("expected ErrQuota; got %v", err)
+	}
+	if f0.Size() != 50 || f1.Size() != 10 {
+		t.Fatalf("sizes: %d %d", f0.Size(), f1.Size())
+	}
+	st := s.Stats()
+	want := Stats{Queries: 2, Files: 2, Bytes: 60, PeakBytes: 60, Created: 2, Rejected: 2}
+	if st != want {
+		t.Fatalf("got stats %+v, want %+v", st, want)
+	}
+
+	// closing the first query releases its quota
+	if err := q0.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(f0.Name()); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("%s not removed: %v", f0.Name(), err)
+	}
+	if _, err := f1.Write(buf); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := q0.Create("spill*"); !errors.Is(err, ErrClosed) {
+		t.Fatalf("expected ErrClosed; got %v", err)
+	}
+	if _, err := f0.Write(buf[:1]); !errors.Is(err, ErrClosed) {
+		t.Fatalf("expected ErrClosed; got %v", err)
+	}
+
+	// check that data can be read back
+	got := make([]byte, 60)
+	if _, err := f1.ReadAt(got, 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := f1.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if n := q1.Used(); n != 0 {
+		t.Fatalf("query uses %d bytes after closing its only file", n)
+	}
+	q1.Close()
+	st = s.Stats()
+	want = Stats{Queries: 0, Files: 0, Bytes: 0, PeakBytes: 60, Created: 2, Rejected: 2}
+	if st != want {
+		t.Fatalf("got stats %+v, want %+v", st, want)
+	}
+	ents, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ents) != 0 {
+		t.Fatalf("%d entries left in %s", len(ents), dir)
+	}
+}
+
+func TestOpenCleans(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), DirName)
+	// simulate files left behind by a crash
+	stale := filepath.Join(dir, "q1", "spill123")
+	if err := os.MkdirAll(filepath.Dir(stale), 0750); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(stale, []byte("stale"), 0640); err != nil {
+		t.Fatal(err)
+	}
+	s, err := Open(dir, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(stale); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("stale file not removed: %v", err)
+	}
+	q, err := s.Query()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := q.Create("spill*"); err != nil {
+		t.Fatal(err)
+	}
+	// closing the store closes every query
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(dir); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("store directory not removed: %v", err)
+	}
+	if _, err := s.Query(); !errors.Is(err, ErrClosed) {
+		t.Fatalf("expected ErrClosed; got %v", err)
+	}
+	if st := s.Stats(); st.Queries != 0 || st.Files != 0 {
+		t.Fatalf("unexpected stats %+v", st)
+	}
+}
@@ -28,6 +28,7 @@ import (
 	"github.com/SnellerInc/sneller/ion"
 	"github.com/SnellerInc/sneller/ion/blockfmt"
 	"github.com/SnellerInc/sneller/plan"
+	"github.com/SnellerInc/sneller/tempstore"
 	"github.com/SnellerInc/sneller/tenant/dcache"
 	"github.com/SnellerInc/sneller/vm"
 	"golang.org/x/exp/constraints"
@@ -41,6 +42,15 @@ var CanVMOpen = false
 // size of a request in bytes exceeds the limit.
 var CacheLimit = memTotal / 2
 
+// DecompressParallel is the number of goroutines
+// used to decompress each compressed blob segment
+// ahead of the goroutines that evaluate it.
+// When DecompressParallel is 0, one goroutine
+// is used for each evaluating goroutine, so a
+// segment that is evaluated by one goroutine
+// is decompressed on that goroutine.
+var DecompressParallel = 0
+
 var onebuf [8]byte
 
 func init() {
@@ -58,6 +68,16 @@ type TenantEnv struct {
 	Events     *os.File
 	Cache      *dcache.Cache
 
+	// Temp, if non-nil, is the storage for
+	// temporary files created by query
+	// operators that spill to disk.
+	Temp *tempstore.Store
+
+	// Keys, if non-nil, is used to resolve
+	// the wrapped data keys of encrypted tables
+	// when a TenantHandle is opened (see FSEnv.DeferKeys).
+	Keys plan.KeyProvider
+
 	// Local causes DecodeUploader to return a
 	// *db.DirFS instead of a *db.S3FS. This is
 	// intended to be used for testing.
@@ -84,10 +104,20 @@ func (t *TenantEnv) Stat(tbl expr.Node, h *plan.Hints) (plan.TableHandle, error)
 	if err != nil {
 		return nil, err
 	}
+	if sh, ok := th.(*SystemHandle); ok {
+		return sh, nil
+	}
 	return &TenantHandle{parent: t, FilterHandle: th.(*FilterHandle)}, nil
 }
 
 func (t *TenantEnv) DecodeHandle(d ion.Datum) (plan.TableHandle, error) {
+	if isSystemHandle(d) {
+		sh := new(SystemHandle)
+		if err := sh.Decode(d); err != nil {
+			return nil, err
+		}
+		return sh, nil
+	}
 	h := new(FilterHandle)
 	if err := h.Decode(d); err != nil {
 		return nil, err
@@ -124,11 +154,15 @@ func (h *TenantHandle) Open(ctx context.Context) (vm.Table, error) {
 	filt, _ := fh.CompileFilter()
 	segs := make([]dcache.Segment, 0, len(lst.Contents))
 	var size int64
+	var keyed map[*blob.Compressed]*blob.Compressed
 	for i := range lst.Contents {
 		if h.parent.HTTPClient != nil {
 			blob.Use(lst.Contents[i], h.parent.HTTPClient)
 		}
-		b := lst.Contents[i]
+		b, err := h.withKey(ctx, lst.Contents[i], &keyed)
+		if err != nil {
+			return nil, err
+		}
 		if pc, ok := b.(*blob.CompressedPart); ok && filt != nil {
 			if !filt.Overlaps(&pc.Parent.Trailer.Sparse, pc.StartBlock, pc.EndBlock) {
 				continue
@@ -157,6 +191,52 @@ func (h *TenantHandle) Open(ctx context.Context) (vm.Table, error) {
 	return h.parent.Cache.MultiTable(ctx, segs, flags), nil
 }
 
+// withKey returns b with the data key of an
+// encrypted blob resolved using the KeyProvider
+// of the parent environment; keyed tracks
+// the blobs for which a key has already been resolved
+//
+// The blobs are copied rather than modified,
+// since the contents of a blob.List may be shared.
+func (h *TenantHandle) withKey(ctx context.Context, b blob.Interface, keyed *map[*blob.Compressed]*blob.Compressed) (blob.Interface, error) {
+	resolve := func(c *blob.Compressed) (*blob.Compressed, error) {
+		if c.Key != nil || c.WrappedKey == nil {
+			return c, nil
+		}
+		if out, ok := (*keyed)[c]; ok {
+			return out, nil
+		}
+		if h.parent.Keys == nil {
+			return nil, fmt.Errorf("%s is encrypted, but no key provider is available", blob.Name(c))
+		}
+		key, err := h.parent.Keys.DataKey(ctx, c.WrappedKey)
+		if err != nil {
+			return nil, fmt.Errorf("resolving data key for %s: %w", blob.Name(c), err)
+		}
+		out := *c
+		out.Key = key
+		out.WrappedKey = nil
+		if *keyed == nil {
+			*keyed = make(map[*blob.Compressed]*blob.Compressed)
+		}
+		(*keyed)[c] = &out
+		return &out, nil
+	}
+	switch c := b.(type) {
+	case *blob.Compressed:
+		return resolve(c)
+	case *blob.CompressedPart:
+		parent, err := resolve(c.Parent)
+		if err != nil || parent == c.Parent {
+			return b, err
+		}
+		part := *c
+		part.Parent = parent
+		return &part, nil
+	}
+	return b, nil
+}
+
 func (h *TenantHandle) Filter(e expr.Node) plan.TableHandle {
 	return &TenantHandle{
 		parent:       h.parent,
@@ -331,23 +411,46 @@ func vmMalloc(size int) []byte {
 
 // Decode implements dcache.Segment.Decode
 func (b *blobSegment) Decode(dst io.Writer, src []byte) error {
+	return b.DecodeParallel([]io.Writer{dst}, src)
+}
+
+func decompressors(writers int) int {
+	if DecompressParallel > 0 {
+		return DecompressParallel
+	}
+	return writers
+}
+
+var _ dcache.ParallelSegment = (*blobSegment)(nil)
+
+// DecodeParallel implements dcache.ParallelSegment.DecodeParallel
+//
+// Compressed blocks are distributed across dst;
+// uncompressed data is written to dst[0].
+func (b *blobSegment) DecodeParallel(dst []io.Writer, src []byte) error {
 	if c, ok := b.blob.(*blob.CompressedPart); ok {
 		// compressed: do decoding
 		var dec blockfmt.Decoder
 		dec.Malloc = vmMalloc
 		dec.Free = vm.Free
 		dec.Fields = b.fieldList()
-		dec.Set(&c.Parent.Trailer, c.EndBlock)
-		_, err := dec.CopyBytes(dst, src)
+		dec.Name = blob.Name(c)
+		dec.Key = c.Parent.Key
+		dec.Path = c.Parent.Path
+		dec.SetRange(&c.Parent.Trailer, c.StartBlock, c.EndBlock)
+		_, err := dec.CopyParallel(dst, src, decompressors(len(dst)))
 		return err
 	}
 	if c, ok := b.blob.(*blob.Compressed); ok {
 		var dec blockfmt.Decoder
 		dec.Malloc = vmMalloc
 		dec.Free = vm.Free
-		dec.Set(&c.Trailer, len(c.Trailer.Blocks))
+		dec.SetRange(&c.Trailer, 0, len(c.Trailer.Blocks))
 		dec.Fields = b.fieldList()
-		_, err := dec.CopyBytes(dst, src)
+		dec.Name = blob.Name(c)
+		dec.Key = c.Key
+		dec.Path = c.Path
+		_, err := dec.CopyParallel(dst, src, decompressors(len(dst)))
 		return err
 	}
 	// default: just write the segments directly
@@ -356,7 +459,7 @@ func (b *blobSegment) Decode(dst io.Writer, src []byte) error {
 		if len(mem) > b.info.Align {
 			mem = mem[:b.info.Align]
 		}
-		_, err := dst.Write(mem)
+		_, err := dst[0].Write(mem)
 		if err != nil {
 			return err
 		}
@@ -444,6 +444,18 @@ type Segment interface {
 	Decode(dst io.Writer, src []byte) error
 }
 
+// ParallelSegment is a Segment that can
+// decode its contents into more than one
+// output at once.
+type ParallelSegment interface {
+	Segment
+	// DecodeParallel is like Decode, except
+	// that it may distribute the data across
+	// the outputs in dst. Each output must only
+	// be written to from one goroutine.
+	DecodeParallel(dst []io.Writer, src []byte) error
+}
+
 // Table is an implementation of vm.Table
 // that wraps a Segment and attempts to provide
 // cached data in place of data read from the Segment.
@@ -520,15 +532,28 @@ func (c *Cache) Table(s Segment, f Flag) *Table {
 	}
 }
 
-func (t *Table) write(w io.Writer) error {
-	ret := make(chan error, 1)
-	t.cache.queue.send(t.seg, w, t.flags, &t.Stats, ret)
-	return <-ret
+func (t *Table) write(dst []io.Writer) error {
+	ret := make(chan error, len(dst))
+	t.cache.queue.send(t.seg, dst, t.flags, &t.Stats, ret)
+	return wait(ret, len(dst))
+}
+
+// wait receives n results from ret
+// and returns the first error
+func wait(ret <-chan error, n int) error {
+	var err error
+	for i := 0; i < n; i++ {
+		if e := <-ret; err == nil {
+			err = e
+		}
+	}
+	return err
 }
 
 // slow-path: read data from the segment into the cache
 // and write it out to the destination at the same time
-func readThrough(seg Segment, mp *mapping, w io.Writer) (bool, error) {
+func readThrough(res *reservation, mp *mapping) (bool, error) {
+	seg := res.seg
 	rd, err := seg.Open()
 	if err != nil {
 		if errors.Is(err, io.EOF) {
@@ -542,7 +567,7 @@ func readThrough(seg Segment, mp *mapping, w io.Writer) (bool, error) {
 		buf = mp.mem
 	} else {
 		if wt, ok := rd.(io.WriterTo); ok {
-			_, err := wt.WriteTo(w)
+			_, err := wt.WriteTo(res)
 			return false, err
 		}
 		size := seg.Size()
@@ -556,7 +581,7 @@ func readThrough(seg Segment, mp *mapping, w io.Writer) (bool, error) {
 		}
 		return false, err
 	}
-	return mp != nil, seg.Decode(w, buf)
+	return mp != nil, res.decode(buf)
 }
 
 // WriteChunks implements vm.Table.WriteChunks
@@ -567,6 +592,12 @@ func readThrough(seg Segment, mp *mapping, w io.Writer) (bool, error) {
 // Each call to WriteChunks accesses the cache a separate time,
 // so it is safe to re-use a Table as long as it is accessed
 // from a single goroutine at a time.
+//
+// If the segment is a ParallelSegment, it is
+// decoded into up to parallel outputs at once.
 func (t *Table) WriteChunks(dst vm.QuerySink, parallel int) error {
-	return vm.SplitInput(dst, 1, t.write)
+	if _, ok := t.seg.(ParallelSegment); !ok {
+		parallel = 1
+	}
+	return vm.SplitInputLanes(dst, 1, parallel, t.write)
 }
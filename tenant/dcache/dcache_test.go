@@ -738,3 +738,106 @@ func testMulti(t *testing.T, flags Flag, hits, misses int64) {
 		want += mo.possible[i].raw
 	}
 }
+
+// parallelSegment is a testSegment that
+// distributes its chunks across its outputs
+type parallelSegment struct {
+	*testSegment
+}
+
+func (ps parallelSegment) Merge(other Segment) {
+	ps.testSegment.Merge(other.(parallelSegment).testSegment)
+}
+
+func (ps parallelSegment) DecodeParallel(dst []io.Writer, src []byte) error {
+	if len(src) != len(ps.all) {
+		panic("unexpected source length")
+	}
+	errs := make([]error, len(dst))
+	var wg sync.WaitGroup
+	wg.Add(len(dst))
+	for i := range dst {
+		go func(i int) {
+			defer wg.Done()
+			for off := i * ps.align; off < len(ps.all); off += len(dst) * ps.align {
+				mem := ps.all[off:]
+				if len(mem) > ps.align {
+					mem = mem[:ps.align]
+				}
+				if _, err := dst[i].Write(mem); err != nil {
+					errs[i] = err
+					return
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+	for i := range errs {
+		if errs[i] != nil {
+			return errs[i]
+		}
+	}
+	return nil
+}
+
+// laneOutput opens a distinct output
+// for each lane and counts the outputs
+// that were written to
+type laneOutput struct {
+	*multiOutput
+	lock    sync.Mutex
+	written map[*laneWriter]bool
+}
+
+type laneWriter struct {
+	parent *laneOutput
+}
+
+func (lo *laneOutput) Open() (io.WriteCloser, error) {
+	return &laneWriter{parent: lo}, nil
+}
+
+func (lw *laneWriter) Write(p []byte) (int, error) {
+	lw.parent.lock.Lock()
+	lw.parent.written[lw] = true
+	lw.parent.lock.Unlock()
+	return lw.parent.multiOutput.Write(p)
+}
+
+func (lw *laneWriter) Close() error { return nil }
+
+func (lw *laneWriter) EndSegment() {}
+
+func TestParallelSegment(t *testing.T) {
+	testFiles(t)
+	seg0 := randseg(1000, 2, 35000)
+	seg1 := randseg(1352, 3, 15872)
+	lo := &laneOutput{
+		multiOutput: &multiOutput{possible: []*testSegOutput{seg0.testout(), seg1.testout()}},
+		written:     make(map[*laneWriter]bool),
+	}
+	c := New(t.TempDir(), func() {})
+	defer c.Close()
+	for _, flags := range []Flag{0, FlagNoFill} {
+		tbl := c.MultiTable(context.Background(), []Segment{parallelSegment{seg0}, parallelSegment{seg1}}, flags)
+		err := tbl.WriteChunks(lo, 8)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, out := range lo.possible {
+			out.rep = 1
+			out.endsegs = 1
+			if err := out.check(); err != nil {
+				t.Fatal(err)
+			}
+			for i := range out.segok {
+				out.segok[i] = 0
+			}
+		}
+		// 2 segments decoded into 4 outputs each
+		if len(lo.written) != 8 {
+			t.Errorf("flags %d: wrote to %d outputs; expected 8", flags, len(lo.written))
+		}
+		lo.written = make(map[*laneWriter]bool)
+	}
+}
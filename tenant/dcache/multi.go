@@ -66,7 +66,7 @@ func (m *MultiTable) get() *Table {
 	return t
 }
 
-func (m *MultiTable) write(w io.Writer) error {
+func (m *MultiTable) write(dst []io.Writer) error {
 	var ret chan error
 	for {
 		t := m.get()
@@ -74,10 +74,10 @@ func (m *MultiTable) write(w io.Writer) error {
 			break
 		}
 		if ret == nil {
-			ret = make(chan error, 1)
+			ret = make(chan error, len(dst))
 		}
-		t.cache.queue.send(t.seg, w, t.flags, &m.Stats, ret)
-		err := <-ret
+		t.cache.queue.send(t.seg, dst, t.flags, &m.Stats, ret)
+		err := wait(ret, len(dst))
 		if err != nil {
 			return err
 		}
@@ -85,6 +85,21 @@ func (m *MultiTable) write(w io.Writer) error {
 	return nil
 }
 
+// lanes returns the number of outputs
+// into which each segment is decoded when
+// parallel outputs are divided among n goroutines
+func (m *MultiTable) lanes(parallel, n int) int {
+	if n == 0 || parallel <= n {
+		return 1
+	}
+	for i := range m.inner {
+		if _, ok := m.inner[i].seg.(ParallelSegment); !ok {
+			return 1
+		}
+	}
+	return parallel / n
+}
+
 func (m *MultiTable) open(parallel int) int {
 	// nothing really to do here, as we
 	// open inner tables lazily, but let's
@@ -100,8 +115,13 @@ func (m *MultiTable) open(parallel int) int {
 }
 
 // WriteChunks implements vm.Table.WriteChunks
+//
+// When there are fewer segments than parallel and
+// the segments are ParallelSegments, each segment
+// is decoded into more than one output at once.
 func (m *MultiTable) WriteChunks(dst vm.QuerySink, parallel int) error {
-	err := vm.SplitInput(dst, m.open(parallel), m.write)
+	n := m.open(parallel)
+	err := vm.SplitInputLanes(dst, n, m.lanes(parallel, n), m.write)
 	m.next = 0
 	if err != nil {
 		return err
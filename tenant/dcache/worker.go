@@ -18,25 +18,42 @@ import (
 	"io"
 	"sync"
 
+	"github.com/SnellerInc/sneller/ion"
 	"github.com/SnellerInc/sneller/vm"
 )
 
-func (r *reservation) add(w io.Writer, ret chan<- error, stats *Stats) {
-	done := func(pos int64, e error) {
+// add attaches the outputs of another query to r
+// and reports whether that was possible; every lane
+// of r needs an output, so a query that provides
+// fewer outputs than r has lanes cannot be attached
+func (r *reservation) add(dst []io.Writer, ret chan<- error, stats *Stats) bool {
+	if len(dst) < len(r.lanes) {
+		return false
+	}
+	for i := range r.lanes {
+		r.lanes[i].Add(dst[i], finalizer(stats, ret))
+	}
+	// the surplus outputs receive no data
+	for range dst[len(r.lanes):] {
+		ret <- nil
+	}
+	return true
+}
+
+func finalizer(stats *Stats, ret chan<- error) func(int64, error) {
+	return func(pos int64, e error) {
 		stats.addBytes(pos)
 		ret <- e
 	}
-	if r.out == nil {
-		r.out = vm.NewTeeWriter(w, done)
-		return
-	}
-	r.out.Add(w, done)
 }
 
 type reservation struct {
-	seg     Segment
-	etag    string
-	out     *vm.TeeWriter
+	seg  Segment
+	etag string
+	// lanes are the outputs into which the
+	// segment is decoded; each query attached
+	// to the reservation has an output in every lane
+	lanes   []*vm.TeeWriter
 	primary *Stats
 
 	// guarded by queue.lock
@@ -65,14 +82,16 @@ func (q *queue) tryBackground() bool {
 	}
 }
 
-func (q *queue) send(seg Segment, dst io.Writer, flags Flag, stats *Stats, ret chan<- error) {
+// send queues seg to be decoded into dst;
+// one value is sent on ret for each output in dst,
+// so ret should have a capacity of at least len(dst)
+func (q *queue) send(seg Segment, dst []io.Writer, flags Flag, stats *Stats, ret chan<- error) {
 	etag := seg.ETag()
 	q.lock.Lock()
 	// TODO: if len(q.reserved) is too large,
 	// reject the query here
-	if res, ok := q.reserved[etag]; ok {
+	if res, ok := q.reserved[etag]; ok && res.add(dst, ret, stats) {
 		res.seg.Merge(seg)
-		res.add(dst, ret, stats)
 		// treat this access as a hit, since it
 		// is coalesced with a miss
 		stats.hit()
@@ -80,34 +99,62 @@ func (q *queue) send(seg Segment, dst io.Writer, flags Flag, stats *Stats, ret c
 		return
 	}
 	res := &reservation{
-		seg:  seg,
-		etag: etag,
-		out: vm.NewTeeWriter(dst, func(pos int64, e error) {
-			stats.addBytes(pos)
-			ret <- e
-		}),
+		seg:     seg,
+		etag:    etag,
+		lanes:   make([]*vm.TeeWriter, len(dst)),
 		primary: stats,
 		flags:   flags,
 	}
-	q.reserved[etag] = res
+	for i := range dst {
+		res.lanes[i] = vm.NewTeeWriter(dst[i], finalizer(stats, ret))
+	}
+	// (a reservation that could not be coalesced
+	// with the existing one for the same segment
+	// is queued without being reserved)
+	if _, ok := q.reserved[etag]; !ok {
+		q.reserved[etag] = res
+	}
 	q.lock.Unlock()
 	q.out <- res
 }
 
 // implements blockfmt.ZionWriter
 func (r *reservation) ConfigureZion(fields []string) bool {
-	return r.out.ConfigureZion(fields)
+	return len(r.lanes) == 1 && r.lanes[0].ConfigureZion(fields)
 }
 
+// Write writes to the first lane
 func (r *reservation) Write(p []byte) (int, error) {
-	return r.out.Write(p)
+	return r.lanes[0].Write(p)
+}
+
+// WriteSymtab implements blockfmt.SymtabWriter
+// by writing to the first lane
+func (r *reservation) WriteSymtab(p []byte, st *ion.Symtab, size int) (int, error) {
+	return r.lanes[0].WriteSymtab(p, st, size)
+}
+
+// decode decodes src into the lanes of r
+func (r *reservation) decode(src []byte) error {
+	if len(r.lanes) > 1 {
+		if ps, ok := r.seg.(ParallelSegment); ok {
+			dst := make([]io.Writer, len(r.lanes))
+			for i := range r.lanes {
+				dst[i] = r.lanes[i]
+			}
+			return ps.DecodeParallel(dst, src)
+		}
+	}
+	return r.seg.Decode(r, src)
 }
 
 func (r *reservation) close(err error) {
-	if err == nil {
-		r.out.Close()
-	} else {
-		r.out.CloseError(err)
+	for i := range r.lanes {
+		if err == nil {
+			r.lanes[i].Close()
+		} else {
+			r.lanes[i].CloseError(err)
+		}
 	}
 }
 
@@ -133,7 +180,7 @@ func (c *Cache) asyncReadThrough(res *reservation, mp *mapping) bool {
 	}
 	go func() {
 		defer c.queue.endBackground()
-		pop, err := readThrough(res.seg, mp, res)
+		pop, err := readThrough(res, mp)
 		if mp != nil {
 			c.finalize(mp, pop)
 			c.unmap(mp)
@@ -153,14 +200,16 @@ outer:
 		// remove from reserved map
 		// so that res.aux is safe to access
 		q.lock.Lock()
-		delete(q.reserved, res.etag)
+		if q.reserved[res.etag] == res {
+			delete(q.reserved, res.etag)
+		}
 		q.lock.Unlock()
 
 		var err error
 		pop := false
 		if mp != nil && mp.populated {
 			res.hit()
-			err = res.seg.Decode(res, mp.mem)
+			err = res.decode(mp.mem)
 			c.unmap(mp)
 		} else {
 			res.miss()
@@ -168,7 +217,7 @@ outer:
 				// res.close() will be called elsewhere
 				continue outer
 			}
-			pop, err = readThrough(res.seg, mp, res)
+			pop, err = readThrough(res, mp)
 			if mp != nil {
 				c.finalize(mp, pop)
 				c.unmap(mp)
@@ -23,6 +23,7 @@ import (
 	"time"
 
 	"github.com/SnellerInc/sneller/heap"
+	"github.com/SnellerInc/sneller/tempstore"
 )
 
 // tenant cache eviction implementation
@@ -200,6 +201,11 @@ func (m *Manager) fill(t *totalHeap, wantsize int64) {
 		if err != nil {
 			return err
 		}
+		if d.IsDir() && d.Name() == tempstore.DirName {
+			// temporary files are owned by running
+			// queries and have their own quota
+			return filepath.SkipDir
+		}
 		if !d.Type().IsRegular() {
 			// don't care about directories,
 			// links, etc.
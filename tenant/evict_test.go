@@ -22,6 +22,8 @@ import (
 	"strings"
 	"testing"
 	"time"
+
+	"github.com/SnellerInc/sneller/tempstore"
 )
 
 func (t *totalHeap) count() int {
@@ -288,3 +290,49 @@ func TestIssue645(t *testing.T) {
 	}
 
 }
+
+// test that temporary files used by
+// running queries are never evicted
+func TestEvictSkipsTemp(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("this doesn't work on windows")
+	}
+
+	oldusage, oldatime := usage, atime
+	t.Cleanup(func() {
+		usage = oldusage
+		atime = oldatime
+	})
+	tmp := t.TempDir()
+
+	base := time.Now().UnixNano()
+	cached := filepath.Join(tmp, "0", "00")
+	spill := filepath.Join(tmp, "0", tempstore.DirName, "q1", "spill")
+	for _, p := range []string{cached, spill} {
+		os.MkdirAll(filepath.Dir(p), 0755)
+		err := os.WriteFile(p, []byte(strings.Repeat("a", 100)), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	// the disk is always full, and the
+	// spill file is old enough that it
+	// would be removed unconditionally
+	usage = func(string) (int64, int64) { return 2000, 2000 }
+	atime = func(i fs.FileInfo) int64 {
+		if i.Name() == "spill" {
+			return base - int64(2*time.Hour)
+		}
+		return base
+	}
+
+	m := NewManager([]string{"/bin/false"})
+	m.CacheDir = tmp
+	m.cacheEvict()
+	if _, err := os.Stat(cached); err == nil {
+		t.Error("cached file was not evicted")
+	}
+	if _, err := os.Stat(spill); err != nil {
+		t.Errorf("spill file was evicted: %v", err)
+	}
+}
@@ -183,6 +183,10 @@ type Manager struct {
 
 	// warn about being unable to sandbox exactly once
 	warnOnce sync.Once
+
+	// handshake is set if tenants are sent
+	// a tnproto.Handshake before their first query
+	handshake bool
 }
 
 // Option is an optional argument
@@ -201,6 +205,28 @@ func WithGCInterval(interval time.Duration) Option {
 	}
 }
 
+// WithHandshake is an option that can be
+// passed to NewManager to indicate that the
+// protocol version and capabilities should be
+// negotiated with each tenant (see tnproto.Handshake)
+// before it is sent its first query, so that
+// requests for optional features that the tenant
+// does not support fail with a descriptive error.
+//
+// Tenant executables that predate protocol version 1
+// exit when they receive the handshake message,
+// so this option should only be provided when
+// the tenant executable is known to be at least
+// as new as this package (for example, when it
+// is the same executable as the caller).
+// Without this option, requests for optional
+// features are sent to tenants unchecked.
+func WithHandshake() Option {
+	return func(m *Manager) {
+		m.handshake = true
+	}
+}
+
 const DefaultCacheDir = "/tmp/tenant-cache"
 
 // DefaultEnv is the default
@@ -330,6 +356,15 @@ type child struct {
 	ctl     *net.UnixConn
 	touched time.Time
 	cg      cgroup.Dir
+
+	// proto is the protocol negotiated
+	// with the child; it is only valid
+	// once negotiated is set
+	proto      tnproto.Hello
+	negotiated bool
+	// handshake is set if the protocol
+	// should be negotiated (see WithHandshake)
+	handshake bool
 }
 
 var bufPool = sync.Pool{
@@ -370,9 +405,26 @@ func (c *child) unlock() {
 // currently pending for the same tenant.
 var ErrOverloaded = errors.New("child overloaded")
 
-func (c *child) directExec(t *plan.Tree, ofmt tnproto.OutputFormat, conn net.Conn) (io.ReadCloser, error) {
+// negotiate negotiates the protocol version
+// with the child if it has not been done yet
+// and the Manager was configured WithHandshake;
+// the caller must hold the child lock
+func (c *child) negotiate() error {
+	if c.negotiated || !c.handshake {
+		return nil
+	}
+	h, err := tnproto.Handshake(c.ctl)
+	if err != nil {
+		return err
+	}
+	c.proto = h
+	c.negotiated = true
+	return nil
+}
+
+func (c *child) directExec(t *plan.Tree, ofmt tnproto.OutputFormat, enc tnproto.Encoding, conn net.Conn) (io.ReadCloser, error) {
 	buf := bufPool.Get().(*tnproto.Buffer)
-	err := buf.Prepare(t, ofmt)
+	err := buf.PrepareEncoded(t, ofmt, enc)
 	if err != nil {
 		return nil, err
 	}
@@ -380,16 +432,60 @@ func (c *child) directExec(t *plan.Tree, ofmt tnproto.OutputFormat, conn net.Con
 		return nil, ErrOverloaded
 	}
 	defer c.unlock()
+	if err := c.negotiate(); err != nil {
+		return nil, err
+	}
+	if err := c.supports(ofmt, enc); err != nil {
+		return nil, err
+	}
 	ret, err := buf.DirectExec(c.ctl, conn)
 	bufPool.Put(buf)
 	return ret, err
 }
 
-func (c *child) proxyExec(peer net.Conn) error {
+// supports returns an error if the child
+// is known not to support the requested output;
+// without a handshake, the request is sent as-is
+func (c *child) supports(ofmt tnproto.OutputFormat, enc tnproto.Encoding) error {
+	if !c.negotiated {
+		return nil
+	}
+	if enc.Compression != tnproto.CompressNone && c.proto.Caps&tnproto.CapCompression == 0 {
+		return fmt.Errorf("tenant does not support %s output", enc.Compression)
+	}
+	if ofmt.Streaming() && c.proto.Caps&tnproto.CapHeartbeat == 0 {
+		return fmt.Errorf("tenant does not support %s output", ofmt)
+	}
+	if enc.JSON != (ion.JSONOptions{}) && c.proto.Caps&tnproto.CapJSONOptions == 0 {
+		return fmt.Errorf("tenant does not support JSON output options")
+	}
+	return nil
+}
+
+// proxyExec attaches peer to the tenant;
+// if the peer asked for compression (see
+// tnproto.AttachCompressed), then the connection
+// is compressed if the tenant supports it
+func (c *child) proxyExec(peer net.Conn, want tnproto.Hello) error {
 	if !c.lock() {
 		return ErrOverloaded
 	}
 	defer c.unlock()
+	if err := c.negotiate(); err != nil {
+		return err
+	}
+	// (a tenant that has not negotiated the
+	// protocol is never sent compressed data)
+	if want.Caps&tnproto.CapZstd == 0 {
+		return tnproto.ProxyExec(c.ctl, peer)
+	}
+	compress := c.proto.Caps&tnproto.CapZstd != 0
+	if err := tnproto.Acknowledge(peer, compress); err != nil {
+		return err
+	}
+	if compress {
+		return tnproto.ProxyExecCompressed(c.ctl, peer)
+	}
 	return tnproto.ProxyExec(c.ctl, peer)
 }
 
@@ -623,12 +719,13 @@ func (m *Manager) launch(id tnproto.ID, key tnproto.Key) (*child, error) {
 	avail := make(chan struct{}, 1)
 	avail <- struct{}{}
 	return &child{
-		key:     key,
-		avail:   avail,
-		proc:    cmd.Process,
-		ctl:     local,
-		touched: time.Now(),
-		cg:      cg,
+		key:       key,
+		avail:     avail,
+		proc:      cmd.Process,
+		ctl:       local,
+		touched:   time.Now(),
+		cg:        cg,
+		handshake: m.handshake,
 	}, nil
 }
 
@@ -691,11 +788,17 @@ func (m *Manager) get(id tnproto.ID, key tnproto.Key) (*child, error) {
 // to Do will not close the connection from
 // the perspective of the tenant process.)
 func (m *Manager) Do(id tnproto.ID, key tnproto.Key, t *plan.Tree, ofmt tnproto.OutputFormat, into net.Conn) (io.ReadCloser, error) {
+	return m.DoEncoded(id, key, t, ofmt, tnproto.Encoding{}, into)
+}
+
+// DoEncoded is identical to Do, except that
+// the query output is compressed according to enc.
+func (m *Manager) DoEncoded(id tnproto.ID, key tnproto.Key, t *plan.Tree, ofmt tnproto.OutputFormat, enc tnproto.Encoding, into net.Conn) (io.ReadCloser, error) {
 	c, err := m.get(id, key)
 	if err != nil {
 		return nil, err
 	}
-	return c.directExec(t, ofmt, into)
+	return c.directExec(t, ofmt, enc, into)
 }
 
 // Quit sends a SIGQUIT to the tenant process
@@ -752,9 +855,13 @@ func (m *Manager) errorf(msg string, args ...interface{}) {
 // tenant on *this* machine
 func (m *Manager) handleRemote(conn net.Conn) {
 	defer conn.Close()
-	id, key, err := tnproto.ReadHeader(conn)
+	id, key, hello, err := tnproto.ReadHello(conn)
 	if err != nil {
 		m.errorf("connection: %s", err)
+		var verr *tnproto.VersionError
+		if errors.As(err, &verr) {
+			reject(conn, err)
+		}
 		return
 	}
 	if id.IsZero() {
@@ -765,12 +872,25 @@ func (m *Manager) handleRemote(conn net.Conn) {
 		m.errorf("couldn't spawn %x: %s", id, err)
 		return
 	}
-	err = c.proxyExec(conn)
+	err = c.proxyExec(conn, hello)
 	if err != nil {
 		m.errorf("id %s: proxy-exec: %s", id, err)
 	}
 }
 
+// reject responds to a remote connection
+// with an error instead of attaching it to a tenant
+func reject(conn net.Conn, err error) {
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	if plan.Reject(conn, err) != nil {
+		return
+	}
+	// wait for the peer to hang up so that
+	// it has a chance to read the error
+	// before the connection is reset
+	io.Copy(io.Discard, conn)
+}
+
 // Stop performs a graceful cleanup
 // of all of the tenant manager subprocesses.
 //
@@ -230,6 +230,7 @@ func TestExec(t *testing.T) {
 		WithGCInterval(time.Hour),
 		WithLogger(log.New(&logbuf, "manager-log: ", 0)),
 		WithRemote(l),
+		WithHandshake(),
 	}
 	// try to do delegated cgroup trickery
 	if !cgroot.IsZero() {
@@ -616,6 +617,9 @@ func testEqual(t *testing.T, query string, m *Manager, id tnproto.ID, key tnprot
 	if len(want) > 0 {
 		t.Errorf("failed to match %d trailing rows", len(want))
 	}
+	if stats.RowsEmitted != int64(rownum) {
+		t.Errorf("%d rows emitted; wanted %d", stats.RowsEmitted, rownum)
+	}
 }
 
 func testCancel(t *testing.T, m *Manager) {
@@ -776,3 +780,33 @@ func toJSON(st *ion.Symtab, d ion.Datum) string {
 	}
 	return sb.String()
 }
+
+// without WithHandshake, a child is never sent
+// the handshake message (which tenants that predate
+// protocol negotiation do not understand), and
+// requests for optional features are not checked
+func TestNoHandshake(t *testing.T) {
+	local, remote, err := usock.SocketPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer local.Close()
+	defer remote.Close()
+	c := &child{ctl: local}
+	if err := c.negotiate(); err != nil {
+		t.Fatal(err)
+	}
+	if c.negotiated {
+		t.Fatal("negotiated without a handshake?")
+	}
+	err = c.supports(tnproto.OutputRaw, tnproto.Encoding{Compression: tnproto.CompressZstd})
+	if err != nil {
+		t.Fatal(err)
+	}
+	remote.SetReadDeadline(time.Now().Add(50 * time.Millisecond))
+	var buf [8]byte
+	n, err := remote.Read(buf[:])
+	if !errors.Is(err, os.ErrDeadlineExceeded) {
+		t.Fatalf("tenant received %q (error %v)", buf[:n], err)
+	}
+}
@@ -15,12 +15,16 @@
 package tnproto
 
 import (
+	"bytes"
 	"context"
 	"crypto/rand"
 	"encoding/binary"
+	"errors"
+	"fmt"
 	"io"
 	"net"
 	"runtime"
+	"strings"
 	"sync"
 	"testing"
 	"time"
@@ -149,3 +153,204 @@ func TestDirectExecHugeBody(t *testing.T) {
 	p.Close()
 	outerwg.Wait()
 }
+
+func TestNegotiate(t *testing.T) {
+	// a peer that predates negotiation
+	h, err := Negotiate(Hello{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h.Version != 0 || h.Caps != 0 {
+		t.Errorf("legacy peer: got %+v", h)
+	}
+	// a newer peer that can still speak our version
+	h, err = Negotiate(Hello{Version: Version + 3, MinVersion: Version, Caps: Capabilities | 1<<20})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h.Version != Version || h.Caps != Capabilities {
+		t.Errorf("newer peer: got %+v", h)
+	}
+	// a newer peer that cannot
+	_, err = Negotiate(Hello{Version: Version + 3, MinVersion: Version + 1})
+	var verr *VersionError
+	if !errors.As(err, &verr) {
+		t.Fatalf("expected a *VersionError; got %v", err)
+	}
+	if verr.Remote.MinVersion != Version+1 || verr.Local.Version != Version {
+		t.Errorf("unexpected error %+v", verr)
+	}
+}
+
+func TestAttachVersion(t *testing.T) {
+	r, w := net.Pipe()
+	defer r.Close()
+	id, key := randpair()
+	go func() {
+		var hdr header
+		hdr.populate(id, key, Capabilities)
+		Hello{Version: Version + 2, MinVersion: Version + 1}.put(hdr.body[helloOffset:])
+		w.Write(hdr.body[:])
+		w.Close()
+	}()
+	_, _, err := ReadHeader(r)
+	var verr *VersionError
+	if !errors.As(err, &verr) {
+		t.Fatalf("expected a *VersionError; got %v", err)
+	}
+
+	r, w = net.Pipe()
+	defer r.Close()
+	go func() {
+		Attach(w, id, key)
+		w.Close()
+	}()
+	_, _, h, err := ReadHello(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// plain Attach does not ask for compression
+	if h.Version != Version || h.Caps != Capabilities&^CapZstd {
+		t.Errorf("got %+v", h)
+	}
+}
+
+func TestAttachCompressed(t *testing.T) {
+	id, key := randpair()
+	run := func(t *testing.T, compress bool) {
+		r, w := net.Pipe()
+		defer r.Close()
+		errc := make(chan error, 1)
+		go func() {
+			defer w.Close()
+			conn, err := AttachCompressed(w, id, key)
+			if err != nil {
+				errc <- err
+				return
+			}
+			if _, ok := conn.(*zstdConn); ok != compress {
+				errc <- fmt.Errorf("compressed connection: %v", ok)
+				return
+			}
+			_, err = conn.Write([]byte("ping"))
+			if err != nil {
+				errc <- err
+				return
+			}
+			var buf [4]byte
+			_, err = io.ReadFull(conn, buf[:])
+			if err == nil && string(buf[:]) != "pong" {
+				err = fmt.Errorf("got %q", buf[:])
+			}
+			errc <- err
+		}()
+		outid, outkey, h, err := ReadHello(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if outid != id || outkey != key {
+			t.Fatal("id or key mismatch")
+		}
+		if h.Caps&CapZstd == 0 {
+			t.Fatalf("hello %+v does not request compression", h)
+		}
+		err = Acknowledge(r, compress)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var conn net.Conn = r
+		if compress {
+			conn, err = newZstdConn(r)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		var buf [4]byte
+		_, err = io.ReadFull(conn, buf[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(buf[:]) != "ping" {
+			t.Fatalf("got %q", buf[:])
+		}
+		_, err = conn.Write([]byte("pong"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := <-errc; err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Run("zstd", func(t *testing.T) { run(t, true) })
+	t.Run("none", func(t *testing.T) { run(t, false) })
+
+	// a rejected connection should
+	// yield the error frame to the caller
+	t.Run("reject", func(t *testing.T) {
+		var want bytes.Buffer
+		plan.Reject(&want, errors.New("rejected"))
+		r, w := net.Pipe()
+		defer r.Close()
+		go func() {
+			ReadHello(r)
+			plan.Reject(r, errors.New("rejected"))
+			r.Close()
+		}()
+		conn, err := AttachCompressed(w, id, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+		got, err := io.ReadAll(conn)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, want.Bytes()) {
+			t.Fatalf("got %x; want %x", got, want.Bytes())
+		}
+	})
+}
+
+func TestHandshake(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip()
+	}
+	here, there, err := usock.SocketPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer here.Close()
+	errc := make(chan error, 1)
+	go func() {
+		errc <- Serve(there, nil)
+	}()
+	h, err := Handshake(here)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h.Version != Version || h.Caps != Capabilities {
+		t.Errorf("got %+v", h)
+	}
+	here.Close()
+	if err := <-errc; err != nil {
+		t.Fatal(err)
+	}
+	there.Close()
+
+	// a tenant that predates negotiation
+	// hangs up on the handshake message
+	here, there, err = usock.SocketPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer here.Close()
+	go func() {
+		var buf [8]byte
+		there.Read(buf[:])
+		there.Close()
+	}()
+	_, err = Handshake(here)
+	if err == nil || !strings.Contains(err.Error(), "predate") {
+		t.Fatalf("unexpected error %v", err)
+	}
+}
@@ -0,0 +1,250 @@
+// Copyright (C) 2022 Sneller, Inc.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package tnproto
+
+import (
+	"fmt"
+	"io"
+	"net"
+
+	"github.com/SnellerInc/sneller/ion"
+
+	"github.com/klauspost/compress/gzip"
+	"github.com/klauspost/compress/zstd"
+)
+
+// Compression selects the compression algorithm
+// applied to the output of a DirectExec request.
+type Compression byte
+
+const (
+	// CompressNone indicates that the
+	// output is not compressed
+	CompressNone Compression = iota
+	// CompressGzip indicates that the
+	// output is compressed with gzip
+	CompressGzip
+	// CompressZstd indicates that the
+	// output is compressed with zstd
+	CompressZstd
+)
+
+// String returns the HTTP content-coding
+// that corresponds to c, or the empty string
+// if c is CompressNone.
+func (c Compression) String() string {
+	switch c {
+	case CompressNone:
+		return ""
+	case CompressGzip:
+		return "gzip"
+	case CompressZstd:
+		return "zstd"
+	default:
+		return fmt.Sprintf("unknown compression %d", byte(c))
+	}
+}
+
+// ParseCompression returns the Compression
+// that corresponds to an HTTP content-coding.
+func ParseCompression(coding string) (Compression, bool) {
+	switch coding {
+	case "identity", "":
+		return CompressNone, true
+	case "gzip", "x-gzip":
+		return CompressGzip, true
+	case "zstd":
+		return CompressZstd, true
+	default:
+		return CompressNone, false
+	}
+}
+
+// Encoding describes the compression
+// applied to the output of a DirectExec request
+// and the formatting of JSON output.
+//
+// For the chunked output formats, the compressed
+// stream is written inside the HTTP chunked encoding,
+// so the HTTP response should indicate the
+// compression algorithm in its Content-Encoding header.
+type Encoding struct {
+	Compression Compression
+	// Level is the compression level to use;
+	// the meaning of the level depends on
+	// the compression algorithm, and the zero
+	// value selects the default level.
+	// Lower levels trade compression ratio
+	// for reduced CPU overhead.
+	Level int8
+	// JSON controls the formatting of values
+	// in the JSON output formats. Non-default
+	// options require CapJSONOptions.
+	JSON ion.JSONOptions
+}
+
+// jsonFlag is set in the compression byte of a
+// DirectExec message when it is followed by the
+// encoded JSON options; it is omitted for the
+// default options so that tenants without
+// CapJSONOptions can still decode the message
+const jsonFlag = 0x80
+
+// encodeJSON packs o into a single byte
+func encodeJSON(o *ion.JSONOptions) byte {
+	b := byte(o.Timestamps&3) | byte(o.NonFinite&3)<<2
+	if o.FloatPoint {
+		b |= 1 << 4
+	}
+	if o.NoExponent {
+		b |= 1 << 5
+	}
+	if o.BigIntsAsStrings {
+		b |= 1 << 6
+	}
+	return b
+}
+
+// decodeJSON is the inverse of encodeJSON
+func decodeJSON(b byte) ion.JSONOptions {
+	return ion.JSONOptions{
+		Timestamps:       ion.TimestampFormat(b & 3),
+		NonFinite:        ion.NonFiniteFormat((b >> 2) & 3),
+		FloatPoint:       b&(1<<4) != 0,
+		NoExponent:       b&(1<<5) != 0,
+		BigIntsAsStrings: b&(1<<6) != 0,
+	}
+}
+
+// NewWriter returns an io.WriteCloser that
+// compresses data into dst. Closing the returned
+// writer flushes the compressed stream, but does
+// not close dst.
+//
+// Separately-compressed streams may be concatenated,
+// so NewWriter can be used to append additional data
+// to the output of a DirectExec request.
+func (e Encoding) NewWriter(dst io.Writer) (io.WriteCloser, error) {
+	switch e.Compression {
+	case CompressNone:
+		return nopCloser{dst}, nil
+	case CompressGzip:
+		level := gzip.DefaultCompression
+		if e.Level != 0 {
+			level = int(e.Level)
+		}
+		w, err := gzip.NewWriterLevel(dst, level)
+		if err != nil {
+			return nil, err
+		}
+		return w, nil
+	case CompressZstd:
+		level := zstd.SpeedDefault
+		if e.Level != 0 {
+			level = zstd.EncoderLevelFromZstd(int(e.Level))
+		}
+		// use one goroutine per stream so that
+		// the CPU cost of compression is bounded
+		// by the number of concurrent queries
+		w, err := zstd.NewWriter(dst,
+			zstd.WithEncoderLevel(level),
+			zstd.WithEncoderConcurrency(1),
+			zstd.WithLowerEncoderMem(true))
+		if err != nil {
+			return nil, err
+		}
+		return w, nil
+	default:
+		return nil, fmt.Errorf("tnproto: %s", e.Compression)
+	}
+}
+
+type nopCloser struct {
+	io.Writer
+}
+
+func (n nopCloser) Close() error { return nil }
+
+// closers closes each of its
+// members in order and returns
+// the first error encountered
+type closers []io.Closer
+
+func (c closers) Close() error {
+	var err error
+	for i := range c {
+		if err2 := c[i].Close(); err == nil {
+			err = err2
+		}
+	}
+	return err
+}
+
+// zstdConn is a net.Conn that compresses the
+// data written to it and decompresses the data
+// read from it. Each call to Write is flushed
+// so that the peer can decode it immediately,
+// so it is suitable for framed protocols
+// like the one spoken by plan.Client.
+type zstdConn struct {
+	net.Conn
+	enc *zstd.Encoder
+	dec *zstd.Decoder
+}
+
+func newZstdConn(c net.Conn) (*zstdConn, error) {
+	enc, err := zstd.NewWriter(c,
+		zstd.WithEncoderLevel(zstd.SpeedFastest),
+		zstd.WithEncoderConcurrency(1),
+		zstd.WithLowerEncoderMem(true))
+	if err != nil {
+		return nil, err
+	}
+	return &zstdConn{Conn: c, enc: enc}, nil
+}
+
+func (z *zstdConn) Read(p []byte) (int, error) {
+	// the decoder is created lazily,
+	// since creating it may block
+	// reading the first frame header
+	if z.dec == nil {
+		dec, err := zstd.NewReader(z.Conn,
+			zstd.WithDecoderConcurrency(1),
+			zstd.WithDecoderLowmem(true))
+		if err != nil {
+			return 0, err
+		}
+		z.dec = dec
+	}
+	return z.dec.Read(p)
+}
+
+func (z *zstdConn) Write(p []byte) (int, error) {
+	n, err := z.enc.Write(p)
+	if err == nil {
+		err = z.enc.Flush()
+	}
+	return n, err
+}
+
+func (z *zstdConn) Close() error {
+	// the peer may have hung up already,
+	// so errors from the encoder are ignored
+	z.enc.Close()
+	if z.dec != nil {
+		z.dec.Close()
+	}
+	return z.Conn.Close()
+}
@@ -0,0 +1,170 @@
+// Copyright (C) 2022 Sneller, Inc.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package tnproto
+
+import (
+	"bytes"
+	"io"
+	"net/http/httputil"
+	"testing"
+
+	"github.com/SnellerInc/sneller/ion"
+	"github.com/klauspost/compress/gzip"
+	"github.com/klauspost/compress/zstd"
+)
+
+type bufCloser struct {
+	bytes.Buffer
+	closed bool
+}
+
+func (b *bufCloser) Close() error {
+	b.closed = true
+	return nil
+}
+
+func decompress(t *testing.T, c Compression, src io.Reader) []byte {
+	var r io.Reader
+	switch c {
+	case CompressNone:
+		r = src
+	case CompressGzip:
+		gr, err := gzip.NewReader(src)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer gr.Close()
+		r = gr
+	case CompressZstd:
+		zr, err := zstd.NewReader(src)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer zr.Close()
+		r = zr
+	}
+	out, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
+
+func TestOutputEncoding(t *testing.T) {
+	var st ion.Symtab
+	var buf ion.Buffer
+	rows := st.Intern("row")
+	st.Marshal(&buf, true)
+	for i := 0; i < 3; i++ {
+		buf.BeginStruct(-1)
+		buf.BeginField(rows)
+		buf.WriteInt(int64(i))
+		buf.EndStruct()
+	}
+	data := buf.Bytes()
+
+	formats := []struct {
+		format  OutputFormat
+		chunked bool
+		want    string
+	}{
+		{OutputRaw, false, string(data)},
+		{OutputChunkedIon, true, string(data)},
+		{OutputChunkedJSON, true, "{\"row\": 0}\n{\"row\": 1}\n{\"row\": 2}\n"},
+		{OutputChunkedJSONArray, true, `[{"row": 0},{"row": 1},{"row": 2}]`},
+	}
+	for _, c := range []Compression{CompressNone, CompressGzip, CompressZstd} {
+		for _, f := range formats {
+			t.Run(f.format.String()+"/"+c.String(), func(t *testing.T) {
+				var dst bufCloser
+				w, err := f.format.writer(&dst, Encoding{Compression: c, Level: 1})
+				if err != nil {
+					t.Fatal(err)
+				}
+				if _, err := w.Write(data); err != nil {
+					t.Fatal(err)
+				}
+				if err := w.Close(); err != nil {
+					t.Fatal(err)
+				}
+				if !dst.closed {
+					t.Fatal("destination not closed")
+				}
+				var src io.Reader = &dst.Buffer
+				if f.chunked {
+					// the writer does not write the terminating
+					// chunk (see OutputFormat.writer), so add it here
+					dst.WriteString("0\r\n\r\n")
+					src = httputil.NewChunkedReader(src)
+				}
+				got := decompress(t, c, src)
+				if string(got) != f.want {
+					t.Errorf("got %q, want %q", got, f.want)
+				}
+			})
+		}
+	}
+
+	var dst bufCloser
+	_, err := OutputFormat('x').writer(&dst, Encoding{})
+	if err == nil {
+		t.Error("expected an error for an unknown output format")
+	}
+	_, err = OutputFormat(OutputRaw).writer(&dst, Encoding{Compression: 0xff})
+	if err == nil {
+		t.Error("expected an error for an unknown compression algorithm")
+	}
+}
+
+func TestJSONOptions(t *testing.T) {
+	opts := []ion.JSONOptions{
+		{},
+		{Timestamps: ion.TimestampEpochMillis},
+		{NonFinite: ion.NonFiniteString, FloatPoint: true},
+		{NonFinite: ion.NonFiniteNull, NoExponent: true, BigIntsAsStrings: true},
+	}
+	for i := range opts {
+		if got := decodeJSON(encodeJSON(&opts[i])); got != opts[i] {
+			t.Errorf("round-trip of %+v produced %+v", opts[i], got)
+		}
+	}
+
+	var st ion.Symtab
+	var body, buf ion.Buffer
+	body.BeginStruct(-1)
+	body.BeginField(st.Intern("f"))
+	body.WriteFloat64(2)
+	body.EndStruct()
+	st.Marshal(&buf, true)
+	data := append(buf.Bytes(), body.Bytes()...)
+
+	var dst bufCloser
+	w, err := OutputFormat(OutputChunkedJSON).writer(&dst, Encoding{JSON: ion.JSONOptions{FloatPoint: true}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write(data); err != nil {
+		t.Fatal(err)
+	}
+	w.Close()
+	dst.WriteString("0\r\n\r\n")
+	got, err := io.ReadAll(httputil.NewChunkedReader(&dst.Buffer))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "{\"f\": 2.0}\n"; string(got) != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,135 @@
+// Copyright (C) 2022 Sneller, Inc.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package tnproto
+
+import (
+	"io"
+	"sync"
+	"time"
+
+	"github.com/SnellerInc/sneller/ion"
+)
+
+// heartbeatInterval is the amount of time that
+// a streaming query may go without producing
+// any output before a heartbeat is written
+var heartbeatInterval = 10 * time.Second
+
+type flusher interface {
+	Flush() error
+}
+
+// heartbeatWriter forwards query output to dst,
+// flushing the output encoder after each write,
+// and writes a heartbeat::{elapsed_ms: ...} value
+// whenever no output has been written for a full
+// heartbeatInterval so that idle connections are
+// not closed by load balancers or proxies
+type heartbeatWriter struct {
+	lock  sync.Mutex
+	dst   io.Writer
+	flush flusher // flushed after each write, or nil
+	final io.Closer
+	start time.Time
+	wrote bool // output was written since the last tick
+	err   error
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+func newHeartbeatWriter(dst io.Writer, enc io.Writer, final io.Closer) *heartbeatWriter {
+	h := &heartbeatWriter{
+		dst:   dst,
+		final: final,
+		start: time.Now(),
+		stop:  make(chan struct{}),
+		done:  make(chan struct{}),
+	}
+	h.flush, _ = enc.(flusher)
+	go h.run(heartbeatInterval)
+	return h
+}
+
+func (h *heartbeatWriter) run(interval time.Duration) {
+	defer close(h.done)
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-h.stop:
+			return
+		case <-t.C:
+		}
+		h.lock.Lock()
+		if !h.wrote && h.err == nil {
+			h.err = h.write(heartbeat(time.Since(h.start)))
+		}
+		h.wrote = false
+		h.lock.Unlock()
+	}
+}
+
+// write writes p to dst and flushes the encoder;
+// the caller must hold h.lock
+func (h *heartbeatWriter) write(p []byte) error {
+	_, err := h.dst.Write(p)
+	if err == nil && h.flush != nil {
+		err = h.flush.Flush()
+	}
+	return err
+}
+
+// Write implements io.Writer
+func (h *heartbeatWriter) Write(p []byte) (int, error) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	if h.err != nil {
+		return 0, h.err
+	}
+	h.err = h.write(p)
+	h.wrote = true
+	if h.err != nil {
+		return 0, h.err
+	}
+	return len(p), nil
+}
+
+// Close stops writing heartbeats
+// and closes the underlying writers
+func (h *heartbeatWriter) Close() error {
+	close(h.stop)
+	<-h.done
+	return h.final.Close()
+}
+
+// heartbeat produces an ion chunk containing
+//
+//	heartbeat::{elapsed_ms: ...}
+func heartbeat(elapsed time.Duration) []byte {
+	var st ion.Symtab
+	var buf ion.Buffer
+	sym := st.Intern("heartbeat")
+	ms := st.Intern("elapsed_ms")
+	st.Marshal(&buf, true)
+	buf.BeginAnnotation(1)
+	buf.BeginField(sym)
+	buf.BeginStruct(-1)
+	buf.BeginField(ms)
+	buf.WriteInt(elapsed.Milliseconds())
+	buf.EndStruct()
+	buf.EndAnnotation()
+	return buf.Bytes()
+}
@@ -25,11 +25,13 @@ import (
 	"io"
 	"net"
 	"net/http/httputil"
+	"sync/atomic"
 	"time"
 
 	"github.com/SnellerInc/sneller/ion"
 	"github.com/SnellerInc/sneller/plan"
 	"github.com/SnellerInc/sneller/usock"
+	"github.com/SnellerInc/sneller/vm"
 )
 
 // OutputFormat selects an output format
@@ -51,8 +53,24 @@ const (
 	// OutputChunkedJSONArray outputs a single
 	// JSON array object using HTTP chunked encoding
 	OutputChunkedJSONArray
+	// OutputStreamIon outputs an ion data stream
+	// using HTTP chunked encoding, flushing each
+	// chunk of results as it is produced and
+	// writing heartbeat::{elapsed_ms: ...} values
+	// while the query produces no output
+	OutputStreamIon
+	// OutputStreamJSON is the NDJSON equivalent
+	// of OutputStreamIon
+	OutputStreamJSON
 )
 
+// Streaming returns whether the output format
+// writes heartbeats while a query is running.
+// Streaming formats require CapHeartbeat.
+func (o OutputFormat) Streaming() bool {
+	return o == OutputStreamIon || o == OutputStreamJSON
+}
+
 func (o OutputFormat) String() string {
 	switch o {
 	case OutputRaw:
@@ -63,6 +81,10 @@ func (o OutputFormat) String() string {
 		return "chunked-json"
 	case OutputChunkedJSONArray:
 		return "chunked-json-array"
+	case OutputStreamIon:
+		return "stream-ion"
+	case OutputStreamJSON:
+		return "stream-json"
 	default:
 		return fmt.Sprintf("unknown format %c", byte(o))
 	}
@@ -73,18 +95,40 @@ func (o OutputFormat) String() string {
 // handled by the net/http package when
 // the parent's HTTP handler returns,
 // hence we do not call http.NewChunkedWriter(...).Close()
-func (o OutputFormat) writer(dst io.WriteCloser) io.WriteCloser {
+//
+// the compressed stream (if any) is written
+// inside the chunked encoding
+func (o OutputFormat) writer(dst io.WriteCloser, enc Encoding) (io.WriteCloser, error) {
+	var inner io.Writer = dst
 	switch o {
 	case OutputRaw:
-		return dst
-	case OutputChunkedIon:
-		return &writerCloser{Writer: httputil.NewChunkedWriter(dst), Closer: dst}
+		if enc.Compression == CompressNone {
+			return dst, nil
+		}
+	case OutputChunkedIon, OutputChunkedJSON, OutputChunkedJSONArray,
+		OutputStreamIon, OutputStreamJSON:
+		inner = httputil.NewChunkedWriter(dst)
+	default:
+		return nil, fmt.Errorf("bad output format: %s", o)
+	}
+	cw, err := enc.NewWriter(inner)
+	if err != nil {
+		return nil, err
+	}
+	switch o {
 	case OutputChunkedJSON:
-		return httpChunkedJSON(dst)
+		return httpChunkedJSON(cw, &enc.JSON, closers{cw, dst}), nil
 	case OutputChunkedJSONArray:
-		return httpJSONArray(dst)
+		return httpJSONArray(cw, &enc.JSON, closers{cw, dst}), nil
+	case OutputStreamIon:
+		return newHeartbeatWriter(cw, cw, closers{cw, dst}), nil
+	case OutputStreamJSON:
+		jw := ion.NewJSONWriter(cw, '\n')
+		jw.ShowAnnotations = true
+		jw.Options = enc.JSON
+		return newHeartbeatWriter(jw, cw, closers{cw, dst}), nil
 	default:
-		panic(fmt.Sprintf("bad output format: %s", o))
+		return &writerCloser{Writer: cw, Closer: closers{cw, dst}}, nil
 	}
 }
 
@@ -106,6 +150,24 @@ func remote(text string) *RemoteError {
 	return &RemoteError{Text: text}
 }
 
+// QueryMemoryLimit, if positive, is the maximum
+// number of bytes of vm memory used to scan the
+// inputs of each query executed by Serve
+// (see plan.ExecParams.Memory).
+var QueryMemoryLimit int
+
+// Speculation, if positive, enables speculative
+// re-execution of straggling sub-queries of the
+// queries executed by Serve
+// (see plan.ExecParams.Speculation).
+var Speculation float64
+
+// SubqueryTimeout is the maximum amount of time
+// that each sub-query of the queries executed
+// by Serve may run before it is canceled
+// (see plan.ExecParams.SubqueryTimeout).
+var SubqueryTimeout time.Duration
+
 var (
 	// prologue to establishing a proxy connection
 	proxymsg = []byte("proxyme\n")
@@ -114,7 +176,11 @@ var (
 	// into a provided file descriptor;
 	// the first 4 zero chars are replaced with
 	// the length of the message (in binary)
-	// and the final char is set to the output format
+	// and the final char is set to the output format;
+	// the body of the message begins with the
+	// output compression flags (see Encoding),
+	// followed by the JSON options if jsonFlag is
+	// set in the compression byte
 	directmsg = []byte("dir00000")
 
 	// response from a tenant that the query plan
@@ -125,6 +191,17 @@ var (
 	// begun execution and error(s) will be written
 	// over the returned pipe
 	detachmsg = []byte("detach!\n")
+
+	// prologue to negotiating the protocol version
+	// (see Handshake); the message is followed by
+	// the encoded Hello of the sender, and the tenant
+	// responds with the same message followed
+	// by its own Hello (or with errmsg)
+	hellomsg = []byte("hello!!\n")
+
+	// prologue to establishing a proxy connection
+	// that is compressed with zstd (see ProxyExecCompressed)
+	proxyzmsg = []byte("proxyz!\n")
 )
 
 // ProxyExec tells the tenant listening on the
@@ -144,6 +221,20 @@ func ProxyExec(ctl *net.UnixConn, conn net.Conn) error {
 	return err
 }
 
+// ProxyExecCompressed is identical to ProxyExec,
+// except that the tenant compresses the data
+// written to 'conn' and decompresses the data
+// read from 'conn' with zstd.
+//
+// The caller should only use ProxyExecCompressed
+// when the negotiated protocol includes CapZstd
+// and the remote end of 'conn' has been
+// told to expect compression (see Acknowledge).
+func ProxyExecCompressed(ctl *net.UnixConn, conn net.Conn) error {
+	_, err := usock.WriteWithConn(ctl, proxyzmsg, conn)
+	return err
+}
+
 // intermediate serialization state
 // for sending DirectExec messages
 // (sometimes the plan.Tree contains
@@ -157,12 +248,17 @@ type serializer struct {
 	prepared bool
 }
 
-func (s *serializer) prepare(t *plan.Tree, f OutputFormat) error {
+func (s *serializer) prepare(t *plan.Tree, f OutputFormat, enc Encoding) error {
 	s.prepared = false
 	s.stbuf.Reset()
 	copy(s.pre[:], directmsg)
 	s.pre[7] = byte(f)
 	s.stbuf.UnsafeAppend(s.pre[:]) // we will frob this later
+	if enc.JSON == (ion.JSONOptions{}) {
+		s.stbuf.UnsafeAppend([]byte{byte(enc.Compression), byte(enc.Level)})
+	} else {
+		s.stbuf.UnsafeAppend([]byte{byte(enc.Compression) | jsonFlag, byte(enc.Level), encodeJSON(&enc.JSON)})
+	}
 	s.mainbuf.Reset()
 	s.st.Reset()
 	err := t.Encode(&s.mainbuf, &s.st)
@@ -211,7 +307,14 @@ type Buffer struct {
 // the serialized query produced by
 // preceding calls to Prepare.
 func (b *Buffer) Prepare(t *plan.Tree, f OutputFormat) error {
-	return b.prepare(t, f)
+	return b.prepare(t, f, Encoding{})
+}
+
+// PrepareEncoded is identical to Prepare,
+// except that it additionally requests that
+// the query output be compressed according to enc.
+func (b *Buffer) PrepareEncoded(t *plan.Tree, f OutputFormat, enc Encoding) error {
+	return b.prepare(t, f, enc)
 }
 
 // DirectExec sends a query plan to a tenant
@@ -285,12 +388,15 @@ func (b *Buffer) DirectExec(ctl *net.UnixConn, conn net.Conn) (io.ReadCloser, er
 	return nil, fmt.Errorf("unexpected tenant response %q", b.pre[:])
 }
 
-// Serve responds to ProxyExec and DirectExec requests
-// over the given control socket.
+// Serve responds to Handshake, ProxyExec and DirectExec
+// requests over the given control socket.
 func Serve(ctl *net.UnixConn, dec plan.Decoder) error {
 	var msgbuf [8]byte
 	var st ion.Symtab
 	var tmp []byte
+	// callers that predate negotiation
+	// support no optional capabilities
+	var peer Hello
 	for {
 		n, conn, err := usock.ReadWithConn(ctl, msgbuf[:])
 		if err != nil {
@@ -302,6 +408,13 @@ func Serve(ctl *net.UnixConn, dec plan.Decoder) error {
 			}
 			return fmt.Errorf("tnproto.Serve: ReadWithConn: %w", err)
 		}
+		if conn == nil && n == len(msgbuf) && bytes.Equal(msgbuf[:], hellomsg) {
+			peer, err = hello(ctl, tmp)
+			if err != nil {
+				return err
+			}
+			continue
+		}
 		if conn == nil {
 			return fmt.Errorf("expected a control socket, but found none...?")
 		}
@@ -314,6 +427,13 @@ func Serve(ctl *net.UnixConn, dec plan.Decoder) error {
 		if bytes.Equal(msgbuf[:], proxymsg) {
 			// proxy request
 			go serveProxy(dec, conn)
+		} else if bytes.Equal(msgbuf[:], proxyzmsg) {
+			zc, err := newZstdConn(conn)
+			if err != nil {
+				conn.Close()
+				return fmt.Errorf("tnproto.Serve: %w", err)
+			}
+			go serveProxy(dec, zc)
 		} else if bytes.Equal(msgbuf[:3], directmsg[:3]) {
 			// need to read the plan
 			// and then execute it directly
@@ -333,13 +453,30 @@ func Serve(ctl *net.UnixConn, dec plan.Decoder) error {
 			if err != nil {
 				return fmt.Errorf("tnproto.Serve: reading DirectExec message: %w", err)
 			}
+			if len(tmp) < 2 {
+				return fmt.Errorf("tnproto.Serve: DirectExec message only %d bytes", len(tmp))
+			}
+			enc := Encoding{Compression: Compression(tmp[0] &^ jsonFlag), Level: int8(tmp[1])}
+			rest := tmp[2:]
+			if tmp[0]&jsonFlag != 0 {
+				if len(rest) < 1 {
+					return fmt.Errorf("tnproto.Serve: DirectExec message missing JSON options")
+				}
+				enc.JSON = decodeJSON(rest[0])
+				rest = rest[1:]
+			}
 			st.Reset()
-			tmp, err = st.Unmarshal(tmp)
+			body, err := st.Unmarshal(rest)
 			if err != nil {
 				return fmt.Errorf("tnproto.Serve: decoding symbol table: %w", err)
 			}
-			t, err := plan.Decode(dec, &st, tmp)
+			t, err := plan.Decode(dec, &st, body)
+			var out io.WriteCloser
+			if err == nil {
+				out, err = ofmt.writer(conn, enc)
+			}
 			if err != nil {
+				conn.Close()
 				err = errnow(ctl, err, tmp)
 				if err != nil {
 					return err
@@ -347,9 +484,10 @@ func Serve(ctl *net.UnixConn, dec plan.Decoder) error {
 			} else {
 				errorWriter, err := detach(ctl)
 				if err != nil {
+					out.Close()
 					return err
 				}
-				go serveDirect(t, ofmt.writer(conn), errorWriter)
+				go serveDirect(t, out, errorWriter, peer.Caps)
 			}
 		} else {
 			if conn != nil {
@@ -404,7 +542,7 @@ func sendError(conn io.WriteCloser, err error) {
 	conn.Write(buf.Bytes())
 }
 
-func serveDirect(t *plan.Tree, conn io.WriteCloser, errpipe net.Conn) {
+func serveDirect(t *plan.Tree, conn io.WriteCloser, errpipe net.Conn, caps Capability) {
 	defer errpipe.Close() // cancels ctx
 	ctx := pipectx(errpipe)
 
@@ -425,11 +563,23 @@ func serveDirect(t *plan.Tree, conn io.WriteCloser, errpipe net.Conn) {
 		}
 	}()
 	pl := plan.LocalTransport{}
+	aw := newAsyncWriter(conn, asyncDepth)
+	rc := &rowCounter{Writer: aw}
 	ep := plan.ExecParams{
-		Output:  conn,
+		Output:  rc,
 		Context: ctx,
+		Memory: &vm.Account{
+			Limit: (QueryMemoryLimit + vm.PageSize - 1) / vm.PageSize,
+		},
+		Speculation:     Speculation,
+		SubqueryTimeout: SubqueryTimeout,
 	}
 	err := pl.Exec(t, &ep)
+	// flush queued output before
+	// writing any error to conn
+	if err2 := aw.Close(); err == nil {
+		err = err2
+	}
 	if err != nil {
 		sendError(conn, err)
 	}
@@ -439,11 +589,160 @@ func serveDirect(t *plan.Tree, conn io.WriteCloser, errpipe net.Conn) {
 	if err != nil {
 		outbuf.WriteString(err.Error())
 	} else {
+		if caps&CapRowStats != 0 {
+			ep.Stats.RowsEmitted = atomic.LoadInt64(&rc.rows)
+		}
 		ep.Stats.Marshal(&outbuf)
 	}
 	errpipe.Write(outbuf.Bytes())
 }
 
+// rowCounter is an io.Writer that counts
+// the rows in the ion chunks written to it
+type rowCounter struct {
+	io.Writer
+	rows int64
+}
+
+func (r *rowCounter) Write(p []byte) (int, error) {
+	atomic.AddInt64(&r.rows, countRows(p))
+	return r.Writer.Write(p)
+}
+
+// Throttle implements vm.Throttler
+func (r *rowCounter) Throttle() error {
+	return vm.Throttle(r.Writer)
+}
+
+// asyncDepth is the number of query output
+// chunks that may be queued for a client
+// before query execution is throttled
+const asyncDepth = 4
+
+// asyncWriter writes to dst from a separate
+// goroutine so that query execution can continue
+// while a (possibly slow) client reads the output.
+// At most depth writes are queued at once;
+// Write and Throttle block while the queue is full,
+// which pauses scanning instead of buffering output.
+type asyncWriter struct {
+	dst    io.Writer
+	slots  chan struct{} // one entry per queued write
+	queue  chan []byte
+	spare  chan []byte   // buffers that can be re-used
+	failed chan struct{} // closed when err is set
+	done   chan struct{} // closed when the writer goroutine exits
+	err    error
+}
+
+func newAsyncWriter(dst io.Writer, depth int) *asyncWriter {
+	a := &asyncWriter{
+		dst:    dst,
+		slots:  make(chan struct{}, depth),
+		queue:  make(chan []byte, depth),
+		spare:  make(chan []byte, depth),
+		failed: make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	go a.run()
+	return a
+}
+
+func (a *asyncWriter) run() {
+	defer close(a.done)
+	for buf := range a.queue {
+		if a.err == nil {
+			_, err := a.dst.Write(buf)
+			if err != nil {
+				a.err = err
+				close(a.failed)
+			}
+		}
+		select {
+		case a.spare <- buf[:0]:
+		default:
+		}
+		<-a.slots
+	}
+}
+
+// Write queues a copy of p to be written to dst.
+// Write should not be called concurrently
+// with other calls to Write or Close.
+func (a *asyncWriter) Write(p []byte) (int, error) {
+	if err := a.failure(); err != nil {
+		return 0, err
+	}
+	select {
+	case a.slots <- struct{}{}:
+	case <-a.failed:
+		return 0, a.err
+	}
+	var buf []byte
+	select {
+	case buf = <-a.spare:
+	default:
+	}
+	a.queue <- append(buf, p...)
+	return len(p), nil
+}
+
+// Throttle implements vm.Throttler by waiting
+// until the queue has room for another write.
+func (a *asyncWriter) Throttle() error {
+	if err := a.failure(); err != nil {
+		return err
+	}
+	select {
+	case a.slots <- struct{}{}:
+		<-a.slots
+		return nil
+	case <-a.failed:
+		return a.err
+	}
+}
+
+// failure returns the write error, if any,
+// without blocking
+func (a *asyncWriter) failure() error {
+	select {
+	case <-a.failed:
+		return a.err
+	default:
+		return nil
+	}
+}
+
+// Close waits for all of the queued data to
+// be written to dst and returns the first error
+// encountered, if any. Close does not close dst.
+func (a *asyncWriter) Close() error {
+	close(a.queue)
+	<-a.done
+	return a.err
+}
+
+// countRows returns the number of
+// structures in an ion chunk
+func countRows(buf []byte) int64 {
+	var n int64
+	for len(buf) > 0 {
+		if ion.IsBVM(buf) {
+			buf = buf[4:]
+			continue
+		}
+		if ion.TypeOf(buf) == ion.StructType {
+			n++
+		}
+		size := ion.SizeOf(buf)
+		if size <= 0 || size > len(buf) {
+			break
+		}
+		buf = buf[size:]
+	}
+	return n
+}
+
 // inside the tenant process,
 // indicate that we encountered an error
 // while unpacking the query plan
@@ -481,12 +780,13 @@ type writerCloser struct {
 	io.Closer
 }
 
-func httpChunkedJSON(dst io.WriteCloser) io.WriteCloser {
-	jw := ion.NewJSONWriter(httputil.NewChunkedWriter(dst), '\n')
+func httpChunkedJSON(dst io.Writer, opts *ion.JSONOptions, final io.Closer) io.WriteCloser {
+	jw := ion.NewJSONWriter(dst, '\n')
 	jw.ShowAnnotations = true
+	jw.Options = *opts
 	return &writerCloser{
 		Writer: jw,
-		Closer: dst,
+		Closer: final,
 	}
 }
 
@@ -495,12 +795,13 @@ type arrayWriter struct {
 	final io.Closer
 }
 
-func httpJSONArray(dst io.WriteCloser) io.WriteCloser {
-	jw := ion.NewJSONWriter(httputil.NewChunkedWriter(dst), ',')
+func httpJSONArray(dst io.Writer, opts *ion.JSONOptions, final io.Closer) io.WriteCloser {
+	jw := ion.NewJSONWriter(dst, ',')
 	jw.ShowAnnotations = true
+	jw.Options = *opts
 	return &arrayWriter{
 		JSONWriter: jw,
-		final:      dst,
+		final:      final,
 	}
 }
 
@@ -0,0 +1,177 @@
+// Copyright (C) 2022 Sneller, Inc.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package tnproto
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http/httputil"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/SnellerInc/sneller/ion"
+)
+
+// gatedWriter blocks each Write
+// until a value is sent on gate
+type gatedWriter struct {
+	gate chan struct{}
+	out  bytes.Buffer
+	err  error
+}
+
+func (g *gatedWriter) Write(p []byte) (int, error) {
+	<-g.gate
+	if g.err != nil {
+		return 0, g.err
+	}
+	return g.out.Write(p)
+}
+
+func TestAsyncWriter(t *testing.T) {
+	const depth = 2
+	g := &gatedWriter{gate: make(chan struct{})}
+	a := newAsyncWriter(g, depth)
+
+	var want []byte
+	for i := 0; i < depth; i++ {
+		p := []byte{byte(i), byte(i)}
+		want = append(want, p...)
+		if _, err := a.Write(p); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// the queue is full, so Throttle
+	// should block until a write completes
+	throttled := make(chan error, 1)
+	go func() {
+		throttled <- a.Throttle()
+	}()
+	select {
+	case <-throttled:
+		t.Fatal("Throttle returned with a full queue")
+	case <-time.After(50 * time.Millisecond):
+	}
+	g.gate <- struct{}{}
+	if err := <-throttled; err != nil {
+		t.Fatal(err)
+	}
+	g.gate <- struct{}{}
+	if err := a.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(g.out.Bytes(), want) {
+		t.Fatalf("got %v, want %v", g.out.Bytes(), want)
+	}
+
+	// write errors are returned from
+	// Throttle, Write and Close
+	errGone := errors.New("client went away")
+	g = &gatedWriter{gate: make(chan struct{}), err: errGone}
+	a = newAsyncWriter(g, depth)
+	for i := 0; i < depth; i++ {
+		if _, err := a.Write([]byte{1}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	go func() {
+		throttled <- a.Throttle()
+	}()
+	g.gate <- struct{}{}
+	if err := <-throttled; err != errGone {
+		t.Fatalf("Throttle: got %v, want %v", err, errGone)
+	}
+	if _, err := a.Write([]byte{1}); err != errGone {
+		t.Fatalf("Write: got %v, want %v", err, errGone)
+	}
+	if err := a.Close(); err != errGone {
+		t.Fatalf("Close: got %v, want %v", err, errGone)
+	}
+}
+
+// lockedBuffer is a bytes.Buffer
+// that implements io.WriteCloser
+type lockedBuffer struct {
+	lock sync.Mutex
+	buf  bytes.Buffer
+}
+
+func (l *lockedBuffer) Write(p []byte) (int, error) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	return l.buf.Write(p)
+}
+
+func (l *lockedBuffer) Close() error { return nil }
+
+func TestHeartbeat(t *testing.T) {
+	saved := heartbeatInterval
+	heartbeatInterval = 5 * time.Millisecond
+	defer func() {
+		heartbeatInterval = saved
+	}()
+
+	var st ion.Symtab
+	var chunk ion.Buffer
+	st.Marshal(&chunk, true)
+	ion.NewStruct(&st, []ion.Field{{Label: "x", Datum: ion.Int(1)}}).Encode(&chunk, &st)
+
+	var out lockedBuffer
+	w, err := OutputFormat(OutputStreamIon).writer(&out, Encoding{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if _, err := w.Write(chunk.Bytes()); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// the terminating chunk is written by net/http
+	out.buf.WriteString("0\r\n\r\n")
+	body, err := io.ReadAll(httputil.NewChunkedReader(&out.buf))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var d, hb ion.Datum
+	dec := ion.NewDecoder(bytes.NewReader(body), len(body))
+	dec.ExtraAnnotations = map[string]any{
+		"heartbeat": &hb,
+	}
+	rows := 0
+	for {
+		err := dec.Decode(&d)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		rows++
+	}
+	if rows != 1 {
+		t.Errorf("got %d rows, want 1", rows)
+	}
+	if hb.IsEmpty() {
+		t.Fatal("no heartbeat written")
+	}
+	if _, err := hb.Field("elapsed_ms").Int(); err != nil {
+		t.Errorf("elapsed_ms: %s", err)
+	}
+}
@@ -43,6 +43,8 @@ const (
 	magicSize   = 8
 	idOffset    = magicOffset + magicSize
 	keyOffset   = idOffset + IDSize
+	helloOffset = keyOffset + KeySize
+	helloSize   = 8
 )
 
 // mostly random, but choosing 0xf0 as the first byte
@@ -95,25 +97,52 @@ func (h *header) Key() (key Key) {
 	return
 }
 
-func (h *header) populate(id ID, key Key) {
+// Hello returns the protocol versions and
+// capabilities advertised by the sender;
+// senders that predate version negotiation
+// leave these bytes zeroed
+func (h *header) Hello() Hello {
+	return getHello(h.body[helloOffset:])
+}
+
+func (h *header) populate(id ID, key Key, caps Capability) {
 	binary.LittleEndian.PutUint64(h.body[magicOffset:], headerMagic)
 	copy(h.body[idOffset:], id[:])
 	copy(h.body[keyOffset:], key[:])
+	hello := local()
+	hello.Caps = caps
+	hello.put(h.body[helloOffset:])
 }
 
 // ReadHeader reads an Attach message from the
 // provided connection and returns the requested ID,
 // or an error if the message could not be read.
+// If the sender speaks an incompatible version
+// of the protocol, the error is a *VersionError.
 //
-// See also: Attach
+// See also: Attach, ReadHello
 func ReadHeader(src net.Conn) (ID, Key, error) {
+	id, key, _, err := ReadHello(src)
+	return id, key, err
+}
+
+// ReadHello is identical to ReadHeader,
+// except that it additionally returns
+// the protocol negotiated with the sender.
+//
+// See also: Negotiate
+func ReadHello(src net.Conn) (ID, Key, Hello, error) {
 	var hdr header
 	_, err := io.ReadFull(src, hdr.body[:])
 	if err != nil {
-		return ID{}, Key{}, err
+		return ID{}, Key{}, Hello{}, err
 	}
 	if hdr.Key().IsZero() && !hdr.ID().IsZero() {
-		return ID{}, Key{}, fmt.Errorf("zero key")
+		return ID{}, Key{}, Hello{}, fmt.Errorf("zero key")
+	}
+	if err := hdr.validate(); err != nil {
+		return ID{}, Key{}, Hello{}, err
 	}
-	return hdr.ID(), hdr.Key(), hdr.validate()
+	h, err := Negotiate(hdr.Hello())
+	return hdr.ID(), hdr.Key(), h, err
 }
@@ -58,6 +58,12 @@ type Remote struct {
 	// of dialing (like DNS resolution)
 	// are part of the timeout window.
 	Timeout time.Duration
+
+	// Compress, if set, asks the remote
+	// proxy to compress the connection
+	// to the tenant with zstd.
+	// See also: AttachCompressed
+	Compress bool
 }
 
 func (r *Remote) SetField(f ion.Field) error {
@@ -71,6 +77,8 @@ func (r *Remote) SetField(f ion.Field) error {
 		var i int64
 		i, err = f.Int()
 		r.Timeout = time.Duration(i)
+	case "compress":
+		r.Compress, err = f.Bool()
 	case "id":
 		var buf []byte
 		buf, err = f.BlobShared()
@@ -102,6 +110,10 @@ func (r *Remote) Encode(dst *ion.Buffer, st *ion.Symtab) {
 	dst.WriteBlob(r.ID[:])
 	dst.BeginField(st.Intern("key"))
 	dst.WriteBlob(r.Key[:])
+	if r.Compress {
+		dst.BeginField(st.Intern("compress"))
+		dst.WriteBool(true)
+	}
 	dst.EndStruct()
 }
 
@@ -116,8 +128,10 @@ var clientPool = sync.Pool{
 // and sending it an Attach message, followed
 // by a single query execution request with
 // plan.Client.Exec.
+// If r.Compress is set, the Attach message
+// is sent with AttachCompressed instead.
 //
-// See also: Attach
+// See also: Attach, AttachCompressed
 func (r *Remote) Exec(t *plan.Tree, ep *plan.ExecParams) error {
 	dl := net.Dialer{Timeout: r.Timeout}
 	conn, err := dl.DialContext(ep.Context, r.Net, r.Addr)
@@ -126,7 +140,15 @@ func (r *Remote) Exec(t *plan.Tree, ep *plan.ExecParams) error {
 	}
 	// tell the tenant manager to attach us
 	// to the right tenant instance
-	err = Attach(conn, r.ID, r.Key)
+	if r.Compress {
+		var zc net.Conn
+		zc, err = AttachCompressed(conn, r.ID, r.Key)
+		if err == nil {
+			conn = zc
+		}
+	} else {
+		err = Attach(conn, r.ID, r.Key)
+	}
 	if err != nil {
 		conn.Close()
 		return err
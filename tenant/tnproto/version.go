@@ -0,0 +1,233 @@
+// Copyright (C) 2022 Sneller, Inc.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package tnproto
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"syscall"
+	"time"
+)
+
+// Version is the version of the tenant
+// protocol implemented by this package.
+// It is incremented whenever the encoding
+// of a message changes incompatibly.
+//
+// Peers that predate version negotiation
+// do not advertise a version, and they
+// are treated as speaking version 0.
+const Version = 1
+
+// MinVersion is the oldest version of
+// the tenant protocol that this package
+// can interoperate with.
+//
+// Version 1 only adds the version negotiation
+// itself, so it is compatible with version 0.
+const MinVersion = 0
+
+// Capability is a set of optional
+// protocol features.
+type Capability uint32
+
+const (
+	// CapCompression indicates that the tenant
+	// honors the output Encoding requested
+	// by Buffer.PrepareEncoded.
+	CapCompression Capability = 1 << iota
+	// CapRowStats indicates that the receiver
+	// of the query statistics produced by
+	// DirectExec understands plan.ExecStats.RowsEmitted.
+	CapRowStats
+	// CapZstd indicates that a connection
+	// opened with AttachCompressed may be
+	// compressed with zstd.
+	CapZstd
+	// CapHeartbeat indicates that the tenant
+	// supports the streaming output formats
+	// (see OutputFormat.Streaming).
+	CapHeartbeat
+	// CapJSONOptions indicates that the tenant
+	// honors Encoding.JSON.
+	CapJSONOptions
+)
+
+// Capabilities is the set of capabilities
+// supported by this package.
+const Capabilities = CapCompression | CapRowStats | CapZstd | CapHeartbeat | CapJSONOptions
+
+// Hello describes the range of protocol
+// versions and the capabilities supported
+// by one end of a connection.
+type Hello struct {
+	// Version is the newest supported version.
+	Version uint16
+	// MinVersion is the oldest supported version.
+	MinVersion uint16
+	// Caps is the set of supported capabilities.
+	Caps Capability
+}
+
+// local returns the Hello that describes this package.
+func local() Hello {
+	return Hello{Version: Version, MinVersion: MinVersion, Caps: Capabilities}
+}
+
+// VersionError is returned when
+// the peer on the other end of a connection
+// speaks an incompatible version of the protocol.
+type VersionError struct {
+	// Local and Remote describe the
+	// versions supported by this package
+	// and the peer, respectively.
+	Local, Remote Hello
+}
+
+// Error implements error
+func (v *VersionError) Error() string {
+	return fmt.Sprintf("tnproto: peer speaks protocol versions %d through %d, but this build speaks versions %d through %d",
+		v.Remote.MinVersion, v.Remote.Version, v.Local.MinVersion, v.Local.Version)
+}
+
+// Negotiate returns the Hello describing the
+// protocol spoken between this package and
+// a peer that advertised h: the newest version
+// supported by both ends and the intersection
+// of the capabilities of both ends.
+// If there is no version supported by both ends,
+// Negotiate returns a *VersionError.
+func Negotiate(h Hello) (Hello, error) {
+	l := local()
+	if h.Version < h.MinVersion {
+		return Hello{}, fmt.Errorf("tnproto: peer advertised minimum version %d above its version %d", h.MinVersion, h.Version)
+	}
+	// (a peer that predates negotiation
+	// advertises a zero Hello, which
+	// yields version 0 here)
+	v := l.Version
+	if h.Version < v {
+		v = h.Version
+	}
+	if v < l.MinVersion || v < h.MinVersion {
+		return Hello{}, &VersionError{Local: l, Remote: h}
+	}
+	return Hello{Version: v, MinVersion: v, Caps: l.Caps & h.Caps}, nil
+}
+
+// put encodes h into dst[:helloSize]
+func (h Hello) put(dst []byte) {
+	binary.LittleEndian.PutUint16(dst, h.Version)
+	binary.LittleEndian.PutUint16(dst[2:], h.MinVersion)
+	binary.LittleEndian.PutUint32(dst[4:], uint32(h.Caps))
+}
+
+// getHello decodes a Hello from src[:helloSize]
+func getHello(src []byte) Hello {
+	return Hello{
+		Version:    binary.LittleEndian.Uint16(src),
+		MinVersion: binary.LittleEndian.Uint16(src[2:]),
+		Caps:       Capability(binary.LittleEndian.Uint32(src[4:])),
+	}
+}
+
+// Handshake negotiates the protocol version
+// and capabilities with the tenant that is
+// listening on the control socket ctl with Serve.
+// Handshake should be called once, before any
+// DirectExec or ProxyExec messages are sent.
+// If the tenant speaks an incompatible version
+// of the protocol, Handshake returns an error
+// describing both versions.
+//
+// Tenants that predate version negotiation
+// do not understand the handshake message
+// and exit, closing the control socket (in which
+// case Handshake returns an error saying so),
+// so Handshake should only be used with tenants
+// that are known to support protocol version 1.
+//
+// Like DirectExec, Handshake makes multiple
+// calls to read and write data via ctl,
+// so the caller must ensure that message
+// exchanges are not interleaved.
+func Handshake(ctl *net.UnixConn) (Hello, error) {
+	msg := make([]byte, len(hellomsg)+helloSize)
+	copy(msg, hellomsg)
+	local().put(msg[len(hellomsg):])
+	ctl.SetWriteDeadline(time.Now().Add(5 * time.Second))
+	_, err := ctl.Write(msg)
+	ctl.SetWriteDeadline(time.Time{})
+	if err != nil {
+		return Hello{}, fmt.Errorf("in Handshake: %w", err)
+	}
+	// the tenant may still be starting up,
+	// so allow for the same delay as DirectExec
+	ctl.SetReadDeadline(time.Now().Add(5 * time.Second))
+	defer ctl.SetReadDeadline(time.Time{})
+	_, err = io.ReadFull(ctl, msg[:len(hellomsg)])
+	if err != nil {
+		// (the socket is reset if the tenant
+		// exits without reading the whole message)
+		if errors.Is(err, io.EOF) || errors.Is(err, syscall.ECONNRESET) {
+			return Hello{}, fmt.Errorf("in Handshake: tenant closed the control socket (does it predate protocol version %d?)", Version)
+		}
+		return Hello{}, fmt.Errorf("in Handshake: reading response: %w", err)
+	}
+	if bytes.Equal(msg[:len(hellomsg)], hellomsg) {
+		_, err = io.ReadFull(ctl, msg[len(hellomsg):])
+		if err != nil {
+			return Hello{}, fmt.Errorf("in Handshake: reading response: %w", err)
+		}
+		return Negotiate(getHello(msg[len(hellomsg):]))
+	}
+	if bytes.Equal(msg[:3], errmsg[:3]) && msg[7] == '\n' {
+		errbuf := make([]byte, binary.LittleEndian.Uint32(msg[3:]))
+		_, err = io.ReadFull(ctl, errbuf)
+		if err != nil {
+			return Hello{}, fmt.Errorf("in Handshake: reading error response: %w", err)
+		}
+		return Hello{}, remote(string(errbuf))
+	}
+	return Hello{}, fmt.Errorf("in Handshake: unexpected tenant response %q", msg[:len(hellomsg)])
+}
+
+// inside the tenant process,
+// respond to a Handshake message
+// and return the negotiated protocol
+func hello(ctl *net.UnixConn, tmp []byte) (Hello, error) {
+	var body [helloSize]byte
+	ctl.SetReadDeadline(time.Now().Add(time.Second))
+	_, err := io.ReadFull(ctl, body[:])
+	ctl.SetReadDeadline(time.Time{})
+	if err != nil {
+		return Hello{}, fmt.Errorf("tnproto.Serve: reading Handshake message: %w", err)
+	}
+	h, err := Negotiate(getHello(body[:]))
+	if err != nil {
+		return Hello{}, errnow(ctl, err, tmp)
+	}
+	tmp = append(tmp[:0], hellomsg...)
+	tmp = append(tmp, body[:]...)
+	local().put(tmp[len(hellomsg):])
+	ctl.SetWriteDeadline(time.Now().Add(1 * time.Second))
+	_, err = ctl.Write(tmp)
+	ctl.SetWriteDeadline(time.Time{})
+	return h, err
+}
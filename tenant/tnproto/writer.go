@@ -15,22 +15,127 @@
 package tnproto
 
 import (
+	"bytes"
 	"errors"
+	"fmt"
 	"io"
 	"net"
+	"time"
 )
 
 // Attach takes a fresh connection to a remote
 // tenant proxy and asks the remote proxy to
 // attach this connection to the tenant
 // given by id.
+//
+// The message advertises the protocol versions
+// and capabilities supported by this package
+// (see Hello); a remote proxy that does not speak
+// a compatible version rejects the connection
+// with an error that describes both versions.
 func Attach(dst net.Conn, id ID, key Key) error {
+	return attach(dst, id, key, Capabilities&^CapZstd)
+}
+
+func attach(dst net.Conn, id ID, key Key, caps Capability) error {
 	var hdr header
-	hdr.populate(id, key)
+	hdr.populate(id, key, caps)
 	_, err := dst.Write(hdr.body[:])
 	return err
 }
 
+// AttachCompressed is identical to Attach,
+// except that it additionally asks the remote
+// proxy to compress the connection with zstd.
+// The remote proxy acknowledges the request
+// before the connection is attached to the tenant,
+// and the returned net.Conn should be used
+// in place of dst for the remainder of the
+// conversation. If the tenant does not support
+// compression, the returned connection is
+// uncompressed.
+//
+// The remote proxy must support CapZstd;
+// a proxy that predates CapZstd never sends
+// an acknowledgement, so AttachCompressed
+// gives up after a few seconds.
+func AttachCompressed(dst net.Conn, id ID, key Key) (net.Conn, error) {
+	err := attach(dst, id, key, Capabilities)
+	if err != nil {
+		return nil, err
+	}
+	msg := make([]byte, len(hellomsg)+helloSize)
+	// the remote proxy may need to
+	// launch the tenant before responding
+	dst.SetReadDeadline(time.Now().Add(5 * time.Second))
+	n, err := io.ReadFull(dst, msg[:len(hellomsg)])
+	if err != nil {
+		dst.SetReadDeadline(time.Time{})
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			if n == 0 {
+				return nil, fmt.Errorf("tnproto.AttachCompressed: remote proxy closed the connection")
+			}
+			// let the caller decode whatever
+			// the proxy managed to send
+			return &prefixConn{Conn: dst, prefix: msg[:n]}, nil
+		}
+		return nil, fmt.Errorf("tnproto.AttachCompressed: waiting for acknowledgement (does the remote proxy support compression?): %w", err)
+	}
+	if !bytes.Equal(msg[:len(hellomsg)], hellomsg) {
+		// the remote proxy rejected the connection
+		// (see plan.Reject); let the caller
+		// see the error as part of the response
+		dst.SetReadDeadline(time.Time{})
+		return &prefixConn{Conn: dst, prefix: msg[:n]}, nil
+	}
+	_, err = io.ReadFull(dst, msg[len(hellomsg):])
+	dst.SetReadDeadline(time.Time{})
+	if err != nil {
+		return nil, fmt.Errorf("tnproto.AttachCompressed: reading acknowledgement: %w", err)
+	}
+	if getHello(msg[len(hellomsg):]).Caps&CapZstd == 0 {
+		return dst, nil
+	}
+	return newZstdConn(dst)
+}
+
+// Acknowledge responds to a connection opened
+// with AttachCompressed (i.e. one that advertises
+// CapZstd in its Hello) and indicates
+// whether or not the connection will be compressed.
+// If compress is set, the caller should attach
+// the connection to the tenant with ProxyExecCompressed.
+func Acknowledge(dst net.Conn, compress bool) error {
+	msg := make([]byte, len(hellomsg)+helloSize)
+	copy(msg, hellomsg)
+	h := local()
+	if !compress {
+		h.Caps &^= CapZstd
+	}
+	h.put(msg[len(hellomsg):])
+	dst.SetWriteDeadline(time.Now().Add(5 * time.Second))
+	_, err := dst.Write(msg)
+	dst.SetWriteDeadline(time.Time{})
+	return err
+}
+
+// prefixConn is a net.Conn that yields
+// prefix before the rest of the data
+// read from Conn
+type prefixConn struct {
+	net.Conn
+	prefix []byte
+}
+
+func (p *prefixConn) Read(b []byte) (int, error) {
+	if len(p.prefix) > 0 {
+		n := copy(b, p.prefix)
+		p.prefix = p.prefix[n:]
+		return n, nil
+	}
+	return p.Conn.Read(b)
+}
+
 // Ping sends an Attach message with a zero
 // tenant ID and waits for the remote end to
 // close the connection.
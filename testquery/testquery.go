@@ -21,12 +21,14 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"flag"
 	"fmt"
 	"io"
 	"math"
 	"math/rand"
 	"os"
 	"path/filepath"
+	"regexp"
 	"strconv"
 	"strings"
 	"sync"
@@ -38,6 +40,7 @@ import (
 	"github.com/SnellerInc/sneller/ion/blockfmt"
 	"github.com/SnellerInc/sneller/ion/zion"
 	"github.com/SnellerInc/sneller/plan"
+	"github.com/SnellerInc/sneller/repro"
 	"github.com/SnellerInc/sneller/tests"
 	"github.com/SnellerInc/sneller/vm"
 
@@ -258,10 +261,11 @@ func (p *parallelchunks) SplitBy(parts []string) ([]plan.TablePart, error) {
 	return ret, nil
 }
 
-/* FIXME: uncomment this once BOOL_AND() is fixed
-      so that partitions for which the condition is not satisfied
-      do not cause the merged result to short-circuit to FALSE
-   func (p *parallelchunks) Split() (plan.Subtables, error) {
+var _ plan.SplitHandle = &parallelchunks{}
+
+// Split implements plan.SplitHandle by splitting
+// the chunks into two halves
+func (p *parallelchunks) Split() (plan.Subtables, error) {
 	tp := &plan.LocalTransport{Threads: 1}
 	if len(p.chunks) == 1 {
 		return plan.SubtableList{{Transport: tp, Handle: p}}, nil
@@ -271,15 +275,14 @@ func (p *parallelchunks) SplitBy(parts []string) ([]plan.TablePart, error) {
 	return plan.SubtableList{
 		{
 			Transport: tp,
-			Handle: &parallelchunks{chunks: first, fields: p.fields},
+			Handle:    &parallelchunks{chunks: first, fields: p.fields},
 		},
 		{
 			Transport: tp,
-			Handle: &parallelchunks{chunks: second, fields: p.fields},
+			Handle:    &parallelchunks{chunks: second, fields: p.fields},
 		},
 	}, nil
-   }
-*/
+}
 
 func (p *parallelchunks) Open(_ context.Context) (vm.Table, error) {
 	return p, nil
@@ -341,26 +344,72 @@ func (p *parallelchunks) WriteChunks(dst vm.QuerySink, parallel int) error {
 }
 
 type Queryenv struct {
-	In   []plan.TableHandle
+	In []plan.TableHandle
+	// Tables, if present, holds the
+	// name and index hints for each of In
+	Tables []TableSpec
+
 	tags map[string]string
+	rows [][]ion.Datum // input rows, for computing time ranges
 }
 
-func (e *Queryenv) handle(t expr.Node) (plan.TableHandle, bool) {
+// TableSpec describes one of the
+// inputs to a test case.
+type TableSpec struct {
+	// Name is the name of the table.
+	// If Name is empty, the table
+	// is named input or inputN.
+	Name string
+	// Partitions, if non-nil, is the list
+	// of fields that the table may be
+	// partitioned on.
+	Partitions []string
+	// TimeIndex is the list of (dotted) paths
+	// for which the table index reports the
+	// minimum and maximum timestamps.
+	TimeIndex []string
+}
+
+func (e *Queryenv) spec(i int) *TableSpec {
+	if i < len(e.Tables) {
+		return &e.Tables[i]
+	}
+	return nil
+}
+
+// lookup returns the position in e.In
+// of the table referenced by t
+func (e *Queryenv) lookup(t expr.Node) (int, bool) {
 	p, ok := expr.FlatPath(t)
 	if !ok {
-		return nil, false
+		return -1, false
+	}
+	if name := strings.Join(p, "."); name != "" {
+		for i := range e.Tables {
+			if e.Tables[i].Name == name {
+				return i, true
+			}
+		}
 	}
 	if len(p) != 1 {
-		return nil, false
+		return -1, false
 	}
 	if p[0] == "input" && len(e.In) == 1 {
-		return e.In[0], true
+		return 0, true
 	}
 	var i int
 	if n, _ := fmt.Sscanf(p[0], "input%d", &i); n > 0 && i >= 0 && i < len(e.In) {
-		return e.In[i], true
+		return i, true
+	}
+	return -1, false
+}
+
+func (e *Queryenv) handle(t expr.Node) (plan.TableHandle, bool) {
+	i, ok := e.lookup(t)
+	if !ok {
+		return nil, false
 	}
-	return nil, false
+	return e.In[i], true
 }
 
 func setHints(h plan.TableHandle, hints *plan.Hints) {
@@ -387,16 +436,55 @@ var _ plan.Indexer = &Queryenv{}
 type handleIndex struct {
 	h    plan.TableHandle
 	part bool
+	spec *TableSpec
+	rows []ion.Datum
 }
 
 func (h *handleIndex) TimeRange(path []string) (min, max date.Time, ok bool) {
-	return // unimplemented for now
+	if h.spec == nil || !slices.Contains(h.spec.TimeIndex, strings.Join(path, ".")) {
+		return
+	}
+	for i := range h.rows {
+		ts, found := timestampAt(h.rows[i], path)
+		if !found {
+			continue
+		}
+		if !ok || ts.Before(min) {
+			min = ts
+		}
+		if !ok || ts.After(max) {
+			max = ts
+		}
+		ok = true
+	}
+	return
+}
+
+// timestampAt returns the timestamp
+// at path within d, if there is one
+func timestampAt(d ion.Datum, path []string) (date.Time, bool) {
+	for _, field := range path {
+		s, err := d.Struct()
+		if err != nil {
+			return date.Time{}, false
+		}
+		f, ok := s.FieldByName(field)
+		if !ok {
+			return date.Time{}, false
+		}
+		d = f.Datum
+	}
+	ts, err := d.Timestamp()
+	return ts, err == nil
 }
 
 func (h *handleIndex) HasPartition(x string) bool {
 	if !h.part {
 		return false
 	}
+	if h.spec != nil && h.spec.Partitions != nil && !slices.Contains(h.spec.Partitions, x) {
+		return false
+	}
 	sh, ok := h.h.(plan.PartitionHandle)
 	if ok {
 		// XXX very slow:
@@ -407,14 +495,19 @@ func (h *handleIndex) HasPartition(x string) bool {
 }
 
 func (e *Queryenv) Index(t expr.Node) (plan.Index, error) {
-	handle, ok := e.handle(t)
+	i, ok := e.lookup(t)
 	if !ok {
 		return nil, fmt.Errorf("unexpected table expression %q", expr.ToString(t))
 	}
-	return &handleIndex{
-		h:    handle,
+	idx := &handleIndex{
+		h:    e.In[i],
 		part: e.tags == nil || e.tags["partition"] != "false",
-	}, nil
+		spec: e.spec(i),
+	}
+	if i < len(e.rows) {
+		idx.rows = e.rows[i]
+	}
+	return idx, nil
 }
 
 var _ plan.TableLister = (*Queryenv)(nil)
@@ -423,12 +516,15 @@ func (e *Queryenv) ListTables(db string) ([]string, error) {
 	if db != "" {
 		return nil, fmt.Errorf("no databases")
 	}
-	if len(e.In) == 1 {
-		return []string{"input"}, nil
-	}
 	ts := make([]string, len(e.In))
 	for i := range e.In {
-		ts[i] = fmt.Sprintf("input%d", i)
+		if s := e.spec(i); s != nil && s.Name != "" {
+			ts[i] = s.Name
+		} else if len(e.In) == 1 {
+			ts[i] = "input"
+		} else {
+			ts[i] = fmt.Sprintf("input%d", i)
+		}
 	}
 	return ts, nil
 }
@@ -440,6 +536,13 @@ type TestCaseIon struct {
 	Input       [][]ion.Datum
 	Output      []ion.Datum
 	Tags        map[string]string
+	// Tables, if present, describes
+	// each of the inputs in Input
+	Tables []TableSpec
+	// Error, if non-nil, indicates that the
+	// query is expected to fail with an error
+	// whose text matches the expression
+	Error *regexp.Regexp
 }
 
 // NeedShuffleOutput determines whether the output
@@ -569,8 +672,111 @@ const (
 	FlagSplit                   // use a split plan
 )
 
+// Variant describes one way of presenting
+// the inputs of a test case to the query engine.
+type Variant struct {
+	Flags RunFlags
+	// Splits, if non-empty, lists the row offsets
+	// at which each input is split into separate
+	// chunks when FlagResymbolize is set.
+	// Otherwise, each input is split in half.
+	Splits []int
+	// Rand, if non-nil, is used to shuffle the
+	// symbol tables when FlagShuffle is set.
+	Rand *rand.Rand
+	// Unordered, if set, indicates that the
+	// order of the output rows is not significant.
+	Unordered bool
+}
+
+// RandomVariant returns a random Variant
+// that is valid for the test case q.
+//
+// The output of queries that have a GROUP BY or
+// DISTINCT but no ORDER BY depends on the way that the
+// input is split, so it is compared without regard
+// to the order of the rows. Queries with a LIMIT but no
+// ORDER BY and test cases tagged with '## fuzz: false'
+// (e.g. those that depend on the order of floating-point
+// operations) always have their input split in half,
+// and the former are never run in parallel.
+func (q *TestCaseIon) RandomVariant(rnd *rand.Rand) *Variant {
+	v := &Variant{Flags: FlagResymbolize, Rand: rnd}
+	sel, _ := q.Query.Body.(*expr.Select)
+	if sel != nil && sel.OrderBy == nil {
+		v.Unordered = sel.GroupBy != nil || sel.HasDistinct()
+	}
+	// the rows that are selected by a LIMIT
+	// without an ORDER BY depend on the order
+	// in which the input is processed
+	limited := sel != nil && sel.OrderBy == nil && sel.Limit != nil
+	fixed := limited || q.Tags["fuzz"] == "false"
+	// see NeedShuffleOutput
+	if rnd.Intn(2) == 0 && !limited && (len(q.Output) <= 1 || !NeedShuffleOutput(q.Query)) {
+		v.Flags |= FlagParallel
+	}
+	if rnd.Intn(2) == 0 && CanShuffleSymtab(q.Query) {
+		v.Flags |= FlagShuffle
+	}
+	if rnd.Intn(2) == 0 {
+		v.Flags |= FlagSplit
+	}
+	rows := 0
+	for i := range q.Input {
+		rows = max(rows, len(q.Input[i]))
+	}
+	if rows > 1 && !fixed {
+		for n := rnd.Intn(4) + 1; n > 0; n-- {
+			v.Splits = append(v.Splits, 1+rnd.Intn(rows-1))
+		}
+		slices.Sort(v.Splits)
+		v.Splits = slices.Compact(v.Splits)
+	}
+	return v
+}
+
+func max(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+// sortRows sorts rows by their JSON representation
+func sortRows(rows []ion.Datum, st *ion.Symtab) []ion.Datum {
+	slices.SortStableFunc(rows, func(a, b ion.Datum) bool {
+		return toJSON(st, a) < toJSON(st, b)
+	})
+	return rows
+}
+
+// chunks splits in into chunks according to v
+func (v *Variant) chunks(in []ion.Datum) [][]ion.Datum {
+	if len(v.Splits) == 0 {
+		half := len(in) / 2
+		return [][]ion.Datum{in[:half], in[half:]}
+	}
+	var out [][]ion.Datum
+	prev := 0
+	for _, off := range v.Splits {
+		if off > prev && off < len(in) {
+			out = append(out, in[prev:off])
+			prev = off
+		}
+	}
+	return append(out, in[prev:])
+}
+
+// Execute runs the test case with the given flags
+// and returns an error if the output does not match
+// the expected output.
 func (q *TestCaseIon) Execute(flags RunFlags) error {
+	return q.ExecuteVariant(&Variant{Flags: flags})
+}
 
+// ExecuteVariant is like Execute, but it presents
+// the inputs to the query engine as described by v.
+func (q *TestCaseIon) ExecuteVariant(v *Variant) error {
 	// fix up the symbols input lst so that they
 	// match the associated symbols input symbolTable
 	fixup := func(lst []ion.Datum, st *ion.Symtab) {
@@ -583,153 +789,43 @@ func (q *TestCaseIon) Execute(flags RunFlags) error {
 			dummy.Reset()
 		}
 	}
-	tags := q.Tags
-	// run a query on the given input table and yield the output list
-	run := func(q *expr.Query, in [][]ion.Datum, st *ion.Symtab, flags RunFlags) ([]ion.Datum, error) {
-		var unsymbolize func(d ion.Datum, st *ion.Symtab) ion.Datum
-		unsymbolize = func(d ion.Datum, st *ion.Symtab) ion.Datum {
-			switch d.Type() {
-			case ion.StructType:
-				d, _ := d.Struct()
-				fields := d.Fields(nil)
-				for i := range fields {
-					if str, err := fields[i].String(); err == nil {
-						fields[i].Datum = ion.String(str)
-					} else {
-						fields[i].Datum = unsymbolize(fields[i].Datum, st)
-					}
-				}
-				return ion.NewStruct(st, fields).Datum()
-			case ion.ListType:
-				d, _ := d.List()
-				items := d.Items(nil)
-				for i := range items {
-					if str, err := items[i].String(); err == nil {
-						items[i] = ion.String(str)
-					} else {
-						items[i] = unsymbolize(items[i], st)
-					}
-				}
-				return ion.NewList(st, items).Datum()
-			}
-			return d
+	gotout, err := q.run(v)
+	if q.Error != nil {
+		if err == nil {
+			return fmt.Errorf("expected an error matching %q", q.Error)
 		}
-
-		// return a symbol table with the symbols
-		// randomly shuffled
-		shuffled := func(st *ion.Symtab) *ion.Symtab {
-			ret := &ion.Symtab{}
-			// if only one symbol is input the input corpus,
-			// then just bump it up one symbol
-			if st.MaxID() == 11 {
-				ret.Intern("a-random-symbol")
-				ret.Intern(st.Get(11))
-				return ret
-			}
-
-			// first 10 symbols are "pre-interned"
-			symbolmap := make([]ion.Symbol, st.MaxID()-10)
-			for i := range symbolmap {
-				symbolmap[i] = ion.Symbol(i) + 10
-			}
-			rand.Shuffle(len(symbolmap), func(i, j int) {
-				symbolmap[i], symbolmap[j] = symbolmap[j], symbolmap[i]
-			})
-
-			// force symbols to be multi-byte sequences:
-			for i := 0; i < 117; i++ {
-				ret.Intern(fmt.Sprintf("..garbage%d", i))
-			}
-
-			for _, s := range symbolmap {
-				ret.Intern(st.Get(s))
-			}
-			return ret
+		if !q.Error.MatchString(err.Error()) {
+			return fmt.Errorf("error %q does not match %q", err, q.Error)
 		}
-
-		input := make([]plan.TableHandle, len(in))
-		maxp := 0
-		for i, in := range in {
-			if (flags&FlagResymbolize) != 0 && len(in) > 1 {
-				half := len(in) / 2
-				first := flatten(in[:half], st)
-
-				var second []byte
-				if flags&FlagShuffle != 0 {
-					second = flatten(in[half:], shuffled(st))
-				} else {
-					second = flatten(in[half:], st)
-				}
-				if flags&FlagParallel != 0 {
-					maxp = 2
-					input[i] = &parallelchunks{chunks: [][]byte{first, second}}
-				} else {
-					input[i] = &chunkshandle{chunks: [][]byte{first, second}}
-				}
-			} else {
-				input[i] = Bufhandle(flatten(in, st))
-			}
-		}
-		env := &Queryenv{In: input, tags: tags}
-		var tree *plan.Tree
-		var err error
-		if flags&FlagSplit != 0 {
-			tree, err = plan.NewSplit(q, env)
-		} else {
-			tree, err = plan.New(q, env)
-		}
-		if err != nil {
-			return nil, err
-		}
-		var out bytes.Buffer
-		lp := plan.LocalTransport{Threads: maxp}
-		params := plan.ExecParams{
-			Output:   &out,
-			Parallel: maxp,
-			Context:  context.Background(),
-		}
-		err = lp.Exec(tree, &params)
-		if err != nil {
-			return nil, err
-		}
-		outbuf := out.Bytes()
-		var datum ion.Datum
-		var outlst []ion.Datum
-		st.Reset()
-		for len(outbuf) > 0 {
-			datum, outbuf, err = ion.ReadDatum(st, outbuf)
-			if err != nil {
-				return nil, err
-			}
-			datum = unsymbolize(datum, st)
-			outlst = append(outlst, datum)
-		}
-		return outlst, nil
+		return nil
 	}
-
-	gotout, err := run(q.Query, q.Input, q.SymbolTable, flags)
 	if err != nil {
 		return err
 	}
 	q.SymbolTable.Reset()
 	fixup(gotout, q.SymbolTable)
 	fixup(q.Output, q.SymbolTable)
-	if len(q.Output) != len(gotout) {
-		err = fmt.Errorf("%d rows output; expected %d", len(gotout), len(q.Output))
+	want := q.Output
+	if v.Unordered {
+		gotout = sortRows(gotout, q.SymbolTable)
+		want = sortRows(slices.Clone(want), q.SymbolTable)
+	}
+	if len(want) != len(gotout) {
+		err = fmt.Errorf("%d rows output; expected %d", len(gotout), len(want))
 	}
 	nErrors := 0
 	if err != nil {
 		nErrors++
 	}
-	for i := range q.Output {
+	for i := range want {
 		if i >= len(gotout) {
-			err = errors.Join(err, fmt.Errorf("missing %s", toJSON(q.SymbolTable, q.Output[i])))
+			err = errors.Join(err, fmt.Errorf("missing %s", toJSON(q.SymbolTable, want[i])))
 			continue
 		}
-		if !ion.Equal(q.Output[i], gotout[i]) {
+		if !ion.Equal(want[i], gotout[i]) {
 			nErrors++
 			err = errors.Join(err, fmt.Errorf("row %d: got  %srow %d: want %s",
-				i, toJSON(q.SymbolTable, gotout[i]), i, toJSON(q.SymbolTable, q.Output[i])))
+				i, toJSON(q.SymbolTable, gotout[i]), i, toJSON(q.SymbolTable, want[i])))
 		}
 		if nErrors > 10 {
 			err = errors.Join(err, fmt.Errorf("... and more %d", nErrors))
@@ -739,6 +835,140 @@ func (q *TestCaseIon) Execute(flags RunFlags) error {
 	return err
 }
 
+// unsymbolize replaces symbols in d with strings
+func unsymbolize(d ion.Datum, st *ion.Symtab) ion.Datum {
+	switch d.Type() {
+	case ion.StructType:
+		d, _ := d.Struct()
+		fields := d.Fields(nil)
+		for i := range fields {
+			if str, err := fields[i].String(); err == nil {
+				fields[i].Datum = ion.String(str)
+			} else {
+				fields[i].Datum = unsymbolize(fields[i].Datum, st)
+			}
+		}
+		return ion.NewStruct(st, fields).Datum()
+	case ion.ListType:
+		d, _ := d.List()
+		items := d.Items(nil)
+		for i := range items {
+			if str, err := items[i].String(); err == nil {
+				items[i] = ion.String(str)
+			} else {
+				items[i] = unsymbolize(items[i], st)
+			}
+		}
+		return ion.NewList(st, items).Datum()
+	}
+	return d
+}
+
+// shuffled returns a symbol table with
+// the symbols in st randomly shuffled
+func shuffled(st *ion.Symtab, rnd *rand.Rand) *ion.Symtab {
+	ret := &ion.Symtab{}
+	// if only one symbol is input the input corpus,
+	// then just bump it up one symbol
+	if st.MaxID() == 11 {
+		ret.Intern("a-random-symbol")
+		ret.Intern(st.Get(11))
+		return ret
+	}
+
+	// first 10 symbols are "pre-interned"
+	symbolmap := make([]ion.Symbol, st.MaxID()-10)
+	for i := range symbolmap {
+		symbolmap[i] = ion.Symbol(i) + 10
+	}
+	swap := func(i, j int) {
+		symbolmap[i], symbolmap[j] = symbolmap[j], symbolmap[i]
+	}
+	if rnd != nil {
+		rnd.Shuffle(len(symbolmap), swap)
+	} else {
+		rand.Shuffle(len(symbolmap), swap)
+	}
+
+	// force symbols to be multi-byte sequences:
+	for i := 0; i < 117; i++ {
+		ret.Intern(fmt.Sprintf("..garbage%d", i))
+	}
+
+	for _, s := range symbolmap {
+		ret.Intern(st.Get(s))
+	}
+	return ret
+}
+
+// run runs the query on the inputs
+// and returns the output rows
+func (q *TestCaseIon) run(v *Variant) ([]ion.Datum, error) {
+	flags := v.Flags
+	st := q.SymbolTable
+	input := make([]plan.TableHandle, len(q.Input))
+	maxp := 0
+	for i, in := range q.Input {
+		if (flags&FlagResymbolize) != 0 && len(in) > 1 {
+			parts := v.chunks(in)
+			chunks := make([][]byte, len(parts))
+			for j := range parts {
+				if j > 0 && flags&FlagShuffle != 0 {
+					chunks[j] = flatten(parts[j], shuffled(st, v.Rand))
+				} else {
+					chunks[j] = flatten(parts[j], st)
+				}
+			}
+			if flags&FlagParallel != 0 {
+				maxp = max(maxp, len(chunks))
+				input[i] = &parallelchunks{chunks: chunks}
+			} else {
+				input[i] = &chunkshandle{chunks: chunks}
+			}
+		} else {
+			input[i] = Bufhandle(flatten(in, st))
+		}
+	}
+	if q.Tags["ansi_nulls"] == "true" {
+		q.Query.SetANSINulls()
+	}
+	env := &Queryenv{In: input, Tables: q.Tables, tags: q.Tags, rows: q.Input}
+	var tree *plan.Tree
+	var err error
+	if flags&FlagSplit != 0 && q.Tags["split"] != "false" {
+		tree, err = plan.NewSplit(q.Query, env)
+	} else {
+		tree, err = plan.New(q.Query, env)
+	}
+	if err != nil {
+		return nil, err
+	}
+	var out bytes.Buffer
+	lp := plan.LocalTransport{Threads: maxp}
+	params := plan.ExecParams{
+		Output:   &out,
+		Parallel: maxp,
+		Context:  context.Background(),
+	}
+	err = lp.Exec(tree, &params)
+	if err != nil {
+		return nil, err
+	}
+	outbuf := out.Bytes()
+	var datum ion.Datum
+	var outlst []ion.Datum
+	st.Reset()
+	for len(outbuf) > 0 {
+		datum, outbuf, err = ion.ReadDatum(st, outbuf)
+		if err != nil {
+			return nil, err
+		}
+		datum = unsymbolize(datum, st)
+		outlst = append(outlst, datum)
+	}
+	return outlst, nil
+}
+
 // ParseTestCaseIon parses the provided query,
 // input and output strings into a test case
 func ParseTestCaseIon(queryStr []string, inputsStr [][]string, outputStr []string, tags map[string]string) (tci *TestCaseIon, err error) {
@@ -800,7 +1030,11 @@ func ParseTestCaseIon(queryStr []string, inputsStr [][]string, outputStr []strin
 	}
 
 	query := part2bytes(queryStr)
-	exprQuery, err := partiql.Parse(query)
+	dialect, ok := partiql.DialectByName(tags["dialect"])
+	if !ok {
+		return nil, fmt.Errorf("unknown dialect %q", tags["dialect"])
+	}
+	exprQuery, err := partiql.ParseDialect(query, dialect)
 	if err != nil {
 		return nil, err
 	}
@@ -822,6 +1056,18 @@ func ParseTestCaseIon(queryStr []string, inputsStr [][]string, outputStr []strin
 // The first part is an SQL query (text), the last part is the
 // expected rows (JSONRL) and the middle parts are inputs (also
 // in the JSONRL format).
+//
+// Each input part may contain the following tags:
+//
+//	## table: <name>          the name of the table (default: input or inputN)
+//	## partitions: <a>, <b>   the only fields the table may be partitioned on
+//	## timeindex: <a.b>, <c>  paths with min/max timestamps in the table index
+//
+// The last part may contain an error tag instead
+// of rows, in which case the query is expected to
+// fail with an error matching the regular expression:
+//
+//	## error: <regexp>
 func ReadTestCaseIonFromFile(fname string) (qtc *TestCaseIon, err error) {
 
 	spec, err := tests.ReadTestCaseSpecFromFile(fname)
@@ -840,7 +1086,192 @@ func ReadTestCaseIonFromFile(fname string) (qtc *TestCaseIon, err error) {
 	for i := 1; i < nSections-1; i++ {
 		inputsStr = append(inputsStr, spec.Sections[i])
 	}
-	return ParseTestCaseIon(queryStr, inputsStr, outputStr, spec.Tags)
+	tci, err := ParseTestCaseIon(queryStr, inputsStr, outputStr, spec.Tags)
+	if err != nil {
+		return nil, err
+	}
+	tables := make([]TableSpec, 0, len(inputsStr))
+	named := false
+	for i := 1; i < nSections-1; i++ {
+		tags := spec.SectionTags[i]
+		ts := TableSpec{
+			Name:      tags["table"],
+			TimeIndex: splitList(tags["timeindex"]),
+		}
+		if p, ok := tags["partitions"]; ok {
+			ts.Partitions = splitList(p)
+			if ts.Partitions == nil {
+				ts.Partitions = []string{}
+			}
+		}
+		named = named || ts.Name != "" || ts.Partitions != nil || ts.TimeIndex != nil
+		tables = append(tables, ts)
+	}
+	if named {
+		tci.Tables = tables
+	}
+	if pattern, ok := spec.SectionTags[nSections-1]["error"]; ok {
+		if len(tci.Output) != 0 {
+			return nil, fmt.Errorf("%s: expected no output rows with an expected error", fname)
+		}
+		tci.Error, err = regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("%s: bad error pattern: %w", fname, err)
+		}
+	}
+	return tci, nil
+}
+
+// splitList splits a comma-separated list
+func splitList(s string) []string {
+	var out []string
+	for _, f := range strings.Split(s, ",") {
+		if f = strings.TrimSpace(f); f != "" {
+			out = append(out, f)
+		}
+	}
+	return out
+}
+
+// ReadBundle reads a repro bundle (see package repro)
+// from fname and returns a test case that replays
+// the query in the bundle against the sampled
+// rows of each of its inputs. The test case
+// expects the query to fail with the same error
+// as the original query, although the positions
+// in the error text are not compared, since the
+// bundle only contains the redacted query text.
+func ReadBundle(fname string) (*TestCaseIon, error) {
+	f, err := os.Open(fname)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	b, err := repro.Read(f)
+	if err != nil {
+		return nil, err
+	}
+	return BundleTestCase(b)
+}
+
+var errorPosition = regexp.MustCompile(` at [0-9]+:[0-9]+`)
+
+// BundleTestCase is like ReadBundle,
+// but it uses a bundle that has
+// already been read into memory.
+func BundleTestCase(b *repro.Bundle) (*TestCaseIon, error) {
+	inputs := make([][]string, len(b.Inputs))
+	tables := make([]TableSpec, len(b.Inputs))
+	for i := range b.Inputs {
+		for _, line := range strings.Split(string(b.Inputs[i].Rows), "\n") {
+			if strings.TrimSpace(line) != "" {
+				inputs[i] = append(inputs[i], line)
+			}
+		}
+		tables[i].Name = b.Inputs[i].Name
+	}
+	tci, err := ParseTestCaseIon([]string{b.Query}, inputs, nil, nil)
+	if err != nil {
+		return nil, fmt.Errorf("parsing bundle query: %w", err)
+	}
+	tci.Tables = tables
+	if b.Error != "" {
+		pattern := errorPosition.ReplaceAllString(regexp.QuoteMeta(b.Error), ` at [0-9]+:[0-9]+`)
+		tci.Error, err = regexp.Compile(pattern)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return tci, nil
+}
+
+var updateFlag = flag.Bool("update", false, "rewrite the expected output of query test cases with their actual output")
+
+// Updating returns whether the -update flag was passed
+// to the test binary, in which case UpdateTestCaseFile
+// should be used instead of running test cases.
+func Updating() bool { return *updateFlag }
+
+// UpdateTestCaseFile runs the test case in fname and
+// replaces its last section (the expected output) with
+// the rows that the query actually produced.
+// Everything up to and including the last '---'
+// line of the file is preserved.
+// Test cases that expect an error are left as-is.
+func UpdateTestCaseFile(fname string) error {
+	tci, err := ReadTestCaseIonFromFile(fname)
+	if err != nil {
+		return err
+	}
+	if tci.Error != nil {
+		return tci.Execute(0)
+	}
+	rows, err := tci.run(&Variant{})
+	if err != nil {
+		return err
+	}
+	buf, err := os.ReadFile(fname)
+	if err != nil {
+		return err
+	}
+	// find the start of the last section
+	end := 0
+	for off := 0; off < len(buf); {
+		line := buf[off:]
+		if i := bytes.IndexByte(line, '\n'); i >= 0 {
+			line = line[:i+1]
+		}
+		off += len(line)
+		if bytes.HasPrefix(line, []byte("---")) {
+			end = off
+		}
+	}
+	if end == 0 {
+		return fmt.Errorf("%s: no output section", fname)
+	}
+	out := slices.Clip(buf[:end])
+	if out[len(out)-1] != '\n' {
+		out = append(out, '\n')
+	}
+	for i := range rows {
+		out = append(out, strings.TrimSpace(toJSON(tci.SymbolTable, specialFloats(rows[i], tci.SymbolTable)))...)
+		out = append(out, '\n')
+	}
+	return os.WriteFile(fname, out, 0644)
+}
+
+// specialFloats is the inverse of
+// the float64:xxx conversion in IonizeRow
+func specialFloats(d ion.Datum, st *ion.Symtab) ion.Datum {
+	switch d.Type() {
+	case ion.FloatType:
+		f, _ := d.Float()
+		switch {
+		case math.IsNaN(f):
+			return ion.String("float64:nan")
+		case math.IsInf(f, 1):
+			return ion.String("float64:+inf")
+		case math.IsInf(f, -1):
+			return ion.String("float64:-inf")
+		case f == 0 && math.Signbit(f):
+			return ion.String("float64:-0")
+		}
+	case ion.StructType:
+		d, _ := d.Struct()
+		fields := d.Fields(nil)
+		for i := range fields {
+			fields[i].Datum = specialFloats(fields[i].Datum, st)
+		}
+		return ion.NewStruct(st, fields).Datum()
+	case ion.ListType:
+		d, _ := d.List()
+		items := d.Items(nil)
+		for i := range items {
+			items[i] = specialFloats(items[i], st)
+		}
+		return ion.NewList(st, items).Datum()
+	}
+	return d
 }
 
 type benchmarkSettings struct {
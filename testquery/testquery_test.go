@@ -15,7 +15,16 @@
 package testquery
 
 import (
+	"math/rand"
+	"os"
+	"path/filepath"
+	"reflect"
 	"testing"
+
+	"github.com/SnellerInc/sneller/expr"
+	"github.com/SnellerInc/sneller/plan"
+
+	"golang.org/x/exp/slices"
 )
 
 func TestExecute(t *testing.T) {
@@ -62,3 +71,172 @@ func TestExecute(t *testing.T) {
 		}
 	}
 }
+
+func TestExecuteVariant(t *testing.T) {
+	inputsStr := []string{`{"x": "a"}`, `{"x": "b"}`, `{"x": "c"}`, `{"x": "a"}`, `{"x": "d"}`}
+	queries := []struct {
+		query  string
+		output []string
+	}{
+		{"SELECT x FROM input", inputsStr},
+		{"SELECT DISTINCT x FROM input ORDER BY x", []string{`{"x": "a"}`, `{"x": "b"}`, `{"x": "c"}`, `{"x": "d"}`}},
+	}
+	for _, q := range queries {
+		for _, splits := range [][]int{nil, {1}, {1, 2, 3, 4}, {2, 100}} {
+			for _, flags := range []RunFlags{FlagResymbolize, FlagResymbolize | FlagShuffle, FlagResymbolize | FlagShuffle | FlagSplit} {
+				tci, err := ParseTestCaseIon([]string{q.query}, [][]string{inputsStr}, q.output, nil)
+				if err != nil {
+					t.Fatal(err)
+				}
+				v := &Variant{Flags: flags, Splits: splits, Rand: rand.New(rand.NewSource(1))}
+				if err := tci.ExecuteVariant(v); err != nil {
+					t.Errorf("%s with flags %#x and splits %v: %s", q.query, flags, splits, err)
+				}
+			}
+		}
+	}
+}
+
+func TestUpdateTestCaseFile(t *testing.T) {
+	text := `# a comment
+## dialect: postgres
+SELECT x + 1 AS y FROM input
+---
+{"x": 1}
+{"x": 2}
+---
+{"y": 0}
+`
+	want := `# a comment
+## dialect: postgres
+SELECT x + 1 AS y FROM input
+---
+{"x": 1}
+{"x": 2}
+---
+{"y": 2}
+{"y": 3}
+`
+	fname := filepath.Join(t.TempDir(), "case.test")
+	if err := os.WriteFile(fname, []byte(text), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := UpdateTestCaseFile(fname); err != nil {
+		t.Fatal(err)
+	}
+	got, err := os.ReadFile(fname)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+	tci, err := ReadTestCaseIonFromFile(fname)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tci.Execute(0); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestTableSpecs(t *testing.T) {
+	text := `SELECT COUNT(*) AS n FROM events
+---
+## table: events
+## partitions: x
+## timeindex: ts, a.b
+{"x": 1, "y": 1, "ts": "2022-01-02T00:00:00Z"}
+{"x": 2, "y": 1, "ts": "2022-01-01T00:00:00Z", "a": {"b": "2021-01-01T00:00:00Z"}}
+---
+{"z": 1}
+---
+{"n": 2}
+`
+	fname := filepath.Join(t.TempDir(), "case.test")
+	if err := os.WriteFile(fname, []byte(text), 0644); err != nil {
+		t.Fatal(err)
+	}
+	tci, err := ReadTestCaseIonFromFile(fname)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []TableSpec{
+		{Name: "events", Partitions: []string{"x"}, TimeIndex: []string{"ts", "a.b"}},
+		{},
+	}
+	if !reflect.DeepEqual(tci.Tables, want) {
+		t.Fatalf("got tables %+v", tci.Tables)
+	}
+	if err := tci.Execute(FlagResymbolize | FlagParallel); err != nil {
+		t.Fatal(err)
+	}
+
+	env := &Queryenv{
+		In:     []plan.TableHandle{&parallelchunks{chunks: [][]byte{flatten(tci.Input[0], tci.SymbolTable)}}, nil},
+		Tables: tci.Tables,
+		rows:   tci.Input,
+	}
+	lst, err := env.ListTables("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(lst, []string{"events", "input1"}) {
+		t.Errorf("ListTables returned %v", lst)
+	}
+	idx, err := env.Index(expr.Ident("events"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !idx.HasPartition("x") {
+		t.Error("expected a partition on x")
+	}
+	if idx.HasPartition("y") {
+		t.Error("unexpected partition on y")
+	}
+	min, max, ok := idx.TimeRange([]string{"ts"})
+	if !ok || min.Day() != 1 || max.Day() != 2 {
+		t.Errorf("TimeRange(ts) = %s, %s, %v", min, max, ok)
+	}
+	min, max, ok = idx.TimeRange([]string{"a", "b"})
+	if !ok || min != max || min.Year() != 2021 {
+		t.Errorf("TimeRange(a.b) = %s, %s, %v", min, max, ok)
+	}
+	if _, _, ok := idx.TimeRange([]string{"x"}); ok {
+		t.Error("unexpected time range for x")
+	}
+}
+
+func TestExpectedError(t *testing.T) {
+	run := func(query, pattern string) error {
+		text := query + "\n---\n{\"x\": 1}\n---\n## error: " + pattern + "\n"
+		fname := filepath.Join(t.TempDir(), "case.test")
+		if err := os.WriteFile(fname, []byte(text), 0644); err != nil {
+			t.Fatal(err)
+		}
+		tci, err := ReadTestCaseIonFromFile(fname)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return tci.Execute(0)
+	}
+	if err := run("SELECT * FROM nosuchtable", "unexpected table"); err != nil {
+		t.Error(err)
+	}
+	if err := run("SELECT * FROM nosuchtable", "^TIME_RANGE"); err == nil {
+		t.Error("expected mismatched error to fail")
+	}
+	if err := run("SELECT * FROM input", "unexpected table"); err == nil {
+		t.Error("expected a query without an error to fail")
+	}
+
+	// rows and an error are mutually exclusive
+	fname := filepath.Join(t.TempDir(), "case.test")
+	text := "SELECT * FROM input\n---\n{\"x\": 1}\n---\n## error: foo\n{\"x\": 1}\n"
+	if err := os.WriteFile(fname, []byte(text), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ReadTestCaseIonFromFile(fname); err == nil {
+		t.Error("expected an error")
+	}
+}
@@ -31,6 +31,10 @@ type TestCaseSpec struct {
 
 	// Map of key:value tags extracted from comments
 	Tags map[string]string
+
+	// SectionTags[i] holds the subset of Tags
+	// that were extracted from Sections[i]
+	SectionTags []map[string]string
 }
 
 // ReadTestCaseSpecFromFile is a wrapper for ReadTestcase that reads
@@ -49,7 +53,8 @@ func ReadTestCaseSpecFromFile(fname string) (*TestCaseSpec, error) {
 //
 // Each part is a list of lines.
 // The procedure skips empty lines and lines staring with the `#`.
-// The procedure collects all key=value settings that start with double '##'.
+// The procedure collects all key=value settings that start with double '##',
+// both for the whole text and for each part.
 func readTestcaseSpec(reader io.Reader) (*TestCaseSpec, error) {
 	rd := bufio.NewScanner(reader)
 
@@ -59,12 +64,14 @@ func readTestcaseSpec(reader io.Reader) (*TestCaseSpec, error) {
 
 	sectionID := 0
 	spec.Sections = append(spec.Sections, []string{})
+	spec.SectionTags = append(spec.SectionTags, make(map[string]string))
 
 	for rd.Scan() {
 		line := rd.Bytes()
 		if bytes.HasPrefix(line, sepdash) {
 			sectionID += 1
 			spec.Sections = append(spec.Sections, []string{})
+			spec.SectionTags = append(spec.SectionTags, make(map[string]string))
 			continue
 		}
 
@@ -79,6 +86,7 @@ func readTestcaseSpec(reader io.Reader) (*TestCaseSpec, error) {
 				// parse '## key: value'
 				if k, v, ok := parseKeyValue(string(line[2:])); ok {
 					spec.Tags[k] = v
+					spec.SectionTags[sectionID][k] = v
 				}
 			}
 
@@ -97,6 +97,13 @@ section 3(b)
 			t.Errorf("wrong key-value map")
 		}
 	}
+
+	if len(spec.SectionTags) != 3 ||
+		!maps.Equal(spec.SectionTags[0], map[string]string{"key1": "value1"}) ||
+		len(spec.SectionTags[1]) != 0 ||
+		!maps.Equal(spec.SectionTags[2], map[string]string{"key2": "value2"}) {
+		t.Errorf("wrong per-section tags %v", spec.SectionTags)
+	}
 }
 
 func slicesEqual(t *testing.T, got, want []string) {
@@ -0,0 +1,202 @@
+// Copyright (C) 2023 Sneller, Inc.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// Command genkernels produces the assembly of the
+// bytecode kernels that differ only by the element type
+// or by the instruction they use from a set of templates
+// and a manifest that lists the instantiations.
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+)
+
+const header = `// Copyright (C) 2023 Sneller, Inc.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// Code generated by genkernels from %s and %s; DO NOT EDIT.
+`
+
+// Type is an element type declared in the manifest;
+// it maps the keys used by the templates to the
+// type-specific macros and instructions.
+type Type map[string]string
+
+// Kernel is a single instantiation of a template.
+type Kernel struct {
+	Name string // opcode name, without the 'bc' prefix
+	Op   string
+	Type Type
+	Args []string
+
+	template string
+	location string
+}
+
+// Arg returns the n-th extra argument of the kernel.
+func (k *Kernel) Arg(n int) (string, error) {
+	if n < 0 || n >= len(k.Args) {
+		return "", fmt.Errorf("%s: kernel %s has no argument %d", k.location, k.Name, n)
+	}
+	return k.Args[n], nil
+}
+
+// Output is a generated file.
+type Output struct {
+	Path    string
+	Kernels []*Kernel
+}
+
+func main() {
+	var manifest, templates string
+	flag.StringVar(&manifest, "m", "", "manifest file path")
+	flag.StringVar(&templates, "t", "", "template file path")
+	flag.Parse()
+	if manifest == "" || templates == "" {
+		flag.Usage()
+		return
+	}
+
+	tmpl, err := template.New(filepath.Base(templates)).Option("missingkey=error").ParseFiles(templates)
+	check(err)
+
+	outputs, err := parseManifest(manifest)
+	check(err)
+
+	for _, out := range outputs {
+		buf := bytes.NewBuffer(nil)
+		fmt.Fprintf(buf, header, filepath.Base(manifest), filepath.Base(templates))
+		for _, k := range out.Kernels {
+			if tmpl.Lookup(k.template) == nil {
+				check(fmt.Errorf("%s: unknown template %q", k.location, k.template))
+			}
+			err := tmpl.ExecuteTemplate(buf, k.template, k)
+			check(err)
+		}
+
+		path := filepath.Join(filepath.Dir(manifest), out.Path)
+		old, _ := os.ReadFile(path)
+		if !bytes.Equal(old, buf.Bytes()) {
+			fmt.Printf("Creating %q\n", path)
+			err := os.WriteFile(path, buf.Bytes(), 0644)
+			check(err)
+		}
+	}
+}
+
+// parseManifest reads the manifest and returns
+// the list of the outputs in the order they were declared.
+func parseManifest(path string) ([]*Output, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	types := make(map[string]Type)
+	seen := make(map[string]string)
+	var outputs []*Output
+	var cur *Output
+
+	s := bufio.NewScanner(f)
+	lineno := 0
+	for s.Scan() {
+		lineno++
+		location := fmt.Sprintf("%s:%d", path, lineno)
+		line, _, _ := strings.Cut(s.Text(), "#")
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		switch fields[0] {
+		case "type":
+			if len(fields) < 2 {
+				return nil, fmt.Errorf("%s: expected type name", location)
+			}
+			name := fields[1]
+			if _, ok := types[name]; ok {
+				return nil, fmt.Errorf("%s: type %q declared more than once", location, name)
+			}
+			t := Type{"name": name}
+			for _, kv := range fields[2:] {
+				key, val, ok := strings.Cut(kv, "=")
+				if !ok || key == "" {
+					return nil, fmt.Errorf("%s: malformed type attribute %q", location, kv)
+				}
+				if _, ok := t[key]; ok {
+					return nil, fmt.Errorf("%s: duplicate type attribute %q", location, key)
+				}
+				t[key] = val
+			}
+			types[name] = t
+		case "output":
+			if len(fields) != 2 {
+				return nil, fmt.Errorf("%s: expected a single output path", location)
+			}
+			cur = &Output{Path: fields[1]}
+			outputs = append(outputs, cur)
+		default:
+			if len(fields) < 3 {
+				return nil, fmt.Errorf("%s: expected <template> <type> <op>", location)
+			}
+			if cur == nil {
+				return nil, fmt.Errorf("%s: kernel declared before any output", location)
+			}
+			t, ok := types[fields[1]]
+			if !ok {
+				return nil, fmt.Errorf("%s: unknown type %q", location, fields[1])
+			}
+			k := &Kernel{
+				Name:     fields[2] + fields[1],
+				Op:       fields[2],
+				Type:     t,
+				Args:     fields[3:],
+				template: fields[0],
+				location: location,
+			}
+			if prev, ok := seen[k.Name]; ok {
+				return nil, fmt.Errorf("%s: kernel %s already declared at %s", location, k.Name, prev)
+			}
+			seen[k.Name] = location
+			cur.Kernels = append(cur.Kernels, k)
+		}
+	}
+	return outputs, s.Err()
+}
+
+func check(err error) {
+	if err != nil {
+		log.Fatal(err)
+	}
+}
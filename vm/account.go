@@ -0,0 +1,140 @@
+// Copyright (C) 2022 Sneller, Inc.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package vm
+
+import (
+	"io"
+	"sync/atomic"
+)
+
+// Account tracks the number of vm pages
+// allocated on behalf of one query.
+//
+// The pages charged to an Account are the ones
+// allocated by the writers opened through WithAccount:
+// their input buffers, their symbol tables and the
+// scratch memory of the query operators that consume
+// their rows. Memory that is shared by queries, such
+// as the buffers used to decompress cached data, is
+// charged to an Account only if it is allocated by
+// one of those writers.
+//
+// The methods of Account are safe to call
+// from multiple goroutines. A nil *Account
+// does not track anything.
+type Account struct {
+	// Limit, if positive, is the maximum number
+	// of pages that can be charged to the Account
+	// at once; allocations beyond the limit fail
+	// with ErrOutOfMemory. Limit must not be changed
+	// once the Account is in use.
+	Limit int
+
+	inuse int64
+	peak  int64
+}
+
+// PagesUsed returns the number of pages charged
+// to a that have not been freed yet.
+func (a *Account) PagesUsed() int {
+	if a == nil {
+		return 0
+	}
+	return int(atomic.LoadInt64(&a.inuse))
+}
+
+// PagesPeak returns the largest value of a.PagesUsed().
+func (a *Account) PagesPeak() int {
+	if a == nil {
+		return 0
+	}
+	return int(atomic.LoadInt64(&a.peak))
+}
+
+// malloc is like TryMalloc, but it
+// charges the allocated page to a
+func (a *Account) malloc() ([]byte, error) {
+	if a == nil {
+		return TryMalloc()
+	}
+	n := atomic.AddInt64(&a.inuse, 1)
+	if a.Limit > 0 && n > int64(a.Limit) {
+		atomic.AddInt64(&a.inuse, -1)
+		return nil, ErrOutOfMemory
+	}
+	buf, err := TryMalloc()
+	if err != nil {
+		atomic.AddInt64(&a.inuse, -1)
+		return nil, err
+	}
+	for {
+		peak := atomic.LoadInt64(&a.peak)
+		if n <= peak || atomic.CompareAndSwapInt64(&a.peak, peak, n) {
+			return buf, nil
+		}
+	}
+}
+
+// free frees a page returned by a.malloc
+func (a *Account) free(buf []byte) {
+	Free(buf)
+	if a != nil {
+		atomic.AddInt64(&a.inuse, -1)
+	}
+}
+
+// accounted is implemented by io.Writers
+// that charge the vm memory that they
+// allocate to an Account
+type accounted interface {
+	account() *Account
+	setAccount(a *Account)
+}
+
+// accountOf returns the Account used by w,
+// or nil if w does not have one
+func accountOf(w io.Writer) *Account {
+	if aw, ok := w.(accounted); ok {
+		return aw.account()
+	}
+	return nil
+}
+
+// WithAccount returns a QuerySink that charges the
+// vm memory allocated by the streams opened from dst,
+// and by the Tables that write to those streams, to a.
+// If a is nil, WithAccount returns dst.
+func WithAccount(dst QuerySink, a *Account) QuerySink {
+	if a == nil {
+		return dst
+	}
+	return &accountedSink{QuerySink: dst, acct: a}
+}
+
+type accountedSink struct {
+	QuerySink
+	acct *Account
+}
+
+func (s *accountedSink) Open() (io.WriteCloser, error) {
+	w, err := s.QuerySink.Open()
+	if err != nil {
+		return nil, err
+	}
+	if aw, ok := w.(accounted); ok {
+		aw.setAccount(s.acct)
+	}
+	return w, nil
+}
@@ -582,10 +582,23 @@ func (a Aggregation) String() string {
 	return out.String()
 }
 
+func (a Aggregation) redacted() string {
+	var out strings.Builder
+	for i := range a {
+		if i != 0 {
+			out.WriteString(", ")
+		}
+		out.WriteString(expr.ToRedacted(a[i].Expr))
+	}
+	return out.String()
+}
+
 func (a Aggregation) Equals(x Aggregation) bool {
 	return slices.EqualFunc(a, x, AggBinding.Equals)
 }
 
+func (q *Aggregate) redacted() string { return q.bind.redacted() }
+
 func (q *Aggregate) Open() (io.WriteCloser, error) {
 	aggregateDataSize := len(q.initialData)
 	partialData := make([]byte, aggregateDataSize)
@@ -656,7 +669,11 @@ func (p *aggregateLocal) writeRows(delims []vmref, rp *rowParams) error {
 
 	p.bc.prepare(rp)
 
+	wd := watch("aggregate", p.parent, &p.prog, &p.bc)
 	rowsCount := evalaggregatebc(&p.bc, delims, p.partialData)
+	if err := wd.done(); err != nil {
+		return err
+	}
 	if p.bc.err != 0 {
 		return bytecodeerror("aggregate", &p.bc)
 	}
@@ -764,7 +781,7 @@ func (q *Aggregate) compileAggregate(agg Aggregation) error {
 			}
 
 		case expr.OpBoolAnd, expr.OpBoolOr:
-			argv, err := p.compileAsBool(agg[i].Expr.Inner)
+			argv, err := p.compileAggregateBool(agg[i].Expr.Inner)
 			if err != nil {
 				return fmt.Errorf("don't know how to aggregate %q: %w", agg[i].Expr.Inner, err)
 			}
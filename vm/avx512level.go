@@ -15,6 +15,10 @@
 package vm
 
 import (
+	"fmt"
+	"sort"
+	"strings"
+
 	"golang.org/x/sys/cpu"
 )
 
@@ -24,32 +28,147 @@ const (
 	avx512level2
 )
 
+// cpufeature is a set of optional AVX-512 extensions.
+//
+// Every bytecode op requires the baseline AVX-512
+// (F, BW, DQ, VL, CD) ISA; some kernels additionally
+// depend on one or more of the extensions below.
+// Those kernels are only dispatched when the current
+// CPU supports all of the extensions they require.
+type cpufeature uint32
+
+const (
+	featVBMI cpufeature = 1 << iota
+	featVBMI2
+	featVPOPCNTDQ
+	featIFMA
+	featBITALG
+	featVAES
+	featGFNI
+	featVPCLMULQDQ
+
+	featAll = featVBMI | featVBMI2 | featVPOPCNTDQ | featIFMA |
+		featBITALG | featVAES | featGFNI | featVPCLMULQDQ
+)
+
+var featnames = []struct {
+	feat cpufeature
+	name string
+}{
+	{featVBMI, "AVX512_VBMI"},
+	{featVBMI2, "AVX512_VBMI2"},
+	{featVPOPCNTDQ, "AVX512_VPOPCNTDQ"},
+	{featIFMA, "AVX512_IFMA"},
+	{featBITALG, "AVX512_BITALG"},
+	{featVAES, "VAES"},
+	{featGFNI, "GFNI"},
+	{featVPCLMULQDQ, "VPCLMULQDQ"},
+}
+
+func (c cpufeature) String() string {
+	var names []string
+	for i := range featnames {
+		if c&featnames[i].feat != 0 {
+			names = append(names, featnames[i].name)
+		}
+	}
+	return strings.Join(names, "+")
+}
+
+// has returns true if c includes all of the features in want.
+func (c cpufeature) has(want cpufeature) bool { return c&want == want }
+
+func detectfeatures() cpufeature {
+	var c cpufeature
+	set := func(ok bool, f cpufeature) {
+		if ok {
+			c |= f
+		}
+	}
+	set(cpu.X86.HasAVX512VBMI, featVBMI)
+	set(cpu.X86.HasAVX512VBMI2, featVBMI2)
+	set(cpu.X86.HasAVX512VPOPCNTDQ, featVPOPCNTDQ)
+	set(cpu.X86.HasAVX512IFMA, featIFMA)
+	set(cpu.X86.HasAVX512BITALG, featBITALG)
+	set(cpu.X86.HasAVX512VAES, featVAES)
+	set(cpu.X86.HasAVX512GFNI, featGFNI)
+	set(cpu.X86.HasAVX512VPCLMULQDQ, featVPCLMULQDQ)
+	return c
+}
+
+// cpufeatures is the set of optional extensions
+// supported by the current CPU.
+var cpufeatures = detectfeatures()
+
+// Supported returns nil if the current CPU implements
+// the baseline AVX-512 ISA that every bytecode op requires,
+// or an error listing the missing extensions otherwise.
+//
+// There is no fallback interpreter for the bytecode,
+// so queries cannot be executed when Supported
+// returns an error.
+func Supported() error {
+	baseline := []struct {
+		ok   bool
+		name string
+	}{
+		{cpu.X86.HasAVX512F, "AVX512F"},
+		{cpu.X86.HasAVX512BW, "AVX512BW"},
+		{cpu.X86.HasAVX512DQ, "AVX512DQ"},
+		{cpu.X86.HasAVX512VL, "AVX512VL"},
+		{cpu.X86.HasAVX512CD, "AVX512CD"},
+	}
+	var missing []string
+	for i := range baseline {
+		if !baseline[i].ok {
+			missing = append(missing, baseline[i].name)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("vm: CPU does not support %s", strings.Join(missing, "+"))
+	}
+	return nil
+}
+
+// opfeatures is the per-kernel dispatch table:
+// it lists the optional extensions each bytecode op
+// depends on. Ops that are not present in this table
+// only require the baseline AVX-512 ISA.
+var opfeatures = map[bcop]cpufeature{
+	opbitcounti64v2:  featVPOPCNTDQ,
+	opaggslotcountv2: featVPOPCNTDQ,
+	opDfaT6:          featVBMI,
+	opDfaT7:          featVBMI,
+	opDfaT8:          featVBMI,
+	opDfaT6Z:         featVBMI,
+	opDfaT7Z:         featVBMI,
+	opDfaT8Z:         featVBMI,
+}
+
 // avx512level determines the current CPU's level of AVX512 instructions.
 func avx512level() uint8 {
-	if cpu.X86.HasAVX512VBMI &&
-		cpu.X86.HasAVX512VBMI2 &&
-		cpu.X86.HasAVX512VPOPCNTDQ &&
-		cpu.X86.HasAVX512IFMA &&
-		cpu.X86.HasAVX512BITALG &&
-		cpu.X86.HasAVX512VAES &&
-		cpu.X86.HasAVX512GFNI &&
-		cpu.X86.HasAVX512VPCLMULQDQ {
+	if cpufeatures.has(featAll) {
 		return avx512level2
 	}
-
 	return avx512level1
 }
 
 // setavx512level sets SSA instructions to use opcodes from given AVX512 ISA level.
+//
+// At level 2 each variant opcode is only selected
+// when the current CPU supports the extensions
+// it requires, so this is safe to call on CPUs
+// that implement only a subset of the level 2 features.
 func setavx512level(level uint8) {
 	switch level {
 	case avx512highestlevel:
-		setavx512level(avx512level())
+		setavx512level(avx512level2)
 
 	case avx512level1:
 		initssadefs()
 
 	case avx512level2:
+		initssadefs()
 		patchssadefs(patchAVX512Level2)
 	}
 }
@@ -58,6 +177,9 @@ func initssadefs() {
 	copy(ssainfo[:], _ssainfo[:])
 }
 
+// patchssadefs replaces each baseline opcode (repl.to)
+// with its variant (repl.from) when the variant
+// is supported on the current hardware.
 func patchssadefs(repl []opreplace) {
 	if len(repl) == 0 {
 		return
@@ -66,7 +188,9 @@ func patchssadefs(repl []opreplace) {
 	lookup := make(map[bcop]bcop)
 	for i := range repl {
 		r := &repl[i]
-		lookup[r.from] = r.to
+		if isSupported(r.from) {
+			lookup[r.to] = r.from
+		}
 	}
 
 	for i := range _ssainfo {
@@ -81,16 +205,32 @@ func patchssadefs(repl []opreplace) {
 // isSupported determines whether the provided bytecode op
 // is supported on the current hardware
 func isSupported(bc bcop) bool {
-	// consider creating a case switch in ops_gen.go that
-	// given a bc returns the level. Then we can remove the
-	// inefficient code below
-	if avx512level() == avx512level2 {
-		return true
-	}
-	for _, repl := range patchAVX512Level2 {
-		if repl.to == bc {
-			return false
+	return cpufeatures.has(opfeatures[bc])
+}
+
+// DisabledKernels returns a human-readable description
+// of each accelerated code path that has been disabled
+// because the current CPU lacks an AVX-512 extension
+// that the path requires. The returned list is empty
+// when every accelerated path is available.
+//
+// This is intended to be logged at startup so that
+// degraded performance on a particular instance type
+// can be diagnosed.
+func DisabledKernels() []string {
+	missing := make(map[cpufeature][]string)
+	for op, feat := range opfeatures {
+		if !cpufeatures.has(feat) {
+			lack := feat &^ cpufeatures
+			missing[lack] = append(missing[lack], opinfo[op].text)
 		}
 	}
-	return true
+	var out []string
+	for lack, ops := range missing {
+		sort.Strings(ops)
+		out = append(out, fmt.Sprintf("%s not supported; disabled kernels: %s",
+			lack, strings.Join(ops, ", ")))
+	}
+	sort.Strings(out)
+	return out
 }
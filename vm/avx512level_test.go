@@ -0,0 +1,61 @@
+// Copyright (C) 2022 Sneller, Inc.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package vm
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPartialFeatureDispatch(t *testing.T) {
+	saved := cpufeatures
+	t.Cleanup(func() {
+		cpufeatures = saved
+		setavx512level(avx512highestlevel)
+	})
+
+	// a CPU with every extension except VPOPCNTDQ
+	cpufeatures = featAll &^ featVPOPCNTDQ
+	setavx512level(avx512highestlevel)
+	if got := ssainfo[sbitcounti].bc; got != opbitcounti64 {
+		t.Errorf("bitcount.i: got op %d, want baseline %d", got, opbitcounti64)
+	}
+	if !isSupported(opDfaT6) {
+		t.Error("dfa_tiny6 should be supported with VBMI")
+	}
+	msgs := DisabledKernels()
+	if len(msgs) != 1 || !strings.Contains(msgs[0], "AVX512_VPOPCNTDQ") {
+		t.Errorf("unexpected diagnostics: %q", msgs)
+	}
+
+	// a CPU with only VPOPCNTDQ
+	cpufeatures = featVPOPCNTDQ
+	setavx512level(avx512highestlevel)
+	if got := ssainfo[sbitcounti].bc; got != opbitcounti64v2 {
+		t.Errorf("bitcount.i: got op %d, want variant %d", got, opbitcounti64v2)
+	}
+	if isSupported(opDfaT6) {
+		t.Error("dfa_tiny6 should not be supported without VBMI")
+	}
+	msgs = DisabledKernels()
+	if len(msgs) != 1 || !strings.Contains(msgs[0], "dfa_tiny6") {
+		t.Errorf("unexpected diagnostics: %q", msgs)
+	}
+
+	cpufeatures = featAll
+	if msgs := DisabledKernels(); len(msgs) != 0 {
+		t.Errorf("unexpected diagnostics: %q", msgs)
+	}
+}
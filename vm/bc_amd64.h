@@ -64,13 +64,15 @@
 // - Z0:Z1      - offset and size of records (can be clobbered by opcodes)
 //
 // BC_ENTER() also takes care to reset the output scratch buffer
+// and to abort (via vmenter) if the watchdog has interrupted
+// the program
 #define BC_ENTER()                                             \
   KMOVW K1, K7                                                 \
   BC_CLEAR_SCRATCH(VIRT_PCREG)                                 \
   BC_CLEAR_ERROR()                                             \
   MOVQ bytecode_compiled(VIRT_BCPTR), VIRT_PCREG               \
   MOVQ bytecode_vstack(VIRT_BCPTR), VIRT_VALUES                \
-  BC_INVOKE()
+  CALL vmenter(SB)
 
 // BC Scratch Buffer
 // -----------------
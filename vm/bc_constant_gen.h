@@ -251,150 +251,153 @@ CONST_DATA_U32(constpool, 548, $16) // 0x00000010
 #define CONSTD_TRUE_BYTE() CONST_GET_PTR(constpool, 552)
 CONST_DATA_U32(constpool, 552, $17) // 0x00000011
 
-#define CONSTD_0x2E() CONST_GET_PTR(constpool, 556)
-CONST_DATA_U32(constpool, 556, $46) // 0x0000002e
+#define CONSTD_31() CONST_GET_PTR(constpool, 556)
+CONST_DATA_U32(constpool, 556, $31) // 0x0000001f
 
-#define CONSTD_131() CONST_GET_PTR(constpool, 560)
-CONST_DATA_U32(constpool, 560, $131) // 0x00000083
+#define CONSTD_0x2E() CONST_GET_PTR(constpool, 560)
+CONST_DATA_U32(constpool, 560, $46) // 0x0000002e
 
-#define CONSTD_0xB0() CONST_GET_PTR(constpool, 564)
-CONST_DATA_U32(constpool, 564, $176) // 0x000000b0
+#define CONSTD_131() CONST_GET_PTR(constpool, 564)
+CONST_DATA_U32(constpool, 564, $131) // 0x00000083
 
-#define CONSTD_0b11000000() CONST_GET_PTR(constpool, 568)
-CONST_DATA_U32(constpool, 568, $192) // 0x000000c0
+#define CONSTD_0xB0() CONST_GET_PTR(constpool, 568)
+CONST_DATA_U32(constpool, 568, $176) // 0x000000b0
 
-#define CONSTD_0xD0() CONST_GET_PTR(constpool, 572)
-CONST_DATA_U32(constpool, 572, $208) // 0x000000d0
+#define CONSTD_0b11000000() CONST_GET_PTR(constpool, 572)
+CONST_DATA_U32(constpool, 572, $192) // 0x000000c0
 
-#define CONSTD_0b11100000() CONST_GET_PTR(constpool, 576)
-CONST_DATA_U32(constpool, 576, $224) // 0x000000e0
+#define CONSTD_0xD0() CONST_GET_PTR(constpool, 576)
+CONST_DATA_U32(constpool, 576, $208) // 0x000000d0
 
-#define CONSTD_0b11110000() CONST_GET_PTR(constpool, 580)
-CONST_DATA_U32(constpool, 580, $240) // 0x000000f0
+#define CONSTD_0b11100000() CONST_GET_PTR(constpool, 580)
+CONST_DATA_U32(constpool, 580, $224) // 0x000000e0
 
-#define CONSTD_0b11111000() CONST_GET_PTR(constpool, 584)
-CONST_DATA_U32(constpool, 584, $248) // 0x000000f8
+#define CONSTD_0b11110000() CONST_GET_PTR(constpool, 584)
+CONST_DATA_U32(constpool, 584, $240) // 0x000000f0
 
-#define CONSTD_0xFF() CONST_GET_PTR(constpool, 588)
-CONST_DATA_U32(constpool, 588, $255) // 0x000000ff
+#define CONSTD_0b11111000() CONST_GET_PTR(constpool, 588)
+CONST_DATA_U32(constpool, 588, $248) // 0x000000f8
 
-#define CONSTD_5243() CONST_GET_PTR(constpool, 592)
-CONST_DATA_U32(constpool, 592, $5243) // 0x0000147b
+#define CONSTD_0xFF() CONST_GET_PTR(constpool, 592)
+CONST_DATA_U32(constpool, 592, $255) // 0x000000ff
 
-#define CONSTD_6554() CONST_GET_PTR(constpool, 596)
-CONST_DATA_U32(constpool, 596, $6554) // 0x0000199a
+#define CONSTD_5243() CONST_GET_PTR(constpool, 596)
+CONST_DATA_U32(constpool, 596, $5243) // 0x0000147b
 
-#define CONSTD_0x3FFF() CONST_GET_PTR(constpool, 600)
-CONST_DATA_U32(constpool, 600, $16383) // 0x00003fff
+#define CONSTD_6554() CONST_GET_PTR(constpool, 600)
+CONST_DATA_U32(constpool, 600, $6554) // 0x0000199a
 
-#define CONSTD_16388() CONST_GET_PTR(constpool, 604)
-CONST_DATA_U32(constpool, 604, $16388) // 0x00004004
+#define CONSTD_0x3FFF() CONST_GET_PTR(constpool, 604)
+CONST_DATA_U32(constpool, 604, $16383) // 0x00003fff
 
-#define CONSTD_0x10101() CONST_GET_PTR(constpool, 608)
-CONST_DATA_U32(constpool, 608, $65793) // 0x00010101
+#define CONSTD_16388() CONST_GET_PTR(constpool, 608)
+CONST_DATA_U32(constpool, 608, $16388) // 0x00004004
 
-#define CONSTD_0x10801() CONST_GET_PTR(constpool, 612)
-CONST_DATA_U32(constpool, 612, $67585) // 0x00010801
+#define CONSTD_0x10101() CONST_GET_PTR(constpool, 612)
+CONST_DATA_U32(constpool, 612, $65793) // 0x00010101
 
-#define CONSTD_0x400001() CONST_GET_PTR(constpool, 616)
-CONST_DATA_U32(constpool, 616, $4194305) // 0x00400001
+#define CONSTD_0x10801() CONST_GET_PTR(constpool, 616)
+CONST_DATA_U32(constpool, 616, $67585) // 0x00010801
 
-#define CONSTD_0x007F007F() CONST_GET_PTR(constpool, 620)
-CONST_DATA_U32(constpool, 620, $8323199) // 0x007f007f
+#define CONSTD_0x400001() CONST_GET_PTR(constpool, 620)
+CONST_DATA_U32(constpool, 620, $4194305) // 0x00400001
 
-#define CONSTD_0x01010101() CONST_GET_PTR(constpool, 624)
-CONST_DATA_U32(constpool, 624, $16843009) // 0x01010101
+#define CONSTD_0x007F007F() CONST_GET_PTR(constpool, 624)
+CONST_DATA_U32(constpool, 624, $8323199) // 0x007f007f
 
-#define CONSTD_134217727() CONST_GET_PTR(constpool, 628)
-CONST_DATA_U32(constpool, 628, $134217727) // 0x07ffffff
+#define CONSTD_0x01010101() CONST_GET_PTR(constpool, 628)
+CONST_DATA_U32(constpool, 628, $16843009) // 0x01010101
 
-#define CONSTD_0x0F0F0F0F() CONST_GET_PTR(constpool, 632)
-CONST_DATA_U32(constpool, 632, $252645135) // 0x0f0f0f0f
+#define CONSTD_134217727() CONST_GET_PTR(constpool, 632)
+CONST_DATA_U32(constpool, 632, $134217727) // 0x07ffffff
 
-#define CONSTD_0x3FFFFFFF() CONST_GET_PTR(constpool, 636)
-CONST_DATA_U32(constpool, 636, $1073741823) // 0x3fffffff
+#define CONSTD_0x0F0F0F0F() CONST_GET_PTR(constpool, 636)
+CONST_DATA_U32(constpool, 636, $252645135) // 0x0f0f0f0f
 
-#define CONSTD_UTF8_4B_MASK() CONST_GET_PTR(constpool, 640)
-CONST_DATA_U32(constpool, 640, $2155905264) // 0x808080f0
+#define CONSTD_0x3FFFFFFF() CONST_GET_PTR(constpool, 640)
+CONST_DATA_U32(constpool, 640, $1073741823) // 0x3fffffff
 
-#define CONSTD_UTF8_3B_MASK() CONST_GET_PTR(constpool, 644)
-CONST_DATA_U32(constpool, 644, $2155929600) // 0x8080e000
+#define CONSTD_UTF8_4B_MASK() CONST_GET_PTR(constpool, 644)
+CONST_DATA_U32(constpool, 644, $2155905264) // 0x808080f0
 
-#define CONSTD_UTF8_2B_MASK() CONST_GET_PTR(constpool, 648)
-CONST_DATA_U32(constpool, 648, $2160066560) // 0x80c00000
+#define CONSTD_UTF8_3B_MASK() CONST_GET_PTR(constpool, 648)
+CONST_DATA_U32(constpool, 648, $2155929600) // 0x8080e000
 
-#define CONSTD_0b11001110_01110011_10011100_11100111() CONST_GET_PTR(constpool, 652)
-CONST_DATA_U32(constpool, 652, $3463683303) // 0xce739ce7
+#define CONSTD_UTF8_2B_MASK() CONST_GET_PTR(constpool, 652)
+CONST_DATA_U32(constpool, 652, $2160066560) // 0x80c00000
 
-#define CONSTD_0xFFFF0000() CONST_GET_PTR(constpool, 656)
-CONST_DATA_U32(constpool, 656, $4294901760) // 0xffff0000
+#define CONSTD_0b11001110_01110011_10011100_11100111() CONST_GET_PTR(constpool, 656)
+CONST_DATA_U32(constpool, 656, $3463683303) // 0xce739ce7
+
+#define CONSTD_0xFFFF0000() CONST_GET_PTR(constpool, 660)
+CONST_DATA_U32(constpool, 660, $4294901760) // 0xffff0000
 
 // uint8 constants
-#define CONSTB_97() CONST_GET_PTR(constpool, 660)
-CONST_DATA_U8(constpool, 660, $97) // 0x61
+#define CONSTB_97() CONST_GET_PTR(constpool, 664)
+CONST_DATA_U8(constpool, 664, $97) // 0x61
 
-#define CONSTB_122() CONST_GET_PTR(constpool, 661)
-CONST_DATA_U8(constpool, 661, $122) // 0x7a
+#define CONSTB_122() CONST_GET_PTR(constpool, 665)
+CONST_DATA_U8(constpool, 665, $122) // 0x7a
 
 // float64 constants
-#define CONSTF64_PI_DIV_180() CONST_GET_PTR(constpool, 662)
-CONST_DATA_U64(constpool, 662, $0x3f91df46a2529d39) // float64(0.017453)
+#define CONSTF64_PI_DIV_180() CONST_GET_PTR(constpool, 666)
+CONST_DATA_U64(constpool, 666, $0x3f91df46a2529d39) // float64(0.017453)
 
-#define CONSTF64_HALF() CONST_GET_PTR(constpool, 670)
-CONST_DATA_U64(constpool, 670, $0x3fe0000000000000) // float64(0.500000)
+#define CONSTF64_HALF() CONST_GET_PTR(constpool, 674)
+CONST_DATA_U64(constpool, 674, $0x3fe0000000000000) // float64(0.500000)
 
-#define CONSTF64_0p9999() CONST_GET_PTR(constpool, 678)
-CONST_DATA_U64(constpool, 678, $0x3fefff2e48e8a71e) // float64(0.999900)
+#define CONSTF64_0p9999() CONST_GET_PTR(constpool, 682)
+CONST_DATA_U64(constpool, 682, $0x3fefff2e48e8a71e) // float64(0.999900)
 
-#define CONSTF64_1() CONST_GET_PTR(constpool, 686)
-CONST_DATA_U64(constpool, 686, $0x3ff0000000000000) // float64(1.000000)
+#define CONSTF64_1() CONST_GET_PTR(constpool, 690)
+CONST_DATA_U64(constpool, 690, $0x3ff0000000000000) // float64(1.000000)
 
-#define CONSTF64_4() CONST_GET_PTR(constpool, 694)
-CONST_DATA_U64(constpool, 694, $0x4010000000000000) // float64(4.000000)
+#define CONSTF64_4() CONST_GET_PTR(constpool, 698)
+CONST_DATA_U64(constpool, 698, $0x4010000000000000) // float64(4.000000)
 
-#define CONSTF64_7() CONST_GET_PTR(constpool, 702)
-CONST_DATA_U64(constpool, 702, $0x401c000000000000) // float64(7.000000)
+#define CONSTF64_7() CONST_GET_PTR(constpool, 706)
+CONST_DATA_U64(constpool, 706, $0x401c000000000000) // float64(7.000000)
 
-#define CONSTF64_11() CONST_GET_PTR(constpool, 710)
-CONST_DATA_U64(constpool, 710, $0x4026000000000000) // float64(11.000000)
+#define CONSTF64_11() CONST_GET_PTR(constpool, 714)
+CONST_DATA_U64(constpool, 714, $0x4026000000000000) // float64(11.000000)
 
-#define CONSTF64_12() CONST_GET_PTR(constpool, 718)
-CONST_DATA_U64(constpool, 718, $0x4028000000000000) // float64(12.000000)
+#define CONSTF64_12() CONST_GET_PTR(constpool, 722)
+CONST_DATA_U64(constpool, 722, $0x4028000000000000) // float64(12.000000)
 
-#define CONSTF64_65536() CONST_GET_PTR(constpool, 726)
-CONST_DATA_U64(constpool, 726, $0x40f0000000000000) // float64(65536.000000)
+#define CONSTF64_65536() CONST_GET_PTR(constpool, 730)
+CONST_DATA_U64(constpool, 730, $0x40f0000000000000) // float64(65536.000000)
 
-#define CONSTF64_MICROSECONDS_IN_1_DAY_SHR_13() CONST_GET_PTR(constpool, 734)
-CONST_DATA_U64(constpool, 734, $0x41641dd760000000) // float64(10546875.000000)
+#define CONSTF64_MICROSECONDS_IN_1_DAY_SHR_13() CONST_GET_PTR(constpool, 738)
+CONST_DATA_U64(constpool, 738, $0x41641dd760000000) // float64(10546875.000000)
 
-#define CONSTF64_12742000() CONST_GET_PTR(constpool, 742)
-CONST_DATA_U64(constpool, 742, $0x41684dae00000000) // float64(12742000.000000)
+#define CONSTF64_12742000() CONST_GET_PTR(constpool, 746)
+CONST_DATA_U64(constpool, 746, $0x41684dae00000000) // float64(12742000.000000)
 
-#define CONSTF64_100000000() CONST_GET_PTR(constpool, 750)
-CONST_DATA_U64(constpool, 750, $0x4197d78400000000) // float64(100000000.000000)
+#define CONSTF64_100000000() CONST_GET_PTR(constpool, 754)
+CONST_DATA_U64(constpool, 754, $0x4197d78400000000) // float64(100000000.000000)
 
-#define CONSTF64_152587890625() CONST_GET_PTR(constpool, 758)
-CONST_DATA_U64(constpool, 758, $0x4241c37937e08000) // float64(152587890625.000000)
+#define CONSTF64_152587890625() CONST_GET_PTR(constpool, 762)
+CONST_DATA_U64(constpool, 762, $0x4241c37937e08000) // float64(152587890625.000000)
 
-#define CONSTF64_281474976710656_DIV_360() CONST_GET_PTR(constpool, 766)
-CONST_DATA_U64(constpool, 766, $0x4266c16c16c16c17) // float64(781874935307.377808)
+#define CONSTF64_281474976710656_DIV_360() CONST_GET_PTR(constpool, 770)
+CONST_DATA_U64(constpool, 770, $0x4266c16c16c16c17) // float64(781874935307.377808)
 
-#define CONSTF64_281474976710656_DIV_4PI() CONST_GET_PTR(constpool, 774)
-CONST_DATA_U64(constpool, 774, $0x42b45f306dc9c883) // float64(22399066950088.511719)
+#define CONSTF64_281474976710656_DIV_4PI() CONST_GET_PTR(constpool, 778)
+CONST_DATA_U64(constpool, 778, $0x42b45f306dc9c883) // float64(22399066950088.511719)
 
-#define CONSTF64_140737488355328() CONST_GET_PTR(constpool, 782)
-CONST_DATA_U64(constpool, 782, $0x42e0000000000000) // float64(140737488355328.000000)
+#define CONSTF64_140737488355328() CONST_GET_PTR(constpool, 786)
+CONST_DATA_U64(constpool, 786, $0x42e0000000000000) // float64(140737488355328.000000)
 
-#define CONSTF64_POSITIVE_INF() CONST_GET_PTR(constpool, 790)
-CONST_DATA_U64(constpool, 790, $0x7ff0000000000000) // float64(+Inf)
+#define CONSTF64_POSITIVE_INF() CONST_GET_PTR(constpool, 794)
+CONST_DATA_U64(constpool, 794, $0x7ff0000000000000) // float64(+Inf)
 
-#define CONSTF64_NAN() CONST_GET_PTR(constpool, 798)
-CONST_DATA_U64(constpool, 798, $0x7ff8000000000001) // float64(NaN)
+#define CONSTF64_NAN() CONST_GET_PTR(constpool, 802)
+CONST_DATA_U64(constpool, 802, $0x7ff8000000000001) // float64(NaN)
 
-#define CONSTF64_MINUS_0p9999() CONST_GET_PTR(constpool, 806)
-CONST_DATA_U64(constpool, 806, $0xbfefff2e48e8a71e) // float64(-0.999900)
+#define CONSTF64_MINUS_0p9999() CONST_GET_PTR(constpool, 810)
+CONST_DATA_U64(constpool, 810, $0xbfefff2e48e8a71e) // float64(-0.999900)
 
-#define CONSTF64_NEGATIVE_INF() CONST_GET_PTR(constpool, 814)
-CONST_DATA_U64(constpool, 814, $0xfff0000000000000) // float64(-Inf)
+#define CONSTF64_NEGATIVE_INF() CONST_GET_PTR(constpool, 818)
+CONST_DATA_U64(constpool, 818, $0xfff0000000000000) // float64(-Inf)
 
-CONST_GLOBAL(constpool, $822)
+CONST_GLOBAL(constpool, $826)
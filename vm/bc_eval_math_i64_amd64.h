@@ -15,16 +15,6 @@
 // Integer Math Instructions - Helpers
 // -----------------------------------
 
-#define BC_ARITH_OP_I64_IMPL(Instruction)                               \
-  BC_UNPACK_4xSLOT(0, OUT(DX), OUT(BX), OUT(CX), OUT(R8))               \
-  BC_LOAD_K1_K2_FROM_SLOT(OUT(K1), OUT(K2), IN(R8))                     \
-  BC_LOAD_I64_FROM_SLOT(OUT(Z2), OUT(Z3), IN(BX))                       \
-                                                                        \
-  Instruction.Z 0(VIRT_VALUES)(CX*1), Z2, K1, Z2                        \
-  Instruction.Z 64(VIRT_VALUES)(CX*1), Z3, K2, Z3                       \
-                                                                        \
-  BC_STORE_I64_TO_SLOT(IN(Z2), IN(Z3), IN(DX))
-
 #define BC_ARITH_OP_I64_IMPL_K(Instruction)                             \
   BC_UNPACK_3xSLOT(BC_SLOT_SIZE*2, OUT(BX), OUT(CX), OUT(R8))           \
   BC_LOAD_K1_K2_FROM_SLOT(OUT(K1), OUT(K2), IN(R8))                     \
@@ -37,16 +27,6 @@
   BC_STORE_I64_TO_SLOT(IN(Z2), IN(Z3), IN(DX))                          \
   BC_STORE_K_TO_SLOT(IN(K1), IN(R8))
 
-#define BC_ARITH_OP_I64_IMM_IMPL(Instruction)                           \
-  BC_UNPACK_2xSLOT_ZI64_SLOT(0, OUT(DX), OUT(BX), OUT(Z4), OUT(R8))     \
-  BC_LOAD_K1_K2_FROM_SLOT(OUT(K1), OUT(K2), IN(R8))                     \
-  BC_LOAD_I64_FROM_SLOT(OUT(Z2), OUT(Z3), IN(BX))                       \
-                                                                        \
-  Instruction.Z Z4, Z2, K1, Z2                                          \
-  Instruction.Z Z4, Z3, K2, Z3                                          \
-                                                                        \
-  BC_STORE_I64_TO_SLOT(IN(Z2), IN(Z3), IN(DX))
-
 #define BC_ARITH_OP_I64_IMM_IMPL_K(Instruction)                         \
   BC_UNPACK_SLOT_ZI64_SLOT(BC_SLOT_SIZE*2, OUT(BX), OUT(Z4), OUT(R8))   \
   BC_LOAD_K1_K2_FROM_SLOT(OUT(K1), OUT(K2), IN(R8))                     \
@@ -418,112 +398,14 @@ TEXT bcaddmuli64imm(SB), NOSPLIT|NOFRAME, $0
 
   NEXT_ADVANCE(BC_SLOT_SIZE*5 + 8)
 
-// Integer Math Instructions - Min / Max
-// -------------------------------------
-
-// i64[0] = minvalue.i64(i64[1], i64[2]).k[3]
-TEXT bcminvaluei64(SB), NOSPLIT|NOFRAME, $0
-  BC_ARITH_OP_I64_IMPL(VPMINSQ)
-  NEXT_ADVANCE(BC_SLOT_SIZE*4)
-
-// i64[0] = minvalue.i64@imm(i64[1], i64@imm[2]).k[3]
-TEXT bcminvaluei64imm(SB), NOSPLIT|NOFRAME, $0
-  BC_ARITH_OP_I64_IMM_IMPL(VPMINSQ)
-  NEXT_ADVANCE(BC_SLOT_SIZE*3 + 8)
-
-// i64[0] = maxvalue.i64(i64[1], i64[2]).k[3]
-TEXT bcmaxvaluei64(SB), NOSPLIT|NOFRAME, $0
-  BC_ARITH_OP_I64_IMPL(VPMAXSQ)
-  NEXT_ADVANCE(BC_SLOT_SIZE*4)
-
-// i64[0] = maxvalue.i64@imm(i64[1], i64@imm[2]).k[3]
-TEXT bcmaxvaluei64imm(SB), NOSPLIT|NOFRAME, $0
-  BC_ARITH_OP_I64_IMM_IMPL(VPMAXSQ)
-  NEXT_ADVANCE(BC_SLOT_SIZE*3 + 8)
-
-// Integer Math Instructions - And
-// -------------------------------
-
-// i64[0] = and.i64(i64[1], i64[2]).k[3]
-TEXT bcandi64(SB), NOSPLIT|NOFRAME, $0
-  BC_ARITH_OP_I64_IMPL(VPANDQ)
-  NEXT_ADVANCE(BC_SLOT_SIZE*4)
-
-// i64[0] = and.i64@imm(i64[1], i64@imm[2]).k[3]
-TEXT bcandi64imm(SB), NOSPLIT|NOFRAME, $0
-  BC_ARITH_OP_I64_IMM_IMPL(VPANDQ)
-  NEXT_ADVANCE(BC_SLOT_SIZE*3 + 8)
-
-// Integer Math Instructions - Or
-// ------------------------------
-
-// i64[0] = or.i64(i64[1], i64[2]).k[3]
-TEXT bcori64(SB), NOSPLIT|NOFRAME, $0
-  BC_ARITH_OP_I64_IMPL(VPORQ)
-  NEXT_ADVANCE(BC_SLOT_SIZE*4)
-
-// i64[0] = or.i64@imm(i64[1], i64@imm[2]).k[3]
-TEXT bcori64imm(SB), NOSPLIT|NOFRAME, $0
-  BC_ARITH_OP_I64_IMM_IMPL(VPORQ)
-  NEXT_ADVANCE(BC_SLOT_SIZE*3 + 8)
-
-// Integer Math Instructions - Xor
-// -------------------------------
-
-// i64[0] = xor.i64(i64[1], i64[2]).k[3]
-TEXT bcxori64(SB), NOSPLIT|NOFRAME, $0
-  BC_ARITH_OP_I64_IMPL(VPXORQ)
-  NEXT_ADVANCE(BC_SLOT_SIZE*4)
-
-// i64[0] = xor.i64@imm(i64[1], i64@imm[2]).k[3]
-TEXT bcxori64imm(SB), NOSPLIT|NOFRAME, $0
-  BC_ARITH_OP_I64_IMM_IMPL(VPXORQ)
-  NEXT_ADVANCE(BC_SLOT_SIZE*3 + 8)
-
-// Integer Math Instructions - SLL
-// -------------------------------
-
-// i64[0] = sll.i64(i64[1], i64[2]).k[3]
-TEXT bcslli64(SB), NOSPLIT|NOFRAME, $0
-  BC_ARITH_OP_I64_IMPL(VPSLLVQ)
-  NEXT_ADVANCE(BC_SLOT_SIZE*4)
-
-// i64[0] = sll.i64@imm(i64[1], i64@imm[2]).k[3]
-TEXT bcslli64imm(SB), NOSPLIT|NOFRAME, $0
-  BC_ARITH_OP_I64_IMM_IMPL(VPSLLVQ)
-  NEXT_ADVANCE(BC_SLOT_SIZE*3 + 8)
-
-// Integer Math Instructions - SRA
-// -------------------------------
-
-// i64[0] = sra.i64(i64[1], i64[2]).k[3]
-TEXT bcsrai64(SB), NOSPLIT|NOFRAME, $0
-  BC_ARITH_OP_I64_IMPL(VPSRAVQ)
-  NEXT_ADVANCE(BC_SLOT_SIZE*4)
-
-// i64[0] = sra.i64@imm(i64[1], i64@imm[2]).k[3]
-TEXT bcsrai64imm(SB), NOSPLIT|NOFRAME, $0
-  BC_ARITH_OP_I64_IMM_IMPL(VPSRAVQ)
-  NEXT_ADVANCE(BC_SLOT_SIZE*3 + 8)
-
-// Integer Math Instructions - SRL
-// -------------------------------
-
-// i64[0] = srl.i64(i64[1], i64[2]).k[3]
-TEXT bcsrli64(SB), NOSPLIT|NOFRAME, $0
-  BC_ARITH_OP_I64_IMPL(VPSRLVQ)
-  NEXT_ADVANCE(BC_SLOT_SIZE*4)
+// Integer Math Instructions - Min / Max / Bitwise / Shifts
+// ---------------------------------------------------------
 
-// i64[0] = srl.i64@imm(i64[1], i64@imm[2]).k[3]
-TEXT bcsrli64imm(SB), NOSPLIT|NOFRAME, $0
-  BC_ARITH_OP_I64_IMM_IMPL(VPSRLVQ)
-  NEXT_ADVANCE(BC_SLOT_SIZE*3 + 8)
+#include "bc_eval_math_i64_gen.h"
 
 // Integer Math Instructions - Cleanup
 // -----------------------------------
 
 #undef BC_ARITH_REVERSE_OP_I64_IMM_IMPL
 #undef BC_ARITH_OP_I64_IMM_IMPL_K
-#undef BC_ARITH_OP_I64_IMM_IMPL
 #undef BC_ARITH_OP_I64_IMPL_K
-#undef BC_ARITH_OP_I64_IMPL
@@ -25,13 +25,16 @@ import (
 	"github.com/SnellerInc/sneller/ion"
 )
 
+//go:generate go run ./_generate/genkernels/ -m bc_kernels.manifest -t bc_kernels.tmpl
 //go:generate go run _generate/genconst.go -i evalbc_amd64.s -o bc_constant_gen.h
 //go:generate go run ./_generate/genbytecode/ -i evalbc_amd64.s -o bytecode_gen.go -s bytecode_amd64.s
 //go:generate gofmt -w bytecode_gen.go
 
 // --- How to Add an Instruction ---
 //  - define a new TEXT label in evalbc_{arch}.s
-//    that begins with 'bc'
+//    that begins with 'bc' (or, if the instruction
+//    is a variant of one of the templates in
+//    bc_kernels.tmpl, add it to bc_kernels.manifest)
 //  - run 'go generate'
 //  - add opcode information below
 
@@ -194,6 +197,10 @@ const (
 	// found symbols, but couldn't process them as
 	// there was no symbol table
 	bcerrNullSymbolTable
+	// Interrupted is returned when the
+	// bytecode was interrupted by the watchdog
+	// (see bytecode.interrupt)
+	bcerrInterrupted
 )
 
 func (b bcerr) Error() string {
@@ -208,6 +215,8 @@ func (b bcerr) Error() string {
 		return "radix tree bounds-check failed"
 	case bcerrNullSymbolTable:
 		return "null symbol table"
+	case bcerrInterrupted:
+		return "interrupted by the watchdog"
 	default:
 		return "unknown bytecode error"
 	}
@@ -229,6 +238,8 @@ type bytecode struct {
 
 	trees []*radixTree64 // trees used for hashmember, etc.
 
+	symmatch []symmatcher // per-symbol results for symmatch
+
 	// scratch buffer used for projection
 	scratch []byte
 	// number of bytes to reserve for scratch, total
@@ -258,6 +269,11 @@ type bytecode struct {
 	// additional error information;
 	// error-specific
 	errinfo int
+
+	// interrupt is set (atomically) by the watchdog
+	// to make the next entry into the bytecode abort
+	// with bcerrInterrupted; it is checked every 16 rows
+	interrupt int32
 }
 
 type bcFormatFlags uint
@@ -546,9 +562,11 @@ func (b *bytecode) dropScratch() {
 
 // restoreScratch updates the scratch state in b
 // so that it has the correct number of bytes allocated
-// from the symbol table's spare pages
+// from the symbol table's spare pages, and updates
+// the per-symbol results for the symbol table
 func (b *bytecode) restoreScratch(st *symtab) {
 	b.symtab = st.symrefs
+	b.refreshSymmatch(st)
 	if b.scratchtotal == 0 {
 		// this will trigger a fault if it is used:
 		b.scratchoff = 0x80000000
@@ -53,22 +53,22 @@ var opinfo = [_maxbcop]bcopinfo{
 	opfloorf64:                {text: "floor.f64", out: bcargs[0:1] /* {bcS} */, in: bcargs[3:5] /* {bcS, bcK} */},
 	opceilf64:                 {text: "ceil.f64", out: bcargs[0:1] /* {bcS} */, in: bcargs[3:5] /* {bcS, bcK} */},
 	opaddf64:                  {text: "add.f64", out: bcargs[3:5] /* {bcS, bcK} */, in: bcargs[2:5] /* {bcS, bcS, bcK} */},
-	opaddf64imm:               {text: "add.f64@imm", out: bcargs[3:5] /* {bcS, bcK} */, in: bcargs[106:109] /* {bcS, bcImmF64, bcK} */},
+	opaddf64imm:               {text: "add.f64@imm", out: bcargs[3:5] /* {bcS, bcK} */, in: bcargs[16:19] /* {bcS, bcImmF64, bcK} */},
 	opsubf64:                  {text: "sub.f64", out: bcargs[3:5] /* {bcS, bcK} */, in: bcargs[2:5] /* {bcS, bcS, bcK} */},
-	opsubf64imm:               {text: "sub.f64@imm", out: bcargs[3:5] /* {bcS, bcK} */, in: bcargs[106:109] /* {bcS, bcImmF64, bcK} */},
-	oprsubf64imm:              {text: "rsub.f64@imm", out: bcargs[3:5] /* {bcS, bcK} */, in: bcargs[106:109] /* {bcS, bcImmF64, bcK} */},
+	opsubf64imm:               {text: "sub.f64@imm", out: bcargs[3:5] /* {bcS, bcK} */, in: bcargs[16:19] /* {bcS, bcImmF64, bcK} */},
+	oprsubf64imm:              {text: "rsub.f64@imm", out: bcargs[3:5] /* {bcS, bcK} */, in: bcargs[16:19] /* {bcS, bcImmF64, bcK} */},
 	opmulf64:                  {text: "mul.f64", out: bcargs[3:5] /* {bcS, bcK} */, in: bcargs[2:5] /* {bcS, bcS, bcK} */},
-	opmulf64imm:               {text: "mul.f64@imm", out: bcargs[3:5] /* {bcS, bcK} */, in: bcargs[106:109] /* {bcS, bcImmF64, bcK} */},
+	opmulf64imm:               {text: "mul.f64@imm", out: bcargs[3:5] /* {bcS, bcK} */, in: bcargs[16:19] /* {bcS, bcImmF64, bcK} */},
 	opdivf64:                  {text: "div.f64", out: bcargs[3:5] /* {bcS, bcK} */, in: bcargs[2:5] /* {bcS, bcS, bcK} */},
-	opdivf64imm:               {text: "div.f64@imm", out: bcargs[3:5] /* {bcS, bcK} */, in: bcargs[106:109] /* {bcS, bcImmF64, bcK} */},
-	oprdivf64imm:              {text: "rdiv.f64@imm", out: bcargs[3:5] /* {bcS, bcK} */, in: bcargs[106:109] /* {bcS, bcImmF64, bcK} */},
+	opdivf64imm:               {text: "div.f64@imm", out: bcargs[3:5] /* {bcS, bcK} */, in: bcargs[16:19] /* {bcS, bcImmF64, bcK} */},
+	oprdivf64imm:              {text: "rdiv.f64@imm", out: bcargs[3:5] /* {bcS, bcK} */, in: bcargs[16:19] /* {bcS, bcImmF64, bcK} */},
 	opmodf64:                  {text: "mod.f64", out: bcargs[3:5] /* {bcS, bcK} */, in: bcargs[2:5] /* {bcS, bcS, bcK} */},
-	opmodf64imm:               {text: "mod.f64@imm", out: bcargs[3:5] /* {bcS, bcK} */, in: bcargs[106:109] /* {bcS, bcImmF64, bcK} */},
-	oprmodf64imm:              {text: "rmod.f64@imm", out: bcargs[3:5] /* {bcS, bcK} */, in: bcargs[106:109] /* {bcS, bcImmF64, bcK} */},
+	opmodf64imm:               {text: "mod.f64@imm", out: bcargs[3:5] /* {bcS, bcK} */, in: bcargs[16:19] /* {bcS, bcImmF64, bcK} */},
+	oprmodf64imm:              {text: "rmod.f64@imm", out: bcargs[3:5] /* {bcS, bcK} */, in: bcargs[16:19] /* {bcS, bcImmF64, bcK} */},
 	opminvaluef64:             {text: "minvalue.f64", out: bcargs[0:1] /* {bcS} */, in: bcargs[2:5] /* {bcS, bcS, bcK} */},
-	opminvaluef64imm:          {text: "minvalue.f64@imm", out: bcargs[0:1] /* {bcS} */, in: bcargs[106:109] /* {bcS, bcImmF64, bcK} */},
+	opminvaluef64imm:          {text: "minvalue.f64@imm", out: bcargs[0:1] /* {bcS} */, in: bcargs[16:19] /* {bcS, bcImmF64, bcK} */},
 	opmaxvaluef64:             {text: "maxvalue.f64", out: bcargs[0:1] /* {bcS} */, in: bcargs[2:5] /* {bcS, bcS, bcK} */},
-	opmaxvaluef64imm:          {text: "maxvalue.f64@imm", out: bcargs[0:1] /* {bcS} */, in: bcargs[106:109] /* {bcS, bcImmF64, bcK} */},
+	opmaxvaluef64imm:          {text: "maxvalue.f64@imm", out: bcargs[0:1] /* {bcS} */, in: bcargs[16:19] /* {bcS, bcImmF64, bcK} */},
 	opsqrtf64:                 {text: "sqrt.f64", out: bcargs[3:5] /* {bcS, bcK} */, in: bcargs[3:5] /* {bcS, bcK} */},
 	opcbrtf64:                 {text: "cbrt.f64", out: bcargs[3:5] /* {bcS, bcK} */, in: bcargs[3:5] /* {bcS, bcK} */},
 	opexpf64:                  {text: "exp.f64", out: bcargs[3:5] /* {bcS, bcK} */, in: bcargs[3:5] /* {bcS, bcK} */},
@@ -90,19 +90,19 @@ var opinfo = [_maxbcop]bcopinfo{
 	oppowf64:                  {text: "pow.f64", out: bcargs[3:5] /* {bcS, bcK} */, in: bcargs[2:5] /* {bcS, bcS, bcK} */},
 	opret:                     {text: "ret"},
 	opretk:                    {text: "ret.k", in: bcargs[4:5] /* {bcK} */},
-	opretbk:                   {text: "ret.b.k", in: bcargs[44:46] /* {bcB, bcK} */},
+	opretbk:                   {text: "ret.b.k", in: bcargs[35:37] /* {bcB, bcK} */},
 	opretsk:                   {text: "ret.s.k", in: bcargs[3:5] /* {bcS, bcK} */},
-	opretbhk:                  {text: "ret.b.h.k", in: bcargs[35:38] /* {bcB, bcH, bcK} */},
-	opinit:                    {text: "init", out: bcargs[44:46] /* {bcB, bcK} */},
+	opretbhk:                  {text: "ret.b.h.k", in: bcargs[32:35] /* {bcB, bcH, bcK} */},
+	opinit:                    {text: "init", out: bcargs[35:37] /* {bcB, bcK} */},
 	opbroadcast0k:             {text: "broadcast0.k", out: bcargs[4:5] /* {bcK} */},
 	opbroadcast1k:             {text: "broadcast1.k", out: bcargs[4:5] /* {bcK} */},
-	opfalse:                   {text: "false.k", out: bcargs[10:12] /* {bcV, bcK} */},
+	opfalse:                   {text: "false.k", out: bcargs[6:8] /* {bcV, bcK} */},
 	opnotk:                    {text: "not.k", out: bcargs[4:5] /* {bcK} */, in: bcargs[4:5] /* {bcK} */},
-	opandk:                    {text: "and.k", out: bcargs[4:5] /* {bcK} */, in: bcargs[7:9] /* {bcK, bcK} */},
-	opandnk:                   {text: "andn.k", out: bcargs[4:5] /* {bcK} */, in: bcargs[7:9] /* {bcK, bcK} */},
-	opork:                     {text: "or.k", out: bcargs[4:5] /* {bcK} */, in: bcargs[7:9] /* {bcK, bcK} */},
-	opxork:                    {text: "xor.k", out: bcargs[4:5] /* {bcK} */, in: bcargs[7:9] /* {bcK, bcK} */},
-	opxnork:                   {text: "xnor.k", out: bcargs[4:5] /* {bcK} */, in: bcargs[7:9] /* {bcK, bcK} */},
+	opandk:                    {text: "and.k", out: bcargs[4:5] /* {bcK} */, in: bcargs[10:12] /* {bcK, bcK} */},
+	opandnk:                   {text: "andn.k", out: bcargs[4:5] /* {bcK} */, in: bcargs[10:12] /* {bcK, bcK} */},
+	opork:                     {text: "or.k", out: bcargs[4:5] /* {bcK} */, in: bcargs[10:12] /* {bcK, bcK} */},
+	opxork:                    {text: "xor.k", out: bcargs[4:5] /* {bcK} */, in: bcargs[10:12] /* {bcK, bcK} */},
+	opxnork:                   {text: "xnor.k", out: bcargs[4:5] /* {bcK} */, in: bcargs[10:12] /* {bcK, bcK} */},
 	opcvtktof64:               {text: "cvt.ktof64", out: bcargs[0:1] /* {bcS} */, in: bcargs[4:5] /* {bcK} */},
 	opcvtktoi64:               {text: "cvt.ktoi64", out: bcargs[0:1] /* {bcS} */, in: bcargs[4:5] /* {bcK} */},
 	opcvti64tok:               {text: "cvt.i64tok", out: bcargs[4:5] /* {bcK} */, in: bcargs[3:5] /* {bcS, bcK} */},
@@ -112,37 +112,37 @@ var opinfo = [_maxbcop]bcopinfo{
 	opcvtfloorf64toi64:        {text: "cvtfloor.f64toi64", out: bcargs[3:5] /* {bcS, bcK} */, in: bcargs[3:5] /* {bcS, bcK} */},
 	opcvtceilf64toi64:         {text: "cvtceil.f64toi64", out: bcargs[3:5] /* {bcS, bcK} */, in: bcargs[3:5] /* {bcS, bcK} */},
 	opcvti64tostr:             {text: "cvt.i64tostr", out: bcargs[3:5] /* {bcS, bcK} */, in: bcargs[3:5] /* {bcS, bcK} */, scratch: 20 * 16},
-	opcmpv:                    {text: "cmpv", out: bcargs[3:5] /* {bcS, bcK} */, in: bcargs[99:102] /* {bcV, bcV, bcK} */},
-	opsortcmpvnf:              {text: "sortcmpv@nf", out: bcargs[3:5] /* {bcS, bcK} */, in: bcargs[99:102] /* {bcV, bcV, bcK} */},
-	opsortcmpvnl:              {text: "sortcmpv@nl", out: bcargs[3:5] /* {bcS, bcK} */, in: bcargs[99:102] /* {bcV, bcV, bcK} */},
-	opcmpvk:                   {text: "cmpv.k", out: bcargs[3:5] /* {bcS, bcK} */, in: bcargs[72:75] /* {bcV, bcK, bcK} */},
-	opcmpvkimm:                {text: "cmpv.k@imm", out: bcargs[3:5] /* {bcS, bcK} */, in: bcargs[53:56] /* {bcV, bcImmU16, bcK} */},
+	opcmpv:                    {text: "cmpv", out: bcargs[3:5] /* {bcS, bcK} */, in: bcargs[83:86] /* {bcV, bcV, bcK} */},
+	opsortcmpvnf:              {text: "sortcmpv@nf", out: bcargs[3:5] /* {bcS, bcK} */, in: bcargs[83:86] /* {bcV, bcV, bcK} */},
+	opsortcmpvnl:              {text: "sortcmpv@nl", out: bcargs[3:5] /* {bcS, bcK} */, in: bcargs[83:86] /* {bcV, bcV, bcK} */},
+	opcmpvk:                   {text: "cmpv.k", out: bcargs[3:5] /* {bcS, bcK} */, in: bcargs[40:43] /* {bcV, bcK, bcK} */},
+	opcmpvkimm:                {text: "cmpv.k@imm", out: bcargs[3:5] /* {bcS, bcK} */, in: bcargs[73:76] /* {bcV, bcImmU16, bcK} */},
 	opcmpvi64:                 {text: "cmpv.i64", out: bcargs[3:5] /* {bcS, bcK} */, in: bcargs[56:59] /* {bcV, bcS, bcK} */},
-	opcmpvi64imm:              {text: "cmpv.i64@imm", out: bcargs[3:5] /* {bcS, bcK} */, in: bcargs[75:78] /* {bcV, bcImmI64, bcK} */},
+	opcmpvi64imm:              {text: "cmpv.i64@imm", out: bcargs[3:5] /* {bcS, bcK} */, in: bcargs[108:111] /* {bcV, bcImmI64, bcK} */},
 	opcmpvf64:                 {text: "cmpv.f64", out: bcargs[3:5] /* {bcS, bcK} */, in: bcargs[56:59] /* {bcV, bcS, bcK} */},
-	opcmpvf64imm:              {text: "cmpv.f64@imm", out: bcargs[3:5] /* {bcS, bcK} */, in: bcargs[16:19] /* {bcV, bcImmF64, bcK} */},
+	opcmpvf64imm:              {text: "cmpv.f64@imm", out: bcargs[3:5] /* {bcS, bcK} */, in: bcargs[86:89] /* {bcV, bcImmF64, bcK} */},
 	opcmpltstr:                {text: "cmplt.str", out: bcargs[4:5] /* {bcK} */, in: bcargs[2:5] /* {bcS, bcS, bcK} */},
 	opcmplestr:                {text: "cmple.str", out: bcargs[4:5] /* {bcK} */, in: bcargs[2:5] /* {bcS, bcS, bcK} */},
 	opcmpgtstr:                {text: "cmpgt.str", out: bcargs[4:5] /* {bcK} */, in: bcargs[2:5] /* {bcS, bcS, bcK} */},
 	opcmpgestr:                {text: "cmpge.str", out: bcargs[4:5] /* {bcK} */, in: bcargs[2:5] /* {bcS, bcS, bcK} */},
-	opcmpltk:                  {text: "cmplt.k", out: bcargs[4:5] /* {bcK} */, in: bcargs[39:42] /* {bcK, bcK, bcK} */},
-	opcmpltkimm:               {text: "cmplt.k@imm", out: bcargs[4:5] /* {bcK} */, in: bcargs[41:44] /* {bcK, bcImmU16, bcK} */},
-	opcmplek:                  {text: "cmple.k", out: bcargs[4:5] /* {bcK} */, in: bcargs[39:42] /* {bcK, bcK, bcK} */},
-	opcmplekimm:               {text: "cmple.k@imm", out: bcargs[4:5] /* {bcK} */, in: bcargs[41:44] /* {bcK, bcImmU16, bcK} */},
-	opcmpgtk:                  {text: "cmpgt.k", out: bcargs[4:5] /* {bcK} */, in: bcargs[39:42] /* {bcK, bcK, bcK} */},
-	opcmpgtkimm:               {text: "cmpgt.k@imm", out: bcargs[4:5] /* {bcK} */, in: bcargs[41:44] /* {bcK, bcImmU16, bcK} */},
-	opcmpgek:                  {text: "cmpge.k", out: bcargs[4:5] /* {bcK} */, in: bcargs[39:42] /* {bcK, bcK, bcK} */},
-	opcmpgekimm:               {text: "cmpge.k@imm", out: bcargs[4:5] /* {bcK} */, in: bcargs[41:44] /* {bcK, bcImmU16, bcK} */},
+	opcmpltk:                  {text: "cmplt.k", out: bcargs[4:5] /* {bcK} */, in: bcargs[114:117] /* {bcK, bcK, bcK} */},
+	opcmpltkimm:               {text: "cmplt.k@imm", out: bcargs[4:5] /* {bcK} */, in: bcargs[42:45] /* {bcK, bcImmU16, bcK} */},
+	opcmplek:                  {text: "cmple.k", out: bcargs[4:5] /* {bcK} */, in: bcargs[114:117] /* {bcK, bcK, bcK} */},
+	opcmplekimm:               {text: "cmple.k@imm", out: bcargs[4:5] /* {bcK} */, in: bcargs[42:45] /* {bcK, bcImmU16, bcK} */},
+	opcmpgtk:                  {text: "cmpgt.k", out: bcargs[4:5] /* {bcK} */, in: bcargs[114:117] /* {bcK, bcK, bcK} */},
+	opcmpgtkimm:               {text: "cmpgt.k@imm", out: bcargs[4:5] /* {bcK} */, in: bcargs[42:45] /* {bcK, bcImmU16, bcK} */},
+	opcmpgek:                  {text: "cmpge.k", out: bcargs[4:5] /* {bcK} */, in: bcargs[114:117] /* {bcK, bcK, bcK} */},
+	opcmpgekimm:               {text: "cmpge.k@imm", out: bcargs[4:5] /* {bcK} */, in: bcargs[42:45] /* {bcK, bcImmU16, bcK} */},
 	opcmpeqf64:                {text: "cmpeq.f64", out: bcargs[4:5] /* {bcK} */, in: bcargs[2:5] /* {bcS, bcS, bcK} */},
-	opcmpeqf64imm:             {text: "cmpeq.f64@imm", out: bcargs[4:5] /* {bcK} */, in: bcargs[106:109] /* {bcS, bcImmF64, bcK} */},
+	opcmpeqf64imm:             {text: "cmpeq.f64@imm", out: bcargs[4:5] /* {bcK} */, in: bcargs[16:19] /* {bcS, bcImmF64, bcK} */},
 	opcmpltf64:                {text: "cmplt.f64", out: bcargs[4:5] /* {bcK} */, in: bcargs[2:5] /* {bcS, bcS, bcK} */},
-	opcmpltf64imm:             {text: "cmplt.f64@imm", out: bcargs[4:5] /* {bcK} */, in: bcargs[106:109] /* {bcS, bcImmF64, bcK} */},
+	opcmpltf64imm:             {text: "cmplt.f64@imm", out: bcargs[4:5] /* {bcK} */, in: bcargs[16:19] /* {bcS, bcImmF64, bcK} */},
 	opcmplef64:                {text: "cmple.f64", out: bcargs[4:5] /* {bcK} */, in: bcargs[2:5] /* {bcS, bcS, bcK} */},
-	opcmplef64imm:             {text: "cmple.f64@imm", out: bcargs[4:5] /* {bcK} */, in: bcargs[106:109] /* {bcS, bcImmF64, bcK} */},
+	opcmplef64imm:             {text: "cmple.f64@imm", out: bcargs[4:5] /* {bcK} */, in: bcargs[16:19] /* {bcS, bcImmF64, bcK} */},
 	opcmpgtf64:                {text: "cmpgt.f64", out: bcargs[4:5] /* {bcK} */, in: bcargs[2:5] /* {bcS, bcS, bcK} */},
-	opcmpgtf64imm:             {text: "cmpgt.f64@imm", out: bcargs[4:5] /* {bcK} */, in: bcargs[106:109] /* {bcS, bcImmF64, bcK} */},
+	opcmpgtf64imm:             {text: "cmpgt.f64@imm", out: bcargs[4:5] /* {bcK} */, in: bcargs[16:19] /* {bcS, bcImmF64, bcK} */},
 	opcmpgef64:                {text: "cmpge.f64", out: bcargs[4:5] /* {bcK} */, in: bcargs[2:5] /* {bcS, bcS, bcK} */},
-	opcmpgef64imm:             {text: "cmpge.f64@imm", out: bcargs[4:5] /* {bcK} */, in: bcargs[106:109] /* {bcS, bcImmF64, bcK} */},
+	opcmpgef64imm:             {text: "cmpge.f64@imm", out: bcargs[4:5] /* {bcK} */, in: bcargs[16:19] /* {bcS, bcImmF64, bcK} */},
 	opcmpeqi64:                {text: "cmpeq.i64", out: bcargs[4:5] /* {bcK} */, in: bcargs[2:5] /* {bcS, bcS, bcK} */},
 	opcmpeqi64imm:             {text: "cmpeq.i64@imm", out: bcargs[4:5] /* {bcK} */, in: bcargs[13:16] /* {bcS, bcImmI64, bcK} */},
 	opcmplti64:                {text: "cmplt.i64", out: bcargs[4:5] /* {bcK} */, in: bcargs[2:5] /* {bcS, bcS, bcK} */},
@@ -154,22 +154,22 @@ var opinfo = [_maxbcop]bcopinfo{
 	opcmpgei64:                {text: "cmpge.i64", out: bcargs[4:5] /* {bcK} */, in: bcargs[2:5] /* {bcS, bcS, bcK} */},
 	opcmpgei64imm:             {text: "cmpge.i64@imm", out: bcargs[4:5] /* {bcK} */, in: bcargs[13:16] /* {bcS, bcImmI64, bcK} */},
 	opisnanf:                  {text: "isnan.f", out: bcargs[4:5] /* {bcK} */, in: bcargs[3:5] /* {bcS, bcK} */},
-	opchecktag:                {text: "checktag", out: bcargs[10:12] /* {bcV, bcK} */, in: bcargs[53:56] /* {bcV, bcImmU16, bcK} */},
-	optypebits:                {text: "typebits", out: bcargs[0:1] /* {bcS} */, in: bcargs[10:12] /* {bcV, bcK} */},
-	opisnullv:                 {text: "isnull.v", out: bcargs[4:5] /* {bcK} */, in: bcargs[10:12] /* {bcV, bcK} */},
-	opisnotnullv:              {text: "isnotnull.v", out: bcargs[4:5] /* {bcK} */, in: bcargs[10:12] /* {bcV, bcK} */},
-	opistruev:                 {text: "istrue.v", out: bcargs[4:5] /* {bcK} */, in: bcargs[10:12] /* {bcV, bcK} */},
-	opisfalsev:                {text: "isfalse.v", out: bcargs[4:5] /* {bcK} */, in: bcargs[10:12] /* {bcV, bcK} */},
+	opchecktag:                {text: "checktag", out: bcargs[6:8] /* {bcV, bcK} */, in: bcargs[73:76] /* {bcV, bcImmU16, bcK} */},
+	optypebits:                {text: "typebits", out: bcargs[0:1] /* {bcS} */, in: bcargs[6:8] /* {bcV, bcK} */},
+	opisnullv:                 {text: "isnull.v", out: bcargs[4:5] /* {bcK} */, in: bcargs[6:8] /* {bcV, bcK} */},
+	opisnotnullv:              {text: "isnotnull.v", out: bcargs[4:5] /* {bcK} */, in: bcargs[6:8] /* {bcV, bcK} */},
+	opistruev:                 {text: "istrue.v", out: bcargs[4:5] /* {bcK} */, in: bcargs[6:8] /* {bcV, bcK} */},
+	opisfalsev:                {text: "isfalse.v", out: bcargs[4:5] /* {bcK} */, in: bcargs[6:8] /* {bcV, bcK} */},
 	opcmpeqslice:              {text: "cmpeq.slice", out: bcargs[4:5] /* {bcK} */, in: bcargs[2:5] /* {bcS, bcS, bcK} */},
-	opcmpeqv:                  {text: "cmpeq.v", out: bcargs[4:5] /* {bcK} */, in: bcargs[99:102] /* {bcV, bcV, bcK} */},
-	opcmpeqvimm:               {text: "cmpeq.v@imm", out: bcargs[4:5] /* {bcK} */, in: bcargs[19:22] /* {bcV, bcLitRef, bcK} */},
+	opcmpeqv:                  {text: "cmpeq.v", out: bcargs[4:5] /* {bcK} */, in: bcargs[83:86] /* {bcV, bcV, bcK} */},
+	opcmpeqvimm:               {text: "cmpeq.v@imm", out: bcargs[4:5] /* {bcK} */, in: bcargs[48:51] /* {bcV, bcLitRef, bcK} */},
 	opdateaddmonth:            {text: "dateaddmonth", out: bcargs[3:5] /* {bcS, bcK} */, in: bcargs[2:5] /* {bcS, bcS, bcK} */},
 	opdateaddmonthimm:         {text: "dateaddmonth.imm", out: bcargs[3:5] /* {bcS, bcK} */, in: bcargs[13:16] /* {bcS, bcImmI64, bcK} */},
 	opdateaddyear:             {text: "dateaddyear", out: bcargs[3:5] /* {bcS, bcK} */, in: bcargs[2:5] /* {bcS, bcS, bcK} */},
 	opdateaddquarter:          {text: "dateaddquarter", out: bcargs[3:5] /* {bcS, bcK} */, in: bcargs[2:5] /* {bcS, bcS, bcK} */},
 	opdatediffmicrosecond:     {text: "datediffmicrosecond", out: bcargs[3:5] /* {bcS, bcK} */, in: bcargs[2:5] /* {bcS, bcS, bcK} */},
-	opdatediffparam:           {text: "datediffparam", out: bcargs[3:5] /* {bcS, bcK} */, in: bcargs[46:50] /* {bcS, bcS, bcImmU64, bcK} */},
-	opdatediffmqy:             {text: "datediffmqy", out: bcargs[3:5] /* {bcS, bcK} */, in: bcargs[88:92] /* {bcS, bcS, bcImmU16, bcK} */},
+	opdatediffparam:           {text: "datediffparam", out: bcargs[3:5] /* {bcS, bcK} */, in: bcargs[69:73] /* {bcS, bcS, bcImmU64, bcK} */},
+	opdatediffmqy:             {text: "datediffmqy", out: bcargs[3:5] /* {bcS, bcK} */, in: bcargs[24:28] /* {bcS, bcS, bcImmU16, bcK} */},
 	opdateextractmicrosecond:  {text: "dateextractmicrosecond", out: bcargs[0:1] /* {bcS} */, in: bcargs[3:5] /* {bcS, bcK} */},
 	opdateextractmillisecond:  {text: "dateextractmillisecond", out: bcargs[0:1] /* {bcS} */, in: bcargs[3:5] /* {bcS, bcK} */},
 	opdateextractsecond:       {text: "dateextractsecond", out: bcargs[0:1] /* {bcS} */, in: bcargs[3:5] /* {bcS, bcK} */},
@@ -188,150 +188,159 @@ var opinfo = [_maxbcop]bcopinfo{
 	opdatetruncminute:         {text: "datetruncminute", out: bcargs[0:1] /* {bcS} */, in: bcargs[3:5] /* {bcS, bcK} */},
 	opdatetrunchour:           {text: "datetrunchour", out: bcargs[0:1] /* {bcS} */, in: bcargs[3:5] /* {bcS, bcK} */},
 	opdatetruncday:            {text: "datetruncday", out: bcargs[0:1] /* {bcS} */, in: bcargs[3:5] /* {bcS, bcK} */},
-	opdatetruncdow:            {text: "datetruncdow", out: bcargs[0:1] /* {bcS} */, in: bcargs[32:35] /* {bcS, bcImmU16, bcK} */},
+	opdatetruncdow:            {text: "datetruncdow", out: bcargs[0:1] /* {bcS} */, in: bcargs[25:28] /* {bcS, bcImmU16, bcK} */},
 	opdatetruncmonth:          {text: "datetruncmonth", out: bcargs[0:1] /* {bcS} */, in: bcargs[3:5] /* {bcS, bcK} */},
 	opdatetruncquarter:        {text: "datetruncquarter", out: bcargs[0:1] /* {bcS} */, in: bcargs[3:5] /* {bcS, bcK} */},
 	opdatetruncyear:           {text: "datetruncyear", out: bcargs[0:1] /* {bcS} */, in: bcargs[3:5] /* {bcS, bcK} */},
-	opunboxts:                 {text: "unboxts", out: bcargs[3:5] /* {bcS, bcK} */, in: bcargs[10:12] /* {bcV, bcK} */},
-	opboxts:                   {text: "boxts", out: bcargs[10:11] /* {bcV} */, in: bcargs[3:5] /* {bcS, bcK} */, scratch: 16 * 16},
+	opunboxts:                 {text: "unboxts", out: bcargs[3:5] /* {bcS, bcK} */, in: bcargs[6:8] /* {bcV, bcK} */},
+	opboxts:                   {text: "boxts", out: bcargs[6:7] /* {bcV} */, in: bcargs[3:5] /* {bcS, bcK} */, scratch: 16 * 16},
 	opwidthbucketf64:          {text: "widthbucket.f64", out: bcargs[0:1] /* {bcS} */, in: bcargs[0:5] /* {bcS, bcS, bcS, bcS, bcK} */},
 	opwidthbucketi64:          {text: "widthbucket.i64", out: bcargs[0:1] /* {bcS} */, in: bcargs[0:5] /* {bcS, bcS, bcS, bcS, bcK} */},
 	optimebucketts:            {text: "timebucket.ts", out: bcargs[0:1] /* {bcS} */, in: bcargs[2:5] /* {bcS, bcS, bcK} */},
 	opgeohash:                 {text: "geohash", out: bcargs[0:1] /* {bcS} */, in: bcargs[1:5] /* {bcS, bcS, bcS, bcK} */, scratch: 16 * 16},
-	opgeohashimm:              {text: "geohashimm", out: bcargs[0:1] /* {bcS} */, in: bcargs[88:92] /* {bcS, bcS, bcImmU16, bcK} */, scratch: 16 * 16},
+	opgeohashimm:              {text: "geohashimm", out: bcargs[0:1] /* {bcS} */, in: bcargs[24:28] /* {bcS, bcS, bcImmU16, bcK} */, scratch: 16 * 16},
 	opgeotilex:                {text: "geotilex", out: bcargs[0:1] /* {bcS} */, in: bcargs[2:5] /* {bcS, bcS, bcK} */},
 	opgeotiley:                {text: "geotiley", out: bcargs[0:1] /* {bcS} */, in: bcargs[2:5] /* {bcS, bcS, bcK} */},
 	opgeotilees:               {text: "geotilees", out: bcargs[0:1] /* {bcS} */, in: bcargs[1:5] /* {bcS, bcS, bcS, bcK} */, scratch: 32 * 16},
-	opgeotileesimm:            {text: "geotilees.imm", out: bcargs[0:1] /* {bcS} */, in: bcargs[88:92] /* {bcS, bcS, bcImmU16, bcK} */, scratch: 32 * 16},
+	opgeotileesimm:            {text: "geotilees.imm", out: bcargs[0:1] /* {bcS} */, in: bcargs[24:28] /* {bcS, bcS, bcImmU16, bcK} */, scratch: 32 * 16},
 	opgeodistance:             {text: "geodistance", out: bcargs[3:5] /* {bcS, bcK} */, in: bcargs[0:5] /* {bcS, bcS, bcS, bcS, bcK} */},
 	opalloc:                   {text: "alloc", out: bcargs[3:5] /* {bcS, bcK} */, in: bcargs[3:5] /* {bcS, bcK} */, scratch: PageSize},
 	opconcatstr:               {text: "concatstr", out: bcargs[3:5] /* {bcS, bcK} */, va: bcargs[3:5] /* {bcS, bcK} */, scratch: PageSize},
-	opfindsym:                 {text: "findsym", out: bcargs[10:12] /* {bcV, bcK} */, in: bcargs[66:69] /* {bcB, bcSymbolID, bcK} */},
-	opfindsym2:                {text: "findsym2", out: bcargs[10:12] /* {bcV, bcK} */, in: bcargs[83:88] /* {bcB, bcV, bcK, bcSymbolID, bcK} */},
-	opblendv:                  {text: "blend.v", out: bcargs[10:12] /* {bcV, bcK} */, in: bcargs[70:74] /* {bcV, bcK, bcV, bcK} */},
-	opblendf64:                {text: "blend.f64", out: bcargs[3:5] /* {bcS, bcK} */, in: bcargs[61:65] /* {bcS, bcK, bcS, bcK} */},
-	opunpack:                  {text: "unpack", out: bcargs[3:5] /* {bcS, bcK} */, in: bcargs[53:56] /* {bcV, bcImmU16, bcK} */},
-	opunsymbolize:             {text: "unsymbolize", out: bcargs[10:11] /* {bcV} */, in: bcargs[10:12] /* {bcV, bcK} */},
-	opunboxktoi64:             {text: "unbox.k@i64", out: bcargs[3:5] /* {bcS, bcK} */, in: bcargs[10:12] /* {bcV, bcK} */},
-	opunboxcoercef64:          {text: "unbox.coerce.f64", out: bcargs[3:5] /* {bcS, bcK} */, in: bcargs[10:12] /* {bcV, bcK} */},
-	opunboxcoercei64:          {text: "unbox.coerce.i64", out: bcargs[3:5] /* {bcS, bcK} */, in: bcargs[10:12] /* {bcV, bcK} */},
-	opunboxcvtf64:             {text: "unbox.cvt.f64", out: bcargs[3:5] /* {bcS, bcK} */, in: bcargs[10:12] /* {bcV, bcK} */},
-	opunboxcvti64:             {text: "unbox.cvt.i64", out: bcargs[3:5] /* {bcS, bcK} */, in: bcargs[10:12] /* {bcV, bcK} */},
-	opboxf64:                  {text: "box.f64", out: bcargs[10:11] /* {bcV} */, in: bcargs[3:5] /* {bcS, bcK} */, scratch: 9 * 16},
-	opboxi64:                  {text: "box.i64", out: bcargs[10:11] /* {bcV} */, in: bcargs[3:5] /* {bcS, bcK} */, scratch: 9 * 16},
-	opboxk:                    {text: "box.k", out: bcargs[10:11] /* {bcV} */, in: bcargs[7:9] /* {bcK, bcK} */, scratch: 16},
-	opboxstr:                  {text: "box.str", out: bcargs[10:11] /* {bcV} */, in: bcargs[3:5] /* {bcS, bcK} */, scratch: PageSize},
-	opboxlist:                 {text: "box.list", out: bcargs[10:11] /* {bcV} */, in: bcargs[3:5] /* {bcS, bcK} */, scratch: PageSize},
-	opmakelist:                {text: "makelist", out: bcargs[10:12] /* {bcV, bcK} */, in: bcargs[4:5] /* {bcK} */, va: bcargs[10:12] /* {bcV, bcK} */, scratch: PageSize},
-	opmakestruct:              {text: "makestruct", out: bcargs[10:12] /* {bcV, bcK} */, in: bcargs[4:5] /* {bcK} */, va: bcargs[96:99] /* {bcSymbolID, bcV, bcK} */, scratch: PageSize},
-	ophashvalue:               {text: "hashvalue", out: bcargs[9:10] /* {bcH} */, in: bcargs[10:12] /* {bcV, bcK} */},
-	ophashvalueplus:           {text: "hashvalue+", out: bcargs[9:10] /* {bcH} */, in: bcargs[9:12] /* {bcH, bcV, bcK} */},
-	ophashmember:              {text: "hashmember", out: bcargs[4:5] /* {bcK} */, in: bcargs[27:30] /* {bcH, bcImmU16, bcK} */},
-	ophashlookup:              {text: "hashlookup", out: bcargs[10:12] /* {bcV, bcK} */, in: bcargs[27:30] /* {bcH, bcImmU16, bcK} */},
-	opaggandk:                 {text: "aggand.k", in: bcargs[38:41] /* {bcAggSlot, bcK, bcK} */},
-	opaggork:                  {text: "aggor.k", in: bcargs[38:41] /* {bcAggSlot, bcK, bcK} */},
-	opaggslotsumf:             {text: "aggslotsum.f64", in: bcargs[92:96] /* {bcAggSlot, bcL, bcS, bcK} */},
-	opaggsumf:                 {text: "aggsum.f64", in: bcargs[50:53] /* {bcAggSlot, bcS, bcK} */},
-	opaggsumi:                 {text: "aggsum.i64", in: bcargs[50:53] /* {bcAggSlot, bcS, bcK} */},
-	opaggminf:                 {text: "aggmin.f64", in: bcargs[50:53] /* {bcAggSlot, bcS, bcK} */},
-	opaggmini:                 {text: "aggmin.i64", in: bcargs[50:53] /* {bcAggSlot, bcS, bcK} */},
-	opaggmaxf:                 {text: "aggmax.f64", in: bcargs[50:53] /* {bcAggSlot, bcS, bcK} */},
-	opaggmaxi:                 {text: "aggmax.i64", in: bcargs[50:53] /* {bcAggSlot, bcS, bcK} */},
-	opaggandi:                 {text: "aggand.i64", in: bcargs[50:53] /* {bcAggSlot, bcS, bcK} */},
-	opaggori:                  {text: "aggor.i64", in: bcargs[50:53] /* {bcAggSlot, bcS, bcK} */},
-	opaggxori:                 {text: "aggxor.i64", in: bcargs[50:53] /* {bcAggSlot, bcS, bcK} */},
-	opaggcount:                {text: "aggcount", in: bcargs[38:40] /* {bcAggSlot, bcK} */},
-	opaggbucket:               {text: "aggbucket", out: bcargs[6:7] /* {bcL} */, in: bcargs[36:38] /* {bcH, bcK} */},
-	opaggslotandk:             {text: "aggslotand.k", in: bcargs[5:9] /* {bcAggSlot, bcL, bcK, bcK} */},
-	opaggslotork:              {text: "aggslotor.k", in: bcargs[5:9] /* {bcAggSlot, bcL, bcK, bcK} */},
-	opaggslotsumi:             {text: "aggslotsum.i64", in: bcargs[92:96] /* {bcAggSlot, bcL, bcS, bcK} */},
-	opaggslotavgf:             {text: "aggslotavg.f64", in: bcargs[92:96] /* {bcAggSlot, bcL, bcS, bcK} */},
-	opaggslotavgi:             {text: "aggslotavg.i64", in: bcargs[92:96] /* {bcAggSlot, bcL, bcS, bcK} */},
-	opaggslotminf:             {text: "aggslotmin.f64", in: bcargs[92:96] /* {bcAggSlot, bcL, bcS, bcK} */},
-	opaggslotmini:             {text: "aggslotmin.i64", in: bcargs[92:96] /* {bcAggSlot, bcL, bcS, bcK} */},
-	opaggslotmaxf:             {text: "aggslotmax.f64", in: bcargs[92:96] /* {bcAggSlot, bcL, bcS, bcK} */},
-	opaggslotmaxi:             {text: "aggslotmax.i64", in: bcargs[92:96] /* {bcAggSlot, bcL, bcS, bcK} */},
-	opaggslotandi:             {text: "aggslotand.i64", in: bcargs[92:96] /* {bcAggSlot, bcL, bcS, bcK} */},
-	opaggslotori:              {text: "aggslotor.i64", in: bcargs[92:96] /* {bcAggSlot, bcL, bcS, bcK} */},
-	opaggslotxori:             {text: "aggslotxor.i64", in: bcargs[92:96] /* {bcAggSlot, bcL, bcS, bcK} */},
-	opaggslotcount:            {text: "aggslotcount", in: bcargs[5:8] /* {bcAggSlot, bcL, bcK} */},
-	opaggslotcountv2:          {text: "aggslotcount", in: bcargs[5:8] /* {bcAggSlot, bcL, bcK} */},
-	oplitref:                  {text: "litref", out: bcargs[10:11] /* {bcV} */, in: bcargs[20:21] /* {bcLitRef} */},
-	opauxval:                  {text: "auxval", out: bcargs[10:12] /* {bcV, bcK} */, in: bcargs[65:66] /* {bcAuxSlot} */},
+	opconcatstrskip:           {text: "concatstrskip", out: bcargs[3:5] /* {bcS, bcK} */, in: bcargs[4:5] /* {bcK} */, va: bcargs[3:5] /* {bcS, bcK} */, scratch: PageSize},
+	opfindsym:                 {text: "findsym", out: bcargs[6:8] /* {bcV, bcK} */, in: bcargs[63:66] /* {bcB, bcSymbolID, bcK} */},
+	opfindsym2:                {text: "findsym2", out: bcargs[6:8] /* {bcV, bcK} */, in: bcargs[51:56] /* {bcB, bcV, bcK, bcSymbolID, bcK} */},
+	opblendv:                  {text: "blend.v", out: bcargs[6:8] /* {bcV, bcK} */, in: bcargs[38:42] /* {bcV, bcK, bcV, bcK} */},
+	opblendf64:                {text: "blend.f64", out: bcargs[3:5] /* {bcS, bcK} */, in: bcargs[57:61] /* {bcS, bcK, bcS, bcK} */},
+	opunpack:                  {text: "unpack", out: bcargs[3:5] /* {bcS, bcK} */, in: bcargs[73:76] /* {bcV, bcImmU16, bcK} */},
+	opunsymbolize:             {text: "unsymbolize", out: bcargs[6:7] /* {bcV} */, in: bcargs[6:8] /* {bcV, bcK} */},
+	opsymmatch:                {text: "symmatch", out: bcargs[4:5] /* {bcK} */, in: bcargs[80:83] /* {bcV, bcDictSlot, bcK} */},
+	opunboxktoi64:             {text: "unbox.k@i64", out: bcargs[3:5] /* {bcS, bcK} */, in: bcargs[6:8] /* {bcV, bcK} */},
+	opunboxcoercef64:          {text: "unbox.coerce.f64", out: bcargs[3:5] /* {bcS, bcK} */, in: bcargs[6:8] /* {bcV, bcK} */},
+	opunboxcoercei64:          {text: "unbox.coerce.i64", out: bcargs[3:5] /* {bcS, bcK} */, in: bcargs[6:8] /* {bcV, bcK} */},
+	opunboxcvtf64:             {text: "unbox.cvt.f64", out: bcargs[3:5] /* {bcS, bcK} */, in: bcargs[6:8] /* {bcV, bcK} */},
+	opunboxcvti64:             {text: "unbox.cvt.i64", out: bcargs[3:5] /* {bcS, bcK} */, in: bcargs[6:8] /* {bcV, bcK} */},
+	opboxf64:                  {text: "box.f64", out: bcargs[6:7] /* {bcV} */, in: bcargs[3:5] /* {bcS, bcK} */, scratch: 9 * 16},
+	opboxi64:                  {text: "box.i64", out: bcargs[6:7] /* {bcV} */, in: bcargs[3:5] /* {bcS, bcK} */, scratch: 9 * 16},
+	opboxk:                    {text: "box.k", out: bcargs[6:7] /* {bcV} */, in: bcargs[10:12] /* {bcK, bcK} */, scratch: 16},
+	opboxstr:                  {text: "box.str", out: bcargs[6:7] /* {bcV} */, in: bcargs[3:5] /* {bcS, bcK} */, scratch: PageSize},
+	opboxlist:                 {text: "box.list", out: bcargs[6:7] /* {bcV} */, in: bcargs[3:5] /* {bcS, bcK} */, scratch: PageSize},
+	opmakelist:                {text: "makelist", out: bcargs[6:8] /* {bcV, bcK} */, in: bcargs[4:5] /* {bcK} */, va: bcargs[6:8] /* {bcV, bcK} */, scratch: PageSize},
+	opmakestruct:              {text: "makestruct", out: bcargs[6:8] /* {bcV, bcK} */, in: bcargs[4:5] /* {bcK} */, va: bcargs[37:40] /* {bcSymbolID, bcV, bcK} */, scratch: PageSize},
+	ophashvalue:               {text: "hashvalue", out: bcargs[5:6] /* {bcH} */, in: bcargs[6:8] /* {bcV, bcK} */},
+	ophashvalueplus:           {text: "hashvalue+", out: bcargs[5:6] /* {bcH} */, in: bcargs[5:8] /* {bcH, bcV, bcK} */},
+	ophashmember:              {text: "hashmember", out: bcargs[4:5] /* {bcK} */, in: bcargs[21:24] /* {bcH, bcImmU16, bcK} */},
+	ophashlookup:              {text: "hashlookup", out: bcargs[6:8] /* {bcV, bcK} */, in: bcargs[21:24] /* {bcH, bcImmU16, bcK} */},
+	opaggandk:                 {text: "aggand.k", in: bcargs[45:48] /* {bcAggSlot, bcK, bcK} */},
+	opaggork:                  {text: "aggor.k", in: bcargs[45:48] /* {bcAggSlot, bcK, bcK} */},
+	opaggslotsumf:             {text: "aggslotsum.f64", in: bcargs[89:93] /* {bcAggSlot, bcL, bcS, bcK} */},
+	opaggsumf:                 {text: "aggsum.f64", in: bcargs[105:108] /* {bcAggSlot, bcS, bcK} */},
+	opaggsumi:                 {text: "aggsum.i64", in: bcargs[105:108] /* {bcAggSlot, bcS, bcK} */},
+	opaggminf:                 {text: "aggmin.f64", in: bcargs[105:108] /* {bcAggSlot, bcS, bcK} */},
+	opaggmini:                 {text: "aggmin.i64", in: bcargs[105:108] /* {bcAggSlot, bcS, bcK} */},
+	opaggmaxf:                 {text: "aggmax.f64", in: bcargs[105:108] /* {bcAggSlot, bcS, bcK} */},
+	opaggmaxi:                 {text: "aggmax.i64", in: bcargs[105:108] /* {bcAggSlot, bcS, bcK} */},
+	opaggandi:                 {text: "aggand.i64", in: bcargs[105:108] /* {bcAggSlot, bcS, bcK} */},
+	opaggori:                  {text: "aggor.i64", in: bcargs[105:108] /* {bcAggSlot, bcS, bcK} */},
+	opaggxori:                 {text: "aggxor.i64", in: bcargs[105:108] /* {bcAggSlot, bcS, bcK} */},
+	opaggcount:                {text: "aggcount", in: bcargs[45:47] /* {bcAggSlot, bcK} */},
+	opaggbucket:               {text: "aggbucket", out: bcargs[9:10] /* {bcL} */, in: bcargs[33:35] /* {bcH, bcK} */},
+	opaggslotandk:             {text: "aggslotand.k", in: bcargs[8:12] /* {bcAggSlot, bcL, bcK, bcK} */},
+	opaggslotork:              {text: "aggslotor.k", in: bcargs[8:12] /* {bcAggSlot, bcL, bcK, bcK} */},
+	opaggslotsumi:             {text: "aggslotsum.i64", in: bcargs[89:93] /* {bcAggSlot, bcL, bcS, bcK} */},
+	opaggslotavgf:             {text: "aggslotavg.f64", in: bcargs[89:93] /* {bcAggSlot, bcL, bcS, bcK} */},
+	opaggslotavgi:             {text: "aggslotavg.i64", in: bcargs[89:93] /* {bcAggSlot, bcL, bcS, bcK} */},
+	opaggslotminf:             {text: "aggslotmin.f64", in: bcargs[89:93] /* {bcAggSlot, bcL, bcS, bcK} */},
+	opaggslotmini:             {text: "aggslotmin.i64", in: bcargs[89:93] /* {bcAggSlot, bcL, bcS, bcK} */},
+	opaggslotmaxf:             {text: "aggslotmax.f64", in: bcargs[89:93] /* {bcAggSlot, bcL, bcS, bcK} */},
+	opaggslotmaxi:             {text: "aggslotmax.i64", in: bcargs[89:93] /* {bcAggSlot, bcL, bcS, bcK} */},
+	opaggslotandi:             {text: "aggslotand.i64", in: bcargs[89:93] /* {bcAggSlot, bcL, bcS, bcK} */},
+	opaggslotori:              {text: "aggslotor.i64", in: bcargs[89:93] /* {bcAggSlot, bcL, bcS, bcK} */},
+	opaggslotxori:             {text: "aggslotxor.i64", in: bcargs[89:93] /* {bcAggSlot, bcL, bcS, bcK} */},
+	opaggslotcount:            {text: "aggslotcount", in: bcargs[8:11] /* {bcAggSlot, bcL, bcK} */},
+	opaggslotcountv2:          {text: "aggslotcount", in: bcargs[8:11] /* {bcAggSlot, bcL, bcK} */},
+	oplitref:                  {text: "litref", out: bcargs[6:7] /* {bcV} */, in: bcargs[49:50] /* {bcLitRef} */},
+	opauxval:                  {text: "auxval", out: bcargs[6:8] /* {bcV, bcK} */, in: bcargs[62:63] /* {bcAuxSlot} */},
 	opsplit:                   {text: "split", out: bcargs[56:59] /* {bcV, bcS, bcK} */, in: bcargs[3:5] /* {bcS, bcK} */},
-	optuple:                   {text: "tuple", out: bcargs[44:46] /* {bcB, bcK} */, in: bcargs[10:12] /* {bcV, bcK} */},
+	optuple:                   {text: "tuple", out: bcargs[35:37] /* {bcB, bcK} */, in: bcargs[6:8] /* {bcV, bcK} */},
 	opmovk:                    {text: "mov.k", out: bcargs[4:5] /* {bcK} */, in: bcargs[4:5] /* {bcK} */},
-	opzerov:                   {text: "zero.v", out: bcargs[10:11] /* {bcV} */},
-	opmovv:                    {text: "mov.v", out: bcargs[10:11] /* {bcV} */, in: bcargs[10:12] /* {bcV, bcK} */},
-	opmovvk:                   {text: "mov.v.k", out: bcargs[10:12] /* {bcV, bcK} */, in: bcargs[10:12] /* {bcV, bcK} */},
+	opzerov:                   {text: "zero.v", out: bcargs[6:7] /* {bcV} */},
+	opmovv:                    {text: "mov.v", out: bcargs[6:7] /* {bcV} */, in: bcargs[6:8] /* {bcV, bcK} */},
+	opmovvk:                   {text: "mov.v.k", out: bcargs[6:8] /* {bcV, bcK} */, in: bcargs[6:8] /* {bcV, bcK} */},
 	opmovf64:                  {text: "mov.f64", out: bcargs[0:1] /* {bcS} */, in: bcargs[3:5] /* {bcS, bcK} */},
 	opmovi64:                  {text: "mov.i64", out: bcargs[0:1] /* {bcS} */, in: bcargs[3:5] /* {bcS, bcK} */},
-	opobjectsize:              {text: "objectsize", out: bcargs[3:5] /* {bcS, bcK} */, in: bcargs[10:12] /* {bcV, bcK} */},
+	opobjectsize:              {text: "objectsize", out: bcargs[3:5] /* {bcS, bcK} */, in: bcargs[6:8] /* {bcV, bcK} */},
 	oparraysize:               {text: "arraysize", out: bcargs[0:1] /* {bcS} */, in: bcargs[3:5] /* {bcS, bcK} */},
-	oparrayposition:           {text: "arrayposition", out: bcargs[3:5] /* {bcS, bcK} */, in: bcargs[69:72] /* {bcS, bcV, bcK} */},
-	opCmpStrEqCs:              {text: "cmp_str_eq_cs", out: bcargs[4:5] /* {bcK} */, in: bcargs[23:26] /* {bcS, bcDictSlot, bcK} */},
-	opCmpStrEqCi:              {text: "cmp_str_eq_ci", out: bcargs[4:5] /* {bcK} */, in: bcargs[23:26] /* {bcS, bcDictSlot, bcK} */},
-	opCmpStrEqUTF8Ci:          {text: "cmp_str_eq_utf8_ci", out: bcargs[4:5] /* {bcK} */, in: bcargs[23:26] /* {bcS, bcDictSlot, bcK} */},
-	opCmpStrFuzzyA3:           {text: "cmp_str_fuzzy_A3", out: bcargs[4:5] /* {bcK} */, in: bcargs[22:26] /* {bcS, bcS, bcDictSlot, bcK} */},
-	opCmpStrFuzzyUnicodeA3:    {text: "cmp_str_fuzzy_unicode_A3", out: bcargs[4:5] /* {bcK} */, in: bcargs[22:26] /* {bcS, bcS, bcDictSlot, bcK} */},
-	opHasSubstrFuzzyA3:        {text: "contains_fuzzy_A3", out: bcargs[4:5] /* {bcK} */, in: bcargs[22:26] /* {bcS, bcS, bcDictSlot, bcK} */},
-	opHasSubstrFuzzyUnicodeA3: {text: "contains_fuzzy_unicode_A3", out: bcargs[4:5] /* {bcK} */, in: bcargs[22:26] /* {bcS, bcS, bcDictSlot, bcK} */},
+	oparrayposition:           {text: "arrayposition", out: bcargs[3:5] /* {bcS, bcK} */, in: bcargs[66:69] /* {bcS, bcV, bcK} */},
+	oparrayelement:            {text: "arrayelement", out: bcargs[6:8] /* {bcV, bcK} */, in: bcargs[2:5] /* {bcS, bcS, bcK} */},
+	oplistfield:               {text: "listfield", out: bcargs[3:5] /* {bcS, bcK} */, in: bcargs[59:62] /* {bcS, bcK, bcSymbolID} */, scratch: PageSize},
+	oplistvalues:              {text: "listvalues", out: bcargs[3:5] /* {bcS, bcK} */, in: bcargs[3:5] /* {bcS, bcK} */, scratch: PageSize},
+	oplistflatten:             {text: "listflatten", out: bcargs[3:5] /* {bcS, bcK} */, in: bcargs[3:5] /* {bcS, bcK} */, scratch: PageSize},
+	opstructvalues:            {text: "structvalues", out: bcargs[3:5] /* {bcS, bcK} */, in: bcargs[35:37] /* {bcB, bcK} */, scratch: PageSize},
+	opobjectmerge:             {text: "objectmerge", out: bcargs[6:8] /* {bcV, bcK} */, in: bcargs[93:96] /* {bcB, bcB, bcK} */, scratch: PageSize},
+	opobjectdelete:            {text: "objectdelete", out: bcargs[6:8] /* {bcV, bcK} */, in: bcargs[35:38] /* {bcB, bcK, bcSymbolID} */, scratch: PageSize},
+	opCmpStrEqCs:              {text: "cmp_str_eq_cs", out: bcargs[4:5] /* {bcK} */, in: bcargs[29:32] /* {bcS, bcDictSlot, bcK} */},
+	opCmpStrEqCi:              {text: "cmp_str_eq_ci", out: bcargs[4:5] /* {bcK} */, in: bcargs[29:32] /* {bcS, bcDictSlot, bcK} */},
+	opCmpStrEqUTF8Ci:          {text: "cmp_str_eq_utf8_ci", out: bcargs[4:5] /* {bcK} */, in: bcargs[29:32] /* {bcS, bcDictSlot, bcK} */},
+	opCmpStrFuzzyA3:           {text: "cmp_str_fuzzy_A3", out: bcargs[4:5] /* {bcK} */, in: bcargs[28:32] /* {bcS, bcS, bcDictSlot, bcK} */},
+	opCmpStrFuzzyUnicodeA3:    {text: "cmp_str_fuzzy_unicode_A3", out: bcargs[4:5] /* {bcK} */, in: bcargs[28:32] /* {bcS, bcS, bcDictSlot, bcK} */},
+	opHasSubstrFuzzyA3:        {text: "contains_fuzzy_A3", out: bcargs[4:5] /* {bcK} */, in: bcargs[28:32] /* {bcS, bcS, bcDictSlot, bcK} */},
+	opHasSubstrFuzzyUnicodeA3: {text: "contains_fuzzy_unicode_A3", out: bcargs[4:5] /* {bcK} */, in: bcargs[28:32] /* {bcS, bcS, bcDictSlot, bcK} */},
 	opSkip1charLeft:           {text: "skip_1char_left", out: bcargs[3:5] /* {bcS, bcK} */, in: bcargs[3:5] /* {bcS, bcK} */},
 	opSkip1charRight:          {text: "skip_1char_right", out: bcargs[3:5] /* {bcS, bcK} */, in: bcargs[3:5] /* {bcS, bcK} */},
 	opSkipNcharLeft:           {text: "skip_nchar_left", out: bcargs[3:5] /* {bcS, bcK} */, in: bcargs[2:5] /* {bcS, bcS, bcK} */},
 	opSkipNcharRight:          {text: "skip_nchar_right", out: bcargs[3:5] /* {bcS, bcK} */, in: bcargs[2:5] /* {bcS, bcS, bcK} */},
 	opTrimWsLeft:              {text: "trim_ws_left", out: bcargs[0:1] /* {bcS} */, in: bcargs[3:5] /* {bcS, bcK} */},
 	opTrimWsRight:             {text: "trim_ws_right", out: bcargs[0:1] /* {bcS} */, in: bcargs[3:5] /* {bcS, bcK} */},
-	opTrim4charLeft:           {text: "trim_char_left", out: bcargs[0:1] /* {bcS} */, in: bcargs[23:26] /* {bcS, bcDictSlot, bcK} */},
-	opTrim4charRight:          {text: "trim_char_right", out: bcargs[0:1] /* {bcS} */, in: bcargs[23:26] /* {bcS, bcDictSlot, bcK} */},
+	opTrim4charLeft:           {text: "trim_char_left", out: bcargs[0:1] /* {bcS} */, in: bcargs[29:32] /* {bcS, bcDictSlot, bcK} */},
+	opTrim4charRight:          {text: "trim_char_right", out: bcargs[0:1] /* {bcS} */, in: bcargs[29:32] /* {bcS, bcDictSlot, bcK} */},
 	opoctetlength:             {text: "octetlength", out: bcargs[0:1] /* {bcS} */, in: bcargs[3:5] /* {bcS, bcK} */},
 	opcharlength:              {text: "characterlength", out: bcargs[0:1] /* {bcS} */, in: bcargs[3:5] /* {bcS, bcK} */},
 	opSubstr:                  {text: "substr", out: bcargs[0:1] /* {bcS} */, in: bcargs[1:5] /* {bcS, bcS, bcS, bcK} */},
-	opSplitPart:               {text: "split_part", out: bcargs[3:5] /* {bcS, bcK} */, in: bcargs[59:63] /* {bcS, bcDictSlot, bcS, bcK} */},
-	opContainsPrefixCs:        {text: "contains_prefix_cs", out: bcargs[3:5] /* {bcS, bcK} */, in: bcargs[23:26] /* {bcS, bcDictSlot, bcK} */},
-	opContainsPrefixCi:        {text: "contains_prefix_ci", out: bcargs[3:5] /* {bcS, bcK} */, in: bcargs[23:26] /* {bcS, bcDictSlot, bcK} */},
-	opContainsPrefixUTF8Ci:    {text: "contains_prefix_utf8_ci", out: bcargs[3:5] /* {bcS, bcK} */, in: bcargs[23:26] /* {bcS, bcDictSlot, bcK} */},
-	opContainsSuffixCs:        {text: "contains_suffix_cs", out: bcargs[3:5] /* {bcS, bcK} */, in: bcargs[23:26] /* {bcS, bcDictSlot, bcK} */},
-	opContainsSuffixCi:        {text: "contains_suffix_ci", out: bcargs[3:5] /* {bcS, bcK} */, in: bcargs[23:26] /* {bcS, bcDictSlot, bcK} */},
-	opContainsSuffixUTF8Ci:    {text: "contains_suffix_utf8_ci", out: bcargs[3:5] /* {bcS, bcK} */, in: bcargs[23:26] /* {bcS, bcDictSlot, bcK} */},
-	opContainsSubstrCs:        {text: "contains_substr_cs", out: bcargs[3:5] /* {bcS, bcK} */, in: bcargs[23:26] /* {bcS, bcDictSlot, bcK} */},
-	opContainsSubstrCi:        {text: "contains_substr_ci", out: bcargs[3:5] /* {bcS, bcK} */, in: bcargs[23:26] /* {bcS, bcDictSlot, bcK} */},
-	opContainsSubstrUTF8Ci:    {text: "contains_substr_utf8_ci", out: bcargs[3:5] /* {bcS, bcK} */, in: bcargs[23:26] /* {bcS, bcDictSlot, bcK} */},
-	opEqPatternCs:             {text: "eq_pattern_cs", out: bcargs[3:5] /* {bcS, bcK} */, in: bcargs[23:26] /* {bcS, bcDictSlot, bcK} */},
-	opEqPatternCi:             {text: "eq_pattern_ci", out: bcargs[3:5] /* {bcS, bcK} */, in: bcargs[23:26] /* {bcS, bcDictSlot, bcK} */},
-	opEqPatternUTF8Ci:         {text: "eq_pattern_utf8_ci", out: bcargs[3:5] /* {bcS, bcK} */, in: bcargs[23:26] /* {bcS, bcDictSlot, bcK} */},
-	opContainsPatternCs:       {text: "contains_pattern_cs", out: bcargs[3:5] /* {bcS, bcK} */, in: bcargs[23:26] /* {bcS, bcDictSlot, bcK} */},
-	opContainsPatternCi:       {text: "contains_pattern_ci", out: bcargs[3:5] /* {bcS, bcK} */, in: bcargs[23:26] /* {bcS, bcDictSlot, bcK} */},
-	opContainsPatternUTF8Ci:   {text: "contains_pattern_utf8_ci", out: bcargs[3:5] /* {bcS, bcK} */, in: bcargs[23:26] /* {bcS, bcDictSlot, bcK} */},
-	opIsSubnetOfIP4:           {text: "is_subnet_of_ip4", out: bcargs[4:5] /* {bcK} */, in: bcargs[23:26] /* {bcS, bcDictSlot, bcK} */},
-	opDfaT6:                   {text: "dfa_tiny6", out: bcargs[4:5] /* {bcK} */, in: bcargs[23:26] /* {bcS, bcDictSlot, bcK} */},
-	opDfaT7:                   {text: "dfa_tiny7", out: bcargs[4:5] /* {bcK} */, in: bcargs[23:26] /* {bcS, bcDictSlot, bcK} */},
-	opDfaT8:                   {text: "dfa_tiny8", out: bcargs[4:5] /* {bcK} */, in: bcargs[23:26] /* {bcS, bcDictSlot, bcK} */},
-	opDfaT6Z:                  {text: "dfa_tiny6Z", out: bcargs[4:5] /* {bcK} */, in: bcargs[23:26] /* {bcS, bcDictSlot, bcK} */},
-	opDfaT7Z:                  {text: "dfa_tiny7Z", out: bcargs[4:5] /* {bcK} */, in: bcargs[23:26] /* {bcS, bcDictSlot, bcK} */},
-	opDfaT8Z:                  {text: "dfa_tiny8Z", out: bcargs[4:5] /* {bcK} */, in: bcargs[23:26] /* {bcS, bcDictSlot, bcK} */},
-	opDfaLZ:                   {text: "dfa_largeZ", out: bcargs[4:5] /* {bcK} */, in: bcargs[23:26] /* {bcS, bcDictSlot, bcK} */},
+	opSplitPart:               {text: "split_part", out: bcargs[3:5] /* {bcS, bcK} */, in: bcargs[111:115] /* {bcS, bcDictSlot, bcS, bcK} */},
+	opContainsPrefixCs:        {text: "contains_prefix_cs", out: bcargs[3:5] /* {bcS, bcK} */, in: bcargs[29:32] /* {bcS, bcDictSlot, bcK} */},
+	opContainsPrefixCi:        {text: "contains_prefix_ci", out: bcargs[3:5] /* {bcS, bcK} */, in: bcargs[29:32] /* {bcS, bcDictSlot, bcK} */},
+	opContainsPrefixUTF8Ci:    {text: "contains_prefix_utf8_ci", out: bcargs[3:5] /* {bcS, bcK} */, in: bcargs[29:32] /* {bcS, bcDictSlot, bcK} */},
+	opContainsSuffixCs:        {text: "contains_suffix_cs", out: bcargs[3:5] /* {bcS, bcK} */, in: bcargs[29:32] /* {bcS, bcDictSlot, bcK} */},
+	opContainsSuffixCi:        {text: "contains_suffix_ci", out: bcargs[3:5] /* {bcS, bcK} */, in: bcargs[29:32] /* {bcS, bcDictSlot, bcK} */},
+	opContainsSuffixUTF8Ci:    {text: "contains_suffix_utf8_ci", out: bcargs[3:5] /* {bcS, bcK} */, in: bcargs[29:32] /* {bcS, bcDictSlot, bcK} */},
+	opContainsSubstrCs:        {text: "contains_substr_cs", out: bcargs[3:5] /* {bcS, bcK} */, in: bcargs[29:32] /* {bcS, bcDictSlot, bcK} */},
+	opContainsSubstrCi:        {text: "contains_substr_ci", out: bcargs[3:5] /* {bcS, bcK} */, in: bcargs[29:32] /* {bcS, bcDictSlot, bcK} */},
+	opContainsSubstrUTF8Ci:    {text: "contains_substr_utf8_ci", out: bcargs[3:5] /* {bcS, bcK} */, in: bcargs[29:32] /* {bcS, bcDictSlot, bcK} */},
+	opEqPatternCs:             {text: "eq_pattern_cs", out: bcargs[3:5] /* {bcS, bcK} */, in: bcargs[29:32] /* {bcS, bcDictSlot, bcK} */},
+	opEqPatternCi:             {text: "eq_pattern_ci", out: bcargs[3:5] /* {bcS, bcK} */, in: bcargs[29:32] /* {bcS, bcDictSlot, bcK} */},
+	opEqPatternUTF8Ci:         {text: "eq_pattern_utf8_ci", out: bcargs[3:5] /* {bcS, bcK} */, in: bcargs[29:32] /* {bcS, bcDictSlot, bcK} */},
+	opContainsPatternCs:       {text: "contains_pattern_cs", out: bcargs[3:5] /* {bcS, bcK} */, in: bcargs[29:32] /* {bcS, bcDictSlot, bcK} */},
+	opContainsPatternCi:       {text: "contains_pattern_ci", out: bcargs[3:5] /* {bcS, bcK} */, in: bcargs[29:32] /* {bcS, bcDictSlot, bcK} */},
+	opContainsPatternUTF8Ci:   {text: "contains_pattern_utf8_ci", out: bcargs[3:5] /* {bcS, bcK} */, in: bcargs[29:32] /* {bcS, bcDictSlot, bcK} */},
+	opIsSubnetOfIP4:           {text: "is_subnet_of_ip4", out: bcargs[4:5] /* {bcK} */, in: bcargs[29:32] /* {bcS, bcDictSlot, bcK} */},
+	opDfaT6:                   {text: "dfa_tiny6", out: bcargs[4:5] /* {bcK} */, in: bcargs[29:32] /* {bcS, bcDictSlot, bcK} */},
+	opDfaT7:                   {text: "dfa_tiny7", out: bcargs[4:5] /* {bcK} */, in: bcargs[29:32] /* {bcS, bcDictSlot, bcK} */},
+	opDfaT8:                   {text: "dfa_tiny8", out: bcargs[4:5] /* {bcK} */, in: bcargs[29:32] /* {bcS, bcDictSlot, bcK} */},
+	opDfaT6Z:                  {text: "dfa_tiny6Z", out: bcargs[4:5] /* {bcK} */, in: bcargs[29:32] /* {bcS, bcDictSlot, bcK} */},
+	opDfaT7Z:                  {text: "dfa_tiny7Z", out: bcargs[4:5] /* {bcK} */, in: bcargs[29:32] /* {bcS, bcDictSlot, bcK} */},
+	opDfaT8Z:                  {text: "dfa_tiny8Z", out: bcargs[4:5] /* {bcK} */, in: bcargs[29:32] /* {bcS, bcDictSlot, bcK} */},
+	opDfaLZ:                   {text: "dfa_largeZ", out: bcargs[4:5] /* {bcK} */, in: bcargs[29:32] /* {bcS, bcDictSlot, bcK} */},
 	opslower:                  {text: "slower", out: bcargs[3:5] /* {bcS, bcK} */, in: bcargs[3:5] /* {bcS, bcK} */, scratch: PageSize},
 	opsupper:                  {text: "supper", out: bcargs[3:5] /* {bcS, bcK} */, in: bcargs[3:5] /* {bcS, bcK} */, scratch: PageSize},
-	opaggapproxcount:          {text: "aggapproxcount", in: bcargs[26:30] /* {bcAggSlot, bcH, bcImmU16, bcK} */},
-	opaggapproxcountmerge:     {text: "aggapproxcountmerge", in: bcargs[102:106] /* {bcAggSlot, bcS, bcImmU16, bcK} */},
-	opaggslotapproxcount:      {text: "aggslotapproxcount", in: bcargs[78:83] /* {bcAggSlot, bcL, bcH, bcImmU16, bcK} */},
-	opaggslotapproxcountmerge: {text: "aggslotapproxcountmerge", in: bcargs[30:35] /* {bcAggSlot, bcL, bcS, bcImmU16, bcK} */},
+	opaggapproxcount:          {text: "aggapproxcount", in: bcargs[76:80] /* {bcAggSlot, bcH, bcImmU16, bcK} */},
+	opaggapproxcountmerge:     {text: "aggapproxcountmerge", in: bcargs[101:105] /* {bcAggSlot, bcS, bcImmU16, bcK} */},
+	opaggslotapproxcount:      {text: "aggslotapproxcount", in: bcargs[19:24] /* {bcAggSlot, bcL, bcH, bcImmU16, bcK} */},
+	opaggslotapproxcountmerge: {text: "aggslotapproxcountmerge", in: bcargs[96:101] /* {bcAggSlot, bcL, bcS, bcImmU16, bcK} */},
 	oppowuintf64:              {text: "powuint.f64", out: bcargs[0:1] /* {bcS} */, in: bcargs[13:16] /* {bcS, bcImmI64, bcK} */},
 }
 
-var bcargs = [109]bcArgType{bcS, bcS, bcS, bcS, bcK, bcAggSlot, bcL, bcK,
-	bcK, bcH, bcV, bcK, bcS, bcS, bcImmI64, bcK, bcV, bcImmF64, bcK,
-	bcV, bcLitRef, bcK, bcS, bcS, bcDictSlot, bcK, bcAggSlot, bcH,
-	bcImmU16, bcK, bcAggSlot, bcL, bcS, bcImmU16, bcK, bcB, bcH, bcK,
-	bcAggSlot, bcK, bcK, bcK, bcImmU16, bcK, bcB, bcK, bcS, bcS,
-	bcImmU64, bcK, bcAggSlot, bcS, bcK, bcV, bcImmU16, bcK, bcV, bcS,
-	bcK, bcS, bcDictSlot, bcS, bcK, bcS, bcK, bcAuxSlot, bcB,
-	bcSymbolID, bcK, bcS, bcV, bcK, bcV, bcK, bcK, bcV, bcImmI64, bcK,
-	bcAggSlot, bcL, bcH, bcImmU16, bcK, bcB, bcV, bcK, bcSymbolID, bcK,
-	bcS, bcS, bcImmU16, bcK, bcAggSlot, bcL, bcS, bcK, bcSymbolID, bcV,
-	bcK, bcV, bcV, bcK, bcAggSlot, bcS, bcImmU16, bcK, bcS, bcImmF64,
-	bcK}
+var bcargs = [117]bcArgType{bcS, bcS, bcS, bcS, bcK, bcH, bcV, bcK,
+	bcAggSlot, bcL, bcK, bcK, bcS, bcS, bcImmI64, bcK, bcS, bcImmF64,
+	bcK, bcAggSlot, bcL, bcH, bcImmU16, bcK, bcS, bcS, bcImmU16, bcK,
+	bcS, bcS, bcDictSlot, bcK, bcB, bcH, bcK, bcB, bcK, bcSymbolID,
+	bcV, bcK, bcV, bcK, bcK, bcImmU16, bcK, bcAggSlot, bcK, bcK, bcV,
+	bcLitRef, bcK, bcB, bcV, bcK, bcSymbolID, bcK, bcV, bcS, bcK, bcS,
+	bcK, bcSymbolID, bcAuxSlot, bcB, bcSymbolID, bcK, bcS, bcV, bcK,
+	bcS, bcS, bcImmU64, bcK, bcV, bcImmU16, bcK, bcAggSlot, bcH,
+	bcImmU16, bcK, bcV, bcDictSlot, bcK, bcV, bcV, bcK, bcV, bcImmF64,
+	bcK, bcAggSlot, bcL, bcS, bcK, bcB, bcB, bcK, bcAggSlot, bcL, bcS,
+	bcImmU16, bcK, bcAggSlot, bcS, bcImmU16, bcK, bcAggSlot, bcS, bcK,
+	bcV, bcImmI64, bcK, bcS, bcDictSlot, bcS, bcK, bcK, bcK}
 
 const (
 	optrap                    bcop = 0
@@ -537,119 +546,128 @@ const (
 	opgeodistance             bcop = 200
 	opalloc                   bcop = 201
 	opconcatstr               bcop = 202
-	opfindsym                 bcop = 203
-	opfindsym2                bcop = 204
-	opblendv                  bcop = 205
-	opblendf64                bcop = 206
-	opunpack                  bcop = 207
-	opunsymbolize             bcop = 208
-	opunboxktoi64             bcop = 209
-	opunboxcoercef64          bcop = 210
-	opunboxcoercei64          bcop = 211
-	opunboxcvtf64             bcop = 212
-	opunboxcvti64             bcop = 213
-	opboxf64                  bcop = 214
-	opboxi64                  bcop = 215
-	opboxk                    bcop = 216
-	opboxstr                  bcop = 217
-	opboxlist                 bcop = 218
-	opmakelist                bcop = 219
-	opmakestruct              bcop = 220
-	ophashvalue               bcop = 221
-	ophashvalueplus           bcop = 222
-	ophashmember              bcop = 223
-	ophashlookup              bcop = 224
-	opaggandk                 bcop = 225
-	opaggork                  bcop = 226
-	opaggslotsumf             bcop = 227
-	opaggsumf                 bcop = 228
-	opaggsumi                 bcop = 229
-	opaggminf                 bcop = 230
-	opaggmini                 bcop = 231
-	opaggmaxf                 bcop = 232
-	opaggmaxi                 bcop = 233
-	opaggandi                 bcop = 234
-	opaggori                  bcop = 235
-	opaggxori                 bcop = 236
-	opaggcount                bcop = 237
-	opaggbucket               bcop = 238
-	opaggslotandk             bcop = 239
-	opaggslotork              bcop = 240
-	opaggslotsumi             bcop = 241
-	opaggslotavgf             bcop = 242
-	opaggslotavgi             bcop = 243
-	opaggslotminf             bcop = 244
-	opaggslotmini             bcop = 245
-	opaggslotmaxf             bcop = 246
-	opaggslotmaxi             bcop = 247
-	opaggslotandi             bcop = 248
-	opaggslotori              bcop = 249
-	opaggslotxori             bcop = 250
-	opaggslotcount            bcop = 251
-	opaggslotcountv2          bcop = 252
-	oplitref                  bcop = 253
-	opauxval                  bcop = 254
-	opsplit                   bcop = 255
-	optuple                   bcop = 256
-	opmovk                    bcop = 257
-	opzerov                   bcop = 258
-	opmovv                    bcop = 259
-	opmovvk                   bcop = 260
-	opmovf64                  bcop = 261
-	opmovi64                  bcop = 262
-	opobjectsize              bcop = 263
-	oparraysize               bcop = 264
-	oparrayposition           bcop = 265
-	opCmpStrEqCs              bcop = 266
-	opCmpStrEqCi              bcop = 267
-	opCmpStrEqUTF8Ci          bcop = 268
-	opCmpStrFuzzyA3           bcop = 269
-	opCmpStrFuzzyUnicodeA3    bcop = 270
-	opHasSubstrFuzzyA3        bcop = 271
-	opHasSubstrFuzzyUnicodeA3 bcop = 272
-	opSkip1charLeft           bcop = 273
-	opSkip1charRight          bcop = 274
-	opSkipNcharLeft           bcop = 275
-	opSkipNcharRight          bcop = 276
-	opTrimWsLeft              bcop = 277
-	opTrimWsRight             bcop = 278
-	opTrim4charLeft           bcop = 279
-	opTrim4charRight          bcop = 280
-	opoctetlength             bcop = 281
-	opcharlength              bcop = 282
-	opSubstr                  bcop = 283
-	opSplitPart               bcop = 284
-	opContainsPrefixCs        bcop = 285
-	opContainsPrefixCi        bcop = 286
-	opContainsPrefixUTF8Ci    bcop = 287
-	opContainsSuffixCs        bcop = 288
-	opContainsSuffixCi        bcop = 289
-	opContainsSuffixUTF8Ci    bcop = 290
-	opContainsSubstrCs        bcop = 291
-	opContainsSubstrCi        bcop = 292
-	opContainsSubstrUTF8Ci    bcop = 293
-	opEqPatternCs             bcop = 294
-	opEqPatternCi             bcop = 295
-	opEqPatternUTF8Ci         bcop = 296
-	opContainsPatternCs       bcop = 297
-	opContainsPatternCi       bcop = 298
-	opContainsPatternUTF8Ci   bcop = 299
-	opIsSubnetOfIP4           bcop = 300
-	opDfaT6                   bcop = 301
-	opDfaT7                   bcop = 302
-	opDfaT8                   bcop = 303
-	opDfaT6Z                  bcop = 304
-	opDfaT7Z                  bcop = 305
-	opDfaT8Z                  bcop = 306
-	opDfaLZ                   bcop = 307
-	opslower                  bcop = 308
-	opsupper                  bcop = 309
-	opaggapproxcount          bcop = 310
-	opaggapproxcountmerge     bcop = 311
-	opaggslotapproxcount      bcop = 312
-	opaggslotapproxcountmerge bcop = 313
-	oppowuintf64              bcop = 314
-	_maxbcop                       = 315
+	opconcatstrskip           bcop = 203
+	opfindsym                 bcop = 204
+	opfindsym2                bcop = 205
+	opblendv                  bcop = 206
+	opblendf64                bcop = 207
+	opunpack                  bcop = 208
+	opunsymbolize             bcop = 209
+	opsymmatch                bcop = 210
+	opunboxktoi64             bcop = 211
+	opunboxcoercef64          bcop = 212
+	opunboxcoercei64          bcop = 213
+	opunboxcvtf64             bcop = 214
+	opunboxcvti64             bcop = 215
+	opboxf64                  bcop = 216
+	opboxi64                  bcop = 217
+	opboxk                    bcop = 218
+	opboxstr                  bcop = 219
+	opboxlist                 bcop = 220
+	opmakelist                bcop = 221
+	opmakestruct              bcop = 222
+	ophashvalue               bcop = 223
+	ophashvalueplus           bcop = 224
+	ophashmember              bcop = 225
+	ophashlookup              bcop = 226
+	opaggandk                 bcop = 227
+	opaggork                  bcop = 228
+	opaggslotsumf             bcop = 229
+	opaggsumf                 bcop = 230
+	opaggsumi                 bcop = 231
+	opaggminf                 bcop = 232
+	opaggmini                 bcop = 233
+	opaggmaxf                 bcop = 234
+	opaggmaxi                 bcop = 235
+	opaggandi                 bcop = 236
+	opaggori                  bcop = 237
+	opaggxori                 bcop = 238
+	opaggcount                bcop = 239
+	opaggbucket               bcop = 240
+	opaggslotandk             bcop = 241
+	opaggslotork              bcop = 242
+	opaggslotsumi             bcop = 243
+	opaggslotavgf             bcop = 244
+	opaggslotavgi             bcop = 245
+	opaggslotminf             bcop = 246
+	opaggslotmini             bcop = 247
+	opaggslotmaxf             bcop = 248
+	opaggslotmaxi             bcop = 249
+	opaggslotandi             bcop = 250
+	opaggslotori              bcop = 251
+	opaggslotxori             bcop = 252
+	opaggslotcount            bcop = 253
+	opaggslotcountv2          bcop = 254
+	oplitref                  bcop = 255
+	opauxval                  bcop = 256
+	opsplit                   bcop = 257
+	optuple                   bcop = 258
+	opmovk                    bcop = 259
+	opzerov                   bcop = 260
+	opmovv                    bcop = 261
+	opmovvk                   bcop = 262
+	opmovf64                  bcop = 263
+	opmovi64                  bcop = 264
+	opobjectsize              bcop = 265
+	oparraysize               bcop = 266
+	oparrayposition           bcop = 267
+	oparrayelement            bcop = 268
+	oplistfield               bcop = 269
+	oplistvalues              bcop = 270
+	oplistflatten             bcop = 271
+	opstructvalues            bcop = 272
+	opobjectmerge             bcop = 273
+	opobjectdelete            bcop = 274
+	opCmpStrEqCs              bcop = 275
+	opCmpStrEqCi              bcop = 276
+	opCmpStrEqUTF8Ci          bcop = 277
+	opCmpStrFuzzyA3           bcop = 278
+	opCmpStrFuzzyUnicodeA3    bcop = 279
+	opHasSubstrFuzzyA3        bcop = 280
+	opHasSubstrFuzzyUnicodeA3 bcop = 281
+	opSkip1charLeft           bcop = 282
+	opSkip1charRight          bcop = 283
+	opSkipNcharLeft           bcop = 284
+	opSkipNcharRight          bcop = 285
+	opTrimWsLeft              bcop = 286
+	opTrimWsRight             bcop = 287
+	opTrim4charLeft           bcop = 288
+	opTrim4charRight          bcop = 289
+	opoctetlength             bcop = 290
+	opcharlength              bcop = 291
+	opSubstr                  bcop = 292
+	opSplitPart               bcop = 293
+	opContainsPrefixCs        bcop = 294
+	opContainsPrefixCi        bcop = 295
+	opContainsPrefixUTF8Ci    bcop = 296
+	opContainsSuffixCs        bcop = 297
+	opContainsSuffixCi        bcop = 298
+	opContainsSuffixUTF8Ci    bcop = 299
+	opContainsSubstrCs        bcop = 300
+	opContainsSubstrCi        bcop = 301
+	opContainsSubstrUTF8Ci    bcop = 302
+	opEqPatternCs             bcop = 303
+	opEqPatternCi             bcop = 304
+	opEqPatternUTF8Ci         bcop = 305
+	opContainsPatternCs       bcop = 306
+	opContainsPatternCi       bcop = 307
+	opContainsPatternUTF8Ci   bcop = 308
+	opIsSubnetOfIP4           bcop = 309
+	opDfaT6                   bcop = 310
+	opDfaT7                   bcop = 311
+	opDfaT8                   bcop = 312
+	opDfaT6Z                  bcop = 313
+	opDfaT7Z                  bcop = 314
+	opDfaT8Z                  bcop = 315
+	opDfaLZ                   bcop = 316
+	opslower                  bcop = 317
+	opsupper                  bcop = 318
+	opaggapproxcount          bcop = 319
+	opaggapproxcountmerge     bcop = 320
+	opaggslotapproxcount      bcop = 321
+	opaggslotapproxcountmerge bcop = 322
+	oppowuintf64              bcop = 323
+	_maxbcop                       = 324
 )
 
 type opreplace struct{ from, to bcop }
@@ -659,4 +677,4 @@ var patchAVX512Level2 []opreplace = []opreplace{
 	{from: opaggslotcountv2, to: opaggslotcount},
 }
 
-// checksum: a0f77f85f6d6c1ee19e7f360d15c2c0d
+// checksum: 1b902f1226a42bff809a9be08c1d3328
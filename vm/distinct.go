@@ -94,6 +94,8 @@ func (d *DistinctFilter) Limit(n int64) {
 	d.remaining = n
 }
 
+func (d *DistinctFilter) redacted() string { return redactList(d.columns) }
+
 func (d *DistinctFilter) Open() (io.WriteCloser, error) {
 	dst, err := d.out.Open()
 	if err != nil {
@@ -130,6 +132,8 @@ type deduper struct {
 	// if we reach the limit
 	// set by the parent
 	closed bool
+
+	zion zionRows
 }
 
 func (d *deduper) symbolize(st *symtab, aux *auxbindings) error {
@@ -179,7 +183,11 @@ func (d *deduper) writeRows(delims []vmref, rp *rowParams) error {
 		d.hashes = make([]uint64, len(delims))
 	}
 	d.bc.prepare(rp)
+	wd := watch("distinct", d.parent, &d.prog, &d.bc)
 	count := evaldedup(&d.bc, delims, d.hashes, d.local, d.hashslot)
+	if err := wd.done(); err != nil {
+		return err
+	}
 	if d.bc.err != 0 {
 		return bytecodeerror("distinct", &d.bc)
 	}
@@ -262,5 +270,17 @@ func (d *deduper) writeRows(delims []vmref, rp *rowParams) error {
 
 func (d *deduper) Close() error {
 	d.bc.reset()
+	d.zion.free()
 	return d.dst.Close()
 }
+
+var _ zionConsumer = &deduper{}
+
+func (d *deduper) zionOk() bool { return true }
+
+func (d *deduper) writeZion(state *zionState) error {
+	if d.closed {
+		return io.EOF
+	}
+	return d.zion.write(state, d)
+}
@@ -371,6 +371,21 @@ TEXT bctrap(SB), NOSPLIT|NOFRAME, $0
   CLC                                     \
   RET
 
+// vmenter is the entry point used by BC_ENTER()
+// (it is deliberately not named bc* so that it is
+// not mistaken for an opcode by genbytecode);
+// it aborts with bcerrInterrupted if the watchdog
+// has set bytecode.interrupt and otherwise jumps
+// to the first instruction
+TEXT vmenter(SB), NOSPLIT|NOFRAME, $0
+  CMPL bytecode_interrupt(VIRT_BCPTR), $0
+  JNE  interrupted
+  ADDQ $8, VIRT_PCREG
+  JMP  -8(VIRT_PCREG)
+interrupted:
+  MOVL $const_bcerrInterrupted, bytecode_err(VIRT_BCPTR)
+  RET_ABORT()
+
 // the 'return' instruction
 //
 // _ = ret()
@@ -1175,7 +1190,7 @@ TEXT bccmpvk(SB), NOSPLIT|NOFRAME, $0
 
   VPBROADCASTD CONSTD_0x0F(), Z6                       // Z6 <- Constant(0xF)
   KMOVW 0(VIRT_VALUES)(CX*1), K2                       // K2 <- right boolean value
-  VPSRLD.Z $3, Z6, Z7                                  // Z7 <- Constant(1)
+  VPSRLD $3, Z6, Z7                                    // Z7 <- Constant(1)
 
   VPSRLD $4, Z4, Z5
   VPANDD Z6, Z5, Z5                                    // Z5 <- left ION type
@@ -1651,30 +1666,6 @@ TEXT bccmpgekimm(SB), NOSPLIT|NOFRAME, $0
   KUNPCKBW K1, K2, K1                                                  \
   BC_STORE_K_TO_SLOT(IN(K1), IN(DX))
 
-// k[0] = cmp_xx(i64[1], i64[2]).k[3]
-#define BC_CMP_OP_I64(Predicate)                                       \
-  BC_UNPACK_4xSLOT(0, OUT(DX), OUT(BX), OUT(CX), OUT(R8))              \
-  BC_LOAD_K1_K2_FROM_SLOT(OUT(K1), OUT(K2), IN(R8))                    \
-  BC_LOAD_I64_FROM_SLOT(OUT(Z2), OUT(Z3), IN(BX))                  \
-                                                                       \
-  VPCMPQ Predicate, 0(VIRT_VALUES)(CX*1), Z2, K1, K1                   \
-  VPCMPQ Predicate, 64(VIRT_VALUES)(CX*1), Z3, K2, K2                  \
-                                                                       \
-  KUNPCKBW K1, K2, K1                                                  \
-  BC_STORE_K_TO_SLOT(IN(K1), IN(DX))
-
-// k[0] = cmp_xx(i64[1], i64@imm[2]).k[3]
-#define BC_CMP_OP_I64_IMM(Predicate)                                   \
-  BC_UNPACK_2xSLOT_ZI64_SLOT(0, OUT(DX), OUT(BX), OUT(Z4), OUT(R8))    \
-  BC_LOAD_K1_K2_FROM_SLOT(OUT(K1), OUT(K2), IN(R8))                    \
-  BC_LOAD_I64_FROM_SLOT(OUT(Z2), OUT(Z3), IN(BX))                  \
-                                                                       \
-  VPCMPQ Predicate, Z4, Z2, K1, K1                                     \
-  VPCMPQ Predicate, Z4, Z3, K2, K2                                     \
-                                                                       \
-  KUNPCKBW K1, K2, K1                                                  \
-  BC_STORE_K_TO_SLOT(IN(K1), IN(DX))
-
 // k[0] = cmpeq.f64(f64[1], f64[2]).k[3]
 //
 // Floating point equality in the sense that
@@ -1849,79 +1840,9 @@ TEXT bccmpgef64imm(SB), NOSPLIT|NOFRAME, $0
   BC_CMP_OP_F64_IMM($VCMP_IMM_NLT_UQ)
   NEXT_ADVANCE(BC_SLOT_SIZE*3 + 8)
 
-//
-// k[0] = cmpeq.i64(i64[1], i64[2]).k[3]
-//
-TEXT bccmpeqi64(SB), NOSPLIT|NOFRAME, $0
-  BC_CMP_OP_I64($VPCMP_IMM_EQ)
-  NEXT_ADVANCE(BC_SLOT_SIZE*4)
-
-//
-// k[0] = cmpeq.i64@imm(i64[1], i64@imm[2]).k[3]
-//
-TEXT bccmpeqi64imm(SB), NOSPLIT|NOFRAME, $0
-  BC_CMP_OP_I64_IMM($VPCMP_IMM_EQ)
-  NEXT_ADVANCE(BC_SLOT_SIZE*3 + 8)
-
-//
-// k[0] = cmplt.i64(i64[1], i64[2]).k[3]
-//
-TEXT bccmplti64(SB), NOSPLIT|NOFRAME, $0
-  BC_CMP_OP_I64($VPCMP_IMM_LT)
-  NEXT_ADVANCE(BC_SLOT_SIZE*4)
-
-//
-// k[0] = cmplt.i64@imm(i64[1], i64@imm[2]).k[3]
-//
-TEXT bccmplti64imm(SB), NOSPLIT|NOFRAME, $0
-  BC_CMP_OP_I64_IMM($VPCMP_IMM_LT)
-  NEXT_ADVANCE(BC_SLOT_SIZE*3 + 8)
-
-//
-// k[0] = cmple.i64(i64[1], i64[2]).k[3]
-//
-TEXT bccmplei64(SB), NOSPLIT|NOFRAME, $0
-  BC_CMP_OP_I64($VPCMP_IMM_LE)
-  NEXT_ADVANCE(BC_SLOT_SIZE*4)
-
-//
-// k[0] = cmple.i64@imm(i64[1], i64@imm[2]).k[3]
-//
-TEXT bccmplei64imm(SB), NOSPLIT|NOFRAME, $0
-  BC_CMP_OP_I64_IMM($VPCMP_IMM_LE)
-  NEXT_ADVANCE(BC_SLOT_SIZE*3 + 8)
-
-//
-// k[0] = cmpgt.i64(i64[1], i64[2]).k[3]
-//
-TEXT bccmpgti64(SB), NOSPLIT|NOFRAME, $0
-  BC_CMP_OP_I64($VPCMP_IMM_GT)
-  NEXT_ADVANCE(BC_SLOT_SIZE*4)
-
-//
-// k[0] = cmpgt.i64@imm(i64[1], i64@imm[2]).k[3]
-//
-TEXT bccmpgti64imm(SB), NOSPLIT|NOFRAME, $0
-  BC_CMP_OP_I64_IMM($VPCMP_IMM_GT)
-  NEXT_ADVANCE(BC_SLOT_SIZE*3 + 8)
-
-//
-// k[0] = cmpge.i64(i64[1], i64[2]).k[3]
-//
-TEXT bccmpgei64(SB), NOSPLIT|NOFRAME, $0
-  BC_CMP_OP_I64($VPCMP_IMM_GE)
-  NEXT_ADVANCE(BC_SLOT_SIZE*4)
-
-//
-// k[0] = cmpge.i64@imm(i64[1], i64@imm[2]).k[3]
-//
-TEXT bccmpgei64imm(SB), NOSPLIT|NOFRAME, $0
-  BC_CMP_OP_I64_IMM($VPCMP_IMM_GE)
-  NEXT_ADVANCE(BC_SLOT_SIZE*3 + 8)
+#include "bc_eval_cmp_i64_gen.h"
 
 #undef BC_CMP_OP_F64_IMM
-#undef BC_CMP_OP_I64_IMM
-#undef BC_CMP_OP_I64
 
 
 // Test Instructions
@@ -4995,6 +4916,141 @@ done:
 
   _BC_ERROR_HANDLER_MORE_SCRATCH()
 
+// slice[0].k[1] = concatstrskip(varargs(str[0].k[1])).k[2]
+//
+// Like concatstr, but an argument that is missing
+// in a lane is treated as an empty string rather
+// than making the whole result missing. The output
+// predicate is k[2].
+//
+// scratch: PageSize
+TEXT bcconcatstrskip(SB), NOSPLIT|NOFRAME, $0
+  BC_UNPACK_SLOT(BC_SLOT_SIZE*2, OUT(R8))
+  BC_UNPACK_RU32(BC_SLOT_SIZE*3, OUT(CX))              // CX <- number of variable arguments
+  BC_LOAD_K1_FROM_SLOT(OUT(K1), IN(R8))                // K1 <- output predicate
+  ADDQ $(BC_SLOT_SIZE*3 + 4), VIRT_PCREG               // VIRT_PCREG <- the current va base
+
+  // Calculate Length
+  // ----------------
+
+  VPXORQ X2, X2, X2                                    // Z2 <- Concat length (low)
+  VPXORQ X3, X3, X3                                    // Z3 <- Concat length (high)
+  VMOVQ VIRT_PCREG, X11                                // X11 <- spilled VIRT_PCREG, the current va base
+
+  TESTL CX, CX                                         // PARANOIA:
+  JZ done                                              // No arguments shouldn't happen, but let's make it safe if it does...
+
+va_len_iter:
+  BC_UNPACK_2xSLOT(0, OUT(BX), OUT(R8))                // BX <- slice slot; R8 <- predicate slot
+  ADDQ $(BC_SLOT_SIZE*2), VIRT_PCREG                   // VIRT_PCREG <- advance this slot pair
+
+  BC_LOAD_K1_FROM_SLOT(OUT(K2), IN(R8))
+  KSHIFTRW $8, K2, K3
+  VPMOVZXDQ.Z 64(VIRT_VALUES)(BX*1), K2, Z4            // Z4 <- lengths (low), zero for missing arguments
+  VPMOVZXDQ.Z 96(VIRT_VALUES)(BX*1), K3, Z5            // Z5 <- lengths (high), zero for missing arguments
+
+  VPADDQ Z4, Z2, Z2
+  VPADDQ Z5, Z3, Z3
+
+  SUBL $1, CX
+  JNE va_len_iter
+
+  KSHIFTRW $8, K1, K2
+  VMOVQ X11, VIRT_PCREG                                // VIRT_PCREG <- rewind va base as we need to iterate it once more
+
+  // Allocate Scratch
+  // ----------------
+
+  VPMOVUSQD.Z Z2, K1, Y4
+  VPMOVUSQD.Z Z3, K2, Y5
+  BC_UNPACK_RU32(-4, OUT(CX))                          // CX <- number of variable arguments (we know it's non-zero if we are here)
+  VINSERTI32X8 $1, Y5, Z4, Z3                          // Z3 <- the final length of all active lanes as 32-bit units (saturated)
+
+  // R15 (DstSum), Z5 (DstOff), Z7 (DstLen), Z4 (DstEnd), K1 (DstMask)
+  BC_HORIZONTAL_LENGTH_SUM(OUT(R15), OUT(Z5), OUT(Z7), OUT(Z4), OUT(K1), IN(Z3), IN(K1), X9, K2)
+
+  BC_ALLOC_SLICE(OUT(Z2), IN(R15), BX, R8)             // Z2 <- Offset of the beginning of the allocated buffer
+  VPADDD.Z Z5, Z2, K1, Z2                              // Z2 <- Offsets of each allocated object
+  VMOVDQA32 Z2, Z6
+
+  // Concatenate Strings
+  // -------------------
+
+va_copy_next:
+  TESTL CX, CX
+  JZ done
+
+va_copy_iter:
+  BC_UNPACK_2xSLOT(0, OUT(BX), OUT(DX))
+  ADDQ VIRT_VALUES, BX                                 // BX <- absolute address of the slice stack-slot to be appended
+  ADDQ $(BC_SLOT_SIZE*2), VIRT_PCREG
+
+  BC_LOAD_K1_FROM_SLOT(OUT(K3), IN(DX))
+  KANDW K1, K3, K3                                     // K3 <- lanes where this argument is present
+  VMOVDQU32.Z 64(BX*1), K3, Z5                         // Z5 <- lengths of all slices to be appended (zero for inactive)
+  VPTESTMD Z5, Z5, K3, K2                              // K2 <- mask of all slices to be appended (non-zero length)
+
+  VMOVDQU32 Z6, bytecode_spillArea(VIRT_BCPTR)         // [] <- Save the current end index of each string where content will be copied
+  VPADDD Z5, Z6, Z6                                    // Z6 <- End index of each output string including current slices
+
+  KMOVW K2, R8                                         // R8 <- mask of all lanes to be appended having non-zero length
+  SUBL $1, CX
+
+  TESTL R8, R8                                         // Go to the next vararg if there are no slices to append
+  JZ va_copy_next
+
+  VMOVQ CX, X12                                        // Spill CX (va counter)
+
+lane_copy_iter:                                        // Iterate over the mask and append each string that has a content
+  TZCNTL R8, R14                                       // R14 <- Index of the lane to process
+  BLSRL R8, R8                                         // R8 <- Clear the index of the iterator
+
+  MOVL 64(BX)(R14 * 4), CX                             // CX <- Input length
+  MOVL bytecode_spillArea(VIRT_BCPTR)(R14 * 4), R15    // R15 <- Output index
+  MOVL 0(BX)(R14 * 4), R14                             // R14 <- Input index
+  ADDQ VIRT_BASE, R15                                  // R15 <- Make output address from output index
+  ADDQ VIRT_BASE, R14                                  // R14 <- Make input address from input index
+
+  SUBL $64, CX
+  JCS lane_64b_tail
+
+  // Main copy loop that processes 64 bytes at once
+lane_64b_iter:
+  VMOVDQU8 0(R14), Z7
+  ADDQ $64, R14
+  VMOVDQU8 Z7, 0(R15)
+  ADDQ $64, R15
+
+  SUBL $64, CX
+  JCC lane_64b_iter
+
+lane_64b_tail:
+  MOVQ $-1, DX
+  SHLQ CL, DX
+  NOTQ DX
+  KMOVQ DX, K2
+
+  VMOVDQU8.Z 0(R14), K2, Z7
+  VMOVDQU8 Z7, K2, 0(R15)
+
+  TESTL R8, R8
+  JNE lane_copy_iter
+
+  VMOVQ X12, CX                                        // Reload CX (va counter)
+  TESTL CX, CX
+  JNE va_copy_iter
+
+done:
+  VMOVQ X11, BX                                        // BX <- Get the original va base and use it to load output slots
+  BC_MOV_SLOT (-BC_SLOT_SIZE*3 - 4)(BX), DX            // DX <- Load the output slice slot
+  BC_MOV_SLOT (-BC_SLOT_SIZE*2 - 4)(BX), BX            // BX <- Load the output predicate slot
+
+  BC_STORE_SLICE_TO_SLOT(IN(Z2), IN(Z3), IN(DX))       // Store the output slice
+  BC_STORE_K_TO_SLOT(IN(K1), IN(BX))                   // Store the output predicate
+  NEXT_ADVANCE(0)
+
+  _BC_ERROR_HANDLER_MORE_SCRATCH()
+
 
 // Find Symbol Instructions
 // ------------------------
@@ -5364,6 +5420,55 @@ next:
   BC_STORE_VALUE_TO_SLOT(IN(Z0), IN(Z1), IN(Z2), IN(Z3), IN(DX))
   NEXT_ADVANCE(BC_SLOT_SIZE*3)
 
+// k[0] = symmatch(v[1], dict[2]).k[3]
+//
+// tests the symbols in v[1] against the per-symbol bitmap in dict[2],
+// which has a bit set for each symbol ID that matches (see symmatcher)
+TEXT bcsymmatch(SB), NOSPLIT|NOFRAME, $0
+  BC_UNPACK_SLOT_DICT_SLOT(BC_SLOT_SIZE*1, OUT(BX), OUT(R14), OUT(R8))
+
+  BC_LOAD_K1_FROM_SLOT(OUT(K1), IN(R8))
+  BC_LOAD_VALUE_TYPEL_FROM_SLOT(OUT(Z2), IN(BX))
+
+  VPBROADCASTD CONSTD_7(), Z20                   // Z20 <- dword(7)
+  VPSRLD $4, Z2, Z4                              // Z4 <- value tag
+  VPCMPEQD Z20, Z4, K1, K1                       // K1 <- values that are symbols
+  KTESTW K1, K1
+  JZ next
+
+  KMOVW K1, K3
+  BC_LOAD_VALUE_SLICE_FROM_SLOT(OUT(Z0), OUT(Z1), IN(BX))
+  BC_LOAD_VALUE_HLEN_FROM_SLOT(OUT(Z3), IN(BX))
+
+  VPGATHERDD 1(VIRT_BASE)(Z0*1), K3, Z6          // Z6 <- SymbolID bytes (without TLV, which was skipped)
+  VPSUBD Z1, Z3, Z4                              // Z4 <- -(SymbolIDLength)
+
+  VBROADCASTI32X4 CONST_GET_PTR(bswap32, 0), Z16 // Z16 <- bswap32 predicate for VPSHUFB
+  VPADDD.BCST CONSTD_4(), Z4, Z4                 // Z4 <- (4 - SymbolIDLength)
+  VPSLLD $3, Z4, Z5                              // Z5 <- (4 - SymbolIDLength) << 3
+  VPSHUFB Z16, Z6, Z6                            // Z6 <- bswap32(symbol bytes)
+  VPSRLVD Z5, Z6, Z6                             // Z6 <- SymbolIDs
+
+  VPBROADCASTD 8(R14), Z7                        // Z7 <- bitmap length in bytes
+  MOVQ 0(R14), R14                               // R14 <- bitmap base
+  VPSLLD $3, Z7, Z7                              // Z7 <- bitmap length in bits
+  VPCMPUD $VPCMP_IMM_LT, Z7, Z6, K1, K1          // K1 <- symbols covered by the bitmap
+
+  KMOVW K1, K3
+  VPSRLD $5, Z6, Z8                              // Z8 <- SymbolID >> 5
+  VPSLLD $2, Z8, Z8                              // Z8 <- offset of the dword holding the bit
+  VPXORD Z9, Z9, Z9
+  VPGATHERDD 0(R14)(Z8*1), K3, Z9                // Z9 <- bitmap dwords
+
+  VPANDD.BCST CONSTD_31(), Z6, Z10               // Z10 <- SymbolID & 31
+  VPSRLVD Z10, Z9, Z9                            // Z9 <- bitmap dwords >> (SymbolID & 31)
+  VPTESTMD.BCST CONSTD_1(), Z9, K1, K1           // K1 <- symbols that matched
+
+next:
+  BC_UNPACK_SLOT(0, OUT(DX))
+  BC_STORE_K_TO_SLOT(IN(K1), IN(DX))
+  NEXT_ADVANCE(BC_SLOT_SIZE*3 + BC_DICT_SIZE)
+
 // i64[0].k[1] = unbox.k@i64(v[2]).k[3]
 //
 // NOTE: This opcode was designed in a way to be followed by cvti64tok, because we
@@ -8342,6 +8447,660 @@ done:
 
   NEXT_ADVANCE(BC_SLOT_SIZE*5)
 
+// v[0].k[1] = arrayelement(s[2], i64[3]).k[4]
+//
+// Put the element of the list s[2] at the zero-based
+// position i64[3] into v[0]. Lanes where the position
+// is negative or past the end of the list are unset.
+TEXT bcarrayelement(SB), NOSPLIT|NOFRAME, $0
+  BC_UNPACK_3xSLOT(BC_SLOT_SIZE*2, OUT(BX), OUT(CX), OUT(R8))
+  BC_LOAD_SLICE_FROM_SLOT(OUT(Z0), OUT(Z1), IN(BX))
+  BC_LOAD_K1_FROM_SLOT(OUT(K1), IN(R8))
+  BC_LOAD_I64_FROM_SLOT(OUT(Z2), OUT(Z3), IN(CX))
+
+  VPMOVUSQD Z2, Y2                                             // Y2 <- uint32(position) (low), saturated
+  VPMOVUSQD Z3, Y3                                             // Y3 <- uint32(position) (high), saturated
+  VINSERTI32X8 $1, Y3, Z2, Z2                                  // Z2 <- number of elements to skip
+
+  VPADDD.Z Z0, Z1, K1, Z1                                      // Z1 <- end of the list
+  VPCMPUD $VPCMP_IMM_LT, Z1, Z0, K1, K1                        // K1 <- lanes to scan (empty lists discarded)
+  KXORW K6, K6, K6                                             // K6 <- lanes where the element was found
+
+  VPBROADCASTD CONSTD_1(), Z13                                 // Z13 <- dword(1)
+  VPXORD X14, X14, X14                                         // Z14 <- element offset
+  VPXORD X15, X15, X15                                         // Z15 <- element length
+  VPXORD X16, X16, X16                                         // Z16 <- element Type|L
+  VPXORD X17, X17, X17                                         // Z17 <- element header length
+
+  KTESTW K1, K1
+  JZ done
+
+loop:
+  KMOVW K1, K2
+  VPXORD X4, X4, X4
+  VPGATHERDD 0(VIRT_BASE)(Z0*1), K2, Z4                        // Z4 <- first 4 ion bytes
+  VPSLLD $5, Z13, Z11                                          // Z11 <- dword(32)
+  VPSHUFB BC_CONST(bswap32), Z4, Z5                            // Z5 <- bswap32(bytes)
+  VPBROADCASTD CONSTD_0x00808080(), Z7                         // Z7 <- dword(0x808080)
+  VPSRLD $24, Z5, Z9                                           // Z9 <- extracted Type|L byte
+  VPANDD Z7, Z5, Z6                                            // Z6 <- bswap32(bytes) & 0x00808080
+  VPANDND Z5, Z7, Z7                                           // Z7 <- bswap32(bytes) & 0xFF7F7F7F
+  VPCMPUD $VPCMP_IMM_GE, Z11, Z9, K1, K3                       // K3 <- Type != NULL|BOOL (Type|L >= 32)
+
+  VPLZCNTD Z6, Z6                                              // Z6 <- lzcnt32(bswap32(bytes) & 0x808080) (number of length bytes in bits)
+  VPANDD.BCST.Z CONSTD_15(), Z9, K3, Z8                        // Z8 <- L field extracted from Type|L and corrected to 0 if NULL/BOOL
+  VPSLLD $8, Z7, Z7                                            // Z7 <- (bswap32(bytes) & 0x7F7F7F) << 8
+  VPCMPEQD.BCST CONSTD_14(), Z8, K1, K3                        // K3 <- lanes that need a separate Length data when L == 14
+
+  VPSUBD Z6, Z11, Z11                                          // Z11 <- 32 - lzcnt32(bswap32(bytes) & 0x808080) (number of bits to trash)
+  VPSRLD.Z $3, Z6, K3, Z10                                     // Z10 <- size of Length field, in bytes (or 0, if there is no Length field)
+  VPSRLVD Z11, Z7, K3, Z8                                      // Z8 <- length data as [00000000|0CCCCCCCC|0BBBBBBBB|0AAAAAAAA]
+  VPADDD.Z Z13, Z10, K1, Z10                                   // Z10 <- header length (includes TLV byte and optional Length field size)
+
+  VPSRLD $1, Z8, Z11                                           // Z11 <- length data as [00000000|00CCCCCCC|C0BBBBBBB|BAAAAAAAA]
+  VPSRLD $2, Z8, Z12                                           // Z12 <- length data as [00000000|000CCCCCC|CC0BBBBBB|BBAAAAAAA]
+  VPTERNLOGD.BCST $TLOG_BLEND_AB, CONSTD_0x7F(), Z11, Z8       // Z8  <- length data as [00000000|00CCCCCCC|C0BBBBBBB|BAAAAAAAA]
+  VPTERNLOGD.BCST $TLOG_BLEND_AB, CONSTD_0x3FFF(), Z12, Z8     // Z8  <- length data as [00000000|000CCCCCC|CCBBBBBBB|BAAAAAAAA]
+  VPADDD.Z Z8, Z10, K1, Z12                                    // Z12 <- value length
+
+  VPTESTNMD Z2, Z2, K1, K2                                     // K2 <- lanes where there is nothing left to skip
+  VMOVDQA32 Z0, K2, Z14                                        // Z14 <- update element offset
+  VMOVDQA32 Z12, K2, Z15                                       // Z15 <- update element length
+  VMOVDQA32 Z9, K2, Z16                                        // Z16 <- update element Type|L
+  VMOVDQA32 Z10, K2, Z17                                       // Z17 <- update element header length
+  KORW K2, K6, K6                                              // K6 <- lanes where the element was found
+  KANDNW K1, K2, K1                                            // K1 <- lanes that still have elements to skip
+
+  VPADDD Z12, Z0, K1, Z0                                       // Z0 <- advance the list by the value length
+  VPSUBD Z13, Z2, K1, Z2                                       // Z2 <- decrement the number of elements to skip
+  VPCMPUD $VPCMP_IMM_LT, Z1, Z0, K1, K1                        // K1 <- remaining lanes to scan
+
+  KTESTW K1, K1
+  JNZ loop
+
+done:
+  BC_UNPACK_2xSLOT(0, OUT(DX), OUT(R8))
+  BC_STORE_VALUE_TO_SLOT(IN(Z14), IN(Z15), IN(Z16), IN(Z17), IN(DX))
+  BC_STORE_K_TO_SLOT(IN(K6), IN(R8))
+
+  NEXT_ADVANCE(BC_SLOT_SIZE*5)
+
+// Wildcard Path Instructions
+// --------------------------
+
+// Wildcard path instructions produce a new list from the
+// elements of an input list (or the values of an input
+// structure). The output of each lane is never larger than
+// its input, so we allocate the input length for each lane
+// and then copy the selected values scalar lane by lane.
+//
+// Spill area layout used by wildcard_tail:
+//
+//   - [  0: 64] - output offsets
+//   - [ 64:128] - input offsets
+//   - [128:192] - input lengths
+//   - [192:256] - output lengths
+//   - [256]     - advance
+//   - [264]     - mode (0=listfield, 1=listvalues, 2=listflatten, 3=structvalues)
+//   - [272]     - end of the input list of the current lane
+//   - [280]     - index of the current lane
+//   - [288]     - lanes remaining to be processed
+
+// s[0].k[1] = listfield(s[2], k[3], symbol[4])
+//
+// Extracts the field `symbol` from each struct in
+// the list s[2]; elements that are not structs or
+// that don't have the field are skipped.
+//
+// scratch: PageSize
+TEXT bclistfield(SB), NOSPLIT|NOFRAME, $0
+  BC_UNPACK_2xSLOT(BC_SLOT_SIZE*2, OUT(BX), OUT(R8))
+  BC_UNPACK_RU32(BC_SLOT_SIZE*4, OUT(R13))             // R13 <- encoded symbol to match
+  BC_CALC_ADVANCE(BC_SLOT_SIZE*4+4, OUT(R14))
+  XORL CX, CX
+  JMP wildcard_tail(SB)
+
+// s[0].k[1] = listvalues(s[2], k[3])
+//
+// Collects the values of each struct in the list s[2].
+//
+// scratch: PageSize
+TEXT bclistvalues(SB), NOSPLIT|NOFRAME, $0
+  BC_UNPACK_2xSLOT(BC_SLOT_SIZE*2, OUT(BX), OUT(R8))
+  BC_CALC_ADVANCE(BC_SLOT_SIZE*4, OUT(R14))
+  MOVL $1, CX
+  JMP wildcard_tail(SB)
+
+// s[0].k[1] = listflatten(s[2], k[3])
+//
+// Concatenates the elements of each list in the list s[2].
+//
+// scratch: PageSize
+TEXT bclistflatten(SB), NOSPLIT|NOFRAME, $0
+  BC_UNPACK_2xSLOT(BC_SLOT_SIZE*2, OUT(BX), OUT(R8))
+  BC_CALC_ADVANCE(BC_SLOT_SIZE*4, OUT(R14))
+  MOVL $2, CX
+  JMP wildcard_tail(SB)
+
+// s[0].k[1] = structvalues(b[2], k[3])
+//
+// Collects the values of the struct b[2] into a list.
+//
+// scratch: PageSize
+TEXT bcstructvalues(SB), NOSPLIT|NOFRAME, $0
+  BC_UNPACK_2xSLOT(BC_SLOT_SIZE*2, OUT(BX), OUT(R8))
+  BC_CALC_ADVANCE(BC_SLOT_SIZE*4, OUT(R14))
+  MOVL $3, CX
+  JMP wildcard_tail(SB)
+
+// Inputs:
+//   - BX  - input slice (or struct) slot
+//   - R8  - input predicate slot
+//   - R14 - instruction advance
+//   - CX  - mode
+//   - R13 - encoded symbol (listfield only)
+TEXT wildcard_tail(SB), NOSPLIT|NOFRAME, $0
+  BC_LOAD_SLICE_FROM_SLOT(OUT(Z0), OUT(Z1), IN(BX))
+  BC_LOAD_K1_FROM_SLOT(OUT(K1), IN(R8))
+  MOVQ R14, bytecode_spillArea+256(VIRT_BCPTR)         // [] <- save advance
+  MOVQ CX, bytecode_spillArea+264(VIRT_BCPTR)          // [] <- save mode
+
+  // R15 (DstSum), Z5 (DstOff), Z7 (DstLen), Z4 (DstEnd), K1 (DstMask)
+  BC_HORIZONTAL_LENGTH_SUM(OUT(R15), OUT(Z5), OUT(Z7), OUT(Z4), OUT(K1), IN(Z1), IN(K1), X9, K2)
+
+  BC_ALLOC_SLICE(OUT(Z2), IN(R15), BX, R8)             // Z2 <- Offset of the beginning of the allocated buffer
+  VPADDD.Z Z5, Z2, K1, Z2                              // Z2 <- Output offset of each lane
+  VPXORD X3, X3, X3                                    // Z3 <- Output length of each lane, initially zero
+  VPTESTMD Z7, Z7, K1, K2                              // K2 <- lanes having non-empty input
+
+  VMOVDQU32 Z2, bytecode_spillArea+0(VIRT_BCPTR)
+  VMOVDQU32 Z0, bytecode_spillArea+64(VIRT_BCPTR)
+  VMOVDQU32 Z7, bytecode_spillArea+128(VIRT_BCPTR)
+  VMOVDQU32 Z3, bytecode_spillArea+192(VIRT_BCPTR)
+
+  KMOVW K2, DX                                         // DX <- lanes to process
+  TESTL DX, DX
+  JZ done
+
+lane_iter:
+  TZCNTL DX, R14                                       // R14 <- index of the lane to process
+  BLSRL DX, DX                                         // DX <- clear the index of the iterator
+  MOVQ DX, bytecode_spillArea+288(VIRT_BCPTR)
+  MOVQ R14, bytecode_spillArea+280(VIRT_BCPTR)
+
+  MOVL bytecode_spillArea+64(VIRT_BCPTR)(R14*4), R8    // R8 <- input offset
+  MOVL bytecode_spillArea+128(VIRT_BCPTR)(R14*4), R11  // R11 <- input length
+  MOVL bytecode_spillArea+0(VIRT_BCPTR)(R14*4), R15    // R15 <- output offset
+  ADDQ VIRT_BASE, R8                                   // R8 <- input address
+  ADDQ VIRT_BASE, R15                                  // R15 <- output address
+  ADDQ R8, R11                                         // R11 <- end of the input
+
+  MOVQ bytecode_spillArea+264(VIRT_BCPTR), DX
+  CMPL DX, $3
+  JEQ field_iter                                       // structvalues: the input itself is a struct
+
+  MOVQ R11, bytecode_spillArea+272(VIRT_BCPTR)         // [] <- end of the input list
+
+elem_iter:
+  MOVQ bytecode_spillArea+272(VIRT_BCPTR), R11
+  CMPQ R8, R11
+  JCC lane_done                                        // end of the input list
+
+  CALL wildcardvalue(SB)                               // BX <- header length, CX <- value length, R14 <- type
+  LEAQ 0(R8)(CX*1), R11                                // R11 <- end of the element
+  CMPQ R11, bytecode_spillArea+272(VIRT_BCPTR)
+  JHI lane_done                                        // PARANOIA: the element crosses the end of the list
+
+  MOVQ bytecode_spillArea+264(VIRT_BCPTR), DX
+  CMPL DX, $2
+  JNE elem_struct
+
+  CMPL R14, $0x0B
+  JNE elem_skip
+  ADDQ BX, R8                                          // R8 <- list content
+  SUBL BX, CX                                          // CX <- list content length
+  CALL wildcardcopy(SB)                                // append the elements of the list
+  JMP elem_iter
+
+elem_struct:
+  CMPL R14, $0x0D
+  JNE elem_skip
+  ADDQ BX, R8                                          // R8 <- struct content, R11 <- end of the struct
+  JMP field_iter
+
+elem_skip:
+  MOVQ R11, R8
+  JMP elem_iter
+
+  // iterate over the fields of a struct ending at R11
+field_iter:
+  CMPQ R8, R11
+  JCC field_done
+
+  MOVL 0(R8), BX                                       // BX <- label bytes
+  MOVL BX, CX
+  ANDL $0x80808080, CX
+  TZCNTL CX, CX                                        // CX <- index of the last bit of the label
+  MOVL $2, DX
+  SHLXL CX, DX, DX
+  SUBL $1, DX                                          // DX <- label mask
+  ANDL DX, BX                                          // BX <- encoded label symbol
+  SHRL $3, CX
+  ADDL $1, CX                                          // CX <- label length
+  ADDQ CX, R8                                          // R8 <- field value
+
+  MOVQ bytecode_spillArea+264(VIRT_BCPTR), DX
+  TESTL DX, DX
+  JNZ field_copy                                       // listvalues, structvalues: take every value
+  CMPL BX, R13
+  JNE field_skip
+
+field_copy:
+  CALL wildcardvalue(SB)
+  LEAQ 0(R8)(CX*1), DX
+  CMPQ DX, R11
+  JHI field_done                                       // PARANOIA: the value crosses the end of the struct
+  CALL wildcardcopy(SB)
+
+  MOVQ bytecode_spillArea+264(VIRT_BCPTR), DX
+  TESTL DX, DX
+  JNZ field_iter
+  JMP field_done                                       // listfield: the first matching field wins
+
+field_skip:
+  CALL wildcardvalue(SB)
+  ADDQ CX, R8
+  JMP field_iter
+
+field_done:
+  MOVQ R11, R8                                         // R8 <- end of the struct
+  MOVQ bytecode_spillArea+264(VIRT_BCPTR), DX
+  CMPL DX, $3
+  JNE elem_iter
+
+lane_done:
+  MOVQ bytecode_spillArea+280(VIRT_BCPTR), R14
+  MOVL bytecode_spillArea+0(VIRT_BCPTR)(R14*4), CX
+  ADDQ VIRT_BASE, CX
+  SUBQ CX, R15                                         // R15 <- output length
+  MOVL R15, bytecode_spillArea+192(VIRT_BCPTR)(R14*4)
+
+  MOVQ bytecode_spillArea+288(VIRT_BCPTR), DX
+  TESTL DX, DX
+  JNZ lane_iter
+
+done:
+  VMOVDQU32 bytecode_spillArea+192(VIRT_BCPTR), Z3     // Z3 <- output lengths
+  BC_UNPACK_2xSLOT(0, OUT(DX), OUT(R8))
+  BC_STORE_SLICE_TO_SLOT(IN(Z2), IN(Z3), IN(DX))
+  BC_STORE_K_TO_SLOT(IN(K1), IN(R8))
+
+  MOVQ bytecode_spillArea+256(VIRT_BCPTR), R15
+  BC_ADVANCE_REG(R15)
+
+  _BC_ERROR_HANDLER_MORE_SCRATCH()
+
+// Decodes the header of the ion value at R8
+//
+// Outputs:
+//   - BX  - header length
+//   - CX  - value length (including header)
+//   - R14 - value type
+//
+// Clobbers DX.
+TEXT wildcardvalue(SB), NOSPLIT|NOFRAME, $0
+  MOVBLZX 0(R8), R14
+  MOVL R14, CX
+  ANDL $0x0F, CX                                       // CX <- L
+  SHRL $4, R14                                         // R14 <- T
+  MOVL $1, BX
+
+  CMPL R14, $1
+  JEQ no_content                                       // bool: L is the value
+  CMPL CX, $14
+  JLT done                                             // L is the content length
+  JNE no_content                                       // L == 15: null
+
+  XORL CX, CX
+varuint:
+  MOVBLZX 0(R8)(BX*1), DX
+  ADDL $1, BX
+  SHLL $7, CX
+  ORL DX, CX
+  TESTL $0x80, DX
+  JZ varuint
+  BTRL $7, CX                                          // CX <- content length
+
+done:
+  ADDL BX, CX
+  RET
+
+no_content:
+  XORL CX, CX
+  JMP done
+
+// Appends CX bytes at R8 to R15 and advances both
+//
+// Clobbers CX, DX, K3, Z4.
+TEXT wildcardcopy(SB), NOSPLIT|NOFRAME, $0
+  SUBL $64, CX
+  JCS tail
+
+loop:
+  VMOVDQU8 0(R8), Z4
+  ADDQ $64, R8
+  VMOVDQU8 Z4, 0(R15)
+  ADDQ $64, R15
+  SUBL $64, CX
+  JCC loop
+
+tail:
+  ADDL $64, CX
+  MOVQ $-1, DX
+  SHLQ CL, DX
+  NOTQ DX
+  KMOVQ DX, K3
+
+  VMOVDQU8.Z 0(R8), K3, Z4
+  VMOVDQU8 Z4, K3, 0(R15)
+  ADDQ CX, R8
+  ADDQ CX, R15
+  RET
+
+// Struct Manipulation Instructions
+// --------------------------------
+
+// objectmerge and objectdelete produce a new struct by merging
+// the fields of two input structs, which are ordered by symbol
+// ID, and optionally skipping the fields with a given symbol.
+// The output of each lane is never larger than the sum of its
+// inputs plus the header of the output struct, so we allocate
+// that much for each lane and then merge the fields scalar lane
+// by lane. The content of each output struct is written 4 bytes
+// past the beginning of its allocation and the header is written
+// right before it once the length of the content is known.
+//
+// Spill area layout used by objectmerge_tail:
+//
+//   - [  0: 64] - output offsets
+//   - [ 64:128] - offsets of the first struct
+//   - [128:192] - lengths of the first struct
+//   - [192:256] - offsets of the second struct
+//   - [256:320] - lengths of the second struct
+//   - [320:384] - output lengths
+//   - [384]     - advance
+//   - [392]     - symbol ID of the fields to skip (or -1)
+//   - [400]     - end of the second struct of the current lane
+//   - [408]     - index of the current lane
+//   - [416]     - lanes remaining to be processed
+//   - [424]     - symbol ID of the current field of the first struct
+//   - [432:448] - output Type|L bytes
+//   - [448:464] - output header lengths
+
+// v[0].k[1] = objectmerge(b[2], b[3], k[4])
+//
+// Merges the fields of the structs b[2] and b[3];
+// when both have a field with the same symbol,
+// the field of b[3] is used.
+//
+// scratch: PageSize
+TEXT bcobjectmerge(SB), NOSPLIT|NOFRAME, $0
+  BC_UNPACK_3xSLOT(BC_SLOT_SIZE*2, OUT(BX), OUT(CX), OUT(R8))
+  BC_LOAD_SLICE_FROM_SLOT(OUT(Z0), OUT(Z1), IN(BX))
+  BC_LOAD_SLICE_FROM_SLOT(OUT(Z2), OUT(Z3), IN(CX))
+  BC_LOAD_K1_FROM_SLOT(OUT(K1), IN(R8))
+  MOVQ $-1, R13                                        // R13 <- no fields are skipped
+  BC_CALC_ADVANCE(BC_SLOT_SIZE*5, OUT(R14))
+  JMP objectmerge_tail(SB)
+
+// v[0].k[1] = objectdelete(b[2], k[3], symbol[4])
+//
+// Copies the struct b[2] without the field `symbol`.
+//
+// scratch: PageSize
+TEXT bcobjectdelete(SB), NOSPLIT|NOFRAME, $0
+  BC_UNPACK_2xSLOT(BC_SLOT_SIZE*2, OUT(BX), OUT(R8))
+  BC_UNPACK_RU32(BC_SLOT_SIZE*4, OUT(R13))             // R13 <- encoded symbol to skip
+  BC_LOAD_SLICE_FROM_SLOT(OUT(Z0), OUT(Z1), IN(BX))
+  BC_LOAD_K1_FROM_SLOT(OUT(K1), IN(R8))
+  VPXORD X2, X2, X2                                    // Z2 <- the second struct is empty
+  VPXORD X3, X3, X3
+
+  XORL CX, CX
+decode_symbol:
+  MOVBLZX R13B, DX
+  SHRL $8, R13
+  SHLL $7, CX
+  BTRL $7, DX                                          // CF <- set on the last byte
+  LEAL 0(CX)(DX*1), CX
+  JCC decode_symbol
+  MOVL CX, R13                                         // R13 <- symbol ID to skip
+
+  BC_CALC_ADVANCE(BC_SLOT_SIZE*4+4, OUT(R14))
+  JMP objectmerge_tail(SB)
+
+// Inputs:
+//   - Z0:Z1 - the first struct
+//   - Z2:Z3 - the second struct
+//   - K1    - predicate
+//   - R13   - symbol ID of the fields to skip
+//   - R14   - instruction advance
+TEXT objectmerge_tail(SB), NOSPLIT|NOFRAME, $0
+  MOVQ R14, bytecode_spillArea+384(VIRT_BCPTR)         // [] <- save advance
+  MOVQ R13, bytecode_spillArea+392(VIRT_BCPTR)         // [] <- save the symbol to skip
+
+  VPADDD Z1, Z3, Z4
+  VPADDD.BCST CONSTD_4(), Z4, Z4                       // Z4 <- output size of each lane
+
+  // R15 (DstSum), Z5 (DstOff), Z7 (DstLen), Z6 (DstEnd), K1 (DstMask)
+  BC_HORIZONTAL_LENGTH_SUM(OUT(R15), OUT(Z5), OUT(Z7), OUT(Z6), OUT(K1), IN(Z4), IN(K1), X9, K2)
+
+  BC_ALLOC_SLICE(OUT(Z8), IN(R15), BX, R8)             // Z8 <- Offset of the beginning of the allocated buffer
+  VPADDD.Z Z5, Z8, K1, Z8                              // Z8 <- Output offset of each lane
+
+  VMOVDQU32 Z8, bytecode_spillArea+0(VIRT_BCPTR)
+  VMOVDQU32 Z0, bytecode_spillArea+64(VIRT_BCPTR)
+  VMOVDQU32 Z1, bytecode_spillArea+128(VIRT_BCPTR)
+  VMOVDQU32 Z2, bytecode_spillArea+192(VIRT_BCPTR)
+  VMOVDQU32 Z3, bytecode_spillArea+256(VIRT_BCPTR)
+
+  KMOVW K1, DX                                         // DX <- lanes to process
+  TESTL DX, DX
+  JZ done
+
+lane_iter:
+  TZCNTL DX, R14                                       // R14 <- index of the lane to process
+  BLSRL DX, DX                                         // DX <- clear the index of the iterator
+  MOVQ DX, bytecode_spillArea+416(VIRT_BCPTR)
+  MOVQ R14, bytecode_spillArea+408(VIRT_BCPTR)
+
+  MOVL bytecode_spillArea+64(VIRT_BCPTR)(R14*4), R8    // R8 <- first struct offset
+  MOVL bytecode_spillArea+128(VIRT_BCPTR)(R14*4), R11  // R11 <- first struct length
+  ADDQ VIRT_BASE, R8                                   // R8 <- first struct address
+  ADDQ R8, R11                                         // R11 <- end of the first struct
+
+  MOVL bytecode_spillArea+192(VIRT_BCPTR)(R14*4), R13  // R13 <- second struct offset
+  MOVL bytecode_spillArea+256(VIRT_BCPTR)(R14*4), CX   // CX <- second struct length
+  ADDQ VIRT_BASE, R13                                  // R13 <- second struct address
+  ADDQ R13, CX
+  MOVQ CX, bytecode_spillArea+400(VIRT_BCPTR)          // [] <- end of the second struct
+
+  MOVL bytecode_spillArea+0(VIRT_BCPTR)(R14*4), R15
+  LEAQ 4(VIRT_BASE)(R15*1), R15                        // R15 <- output content address
+
+field_iter:
+  MOVL $-1, BX
+  CMPQ R8, R11
+  JCC a_done
+  CALL objectfield(SB)                                 // BX <- symbol ID of the first struct field
+a_done:
+  MOVQ BX, bytecode_spillArea+424(VIRT_BCPTR)
+
+  MOVL $-1, BX
+  CMPQ R13, bytecode_spillArea+400(VIRT_BCPTR)
+  JCC b_done
+  XCHGQ R8, R13
+  CALL objectfield(SB)                                 // BX <- symbol ID of the second struct field
+  XCHGQ R8, R13
+b_done:
+  MOVQ bytecode_spillArea+424(VIRT_BCPTR), DX
+  CMPL DX, BX
+  JCS copy_a                                           // the field of the first struct comes first
+  JNE copy_b                                           // the field of the second struct comes first
+  CMPL BX, $-1
+  JEQ lane_done                                        // both structs are exhausted
+
+  CALL objectfield(SB)                                 // the same symbol: skip the field of the first struct
+  ADDQ CX, R8
+
+copy_b:
+  XCHGQ R8, R13
+  CALL objectfield(SB)                                 // CX <- field length
+  LEAQ 0(R8)(CX*1), DX
+  CMPQ DX, bytecode_spillArea+400(VIRT_BCPTR)
+  JHI b_truncated                                      // PARANOIA: the field crosses the end of the struct
+  CALL wildcardcopy(SB)
+  XCHGQ R8, R13
+  JMP field_iter
+
+b_truncated:
+  MOVQ bytecode_spillArea+400(VIRT_BCPTR), R8
+  XCHGQ R8, R13
+  JMP field_iter
+
+copy_a:
+  CALL objectfield(SB)                                 // BX <- symbol ID, CX <- field length
+  LEAQ 0(R8)(CX*1), DX
+  CMPQ DX, R11
+  JHI a_truncated                                      // PARANOIA: the field crosses the end of the struct
+  CMPL BX, bytecode_spillArea+392(VIRT_BCPTR)
+  JEQ skip_a
+  CALL wildcardcopy(SB)
+  JMP field_iter
+
+skip_a:
+  MOVQ DX, R8
+  JMP field_iter
+
+a_truncated:
+  MOVQ R11, R8
+  JMP field_iter
+
+lane_done:
+  MOVQ bytecode_spillArea+408(VIRT_BCPTR), R14
+  MOVL bytecode_spillArea+0(VIRT_BCPTR)(R14*4), CX
+  LEAQ 4(VIRT_BASE)(CX*1), CX                          // CX <- output content address
+  SUBQ CX, R15                                         // R15 <- output content length
+  LEAQ -1(CX), R11                                     // R11 <- address of the last header byte
+
+  CMPL R15, $14
+  JCC long_header
+
+  MOVL R15, BX
+  ORL $0xD0, BX
+  MOVB BX, 0(R11)                                      // Type|L with L being the content length
+  JMP header_done
+
+long_header:
+  MOVL R15, BX
+  MOVL BX, R8
+  ANDL $0x7F, R8
+  ORL $0x80, R8
+  MOVB R8, 0(R11)                                      // last byte of the varuint length
+  SHRL $7, BX
+
+varuint_loop:
+  TESTL BX, BX
+  JZ varuint_done
+  MOVL BX, R8
+  ANDL $0x7F, R8
+  DECQ R11
+  MOVB R8, 0(R11)
+  SHRL $7, BX
+  JMP varuint_loop
+
+varuint_done:
+  DECQ R11
+  MOVB $0xDE, 0(R11)                                   // Type|L with L == 14
+
+header_done:
+  MOVBLZX 0(R11), BX
+  MOVB BX, bytecode_spillArea+432(VIRT_BCPTR)(R14*1)   // [] <- Type|L
+  SUBQ R11, CX                                         // CX <- header length
+  MOVB CX, bytecode_spillArea+448(VIRT_BCPTR)(R14*1)   // [] <- header length
+  ADDQ CX, R15                                         // R15 <- output length
+  MOVL R15, bytecode_spillArea+320(VIRT_BCPTR)(R14*4)
+  SUBQ VIRT_BASE, R11
+  MOVL R11, bytecode_spillArea+0(VIRT_BCPTR)(R14*4)    // [] <- output offset
+
+  MOVQ bytecode_spillArea+416(VIRT_BCPTR), DX
+  TESTL DX, DX
+  JNZ lane_iter
+
+done:
+  VMOVDQU32.Z bytecode_spillArea+0(VIRT_BCPTR), K1, Z8     // Z8 <- output offsets
+  VMOVDQU32.Z bytecode_spillArea+320(VIRT_BCPTR), K1, Z9   // Z9 <- output lengths
+  VPMOVZXBD.Z bytecode_spillArea+432(VIRT_BCPTR), K1, Z10  // Z10 <- output Type|L
+  VPMOVZXBD.Z bytecode_spillArea+448(VIRT_BCPTR), K1, Z11  // Z11 <- output header lengths
+
+  BC_UNPACK_2xSLOT(0, OUT(DX), OUT(R8))
+  BC_STORE_VALUE_TO_SLOT(IN(Z8), IN(Z9), IN(Z10), IN(Z11), IN(DX))
+  BC_STORE_K_TO_SLOT(IN(K1), IN(R8))
+
+  MOVQ bytecode_spillArea+384(VIRT_BCPTR), R15
+  BC_ADVANCE_REG(R15)
+
+  _BC_ERROR_HANDLER_MORE_SCRATCH()
+
+// Decodes the struct field at R8
+//
+// Outputs:
+//   - BX - symbol ID of the field
+//   - CX - field length (label and value)
+//
+// Clobbers DX, R14.
+TEXT objectfield(SB), NOSPLIT|NOFRAME, $0
+  MOVQ R8, R14
+  XORL BX, BX
+
+label:
+  MOVBLZX 0(R14), DX
+  ADDQ $1, R14
+  SHLL $7, BX
+  BTRL $7, DX                                          // CF <- set on the last byte
+  LEAL 0(BX)(DX*1), BX
+  JCC label                                            // BX <- symbol ID when done
+
+  MOVBLZX 0(R14), CX
+  ADDQ $1, R14
+  MOVL CX, DX
+  SHRL $4, DX                                          // DX <- T
+  ANDL $0x0F, CX                                       // CX <- L
+
+  CMPL DX, $1
+  JEQ no_content                                       // bool: L is the value
+  CMPL CX, $14
+  JLT done                                             // L is the content length
+  JNE no_content                                       // L == 15: null
+
+  XORL CX, CX
+varuint:
+  MOVBLZX 0(R14), DX
+  ADDQ $1, R14
+  SHLL $7, CX
+  BTRL $7, DX                                          // CF <- set on the last byte
+  LEAL 0(CX)(DX*1), CX
+  JCC varuint                                          // CX <- content length when done
+
+done:
+  ADDQ R14, CX
+  SUBQ R8, CX                                          // CX <- field length
+  RET
+
+no_content:
+  XORL CX, CX
+  JMP done
+
 // String Instructions
 // -------------------
 
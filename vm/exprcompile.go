@@ -51,13 +51,17 @@ func (v *value) ret() ssatype {
 // primary yields the primary return type of v
 // (if a value returns more than one type,
 // primary returns the type minus the mask
-// and list components)
+// and list components, unless the value
+// is only a list)
 func (v *value) primary() ssatype {
 	r := v.ret()
 	if r == stBool {
 		return r
 	}
-	r &^= stBool | stList
+	r &^= stBool
+	if r != stList {
+		r &^= stList
+	}
 	return r
 }
 
@@ -129,13 +133,28 @@ func compile(p *prog, e expr.Node) (*value, error) {
 	case *expr.StringMatch:
 		switch n.Op {
 		case expr.Like, expr.Ilike:
-			left, err := p.compileAsString(n.Expr)
-			if err != nil {
-				return nil, err
-			}
 			// NOTE: StringMatch.check checks if n.Escape has valid content
 			escRune, _ := utf8.DecodeRuneInString(n.Escape)
 			caseSensitive := n.Op == expr.Like
+			v, err := compile(p, n.Expr)
+			if err != nil {
+				return nil, err
+			}
+			if v.op != sliteral && v.primary() == stValue && maysymbol(v) {
+				// symbols are matched using the results computed
+				// once per symbol table rather than once per row;
+				// only the lanes that hold strings are matched directly
+				str := p.ssa2(stostr, v, p.mask(v))
+				inner := p.or(p.like(str, n.Pattern, escRune, caseSensitive),
+					p.likeSymbols(v, n.Pattern, escRune, caseSensitive))
+				ret := p.ssa1(snotmissing, inner)
+				ret.notMissing = p.or(p.mask(str), p.mask(p.checkTag(v, expr.SymbolType)))
+				return ret, nil
+			}
+			left, err := p.asString(v, n.Expr)
+			if err != nil {
+				return nil, err
+			}
 			inner := p.like(left, n.Pattern, escRune, caseSensitive)
 			// the bool-typed result is just the opcode mask
 			ret := p.ssa1(snotmissing, inner)
@@ -254,15 +273,40 @@ func compile(p *prog, e expr.Node) (*value, error) {
 		if err != nil {
 			return nil, err
 		}
+		if expr.HasWildcard(n.Inner) {
+			return p.listField(inner, n.Field), nil
+		}
 		return p.dot(n.Field, inner), nil
 	case expr.Ident:
 		return p.dot(string(n), p.validLanes()), nil
 	case *expr.Index:
+		if expr.HasWildcard(n.Inner) {
+			return nil, fmt.Errorf("cannot index the result of wildcard path %q", expr.ToString(n.Inner))
+		}
 		inner, err := compile(p, n.Inner)
 		if err != nil {
 			return nil, err
 		}
+		if n.Offset < 0 {
+			return p.arrayElement(inner, p.constant(int64(n.Offset))), nil
+		}
 		return p.index(inner, n.Offset), nil
+	case *expr.Wildcard:
+		inner, err := compile(p, n.Inner)
+		if err != nil {
+			return nil, err
+		}
+		if expr.HasWildcard(n.Inner) {
+			// apply the step to each element
+			if n.Struct {
+				return p.listValues(inner), nil
+			}
+			return p.listFlatten(inner), nil
+		}
+		if n.Struct {
+			return p.structValues(inner), nil
+		}
+		return p.tolist(inner), nil
 	case *expr.IsKey:
 		inner, err := compile(p, n.Expr)
 		if err != nil {
@@ -360,6 +404,19 @@ func (p *prog) compileAsBool(e expr.Node) (*value, error) {
 	return v, nil
 }
 
+// compileAggregateBool compiles the argument of
+// BOOL_AND or BOOL_OR; unlike compileAsBool, path
+// expressions are left boxed so that lanes holding
+// NULL or non-boolean values are excluded from the
+// aggregate rather than being treated as FALSE
+func (p *prog) compileAggregateBool(e expr.Node) (*value, error) {
+	switch e.(type) {
+	case *expr.Member, *expr.Index, *expr.Dot, expr.Ident:
+		return compile(p, e)
+	}
+	return p.compileAsBool(e)
+}
+
 func (p *prog) compileAsNumber(e expr.Node) (*value, error) {
 	if c, ok := e.(*expr.Case); ok {
 		return p.compileNumericCase(c)
@@ -405,6 +462,12 @@ func (p *prog) compileAsString(e expr.Node) (*value, error) {
 	if err != nil {
 		return nil, err
 	}
+	return p.asString(v, e)
+}
+
+// asString converts v, the result of compiling e,
+// into a string
+func (p *prog) asString(v *value, e expr.Node) (*value, error) {
 	if v.op == sliteral {
 		return v, nil
 	}
@@ -704,6 +767,17 @@ func compilefuncaux(p *prog, b *expr.Builtin, args []expr.Node) (*value, error)
 		}
 		return p.concat(sargs...), nil
 
+	case expr.ConcatWS:
+		sargs := make([]*value, len(args))
+		for i := range args {
+			sarg, err := p.compileAsString(args[i])
+			if err != nil {
+				return nil, err
+			}
+			sargs[i] = sarg
+		}
+		return p.concatWS(sargs[0], sargs[1:]...), nil
+
 	case expr.Least, expr.Greatest:
 		least := fn == expr.Least
 		count := len(args)
@@ -959,6 +1033,49 @@ func compilefuncaux(p *prog, b *expr.Builtin, args []expr.Node) (*value, error)
 		}
 		return p.arrayPosition(v[0], v[1]), nil
 
+	case expr.ObjectSet:
+		key, ok := args[1].(expr.String)
+		if !ok {
+			return nil, fmt.Errorf("%s key must be a string", fn)
+		}
+		v, err := compile(p, args[0])
+		if err != nil {
+			return nil, err
+		}
+		val, err := p.serialized(args[2])
+		if err != nil {
+			return nil, err
+		}
+		return p.objectSet(v, string(key), p.unsymbolized(val)), nil
+
+	case expr.ObjectDelete:
+		key, ok := args[1].(expr.String)
+		if !ok {
+			return nil, fmt.Errorf("%s key must be a string", fn)
+		}
+		v, err := compile(p, args[0])
+		if err != nil {
+			return nil, err
+		}
+		return p.objectDelete(v, string(key)), nil
+
+	case expr.ObjectMerge:
+		v, err := compileargs(p, args, compileExpression, compileExpression)
+		if err != nil {
+			return nil, err
+		}
+		return p.objectMerge(v[0], v[1]), nil
+
+	case expr.ArrayElement:
+		if expr.HasWildcard(args[0]) {
+			return nil, fmt.Errorf("cannot index the result of wildcard path %q", expr.ToString(args[0]))
+		}
+		v, err := compileargs(p, args, compileExpression, compileNumber)
+		if err != nil {
+			return nil, err
+		}
+		return p.arrayElement(v[0], v[1]), nil
+
 	case expr.Lower, expr.Upper:
 		vals, err := compileargs(p, args, compileString)
 		if err != nil {
@@ -25,6 +25,7 @@ import (
 // of QuerySink that applies a filter to
 // incoming rows.
 type Filter struct {
+	expr expr.Node
 	prog *prog
 	rest QuerySink // rest of sub-query
 }
@@ -37,13 +38,17 @@ func NewFilter(e expr.Node, rest QuerySink) (*Filter, error) {
 	if err != nil {
 		return nil, err
 	}
-	return where(prog, rest), nil
+	f := where(prog, rest)
+	f.expr = e
+	return f, nil
 }
 
 func where(p *prog, rest QuerySink) *Filter {
 	return &Filter{prog: p, rest: rest}
 }
 
+func (r *Filter) redacted() string { return expr.ToRedacted(r.expr) }
+
 // Open implements QuerySink.Open
 func (r *Filter) Open() (io.WriteCloser, error) {
 	q, err := r.rest.Open()
@@ -116,6 +121,7 @@ type wherebc struct {
 	dst         rowConsumer
 	params      rowParams
 	constResult int // indicates the result of compiled program
+	zion        zionRows
 }
 
 //go:noescape
@@ -175,7 +181,11 @@ func (w *wherebc) writeRows(delims []vmref, rp *rowParams) error {
 	}
 
 	w.bc.prepare(rp)
+	wd := watch("filter", w.parent, &w.ssa, &w.bc)
 	valid := evalfilterbc(&w.bc, delims)
+	if err := wd.done(); err != nil {
+		return err
+	}
 	if w.bc.err != 0 {
 		return bytecodeerror("filter", &w.bc)
 	}
@@ -192,5 +202,14 @@ func (w *wherebc) writeRows(delims []vmref, rp *rowParams) error {
 
 func (w *wherebc) Close() error {
 	w.bc.reset()
+	w.zion.free()
 	return w.dst.Close()
 }
+
+var _ zionConsumer = &wherebc{}
+
+func (w *wherebc) zionOk() bool { return true }
+
+func (w *wherebc) writeZion(state *zionState) error {
+	return w.zion.write(state, w)
+}
@@ -65,6 +65,12 @@ type window struct {
 	fn         windowFunc
 	final      []uint // actual final results
 	result     string
+
+	// pfn is set instead of fn for window
+	// functions evaluated over entire partitions;
+	// values holds the ion-encoded result for each pair
+	pfn    partitionFunc
+	values [][]byte
 }
 
 // run computes the results of applying the window function
@@ -102,7 +108,13 @@ func (w *window) run(agt *aggtable) {
 		dir := cmp(i, j)
 		return dir < 0
 	})
-	// walk pairs in order
+	if w.pfn != nil {
+		w.values = w.runPartitions(agt, order, cmp, partcmp)
+		return
+	}
+	// walk pairs in order; as with RANGE framing,
+	// adjacent pairs whose ORDER BY values compare
+	// equal are peers and receive the same rank
 	repeat := false
 	for i := range order {
 		repeat = i > 0 && cmp(order[i-1], order[i]) == 0
@@ -131,21 +143,54 @@ func (h *HashAggregate) groupFn(n int, ordering SortOrdering) aggOrderFn {
 }
 
 func (h *HashAggregate) aggFn(n int, ordering SortOrdering) aggOrderFn {
+	// the final values are compared in their ion
+	// representation so that NULL results and mixed
+	// numeric types are ordered the same way as
+	// they are by an ordinary ORDER BY; the values
+	// are materialized once per aggtable
+	var cached *aggtable
+	var values [][]byte
 	return func(agt *aggtable, i, j int) int {
-		aggregateFn := h.aggregateOps[n].fn
-		lmem := agt.valueof(&agt.pairs[i])
-		rmem := agt.valueof(&agt.pairs[j])
-		dir := aggcmp(aggregateFn, lmem, rmem)
-		if ordering.Direction == SortDescending {
-			return -dir
+		if agt != cached {
+			values = h.aggValues(agt, n)
+			cached = agt
 		}
-		return dir
+		return ordering.Compare(values[i], values[j])
+	}
+}
+
+// aggValues returns the ion-encoded
+// final value of the n'th aggregate
+// for each of the pairs in agt
+func (h *HashAggregate) aggValues(agt *aggtable, n int) [][]byte {
+	off := 0
+	for _, op := range h.aggregateOps[:n] {
+		off += op.dataSize()
+	}
+	var buf ion.Buffer
+	ends := make([]int, len(agt.pairs))
+	for i := range agt.pairs {
+		writeAggregatedValue(&buf, agt.valueof(&agt.pairs[i])[off:], h.aggregateOps[n])
+		ends[i] = buf.Size()
+	}
+	mem := buf.Bytes()
+	ret := make([][]byte, len(ends))
+	start := 0
+	for i, end := range ends {
+		ret[i] = mem[start:end]
+		start = end
 	}
+	return ret
 }
 
 func (h *HashAggregate) windowOrder(n int, ordering SortOrdering) aggOrderFn {
+	if h.windows[n].pfn != nil {
+		return func(agt *aggtable, i, j int) int {
+			return ordering.Compare(h.windows[n].values[i], h.windows[n].values[j])
+		}
+	}
 	return func(agt *aggtable, i, j int) int {
-		return int(h.windows[n].final[i]) - int(h.windows[n].final[j])
+		return int(ordering.Direction) * (int(h.windows[n].final[i]) - int(h.windows[n].final[j]))
 	}
 }
 
@@ -161,11 +206,7 @@ func (h *HashAggregate) OrderByAggregate(n int, ordering SortOrdering) error {
 	if n < 0 || n >= len(h.agg) {
 		return fmt.Errorf("aggregate %d doesn't exist", n)
 	}
-	o := SortOrdering{
-		Direction:  ordering.Direction,
-		NullsOrder: SortNullsFirst,
-	}
-	h.order = append(h.order, h.aggFn(n, o))
+	h.order = append(h.order, h.aggFn(n, ordering))
 	return nil
 }
 
@@ -292,7 +333,7 @@ func NewHashAggregate(agg, windows Aggregation, by Selection, dst QuerySink) (*H
 			}
 
 		case expr.OpBoolAnd, expr.OpBoolOr:
-			argv, err := prog.compileAsBool(h.agg[i].Expr.Inner)
+			argv, err := prog.compileAggregateBool(h.agg[i].Expr.Inner)
 			if err != nil {
 				return nil, fmt.Errorf("don't know how to aggregate %q: %w", h.agg[i].Expr.Inner, err)
 			}
@@ -385,6 +426,10 @@ func NewHashAggregate(agg, windows Aggregation, by Selection, dst QuerySink) (*H
 	return h, nil
 }
 
+func (h *HashAggregate) redacted() string {
+	return h.agg.redacted() + " GROUP BY " + h.by.redacted()
+}
+
 func (h *HashAggregate) Open() (io.WriteCloser, error) {
 	at := &aggtable{
 		parent:       h,
@@ -510,6 +555,10 @@ func (h *HashAggregate) Close() error {
 		}
 		for j, sym := range windowsyms {
 			outbuf.BeginField(sym)
+			if h.windows[j].pfn != nil {
+				outbuf.UnsafeAppend(h.windows[j].values[n])
+				continue
+			}
 			outbuf.WriteUint(uint64(h.windows[j].final[n]))
 		}
 		outbuf.EndStruct()
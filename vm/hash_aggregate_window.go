@@ -18,6 +18,7 @@ import (
 	"fmt"
 
 	"github.com/SnellerInc/sneller/expr"
+	"github.com/SnellerInc/sneller/ion"
 )
 
 type windowFunc interface {
@@ -57,6 +58,30 @@ func (h *HashAggregate) compileWindows(windowed Aggregation, by Selection) error
 		}
 		return nil, fmt.Errorf("unexpected expression %s in window function", expr.ToString(e))
 	}
+	// matchArg returns a function that yields the
+	// ion representation of e for a given pair
+	matchArg := func(e expr.Node) (argFunc, bool) {
+		for i := range h.agg {
+			if e == expr.Ident(h.agg[i].Result) ||
+				h.agg[i].Expr.Equals(e) {
+				var cached *aggtable
+				var values [][]byte
+				return func(agt *aggtable, n int) []byte {
+					if agt != cached {
+						values = h.aggValues(agt, i)
+						cached = agt
+					}
+					return values[n]
+				}, true
+			}
+		}
+		if grp, ok := pickGroup(e); ok {
+			return func(agt *aggtable, n int) []byte {
+				return agt.repridx(&agt.pairs[n], grp)
+			}, true
+		}
+		return nil, false
+	}
 
 	for i := range windowed {
 		var order []aggOrderFn
@@ -64,9 +89,14 @@ func (h *HashAggregate) compileWindows(windowed Aggregation, by Selection) error
 		if wind == nil {
 			return fmt.Errorf("%s missing OVER", expr.ToString(windowed[i].Expr))
 		}
+		var pfn partitionFunc
 		wfn, ok := getWindowFunc(windowed[i].Expr.Op)
 		if !ok {
-			return fmt.Errorf("no support for window function %s", expr.ToString(windowed[i].Expr))
+			var err error
+			pfn, err = getPartitionFunc(windowed[i].Expr, matchArg)
+			if err != nil {
+				return err
+			}
 		}
 		for j := range wind.PartitionBy {
 			fn, err := pickOrder(wind.PartitionBy[j], defaultSortOrdering)
@@ -96,6 +126,7 @@ func (h *HashAggregate) compileWindows(windowed Aggregation, by Selection) error
 			order:      order,
 			result:     windowed[i].Result,
 			fn:         wfn,
+			pfn:        pfn,
 			partitions: len(wind.PartitionBy),
 		})
 	}
@@ -152,3 +183,356 @@ func getWindowFunc(op expr.AggregateOp) (windowFunc, bool) {
 		return nil, false
 	}
 }
+
+// partitionFunc is a window function that is evaluated
+// over the sorted pairs of an entire partition,
+// which is necessary when the result for a pair depends
+// on the size of the partition or on the peers that follow it
+type partitionFunc interface {
+	// eval writes the result for part[k] into dst;
+	// part holds the indices of the pairs in the partition
+	// in sorted order and peers[k] is the position in part
+	// just past the last peer of part[k]
+	eval(agt *aggtable, part, peers []int, k int, dst *ion.Buffer)
+}
+
+// isFirstPeer returns whether part[k] is the
+// first of its peers given peers (see partitionFunc.eval)
+func isFirstPeer(peers []int, k int) bool {
+	return k == 0 || peers[k-1] != peers[k]
+}
+
+// runPartitions computes the results of w.pfn
+// for all pairs given the sorted order of pairs
+// and returns the ion-encoded result for each pair
+func (w *window) runPartitions(agt *aggtable, order []int, cmp, partcmp func(i, j int) int) [][]byte {
+	// compute the end of each peer group;
+	// peer groups never cross partitions, since
+	// cmp includes the partition columns
+	peers := make([]int, len(order))
+	for i := len(order) - 1; i >= 0; i-- {
+		if i == len(order)-1 || cmp(order[i], order[i+1]) != 0 {
+			peers[i] = i + 1
+		} else {
+			peers[i] = peers[i+1]
+		}
+	}
+	var buf ion.Buffer
+	starts := make([]int, len(order))
+	for start := 0; start < len(order); {
+		end := start + 1
+		for end < len(order) && partcmp(order[end-1], order[end]) == 0 {
+			end++
+		}
+		part := order[start:end]
+		rel := peers[start:end]
+		for k := range rel {
+			rel[k] -= start
+		}
+		for k := range part {
+			starts[part[k]] = buf.Size()
+			w.pfn.eval(agt, part, rel, k, &buf)
+		}
+		start = end
+	}
+	mem := buf.Bytes()
+	ret := make([][]byte, len(order))
+	for i, start := range starts {
+		ret[i] = mem[start : start+ion.SizeOf(mem[start:])]
+	}
+	return ret
+}
+
+// argFunc yields the ion representation
+// of an argument for the i'th pair
+type argFunc func(agt *aggtable, i int) []byte
+
+func getPartitionFunc(agg *expr.Aggregate, matchArg func(expr.Node) (argFunc, bool)) (partitionFunc, error) {
+	switch agg.Op {
+	case expr.OpNtile:
+		n, ok := agg.Inner.(expr.Integer)
+		if !ok || n <= 0 {
+			return nil, fmt.Errorf("%s: NTILE requires a positive integer constant", expr.ToString(agg))
+		}
+		return &ntile{n: uint(n)}, nil
+	case expr.OpPercentRank:
+		return &percentRank{}, nil
+	case expr.OpCumeDist:
+		return cumeDist{}, nil
+	}
+	if !agg.Op.Cumulative() {
+		return nil, fmt.Errorf("no support for window function %s", expr.ToString(agg))
+	}
+	c := &cumulative{op: agg.Op}
+	if _, ok := agg.Inner.(expr.Star); !ok {
+		fn, ok := matchArg(agg.Inner)
+		if !ok {
+			return nil, fmt.Errorf("unexpected expression %s in window function", expr.ToString(agg.Inner))
+		}
+		c.arg = fn
+	}
+	if agg.Filter != nil {
+		f, err := newWindowFilter(agg.Filter, matchArg)
+		if err != nil {
+			return nil, err
+		}
+		c.filter = f
+	}
+	return c, nil
+}
+
+// windowFilter evaluates the FILTER clause
+// of a window function for each pair by
+// substituting the aggregates and grouping
+// columns it references with their values
+// and constant-folding the result
+type windowFilter struct {
+	cond  expr.Node
+	terms []expr.Node
+	args  []argFunc
+
+	// the pair being evaluated
+	agt *aggtable
+	n   int
+	st  ion.Symtab
+}
+
+func newWindowFilter(cond expr.Node, matchArg func(expr.Node) (argFunc, bool)) (*windowFilter, error) {
+	f := &windowFilter{cond: cond}
+	var err error
+	visit := expr.WalkFunc(func(e expr.Node) bool {
+		if err != nil {
+			return false
+		}
+		if fn, ok := matchArg(e); ok {
+			f.terms = append(f.terms, e)
+			f.args = append(f.args, fn)
+			return false
+		}
+		switch e.(type) {
+		case *expr.Aggregate, expr.Ident:
+			err = fmt.Errorf("unexpected expression %s in window FILTER", expr.ToString(e))
+			return false
+		}
+		return true
+	})
+	expr.Walk(visit, cond)
+	if err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (f *windowFilter) term(e expr.Node) int {
+	for i := range f.terms {
+		if f.terms[i].Equals(e) {
+			return i
+		}
+	}
+	return -1
+}
+
+func (f *windowFilter) Walk(e expr.Node) expr.Rewriter {
+	if f.term(e) >= 0 {
+		return nil
+	}
+	return f
+}
+
+func (f *windowFilter) Rewrite(e expr.Node) expr.Node {
+	i := f.term(e)
+	if i < 0 {
+		return e
+	}
+	d, _, err := ion.ReadDatum(&f.st, f.args[i](f.agt, f.n))
+	if err != nil {
+		return expr.Missing{}
+	}
+	c, ok := expr.AsConstant(d)
+	if !ok {
+		return expr.Missing{}
+	}
+	return c
+}
+
+// match returns whether the FILTER
+// clause is true for the n'th pair
+func (f *windowFilter) match(agt *aggtable, n int) bool {
+	f.agt, f.n = agt, n
+	ret := expr.Simplify(expr.Rewrite(f, expr.Copy(f.cond)), expr.NoHint)
+	return ret == expr.Bool(true)
+}
+
+// ntile divides each partition into n buckets
+// whose sizes differ by at most one, with the
+// larger buckets first, and produces the 1-based
+// bucket number of each row
+type ntile struct {
+	n uint
+}
+
+func (t *ntile) eval(_ *aggtable, part, _ []int, k int, dst *ion.Buffer) {
+	size, row := uint(len(part)), uint(k)
+	q, rem := size/t.n, size%t.n
+	// the first rem buckets have q+1 rows
+	var bucket uint
+	if row < rem*(q+1) {
+		bucket = row / (q + 1)
+	} else {
+		bucket = rem + (row-rem*(q+1))/q
+	}
+	dst.WriteUint(uint64(bucket + 1))
+}
+
+// percentRank produces (rank - 1) / (rows - 1)
+// for each row, or 0 for single-row partitions
+type percentRank struct {
+	first int // position of the first peer
+}
+
+func (p *percentRank) eval(_ *aggtable, part, peers []int, k int, dst *ion.Buffer) {
+	if isFirstPeer(peers, k) {
+		p.first = k
+	}
+	if len(part) == 1 {
+		dst.WriteCanonicalFloat(0)
+		return
+	}
+	dst.WriteCanonicalFloat(float64(p.first) / float64(len(part)-1))
+}
+
+// cumeDist produces the fraction of rows in the
+// partition that precede or are peers of each row
+type cumeDist struct{}
+
+func (cumeDist) eval(_ *aggtable, part, peers []int, k int, dst *ion.Buffer) {
+	dst.WriteCanonicalFloat(float64(peers[k]) / float64(len(part)))
+}
+
+// cumulative computes a running aggregate over each
+// window partition using the default frame
+// (RANGE BETWEEN UNBOUNDED PRECEDING AND CURRENT ROW),
+// so peer rows all receive the same result
+type cumulative struct {
+	op expr.AggregateOp
+	// arg yields the argument for the i'th pair;
+	// it is nil for COUNT(*)
+	arg argFunc
+	// filter is the FILTER clause, if any
+	filter *windowFilter
+
+	count int64 // number of values accumulated
+	ival  int64
+	fval  float64
+}
+
+func (c *cumulative) reset() {
+	c.count = 0
+	c.ival = 0
+	c.fval = 0
+}
+
+func (c *cumulative) add(v []byte) {
+	if c.arg == nil {
+		c.count++
+		return
+	}
+	if len(v) == 0 {
+		return
+	}
+	var i int64
+	var f float64
+	isint := true
+	switch ion.TypeOf(v) {
+	case ion.NullType:
+		return
+	case ion.IntType:
+		n, _, err := ion.ReadInt(v)
+		if err != nil {
+			return
+		}
+		i, f = n, float64(n)
+	case ion.UintType:
+		n, _, err := ion.ReadUint(v)
+		if err != nil {
+			return
+		}
+		i, f = int64(n), float64(n)
+	case ion.FloatType:
+		n, _, err := ion.ReadFloat64(v)
+		if err != nil {
+			return
+		}
+		f, isint = n, false
+	default:
+		if c.op == expr.OpCount {
+			c.count++
+		}
+		return
+	}
+	switch c.op {
+	case expr.OpSum:
+		c.fval += f
+	case expr.OpSumInt:
+		if !isint {
+			i = int64(f)
+		}
+		c.ival += i
+	case expr.OpMin:
+		if c.count == 0 || f < c.fval {
+			c.fval = f
+		}
+	case expr.OpMax:
+		if c.count == 0 || f > c.fval {
+			c.fval = f
+		}
+	case expr.OpBitAnd, expr.OpBitOr, expr.OpBitXor:
+		if !isint {
+			return
+		}
+		switch {
+		case c.count == 0:
+			c.ival = i
+		case c.op == expr.OpBitAnd:
+			c.ival &= i
+		case c.op == expr.OpBitOr:
+			c.ival |= i
+		default:
+			c.ival ^= i
+		}
+	}
+	c.count++
+}
+
+func (c *cumulative) write(dst *ion.Buffer) {
+	switch {
+	case c.op == expr.OpCount:
+		dst.WriteInt(c.count)
+	case c.count == 0:
+		dst.WriteNull()
+	case c.op == expr.OpSum, c.op == expr.OpMin, c.op == expr.OpMax:
+		dst.WriteCanonicalFloat(c.fval)
+	default:
+		dst.WriteInt(c.ival)
+	}
+}
+
+func (c *cumulative) eval(agt *aggtable, part, peers []int, k int, dst *ion.Buffer) {
+	if k == 0 {
+		c.reset()
+	}
+	// accumulate all the peers of part[k] at once
+	if isFirstPeer(peers, k) {
+		for j := k; j < peers[k]; j++ {
+			if c.filter != nil && !c.filter.match(agt, part[j]) {
+				continue
+			}
+			var v []byte
+			if c.arg != nil {
+				v = c.arg(agt, part[j])
+			}
+			c.add(v)
+		}
+	}
+	c.write(dst)
+}
@@ -68,6 +68,16 @@ func (l *limiter) Close() error {
 	return nil
 }
 
+// Throttle implements Throttler;
+// once the limit has been reached
+// there is no point in producing more rows
+func (l *limiter) Throttle() error {
+	if l.done || atomic.LoadInt64(&l.parent.remaining) <= 0 {
+		return io.EOF
+	}
+	return nil
+}
+
 func (l *limiter) symbolize(st *symtab, aux *auxbindings) error {
 	return l.dst.symbolize(st, aux)
 }
@@ -15,7 +15,11 @@
 package vm
 
 import (
+	"bytes"
+	"errors"
+	"io"
 	"os"
+	"sync/atomic"
 	"testing"
 )
 
@@ -49,3 +53,67 @@ func TestLimit(t *testing.T) {
 		}
 	}
 }
+
+// throttledSink is an io.Writer that
+// stops accepting data after a number
+// of calls to Throttle
+type throttledSink struct {
+	calls, max int64
+}
+
+var errThrottled = errors.New("throttled")
+
+func (t *throttledSink) Write(p []byte) (int, error) { return len(p), nil }
+
+func (t *throttledSink) Throttle() error {
+	if atomic.AddInt64(&t.calls, 1) > t.max {
+		return errThrottled
+	}
+	return nil
+}
+
+func TestThrottle(t *testing.T) {
+	buf, err := os.ReadFile("../testdata/parking.10n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the limiter should ask for scanning to stop
+	// once the limit has been reached
+	var dst QueryBuffer
+	l := NewLimit(1, &dst)
+	w, err := l.Open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := Throttle(w); err != nil {
+		t.Fatalf("Throttle before writing: %v", err)
+	}
+	mem := Malloc()
+	defer Free(mem)
+	_, err = w.Write(mem[:copy(mem, buf)])
+	if err != nil && err != io.EOF {
+		t.Fatal(err)
+	}
+	if err := Throttle(w); err != io.EOF {
+		t.Fatalf("Throttle after the limit: got %v, want io.EOF", err)
+	}
+	w.Close()
+	l.Close()
+
+	// scanning should stop as soon as
+	// the final output stops accepting data
+	chunks := 5
+	tbl := &BufferedTable{buf: bytes.Repeat(buf, chunks), align: len(buf)}
+	out := &throttledSink{max: 2}
+	s, err := NewProjection(selection("Ticket as t"), LockedSink(out))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = CopyRows(s, tbl, 1)
+	if !errors.Is(err, errThrottled) {
+		t.Fatalf("got error %v, want %v", err, errThrottled)
+	}
+	if tbl.off >= int64(chunks*len(buf)) {
+		t.Errorf("scanned all %d chunks after output stopped", chunks)
+	}
+}
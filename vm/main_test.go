@@ -26,6 +26,12 @@ const (
 )
 
 func TestMain(m *testing.M) {
+	if err := Supported(); err != nil {
+		// the bytecode cannot be executed at all
+		// on this CPU, so there is nothing to test
+		fmt.Printf("skipping vm tests: %s\n", err)
+		os.Exit(0)
+	}
 	{
 		val, _ := os.LookupEnv(envvar)
 		switch val {
@@ -15,7 +15,12 @@
 package vm
 
 import (
+	"errors"
+	"fmt"
 	"math/bits"
+	"os"
+	"strconv"
+	"sync"
 	"sync/atomic"
 	"unsafe"
 )
@@ -29,21 +34,32 @@ import (
 // "absolute" addresses in the VM even though
 // the VM may reference data in different buffers
 // (i.e. one from io.Writer.Write, the scratch buffer, etc.)
+//
+// Only part of the reserved region is made
+// available via Malloc; the size of that part
+// defaults to vmDefaultUse, can be set at start-up
+// with the SNELLER_VM_MEMORY environment variable,
+// and can be changed later with SetMemoryLimit.
 
 const (
 	pageBits = 20
 	pageSize = 1 << pageBits
 
-	// number of bytes within reserved
+	// default number of bytes within reserved
 	// mapping to make available via Malloc
-	// (can be up to vmReserve - vmStart)
-	vmUse = 1 << 29
+	vmDefaultUse = 1 << 29
+
+	// maximum number of bytes within the reserved
+	// mapping that can be made available via Malloc
+	// (can be up to vmReserve - vmStart, less the
+	// extra byte that we map past the final page)
+	vmMaxUse = 1 << 30
 
-	// total pages that can be used
-	vmPages = vmUse >> pageBits
+	// maximum number of pages that can be used
+	vmMaxPages = vmMaxUse >> pageBits
 
 	// 64-bit words in allocation bitmap
-	vmWords = vmPages / 64
+	vmMaxWords = vmMaxPages / 64
 
 	// total number of bytes to reserve
 	vmReserve = 1 << 32
@@ -57,9 +73,26 @@ const (
 	PageSize = pageSize
 )
 
+// ErrOutOfMemory is the error produced when
+// there are no pages left in the vm memory arena
+// or an Account has reached its limit.
+// Malloc panics with ErrOutOfMemory, and query
+// execution converts that panic back into
+// ErrOutOfMemory (see HandlePanic).
+var ErrOutOfMemory = errors.New("vm: out of memory")
+
 var (
-	vmm    *[vmUse]byte
-	vmbits [vmWords]uint64
+	vmm    *[vmMaxUse]byte
+	vmbits [vmMaxWords]uint64
+
+	// vmlock serializes calls to SetMemoryLimit
+	vmlock sync.Mutex
+	// # bytes of vmm that have been mapped
+	// for use by Malloc; this only ever grows
+	vmMapped int64
+	// # pages of vmm that are available via Malloc;
+	// always a multiple of 64
+	vmPages int64
 
 	// # pages in use; this may differ
 	// slightly from the bitmap count
@@ -67,6 +100,76 @@ var (
 	vminuse int64
 )
 
+// ParseMemorySize parses a size of vm memory in bytes,
+// optionally followed by a K, M or G suffix, for use
+// with SetMemoryLimit.
+func ParseMemorySize(str string) (int, error) {
+	if str == "" {
+		return 0, fmt.Errorf("vm: empty memory size")
+	}
+	orig := str
+	shift := 0
+	switch str[len(str)-1] {
+	case 'k', 'K':
+		shift = 10
+	case 'm', 'M':
+		shift = 20
+	case 'g', 'G':
+		shift = 30
+	}
+	if shift != 0 {
+		str = str[:len(str)-1]
+	}
+	n, err := strconv.ParseUint(str, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("vm: bad memory size %q", orig)
+	}
+	if n > vmMaxUse>>shift {
+		return 0, fmt.Errorf("vm: memory size %q exceeds the maximum of %d bytes", orig, vmMaxUse)
+	}
+	return int(n << shift), nil
+}
+
+// MemoryLimit returns the number of bytes
+// of vm memory that can be allocated with Malloc.
+func MemoryLimit() int {
+	return PagesLimit() << pageBits
+}
+
+// SetMemoryLimit sets the number of bytes of
+// vm memory that can be allocated with Malloc
+// and returns the new limit. The size is rounded
+// up to a multiple of 64 pages and clamped to the
+// size of the region reserved for the vm (1GiB).
+//
+// Raising the limit makes more of the reserved region
+// available immediately. Lowering the limit does not
+// affect the pages that have already been allocated,
+// but Malloc will not return pages beyond the new limit.
+func SetMemoryLimit(size int) int {
+	const granule = 64 * pageSize
+	size = (size + granule - 1) &^ (granule - 1)
+	if size < granule {
+		size = granule
+	} else if size > vmMaxUse {
+		size = vmMaxUse
+	}
+	vmlock.Lock()
+	defer vmlock.Unlock()
+	if mapped := int(vmMapped); size > mapped {
+		// we map one byte past the final page
+		// (see mapVM) so that the user of the final
+		// page can have a multi-byte load extend past
+		// the final page boundary
+		mem := unsafe.Slice((*byte)(unsafe.Pointer(vmm)), vmMaxUse+1)
+		growVM(mem[mapped : size+1])
+		guard(vmm[mapped:size])
+		atomic.StoreInt64(&vmMapped, int64(size))
+	}
+	atomic.StoreInt64(&vmPages, int64(size>>pageBits))
+	return size
+}
+
 // vmref is a (type, length) tuple
 // referring to memory within the VMM
 type vmref [2]uint32
@@ -95,7 +198,8 @@ func (v vmref) valid() bool {
 	}
 	// references should not point outside
 	// the usable memory region
-	if v[0] > vmUse || v[0]+v[1] > vmUse {
+	mapped := int(atomic.LoadInt64(&vmMapped))
+	if int(v[0]) > mapped || int(v[0])+int(v[1]) > mapped {
 		return false
 	}
 	// references should not overlap a page boundary:
@@ -111,8 +215,16 @@ func (v vmref) valid() bool {
 }
 
 func init() {
+	use := vmDefaultUse
+	if str := os.Getenv("SNELLER_VM_MEMORY"); str != "" {
+		n, err := ParseMemorySize(str)
+		if err != nil {
+			panic("invalid SNELLER_VM_MEMORY: " + err.Error())
+		}
+		use = n
+	}
 	vmm = mapVM()
-	guard(vmm[:vmUse])
+	SetMemoryLimit(use)
 }
 
 func vmbase() uintptr {
@@ -120,7 +232,7 @@ func vmbase() uintptr {
 }
 
 func vmend() uintptr {
-	return vmbase() + vmUse
+	return vmbase() + uintptr(vmMaxUse)
 }
 
 // vmdispl returns the displacement
@@ -151,13 +263,31 @@ func Allocated(buf []byte) bool {
 // Malloc returns a new buffer suitable
 // for passing to VM operations.
 //
-// If there is no VM memory available, Malloc panics.
+// If there is no VM memory available,
+// Malloc panics with ErrOutOfMemory.
+// Use TryMalloc to receive the error instead.
 func Malloc() []byte {
+	buf, err := TryMalloc()
+	if err != nil {
+		panic(err)
+	}
+	return buf
+}
+
+// TryMalloc is like Malloc, but it returns
+// ErrOutOfMemory rather than panicking when
+// there is no VM memory available.
+func TryMalloc() ([]byte, error) {
 	// we loop while vminuse < vmPages because
 	// we may be racing with Free locking groups
 	// of pages in order to pass them to madvise(mem, MADV_FREE)
-	for atomic.LoadInt64(&vminuse) < vmPages {
-		for i := 0; i < vmWords; i++ {
+	for {
+		pages := atomic.LoadInt64(&vmPages)
+		if atomic.LoadInt64(&vminuse) >= pages {
+			break
+		}
+		words := int(pages / 64)
+		for i := 0; i < words; i++ {
 			addr := &vmbits[i]
 			mask := atomic.LoadUint64(addr)
 			avail := ^mask
@@ -174,10 +304,10 @@ func Malloc() []byte {
 			buf = buf[:pageSize:pageSize]
 			unguard(buf) // if -tags=vmfence, unprotect this memory
 			leakstart(i*64 + bit)
-			return buf
+			return buf, nil
 		}
 	}
-	panic("out of VM memory")
+	return nil, ErrOutOfMemory
 }
 
 // PagesUsed returns the number of currently-active
@@ -187,6 +317,12 @@ func PagesUsed() int {
 	return int(atomic.LoadInt64(&vminuse))
 }
 
+// PagesLimit returns the total number
+// of pages that can be returned by Malloc.
+func PagesLimit() int {
+	return int(atomic.LoadInt64(&vmPages))
+}
+
 // should ordinarily return the same
 // result as PagesUsed(), except when
 // we are freeing pages
@@ -15,7 +15,11 @@
 package vm
 
 import (
+	"errors"
 	"testing"
+
+	"github.com/SnellerInc/sneller/expr"
+	"github.com/SnellerInc/sneller/ion"
 )
 
 func TestMalloc(t *testing.T) {
@@ -40,3 +44,192 @@ func TestMalloc(t *testing.T) {
 		}
 	}
 }
+
+func TestMallocExhausted(t *testing.T) {
+	before := PagesUsed()
+	var bufs [][]byte
+	defer func() {
+		for i := range bufs {
+			Free(bufs[i])
+		}
+	}()
+	for {
+		buf, err := TryMalloc()
+		if err != nil {
+			if !errors.Is(err, ErrOutOfMemory) {
+				t.Fatalf("unexpected error %v", err)
+			}
+			break
+		}
+		bufs = append(bufs, buf)
+		if len(bufs) > PagesLimit() {
+			t.Fatal("allocated more than PagesLimit() pages")
+		}
+	}
+	if len(bufs) != PagesLimit()-before {
+		t.Errorf("allocated %d pages; expected %d", len(bufs), PagesLimit()-before)
+	}
+	err := protect(func() error {
+		Malloc()
+		return nil
+	})
+	if err != ErrOutOfMemory {
+		t.Errorf("got error %v from Malloc; want ErrOutOfMemory", err)
+	}
+}
+
+func TestParseMemorySize(t *testing.T) {
+	testcases := []struct {
+		str  string
+		want int // -1 if invalid
+	}{
+		{"1", 1},
+		{"256M", 256 << 20},
+		{"100m", 100 << 20},
+		{"4k", 4 << 10},
+		{"1G", 1 << 30},
+		{"2G", -1},
+		{"lots", -1},
+		{"", -1},
+	}
+	for _, tc := range testcases {
+		got, err := ParseMemorySize(tc.str)
+		if tc.want < 0 {
+			if err == nil {
+				t.Errorf("%q: expected an error", tc.str)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: %s", tc.str, err)
+		} else if got != tc.want {
+			t.Errorf("%q: got %d, want %d", tc.str, got, tc.want)
+		}
+	}
+}
+
+func TestSetMemoryLimit(t *testing.T) {
+	orig := MemoryLimit()
+	defer SetMemoryLimit(orig)
+
+	for _, tc := range []struct {
+		size, want int
+	}{
+		{1, 64 * pageSize},
+		{100 << 20, 128 * pageSize},
+		{2 << 30, vmMaxUse},
+		{orig, orig},
+	} {
+		if got := SetMemoryLimit(tc.size); got != tc.want {
+			t.Errorf("SetMemoryLimit(%d) = %d, want %d", tc.size, got, tc.want)
+		}
+		if MemoryLimit() != tc.want {
+			t.Errorf("after SetMemoryLimit(%d): MemoryLimit() = %d", tc.size, MemoryLimit())
+		}
+	}
+
+	// shrink the arena and then grow it again;
+	// every page up to the new limit must be usable
+	before := PagesUsed()
+	SetMemoryLimit(64 * pageSize)
+	SetMemoryLimit(orig + 64*pageSize)
+	var bufs [][]byte
+	defer func() {
+		for i := range bufs {
+			Free(bufs[i])
+		}
+	}()
+	for {
+		buf, err := TryMalloc()
+		if err != nil {
+			break
+		}
+		buf[0] = 'x'
+		buf[len(buf)-1] = 'y'
+		bufs = append(bufs, buf)
+	}
+	if len(bufs) != PagesLimit()-before {
+		t.Errorf("allocated %d pages; expected %d", len(bufs), PagesLimit()-before)
+	}
+	// lowering the limit leaves the
+	// pages that have been allocated alone
+	SetMemoryLimit(orig)
+	if _, err := TryMalloc(); err != ErrOutOfMemory {
+		t.Errorf("got error %v from TryMalloc; want ErrOutOfMemory", err)
+	}
+	for i := range bufs {
+		if !Allocated(bufs[i]) {
+			t.Fatalf("buffer %d not allocated?", i)
+		}
+		Free(bufs[i])
+	}
+	bufs = nil
+	if PagesUsed() != before {
+		t.Errorf("%d pages used; want %d", PagesUsed(), before)
+	}
+}
+
+func TestAccount(t *testing.T) {
+	a := &Account{Limit: 2}
+	var bufs [][]byte
+	for i := 0; i < 2; i++ {
+		buf, err := a.malloc()
+		if err != nil {
+			t.Fatal(err)
+		}
+		bufs = append(bufs, buf)
+	}
+	if _, err := a.malloc(); err != ErrOutOfMemory {
+		t.Errorf("got error %v beyond the Account limit; want ErrOutOfMemory", err)
+	}
+	a.free(bufs[0])
+	if a.PagesUsed() != 1 || a.PagesPeak() != 2 {
+		t.Errorf("used %d, peak %d; want 1, 2", a.PagesUsed(), a.PagesPeak())
+	}
+	a.free(bufs[1])
+
+	// a query opened with WithAccount charges
+	// the table buffers and the symbol table
+	// memory to the Account
+	var st ion.Symtab
+	var body, buf ion.Buffer
+	for i := 0; i < 10; i++ {
+		ion.NewStruct(&st, []ion.Field{{Label: "x", Datum: ion.Int(int64(i))}}).Datum().Encode(&body, &st)
+	}
+	st.Marshal(&buf, true)
+	buf.UnsafeAppend(body.Bytes())
+
+	before := PagesUsed()
+	a = new(Account)
+	var c Count
+	f, err := NewFilter(expr.Compare(expr.Greater, expr.Ident("x"), expr.Integer(1)), &c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = BufferTable(buf.Bytes(), PageSize).WriteChunks(WithAccount(f, a), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if c.Value() != 8 {
+		t.Errorf("got %d rows; want 8", c.Value())
+	}
+	if a.PagesPeak() < 2 {
+		t.Errorf("peak of %d pages charged; want at least 2", a.PagesPeak())
+	}
+	if a.PagesUsed() != 0 || PagesUsed() != before {
+		t.Errorf("%d pages still charged, %d pages in use", a.PagesUsed(), PagesUsed()-before)
+	}
+
+	// the Account limit fails the query
+	a = &Account{Limit: 1}
+	err = BufferTable(buf.Bytes(), PageSize).WriteChunks(WithAccount(f, a), 1)
+	if !errors.Is(err, ErrOutOfMemory) {
+		t.Errorf("got error %v with a one-page limit; want ErrOutOfMemory", err)
+	}
+	if PagesUsed() != before {
+		t.Errorf("%d pages leaked", PagesUsed()-before)
+	}
+}
@@ -22,16 +22,19 @@ import (
 	"golang.org/x/sys/windows"
 )
 
-func mapVM() *[vmUse]byte {
+func mapVM() *[vmMaxUse]byte {
 	base, err := windows.VirtualAlloc(0, vmReserve, windows.MEM_RESERVE, windows.PAGE_NOACCESS)
 	if err != nil {
 		panic("VirtualAlloc(reserve) failed: " + err.Error())
 	}
-	_, err = windows.VirtualAlloc(base+vmStart, vmUse+1, windows.MEM_COMMIT, windows.PAGE_READWRITE)
+	return (*[vmMaxUse]byte)(unsafe.Pointer(base + vmStart))
+}
+
+func growVM(mem []byte) {
+	_, err := windows.VirtualAlloc(uintptr(unsafe.Pointer(&mem[0])), uintptr(len(mem)), windows.MEM_COMMIT, windows.PAGE_READWRITE)
 	if err != nil {
 		panic("VirtualAlloc(commit) failed: " + err.Error())
 	}
-	return (*[vmUse]byte)(unsafe.Pointer(base + vmStart))
 }
 
 func hintUnused(mem []byte) {}
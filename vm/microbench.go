@@ -0,0 +1,248 @@
+// Copyright (C) 2022 Sneller, Inc.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package vm
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/SnellerInc/sneller/date"
+	"github.com/SnellerInc/sneller/expr"
+	"github.com/SnellerInc/sneller/ion"
+	"golang.org/x/exp/slices"
+)
+
+// Kernel describes a bytecode op that
+// depends on optional AVX-512 extensions.
+type Kernel struct {
+	Op       string // name of the bytecode op
+	Requires string // extensions required by the op
+	Selected bool   // whether the op is dispatched on this CPU
+}
+
+// Kernels returns the bytecode ops that have
+// optional AVX-512 extension requirements along with
+// whether or not each op is selected on the current CPU.
+func Kernels() []Kernel {
+	out := make([]Kernel, 0, len(opfeatures))
+	for op, feat := range opfeatures {
+		out = append(out, Kernel{
+			Op:       opinfo[op].text,
+			Requires: feat.String(),
+			Selected: kernelSelected(op),
+		})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].Op < out[j].Op
+	})
+	return out
+}
+
+// kernelSelected returns whether op is used
+// by compiled programs on the current CPU
+func kernelSelected(op bcop) bool {
+	for i := range patchAVX512Level2 {
+		if patchAVX512Level2[i].from != op {
+			continue
+		}
+		// variants are only used if they
+		// have been patched into ssainfo
+		for j := range ssainfo {
+			if ssainfo[j].bc == op {
+				return true
+			}
+		}
+		return false
+	}
+	return isSupported(op)
+}
+
+// OpBenchmark is the result of running one
+// of the microbenchmarks in BenchmarkOps.
+type OpBenchmark struct {
+	Name    string        // the benchmarked expression
+	Ops     []string      // bytecode ops in the compiled program
+	Rows    int64         // number of rows processed
+	Elapsed time.Duration // time spent processing the rows
+}
+
+// RowsPerSecond returns the measured throughput.
+func (o *OpBenchmark) RowsPerSecond() float64 {
+	if o.Elapsed <= 0 {
+		return 0
+	}
+	return float64(o.Rows) / o.Elapsed.Seconds()
+}
+
+func (o *OpBenchmark) String() string {
+	return fmt.Sprintf("%-40s %14.0f rows/s  [%s]", o.Name, o.RowsPerSecond(), strings.Join(o.Ops, " "))
+}
+
+// microbenchmarks is the set of filter expressions
+// run by BenchmarkOps; each one is dominated by
+// a small number of bytecode ops
+var microbenchmarks = []expr.Node{
+	expr.Compare(expr.Greater, expr.Identifier("i"), expr.Integer(500)),
+	expr.Compare(expr.Less, expr.Identifier("f"), expr.Float(0.5)),
+	expr.Compare(expr.Equals, expr.Add(expr.Identifier("i"), expr.Integer(1)), expr.Integer(10)),
+	expr.Compare(expr.Equals, expr.Mul(expr.Identifier("f"), expr.Float(3)), expr.Float(1.5)),
+	expr.Compare(expr.Equals, expr.Identifier("s"), expr.String("delta")),
+	expr.Compare(expr.Equals, expr.Identifier("sym"), expr.String("delta")),
+	expr.Compare(expr.Equals, expr.Call(expr.Upper, expr.Identifier("s")), expr.String("DELTA")),
+	expr.Compare(expr.Equals, expr.Call(expr.CharLength, expr.Identifier("s")), expr.Integer(5)),
+	&expr.StringMatch{Op: expr.Like, Expr: expr.Identifier("s"), Pattern: "%elt%"},
+	&expr.StringMatch{Op: expr.Ilike, Expr: expr.Identifier("s"), Pattern: "%ELT%"},
+	&expr.StringMatch{Op: expr.Like, Expr: expr.Identifier("sym"), Pattern: "%elt%"},
+	&expr.StringMatch{Op: expr.RegexpMatch, Expr: expr.Identifier("s"), Pattern: "d[aeiou]l.a"},
+	expr.Compare(expr.Less, expr.Identifier("ts"), &expr.Timestamp{Value: date.Date(2022, 6, 1, 0, 0, 0, 0)}),
+	&expr.IsKey{Expr: expr.Identifier("n"), Key: expr.IsNull},
+	expr.In(expr.Identifier("i"), expr.Integer(1), expr.Integer(2), expr.Integer(3), expr.Integer(5),
+		expr.Integer(8), expr.Integer(13), expr.Integer(21), expr.Integer(34), expr.Integer(55),
+		expr.Integer(89), expr.Integer(144), expr.Integer(233)),
+}
+
+// benchwords are the string values used
+// in the microbenchmark input
+var benchwords = []string{"alpha", "bravo", "charlie", "delta", "echo", "foxtrot", "golf", "hotel"}
+
+// benchrows is the number of rows
+// in the microbenchmark input chunk
+const benchrows = 1000
+
+// benchchunk produces a chunk of ion data
+// with a mix of field types for microbenchmarks
+func benchchunk() []byte {
+	var st ion.Symtab
+	var body ion.Buffer
+	start := date.Date(2022, 1, 1, 0, 0, 0, 0)
+	fields := make([]ion.Field, 0, 7)
+	for i := 0; i < benchrows; i++ {
+		word := benchwords[i%len(benchwords)]
+		fields = append(fields[:0],
+			ion.Field{Label: "i", Datum: ion.Int(int64(i))},
+			ion.Field{Label: "f", Datum: ion.Float(float64(i%100) / 100)},
+			ion.Field{Label: "s", Datum: ion.String(word)},
+			ion.Field{Label: "sym", Datum: ion.Interned(&st, word)},
+			ion.Field{Label: "ts", Datum: ion.Timestamp(start.Add(time.Duration(i) * time.Hour * 24))},
+		)
+		if i%3 == 0 {
+			fields = append(fields, ion.Field{Label: "n", Datum: ion.Null})
+		}
+		ion.NewStruct(&st, fields).Datum().Encode(&body, &st)
+	}
+	var out ion.Buffer
+	st.Marshal(&out, true)
+	return append(out.Bytes(), body.Bytes()...)
+}
+
+// benchops returns the names of the bytecode ops
+// that e compiles into for the symbol table in chunk
+func benchops(e expr.Node, chunk []byte) ([]string, error) {
+	p, err := compileLogical(e)
+	if err != nil {
+		return nil, err
+	}
+	var st symtab
+	defer st.free()
+	if _, err := st.Unmarshal(chunk); err != nil {
+		return nil, err
+	}
+	if err := p.symbolize(&st, &auxbindings{}); err != nil {
+		return nil, err
+	}
+	var bc bytecode
+	defer bc.reset()
+	if err := p.compile(&bc, &st, "benchops"); err != nil {
+		return nil, err
+	}
+	var ops []string
+	err = visitBytecode(&bc, func(_ int, _ bcop, info *bcopinfo) error {
+		if !slices.Contains(ops, info.text) {
+			ops = append(ops, info.text)
+		}
+		return nil
+	})
+	return ops, err
+}
+
+// BenchmarkOps runs a fixed set of microbenchmarks
+// that exercise individual bytecode ops and reports
+// the throughput of each one. Each benchmark whose
+// expression contains the string match (or every
+// benchmark if match is empty) runs for duration d
+// using the given number of goroutines.
+//
+// The results reflect the kernel variants selected
+// for the current CPU; see Kernels.
+func BenchmarkOps(match string, d time.Duration, parallel int) ([]OpBenchmark, error) {
+	if err := Supported(); err != nil {
+		return nil, err
+	}
+	if parallel <= 0 {
+		parallel = 1
+	}
+	chunk := benchchunk()
+	var out []OpBenchmark
+	for _, e := range microbenchmarks {
+		name := expr.ToString(e)
+		if !strings.Contains(name, match) {
+			continue
+		}
+		ops, err := benchops(e, chunk)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		res, err := runOpBenchmark(e, chunk, d, parallel)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		res.Name = name
+		res.Ops = ops
+		out = append(out, res)
+	}
+	return out, nil
+}
+
+func runOpBenchmark(e expr.Node, chunk []byte, d time.Duration, parallel int) (OpBenchmark, error) {
+	var c Count
+	f, err := NewFilter(e, &c)
+	if err != nil {
+		return OpBenchmark{}, err
+	}
+	var rows int64
+	start := time.Now()
+	deadline := start.Add(d)
+	err = SplitInput(f, parallel, func(w io.Writer) error {
+		tmp := Malloc()
+		defer Free(tmp)
+		tmp = tmp[:copy(tmp, chunk)]
+		for time.Now().Before(deadline) {
+			if _, err := w.Write(tmp); err != nil {
+				return err
+			}
+			atomic.AddInt64(&rows, benchrows)
+		}
+		return nil
+	})
+	elapsed := time.Since(start)
+	if err2 := f.Close(); err == nil {
+		err = err2
+	}
+	return OpBenchmark{Rows: rows, Elapsed: elapsed}, err
+}
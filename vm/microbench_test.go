@@ -0,0 +1,58 @@
+// Copyright (C) 2022 Sneller, Inc.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package vm
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBenchmarkOps(t *testing.T) {
+	res, err := BenchmarkOps("", time.Millisecond, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res) != len(microbenchmarks) {
+		t.Fatalf("got %d results, want %d", len(res), len(microbenchmarks))
+	}
+	for i := range res {
+		if res[i].Rows == 0 || res[i].Rows%benchrows != 0 {
+			t.Errorf("%s: unexpected row count %d", res[i].Name, res[i].Rows)
+		}
+		if len(res[i].Ops) == 0 {
+			t.Errorf("%s: no ops", res[i].Name)
+		}
+	}
+
+	res, err = BenchmarkOps("LIKE", time.Millisecond, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res) != 3 {
+		t.Errorf("got %d results matching LIKE, want 3", len(res))
+	}
+}
+
+func TestKernels(t *testing.T) {
+	k := Kernels()
+	if len(k) != len(opfeatures) {
+		t.Fatalf("got %d kernels, want %d", len(k), len(opfeatures))
+	}
+	for i := range k {
+		if k[i].Requires == "" {
+			t.Errorf("%s: no required extensions", k[i].Op)
+		}
+	}
+}
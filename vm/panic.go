@@ -0,0 +1,67 @@
+// Copyright (C) 2022 Sneller, Inc.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package vm
+
+import (
+	"fmt"
+	"runtime/debug"
+)
+
+// PanicError is the error produced when
+// a panic is recovered while executing a query.
+//
+// Query operators run in goroutines that are
+// shared by a single query, so a panic in one
+// of them is converted into a PanicError and
+// returned to the caller rather than being allowed
+// to take down the whole process (and every
+// other query that it is executing).
+type PanicError struct {
+	// Value is the value that was passed to panic.
+	Value any
+	// Stack is the stack trace of the
+	// goroutine that panicked.
+	Stack []byte
+}
+
+// Error implements error
+func (p *PanicError) Error() string {
+	return fmt.Sprintf("query execution panicked: %v", p.Value)
+}
+
+// HandlePanic recovers from a panic
+// and stores a *PanicError in *err.
+// Running out of vm memory is not a bug, so
+// a panic with ErrOutOfMemory stores ErrOutOfMemory
+// in *err rather than a *PanicError.
+// HandlePanic must be called directly via defer:
+//
+//	defer vm.HandlePanic(&err)
+func HandlePanic(err *error) {
+	if e := recover(); e != nil {
+		if e == ErrOutOfMemory {
+			*err = ErrOutOfMemory
+			return
+		}
+		*err = &PanicError{Value: e, Stack: debug.Stack()}
+	}
+}
+
+// protect calls fn and converts
+// any panic into a *PanicError
+func protect(fn func() error) (err error) {
+	defer HandlePanic(&err)
+	return fn()
+}
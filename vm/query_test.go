@@ -89,7 +89,8 @@ func testInput(t *testing.T, tci *testquery.TestCaseIon, shuffleCount int) {
 			tci.SymbolTable.Reset()
 
 			var err error
-			if tci.Query, err = partiql.Parse(tci.QueryStr); err != nil {
+			dialect, _ := partiql.DialectByName(tci.Tags["dialect"])
+			if tci.Query, err = partiql.ParseDialect(tci.QueryStr, dialect); err != nil {
 				t.Fatal(err)
 			}
 
@@ -278,6 +279,12 @@ func TestQueries(t *testing.T) {
 		path := test[i].path
 		t.Run(test[i].name, func(t *testing.T) {
 			t.Parallel()
+			if testquery.Updating() {
+				if err := testquery.UpdateTestCaseFile(path); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
 			tci, err := testquery.ReadTestCaseIonFromFile(path)
 			if err != nil {
 				t.Fatal(err)
@@ -287,6 +294,30 @@ func TestQueries(t *testing.T) {
 	}
 }
 
+// FuzzQueries runs the tests in testdata/queries/*.test
+// with randomly chosen symbol tables, chunk boundaries
+// and execution flags (see testquery.RandomVariant)
+func FuzzQueries(f *testing.F) {
+	test, err := findQueries("./testdata/queries/", ".test", *symLinkFlag)
+	if err != nil {
+		f.Fatal(err)
+	}
+	for i := range test {
+		f.Add(uint(i), int64(i))
+	}
+	f.Fuzz(func(t *testing.T, n uint, seed int64) {
+		path := test[n%uint(len(test))].path
+		tci, err := testquery.ReadTestCaseIonFromFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		v := tci.RandomVariant(rand.New(rand.NewSource(seed)))
+		if err := tci.ExecuteVariant(v); err != nil {
+			t.Errorf("%s with flags %#x and splits %v: %s", path, v.Flags, v.Splits, err)
+		}
+	})
+}
+
 type queryTest struct {
 	name, path string
 }
@@ -550,7 +550,11 @@ func (a *aggtable) writeRows(delims []vmref, rp *rowParams) error {
 	var abort uint16
 	a.bc.prepare(rp)
 	for len(delims) > 0 {
+		wd := watch("hash aggregate", a.parent, &a.prog, &a.bc)
 		n := a.fasteval(delims, &abort)
+		if err := wd.done(); err != nil {
+			return err
+		}
 		if a.bc.err != 0 && a.bc.err != bcerrNeedRadix {
 			return bytecodeerror("hash aggregate", &a.bc)
 		}
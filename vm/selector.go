@@ -81,6 +81,16 @@ type Projection struct {
 	constexpr *ion.Struct
 }
 
+func (s Selection) redacted() string {
+	sub := make([]string, len(s))
+	for i := range s {
+		sub[i] = expr.ToRedacted(&s[i])
+	}
+	return strings.Join(sub, ", ")
+}
+
+func (p *Projection) redacted() string { return p.sel.redacted() }
+
 func (s Selection) toConst() (ion.Struct, bool) {
 	var fields []ion.Field
 	for i := range s {
@@ -144,6 +154,8 @@ type projector struct {
 	// in that case we should preserve the delimiters
 	// as we compute them
 	dstrc rowConsumer // if dst is a RowConsumer, this is set
+
+	zion zionRows
 }
 
 func (p *Projection) Open() (io.WriteCloser, error) {
@@ -199,9 +211,18 @@ func (p *projector) symbolize(st *symtab, aux *auxbindings) error {
 
 func (p *projector) Close() error {
 	p.bc.reset()
+	p.zion.free()
 	return p.aw.Close()
 }
 
+var _ zionConsumer = &projector{}
+
+func (p *projector) zionOk() bool { return true }
+
+func (p *projector) writeZion(state *zionState) error {
+	return p.zion.write(state, p)
+}
+
 func (p *projector) flush() error {
 	_, err := p.aw.flush()
 	return err
@@ -223,6 +244,16 @@ func (p *projector) bcproject(delims []vmref, dst []byte, out []syminfo) (int, i
 
 func (p *projector) next() rowConsumer { return p.dstrc }
 
+// Throttle implements Throttler; if dst is
+// a rowConsumer, then rowSplitter.Throttle
+// reaches it via next() instead
+func (p *projector) Throttle() error {
+	if p.dstrc != nil {
+		return nil
+	}
+	return Throttle(p.dst)
+}
+
 func (p *projector) EndSegment() {
 	// if we do not have any buffered data,
 	// then do not eat up vm memory
@@ -267,7 +298,11 @@ func (p *projector) writeRows(delims []vmref, rp *rowParams) error {
 
 	p.bc.prepare(rp)
 	for len(delims) > 0 {
+		wd := watch("projection", p.parent, &p.prog, &p.bc)
 		off, rewrote := p.bcproject(delims, p.aw.buf[p.aw.off:], p.outsel)
+		if err := wd.done(); err != nil {
+			return err
+		}
 		if p.bc.err != 0 {
 			// we don't expect to encounter
 			// any errors...
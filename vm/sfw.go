@@ -160,7 +160,15 @@ type rowSplitter struct {
 	params      rowParams
 	aux         auxbindings
 
+	// symraw is the encoding of the most recent
+	// BVM symbol table, and incoming is scratch space
+	// for decoding the next symbol table;
+	// see reuseSymtab
+	symraw   []byte
+	incoming ion.Symtab
+
 	vmcache []byte
+	acct    *Account // see WithAccount
 
 	pos *int64
 
@@ -206,7 +214,11 @@ func (q *rowSplitter) writeVM(src []byte, delims []vmref) error {
 // write non-vmm bytes by copying immediately after scanning
 func (q *rowSplitter) writeVMCopy(src []byte, delims []vmref) error {
 	if q.vmcache == nil {
-		q.vmcache = Malloc()
+		var err error
+		q.vmcache, err = q.acct.malloc()
+		if err != nil {
+			return err
+		}
 	}
 
 	const (
@@ -285,12 +297,45 @@ func HintEndSegment(w io.Writer) {
 	}
 }
 
+// Throttler is implemented by io.Writers
+// (including some of those returned by QuerySink.Open)
+// that can signal backpressure to the code producing
+// their input. Throttle blocks until the writer is
+// ready to accept more data, and it returns an error
+// if the writer will never accept more data (for example,
+// because a LIMIT has been reached or the client reading
+// the query results has gone away).
+// Throttle must be safe to call concurrently with
+// calls to Write.
+//
+// See also: Throttle.
+type Throttler interface {
+	Throttle() error
+}
+
+// Throttle calls Throttle() on w if it
+// can be cast to a Throttler and returns nil otherwise.
+//
+// Callers that scan input data (i.e. Table implementations)
+// should call Throttle before producing each chunk of data
+// so that scanning pauses rather than buffering output
+// when the final destination of the query is slow,
+// and so that scanning stops entirely once the query
+// output is no longer needed.
+func Throttle(w io.Writer) error {
+	if t, ok := w.(Throttler); ok {
+		return t.Throttle()
+	}
+	return nil
+}
+
 // EndSegment implements blockfmt.SegmentHintWriter.EndSegment
 func (q *rowSplitter) EndSegment() {
 	// since we know we will have to re-build the symbol table
 	// anyway, we can free the symbol table memory so that
 	// interleaved queries can use the same vm buffers
 	q.symbolized = false
+	q.symraw = q.symraw[:0]
 	q.shared.Reset()
 	for rc := q.rowConsumer; rc != nil; rc = rc.next() {
 		if esw, ok := rc.(EndSegmentWriter); ok {
@@ -303,7 +348,7 @@ func (q *rowSplitter) drop() {
 	noLeakCheck(q)
 	q.shared.Reset()
 	if q.vmcache != nil {
-		Free(q.vmcache)
+		q.acct.free(q.vmcache)
 		q.vmcache = nil
 		if q.zstate != nil {
 			q.zstate.buckets.Decompressed = nil
@@ -311,6 +356,29 @@ func (q *rowSplitter) drop() {
 	}
 }
 
+func (q *rowSplitter) account() *Account { return q.acct }
+
+// setAccount charges the memory allocated
+// by q and its symbol table to a; it must
+// be called before the first call to Write
+func (q *rowSplitter) setAccount(a *Account) {
+	q.acct = a
+	q.shared.slab.acct = a
+}
+
+// Throttle implements Throttler by
+// asking each of the row consumers in turn
+func (q *rowSplitter) Throttle() error {
+	for rc := q.rowConsumer; rc != nil; rc = rc.next() {
+		if t, ok := rc.(Throttler); ok {
+			if err := t.Throttle(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
 func (q *rowSplitter) Close() error {
 	err := q.rowConsumer.Close()
 	q.drop()
@@ -370,7 +438,10 @@ func (q *rowSplitter) writeZion(src []byte) (int, error) {
 	}
 	q.zstate.buckets.Reset(&q.zstate.shape, rest)
 	if q.vmcache == nil {
-		q.vmcache = Malloc()
+		q.vmcache, err = q.acct.malloc()
+		if err != nil {
+			return 0, err
+		}
 	}
 	// make sure decompression writes into vmm
 	q.zstate.buckets.Decompressed = q.vmcache[:0]
@@ -391,6 +462,18 @@ func (q *rowSplitter) writeZion(src []byte) (int, error) {
 // The data passed to Write may contain a symbol table,
 // but if it does, it must come first.
 func (q *rowSplitter) Write(buf []byte) (int, error) {
+	return q.write(buf, nil, 0)
+}
+
+// WriteSymtab implements blockfmt.SymtabWriter
+//
+// WriteSymtab is like Write, but the symbol table
+// at the start of buf has already been decoded into st.
+func (q *rowSplitter) WriteSymtab(buf []byte, st *ion.Symtab, size int) (int, error) {
+	return q.write(buf, st, size)
+}
+
+func (q *rowSplitter) write(buf []byte, st *ion.Symtab, size int) (int, error) {
 	if q.zstate != nil && zll.IsMagic(buf) {
 		return q.writeZion(buf)
 	}
@@ -404,20 +487,42 @@ func (q *rowSplitter) Write(buf []byte) (int, error) {
 		if !q.shared.resident() {
 			leakCheck(q)
 		}
-		q.shared.rewind() // revert to previous Unmarshal state
-		rest, err := q.shared.Unmarshal(buf)
+		n, err := q.reuseSymtab(buf, st, size)
 		if err != nil {
-			return 0, fmt.Errorf("rowSplitter.Write: %w", err)
+			return 0, err
 		}
-		q.shared.snapshot() // mark this point for the next rewind()
-		q.shared.flags.clear(sfZion)
-		q.symbolized = true
-		boff = int32(len(buf) - len(rest))
+		if n > 0 {
+			boff = int32(n)
+		} else {
+			q.shared.rewind() // revert to previous Unmarshal state
+			if st != nil {
+				// the table was decoded ahead of time,
+				// so we only need to copy the symbols
+				q.shared.resetNoFree()
+				st.CloneInto(&q.shared.Symtab)
+				q.shared.buildFrom(buf[:size])
+				boff = int32(size)
+			} else {
+				rest, err := q.shared.Unmarshal(buf)
+				if err != nil {
+					return 0, fmt.Errorf("rowSplitter.Write: %w", err)
+				}
+				boff = int32(len(buf) - len(rest))
+			}
+			q.shared.snapshot() // mark this point for the next rewind()
+			q.shared.flags.clear(sfZion)
+			q.symbolized = true
+			if ion.IsBVM(buf) {
+				q.symraw = append(q.symraw[:0], buf[:boff]...)
+			} else {
+				q.symraw = q.symraw[:0]
+			}
 
-		q.aux.reset()
-		err = q.symbolize(&q.shared, &q.aux)
-		if err != nil {
-			return 0, err
+			q.aux.reset()
+			err = q.symbolize(&q.shared, &q.aux)
+			if err != nil {
+				return 0, err
+			}
 		}
 	}
 	// we round up rather than down for each
@@ -440,6 +545,62 @@ func (q *rowSplitter) Write(buf []byte) (int, error) {
 	return len(buf), err
 }
 
+// reuseSymtab tries to handle the BVM symbol table
+// at the start of buf without rebuilding q.shared.
+// Consecutive blocks in a segment usually have the
+// same symbol table or a symbol table that extends
+// the previous one, so rather than re-symbolizing
+// every consumer from scratch (and invalidating their
+// scratch buffers) we keep the current table if it
+// is byte-for-byte identical, or merge the new symbols
+// into it if the two tables agree on their common prefix.
+// Symbol IDs in the data stay valid in the merged table
+// because every ID in the new table refers to the same
+// string in the merged table.
+//
+// If st is non-nil, it holds the symbol table
+// in buf[:size] that has already been decoded
+// (see WriteSymtab), so reuseSymtab does not
+// have to decode the table itself.
+//
+// reuseSymtab returns the number of bytes of buf
+// occupied by the symbol table, or 0 if the caller
+// needs to load the symbol table from scratch.
+func (q *rowSplitter) reuseSymtab(buf []byte, st *ion.Symtab, size int) (int, error) {
+	if !q.symbolized || len(q.symraw) == 0 ||
+		q.shared.flags&sfZion != 0 || len(buf) < 4 || !ion.IsBVM(buf) {
+		return 0, nil
+	}
+	if st == nil {
+		size = ion.SizeOf(buf[4:])
+		if size <= 0 || 4+size > len(buf) {
+			return 0, nil // let Unmarshal produce the error
+		}
+		size += 4
+	}
+	if bytes.Equal(buf[:size], q.symraw) {
+		return size, nil
+	}
+	if st == nil {
+		if _, err := q.incoming.Unmarshal(buf[:size]); err != nil {
+			return 0, nil
+		}
+		st = &q.incoming
+	}
+	// drop any symbols interned by the consumers
+	// and then try to merge the new table into the
+	// symbols from the previous block
+	q.shared.rewind()
+	if _, ok := q.shared.Symtab.Merge(st); !ok {
+		return 0, nil
+	}
+	q.shared.build()
+	q.shared.snapshot()
+	q.symraw = append(q.symraw[:0], buf[:size]...)
+	q.aux.reset()
+	return size, q.symbolize(&q.shared, &q.aux)
+}
+
 // QueryBuffer is an in-memory implementation
 // of QuerySink that can be trivially converted
 // to a Table. It can be used to force a sub-query
@@ -589,8 +750,12 @@ func (m *Rematerializer) writeRows(delims []vmref, rp *rowParams) error {
 
 		// let BeginField handle the sorting
 		for _, pos := range m.auxpos {
+			v := rp.auxbound[pos][i]
+			if v[1] == 0 {
+				continue // MISSING
+			}
 			m.buf.BeginField(m.aux[pos])
-			m.buf.UnsafeAppend(rp.auxbound[pos][i].mem())
+			m.buf.UnsafeAppend(v.mem())
 		}
 
 		for len(mem) > 0 {
@@ -629,6 +794,11 @@ func (m *Rematerializer) writeRowsFast(delims []vmref) error {
 	return nil
 }
 
+// Throttle implements Throttler
+func (m *Rematerializer) Throttle() error {
+	return Throttle(m.out)
+}
+
 // Close implements io.Closer
 func (m *Rematerializer) Close() error {
 	err := m.flush()
@@ -681,5 +851,11 @@ func (s *sink) EndSegment() {
 	HintEndSegment(s.dst)
 }
 
+// Throttle does not take s.lock so that
+// concurrent writers can wait simultaneously
+func (s *sink) Throttle() error {
+	return Throttle(s.dst)
+}
+
 func (s *sink) Open() (io.WriteCloser, error) { return s, nil }
 func (s *sink) Close() error                  { return nil }
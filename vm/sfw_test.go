@@ -17,6 +17,7 @@ package vm
 import (
 	"bufio"
 	"bytes"
+	"fmt"
 	"io"
 	"strings"
 	"testing"
@@ -129,3 +130,96 @@ func TestRematerializeIssue561(t *testing.T) {
 		t.Errorf("found %d symbol tables; expected 2", stcount)
 	}
 }
+
+func TestSplitterReuseSymtab(t *testing.T) {
+	t.Run("write", func(t *testing.T) {
+		testSplitterReuseSymtab(t, false)
+	})
+	// symbol tables decoded ahead of time
+	// (see blockfmt.SymtabWriter) must behave
+	// the same way as the ones decoded by Write
+	t.Run("write-symtab", func(t *testing.T) {
+		testSplitterReuseSymtab(t, true)
+	})
+}
+
+func testSplitterReuseSymtab(t *testing.T, prepared bool) {
+	// each chunk has a symbol table with the given
+	// symbols (in order) followed by one row
+	type chunk struct {
+		symbols []string
+		reuse   bool // expect the symbol table to be reused
+	}
+	chunks := []chunk{
+		{symbols: []string{"foo", "bar", "x"}},
+		{symbols: []string{"foo", "bar", "x"}, reuse: true},
+		{symbols: []string{"foo", "bar", "x", "baz", "y"}, reuse: true},
+		{symbols: []string{"foo", "bar"}, reuse: true},
+		{symbols: []string{"bar", "foo", "x"}},
+		{symbols: []string{"bar", "foo", "x", "y"}, reuse: true},
+	}
+	var tmp bytes.Buffer
+	rc := asRowConsumer(&noClose{&tmp})
+	s := splitter(rc)
+
+	mem := Malloc()
+	defer Free(mem)
+	var want []string
+	for i := range chunks {
+		var st ion.Symtab
+		for _, sym := range chunks[i].symbols {
+			st.Intern(sym)
+		}
+		fields := []ion.Field{{Label: "foo", Datum: ion.Int(int64(i))}}
+		if n := len(chunks[i].symbols); n > 2 {
+			val := chunks[i].symbols[n-1]
+			fields = append(fields, ion.Field{Label: "bar", Datum: ion.Interned(&st, val)})
+			// fields are encoded in symbol ID order
+			format := `{"foo": %[1]d, "bar": %[2]q}`
+			if chunks[i].symbols[0] == "bar" {
+				format = `{"bar": %[2]q, "foo": %[1]d}`
+			}
+			want = append(want, fmt.Sprintf(format, i, val))
+		} else {
+			want = append(want, fmt.Sprintf(`{"foo": %d}`, i))
+		}
+		var body, buf ion.Buffer
+		ion.NewStruct(&st, fields).Datum().Encode(&body, &st)
+		st.Marshal(&buf, true)
+		buf.UnsafeAppend(body.Bytes())
+
+		epoch := s.shared.epoch
+		size := copy(mem, buf.Bytes())
+		noppad(mem[size:1024])
+		var err error
+		if prepared {
+			var pst ion.Symtab
+			rest, uerr := pst.Unmarshal(mem[:1024])
+			if uerr != nil {
+				t.Fatal(uerr)
+			}
+			_, err = s.WriteSymtab(mem[:1024], &pst, 1024-len(rest))
+		} else {
+			_, err = s.Write(mem[:1024])
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if reused := s.shared.epoch == epoch; i > 0 && reused != chunks[i].reuse {
+			t.Errorf("chunk %d: reused symbol table = %v, want %v", i, reused, chunks[i].reuse)
+		}
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	_, err := ion.ToJSON(&out, bufio.NewReader(&tmp))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := strings.TrimSpace(out.String())
+	if got != strings.Join(want, "\n") {
+		t.Errorf("got:\n%s", got)
+		t.Errorf("want:\n%s", strings.Join(want, "\n"))
+	}
+}
@@ -228,32 +228,32 @@ func rewrite1(p *prog, v *value) (*value, bool) {
 				}
 			}
 		}
-	case 72: /* cvt.k@i64 */
+	case 73: /* cvt.k@i64 */
 		if len(v.args) == 2 {
 			// (cvt.k@i64 (init) _) -> (broadcast.i 1)
 			if _tmp23 := v.args[0]; _tmp23.op == 1 {
-				return /* clobber v */ p.setssa(v, 148, 1), true
+				return /* clobber v */ p.setssa(v, 151, 1), true
 			}
 			// (cvt.k@i64 (false) _) -> (broadcast.i 0)
 			if _tmp24 := v.args[0]; _tmp24.op == 7 {
-				return /* clobber v */ p.setssa(v, 148, 0), true
+				return /* clobber v */ p.setssa(v, 151, 0), true
 			}
 		}
-	case 73: /* cvt.k@f64 */
+	case 74: /* cvt.k@f64 */
 		if len(v.args) == 2 {
 			// (cvt.k@f64 (init) _) -> (broadcast.f 1)
 			if _tmp25 := v.args[0]; _tmp25.op == 1 {
-				return /* clobber v */ p.setssa(v, 147, 1), true
+				return /* clobber v */ p.setssa(v, 150, 1), true
 			}
 			// (cvt.k@f64 (false) _) -> (broadcast.f 0)
 			if _tmp26 := v.args[0]; _tmp26.op == 7 {
-				return /* clobber v */ p.setssa(v, 147, 0), true
+				return /* clobber v */ p.setssa(v, 150, 0), true
 			}
 		}
-	case 74: /* cvt.i64@k */
+	case 75: /* cvt.i64@k */
 		if len(v.args) == 2 {
 			// (cvt.i64@k _tmp0:(broadcast.i imm) k) -> (and.k "p.choose(imm != 0)" k)
-			if _tmp0 := v.args[0]; _tmp0.op == 148 {
+			if _tmp0 := v.args[0]; _tmp0.op == 151 {
 				if k := v.args[1]; true {
 					if imm := toi64(_tmp0.imm); true {
 						return /* clobber v */ p.setssa(v, 8, nil, p.choose(imm != 0), k), true
@@ -261,7 +261,7 @@ func rewrite1(p *prog, v *value) (*value, bool) {
 				}
 			}
 		}
-	case 135: /* store.v */
+	case 137: /* store.v */
 		if len(v.args) == 3 {
 			// (store.v mem ov k:(false) slot), "ov != k" -> (store.v mem k k slot)
 			if mem := v.args[0]; true {
@@ -269,84 +269,84 @@ func rewrite1(p *prog, v *value) (*value, bool) {
 					if k := v.args[2]; k.op == 7 {
 						if slot := v.imm; true {
 							if ov != k {
-								return /* clobber v */ p.setssa(v, 135, slot, mem, k, k), true
+								return /* clobber v */ p.setssa(v, 137, slot, mem, k, k), true
 							}
 						}
 					}
 				}
 			}
 		}
-	case 142: /* make.vk */
+	case 144: /* make.vk */
 		if len(v.args) == 2 {
-			// (make.vk val k), "p.mask(val) == k" -> val
+			// (make.vk val k), "val.ret()&stBool != 0 && p.mask(val) == k" -> val
 			if val := v.args[0]; true {
 				if k := v.args[1]; true {
-					if p.mask(val) == k {
+					if val.ret()&stBool != 0 && p.mask(val) == k {
 						return val, true
 					}
 				}
 			}
 		}
-	case 143: /* floatk */
+	case 145: /* floatk */
 		if len(v.args) == 2 {
-			// (floatk f k), "p.mask(f) == k" -> f
+			// (floatk f k), "f.ret()&stBool != 0 && p.mask(f) == k" -> f
 			if f := v.args[0]; true {
 				if k := v.args[1]; true {
-					if p.mask(f) == k {
+					if f.ret()&stBool != 0 && p.mask(f) == k {
 						return f, true
 					}
 				}
 			}
 		}
-	case 145: /* blend.v */
+	case 147: /* blend.v */
 		if len(v.args) == 4 {
 			// (blend.v _ (false) y k) -> (make.vk y k)
 			if _tmp27 := v.args[1]; _tmp27.op == 7 {
 				if y := v.args[2]; true {
 					if k := v.args[3]; true {
-						return /* clobber v */ p.setssa(v, 142, nil, y, k), true
+						return /* clobber v */ p.setssa(v, 144, nil, y, k), true
 					}
 				}
 			}
 			// (blend.v _ _ y (init)) -> (make.vk y (init))
 			if y := v.args[2]; true {
 				if _tmp28 := v.args[3]; _tmp28.op == 1 {
-					return /* clobber v */ p.setssa(v, 142, nil, y, p.values[0]), true
+					return /* clobber v */ p.setssa(v, 144, nil, y, p.values[0]), true
 				}
 			}
 			// (blend.v x k _ (false)) -> (make.vk x k)
 			if x := v.args[0]; true {
 				if k := v.args[1]; true {
 					if _tmp29 := v.args[3]; _tmp29.op == 7 {
-						return /* clobber v */ p.setssa(v, 142, nil, x, k), true
+						return /* clobber v */ p.setssa(v, 144, nil, x, k), true
 					}
 				}
 			}
 		}
-	case 181: /* add.f */
+	case 184: /* add.f */
 		if len(v.args) == 3 {
 			// (add.f _tmp1:(broadcast.f imm) f k) -> (add.imm.f f k imm)
-			if _tmp1 := v.args[0]; _tmp1.op == 147 {
+			if _tmp1 := v.args[0]; _tmp1.op == 150 {
 				if f := v.args[1]; true {
 					if k := v.args[2]; true {
 						if imm := tof64(_tmp1.imm); true {
-							return /* clobber v */ p.setssa(v, 183, imm, f, k), true
+							return /* clobber v */ p.setssa(v, 186, imm, f, k), true
 						}
 					}
 				}
 			}
 			// (add.f f _tmp2:(broadcast.f imm) k) -> (add.imm.f f k imm)
 			if f := v.args[0]; true {
-				if _tmp2 := v.args[1]; _tmp2.op == 147 {
+				if _tmp2 := v.args[1]; _tmp2.op == 150 {
 					if k := v.args[2]; true {
 						if imm := tof64(_tmp2.imm); true {
-							return /* clobber v */ p.setssa(v, 183, imm, f, k), true
+							return /* clobber v */ p.setssa(v, 186, imm, f, k), true
 						}
 					}
 				}
 			}
 		}
-	case 183: /* add.imm.f */
+	case 186: /* add.imm.f */
 		if len(v.args) == 2 {
 			// (add.imm.f f _ 0) -> f
 			if f := v.args[0]; true {
@@ -355,7 +355,7 @@ func rewrite1(p *prog, v *value) (*value, bool) {
 				}
 			}
 		}
-	case 184: /* add.imm.i */
+	case 187: /* add.imm.i */
 		if len(v.args) == 2 {
 			// (add.imm.i i _ 0) -> i
 			if i := v.args[0]; true {
@@ -364,30 +364,30 @@ func rewrite1(p *prog, v *value) (*value, bool) {
 				}
 			}
 		}
-	case 185: /* sub.f */
+	case 188: /* sub.f */
 		if len(v.args) == 3 {
 			// (sub.f _tmp3:(broadcast.f imm) f k) -> (rsub.imm.f f k imm)
-			if _tmp3 := v.args[0]; _tmp3.op == 147 {
+			if _tmp3 := v.args[0]; _tmp3.op == 150 {
 				if f := v.args[1]; true {
 					if k := v.args[2]; true {
 						if imm := tof64(_tmp3.imm); true {
-							return /* clobber v */ p.setssa(v, 191, imm, f, k), true
+							return /* clobber v */ p.setssa(v, 194, imm, f, k), true
 						}
 					}
 				}
 			}
 			// (sub.f f _tmp4:(broadcast.f imm) k) -> (sub.imm.f f k imm)
 			if f := v.args[0]; true {
-				if _tmp4 := v.args[1]; _tmp4.op == 147 {
+				if _tmp4 := v.args[1]; _tmp4.op == 150 {
 					if k := v.args[2]; true {
 						if imm := tof64(_tmp4.imm); true {
-							return /* clobber v */ p.setssa(v, 187, imm, f, k), true
+							return /* clobber v */ p.setssa(v, 190, imm, f, k), true
 						}
 					}
 				}
 			}
 		}
-	case 187: /* sub.imm.f */
+	case 190: /* sub.imm.f */
 		if len(v.args) == 2 {
 			// (sub.imm.f f _ 0) -> f
 			if f := v.args[0]; true {
@@ -396,7 +396,7 @@ func rewrite1(p *prog, v *value) (*value, bool) {
 				}
 			}
 		}
-	case 188: /* sub.imm.i */
+	case 191: /* sub.imm.i */
 		if len(v.args) == 2 {
 			// (sub.imm.i i _ 0) -> i
 			if i := v.args[0]; true {
@@ -405,52 +405,52 @@ func rewrite1(p *prog, v *value) (*value, bool) {
 				}
 			}
 		}
-	case 191: /* rsub.imm.f */
+	case 194: /* rsub.imm.f */
 		if len(v.args) == 2 {
 			// (rsub.imm.f f k 0) -> (neg.f f k)
 			if f := v.args[0]; true {
 				if k := v.args[1]; true {
 					if tof64(v.imm) == 0 {
-						return /* clobber v */ p.setssa(v, 151, nil, f, k), true
+						return /* clobber v */ p.setssa(v, 154, nil, f, k), true
 					}
 				}
 			}
 		}
-	case 192: /* rsub.imm.i */
+	case 195: /* rsub.imm.i */
 		if len(v.args) == 2 {
 			// (rsub.imm.i i k 0) -> (neg.i i k)
 			if i := v.args[0]; true {
 				if k := v.args[1]; true {
 					if toi64(v.imm) == 0 {
-						return /* clobber v */ p.setssa(v, 152, nil, i, k), true
+						return /* clobber v */ p.setssa(v, 155, nil, i, k), true
 					}
 				}
 			}
 		}
-	case 193: /* mul.f */
+	case 196: /* mul.f */
 		if len(v.args) == 3 {
 			// (mul.f _tmp5:(broadcast.f imm) f k) -> (mul.imm.f f k imm)
-			if _tmp5 := v.args[0]; _tmp5.op == 147 {
+			if _tmp5 := v.args[0]; _tmp5.op == 150 {
 				if f := v.args[1]; true {
 					if k := v.args[2]; true {
 						if imm := tof64(_tmp5.imm); true {
-							return /* clobber v */ p.setssa(v, 195, imm, f, k), true
+							return /* clobber v */ p.setssa(v, 198, imm, f, k), true
 						}
 					}
 				}
 			}
 			// (mul.f f _tmp6:(broadcast.f imm) k) -> (mul.imm.f f k imm)
 			if f := v.args[0]; true {
-				if _tmp6 := v.args[1]; _tmp6.op == 147 {
+				if _tmp6 := v.args[1]; _tmp6.op == 150 {
 					if k := v.args[2]; true {
 						if imm := tof64(_tmp6.imm); true {
-							return /* clobber v */ p.setssa(v, 195, imm, f, k), true
+							return /* clobber v */ p.setssa(v, 198, imm, f, k), true
 						}
 					}
 				}
 			}
 		}
-	case 195: /* mul.imm.f */
+	case 198: /* mul.imm.f */
 		if len(v.args) == 2 {
 			// (mul.imm.f f _ 1) -> f
 			if f := v.args[0]; true {
@@ -459,7 +459,7 @@ func rewrite1(p *prog, v *value) (*value, bool) {
 				}
 			}
 		}
-	case 196: /* mul.imm.i */
+	case 199: /* mul.imm.i */
 		if len(v.args) == 2 {
 			// (mul.imm.i i _ 1) -> i
 			if i := v.args[0]; true {
@@ -468,30 +468,30 @@ func rewrite1(p *prog, v *value) (*value, bool) {
 				}
 			}
 		}
-	case 197: /* div.f */
+	case 200: /* div.f */
 		if len(v.args) == 3 {
 			// (div.f f _tmp7:(broadcast.f imm) k) -> (div.imm.f f k imm)
 			if f := v.args[0]; true {
-				if _tmp7 := v.args[1]; _tmp7.op == 147 {
+				if _tmp7 := v.args[1]; _tmp7.op == 150 {
 					if k := v.args[2]; true {
 						if imm := tof64(_tmp7.imm); true {
-							return /* clobber v */ p.setssa(v, 199, imm, f, k), true
+							return /* clobber v */ p.setssa(v, 202, imm, f, k), true
 						}
 					}
 				}
 			}
 			// (div.f _tmp8:(broadcast.f imm) f k) -> (rdiv.imm.f f k imm)
-			if _tmp8 := v.args[0]; _tmp8.op == 147 {
+			if _tmp8 := v.args[0]; _tmp8.op == 150 {
 				if f := v.args[1]; true {
 					if k := v.args[2]; true {
 						if imm := tof64(_tmp8.imm); true {
-							return /* clobber v */ p.setssa(v, 201, imm, f, k), true
+							return /* clobber v */ p.setssa(v, 204, imm, f, k), true
 						}
 					}
 				}
 			}
 		}
-	case 220: /* or.imm.i */
+	case 223: /* or.imm.i */
 		if len(v.args) == 2 {
 			// (or.imm.i i _ 0) -> i
 			if i := v.args[0]; true {
@@ -500,7 +500,7 @@ func rewrite1(p *prog, v *value) (*value, bool) {
 				}
 			}
 		}
-	case 224: /* sll.imm.i */
+	case 227: /* sll.imm.i */
 		if len(v.args) == 2 {
 			// (sll.imm.i i _ 0) -> i
 			if i := v.args[0]; true {
@@ -509,7 +509,7 @@ func rewrite1(p *prog, v *value) (*value, bool) {
 				}
 			}
 		}
-	case 226: /* sra.imm.i */
+	case 229: /* sra.imm.i */
 		if len(v.args) == 2 {
 			// (sra.imm.i i _ 0) -> i
 			if i := v.args[0]; true {
@@ -518,7 +518,7 @@ func rewrite1(p *prog, v *value) (*value, bool) {
 				}
 			}
 		}
-	case 228: /* srl.imm.i */
+	case 231: /* srl.imm.i */
 		if len(v.args) == 2 {
 			// (srl.imm.i i _ 0) -> i
 			if i := v.args[0]; true {
@@ -527,7 +527,7 @@ func rewrite1(p *prog, v *value) (*value, bool) {
 				}
 			}
 		}
-	case 236: /* aggand.k */
+	case 239: /* aggand.k */
 		if len(v.args) == 3 {
 			// (aggand.k mem _ (false) _) -> mem
 			if mem := v.args[0]; true {
@@ -536,7 +536,7 @@ func rewrite1(p *prog, v *value) (*value, bool) {
 				}
 			}
 		}
-	case 237: /* aggor.k */
+	case 240: /* aggor.k */
 		if len(v.args) == 3 {
 			// (aggor.k mem _ (false) _) -> mem
 			if mem := v.args[0]; true {
@@ -545,7 +545,7 @@ func rewrite1(p *prog, v *value) (*value, bool) {
 				}
 			}
 		}
-	case 238: /* aggsum.f */
+	case 241: /* aggsum.f */
 		if len(v.args) == 3 {
 			// (aggsum.f mem _ (false) _) -> mem
 			if mem := v.args[0]; true {
@@ -554,7 +554,7 @@ func rewrite1(p *prog, v *value) (*value, bool) {
 				}
 			}
 		}
-	case 239: /* aggsum.i */
+	case 242: /* aggsum.i */
 		if len(v.args) == 3 {
 			// (aggsum.i mem _ (false) _) -> mem
 			if mem := v.args[0]; true {
@@ -563,7 +563,7 @@ func rewrite1(p *prog, v *value) (*value, bool) {
 				}
 			}
 		}
-	case 242: /* aggmin.f */
+	case 245: /* aggmin.f */
 		if len(v.args) == 3 {
 			// (aggmin.f mem _ (false) _) -> mem
 			if mem := v.args[0]; true {
@@ -572,7 +572,7 @@ func rewrite1(p *prog, v *value) (*value, bool) {
 				}
 			}
 		}
-	case 243: /* aggmin.i */
+	case 246: /* aggmin.i */
 		if len(v.args) == 3 {
 			// (aggmin.i mem _ (false) _) -> mem
 			if mem := v.args[0]; true {
@@ -581,7 +581,7 @@ func rewrite1(p *prog, v *value) (*value, bool) {
 				}
 			}
 		}
-	case 244: /* aggmax.f */
+	case 247: /* aggmax.f */
 		if len(v.args) == 3 {
 			// (aggmax.f mem _ (false) _) -> mem
 			if mem := v.args[0]; true {
@@ -590,7 +590,7 @@ func rewrite1(p *prog, v *value) (*value, bool) {
 				}
 			}
 		}
-	case 245: /* aggmax.i */
+	case 248: /* aggmax.i */
 		if len(v.args) == 3 {
 			// (aggmax.i mem _ (false) _) -> mem
 			if mem := v.args[0]; true {
@@ -599,7 +599,7 @@ func rewrite1(p *prog, v *value) (*value, bool) {
 				}
 			}
 		}
-	case 246: /* aggmin.ts */
+	case 249: /* aggmin.ts */
 		if len(v.args) == 3 {
 			// (aggmin.ts mem _ (false) _) -> mem
 			if mem := v.args[0]; true {
@@ -608,7 +608,7 @@ func rewrite1(p *prog, v *value) (*value, bool) {
 				}
 			}
 		}
-	case 247: /* aggmax.ts */
+	case 250: /* aggmax.ts */
 		if len(v.args) == 3 {
 			// (aggmax.ts mem _ (false) _) -> mem
 			if mem := v.args[0]; true {
@@ -617,7 +617,7 @@ func rewrite1(p *prog, v *value) (*value, bool) {
 				}
 			}
 		}
-	case 248: /* aggand.i */
+	case 251: /* aggand.i */
 		if len(v.args) == 3 {
 			// (aggand.i mem _ (false) _) -> mem
 			if mem := v.args[0]; true {
@@ -626,7 +626,7 @@ func rewrite1(p *prog, v *value) (*value, bool) {
 				}
 			}
 		}
-	case 249: /* aggor.i */
+	case 252: /* aggor.i */
 		if len(v.args) == 3 {
 			// (aggor.i mem _ (false) _) -> mem
 			if mem := v.args[0]; true {
@@ -635,7 +635,7 @@ func rewrite1(p *prog, v *value) (*value, bool) {
 				}
 			}
 		}
-	case 250: /* aggxor.i */
+	case 253: /* aggxor.i */
 		if len(v.args) == 3 {
 			// (aggxor.i mem _ (false) _) -> mem
 			if mem := v.args[0]; true {
@@ -644,7 +644,7 @@ func rewrite1(p *prog, v *value) (*value, bool) {
 				}
 			}
 		}
-	case 251: /* aggcount */
+	case 254: /* aggcount */
 		if len(v.args) == 2 {
 			// (aggcount mem (false) _) -> mem
 			if mem := v.args[0]; true {
@@ -653,7 +653,7 @@ func rewrite1(p *prog, v *value) (*value, bool) {
 				}
 			}
 		}
-	case 253: /* aggslotand.k */
+	case 256: /* aggslotand.k */
 		if len(v.args) == 4 {
 			// (aggslotand.k mem _ _ (false) _) -> mem
 			if mem := v.args[0]; true {
@@ -662,7 +662,7 @@ func rewrite1(p *prog, v *value) (*value, bool) {
 				}
 			}
 		}
-	case 254: /* aggslotor.k */
+	case 257: /* aggslotor.k */
 		if len(v.args) == 4 {
 			// (aggslotor.k mem _ _ (false) _) -> mem
 			if mem := v.args[0]; true {
@@ -671,7 +671,7 @@ func rewrite1(p *prog, v *value) (*value, bool) {
 				}
 			}
 		}
-	case 255: /* aggslotsum.f */
+	case 258: /* aggslotsum.f */
 		if len(v.args) == 4 {
 			// (aggslotsum.f mem _ _ (false) _) -> mem
 			if mem := v.args[0]; true {
@@ -680,7 +680,7 @@ func rewrite1(p *prog, v *value) (*value, bool) {
 				}
 			}
 		}
-	case 256: /* aggslotsum.i */
+	case 259: /* aggslotsum.i */
 		if len(v.args) == 4 {
 			// (aggslotsum.i mem _ _ (false) _) -> mem
 			if mem := v.args[0]; true {
@@ -689,7 +689,7 @@ func rewrite1(p *prog, v *value) (*value, bool) {
 				}
 			}
 		}
-	case 259: /* aggslotmin.f */
+	case 262: /* aggslotmin.f */
 		if len(v.args) == 4 {
 			// (aggslotmin.f mem _ _ (false) _) -> mem
 			if mem := v.args[0]; true {
@@ -698,7 +698,7 @@ func rewrite1(p *prog, v *value) (*value, bool) {
 				}
 			}
 		}
-	case 260: /* aggslotmin.i */
+	case 263: /* aggslotmin.i */
 		if len(v.args) == 4 {
 			// (aggslotmin.i mem _ _ (false) _) -> mem
 			if mem := v.args[0]; true {
@@ -707,7 +707,7 @@ func rewrite1(p *prog, v *value) (*value, bool) {
 				}
 			}
 		}
-	case 261: /* aggslotmax.f */
+	case 264: /* aggslotmax.f */
 		if len(v.args) == 4 {
 			// (aggslotmax.f mem _ _ (false) _) -> mem
 			if mem := v.args[0]; true {
@@ -716,7 +716,7 @@ func rewrite1(p *prog, v *value) (*value, bool) {
 				}
 			}
 		}
-	case 262: /* aggslotmax.i */
+	case 265: /* aggslotmax.i */
 		if len(v.args) == 4 {
 			// (aggslotmax.i mem _ _ (false) _) -> mem
 			if mem := v.args[0]; true {
@@ -725,7 +725,7 @@ func rewrite1(p *prog, v *value) (*value, bool) {
 				}
 			}
 		}
-	case 263: /* aggslotmin.ts */
+	case 266: /* aggslotmin.ts */
 		if len(v.args) == 4 {
 			// (aggslotmin.ts mem _ _ (false) _) -> mem
 			if mem := v.args[0]; true {
@@ -734,7 +734,7 @@ func rewrite1(p *prog, v *value) (*value, bool) {
 				}
 			}
 		}
-	case 264: /* aggslotmax.ts */
+	case 267: /* aggslotmax.ts */
 		if len(v.args) == 4 {
 			// (aggslotmax.ts mem _ _ (false) _) -> mem
 			if mem := v.args[0]; true {
@@ -743,7 +743,7 @@ func rewrite1(p *prog, v *value) (*value, bool) {
 				}
 			}
 		}
-	case 265: /* aggslotand.i */
+	case 268: /* aggslotand.i */
 		if len(v.args) == 4 {
 			// (aggslotand.i mem _ _ (false) _) -> mem
 			if mem := v.args[0]; true {
@@ -752,7 +752,7 @@ func rewrite1(p *prog, v *value) (*value, bool) {
 				}
 			}
 		}
-	case 266: /* aggslotor.i */
+	case 269: /* aggslotor.i */
 		if len(v.args) == 4 {
 			// (aggslotor.i mem _ _ (false) _) -> mem
 			if mem := v.args[0]; true {
@@ -761,7 +761,7 @@ func rewrite1(p *prog, v *value) (*value, bool) {
 				}
 			}
 		}
-	case 267: /* aggslotxor.i */
+	case 270: /* aggslotxor.i */
 		if len(v.args) == 4 {
 			// (aggslotxor.i mem _ _ (false) _) -> mem
 			if mem := v.args[0]; true {
@@ -770,7 +770,7 @@ func rewrite1(p *prog, v *value) (*value, bool) {
 				}
 			}
 		}
-	case 268: /* aggslotcount */
+	case 271: /* aggslotcount */
 		if len(v.args) == 3 {
 			// (aggslotcount mem _ (false) _) -> mem
 			if mem := v.args[0]; true {
@@ -779,36 +779,36 @@ func rewrite1(p *prog, v *value) (*value, bool) {
 				}
 			}
 		}
-	case 318: /* boxint */
+	case 328: /* boxint */
 		if len(v.args) == 2 {
 			// (boxint _tmp9:(broadcast.i lit) _) -> (literal lit)
-			if _tmp9 := v.args[0]; _tmp9.op == 148 {
+			if _tmp9 := v.args[0]; _tmp9.op == 151 {
 				if lit := toi64(_tmp9.imm); true {
-					return /* clobber v */ p.setssa(v, 129, lit), true
+					return /* clobber v */ p.setssa(v, 131, lit), true
 				}
 			}
 		}
-	case 319: /* boxfloat */
+	case 329: /* boxfloat */
 		if len(v.args) == 2 {
 			// (boxfloat _tmp10:(broadcast.f lit) _) -> (literal lit)
-			if _tmp10 := v.args[0]; _tmp10.op == 147 {
+			if _tmp10 := v.args[0]; _tmp10.op == 150 {
 				if lit := tof64(_tmp10.imm); true {
-					return /* clobber v */ p.setssa(v, 129, lit), true
+					return /* clobber v */ p.setssa(v, 131, lit), true
 				}
 			}
 		}
-	case 321: /* boxts */
+	case 331: /* boxts */
 		if len(v.args) == 2 {
 			// (boxts _tmp11:(broadcast.ts lit) _), "ts := date.UnixMicro(int64(lit)); true" -> (literal ts)
-			if _tmp11 := v.args[0]; _tmp11.op == 269 {
+			if _tmp11 := v.args[0]; _tmp11.op == 272 {
 				if lit := toi64(_tmp11.imm); true {
 					if ts := date.UnixMicro(int64(lit)); true {
-						return /* clobber v */ p.setssa(v, 129, ts), true
+						return /* clobber v */ p.setssa(v, 131, ts), true
 					}
 				}
 			}
 		}
-	case 328: /* aggapproxcount */
+	case 338: /* aggapproxcount */
 		if len(v.args) == 2 {
 			// (aggapproxcount mem (false) _) -> mem
 			if mem := v.args[0]; true {
@@ -817,7 +817,7 @@ func rewrite1(p *prog, v *value) (*value, bool) {
 				}
 			}
 		}
-	case 329: /* aggapproxcount.partial */
+	case 339: /* aggapproxcount.partial */
 		if len(v.args) == 2 {
 			// (aggapproxcount.partial mem (false) _) -> mem
 			if mem := v.args[0]; true {
@@ -826,7 +826,7 @@ func rewrite1(p *prog, v *value) (*value, bool) {
 				}
 			}
 		}
-	case 330: /* aggapproxcount.merge */
+	case 340: /* aggapproxcount.merge */
 		if len(v.args) == 2 {
 			// (aggapproxcount.merge mem (false) _) -> mem
 			if mem := v.args[0]; true {
@@ -835,7 +835,7 @@ func rewrite1(p *prog, v *value) (*value, bool) {
 				}
 			}
 		}
-	case 331: /* aggslotapproxcount */
+	case 341: /* aggslotapproxcount */
 		if len(v.args) == 4 {
 			// (aggslotapproxcount mem _ _ (false) _) -> mem
 			if mem := v.args[0]; true {
@@ -844,7 +844,7 @@ func rewrite1(p *prog, v *value) (*value, bool) {
 				}
 			}
 		}
-	case 332: /* aggslotapproxcount.partial */
+	case 342: /* aggslotapproxcount.partial */
 		if len(v.args) == 4 {
 			// (aggslotapproxcount.partial mem _ _ (false) _) -> mem
 			if mem := v.args[0]; true {
@@ -853,7 +853,7 @@ func rewrite1(p *prog, v *value) (*value, bool) {
 				}
 			}
 		}
-	case 333: /* aggslotapproxcount.merge */
+	case 343: /* aggslotapproxcount.merge */
 		if len(v.args) == 4 {
 			// (aggslotapproxcount.merge mem _ _ (false) _) -> mem
 			if mem := v.args[0]; true {
@@ -25,9 +25,9 @@ type pageref struct {
 	off int    // allocation offset
 }
 
-func (p *pageref) drop() {
+func (p *pageref) drop(a *Account) {
 	if p.mem != nil {
-		Free(p.mem)
+		a.free(p.mem)
 		p.mem = nil
 	}
 	p.off = 0
@@ -37,12 +37,13 @@ type slab struct {
 	_        noCopy
 	pages    []pageref
 	oldpages []pageref // recorded in snapshot()
+	acct     *Account  // charged for pages, if non-nil
 }
 
 // reset rewinds the slab state
 func (s *slab) reset() {
 	for i := range s.pages {
-		s.pages[i].drop()
+		s.pages[i].drop(s.acct)
 	}
 	s.pages = s.pages[:0]
 	s.oldpages = s.oldpages[:0]
@@ -55,7 +56,7 @@ func (s *slab) resetNoFree() {
 	default:
 		tail := s.pages[1:]
 		for i := range tail {
-			tail[i].drop()
+			tail[i].drop(s.acct)
 		}
 		s.pages = s.pages[:1]
 		fallthrough
@@ -83,7 +84,7 @@ func (s *slab) rewind() {
 	}
 	tail := s.pages[len(s.oldpages):]
 	for i := range tail {
-		tail[i].drop()
+		tail[i].drop(s.acct)
 	}
 	s.pages = append(s.pages[:0], s.oldpages...) // restore old state
 	s.oldpages = s.oldpages[:0]                  // invalidate snapshot
@@ -109,7 +110,10 @@ func (s *slab) malloc(n int) []byte {
 			return mem[:n:need]
 		}
 	}
-	mem := Malloc()
+	mem, err := s.acct.malloc()
+	if err != nil {
+		panic(err)
+	}
 	s.pages = append(s.pages, pageref{mem: mem, off: need})
 	return mem[:n:need]
 }
@@ -211,6 +211,9 @@ func (s *Order) finalizeKtop() error {
 		out = append(out, tmp.Bytes()[:slice]...)
 		globalst.Reset()
 		tmp.Reset()
+		if err := Throttle(s.dst); err != nil {
+			return err
+		}
 		_, err := s.dst.Write(out)
 		return err
 	}
@@ -628,8 +631,12 @@ outer:
 			data = data[size:]
 		}
 		for j := range s.auxsyms {
+			v := rp.auxbound[j][rowID]
+			if v[1] == 0 {
+				continue // MISSING
+			}
 			s.scratch.BeginField(s.auxsyms[j])
-			s.scratch.UnsafeAppend(rp.auxbound[j][rowID].mem())
+			s.scratch.UnsafeAppend(v.mem())
 		}
 		s.scratch.EndStruct()
 		dat, _, _ := ion.ReadDatum(&s.st.Symtab, s.scratch.Bytes())
@@ -23,7 +23,6 @@ import (
 	"strconv"
 
 	"golang.org/x/exp/slices"
-	"golang.org/x/sys/cpu"
 
 	"github.com/SnellerInc/sneller/date"
 	"github.com/SnellerInc/sneller/expr"
@@ -156,6 +155,8 @@ func (p *prog) tobits(imm any) uint64 {
 		return uint64(v)
 	case string:
 		return p.binaryDataToBits(v)
+	case symlike:
+		return p.binaryDataToBits(v.key())
 	case bool:
 		if v {
 			return 1
@@ -627,9 +628,14 @@ func (p *prog) notMissing(v *value) *value {
 		return v.notMissing
 	}
 	nonLogical := func(v *value) bool {
-		info := ssainfo[v.op].argtypes
-		for i := range info {
-			if info[i] != stBool {
+		info := &ssainfo[v.op]
+		for i := range info.argtypes {
+			if info.argtypes[i] != stBool {
+				return true
+			}
+		}
+		for i := range info.vaArgs {
+			if info.vaArgs[i] != stBool {
 				return true
 			}
 		}
@@ -1229,6 +1235,31 @@ func (p *prog) concat(args ...*value) *value {
 	return p.ssava(sstrconcat, values)
 }
 
+// concatWS concatenates args separated by sep,
+// skipping the arguments that are missing
+func (p *prog) concatWS(sep *value, args ...*value) *value {
+	sep = p.coerceStr(sep)
+	values := make([]*value, 0, len(args)*4+1)
+	values = append(values, p.mask(sep))
+
+	// seen is the set of lanes in which
+	// at least one argument has been present,
+	// so the next argument needs a separator
+	var seen *value
+	for _, arg := range args {
+		s := p.coerceStr(arg)
+		k := p.mask(s)
+		if seen == nil {
+			seen = k
+		} else {
+			values = append(values, sep, p.and(seen, k))
+			seen = p.or(seen, k)
+		}
+		values = append(values, s, k)
+	}
+	return p.ssava(sstrconcatskip, values)
+}
+
 func (p *prog) makeList(args ...*value) *value {
 	var values []*value = make([]*value, 0, len(args)*2+1)
 
@@ -1622,7 +1653,7 @@ func (p *prog) regexMatch(str *value, store *regexp2.DFAStore) (*value, error) {
 		}
 		return p.ssa0(skfalse), nil
 	}
-	if cpu.X86.HasAVX512VBMI && !store.HasUnicodeEdge() {
+	if isSupported(opDfaT6) && !store.HasUnicodeEdge() {
 		hasRLZA := store.HasRLZA()
 		hasWildcard, wildcardRange := store.HasUnicodeWildcard()
 		if dsTiny, err := regexp2.NewDsTiny(store); err == nil {
@@ -1970,7 +2001,7 @@ func (p *prog) andn(left, right *value) *value {
 	// usually we hit this with Not(Not(x)),
 	// as it would show up as (andn (andn x true) true)
 	if left.op == sandn && left.args[1] == right {
-		return p.and(left, right)
+		return p.and(left.args[0], right)
 	}
 	return p.ssa2(sandn, left, right)
 }
@@ -2372,6 +2403,9 @@ var timePartMultiplier = [...]uint64{
 	expr.Month:       0,
 	expr.Quarter:     0,
 	expr.Year:        0,
+	expr.ISODOW:      0,
+	expr.ISOYear:     0,
+	expr.ISOWeek:     0,
 }
 
 func (p *prog) dateAdd(part expr.Timepart, arg0, arg1 *value) *value {
@@ -2470,6 +2504,14 @@ func (p *prog) dateExtract(part expr.Timepart, val *value) *value {
 		return p.ssa2(sdateextractdow, v, m)
 	case expr.DOY:
 		return p.ssa2(sdateextractdoy, v, m)
+	case expr.ISODOW:
+		return p.isodow(v, m)
+	case expr.ISOYear:
+		// the ISO year is the year of the Thursday
+		// of the ISO week, which is (4 - ISODOW) days away
+		delta := p.ssa2imm(srsubimmi, p.isodow(v, m), m, 4)
+		thursday := p.ssa3imm(sdateaddmulimm, v, delta, m, timePartMultiplier[expr.Day])
+		return p.ssa2(sdateextractyear, thursday, m)
 	case expr.Month:
 		return p.ssa2(sdateextractmonth, v, m)
 	case expr.Quarter:
@@ -2481,6 +2523,15 @@ func (p *prog) dateExtract(part expr.Timepart, val *value) *value {
 	}
 }
 
+// isodow computes the ISO day of week (Monday = 1, Sunday = 7)
+// from the day of week (Sunday = 0, Saturday = 6) as ((DOW + 6) % 7) + 1
+func (p *prog) isodow(v, m *value) *value {
+	dow := p.ssa2(sdateextractdow, v, m)
+	dow = p.ssa2imm(saddimmi, dow, m, 6)
+	dow = p.ssa2imm(smodimmi, dow, m, 7)
+	return p.ssa2imm(saddimmi, dow, m, 1)
+}
+
 func (p *prog) dateToUnixEpoch(val *value) *value {
 	v, m := p.coerceTimestamp(val)
 	return p.ssa2(sdatetounixepoch, v, m)
@@ -2617,6 +2668,80 @@ func (p *prog) arrayPosition(array, item *value) *value {
 	return p.ssa3(sarrayposition, array, item, mask)
 }
 
+// arrayElement selects the element of the list v at
+// the position i, which is computed at runtime;
+// a negative position counts from the end of the list
+func (p *prog) arrayElement(v, i *value) *value {
+	l := p.tolist(v)
+	i, imask := p.coerceI64(i)
+	mask := p.and(p.mask(l), imask)
+	// only the lanes that count from the end
+	// need to compute the size of the list
+	neg := p.ssa2imm(scmpltimmi, i, mask, int64(0))
+	end := p.ssa3(saddi, i, p.ssa2(sarraysize, l, neg), neg)
+	i = p.ssa4(sblendi64, i, mask, end, end)
+	return p.ssa3(sarrayelement, l, i, p.mask(i))
+}
+
+// structBase returns the interior of
+// the struct v (see stuples)
+func (p *prog) structBase(v *value) *value {
+	if v.primary() != stBase {
+		v = p.ssa2(stuples, v, p.mask(v))
+	}
+	return v
+}
+
+// objectMerge merges the fields of the structs
+// a and b; the fields of b take precedence
+func (p *prog) objectMerge(a, b *value) *value {
+	a = p.structBase(a)
+	b = p.structBase(b)
+	return p.ssa3(sobjectmerge, a, b, p.and(p.mask(a), p.mask(b)))
+}
+
+// objectSet sets the field key of the struct v to val
+func (p *prog) objectSet(v *value, key string, val *value) *value {
+	s := p.makeStruct([]*value{p.validLanes(), p.ssa0imm(smakestructkey, key), val, p.mask(val)})
+	return p.objectMerge(v, s)
+}
+
+// objectDelete removes the field key from the struct v
+func (p *prog) objectDelete(v *value, key string) *value {
+	v = p.structBase(v)
+	return p.ssa2imm(sobjectdelete, v, p.mask(v), key)
+}
+
+// listField selects the field col from
+// each struct in the list v; elements that are
+// not structs or that don't have the field are
+// not part of the result
+func (p *prog) listField(v *value, col string) *value {
+	v = p.tolist(v)
+	return p.ssa2imm(slistfield, v, p.mask(v), col)
+}
+
+// listValues collects the values of
+// each struct in the list v into a list
+func (p *prog) listValues(v *value) *value {
+	v = p.tolist(v)
+	return p.ssa2(slistvalues, v, p.mask(v))
+}
+
+// listFlatten concatenates the elements
+// of each list in the list v into a list
+func (p *prog) listFlatten(v *value) *value {
+	v = p.tolist(v)
+	return p.ssa2(slistflatten, v, p.mask(v))
+}
+
+// structValues collects the values
+// of the struct v into a list
+func (p *prog) structValues(v *value) *value {
+	v = p.structBase(v)
+	return p.ssa2(sstructvalues, v, p.mask(v))
+}
+
 func emitNone(v *value, c *compilestate) {
 	// does nothing...
 }
@@ -3406,10 +3531,11 @@ type compilestate struct {
 	lr    lranges  // variable live ranges
 	stack stackmap // stack map
 
-	trees  []*radixTree64
-	asm    assembler
-	dict   []string
-	litbuf []byte // output datum literals
+	trees    []*radixTree64
+	symmatch []symmatcher
+	asm      assembler
+	dict     []string
+	litbuf   []byte // output datum literals
 }
 
 func (c *compilestate) emit(v *value, op bcop, args ...any) {
@@ -3564,7 +3690,7 @@ func (c *compilestate) slotOf(v *value, rc regclass) stackslot {
 func (c *compilestate) dictimm(str string) uint16 {
 	n := -1
 	for i := range c.dict {
-		if c.dict[i] == str {
+		if c.dict[i] == str && !c.symmatchslot(i) {
 			n = i
 			break
 		}
@@ -3934,6 +4060,22 @@ func emitConcatStr(v *value, c *compilestate) {
 	c.emit(v, info.bc, args...)
 }
 
+func emitConcatStrSkip(v *value, c *compilestate) {
+	if len(v.args)&1 != 1 {
+		panic(fmt.Sprintf("The number of arguments to emitConcatStrSkip() must be odd, not %d", len(v.args)))
+	}
+
+	args := make([]any, len(v.args))
+	args[0] = c.slotOf(v.args[0], regK)
+	for i := 1; i < len(args); i += 2 {
+		args[i+0] = c.slotOf(v.args[i+0], regS)
+		args[i+1] = c.slotOf(v.args[i+1], regK)
+	}
+
+	info := &ssainfo[v.op]
+	c.emit(v, info.bc, args...)
+}
+
 func emitMakeList(v *value, c *compilestate) {
 	args := make([]any, 0, 1+len(v.args)-1)
 	args = append(args, c.slotOf(v.args[0], regK))
@@ -4128,6 +4270,7 @@ func (p *prog) compile(dst *bytecode, st *symtab, callerName string) error {
 	dst.allocStacks()
 	dst.trees = c.trees
 	dst.dict = c.dict
+	dst.symmatch = c.symmatch
 	dst.compiled = c.asm.grabCode()
 
 	reserve := c.asm.scratchuse + len(c.litbuf)
@@ -4231,22 +4374,28 @@ func (p *prog) cloneSymbolize(st *symtab, dst *prog, aux *auxbindings) error {
 	return dst.symbolize(st, aux)
 }
 
-// unsymbolized takes an stValue-typed instruction
-// and ensures that the result is never a symbol
-func (p *prog) unsymbolized(v *value) *value {
+// maysymbol returns whether the stValue-typed
+// instruction v may produce a symbol
+func maysymbol(v *value) bool {
 	switch v.op {
-	case sdot, sdot2, ssplit, sauxval:
-		return p.ssa2(sunsymbolize, v, p.mask(v))
+	case sdot, sdot2, ssplit, sarrayelement, sauxval:
+		return true
 	case schecktag:
 		// checktag that includes symbol bits
 		// may also yield a symbol result:
-		if v.imm.(uint16)&uint16(expr.SymbolType) != 0 {
-			return p.ssa2(sunsymbolize, v, p.mask(v))
-		}
-		fallthrough
+		return v.imm.(uint16)&uint16(expr.SymbolType) != 0
 	default: // can never be a symbol
-		return v
+		return false
+	}
+}
+
+// unsymbolized takes an stValue-typed instruction
+// and ensures that the result is never a symbol
+func (p *prog) unsymbolized(v *value) *value {
+	if maysymbol(v) {
+		return p.ssa2(sunsymbolize, v, p.mask(v))
 	}
+	return v
 }
 
 // recompile updates the final bytecode
@@ -4357,6 +4506,24 @@ func (p *prog) symbolize(st *symtab, aux *auxbindings) error {
 			}
 			v.imm = sym
 			p.record(str, sym)
+		case slistfield, sobjectdelete:
+			str := v.imm.(string)
+			sym, ok := st.Symbolize(str)
+			if !ok {
+				// no struct can contain the field,
+				// but the op still produces a result
+				// (an empty list or a copy of the struct);
+				// use a symbol ID that never matches
+				// a label instead of removing the op
+				p.recordEmpty(str)
+				v.imm = ion.Symbol(MaxSymbolID + 1)
+				continue
+			}
+			if sym > MaxSymbolID {
+				return fmt.Errorf("symbol %x (%q) greater than max symbol ID", sym, str)
+			}
+			v.imm = sym
+			p.record(str, sym)
 		case smakestructkey:
 			str := v.imm.(string)
 			sym := st.Intern(str)
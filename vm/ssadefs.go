@@ -109,6 +109,7 @@ const (
 	stolist
 	stoblob
 	sunsymbolize
+	ssymmatch // LIKE on symbols using per-symbol results
 
 	scvtktoi64   // bool to 0 or 1
 	scvtktof64   // bool to 0.0 or 1.0
@@ -119,7 +120,8 @@ const (
 
 	scvti64tostr // int64 to string
 
-	sstrconcat // string concatenation
+	sstrconcat     // string concatenation
+	sstrconcatskip // string concatenation skipping missing arguments
 
 	slowerstr
 	supperstr
@@ -205,6 +207,7 @@ const (
 	// blend ops (just conditional moves)
 	sblendv
 	sblendf64
+	sblendi64
 
 	// broadcasts a constant to all lanes
 	sbroadcastf // out = broadcast(float64(imm))
@@ -387,6 +390,15 @@ const (
 	sobjectsize // built-in function SIZE()
 	sarraysize
 	sarrayposition
+	sarrayelement
+
+	sobjectmerge  // OBJECT_MERGE(a, b)
+	sobjectdelete // OBJECT_DELETE(x, 'key')
+
+	slistfield    // x[*].field
+	slistvalues   // x[*].*
+	slistflatten  // x[*][*]
+	sstructvalues // x.*
 
 	sboxmask  // box a mask
 	sboxint   // box an integer
@@ -728,6 +740,7 @@ var _ssainfo = [_ssamax]ssaopinfo{
 	stoblob: {text: "toblob", argtypes: scalar1Args, rettype: stBlobMasked, bc: opunpack, emit: emitslice},
 
 	sunsymbolize: {text: "unsymbolize", argtypes: scalar1Args, rettype: stValue, bc: opunsymbolize, safeValueMask: true},
+	ssymmatch:    {text: "symmatch", argtypes: scalar1Args, rettype: stBool, immfmt: fmtother, bc: opsymmatch, emit: emitsymmatch},
 
 	// boolean -> scalar conversions;
 	// first argument is true/false; second is present/missing
@@ -741,7 +754,8 @@ var _ssainfo = [_ssamax]ssaopinfo{
 	scvtf64toi64: {text: "cvt.f64@i64", argtypes: fp1Args, rettype: stIntMasked, bc: opcvttruncf64toi64},
 	scvti64tostr: {text: "cvt.i64@str", argtypes: int1Args, rettype: stStringMasked, bc: opcvti64tostr},
 
-	sstrconcat: {text: "strconcat", rettype: stStringMasked, argtypes: []ssatype{}, vaArgs: []ssatype{stString, stBool}, bc: opconcatstr, emit: emitConcatStr},
+	sstrconcat:     {text: "strconcat", rettype: stStringMasked, argtypes: []ssatype{}, vaArgs: []ssatype{stString, stBool}, bc: opconcatstr, emit: emitConcatStr},
+	sstrconcatskip: {text: "strconcatskip", rettype: stStringMasked, argtypes: []ssatype{stBool}, vaArgs: []ssatype{stString, stBool}, bc: opconcatstrskip, emit: emitConcatStrSkip, disjunctive: true},
 
 	//#region string operations
 	slowerstr: {text: "lower.str", argtypes: str1Args, rettype: stStringMasked, bc: opslower},
@@ -796,8 +810,8 @@ var _ssainfo = [_ssamax]ssaopinfo{
 	// s, k = skip_nchar_right s, k -- skip n unicode character off the end (right) of a string slice
 	sStrSkipNCharRight: {text: "skip_nchar_right", argtypes: []ssatype{stString, stInt, stBool}, rettype: stStringMasked, bc: opSkipNcharRight},
 
-	soctetlength:     {text: "octetlength", argtypes: str1Args, rettype: stIntMasked, bc: opoctetlength},
-	scharacterlength: {text: "characterlength", argtypes: str1Args, rettype: stIntMasked, bc: opcharlength},
+	soctetlength:     {text: "octetlength", argtypes: str1Args, rettype: stInt, bc: opoctetlength},
+	scharacterlength: {text: "characterlength", argtypes: str1Args, rettype: stInt, bc: opcharlength},
 	sSubStr:          {text: "substr", argtypes: []ssatype{stString, stInt, stInt, stBool}, rettype: stString, bc: opSubstr},
 	sSplitPart:       {text: "split_part", argtypes: []ssatype{stString, stInt, stBool}, rettype: stStringMasked, immfmt: fmtdict, bc: opSplitPart},
 
@@ -855,6 +869,7 @@ var _ssainfo = [_ssamax]ssaopinfo{
 
 	sblendv:   {text: "blend.v", rettype: stValueMasked, argtypes: []ssatype{stValue, stBool, stValue, stBool}, bc: opblendv, disjunctive: true, safeValueMask: true},
 	sblendf64: {text: "blend.f64", rettype: stFloatMasked, argtypes: []ssatype{stFloat, stBool, stFloat, stBool}, bc: opblendf64, disjunctive: true},
+	sblendi64: {text: "blend.i64", rettype: stIntMasked, argtypes: []ssatype{stInt, stBool, stInt, stBool}, bc: opblendf64, disjunctive: true}, // blend.f64 is a bitwise move
 
 	sbroadcastf: {text: "broadcast.f", rettype: stFloat, argtypes: []ssatype{}, immfmt: fmtf64, bc: opbroadcastf64},
 	sbroadcasti: {text: "broadcast.i", rettype: stInt, argtypes: []ssatype{}, immfmt: fmti64, bc: opbroadcasti64},
@@ -1052,6 +1067,15 @@ var _ssainfo = [_ssamax]ssaopinfo{
 	sobjectsize:    {text: "objectsize", argtypes: []ssatype{stValue, stBool}, rettype: stIntMasked, bc: opobjectsize},
 	sarraysize:     {text: "arraysize", argtypes: []ssatype{stList, stBool}, rettype: stInt, bc: oparraysize},
 	sarrayposition: {text: "arrayposition", argtypes: []ssatype{stList, stValue, stBool}, rettype: stIntMasked, bc: oparrayposition},
+	sarrayelement:  {text: "arrayelement", argtypes: []ssatype{stList, stInt, stBool}, rettype: stValueMasked, bc: oparrayelement, priority: prioParse},
+
+	sobjectmerge:  {text: "objectmerge", argtypes: []ssatype{stBase, stBase, stBool}, rettype: stValueMasked, bc: opobjectmerge},
+	sobjectdelete: {text: "objectdelete", argtypes: []ssatype{stBase, stBool}, rettype: stValueMasked, immfmt: fmtother, bc: opobjectdelete},
+
+	slistfield:    {text: "listfield", argtypes: []ssatype{stList, stBool}, rettype: stListMasked, immfmt: fmtother, bc: oplistfield},
+	slistvalues:   {text: "listvalues", argtypes: []ssatype{stList, stBool}, rettype: stListMasked, bc: oplistvalues},
+	slistflatten:  {text: "listflatten", argtypes: []ssatype{stList, stBool}, rettype: stListMasked, bc: oplistflatten},
+	sstructvalues: {text: "structvalues", argtypes: []ssatype{stBase, stBool}, rettype: stListMasked, bc: opstructvalues},
 
 	saggapproxcount: {
 		text:     "aggapproxcount",
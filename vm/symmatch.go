@@ -0,0 +1,270 @@
+// Copyright (C) 2022 Sneller, Inc.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package vm
+
+import (
+	"strconv"
+
+	"github.com/SnellerInc/sneller/internal/stringext"
+	"github.com/SnellerInc/sneller/ion"
+
+	"golang.org/x/exp/slices"
+)
+
+// symlike is the immediate of ssymmatch;
+// it describes a LIKE or ILIKE pattern
+// that is evaluated against the symbol table
+type symlike struct {
+	pattern       string
+	escape        rune
+	caseSensitive bool
+}
+
+// key produces a string that uniquely
+// identifies l for hashing common subexpressions
+func (l symlike) key() string {
+	flag := "i"
+	if l.caseSensitive {
+		flag = "c"
+	}
+	return flag + strconv.Itoa(int(l.escape)) + ":" + l.pattern
+}
+
+// likeMatcher matches strings against a LIKE pattern
+// using the same segments that prog.like compiles
+// into bytecode, so that the results are identical
+// to the results produced by the vm
+//
+// The segments are grouped into blocks that begin
+// at each unbounded skip ('%'); since the following
+// segments are at fixed offsets, each block matches
+// at fixed offsets, and the leftmost match of each
+// block leaves the most room for the remaining blocks,
+// so the string is matched without backtracking.
+type likeMatcher struct {
+	blocks        []likeBlock
+	caseSensitive bool
+}
+
+// likeBlock is a sequence of segments where
+// only the first segment may skip an unbounded
+// number of runes
+type likeBlock struct {
+	skip      int  // minimum number of runes skipped
+	unbounded bool // more than skip runes may be skipped
+	needle    []rune
+	wildcard  []bool
+}
+
+func newLikeMatcher(l symlike) *likeMatcher {
+	const wc = '_'
+	const ks = '%'
+
+	pattern := l.pattern
+	if !l.caseSensitive {
+		pattern = stringext.NormalizeString(pattern)
+	}
+	segs := stringext.SimplifyLikeExpr(pattern, wc, ks, l.escape)
+	m := &likeMatcher{caseSensitive: l.caseSensitive}
+	for i := range segs {
+		// SimplifyLikeExpr produces segments that
+		// skip either exactly SkipMin runes or at
+		// least SkipMin runes (SkipMax == -1)
+		seg := &segs[i]
+		needle := []rune(string(seg.Pattern.Needle))
+		if i == 0 || seg.SkipMax == -1 {
+			m.blocks = append(m.blocks, likeBlock{
+				skip:      seg.SkipMin,
+				unbounded: seg.SkipMax == -1,
+				needle:    needle,
+				wildcard:  slices.Clone(seg.Pattern.Wildcard),
+			})
+			continue
+		}
+		b := &m.blocks[len(m.blocks)-1]
+		for j := 0; j < seg.SkipMin; j++ {
+			b.needle = append(b.needle, 0)
+			b.wildcard = append(b.wildcard, true)
+		}
+		b.needle = append(b.needle, needle...)
+		b.wildcard = append(b.wildcard, seg.Pattern.Wildcard...)
+	}
+	return m
+}
+
+func (m *likeMatcher) match(str string) bool {
+	runes := []rune(str)
+	if !m.caseSensitive {
+		for i := range runes {
+			runes[i] = stringext.NormalizeRune(runes[i])
+		}
+	}
+	return m.matchBlocks(runes)
+}
+
+// matchBlocks matches each block in order at the
+// leftmost position where it matches, except that
+// the last block must end at the end of the string
+func (m *likeMatcher) matchBlocks(str []rune) bool {
+	pos := 0
+	for i := range m.blocks {
+		b := &m.blocks[i]
+		lo := pos + b.skip
+		hi := len(str) - len(b.needle)
+		if !b.unbounded {
+			if lo < hi {
+				hi = lo
+			}
+		} else if i == len(m.blocks)-1 {
+			lo = hi // anchored at the end
+			if lo < pos+b.skip {
+				return false
+			}
+		}
+		start := -1
+		for p := lo; p <= hi; p++ {
+			if b.matchNeedle(str[p:]) {
+				start = p
+				break
+			}
+		}
+		if start < 0 {
+			return false
+		}
+		pos = start + len(b.needle)
+	}
+	return pos == len(str)
+}
+
+func (b *likeBlock) matchNeedle(str []rune) bool {
+	for i, r := range b.needle {
+		if !b.wildcard[i] && str[i] != r {
+			return false
+		}
+	}
+	return true
+}
+
+// symmatcher holds the results of matching
+// a LIKE pattern against each of the symbols
+// in the current symbol table; the results are
+// stored as a bitmap in bytecode.dict[slot]
+// so that ssymmatch can test them per row
+type symmatcher struct {
+	slot    uint16
+	matcher *likeMatcher
+	syms    []string // symbols evaluated for bits
+	bits    []byte   // bits[i>>3]&(1<<(i&7)) is set if syms[i] matched
+}
+
+// update re-evaluates the pattern for the symbols
+// in st that differ from the most recent symbol table
+// and returns whether or not any of the results changed
+func (s *symmatcher) update(st *symtab) bool {
+	n := len(st.symrefs)
+	// the bitmap is tested with dword gathers,
+	// so it is always a multiple of 4 bytes
+	size := ((n + 31) >> 5) << 2
+	changed := size != len(s.bits)
+	if len(s.syms) > n {
+		for i := n; i < len(s.syms); i++ {
+			s.bits[i>>3] &^= 1 << (i & 7)
+		}
+		s.syms = s.syms[:n]
+		changed = true
+	}
+	if cap(s.bits) < size {
+		bits := make([]byte, size)
+		copy(bits, s.bits)
+		s.bits = bits
+	}
+	s.bits = s.bits[:size]
+	for i := 0; i < n; i++ {
+		str := st.Get(ion.Symbol(i))
+		if i < len(s.syms) {
+			if s.syms[i] == str {
+				continue
+			}
+			s.syms[i] = str
+		} else {
+			s.syms = append(s.syms, str)
+		}
+		mask := byte(1) << (i & 7)
+		old := s.bits[i>>3] & mask
+		if s.matcher.match(str) {
+			s.bits[i>>3] |= mask
+		} else {
+			s.bits[i>>3] &^= mask
+		}
+		changed = changed || old != s.bits[i>>3]&mask
+	}
+	return changed
+}
+
+// refreshSymmatch updates the per-symbol
+// LIKE results in b for the symbol table st
+func (b *bytecode) refreshSymmatch(st *symtab) {
+	for i := range b.symmatch {
+		s := &b.symmatch[i]
+		if s.update(st) || len(b.dict[s.slot]) != len(s.bits) {
+			b.dict[s.slot] = string(s.bits)
+		}
+	}
+}
+
+func emitsymmatch(v *value, c *compilestate) {
+	arg := v.args[0]
+	k := v.args[1]
+
+	// the dictionary entry is populated by
+	// bytecode.refreshSymmatch, so it must not
+	// be shared with any other instruction
+	slot := len(c.dict)
+	if slot > 65535 {
+		panic("dictionary reference exceeds 65535 limit")
+	}
+	c.dict = append(c.dict, "")
+	c.symmatch = append(c.symmatch, symmatcher{
+		slot:    uint16(slot),
+		matcher: newLikeMatcher(v.imm.(symlike)),
+	})
+	c.emit(v, ssainfo[v.op].bc,
+		c.slotOf(arg, regV),
+		uint64(slot),
+		c.slotOf(k, regK),
+	)
+}
+
+// likeSymbols matches the symbols in v against
+// a LIKE pattern using the per-symbol results
+// computed once for each symbol table
+func (p *prog) likeSymbols(v *value, pattern string, escape rune, caseSensitive bool) *value {
+	return p.ssa2imm(ssymmatch, v, p.mask(v), symlike{
+		pattern:       pattern,
+		escape:        escape,
+		caseSensitive: caseSensitive,
+	})
+}
+
+// symmatchslot returns whether dict[i] is
+// owned by a symmatch instruction
+func (c *compilestate) symmatchslot(i int) bool {
+	for j := range c.symmatch {
+		if int(c.symmatch[j].slot) == i {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,145 @@
+// Copyright (C) 2022 Sneller, Inc.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package vm
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/SnellerInc/sneller/internal/stringext"
+)
+
+func TestLikeMatcher(t *testing.T) {
+	testcases := []struct {
+		pattern string
+		escape  rune
+		cs      bool
+		str     string
+		want    bool
+	}{
+		{"abc", stringext.NoEscape, true, "abc", true},
+		{"abc", stringext.NoEscape, true, "abcd", false},
+		{"abc", stringext.NoEscape, true, "ABC", false},
+		{"abc", stringext.NoEscape, false, "ABC", true},
+		{"a%", stringext.NoEscape, true, "a", true},
+		{"a%", stringext.NoEscape, true, "ba", false},
+		{"%a", stringext.NoEscape, true, "ba", true},
+		{"%a%", stringext.NoEscape, true, "bab", true},
+		{"%a%", stringext.NoEscape, true, "bbb", false},
+		{"a_c", stringext.NoEscape, true, "abc", true},
+		{"a_c", stringext.NoEscape, true, "a€c", true},
+		{"a_c", stringext.NoEscape, true, "ac", false},
+		{"%a_c%", stringext.NoEscape, true, "xxabcxx", true},
+		{"%a_c%", stringext.NoEscape, true, "xxacxx", false},
+		{"%a%b%a", stringext.NoEscape, true, "abbaba", true},
+		{"%a%b%a", stringext.NoEscape, true, "abbab", false},
+		{"___", stringext.NoEscape, true, "a€c", true},
+		{"__%", stringext.NoEscape, true, "a", false},
+		{"%", stringext.NoEscape, true, "", true},
+		{"_@%", '@', true, "x%", true},
+		{"_@%", '@', true, "xy", false},
+		{"%Ĳ", stringext.NoEscape, false, "xĳ", true},
+		{"%s", stringext.NoEscape, false, "xſ", true},
+		{"%s", stringext.NoEscape, true, "xſ", false},
+		{"%ab_d%_x", stringext.NoEscape, true, "abab_abcdyyx", true},
+		{"%ab_d%_x", stringext.NoEscape, true, "abcdx", false},
+		{"a%%b", stringext.NoEscape, true, "ab", true},
+	}
+	for i := range testcases {
+		tc := &testcases[i]
+		m := newLikeMatcher(symlike{pattern: tc.pattern, escape: tc.escape, caseSensitive: tc.cs})
+		if got := m.match(tc.str); got != tc.want {
+			t.Errorf("%q LIKE %q (case-sensitive=%v): got %v, want %v", tc.str, tc.pattern, tc.cs, got, tc.want)
+		}
+	}
+}
+
+func TestLikeMatcherLinear(t *testing.T) {
+	// this pattern takes exponential time
+	// to reject with a backtracking matcher
+	pattern := strings.Repeat("%a", 30) + "%b"
+	str := strings.Repeat("a", 5000)
+	m := newLikeMatcher(symlike{pattern: pattern, escape: stringext.NoEscape, caseSensitive: true})
+	done := make(chan bool, 1)
+	go func() { done <- m.match(str) }()
+	select {
+	case got := <-done:
+		if got {
+			t.Fatal("unexpected match")
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("match did not complete")
+	}
+}
+
+func TestSymmatchUpdate(t *testing.T) {
+	var st symtab
+	defer st.free()
+
+	s := symmatcher{
+		matcher: newLikeMatcher(symlike{pattern: "x%", escape: stringext.NoEscape, caseSensitive: true}),
+	}
+	check := func(syms ...string) {
+		t.Helper()
+		if len(s.bits)&3 != 0 {
+			t.Fatalf("bitmap length %d not a multiple of 4", len(s.bits))
+		}
+		for _, str := range syms {
+			id, ok := st.Symbolize(str)
+			if !ok {
+				t.Fatalf("symbol %q not defined", str)
+			}
+			want := str[0] == 'x'
+			got := s.bits[id>>3]&(1<<(id&7)) != 0
+			if got != want {
+				t.Errorf("symbol %q (%d): got %v, want %v", str, id, got, want)
+			}
+		}
+		for i := len(st.symrefs); i < len(s.bits)*8; i++ {
+			if s.bits[i>>3]&(1<<(i&7)) != 0 {
+				t.Errorf("bit %d set beyond the end of the symbol table", i)
+			}
+		}
+	}
+
+	st.Intern("xa")
+	st.Intern("b")
+	if !s.update(&st) {
+		t.Fatal("expected the first update to change the results")
+	}
+	check("xa", "b")
+	if s.update(&st) {
+		t.Fatal("expected no change with the same symbol table")
+	}
+
+	// appending symbols only evaluates the new ones
+	for _, str := range []string{"c", "xd", "xe", "f", "g", "xh", "i", "j", "xk"} {
+		st.Intern(str)
+	}
+	if !s.update(&st) {
+		t.Fatal("expected new symbols to change the results")
+	}
+	check("xa", "b", "c", "xd", "xe", "f", "g", "xh", "i", "j", "xk")
+
+	// a different symbol table with fewer symbols
+	st.Reset()
+	st.Intern("b")
+	st.Intern("xa")
+	if !s.update(&st) {
+		t.Fatal("expected a new symbol table to change the results")
+	}
+	check("b", "xa")
+}
@@ -18,12 +18,15 @@ import (
 	"fmt"
 	"io"
 	"math"
+	"math/bits"
 	"sort"
 	"sync"
 
+	"github.com/SnellerInc/sneller/date"
 	"github.com/SnellerInc/sneller/ion"
 	"github.com/SnellerInc/sneller/utf8"
 
+	"github.com/dchest/siphash"
 	"golang.org/x/exp/slices"
 )
 
@@ -33,6 +36,16 @@ func NewSystemDatashape(dst QuerySink) QuerySink {
 	return &systemDatashape{dst: dst}
 }
 
+// NewSystemDatashapeHistograms constructs a QuerySink implementing
+// the `SYSTEM_DATASHAPE(*, 'histograms')` aggregation, which
+// collects value statistics for each path in addition to the
+// counts of each type. If partial is set, the results include
+// the sketches that are necessary to merge them with
+// NewSystemDatashapeMerge.
+func NewSystemDatashapeHistograms(dst QuerySink, partial bool) QuerySink {
+	return &systemDatashape{dst: dst, histograms: true, partial: partial}
+}
+
 // NewSystemDatashapeMerge constructs a QuerySink that merges
 // the results of multiple `SYSTEM_DATASHAPE(*)` aggregations
 func NewSystemDatashapeMerge(dst QuerySink) QuerySink {
@@ -67,20 +80,29 @@ const (
 	intMaxValueField     = "int-max-value"
 	floatMinValueField   = "float-min-value"
 	floatMaxValueField   = "float-max-value"
+
+	// value statistics ('histograms' mode)
+	timestampMinValueField = "timestamp-min-value"
+	timestampMaxValueField = "timestamp-max-value"
+	approxDistinctField    = "approx-distinct"
+	distinctSketchField    = "approx-distinct-sketch"
+	nullFractionField      = "null-fraction"
 )
 
 // systemDatashape is the main QuerySink that collects
 // the data shape of the whole dataset.
 type systemDatashape struct {
-	dst       QuerySink
-	datashape *queryDatashape
-	mutex     sync.Mutex
+	dst        QuerySink
+	datashape  *queryDatashape
+	mutex      sync.Mutex
+	histograms bool // collect value statistics
+	partial    bool // output sketches for merging
 }
 
 func (s *systemDatashape) Open() (io.WriteCloser, error) {
 	return splitter(&systemDatashapeTable{
 		parent:    s,
-		datashape: newQueryDatashape(),
+		datashape: newQueryDatashape(s.histograms),
 	}), nil
 }
 
@@ -89,7 +111,10 @@ func (s *systemDatashape) Close() error {
 	var data ion.Buffer
 	var buf ion.Buffer
 
-	s.datashape.writeIon(&buf, &st)
+	if s.datashape == nil {
+		s.datashape = newQueryDatashape(s.histograms)
+	}
+	s.datashape.writeIon(&buf, &st, s.partial)
 
 	st.Marshal(&data, true)
 	data.UnsafeAppend(buf.Bytes())
@@ -169,7 +194,7 @@ func (s *systemDatashapeTable) Close() error {
 	defer s.parent.mutex.Unlock()
 
 	if s.parent.datashape == nil {
-		s.parent.datashape = newQueryDatashape()
+		s.parent.datashape = newQueryDatashape(s.parent.histograms)
 	}
 
 	s.parent.datashape.merge(s.datashape)
@@ -238,6 +263,9 @@ func (s *systemDatashapeTable) processStruct(node *datashapeNode, body []byte) e
 func (s *systemDatashapeTable) processValue(node *datashapeNode, val []byte) error {
 	typ := ion.TypeOf(val)
 	node.update(typ)
+	if h := node.stats.hist; h != nil {
+		s.updateHistogram(h, typ, val)
+	}
 
 	updateStringRanges := func(str []byte) {
 		// Note: utf8.RuneCount is expansive, we first compare raw bytes length
@@ -303,6 +331,41 @@ func (s *systemDatashapeTable) processValue(node *datashapeNode, val []byte) err
 	return nil
 }
 
+// updateHistogram updates the value statistics of a path
+// with a single value; only scalar values are counted
+// towards the number of distinct values
+func (s *systemDatashapeTable) updateHistogram(h *valueHistogram, typ ion.Type, val []byte) {
+	switch typ {
+	case ion.NullType, ion.StructType, ion.ListType, ion.SexpType:
+		return
+
+	case ion.StringType:
+		str, _ := ion.Contents(val)
+		h.add(ion.StringType, str)
+
+	case ion.SymbolType:
+		// symbols are counted as the equivalent strings
+		id, _, err := ion.ReadSymbol(val)
+		if err != nil {
+			return
+		}
+		str, ok := s.symtab.Lookup(id)
+		if ok {
+			h.add(ion.StringType, []byte(str))
+		}
+
+	case ion.TimestampType:
+		t, _, err := ion.ReadTime(val)
+		if err == nil {
+			h.rangeTime.update(t.UnixMicro())
+		}
+		h.add(typ, val)
+
+	default:
+		h.add(typ, val)
+	}
+}
+
 // ----------------------------------------
 
 // queryDatashape is a collection of object paths
@@ -314,10 +377,10 @@ type queryDatashape struct {
 	root *datashapeNode // The root node of trie
 }
 
-func newQueryDatashape() *queryDatashape {
+func newQueryDatashape(histograms bool) *queryDatashape {
 	ds := &queryDatashape{}
 	ds.capacity = systemDatashapeMaxRows
-	ds.root, _ = newDatashapeNode(&ds.capacity)
+	ds.root, _ = newDatashapeNode(&ds.capacity, histograms)
 
 	return ds
 }
@@ -357,7 +420,7 @@ func (qs *queryDatashape) merge(qs2 *queryDatashape) {
 	}
 }
 
-func (qs *queryDatashape) writeIon(buf *ion.Buffer, st *ion.Symtab) {
+func (qs *queryDatashape) writeIon(buf *ion.Buffer, st *ion.Symtab, partial bool) {
 	buf.BeginStruct(-1)
 	{
 		if qs.capacity <= 0 {
@@ -380,7 +443,7 @@ func (qs *queryDatashape) writeIon(buf *ion.Buffer, st *ion.Symtab) {
 
 		for _, path := range paths {
 			buf.BeginField(st.Intern(path))
-			tmp[path].writeIon(buf, st)
+			tmp[path].writeIon(buf, st, partial)
 		}
 		buf.EndStruct()
 	}
@@ -395,7 +458,7 @@ type datashapeNode struct {
 	capacity *int64                    // Total trie capacity
 }
 
-func newDatashapeNode(capacity *int64) (*datashapeNode, bool) {
+func newDatashapeNode(capacity *int64, histograms bool) (*datashapeNode, bool) {
 	*capacity -= 1
 
 	node := &datashapeNode{
@@ -404,6 +467,9 @@ func newDatashapeNode(capacity *int64) (*datashapeNode, bool) {
 	}
 
 	node.stats.init()
+	if histograms {
+		node.stats.hist = newValueHistogram()
+	}
 
 	return node, *capacity >= 0
 }
@@ -418,7 +484,7 @@ func (n *datashapeNode) child(field string) (*datashapeNode, bool) {
 		return c, true
 	}
 
-	c, ok = newDatashapeNode(n.capacity)
+	c, ok = newDatashapeNode(n.capacity, n.stats.hist != nil)
 	if ok {
 		n.next[field] = c
 	}
@@ -504,6 +570,70 @@ func (m *minMaxFloat64) update(v float64) {
 	}
 }
 
+// datashapeSketchBits is the number of bits of
+// a hash that select a HyperLogLog register
+const datashapeSketchBits = 8
+
+// keys for hashing the values of a path; they must
+// be the same for every process that computes
+// partial results that are merged together
+const (
+	datashapeKey0 = 0x736e656c6c657221
+	datashapeKey1 = 0x64617461736861
+)
+
+// valueHistogram holds the value statistics
+// of a path that are not collected by default
+type valueHistogram struct {
+	rangeTime minMaxInt64                     // timestamps as Unix microseconds
+	sketch    [1 << datashapeSketchBits]uint8 // HyperLogLog registers
+}
+
+func newValueHistogram() *valueHistogram {
+	h := &valueHistogram{}
+	h.rangeTime.min = math.MaxInt64
+	h.rangeTime.max = math.MinInt64
+	return h
+}
+
+func (h *valueHistogram) add(typ ion.Type, b []byte) {
+	x := siphash.Hash(datashapeKey0, datashapeKey1^uint64(typ), b)
+	idx := x >> (64 - datashapeSketchBits)
+	rest := (x << datashapeSketchBits) | (1 << (datashapeSketchBits - 1))
+	rank := uint8(bits.LeadingZeros64(rest) + 1)
+	if rank > h.sketch[idx] {
+		h.sketch[idx] = rank
+	}
+}
+
+func (h *valueHistogram) merge(o *valueHistogram) {
+	h.rangeTime.merge(&o.rangeTime)
+	aggApproxCountDistinctUpdateBuckets(len(h.sketch), h.sketch[:], o.sketch[:])
+}
+
+func (h *valueHistogram) writeIon(buf *ion.Buffer, st *ion.Symtab, count *ionTypeCounter, partial bool) {
+	if count[int(ion.TimestampType)] > 0 && h.rangeTime.min <= h.rangeTime.max {
+		buf.BeginField(st.Intern(timestampMinValueField))
+		buf.WriteTime(date.UnixMicro(h.rangeTime.min))
+		buf.BeginField(st.Intern(timestampMaxValueField))
+		buf.WriteTime(date.UnixMicro(h.rangeTime.max))
+	}
+	buf.BeginField(st.Intern(approxDistinctField))
+	buf.WriteUint(aggApproxCountDistinctHLL(h.sketch[:]))
+	total := int64(0)
+	for i := range count {
+		total += count[i]
+	}
+	if total > 0 {
+		buf.BeginField(st.Intern(nullFractionField))
+		buf.WriteFloat64(float64(count[int(ion.NullType)]) / float64(total))
+	}
+	if partial {
+		buf.BeginField(st.Intern(distinctSketchField))
+		buf.WriteBlob(h.sketch[:])
+	}
+}
+
 // ionTypeCounter maps an Ion type to the count of fields with this type
 type ionTypeCounter [16]int64
 
@@ -556,6 +686,7 @@ type ionStatistics struct {
 	rangeStringLen minMaxInt64
 	minBytesLen    int
 	maxBytesLen    int
+	hist           *valueHistogram // non-nil if value statistics are collected
 }
 
 func (s *ionStatistics) init() {
@@ -574,9 +705,15 @@ func (s *ionStatistics) merge(o *ionStatistics) {
 	s.rangeInt64.merge(&o.rangeInt64)
 	s.rangeFloat64.merge(&o.rangeFloat64)
 	s.rangeStringLen.merge(&o.rangeStringLen)
+	if o.hist != nil {
+		if s.hist == nil {
+			s.hist = newValueHistogram()
+		}
+		s.hist.merge(o.hist)
+	}
 }
 
-func (s *ionStatistics) writeIon(buf *ion.Buffer, st *ion.Symtab) {
+func (s *ionStatistics) writeIon(buf *ion.Buffer, st *ion.Symtab, partial bool) {
 	writeInt64 := func(name string, v int64) {
 		buf.BeginField(st.Intern(name))
 		buf.WriteInt(v)
@@ -601,6 +738,9 @@ func (s *ionStatistics) writeIon(buf *ion.Buffer, st *ion.Symtab) {
 		writeInt64(stringMinLengthField, s.rangeStringLen.min)
 		writeInt64(stringMaxLengthField, s.rangeStringLen.max)
 	}
+	if s.hist != nil {
+		s.hist.writeIon(buf, st, &s.count, partial)
+	}
 	buf.EndStruct()
 }
 
@@ -656,12 +796,19 @@ func (s *systemDatashapeMergeTable) next() rowConsumer {
 
 // implementation of rowConsumer.writeRows
 func (s *systemDatashapeMergeTable) writeRows(delims []vmref, params *rowParams) error {
-	n := len(delims)
-	if n != 1 {
-		return fmt.Errorf("systemDatashapeMergeTable: expected exactly one input row, got %d", n)
+	// each row is a partial data shape produced by
+	// one of the subtables; a single table may see
+	// more than one of them, so they must be merged
+	// rather than overwrite each other
+	for i := range delims {
+		partial := newQueryDatashapeMerge()
+		err := partial.unmarshal(&s.symtab.Symtab, delims[i].mem())
+		if err != nil {
+			return err
+		}
+		s.datashape.merge(partial)
 	}
-
-	return s.datashape.unmarshal(&s.symtab.Symtab, delims[0].mem())
+	return nil
 }
 
 func (s *systemDatashapeMergeTable) Close() error {
@@ -712,6 +859,7 @@ func (q *queryDatashapeFinal) unmarshal(st *ion.Symtab, msg []byte) error {
 		case fieldsField:
 			_, err := ion.UnpackStruct(st, val, func(name string, msg []byte) error {
 				stats := &ionStatistics{}
+				stats.init()
 				q.fields[name] = stats
 				return stats.unmarshal(st, msg)
 			})
@@ -772,7 +920,7 @@ func (q *queryDatashapeFinal) writeIon(buf *ion.Buffer, st *ion.Symtab) {
 		for _, path := range paths {
 			stats := q.fields[path]
 			buf.BeginField(st.Intern(path))
-			stats.writeIon(buf, st)
+			stats.writeIon(buf, st, false)
 		}
 		buf.EndStruct()
 
@@ -829,6 +977,13 @@ func (s *ionStatistics) unmarshal(st *ion.Symtab, msg []byte) error {
 			return num
 		}
 
+		histogram := func() *valueHistogram {
+			if s.hist == nil {
+				s.hist = newValueHistogram()
+			}
+			return s.hist
+		}
+
 		if t := field2iontype(name); t != ion.ReservedType {
 			s.count[int(t)] = readInt()
 			return nil
@@ -847,6 +1002,25 @@ func (s *ionStatistics) unmarshal(st *ion.Symtab, msg []byte) error {
 			s.rangeFloat64.min = readFloat()
 		case floatMaxValueField:
 			s.rangeFloat64.max = readFloat()
+		case timestampMinValueField, timestampMaxValueField:
+			var t date.Time
+			t, _, err = ion.ReadTime(val)
+			if name == timestampMinValueField {
+				histogram().rangeTime.min = t.UnixMicro()
+			} else {
+				histogram().rangeTime.max = t.UnixMicro()
+			}
+		case distinctSketchField:
+			var sketch []byte
+			sketch, _, err = ion.ReadBytesShared(val)
+			h := histogram()
+			if err == nil && len(sketch) != len(h.sketch) {
+				err = fmt.Errorf("%s: unexpected sketch size %d", name, len(sketch))
+			}
+			copy(h.sketch[:], sketch)
+		case approxDistinctField, nullFractionField:
+			// derived from the other fields
+			histogram()
 
 		default:
 			err = fmt.Errorf("unknown field %q", name)
@@ -49,6 +49,8 @@ type Table interface {
 // to into() in different goroutines. SplitInput takes
 // care of closing the outputs returned from dst.Open()
 // and waits for each goroutine to return.
+// A panic in into() or in closing an output is
+// returned as a *PanicError.
 func SplitInput(dst QuerySink, parallel int, into func(io.Writer) error) error {
 	merge := func(first, second error) error {
 		ret := first
@@ -64,8 +66,8 @@ func SplitInput(dst QuerySink, parallel int, into func(io.Writer) error) error {
 			return err
 		}
 
-		err = into(w)
-		return merge(err, w.Close())
+		err = protect(func() error { return into(w) })
+		return merge(err, protect(w.Close))
 	}
 	var wg sync.WaitGroup
 	errlist := make([]error, parallel)
@@ -83,10 +85,10 @@ func SplitInput(dst QuerySink, parallel int, into func(io.Writer) error) error {
 		wg.Add(1)
 		go func(i int) {
 			defer wg.Done()
-			err := into(w)
+			err := protect(func() error { return into(w) })
 			// make sure w.Close() is safe to call
 			<-opendone
-			errlist[i] = merge(err, w.Close())
+			errlist[i] = merge(err, protect(w.Close))
 		}(i)
 	}
 	// we don't start any children goroutines
@@ -105,6 +107,69 @@ func SplitInput(dst QuerySink, parallel int, into func(io.Writer) error) error {
 	return nil
 }
 
+// SplitInputLanes is like SplitInput, except that
+// it calls dst.Open() up to parallel*lanes times and
+// passes groups of up to lanes outputs to separate
+// calls to into() in different goroutines, so that
+// each call to into() can distribute its input
+// across more than one output. If fewer than
+// parallel*lanes outputs can be opened, the outputs
+// that were opened are distributed as evenly as possible.
+func SplitInputLanes(dst QuerySink, parallel, lanes int, into func([]io.Writer) error) error {
+	if lanes <= 1 {
+		return SplitInput(dst, parallel, func(w io.Writer) error {
+			return into([]io.Writer{w})
+		})
+	}
+	if parallel < 1 {
+		parallel = 1
+	}
+	var outputs []io.WriteCloser
+	for i := 0; i < parallel*lanes; i++ {
+		w, err := dst.Open()
+		if err != nil {
+			if i == 0 {
+				return err
+			}
+			// just stop opening
+			// more parallel streams
+			break
+		}
+		outputs = append(outputs, w)
+	}
+	if parallel > len(outputs) {
+		parallel = len(outputs)
+	}
+	groups := make([][]io.Writer, parallel)
+	for i := range outputs {
+		groups[i%parallel] = append(groups[i%parallel], outputs[i])
+	}
+	var wg sync.WaitGroup
+	errlist := make([]error, parallel)
+	wg.Add(parallel)
+	for i := range groups {
+		go func(i int) {
+			defer wg.Done()
+			errlist[i] = protect(func() error { return into(groups[i]) })
+		}(i)
+	}
+	wg.Wait()
+	// close the outputs once every goroutine has returned
+	for i := range outputs {
+		err := protect(outputs[i].Close)
+		g := i % parallel
+		if errlist[g] == nil || errors.Is(errlist[g], io.EOF) {
+			errlist[g] = err
+		}
+	}
+	for i := range errlist {
+		if errlist[i] != nil {
+			return errlist[i]
+		}
+	}
+	return nil
+}
+
 // NewReaderAtTable table constructs a ReaderAtTable
 // that reads from the provided ReaderAt
 // at the specified alignment and up to size bytes.
@@ -142,14 +207,22 @@ func (r *ReaderAtTable) run(dst io.Writer) error {
 	if r.align > PageSize {
 		return fmt.Errorf("align %d < PageSize (%d)", r.align, PageSize)
 	}
-	chunk := Malloc()[:r.align]
-	defer Free(chunk)
+	acct := accountOf(dst)
+	chunk, err := acct.malloc()
+	if err != nil {
+		return err
+	}
+	defer acct.free(chunk)
+	chunk = chunk[:r.align]
 	step := int64(r.align)
 	for {
 		off := atomic.AddInt64(&r.off, step) - step
 		if r.size != -1 && off >= r.size {
 			return nil
 		}
+		if err := Throttle(dst); err != nil {
+			return err
+		}
 		n, err := r.src.ReadAt(chunk, off)
 		if err != nil {
 			if errors.Is(err, io.EOF) {
@@ -200,8 +273,12 @@ func BufferTable(buf []byte, align int) *BufferedTable {
 func (b *BufferedTable) Size() int64 { return int64(len(b.buf)) }
 
 func (b *BufferedTable) run(w io.Writer) error {
-	tmp := Malloc()
-	defer Free(tmp)
+	acct := accountOf(w)
+	tmp, err := acct.malloc()
+	if err != nil {
+		return err
+	}
+	defer acct.free(tmp)
 	for {
 		off := atomic.AddInt64(&b.off, int64(b.align)) - int64(b.align)
 		if off >= int64(len(b.buf)) {
@@ -211,6 +288,9 @@ func (b *BufferedTable) run(w io.Writer) error {
 		if off+size > int64(len(b.buf)) {
 			size = int64(len(b.buf)) - off
 		}
+		if err := Throttle(w); err != nil {
+			return err
+		}
 		copy(tmp, b.buf[off:off+size])
 		_, err := w.Write(tmp[:size])
 		if err != nil {
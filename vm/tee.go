@@ -16,6 +16,8 @@ package vm
 
 import (
 	"io"
+
+	"github.com/SnellerInc/sneller/ion"
 )
 
 var _ EndSegmentWriter = (*TeeWriter)(nil)
@@ -115,6 +117,9 @@ func (t *TeeWriter) Add(w io.Writer, final func(int64, error)) {
 			// splitter we are actually using for (potentially)
 			// multiple outputs
 			sp := splitter(ts)
+			// the splitter may be shared by several
+			// queries; charge it to the first one
+			sp.setAccount(rs.acct)
 			// have the rowSplitter update ts.pos
 			// on each call to Write:
 			sp.pos = &ts.pos
@@ -162,12 +167,37 @@ func (t *TeeWriter) Add(w io.Writer, final func(int64, error)) {
 
 // Write implements io.Writer
 func (t *TeeWriter) Write(p []byte) (int, error) {
+	return t.write(p, nil, 0)
+}
+
+// symtabWriter is implemented by io.Writers
+// that accept a pre-decoded symbol table
+// (see blockfmt.SymtabWriter)
+type symtabWriter interface {
+	WriteSymtab(buf []byte, st *ion.Symtab, size int) (int, error)
+}
+
+// WriteSymtab implements blockfmt.SymtabWriter
+//
+// The symbol table is passed on to the writers
+// that accept it; the other writers receive p via Write.
+func (t *TeeWriter) WriteSymtab(p []byte, st *ion.Symtab, size int) (int, error) {
+	return t.write(p, st, size)
+}
+
+func (t *TeeWriter) write(p []byte, st *ion.Symtab, size int) (int, error) {
 	any := false
 	for i := 0; i < len(t.state); i++ {
 		if t.state[i].w == nil {
 			continue
 		}
-		n, err := t.state[i].w.Write(p)
+		var n int
+		var err error
+		if sw, ok := t.state[i].w.(symtabWriter); ok && st != nil {
+			n, err = sw.WriteSymtab(p, st, size)
+		} else {
+			n, err = t.state[i].w.Write(p)
+		}
 		if err != nil {
 			t.state[i].final(int64(n)+t.pos, err)
 			t.state = deleteOne(t.state, i)
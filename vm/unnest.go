@@ -15,6 +15,7 @@
 package vm
 
 import (
+	"fmt"
 	"io"
 
 	"github.com/SnellerInc/sneller/expr"
@@ -25,74 +26,126 @@ import (
 // that have their contents cross-joined with
 // the array contents as an auxiliary binding
 type Unnest struct {
-	dst    QuerySink
-	field  expr.Node
-	prog   prog
-	result string
+	dst     QuerySink
+	fields  []expr.Node
+	progs   []prog
+	results []string
 }
 
 // NewUnnest creates an Unnest QuerySink that cross-joins
 // the given field (which should be an array) into the
 // input stream as an auxiliary binding with the given name.
 func NewUnnest(dst QuerySink, field expr.Node, result string) (*Unnest, error) {
+	return NewUnnestZip(dst, []expr.Node{field}, []string{result})
+}
+
+// NewUnnestZip creates an Unnest QuerySink that joins
+// the given fields (which should be arrays) into the
+// input stream in lock-step, so that the n-th output row
+// for an input row binds results[i] to the n-th element
+// of fields[i]. The number of rows produced for an input
+// row is the length of the longest array; the elements
+// past the end of the shorter arrays are MISSING.
+func NewUnnestZip(dst QuerySink, fields []expr.Node, results []string) (*Unnest, error) {
+	if len(fields) == 0 || len(fields) != len(results) {
+		return nil, fmt.Errorf("vm.NewUnnestZip: %d fields but %d results", len(fields), len(results))
+	}
 	u := &Unnest{
-		dst:    dst,
-		field:  field,
-		result: result,
-	}
-	p := &u.prog
-	p.begin()
-	// produce a program that sticks the slice
-	// to be splat-ed into Z2:Z3
-	v, err := compile(p, u.field)
-	if err != nil {
-		return nil, err
+		dst:     dst,
+		fields:  fields,
+		progs:   make([]prog, len(fields)),
+		results: results,
+	}
+	for i := range u.fields {
+		p := &u.progs[i]
+		p.begin()
+		// produce a program that sticks the slice
+		// to be splat-ed into Z2:Z3
+		v, err := compile(p, u.fields[i])
+		if err != nil {
+			return nil, err
+		}
+		list := p.ssa2(stolist, v, p.mask(v))
+		p.returnScalar(p.initMem(), list, p.mask(list))
+		p.Renumber()
 	}
-	list := p.ssa2(stolist, v, p.mask(v))
-	p.returnScalar(p.initMem(), list, p.mask(list))
-	p.Renumber()
 	return u, nil
 }
 
+func (u *Unnest) redacted() string { return redactList(u.fields) }
+
 func (u *Unnest) Open() (io.WriteCloser, error) {
 	dst, err := u.dst.Open()
 	if err != nil {
 		return nil, err
 	}
-	unnest := &unnesting{parent: u, dstrc: asRowConsumer(dst)}
+	unnest := &unnesting{
+		parent: u,
+		lists:  make([]unnestList, len(u.fields)),
+		dstrc:  asRowConsumer(dst),
+	}
 	return splitter(unnest), nil
 }
 
 func (u *Unnest) Close() error {
-	u.prog.reset()
+	for i := range u.progs {
+		u.progs[i].reset()
+	}
 	return u.dst.Close()
 }
 
+// unnestList is the state used
+// to splat one of the unnested arrays
+type unnestList struct {
+	prog  prog
+	splat bytecode
+	perms []int32
+	outer []vmref
+}
+
+// grow doubles the space available for splatting
+func (l *unnestList) grow() {
+	l.outer = slices.Grow(l.outer, len(l.outer))
+	l.perms = slices.Grow(l.perms, len(l.perms))
+}
+
 type unnesting struct {
 	parent *Unnest
-	prog   prog
-	splat  bytecode
-	perms  []int32
+	lists  []unnestList
 	dstrc  rowConsumer
 	params rowParams
 	auxnum int
 
-	// cached buffers for inner and outer refs
-	inner, outer []vmref
+	// cached buffers for inner refs
+	inner []vmref
+	// cached buffers for zipped outer refs
+	// and their permutation
+	zipped [][]vmref
+	zperms []int32
 }
 
 func (u *unnesting) next() rowConsumer { return u.dstrc }
 
 func (u *unnesting) EndSegment() {
-	u.splat.dropScratch()
+	for i := range u.lists {
+		u.lists[i].splat.dropScratch()
+	}
 }
 
 func (u *unnesting) symbolize(st *symtab, aux *auxbindings) error {
-	err := recompile(st, &u.parent.prog, &u.prog, &u.splat, aux, "unnesting")
-	if err != nil {
-		return err
+	for i := range u.lists {
+		l := &u.lists[i]
+		err := recompile(st, &u.parent.progs[i], &l.prog, &l.splat, aux, "unnesting")
+		if err != nil {
+			return err
+		}
+	}
+	for i := range u.parent.results {
+		n := aux.push(u.parent.results[i])
+		if i == 0 {
+			u.auxnum = n
+		}
 	}
-	u.auxnum = aux.push(u.parent.result)
 	return u.dstrc.symbolize(st, aux)
 }
 
@@ -127,23 +180,27 @@ func sanitizeAux(aux []vmref, size int) []vmref {
 	return aux
 }
 
-func (u *unnesting) splatParams(in *rowParams, consumed int, perm []int32, inner []vmref) *rowParams {
-	if len(perm) != len(inner) {
-		panic("???")
+func (u *unnesting) splatParams(in *rowParams, consumed int, perm []int32, inner ...[]vmref) *rowParams {
+	for i := range inner {
+		if len(perm) != len(inner[i]) {
+			panic("???")
+		}
 	}
 	if len(in.auxbound) != u.auxnum {
 		panic("unexpected auxilliary inputs")
 	}
 	// splat existing row-oriented bindings
-	u.params.auxbound = shrink(u.params.auxbound, u.auxnum+1)
+	u.params.auxbound = shrink(u.params.auxbound, u.auxnum+len(inner))
 	for i := range in.auxbound {
-		u.params.auxbound[i] = sanitizeAux(u.params.auxbound[i], len(inner))
+		u.params.auxbound[i] = sanitizeAux(u.params.auxbound[i], len(perm))
 		for j, n := range perm {
 			u.params.auxbound[i][j] = in.auxbound[i][consumed+int(n)]
 		}
 	}
 	// add new bindings
-	u.params.auxbound[u.auxnum] = inner
+	for i := range inner {
+		u.params.auxbound[u.auxnum+i] = inner[i]
+	}
 	return &u.params
 }
 
@@ -151,33 +208,43 @@ func (u *unnesting) writeRows(delims []vmref, rp *rowParams) error {
 	if len(delims) == 0 {
 		return nil
 	}
-	if cap(u.outer) == 0 {
-		u.outer = make([]vmref, 1024)
-		u.perms = make([]int32, 1024)
+	for i := range u.lists {
+		l := &u.lists[i]
+		if cap(l.outer) == 0 {
+			l.outer = make([]vmref, 1024)
+			l.perms = make([]int32, 1024)
+		}
+		if l.splat.compiled == nil {
+			panic("WriteRows() called before Symbolize()")
+		}
+		l.splat.prepare(rp)
 	}
-	if u.splat.compiled == nil {
-		panic("WriteRows() called before Symbolize()")
+	if len(u.lists) > 1 {
+		return u.writeZipRows(delims, rp)
 	}
 
-	u.splat.prepare(rp)
+	l := &u.lists[0]
 	consumed := 0
 	for consumed < len(delims) {
 		// provide as much space as possible:
-		u.outer = u.outer[:cap(u.outer)]
-		u.perms = u.perms[:cap(u.perms)]
-		in, out := evalsplat(&u.splat, delims[consumed:], u.outer, u.perms)
-		if u.splat.err != 0 {
-			return bytecodeerror("unnest", &u.splat)
+		l.outer = l.outer[:cap(l.outer)]
+		l.perms = l.perms[:cap(l.perms)]
+		wd := watch("unnest", u.parent, &l.prog, &l.splat)
+		in, out := evalsplat(&l.splat, delims[consumed:], l.outer, l.perms)
+		if err := wd.done(); err != nil {
+			return err
+		}
+		if l.splat.err != 0 {
+			return bytecodeerror("unnest", &l.splat)
 		}
 		// adjust this to take into account the fact
 		// that we may not have actually handled all the lanes
-		u.splat.auxpos = consumed + in
+		l.splat.auxpos = consumed + in
 		if in == 0 {
 			// there wasn't enough room to splat a single
 			// lane's array members! we need more space,
 			// so double the slice sizes here
-			u.outer = slices.Grow(u.outer, len(u.outer))
-			u.perms = slices.Grow(u.perms, len(u.perms))
+			l.grow()
 			continue
 		}
 		if out == 0 {
@@ -186,9 +253,9 @@ func (u *unnesting) writeRows(delims []vmref, rp *rowParams) error {
 		}
 		// incorporate inner and outer values
 		// in two slices adjacent to one another:
-		outer := u.outer[:out]
+		outer := l.outer[:out]
 		u.inner = shrink(u.inner, len(outer))
-		innerperm := u.perms[:out]
+		innerperm := l.perms[:out]
 		// permute delimiters into unrolled delimiters:
 		for i, n := range innerperm {
 			u.inner[i] = delims[consumed+int(n)]
@@ -202,7 +269,118 @@ func (u *unnesting) writeRows(delims []vmref, rp *rowParams) error {
 	return nil
 }
 
+// writeZipRows splats each of the arrays over
+// the same input rows and then zips the elements
+// of the arrays together by their position
+func (u *unnesting) writeZipRows(delims []vmref, rp *rowParams) error {
+	outs := make([]int, len(u.lists))
+	consumed := 0
+	for consumed < len(delims) {
+		// splat each of the arrays; the number of rows
+		// that are handled is limited by the array
+		// that runs out of space first
+		avail := len(delims) - consumed
+		for i := 0; i < len(u.lists); i++ {
+			l := &u.lists[i]
+			l.outer = l.outer[:cap(l.outer)]
+			l.perms = l.perms[:cap(l.perms)]
+			l.splat.auxpos = consumed
+			wd := watch("unnest", u.parent, &l.prog, &l.splat)
+			in, out := evalsplat(&l.splat, delims[consumed:consumed+avail], l.outer, l.perms)
+			if err := wd.done(); err != nil {
+				return err
+			}
+			if l.splat.err != 0 {
+				return bytecodeerror("unnest", &l.splat)
+			}
+			if in == 0 {
+				// not enough room for a single
+				// lane; grow and try again
+				l.grow()
+				i--
+				continue
+			}
+			avail = in
+			outs[i] = out
+		}
+		// the arrays splatted earlier may include
+		// elements from rows at or past avail
+		// (including partially-splatted rows),
+		// so trim those away
+		for i := range u.lists {
+			l := &u.lists[i]
+			for outs[i] > 0 && int(l.perms[outs[i]-1]) >= avail {
+				outs[i]--
+			}
+		}
+		n := u.zip(outs, avail)
+		if n > 0 {
+			u.inner = shrink(u.inner, n)
+			for i, p := range u.zperms[:n] {
+				u.inner[i] = delims[consumed+int(p)]
+			}
+			err := u.dstrc.writeRows(u.inner, u.splatParams(rp, consumed, u.zperms[:n], u.zipped...))
+			if err != nil {
+				return err
+			}
+		}
+		consumed += avail
+	}
+	return nil
+}
+
+// zip merges the first outs[i] splatted elements
+// of each array for the first rows input rows
+// into u.zipped and u.zperms and returns the number
+// of rows produced
+func (u *unnesting) zip(outs []int, rows int) int {
+	total := 0
+	pos := make([]int, len(u.lists))
+	u.zipped = shrink(u.zipped, len(u.lists))
+	for row := 0; row < rows; row++ {
+		// determine the length of the
+		// longest array in this row
+		longest := 0
+		for i := range u.lists {
+			l := &u.lists[i]
+			n := 0
+			for pos[i]+n < outs[i] && int(l.perms[pos[i]+n]) == row {
+				n++
+			}
+			if n > longest {
+				longest = n
+			}
+		}
+		if longest == 0 {
+			continue
+		}
+		u.zperms = slices.Grow(u.zperms[:total], longest)[:total+longest]
+		for j := total; j < total+longest; j++ {
+			u.zperms[j] = int32(row)
+		}
+		for i := range u.lists {
+			l := &u.lists[i]
+			u.zipped[i] = slices.Grow(u.zipped[i][:total], longest)[:total+longest]
+			for j := 0; j < longest; j++ {
+				if pos[i] < outs[i] && int(l.perms[pos[i]]) == row {
+					u.zipped[i][total+j] = l.outer[pos[i]]
+					pos[i]++
+				} else {
+					u.zipped[i][total+j] = vmref{} // MISSING
+				}
+			}
+		}
+		total += longest
+	}
+	for i := range u.zipped {
+		u.zipped[i] = sanitizeAux(u.zipped[i], total)
+	}
+	return total
+}
+
 func (u *unnesting) Close() error {
-	u.splat.reset()
+	for i := range u.lists {
+		u.lists[i].splat.reset()
+	}
 	return u.dstrc.Close()
 }
@@ -20,6 +20,7 @@ import (
 	"testing"
 
 	"github.com/SnellerInc/sneller/expr"
+	"github.com/SnellerInc/sneller/ion"
 )
 
 func path(t testing.TB, s string) expr.Node {
@@ -152,3 +153,128 @@ func TestSplat(t *testing.T) {
 		}
 	}
 }
+
+func TestUnnestZip(t *testing.T) {
+	// produce rows with arrays of different
+	// lengths, large enough that the splatted
+	// elements do not fit in the initial buffers
+	const rows = 64
+	lengths := func(i int) (int, int) {
+		return (i * 37) % 1100, (i * 53) % 1300
+	}
+	var st ion.Symtab
+	var body ion.Buffer
+	id, a, b := st.Intern("id"), st.Intern("a"), st.Intern("b")
+	for i := 0; i < rows; i++ {
+		la, lb := lengths(i)
+		body.BeginStruct(-1)
+		body.BeginField(id)
+		body.WriteInt(int64(i))
+		body.BeginField(a)
+		body.BeginList(-1)
+		for j := 0; j < la; j++ {
+			body.WriteInt(int64(j))
+		}
+		body.EndList()
+		body.BeginField(b)
+		body.BeginList(-1)
+		for j := 0; j < lb; j++ {
+			body.WriteInt(int64(-j))
+		}
+		body.EndList()
+		body.EndStruct()
+	}
+	var buf ion.Buffer
+	st.Marshal(&buf, true)
+	buf.UnsafeAppend(body.Bytes())
+	if buf.Size() > defaultAlign {
+		t.Fatalf("input is %d bytes", buf.Size())
+	}
+
+	var dst QueryBuffer
+	p, err := NewProjection(selection("id, x, y"), &dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, err := NewUnnestZip(p, []expr.Node{path(t, "a"), path(t, "b")}, []string{"x", "y"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = CopyRows(u, buftbl(buf.Bytes()), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// each row should produce max(len(a), len(b))
+	// rows, each of which has x = -y when both
+	// of the elements are present
+	seen := make([][]bool, rows)
+	for i := range seen {
+		la, lb := lengths(i)
+		if lb > la {
+			la = lb
+		}
+		seen[i] = make([]bool, la)
+	}
+	var outst ion.Symtab
+	out := dst.Bytes()
+	for len(out) > 0 {
+		if ion.IsBVM(out) || ion.TypeOf(out) == ion.AnnotationType {
+			out, err = outst.Unmarshal(out)
+			if err != nil {
+				t.Fatal(err)
+			}
+			continue
+		}
+		var dat ion.Datum
+		dat, out, err = ion.ReadDatum(&outst, out)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if dat.IsNull() {
+			continue // padding
+		}
+		s, err := dat.Struct()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var row, x, y int64
+		var hasx, hasy bool
+		err = s.Each(func(f ion.Field) error {
+			var err error
+			switch f.Label {
+			case "id":
+				row, err = f.Int()
+			case "x":
+				x, err = f.Int()
+				hasx = true
+			case "y":
+				y, err = f.Int()
+				hasy = true
+			}
+			return err
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		la, lb := lengths(int(row))
+		j := x
+		if !hasx {
+			j = -y
+		}
+		if hasx != (int(j) < la) || hasy != (int(j) < lb) || (hasx && hasy && x != -y) {
+			t.Fatalf("unexpected row %s", dat)
+		}
+		if seen[row][j] {
+			t.Fatalf("duplicate row %s", dat)
+		}
+		seen[row][j] = true
+	}
+	for i := range seen {
+		for j := range seen[i] {
+			if !seen[i][j] {
+				t.Fatalf("missing element %d of row %d", j, i)
+			}
+		}
+	}
+}
@@ -0,0 +1,245 @@
+// Copyright (C) 2022 Sneller, Inc.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package vm
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/SnellerInc/sneller/expr"
+)
+
+var watchdog struct {
+	limit atomic.Int64 // time.Duration; zero if disabled
+	trips atomic.Int64
+
+	lock   sync.Mutex
+	active map[*batch]struct{}
+	stop   chan struct{}
+}
+
+// WatchdogError is the error produced when
+// a query operator spends more than the
+// watchdog limit (see SetWatchdog) processing
+// a single batch of rows.
+type WatchdogError struct {
+	// Op is the name of the operator
+	// that was processing the batch.
+	Op string
+	// Expr is the (redacted) text of the
+	// expression(s) that the operator was evaluating.
+	Expr string
+	// Elapsed is the amount of time
+	// that the operator spent on the batch.
+	Elapsed time.Duration
+	// Limit is the watchdog limit
+	// that was exceeded.
+	Limit time.Duration
+}
+
+// Error implements error
+func (w *WatchdogError) Error() string {
+	return fmt.Sprintf("query aborted: %s (%s) spent %s on one batch of rows (limit %s)",
+		w.Op, w.Expr, w.Elapsed.Round(time.Millisecond), w.Limit)
+}
+
+// SetWatchdog sets the maximum amount of time
+// that a query operator may spend evaluating
+// bytecode on a single batch of rows.
+// A limit of zero disables the watchdog.
+//
+// Batches that take longer than the limit
+// (typically due to pathological regular expressions
+// or bugs in bytecode kernels) are reported via Errorf
+// as soon as they are detected, and the bytecode
+// evaluating the batch is interrupted before it
+// processes the next group of 16 rows, so the query
+// fails with a *WatchdogError without finishing the batch.
+// (A single instruction that never returns cannot be
+// interrupted; it is reported but not stopped.)
+func SetWatchdog(limit time.Duration) {
+	if limit < 0 {
+		panic("vm.SetWatchdog: negative limit")
+	}
+	watchdog.lock.Lock()
+	defer watchdog.lock.Unlock()
+	watchdog.limit.Store(int64(limit))
+	if watchdog.stop != nil {
+		close(watchdog.stop)
+		watchdog.stop = nil
+	}
+	if limit == 0 {
+		return
+	}
+	if watchdog.active == nil {
+		watchdog.active = make(map[*batch]struct{})
+	}
+	watchdog.stop = make(chan struct{})
+	go monitor(limit, watchdog.stop)
+}
+
+// WatchdogTrips returns the number of batches
+// that have exceeded the watchdog limit
+// since the process started.
+func WatchdogTrips() int64 {
+	return watchdog.trips.Load()
+}
+
+func monitor(limit time.Duration, stop chan struct{}) {
+	interval := limit / 4
+	if interval < time.Millisecond {
+		interval = time.Millisecond
+	}
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case now := <-t.C:
+			watchdog.lock.Lock()
+			for b := range watchdog.active {
+				if elapsed := now.Sub(b.start); elapsed > limit {
+					b.trip(elapsed, limit)
+				}
+			}
+			watchdog.lock.Unlock()
+		}
+	}
+}
+
+// watched is implemented by the query operators
+// whose bytecode is tracked by the watchdog
+type watched interface {
+	// redacted returns the text of the expression(s)
+	// evaluated by the operator with constants redacted
+	redacted() string
+}
+
+// batch is a single in-progress
+// bytecode evaluation tracked by the watchdog
+type batch struct {
+	op      string
+	what    watched
+	prog    *prog
+	bc      *bytecode
+	start   time.Time
+	tripped atomic.Bool
+}
+
+// watch begins tracking the evaluation
+// of one batch of rows by an operator;
+// the caller must call done once the
+// bytecode has returned
+//
+// watch returns nil if the watchdog is disabled
+func watch(op string, what watched, p *prog, bc *bytecode) *batch {
+	if watchdog.limit.Load() == 0 {
+		return nil
+	}
+	b := &batch{op: op, what: what, prog: p, bc: bc, start: time.Now()}
+	watchdog.lock.Lock()
+	if watchdog.active != nil {
+		watchdog.active[b] = struct{}{}
+	}
+	watchdog.lock.Unlock()
+	return b
+}
+
+// done stops tracking b and returns
+// a *WatchdogError if b exceeded the limit
+func (b *batch) done() error {
+	if b == nil {
+		return nil
+	}
+	elapsed := time.Since(b.start)
+	limit := time.Duration(watchdog.limit.Load())
+	watchdog.lock.Lock()
+	delete(watchdog.active, b)
+	if limit > 0 && elapsed > limit {
+		// finished before the monitor noticed
+		b.trip(elapsed, limit)
+	}
+	watchdog.lock.Unlock()
+	if !b.tripped.Load() {
+		return nil
+	}
+	atomic.StoreInt32(&b.bc.interrupt, 0)
+	return &WatchdogError{Op: b.op, Expr: b.what.redacted(), Elapsed: elapsed, Limit: limit}
+}
+
+// trip reports b as having exceeded the limit;
+// the caller must hold watchdog.lock so that
+// b.prog and b.bc are not recompiled concurrently
+func (b *batch) trip(elapsed, limit time.Duration) {
+	if !b.tripped.CompareAndSwap(false, true) {
+		return
+	}
+	// stop the bytecode at the next group of lanes
+	atomic.StoreInt32(&b.bc.interrupt, 1)
+	watchdog.trips.Add(1)
+	var ssa strings.Builder
+	writeShape(&ssa, b.what, b.prog)
+	errorf("watchdog: %s has spent %s on one batch of rows (limit %s)", b.op, elapsed.Round(time.Millisecond), limit)
+	errorf("watchdog: %s program:\n%s", b.op, ssa.String())
+	errorf("watchdog: %s bytecode:\n%s", b.op, formatBytecode(b.bc, 0))
+}
+
+// writeShape writes the redacted expression
+// evaluated by an operator followed by the
+// textual representation of p with immediates
+// elided so that no literal values from the
+// query end up in the diagnostic log
+func writeShape(dst *strings.Builder, what watched, p *prog) {
+	fmt.Fprintf(dst, "expr: %s\n", what.redacted())
+	if p == nil {
+		return
+	}
+	for _, v := range p.values {
+		dst.WriteString(v.Name())
+		dst.WriteString(" = ")
+		dst.WriteString(v.op.String())
+		info := &ssainfo[v.op]
+		for i := range v.args {
+			dst.WriteByte(' ')
+			dst.WriteByte(info.argType(i).char())
+			dst.WriteString(strconv.Itoa(v.args[i].id))
+		}
+		if v.imm != nil {
+			dst.WriteString(" $?")
+		}
+		dst.WriteByte('\n')
+	}
+	if p.ret != nil {
+		fmt.Fprintf(dst, "ret: %s\n", p.ret.Name())
+	}
+}
+
+// redactList returns the redacted text
+// of a list of expressions
+func redactList(lst []expr.Node) string {
+	var dst strings.Builder
+	for i := range lst {
+		if i > 0 {
+			dst.WriteString(", ")
+		}
+		dst.WriteString(expr.ToRedacted(lst[i]))
+	}
+	return dst.String()
+}
@@ -0,0 +1,134 @@
+// Copyright (C) 2022 Sneller, Inc.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package vm
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/SnellerInc/sneller/expr"
+)
+
+func TestWatchdog(t *testing.T) {
+	buf, err := os.ReadFile("../testdata/parking.10n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var lock sync.Mutex
+	var log strings.Builder
+	saved := Errorf
+	Errorf = func(f string, args ...any) {
+		lock.Lock()
+		defer lock.Unlock()
+		fmt.Fprintf(&log, f+"\n", args...)
+	}
+	defer func() { Errorf = saved }()
+
+	// every batch takes longer than this
+	SetWatchdog(time.Nanosecond)
+	defer SetWatchdog(0)
+
+	before := WatchdogTrips()
+	var dst QueryBuffer
+	s, err := NewProjection(selection("Ticket as t"), &dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = CopyRows(s, buftbl(buf), 1)
+	var we *WatchdogError
+	if !errors.As(err, &we) {
+		t.Fatalf("got error %v; expected a *WatchdogError", err)
+	}
+	if we.Op != "projection" {
+		t.Errorf("unexpected operator %q", we.Op)
+	}
+	if we.Expr != "Ticket AS t" {
+		t.Errorf("unexpected expression %q", we.Expr)
+	}
+	if WatchdogTrips() <= before {
+		t.Error("trip count did not increase")
+	}
+	lock.Lock()
+	text := log.String()
+	lock.Unlock()
+	if !strings.Contains(text, "watchdog: projection") || !strings.Contains(text, "expr: Ticket AS t") {
+		t.Errorf("missing diagnostics in log:\n%s", text)
+	}
+
+	// disabling the watchdog lets the query complete
+	SetWatchdog(0)
+	dst.Reset()
+	s, err = NewProjection(selection("Ticket as t"), &dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = CopyRows(s, buftbl(buf), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWatchdogInterrupt(t *testing.T) {
+	var st symtab
+	defer st.free()
+	orig := unhex(parkingCitations1KLines)
+	buf := Malloc()
+	defer Free(buf)
+	buf = buf[:copy(buf, orig)]
+	_, err := st.Unmarshal(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	delims := make([]vmref, 1024)
+	n, _ := scanvmm(buf, delims)
+	delims = delims[:n]
+
+	p, err := compileLogical(expr.Compare(expr.Greater, expr.Identifier("Ticket"), expr.Integer(0)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = p.symbolize(&st, &auxbindings{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var bc bytecode
+	defer bc.reset()
+	err = p.compile(&bc, &st, "TestWatchdogInterrupt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	bc.prepare(&rowParams{})
+
+	// an interrupted program should abort
+	// (the return value is meaningless on error)
+	bc.interrupt = 1
+	evalfilterbc(&bc, delims)
+	if bc.err != bcerrInterrupted {
+		t.Fatalf("got error %v; expected %v", bc.err, bcerrInterrupted)
+	}
+
+	bc.interrupt = 0
+	if valid := evalfilterbc(&bc, delims); valid == 0 {
+		t.Error("filter returned no rows")
+	}
+	if bc.err != 0 {
+		t.Fatal(bc.err)
+	}
+}
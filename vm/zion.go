@@ -15,6 +15,9 @@
 package vm
 
 import (
+	"fmt"
+
+	"github.com/SnellerInc/sneller/ion"
 	"github.com/SnellerInc/sneller/ion/zion/zll"
 )
 
@@ -28,3 +31,171 @@ type zionConsumer interface {
 	zionOk() bool
 	writeZion(state *zionState) error
 }
+
+// zionRows converts the decompressed buckets
+// of a zion block into rows that can be passed
+// directly to rowConsumer.writeRows, which lets
+// row-oriented kernels consume zion data without
+// first re-encoding it as ion and copying it into vmm.
+//
+// Rows whose selected fields are adjacent in the
+// decompressed buckets (including every row when only
+// one field is selected) reference the bucket memory
+// directly; the remaining rows are assembled in a
+// separate vmm page.
+type zionRows struct {
+	delims []vmref
+	params rowParams  // always empty
+	out    []byte     // vmm page for assembled rows
+	fields [][2]int32 // fields in the current row
+}
+
+// free releases the vmm page used for assembled rows
+func (z *zionRows) free() {
+	if z.out != nil {
+		Free(z.out)
+		z.out = nil
+	}
+}
+
+func (z *zionRows) flush(dst rowConsumer) error {
+	if len(z.delims) == 0 {
+		return nil
+	}
+	err := dst.writeRows(z.delims, &z.params)
+	z.delims = z.delims[:0]
+	if z.out != nil {
+		z.out = z.out[:0]
+	}
+	return err
+}
+
+// write decompresses the buckets needed for
+// state.components and passes the resulting rows
+// to dst in batches
+func (z *zionRows) write(state *zionState, dst rowConsumer) error {
+	b := &state.buckets
+	var err error
+	precise := state.components != nil
+	if precise {
+		err = b.Select(state.components)
+	} else {
+		err = b.SelectAll()
+	}
+	if err != nil {
+		return err
+	}
+	if z.delims == nil {
+		z.delims = make([]vmref, 0, defaultDelims)
+	}
+	// see zion.Decoder.walk
+	var base [zll.NumBuckets]int32
+	mem := b.Decompressed
+	shape := state.shape.Bits[state.shape.Start:]
+	instruct := false
+	z.fields = z.fields[:0]
+	for len(shape) > 0 {
+		fc := int(shape[0] & 0x1f)
+		if fc > 16 {
+			return fmt.Errorf("vm: zion shape: fc = %x", fc)
+		}
+		skip := (fc + 3) / 2
+		if len(shape) < skip {
+			return fmt.Errorf("vm: zion shape: skip %d > len(shape)=%d", skip, len(shape))
+		}
+		nibbles := uint64(0)
+		for i, c := range shape[1:skip] {
+			nibbles |= uint64(c) << (i * 8)
+		}
+		shape = shape[skip:]
+		instruct = true
+		for i := 0; i < fc; i++ {
+			bucket := nibbles & 0xf
+			nibbles >>= 4
+			if b.Pos[bucket] < 0 {
+				continue // bucket not decompressed
+			}
+			start := b.Pos[bucket] + base[bucket]
+			if int(start) >= len(mem) {
+				return fmt.Errorf("vm: zion buckets: unexpected bucket EOF")
+			}
+			sym, rest, err := ion.ReadLabel(mem[start:])
+			if err != nil {
+				return fmt.Errorf("vm: zion buckets: %w", err)
+			}
+			size := ion.SizeOf(rest)
+			if size <= 0 || size > len(rest) {
+				return fmt.Errorf("vm: zion buckets: SizeOf=%d", size)
+			}
+			end := int32(len(mem) - len(rest) + size)
+			base[bucket] = end - b.Pos[bucket]
+			if precise && !b.Selected(sym) {
+				continue
+			}
+			z.fields = append(z.fields, [2]int32{start, end})
+		}
+		if fc < 16 {
+			if err := z.row(mem, dst); err != nil {
+				return err
+			}
+			instruct = false
+		}
+	}
+	if instruct {
+		return fmt.Errorf("vm: zion shape: missing terminal 0x10 fc marker")
+	}
+	return z.flush(dst)
+}
+
+// row adds a delimiter for the fields in z.fields
+func (z *zionRows) row(mem []byte, dst rowConsumer) error {
+	if len(z.delims) == cap(z.delims) {
+		if err := z.flush(dst); err != nil {
+			return err
+		}
+	}
+	fields := z.fields
+	z.fields = z.fields[:0]
+	if len(fields) == 0 {
+		z.delims = append(z.delims, vmref{})
+		return nil
+	}
+	contiguous := true
+	size := 0
+	for i := range fields {
+		if i > 0 && fields[i][0] != fields[i-1][1] {
+			contiguous = false
+		}
+		size += int(fields[i][1] - fields[i][0])
+	}
+	if contiguous {
+		pos, ok := vmdispl(mem[fields[0][0]:])
+		if !ok {
+			panic("zion decompressed buckets data not in vmm")
+		}
+		z.delims = append(z.delims, vmref{pos, uint32(size)})
+		return nil
+	}
+	if z.out == nil {
+		out, err := TryMalloc()
+		if err != nil {
+			return err
+		}
+		z.out = out[:0]
+	}
+	if cap(z.out)-len(z.out) < size {
+		if err := z.flush(dst); err != nil {
+			return err
+		}
+	}
+	off := len(z.out)
+	for i := range fields {
+		z.out = append(z.out, mem[fields[i][0]:fields[i][1]]...)
+	}
+	pos, ok := vmdispl(z.out[off:])
+	if !ok {
+		panic("zionRows.out not in vmm")
+	}
+	z.delims = append(z.delims, vmref{pos, uint32(size)})
+	return nil
+}